@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/routing"
+	"split-vpn-webui/internal/server"
+)
+
+const cliUsage = `usage: split-vpn-webui <command> [flags]
+
+Commands talk to the running server over its control socket:
+  status               show service health, active WAN and VPN states
+  vpn list             list VPN profiles and whether they are connected
+  group apply          re-apply all domain group routing rules
+  backup export        write a configuration backup (stdout or -o file)
+  resolver run         start a policy resolver refresh (-wait to follow it)
+
+Common flags:
+  -data-dir <dir>      persistent data directory (default ` + defaultDataDir + `)
+  -socket <path>       control socket (default <data-dir>/control.sock)
+  -json                print raw JSON responses`
+
+// isCLICommand reports whether name is a management subcommand.
+func isCLICommand(name string) bool {
+	switch name {
+	case "status", "vpn", "group", "backup", "resolver":
+		return true
+	}
+	return false
+}
+
+// cliCommand is one management subcommand.
+type cliCommand struct {
+	run   func(c *cliClient, flags *flag.FlagSet) error
+	setup func(flags *flag.FlagSet)
+}
+
+var cliCommands = map[string]cliCommand{
+	"status":        {run: runStatusCommand},
+	"vpn list":      {run: runVPNListCommand},
+	"group apply":   {run: runGroupApplyCommand},
+	"backup export": {run: runBackupExportCommand, setup: func(flags *flag.FlagSet) { flags.String("o", "", "output file (default stdout)") }},
+	"resolver run": {run: runResolverRunCommand, setup: func(flags *flag.FlagSet) {
+		flags.Bool("wait", false, "wait for the run to finish and print its summary")
+	}},
+}
+
+// runCLICommand implements `split-vpn-webui <command> [subcommand] [flags]`
+// and returns the process exit code.
+func runCLICommand(args []string, stdout, stderr io.Writer) int {
+	name := args[0]
+	rest := args[1:]
+	if _, ok := cliCommands[name]; !ok {
+		if len(rest) == 0 || strings.HasPrefix(rest[0], "-") {
+			fmt.Fprintln(stderr, cliUsage)
+			return 2
+		}
+		name += " " + rest[0]
+		rest = rest[1:]
+	}
+	command, ok := cliCommands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s\n", name, cliUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dataDir := flags.String("data-dir", defaultDataDir, "persistent data directory")
+	socketPath := flags.String("socket", "", "control socket path")
+	rawJSON := flags.Bool("json", false, "print raw JSON responses")
+	if command.setup != nil {
+		command.setup(flags)
+	}
+	if err := flags.Parse(rest); err != nil {
+		return 2
+	}
+	if *socketPath == "" {
+		*socketPath = defaultControlSocket(*dataDir)
+	}
+
+	client := newCLIClient(*socketPath, stdout, *rawJSON)
+	if err := command.run(client, flags); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// cliClient issues API requests over the control socket.
+type cliClient struct {
+	http    *http.Client
+	socket  string
+	out     io.Writer
+	rawJSON bool
+}
+
+func newCLIClient(socketPath string, out io.Writer, rawJSON bool) *cliClient {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &cliClient{
+		http:    &http.Client{Transport: transport, Timeout: 5 * time.Minute},
+		socket:  socketPath,
+		out:     out,
+		rawJSON: rawJSON,
+	}
+}
+
+// do sends a request and returns the response body, turning API errors into
+// Go errors carrying the server's message.
+func (c *cliClient) do(method, path string) ([]byte, error) {
+	req, err := http.NewRequest(method, "http://control"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, fmt.Errorf("cannot reach split-vpn-webui on %s (is the service running and are you root?): %w", c.socket, err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return nil, errors.New(apiErr.Error)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return body, nil
+}
+
+// fetchJSON decodes the response to method path into target. With -json the raw body is printed and
+// printed reports true so callers skip their table output.
+func (c *cliClient) fetchJSON(method, path string, target any) (printed bool, err error) {
+	body, err := c.do(method, path)
+	if err != nil {
+		return false, err
+	}
+	if c.rawJSON {
+		return true, c.printRaw(body)
+	}
+	return false, json.Unmarshal(body, target)
+}
+
+func (c *cliClient) printRaw(body []byte) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err != nil {
+		_, err = c.out.Write(body)
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(c.out)
+	return err
+}
+
+func runStatusCommand(c *cliClient, _ *flag.FlagSet) error {
+	if c.rawJSON {
+		body, err := c.do(http.MethodGet, "/api/stats")
+		if err != nil {
+			return err
+		}
+		return c.printRaw(body)
+	}
+	var health struct {
+		Status        string `json:"status"`
+		SchemaVersion int    `json:"schemaVersion"`
+		SchemaLatest  int    `json:"schemaLatest"`
+	}
+	if _, err := c.fetchJSON(http.MethodGet, "/api/health", &health); err != nil {
+		return err
+	}
+	var payload server.UpdatePayload
+	if _, err := c.fetchJSON(http.MethodGet, "/api/stats", &payload); err != nil {
+		return err
+	}
+	connected := 0
+	for _, cfg := range payload.Configs {
+		if cfg.Connected {
+			connected++
+		}
+	}
+	activeWAN := payload.Stats.ActiveWAN
+	if activeWAN == "" {
+		activeWAN = "-"
+	}
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Service:\t%s (schema %d/%d)\n", health.Status, health.SchemaVersion, health.SchemaLatest)
+	fmt.Fprintf(tw, "Active WAN:\t%s\n", activeWAN)
+	fmt.Fprintf(tw, "VPNs:\t%d of %d connected\n", connected, len(payload.Configs))
+	for name, message := range payload.Errors {
+		fmt.Fprintf(tw, "Error:\t%s: %s\n", name, message)
+	}
+	return tw.Flush()
+}
+
+func runVPNListCommand(c *cliClient, _ *flag.FlagSet) error {
+	var payload server.UpdatePayload
+	printed, err := c.fetchJSON(http.MethodGet, "/api/stats", &payload)
+	if err != nil || printed {
+		return err
+	}
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tINTERFACE\tSTATE\tAUTOSTART")
+	for _, cfg := range payload.Configs {
+		state := "down"
+		if cfg.Connected {
+			state = "up"
+		}
+		autostart := "no"
+		if cfg.Autostart {
+			autostart = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", cfg.Name, cfg.VPNType, cfg.InterfaceName, state, autostart)
+	}
+	return tw.Flush()
+}
+
+func runGroupApplyCommand(c *cliClient, _ *flag.FlagSet) error {
+	var payload struct {
+		Status string `json:"status"`
+	}
+	printed, err := c.fetchJSON(http.MethodPost, "/api/routing/apply", &payload)
+	if err != nil || printed {
+		return err
+	}
+	fmt.Fprintln(c.out, "Routing rules applied.")
+	return nil
+}
+
+func runBackupExportCommand(c *cliClient, flags *flag.FlagSet) error {
+	body, err := c.do(http.MethodGet, "/api/backup/export")
+	if err != nil {
+		return err
+	}
+	output := flags.Lookup("o").Value.String()
+	if output == "" || output == "-" {
+		_, err := c.out.Write(body)
+		return err
+	}
+	// Backups contain VPN credentials.
+	if err := os.WriteFile(output, body, 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Backup written to %s\n", output)
+	return nil
+}
+
+func runResolverRunCommand(c *cliClient, flags *flag.FlagSet) error {
+	if _, err := c.do(http.MethodPost, "/api/resolver/run"); err != nil {
+		return err
+	}
+	if flags.Lookup("wait").Value.String() != "true" {
+		fmt.Fprintln(c.out, "Resolver run started.")
+		return nil
+	}
+	for {
+		var list struct {
+			Jobs    []jobs.Job                 `json:"jobs"`
+			LastRun *routing.ResolverRunRecord `json:"lastRun"`
+		}
+		body, err := c.do(http.MethodGet, "/api/jobs?kind=resolver&limit=1")
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return err
+		}
+		if len(list.Jobs) == 0 || list.Jobs[0].State.Terminal() {
+			if c.rawJSON {
+				return c.printRaw(body)
+			}
+			if len(list.Jobs) > 0 && list.Jobs[0].State != jobs.StateSucceeded && list.LastRun == nil {
+				return fmt.Errorf("resolver run %s: %s", list.Jobs[0].State, list.Jobs[0].Error)
+			}
+			return printResolverRun(c.out, list.LastRun)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func printResolverRun(out io.Writer, run *routing.ResolverRunRecord) error {
+	if run == nil {
+		fmt.Fprintln(out, "Resolver finished; no run recorded.")
+		return nil
+	}
+	if run.Error != "" {
+		return fmt.Errorf("resolver run failed: %s", run.Error)
+	}
+	fmt.Fprintf(out, "Resolver run finished in %s: %d/%d selectors, %d prefixes.\n",
+		(time.Duration(run.DurationMS) * time.Millisecond).Round(time.Millisecond),
+		run.SelectorsDone, run.SelectorsTotal, run.PrefixesResolved)
+	if run.SelectorsFailed > 0 {
+		fmt.Fprintf(out, "%d selectors failed; see GET /api/resolver/runs/%d.\n", run.SelectorsFailed, run.ID)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/auth"
+	"split-vpn-webui/internal/server"
+	"split-vpn-webui/internal/settings"
+)
+
+func serveTestSocket(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	// Keep the path short: unix socket paths are limited to ~108 bytes.
+	dir, err := os.MkdirTemp("", "svpn")
+	if err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, controlSocketName)
+	listener, err := listenControlSocket(path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: handler}
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return path
+}
+
+func TestCLIVPNListPrintsTable(t *testing.T) {
+	socket := serveTestSocket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/stats" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(server.UpdatePayload{Configs: []server.ConfigStatus{
+			{Name: "wg-sgp", VPNType: "wireguard", InterfaceName: "wg-sv-sgp", Connected: true, Autostart: true},
+			{Name: "ovpn-us", VPNType: "openvpn", InterfaceName: "tun0"},
+		}})
+	}))
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLICommand([]string{"vpn", "list", "-socket", socket}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "NAME") {
+		t.Fatalf("unexpected output:\n%s", stdout.String())
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 5 || fields[0] != "wg-sgp" || fields[3] != "up" || fields[4] != "yes" {
+		t.Fatalf("unexpected row %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[0] != "ovpn-us" || fields[3] != "down" {
+		t.Fatalf("unexpected row %q", lines[2])
+	}
+}
+
+func TestCLIReportsAPIErrors(t *testing.T) {
+	socket := serveTestSocket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/resolver/run" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error":"resolver run already in progress"}`))
+	}))
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLICommand([]string{"resolver", "run", "-socket", socket}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "resolver run already in progress") {
+		t.Fatalf("expected API error in stderr, got %q", stderr.String())
+	}
+}
+
+func TestCLIResolverRunWaitsOnJobList(t *testing.T) {
+	socket := serveTestSocket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/resolver/run":
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"status":"started"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/jobs" && r.URL.Query().Get("kind") == "resolver":
+			_, _ = w.Write([]byte(`{"jobs":[{"id":3,"kind":"resolver","state":"succeeded"}],` +
+				`"lastRun":{"id":7,"durationMs":1500,"selectorsTotal":4,"selectorsDone":4,"prefixesResolved":12}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLICommand([]string{"resolver", "run", "-wait", "-socket", socket}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "4/4 selectors, 12 prefixes") {
+		t.Fatalf("unexpected output %q", stdout.String())
+	}
+}
+
+func TestCLIUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCLICommand([]string{"vpn", "explode"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage exit 2, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "usage:") {
+		t.Fatalf("expected usage text, got %q", stderr.String())
+	}
+}
+
+func TestListControlSocketReplacesStaleSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "svpn")
+	if err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, controlSocketName)
+
+	// A socket file nobody listens on, as left behind by a crash.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen stale: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listenControlSocket(path)
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced: %v", err)
+	}
+	defer listener.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 socket, got %v", info.Mode().Perm())
+	}
+	if _, err := listenControlSocket(path); err == nil {
+		t.Fatalf("expected error while another listener is live")
+	}
+}
+
+func TestControlServerBypassesAuthOnlyForRoot(t *testing.T) {
+	dir, err := os.MkdirTemp("", "svpn")
+	if err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	manager := auth.NewManager(settings.NewManager(filepath.Join(dir, "settings.json")))
+	if err := manager.EnsureDefaults(); err != nil {
+		t.Fatalf("ensure auth defaults: %v", err)
+	}
+	protected := manager.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+
+	listener, err := listenControlSocket(filepath.Join(dir, controlSocketName))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := newControlServer(protected)
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Close()
+
+	client := newCLIClient(filepath.Join(dir, controlSocketName), io.Discard, false)
+	_, err = client.do(http.MethodGet, "/api/stats")
+	if os.Geteuid() == 0 && runtime.GOOS == "linux" {
+		if err != nil {
+			t.Fatalf("expected root caller to bypass auth, got %v", err)
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Fatalf("expected non-root caller to need auth, got %v", err)
+	}
+}
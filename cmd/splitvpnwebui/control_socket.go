@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"split-vpn-webui/internal/auth"
+)
+
+// controlSocketName is the unix socket in the data directory that the
+// management subcommands use to reach the running server.
+const controlSocketName = "control.sock"
+
+func defaultControlSocket(dataDir string) string {
+	return filepath.Join(dataDir, controlSocketName)
+}
+
+// listenControlSocket opens the root-only control socket. A stale socket
+// left behind by a crash is replaced; a live one means another instance
+// owns it.
+func listenControlSocket(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another instance is listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+type rootPeerKey struct{}
+
+// newControlServer serves handler on the control socket. Requests from root
+// peers skip authentication so the CLI and boot scripts keep working when
+// auth is misconfigured; any other caller still needs a token or session.
+func newControlServer(handler http.Handler) *http.Server {
+	trusted := auth.TrustLocal(handler)
+	return &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if root, _ := r.Context().Value(rootPeerKey{}).(bool); root {
+				trusted.ServeHTTP(w, r)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}),
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			uid, ok := peerUID(conn)
+			return context.WithValue(ctx, rootPeerKey{}, ok && uid == 0)
+		},
+		ReadTimeout: 15 * time.Second,
+		IdleTimeout: 120 * time.Second,
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/listen"
+	"split-vpn-webui/internal/server"
+)
+
+// resolveListeners expands the listen setting into one address per listener.
+// An empty setting, or one where nothing resolves, falls back to the single
+// address picked by resolveListenAddress so the UI stays reachable.
+func resolveListeners(defaultAddr, spec string) []listen.Address {
+	fallback := func() []listen.Address {
+		return []listen.Address{{Addr: resolveListenAddress(defaultAddr, ""), Source: defaultAddr}}
+	}
+	entries, err := listen.ParseSpec(spec)
+	if err != nil {
+		log.Printf("warning: ignoring invalid listen setting %q: %v", spec, err)
+		return fallback()
+	}
+	if len(entries) == 0 {
+		return fallback()
+	}
+	_, port, err := net.SplitHostPort(defaultAddr)
+	if err != nil || port == "" {
+		port = "8091"
+	}
+	addrs, errs := listen.Resolve(entries, port, listen.InterfaceIPs)
+	for _, err := range errs {
+		log.Printf("warning: %v", err)
+	}
+	if len(addrs) == 0 {
+		return fallback()
+	}
+	return addrs
+}
+
+// resolveKioskListeners expands the kiosk setting into listener addresses.
+// Unlike the main listeners there is no fallback: an empty or unresolvable
+// setting leaves the kiosk off.
+func resolveKioskListeners(spec string) []listen.Address {
+	entries, err := listen.ParseSpec(spec)
+	if err != nil {
+		log.Printf("warning: ignoring invalid kiosk listen setting %q: %v", spec, err)
+		return nil
+	}
+	addrs, errs := listen.Resolve(entries, server.DefaultKioskPort, listen.InterfaceIPs)
+	for _, err := range errs {
+		log.Printf("warning: kiosk: %v", err)
+	}
+	return addrs
+}
+
+// listenerSet runs one http.Server per listen address, all sharing a
+// handler. Reload swaps the address set while the process keeps running.
+type listenerSet struct {
+	handler  http.Handler
+	certPath string
+	keyPath  string
+
+	mu      sync.Mutex
+	servers map[listenerKey]*boundListener
+}
+
+type listenerKey struct {
+	addr string
+	tls  bool
+}
+
+type boundListener struct {
+	server   *http.Server
+	listener net.Listener
+	source   string
+}
+
+var errNoListeners = errors.New("no listen address could be bound")
+
+func newListenerSet(handler http.Handler, certPath, keyPath string) *listenerSet {
+	return &listenerSet{
+		handler:  handler,
+		certPath: certPath,
+		keyPath:  keyPath,
+		servers:  make(map[listenerKey]*boundListener),
+	}
+}
+
+// Reload serves exactly addrs. Listeners already bound keep running so
+// existing connections are not dropped; removed ones stop accepting at once
+// and drain in the background. If nothing could be bound the previous set is
+// restored and an error returned. Empty addrs stops every listener.
+func (s *listenerSet) Reload(addrs []listen.Address) ([]listen.Address, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[listenerKey]listen.Address, len(addrs))
+	for _, addr := range addrs {
+		wanted[listenerKey{addr: addr.Addr, tls: addr.TLS}] = addr
+	}
+	removed := make(map[listenerKey]*boundListener)
+	for key, bound := range s.servers {
+		if _, ok := wanted[key]; !ok {
+			// Close the socket first so a TLS toggle can re-bind the port.
+			bound.listener.Close()
+			removed[key] = bound
+			delete(s.servers, key)
+		}
+	}
+
+	var tlsConfig *tls.Config
+	var bindErrs []error
+	for _, addr := range addrs {
+		key := listenerKey{addr: addr.Addr, tls: addr.TLS}
+		if _, ok := s.servers[key]; ok {
+			continue
+		}
+		if addr.TLS && tlsConfig == nil {
+			config, err := s.tlsConfig(addrs)
+			if err != nil {
+				bindErrs = append(bindErrs, fmt.Errorf("https listeners disabled: %w", err))
+				log.Printf("warning: https listeners disabled: %v", err)
+				continue
+			}
+			tlsConfig = config
+		}
+		if addr.TLS && tlsConfig == nil {
+			continue
+		}
+		bound, err := s.bind(addr, tlsConfig)
+		if err != nil {
+			bindErrs = append(bindErrs, err)
+			log.Printf("warning: %v", err)
+			continue
+		}
+		s.servers[key] = bound
+	}
+
+	if len(addrs) == 0 {
+		// Nothing wanted: an optional listener set was turned off.
+		for _, old := range removed {
+			go drainServer(old.server)
+		}
+		return nil, nil
+	}
+	if len(s.servers) == 0 && len(removed) > 0 {
+		// Nothing new came up; put the old listeners back.
+		for key, old := range removed {
+			restored, err := s.bind(listen.Address{Addr: key.addr, TLS: key.tls, Source: old.source}, old.server.TLSConfig)
+			if err != nil {
+				log.Printf("warning: restore listener: %v", err)
+				continue
+			}
+			s.servers[key] = restored
+		}
+		return s.addressesLocked(), fmt.Errorf("%w: %v", errNoListeners, errors.Join(bindErrs...))
+	}
+	for _, old := range removed {
+		go drainServer(old.server)
+	}
+	if len(s.servers) == 0 {
+		return nil, fmt.Errorf("%w: %v", errNoListeners, errors.Join(bindErrs...))
+	}
+	return s.addressesLocked(), nil
+}
+
+func (s *listenerSet) tlsConfig(addrs []listen.Address) (*tls.Config, error) {
+	hosts := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if host, _, err := net.SplitHostPort(a.Addr); err == nil {
+			hosts = append(hosts, host)
+		}
+	}
+	cert, err := listen.LoadOrCreateCertificate(s.certPath, s.keyPath, hosts)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// bind opens the socket synchronously so failures are reported to the
+// caller, then serves on it in the background.
+func (s *listenerSet) bind(addr listen.Address, tlsConfig *tls.Config) (*boundListener, error) {
+	listener, err := net.Listen("tcp", addr.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s (%s): %w", addr.Addr, addr.Source, err)
+	}
+	server := &http.Server{
+		Addr:        addr.Addr,
+		Handler:     s.handler,
+		ReadTimeout: 15 * time.Second,
+		// WriteTimeout is intentionally not set (or set long) because SSE
+		// connections are long-lived; a strict timeout would drop them.
+		WriteTimeout: 0,
+		IdleTimeout:  120 * time.Second,
+	}
+	scheme := "http"
+	if addr.TLS {
+		scheme = "https"
+		server.TLSConfig = tlsConfig.Clone()
+	}
+	go func() {
+		log.Printf("split-vpn-webui listening on %s://%s", scheme, addr.Addr)
+		var err error
+		if addr.TLS {
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed && !errors.Is(err, net.ErrClosed) {
+			log.Printf("%s listener %s error: %v", scheme, addr.Addr, err)
+		}
+	}()
+	return &boundListener{server: server, listener: listener, source: addr.Source}, nil
+}
+
+func (s *listenerSet) addressesLocked() []listen.Address {
+	addrs := make([]listen.Address, 0, len(s.servers))
+	for key, bound := range s.servers {
+		addrs = append(addrs, listen.Address{Addr: key.addr, TLS: key.tls, Source: bound.source})
+	}
+	return addrs
+}
+
+// Count returns the number of bound listeners.
+func (s *listenerSet) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.servers)
+}
+
+// Shutdown gracefully stops every listener.
+func (s *listenerSet) Shutdown(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, bound := range s.servers {
+		if err := bound.server.Shutdown(ctx); err != nil {
+			log.Printf("graceful shutdown error on %s: %v", key.addr, err)
+		}
+	}
+}
+
+// drainServer lets a removed listener finish in-flight requests. Long-lived
+// SSE streams are cut after the grace period; clients reconnect on their own.
+func drainServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+	}
+}
+
+// defaultTLSPaths returns the certificate and key used for https listeners.
+func defaultTLSPaths(dataDir string) (string, string) {
+	dir := filepath.Join(dataDir, "tls")
+	return filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+}
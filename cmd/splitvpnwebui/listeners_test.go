@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"split-vpn-webui/internal/listen"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("reserve port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestListenerSetReloadKeepsUnchangedListeners(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	set := newListenerSet(handler, "", "")
+	defer set.Shutdown(context.Background())
+
+	first, second := freeAddr(t), freeAddr(t)
+	if _, err := set.Reload([]listen.Address{{Addr: first, Source: "a"}}); err != nil {
+		t.Fatalf("initial reload: %v", err)
+	}
+	kept := set.servers[listenerKey{addr: first}]
+
+	addrs, err := set.Reload([]listen.Address{{Addr: first, Source: "a"}, {Addr: second, Source: "b"}})
+	if err != nil || len(addrs) != 2 {
+		t.Fatalf("second reload: %v %+v", err, addrs)
+	}
+	if set.servers[listenerKey{addr: first}] != kept {
+		t.Fatalf("unchanged listener was re-bound")
+	}
+	if _, err := http.Get("http://" + second + "/"); err != nil {
+		t.Fatalf("new listener not serving: %v", err)
+	}
+
+	// A reload where nothing binds restores the previous listeners.
+	addrs, err = set.Reload([]listen.Address{{Addr: "256.0.0.1:80", Source: "bad"}})
+	if err == nil {
+		t.Fatalf("expected bind failure")
+	}
+	if len(addrs) != 2 || set.Count() != 2 {
+		t.Fatalf("expected previous listeners restored, got %+v", addrs)
+	}
+	if _, err := http.Get("http://" + first + "/"); err != nil {
+		t.Fatalf("restored listener not serving: %v", err)
+	}
+}
+
+func TestListenerSetReloadEmptyStopsAll(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	set := newListenerSet(handler, "", "")
+	defer set.Shutdown(context.Background())
+
+	addr := freeAddr(t)
+	if _, err := set.Reload([]listen.Address{{Addr: addr, Source: "kiosk"}}); err != nil {
+		t.Fatalf("initial reload: %v", err)
+	}
+	if addrs, err := set.Reload(nil); err != nil || len(addrs) != 0 || set.Count() != 0 {
+		t.Fatalf("expected every listener to stop, got %+v %v", addrs, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
@@ -14,22 +15,33 @@ import (
 	"syscall"
 	"time"
 
+	"split-vpn-webui/internal/agent"
 	"split-vpn-webui/internal/auth"
+	"split-vpn-webui/internal/authevents"
 	"split-vpn-webui/internal/backup"
 	"split-vpn-webui/internal/config"
 	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/dbmaint"
 	"split-vpn-webui/internal/diaglog"
+	"split-vpn-webui/internal/flowhistory"
 	"split-vpn-webui/internal/latency"
+	"split-vpn-webui/internal/listen"
 	"split-vpn-webui/internal/prewarm"
+	"split-vpn-webui/internal/privhelper"
+	"split-vpn-webui/internal/quota"
 	"split-vpn-webui/internal/routing"
 	"split-vpn-webui/internal/server"
+	"split-vpn-webui/internal/sessions"
 	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/settingsrevisions"
 	"split-vpn-webui/internal/stats"
 	"split-vpn-webui/internal/systemd"
 	"split-vpn-webui/internal/update"
 	"split-vpn-webui/internal/util"
 	"split-vpn-webui/internal/version"
 	"split-vpn-webui/internal/vpn"
+	"split-vpn-webui/internal/vpnevents"
+	"split-vpn-webui/internal/vpnrevisions"
 )
 
 const defaultDataDir = "/data/split-vpn-webui"
@@ -39,6 +51,13 @@ func main() {
 		runTunnelCommand(os.Args[2:])
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "privhelper" {
+		runPrivHelperCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && isCLICommand(os.Args[1]) {
+		os.Exit(runCLICommand(os.Args[1:], os.Stdout, os.Stderr))
+	}
 
 	addr := flag.String("addr", "127.0.0.1:8091", "listen address (host:port)")
 	dataDir := flag.String("data-dir", defaultDataDir, "persistent data directory")
@@ -50,6 +69,11 @@ func main() {
 	versionOnly := flag.Bool("version", false, "print version and exit")
 	versionJSON := flag.Bool("version-json", false, "print version metadata as JSON and exit")
 	selfUpdateRun := flag.Bool("self-update-run", false, "run pending self-update job and exit")
+	restoreRouting := flag.Bool("restore-routing", false, "re-apply persisted routing state and exit (used by the boot restore unit)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate for https listeners (defaults to <data-dir>/tls/cert.pem)")
+	tlsKey := flag.String("tls-key", "", "TLS private key for https listeners (defaults to <data-dir>/tls/key.pem)")
+	socketPath := flag.String("socket", "", "control socket path for CLI subcommands (defaults to <data-dir>/control.sock)")
+	privHelperSocket := flag.String("privhelper-socket", "", "run ipset, iptables, ip and systemctl through the privilege helper on this socket instead of directly")
 	flag.Parse()
 
 	if *versionJSON {
@@ -98,13 +122,20 @@ func main() {
 		log.Fatalf("failed to open database %s: %v", resolvedDB, err)
 	}
 	defer db.Close()
-	if err := database.Cleanup(db); err != nil {
-		log.Printf("warning: failed to prune stale stats history: %v", err)
-	}
 
 	settingsPath := filepath.Join(*dataDir, "settings.json")
 	settingsManager := settings.NewManager(settingsPath)
+	if *restoreRouting {
+		if err := runRoutingRestore(db, *dataDir, settingsManager); err != nil {
+			log.Fatalf("routing restore failed: %v", err)
+		}
+		return
+	}
 	diagLogger := diaglog.New(filepath.Join(*dataDir, "logs", "diagnostics.log"))
+	// Standard log output keeps going to journald and is also captured for
+	// the in-UI log viewer; diagLog warnings are mirrored the other way.
+	diagLogger.SetConsole(os.Stderr)
+	log.SetOutput(io.MultiWriter(os.Stderr, diagLogger.StdWriter("main")))
 	defer func() {
 		if err := diagLogger.Close(); err != nil {
 			log.Printf("warning: failed to close diagnostics log: %v", err)
@@ -119,14 +150,29 @@ func main() {
 	// VPN config discovery scans the vpns/ subdirectory.
 	vpnsDir := filepath.Join(*dataDir, "vpns")
 	cfgManager := config.NewManager(vpnsDir)
-	systemdManager := systemd.NewManager(*dataDir)
-	if err := systemdManager.WriteBootHook(); err != nil {
-		log.Printf("warning: failed to write boot hook: %v", err)
+	// Behind the privilege helper, privileged commands go through its socket
+	// and the helper, running as root, maintains the boot hook and unit.
+	var commandExec routing.Executor
+	var unitRunner systemd.CommandRunner
+	var unitWriter vpn.UnitWriter
+	if *privHelperSocket != "" {
+		helper := privhelper.NewClient(*privHelperSocket)
+		commandExec, unitRunner, unitWriter = helper, helper, helper
+	}
+	systemdManager := systemd.NewManagerWithRunner(*dataDir, unitRunner)
+	updateUnsupported := ""
+	if *privHelperSocket == "" {
+		writeBootFiles(systemdManager)
+	} else {
+		// The updater runs as root and installs a binary the web UI staged,
+		// so the helper does not start it for an unprivileged web UI.
+		updateUnsupported = "the web UI runs unprivileged behind the privilege helper; update the binary as root"
 	}
 	updater, err := update.NewManager(update.Options{
-		DataDir:    *dataDir,
-		BinaryPath: filepath.Join(*dataDir, "split-vpn-webui"),
-		Systemd:    systemdManager,
+		DataDir:     *dataDir,
+		BinaryPath:  filepath.Join(*dataDir, "split-vpn-webui"),
+		Systemd:     systemdManager,
+		Unsupported: updateUnsupported,
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize updater: %v", err)
@@ -135,10 +181,19 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to initialize vpn manager: %v", err)
 	}
-	routingManager, err := routing.NewManager(db, vpnManager)
+	if unitWriter != nil {
+		// The helper generates VPN units itself; replace any written
+		// before the web UI ran unprivileged.
+		vpnManager.SetUnitWriter(unitWriter)
+		if err := vpnManager.RewriteUnits(); err != nil {
+			log.Printf("warning: failed to install vpn units through the privilege helper: %v", err)
+		}
+	}
+	routingManager, err := routing.NewManagerWithExecutor(db, vpnManager, commandExec)
 	if err != nil {
 		log.Fatalf("failed to initialize routing manager: %v", err)
 	}
+	configureDNSBackend(routingManager, settingsManager, *dataDir, commandExec)
 	if err := routingManager.Apply(context.Background()); err != nil {
 		log.Printf("warning: failed to apply routing state on startup: %v", err)
 	}
@@ -150,7 +205,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to initialize resolver scheduler: %v", err)
 	}
-	prewarmScheduler, err := prewarm.NewScheduler(db, settingsManager, routingManager, vpnManager, nil)
+	prewarmScheduler, err := prewarm.NewScheduler(db, settingsManager, routingManager, vpnManager, routing.NewIPSetManager(commandExec))
 	if err != nil {
 		log.Fatalf("failed to initialize prewarm scheduler: %v", err)
 	}
@@ -166,14 +221,69 @@ func main() {
 	if err := diagLogger.Configure(diagEnabled, storedSettings.DebugLogLevel); err != nil {
 		log.Printf("warning: failed to configure diagnostics logging: %v", err)
 	}
+	if _, err := database.Prune(context.Background(), db, dbmaint.RetentionFromSettings(storedSettings), time.Now()); err != nil {
+		log.Printf("warning: failed to prune database history: %v", err)
+	}
+	dbMaintainer, err := dbmaint.NewMaintainer(db, resolvedDB, settingsManager)
+	if err != nil {
+		log.Fatalf("failed to initialize database maintenance: %v", err)
+	}
 
 	collector := stats.NewCollector("", *poll, *history)
 	if storedSettings.WANInterface != "" {
 		collector.SetWANInterface(storedSettings.WANInterface)
 	}
 	latencyMonitor := latency.NewMonitor(*latencyInterval)
+	eventStore, err := vpnevents.NewStore(db)
+	if err != nil {
+		log.Fatalf("failed to initialize vpn event store: %v", err)
+	}
+
+	revisionStore, err := vpnrevisions.NewStore(db)
+	if err != nil {
+		log.Fatalf("failed to initialize vpn revision store: %v", err)
+	}
+	if imported, err := revisionStore.ImportLegacy(context.Background(), filepath.Join(*dataDir, vpnrevisions.LegacyDirName)); err != nil {
+		log.Printf("warning: failed to import file-based config revisions: %v", err)
+	} else if imported > 0 {
+		log.Printf("imported %d file-based config revisions", imported)
+	}
+
+	settingsRevisionStore, err := settingsrevisions.NewStore(db)
+	if err != nil {
+		log.Fatalf("failed to initialize settings revision store: %v", err)
+	}
+
+	authEventStore, err := authevents.NewStore(db)
+	if err != nil {
+		log.Fatalf("failed to initialize auth event store: %v", err)
+	}
+	sessionStore, err := sessions.NewStore(db)
+	if err != nil {
+		log.Fatalf("failed to initialize session store: %v", err)
+	}
+	authManager.SetSessionStore(sessionStore)
+
+	flowStore, err := flowhistory.NewStore(db)
+	if err != nil {
+		log.Fatalf("failed to initialize flow history store: %v", err)
+	}
+
+	quotaStore, err := quota.NewStore(db)
+	if err != nil {
+		log.Fatalf("failed to initialize quota store: %v", err)
+	}
+
+	var agentManager *agent.Manager
+	if agentStore, err := agent.NewStore(db); err != nil {
+		log.Printf("warning: failed to initialize remote agent store: %v", err)
+	} else if signer, err := agent.LoadOrCreateSigner(filepath.Join(*dataDir, "keys", "agent_ed25519")); err != nil {
+		log.Printf("warning: remote agents disabled: %v", err)
+	} else if agentManager, err = agent.NewManager(agentStore, routingManager, signer); err != nil {
+		log.Printf("warning: remote agents disabled: %v", err)
+	}
 
-	listenAddr := resolveListenAddress(*addr, storedSettings.ListenInterface)
+	listenAddrs := resolveListeners(*addr, storedSettings.ListenInterface)
 
 	srv, err := server.New(
 		cfgManager,
@@ -189,6 +299,14 @@ func main() {
 		authManager,
 		backupManager,
 		updater,
+		eventStore,
+		dbMaintainer,
+		revisionStore,
+		settingsRevisionStore,
+		authEventStore,
+		flowStore,
+		quotaStore,
+		agentManager,
 		*systemdMode,
 	)
 	if err != nil {
@@ -223,22 +341,61 @@ func main() {
 	go collector.Start(stop)
 	go srv.StartBackground(stop)
 
-	httpServer := &http.Server{
-		Addr:        listenAddr,
-		Handler:     router,
-		ReadTimeout: 15 * time.Second,
-		// WriteTimeout is intentionally not set (or set long) because SSE
-		// connections are long-lived; a strict timeout would drop them.
-		WriteTimeout: 0,
-		IdleTimeout:  120 * time.Second,
+	// The control socket comes up first and stays up on its own, so local
+	// root can still manage the service when the TCP listener cannot bind.
+	controlPath := *socketPath
+	if controlPath == "" {
+		controlPath = defaultControlSocket(*dataDir)
+	}
+	var controlServer *http.Server
+	if listener, err := listenControlSocket(controlPath); err != nil {
+		log.Printf("warning: control socket unavailable, CLI subcommands will not work: %v", err)
+	} else {
+		controlServer = newControlServer(router)
+		go func() {
+			log.Printf("split-vpn-webui control socket on %s", controlPath)
+			if err := controlServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Printf("control socket error: %v", err)
+			}
+		}()
 	}
 
-	go func() {
-		log.Printf("split-vpn-webui listening on %s (data: %s)", listenAddr, *dataDir)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	certPath, keyPath := defaultTLSPaths(*dataDir)
+	if *tlsCert != "" {
+		certPath = *tlsCert
+	}
+	if *tlsKey != "" {
+		keyPath = *tlsKey
+	}
+	listeners := newListenerSet(router, certPath, keyPath)
+	if _, err := listeners.Reload(listenAddrs); err != nil {
+		if controlServer == nil {
 			log.Fatalf("http server error: %v", err)
 		}
-	}()
+		log.Printf("http server error: %v; the API remains available on %s", err, controlPath)
+	}
+	log.Printf("split-vpn-webui serving %d listener(s) (data: %s)", listeners.Count(), *dataDir)
+	srv.SetListenReloader(func(spec string) ([]listen.Address, error) {
+		return listeners.Reload(resolveListeners(*addr, spec))
+	})
+	srv.SetDNSBackendFactory(func(cfg routing.DNSBackendConfig) (routing.DNSManager, error) {
+		return routing.NewDNSBackend(cfg, *dataDir, commandExec)
+	})
+
+	// The read-only kiosk dashboard gets its own unauthenticated listeners.
+	kioskRouter, err := srv.KioskRouter()
+	if err != nil {
+		log.Fatalf("failed to prepare kiosk router: %v", err)
+	}
+	kioskListeners := newListenerSet(kioskRouter, certPath, keyPath)
+	if kioskAddrs := resolveKioskListeners(storedSettings.KioskListen); len(kioskAddrs) > 0 {
+		if _, err := kioskListeners.Reload(kioskAddrs); err != nil {
+			log.Printf("warning: kiosk unavailable: %v", err)
+		}
+	}
+	srv.SetKioskListenReloader(func(spec string) ([]listen.Address, error) {
+		return kioskListeners.Reload(resolveKioskListeners(spec))
+	})
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
@@ -249,8 +406,12 @@ func main() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if err := httpServer.Shutdown(ctx); err != nil {
-		log.Printf("graceful shutdown error: %v", err)
+	listeners.Shutdown(ctx)
+	kioskListeners.Shutdown(ctx)
+	if controlServer != nil {
+		if err := controlServer.Shutdown(ctx); err != nil {
+			log.Printf("control socket shutdown error: %v", err)
+		}
 	}
 	if err := collector.Persist(db); err != nil {
 		log.Printf("warning: failed to persist stats history: %v", err)
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "net"
+
+// peerUID is unavailable off Linux; control socket callers then fall back
+// to regular token or session authentication.
+func peerUID(conn net.Conn) (uint32, bool) {
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"split-vpn-webui/internal/privhelper"
+	"split-vpn-webui/internal/systemd"
+)
+
+// runPrivHelperCommand implements `split-vpn-webui privhelper`. It runs as
+// root next to an unprivileged web UI started with --privhelper-socket and
+// executes the allowlisted commands it asks for until SIGTERM.
+func runPrivHelperCommand(args []string) {
+	flags := flag.NewFlagSet("privhelper", flag.ExitOnError)
+	dataDir := flags.String("data-dir", defaultDataDir, "persistent data directory")
+	socketPath := flags.String("socket", "", "helper socket path (defaults to <data-dir>/"+privhelper.DefaultSocketName+")")
+	allowUID := flags.Int("allow-uid", -1, "uid the unprivileged web UI runs as")
+	if err := flags.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if os.Geteuid() != 0 {
+		log.Fatal("privhelper must run as root")
+	}
+	if *socketPath == "" {
+		*socketPath = filepath.Join(*dataDir, privhelper.DefaultSocketName)
+	}
+
+	binary, err := os.Executable()
+	if err == nil {
+		binary, err = filepath.EvalSymlinks(binary)
+	}
+	if err != nil {
+		log.Fatalf("failed to locate the binary: %v", err)
+	}
+	if err := privhelper.CheckInstall(*dataDir, binary); err != nil {
+		log.Fatalf("refusing to run: %v (the data directory, binary and units directory must be root-owned; see Running Unprivileged in the README)", err)
+	}
+
+	// The web UI cannot write outside the data directory once unprivileged.
+	writeBootFiles(systemd.NewManager(*dataDir))
+
+	listener, err := privhelper.Listen(*socketPath, *allowUID)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", *socketPath, err)
+	}
+	var allowed []uint32
+	if *allowUID > 0 {
+		allowed = append(allowed, uint32(*allowUID))
+	}
+	server := privhelper.NewServer(privhelper.NewPolicy(), privhelper.NewUnits(*dataDir), allowed)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+	log.Printf("privilege helper listening on %s", *socketPath)
+	if err := server.Serve(listener); err != nil {
+		log.Fatalf("privilege helper stopped: %v", err)
+	}
+}
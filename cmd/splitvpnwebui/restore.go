@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"split-vpn-webui/internal/routing"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/systemd"
+	"split-vpn-webui/internal/vpn"
+)
+
+// restoreTimeout bounds the boot-time apply; the unit's own start timeout
+// is slightly longer so a hung apply is reported rather than killed.
+const restoreTimeout = 75 * time.Second
+
+// runRoutingRestore re-applies ipsets, ip rules and chains from the
+// persisted store and returns. The boot restore unit runs it before the
+// network comes up, so routing is enforced before the web UI starts.
+func runRoutingRestore(db *sql.DB, dataDir string, settingsManager *settings.Manager) error {
+	vpnManager, err := vpn.NewManager(filepath.Join(dataDir, "vpns"), nil, systemd.NewManager(dataDir))
+	if err != nil {
+		return fmt.Errorf("initialize vpn manager: %w", err)
+	}
+	routingManager, err := routing.NewManager(db, vpnManager)
+	if err != nil {
+		return fmt.Errorf("initialize routing manager: %w", err)
+	}
+	// A single apply has nothing to coalesce with.
+	routingManager.SetApplyDebounce(0)
+	configureDNSBackend(routingManager, settingsManager, dataDir, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+	started := time.Now()
+	if err := routingManager.Apply(ctx); err != nil {
+		return fmt.Errorf("apply routing state: %w", err)
+	}
+	log.Printf("restored routing state in %s", time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// configureDNSBackend switches the routing manager to the DNS backend chosen
+// in settings, reloading it through exec. The default dnsmasq backend needs
+// no change.
+func configureDNSBackend(routingManager *routing.Manager, settingsManager *settings.Manager, dataDir string, exec routing.Executor) {
+	current, err := settingsManager.Get()
+	if err != nil {
+		return
+	}
+	cfg := routing.DNSBackendConfigFromSettings(current)
+	if cfg.Backend == routing.DNSBackendDnsmasq && cfg.ConfigPath == "" {
+		return
+	}
+	backend, err := routing.NewDNSBackend(cfg, dataDir, exec)
+	if err != nil {
+		log.Printf("warning: failed to initialize %s dns backend: %v", cfg.Backend, err)
+		return
+	}
+	if err := routingManager.SetDNSBackend(backend); err != nil {
+		log.Printf("warning: %v", err)
+	}
+}
+
+// writeBootFiles installs the on-boot hook and the boot restore unit. Both
+// live outside the data directory, so only a root process can write them.
+func writeBootFiles(systemdManager *systemd.Manager) {
+	if err := systemdManager.WriteBootHook(); err != nil {
+		log.Printf("warning: failed to write boot hook: %v", err)
+	}
+	if err := systemdManager.WriteRestoreUnit(); err != nil {
+		log.Printf("warning: failed to write boot restore unit: %v", err)
+	}
+}
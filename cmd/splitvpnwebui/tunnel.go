@@ -17,13 +17,14 @@ import (
 // until the tunnel is stopped (SIGTERM) or dies.
 func runTunnelCommand(args []string) {
 	if len(args) < 1 || args[0] != "run" {
-		fmt.Fprintln(os.Stderr, "usage: split-vpn-webui tunnel run --name <vpn> [--data-dir <dir>]")
+		fmt.Fprintln(os.Stderr, "usage: split-vpn-webui tunnel run --name <vpn> [--data-dir <dir>] [--vpns-dir <dir>]")
 		os.Exit(2)
 	}
 
 	flags := flag.NewFlagSet("tunnel run", flag.ExitOnError)
 	name := flags.String("name", "", "vpn profile name")
 	dataDir := flags.String("data-dir", defaultDataDir, "persistent data directory")
+	vpnsDir := flags.String("vpns-dir", "", "directory holding the vpn profile (defaults to <data-dir>/vpns)")
 	if err := flags.Parse(args[1:]); err != nil {
 		os.Exit(2)
 	}
@@ -34,7 +35,10 @@ func runTunnelCommand(args []string) {
 
 	logger := log.New(os.Stderr, fmt.Sprintf("tunnel[%s] ", *name), log.LstdFlags)
 
-	manager, err := vpn.NewManager(filepath.Join(*dataDir, "vpns"), nil, nil)
+	if *vpnsDir == "" {
+		*vpnsDir = filepath.Join(*dataDir, "vpns")
+	}
+	manager, err := vpn.NewManager(*vpnsDir, nil, nil)
 	if err != nil {
 		logger.Fatalf("failed to open vpn store: %v", err)
 	}
@@ -16,11 +16,12 @@ SYSTEMD_DIR="/etc/systemd/system"
 # Ensure the units directory exists (it should, but be defensive).
 mkdir -p "${UNITS_DIR}"
 
-# Re-link this app's own service unit.
-if [ -f "${UNITS_DIR}/split-vpn-webui.service" ]; then
-    ln -sf "${UNITS_DIR}/split-vpn-webui.service" \
-        "${SYSTEMD_DIR}/split-vpn-webui.service"
-fi
+# Re-link this app's own service unit and the boot-time routing restore
+# unit (written by the service on first start).
+for name in split-vpn-webui.service split-vpn-webui-restore.service; do
+    [ -f "${UNITS_DIR}/${name}" ] || continue
+    ln -sf "${UNITS_DIR}/${name}" "${SYSTEMD_DIR}/${name}"
+done
 
 # Re-link all managed VPN units (svpn-*.service).
 for unit in "${UNITS_DIR}"/svpn-*.service; do
@@ -33,4 +34,7 @@ systemctl daemon-reload
 
 # Enable and start the web UI service.
 systemctl enable split-vpn-webui.service 2>/dev/null || true
+if [ -f "${UNITS_DIR}/split-vpn-webui-restore.service" ]; then
+    systemctl enable split-vpn-webui-restore.service 2>/dev/null || true
+fi
 systemctl restart split-vpn-webui.service
@@ -278,9 +278,10 @@ SYSTEMD_DIR="/etc/systemd/system"
 
 mkdir -p "${UNITS_DIR}"
 
-if [ -f "${UNITS_DIR}/split-vpn-webui.service" ]; then
-	ln -sf "${UNITS_DIR}/split-vpn-webui.service" "${SYSTEMD_DIR}/split-vpn-webui.service"
-fi
+for name in split-vpn-webui.service split-vpn-webui-restore.service; do
+	[ -f "${UNITS_DIR}/${name}" ] || continue
+	ln -sf "${UNITS_DIR}/${name}" "${SYSTEMD_DIR}/${name}"
+done
 
 for unit in "${UNITS_DIR}"/svpn-*.service; do
 	[ -f "${unit}" ] || continue
@@ -289,6 +290,9 @@ done
 
 systemctl daemon-reload
 systemctl enable split-vpn-webui.service >/dev/null 2>&1 || true
+if [ -f "${UNITS_DIR}/split-vpn-webui-restore.service" ]; then
+	systemctl enable split-vpn-webui-restore.service >/dev/null 2>&1 || true
+fi
 systemctl restart split-vpn-webui.service
 HOOK
 	chmod 0755 "${BOOT_SCRIPT_PATH}"
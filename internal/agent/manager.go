@@ -0,0 +1,413 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"split-vpn-webui/internal/routing"
+)
+
+// Event kinds.
+const (
+	KindFailed    = "agent_failed"
+	KindRecovered = "agent_recovered"
+)
+
+const (
+	checkInterval = 5 * time.Second
+	// resyncInterval re-applies agents whose state has not changed here, so
+	// a device that rebooted or was edited by hand converges again.
+	resyncInterval = 10 * time.Minute
+	// retryDelay holds off another apply after one failed.
+	retryDelay = time.Minute
+)
+
+// Event is a change between failing and working applies on an agent.
+type Event struct {
+	AgentID int64     `json:"agentId"`
+	Agent   string    `json:"agent"`
+	Kind    string    `json:"kind"`
+	Detail  string    `json:"detail"`
+	At      time.Time `json:"at"`
+}
+
+// Status is the result of the last apply on one agent.
+type Status struct {
+	AgentID     int64     `json:"agentId"`
+	Agent       string    `json:"agent"`
+	Enabled     bool      `json:"enabled"`
+	InSync      bool      `json:"inSync"`
+	LastAttempt time.Time `json:"lastAttempt,omitzero"`
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// ExecutorFactory opens the command channel to an agent.
+type ExecutorFactory func(agent Agent) (routing.Executor, error)
+
+type remote struct {
+	agent   Agent
+	exec    routing.Executor
+	routing *routing.Manager
+	// generation is the local routing generation last applied; applied
+	// tells whether that apply succeeded.
+	generation uint64
+	applied    bool
+	status     Status
+}
+
+// Manager keeps every enabled agent converged with the local routing
+// groups. An agent is applied whenever a local apply happened since its
+// last one, and every resyncInterval regardless.
+type Manager struct {
+	store       *Store
+	routing     *routing.Manager
+	signer      ssh.Signer
+	newExecutor ExecutorFactory
+	now         func() time.Time
+
+	runMu sync.Mutex
+
+	mu         sync.Mutex
+	remotes    map[int64]*remote
+	handler    func(Event)
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewManager creates a manager that drives agents from store with the
+// groups of routingManager, connecting over SSH with signer.
+func NewManager(store *Store, routingManager *routing.Manager, signer ssh.Signer) (*Manager, error) {
+	switch {
+	case store == nil:
+		return nil, fmt.Errorf("agent store is required")
+	case routingManager == nil:
+		return nil, fmt.Errorf("routing manager is required")
+	case signer == nil:
+		return nil, fmt.Errorf("agent signer is required")
+	}
+	return &Manager{
+		store:   store,
+		routing: routingManager,
+		signer:  signer,
+		newExecutor: func(agent Agent) (routing.Executor, error) {
+			return NewExecutor(agent, signer)
+		},
+		now:     time.Now,
+		remotes: make(map[int64]*remote),
+	}, nil
+}
+
+// Store returns the backing store.
+func (m *Manager) Store() *Store {
+	return m.store
+}
+
+// PublicKey is the authorized_keys line agents must trust.
+func (m *Manager) PublicKey() string {
+	return AuthorizedKey(m.signer)
+}
+
+// SetHandler registers a callback for agent events.
+func (m *Manager) SetHandler(handler func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// Status reports the last apply of every agent, by name.
+func (m *Manager) Status(ctx context.Context) ([]Status, error) {
+	agents, err := m.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Status, 0, len(agents))
+	for _, agent := range agents {
+		status := Status{AgentID: agent.ID, Agent: agent.Name}
+		if existing, ok := m.remotes[agent.ID]; ok {
+			status = existing.status
+		}
+		status.Agent = agent.Name
+		status.Enabled = agent.Enabled
+		out = append(out, status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Agent < out[j].Agent })
+	return out, nil
+}
+
+// Start launches the periodic apply loop.
+func (m *Manager) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.started = true
+	m.loopCancel = cancel
+	m.mu.Unlock()
+
+	m.loopWG.Add(1)
+	go func() {
+		defer m.loopWG.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = m.Check(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the periodic loop and closes agent connections. Rules
+// already applied on agents stay in place.
+func (m *Manager) Stop() error {
+	m.mu.Lock()
+	loopCancel := m.loopCancel
+	m.started = false
+	m.loopCancel = nil
+	m.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	m.loopWG.Wait()
+
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, existing := range m.remotes {
+		closeExecutor(existing.exec)
+		delete(m.remotes, id)
+	}
+	return nil
+}
+
+// Check applies every enabled agent that is behind the local routing state
+// or due for a resync, and forgets agents that were deleted or disabled.
+func (m *Manager) Check(ctx context.Context) error {
+	return m.run(ctx, 0, false)
+}
+
+// ApplyNow applies one agent immediately.
+func (m *Manager) ApplyNow(ctx context.Context, id int64) (Status, error) {
+	agent, err := m.store.Get(ctx, id)
+	if err != nil {
+		return Status{}, err
+	}
+	if !agent.Enabled {
+		return Status{}, fmt.Errorf("agent %s is disabled", agent.Name)
+	}
+	err = m.run(ctx, id, true)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.remotes[id]; ok {
+		return existing.status, err
+	}
+	return Status{AgentID: id, Agent: agent.Name, Enabled: true}, err
+}
+
+// Remove clears the managed rules, sets and dnsmasq entries from an agent
+// and deletes it. The agent is kept when the device cannot be cleared,
+// unless force is set.
+func (m *Manager) Remove(ctx context.Context, id int64, force bool) error {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+	agent, err := m.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	clearErr := m.clear(agent)
+	if clearErr != nil && !force {
+		return fmt.Errorf("clear agent %s: %w", agent.Name, clearErr)
+	}
+	m.forget(id)
+	return m.store.Delete(ctx, id)
+}
+
+func (m *Manager) run(ctx context.Context, only int64, force bool) error {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	agents, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	generation := m.routing.Generation()
+	now := m.now()
+	seen := make(map[int64]struct{}, len(agents))
+	events := make([]Event, 0)
+	var errs []error
+	for _, agent := range agents {
+		if !agent.Enabled {
+			continue
+		}
+		seen[agent.ID] = struct{}{}
+		if only != 0 && agent.ID != only {
+			continue
+		}
+		existing, err := m.remoteFor(agent)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", agent.Name, err))
+			continue
+		}
+		if !force && !m.due(existing, generation, now) {
+			continue
+		}
+		applyErr := existing.routing.Apply(ctx)
+		if event, ok := m.record(existing, generation, now, applyErr); ok {
+			events = append(events, event)
+		}
+		if applyErr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", agent.Name, applyErr))
+		}
+	}
+	if only == 0 {
+		m.mu.Lock()
+		stale := make([]int64, 0)
+		for id := range m.remotes {
+			if _, ok := seen[id]; !ok {
+				stale = append(stale, id)
+			}
+		}
+		m.mu.Unlock()
+		for _, id := range stale {
+			m.forget(id)
+		}
+	}
+
+	m.mu.Lock()
+	handler := m.handler
+	m.mu.Unlock()
+	if handler != nil {
+		for _, event := range events {
+			handler(event)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) due(existing *remote, generation uint64, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := existing.status
+	if status.LastAttempt.IsZero() {
+		return true
+	}
+	if !existing.applied {
+		return now.Sub(status.LastAttempt) >= retryDelay
+	}
+	return existing.generation != generation || now.Sub(status.LastAttempt) >= resyncInterval
+}
+
+func (m *Manager) record(existing *remote, generation uint64, now time.Time, err error) (Event, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previousErr := existing.status.LastError
+	existing.generation = generation
+	existing.applied = err == nil
+	existing.status.LastAttempt = now
+	existing.status.InSync = err == nil
+	event := Event{AgentID: existing.agent.ID, Agent: existing.agent.Name, At: now}
+	if err != nil {
+		existing.status.LastError = err.Error()
+		if previousErr != "" {
+			return Event{}, false
+		}
+		event.Kind = KindFailed
+		event.Detail = fmt.Sprintf("routing apply on %s (%s) failed: %v", existing.agent.Name, existing.agent.Address(), err)
+		return event, true
+	}
+	existing.status.LastError = ""
+	existing.status.LastSuccess = now
+	if previousErr == "" {
+		return Event{}, false
+	}
+	event.Kind = KindRecovered
+	event.Detail = fmt.Sprintf("routing apply on %s (%s) works again", existing.agent.Name, existing.agent.Address())
+	return event, true
+}
+
+// remoteFor returns the cached remote of agent, reconnecting when its
+// settings changed. Callers hold runMu.
+func (m *Manager) remoteFor(agent Agent) (*remote, error) {
+	m.mu.Lock()
+	existing, ok := m.remotes[agent.ID]
+	m.mu.Unlock()
+	if ok && existing.agent == agent {
+		return existing, nil
+	}
+	if ok {
+		m.forget(agent.ID)
+	}
+	exec, err := m.newExecutor(agent)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := m.routing.Remote(exec, agent.DnsmasqDir)
+	if err != nil {
+		closeExecutor(exec)
+		return nil, err
+	}
+	created := &remote{
+		agent:   agent,
+		exec:    exec,
+		routing: manager,
+		status:  Status{AgentID: agent.ID, Agent: agent.Name, Enabled: agent.Enabled},
+	}
+	m.mu.Lock()
+	m.remotes[agent.ID] = created
+	m.mu.Unlock()
+	return created, nil
+}
+
+// clear empties the managed state on an agent's device. Callers hold runMu.
+func (m *Manager) clear(agent Agent) error {
+	m.mu.Lock()
+	existing, ok := m.remotes[agent.ID]
+	m.mu.Unlock()
+	if ok && existing.agent == agent {
+		return existing.routing.Clear()
+	}
+	exec, err := m.newExecutor(agent)
+	if err != nil {
+		return err
+	}
+	defer closeExecutor(exec)
+	manager, err := m.routing.Remote(exec, agent.DnsmasqDir)
+	if err != nil {
+		return err
+	}
+	return manager.Clear()
+}
+
+func (m *Manager) forget(id int64) {
+	m.mu.Lock()
+	existing, ok := m.remotes[id]
+	delete(m.remotes, id)
+	m.mu.Unlock()
+	if ok {
+		closeExecutor(existing.exec)
+	}
+}
+
+func closeExecutor(exec routing.Executor) {
+	if closer, ok := exec.(interface{ Close() error }); ok {
+		_ = closer.Close()
+	}
+}
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/routing"
+	"split-vpn-webui/internal/vpn"
+)
+
+type staticLister []*vpn.VPNProfile
+
+func (l staticLister) List() ([]*vpn.VPNProfile, error) {
+	return append([]*vpn.VPNProfile(nil), l...), nil
+}
+
+// flakyExec fails every command while down is set.
+type flakyExec struct {
+	routing.MockExec
+	mu   sync.Mutex
+	down bool
+}
+
+func (f *flakyExec) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *flakyExec) failing() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.down
+}
+
+func (f *flakyExec) Run(name string, args ...string) error {
+	if f.failing() {
+		return errors.New("connection refused")
+	}
+	return f.MockExec.Run(name, args...)
+}
+
+func (f *flakyExec) Output(name string, args ...string) ([]byte, error) {
+	if f.failing() {
+		return nil, errors.New("connection refused")
+	}
+	return f.MockExec.Output(name, args...)
+}
+
+func (f *flakyExec) RunWithInput(input []byte, name string, args ...string) error {
+	if f.failing() {
+		return errors.New("connection refused")
+	}
+	return f.MockExec.RunWithInput(input, name, args...)
+}
+
+func (f *flakyExec) calls() int {
+	return len(f.MockExec.RunCalls)
+}
+
+func newExec() *flakyExec {
+	return &flakyExec{MockExec: routing.MockExec{Outputs: map[string][]byte{"ipset list -name": {}}}}
+}
+
+func TestManagerAppliesAgentsWhenRoutingChanges(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := database.Open(filepath.Join(dir, "agent.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	routingStore, err := routing.NewStore(db)
+	if err != nil {
+		t.Fatalf("routing store: %v", err)
+	}
+	localExec := newExec()
+	local, err := routing.NewManagerWithDeps(
+		routingStore,
+		routing.NewIPSetManager(localExec),
+		routing.NewDnsmasqManagerWithPath(filepath.Join(dir, "dnsmasq.conf"), localExec),
+		routing.NewRuleManager(localExec),
+		staticLister{{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"}},
+	)
+	if err != nil {
+		t.Fatalf("routing manager: %v", err)
+	}
+	group, err := local.CreateGroup(ctx, routing.DomainGroup{
+		Name:      "Streaming-SG",
+		EgressVPN: "wg-sgp",
+		Rules:     []routing.RoutingRule{{Domains: []string{"max.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("agent store: %v", err)
+	}
+	signer, err := LoadOrCreateSigner(filepath.Join(dir, "keys", "agent_ed25519"))
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	manager, err := NewManager(store, local, signer)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+	execs := map[string]*flakyExec{"edge-1": newExec(), "edge-2": newExec()}
+	manager.newExecutor = func(agent Agent) (routing.Executor, error) {
+		return execs[agent.Name], nil
+	}
+	events := make([]Event, 0)
+	manager.SetHandler(func(event Event) { events = append(events, event) })
+
+	edge1, err := store.Create(ctx, Agent{Name: "edge-1", Host: "192.0.2.10", HostKey: testHostKey, Enabled: true})
+	if err != nil {
+		t.Fatalf("create agent: %v", err)
+	}
+	edge2, err := store.Create(ctx, Agent{Name: "edge-2", Host: "192.0.2.11", HostKey: testHostKey, Enabled: true})
+	if err != nil {
+		t.Fatalf("create agent: %v", err)
+	}
+	execs["edge-2"].setDown(true)
+
+	if err := manager.Check(ctx); err == nil {
+		t.Fatalf("expected the unreachable agent to be reported")
+	}
+	applied := execs["edge-1"].calls()
+	if applied == 0 {
+		t.Fatalf("expected routing commands on edge-1")
+	}
+	if len(events) != 1 || events[0].Kind != KindFailed || events[0].AgentID != edge2.ID {
+		t.Fatalf("expected one failure event for edge-2, got %+v", events)
+	}
+
+	now = now.Add(30 * time.Second)
+	_ = manager.Check(ctx)
+	if execs["edge-1"].calls() != applied {
+		t.Fatalf("expected no apply while routing is unchanged")
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected repeated failures to stay quiet, got %+v", events)
+	}
+
+	group.Rules[0].Domains = []string{"max.com", "hbo.com"}
+	if _, err := local.UpdateGroup(ctx, group.ID, *group); err != nil {
+		t.Fatalf("update group: %v", err)
+	}
+	execs["edge-2"].setDown(false)
+	now = now.Add(time.Minute)
+	if err := manager.Check(ctx); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if execs["edge-1"].calls() == applied {
+		t.Fatalf("expected edge-1 to be re-applied after the local apply")
+	}
+	if len(events) != 2 || events[1].Kind != KindRecovered {
+		t.Fatalf("expected a recovery event for edge-2, got %+v", events)
+	}
+	statuses, err := manager.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.InSync || status.LastError != "" || !status.LastSuccess.Equal(now) {
+			t.Fatalf("expected every agent in sync, got %+v", statuses)
+		}
+	}
+
+	before := execs["edge-1"].calls()
+	if err := manager.Remove(ctx, edge1.ID, false); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	cleared := false
+	exec := execs["edge-1"]
+	for i := before; i < len(exec.RunCalls); i++ {
+		if exec.RunCalls[i][0] == "sh" && len(exec.Inputs[i]) == 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Fatalf("expected removal to clear the remote dnsmasq config")
+	}
+	if _, err := store.Get(ctx, edge1.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected removed agent to be deleted, got %v", err)
+	}
+
+	execs["edge-2"].setDown(true)
+	if err := manager.Remove(ctx, edge2.ID, false); err == nil {
+		t.Fatalf("expected removal of an unreachable agent to fail without force")
+	}
+	if err := manager.Remove(ctx, edge2.ID, true); err != nil {
+		t.Fatalf("forced remove: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	dialTimeout    = 10 * time.Second
+	commandTimeout = 2 * time.Minute
+	keyComment     = "split-vpn-webui"
+	// commandPath makes the routing tools in sbin reachable from the
+	// non-login shells SSH commands run in.
+	commandPath = `PATH="$PATH:/usr/sbin:/sbin" `
+)
+
+var errHostKeySeen = errors.New("host key captured")
+
+// NormalizeFingerprint returns a SHA256 host key fingerprint in the
+// "SHA256:<base64>" form ssh-keygen prints. The prefix and base64 padding
+// are optional on input.
+func NormalizeFingerprint(value string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+	trimmed = strings.TrimPrefix(trimmed, "SHA256:")
+	trimmed = strings.TrimRight(trimmed, "=")
+	raw, err := base64.RawStdEncoding.DecodeString(trimmed)
+	if err != nil || len(raw) != 32 {
+		return "", fmt.Errorf("agent hostKey must be a SHA256 fingerprint such as SHA256:%s", strings.Repeat("A", 43))
+	}
+	return "SHA256:" + trimmed, nil
+}
+
+// LoadOrCreateSigner reads this instance's SSH private key from path,
+// generating an ed25519 key there on first use.
+func LoadOrCreateSigner(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = generateKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("agent key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse agent key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+func generateKey(path string) ([]byte, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(private, keyComment)
+	if err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(block)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// AuthorizedKey renders the signer's public key as an authorized_keys line
+// to install on remote devices.
+func AuthorizedKey(signer ssh.Signer) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))) + " " + keyComment
+}
+
+// ScanHostKey connects to address and returns the fingerprint of the host
+// key it presents, without authenticating. The result should be compared
+// with the device's own `ssh-keygen -lf` output before it is pinned.
+func ScanHostKey(ctx context.Context, address string) (string, error) {
+	var fingerprint string
+	config := &ssh.ClientConfig{
+		User: defaultUser,
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			fingerprint = ssh.FingerprintSHA256(key)
+			return errHostKeySeen
+		},
+		Timeout: dialTimeout,
+	}
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_, _, _, err = ssh.NewClientConn(conn, address, config)
+	if fingerprint != "" {
+		return fingerprint, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no host key presented")
+	}
+	return "", err
+}
+
+// Executor runs routing commands on an agent over SSH. It implements
+// routing.Executor; one connection is kept open and re-established when it
+// drops, and every command runs in its own session.
+type Executor struct {
+	address string
+	config  *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// NewExecutor creates an executor for agent authenticating with signer.
+// Connections fail unless the device presents the agent's pinned host key.
+func NewExecutor(agent Agent, signer ssh.Signer) (*Executor, error) {
+	agent, err := agent.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("agent signer is required")
+	}
+	pinned := agent.HostKey
+	return &Executor{
+		address: agent.Address(),
+		config: &ssh.ClientConfig{
+			User: agent.User,
+			Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+				if got := ssh.FingerprintSHA256(key); got != pinned {
+					return fmt.Errorf("host key mismatch: device presented %s, expected %s", got, pinned)
+				}
+				return nil
+			},
+			Timeout: dialTimeout,
+		},
+	}, nil
+}
+
+func (e *Executor) Run(name string, args ...string) error {
+	_, err := e.run(nil, name, args)
+	return err
+}
+
+func (e *Executor) Output(name string, args ...string) ([]byte, error) {
+	return e.run(nil, name, args)
+}
+
+func (e *Executor) RunWithInput(input []byte, name string, args ...string) error {
+	output, err := e.run(input, name, args)
+	if err != nil {
+		if detail := strings.TrimSpace(string(output)); detail != "" {
+			return fmt.Errorf("%w: %s", err, detail)
+		}
+		return err
+	}
+	return nil
+}
+
+// Close drops the connection.
+func (e *Executor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.client == nil {
+		return nil
+	}
+	err := e.client.Close()
+	e.client = nil
+	return err
+}
+
+// run executes one command and returns its combined output. A session that
+// cannot be opened on the cached connection gets one retry on a new one.
+func (e *Executor) run(input []byte, name string, args []string) ([]byte, error) {
+	session, err := e.session()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	var output bytes.Buffer
+	session.Stdout = &output
+	session.Stderr = &output
+	if input != nil {
+		session.Stdin = bytes.NewReader(input)
+	}
+	timer := time.AfterFunc(commandTimeout, func() { _ = session.Close() })
+	defer timer.Stop()
+	if err := session.Run(commandPath + ShellJoin(name, args...)); err != nil {
+		return output.Bytes(), fmt.Errorf("%s on %s: %w", name, e.address, err)
+	}
+	return output.Bytes(), nil
+}
+
+func (e *Executor) session() (*ssh.Session, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		if e.client == nil {
+			client, err := ssh.Dial("tcp", e.address, e.config)
+			if err != nil {
+				return nil, fmt.Errorf("connect to %s: %w", e.address, err)
+			}
+			e.client = client
+		}
+		session, err := e.client.NewSession()
+		if err == nil {
+			return session, nil
+		}
+		_ = e.client.Close()
+		e.client = nil
+	}
+	return nil, fmt.Errorf("open session on %s: connection lost", e.address)
+}
+
+// ShellJoin quotes a command and its arguments for a POSIX shell.
+func ShellJoin(name string, args ...string) string {
+	words := make([]string, 0, len(args)+1)
+	for _, word := range append([]string{name}, args...) {
+		words = append(words, shellQuote(word))
+	}
+	return strings.Join(words, " ")
+}
+
+func shellQuote(word string) string {
+	if word != "" && strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,+@%") == "" {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+func joinHostPort(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
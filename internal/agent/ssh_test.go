@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is an SSH server that records exec requests and echoes their
+// stdin back.
+type testServer struct {
+	address  string
+	hostKey  ssh.Signer
+	mu       sync.Mutex
+	commands []string
+}
+
+func startTestServer(t *testing.T, clientKey ssh.PublicKey) *testServer {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("host key: %v", err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatalf("host signer: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	server := &testServer{address: listener.Addr().String(), hostKey: hostKey}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for request := range channelRequests {
+				if request.Type != "exec" {
+					_ = request.Reply(false, nil)
+					continue
+				}
+				var payload struct{ Command string }
+				_ = ssh.Unmarshal(request.Payload, &payload)
+				_ = request.Reply(true, nil)
+				s.mu.Lock()
+				s.commands = append(s.commands, payload.Command)
+				s.mu.Unlock()
+				status := uint32(0)
+				if strings.Contains(payload.Command, "false") {
+					status = 1
+				}
+				_, _ = io.Copy(channel, channel)
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				return
+			}
+		}()
+	}
+}
+
+func TestExecutorRunsQuotedCommandsWithPinnedHostKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "agent_ed25519")
+	signer, err := LoadOrCreateSigner(keyPath)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	server := startTestServer(t, signer.PublicKey())
+	host, portText, _ := net.SplitHostPort(server.address)
+	port, _ := strconv.Atoi(portText)
+	ctx := context.Background()
+
+	fingerprint, err := ScanHostKey(ctx, server.address)
+	if err != nil {
+		t.Fatalf("scan host key: %v", err)
+	}
+	if fingerprint != ssh.FingerprintSHA256(server.hostKey.PublicKey()) {
+		t.Fatalf("scanned %s, want %s", fingerprint, ssh.FingerprintSHA256(server.hostKey.PublicKey()))
+	}
+
+	exec, err := NewExecutor(Agent{Name: "edge", Host: host, Port: port, HostKey: fingerprint}, signer)
+	if err != nil {
+		t.Fatalf("new executor: %v", err)
+	}
+	defer exec.Close()
+	if err := exec.RunWithInput([]byte("create x\n"), "ipset", "restore", "-exist"); err != nil {
+		t.Fatalf("run with input: %v", err)
+	}
+	output, err := exec.Output("iptables", "-m", "comment", "--comment", "it's split")
+	if err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if len(output) != 0 {
+		t.Fatalf("expected empty output without stdin, got %q", output)
+	}
+	if err := exec.Run("false"); err == nil {
+		t.Fatalf("expected a non-zero exit status to fail")
+	}
+	server.mu.Lock()
+	commands := append([]string(nil), server.commands...)
+	server.mu.Unlock()
+	if len(commands) != 3 || commands[1] != commandPath+`iptables -m comment --comment 'it'\''s split'` {
+		t.Fatalf("unexpected remote commands %q", commands)
+	}
+
+	wrong, err := NewExecutor(Agent{Name: "edge", Host: host, Port: port, HostKey: testHostKey}, signer)
+	if err != nil {
+		t.Fatalf("new executor: %v", err)
+	}
+	if err := wrong.Run("true"); err == nil || !strings.Contains(err.Error(), "host key mismatch") {
+		t.Fatalf("expected a host key mismatch, got %v", err)
+	}
+
+	reloaded, err := LoadOrCreateSigner(keyPath)
+	if err != nil {
+		t.Fatalf("reload signer: %v", err)
+	}
+	if AuthorizedKey(reloaded) != AuthorizedKey(signer) || !strings.HasPrefix(AuthorizedKey(signer), "ssh-ed25519 ") {
+		t.Fatalf("expected the stored key to be reused, got %q and %q", AuthorizedKey(reloaded), AuthorizedKey(signer))
+	}
+}
@@ -0,0 +1,234 @@
+// Package agent drives split-vpn routing on other devices from this web UI.
+// A remote agent is an EdgeRouter, UDM or other Linux router reachable over
+// SSH: the routing manager plans groups here as usual, and every ipset,
+// iptables, ip rule and dnsmasq change is run on the device through an SSH
+// session authenticated with this instance's key. The device needs nothing
+// installed beyond the tools a local apply uses, and must host the tunnels
+// the groups egress through under the same interface names, marks and
+// tables.
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPort       = 22
+	defaultUser       = "root"
+	defaultDnsmasqDir = "/run/dnsmasq.d"
+)
+
+var (
+	// ErrNotFound is returned when no agent has the requested id.
+	ErrNotFound = errors.New("agent not found")
+	// ErrNameTaken is returned when another agent already uses a name.
+	ErrNameTaken = errors.New("agent name already in use")
+)
+
+// Agent is a remote device driven by this instance.
+type Agent struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	User string `json:"user"`
+	// HostKey is the SHA256 fingerprint of the device's SSH host key, as
+	// printed by ssh-keygen -lf. Connections presenting another key fail.
+	HostKey string `json:"hostKey"`
+	// DnsmasqDir is the conf.d directory dnsmasq reads on the device.
+	DnsmasqDir string    `json:"dnsmasqDir"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Address is the host:port the agent's SSH server listens on.
+func (a Agent) Address() string {
+	return joinHostPort(a.Host, a.Port)
+}
+
+// Normalize validates a and fills defaults.
+func (a Agent) Normalize() (Agent, error) {
+	a.Name = strings.TrimSpace(a.Name)
+	a.Host = strings.TrimSpace(a.Host)
+	a.User = strings.TrimSpace(a.User)
+	a.DnsmasqDir = strings.TrimSpace(a.DnsmasqDir)
+	if a.Name == "" {
+		return Agent{}, fmt.Errorf("agent name is required")
+	}
+	if a.Host == "" || strings.ContainsAny(a.Host, " /@") {
+		return Agent{}, fmt.Errorf("agent host must be a hostname or IP address")
+	}
+	if a.Port == 0 {
+		a.Port = defaultPort
+	}
+	if a.Port < 1 || a.Port > 65535 {
+		return Agent{}, fmt.Errorf("agent port must be between 1 and 65535")
+	}
+	if a.User == "" {
+		a.User = defaultUser
+	}
+	hostKey, err := NormalizeFingerprint(a.HostKey)
+	if err != nil {
+		return Agent{}, err
+	}
+	a.HostKey = hostKey
+	if a.DnsmasqDir == "" {
+		a.DnsmasqDir = defaultDnsmasqDir
+	}
+	if !path.IsAbs(a.DnsmasqDir) {
+		return Agent{}, fmt.Errorf("agent dnsmasqDir must be an absolute path")
+	}
+	a.DnsmasqDir = path.Clean(a.DnsmasqDir)
+	return a, nil
+}
+
+// Store persists agents in the remote_agents table.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a store backed by db.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	return &Store{db: db}, nil
+}
+
+const agentColumns = `id, name, host, port, user, host_key, dnsmasq_dir, enabled, created_at, updated_at`
+
+// List returns every agent ordered by name.
+func (s *Store) List(ctx context.Context) ([]Agent, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+agentColumns+` FROM remote_agents ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	agents := make([]Agent, 0)
+	for rows.Next() {
+		agent, err := scanAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+	return agents, rows.Err()
+}
+
+// Get returns one agent, or ErrNotFound.
+func (s *Store) Get(ctx context.Context, id int64) (Agent, error) {
+	agent, err := scanAgent(s.db.QueryRowContext(ctx, `SELECT `+agentColumns+` FROM remote_agents WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Agent{}, ErrNotFound
+	}
+	return agent, err
+}
+
+// Create stores a new agent.
+func (s *Store) Create(ctx context.Context, agent Agent) (Agent, error) {
+	agent, err := agent.Normalize()
+	if err != nil {
+		return Agent{}, err
+	}
+	if err := s.checkName(ctx, agent.Name, 0); err != nil {
+		return Agent{}, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	agent.CreatedAt, agent.UpdatedAt = now, now
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO remote_agents (name, host, port, user, host_key, dnsmasq_dir, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, agent.Name, agent.Host, agent.Port, agent.User, agent.HostKey, agent.DnsmasqDir,
+		boolToInt(agent.Enabled), now.Unix(), now.Unix())
+	if err != nil {
+		return Agent{}, err
+	}
+	agent.ID, err = result.LastInsertId()
+	if err != nil {
+		return Agent{}, err
+	}
+	return agent, nil
+}
+
+// Update replaces an agent's settings.
+func (s *Store) Update(ctx context.Context, id int64, agent Agent) (Agent, error) {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return Agent{}, err
+	}
+	agent, err = agent.Normalize()
+	if err != nil {
+		return Agent{}, err
+	}
+	if err := s.checkName(ctx, agent.Name, id); err != nil {
+		return Agent{}, err
+	}
+	agent.ID = id
+	agent.CreatedAt = existing.CreatedAt
+	agent.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE remote_agents SET
+			name = ?, host = ?, port = ?, user = ?, host_key = ?, dnsmasq_dir = ?, enabled = ?, updated_at = ?
+		WHERE id = ?
+	`, agent.Name, agent.Host, agent.Port, agent.User, agent.HostKey, agent.DnsmasqDir,
+		boolToInt(agent.Enabled), agent.UpdatedAt.Unix(), id); err != nil {
+		return Agent{}, err
+	}
+	return agent, nil
+}
+
+// Delete removes an agent.
+func (s *Store) Delete(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM remote_agents WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) checkName(ctx context.Context, name string, id int64) error {
+	var other int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM remote_agents WHERE name = ? AND id != ?`, name, id).Scan(&other)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrNameTaken, name)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAgent(row rowScanner) (Agent, error) {
+	var agent Agent
+	var enabled int
+	var createdAt, updatedAt int64
+	if err := row.Scan(&agent.ID, &agent.Name, &agent.Host, &agent.Port, &agent.User, &agent.HostKey,
+		&agent.DnsmasqDir, &enabled, &createdAt, &updatedAt); err != nil {
+		return Agent{}, err
+	}
+	agent.Enabled = enabled != 0
+	agent.CreatedAt = time.Unix(createdAt, 0).UTC()
+	agent.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return agent, nil
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
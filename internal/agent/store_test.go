@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"split-vpn-webui/internal/database"
+)
+
+const testHostKey = "SHA256:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU"
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "agent.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return store
+}
+
+func TestStoreCreateNormalizesAndUpdates(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	created, err := store.Create(ctx, Agent{Name: " edge-1 ", Host: "192.0.2.10", HostKey: "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", Enabled: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.ID == 0 || created.Name != "edge-1" || created.Port != 22 || created.User != "root" ||
+		created.HostKey != testHostKey || created.DnsmasqDir != "/run/dnsmasq.d" {
+		t.Fatalf("unexpected normalized agent %+v", created)
+	}
+	if _, err := store.Create(ctx, Agent{Name: "edge-1", Host: "192.0.2.11", HostKey: testHostKey}); !errors.Is(err, ErrNameTaken) {
+		t.Fatalf("expected duplicate name to fail with ErrNameTaken, got %v", err)
+	}
+	if _, err := store.Create(ctx, Agent{Name: "edge-2", Host: "192.0.2.11", HostKey: "not-a-key"}); err == nil {
+		t.Fatalf("expected an invalid host key to be rejected")
+	}
+	if _, err := store.Create(ctx, Agent{Name: "edge-2", Host: "192.0.2.11", HostKey: testHostKey, DnsmasqDir: "etc/dnsmasq.d"}); err == nil {
+		t.Fatalf("expected a relative dnsmasq directory to be rejected")
+	}
+
+	created.Port = 2222
+	created.Enabled = false
+	updated, err := store.Update(ctx, created.ID, created)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	loaded, err := store.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if loaded != updated || loaded.Port != 2222 || loaded.Enabled || loaded.Address() != "192.0.2.10:2222" {
+		t.Fatalf("unexpected stored agent %+v, want %+v", loaded, updated)
+	}
+
+	if err := store.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Get(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
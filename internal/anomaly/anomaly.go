@@ -0,0 +1,361 @@
+// Package anomaly watches VPN throughput for conditions a user should hear
+// about: a tunnel carrying more than a configured rate for too long, or a
+// tunnel that stopped moving data while connections are still routed
+// through it, which usually means it has silently stalled.
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+// Event kinds. Each raised kind is followed by its cleared kind once the
+// condition ends.
+const (
+	KindHigh         = "traffic_high"
+	KindHighCleared  = "traffic_normal"
+	KindStall        = "traffic_stalled"
+	KindStallCleared = "traffic_resumed"
+)
+
+const (
+	checkInterval       = 15 * time.Second
+	defaultHighMinutes  = 5
+	maxThresholdMinutes = 24 * 60
+	maxHighMbps         = 100000
+	// idleBitsPerSecond is the rate below which a tunnel counts as idle;
+	// WireGuard keepalives alone stay well under it.
+	idleBitsPerSecond = 1000
+)
+
+// Config holds the thresholds read from settings.
+type Config struct {
+	HighMbps int
+	HighFor  time.Duration
+	StallFor time.Duration
+}
+
+// Enabled reports whether any check is on.
+func (c Config) Enabled() bool {
+	return c.HighMbps > 0 || c.StallFor > 0
+}
+
+// ConfigFromSettings reads the anomaly thresholds from settings.
+func ConfigFromSettings(current settings.Settings) Config {
+	cfg := Config{HighMbps: current.AnomalyHighMbps}
+	if cfg.HighMbps > 0 {
+		minutes := current.AnomalyHighMinutes
+		if minutes <= 0 {
+			minutes = defaultHighMinutes
+		}
+		cfg.HighFor = time.Duration(minutes) * time.Minute
+	}
+	if current.AnomalyStallMinutes > 0 {
+		cfg.StallFor = time.Duration(current.AnomalyStallMinutes) * time.Minute
+	}
+	return cfg
+}
+
+// ValidateSettings checks the anomaly fields of a settings update.
+func ValidateSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	if current.AnomalyHighMbps < 0 || current.AnomalyHighMbps > maxHighMbps {
+		errs.Addf("anomalyHighMbps", "anomalyHighMbps must be between 0 and %d", maxHighMbps)
+	}
+	if current.AnomalyHighMinutes < 0 || current.AnomalyHighMinutes > maxThresholdMinutes {
+		errs.Addf("anomalyHighMinutes", "anomalyHighMinutes must be between 0 and %d", maxThresholdMinutes)
+	}
+	if current.AnomalyStallMinutes < 0 || current.AnomalyStallMinutes > maxThresholdMinutes {
+		errs.Addf("anomalyStallMinutes", "anomalyStallMinutes must be between 0 and %d", maxThresholdMinutes)
+	}
+	return errs.Err()
+}
+
+// Sample is the current throughput of one VPN.
+type Sample struct {
+	VPN string
+	// Available is false when the interface counters could not be read;
+	// link state is reported by the VPN event tracker instead.
+	Available     bool
+	ThroughputBps float64
+}
+
+// Event is a raised or cleared anomaly.
+type Event struct {
+	VPN           string    `json:"vpn"`
+	Kind          string    `json:"kind"`
+	Detail        string    `json:"detail"`
+	At            time.Time `json:"at"`
+	ThroughputBps float64   `json:"throughputBps"`
+	Flows         int       `json:"flows,omitempty"`
+}
+
+// Active is an anomaly that has been raised and not yet cleared.
+type Active struct {
+	VPN   string    `json:"vpn"`
+	Kind  string    `json:"kind"`
+	Since time.Time `json:"since"`
+}
+
+// SampleSource returns the current throughput of every VPN.
+type SampleSource func() []Sample
+
+// FlowCounter returns how many conntrack flows are marked for each of the
+// named VPNs.
+type FlowCounter func(ctx context.Context, vpns []string) (map[string]int, error)
+
+type vpnState struct {
+	highSince   time.Time
+	highActive  bool
+	stallSince  time.Time
+	stallActive bool
+}
+
+// Monitor evaluates the thresholds against throughput samples.
+type Monitor struct {
+	settings *settings.Manager
+	samples  SampleSource
+	flows    FlowCounter
+	now      func() time.Time
+
+	mu         sync.Mutex
+	checkMu    sync.Mutex
+	states     map[string]*vpnState
+	handler    func(Event)
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor that reads thresholds from settingsManager.
+func NewMonitor(settingsManager *settings.Manager, samples SampleSource, flows FlowCounter) (*Monitor, error) {
+	if settingsManager == nil {
+		return nil, fmt.Errorf("settings manager is required")
+	}
+	if samples == nil {
+		return nil, fmt.Errorf("sample source is required")
+	}
+	return &Monitor{
+		settings: settingsManager,
+		samples:  samples,
+		flows:    flows,
+		now:      time.Now,
+		states:   make(map[string]*vpnState),
+	}, nil
+}
+
+// SetHandler registers a callback for raised and cleared anomalies.
+func (m *Monitor) SetHandler(handler func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// Active lists the anomalies currently raised, by VPN name.
+func (m *Monitor) Active() []Active {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Active, 0)
+	for name, state := range m.states {
+		if state.highActive {
+			out = append(out, Active{VPN: name, Kind: KindHigh, Since: state.highSince})
+		}
+		if state.stallActive {
+			out = append(out, Active{VPN: name, Kind: KindStall, Since: state.stallSince})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].VPN == out[j].VPN {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].VPN < out[j].VPN
+	})
+	return out
+}
+
+// Start launches the periodic check loop.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.started = true
+	m.loopCancel = cancel
+	m.mu.Unlock()
+
+	m.loopWG.Add(1)
+	go func() {
+		defer m.loopWG.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current, err := m.settings.Get()
+				if err != nil {
+					continue
+				}
+				_ = m.Check(ctx, ConfigFromSettings(current))
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the periodic loop.
+func (m *Monitor) Stop() error {
+	m.mu.Lock()
+	loopCancel := m.loopCancel
+	m.started = false
+	m.loopCancel = nil
+	m.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	m.loopWG.Wait()
+	return nil
+}
+
+// Check evaluates one set of samples against cfg and reports transitions to
+// the handler. Conntrack is only read for VPNs that are idle, and only when
+// the stall check is on.
+func (m *Monitor) Check(ctx context.Context, cfg Config) error {
+	m.checkMu.Lock()
+	defer m.checkMu.Unlock()
+
+	now := m.now()
+	samples := m.samples()
+	idle := make([]string, 0)
+	if cfg.StallFor > 0 {
+		for _, sample := range samples {
+			if sample.Available && sample.ThroughputBps < idleBitsPerSecond {
+				idle = append(idle, sample.VPN)
+			}
+		}
+	}
+	var (
+		flows   map[string]int
+		flowErr error
+	)
+	if len(idle) > 0 && m.flows != nil {
+		flows, flowErr = m.flows(ctx, idle)
+	}
+
+	m.mu.Lock()
+	events := make([]Event, 0)
+	seen := make(map[string]struct{}, len(samples))
+	for _, sample := range samples {
+		seen[sample.VPN] = struct{}{}
+		state, ok := m.states[sample.VPN]
+		if !ok || !sample.Available {
+			// A tunnel whose counters cannot be read starts over quietly;
+			// its link state is on the timeline already.
+			state = &vpnState{}
+			m.states[sample.VPN] = state
+		}
+		events = append(events, evaluateHigh(state, sample, cfg, now)...)
+		if flowErr == nil {
+			events = append(events, evaluateStall(state, sample, flows[sample.VPN], cfg, now)...)
+		}
+	}
+	for name := range m.states {
+		if _, ok := seen[name]; !ok {
+			delete(m.states, name)
+		}
+	}
+	handler := m.handler
+	m.mu.Unlock()
+
+	if handler != nil {
+		for _, event := range events {
+			handler(event)
+		}
+	}
+	return flowErr
+}
+
+func evaluateHigh(state *vpnState, sample Sample, cfg Config, now time.Time) []Event {
+	limit := float64(cfg.HighMbps) * 1e6
+	if cfg.HighMbps > 0 && sample.Available && sample.ThroughputBps >= limit {
+		if state.highSince.IsZero() {
+			state.highSince = now
+		}
+		if !state.highActive && now.Sub(state.highSince) >= cfg.HighFor {
+			state.highActive = true
+			return []Event{{
+				VPN:           sample.VPN,
+				Kind:          KindHigh,
+				Detail:        fmt.Sprintf("throughput %.1f Mbit/s above %d Mbit/s for %s", sample.ThroughputBps/1e6, cfg.HighMbps, cfg.HighFor),
+				At:            now,
+				ThroughputBps: sample.ThroughputBps,
+			}}
+		}
+		return nil
+	}
+	state.highSince = time.Time{}
+	if !state.highActive {
+		return nil
+	}
+	state.highActive = false
+	detail := fmt.Sprintf("throughput back to %.1f Mbit/s", sample.ThroughputBps/1e6)
+	if cfg.HighMbps <= 0 {
+		detail = "throughput check turned off"
+	}
+	return []Event{{
+		VPN:           sample.VPN,
+		Kind:          KindHighCleared,
+		Detail:        detail,
+		At:            now,
+		ThroughputBps: sample.ThroughputBps,
+	}}
+}
+
+func evaluateStall(state *vpnState, sample Sample, flows int, cfg Config, now time.Time) []Event {
+	if cfg.StallFor > 0 && sample.Available && sample.ThroughputBps < idleBitsPerSecond && flows > 0 {
+		if state.stallSince.IsZero() {
+			state.stallSince = now
+		}
+		if !state.stallActive && now.Sub(state.stallSince) >= cfg.StallFor {
+			state.stallActive = true
+			return []Event{{
+				VPN:           sample.VPN,
+				Kind:          KindStall,
+				Detail:        fmt.Sprintf("no traffic for %s while %d flows are routed through the tunnel", cfg.StallFor, flows),
+				At:            now,
+				ThroughputBps: sample.ThroughputBps,
+				Flows:         flows,
+			}}
+		}
+		return nil
+	}
+	state.stallSince = time.Time{}
+	if !state.stallActive {
+		return nil
+	}
+	state.stallActive = false
+	detail := "traffic resumed"
+	switch {
+	case cfg.StallFor <= 0:
+		detail = "stall check turned off"
+	case flows == 0 && sample.ThroughputBps < idleBitsPerSecond:
+		detail = "no flows routed through the tunnel any more"
+	}
+	return []Event{{
+		VPN:           sample.VPN,
+		Kind:          KindStallCleared,
+		Detail:        detail,
+		At:            now,
+		ThroughputBps: sample.ThroughputBps,
+		Flows:         flows,
+	}}
+}
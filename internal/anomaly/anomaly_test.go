@@ -0,0 +1,101 @@
+package anomaly
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestMonitor(samples *[]Sample, flows map[string]int, now *time.Time) (*Monitor, *[]Event) {
+	events := make([]Event, 0)
+	monitor := &Monitor{
+		samples: func() []Sample { return *samples },
+		flows: func(ctx context.Context, vpns []string) (map[string]int, error) {
+			return flows, nil
+		},
+		now:    func() time.Time { return *now },
+		states: make(map[string]*vpnState),
+	}
+	monitor.SetHandler(func(event Event) { events = append(events, event) })
+	return monitor, &events
+}
+
+func TestCheckRaisesHighThroughputAfterDuration(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	samples := []Sample{{VPN: "wg-sgp", Available: true, ThroughputBps: 80e6}}
+	monitor, events := newTestMonitor(&samples, nil, &now)
+	cfg := Config{HighMbps: 50, HighFor: 5 * time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if err := monitor.Check(context.Background(), cfg); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		now = now.Add(2 * time.Minute)
+	}
+	if len(*events) != 0 {
+		t.Fatalf("expected no event before the threshold duration, got %+v", *events)
+	}
+	_ = monitor.Check(context.Background(), cfg)
+	if len(*events) != 1 || (*events)[0].Kind != KindHigh {
+		t.Fatalf("expected a high-traffic event, got %+v", *events)
+	}
+	if active := monitor.Active(); len(active) != 1 || active[0].VPN != "wg-sgp" {
+		t.Fatalf("unexpected active anomalies %+v", active)
+	}
+
+	_ = monitor.Check(context.Background(), cfg)
+	if len(*events) != 1 {
+		t.Fatalf("expected the raised anomaly not to repeat, got %+v", *events)
+	}
+
+	samples[0].ThroughputBps = 10e6
+	_ = monitor.Check(context.Background(), cfg)
+	if len(*events) != 2 || (*events)[1].Kind != KindHighCleared {
+		t.Fatalf("expected a cleared event, got %+v", *events)
+	}
+	if active := monitor.Active(); len(active) != 0 {
+		t.Fatalf("expected no active anomalies, got %+v", active)
+	}
+}
+
+func TestCheckRaisesStallOnlyWhileFlowsExist(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	samples := []Sample{
+		{VPN: "wg-sgp", Available: true, ThroughputBps: 0},
+		{VPN: "wg-idle", Available: true, ThroughputBps: 0},
+	}
+	flows := map[string]int{"wg-sgp": 12}
+	monitor, events := newTestMonitor(&samples, flows, &now)
+	cfg := Config{StallFor: 2 * time.Minute}
+
+	_ = monitor.Check(context.Background(), cfg)
+	now = now.Add(2 * time.Minute)
+	_ = monitor.Check(context.Background(), cfg)
+	if len(*events) != 1 || (*events)[0].VPN != "wg-sgp" || (*events)[0].Kind != KindStall || (*events)[0].Flows != 12 {
+		t.Fatalf("expected one stall event for the VPN with flows, got %+v", *events)
+	}
+
+	samples[0].ThroughputBps = 5e6
+	_ = monitor.Check(context.Background(), cfg)
+	if len(*events) != 2 || (*events)[1].Kind != KindStallCleared {
+		t.Fatalf("expected a resumed event, got %+v", *events)
+	}
+}
+
+func TestCheckResetsWhenCountersUnavailable(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	samples := []Sample{{VPN: "wg-sgp", Available: true, ThroughputBps: 80e6}}
+	monitor, events := newTestMonitor(&samples, nil, &now)
+	cfg := Config{HighMbps: 50, HighFor: time.Minute}
+
+	_ = monitor.Check(context.Background(), cfg)
+	samples[0].Available = false
+	now = now.Add(30 * time.Second)
+	_ = monitor.Check(context.Background(), cfg)
+	samples[0].Available = true
+	now = now.Add(45 * time.Second)
+	_ = monitor.Check(context.Background(), cfg)
+	if len(*events) != 0 {
+		t.Fatalf("expected the high-traffic timer to restart after a gap, got %+v", *events)
+	}
+}
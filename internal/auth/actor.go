@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Actor describes who sent an authenticated request, for change history:
+// the control socket, or the auth method plus client address.
+func Actor(r *http.Request) string {
+	if isTrustedLocal(r) {
+		return "control socket"
+	}
+	method := "web session"
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		method = "api token"
+	}
+	host := clientAddress(r)
+	if host == "" {
+		return method
+	}
+	return method + " from " + host
+}
+
+// clientAddress returns the client IP of r without its port, as reported
+// by RemoteAddr (which forwarding headers may have rewritten).
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
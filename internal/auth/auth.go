@@ -1,5 +1,5 @@
-// Package auth manages password authentication and API token validation
-// for the split-vpn-webui single-admin web interface.
+// Package auth manages password authentication, browser sessions and API
+// token validation for the split-vpn-webui single-admin web interface.
 package auth
 
 import (
@@ -10,6 +10,7 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 
+	"split-vpn-webui/internal/sessions"
 	"split-vpn-webui/internal/settings"
 )
 
@@ -23,11 +24,14 @@ var bcryptCost = bcrypt.DefaultCost
 // Auth state is persisted inside the Settings struct.
 type Manager struct {
 	settings *settings.Manager
+	sessions *sessions.Store
+	guard    *guard
+	audit    func(Event)
 }
 
 // NewManager creates an auth manager backed by the provided settings manager.
 func NewManager(sm *settings.Manager) *Manager {
-	return &Manager{settings: sm}
+	return &Manager{settings: sm, guard: newGuard()}
 }
 
 // EnsureDefaults initialises auth credentials on first run.
@@ -107,7 +111,7 @@ func (m *Manager) ValidateToken(token string) bool {
 	return subtle.ConstantTimeCompare([]byte(token), []byte(s.AuthToken)) == 1
 }
 
-// GetToken returns the current API / session token.
+// GetToken returns the current API token.
 func (m *Manager) GetToken() (string, error) {
 	s, err := m.settings.Get()
 	if err != nil {
@@ -117,7 +121,7 @@ func (m *Manager) GetToken() (string, error) {
 }
 
 // RegenerateToken creates a new random API token, persists it, and returns it.
-// All existing sessions are invalidated when the token changes.
+// Scripts using the old token stop working; browser sessions are unaffected.
 func (m *Manager) RegenerateToken() (string, error) {
 	token, err := generateToken()
 	if err != nil {
@@ -134,6 +138,55 @@ func (m *Manager) RegenerateToken() (string, error) {
 	return token, nil
 }
 
+// ValidateHomeAssistantToken returns true if token matches the stored Home
+// Assistant token. It never matches while that token is unset.
+func (m *Manager) ValidateHomeAssistantToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	s, err := m.settings.Get()
+	if err != nil || s.HomeAssistantToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.HomeAssistantToken)) == 1
+}
+
+// GetHomeAssistantToken returns the Home Assistant token, empty when unset.
+func (m *Manager) GetHomeAssistantToken() (string, error) {
+	s, err := m.settings.Get()
+	if err != nil {
+		return "", err
+	}
+	return s.HomeAssistantToken, nil
+}
+
+// RegenerateHomeAssistantToken creates a new random Home Assistant token,
+// persists it, and returns it. The previous token stops working.
+func (m *Manager) RegenerateHomeAssistantToken() (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	if err := m.setHomeAssistantToken(token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeHomeAssistantToken removes the Home Assistant token.
+func (m *Manager) RevokeHomeAssistantToken() error {
+	return m.setHomeAssistantToken("")
+}
+
+func (m *Manager) setHomeAssistantToken(token string) error {
+	s, err := m.settings.Get()
+	if err != nil {
+		return err
+	}
+	s.HomeAssistantToken = token
+	return m.settings.Save(s)
+}
+
 // generateToken returns a cryptographically random 32-byte hex string.
 func generateToken() (string, error) {
 	b := make([]byte, 32)
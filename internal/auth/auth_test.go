@@ -1,9 +1,16 @@
 package auth
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/sessions"
 	"split-vpn-webui/internal/settings"
 )
 
@@ -133,3 +140,308 @@ func TestRegenerateToken(t *testing.T) {
 		t.Error("old token should be invalidated")
 	}
 }
+
+func TestMiddleware_TrustLocalBypassesAuth(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.EnsureDefaults(); err != nil {
+		t.Fatalf("EnsureDefaults: %v", err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	m.Middleware(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/vpns", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without credentials, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	TrustLocal(m.Middleware(ok)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/vpns", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected trusted socket request to pass, got %d", rec.Code)
+	}
+}
+
+func TestHomeAssistantMiddleware_TokenIsScoped(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.EnsureDefaults(); err != nil {
+		t.Fatalf("EnsureDefaults: %v", err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	request := func(handler http.Handler, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/ha/vpns", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if m.ValidateHomeAssistantToken("") {
+		t.Fatal("unset Home Assistant token must not match an empty token")
+	}
+	haToken, err := m.RegenerateHomeAssistantToken()
+	if err != nil {
+		t.Fatalf("RegenerateHomeAssistantToken: %v", err)
+	}
+	if code := request(m.HomeAssistantMiddleware(ok), haToken); code != http.StatusNoContent {
+		t.Fatalf("expected Home Assistant token to open Home Assistant routes, got %d", code)
+	}
+	if code := request(m.Middleware(ok), haToken); code != http.StatusUnauthorized {
+		t.Fatalf("expected Home Assistant token to be rejected elsewhere, got %d", code)
+	}
+	apiToken, _ := m.GetToken()
+	if code := request(m.HomeAssistantMiddleware(ok), apiToken); code != http.StatusNoContent {
+		t.Fatalf("expected API token to open Home Assistant routes, got %d", code)
+	}
+
+	if err := m.RevokeHomeAssistantToken(); err != nil {
+		t.Fatalf("RevokeHomeAssistantToken: %v", err)
+	}
+	if code := request(m.HomeAssistantMiddleware(ok), haToken); code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked token to be rejected, got %d", code)
+	}
+}
+
+func TestActor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/api/vpns/wg", nil)
+	req.RemoteAddr = "192.168.1.20:51000"
+	if got := Actor(req); got != "web session from 192.168.1.20" {
+		t.Fatalf("unexpected session actor %q", got)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	if got := Actor(req); got != "api token from 192.168.1.20" {
+		t.Fatalf("unexpected token actor %q", got)
+	}
+	var local string
+	TrustLocal(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		local = Actor(r)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	if local != "control socket" {
+		t.Fatalf("unexpected control socket actor %q", local)
+	}
+}
+
+func TestLogin_LocksOutAfterRepeatedFailures(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.EnsureDefaults(); err != nil {
+		t.Fatalf("EnsureDefaults: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.guard.now = func() time.Time { return now }
+	var events []Event
+	m.SetAuditHook(func(event Event) { events = append(events, event) })
+
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = "192.168.1.50:51000"
+	for i := 0; i < lockoutThreshold; i++ {
+		if ok, wait := m.Login(req, "wrong"); ok || wait != 0 {
+			t.Fatalf("attempt %d: expected a plain failure, got ok=%v wait=%s", i, ok, wait)
+		}
+	}
+	if last := events[len(events)-1]; last.Type != EventLockout || last.Remote != "192.168.1.50" {
+		t.Fatalf("expected a lockout event, got %+v", last)
+	}
+	if ok, wait := m.Login(req, defaultPassword); ok || wait != lockoutBase {
+		t.Fatalf("expected the correct password to be refused while locked out, got ok=%v wait=%s", ok, wait)
+	}
+
+	other := httptest.NewRequest(http.MethodPost, "/login", nil)
+	other.RemoteAddr = "192.168.1.51:51000"
+	if ok, _ := m.Login(other, defaultPassword); !ok {
+		t.Fatal("expected another client to be unaffected")
+	}
+
+	// The next failure after the lockout doubles it.
+	now = now.Add(lockoutBase)
+	if ok, _ := m.Login(req, "wrong"); ok {
+		t.Fatal("expected wrong password to fail")
+	}
+	if wait := m.guard.lockedFor("192.168.1.50"); wait != 2*lockoutBase {
+		t.Fatalf("expected a doubled lockout, got %s", wait)
+	}
+	now = now.Add(2 * lockoutBase)
+	if ok, wait := m.Login(req, defaultPassword); !ok || wait != 0 {
+		t.Fatalf("expected login after the lockout, got ok=%v wait=%s", ok, wait)
+	}
+	if last := events[len(events)-1]; last.Type != EventLoginSucceeded {
+		t.Fatalf("expected a success event, got %+v", last)
+	}
+}
+
+func TestLogin_RateLimitsAttempts(t *testing.T) {
+	m := newTestManager(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.guard.now = func() time.Time { return now }
+	var limited int
+	m.SetAuditHook(func(event Event) {
+		if event.Type == EventRateLimited {
+			limited++
+		}
+	})
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = "10.0.0.9:4000"
+	for i := 0; i < loginRateLimit; i++ {
+		if ok, _ := m.Login(req, defaultPassword); !ok {
+			t.Fatalf("attempt %d: expected success within the rate limit", i)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if ok, wait := m.Login(req, defaultPassword); ok || wait != loginRateWindow {
+			t.Fatalf("expected rate limiting, got ok=%v wait=%s", ok, wait)
+		}
+	}
+	if limited != 1 {
+		t.Fatalf("expected one rate-limit event per window, got %d", limited)
+	}
+	now = now.Add(loginRateWindow)
+	if ok, _ := m.Login(req, defaultPassword); !ok {
+		t.Fatal("expected a new window to allow attempts again")
+	}
+}
+
+func TestMiddleware_InvalidBearerTokensLockOut(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.EnsureDefaults(); err != nil {
+		t.Fatalf("EnsureDefaults: %v", err)
+	}
+	token, _ := m.GetToken()
+	var rejected int
+	m.SetAuditHook(func(event Event) {
+		if event.Type == EventTokenRejected {
+			rejected++
+		}
+	})
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	call := func(bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/vpns", nil)
+		req.RemoteAddr = "10.0.0.7:3000"
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	for i := 0; i < lockoutThreshold; i++ {
+		if rec := call("guess"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i, rec.Code)
+		}
+	}
+	if rejected != lockoutThreshold {
+		t.Fatalf("expected %d rejected-token events, got %d", lockoutThreshold, rejected)
+	}
+	rec := call(token)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected a locked-out client to get 429 with Retry-After, got %d", rec.Code)
+	}
+}
+
+func TestLogin_ForwardedAddressDoesNotEvadeLockout(t *testing.T) {
+	m := newTestManager(t)
+	handler := RecordPeer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stand-in for a proxy-header middleware rewriting RemoteAddr.
+		r.RemoteAddr = r.Header.Get("X-Forwarded-For") + ":1"
+		if ok, wait := m.Login(r, "wrong"); ok || wait > 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	var code int
+	for i := 0; i <= lockoutThreshold; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "10.0.0.66:5000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("172.16.0.%d", i+1))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		code = rec.Code
+	}
+	if code != http.StatusTooManyRequests {
+		t.Fatalf("expected rotating forwarded addresses to stay locked out, got %d", code)
+	}
+}
+
+func TestMiddleware_SessionsRequireCSRFForWrites(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.EnsureDefaults(); err != nil {
+		t.Fatalf("EnsureDefaults: %v", err)
+	}
+	db, err := database.Open(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := sessions.NewStore(db)
+	if err != nil {
+		t.Fatalf("new session store: %v", err)
+	}
+	m.SetSessionStore(store)
+
+	login := httptest.NewRecorder()
+	if err := m.StartSession(login, httptest.NewRequest(http.MethodPost, "/login", nil)); err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	cookies := login.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookieName || !cookies[0].HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie, got %+v", cookies)
+	}
+	apiToken, _ := m.GetToken()
+	if cookies[0].Value == apiToken {
+		t.Fatal("session cookie must not be the API token")
+	}
+
+	var seen sessions.Session
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = CurrentSession(r)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func(method, csrf string) int {
+		req := httptest.NewRequest(method, "/api/vpns", nil)
+		req.AddCookie(cookies[0])
+		if csrf != "" {
+			req.Header.Set(CSRFHeader, csrf)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := call(http.MethodGet, ""); code != http.StatusNoContent {
+		t.Fatalf("expected session read without CSRF token to pass, got %d", code)
+	}
+	if seen.ID == "" || seen.CSRFToken == "" {
+		t.Fatalf("expected the session in the request context, got %+v", seen)
+	}
+	if code := call(http.MethodPost, ""); code != http.StatusForbidden {
+		t.Fatalf("expected session write without CSRF token to be refused, got %d", code)
+	}
+	if code := call(http.MethodDelete, "wrong"); code != http.StatusForbidden {
+		t.Fatalf("expected session write with a wrong CSRF token to be refused, got %d", code)
+	}
+	if code := call(http.MethodPost, seen.CSRFToken); code != http.StatusNoContent {
+		t.Fatalf("expected session write with its CSRF token to pass, got %d", code)
+	}
+	sessionID := seen.ID
+
+	// API tokens are not sent automatically by browsers and need no CSRF token.
+	req := httptest.NewRequest(http.MethodPost, "/api/vpns", nil)
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected API token write to pass, got %d", rec.Code)
+	}
+
+	if seen.ID != "" {
+		t.Fatal("expected no session for an API token request")
+	}
+	if err := m.RevokeSession(context.Background(), sessionID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if code := call(http.MethodGet, ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked session to be refused, got %d", code)
+	}
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Audit event types.
+const (
+	EventLoginSucceeded = "login_succeeded"
+	EventLoginFailed    = "login_failed"
+	EventLockout        = "lockout"
+	EventRateLimited    = "rate_limited"
+	EventTokenRejected  = "token_rejected"
+)
+
+const (
+	// lockoutThreshold failures from one client lock it out for
+	// lockoutBase, doubling with every further failure up to lockoutMax.
+	lockoutThreshold = 5
+	lockoutBase      = time.Minute
+	lockoutMax       = time.Hour
+	// failureMemory forgets a client's failures after this long without
+	// another one.
+	failureMemory = 24 * time.Hour
+	// loginRateLimit bounds password attempts per client per
+	// loginRateWindow, successful or not.
+	loginRateLimit  = 10
+	loginRateWindow = time.Minute
+	// maxTrackedClients bounds memory when many addresses fail.
+	maxTrackedClients = 4096
+)
+
+// Event is an authentication audit record.
+type Event struct {
+	Type   string
+	Remote string
+	Detail string
+}
+
+type clientState struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+	windowStart time.Time
+	attempts    int
+	// limitReported is set once a rate-limit refusal in the current window
+	// has been audited, so a flood produces one event rather than many.
+	limitReported bool
+}
+
+// guard tracks failed attempts, lockouts and the login rate per client
+// address.
+type guard struct {
+	mu      sync.Mutex
+	clients map[string]*clientState
+	now     func() time.Time
+}
+
+func newGuard() *guard {
+	return &guard{clients: make(map[string]*clientState), now: time.Now}
+}
+
+// lockedFor returns how long remote stays locked out.
+func (g *guard) lockedFor(remote string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state, ok := g.clients[remote]
+	if !ok {
+		return 0
+	}
+	return remainingLockout(state, g.now())
+}
+
+// attempt counts a password attempt from remote. wait is non-zero when the
+// attempt is refused; report is true for the first refusal of a rate
+// window, which is worth auditing.
+func (g *guard) attempt(remote string) (wait time.Duration, report bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	state := g.stateLocked(remote, now)
+	if wait := remainingLockout(state, now); wait > 0 {
+		return wait, false
+	}
+	if now.Sub(state.windowStart) >= loginRateWindow {
+		state.windowStart = now
+		state.attempts = 0
+		state.limitReported = false
+	}
+	if state.attempts >= loginRateLimit {
+		report = !state.limitReported
+		state.limitReported = true
+		return state.windowStart.Add(loginRateWindow).Sub(now), report
+	}
+	state.attempts++
+	return 0, false
+}
+
+// fail records a failure from remote and returns the failure count and the
+// lockout it started, if any.
+func (g *guard) fail(remote string) (failures int, lockout time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	state := g.stateLocked(remote, now)
+	if now.Sub(state.lastFailure) > failureMemory {
+		state.failures = 0
+	}
+	state.failures++
+	state.lastFailure = now
+	if state.failures < lockoutThreshold {
+		return state.failures, 0
+	}
+	lockout = lockoutBase
+	for i := lockoutThreshold; i < state.failures && lockout < lockoutMax; i++ {
+		lockout *= 2
+	}
+	if lockout > lockoutMax {
+		lockout = lockoutMax
+	}
+	state.lockedUntil = now.Add(lockout)
+	return state.failures, lockout
+}
+
+// succeed clears remote's failures. The rate window is kept.
+func (g *guard) succeed(remote string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if state, ok := g.clients[remote]; ok {
+		state.failures = 0
+		state.lockedUntil = time.Time{}
+	}
+}
+
+func (g *guard) stateLocked(remote string, now time.Time) *clientState {
+	if state, ok := g.clients[remote]; ok {
+		return state
+	}
+	if len(g.clients) >= maxTrackedClients {
+		g.pruneLocked(now)
+	}
+	state := &clientState{}
+	g.clients[remote] = state
+	return state
+}
+
+// pruneLocked drops clients that are neither locked out nor failing
+// recently, then, if that was not enough, everything not locked out.
+func (g *guard) pruneLocked(now time.Time) {
+	for remote, state := range g.clients {
+		if remainingLockout(state, now) == 0 && now.Sub(state.lastFailure) > failureMemory && now.Sub(state.windowStart) >= loginRateWindow {
+			delete(g.clients, remote)
+		}
+	}
+	if len(g.clients) < maxTrackedClients {
+		return
+	}
+	for remote, state := range g.clients {
+		if remainingLockout(state, now) == 0 {
+			delete(g.clients, remote)
+		}
+	}
+}
+
+func remainingLockout(state *clientState, now time.Time) time.Duration {
+	if wait := state.lockedUntil.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// SetAuditHook registers fn to receive authentication audit events. Set it
+// before serving requests.
+func (m *Manager) SetAuditHook(fn func(Event)) {
+	m.audit = fn
+}
+
+// Login checks a password submitted to the login form by r's client. A
+// non-zero wait means the attempt was refused without checking the
+// password, because the client is locked out or over the login rate.
+func (m *Manager) Login(r *http.Request, password string) (ok bool, wait time.Duration) {
+	return m.checkPasswordAttempt(r, password, "login")
+}
+
+// ConfirmPassword checks the current password when changing it, with the
+// same limits as Login.
+func (m *Manager) ConfirmPassword(r *http.Request, password string) (ok bool, wait time.Duration) {
+	return m.checkPasswordAttempt(r, password, "password change")
+}
+
+func (m *Manager) checkPasswordAttempt(r *http.Request, password, purpose string) (bool, time.Duration) {
+	remote := peerAddress(r)
+	if wait, report := m.guard.attempt(remote); wait > 0 {
+		if report {
+			m.emit(Event{Type: EventRateLimited, Remote: remote, Detail: fmt.Sprintf("more than %d %s attempts a minute", loginRateLimit, purpose)})
+		}
+		return false, wait
+	}
+	if !m.CheckPassword(password) {
+		m.recordFailure(remote, EventLoginFailed, "wrong password on "+purpose)
+		return false, 0
+	}
+	m.guard.succeed(remote)
+	if purpose == "login" {
+		m.emit(Event{Type: EventLoginSucceeded, Remote: remote})
+	}
+	return true, 0
+}
+
+// recordFailure counts a failure against remote and audits it, along with
+// the lockout it triggers.
+func (m *Manager) recordFailure(remote, eventType, detail string) {
+	failures, lockout := m.guard.fail(remote)
+	m.emit(Event{Type: eventType, Remote: remote, Detail: fmt.Sprintf("%s (failure %d)", detail, failures)})
+	if lockout > 0 {
+		m.emit(Event{Type: EventLockout, Remote: remote, Detail: fmt.Sprintf("locked out for %s after %d failures", lockout, failures)})
+	}
+}
+
+func (m *Manager) emit(event Event) {
+	if m.audit != nil {
+		m.audit(event)
+	}
+}
+
+// writeTooManyRequests refuses a locked-out client.
+func writeTooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", RetryAfterSeconds(wait))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_, _ = fmt.Fprintf(w, `{"error":"too many failed attempts; retry in %s"}`, wait.Round(time.Second))
+}
+
+// RetryAfterSeconds formats wait for a Retry-After header, rounding up.
+func RetryAfterSeconds(wait time.Duration) string {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"strings"
 )
@@ -12,11 +13,13 @@ const sessionCookieName = "svpn_session"
 // Public paths that bypass auth:
 //   - GET  /login   (login page)
 //   - POST /login   (login form submission)
-//   - POST /logout  (cookie clearing — harmless without a session)
+//   - POST /logout  (ends the cookie's session, if any)
 //   - /static/*     (CSS, JS, fonts needed by the login page)
 //
 // API requests (/api/*) that fail auth receive a 401 JSON response.
-// All other unauthenticated requests are redirected to /login.
+// All other unauthenticated requests are redirected to /login. Requests
+// authenticated by a session cookie that change state must carry the
+// session's CSRF token in the X-CSRF-Token header, or get a 403.
 func (m *Manager) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
@@ -25,10 +28,37 @@ func (m *Manager) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// A wrong Bearer token counts as a failed attempt, and a locked-out
+		// client's tokens are refused until the lockout ends.
+		if bearer, ok := bearerToken(r); ok && !isTrustedLocal(r) {
+			remote := peerAddress(r)
+			if wait := m.guard.lockedFor(remote); wait > 0 {
+				writeTooManyRequests(w, wait)
+				return
+			}
+			if !m.ValidateToken(bearer) {
+				m.recordFailure(remote, EventTokenRejected, "invalid API token for "+r.Method+" "+path)
+			}
+		}
+
 		if m.isAuthenticated(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
+		// Browser sessions, unlike tokens, are sent automatically, so their
+		// mutating requests must also prove they came from the UI.
+		if _, hasBearer := bearerToken(r); !hasBearer {
+			if session, ok := m.lookupSession(r); ok {
+				if !validCSRF(r, session) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"error":"missing or invalid CSRF token"}`))
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, session)))
+				return
+			}
+		}
 
 			if strings.HasPrefix(path, "/api/") {
 				w.Header().Set("Content-Type", "application/json")
@@ -41,19 +71,42 @@ func (m *Manager) Middleware(next http.Handler) http.Handler {
 	})
 }
 
-// isAuthenticated checks the request for a valid session cookie or Bearer token.
+// HomeAssistantMiddleware guards the Home Assistant endpoints: it accepts
+// the Home Assistant token as a Bearer token in addition to everything
+// Middleware accepts.
+func (m *Manager) HomeAssistantMiddleware(next http.Handler) http.Handler {
+	guarded := m.Middleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bearer, ok := bearerToken(r); ok && m.guard.lockedFor(peerAddress(r)) == 0 &&
+			m.ValidateHomeAssistantToken(bearer) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		guarded.ServeHTTP(w, r)
+	})
+}
+
+// isAuthenticated checks the request for a valid Bearer token, or whether
+// it arrived on the trusted control socket. Session cookies are checked
+// separately, together with their CSRF token.
 func (m *Manager) isAuthenticated(r *http.Request) bool {
-	// Bearer token header takes precedence (for API/script access).
-	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
-		return m.ValidateToken(strings.TrimPrefix(auth, "Bearer "))
+	if isTrustedLocal(r) {
+		return true
 	}
-	// Session cookie — value equals the stored API token.
-	if cookie, err := r.Cookie(sessionCookieName); err == nil {
-		return m.ValidateToken(cookie.Value)
+	if bearer, ok := bearerToken(r); ok {
+		return m.ValidateToken(bearer)
 	}
 	return false
 }
 
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(auth, "Bearer "), true
+}
+
 func isPublicPath(path string) bool {
 	return path == "/login" ||
 		path == "/logout" ||
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"split-vpn-webui/internal/sessions"
+)
+
+// CSRFHeader carries a session's CSRF token on mutating requests.
+const CSRFHeader = "X-CSRF-Token"
+
+type sessionKey struct{}
+
+// SetSessionStore enables browser sessions. Without a store the login form
+// cannot sign anyone in and only API tokens are accepted.
+func (m *Manager) SetSessionStore(store *sessions.Store) {
+	m.sessions = store
+}
+
+// StartSession creates a session for r's browser and sets its cookie.
+func (m *Manager) StartSession(w http.ResponseWriter, r *http.Request) error {
+	if m.sessions == nil {
+		return errors.New("session store unavailable")
+	}
+	session, token, err := m.sessions.Create(r.Context(), r.UserAgent(), clientAddress(r))
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  session.ExpiresAt,
+	})
+	return nil
+}
+
+// EndSession revokes the session r's cookie belongs to, if any, and clears
+// the cookie.
+func (m *Manager) EndSession(w http.ResponseWriter, r *http.Request) {
+	if session, ok := m.lookupSession(r); ok {
+		_ = m.sessions.Revoke(r.Context(), session.ID)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+}
+
+// HasSession reports whether r carries a live session cookie.
+func (m *Manager) HasSession(r *http.Request) bool {
+	_, ok := m.lookupSession(r)
+	return ok
+}
+
+// CurrentSession returns the session that authenticated r, if a cookie did.
+func CurrentSession(r *http.Request) (sessions.Session, bool) {
+	session, ok := r.Context().Value(sessionKey{}).(sessions.Session)
+	return session, ok
+}
+
+// ListSessions returns every live browser session.
+func (m *Manager) ListSessions(ctx context.Context) ([]sessions.Session, error) {
+	if m.sessions == nil {
+		return nil, errors.New("session store unavailable")
+	}
+	return m.sessions.List(ctx)
+}
+
+// RevokeSession signs out one browser session.
+func (m *Manager) RevokeSession(ctx context.Context, id string) error {
+	if m.sessions == nil {
+		return errors.New("session store unavailable")
+	}
+	return m.sessions.Revoke(ctx, id)
+}
+
+// RevokeOtherSessions signs out every browser session except the one
+// making r, and returns how many were ended.
+func (m *Manager) RevokeOtherSessions(r *http.Request) (int, error) {
+	if m.sessions == nil {
+		return 0, nil
+	}
+	current, _ := CurrentSession(r)
+	return m.sessions.RevokeAll(r.Context(), current.ID)
+}
+
+func (m *Manager) lookupSession(r *http.Request) (sessions.Session, bool) {
+	if m.sessions == nil {
+		return sessions.Session{}, false
+	}
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return sessions.Session{}, false
+	}
+	session, err := m.sessions.Lookup(r.Context(), cookie.Value)
+	if err != nil {
+		return sessions.Session{}, false
+	}
+	return session, true
+}
+
+// validCSRF reports whether a cookie-authenticated request may proceed:
+// reads always may, anything else must echo the session's CSRF token.
+func validCSRF(r *http.Request, session sessions.Session) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	token := r.Header.Get(CSRFHeader)
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
@@ -0,0 +1,117 @@
+// Package authevents keeps the authentication audit trail — failed and
+// successful logins, lockouts and rejected API tokens — so repeated guessing
+// from a LAN device shows up in the UI rather than only in the logs.
+package authevents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageSize and MaxPageSize bound List pagination.
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+	// MaxEvents caps the events kept; the oldest are pruned.
+	MaxEvents = 1000
+)
+
+// Event is one authentication audit entry.
+type Event struct {
+	ID     int64     `json:"id"`
+	Type   string    `json:"type"`
+	Remote string    `json:"remote,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Page is one page of events, newest first.
+type Page struct {
+	Events []Event `json:"events"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// Store persists events in the auth_events table.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a store backed by db.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	return &Store{db: db}, nil
+}
+
+// Record appends an event and prunes the oldest beyond MaxEvents. A zero At
+// is set to the current time.
+func (s *Store) Record(ctx context.Context, event Event) (Event, error) {
+	event.Type = strings.TrimSpace(event.Type)
+	if event.Type == "" {
+		return Event{}, fmt.Errorf("event type is required")
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	event.At = event.At.UTC().Truncate(time.Second)
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO auth_events (type, remote, detail, at) VALUES (?, ?, ?, ?)`,
+		event.Type, event.Remote, event.Detail, event.At.Unix(),
+	)
+	if err != nil {
+		return Event{}, err
+	}
+	if event.ID, err = result.LastInsertId(); err != nil {
+		return Event{}, err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM auth_events
+		WHERE id NOT IN (SELECT id FROM auth_events ORDER BY id DESC LIMIT ?)
+	`, MaxEvents); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
+
+// List returns one page of events, newest first.
+func (s *Store) List(ctx context.Context, limit, offset int) (Page, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	page := Page{Events: []Event{}, Limit: limit, Offset: offset}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM auth_events`).Scan(&page.Total); err != nil {
+		return Page{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, type, remote, detail, at
+		FROM auth_events
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event Event
+		var at int64
+		if err := rows.Scan(&event.ID, &event.Type, &event.Remote, &event.Detail, &at); err != nil {
+			return Page{}, err
+		}
+		event.At = time.Unix(at, 0).UTC()
+		page.Events = append(page.Events, event)
+	}
+	return page, rows.Err()
+}
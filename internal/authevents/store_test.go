@@ -0,0 +1,41 @@
+package authevents
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"split-vpn-webui/internal/database"
+)
+
+func TestStoreRecordsAndPrunes(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Open(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if _, err := store.Record(ctx, Event{Remote: "10.0.0.2"}); err == nil {
+		t.Fatal("expected an event without a type to be rejected")
+	}
+	for i := 0; i < MaxEvents+5; i++ {
+		if _, err := store.Record(ctx, Event{Type: "login_failed", Remote: "10.0.0.2", Detail: fmt.Sprintf("attempt %d", i)}); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+	page, err := store.List(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if page.Total != MaxEvents || len(page.Events) != 2 {
+		t.Fatalf("expected %d pruned events and a page of 2, got %+v", MaxEvents, page)
+	}
+	if got := page.Events[0]; got.Detail != fmt.Sprintf("attempt %d", MaxEvents+4) || got.Remote != "10.0.0.2" || got.At.IsZero() {
+		t.Fatalf("expected newest event first, got %+v", got)
+	}
+}
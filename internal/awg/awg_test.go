@@ -68,7 +68,7 @@ H3 = 1086373347
 H4 = 1086373348`
 
 func TestBuildSpec(t *testing.T) {
-	spec, err := BuildSpec(testProfile(t, awgParamLines+"\nMTU = 1380\nListenPort = 51821\nPreUp = ip route add 203.0.113.0/24 dev %i"))
+	spec, err := BuildSpec(testProfile(t, awgParamLines+"\nMTU = 1380\nListenPort = 51821\nFwMark = 0xc9\nPreUp = ip route add 203.0.113.0/24 dev %i"))
 	if err != nil {
 		t.Fatalf("BuildSpec failed: %v", err)
 	}
@@ -81,6 +81,9 @@ func TestBuildSpec(t *testing.T) {
 	if spec.ListenPort != 51821 {
 		t.Fatalf("expected ListenPort 51821, got %d", spec.ListenPort)
 	}
+	if spec.FwMark != 201 {
+		t.Fatalf("expected FwMark 201, got %d", spec.FwMark)
+	}
 	if len(spec.Addresses) != 2 || spec.Addresses[0] != "10.49.1.2/32" {
 		t.Fatalf("unexpected addresses: %v", spec.Addresses)
 	}
@@ -59,6 +59,10 @@ func (b *kernelBackend) configure(ctx context.Context, spec *TunnelSpec) error {
 		port := spec.ListenPort
 		cfg.ListenPort = &port
 	}
+	if spec.FwMark > 0 {
+		mark := spec.FwMark
+		cfg.FirewallMark = &mark
+	}
 	applyKernelParams(&cfg, spec)
 
 	for i, peer := range spec.Peers {
@@ -31,6 +31,7 @@ type TunnelSpec struct {
 	InterfaceName string
 	MTU           int
 	ListenPort    int
+	FwMark        int
 	PrivateKey    string
 	Addresses     []string
 	RouteTable    int
@@ -100,6 +101,13 @@ func BuildSpec(profile *vpn.VPNProfile) (*TunnelSpec, error) {
 		}
 		spec.ListenPort = port
 	}
+	if raw := firstExtra(cfg.Interface.Extras, "fwmark"); raw != "" && !strings.EqualFold(raw, "off") {
+		mark, err := strconv.ParseUint(raw, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FwMark %q", raw)
+		}
+		spec.FwMark = int(mark)
+	}
 
 	for i, peer := range cfg.Peers {
 		peerSpec := PeerSpec{
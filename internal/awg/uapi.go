@@ -27,6 +27,9 @@ func BuildUAPI(spec *TunnelSpec, endpoints []netip.AddrPort) (string, error) {
 	if spec.ListenPort > 0 {
 		fmt.Fprintf(&b, "listen_port=%d\n", spec.ListenPort)
 	}
+	if spec.FwMark > 0 {
+		fmt.Fprintf(&b, "fwmark=%d\n", spec.FwMark)
+	}
 	if err := writeUAPIParams(&b, spec.Params); err != nil {
 		return "", err
 	}
@@ -41,6 +41,13 @@ type routingStore interface {
 	ReplaceState(ctx context.Context, groups []routing.DomainGroup, snapshot map[routing.ResolverSelector]routing.ResolverValues) error
 }
 
+// deviceGroupStore is implemented by routing stores that persist device
+// groups. Device groups are restored before rules so references resolve.
+type deviceGroupStore interface {
+	ListDeviceGroups(ctx context.Context) ([]routing.DeviceGroup, error)
+	ReplaceDeviceGroups(ctx context.Context, groups []routing.DeviceGroup) error
+}
+
 type systemdStore interface {
 	Stop(unitName string) error
 }
@@ -109,11 +116,16 @@ func (m *Manager) Import(ctx context.Context, snapshot Snapshot) (ImportResult,
 	if err != nil {
 		return ImportResult{}, err
 	}
-	result, importErr := m.applyLocked(ctx, normalized)
+	return m.restoreLocked(ctx, normalized, current)
+}
+
+// restoreLocked applies target and falls back to previous when that fails.
+func (m *Manager) restoreLocked(ctx context.Context, target, previous Snapshot) (ImportResult, error) {
+	result, importErr := m.applyLocked(ctx, target)
 	if importErr == nil {
 		return result, nil
 	}
-	if _, rollbackErr := m.applyLocked(ctx, current); rollbackErr != nil {
+	if _, rollbackErr := m.applyLocked(ctx, previous); rollbackErr != nil {
 		return result, fmt.Errorf("restore failed: %v; rollback failed: %w", importErr, rollbackErr)
 	}
 	return result, fmt.Errorf("restore failed and was rolled back: %w", importErr)
@@ -140,6 +152,16 @@ func (m *Manager) exportLocked(ctx context.Context) (Snapshot, error) {
 	if err != nil {
 		return Snapshot{}, err
 	}
+	var deviceRecords []DeviceGroupRecord
+	if devices, ok := m.routing.(deviceGroupStore); ok {
+		deviceGroups, err := devices.ListDeviceGroups(ctx)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		for _, group := range deviceGroups {
+			deviceRecords = append(deviceRecords, deviceGroupToRecord(group))
+		}
+	}
 
 	vpnRecords := make([]VPNRecord, 0, len(profiles))
 	basePath := m.config.BasePath()
@@ -176,19 +198,28 @@ func (m *Manager) exportLocked(ctx context.Context) (Snapshot, error) {
 		Settings:         settingsValue,
 		VPNs:             vpnRecords,
 		Groups:           groupRecords,
+		DeviceGroups:     deviceRecords,
 		ResolverSnapshot: resolverRecords,
 	}, nil
 }
 
 func (m *Manager) profileToRecord(basePath string, profile *vpn.VPNProfile, autostart bool) (VPNRecord, error) {
 	record := VPNRecord{
-		Name:           profile.Name,
-		Type:           profile.Type,
-		Config:         profile.RawConfig,
-		ConfigFile:     profile.ConfigFile,
-		InterfaceName:  profile.InterfaceName,
-		BoundInterface: profile.BoundInterface,
-		Autostart:      autostart,
+		Name:                   profile.Name,
+		Type:                   profile.Type,
+		Config:                 profile.RawConfig,
+		ConfigFile:             profile.ConfigFile,
+		InterfaceName:          profile.InterfaceName,
+		BoundInterface:         profile.BoundInterface,
+		DependsOn:              append([]string(nil), profile.DependsOn...),
+		UplinkVPN:              profile.UplinkVPN,
+		IPv6Policy:             profile.IPv6Policy,
+		IPv6Prefix:             profile.IPv6Prefix,
+		OnDemandIdleMinutes:    profile.OnDemandIdleMinutes,
+		LatencyProbe:           profile.LatencyProbe,
+		LatencyProbeTarget:     profile.LatencyProbeTarget,
+		EndpointRefreshSeconds: profile.EndpointRefreshSeconds,
+		Autostart:              autostart,
 	}
 	if len(profile.SupportingFiles) == 0 {
 		return record, nil
@@ -239,35 +270,48 @@ func (m *Manager) applyLocked(ctx context.Context, snapshot Snapshot) (ImportRes
 			warnings = append(warnings, fmt.Sprintf("failed to stop %s: %v", unitName, err))
 		}
 	}
-	sort.Slice(existing, func(i, j int) bool {
-		left := ""
-		if existing[i] != nil {
-			left = existing[i].Name
-		}
-		right := ""
-		if existing[j] != nil {
-			right = existing[j].Name
-		}
-		return left < right
-	})
-	for _, profile := range existing {
-		if profile == nil {
-			continue
-		}
-		if err := m.vpns.Delete(profile.Name); err != nil {
+	// Remove dependents before the VPNs they require.
+	deleteOrder, err := vpn.DependencyOrder(vpn.Dependencies(existing))
+	if err != nil {
+		return ImportResult{Warnings: warnings}, err
+	}
+	for i := len(deleteOrder) - 1; i >= 0; i-- {
+		if err := m.vpns.Delete(deleteOrder[i]); err != nil {
 			return ImportResult{Warnings: warnings}, err
 		}
 	}
 
+	restoreDeps := make(map[string][]string, len(normalized.VPNs))
+	records := make(map[string]VPNRecord, len(normalized.VPNs))
 	for _, item := range normalized.VPNs {
+		restoreDeps[item.Name] = item.DependsOn
+		if item.UplinkVPN != "" {
+			restoreDeps[item.Name] = append(append([]string(nil), item.DependsOn...), item.UplinkVPN)
+		}
+		records[item.Name] = item
+	}
+	createOrder, err := vpn.DependencyOrder(restoreDeps)
+	if err != nil {
+		return ImportResult{Warnings: warnings}, err
+	}
+	for _, name := range createOrder {
+		item := records[name]
 		request := vpn.UpsertRequest{
-			Name:            item.Name,
-			Type:            item.Type,
-			Config:          item.Config,
-			ConfigFile:      item.ConfigFile,
-			SupportingFiles: append([]vpn.SupportingFileUpload(nil), item.SupportingFiles...),
-			InterfaceName:   item.InterfaceName,
-			BoundInterface:  item.BoundInterface,
+			Name:                   item.Name,
+			Type:                   item.Type,
+			Config:                 item.Config,
+			ConfigFile:             item.ConfigFile,
+			SupportingFiles:        append([]vpn.SupportingFileUpload(nil), item.SupportingFiles...),
+			InterfaceName:          item.InterfaceName,
+			BoundInterface:         item.BoundInterface,
+			DependsOn:              append([]string(nil), item.DependsOn...),
+			UplinkVPN:              item.UplinkVPN,
+			IPv6Policy:             item.IPv6Policy,
+			IPv6Prefix:             item.IPv6Prefix,
+			OnDemandIdleMinutes:    item.OnDemandIdleMinutes,
+			LatencyProbe:           item.LatencyProbe,
+			LatencyProbeTarget:     item.LatencyProbeTarget,
+			EndpointRefreshSeconds: item.EndpointRefreshSeconds,
 		}
 		if _, err := m.vpns.Create(request); err != nil {
 			return ImportResult{Warnings: warnings}, err
@@ -279,6 +323,16 @@ func (m *Manager) applyLocked(ctx context.Context, snapshot Snapshot) (ImportRes
 		}
 	}
 
+	if devices, ok := m.routing.(deviceGroupStore); ok {
+		deviceState := make([]routing.DeviceGroup, 0, len(normalized.DeviceGroups))
+		for _, group := range normalized.DeviceGroups {
+			deviceState = append(deviceState, deviceGroupToRouting(group))
+		}
+		if err := devices.ReplaceDeviceGroups(ctx, deviceState); err != nil {
+			return ImportResult{Warnings: warnings}, err
+		}
+	}
+
 	groupState := make([]routing.DomainGroup, 0, len(normalized.Groups))
 	for _, group := range normalized.Groups {
 		groupState = append(groupState, groupToRouting(group))
@@ -316,6 +370,10 @@ func normalizeSnapshot(raw Snapshot) (Snapshot, error) {
 		item.ConfigFile = strings.TrimSpace(item.ConfigFile)
 		item.InterfaceName = strings.TrimSpace(item.InterfaceName)
 		item.BoundInterface = strings.TrimSpace(item.BoundInterface)
+		item.UplinkVPN = strings.TrimSpace(item.UplinkVPN)
+		for j := range item.DependsOn {
+			item.DependsOn[j] = strings.TrimSpace(item.DependsOn[j])
+		}
 		if err := vpn.ValidateName(item.Name); err != nil {
 			return Snapshot{}, fmt.Errorf("%w: invalid vpn name %q: %v", ErrInvalidSnapshot, item.Name, err)
 		}
@@ -334,6 +392,33 @@ func normalizeSnapshot(raw Snapshot) (Snapshot, error) {
 		})
 	}
 	sort.Slice(snapshot.VPNs, func(i, j int) bool { return snapshot.VPNs[i].Name < snapshot.VPNs[j].Name })
+	for _, item := range snapshot.VPNs {
+		for _, dep := range item.DependsOn {
+			if _, exists := seenNames[dep]; !exists {
+				return Snapshot{}, fmt.Errorf("%w: vpn %q depends on missing vpn %q", ErrInvalidSnapshot, item.Name, dep)
+			}
+		}
+		if item.UplinkVPN != "" {
+			if _, exists := seenNames[item.UplinkVPN]; !exists {
+				return Snapshot{}, fmt.Errorf("%w: vpn %q uses missing uplink vpn %q", ErrInvalidSnapshot, item.Name, item.UplinkVPN)
+			}
+		}
+	}
+
+	deviceNames := make(map[string]struct{}, len(snapshot.DeviceGroups))
+	for i := range snapshot.DeviceGroups {
+		record := &snapshot.DeviceGroups[i]
+		deviceGroup, err := routing.NormalizeDeviceGroup(deviceGroupToRouting(*record))
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("%w: invalid device group %q: %v", ErrInvalidSnapshot, record.Name, err)
+		}
+		if _, exists := deviceNames[deviceGroup.Name]; exists {
+			return Snapshot{}, fmt.Errorf("%w: duplicate device group name %q", ErrInvalidSnapshot, deviceGroup.Name)
+		}
+		deviceNames[deviceGroup.Name] = struct{}{}
+		*record = deviceGroupToRecord(deviceGroup)
+	}
+	sort.Slice(snapshot.DeviceGroups, func(i, j int) bool { return snapshot.DeviceGroups[i].Name < snapshot.DeviceGroups[j].Name })
 
 	for i := range snapshot.Groups {
 		group := &snapshot.Groups[i]
@@ -351,6 +436,18 @@ func normalizeSnapshot(raw Snapshot) (Snapshot, error) {
 				routingGroup.EgressVPN,
 			)
 		}
+		for _, rule := range routingGroup.Rules {
+			for _, name := range rule.SourceDeviceGroups {
+				if _, exists := deviceNames[name]; !exists {
+					return Snapshot{}, fmt.Errorf(
+						"%w: group %q references missing device group %q",
+						ErrInvalidSnapshot,
+						routingGroup.Name,
+						name,
+					)
+				}
+			}
+		}
 		*group = groupToRecord(routingGroup)
 	}
 	sort.Slice(snapshot.Groups, func(i, j int) bool { return snapshot.Groups[i].Name < snapshot.Groups[j].Name })
@@ -380,118 +477,6 @@ func normalizeSnapshot(raw Snapshot) (Snapshot, error) {
 	return snapshot, nil
 }
 
-func groupToRecord(group routing.DomainGroup) GroupRecord {
-	rules := make([]RuleRecord, 0, len(group.Rules))
-	for _, rule := range group.Rules {
-		ports := make([]PortRecord, 0, len(rule.DestinationPorts))
-		for _, port := range rule.DestinationPorts {
-			ports = append(ports, PortRecord{
-				Protocol: port.Protocol,
-				Start:    port.Start,
-				End:      port.End,
-			})
-		}
-		rules = append(rules, RuleRecord{
-			Name:             rule.Name,
-			SourceInterfaces: append([]string(nil), rule.SourceInterfaces...),
-			SourceCIDRs:      append([]string(nil), rule.SourceCIDRs...),
-			SourceMACs:       append([]string(nil), rule.SourceMACs...),
-			DestinationCIDRs: append([]string(nil), rule.DestinationCIDRs...),
-			DestinationPorts: ports,
-			DestinationASNs:  append([]string(nil), rule.DestinationASNs...),
-			Domains:          append([]string(nil), rule.Domains...),
-			WildcardDomains:  append([]string(nil), rule.WildcardDomains...),
-		})
-	}
-	return GroupRecord{
-		Name:      group.Name,
-		EgressVPN: group.EgressVPN,
-		Rules:     rules,
-	}
-}
-
-func groupToRouting(group GroupRecord) routing.DomainGroup {
-	rules := make([]routing.RoutingRule, 0, len(group.Rules))
-	for _, rule := range group.Rules {
-		ports := make([]routing.PortRange, 0, len(rule.DestinationPorts))
-		for _, port := range rule.DestinationPorts {
-			ports = append(ports, routing.PortRange{
-				Protocol: port.Protocol,
-				Start:    port.Start,
-				End:      port.End,
-			})
-		}
-		rules = append(rules, routing.RoutingRule{
-			Name:             rule.Name,
-			SourceInterfaces: append([]string(nil), rule.SourceInterfaces...),
-			SourceCIDRs:      append([]string(nil), rule.SourceCIDRs...),
-			SourceMACs:       append([]string(nil), rule.SourceMACs...),
-			DestinationCIDRs: append([]string(nil), rule.DestinationCIDRs...),
-			DestinationPorts: ports,
-			DestinationASNs:  append([]string(nil), rule.DestinationASNs...),
-			Domains:          append([]string(nil), rule.Domains...),
-			WildcardDomains:  append([]string(nil), rule.WildcardDomains...),
-		})
-	}
-	return routing.DomainGroup{
-		Name:      group.Name,
-		EgressVPN: group.EgressVPN,
-		Rules:     rules,
-	}
-}
-
-func resolverSnapshotToRecords(
-	snapshot map[routing.ResolverSelector]routing.ResolverValues,
-) []ResolverCacheRecord {
-	if len(snapshot) == 0 {
-		return nil
-	}
-	records := make([]ResolverCacheRecord, 0, len(snapshot))
-	for selector, values := range snapshot {
-		records = append(records, ResolverCacheRecord{
-			Type: selector.Type,
-			Key:  selector.Key,
-			V4:   dedupeSorted(values.V4),
-			V6:   dedupeSorted(values.V6),
-		})
-	}
-	return records
-}
-
-func resolverRecordsToSnapshot(
-	records []ResolverCacheRecord,
-) map[routing.ResolverSelector]routing.ResolverValues {
-	snapshot := make(map[routing.ResolverSelector]routing.ResolverValues, len(records))
-	for _, item := range records {
-		snapshot[routing.ResolverSelector{Type: item.Type, Key: item.Key}] = routing.ResolverValues{
-			V4: append([]string(nil), item.V4...),
-			V6: append([]string(nil), item.V6...),
-		}
-	}
-	return snapshot
-}
-
-func dedupeSorted(values []string) []string {
-	if len(values) == 0 {
-		return nil
-	}
-	seen := make(map[string]struct{}, len(values))
-	out := make([]string, 0, len(values))
-	for _, raw := range values {
-		trimmed := strings.TrimSpace(raw)
-		if trimmed == "" {
-			continue
-		}
-		if _, exists := seen[trimmed]; exists {
-			continue
-		}
-		seen[trimmed] = struct{}{}
-		out = append(out, trimmed)
-	}
-	sort.Strings(out)
-	return out
-}
-
 func vpnServiceUnitName(name string) string {
 	return "svpn-" + name + ".service"
 }
@@ -231,6 +231,45 @@ func TestImportRecreatesViaAPIAndRestoresState(t *testing.T) {
 	}
 }
 
+func TestImportOrdersVPNsByDependencies(t *testing.T) {
+	vpnStore := &mockVPNStore{
+		profiles: map[string]*vpn.VPNProfile{
+			"a-app":   {Name: "a-app", Type: "wireguard", DependsOn: []string{"z-outer"}},
+			"z-outer": {Name: "z-outer", Type: "wireguard"},
+		},
+	}
+	manager := &Manager{
+		config:   &mockConfigStore{basePath: t.TempDir()},
+		settings: &mockSettingsStore{},
+		vpns:     vpnStore,
+		routing:  &mockRoutingStore{},
+		now:      time.Now,
+	}
+	wgConfig := "[Interface]\nPrivateKey = test\n[Peer]\nPublicKey = peer\n"
+	snapshot := Snapshot{
+		Format:  FormatName,
+		Version: CurrentVersion,
+		VPNs: []VPNRecord{
+			{Name: "a-app", Type: "wireguard", Config: wgConfig, DependsOn: []string{"z-outer"}},
+			{Name: "z-outer", Type: "wireguard", Config: wgConfig},
+		},
+	}
+	if _, err := manager.Import(context.Background(), snapshot); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if len(vpnStore.deleted) != 2 || vpnStore.deleted[0] != "a-app" {
+		t.Fatalf("expected dependent deleted first, got %#v", vpnStore.deleted)
+	}
+	if len(vpnStore.created) != 2 || vpnStore.created[0].Name != "z-outer" || vpnStore.created[1].Name != "a-app" {
+		t.Fatalf("expected dependency created first, got %#v", vpnStore.created)
+	}
+
+	snapshot.VPNs = snapshot.VPNs[:1]
+	if _, err := manager.Import(context.Background(), snapshot); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("expected missing dependency to be rejected, got %v", err)
+	}
+}
+
 type mockConfigStore struct {
 	basePath   string
 	autostart  map[string]bool
@@ -309,6 +348,8 @@ func (m *mockVPNStore) Create(req vpn.UpsertRequest) (*vpn.VPNProfile, error) {
 		ConfigFile:     req.ConfigFile,
 		InterfaceName:  req.InterfaceName,
 		BoundInterface: req.BoundInterface,
+		DependsOn:      req.DependsOn,
+		UplinkVPN:      req.UplinkVPN,
 	}
 	profile := m.profiles[req.Name]
 	copied := *profile
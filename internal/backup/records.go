@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"sort"
+	"strings"
+
+	"split-vpn-webui/internal/routing"
+)
+
+func groupToRecord(group routing.DomainGroup) GroupRecord {
+	rules := make([]RuleRecord, 0, len(group.Rules))
+	for _, rule := range group.Rules {
+		ports := make([]PortRecord, 0, len(rule.DestinationPorts))
+		for _, port := range rule.DestinationPorts {
+			ports = append(ports, PortRecord{
+				Protocol: port.Protocol,
+				Start:    port.Start,
+				End:      port.End,
+			})
+		}
+		staticHosts := make([]StaticHostRecord, 0, len(rule.StaticHosts))
+		for _, host := range rule.StaticHosts {
+			staticHosts = append(staticHosts, StaticHostRecord{
+				Domain: host.Domain,
+				CIDRs:  append([]string(nil), host.CIDRs...),
+			})
+		}
+		rules = append(rules, RuleRecord{
+			Name:               rule.Name,
+			SourceInterfaces:   append([]string(nil), rule.SourceInterfaces...),
+			SourceCIDRs:        append([]string(nil), rule.SourceCIDRs...),
+			SourceMACs:         append([]string(nil), rule.SourceMACs...),
+			SourceDeviceGroups: append([]string(nil), rule.SourceDeviceGroups...),
+			DestinationCIDRs:   append([]string(nil), rule.DestinationCIDRs...),
+			DestinationPorts:   ports,
+			DestinationASNs:    append([]string(nil), rule.DestinationASNs...),
+			Domains:            append([]string(nil), rule.Domains...),
+			WildcardDomains:    append([]string(nil), rule.WildcardDomains...),
+			StaticHosts:        staticHosts,
+			UploadLimitKbit:    rule.UploadLimitKbit,
+			DownloadLimitKbit:  rule.DownloadLimitKbit,
+			MonitorOnly:        rule.MonitorOnly,
+			Notes:              rule.Notes,
+			Tags:               append([]string(nil), rule.Tags...),
+			ExpiresAt:          rule.ExpiresAt,
+		})
+	}
+	return GroupRecord{
+		Name:               group.Name,
+		EgressVPN:          group.EgressVPN,
+		DNSRedirect:        group.DNSRedirect,
+		InvertDestinations: group.InvertDestinations,
+		FullTunnel:         group.FullTunnel,
+		KillSwitch:         group.KillSwitch,
+		Managed:            group.Managed,
+		Priority:           group.Priority,
+		Notes:              group.Notes,
+		Tags:               append([]string(nil), group.Tags...),
+		Rules:              rules,
+	}
+}
+
+func groupToRouting(group GroupRecord) routing.DomainGroup {
+	rules := make([]routing.RoutingRule, 0, len(group.Rules))
+	for _, rule := range group.Rules {
+		ports := make([]routing.PortRange, 0, len(rule.DestinationPorts))
+		for _, port := range rule.DestinationPorts {
+			ports = append(ports, routing.PortRange{
+				Protocol: port.Protocol,
+				Start:    port.Start,
+				End:      port.End,
+			})
+		}
+		staticHosts := make([]routing.StaticHostMapping, 0, len(rule.StaticHosts))
+		for _, host := range rule.StaticHosts {
+			staticHosts = append(staticHosts, routing.StaticHostMapping{
+				Domain: host.Domain,
+				CIDRs:  append([]string(nil), host.CIDRs...),
+			})
+		}
+		rules = append(rules, routing.RoutingRule{
+			Name:               rule.Name,
+			SourceInterfaces:   append([]string(nil), rule.SourceInterfaces...),
+			SourceCIDRs:        append([]string(nil), rule.SourceCIDRs...),
+			SourceMACs:         append([]string(nil), rule.SourceMACs...),
+			SourceDeviceGroups: append([]string(nil), rule.SourceDeviceGroups...),
+			DestinationCIDRs:   append([]string(nil), rule.DestinationCIDRs...),
+			DestinationPorts:   ports,
+			DestinationASNs:    append([]string(nil), rule.DestinationASNs...),
+			Domains:            append([]string(nil), rule.Domains...),
+			WildcardDomains:    append([]string(nil), rule.WildcardDomains...),
+			StaticHosts:        staticHosts,
+			UploadLimitKbit:    rule.UploadLimitKbit,
+			DownloadLimitKbit:  rule.DownloadLimitKbit,
+			MonitorOnly:        rule.MonitorOnly,
+			Notes:              rule.Notes,
+			Tags:               append([]string(nil), rule.Tags...),
+			ExpiresAt:          rule.ExpiresAt,
+		})
+	}
+	return routing.DomainGroup{
+		Name:               group.Name,
+		EgressVPN:          group.EgressVPN,
+		DNSRedirect:        group.DNSRedirect,
+		InvertDestinations: group.InvertDestinations,
+		FullTunnel:         group.FullTunnel,
+		KillSwitch:         group.KillSwitch,
+		Managed:            group.Managed,
+		Priority:           group.Priority,
+		Notes:              group.Notes,
+		Tags:               append([]string(nil), group.Tags...),
+		Rules:              rules,
+	}
+}
+
+func deviceGroupToRecord(group routing.DeviceGroup) DeviceGroupRecord {
+	return DeviceGroupRecord{
+		Name:      group.Name,
+		MACs:      append([]string(nil), group.MACs...),
+		CIDRs:     append([]string(nil), group.CIDRs...),
+		SyncNames: append([]string(nil), group.SyncNames...),
+	}
+}
+
+func deviceGroupToRouting(group DeviceGroupRecord) routing.DeviceGroup {
+	return routing.DeviceGroup{
+		Name:      group.Name,
+		MACs:      append([]string(nil), group.MACs...),
+		CIDRs:     append([]string(nil), group.CIDRs...),
+		SyncNames: append([]string(nil), group.SyncNames...),
+	}
+}
+
+func resolverSnapshotToRecords(
+	snapshot map[routing.ResolverSelector]routing.ResolverValues,
+) []ResolverCacheRecord {
+	if len(snapshot) == 0 {
+		return nil
+	}
+	records := make([]ResolverCacheRecord, 0, len(snapshot))
+	for selector, values := range snapshot {
+		records = append(records, ResolverCacheRecord{
+			Type: selector.Type,
+			Key:  selector.Key,
+			V4:   dedupeSorted(values.V4),
+			V6:   dedupeSorted(values.V6),
+		})
+	}
+	return records
+}
+
+func resolverRecordsToSnapshot(
+	records []ResolverCacheRecord,
+) map[routing.ResolverSelector]routing.ResolverValues {
+	snapshot := make(map[routing.ResolverSelector]routing.ResolverValues, len(records))
+	for _, item := range records {
+		snapshot[routing.ResolverSelector{Type: item.Type, Key: item.Key}] = routing.ResolverValues{
+			V4: append([]string(nil), item.V4...),
+			V6: append([]string(nil), item.V6...),
+		}
+	}
+	return snapshot
+}
+
+func dedupeSorted(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(values))
+	out := make([]string, 0, len(values))
+	for _, raw := range values {
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" {
+			continue
+		}
+		if _, exists := seen[trimmed]; exists {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		out = append(out, trimmed)
+	}
+	sort.Strings(out)
+	return out
+}
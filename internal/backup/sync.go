@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// SyncResult reports whether replicating a peer snapshot changed anything.
+type SyncResult struct {
+	ImportResult
+	Changed bool `json:"changed"`
+}
+
+// Differs reports whether the replicated parts of a peer snapshot — VPN
+// profiles, routing groups and device groups — differ from local state.
+func (m *Manager) Differs(ctx context.Context, snapshot Snapshot) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	normalized, err := normalizeSnapshot(snapshot)
+	if err != nil {
+		return false, err
+	}
+	current, err := m.exportLocked(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !sameReplicatedState(current, normalized), nil
+}
+
+// Sync replaces VPN profiles, routing groups, device groups and the resolver
+// cache with those of a peer snapshot. Local settings are kept, and so are
+// autostart flags: profiles new to this node start with autostart off so a
+// standby does not bring up tunnels that share keys with its peer.
+func (m *Manager) Sync(ctx context.Context, snapshot Snapshot) (SyncResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	normalized, err := normalizeSnapshot(snapshot)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	current, err := m.exportLocked(ctx)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if sameReplicatedState(current, normalized) {
+		return SyncResult{}, nil
+	}
+
+	autostart := make(map[string]bool, len(current.VPNs))
+	for _, record := range current.VPNs {
+		autostart[record.Name] = record.Autostart
+	}
+	normalized.Settings = current.Settings
+	for i := range normalized.VPNs {
+		normalized.VPNs[i].Autostart = autostart[normalized.VPNs[i].Name]
+	}
+	result, err := m.restoreLocked(ctx, normalized, current)
+	return SyncResult{ImportResult: result, Changed: true}, err
+}
+
+// sameReplicatedState compares the parts of two snapshots that Sync
+// replicates. Autostart and the resolver cache are ignored: the first is
+// local, the second changes with every resolver run.
+func sameReplicatedState(local, peer Snapshot) bool {
+	normalizedLocal, err := normalizeSnapshot(local)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(replicatedJSON(normalizedLocal), replicatedJSON(peer))
+}
+
+func replicatedJSON(snapshot Snapshot) []byte {
+	vpns := make([]VPNRecord, 0, len(snapshot.VPNs))
+	for _, record := range snapshot.VPNs {
+		record.Autostart = false
+		vpns = append(vpns, record)
+	}
+	groups := append(make([]GroupRecord, 0, len(snapshot.Groups)), snapshot.Groups...)
+	deviceGroups := append(make([]DeviceGroupRecord, 0, len(snapshot.DeviceGroups)), snapshot.DeviceGroups...)
+	payload, err := json.Marshal(struct {
+		VPNs         []VPNRecord         `json:"vpns"`
+		Groups       []GroupRecord       `json:"groups"`
+		DeviceGroups []DeviceGroupRecord `json:"deviceGroups"`
+	}{vpns, groups, deviceGroups})
+	if err != nil {
+		return nil
+	}
+	return payload
+}
@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestSyncKeepsLocalSettingsAndAutostart(t *testing.T) {
+	wgConfig := "[Interface]\nPrivateKey = test\n[Peer]\nPublicKey = peer\n"
+	configStore := &mockConfigStore{basePath: t.TempDir(), autostart: map[string]bool{"shared": true}}
+	settingsStore := &mockSettingsStore{value: settings.Settings{ListenInterface: "br0", AuthToken: "follower-token"}}
+	vpnStore := &mockVPNStore{
+		profiles: map[string]*vpn.VPNProfile{
+			"shared": {Name: "shared", Type: "wireguard", RawConfig: wgConfig, InterfaceName: "wg-sv-shared"},
+		},
+	}
+	routingStore := &mockRoutingStore{}
+	manager := &Manager{
+		config:   configStore,
+		settings: settingsStore,
+		vpns:     vpnStore,
+		routing:  routingStore,
+		systemd:  &mockSystemdStore{},
+		now:      time.Now,
+	}
+
+	peer := Snapshot{
+		Format:   FormatName,
+		Version:  CurrentVersion,
+		Settings: settings.Settings{ListenInterface: "br9", AuthToken: "leader-token"},
+		VPNs: []VPNRecord{
+			{Name: "shared", Type: "wireguard", Config: wgConfig, InterfaceName: "wg-sv-shared", Autostart: false},
+			{Name: "added", Type: "wireguard", Config: wgConfig, InterfaceName: "wg-sv-added", Autostart: true},
+		},
+		Groups: []GroupRecord{
+			{Name: "Streaming", EgressVPN: "added", Rules: []RuleRecord{{Name: "Rule 1", Domains: []string{"example.com"}}}},
+		},
+	}
+
+	differs, err := manager.Differs(context.Background(), peer)
+	if err != nil || !differs {
+		t.Fatalf("expected peer snapshot to differ, got %v %v", differs, err)
+	}
+	result, err := manager.Sync(context.Background(), peer)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if !result.Changed {
+		t.Fatalf("expected sync to report a change")
+	}
+	if settingsStore.value.AuthToken != "follower-token" || settingsStore.value.ListenInterface != "br0" {
+		t.Fatalf("expected local settings kept, got %#v", settingsStore.value)
+	}
+	if !configStore.autostart["shared"] || configStore.autostart["added"] {
+		t.Fatalf("expected local autostart kept and new profiles off, got %#v", configStore.autostart)
+	}
+	if len(routingStore.groups) != 1 || routingStore.groups[0].Name != "Streaming" {
+		t.Fatalf("expected peer groups replicated, got %#v", routingStore.groups)
+	}
+
+	created := len(vpnStore.created)
+	result, err = manager.Sync(context.Background(), peer)
+	if err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+	if result.Changed || len(vpnStore.created) != created {
+		t.Fatalf("expected unchanged snapshot to be a no-op, changed=%v created=%d", result.Changed, len(vpnStore.created)-created)
+	}
+	if differs, err := manager.Differs(context.Background(), peer); err != nil || differs {
+		t.Fatalf("expected no difference after sync, got %v %v", differs, err)
+	}
+}
@@ -27,39 +27,72 @@ type Snapshot struct {
 	Settings         settings.Settings     `json:"settings"`
 	VPNs             []VPNRecord           `json:"vpns"`
 	Groups           []GroupRecord         `json:"groups"`
+	DeviceGroups     []DeviceGroupRecord   `json:"deviceGroups,omitempty"`
 	ResolverSnapshot []ResolverCacheRecord `json:"resolverSnapshot,omitempty"`
 }
 
 // VPNRecord stores one VPN profile in source payload form.
 type VPNRecord struct {
-	Name            string                     `json:"name"`
-	Type            string                     `json:"type"`
-	Config          string                     `json:"config"`
-	ConfigFile      string                     `json:"configFile,omitempty"`
-	InterfaceName   string                     `json:"interfaceName,omitempty"`
-	BoundInterface  string                     `json:"boundInterface,omitempty"`
-	SupportingFiles []vpn.SupportingFileUpload `json:"supportingFiles,omitempty"`
-	Autostart       bool                       `json:"autostart"`
+	Name                   string                     `json:"name"`
+	Type                   string                     `json:"type"`
+	Config                 string                     `json:"config"`
+	ConfigFile             string                     `json:"configFile,omitempty"`
+	InterfaceName          string                     `json:"interfaceName,omitempty"`
+	BoundInterface         string                     `json:"boundInterface,omitempty"`
+	DependsOn              []string                   `json:"dependsOn,omitempty"`
+	UplinkVPN              string                     `json:"uplinkVpn,omitempty"`
+	IPv6Policy             string                     `json:"ipv6Policy,omitempty"`
+	IPv6Prefix             string                     `json:"ipv6Prefix,omitempty"`
+	OnDemandIdleMinutes    int                        `json:"onDemandIdleMinutes,omitempty"`
+	LatencyProbe           string                     `json:"latencyProbe,omitempty"`
+	LatencyProbeTarget     string                     `json:"latencyProbeTarget,omitempty"`
+	EndpointRefreshSeconds int                        `json:"endpointRefreshSeconds,omitempty"`
+	SupportingFiles        []vpn.SupportingFileUpload `json:"supportingFiles,omitempty"`
+	Autostart              bool                       `json:"autostart"`
 }
 
 // GroupRecord stores one policy group and all of its selectors.
 type GroupRecord struct {
-	Name      string       `json:"name"`
-	EgressVPN string       `json:"egressVpn"`
-	Rules     []RuleRecord `json:"rules"`
+	Name               string       `json:"name"`
+	EgressVPN          string       `json:"egressVpn"`
+	DNSRedirect        string       `json:"dnsRedirect,omitempty"`
+	InvertDestinations bool         `json:"invertDestinations,omitempty"`
+	FullTunnel         bool         `json:"fullTunnel,omitempty"`
+	KillSwitch         bool         `json:"killSwitch,omitempty"`
+	Managed            string       `json:"managed,omitempty"`
+	Priority           int          `json:"priority,omitempty"`
+	Notes              string       `json:"notes,omitempty"`
+	Tags               []string     `json:"tags,omitempty"`
+	Rules              []RuleRecord `json:"rules"`
 }
 
 // RuleRecord stores one AND-combined routing selector set.
 type RuleRecord struct {
-	Name             string       `json:"name,omitempty"`
-	SourceInterfaces []string     `json:"sourceInterfaces,omitempty"`
-	SourceCIDRs      []string     `json:"sourceCidrs,omitempty"`
-	SourceMACs       []string     `json:"sourceMacs,omitempty"`
-	DestinationCIDRs []string     `json:"destinationCidrs,omitempty"`
-	DestinationPorts []PortRecord `json:"destinationPorts,omitempty"`
-	DestinationASNs  []string     `json:"destinationAsns,omitempty"`
-	Domains          []string     `json:"domains,omitempty"`
-	WildcardDomains  []string     `json:"wildcardDomains,omitempty"`
+	Name               string             `json:"name,omitempty"`
+	SourceInterfaces   []string           `json:"sourceInterfaces,omitempty"`
+	SourceCIDRs        []string           `json:"sourceCidrs,omitempty"`
+	SourceMACs         []string           `json:"sourceMacs,omitempty"`
+	SourceDeviceGroups []string           `json:"sourceDeviceGroups,omitempty"`
+	DestinationCIDRs   []string           `json:"destinationCidrs,omitempty"`
+	DestinationPorts   []PortRecord       `json:"destinationPorts,omitempty"`
+	DestinationASNs    []string           `json:"destinationAsns,omitempty"`
+	Domains            []string           `json:"domains,omitempty"`
+	WildcardDomains    []string           `json:"wildcardDomains,omitempty"`
+	StaticHosts        []StaticHostRecord `json:"staticHosts,omitempty"`
+	UploadLimitKbit    int                `json:"uploadLimitKbit,omitempty"`
+	DownloadLimitKbit  int                `json:"downloadLimitKbit,omitempty"`
+	MonitorOnly        bool               `json:"monitorOnly,omitempty"`
+	Notes              string             `json:"notes,omitempty"`
+	Tags               []string           `json:"tags,omitempty"`
+	ExpiresAt          int64              `json:"expiresAt,omitempty"`
+}
+
+// DeviceGroupRecord stores one named device set referenced by rules.
+type DeviceGroupRecord struct {
+	Name      string   `json:"name"`
+	MACs      []string `json:"macs,omitempty"`
+	CIDRs     []string `json:"cidrs,omitempty"`
+	SyncNames []string `json:"syncNames,omitempty"`
 }
 
 // PortRecord stores one destination port/range selector.
@@ -69,6 +102,12 @@ type PortRecord struct {
 	End      int    `json:"end,omitempty"`
 }
 
+// StaticHostRecord stores addresses pinned to one of a rule's domains.
+type StaticHostRecord struct {
+	Domain string   `json:"domain"`
+	CIDRs  []string `json:"cidrs"`
+}
+
 // ResolverCacheRecord stores one selector's resolved IPv4/IPv6 prefixes.
 type ResolverCacheRecord struct {
 	Type string   `json:"type"`
@@ -0,0 +1,260 @@
+// Package capabilities checks the router for the tools and kernel modules
+// split routing depends on, with versions and remediation hints, so a
+// "nothing routes" report can be triaged from a single response.
+package capabilities
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Check statuses.
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Check kinds.
+const (
+	KindTool   = "tool"
+	KindModule = "module"
+)
+
+const commandTimeout = 5 * time.Second
+
+// Check is the outcome for one tool or kernel module. Required checks fail
+// when missing; the others only warn, since they back optional features.
+type Check struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Status   string `json:"status"`
+	Required bool   `json:"required"`
+	Path     string `json:"path,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Detail   string `json:"detail"`
+	Remedy   string `json:"remedy,omitempty"`
+}
+
+// Report lists every check. Passed is false when a required check failed.
+type Report struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	Kernel    string    `json:"kernel,omitempty"`
+	Passed    bool      `json:"passed"`
+	Checks    []Check   `json:"checks"`
+}
+
+// tool describes a command the service runs and how to read its version.
+type tool struct {
+	name     string
+	args     []string
+	required bool
+	usedFor  string
+}
+
+var tools = []tool{
+	{name: "ip", args: []string{"-V"}, required: true, usedFor: "policy routing rules and routes"},
+	{name: "iptables", args: []string{"-V"}, required: true, usedFor: "marking routed traffic"},
+	{name: "ip6tables", args: []string{"-V"}, usedFor: "marking routed IPv6 traffic"},
+	{name: "ipset", args: []string{"version"}, required: true, usedFor: "domain, ASN and CIDR selectors"},
+	{name: "conntrack", args: []string{"-V"}, usedFor: "the flow inspector and flow history"},
+	{name: "wg", args: []string{"--version"}, usedFor: "WireGuard VPNs"},
+	{name: "openvpn", args: []string{"--version"}, usedFor: "OpenVPN VPNs"},
+	{name: "dnsmasq", args: []string{"--version"}, usedFor: "filling ipsets from DNS answers with the dnsmasq backend"},
+	{name: "systemctl", args: []string{"--version"}, required: true, usedFor: "starting and stopping VPN units"},
+}
+
+// module describes a kernel module the routing rules rely on.
+type module struct {
+	name     string
+	required bool
+	usedFor  string
+}
+
+var modules = []module{
+	{name: "ip_set", required: true, usedFor: "ipsets"},
+	{name: "ip_set_hash_net", required: true, usedFor: "hash:net ipsets"},
+	{name: "xt_set", required: true, usedFor: "iptables --match-set"},
+	{name: "xt_mark", required: true, usedFor: "iptables MARK"},
+	{name: "nf_conntrack", usedFor: "connection tracking and the flow inspector"},
+	{name: "xt_conntrack", usedFor: "new-connection and reply matches"},
+	{name: "xt_hashlimit", usedFor: "per-rule rate limits"},
+	{name: "wireguard", usedFor: "WireGuard VPNs"},
+	{name: "tun", usedFor: "OpenVPN VPNs"},
+}
+
+// Probe inspects the router.
+type Probe interface {
+	LookPath(name string) (string, error)
+	Output(ctx context.Context, name string, args ...string) (string, error)
+	ReadFile(path string) ([]byte, error)
+	Exists(path string) bool
+}
+
+type systemProbe struct{}
+
+func (systemProbe) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+func (systemProbe) Output(ctx context.Context, name string, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+func (systemProbe) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (systemProbe) Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Checker runs capability checks.
+type Checker struct {
+	probe Probe
+	now   func() time.Time
+}
+
+// NewChecker creates a checker for the running system.
+func NewChecker() *Checker {
+	return NewCheckerWithProbe(systemProbe{})
+}
+
+// NewCheckerWithProbe creates a checker with a custom probe, for tests.
+func NewCheckerWithProbe(probe Probe) *Checker {
+	return &Checker{probe: probe, now: time.Now}
+}
+
+// Check inspects every tool and module.
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{CheckedAt: c.now().UTC(), Passed: true}
+	if data, err := c.probe.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		report.Kernel = strings.TrimSpace(string(data))
+	}
+	for _, t := range tools {
+		report.Checks = append(report.Checks, c.checkTool(ctx, t))
+	}
+	index := c.moduleIndex(report.Kernel)
+	for _, m := range modules {
+		report.Checks = append(report.Checks, c.checkModule(m, index))
+	}
+	for _, check := range report.Checks {
+		if check.Status == StatusFail {
+			report.Passed = false
+		}
+	}
+	return report
+}
+
+func (c *Checker) checkTool(ctx context.Context, t tool) Check {
+	check := Check{Name: t.name, Kind: KindTool, Required: t.required}
+	path, err := c.probe.LookPath(t.name)
+	if err != nil {
+		check.Status = missingStatus(t.required)
+		check.Detail = fmt.Sprintf("not found in PATH; needed for %s", t.usedFor)
+		check.Remedy = fmt.Sprintf("install %s or add its directory to the service's PATH", t.name)
+		return check
+	}
+	check.Path = path
+	runCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	output, err := c.probe.Output(runCtx, path, t.args...)
+	check.Version = firstLine(output)
+	// Some tools, openvpn among them, exit non-zero after printing their
+	// version; only an empty output counts as broken.
+	if check.Version == "" && err != nil {
+		check.Status = StatusWarn
+		check.Detail = fmt.Sprintf("installed but its version could not be read: %v", err)
+		check.Remedy = fmt.Sprintf("run %s %s on the router to see why it fails", t.name, strings.Join(t.args, " "))
+		return check
+	}
+	check.Status = StatusOK
+	check.Detail = "used for " + t.usedFor
+	return check
+}
+
+// moduleIndex holds the kernel's module lists. known is false when they
+// could not be read, so absent modules cannot be told from loadable ones.
+type moduleIndex struct {
+	loaded    map[string]bool
+	available string
+	known     bool
+}
+
+func (c *Checker) moduleIndex(kernel string) moduleIndex {
+	index := moduleIndex{loaded: make(map[string]bool)}
+	if data, err := c.probe.ReadFile("/proc/modules"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if name, _, _ := strings.Cut(line, " "); name != "" {
+				index.loaded[name] = true
+			}
+		}
+	}
+	if kernel == "" {
+		return index
+	}
+	var lists strings.Builder
+	for _, file := range []string{"modules.builtin", "modules.dep"} {
+		data, err := c.probe.ReadFile(fmt.Sprintf("/lib/modules/%s/%s", kernel, file))
+		if err != nil {
+			continue
+		}
+		index.known = true
+		lists.Write(data)
+		lists.WriteByte('\n')
+	}
+	index.available = lists.String()
+	return index
+}
+
+func (c *Checker) checkModule(m module, index moduleIndex) Check {
+	check := Check{Name: m.name, Kind: KindModule, Required: m.required}
+	switch {
+	case index.loaded[m.name] || c.probe.Exists("/sys/module/"+m.name):
+		check.Status = StatusOK
+		check.Detail = "loaded; used for " + m.usedFor
+	case moduleListed(index.available, m.name):
+		check.Status = StatusOK
+		check.Detail = "available and loaded on first use; used for " + m.usedFor
+	case index.known:
+		check.Status = missingStatus(m.required)
+		check.Detail = fmt.Sprintf("not provided by this kernel; needed for %s", m.usedFor)
+		check.Remedy = "update the router firmware to one whose kernel ships " + m.name
+	default:
+		check.Status = StatusWarn
+		check.Detail = fmt.Sprintf("not loaded, and the kernel's module lists could not be read; needed for %s", m.usedFor)
+		check.Remedy = "run modprobe " + m.name + " on the router and check dmesg if it fails"
+	}
+	return check
+}
+
+// moduleListed reports whether a modules.builtin or modules.dep listing
+// names the module. Paths use the file name, where "_" may be written "-".
+func moduleListed(listing, name string) bool {
+	for _, candidate := range []string{name, strings.ReplaceAll(name, "_", "-")} {
+		for _, suffix := range []string{".ko:", ".ko\n", ".ko ", ".ko.xz", ".ko.gz", ".ko.zst"} {
+			if strings.Contains(listing, "/"+candidate+suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func missingStatus(required bool) string {
+	if required {
+		return StatusFail
+	}
+	return StatusWarn
+}
+
+func firstLine(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,103 @@
+package capabilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+type fakeProbe struct {
+	outputs map[string]string
+	files   map[string]string
+	paths   map[string]bool
+}
+
+func (f *fakeProbe) LookPath(name string) (string, error) {
+	if _, ok := f.outputs[name]; !ok {
+		return "", fmt.Errorf("exec: %q: executable file not found in $PATH", name)
+	}
+	return "/usr/sbin/" + name, nil
+}
+
+func (f *fakeProbe) Output(_ context.Context, path string, _ ...string) (string, error) {
+	for name, output := range f.outputs {
+		if path == "/usr/sbin/"+name {
+			if output == "" {
+				return "", errors.New("exit status 1")
+			}
+			return output, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+func (f *fakeProbe) ReadFile(path string) ([]byte, error) {
+	content, ok := f.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (f *fakeProbe) Exists(path string) bool {
+	return f.paths[path]
+}
+
+func checksByName(report Report) map[string]Check {
+	byName := make(map[string]Check)
+	for _, check := range report.Checks {
+		byName[check.Kind+"/"+check.Name] = check
+	}
+	return byName
+}
+
+func TestCheckReportsToolsAndModules(t *testing.T) {
+	probe := &fakeProbe{
+		outputs: map[string]string{
+			"ip":        "ip utility, iproute2-6.1.0",
+			"iptables":  "iptables v1.8.7 (legacy)",
+			"ipset":     "ipset v7.17, protocol version: 7",
+			"systemctl": "systemd 247 (247.3-7)\n+PAM +AUDIT",
+			"openvpn":   "",
+		},
+		files: map[string]string{
+			"/proc/sys/kernel/osrelease":            "5.4.0-ui\n",
+			"/proc/modules":                         "ip_set 45056 3 ip_set_hash_net,xt_set, Live 0x0\nxt_set 16384 5 - Live 0x0\n",
+			"/lib/modules/5.4.0-ui/modules.builtin": "kernel/net/netfilter/xt_mark.ko\n",
+			"/lib/modules/5.4.0-ui/modules.dep":     "kernel/net/netfilter/ipset/ip_set_hash_net.ko: kernel/net/netfilter/ipset/ip_set.ko\n",
+		},
+		paths: map[string]bool{"/sys/module/nf_conntrack": true},
+	}
+	report := NewCheckerWithProbe(probe).Check(context.Background())
+	if !report.Passed || report.Kernel != "5.4.0-ui" {
+		t.Fatalf("expected the required checks to pass, got %+v", report)
+	}
+	checks := checksByName(report)
+	if check := checks["tool/systemctl"]; check.Status != StatusOK || check.Version != "systemd 247 (247.3-7)" || check.Path != "/usr/sbin/systemctl" {
+		t.Fatalf("unexpected systemctl check: %+v", check)
+	}
+	if check := checks["tool/wg"]; check.Status != StatusWarn || check.Remedy == "" {
+		t.Fatalf("expected a missing optional tool to warn, got %+v", check)
+	}
+	if check := checks["tool/openvpn"]; check.Status != StatusWarn {
+		t.Fatalf("expected an unreadable version to warn, got %+v", check)
+	}
+	for _, name := range []string{"ip_set", "xt_set", "xt_mark", "ip_set_hash_net", "nf_conntrack"} {
+		if check := checks["module/"+name]; check.Status != StatusOK {
+			t.Fatalf("expected %s to be available, got %+v", name, check)
+		}
+	}
+	if check := checks["module/wireguard"]; check.Status != StatusWarn || check.Detail == "" {
+		t.Fatalf("expected a missing optional module to warn, got %+v", check)
+	}
+
+	delete(probe.outputs, "ipset")
+	delete(probe.files, "/lib/modules/5.4.0-ui/modules.builtin")
+	report = NewCheckerWithProbe(probe).Check(context.Background())
+	checks = checksByName(report)
+	if report.Passed || checks["tool/ipset"].Status != StatusFail || checks["module/xt_mark"].Status != StatusFail {
+		t.Fatalf("expected missing required tools and modules to fail, got %+v", report)
+	}
+}
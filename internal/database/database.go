@@ -3,11 +3,10 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	_ "modernc.org/sqlite" // registers the "sqlite" driver
@@ -46,54 +45,12 @@ func Open(path string) (*sql.DB, error) {
 	return db, nil
 }
 
-// migrate executes the schema DDL. All statements are idempotent.
-func migrate(db *sql.DB) error {
-	if _, err := db.Exec(schema); err != nil {
-		return err
-	}
-	return ensureColumn(db, "routing_rules", "exclude_multicast", "INTEGER NOT NULL DEFAULT 1")
-}
-
-func ensureColumn(db *sql.DB, tableName, columnName, definition string) error {
-	rows, err := db.Query("PRAGMA table_info(" + tableName + ")")
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var (
-			cid        int
-			name       string
-			columnType string
-			notNull    int
-			defaultVal sql.NullString
-			primaryKey int
-		)
-		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
-			return err
-		}
-		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(columnName)) {
-			return rows.Err()
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return err
-	}
-	_, err = db.Exec("ALTER TABLE " + tableName + " ADD COLUMN " + columnName + " " + definition)
-	return err
-}
-
-// Cleanup prunes stale rows from tables that are expected to be bounded.
+// Cleanup prunes stale rows using the default retention windows.
 func Cleanup(db *sql.DB) error {
 	return cleanupBefore(db, time.Now().UTC())
 }
 
 func cleanupBefore(db *sql.DB, now time.Time) error {
-	if db == nil {
-		return errors.New("database handle is required")
-	}
-	cutoff := now.Add(-7 * 24 * time.Hour).Unix()
-	_, err := db.Exec(`DELETE FROM stats_history WHERE timestamp < ?`, cutoff)
+	_, err := Prune(context.Background(), db, DefaultRetention(), now)
 	return err
 }
@@ -22,6 +22,10 @@ func TestOpen_InMemory(t *testing.T) {
 		"routing_rule_excluded_source_cidrs",
 		"routing_rule_source_interfaces",
 		"routing_rule_source_macs",
+		"routing_rule_source_device_groups",
+		"device_groups",
+		"device_group_members",
+		"device_aliases",
 		"routing_rule_destination_cidrs",
 		"routing_rule_excluded_destination_cidrs",
 		"routing_rule_ports",
@@ -34,6 +38,12 @@ func TestOpen_InMemory(t *testing.T) {
 		"resolver_runs",
 		"prewarm_runs",
 		"prewarm_cache",
+		"prewarm_run_diffs",
+		"vpn_events",
+		"vpn_revisions",
+		"settings_revisions",
+		"auth_events",
+		"sessions",
 	}
 	for _, table := range tables {
 		var name string
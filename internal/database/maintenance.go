@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Retention bounds how long each kind of history is kept. A zero window
+// keeps that history indefinitely.
+type Retention struct {
+	// Stats covers persisted throughput history.
+	Stats time.Duration
+	// Runs covers resolver and pre-warm run records, including run diffs
+	// and per-run errors.
+	Runs time.Duration
+	// Events covers the per-VPN connection timeline, which also records
+	// start, stop and restart actions taken in the web UI.
+	Events time.Duration
+	// Trash covers deleted groups kept in the recycle bin.
+	Trash time.Duration
+}
+
+// DefaultRetention returns the windows used when none are configured.
+func DefaultRetention() Retention {
+	return Retention{
+		Stats:  7 * 24 * time.Hour,
+		Runs:   30 * 24 * time.Hour,
+		Events: 30 * 24 * time.Hour,
+		Trash:  7 * 24 * time.Hour,
+	}
+}
+
+// Pruned counts the rows Prune removed per table.
+type Pruned map[string]int64
+
+// Total returns the number of rows removed across all tables.
+func (p Pruned) Total() int64 {
+	var total int64
+	for _, count := range p {
+		total += count
+	}
+	return total
+}
+
+// Prune deletes rows older than the retention windows. Unfinished runs and
+// the latest run of each kind are kept so status views always have a last run.
+func Prune(ctx context.Context, db *sql.DB, retention Retention, now time.Time) (Pruned, error) {
+	if db == nil {
+		return nil, errors.New("database handle is required")
+	}
+	statements := []struct {
+		table  string
+		window time.Duration
+		query  string
+	}{
+		{"stats_history", retention.Stats, `DELETE FROM stats_history WHERE timestamp < ?`},
+		// Run diffs and run errors follow their run through ON DELETE CASCADE.
+		{"resolver_runs", retention.Runs, `
+			DELETE FROM resolver_runs
+			WHERE started_at < ? AND finished_at IS NOT NULL
+				AND id <> (SELECT MAX(id) FROM resolver_runs)`},
+		{"prewarm_runs", retention.Runs, `
+			DELETE FROM prewarm_runs
+			WHERE started_at < ? AND finished_at IS NOT NULL
+				AND id <> (SELECT MAX(id) FROM prewarm_runs)`},
+		{"vpn_events", retention.Events, `DELETE FROM vpn_events WHERE at < ?`},
+		{"group_trash", retention.Trash, `DELETE FROM group_trash WHERE deleted_at < ?`},
+	}
+	pruned := make(Pruned, len(statements))
+	for _, stmt := range statements {
+		if stmt.window <= 0 {
+			continue
+		}
+		result, err := db.ExecContext(ctx, stmt.query, now.Add(-stmt.window).Unix())
+		if err != nil {
+			return pruned, fmt.Errorf("prune %s: %w", stmt.table, err)
+		}
+		if count, err := result.RowsAffected(); err == nil && count > 0 {
+			pruned[stmt.table] = count
+		}
+	}
+	return pruned, nil
+}
+
+// Checkpoint copies the WAL into the main database file and truncates it.
+func Checkpoint(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`)
+	return err
+}
+
+// Vacuum rebuilds the database file to return free pages to the filesystem.
+// It rewrites the whole file, so callers should only run it while idle.
+func Vacuum(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `VACUUM`)
+	return err
+}
+
+// TableStatus is the row count of one table.
+type TableStatus struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// Status describes the database file and its contents.
+type Status struct {
+	Path          string        `json:"path,omitempty"`
+	SchemaVersion int           `json:"schemaVersion"`
+	SizeBytes     int64         `json:"sizeBytes"`
+	WALBytes      int64         `json:"walBytes"`
+	PageSize      int64         `json:"pageSize"`
+	PageCount     int64         `json:"pageCount"`
+	FreePages     int64         `json:"freePages"`
+	Tables        []TableStatus `json:"tables"`
+}
+
+// Inspect reports file sizes, page usage and per-table row counts. path may
+// be empty or ":memory:", in which case file sizes are zero.
+func Inspect(ctx context.Context, db *sql.DB, path string) (Status, error) {
+	if db == nil {
+		return Status{}, errors.New("database handle is required")
+	}
+	status := Status{Path: path, Tables: []TableStatus{}}
+	version, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return Status{}, fmt.Errorf("read schema version: %w", err)
+	}
+	status.SchemaVersion = version
+	if path != "" && path != ":memory:" {
+		if info, err := os.Stat(path); err == nil {
+			status.SizeBytes = info.Size()
+		}
+		if info, err := os.Stat(path + "-wal"); err == nil {
+			status.WALBytes = info.Size()
+		}
+	}
+	for _, pragma := range []struct {
+		name   string
+		target *int64
+	}{
+		{"page_size", &status.PageSize},
+		{"page_count", &status.PageCount},
+		{"freelist_count", &status.FreePages},
+	} {
+		if err := db.QueryRowContext(ctx, "PRAGMA "+pragma.name).Scan(pragma.target); err != nil {
+			return Status{}, fmt.Errorf("read %s: %w", pragma.name, err)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return Status{}, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return Status{}, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Close(); err != nil {
+		return Status{}, err
+	}
+	for _, name := range names {
+		table := TableStatus{Name: name}
+		// Names come from sqlite_master, not user input.
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+name+`"`).Scan(&table.Rows); err != nil {
+			return Status{}, fmt.Errorf("count %s: %w", name, err)
+		}
+		status.Tables = append(status.Tables, table)
+	}
+	return status, nil
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneAppliesRetentionWindows(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Unix(1_700_000_000, 0)
+	day := 24 * time.Hour
+	old := now.Add(-40 * day).Unix()
+	recent := now.Add(-day).Unix()
+
+	if _, err := db.Exec(`
+		INSERT INTO prewarm_runs (id, started_at, finished_at) VALUES
+			(1, ?, ?), (2, ?, NULL), (3, ?, ?), (4, ?, ?)
+	`, old, old, old, old, old, recent, recent); err != nil {
+		t.Fatalf("seed prewarm_runs: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO prewarm_run_diffs (run_id, set_name, family, cidr, change)
+		VALUES (1, 'set', 'inet', '1.1.1.1/32', 'added')
+	`); err != nil {
+		t.Fatalf("seed prewarm_run_diffs: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO resolver_runs (id, started_at, finished_at) VALUES (1, ?, ?)
+	`, old, old); err != nil {
+		t.Fatalf("seed resolver_runs: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO vpn_events (vpn, type, at) VALUES ('wg', 'up', ?), ('wg', 'down', ?)
+	`, old, recent); err != nil {
+		t.Fatalf("seed vpn_events: %v", err)
+	}
+
+	pruned, err := Prune(context.Background(), db, Retention{Runs: 30 * day, Events: 30 * day}, now)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if pruned["prewarm_runs"] != 2 || pruned["vpn_events"] != 1 || pruned.Total() != 3 {
+		t.Fatalf("unexpected pruned counts: %v", pruned)
+	}
+
+	var ids []int64
+	rows, err := db.Query(`SELECT id FROM prewarm_runs ORDER BY id`)
+	if err != nil {
+		t.Fatalf("select prewarm_runs: %v", err)
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	// The unfinished run and the latest run survive.
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 4 {
+		t.Fatalf("unexpected remaining runs: %v", ids)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM prewarm_run_diffs`).Scan(&count); err != nil {
+		t.Fatalf("count diffs: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected diffs of pruned runs to cascade, got %d", count)
+	}
+	// The only resolver run is also the latest one.
+	if err := db.QueryRow(`SELECT COUNT(*) FROM resolver_runs`).Scan(&count); err != nil {
+		t.Fatalf("count resolver_runs: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected latest resolver run to remain, got %d", count)
+	}
+}
+
+func TestInspectReportsSizeAndRowCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO vpn_events (vpn, type, at) VALUES ('wg', 'up', 1), ('wg', 'down', 2)`); err != nil {
+		t.Fatalf("seed vpn_events: %v", err)
+	}
+	ctx := context.Background()
+	if err := Checkpoint(ctx, db); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	if err := Vacuum(ctx, db); err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+
+	status, err := Inspect(ctx, db, path)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if status.SizeBytes <= 0 || status.PageSize <= 0 || status.PageCount <= 0 {
+		t.Fatalf("expected file size and page stats, got %+v", status)
+	}
+	counts := make(map[string]int64, len(status.Tables))
+	for _, table := range status.Tables {
+		counts[table.Name] = table.Rows
+	}
+	if counts["vpn_events"] != 2 {
+		t.Fatalf("expected 2 vpn_events rows, got %v", counts)
+	}
+	if _, ok := counts["stats_history"]; !ok {
+		t.Fatalf("expected stats_history in table list, got %v", counts)
+	}
+}
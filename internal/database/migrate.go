@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations live in migrations/NNNN_description.sql and are applied in
+// version order, each in its own transaction together with its
+// schema_version row. Released migrations must never be edited; alter the
+// schema by adding the next numbered file.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const schemaVersionTable = `
+CREATE TABLE IF NOT EXISTS schema_version (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT    NOT NULL,
+    applied_at INTEGER NOT NULL
+);`
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// legacyColumns were added with ALTER TABLE before versioned migrations
+// existed. Databases from those releases may lack some of them, so the
+// baseline migration backfills them.
+var legacyColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"domain_groups", "dns_redirect", "TEXT NOT NULL DEFAULT ''"},
+	{"routing_rules", "exclude_multicast", "INTEGER NOT NULL DEFAULT 1"},
+	{"prewarm_runs", "prefixes_added", "INTEGER NOT NULL DEFAULT 0"},
+	{"prewarm_runs", "prefixes_removed", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// LatestSchemaVersion returns the newest schema version this build knows.
+func LatestSchemaVersion() int {
+	migrations, err := loadMigrations()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the schema version recorded in db, or 0 when no
+// migration has been applied.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// migrate applies every migration newer than the recorded schema version.
+// A database from a newer release is left untouched so a rolled-back binary
+// can still start; released migrations only add tables and columns.
+func migrate(db *sql.DB) error {
+	ctx := context.Background()
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, schemaVersionTable); err != nil {
+		return err
+	}
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("apply migration %04d_%s: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+	if m.version == 1 {
+		for _, col := range legacyColumns {
+			if err := ensureColumn(ctx, tx, col.table, col.column, col.definition); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, time.Now().Unix(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 || name == "" {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		body, err := fs.ReadFile(migrationFiles, path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{version: version, name: name, sql: string(body)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	for i, m := range migrations {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous from 1: found %04d at position %d", m.version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+func ensureColumn(ctx context.Context, tx *sql.Tx, tableName, columnName, definition string) error {
+	rows, err := tx.QueryContext(ctx, "PRAGMA table_info("+tableName+")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return err
+		}
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(columnName)) {
+			return rows.Err()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "ALTER TABLE "+tableName+" ADD COLUMN "+columnName+" "+definition)
+	return err
+}
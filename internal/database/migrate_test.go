@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate_RecordsSchemaVersion(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	version, err := SchemaVersion(context.Background(), db)
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version != LatestSchemaVersion() || version < 1 {
+		t.Fatalf("expected schema version %d, got %d", LatestSchemaVersion(), version)
+	}
+	if err := migrate(db); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&rows); err != nil {
+		t.Fatalf("count schema_version: %v", err)
+	}
+	if rows != version {
+		t.Fatalf("expected one schema_version row per migration, got %d", rows)
+	}
+}
+
+func TestMigrate_AdoptsLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open legacy: %v", err)
+	}
+	// A pre-migration release: prewarm_runs without the diff counters and
+	// no schema_version table.
+	if _, err := legacy.Exec(`
+		CREATE TABLE prewarm_runs (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			started_at   INTEGER NOT NULL,
+			finished_at  INTEGER,
+			duration_ms  INTEGER,
+			domains_total INTEGER NOT NULL DEFAULT 0,
+			domains_done INTEGER NOT NULL DEFAULT 0,
+			ips_inserted INTEGER NOT NULL DEFAULT 0,
+			error        TEXT
+		);
+		INSERT INTO prewarm_runs (started_at) VALUES (1);
+	`); err != nil {
+		t.Fatalf("seed legacy schema: %v", err)
+	}
+	legacy.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	var added, removed int
+	if err := db.QueryRow(`SELECT prefixes_added, prefixes_removed FROM prewarm_runs`).Scan(&added, &removed); err != nil {
+		t.Fatalf("legacy columns not backfilled: %v", err)
+	}
+	var name string
+	if err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type='table' AND name='vpn_events'`).Scan(&name); err != nil {
+		t.Fatalf("baseline tables missing after adoption: %v", err)
+	}
+	version, err := SchemaVersion(context.Background(), db)
+	if err != nil || version != LatestSchemaVersion() {
+		t.Fatalf("expected schema version %d, got %d err=%v", LatestSchemaVersion(), version, err)
+	}
+}
+
+func TestMigrate_LeavesNewerSchemaAlone(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	future := LatestSchemaVersion() + 1
+	if _, err := db.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, 'future', 0)`, future); err != nil {
+		t.Fatalf("insert future version: %v", err)
+	}
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate with newer schema: %v", err)
+	}
+	version, err := SchemaVersion(context.Background(), db)
+	if err != nil || version != future {
+		t.Fatalf("expected schema version %d, got %d err=%v", future, version, err)
+	}
+}
+
+func TestLoadMigrations_Contiguous(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for i, m := range migrations {
+		if m.version != i+1 || m.name == "" || m.sql == "" {
+			t.Fatalf("unexpected migration at %d: %+v", i, m)
+		}
+	}
+}
@@ -1,7 +1,5 @@
-package database
-
-// schema contains all table definitions. Each statement is idempotent (CREATE IF NOT EXISTS).
-const schema = `
+-- Baseline schema. Statements are idempotent so databases created before
+-- versioned migrations adopt it without changes.
 CREATE TABLE IF NOT EXISTS stats_history (
     id        INTEGER PRIMARY KEY AUTOINCREMENT,
     interface TEXT    NOT NULL,
@@ -16,6 +14,7 @@ CREATE TABLE IF NOT EXISTS domain_groups (
     id         INTEGER PRIMARY KEY AUTOINCREMENT,
     name       TEXT    NOT NULL UNIQUE,
     egress_vpn TEXT    NOT NULL DEFAULT '',
+    dns_redirect TEXT  NOT NULL DEFAULT '',
     created_at INTEGER NOT NULL DEFAULT (strftime('%s','now')),
     updated_at INTEGER NOT NULL DEFAULT (strftime('%s','now'))
 );
@@ -75,6 +74,38 @@ CREATE TABLE IF NOT EXISTS routing_rule_source_macs (
 CREATE INDEX IF NOT EXISTS idx_routing_rule_source_macs_rule
     ON routing_rule_source_macs (rule_id);
 
+CREATE TABLE IF NOT EXISTS routing_rule_source_device_groups (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id      INTEGER NOT NULL REFERENCES routing_rules(id) ON DELETE CASCADE,
+    device_group TEXT    NOT NULL,
+    UNIQUE(rule_id, device_group)
+);
+CREATE INDEX IF NOT EXISTS idx_routing_rule_source_device_groups_rule
+    ON routing_rule_source_device_groups (rule_id);
+
+CREATE TABLE IF NOT EXISTS device_groups (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT    NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s','now')),
+    updated_at INTEGER NOT NULL DEFAULT (strftime('%s','now'))
+);
+
+CREATE TABLE IF NOT EXISTS device_group_members (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id INTEGER NOT NULL REFERENCES device_groups(id) ON DELETE CASCADE,
+    kind     TEXT    NOT NULL,
+    value    TEXT    NOT NULL,
+    UNIQUE(group_id, kind, value)
+);
+CREATE INDEX IF NOT EXISTS idx_device_group_members_group
+    ON device_group_members (group_id, kind);
+
+CREATE TABLE IF NOT EXISTS device_aliases (
+    mac        TEXT    PRIMARY KEY,
+    name       TEXT    NOT NULL,
+    updated_at INTEGER NOT NULL DEFAULT (strftime('%s','now'))
+);
+
 CREATE TABLE IF NOT EXISTS routing_rule_destination_cidrs (
     id      INTEGER PRIMARY KEY AUTOINCREMENT,
     rule_id INTEGER NOT NULL REFERENCES routing_rules(id) ON DELETE CASCADE,
@@ -184,7 +215,9 @@ CREATE TABLE IF NOT EXISTS prewarm_runs (
     domains_total    INTEGER NOT NULL DEFAULT 0,
     domains_done     INTEGER NOT NULL DEFAULT 0,
     ips_inserted     INTEGER NOT NULL DEFAULT 0,
-    error            TEXT
+    error            TEXT,
+    prefixes_added   INTEGER NOT NULL DEFAULT 0,
+    prefixes_removed INTEGER NOT NULL DEFAULT 0
 );
 
 CREATE TABLE IF NOT EXISTS prewarm_cache (
@@ -197,4 +230,25 @@ CREATE TABLE IF NOT EXISTS prewarm_cache (
 );
 CREATE INDEX IF NOT EXISTS idx_prewarm_cache_set
     ON prewarm_cache (set_name, family);
-`
+
+CREATE TABLE IF NOT EXISTS prewarm_run_diffs (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id   INTEGER NOT NULL REFERENCES prewarm_runs(id) ON DELETE CASCADE,
+    set_name TEXT    NOT NULL,
+    family   TEXT    NOT NULL,
+    cidr     TEXT    NOT NULL,
+    change   TEXT    NOT NULL,
+    UNIQUE(run_id, set_name, family, cidr, change)
+);
+CREATE INDEX IF NOT EXISTS idx_prewarm_run_diffs_run
+    ON prewarm_run_diffs (run_id, set_name);
+
+CREATE TABLE IF NOT EXISTS vpn_events (
+    id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    vpn    TEXT    NOT NULL,
+    type   TEXT    NOT NULL,
+    detail TEXT    NOT NULL DEFAULT '',
+    at     INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_vpn_events_vpn_at
+    ON vpn_events (vpn, at);
@@ -0,0 +1,12 @@
+-- Config revision history: every saved WireGuard/OpenVPN config per profile,
+-- with who made the change and how.
+CREATE TABLE IF NOT EXISTS vpn_revisions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    vpn        TEXT    NOT NULL,
+    action     TEXT    NOT NULL,
+    actor      TEXT    NOT NULL DEFAULT '',
+    content    TEXT    NOT NULL,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_vpn_revisions_vpn_id
+    ON vpn_revisions (vpn, id);
@@ -0,0 +1,3 @@
+-- Optional per-rule bandwidth limits in kbit/s; 0 means unlimited.
+ALTER TABLE routing_rules ADD COLUMN upload_limit_kbit INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE routing_rules ADD COLUMN download_limit_kbit INTEGER NOT NULL DEFAULT 0;
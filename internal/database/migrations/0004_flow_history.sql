@@ -0,0 +1,22 @@
+-- Flow inspector history: one row per conntrack flow seen on a VPN, with its
+-- latest byte counters. Rows form a per-VPN ring buffer pruned by age and
+-- count, so short-lived connections can still be reviewed after they close.
+CREATE TABLE IF NOT EXISTS flow_history (
+    id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+    vpn                TEXT    NOT NULL,
+    flow_key           TEXT    NOT NULL,
+    protocol           TEXT    NOT NULL DEFAULT '',
+    source_ip          TEXT    NOT NULL DEFAULT '',
+    source_mac         TEXT    NOT NULL DEFAULT '',
+    source_device      TEXT    NOT NULL DEFAULT '',
+    destination_ip     TEXT    NOT NULL DEFAULT '',
+    destination_port   INTEGER NOT NULL DEFAULT 0,
+    destination_domain TEXT    NOT NULL DEFAULT '',
+    upload_bytes       INTEGER NOT NULL DEFAULT 0,
+    download_bytes     INTEGER NOT NULL DEFAULT 0,
+    first_seen         INTEGER NOT NULL,
+    last_seen          INTEGER NOT NULL,
+    UNIQUE (vpn, flow_key)
+);
+CREATE INDEX IF NOT EXISTS idx_flow_history_vpn_last_seen
+    ON flow_history (vpn, last_seen);
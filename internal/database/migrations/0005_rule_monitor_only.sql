@@ -0,0 +1,2 @@
+-- Monitor-only rules log the traffic they would capture instead of marking it.
+ALTER TABLE routing_rules ADD COLUMN monitor_only INTEGER NOT NULL DEFAULT 0;
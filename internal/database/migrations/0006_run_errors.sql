@@ -0,0 +1,41 @@
+-- Per-run query errors for resolver and pre-warm runs. Each run keeps a
+-- bounded sample of individual failures plus exact per-provider totals, so
+-- run history can break errors down even when the sample was truncated.
+-- Rows follow their run through ON DELETE CASCADE.
+CREATE TABLE IF NOT EXISTS prewarm_run_errors (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id    INTEGER NOT NULL REFERENCES prewarm_runs(id) ON DELETE CASCADE,
+    at        INTEGER NOT NULL,
+    stage     TEXT    NOT NULL DEFAULT '',
+    provider  TEXT    NOT NULL DEFAULT '',
+    domain    TEXT    NOT NULL DEFAULT '',
+    interface TEXT    NOT NULL DEFAULT '',
+    error     TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_prewarm_run_errors_run
+    ON prewarm_run_errors (run_id);
+
+CREATE TABLE IF NOT EXISTS prewarm_run_error_totals (
+    run_id   INTEGER NOT NULL REFERENCES prewarm_runs(id) ON DELETE CASCADE,
+    provider TEXT    NOT NULL,
+    count    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (run_id, provider)
+);
+
+CREATE TABLE IF NOT EXISTS resolver_run_errors (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id   INTEGER NOT NULL REFERENCES resolver_runs(id) ON DELETE CASCADE,
+    at       INTEGER NOT NULL,
+    provider TEXT    NOT NULL DEFAULT '',
+    selector TEXT    NOT NULL DEFAULT '',
+    error    TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_resolver_run_errors_run
+    ON resolver_run_errors (run_id);
+
+CREATE TABLE IF NOT EXISTS resolver_run_error_totals (
+    run_id   INTEGER NOT NULL REFERENCES resolver_runs(id) ON DELETE CASCADE,
+    provider TEXT    NOT NULL,
+    count    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (run_id, provider)
+);
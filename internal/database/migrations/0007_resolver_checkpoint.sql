@@ -0,0 +1,10 @@
+-- Selectors completed by the current or last interrupted resolver run. A
+-- fresh run clears the table, every flushed batch of resolved selectors is
+-- recorded after its snapshot upsert, and a run that finishes without errors
+-- clears it again, so rows only remain when there is work left to resume.
+CREATE TABLE IF NOT EXISTS resolver_checkpoint (
+    selector_type TEXT    NOT NULL,
+    selector_key  TEXT    NOT NULL,
+    completed_at  INTEGER NOT NULL,
+    PRIMARY KEY (selector_type, selector_key)
+);
@@ -0,0 +1,3 @@
+-- Resolver runs no longer fail as a whole when single selectors fail; the
+-- run row records how many selectors failed instead.
+ALTER TABLE resolver_runs ADD COLUMN selectors_failed INTEGER NOT NULL DEFAULT 0;
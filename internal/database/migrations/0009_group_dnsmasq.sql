@@ -0,0 +1,5 @@
+-- Per-group dnsmasq options: an isolated conf fragment, upstream DNS servers
+-- for the group's domains (comma-separated) and an ipset timeout override.
+ALTER TABLE domain_groups ADD COLUMN dnsmasq_isolated INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE domain_groups ADD COLUMN dnsmasq_upstreams TEXT NOT NULL DEFAULT '';
+ALTER TABLE domain_groups ADD COLUMN ipset_timeout_seconds INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+-- Static host mappings: addresses pinned to a rule's domain selector and
+-- merged into its destination sets regardless of resolver results.
+CREATE TABLE IF NOT EXISTS routing_rule_static_hosts (
+    id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id INTEGER NOT NULL REFERENCES routing_rules(id) ON DELETE CASCADE,
+    domain  TEXT    NOT NULL,
+    cidr    TEXT    NOT NULL,
+    UNIQUE(rule_id, domain, cidr)
+);
+CREATE INDEX IF NOT EXISTS idx_routing_rule_static_hosts_rule
+    ON routing_rule_static_hosts (rule_id);
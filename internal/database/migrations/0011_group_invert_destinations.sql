@@ -0,0 +1,3 @@
+-- Inverted groups route everything from a rule's sources except its
+-- destination selectors.
+ALTER TABLE domain_groups ADD COLUMN invert_destinations INTEGER NOT NULL DEFAULT 0;
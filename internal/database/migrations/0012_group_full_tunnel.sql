@@ -0,0 +1,3 @@
+-- Full tunnel groups route all traffic from their sources and rank below
+-- destination-based groups.
+ALTER TABLE domain_groups ADD COLUMN full_tunnel INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Group priority: among groups claiming the same packet, the higher value
+-- wins. Equal priorities fall back to the group name.
+ALTER TABLE domain_groups ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
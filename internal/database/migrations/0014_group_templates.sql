@@ -0,0 +1,10 @@
+-- Group templates: reusable group definitions (rules and options, without a
+-- name or egress VPN) stored as JSON and instantiated into new groups.
+CREATE TABLE IF NOT EXISTS group_templates (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        TEXT    NOT NULL UNIQUE,
+    description TEXT    NOT NULL DEFAULT '',
+    definition  TEXT    NOT NULL,
+    created_at  INTEGER NOT NULL DEFAULT (strftime('%s','now')),
+    updated_at  INTEGER NOT NULL DEFAULT (strftime('%s','now'))
+);
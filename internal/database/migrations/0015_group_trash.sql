@@ -0,0 +1,12 @@
+-- Recycle bin for deleted domain groups. The full group definition is kept
+-- as JSON until it is restored, removed or pruned past the trash retention.
+CREATE TABLE IF NOT EXISTS group_trash (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id    INTEGER NOT NULL,
+    name        TEXT    NOT NULL,
+    egress_vpn  TEXT    NOT NULL DEFAULT '',
+    definition  TEXT    NOT NULL,
+    deleted_at  INTEGER NOT NULL DEFAULT (strftime('%s','now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_trash_deleted_at ON group_trash(deleted_at);
@@ -0,0 +1,9 @@
+-- Change staging: while a row exists, group edits are saved but runtime
+-- routing keeps following the published group set stored here as JSON until
+-- the pending changes are published.
+CREATE TABLE IF NOT EXISTS routing_staging (
+    id           INTEGER PRIMARY KEY CHECK (id = 1),
+    published    TEXT    NOT NULL,
+    enabled_at   INTEGER NOT NULL DEFAULT (strftime('%s','now')),
+    published_at INTEGER NOT NULL DEFAULT (strftime('%s','now'))
+);
@@ -0,0 +1,23 @@
+-- Monthly data quotas per VPN, and the transfer counted against them. Usage
+-- is kept per billing period so past months stay visible; a period starts on
+-- the quota's reset day at local midnight.
+CREATE TABLE IF NOT EXISTS vpn_quotas (
+    vpn           TEXT    PRIMARY KEY,
+    limit_bytes   INTEGER NOT NULL,
+    warn_percents TEXT    NOT NULL DEFAULT '',
+    reset_day     INTEGER NOT NULL DEFAULT 1,
+    action        TEXT    NOT NULL DEFAULT 'none',
+    failover_vpn  TEXT    NOT NULL DEFAULT '',
+    updated_at    INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS vpn_usage (
+    vpn            TEXT    NOT NULL,
+    period_start   INTEGER NOT NULL,
+    rx_bytes       INTEGER NOT NULL DEFAULT 0,
+    tx_bytes       INTEGER NOT NULL DEFAULT 0,
+    warned_percent INTEGER NOT NULL DEFAULT 0,
+    exceeded_at    INTEGER NOT NULL DEFAULT 0,
+    updated_at     INTEGER NOT NULL,
+    PRIMARY KEY (vpn, period_start)
+);
@@ -0,0 +1,14 @@
+-- Remote devices whose routing state this instance converges over SSH. The
+-- host key is the pinned SHA256 fingerprint of the device's SSH host key.
+CREATE TABLE IF NOT EXISTS remote_agents (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        TEXT    NOT NULL UNIQUE,
+    host        TEXT    NOT NULL,
+    port        INTEGER NOT NULL DEFAULT 22,
+    user        TEXT    NOT NULL DEFAULT 'root',
+    host_key    TEXT    NOT NULL,
+    dnsmasq_dir TEXT    NOT NULL,
+    enabled     INTEGER NOT NULL DEFAULT 1,
+    created_at  INTEGER NOT NULL,
+    updated_at  INTEGER NOT NULL
+);
@@ -0,0 +1,4 @@
+-- Kill switch groups drop their clients' traffic while the egress VPN is
+-- down; managed groups are generated by a feature such as guest safe mode.
+ALTER TABLE domain_groups ADD COLUMN kill_switch INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE domain_groups ADD COLUMN managed TEXT NOT NULL DEFAULT '';
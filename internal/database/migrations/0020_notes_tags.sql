@@ -0,0 +1,5 @@
+-- Free-text notes and tags (comma-separated) documenting groups and rules.
+ALTER TABLE domain_groups ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+ALTER TABLE domain_groups ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+ALTER TABLE routing_rules ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+ALTER TABLE routing_rules ADD COLUMN tags TEXT NOT NULL DEFAULT '';
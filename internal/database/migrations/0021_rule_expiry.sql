@@ -0,0 +1,2 @@
+-- Unix time after which a rule stops routing; 0 never expires.
+ALTER TABLE routing_rules ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Settings revision history: every saved settings file, with who made the
+-- change, how, and which fields it touched.
+CREATE TABLE IF NOT EXISTS settings_revisions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    action     TEXT    NOT NULL,
+    actor      TEXT    NOT NULL DEFAULT '',
+    fields     TEXT    NOT NULL DEFAULT '',
+    content    TEXT    NOT NULL,
+    created_at INTEGER NOT NULL
+);
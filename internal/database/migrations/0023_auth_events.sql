@@ -0,0 +1,9 @@
+-- Authentication audit trail: failed and successful logins, lockouts and
+-- rejected API tokens, with the client address they came from.
+CREATE TABLE IF NOT EXISTS auth_events (
+    id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    type   TEXT    NOT NULL,
+    remote TEXT    NOT NULL DEFAULT '',
+    detail TEXT    NOT NULL DEFAULT '',
+    at     INTEGER NOT NULL
+);
@@ -0,0 +1,12 @@
+-- Browser sessions created at login. Only a hash of the cookie token is
+-- stored; revoking a session deletes its row.
+CREATE TABLE IF NOT EXISTS sessions (
+    id         TEXT    PRIMARY KEY,
+    token_hash TEXT    NOT NULL UNIQUE,
+    csrf_token TEXT    NOT NULL,
+    user_agent TEXT    NOT NULL DEFAULT '',
+    remote     TEXT    NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    last_seen  INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL
+);
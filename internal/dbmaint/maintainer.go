@@ -0,0 +1,266 @@
+// Package dbmaint keeps the SQLite database bounded: it prunes history past
+// the configured retention windows and, while the app is idle, checkpoints
+// the WAL and vacuums away free pages.
+package dbmaint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/settings"
+)
+
+const (
+	pollInterval   = 15 * time.Minute
+	vacuumInterval = 24 * time.Hour
+	// vacuumFreeRatio is the share of free pages that makes a VACUUM worth
+	// rewriting the whole file.
+	vacuumFreeRatio = 0.2
+
+	// MaxRetentionDays bounds the configurable retention windows.
+	MaxRetentionDays = 3650
+)
+
+// Result is the outcome of one maintenance pass.
+type Result struct {
+	At           time.Time       `json:"at"`
+	Pruned       database.Pruned `json:"pruned,omitempty"`
+	Checkpointed bool            `json:"checkpointed"`
+	Vacuumed     bool            `json:"vacuumed"`
+	// Deferred is set when checkpoint and vacuum were skipped because a
+	// resolver or pre-warm run was busy with the database.
+	Deferred bool   `json:"deferred,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Status reports the effective retention and the latest maintenance passes.
+type Status struct {
+	StatsRetentionDays int        `json:"statsRetentionDays"`
+	RunRetentionDays   int        `json:"runRetentionDays"`
+	EventRetentionDays int        `json:"eventRetentionDays"`
+	TrashRetentionDays int        `json:"trashRetentionDays"`
+	LastRun            *Result    `json:"lastRun,omitempty"`
+	LastVacuum         *time.Time `json:"lastVacuum,omitempty"`
+}
+
+// Maintainer runs database maintenance periodically.
+type Maintainer struct {
+	db       *sql.DB
+	path     string
+	settings *settings.Manager
+	now      func() time.Time
+
+	mu         sync.Mutex
+	started    bool
+	idle       func() bool
+	handler    func(Result)
+	lastRun    *Result
+	lastVacuum time.Time
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewMaintainer creates a maintainer for the database file at path.
+func NewMaintainer(db *sql.DB, path string, settingsManager *settings.Manager) (*Maintainer, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	if settingsManager == nil {
+		return nil, fmt.Errorf("settings manager is required")
+	}
+	return &Maintainer{
+		db:       db,
+		path:     path,
+		settings: settingsManager,
+		now:      time.Now,
+		idle:     func() bool { return true },
+	}, nil
+}
+
+// RetentionFromSettings converts the configured retention days, falling back
+// to the defaults for unset values.
+func RetentionFromSettings(current settings.Settings) database.Retention {
+	retention := database.DefaultRetention()
+	if days := clampDays(current.StatsRetentionDays); days > 0 {
+		retention.Stats = time.Duration(days) * 24 * time.Hour
+	}
+	if days := clampDays(current.RunRetentionDays); days > 0 {
+		retention.Runs = time.Duration(days) * 24 * time.Hour
+	}
+	if days := clampDays(current.EventRetentionDays); days > 0 {
+		retention.Events = time.Duration(days) * 24 * time.Hour
+	}
+	if days := clampDays(current.TrashRetentionDays); days > 0 {
+		retention.Trash = time.Duration(days) * 24 * time.Hour
+	}
+	return retention
+}
+
+func clampDays(days int) int {
+	if days > MaxRetentionDays {
+		return MaxRetentionDays
+	}
+	return days
+}
+
+// SetIdleCheck registers the function deciding whether heavy maintenance may
+// run now.
+func (m *Maintainer) SetIdleCheck(idle func() bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if idle == nil {
+		idle = func() bool { return true }
+	}
+	m.idle = idle
+}
+
+// SetHandler registers a callback invoked after every maintenance pass.
+func (m *Maintainer) SetHandler(handler func(Result)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// Start launches the periodic maintenance loop.
+func (m *Maintainer) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.started = true
+	m.loopCancel = cancel
+	m.mu.Unlock()
+
+	m.loopWG.Add(1)
+	go func() {
+		defer m.loopWG.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, _ = m.Poll(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the maintenance loop.
+func (m *Maintainer) Stop() error {
+	m.mu.Lock()
+	loopCancel := m.loopCancel
+	m.started = false
+	m.loopCancel = nil
+	m.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	m.loopWG.Wait()
+	return nil
+}
+
+// Poll runs one maintenance pass. Pruning always runs; the WAL checkpoint
+// and VACUUM wait until the idle check passes.
+func (m *Maintainer) Poll(ctx context.Context) (Result, error) {
+	result, err := m.run(ctx)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	m.mu.Lock()
+	m.lastRun = &result
+	if result.Vacuumed {
+		m.lastVacuum = result.At
+	}
+	handler := m.handler
+	m.mu.Unlock()
+	if handler != nil {
+		handler(result)
+	}
+	return result, err
+}
+
+func (m *Maintainer) run(ctx context.Context) (Result, error) {
+	now := m.now()
+	result := Result{At: now}
+	current, err := m.settings.Get()
+	if err != nil {
+		return result, fmt.Errorf("load settings: %w", err)
+	}
+	pruned, err := database.Prune(ctx, m.db, RetentionFromSettings(current), now)
+	result.Pruned = pruned
+	if err != nil {
+		return result, err
+	}
+
+	m.mu.Lock()
+	idle := m.idle
+	lastVacuum := m.lastVacuum
+	m.mu.Unlock()
+	if !idle() {
+		result.Deferred = true
+		return result, nil
+	}
+	if err := database.Checkpoint(ctx, m.db); err != nil {
+		return result, fmt.Errorf("checkpoint: %w", err)
+	}
+	result.Checkpointed = true
+
+	if now.Sub(lastVacuum) < vacuumInterval {
+		return result, nil
+	}
+	status, err := database.Inspect(ctx, m.db, m.path)
+	if err != nil {
+		return result, err
+	}
+	if status.PageCount == 0 || float64(status.FreePages)/float64(status.PageCount) < vacuumFreeRatio {
+		return result, nil
+	}
+	if err := database.Vacuum(ctx, m.db); err != nil {
+		return result, fmt.Errorf("vacuum: %w", err)
+	}
+	result.Vacuumed = true
+	return result, nil
+}
+
+// Status returns the effective retention and the latest maintenance passes.
+func (m *Maintainer) Status() Status {
+	current, _ := m.settings.Get()
+	retention := RetentionFromSettings(current)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := Status{
+		StatsRetentionDays: int(retention.Stats / (24 * time.Hour)),
+		RunRetentionDays:   int(retention.Runs / (24 * time.Hour)),
+		EventRetentionDays: int(retention.Events / (24 * time.Hour)),
+		TrashRetentionDays: int(retention.Trash / (24 * time.Hour)),
+	}
+	if !m.lastVacuum.IsZero() {
+		lastVacuum := m.lastVacuum
+		status.LastVacuum = &lastVacuum
+	}
+	if m.lastRun != nil {
+		last := *m.lastRun
+		status.LastRun = &last
+	}
+	return status
+}
+
+// SchemaVersion returns the schema version applied to the database.
+func (m *Maintainer) SchemaVersion(ctx context.Context) (int, error) {
+	return database.SchemaVersion(ctx, m.db)
+}
+
+// Inspect reports the current database file size and row counts.
+func (m *Maintainer) Inspect(ctx context.Context) (database.Status, error) {
+	return database.Inspect(ctx, m.db, m.path)
+}
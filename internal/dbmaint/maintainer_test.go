@@ -0,0 +1,85 @@
+package dbmaint
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/settings"
+)
+
+func newTestMaintainer(t *testing.T, current settings.Settings) *Maintainer {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stats.db")
+	db, err := database.Open(path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	manager := settings.NewManager(filepath.Join(dir, "settings.json"))
+	if err := manager.Save(current); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	maintainer, err := NewMaintainer(db, path, manager)
+	if err != nil {
+		t.Fatalf("new maintainer: %v", err)
+	}
+	return maintainer
+}
+
+func TestRetentionFromSettings(t *testing.T) {
+	defaults := database.DefaultRetention()
+	if got := RetentionFromSettings(settings.Settings{}); got != defaults {
+		t.Fatalf("expected defaults %+v, got %+v", defaults, got)
+	}
+	got := RetentionFromSettings(settings.Settings{StatsRetentionDays: 2, EventRetentionDays: MaxRetentionDays + 1})
+	if got.Stats != 48*time.Hour || got.Runs != defaults.Runs || got.Events != MaxRetentionDays*24*time.Hour {
+		t.Fatalf("unexpected retention %+v", got)
+	}
+}
+
+func TestPollPrunesAndDefersHeavyWorkWhileBusy(t *testing.T) {
+	maintainer := newTestMaintainer(t, settings.Settings{StatsRetentionDays: 1})
+	now := time.Unix(1_700_000_000, 0)
+	maintainer.now = func() time.Time { return now }
+	if _, err := maintainer.db.Exec(`
+		INSERT INTO stats_history (interface, timestamp, rx_bytes, tx_bytes)
+		VALUES ('WAN', ?, 1, 1), ('WAN', ?, 1, 1)
+	`, now.Add(-48*time.Hour).Unix(), now.Add(-time.Hour).Unix()); err != nil {
+		t.Fatalf("seed stats_history: %v", err)
+	}
+	maintainer.SetIdleCheck(func() bool { return false })
+	var handled []Result
+	maintainer.SetHandler(func(result Result) { handled = append(handled, result) })
+
+	result, err := maintainer.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if result.Pruned["stats_history"] != 1 {
+		t.Fatalf("expected 1 pruned stats row, got %v", result.Pruned)
+	}
+	if !result.Deferred || result.Checkpointed || result.Vacuumed {
+		t.Fatalf("expected deferred checkpoint while busy, got %+v", result)
+	}
+
+	maintainer.SetIdleCheck(func() bool { return true })
+	result, err = maintainer.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if result.Deferred || !result.Checkpointed {
+		t.Fatalf("expected checkpoint while idle, got %+v", result)
+	}
+	if len(handled) != 2 {
+		t.Fatalf("expected handler per poll, got %d", len(handled))
+	}
+
+	status := maintainer.Status()
+	if status.StatsRetentionDays != 1 || status.RunRetentionDays != 30 || status.LastRun == nil {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
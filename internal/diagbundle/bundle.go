@@ -0,0 +1,204 @@
+// Package diagbundle assembles a gzipped tarball of diagnostics for bug
+// reports: app state supplied by the caller plus the output of system
+// commands such as iptables-save and ipset list. Command failures never
+// abort a bundle; they are recorded in its manifest instead.
+package diagbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// CommandTimeout bounds each command in a bundle.
+	CommandTimeout = 15 * time.Second
+	// MaxFileBytes caps one file in a bundle; larger output is truncated.
+	MaxFileBytes = 16 << 20
+
+	manifestName = "manifest.json"
+	truncatedTag = "\n... truncated ...\n"
+)
+
+// Command is one system command whose output becomes a bundle file.
+type Command struct {
+	File string
+	Name string
+	Args []string
+}
+
+// DefaultCommands capture routing, firewall and service state. `wg show`
+// hides private keys; the journal is redacted like every other file.
+var DefaultCommands = []Command{
+	{File: "system/uname.txt", Name: "uname", Args: []string{"-a"}},
+	{File: "system/ip-addr.txt", Name: "ip", Args: []string{"addr", "show"}},
+	{File: "system/ip-rule.txt", Name: "ip", Args: []string{"rule", "show"}},
+	{File: "system/ip6-rule.txt", Name: "ip", Args: []string{"-6", "rule", "show"}},
+	{File: "system/ip-route.txt", Name: "ip", Args: []string{"route", "show", "table", "all"}},
+	{File: "system/ip6-route.txt", Name: "ip", Args: []string{"-6", "route", "show", "table", "all"}},
+	{File: "system/iptables-save.txt", Name: "iptables-save"},
+	{File: "system/ip6tables-save.txt", Name: "ip6tables-save"},
+	{File: "system/ipset-list.txt", Name: "ipset", Args: []string{"list"}},
+	{File: "system/wg-show.txt", Name: "wg", Args: []string{"show", "all"}},
+	{File: "logs/journal.txt", Name: "journalctl", Args: []string{"-u", "split-vpn-webui.service", "-n", "2000", "--no-pager", "-o", "short-iso"}},
+}
+
+// Runner runs one command and returns its combined output.
+type Runner interface {
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+type execRunner struct{}
+
+func (execRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// Entry records one file of a bundle in its manifest.
+type Entry struct {
+	File      string `json:"file"`
+	Command   string `json:"command,omitempty"`
+	Bytes     int    `json:"bytes"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Writer streams a bundle to an underlying writer.
+type Writer struct {
+	runner  Runner
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	now     time.Time
+	entries []Entry
+}
+
+// NewWriter starts a bundle on dst using the system's commands.
+func NewWriter(dst io.Writer) *Writer {
+	return NewWriterWithRunner(dst, execRunner{})
+}
+
+// NewWriterWithRunner starts a bundle with a custom runner, for tests.
+func NewWriterWithRunner(dst io.Writer, runner Runner) *Writer {
+	gz := gzip.NewWriter(dst)
+	return &Writer{
+		runner: runner,
+		gz:     gz,
+		tw:     tar.NewWriter(gz),
+		now:    time.Now().UTC(),
+	}
+}
+
+// AddFile adds data as name after redacting secrets.
+func (w *Writer) AddFile(name string, data []byte) error {
+	entry := Entry{File: name}
+	return w.add(&entry, data)
+}
+
+// AddJSON adds v encoded as indented JSON.
+func (w *Writer) AddJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", name, err)
+	}
+	return w.AddFile(name, append(data, '\n'))
+}
+
+// AddError records a file that could not be produced.
+func (w *Writer) AddError(name string, cause error) {
+	w.entries = append(w.entries, Entry{File: name, Error: cause.Error()})
+}
+
+// AddCommand runs cmd and adds its output. A failing command still adds
+// whatever it printed, and its error is recorded in the manifest.
+func (w *Writer) AddCommand(ctx context.Context, cmd Command) error {
+	runCtx, cancel := context.WithTimeout(ctx, CommandTimeout)
+	defer cancel()
+	output, runErr := w.runner.Output(runCtx, cmd.Name, cmd.Args...)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	entry := Entry{File: cmd.File, Command: strings.TrimSpace(cmd.Name + " " + strings.Join(cmd.Args, " "))}
+	if runErr != nil {
+		entry.Error = runErr.Error()
+		if errors.Is(runErr, exec.ErrNotFound) && len(output) == 0 {
+			w.entries = append(w.entries, entry)
+			return nil
+		}
+	}
+	return w.add(&entry, output)
+}
+
+// Close writes the manifest and finishes the archive.
+func (w *Writer) Close() error {
+	manifest := map[string]any{
+		"generatedAt": w.now,
+		"files":       w.entries,
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.writeEntry(manifestName, append(data, '\n')); err != nil {
+		return err
+	}
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+func (w *Writer) add(entry *Entry, data []byte) error {
+	data = Redact(data)
+	if len(data) > MaxFileBytes {
+		data = append(data[:MaxFileBytes:MaxFileBytes], truncatedTag...)
+		entry.Truncated = true
+	}
+	entry.Bytes = len(data)
+	if err := w.writeEntry(entry.File, data); err != nil {
+		return err
+	}
+	w.entries = append(w.entries, *entry)
+	return nil
+}
+
+func (w *Writer) writeEntry(name string, data []byte) error {
+	header := &tar.Header{
+		Name:    "split-vpn-webui-diagnostics/" + name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: w.now,
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// secretPattern matches "key = value" and "key: value" lines whose key
+// names a credential, as found in WireGuard and OpenVPN configs, settings
+// and log messages.
+var secretPattern = regexp.MustCompile(`(?im)\b(private[ _-]?key|preshared[ _-]?key|password|passwd|secret|token|api[ _-]?key|auth[ _-]?hash)(["']?\s*[:=]\s*)("[^"\n]*"|\S+)`)
+
+// Redact replaces credential values in data with a placeholder, keeping
+// quoted values quoted so JSON stays valid.
+func Redact(data []byte) []byte {
+	return secretPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		parts := secretPattern.FindSubmatch(match)
+		value := "<redacted>"
+		if len(parts[3]) > 0 && parts[3][0] == '"' {
+			value = `"<redacted>"`
+		}
+		out := append([]byte(nil), parts[1]...)
+		out = append(out, parts[2]...)
+		return append(out, value...)
+	})
+}
@@ -0,0 +1,107 @@
+package diagbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type fakeRunner struct {
+	outputs map[string]string
+	errs    map[string]error
+}
+
+func (f fakeRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	key := strings.TrimSpace(name + " " + strings.Join(args, " "))
+	return []byte(f.outputs[key]), f.errs[key]
+}
+
+func readBundle(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		files[strings.TrimPrefix(header.Name, "split-vpn-webui-diagnostics/")] = string(body)
+	}
+	return files
+}
+
+func TestWriterRecordsCommandsAndFailures(t *testing.T) {
+	var buf bytes.Buffer
+	runner := fakeRunner{
+		outputs: map[string]string{
+			"iptables-save": "*mangle\n-A PREROUTING -j SVPN_MARK\nCOMMIT\n",
+			"ipset list":    "ipset v7: kernel error\n",
+		},
+		errs: map[string]error{"ipset list": errors.New("exit status 1")},
+	}
+	w := NewWriterWithRunner(&buf, runner)
+	ctx := context.Background()
+	if err := w.AddCommand(ctx, Command{File: "system/iptables-save.txt", Name: "iptables-save"}); err != nil {
+		t.Fatalf("AddCommand: %v", err)
+	}
+	if err := w.AddCommand(ctx, Command{File: "system/ipset-list.txt", Name: "ipset", Args: []string{"list"}}); err != nil {
+		t.Fatalf("AddCommand: %v", err)
+	}
+	if err := w.AddJSON("settings.json", map[string]string{"wanInterface": "eth8", "password": "hunter2"}); err != nil {
+		t.Fatalf("AddJSON: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	files := readBundle(t, buf.Bytes())
+	if !strings.Contains(files["system/iptables-save.txt"], "SVPN_MARK") {
+		t.Fatalf("missing iptables output: %#v", files)
+	}
+	if strings.Contains(files["settings.json"], "hunter2") {
+		t.Fatalf("secret leaked into settings: %s", files["settings.json"])
+	}
+	var settings map[string]string
+	if err := json.Unmarshal([]byte(files["settings.json"]), &settings); err != nil {
+		t.Fatalf("redacted JSON must stay valid: %v", err)
+	}
+
+	var manifest struct {
+		Files []Entry `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if len(manifest.Files) != 3 || manifest.Files[1].Error != "exit status 1" || manifest.Files[1].Command != "ipset list" {
+		t.Fatalf("unexpected manifest %#v", manifest.Files)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	for input, want := range map[string]string{
+		"PrivateKey = abc123=":        "PrivateKey = <redacted>",
+		"  private key: (hidden)":     "  private key: <redacted>",
+		"PresharedKey=xyz":            "PresharedKey=<redacted>",
+		`{"token": "t0k"}`:            `{"token": "<redacted>"}`,
+		"tokenCount: 3":               "tokenCount: 3",
+		"dev wg-sv-nl table 201 mark": "dev wg-sv-nl table 201 mark",
+	} {
+		if got := string(Redact([]byte(input))); got != want {
+			t.Fatalf("Redact(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package diaglog
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BufferSize is how many recent entries are kept in memory for the UI.
+const BufferSize = 2000
+
+const subscriberBuffer = 64
+
+// Entry is one structured log record.
+type Entry struct {
+	ID      uint64            `json:"id"`
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Module  string            `json:"module"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Filter selects buffered entries.
+type Filter struct {
+	// MinLevel drops entries below this level.
+	MinLevel Level
+	// Module keeps only entries from this module when set.
+	Module string
+	// SinceID keeps only entries newer than this ID.
+	SinceID uint64
+	// Limit keeps only the newest entries when positive.
+	Limit int
+}
+
+// Entries returns buffered entries matching filter, oldest first.
+func (m *Manager) Entries(filter Filter) []Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	module := strings.ToLower(strings.TrimSpace(filter.Module))
+	out := make([]Entry, 0)
+	for i := 0; i < len(m.buffer); i++ {
+		entry := m.buffer[(m.head+i)%len(m.buffer)]
+		if entry.ID <= filter.SinceID || parseLevel(entry.Level) < filter.MinLevel {
+			continue
+		}
+		if module != "" && entry.Module != module {
+			continue
+		}
+		out = append(out, entry)
+	}
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[len(out)-filter.Limit:]
+	}
+	return out
+}
+
+// Modules returns the distinct modules present in the buffer, sorted.
+func (m *Manager) Modules() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	modules := make([]string, 0)
+	for _, entry := range m.buffer {
+		if _, ok := seen[entry.Module]; ok {
+			continue
+		}
+		seen[entry.Module] = struct{}{}
+		modules = append(modules, entry.Module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// Subscribe streams new entries until the returned cancel func is called.
+// Slow subscribers miss entries rather than blocking the logger.
+func (m *Manager) Subscribe() (<-chan Entry, func()) {
+	ch := make(chan Entry, subscriberBuffer)
+	m.mu.Lock()
+	if m.subscribers == nil {
+		m.subscribers = make(map[chan Entry]struct{})
+	}
+	m.subscribers[ch] = struct{}{}
+	m.mu.Unlock()
+	return ch, func() {
+		m.mu.Lock()
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// recordLocked appends entry to the ring and fans it out. Callers hold mu.
+func (m *Manager) recordLocked(entry Entry) {
+	if len(m.buffer) < BufferSize {
+		m.buffer = append(m.buffer, entry)
+	} else {
+		m.buffer[m.head] = entry
+		m.head = (m.head + 1) % BufferSize
+	}
+	for ch := range m.subscribers {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// fieldPattern matches key=value tokens; values may be Go-quoted.
+var fieldPattern = regexp.MustCompile(`(?:^|\s)([A-Za-z][\w.-]*)=("(?:[^"\\]|\\.)*"|\S*)`)
+
+// parseFields extracts key=value pairs from a message.
+func parseFields(message string) map[string]string {
+	matches := fieldPattern.FindAllStringSubmatch(message, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	fields := make(map[string]string, len(matches))
+	for _, match := range matches {
+		value := match[2]
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		fields[match[1]] = value
+	}
+	return fields
+}
+
+// inferModule takes the module from the message's first word, which by
+// convention names the subsystem ("prewarm run started ...").
+func inferModule(message string) string {
+	word, _, _ := strings.Cut(strings.TrimSpace(message), " ")
+	word = strings.ToLower(strings.TrimSuffix(word, ":"))
+	if word == "" || strings.ContainsAny(word, "=\"'()[]{}/") {
+		return "app"
+	}
+	return word
+}
+
+// stdPrefix matches the timestamp the standard log package prepends.
+var stdPrefix = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? `)
+
+// stdWriter feeds lines written by the standard log package into the
+// buffer under a fixed module.
+type stdWriter struct {
+	m      *Manager
+	module string
+}
+
+// StdWriter returns a writer for log.SetOutput that records each line as an
+// entry of module. Lines starting with "warning:" or mentioning a failure
+// are recorded as warnings. These lines are not mirrored to the console,
+// since the standard logger already writes there.
+func (m *Manager) StdWriter(module string) io.Writer {
+	return &stdWriter{m: m, module: module}
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		message := strings.TrimSpace(stdPrefix.ReplaceAllString(string(line), ""))
+		if message == "" {
+			continue
+		}
+		level := LevelInfo
+		lower := strings.ToLower(message)
+		if strings.HasPrefix(lower, "warning:") || strings.Contains(lower, "failed") || strings.Contains(lower, "error") {
+			level = LevelWarn
+		}
+		w.m.record(level, w.module, message, false)
+	}
+	return len(p), nil
+}
+
+// ParseLevel maps "debug", "info", "warn" or "error" to a Level, defaulting
+// to LevelInfo.
+func ParseLevel(raw string) Level {
+	return parseLevel(raw)
+}
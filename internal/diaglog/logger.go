@@ -1,7 +1,10 @@
 package diaglog
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -23,13 +26,20 @@ const (
 	LevelError
 )
 
-// Manager writes optional diagnostic logs to a persistent file.
+// Manager records leveled log entries in an in-memory ring buffer for the
+// UI and, when enabled, appends them to a persistent file.
 type Manager struct {
 	path    string
 	mu      sync.RWMutex
 	enabled bool
 	level   Level
 	file    *os.File
+	console io.Writer
+
+	nextID      uint64
+	buffer      []Entry
+	head        int
+	subscribers map[chan Entry]struct{}
 }
 
 // New creates a diagnostics logger writing to path when enabled.
@@ -70,24 +80,32 @@ func (m *Manager) Close() error {
 	return err
 }
 
+// SetConsole mirrors warnings and errors to w, typically stderr, so they
+// still reach journald when file logging is disabled.
+func (m *Manager) SetConsole(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.console = w
+}
+
 // Debugf logs a debug-level message.
 func (m *Manager) Debugf(format string, args ...any) {
-	m.logf(LevelDebug, "DEBUG", format, args...)
+	m.logf(LevelDebug, format, args...)
 }
 
 // Infof logs an info-level message.
 func (m *Manager) Infof(format string, args ...any) {
-	m.logf(LevelInfo, "INFO", format, args...)
+	m.logf(LevelInfo, format, args...)
 }
 
 // Warnf logs a warning-level message.
 func (m *Manager) Warnf(format string, args ...any) {
-	m.logf(LevelWarn, "WARN", format, args...)
+	m.logf(LevelWarn, format, args...)
 }
 
 // Errorf logs an error-level message.
 func (m *Manager) Errorf(format string, args ...any) {
-	m.logf(LevelError, "ERROR", format, args...)
+	m.logf(LevelError, format, args...)
 }
 
 // Enabled returns whether diagnostics logging is currently enabled.
@@ -97,11 +115,71 @@ func (m *Manager) Enabled() bool {
 	return m.enabled
 }
 
-func (m *Manager) logf(level Level, label string, format string, args ...any) {
+// Tail returns up to maxBytes from the end of the log file, starting at a
+// line boundary. A missing file yields no data.
+func (m *Manager) Tail(maxBytes int64) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - maxBytes
+	if offset < 0 {
+		offset = 0
+	}
+	data, err := io.ReadAll(io.NewSectionReader(file, offset, info.Size()-offset))
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+			data = data[idx+1:]
+		}
+	}
+	return data, nil
+}
+
+func (m *Manager) logf(level Level, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	m.record(level, inferModule(message), message, true)
+}
+
+// record buffers an entry at or above the configured level, mirrors
+// warnings to the console when mirror is set and appends the entry to the
+// file when file logging is enabled.
+func (m *Manager) record(level Level, module string, message string, mirror bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if !m.enabled || level < m.level {
+	if level < m.level {
+		return
+	}
+	m.nextID++
+	entry := Entry{
+		ID:      m.nextID,
+		Time:    time.Now().UTC(),
+		Level:   levelLabel(level),
+		Module:  module,
+		Message: message,
+		Fields:  parseFields(message),
+	}
+	m.recordLocked(entry)
+	if mirror && m.console != nil && level >= LevelWarn {
+		_, _ = fmt.Fprintf(m.console, "[%s] %s\n", entry.Level, message)
+	}
+	if !m.enabled {
 		return
 	}
 	if err := m.ensureFileLocked(); err != nil {
@@ -110,11 +188,10 @@ func (m *Manager) logf(level Level, label string, format string, args ...any) {
 	if m.file == nil {
 		return
 	}
-	message := fmt.Sprintf(format, args...)
 	line := fmt.Sprintf(
 		"%s [%s] %s\n",
-		time.Now().UTC().Format(time.RFC3339),
-		label,
+		entry.Time.Format(time.RFC3339),
+		entry.Level,
 		message,
 	)
 	_, _ = m.file.WriteString(line)
@@ -150,3 +227,16 @@ func parseLevel(raw string) Level {
 		return LevelInfo
 	}
 }
+
+func levelLabel(level Level) string {
+	switch level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
@@ -82,3 +82,95 @@ func TestManagerDisableStopsWriting(t *testing.T) {
 		t.Fatalf("did not expect line after disable in log: %q", text)
 	}
 }
+
+func TestManagerTailStartsAtLineBoundary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diagnostics.log")
+	if err := os.WriteFile(path, []byte("first line\nsecond line\nthird\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	logger := New(path)
+	tail, err := logger.Tail(15)
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+	if string(tail) != "third\n" {
+		t.Fatalf("unexpected tail %q", tail)
+	}
+	missing, err := New(filepath.Join(t.TempDir(), "none.log")).Tail(10)
+	if err != nil || missing != nil {
+		t.Fatalf("expected empty tail for missing file, got %q %v", missing, err)
+	}
+}
+
+func TestManagerBuffersStructuredEntriesWithoutFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diagnostics.log")
+	logger := New(path)
+	var console strings.Builder
+	logger.SetConsole(&console)
+
+	logger.Infof("prewarm run started timeout=%ds label=%q", 10, "cloudflare dns")
+	logger.Warnf("routing apply failed group=%s", "Streaming")
+	logger.Debugf("debug hidden at info level")
+
+	entries := logger.Entries(Filter{})
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 buffered entries, got %#v", entries)
+	}
+	first := entries[0]
+	if first.Module != "prewarm" || first.Level != "INFO" || first.Fields["timeout"] != "10s" || first.Fields["label"] != "cloudflare dns" {
+		t.Fatalf("unexpected first entry %#v", first)
+	}
+	if got := logger.Entries(Filter{MinLevel: LevelWarn}); len(got) != 1 || got[0].Module != "routing" {
+		t.Fatalf("unexpected warn filter result %#v", got)
+	}
+	if got := logger.Entries(Filter{Module: "prewarm", SinceID: first.ID}); len(got) != 0 {
+		t.Fatalf("expected no prewarm entries after %d, got %#v", first.ID, got)
+	}
+	if console.String() != "[WARN] routing apply failed group=Streaming\n" {
+		t.Fatalf("expected only warnings on console, got %q", console.String())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no log file while disabled, got %v", err)
+	}
+}
+
+func TestManagerBufferWrapsAndStreams(t *testing.T) {
+	logger := New("")
+	updates, cancel := logger.Subscribe()
+	defer cancel()
+
+	for i := 0; i < BufferSize+5; i++ {
+		logger.Infof("stats tick n=%d", i)
+	}
+	entries := logger.Entries(Filter{Limit: 3})
+	if len(entries) != 3 || entries[2].Fields["n"] != "2004" {
+		t.Fatalf("unexpected newest entries %#v", entries)
+	}
+	if all := logger.Entries(Filter{}); len(all) != BufferSize || all[0].Fields["n"] != "5" {
+		t.Fatalf("expected ring to keep the newest %d entries, got %d starting %#v", BufferSize, len(all), all[0])
+	}
+	if first := <-updates; first.Fields["n"] != "0" {
+		t.Fatalf("unexpected streamed entry %#v", first)
+	}
+}
+
+func TestStdWriterClassifiesLines(t *testing.T) {
+	logger := New("")
+	var console strings.Builder
+	logger.SetConsole(&console)
+	writer := logger.StdWriter("main")
+
+	_, _ = writer.Write([]byte("2026/01/02 03:04:05 split-vpn-webui listening on http://0.0.0.0:8091\n"))
+	_, _ = writer.Write([]byte("2026/01/02 03:04:05 warning: failed to load settings: boom\n"))
+
+	entries := logger.Entries(Filter{})
+	if len(entries) != 2 || entries[0].Module != "main" || entries[0].Message != "split-vpn-webui listening on http://0.0.0.0:8091" {
+		t.Fatalf("unexpected entries %#v", entries)
+	}
+	if entries[1].Level != "WARN" {
+		t.Fatalf("expected warning level, got %#v", entries[1])
+	}
+	if console.Len() != 0 {
+		t.Fatalf("std log lines must not be mirrored again, got %q", console.String())
+	}
+}
@@ -0,0 +1,282 @@
+// Package dnsleak checks which recursive resolvers answer DNS for a VPN
+// tunnel. It asks leak-test names whose answer is the address of the
+// resolver that queried them, then compares each resolver's ASN with the ASN
+// of the tunnel's exit address.
+package dnsleak
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	defaultQueryTimeout = 3 * time.Second
+	defaultResolvConf   = "/etc/resolv.conf"
+)
+
+// Paths are the resolution routes a test exercises.
+const (
+	// PathVPN queries the resolvers pushed by the VPN, through the tunnel.
+	PathVPN = "vpn"
+	// PathRouter queries the router's own resolver, which is what LAN
+	// clients without DNS redirection use.
+	PathRouter = "router"
+)
+
+// probe is a leak-test name and the record type that carries the address of
+// the resolver asking for it.
+type probe struct {
+	service string
+	name    string
+	qtype   dnsmessage.Type
+}
+
+var resolverProbes = []probe{
+	{service: "akamai", name: "whoami.akamai.net.", qtype: dnsmessage.TypeA},
+	{service: "google", name: "o-o.myaddr.l.google.com.", qtype: dnsmessage.TypeTXT},
+}
+
+// exitProbes are queried directly at authoritative servers that answer with
+// the querying client's address, revealing the tunnel's exit IP.
+var exitProbes = []struct {
+	server netip.AddrPort
+	probe  probe
+}{
+	{server: netip.MustParseAddrPort("208.67.222.222:53"), probe: probe{service: "opendns", name: "myip.opendns.com.", qtype: dnsmessage.TypeA}},
+	{server: netip.MustParseAddrPort("216.239.32.10:53"), probe: probe{service: "google", name: "o-o.myaddr.l.google.com.", qtype: dnsmessage.TypeTXT}},
+}
+
+// Exchanger sends one DNS query, optionally bound to an interface, and
+// returns the answer values as strings.
+type Exchanger interface {
+	Exchange(ctx context.Context, iface string, server netip.AddrPort, name string, qtype dnsmessage.Type) ([]string, error)
+}
+
+// ASNLookup maps an address to the autonomous systems announcing it.
+type ASNLookup interface {
+	Lookup(ctx context.Context, addr netip.Addr) ([]string, error)
+}
+
+// Options selects the tunnel under test.
+type Options struct {
+	VPN       string
+	Interface string
+	// Resolvers are the VPN-pushed resolvers, queried through Interface.
+	Resolvers []netip.Addr
+}
+
+// Resolver is one recursive resolver seen by a leak-test service.
+type Resolver struct {
+	IP         string   `json:"ip"`
+	Services   []string `json:"services"`
+	ASNs       []string `json:"asns,omitempty"`
+	MatchesVPN bool     `json:"matchesVpn"`
+}
+
+// PathResult lists the resolvers that answered for one resolution path.
+type PathResult struct {
+	Path      string     `json:"path"`
+	Via       string     `json:"via"`
+	Resolvers []Resolver `json:"resolvers"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Result is the outcome of a leak test.
+type Result struct {
+	VPN       string       `json:"vpn"`
+	Interface string       `json:"interface"`
+	ExitIP    string       `json:"exitIp,omitempty"`
+	VPNASNs   []string     `json:"vpnAsns,omitempty"`
+	Paths     []PathResult `json:"paths"`
+	// Leak reports a resolver outside the VPN's ASN. It is false when the
+	// VPN's ASN could not be determined.
+	Leak      bool      `json:"leak"`
+	Warnings  []string  `json:"warnings,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Tester runs DNS leak tests.
+type Tester struct {
+	exchange   Exchanger
+	asn        ASNLookup
+	resolvConf string
+}
+
+// NewTester returns a tester using UDP DNS and RIPEstat ASN lookups.
+func NewTester() *Tester {
+	return NewTesterWith(udpExchanger{timeout: defaultQueryTimeout}, newRIPELookup(defaultQueryTimeout))
+}
+
+// NewTesterWith returns a tester with custom transports, for tests.
+func NewTesterWith(exchange Exchanger, asn ASNLookup) *Tester {
+	return &Tester{exchange: exchange, asn: asn, resolvConf: defaultResolvConf}
+}
+
+// Run tests the tunnel in opts. Failures of individual probes are reported
+// in the result; only an invalid request returns an error.
+func (t *Tester) Run(ctx context.Context, opts Options) (Result, error) {
+	iface := strings.TrimSpace(opts.Interface)
+	if iface == "" {
+		return Result{}, fmt.Errorf("vpn interface is required")
+	}
+	result := Result{VPN: opts.VPN, Interface: iface, Paths: make([]PathResult, 0, 2)}
+
+	exitIP, err := t.exitAddress(ctx, iface)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("exit address lookup failed: %v", err))
+	} else {
+		result.ExitIP = exitIP.String()
+		asns, err := t.asn.Lookup(ctx, exitIP)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("exit ASN lookup failed: %v", err))
+		}
+		result.VPNASNs = asns
+	}
+
+	if len(opts.Resolvers) == 0 {
+		result.Warnings = append(result.Warnings, "VPN profile has no DNS servers; skipping the tunnel resolver path")
+	}
+	for _, server := range opts.Resolvers {
+		result.Paths = append(result.Paths, t.testPath(ctx, PathVPN, iface, netip.AddrPortFrom(server, 53)))
+	}
+	if router, err := t.routerResolver(); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("router resolver unavailable: %v", err))
+	} else {
+		result.Paths = append(result.Paths, t.testPath(ctx, PathRouter, "", router))
+	}
+
+	asnCache := make(map[netip.Addr][]string)
+	for pi := range result.Paths {
+		for ri := range result.Paths[pi].Resolvers {
+			resolver := &result.Paths[pi].Resolvers[ri]
+			addr, err := netip.ParseAddr(resolver.IP)
+			if err != nil {
+				continue
+			}
+			asns, cached := asnCache[addr]
+			if !cached {
+				asns, _ = t.asn.Lookup(ctx, addr)
+				asnCache[addr] = asns
+			}
+			resolver.ASNs = asns
+			resolver.MatchesVPN = sharesASN(asns, result.VPNASNs)
+			if len(result.VPNASNs) > 0 && !resolver.MatchesVPN {
+				result.Leak = true
+			}
+		}
+	}
+	result.CheckedAt = time.Now().UTC()
+	return result, nil
+}
+
+func (t *Tester) exitAddress(ctx context.Context, iface string) (netip.Addr, error) {
+	var lastErr error
+	for _, exit := range exitProbes {
+		values, err := t.exchange.Exchange(ctx, iface, exit.server, exit.probe.name, exit.probe.qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, value := range values {
+			if addr, ok := parseAnswerAddr(value); ok {
+				return addr, nil
+			}
+		}
+		lastErr = fmt.Errorf("%s returned no address", exit.probe.service)
+	}
+	return netip.Addr{}, lastErr
+}
+
+func (t *Tester) testPath(ctx context.Context, path, iface string, server netip.AddrPort) PathResult {
+	result := PathResult{Path: path, Via: server.Addr().String(), Resolvers: make([]Resolver, 0)}
+	seen := make(map[string]int)
+	var errs []string
+	for _, probe := range resolverProbes {
+		values, err := t.exchange.Exchange(ctx, iface, server, probe.name, probe.qtype)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", probe.service, err))
+			continue
+		}
+		for _, value := range values {
+			addr, ok := parseAnswerAddr(value)
+			if !ok {
+				continue
+			}
+			key := addr.String()
+			if idx, exists := seen[key]; exists {
+				result.Resolvers[idx].Services = appendUnique(result.Resolvers[idx].Services, probe.service)
+				continue
+			}
+			seen[key] = len(result.Resolvers)
+			result.Resolvers = append(result.Resolvers, Resolver{IP: key, Services: []string{probe.service}})
+		}
+	}
+	if len(result.Resolvers) == 0 && len(errs) > 0 {
+		result.Error = strings.Join(errs, "; ")
+	}
+	sort.Slice(result.Resolvers, func(i, j int) bool { return result.Resolvers[i].IP < result.Resolvers[j].IP })
+	return result
+}
+
+// routerResolver returns the first nameserver in resolv.conf.
+func (t *Tester) routerResolver() (netip.AddrPort, error) {
+	file, err := os.Open(t.resolvConf)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		addr, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		return netip.AddrPortFrom(addr.Unmap(), 53), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return netip.AddrPort{}, err
+	}
+	return netip.AddrPort{}, fmt.Errorf("no nameserver in %s", t.resolvConf)
+}
+
+// parseAnswerAddr reads an address from an A record or from a TXT record,
+// where Google may append "edns0-client-subnet ..." in a separate string.
+func parseAnswerAddr(value string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func sharesASN(asns, vpnASNs []string) bool {
+	for _, asn := range asns {
+		for _, vpnASN := range vpnASNs {
+			if asn == vpnASN {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
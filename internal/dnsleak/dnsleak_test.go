@@ -0,0 +1,169 @@
+package dnsleak
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type fakeExchanger struct {
+	// answers is keyed by server address, then query name.
+	answers map[string]map[string][]string
+	ifaces  map[string]string
+}
+
+func (f *fakeExchanger) Exchange(ctx context.Context, iface string, server netip.AddrPort, name string, qtype dnsmessage.Type) ([]string, error) {
+	if f.ifaces == nil {
+		f.ifaces = make(map[string]string)
+	}
+	f.ifaces[server.Addr().String()] = iface
+	values, ok := f.answers[server.Addr().String()][name]
+	if !ok {
+		return nil, errors.New("timeout")
+	}
+	return values, nil
+}
+
+type fakeASNLookup map[string][]string
+
+func (f fakeASNLookup) Lookup(ctx context.Context, addr netip.Addr) ([]string, error) {
+	return f[addr.String()], nil
+}
+
+func TestRunFlagsResolversOutsideVPNASN(t *testing.T) {
+	exchange := &fakeExchanger{answers: map[string]map[string][]string{
+		"208.67.222.222": {"myip.opendns.com.": {"185.1.1.10"}},
+		"10.2.0.1": {
+			"whoami.akamai.net.":       {"185.1.1.53"},
+			"o-o.myaddr.l.google.com.": {"185.1.1.53", "edns0-client-subnet 185.1.1.0/24"},
+		},
+		"127.0.0.1": {
+			"whoami.akamai.net.": {"84.2.2.2"},
+		},
+	}}
+	asns := fakeASNLookup{"185.1.1.10": {"AS9009"}, "185.1.1.53": {"AS9009"}, "84.2.2.2": {"AS3320"}}
+	tester := NewTesterWith(exchange, asns)
+	tester.resolvConf = writeResolvConf(t, "# generated\nsearch lan\nnameserver 127.0.0.1\nnameserver 1.1.1.1\n")
+
+	result, err := tester.Run(context.Background(), Options{
+		VPN:       "wg-sgp",
+		Interface: "wg-sgp",
+		Resolvers: []netip.Addr{netip.MustParseAddr("10.2.0.1")},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.ExitIP != "185.1.1.10" || !reflect.DeepEqual(result.VPNASNs, []string{"AS9009"}) {
+		t.Fatalf("unexpected exit: %s %v", result.ExitIP, result.VPNASNs)
+	}
+	if len(result.Paths) != 2 {
+		t.Fatalf("expected vpn and router paths, got %#v", result.Paths)
+	}
+	vpnPath := result.Paths[0]
+	if vpnPath.Path != PathVPN || len(vpnPath.Resolvers) != 1 || !vpnPath.Resolvers[0].MatchesVPN ||
+		!reflect.DeepEqual(vpnPath.Resolvers[0].Services, []string{"akamai", "google"}) {
+		t.Fatalf("unexpected vpn path: %#v", vpnPath)
+	}
+	routerPath := result.Paths[1]
+	if routerPath.Path != PathRouter || routerPath.Via != "127.0.0.1" || len(routerPath.Resolvers) != 1 || routerPath.Resolvers[0].MatchesVPN {
+		t.Fatalf("unexpected router path: %#v", routerPath)
+	}
+	if !result.Leak {
+		t.Fatalf("expected router resolver outside the VPN ASN to be reported as a leak")
+	}
+	if exchange.ifaces["10.2.0.1"] != "wg-sgp" || exchange.ifaces["127.0.0.1"] != "" {
+		t.Fatalf("expected only tunnel queries to be bound: %#v", exchange.ifaces)
+	}
+}
+
+func TestRunWithoutExitASNDoesNotReportLeak(t *testing.T) {
+	exchange := &fakeExchanger{answers: map[string]map[string][]string{
+		"10.2.0.1": {"whoami.akamai.net.": {"84.2.2.2"}},
+	}}
+	tester := NewTesterWith(exchange, fakeASNLookup{"84.2.2.2": {"AS3320"}})
+	tester.resolvConf = filepath.Join(t.TempDir(), "missing")
+
+	result, err := tester.Run(context.Background(), Options{Interface: "wg-sgp", Resolvers: []netip.Addr{netip.MustParseAddr("10.2.0.1")}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Leak || result.ExitIP != "" {
+		t.Fatalf("expected no leak verdict without an exit ASN: %#v", result)
+	}
+	if len(result.Warnings) != 2 {
+		t.Fatalf("expected exit and router warnings, got %v", result.Warnings)
+	}
+	if _, err := tester.Run(context.Background(), Options{}); err == nil {
+		t.Fatalf("expected missing interface to be rejected")
+	}
+}
+
+func TestParseAnswersReadsAddressAndTXTRecords(t *testing.T) {
+	name := dnsmessage.MustNewName("o-o.myaddr.l.google.com.")
+	message := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 7, Response: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET}},
+		Answers: []dnsmessage.Resource{
+			{
+				Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.TXTResource{TXT: []string{"2001:db8::53"}},
+			},
+			{
+				Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+			},
+		},
+	}
+	packed, err := message.Pack()
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	values, err := parseAnswers(7, packed)
+	if err != nil {
+		t.Fatalf("parseAnswers failed: %v", err)
+	}
+	if !reflect.DeepEqual(values, []string{"2001:db8::53", "192.0.2.1"}) {
+		t.Fatalf("unexpected values %v", values)
+	}
+	if _, err := parseAnswers(8, packed); err == nil {
+		t.Fatalf("expected mismatched id to be rejected")
+	}
+}
+
+func TestRIPELookupNormalizesASNs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("resource") != "185.1.1.10" {
+			http.Error(w, "bad resource", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"asns":["9009"," as1234 "],"prefix":"185.1.1.0/24"}}`))
+	}))
+	defer server.Close()
+
+	lookup := newRIPELookup(defaultQueryTimeout)
+	lookup.baseURL = server.URL
+	asns, err := lookup.Lookup(context.Background(), netip.MustParseAddr("185.1.1.10"))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if !reflect.DeepEqual(asns, []string{"AS9009", "AS1234"}) {
+		t.Fatalf("unexpected asns %v", asns)
+	}
+}
+
+func writeResolvConf(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write resolv.conf: %v", err)
+	}
+	return path
+}
@@ -0,0 +1,174 @@
+package dnsleak
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"split-vpn-webui/internal/netbind"
+)
+
+const ripeNetworkInfoEndpoint = "https://stat.ripe.net/data/network-info/data.json"
+
+// udpExchanger sends plain DNS over UDP. A non-empty interface binds the
+// socket to it so the query leaves through that tunnel.
+type udpExchanger struct {
+	timeout time.Duration
+}
+
+func (e udpExchanger) Exchange(ctx context.Context, iface string, server netip.AddrPort, name string, qtype dnsmessage.Type) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	id := uint16(rand.N(1 << 16))
+	query, err := buildQuery(id, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+	dialer := net.Dialer{Control: netbind.Control(iface)}
+	conn, err := dialer.DialContext(ctx, "udp", server.String())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, 1500)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return parseAnswers(id, buffer[:n])
+}
+
+func buildQuery(id uint16, name string, qtype dnsmessage.Type) ([]byte, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	message := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	return message.Pack()
+}
+
+// parseAnswers returns A/AAAA addresses and TXT strings from a response.
+func parseAnswers(id uint16, response []byte) ([]string, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(response)
+	if err != nil {
+		return nil, err
+	}
+	if !header.Response || header.ID != id {
+		return nil, fmt.Errorf("unexpected response id %d", header.ID)
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("resolver answered %s", header.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+	values := make([]string, 0)
+	for {
+		answer, err := parser.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch answer.Type {
+		case dnsmessage.TypeA:
+			record, err := parser.AResource()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, netip.AddrFrom4(record.A).String())
+		case dnsmessage.TypeAAAA:
+			record, err := parser.AAAAResource()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, netip.AddrFrom16(record.AAAA).String())
+		case dnsmessage.TypeTXT:
+			record, err := parser.TXTResource()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, record.TXT...)
+		default:
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// ripeLookup resolves origin ASNs with the RIPEstat network-info endpoint.
+type ripeLookup struct {
+	baseURL string
+	client  *http.Client
+}
+
+type ripeNetworkInfo struct {
+	Data struct {
+		ASNs []string `json:"asns"`
+	} `json:"data"`
+}
+
+func newRIPELookup(timeout time.Duration) *ripeLookup {
+	return &ripeLookup{baseURL: ripeNetworkInfoEndpoint, client: &http.Client{Timeout: timeout}}
+}
+
+func (r *ripeLookup) Lookup(ctx context.Context, addr netip.Addr) ([]string, error) {
+	base, err := url.Parse(r.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	query := base.Query()
+	query.Set("resource", addr.String())
+	base.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("asn lookup status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var payload ripeNetworkInfo
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	asns := make([]string, 0, len(payload.Data.ASNs))
+	for _, asn := range payload.Data.ASNs {
+		if trimmed := strings.TrimSpace(asn); trimmed != "" {
+			asns = append(asns, "AS"+strings.TrimPrefix(strings.ToUpper(trimmed), "AS"))
+		}
+	}
+	return asns, nil
+}
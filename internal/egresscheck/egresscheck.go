@@ -0,0 +1,230 @@
+// Package egresscheck fetches a group's sites through its VPN tunnel and
+// through the WAN and compares the outcomes, telling apart services that
+// work through the VPN, refuse the VPN's region, or are down everywhere.
+package egresscheck
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProbeTimeout = 10 * time.Second
+	// maxBodyBytes bounds how much of a page is read for geo-block markers.
+	maxBodyBytes = 64 << 10
+	// maxConcurrent bounds how many sites are checked at once.
+	maxConcurrent = 4
+)
+
+// Paths are the routes each site is fetched through.
+const (
+	// PathVPN binds the request to the VPN interface.
+	PathVPN = "vpn"
+	// PathWAN leaves the request to the router's default route.
+	PathWAN = "wan"
+)
+
+// Verdicts summarize how a site behaves through the two paths.
+const (
+	// VerdictOK means the site answered through the VPN without signs of
+	// a geo block.
+	VerdictOK = "ok"
+	// VerdictVPNBlocked means the site answered through the VPN with a
+	// geo-block page or status.
+	VerdictVPNBlocked = "vpn-blocked"
+	// VerdictVPNUnreachable means the site answered through the WAN only.
+	VerdictVPNUnreachable = "vpn-unreachable"
+	// VerdictDown means the site answered through neither path.
+	VerdictDown = "down"
+)
+
+// Response is what a fetch returned: the status, the URL after redirects
+// and the start of the body.
+type Response struct {
+	StatusCode int
+	FinalURL   string
+	Body       []byte
+}
+
+// Fetcher performs one HTTP GET, bound to iface when it is set. resolvers,
+// when set, answer DNS for the request through the same interface.
+type Fetcher interface {
+	Fetch(ctx context.Context, iface string, resolvers []netip.Addr, url string) (Response, error)
+}
+
+// Options selects the tunnel and the sites to check.
+type Options struct {
+	VPN       string
+	Interface string
+	// Resolvers are the VPN-pushed resolvers. When set, VPN-path lookups
+	// use them so CDNs pick servers for the tunnel's region.
+	Resolvers []netip.Addr
+	Domains   []string
+}
+
+// Probe is the outcome of fetching one site through one path.
+type Probe struct {
+	Path string `json:"path"`
+	Via  string `json:"via"`
+	// Reachable reports any HTTP response, whatever its status.
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	FinalURL   string `json:"finalUrl,omitempty"`
+	GeoBlocked bool   `json:"geoBlocked"`
+	// GeoBlockReason names the status or page text that looked like a
+	// geo block.
+	GeoBlockReason string `json:"geoBlockReason,omitempty"`
+	LatencyMs      int64  `json:"latencyMs,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// SiteResult compares one site through both paths.
+type SiteResult struct {
+	Domain  string `json:"domain"`
+	URL     string `json:"url"`
+	VPN     Probe  `json:"vpn"`
+	WAN     Probe  `json:"wan"`
+	Verdict string `json:"verdict"`
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	VPN       string       `json:"vpn"`
+	Interface string       `json:"interface"`
+	Sites     []SiteResult `json:"sites"`
+	CheckedAt time.Time    `json:"checkedAt"`
+}
+
+// Checker runs egress checks.
+type Checker struct {
+	fetch Fetcher
+}
+
+// NewChecker returns a checker fetching over HTTPS.
+func NewChecker() *Checker {
+	return NewCheckerWith(NewHTTPFetcher(defaultProbeTimeout))
+}
+
+// NewCheckerWith returns a checker with a custom fetcher, for tests.
+func NewCheckerWith(fetch Fetcher) *Checker {
+	return &Checker{fetch: fetch}
+}
+
+// Run fetches every domain's front page through the VPN interface and
+// through the WAN. Sites are reported in the order given.
+func (c *Checker) Run(ctx context.Context, opts Options) (Result, error) {
+	iface := strings.TrimSpace(opts.Interface)
+	if iface == "" {
+		return Result{}, fmt.Errorf("vpn interface is required")
+	}
+	if len(opts.Domains) == 0 {
+		return Result{}, fmt.Errorf("no domains to check")
+	}
+	result := Result{
+		VPN:       opts.VPN,
+		Interface: iface,
+		Sites:     make([]SiteResult, len(opts.Domains)),
+		CheckedAt: time.Now().UTC(),
+	}
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrent)
+	for idx, domain := range opts.Domains {
+		wg.Add(1)
+		go func(idx int, domain string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			url := "https://" + domain + "/"
+			site := SiteResult{Domain: domain, URL: url}
+			site.VPN = c.probe(ctx, PathVPN, iface, opts.Resolvers, url)
+			site.WAN = c.probe(ctx, PathWAN, "", nil, url)
+			site.Verdict = verdict(site.VPN, site.WAN)
+			result.Sites[idx] = site
+		}(idx, domain)
+	}
+	wg.Wait()
+	return result, nil
+}
+
+func (c *Checker) probe(ctx context.Context, path, iface string, resolvers []netip.Addr, url string) Probe {
+	probe := Probe{Path: path, Via: iface}
+	if iface == "" {
+		probe.Via = "default route"
+	}
+	started := time.Now()
+	response, err := c.fetch.Fetch(ctx, iface, resolvers, url)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	probe.Reachable = true
+	probe.LatencyMs = time.Since(started).Milliseconds()
+	probe.StatusCode = response.StatusCode
+	if response.FinalURL != url {
+		probe.FinalURL = response.FinalURL
+	}
+	probe.GeoBlockReason = geoBlockReason(response)
+	probe.GeoBlocked = probe.GeoBlockReason != ""
+	return probe
+}
+
+func verdict(vpn, wan Probe) string {
+	switch {
+	case vpn.Reachable && vpn.GeoBlocked:
+		return VerdictVPNBlocked
+	case vpn.Reachable:
+		return VerdictOK
+	case wan.Reachable:
+		return VerdictVPNUnreachable
+	default:
+		return VerdictDown
+	}
+}
+
+// geoBlockMarkers are page texts services show visitors from regions they
+// do not serve. They are matched case-insensitively against the start of
+// the page.
+var geoBlockMarkers = []string{
+	"not available in your country",
+	"not available in your region",
+	"not available in your location",
+	"not available in your area",
+	"unavailable in your country",
+	"unavailable in your region",
+	"isn't available in your country",
+	"isn't available in your region",
+	"blocked in your country",
+	"geo-restricted",
+	"georestricted",
+	"geo-blocked",
+	"geoblocked",
+}
+
+// geoBlockURLMarkers are path fragments of the pages some services redirect
+// unsupported regions to.
+var geoBlockURLMarkers = []string{"geoblock", "geo-block", "geo_block", "unsupported-region", "region-unavailable", "country-unavailable"}
+
+// geoBlockReason explains why a response looks like a geo block, or returns
+// "" when it does not.
+func geoBlockReason(response Response) string {
+	if response.StatusCode == 451 {
+		return "HTTP 451 Unavailable For Legal Reasons"
+	}
+	finalURL := strings.ToLower(response.FinalURL)
+	for _, marker := range geoBlockURLMarkers {
+		if strings.Contains(finalURL, marker) {
+			return fmt.Sprintf("redirected to %s", response.FinalURL)
+		}
+	}
+	body := strings.ToLower(string(response.Body))
+	for _, marker := range geoBlockMarkers {
+		if strings.Contains(body, marker) {
+			return fmt.Sprintf("page says %q", marker)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package egresscheck
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+type fakeFetcher struct {
+	mu        sync.Mutex
+	responses map[string]Response
+	resolvers map[string][]netip.Addr
+}
+
+func (f *fakeFetcher) Fetch(_ context.Context, iface string, resolvers []netip.Addr, url string) (Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.resolvers == nil {
+		f.resolvers = make(map[string][]netip.Addr)
+	}
+	f.resolvers[iface] = resolvers
+	response, ok := f.responses[iface+" "+url]
+	if !ok {
+		return Response{}, errors.New("connection timed out")
+	}
+	if response.FinalURL == "" {
+		response.FinalURL = url
+	}
+	return response, nil
+}
+
+func TestRunComparesVPNAndWANPaths(t *testing.T) {
+	fetcher := &fakeFetcher{responses: map[string]Response{
+		"wg-sgp https://ok.example/":       {StatusCode: 200, Body: []byte("<html>welcome</html>")},
+		" https://ok.example/":             {StatusCode: 200},
+		"wg-sgp https://stream.example/":   {StatusCode: 403, Body: []byte("Sorry, this title is NOT AVAILABLE IN YOUR REGION.")},
+		" https://stream.example/":         {StatusCode: 200},
+		"wg-sgp https://legal.example/":    {StatusCode: 451},
+		"wg-sgp https://redirect.example/": {StatusCode: 200, FinalURL: "https://redirect.example/geoblock?c=SG"},
+		" https://vpn-refused.example/":    {StatusCode: 200},
+	}}
+	resolvers := []netip.Addr{netip.MustParseAddr("10.2.0.1")}
+	result, err := NewCheckerWith(fetcher).Run(context.Background(), Options{
+		VPN:       "wg-sgp",
+		Interface: "wg-sgp",
+		Resolvers: resolvers,
+		Domains:   []string{"ok.example", "stream.example", "legal.example", "redirect.example", "vpn-refused.example", "gone.example"},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := []string{VerdictOK, VerdictVPNBlocked, VerdictVPNBlocked, VerdictVPNBlocked, VerdictVPNUnreachable, VerdictDown}
+	if len(result.Sites) != len(want) {
+		t.Fatalf("expected %d sites, got %d", len(want), len(result.Sites))
+	}
+	for idx, site := range result.Sites {
+		if site.Verdict != want[idx] {
+			t.Fatalf("site %s: expected verdict %s, got %s (%+v)", site.Domain, want[idx], site.Verdict, site)
+		}
+	}
+	stream := result.Sites[1]
+	if stream.VPN.StatusCode != 403 || stream.VPN.Via != "wg-sgp" || stream.WAN.GeoBlocked || stream.WAN.Via != "default route" {
+		t.Fatalf("unexpected stream probes: %+v", stream)
+	}
+	if result.Sites[3].VPN.FinalURL == "" || result.Sites[5].VPN.Error == "" {
+		t.Fatalf("expected redirect target and fetch error to be reported: %+v", result.Sites)
+	}
+	if got := fetcher.resolvers["wg-sgp"]; len(got) != 1 || got[0] != resolvers[0] {
+		t.Fatalf("expected VPN path to use the VPN resolvers, got %v", got)
+	}
+	if got := fetcher.resolvers[""]; len(got) != 0 {
+		t.Fatalf("expected WAN path to use the system resolver, got %v", got)
+	}
+}
+
+func TestRunRequiresInterfaceAndDomains(t *testing.T) {
+	checker := NewCheckerWith(&fakeFetcher{})
+	if _, err := checker.Run(context.Background(), Options{Domains: []string{"example.com"}}); err == nil {
+		t.Fatalf("expected missing interface to fail")
+	}
+	if _, err := checker.Run(context.Background(), Options{Interface: "wg-sgp"}); err == nil {
+		t.Fatalf("expected missing domains to fail")
+	}
+}
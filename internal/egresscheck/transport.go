@@ -0,0 +1,60 @@
+package egresscheck
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"split-vpn-webui/internal/netbind"
+)
+
+// userAgent looks like a desktop browser: several services answer bare HTTP
+// clients with bot challenges that would hide a geo block.
+const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36"
+
+// httpFetcher fetches with sockets bound to the requested interface. A
+// non-empty resolver list sends lookups to the first resolver through the
+// same interface.
+type httpFetcher struct {
+	timeout time.Duration
+}
+
+// NewHTTPFetcher returns the fetcher NewChecker uses, for other packages
+// that probe services through a tunnel.
+func NewHTTPFetcher(timeout time.Duration) Fetcher {
+	return httpFetcher{timeout: timeout}
+}
+
+func (f httpFetcher) Fetch(ctx context.Context, iface string, resolvers []netip.Addr, url string) (Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	dialer := netbind.Dialer(f.timeout, netbind.Egress{Interface: iface, Resolvers: resolvers})
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: f.timeout,
+		DisableKeepAlives:   true,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Response{}, err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	response, err := client.Do(request)
+	if err != nil {
+		return Response{}, err
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxBodyBytes))
+	return Response{
+		StatusCode: response.StatusCode,
+		FinalURL:   response.Request.URL.String(),
+		Body:       body,
+	}, nil
+}
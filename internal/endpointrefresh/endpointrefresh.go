@@ -0,0 +1,399 @@
+// Package endpointrefresh keeps WireGuard tunnels pointed at peers whose
+// endpoint is a host name. The kernel only ever sees the address wg-quick
+// resolved when the tunnel came up, so when a dynamic DNS name moves the
+// tunnel keeps sending handshakes to the old address until it is restarted.
+// The monitor looks the names up again at each VPN's interval and moves the
+// running peer with `wg set ... endpoint` when the address changed.
+package endpointrefresh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event kinds.
+const (
+	KindChanged = "endpoint_changed"
+	KindFailed  = "endpoint_failed"
+)
+
+const (
+	checkInterval = 10 * time.Second
+	lookupTimeout = 10 * time.Second
+)
+
+// Peer is a peer whose configured endpoint is host:port with a host name.
+type Peer struct {
+	PublicKey string
+	Endpoint  string
+}
+
+// Target is a VPN with endpoint re-resolution turned on. Tool is the
+// wg-compatible command that manages its interface ("wg" or "awg").
+type Target struct {
+	VPN       string
+	Interface string
+	Tool      string
+	Interval  time.Duration
+	Peers     []Peer
+}
+
+// TargetSource lists the VPNs with endpoint re-resolution turned on.
+type TargetSource func() ([]Target, error)
+
+// Device reads and changes the endpoints of a running interface.
+type Device interface {
+	// Endpoints returns the current endpoint of each peer by public key.
+	// Peers without one are left out. An error means the interface is not
+	// up.
+	Endpoints(tool, iface string) (map[string]netip.AddrPort, error)
+	SetEndpoint(tool, iface, publicKey string, endpoint netip.AddrPort) error
+}
+
+// Event is an endpoint moved by the monitor, or the first failure to keep
+// one current.
+type Event struct {
+	VPN    string    `json:"vpn"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail"`
+	At     time.Time `json:"at"`
+}
+
+// PeerStatus is the monitor's view of one peer.
+type PeerStatus struct {
+	PublicKey string `json:"publicKey"`
+	Endpoint  string `json:"endpoint"`
+	Address   string `json:"address,omitempty"`
+}
+
+// Status is the monitor's view of one VPN.
+type Status struct {
+	VPN             string       `json:"vpn"`
+	IntervalSeconds int          `json:"intervalSeconds"`
+	Running         bool         `json:"running"`
+	LastCheck       time.Time    `json:"lastCheck,omitzero"`
+	LastChange      time.Time    `json:"lastChange,omitzero"`
+	Error           string       `json:"error,omitempty"`
+	Peers           []PeerStatus `json:"peers"`
+}
+
+type vpnState struct {
+	interval   time.Duration
+	running    bool
+	lastCheck  time.Time
+	lastChange time.Time
+	err        string
+	peers      []PeerStatus
+}
+
+// Monitor re-resolves peer endpoints and updates running tunnels.
+type Monitor struct {
+	targets TargetSource
+	device  Device
+	lookup  func(ctx context.Context, host string) ([]netip.Addr, error)
+	now     func() time.Time
+
+	checkMu sync.Mutex
+
+	mu         sync.Mutex
+	states     map[string]*vpnState
+	handler    func(Event)
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor that looks names up with the system
+// resolver.
+func NewMonitor(targets TargetSource, device Device) (*Monitor, error) {
+	switch {
+	case targets == nil:
+		return nil, fmt.Errorf("target source is required")
+	case device == nil:
+		return nil, fmt.Errorf("device is required")
+	}
+	return &Monitor{
+		targets: targets,
+		device:  device,
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+		now:    time.Now,
+		states: make(map[string]*vpnState),
+	}, nil
+}
+
+// SetHandler registers a callback for endpoint changes and failures.
+func (m *Monitor) SetHandler(handler func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// Status lists the VPNs seen by the last check, by name.
+func (m *Monitor) Status() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Status, 0, len(m.states))
+	for name, state := range m.states {
+		out = append(out, Status{
+			VPN:             name,
+			IntervalSeconds: int(state.interval / time.Second),
+			Running:         state.running,
+			LastCheck:       state.lastCheck,
+			LastChange:      state.lastChange,
+			Error:           state.err,
+			Peers:           append([]PeerStatus(nil), state.peers...),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VPN < out[j].VPN })
+	return out
+}
+
+// Start launches the periodic check loop.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.started = true
+	m.loopCancel = cancel
+	m.mu.Unlock()
+
+	m.loopWG.Add(1)
+	go func() {
+		defer m.loopWG.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = m.Check(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the periodic loop.
+func (m *Monitor) Stop() error {
+	m.mu.Lock()
+	loopCancel := m.loopCancel
+	m.started = false
+	m.loopCancel = nil
+	m.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	m.loopWG.Wait()
+	return nil
+}
+
+// Check resolves the endpoints of every VPN whose interval has passed and
+// moves running peers whose address is no longer among the answers. An
+// address that is still returned is kept, so round-robin names do not make
+// the tunnel hop between servers.
+func (m *Monitor) Check(ctx context.Context) error {
+	m.checkMu.Lock()
+	defer m.checkMu.Unlock()
+
+	targets, err := m.targets()
+	if err != nil {
+		return err
+	}
+	now := m.now()
+
+	events := make([]Event, 0)
+	seen := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		seen[target.VPN] = struct{}{}
+		m.mu.Lock()
+		state, ok := m.states[target.VPN]
+		if !ok {
+			state = &vpnState{}
+			m.states[target.VPN] = state
+		}
+		state.interval = target.Interval
+		due := state.lastCheck.IsZero() || now.Sub(state.lastCheck) >= target.Interval
+		m.mu.Unlock()
+		if !due {
+			continue
+		}
+		if event, ok := m.refresh(ctx, target, state, now); ok {
+			events = append(events, event)
+		}
+	}
+	m.mu.Lock()
+	for name := range m.states {
+		if _, ok := seen[name]; !ok {
+			delete(m.states, name)
+		}
+	}
+	handler := m.handler
+	m.mu.Unlock()
+
+	if handler != nil {
+		for _, event := range events {
+			handler(event)
+		}
+	}
+	return nil
+}
+
+func (m *Monitor) refresh(ctx context.Context, target Target, state *vpnState, now time.Time) (Event, bool) {
+	peers := make([]PeerStatus, 0, len(target.Peers))
+	for _, peer := range target.Peers {
+		peers = append(peers, PeerStatus{PublicKey: peer.PublicKey, Endpoint: peer.Endpoint})
+	}
+	current, err := m.device.Endpoints(target.Tool, target.Interface)
+	if err != nil {
+		// The tunnel is down; wg-quick resolves the names itself when it
+		// comes back up.
+		m.mu.Lock()
+		state.running, state.lastCheck, state.err, state.peers = false, now, "", peers
+		m.mu.Unlock()
+		return Event{}, false
+	}
+
+	failures := make([]string, 0)
+	changes := make([]string, 0)
+	for i, peer := range target.Peers {
+		address, ok := current[peer.PublicKey]
+		if ok {
+			peers[i].Address = address.String()
+		}
+		next, err := m.resolve(ctx, peer.Endpoint, address)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", peer.Endpoint, err))
+			continue
+		}
+		if ok && next == address {
+			continue
+		}
+		if err := m.device.SetEndpoint(target.Tool, target.Interface, peer.PublicKey, next); err != nil {
+			failures = append(failures, fmt.Sprintf("set %s endpoint: %v", peer.Endpoint, err))
+			continue
+		}
+		peers[i].Address = next.String()
+		from := "none"
+		if ok {
+			from = address.String()
+		}
+		changes = append(changes, fmt.Sprintf("%s moved from %s to %s", peer.Endpoint, from, next))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	failed := strings.Join(failures, "; ")
+	firstFailure := failed != "" && state.err == ""
+	state.running, state.lastCheck, state.err, state.peers = true, now, failed, peers
+	switch {
+	case len(changes) > 0:
+		state.lastChange = now
+		detail := "endpoint " + strings.Join(changes, "; ")
+		if failed != "" {
+			detail += "; " + failed
+		}
+		return Event{VPN: target.VPN, Kind: KindChanged, Detail: detail, At: now}, true
+	case firstFailure:
+		return Event{VPN: target.VPN, Kind: KindFailed, Detail: "endpoint re-resolution failed: " + failed, At: now}, true
+	}
+	return Event{}, false
+}
+
+// resolve looks up the host of a host:port endpoint. It keeps current when
+// the name still resolves to it and otherwise prefers IPv4, like wg-quick.
+func (m *Monitor) resolve(ctx context.Context, endpoint string, current netip.AddrPort) (netip.AddrPort, error) {
+	host, portText, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil || port == 0 {
+		return netip.AddrPort{}, fmt.Errorf("invalid port %q", portText)
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	addrs, err := m.lookup(lookupCtx, host)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	if len(addrs) == 0 {
+		return netip.AddrPort{}, fmt.Errorf("no addresses for %s", host)
+	}
+	var chosen netip.Addr
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if current.IsValid() && addr == current.Addr() && uint16(port) == current.Port() {
+			return current, nil
+		}
+		if !chosen.IsValid() || (addr.Is4() && !chosen.Is4()) {
+			chosen = addr
+		}
+	}
+	return netip.AddrPortFrom(chosen, uint16(port)), nil
+}
+
+// CommandDevice drives interfaces with the wg (or awg) command.
+type CommandDevice struct{}
+
+// Endpoints runs `wg show <iface> endpoints`.
+func (CommandDevice) Endpoints(tool, iface string) (map[string]netip.AddrPort, error) {
+	output, err := runTool(tool, "show", iface, "endpoints")
+	if err != nil {
+		return nil, err
+	}
+	return parseEndpoints(output), nil
+}
+
+// SetEndpoint runs `wg set <iface> peer <key> endpoint <addr:port>`.
+func (CommandDevice) SetEndpoint(tool, iface, publicKey string, endpoint netip.AddrPort) error {
+	_, err := runTool(tool, "set", iface, "peer", publicKey, "endpoint", endpoint.String())
+	return err
+}
+
+func runTool(tool string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool, args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("%w: %s", err, detail)
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// parseEndpoints reads `wg show <iface> endpoints` output: one peer per
+// line, public key and endpoint separated by a tab, "(none)" when unset.
+func parseEndpoints(output string) map[string]netip.AddrPort {
+	endpoints := make(map[string]netip.AddrPort)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		endpoint, err := netip.ParseAddrPort(fields[1])
+		if err != nil {
+			continue
+		}
+		endpoints[fields[0]] = netip.AddrPortFrom(endpoint.Addr().Unmap(), endpoint.Port())
+	}
+	return endpoints
+}
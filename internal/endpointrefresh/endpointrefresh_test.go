@@ -0,0 +1,170 @@
+package endpointrefresh
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeDevice struct {
+	endpoints map[string]netip.AddrPort
+	down      bool
+	setErr    error
+	sets      int
+}
+
+func (f *fakeDevice) Endpoints(tool, iface string) (map[string]netip.AddrPort, error) {
+	if f.down {
+		return nil, errors.New("Unable to access interface: No such device")
+	}
+	out := make(map[string]netip.AddrPort, len(f.endpoints))
+	for key, value := range f.endpoints {
+		out[key] = value
+	}
+	return out, nil
+}
+
+func (f *fakeDevice) SetEndpoint(tool, iface, publicKey string, endpoint netip.AddrPort) error {
+	f.sets++
+	if f.setErr != nil {
+		return f.setErr
+	}
+	f.endpoints[publicKey] = endpoint
+	return nil
+}
+
+type harness struct {
+	monitor   *Monitor
+	device    *fakeDevice
+	addrs     []netip.Addr
+	lookupErr error
+	now       time.Time
+	events    []Event
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	h := &harness{
+		device: &fakeDevice{endpoints: map[string]netip.AddrPort{
+			"peerkey=": netip.MustParseAddrPort("198.51.100.7:51820"),
+		}},
+		addrs: []netip.Addr{netip.MustParseAddr("198.51.100.7")},
+		now:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	targets := func() ([]Target, error) {
+		return []Target{{
+			VPN:       "home",
+			Interface: "wg-sv-home",
+			Tool:      "wg",
+			Interval:  time.Minute,
+			Peers:     []Peer{{PublicKey: "peerkey=", Endpoint: "home.example.net:51820"}},
+		}}, nil
+	}
+	monitor, err := NewMonitor(targets, h.device)
+	if err != nil {
+		t.Fatalf("new monitor: %v", err)
+	}
+	monitor.lookup = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		if host != "home.example.net" {
+			t.Fatalf("unexpected lookup of %q", host)
+		}
+		return h.addrs, h.lookupErr
+	}
+	monitor.now = func() time.Time { return h.now }
+	monitor.SetHandler(func(event Event) { h.events = append(h.events, event) })
+	h.monitor = monitor
+	return h
+}
+
+func (h *harness) check(t *testing.T, after time.Duration) {
+	t.Helper()
+	h.now = h.now.Add(after)
+	if err := h.monitor.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+}
+
+func TestMonitorMovesPeerWhenNameChanges(t *testing.T) {
+	h := newHarness(t)
+	h.check(t, 0)
+	if h.device.sets != 0 || len(h.events) != 0 {
+		t.Fatalf("unchanged endpoint was touched: sets=%d events=%v", h.device.sets, h.events)
+	}
+
+	h.addrs = []netip.Addr{netip.MustParseAddr("2001:db8::7"), netip.MustParseAddr("203.0.113.9")}
+	h.check(t, 30*time.Second)
+	if h.device.sets != 0 {
+		t.Fatalf("checked before the interval passed")
+	}
+	h.check(t, 30*time.Second)
+	if got := h.device.endpoints["peerkey="]; got != netip.MustParseAddrPort("203.0.113.9:51820") {
+		t.Fatalf("endpoint = %s, want IPv4 answer", got)
+	}
+	if len(h.events) != 1 || h.events[0].Kind != KindChanged || !strings.Contains(h.events[0].Detail, "198.51.100.7:51820 to 203.0.113.9:51820") {
+		t.Fatalf("unexpected events %+v", h.events)
+	}
+	status := h.monitor.Status()
+	if len(status) != 1 || !status[0].Running || status[0].LastChange != h.now || status[0].Peers[0].Address != "203.0.113.9:51820" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestMonitorKeepsAddressStillReturned(t *testing.T) {
+	h := newHarness(t)
+	h.addrs = []netip.Addr{netip.MustParseAddr("203.0.113.9"), netip.MustParseAddr("198.51.100.7")}
+	h.check(t, 0)
+	if h.device.sets != 0 || len(h.events) != 0 {
+		t.Fatalf("round-robin answer moved the peer: sets=%d events=%v", h.device.sets, h.events)
+	}
+}
+
+func TestMonitorReportsFailureOnce(t *testing.T) {
+	h := newHarness(t)
+	h.lookupErr = errors.New("no such host")
+	h.check(t, 0)
+	h.check(t, time.Minute)
+	if len(h.events) != 1 || h.events[0].Kind != KindFailed {
+		t.Fatalf("expected one failure event, got %+v", h.events)
+	}
+	if status := h.monitor.Status(); status[0].Error == "" {
+		t.Fatalf("failure missing from status")
+	}
+
+	h.lookupErr = nil
+	h.check(t, time.Minute)
+	if status := h.monitor.Status(); status[0].Error != "" {
+		t.Fatalf("error not cleared: %q", status[0].Error)
+	}
+	h.lookupErr = errors.New("no such host")
+	h.check(t, time.Minute)
+	if len(h.events) != 2 {
+		t.Fatalf("expected a new failure event after recovery, got %+v", h.events)
+	}
+}
+
+func TestMonitorSkipsTunnelThatIsDown(t *testing.T) {
+	h := newHarness(t)
+	h.device.down = true
+	h.addrs = []netip.Addr{netip.MustParseAddr("203.0.113.9")}
+	h.check(t, 0)
+	if h.device.sets != 0 || len(h.events) != 0 {
+		t.Fatalf("down tunnel was touched: sets=%d events=%v", h.device.sets, h.events)
+	}
+	if status := h.monitor.Status(); status[0].Running || status[0].Error != "" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	output := "peerA=\t198.51.100.7:51820\npeerB=\t(none)\npeerC=\t[2001:db8::1]:51820\n"
+	got := parseEndpoints(output)
+	if len(got) != 2 {
+		t.Fatalf("unexpected endpoints %v", got)
+	}
+	if got["peerA="] != netip.MustParseAddrPort("198.51.100.7:51820") || got["peerC="] != netip.MustParseAddrPort("[2001:db8::1]:51820") {
+		t.Fatalf("unexpected endpoints %v", got)
+	}
+}
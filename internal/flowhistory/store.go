@@ -0,0 +1,323 @@
+// Package flowhistory keeps the flows sampled from conntrack for each VPN so
+// the flow inspector can show what crossed a tunnel over the last hours,
+// including connections that closed before anyone opened the inspector.
+package flowhistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Retention is how long a flow is kept after it was last seen.
+	Retention = 24 * time.Hour
+	// MaxFlowsPerVPN caps the rows kept per VPN; the oldest flows are
+	// dropped first.
+	MaxFlowsPerVPN = 20000
+	// DefaultWindow is the lookback used when a summary asks for none.
+	DefaultWindow = time.Hour
+	// DefaultLimit and MaxLimit bound the destinations in a summary.
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Summary sort orders.
+const (
+	SortBytes    = "bytes"
+	SortUpload   = "upload"
+	SortDownload = "download"
+	SortFlows    = "flows"
+	SortRecent   = "recent"
+)
+
+// Flow is one conntrack flow as sampled. Byte counters are the flow's
+// cumulative conntrack counters, so the highest value seen wins.
+type Flow struct {
+	Key               string
+	Protocol          string
+	SourceIP          string
+	SourceMAC         string
+	SourceDevice      string
+	DestinationIP     string
+	DestinationPort   int
+	DestinationDomain string
+	UploadBytes       uint64
+	DownloadBytes     uint64
+}
+
+// Destination aggregates the flows to one domain, or to one IP address when
+// the domain is unknown.
+type Destination struct {
+	Destination   string    `json:"destination"`
+	Domain        string    `json:"domain,omitempty"`
+	Flows         int       `json:"flows"`
+	Addresses     int       `json:"addresses"`
+	Sources       int       `json:"sources"`
+	Ports         []int     `json:"ports"`
+	UploadBytes   uint64    `json:"uploadBytes"`
+	DownloadBytes uint64    `json:"downloadBytes"`
+	TotalBytes    uint64    `json:"totalBytes"`
+	FirstSeen     time.Time `json:"firstSeen"`
+	LastSeen      time.Time `json:"lastSeen"`
+	// SourceDevices names the known clients behind the flows.
+	SourceDevices []string `json:"sourceDevices,omitempty"`
+}
+
+// Summary is the per-destination view of a VPN's flows within a window.
+type Summary struct {
+	VPN           string        `json:"vpn"`
+	WindowSeconds int           `json:"windowSeconds"`
+	Since         time.Time     `json:"since"`
+	Sort          string        `json:"sort"`
+	Flows         int           `json:"flows"`
+	UploadBytes   uint64        `json:"uploadBytes"`
+	DownloadBytes uint64        `json:"downloadBytes"`
+	TotalBytes    uint64        `json:"totalBytes"`
+	Destinations  []Destination `json:"destinations"`
+}
+
+// DeviceUsage totals the flows of one client through one VPN.
+type DeviceUsage struct {
+	VPN string `json:"vpn"`
+	// Device is the client's known name, or its source IP when unnamed.
+	Device        string `json:"device"`
+	Flows         int    `json:"flows"`
+	UploadBytes   uint64 `json:"uploadBytes"`
+	DownloadBytes uint64 `json:"downloadBytes"`
+}
+
+// Store persists sampled flows in the flow_history table.
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewStore creates a store backed by db.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	return &Store{db: db, now: time.Now}, nil
+}
+
+// Record upserts one sampling pass of a VPN's flows and prunes the VPN's
+// ring buffer.
+func (s *Store) Record(ctx context.Context, vpn string, flows []Flow) error {
+	vpn = strings.TrimSpace(vpn)
+	if vpn == "" {
+		return fmt.Errorf("flow history vpn is required")
+	}
+	now := s.now().UTC().Unix()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO flow_history (
+			vpn, flow_key, protocol, source_ip, source_mac, source_device,
+			destination_ip, destination_port, destination_domain,
+			upload_bytes, download_bytes, first_seen, last_seen
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (vpn, flow_key) DO UPDATE SET
+			source_mac = excluded.source_mac,
+			source_device = excluded.source_device,
+			destination_domain = CASE WHEN excluded.destination_domain <> '' THEN excluded.destination_domain ELSE destination_domain END,
+			upload_bytes = MAX(upload_bytes, excluded.upload_bytes),
+			download_bytes = MAX(download_bytes, excluded.download_bytes),
+			last_seen = excluded.last_seen
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, flow := range flows {
+		key := strings.TrimSpace(flow.Key)
+		if key == "" {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx,
+			vpn, key, flow.Protocol, flow.SourceIP, flow.SourceMAC, flow.SourceDevice,
+			flow.DestinationIP, flow.DestinationPort, strings.ToLower(flow.DestinationDomain),
+			int64(flow.UploadBytes), int64(flow.DownloadBytes), now, now,
+		); err != nil {
+			return fmt.Errorf("record flow %s: %w", key, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM flow_history
+		WHERE vpn = ? AND (last_seen < ? OR id NOT IN (
+			SELECT id FROM flow_history WHERE vpn = ? ORDER BY id DESC LIMIT ?
+		))
+	`, vpn, now-int64(Retention.Seconds()), vpn, MaxFlowsPerVPN); err != nil {
+		return fmt.Errorf("prune flow history: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Summarize aggregates a VPN's flows last seen within window by destination.
+func (s *Store) Summarize(ctx context.Context, vpn string, window time.Duration, sortBy string, limit int) (Summary, error) {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if window > Retention {
+		window = Retention
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	order, ok := summaryOrders[sortBy]
+	if !ok {
+		sortBy = SortBytes
+		order = summaryOrders[SortBytes]
+	}
+	since := s.now().UTC().Add(-window).Truncate(time.Second)
+	summary := Summary{
+		VPN:           vpn,
+		WindowSeconds: int(window.Seconds()),
+		Since:         since,
+		Sort:          sortBy,
+		Destinations:  []Destination{},
+	}
+	var upload, download int64
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(upload_bytes), 0), COALESCE(SUM(download_bytes), 0)
+		FROM flow_history WHERE vpn = ? AND last_seen >= ?
+	`, vpn, since.Unix()).Scan(&summary.Flows, &upload, &download); err != nil {
+		return Summary{}, err
+	}
+	summary.UploadBytes, summary.DownloadBytes = uint64(upload), uint64(download)
+	summary.TotalBytes = summary.UploadBytes + summary.DownloadBytes
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			CASE WHEN destination_domain <> '' THEN destination_domain ELSE destination_ip END AS destination,
+			MAX(destination_domain),
+			COUNT(*),
+			COUNT(DISTINCT destination_ip),
+			COUNT(DISTINCT source_ip),
+			GROUP_CONCAT(DISTINCT destination_port),
+			GROUP_CONCAT(DISTINCT NULLIF(source_device, '')),
+			SUM(upload_bytes),
+			SUM(download_bytes),
+			MIN(first_seen),
+			MAX(last_seen)
+		FROM flow_history
+		WHERE vpn = ? AND last_seen >= ?
+		GROUP BY destination
+		ORDER BY `+order+`, destination ASC
+		LIMIT ?
+	`, vpn, since.Unix(), limit)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dest Destination
+		var ports, devices sql.NullString
+		var up, down, first, last int64
+		if err := rows.Scan(&dest.Destination, &dest.Domain, &dest.Flows, &dest.Addresses, &dest.Sources,
+			&ports, &devices, &up, &down, &first, &last); err != nil {
+			return Summary{}, err
+		}
+		dest.Ports = parsePorts(ports.String)
+		dest.SourceDevices = splitList(devices.String)
+		dest.UploadBytes, dest.DownloadBytes = uint64(up), uint64(down)
+		dest.TotalBytes = dest.UploadBytes + dest.DownloadBytes
+		dest.FirstSeen = time.Unix(first, 0).UTC()
+		dest.LastSeen = time.Unix(last, 0).UTC()
+		summary.Destinations = append(summary.Destinations, dest)
+	}
+	return summary, rows.Err()
+}
+
+// Devices totals the flows last seen within window by VPN and client,
+// largest first.
+func (s *Store) Devices(ctx context.Context, window time.Duration) ([]DeviceUsage, error) {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if window > Retention {
+		window = Retention
+	}
+	since := s.now().UTC().Add(-window).Truncate(time.Second)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			vpn,
+			CASE WHEN source_device <> '' THEN source_device ELSE source_ip END AS device,
+			COUNT(*),
+			SUM(upload_bytes),
+			SUM(download_bytes)
+		FROM flow_history
+		WHERE last_seen >= ?
+		GROUP BY vpn, device
+		ORDER BY SUM(upload_bytes) + SUM(download_bytes) DESC, vpn ASC, device ASC
+	`, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	devices := []DeviceUsage{}
+	for rows.Next() {
+		var usage DeviceUsage
+		var up, down int64
+		if err := rows.Scan(&usage.VPN, &usage.Device, &usage.Flows, &up, &down); err != nil {
+			return nil, err
+		}
+		if usage.Device == "" {
+			continue
+		}
+		usage.UploadBytes, usage.DownloadBytes = uint64(up), uint64(down)
+		devices = append(devices, usage)
+	}
+	return devices, rows.Err()
+}
+
+// Delete removes a VPN's flow history, used when the profile is deleted.
+func (s *Store) Delete(ctx context.Context, vpn string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM flow_history WHERE vpn = ?`, vpn)
+	return err
+}
+
+// summaryOrders maps sort names to trusted ORDER BY clauses.
+var summaryOrders = map[string]string{
+	SortBytes:    "SUM(upload_bytes) + SUM(download_bytes) DESC",
+	SortUpload:   "SUM(upload_bytes) DESC",
+	SortDownload: "SUM(download_bytes) DESC",
+	SortFlows:    "COUNT(*) DESC",
+	SortRecent:   "MAX(last_seen) DESC",
+}
+
+func parsePorts(raw string) []int {
+	ports := make([]int, 0)
+	for _, item := range splitList(raw) {
+		var port int
+		if _, err := fmt.Sscanf(item, "%d", &port); err == nil && port > 0 {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+func splitList(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	parts := strings.Split(raw, ",")
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,111 @@
+package flowhistory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/database"
+)
+
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "flows.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestStoreAggregatesFlowsByDestination(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore(t)
+
+	if err := store.Record(ctx, "wg-fra", []Flow{
+		{Key: "tcp|10.0.0.5|5000|1.1.1.1|443", Protocol: "tcp", SourceIP: "10.0.0.5", SourceDevice: "laptop", DestinationIP: "1.1.1.1", DestinationPort: 443, DestinationDomain: "Video.example", UploadBytes: 100, DownloadBytes: 1000},
+		{Key: "udp|10.0.0.6|6000|9.9.9.9|53", Protocol: "udp", SourceIP: "10.0.0.6", DestinationIP: "9.9.9.9", DestinationPort: 53, UploadBytes: 50, DownloadBytes: 60},
+	}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	*now = now.Add(time.Minute)
+	// Counters grow for the long flow; a short flow to the same domain appears
+	// and closes between samples of the live view.
+	if err := store.Record(ctx, "wg-fra", []Flow{
+		{Key: "tcp|10.0.0.5|5000|1.1.1.1|443", Protocol: "tcp", SourceIP: "10.0.0.5", SourceDevice: "laptop", DestinationIP: "1.1.1.1", DestinationPort: 443, UploadBytes: 200, DownloadBytes: 5000},
+		{Key: "tcp|10.0.0.7|5001|1.1.1.2|80", Protocol: "tcp", SourceIP: "10.0.0.7", SourceDevice: "tv", DestinationIP: "1.1.1.2", DestinationPort: 80, DestinationDomain: "video.example", UploadBytes: 10, DownloadBytes: 20},
+	}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := store.Record(ctx, "wg-ams", []Flow{{Key: "other", DestinationIP: "8.8.8.8", DownloadBytes: 1 << 30}}); err != nil {
+		t.Fatalf("record other vpn: %v", err)
+	}
+
+	summary, err := store.Summarize(ctx, "wg-fra", time.Hour, SortBytes, 0)
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	if summary.Flows != 3 || summary.TotalBytes != 200+5000+10+20+50+60 || len(summary.Destinations) != 2 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	video := summary.Destinations[0]
+	if video.Destination != "video.example" || video.Flows != 2 || video.Addresses != 2 || video.Sources != 2 ||
+		video.TotalBytes != 5230 || fmt.Sprint(video.Ports) != "[80 443]" || fmt.Sprint(video.SourceDevices) != "[laptop tv]" {
+		t.Fatalf("unexpected video destination: %+v", video)
+	}
+	if summary.Destinations[1].Destination != "9.9.9.9" {
+		t.Fatalf("expected ip fallback destination, got %+v", summary.Destinations[1])
+	}
+
+	devices, err := store.Devices(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("devices: %v", err)
+	}
+	// The wg-ams flow has no source to attribute it to.
+	if fmt.Sprint(devices) != "[{wg-fra laptop 1 200 5000} {wg-fra 10.0.0.6 1 50 60} {wg-fra tv 1 10 20}]" {
+		t.Fatalf("unexpected devices: %+v", devices)
+	}
+
+	byFlows, err := store.Summarize(ctx, "wg-fra", time.Hour, SortRecent, 1)
+	if err != nil || len(byFlows.Destinations) != 1 || byFlows.Destinations[0].Destination != "video.example" {
+		t.Fatalf("unexpected recent summary: %+v err=%v", byFlows, err)
+	}
+
+	// The udp flow was last seen 61 minutes before the window end.
+	*now = now.Add(time.Hour)
+	recent, err := store.Summarize(ctx, "wg-fra", time.Hour, "bogus", 0)
+	if err != nil || recent.Sort != SortBytes || recent.Flows != 2 {
+		t.Fatalf("unexpected windowed summary: %+v err=%v", recent, err)
+	}
+}
+
+func TestStorePrunesExpiredFlows(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore(t)
+	if err := store.Record(ctx, "wg-fra", []Flow{{Key: "old", DestinationIP: "1.1.1.1"}}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	*now = now.Add(Retention + time.Minute)
+	if err := store.Record(ctx, "wg-fra", []Flow{{Key: "new", DestinationIP: "2.2.2.2"}}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	var rows int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM flow_history WHERE vpn = 'wg-fra'`).Scan(&rows); err != nil || rows != 1 {
+		t.Fatalf("expected expired flow to be pruned, rows=%d err=%v", rows, err)
+	}
+	if err := store.Delete(ctx, "wg-fra"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	summary, err := store.Summarize(ctx, "wg-fra", Retention, SortBytes, 0)
+	if err != nil || summary.Flows != 0 || len(summary.Destinations) != 0 {
+		t.Fatalf("expected empty history after delete: %+v err=%v", summary, err)
+	}
+}
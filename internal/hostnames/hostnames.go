@@ -0,0 +1,282 @@
+// Package hostnames actively resolves LAN device hostnames over mDNS, LLMNR
+// and NetBIOS for devices that never registered a DHCP hostname.
+package hostnames
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultInterval     = 10 * time.Minute
+	defaultProbeTimeout = 1500 * time.Millisecond
+	defaultFoundTTL     = 6 * time.Hour
+	defaultMissTTL      = 30 * time.Minute
+	defaultMaxEntries   = 4096
+	// defaultConcurrency keeps a sweep of a busy LAN from bursting hundreds
+	// of UDP probes at once.
+	defaultConcurrency = 8
+)
+
+// Prober resolves one address to a hostname over a single protocol.
+type Prober interface {
+	Name() string
+	Probe(ctx context.Context, addr netip.Addr) (string, error)
+}
+
+// Name is a discovered hostname and the protocol that produced it.
+type Name struct {
+	Hostname   string    `json:"hostname"`
+	Source     string    `json:"source"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+}
+
+type cacheEntry struct {
+	name      Name
+	expiresAt time.Time
+}
+
+// Discoverer periodically probes the addresses returned by its target
+// function and caches the names it finds. Callers read the cache; they never
+// wait on the network.
+type Discoverer struct {
+	mu         sync.Mutex
+	probers    []Prober
+	targets    func(ctx context.Context) []netip.Addr
+	cache      map[netip.Addr]cacheEntry
+	interval   time.Duration
+	timeout    time.Duration
+	foundTTL   time.Duration
+	missTTL    time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewDiscoverer creates a discoverer probing targets with the mDNS, LLMNR and
+// NetBIOS probers.
+func NewDiscoverer(targets func(ctx context.Context) []netip.Addr) (*Discoverer, error) {
+	return NewDiscovererWithProbers(targets, []Prober{NewMDNSProber(), NewLLMNRProber(), NewNetBIOSProber()})
+}
+
+// NewDiscovererWithProbers creates a discoverer with custom probers, tried in
+// order until one returns a name.
+func NewDiscovererWithProbers(targets func(ctx context.Context) []netip.Addr, probers []Prober) (*Discoverer, error) {
+	if targets == nil {
+		return nil, fmt.Errorf("target function is required")
+	}
+	if len(probers) == 0 {
+		return nil, fmt.Errorf("at least one prober is required")
+	}
+	return &Discoverer{
+		probers:    append([]Prober(nil), probers...),
+		targets:    targets,
+		cache:      make(map[netip.Addr]cacheEntry),
+		interval:   defaultInterval,
+		timeout:    defaultProbeTimeout,
+		foundTTL:   defaultFoundTTL,
+		missTTL:    defaultMissTTL,
+		maxEntries: defaultMaxEntries,
+		now:        time.Now,
+	}, nil
+}
+
+// Start launches the periodic discovery loop; the first sweep runs at once.
+func (d *Discoverer) Start() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.started {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.loopCancel = cancel
+	d.started = true
+	d.loopWG.Add(1)
+	go d.loop(ctx)
+	return nil
+}
+
+// Stop halts the discovery loop and waits for an in-flight sweep to end.
+func (d *Discoverer) Stop() error {
+	d.mu.Lock()
+	if !d.started {
+		d.mu.Unlock()
+		return nil
+	}
+	cancel := d.loopCancel
+	d.started = false
+	d.loopCancel = nil
+	d.mu.Unlock()
+
+	cancel()
+	d.loopWG.Wait()
+	return nil
+}
+
+func (d *Discoverer) loop(ctx context.Context) {
+	defer d.loopWG.Done()
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.Sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep probes every target whose cached result has expired and returns the
+// number of names found.
+func (d *Discoverer) Sweep(ctx context.Context) int {
+	due := d.dueTargets(d.targets(ctx))
+	if len(due) == 0 {
+		return 0
+	}
+	sem := make(chan struct{}, defaultConcurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	found := 0
+	for _, addr := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(addr netip.Addr) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if d.resolve(ctx, addr) {
+				mu.Lock()
+				found++
+				mu.Unlock()
+			}
+		}(addr)
+	}
+	wg.Wait()
+	return found
+}
+
+func (d *Discoverer) dueTargets(targets []netip.Addr) []netip.Addr {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	seen := make(map[netip.Addr]struct{}, len(targets))
+	due := make([]netip.Addr, 0, len(targets))
+	for _, addr := range targets {
+		addr = addr.Unmap()
+		if !eligibleAddr(addr) {
+			continue
+		}
+		if _, exists := seen[addr]; exists {
+			continue
+		}
+		seen[addr] = struct{}{}
+		if entry, ok := d.cache[addr]; ok && now.Before(entry.expiresAt) {
+			continue
+		}
+		due = append(due, addr)
+	}
+	return due
+}
+
+func (d *Discoverer) resolve(ctx context.Context, addr netip.Addr) bool {
+	name := Name{}
+	for _, prober := range d.probers {
+		probeCtx, cancel := context.WithTimeout(ctx, d.timeout)
+		hostname, err := prober.Probe(probeCtx, addr)
+		cancel()
+		if err != nil {
+			continue
+		}
+		if hostname = normalizeHostname(hostname); hostname != "" {
+			name = Name{Hostname: hostname, Source: prober.Name()}
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	ttl := d.missTTL
+	if name.Hostname != "" {
+		name.ResolvedAt = now.UTC()
+		ttl = d.foundTTL
+	}
+	d.evictLocked(now)
+	d.cache[addr] = cacheEntry{name: name, expiresAt: now.Add(ttl)}
+	return name.Hostname != ""
+}
+
+// Names returns every cached hostname keyed by IP address. Expired names
+// are kept until re-probed so a device that went quiet keeps its name.
+func (d *Discoverer) Names() map[string]Name {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make(map[string]Name, len(d.cache))
+	for addr, entry := range d.cache {
+		if entry.name.Hostname == "" {
+			continue
+		}
+		out[addr.String()] = entry.name
+	}
+	return out
+}
+
+// evictLocked drops expired misses and, if the cache is still full, the
+// entries closest to expiry.
+func (d *Discoverer) evictLocked(now time.Time) {
+	if len(d.cache) < d.maxEntries {
+		return
+	}
+	for addr, entry := range d.cache {
+		if entry.name.Hostname == "" && now.After(entry.expiresAt) {
+			delete(d.cache, addr)
+		}
+	}
+	if len(d.cache) < d.maxEntries {
+		return
+	}
+	type aged struct {
+		addr      netip.Addr
+		expiresAt time.Time
+	}
+	entries := make([]aged, 0, len(d.cache))
+	for addr, entry := range d.cache {
+		entries = append(entries, aged{addr: addr, expiresAt: entry.expiresAt})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].expiresAt.Before(entries[j].expiresAt) })
+	for _, entry := range entries[:len(d.cache)-d.maxEntries+1] {
+		delete(d.cache, entry.addr)
+	}
+}
+
+// eligibleAddr limits probing to LAN addresses; these protocols are
+// link-local and public hosts would never answer.
+func eligibleAddr(addr netip.Addr) bool {
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	return addr.IsPrivate() || addr.IsLinkLocalUnicast()
+}
+
+// normalizeHostname strips the trailing root dot and the .local suffix.
+func normalizeHostname(raw string) string {
+	name := strings.TrimSuffix(strings.TrimSpace(raw), ".")
+	if len(name) > len(".local") && strings.EqualFold(name[len(name)-len(".local"):], ".local") {
+		name = name[:len(name)-len(".local")]
+	}
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,167 @@
+package hostnames
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type fakeProber struct {
+	name  string
+	names map[netip.Addr]string
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeProber) Name() string { return f.name }
+
+func (f *fakeProber) Probe(ctx context.Context, addr netip.Addr) (string, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	if name, ok := f.names[addr]; ok {
+		return name, nil
+	}
+	return "", errNoName
+}
+
+func TestSweepTriesProbersInOrderAndCachesResults(t *testing.T) {
+	lan := netip.MustParseAddr("192.168.1.20")
+	quiet := netip.MustParseAddr("192.168.1.21")
+	public := netip.MustParseAddr("8.8.8.8")
+	mdns := &fakeProber{name: "mdns", names: map[netip.Addr]string{}}
+	netbios := &fakeProber{name: "netbios", names: map[netip.Addr]string{lan: "DESKTOP-1"}}
+	discoverer, err := NewDiscovererWithProbers(func(context.Context) []netip.Addr {
+		return []netip.Addr{lan, quiet, public, lan}
+	}, []Prober{mdns, netbios})
+	if err != nil {
+		t.Fatalf("NewDiscovererWithProbers failed: %v", err)
+	}
+
+	if found := discoverer.Sweep(context.Background()); found != 1 {
+		t.Fatalf("expected one name found, got %d", found)
+	}
+	names := discoverer.Names()
+	if len(names) != 1 || names["192.168.1.20"].Hostname != "DESKTOP-1" || names["192.168.1.20"].Source != "netbios" {
+		t.Fatalf("unexpected names: %#v", names)
+	}
+	if mdns.calls != 2 {
+		t.Fatalf("expected public address to be skipped, mdns calls=%d", mdns.calls)
+	}
+
+	discoverer.Sweep(context.Background())
+	if mdns.calls != 2 {
+		t.Fatalf("expected cached hits and misses to skip probing, mdns calls=%d", mdns.calls)
+	}
+}
+
+func TestNormalizeHostnameStripsLocalSuffix(t *testing.T) {
+	for raw, want := range map[string]string{
+		"Living-Room.local.": "Living-Room",
+		"printer.LOCAL":      "printer",
+		"nas.lan.":           "nas.lan",
+		".local":             ".local",
+	} {
+		if got := normalizeHostname(raw); got != want {
+			t.Fatalf("normalizeHostname(%q)=%q want %q", raw, got, want)
+		}
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	if got := reverseName(netip.MustParseAddr("192.168.1.20")); got != "20.1.168.192.in-addr.arpa." {
+		t.Fatalf("unexpected IPv4 reverse name %q", got)
+	}
+	got := reverseName(netip.MustParseAddr("fe80::1"))
+	want := "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.e.f.ip6.arpa."
+	if got != want {
+		t.Fatalf("unexpected IPv6 reverse name %q", got)
+	}
+}
+
+func TestPTRProberReadsAnswerFromResponder(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 512)
+		n, peer, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buffer[:n]); err != nil {
+			return
+		}
+		target, _ := dnsmessage.NewName("macbook.local.")
+		response := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+			Questions: query.Questions,
+			Answers: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: 120},
+				Body:   &dnsmessage.PTRResource{PTR: target},
+			}},
+		}
+		packed, _ := response.Pack()
+		_, _ = conn.WriteTo(packed, peer)
+	}()
+
+	prober := &PTRProber{name: "mdns", port: conn.LocalAddr().(*net.UDPAddr).Port}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultProbeTimeout)
+	defer cancel()
+	name, err := prober.Probe(ctx, netip.MustParseAddr("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if normalizeHostname(name) != "macbook" {
+		t.Fatalf("unexpected PTR name %q", name)
+	}
+}
+
+func TestParseNodeStatusResponsePicksWorkstationName(t *testing.T) {
+	request := buildNodeStatusRequest(0x1234)
+	if len(request) != 50 {
+		t.Fatalf("expected 50-byte node status request, got %d", len(request))
+	}
+
+	response := append([]byte(nil), request[:12]...)
+	response[2] = 0x84 // response, authoritative
+	binary.BigEndian.PutUint16(response[4:6], 0)
+	binary.BigEndian.PutUint16(response[6:8], 1)
+	response = append(response, request[12:46]...)
+	response = append(response, 0x00, 0x21, 0x00, 0x01, 0, 0, 0, 0, 0x00, 0x00)
+	response = append(response, 2)
+	response = append(response, netbiosEntry("WORKGROUP", 0x00, netbiosGroupFlag)...)
+	response = append(response, netbiosEntry("DESKTOP-7Q2", 0x00, 0x0400)...)
+
+	name, err := parseNodeStatusResponse(0x1234, response)
+	if err != nil {
+		t.Fatalf("parseNodeStatusResponse failed: %v", err)
+	}
+	if name != "DESKTOP-7Q2" {
+		t.Fatalf("expected workstation name, got %q", name)
+	}
+	if _, err := parseNodeStatusResponse(0x9999, response); err == nil {
+		t.Fatalf("expected mismatched id to be rejected")
+	}
+	if _, err := parseNodeStatusResponse(0x1234, response[:60]); !errors.Is(err, errNoName) {
+		t.Fatalf("expected truncated table to yield no name, got %v", err)
+	}
+}
+
+func netbiosEntry(name string, suffix byte, flags uint16) []byte {
+	entry := make([]byte, 18)
+	copy(entry, fmt.Sprintf("%-15.15s", name))
+	entry[15] = suffix
+	binary.BigEndian.PutUint16(entry[16:], flags)
+	return entry
+}
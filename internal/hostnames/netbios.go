@@ -0,0 +1,130 @@
+package hostnames
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"strings"
+)
+
+const (
+	netbiosPort       = 137
+	netbiosTypeNBSTAT = 0x0021
+	netbiosClassIN    = 0x0001
+	// netbiosGroupFlag marks group names (workgroups), not host names.
+	netbiosGroupFlag = 0x8000
+	// netbiosWorkstation is the suffix of a machine's own name.
+	netbiosWorkstation = 0x00
+)
+
+// NetBIOSProber sends a NetBIOS node status request (RFC 1002 section
+// 4.2.17), which Windows and Samba hosts answer with their registered names.
+type NetBIOSProber struct {
+	port int
+}
+
+// NewNetBIOSProber returns a prober for NetBIOS name service responders.
+func NewNetBIOSProber() *NetBIOSProber {
+	return &NetBIOSProber{port: netbiosPort}
+}
+
+// Name implements Prober.
+func (p *NetBIOSProber) Name() string { return "netbios" }
+
+// Probe implements Prober. NetBIOS is IPv4-only.
+func (p *NetBIOSProber) Probe(ctx context.Context, addr netip.Addr) (string, error) {
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return "", errNoName
+	}
+	id := uint16(rand.N(1 << 16))
+	response, err := exchangeUDP(ctx, netip.AddrPortFrom(addr, uint16(p.port)), buildNodeStatusRequest(id))
+	if err != nil {
+		return "", err
+	}
+	return parseNodeStatusResponse(id, response)
+}
+
+// buildNodeStatusRequest queries the wildcard name "*", which every node
+// answers with its full name table.
+func buildNodeStatusRequest(id uint16) []byte {
+	packet := make([]byte, 0, 50)
+	packet = binary.BigEndian.AppendUint16(packet, id)
+	packet = append(packet, 0x00, 0x00) // flags: query
+	packet = append(packet, 0x00, 0x01) // qdcount
+	packet = append(packet, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	packet = append(packet, 0x20)
+	packet = append(packet, encodeNetBIOSName("*")...)
+	packet = append(packet, 0x00)
+	packet = binary.BigEndian.AppendUint16(packet, netbiosTypeNBSTAT)
+	packet = binary.BigEndian.AppendUint16(packet, netbiosClassIN)
+	return packet
+}
+
+// encodeNetBIOSName applies first-level encoding to a 16-byte, NUL-padded
+// name: each nibble becomes a letter starting at 'A'.
+func encodeNetBIOSName(name string) []byte {
+	raw := make([]byte, 16)
+	copy(raw, name)
+	out := make([]byte, 0, 32)
+	for _, b := range raw {
+		out = append(out, 'A'+(b>>4), 'A'+(b&0x0f))
+	}
+	return out
+}
+
+func parseNodeStatusResponse(id uint16, response []byte) (string, error) {
+	if len(response) < 12 {
+		return "", fmt.Errorf("short netbios response")
+	}
+	if binary.BigEndian.Uint16(response[0:2]) != id {
+		return "", fmt.Errorf("unexpected netbios response id")
+	}
+	if response[2]&0x80 == 0 {
+		return "", fmt.Errorf("netbios packet is not a response")
+	}
+	offset, err := skipNetBIOSName(response, 12)
+	if err != nil {
+		return "", err
+	}
+	// type(2) class(2) ttl(4) rdlength(2) then the name count.
+	offset += 10
+	if offset >= len(response) {
+		return "", fmt.Errorf("truncated netbios response")
+	}
+	count := int(response[offset])
+	offset++
+	for i := 0; i < count; i++ {
+		if offset+18 > len(response) {
+			break
+		}
+		entry := response[offset : offset+18]
+		offset += 18
+		suffix := entry[15]
+		flags := binary.BigEndian.Uint16(entry[16:18])
+		if suffix != netbiosWorkstation || flags&netbiosGroupFlag != 0 {
+			continue
+		}
+		if name := strings.TrimRight(string(entry[:15]), " \x00"); name != "" {
+			return name, nil
+		}
+	}
+	return "", errNoName
+}
+
+// skipNetBIOSName steps over a length-prefixed label sequence or pointer.
+func skipNetBIOSName(packet []byte, offset int) (int, error) {
+	for offset < len(packet) {
+		length := int(packet[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xc0 == 0xc0:
+			return offset + 2, nil
+		}
+		offset += 1 + length
+	}
+	return 0, fmt.Errorf("truncated netbios name")
+}
@@ -0,0 +1,149 @@
+package hostnames
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	mdnsPort  = 5353
+	llmnrPort = 5355
+)
+
+var errNoName = errors.New("no hostname in response")
+
+// PTRProber sends a reverse-lookup PTR query straight to the device. mDNS
+// responders answer such unicast queries on 5353 (RFC 6762 section 6.7) and
+// LLMNR responders on 5355 (RFC 4795).
+type PTRProber struct {
+	name string
+	port int
+}
+
+// NewMDNSProber returns a prober for mDNS responders (Apple, Linux, printers).
+func NewMDNSProber() *PTRProber {
+	return &PTRProber{name: "mdns", port: mdnsPort}
+}
+
+// NewLLMNRProber returns a prober for LLMNR responders (Windows).
+func NewLLMNRProber() *PTRProber {
+	return &PTRProber{name: "llmnr", port: llmnrPort}
+}
+
+// Name implements Prober.
+func (p *PTRProber) Name() string { return p.name }
+
+// Probe implements Prober.
+func (p *PTRProber) Probe(ctx context.Context, addr netip.Addr) (string, error) {
+	id := uint16(rand.N(1 << 16))
+	query, err := buildPTRQuery(id, addr)
+	if err != nil {
+		return "", err
+	}
+	response, err := exchangeUDP(ctx, netip.AddrPortFrom(addr, uint16(p.port)), query)
+	if err != nil {
+		return "", err
+	}
+	return parsePTRResponse(id, response)
+}
+
+// reverseName returns the in-addr.arpa or ip6.arpa name for addr.
+func reverseName(addr netip.Addr) string {
+	addr = addr.Unmap()
+	if addr.Is4() {
+		octets := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", octets[3], octets[2], octets[1], octets[0])
+	}
+	raw := addr.As16()
+	var builder strings.Builder
+	for i := len(raw) - 1; i >= 0; i-- {
+		fmt.Fprintf(&builder, "%x.%x.", raw[i]&0x0f, raw[i]>>4)
+	}
+	builder.WriteString("ip6.arpa.")
+	return builder.String()
+}
+
+func buildPTRQuery(id uint16, addr netip.Addr) ([]byte, error) {
+	name, err := dnsmessage.NewName(reverseName(addr))
+	if err != nil {
+		return nil, err
+	}
+	message := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  dnsmessage.TypePTR,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	return message.Pack()
+}
+
+func parsePTRResponse(id uint16, response []byte) (string, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(response)
+	if err != nil {
+		return "", err
+	}
+	// mDNS responders may zero the ID; only reject a mismatched non-zero one.
+	if !header.Response || (header.ID != 0 && header.ID != id) {
+		return "", fmt.Errorf("unexpected response id %d", header.ID)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return "", err
+	}
+	for {
+		answer, err := parser.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			return "", errNoName
+		}
+		if err != nil {
+			return "", err
+		}
+		if answer.Type != dnsmessage.TypePTR {
+			if err := parser.SkipAnswer(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		ptr, err := parser.PTRResource()
+		if err != nil {
+			return "", err
+		}
+		return ptr.PTR.String(), nil
+	}
+}
+
+// exchangeUDP sends one datagram and waits for the first reply from target.
+func exchangeUDP(ctx context.Context, target netip.AddrPort, payload []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", target.String())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultProbeTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(payload); err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, 1500)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer[:n], nil
+}
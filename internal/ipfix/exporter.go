@@ -0,0 +1,292 @@
+// Package ipfix exports the flows matched to routing groups as IPFIX
+// (RFC 7011) records over UDP, so an existing collector can account for
+// the traffic each group steers through a VPN.
+package ipfix
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+// DefaultPort is the IANA IPFIX port, used when the collector names none.
+const DefaultPort = "4739"
+
+const sendTimeout = 5 * time.Second
+
+// CollectorAddress returns the collector's host:port from settings, or ""
+// when export is off.
+func CollectorAddress(current settings.Settings) string {
+	collector := strings.TrimSpace(current.IPFIXCollector)
+	if collector == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(collector); err != nil {
+		return net.JoinHostPort(strings.Trim(collector, "[]"), DefaultPort)
+	}
+	return collector
+}
+
+// ValidateSettings checks the IPFIX settings.
+func ValidateSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	if address := CollectorAddress(current); address != "" {
+		host, port, err := net.SplitHostPort(address)
+		number, portErr := strconv.Atoi(port)
+		if err != nil || host == "" || portErr != nil || number < 1 || number > 65535 {
+			errs.Addf("ipfixCollector", "ipfixCollector must be a host or host:port")
+		}
+	}
+	if current.IPFIXObservationDomain < 0 || current.IPFIXObservationDomain > math.MaxUint32 {
+		errs.Addf("ipfixObservationDomain", "ipfixObservationDomain must be between 0 and %d", uint32(math.MaxUint32))
+	}
+	return errs.Err()
+}
+
+// SettingsSource provides the current IPFIX settings.
+type SettingsSource interface {
+	Get() (settings.Settings, error)
+}
+
+// Flow is one matched conntrack flow with its cumulative counters.
+type Flow struct {
+	Key             string
+	Group           string
+	Protocol        string
+	SourceIP        string
+	SourcePort      int
+	DestinationIP   string
+	DestinationPort int
+	UploadBytes     uint64
+	DownloadBytes   uint64
+	UploadPackets   uint64
+	DownloadPackets uint64
+}
+
+// Status describes the collector and the last export.
+type Status struct {
+	Enabled    bool      `json:"enabled"`
+	Collector  string    `json:"collector,omitempty"`
+	LastExport time.Time `json:"lastExport,omitzero"`
+	Records    uint64    `json:"records"`
+	LastError  string    `json:"lastError,omitempty"`
+}
+
+// counters is what a flow had reached when last exported.
+type counters struct {
+	upload, download               uint64
+	uploadPackets, downloadPackets uint64
+	at                             time.Time
+}
+
+// Exporter turns sampled flows into delta records: each export carries the
+// bytes and packets a flow moved since the previous sample, one record per
+// direction.
+type Exporter struct {
+	settings SettingsSource
+	dial     func(ctx context.Context, address string) (net.Conn, error)
+	now      func() time.Time
+
+	mu        sync.Mutex
+	collector string
+	conn      net.Conn
+	seen      map[string]map[string]counters
+	sequence  uint32
+	status    Status
+}
+
+// NewExporter creates an exporter.
+func NewExporter(source SettingsSource) (*Exporter, error) {
+	if source == nil {
+		return nil, fmt.Errorf("settings source is required")
+	}
+	return &Exporter{
+		settings: source,
+		dial: func(ctx context.Context, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", address)
+		},
+		now:  time.Now,
+		seen: make(map[string]map[string]counters),
+	}, nil
+}
+
+// Status reports the configured collector and the outcome of the last
+// export to it.
+func (e *Exporter) Status() Status {
+	collector := ""
+	if current, err := e.settings.Get(); err == nil {
+		collector = CollectorAddress(current)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	status := e.status
+	if collector != e.collector {
+		status = Status{}
+	}
+	status.Enabled = collector != ""
+	status.Collector = collector
+	return status
+}
+
+// Export sends the growth of vpn's flows since its previous export. Flows
+// that disappeared are forgotten; a flow seen for the first time exports
+// everything it has counted.
+func (e *Exporter) Export(ctx context.Context, vpn string, flows []Flow) error {
+	current, err := e.settings.Get()
+	if err != nil {
+		return err
+	}
+	collector := CollectorAddress(current)
+	domain := uint32(current.IPFIXObservationDomain)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if collector != e.collector {
+		e.closeLocked()
+		e.collector = collector
+		e.seen = make(map[string]map[string]counters)
+		e.status = Status{}
+	}
+	if collector == "" {
+		return nil
+	}
+
+	now := e.now()
+	previous := e.seen[vpn]
+	next := make(map[string]counters, len(flows))
+	records := make([]Record, 0, 2*len(flows))
+	for _, flow := range flows {
+		source, sourceErr := netip.ParseAddr(flow.SourceIP)
+		destination, destinationErr := netip.ParseAddr(flow.DestinationIP)
+		if sourceErr != nil || destinationErr != nil {
+			continue
+		}
+		source, destination = source.Unmap(), destination.Unmap()
+		if source.Is4() != destination.Is4() {
+			continue
+		}
+		last, known := previous[flow.Key]
+		sample := counters{upload: flow.UploadBytes, download: flow.DownloadBytes, uploadPackets: flow.UploadPackets, downloadPackets: flow.DownloadPackets, at: now}
+		next[flow.Key] = sample
+		start := now
+		if known {
+			start = last.at
+		}
+		base := Record{
+			Protocol: protocolNumber(flow.Protocol),
+			Start:    start,
+			End:      now,
+			VPN:      vpn,
+			Group:    flow.Group,
+		}
+		if octets, packets := delta(sample.upload, last.upload, sample.uploadPackets, last.uploadPackets); octets > 0 {
+			forward := base
+			forward.Source, forward.Destination = source, destination
+			forward.SourcePort, forward.DestinationPort = uint16(flow.SourcePort), uint16(flow.DestinationPort)
+			forward.Octets, forward.Packets = octets, packets
+			records = append(records, forward)
+		}
+		if octets, packets := delta(sample.download, last.download, sample.downloadPackets, last.downloadPackets); octets > 0 {
+			reverse := base
+			reverse.Source, reverse.Destination = destination, source
+			reverse.SourcePort, reverse.DestinationPort = uint16(flow.DestinationPort), uint16(flow.SourcePort)
+			reverse.Octets, reverse.Packets = octets, packets
+			records = append(records, reverse)
+		}
+	}
+	e.seen[vpn] = next
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := e.sendLocked(ctx, Messages(records, domain, now, e.sequence)); err != nil {
+		e.status.LastError = err.Error()
+		return err
+	}
+	e.sequence += uint32(len(records))
+	e.status.Records += uint64(len(records))
+	e.status.LastExport = now
+	e.status.LastError = ""
+	return nil
+}
+
+// Forget drops a VPN's flow state, used when its profile is deleted.
+func (e *Exporter) Forget(vpn string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.seen, vpn)
+}
+
+// Close releases the collector socket.
+func (e *Exporter) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closeLocked()
+}
+
+func (e *Exporter) sendLocked(ctx context.Context, messages [][]byte) error {
+	if e.conn == nil {
+		dialCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		conn, err := e.dial(dialCtx, e.collector)
+		cancel()
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+	}
+	_ = e.conn.SetWriteDeadline(time.Now().Add(sendTimeout))
+	for _, message := range messages {
+		if _, err := e.conn.Write(message); err != nil {
+			e.closeLocked()
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) closeLocked() {
+	if e.conn != nil {
+		_ = e.conn.Close()
+		e.conn = nil
+	}
+}
+
+// delta returns how far counters grew; a counter that went backwards
+// belongs to a new connection reusing the key and counts from zero.
+func delta(octets, lastOctets, packets, lastPackets uint64) (uint64, uint64) {
+	if octets < lastOctets || packets < lastPackets {
+		return octets, packets
+	}
+	return octets - lastOctets, packets - lastPackets
+}
+
+func protocolNumber(protocol string) uint8 {
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "icmp":
+		return 1
+	case "tcp":
+		return 6
+	case "udp":
+		return 17
+	case "gre":
+		return 47
+	case "icmpv6":
+		return 58
+	case "sctp":
+		return 132
+	default:
+		if number, err := strconv.Atoi(protocol); err == nil && number >= 0 && number <= 255 {
+			return uint8(number)
+		}
+		return 0
+	}
+}
@@ -0,0 +1,274 @@
+package ipfix
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+type staticSettings struct {
+	value settings.Settings
+}
+
+func (s *staticSettings) Get() (settings.Settings, error) { return s.value, nil }
+
+var testTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// decodedRecord is a data record read back from a message.
+type decodedRecord struct {
+	template        uint16
+	source, dest    netip.Addr
+	sport, dport    uint16
+	protocol        uint8
+	octets, packets uint64
+	start, end      uint32
+	vpn, group      string
+}
+
+// decodeMessage checks a message's header and template set and returns its
+// sequence number and data records.
+func decodeMessage(t *testing.T, message []byte) (uint32, uint32, []decodedRecord) {
+	t.Helper()
+	if len(message) < headerLength {
+		t.Fatalf("message too short: %d", len(message))
+	}
+	if v := binary.BigEndian.Uint16(message[0:]); v != version {
+		t.Fatalf("unexpected version %d", v)
+	}
+	if n := binary.BigEndian.Uint16(message[2:]); int(n) != len(message) {
+		t.Fatalf("header length %d, message is %d bytes", n, len(message))
+	}
+	if len(message) > maxMessage {
+		t.Fatalf("message of %d bytes exceeds %d", len(message), maxMessage)
+	}
+	sequence := binary.BigEndian.Uint32(message[8:])
+	domain := binary.BigEndian.Uint32(message[12:])
+	var records []decodedRecord
+	sawTemplates := false
+	for rest := message[headerLength:]; len(rest) > 0; {
+		id := binary.BigEndian.Uint16(rest[0:])
+		length := int(binary.BigEndian.Uint16(rest[2:]))
+		set := rest[4:length]
+		rest = rest[length:]
+		if id == templateSetID {
+			sawTemplates = true
+			// IPv4 template: ID, field count, then the first field.
+			if binary.BigEndian.Uint16(set[0:]) != templateIPv4 || binary.BigEndian.Uint16(set[2:]) != 11 {
+				t.Fatalf("unexpected first template header % x", set[:4])
+			}
+			if binary.BigEndian.Uint16(set[4:]) != fieldSourceIPv4Address.id {
+				t.Fatalf("unexpected first template field % x", set[4:8])
+			}
+			continue
+		}
+		addrLen := 4
+		if id == templateIPv6 {
+			addrLen = 16
+		}
+		for len(set) > 0 {
+			var record decodedRecord
+			record.template = id
+			record.source, _ = netip.AddrFromSlice(set[:addrLen])
+			record.dest, _ = netip.AddrFromSlice(set[addrLen : 2*addrLen])
+			set = set[2*addrLen:]
+			record.sport = binary.BigEndian.Uint16(set[0:])
+			record.dport = binary.BigEndian.Uint16(set[2:])
+			record.protocol = set[4]
+			record.octets = binary.BigEndian.Uint64(set[5:])
+			record.packets = binary.BigEndian.Uint64(set[13:])
+			record.start = binary.BigEndian.Uint32(set[21:])
+			record.end = binary.BigEndian.Uint32(set[25:])
+			set = set[29:]
+			n := int(set[0])
+			record.vpn = string(set[1 : 1+n])
+			set = set[1+n:]
+			n = int(set[0])
+			record.group = string(set[1 : 1+n])
+			set = set[1+n:]
+			records = append(records, record)
+		}
+	}
+	if !sawTemplates {
+		t.Fatal("message carries no template set")
+	}
+	return sequence, domain, records
+}
+
+func TestMessagesEncodeRecords(t *testing.T) {
+	records := []Record{
+		{
+			Source: netip.MustParseAddr("192.168.1.20"), Destination: netip.MustParseAddr("1.1.1.1"),
+			SourcePort: 51000, DestinationPort: 443, Protocol: 6, Octets: 1200, Packets: 10,
+			Start: testTime.Add(-time.Minute), End: testTime, VPN: "wg-fra", Group: "streaming",
+		},
+		{
+			Source: netip.MustParseAddr("fd00::20"), Destination: netip.MustParseAddr("2606:4700::1111"),
+			SourcePort: 51001, DestinationPort: 53, Protocol: 17, Octets: 80, Packets: 1,
+			Start: testTime, End: testTime, VPN: "wg-fra",
+		},
+	}
+	messages := Messages(records, 7, testTime, 42)
+	if len(messages) != 1 {
+		t.Fatalf("expected one message, got %d", len(messages))
+	}
+	sequence, domain, got := decodeMessage(t, messages[0])
+	if sequence != 42 || domain != 7 {
+		t.Fatalf("unexpected sequence %d domain %d", sequence, domain)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected two records, got %+v", got)
+	}
+	want := decodedRecord{
+		template: templateIPv4, source: records[0].Source, dest: records[0].Destination,
+		sport: 51000, dport: 443, protocol: 6, octets: 1200, packets: 10,
+		start: uint32(testTime.Add(-time.Minute).Unix()), end: uint32(testTime.Unix()),
+		vpn: "wg-fra", group: "streaming",
+	}
+	if got[0] != want {
+		t.Fatalf("unexpected IPv4 record\n got %+v\nwant %+v", got[0], want)
+	}
+	if got[1].template != templateIPv6 || got[1].dest != records[1].Destination || got[1].group != "" {
+		t.Fatalf("unexpected IPv6 record %+v", got[1])
+	}
+}
+
+func TestMessagesSplitLargeExports(t *testing.T) {
+	records := make([]Record, 100)
+	for i := range records {
+		records[i] = Record{
+			Source: netip.MustParseAddr("10.0.0.1"), Destination: netip.MustParseAddr("10.0.0.2"),
+			Octets: uint64(i + 1), VPN: "wg-fra", Group: strings.Repeat("g", 40),
+		}
+	}
+	messages := Messages(records, 0, testTime, 0)
+	if len(messages) < 2 {
+		t.Fatalf("expected the export to be split, got %d message", len(messages))
+	}
+	total := 0
+	for _, message := range messages {
+		sequence, _, got := decodeMessage(t, message)
+		if int(sequence) != total {
+			t.Fatalf("message sequence %d, expected %d", sequence, total)
+		}
+		total += len(got)
+	}
+	if total != len(records) {
+		t.Fatalf("expected %d records, decoded %d", len(records), total)
+	}
+}
+
+func TestExporterSendsDeltas(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	exporter, err := NewExporter(&staticSettings{value: settings.Settings{IPFIXCollector: listener.LocalAddr().String(), IPFIXObservationDomain: 3}})
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+	defer exporter.Close()
+	at := testTime
+	exporter.now = func() time.Time { return at }
+
+	receive := func() []decodedRecord {
+		t.Helper()
+		buf := make([]byte, 2048)
+		_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		_, domain, records := decodeMessage(t, buf[:n])
+		if domain != 3 {
+			t.Fatalf("unexpected observation domain %d", domain)
+		}
+		return records
+	}
+
+	flow := Flow{
+		Key: "tcp|192.168.1.20|51000|1.1.1.1|443", Group: "streaming", Protocol: "tcp",
+		SourceIP: "192.168.1.20", SourcePort: 51000, DestinationIP: "1.1.1.1", DestinationPort: 443,
+		UploadBytes: 500, DownloadBytes: 4000, UploadPackets: 5, DownloadPackets: 8,
+	}
+	if err := exporter.Export(context.Background(), "wg-fra", []Flow{flow}); err != nil {
+		t.Fatalf("first export: %v", err)
+	}
+	first := receive()
+	if len(first) != 2 {
+		t.Fatalf("expected a record per direction, got %+v", first)
+	}
+	if first[0].octets != 500 || first[0].sport != 51000 || first[0].protocol != 6 || first[0].group != "streaming" {
+		t.Fatalf("unexpected forward record %+v", first[0])
+	}
+	if first[1].octets != 4000 || first[1].source != netip.MustParseAddr("1.1.1.1") || first[1].dport != 51000 {
+		t.Fatalf("unexpected reverse record %+v", first[1])
+	}
+
+	// Only the download grew, so only a reverse record carrying the growth
+	// since the first export goes out.
+	at = testTime.Add(time.Minute)
+	flow.DownloadBytes, flow.DownloadPackets = 6000, 10
+	if err := exporter.Export(context.Background(), "wg-fra", []Flow{flow}); err != nil {
+		t.Fatalf("second export: %v", err)
+	}
+	second := receive()
+	if len(second) != 1 || second[0].octets != 2000 || second[0].packets != 2 {
+		t.Fatalf("unexpected delta records %+v", second)
+	}
+	if second[0].start != uint32(testTime.Unix()) || second[0].end != uint32(at.Unix()) {
+		t.Fatalf("unexpected delta interval %+v", second[0])
+	}
+
+	// An unchanged flow sends nothing.
+	if err := exporter.Export(context.Background(), "wg-fra", []Flow{flow}); err != nil {
+		t.Fatalf("third export: %v", err)
+	}
+	if status := exporter.Status(); !status.Enabled || status.Records != 3 || status.LastError != "" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestExporterOffSendsNothing(t *testing.T) {
+	exporter, err := NewExporter(&staticSettings{})
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+	exporter.dial = func(context.Context, string) (net.Conn, error) {
+		t.Fatal("expected no collector connection while export is off")
+		return nil, nil
+	}
+	flow := Flow{Key: "k", SourceIP: "10.0.0.1", DestinationIP: "10.0.0.2", UploadBytes: 1}
+	if err := exporter.Export(context.Background(), "wg-fra", []Flow{flow}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if status := exporter.Status(); status.Enabled {
+		t.Fatalf("expected export to be off, got %+v", status)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	for _, collector := range []string{"", "collector.lan", "10.0.0.5:2055", "[fd00::5]:4739", "fd00::5"} {
+		if err := ValidateSettings(settings.Settings{IPFIXCollector: collector}); err != nil {
+			t.Fatalf("expected %q to be valid, got %v", collector, err)
+		}
+	}
+	if got := CollectorAddress(settings.Settings{IPFIXCollector: "fd00::5"}); got != "[fd00::5]:4739" {
+		t.Fatalf("unexpected default port address %q", got)
+	}
+	for _, collector := range []string{"collector.lan:0", "collector.lan:flow", ":4739"} {
+		if err := ValidateSettings(settings.Settings{IPFIXCollector: collector}); err == nil {
+			t.Fatalf("expected %q to be rejected", collector)
+		}
+	}
+	if err := ValidateSettings(settings.Settings{IPFIXObservationDomain: -1}); err == nil || !strings.Contains(err.Error(), "ipfixObservationDomain") {
+		t.Fatalf("expected negative domain to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package ipfix
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"time"
+)
+
+const (
+	version       = 10
+	headerLength  = 16
+	templateSetID = 2
+
+	// Template IDs for IPv4 and IPv6 records; data sets carry these IDs.
+	templateIPv4 = 256
+	templateIPv6 = 257
+
+	// EnterpriseNumber scopes the vpnName and groupName elements. It is the
+	// documentation number from RFC 5612; collectors map it like any other
+	// private enterprise.
+	EnterpriseNumber = 32473
+
+	// maxMessage keeps each message within one unfragmented datagram.
+	maxMessage = 1400
+	// maxName bounds the variable-length name fields to their short form.
+	maxName = 254
+
+	enterpriseBit  = 0x8000
+	variableLength = 0xffff
+)
+
+// Information elements (IANA registry unless enterprise is set).
+type field struct {
+	id         uint16
+	length     uint16
+	enterprise bool
+}
+
+var (
+	fieldOctetDeltaCount        = field{id: 1, length: 8}
+	fieldPacketDeltaCount       = field{id: 2, length: 8}
+	fieldProtocolIdentifier     = field{id: 4, length: 1}
+	fieldSourceTransportPort    = field{id: 7, length: 2}
+	fieldSourceIPv4Address      = field{id: 8, length: 4}
+	fieldDestinationTransport   = field{id: 11, length: 2}
+	fieldDestinationIPv4Address = field{id: 12, length: 4}
+	fieldSourceIPv6Address      = field{id: 27, length: 16}
+	fieldDestinationIPv6Address = field{id: 28, length: 16}
+	fieldFlowStartSeconds       = field{id: 150, length: 4}
+	fieldFlowEndSeconds         = field{id: 151, length: 4}
+	// VPNName and GroupName are enterprise elements 1 and 2.
+	fieldVPNName   = field{id: 1, length: variableLength, enterprise: true}
+	fieldGroupName = field{id: 2, length: variableLength, enterprise: true}
+)
+
+func templateFields(v6 bool) []field {
+	source, destination := fieldSourceIPv4Address, fieldDestinationIPv4Address
+	if v6 {
+		source, destination = fieldSourceIPv6Address, fieldDestinationIPv6Address
+	}
+	return []field{
+		source,
+		destination,
+		fieldSourceTransportPort,
+		fieldDestinationTransport,
+		fieldProtocolIdentifier,
+		fieldOctetDeltaCount,
+		fieldPacketDeltaCount,
+		fieldFlowStartSeconds,
+		fieldFlowEndSeconds,
+		fieldVPNName,
+		fieldGroupName,
+	}
+}
+
+// Record is one unidirectional flow record.
+type Record struct {
+	Source          netip.Addr
+	Destination     netip.Addr
+	SourcePort      uint16
+	DestinationPort uint16
+	Protocol        uint8
+	Octets          uint64
+	Packets         uint64
+	Start           time.Time
+	End             time.Time
+	VPN             string
+	Group           string
+}
+
+// templateSet encodes the template set announcing both templates.
+func templateSet() []byte {
+	set := []byte{0, templateSetID, 0, 0}
+	for _, template := range []struct {
+		id uint16
+		v6 bool
+	}{{templateIPv4, false}, {templateIPv6, true}} {
+		fields := templateFields(template.v6)
+		set = binary.BigEndian.AppendUint16(set, template.id)
+		set = binary.BigEndian.AppendUint16(set, uint16(len(fields)))
+		for _, f := range fields {
+			id := f.id
+			if f.enterprise {
+				id |= enterpriseBit
+			}
+			set = binary.BigEndian.AppendUint16(set, id)
+			set = binary.BigEndian.AppendUint16(set, f.length)
+			if f.enterprise {
+				set = binary.BigEndian.AppendUint32(set, EnterpriseNumber)
+			}
+		}
+	}
+	binary.BigEndian.PutUint16(set[2:], uint16(len(set)))
+	return set
+}
+
+// encodeRecord appends record's data fields in template order.
+func encodeRecord(b []byte, record Record) []byte {
+	b = append(b, record.Source.AsSlice()...)
+	b = append(b, record.Destination.AsSlice()...)
+	b = binary.BigEndian.AppendUint16(b, record.SourcePort)
+	b = binary.BigEndian.AppendUint16(b, record.DestinationPort)
+	b = append(b, record.Protocol)
+	b = binary.BigEndian.AppendUint64(b, record.Octets)
+	b = binary.BigEndian.AppendUint64(b, record.Packets)
+	b = binary.BigEndian.AppendUint32(b, uint32(record.Start.Unix()))
+	b = binary.BigEndian.AppendUint32(b, uint32(record.End.Unix()))
+	b = appendName(b, record.VPN)
+	b = appendName(b, record.Group)
+	return b
+}
+
+func appendName(b []byte, name string) []byte {
+	if len(name) > maxName {
+		name = name[:maxName]
+	}
+	b = append(b, byte(len(name)))
+	return append(b, name...)
+}
+
+// Messages packs records into IPFIX messages of at most maxMessage bytes.
+// Every message repeats the templates, so a collector that restarts or
+// drops a datagram can decode the next one; sequence counts the data
+// records sent before each message.
+func Messages(records []Record, domain uint32, exportTime time.Time, sequence uint32) [][]byte {
+	templates := templateSet()
+	var messages [][]byte
+	var message []byte
+	var set []byte
+	setTemplate := uint16(0)
+	flushSet := func() {
+		if len(set) > 0 {
+			binary.BigEndian.PutUint16(set[2:], uint16(len(set)))
+			message = append(message, set...)
+			set = nil
+		}
+	}
+	flushMessage := func() {
+		flushSet()
+		if message == nil {
+			return
+		}
+		binary.BigEndian.PutUint16(message[2:], uint16(len(message)))
+		messages = append(messages, message)
+		message = nil
+	}
+	for _, record := range records {
+		template := uint16(templateIPv4)
+		if record.Source.Is6() {
+			template = templateIPv6
+		}
+		encoded := encodeRecord(nil, record)
+		if message != nil && len(message)+len(set)+len(encoded)+4 > maxMessage {
+			flushMessage()
+		}
+		if message == nil {
+			message = make([]byte, headerLength, maxMessage)
+			binary.BigEndian.PutUint16(message[0:], version)
+			binary.BigEndian.PutUint32(message[4:], uint32(exportTime.Unix()))
+			binary.BigEndian.PutUint32(message[8:], sequence)
+			binary.BigEndian.PutUint32(message[12:], domain)
+			message = append(message, templates...)
+			setTemplate = 0
+		}
+		if set == nil || template != setTemplate {
+			flushSet()
+			set = binary.BigEndian.AppendUint16(nil, template)
+			set = append(set, 0, 0)
+			setTemplate = template
+		}
+		set = append(set, encoded...)
+		sequence++
+	}
+	flushMessage()
+	return messages
+}
@@ -0,0 +1,242 @@
+// Package iprule manages fwmark policy routing rules and inspects route
+// tables over rtnetlink, replacing `ip rule` / `ip route` shell-outs and the
+// text parsing of their output.
+package iprule
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/mdlayher/netlink"
+)
+
+// rtnetlink message types and attributes (linux/rtnetlink.h, linux/fib_rules.h).
+const (
+	netlinkRoute = 0
+
+	rtmGetRoute = 26
+	rtmNewRule  = 32
+	rtmDelRule  = 33
+	rtmGetRule  = 34
+
+	afInet  = 2
+	afInet6 = 10
+
+	fraPriority = 6
+	fraFWMark   = 10
+	fraTable    = 15
+	fraFWMask   = 16
+
+	rtaTable = 15
+
+	frActToTable  = 1
+	rtTableUnspec = 0
+
+	// fib_rule_hdr and rtmsg are both 12 bytes.
+	headerLen = 12
+)
+
+// Rule is one fwmark -> table policy rule.
+type Rule struct {
+	Priority uint32
+	Mark     uint32
+	// Mask is the fwmark mask; zero means the kernel default of 0xffffffff.
+	Mask  uint32
+	Table int
+}
+
+// Client talks rtnetlink. Each call opens its own short-lived socket, so a
+// Client is safe for concurrent use.
+type Client struct{}
+
+// New returns an rtnetlink client.
+func New() *Client {
+	return &Client{}
+}
+
+// List returns all fwmark rules of one address family. Rules without a
+// fwmark selector are skipped.
+func (c *Client) List(ipv6 bool) ([]Rule, error) {
+	replies, err := execute(netlink.Message{
+		Header: netlink.Header{Type: rtmGetRule, Flags: netlink.Request | netlink.Dump},
+		Data:   make([]byte, headerLen),
+	}, ipv6)
+	if err != nil {
+		return nil, fmt.Errorf("list ip rules: %w", err)
+	}
+	rules := make([]Rule, 0, len(replies))
+	for _, reply := range replies {
+		if reply.Header.Type != rtmNewRule {
+			continue
+		}
+		rule, ok, err := decodeRule(reply.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode ip rule: %w", err)
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// Add installs a rule. Adding a rule that already exists is not an error.
+func (c *Client) Add(rule Rule, ipv6 bool) error {
+	data, err := encodeRule(rule, ipv6)
+	if err != nil {
+		return err
+	}
+	_, err = execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  rtmNewRule,
+			Flags: netlink.Request | netlink.Acknowledge | netlink.Create | netlink.Excl,
+		},
+		Data: data,
+	}, ipv6)
+	if err != nil && !errors.Is(err, syscall.EEXIST) {
+		return fmt.Errorf("add ip rule fwmark 0x%x table %d: %w", rule.Mark, rule.Table, err)
+	}
+	return nil
+}
+
+// Delete removes one matching rule. It returns an error wrapping
+// syscall.ENOENT when no such rule exists.
+func (c *Client) Delete(rule Rule, ipv6 bool) error {
+	data, err := encodeRule(rule, ipv6)
+	if err != nil {
+		return err
+	}
+	_, err = execute(netlink.Message{
+		Header: netlink.Header{Type: rtmDelRule, Flags: netlink.Request | netlink.Acknowledge},
+		Data:   data,
+	}, ipv6)
+	if err != nil {
+		return fmt.Errorf("delete ip rule fwmark 0x%x table %d: %w", rule.Mark, rule.Table, err)
+	}
+	return nil
+}
+
+// RouteTables returns the distinct route table ids that hold at least one
+// route of the given family.
+func (c *Client) RouteTables(ipv6 bool) ([]int, error) {
+	replies, err := execute(netlink.Message{
+		Header: netlink.Header{Type: rtmGetRoute, Flags: netlink.Request | netlink.Dump},
+		Data:   make([]byte, headerLen),
+	}, ipv6)
+	if err != nil {
+		return nil, fmt.Errorf("list routes: %w", err)
+	}
+	seen := make(map[int]struct{})
+	tables := make([]int, 0)
+	for _, reply := range replies {
+		table, ok, err := decodeRouteTable(reply.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode route: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		if _, exists := seen[table]; exists {
+			continue
+		}
+		seen[table] = struct{}{}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func execute(request netlink.Message, ipv6 bool) ([]netlink.Message, error) {
+	request.Data[0] = family(ipv6)
+	conn, err := netlink.Dial(netlinkRoute, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.Execute(request)
+}
+
+func family(ipv6 bool) byte {
+	if ipv6 {
+		return afInet6
+	}
+	return afInet
+}
+
+func encodeRule(rule Rule, ipv6 bool) ([]byte, error) {
+	if rule.Table <= 0 || int64(rule.Table) > 0xffffffff {
+		return nil, fmt.Errorf("invalid route table %d", rule.Table)
+	}
+	header := make([]byte, headerLen)
+	header[0] = family(ipv6)
+	header[4] = rtTableUnspec
+	if rule.Table < 256 {
+		header[4] = byte(rule.Table)
+	}
+	header[7] = frActToTable
+
+	encoder := netlink.NewAttributeEncoder()
+	encoder.Uint32(fraPriority, rule.Priority)
+	encoder.Uint32(fraFWMark, rule.Mark)
+	if rule.Mask != 0 {
+		encoder.Uint32(fraFWMask, rule.Mask)
+	}
+	encoder.Uint32(fraTable, uint32(rule.Table))
+	attrs, err := encoder.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return append(header, attrs...), nil
+}
+
+// decodeRule parses a fib_rule_hdr message. ok is false for rules that have
+// no fwmark selector.
+func decodeRule(data []byte) (Rule, bool, error) {
+	if len(data) < headerLen {
+		return Rule{}, false, fmt.Errorf("short rule message (%d bytes)", len(data))
+	}
+	rule := Rule{Table: int(data[4])}
+	hasMark := false
+	decoder, err := netlink.NewAttributeDecoder(data[headerLen:])
+	if err != nil {
+		return Rule{}, false, err
+	}
+	for decoder.Next() {
+		switch decoder.Type() {
+		case fraPriority:
+			rule.Priority = decoder.Uint32()
+		case fraFWMark:
+			rule.Mark = decoder.Uint32()
+			hasMark = true
+		case fraFWMask:
+			rule.Mask = decoder.Uint32()
+		case fraTable:
+			rule.Table = int(decoder.Uint32())
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return Rule{}, false, err
+	}
+	return rule, hasMark, nil
+}
+
+// decodeRouteTable parses the table id of an rtmsg route message.
+func decodeRouteTable(data []byte) (int, bool, error) {
+	if len(data) < headerLen {
+		return 0, false, fmt.Errorf("short route message (%d bytes)", len(data))
+	}
+	table := int(data[4])
+	decoder, err := netlink.NewAttributeDecoder(data[headerLen:])
+	if err != nil {
+		return 0, false, err
+	}
+	for decoder.Next() {
+		if decoder.Type() == rtaTable {
+			table = int(decoder.Uint32())
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return 0, false, err
+	}
+	return table, table != rtTableUnspec, nil
+}
@@ -0,0 +1,69 @@
+package iprule
+
+import (
+	"testing"
+
+	"github.com/mdlayher/netlink"
+)
+
+func TestEncodeRuleRoundTrip(t *testing.T) {
+	for _, want := range []Rule{
+		{Priority: 100, Mark: 0x169, Table: 201},
+		{Priority: 100, Mark: 0x1a0000, Mask: 0xff0000, Table: 1000},
+	} {
+		data, err := encodeRule(want, true)
+		if err != nil {
+			t.Fatalf("encode %+v: %v", want, err)
+		}
+		if data[0] != afInet6 || data[7] != frActToTable {
+			t.Fatalf("unexpected rule header % x", data[:headerLen])
+		}
+		got, ok, err := decodeRule(data)
+		if err != nil || !ok {
+			t.Fatalf("decode %+v: ok=%v err=%v", want, ok, err)
+		}
+		if got != want {
+			t.Fatalf("round trip mismatch: got %+v want %+v", got, want)
+		}
+	}
+	if _, err := encodeRule(Rule{Mark: 0x169}, false); err == nil {
+		t.Fatalf("expected missing table to be rejected")
+	}
+}
+
+func TestDecodeRuleSkipsRulesWithoutMark(t *testing.T) {
+	header := make([]byte, headerLen)
+	header[4] = 254
+	encoder := netlink.NewAttributeEncoder()
+	encoder.Uint32(fraPriority, 32766)
+	attrs, err := encoder.Encode()
+	if err != nil {
+		t.Fatalf("encode attrs: %v", err)
+	}
+	rule, ok, err := decodeRule(append(header, attrs...))
+	if err != nil || ok {
+		t.Fatalf("expected main-table rule without fwmark to be skipped, got %+v ok=%v err=%v", rule, ok, err)
+	}
+	if _, _, err := decodeRule([]byte{2}); err == nil {
+		t.Fatalf("expected short message to fail")
+	}
+}
+
+func TestDecodeRouteTablePrefersTableAttribute(t *testing.T) {
+	header := make([]byte, headerLen)
+	header[4] = 252
+	encoder := netlink.NewAttributeEncoder()
+	encoder.Uint32(rtaTable, 1201)
+	attrs, err := encoder.Encode()
+	if err != nil {
+		t.Fatalf("encode attrs: %v", err)
+	}
+	table, ok, err := decodeRouteTable(append(header, attrs...))
+	if err != nil || !ok || table != 1201 {
+		t.Fatalf("expected table 1201, got %d ok=%v err=%v", table, ok, err)
+	}
+	header[4] = 201
+	if table, ok, _ := decodeRouteTable(header); !ok || table != 201 {
+		t.Fatalf("expected header table 201, got %d ok=%v", table, ok)
+	}
+}
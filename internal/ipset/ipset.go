@@ -0,0 +1,381 @@
+// Package ipset manages hash:net sets over the nfnetlink ipset protocol,
+// replacing `ipset` shell-outs and the text parsing of their output.
+package ipset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/mdlayher/netlink"
+)
+
+// nfnetlink ipset message types and attributes (linux/netfilter/ipset/ip_set.h).
+const (
+	netlinkNetfilter = 12
+	nfnlSubsysIPSet  = 6
+
+	// protocolVersion is IPSET_PROTOCOL_MIN, accepted by every kernel with
+	// ipset support.
+	protocolVersion = 6
+
+	cmdCreate  = 2
+	cmdDestroy = 3
+	cmdFlush   = 4
+	cmdSwap    = 6
+	cmdList    = 7
+	cmdAdd     = 9
+	cmdDel     = 10
+
+	attrProtocol = 1
+	attrSetName  = 2
+	attrTypeName = 3
+	attrSetName2 = 3
+	attrRevision = 4
+	attrFamily   = 5
+	attrFlags    = 6
+	attrData     = 7
+	attrADT      = 8
+
+	attrIP      = 1
+	attrCIDR    = 3
+	attrTimeout = 6
+
+	attrIPAddrIPv4 = 1
+	attrIPAddrIPv6 = 2
+
+	flagListSetName = 1 << 1
+
+	afInet      = 2
+	nfprotoIPv4 = 2
+	nfprotoIPv6 = 10
+
+	hashNetType     = "hash:net"
+	hashNetRevision = 0
+
+	// nfgenmsg is family, version and a 16-bit resource id.
+	headerLen = 4
+
+	// maxBatchEntries keeps one ADT attribute well under the 64 KiB netlink
+	// attribute length limit.
+	maxBatchEntries = 1000
+)
+
+// Client talks nfnetlink. Each call opens its own short-lived socket, so a
+// Client is safe for concurrent use.
+type Client struct{}
+
+// New returns an nfnetlink ipset client.
+func New() *Client {
+	return &Client{}
+}
+
+// Create makes a hash:net set of the given family ("inet" or "inet6") with a
+// default entry timeout. Creating a set that already exists with the same
+// parameters is not an error.
+func (c *Client) Create(name, family string, timeoutSeconds int) error {
+	proto, err := nfproto(family)
+	if err != nil {
+		return err
+	}
+	_, err = execute(cmdCreate, 0, func(ae *netlink.AttributeEncoder) {
+		ae.String(attrSetName, name)
+		ae.String(attrTypeName, hashNetType)
+		ae.Uint8(attrRevision, hashNetRevision)
+		ae.Uint8(attrFamily, proto)
+		ae.Nested(attrData, func(nae *netlink.AttributeEncoder) error {
+			putNetUint32(nae, attrTimeout, uint32(timeoutSeconds))
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("create ipset %s: %w", name, err)
+	}
+	return nil
+}
+
+// Add inserts IP or CIDR entries with a timeout, replacing entries that are
+// already present. Large inputs are sent in batches.
+func (c *Client) Add(name string, values []string, timeoutSeconds int) error {
+	if err := modifyEntries(cmdAdd, name, values, timeoutSeconds); err != nil {
+		return fmt.Errorf("add entries to ipset %s: %w", name, err)
+	}
+	return nil
+}
+
+// Del removes IP or CIDR entries. Entries that are not in the set are
+// ignored. Large inputs are sent in batches.
+func (c *Client) Del(name string, values []string) error {
+	if err := modifyEntries(cmdDel, name, values, 0); err != nil {
+		return fmt.Errorf("delete entries from ipset %s: %w", name, err)
+	}
+	return nil
+}
+
+func modifyEntries(cmd uint16, name string, values []string, timeoutSeconds int) error {
+	entries := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		entry, err := parseEntry(value)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	for start := 0; start < len(entries); start += maxBatchEntries {
+		end := min(start+maxBatchEntries, len(entries))
+		batch := entries[start:end]
+		_, err := execute(cmd, 0, func(ae *netlink.AttributeEncoder) {
+			ae.String(attrSetName, name)
+			ae.Nested(attrADT, func(nae *netlink.AttributeEncoder) error {
+				for _, entry := range batch {
+					nae.Nested(attrData, func(dae *netlink.AttributeEncoder) error {
+						encodeEntry(dae, entry, timeoutSeconds)
+						return nil
+					})
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("batch of %d entries: %w", len(batch), err)
+		}
+	}
+	return nil
+}
+
+// Flush removes every entry from a set.
+func (c *Client) Flush(name string) error {
+	if _, err := execute(cmdFlush, 0, func(ae *netlink.AttributeEncoder) {
+		ae.String(attrSetName, name)
+	}); err != nil {
+		return fmt.Errorf("flush ipset %s: %w", name, err)
+	}
+	return nil
+}
+
+// Swap exchanges the contents of two sets of the same type.
+func (c *Client) Swap(setA, setB string) error {
+	if _, err := execute(cmdSwap, 0, func(ae *netlink.AttributeEncoder) {
+		ae.String(attrSetName, setA)
+		ae.String(attrSetName2, setB)
+	}); err != nil {
+		return fmt.Errorf("swap ipsets %s %s: %w", setA, setB, err)
+	}
+	return nil
+}
+
+// Destroy removes a set. It fails while iptables rules still reference it.
+func (c *Client) Destroy(name string) error {
+	if _, err := execute(cmdDestroy, 0, func(ae *netlink.AttributeEncoder) {
+		ae.String(attrSetName, name)
+	}); err != nil {
+		return fmt.Errorf("destroy ipset %s: %w", name, err)
+	}
+	return nil
+}
+
+// ListNames returns the names of all sets, sorted.
+func (c *Client) ListNames() ([]string, error) {
+	replies, err := execute(cmdList, netlink.Dump, func(ae *netlink.AttributeEncoder) {
+		putNetUint32(ae, attrFlags, flagListSetName)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list ipsets: %w", err)
+	}
+	seen := make(map[string]struct{})
+	names := make([]string, 0, len(replies))
+	for _, reply := range replies {
+		name, _, err := decodeList(reply.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode ipset list: %w", err)
+		}
+		if _, exists := seen[name]; name == "" || exists {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListMembers returns the entries of one set, sorted, formatted like
+// `ipset save`: host entries without a prefix length, networks in CIDR form.
+func (c *Client) ListMembers(name string) ([]string, error) {
+	replies, err := execute(cmdList, netlink.Dump, func(ae *netlink.AttributeEncoder) {
+		ae.String(attrSetName, name)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list ipset %s: %w", name, err)
+	}
+	members := make([]string, 0)
+	for _, reply := range replies {
+		_, entries, err := decodeList(reply.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode ipset %s: %w", name, err)
+		}
+		members = append(members, entries...)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func execute(cmd uint16, flags netlink.HeaderFlags, encode func(ae *netlink.AttributeEncoder)) ([]netlink.Message, error) {
+	data, err := encodeRequest(encode)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := netlink.Dial(netlinkNetfilter, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.Execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.HeaderType(nfnlSubsysIPSet<<8 | cmd),
+			Flags: netlink.Request | netlink.Acknowledge | flags,
+		},
+		Data: data,
+	})
+}
+
+// encodeRequest prefixes the protocol attribute every ipset command needs
+// with an nfgenmsg header. NLM_F_EXCL is never set, so create, add and del
+// behave like `ipset -exist`.
+func encodeRequest(encode func(ae *netlink.AttributeEncoder)) ([]byte, error) {
+	encoder := netlink.NewAttributeEncoder()
+	encoder.Uint8(attrProtocol, protocolVersion)
+	encode(encoder)
+	attrs, err := encoder.Encode()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerLen)
+	header[0] = afInet
+	return append(header, attrs...), nil
+}
+
+func nfproto(family string) (uint8, error) {
+	switch family {
+	case "inet":
+		return nfprotoIPv4, nil
+	case "inet6":
+		return nfprotoIPv6, nil
+	default:
+		return 0, fmt.Errorf("invalid ipset family %q", family)
+	}
+}
+
+// putNetUint32 encodes the big-endian u32 attributes ipset flags with
+// NLA_F_NET_BYTEORDER.
+func putNetUint32(ae *netlink.AttributeEncoder, typ uint16, value uint32) {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, value)
+	ae.Bytes(typ|netlink.NetByteOrder, buf)
+}
+
+func parseEntry(value string) (*net.IPNet, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		if v4 := ip.To4(); v4 != nil {
+			return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP/CIDR value %q", value)
+	}
+	if v4 := network.IP.To4(); v4 != nil {
+		network.IP = v4
+	}
+	return network, nil
+}
+
+func encodeEntry(ae *netlink.AttributeEncoder, entry *net.IPNet, timeoutSeconds int) {
+	ones, _ := entry.Mask.Size()
+	ae.Nested(attrIP, func(nae *netlink.AttributeEncoder) error {
+		if len(entry.IP) == net.IPv4len {
+			nae.Bytes(attrIPAddrIPv4|netlink.NetByteOrder, entry.IP)
+		} else {
+			nae.Bytes(attrIPAddrIPv6|netlink.NetByteOrder, entry.IP.To16())
+		}
+		return nil
+	})
+	ae.Uint8(attrCIDR, uint8(ones))
+	if timeoutSeconds > 0 {
+		putNetUint32(ae, attrTimeout, uint32(timeoutSeconds))
+	}
+}
+
+// decodeList parses one list reply into its set name and the entries of its
+// ADT attribute, if any.
+func decodeList(data []byte) (string, []string, error) {
+	if len(data) < headerLen {
+		return "", nil, fmt.Errorf("short ipset message (%d bytes)", len(data))
+	}
+	decoder, err := netlink.NewAttributeDecoder(data[headerLen:])
+	if err != nil {
+		return "", nil, err
+	}
+	name := ""
+	entries := make([]string, 0)
+	for decoder.Next() {
+		switch decoder.Type() {
+		case attrSetName:
+			name = decoder.String()
+		case attrADT:
+			decoder.Nested(func(adt *netlink.AttributeDecoder) error {
+				for adt.Next() {
+					if adt.Type() != attrData {
+						continue
+					}
+					adt.Nested(func(nad *netlink.AttributeDecoder) error {
+						entry, err := decodeEntry(nad)
+						if err != nil {
+							return err
+						}
+						entries = append(entries, entry)
+						return nil
+					})
+				}
+				return adt.Err()
+			})
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return "", nil, err
+	}
+	return name, entries, nil
+}
+
+func decodeEntry(decoder *netlink.AttributeDecoder) (string, error) {
+	var ip net.IP
+	cidr := -1
+	for decoder.Next() {
+		switch decoder.Type() {
+		case attrIP:
+			decoder.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					switch nad.Type() {
+					case attrIPAddrIPv4, attrIPAddrIPv6:
+						ip = net.IP(nad.Bytes())
+					}
+				}
+				return nad.Err()
+			})
+		case attrCIDR:
+			cidr = int(decoder.Uint8())
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return "", err
+	}
+	if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+		return "", fmt.Errorf("entry without address")
+	}
+	if cidr < 0 || cidr == len(ip)*8 {
+		return ip.String(), nil
+	}
+	return fmt.Sprintf("%s/%d", ip, cidr), nil
+}
@@ -0,0 +1,71 @@
+package ipset
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mdlayher/netlink"
+)
+
+func TestEncodeRequestStartsWithProtocol(t *testing.T) {
+	data, err := encodeRequest(func(ae *netlink.AttributeEncoder) {
+		ae.String(attrSetName, "svpn_kids_r1s4")
+	})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if data[0] != afInet {
+		t.Fatalf("unexpected nfgenmsg header % x", data[:headerLen])
+	}
+	decoder, err := netlink.NewAttributeDecoder(data[headerLen:])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !decoder.Next() || decoder.Type() != attrProtocol || decoder.Uint8() != protocolVersion {
+		t.Fatalf("expected protocol attribute first")
+	}
+	if !decoder.Next() || decoder.Type() != attrSetName || decoder.String() != "svpn_kids_r1s4" {
+		t.Fatalf("expected set name attribute")
+	}
+}
+
+func TestEntryRoundTripThroughListReply(t *testing.T) {
+	values := []string{"10.0.0.0/8", "192.0.2.7", "2001:db8::/32", "2001:db8::1"}
+	encoder := netlink.NewAttributeEncoder()
+	encoder.String(attrSetName, "svpn_kids_r1d4")
+	encoder.Nested(attrADT, func(nae *netlink.AttributeEncoder) error {
+		for _, value := range values {
+			entry, err := parseEntry(value)
+			if err != nil {
+				return err
+			}
+			nae.Nested(attrData, func(dae *netlink.AttributeEncoder) error {
+				encodeEntry(dae, entry, 600)
+				return nil
+			})
+		}
+		return nil
+	})
+	attrs, err := encoder.Encode()
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	name, entries, err := decodeList(append(make([]byte, headerLen), attrs...))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if name != "svpn_kids_r1d4" || !reflect.DeepEqual(entries, values) {
+		t.Fatalf("unexpected round trip %q %#v", name, entries)
+	}
+}
+
+func TestParseEntryRejectsInvalidValues(t *testing.T) {
+	for _, value := range []string{"", "example.com", "10.0.0.0/33"} {
+		if _, err := parseEntry(value); err == nil {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+	if _, err := nfproto("ipx"); err == nil {
+		t.Fatalf("expected unknown family to be rejected")
+	}
+}
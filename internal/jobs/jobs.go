@@ -0,0 +1,474 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxJobsRetained bounds in-memory job history.
+	maxJobsRetained = 200
+	// maxLogEntriesPerJob bounds per-job log buffers; older lines are dropped.
+	maxLogEntriesPerJob = 500
+	queueCapacity       = 32
+)
+
+var (
+	// ErrJobNotFound indicates the requested job id is unknown or was pruned.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobNotCancelable indicates the job has no cancel hook.
+	ErrJobNotCancelable = errors.New("job cannot be canceled")
+	// ErrJobFinished indicates the job already reached a terminal state.
+	ErrJobFinished = errors.New("job already finished")
+	// ErrQueueFull indicates too many jobs are waiting to run.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrQueueStopped indicates the queue worker is not running.
+	ErrQueueStopped = errors.New("job queue is stopped")
+)
+
+// Kind identifies the operation a job performs.
+type Kind string
+
+const (
+	KindResolver     Kind = "resolver"
+	KindPrewarm      Kind = "prewarm"
+	KindApply        Kind = "apply"
+	KindBackupExport Kind = "backup_export"
+	KindBackupImport Kind = "backup_import"
+)
+
+// State is the lifecycle state of a job.
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCanceled  State = "canceled"
+)
+
+// Job is the public view of one queued, running or finished operation.
+type Job struct {
+	ID         int64  `json:"id"`
+	Kind       Kind   `json:"kind"`
+	Trigger    string `json:"trigger,omitempty"`
+	State      State  `json:"state"`
+	CreatedAt  int64  `json:"createdAt"`
+	StartedAt  int64  `json:"startedAt,omitempty"`
+	FinishedAt int64  `json:"finishedAt,omitempty"`
+	DurationMS int64  `json:"durationMs,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Cancelable bool   `json:"cancelable"`
+	Progress   any    `json:"progress,omitempty"`
+}
+
+// LogEntry is one line of a job's log.
+type LogEntry struct {
+	Time    int64  `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Func is the body of a job executed by the queue worker.
+type Func func(ctx context.Context, handle *Handle) error
+
+type jobEntry struct {
+	job    Job
+	logs   []LogEntry
+	cancel func() error
+	fn     Func
+	ctx    context.Context
+	// started keeps sub-second precision for DurationMS.
+	started time.Time
+}
+
+// Queue runs submitted jobs one at a time and records externally-run jobs
+// (scheduler runs) so every long-running operation shares one history,
+// cancel and log surface.
+type Queue struct {
+	mu      sync.Mutex
+	nextID  int64
+	entries map[int64]*jobEntry
+	order   []int64
+	pending chan int64
+	started bool
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+	now     func() time.Time
+}
+
+// NewQueue creates an idle queue; call Start to run submitted jobs.
+func NewQueue() *Queue {
+	return &Queue{
+		entries: make(map[int64]*jobEntry),
+		pending: make(chan int64, queueCapacity),
+		now:     time.Now,
+	}
+}
+
+// Start launches the worker that executes submitted jobs sequentially.
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q.started = true
+	q.stop = cancel
+	q.wg.Add(1)
+	go q.worker(ctx)
+}
+
+// Stop halts the worker after the current job; queued jobs are canceled.
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	stop := q.stop
+	q.started = false
+	q.stop = nil
+	q.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+	q.wg.Wait()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, entry := range q.entries {
+		if entry.job.State == StateQueued {
+			if entry.cancel != nil {
+				_ = entry.cancel()
+			}
+			q.finishLocked(entry, context.Canceled)
+		}
+	}
+}
+
+// Submit enqueues fn for sequential execution and returns the queued job.
+func (q *Queue) Submit(kind Kind, trigger string, fn Func) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.started {
+		return Job{}, ErrQueueStopped
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := q.newEntryLocked(kind, trigger)
+	entry.fn = fn
+	entry.ctx = ctx
+	entry.cancel = func() error {
+		cancel()
+		return nil
+	}
+	entry.job.Cancelable = true
+	select {
+	case q.pending <- entry.job.ID:
+	default:
+		cancel()
+		q.removeLocked(entry.job.ID)
+		return Job{}, ErrQueueFull
+	}
+	return entry.job, nil
+}
+
+// Track registers a job executed elsewhere and marks it running. cancel may
+// be nil when the operation cannot be interrupted.
+func (q *Queue) Track(kind Kind, trigger string, cancel func() error) *Handle {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry := q.newEntryLocked(kind, trigger)
+	entry.cancel = cancel
+	entry.job.Cancelable = cancel != nil
+	entry.job.State = StateRunning
+	entry.started = q.now()
+	entry.job.StartedAt = entry.started.Unix()
+	return &Handle{queue: q, id: entry.job.ID}
+}
+
+// Get returns one job by id.
+func (q *Queue) Get(id int64) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return entry.job, nil
+}
+
+// List returns jobs newest first, optionally filtered by kind.
+func (q *Queue) List(kind Kind, limit int) []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Job, 0, len(q.order))
+	for idx := len(q.order) - 1; idx >= 0; idx-- {
+		entry := q.entries[q.order[idx]]
+		if kind != "" && entry.job.Kind != kind {
+			continue
+		}
+		out = append(out, entry.job)
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out
+}
+
+// Active returns the newest queued or running job of kind, if any.
+func (q *Queue) Active(kind Kind) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for idx := len(q.order) - 1; idx >= 0; idx-- {
+		entry := q.entries[q.order[idx]]
+		if entry.job.Kind == kind && !entry.job.State.Terminal() {
+			return entry.job, true
+		}
+	}
+	return Job{}, false
+}
+
+// Logs returns a copy of a job's log buffer.
+func (q *Queue) Logs(id int64) ([]LogEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return append([]LogEntry(nil), entry.logs...), nil
+}
+
+// Cancel requests cancellation of a queued or running job.
+func (q *Queue) Cancel(id int64) error {
+	q.mu.Lock()
+	entry, ok := q.entries[id]
+	if !ok {
+		q.mu.Unlock()
+		return ErrJobNotFound
+	}
+	if entry.job.State.Terminal() {
+		q.mu.Unlock()
+		return ErrJobFinished
+	}
+	if entry.cancel == nil {
+		q.mu.Unlock()
+		return ErrJobNotCancelable
+	}
+	cancel := entry.cancel
+	q.appendLogLocked(entry, "warn", "cancellation requested")
+	if entry.job.State == StateQueued {
+		q.finishLocked(entry, context.Canceled)
+	}
+	q.mu.Unlock()
+	return cancel()
+}
+
+// Terminal reports whether the state is final.
+func (s State) Terminal() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCanceled
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-q.pending:
+			q.run(id)
+		}
+	}
+}
+
+func (q *Queue) run(id int64) {
+	q.mu.Lock()
+	entry, ok := q.entries[id]
+	if !ok || entry.job.State != StateQueued {
+		q.mu.Unlock()
+		return
+	}
+	entry.job.State = StateRunning
+	entry.started = q.now()
+	entry.job.StartedAt = entry.started.Unix()
+	fn := entry.fn
+	ctx := entry.ctx
+	q.mu.Unlock()
+
+	handle := &Handle{queue: q, id: id}
+	err := runSafely(ctx, handle, fn)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	handle.Finish(err)
+}
+
+func runSafely(ctx context.Context, handle *Handle, fn Func) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return fn(ctx, handle)
+}
+
+func (q *Queue) newEntryLocked(kind Kind, trigger string) *jobEntry {
+	q.nextID++
+	entry := &jobEntry{job: Job{
+		ID:        q.nextID,
+		Kind:      kind,
+		Trigger:   strings.TrimSpace(trigger),
+		State:     StateQueued,
+		CreatedAt: q.now().Unix(),
+	}}
+	q.entries[entry.job.ID] = entry
+	q.order = append(q.order, entry.job.ID)
+	q.pruneLocked()
+	return entry
+}
+
+// pruneLocked drops the oldest finished jobs beyond the retention limit.
+func (q *Queue) pruneLocked() {
+	if len(q.order) <= maxJobsRetained {
+		return
+	}
+	kept := make([]int64, 0, len(q.order))
+	excess := len(q.order) - maxJobsRetained
+	for _, id := range q.order {
+		if excess > 0 && q.entries[id].job.State.Terminal() {
+			delete(q.entries, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	q.order = kept
+}
+
+func (q *Queue) removeLocked(id int64) {
+	delete(q.entries, id)
+	for idx, candidate := range q.order {
+		if candidate == id {
+			q.order = append(q.order[:idx], q.order[idx+1:]...)
+			return
+		}
+	}
+}
+
+func (q *Queue) finishLocked(entry *jobEntry, err error) {
+	if entry.job.State.Terminal() {
+		return
+	}
+	now := q.now()
+	entry.job.FinishedAt = now.Unix()
+	if !entry.started.IsZero() {
+		entry.job.DurationMS = now.Sub(entry.started).Milliseconds()
+	}
+	entry.job.Cancelable = false
+	entry.cancel = nil
+	entry.fn = nil
+	switch {
+	case err == nil:
+		entry.job.State = StateSucceeded
+		q.appendLogLocked(entry, "info", "job succeeded")
+	case errors.Is(err, context.Canceled):
+		entry.job.State = StateCanceled
+		entry.job.Error = err.Error()
+		q.appendLogLocked(entry, "warn", "job canceled")
+	default:
+		entry.job.State = StateFailed
+		entry.job.Error = err.Error()
+		q.appendLogLocked(entry, "error", "job failed: "+err.Error())
+	}
+}
+
+func (q *Queue) appendLogLocked(entry *jobEntry, level, message string) {
+	entry.logs = append(entry.logs, LogEntry{Time: q.now().Unix(), Level: level, Message: message})
+	if overflow := len(entry.logs) - maxLogEntriesPerJob; overflow > 0 {
+		entry.logs = append([]LogEntry(nil), entry.logs[overflow:]...)
+	}
+}
+
+// Handle lets a job body report logs, progress and completion.
+type Handle struct {
+	queue *Queue
+	id    int64
+}
+
+// ID returns the job id.
+func (h *Handle) ID() int64 {
+	if h == nil {
+		return 0
+	}
+	return h.id
+}
+
+// Logf appends a log line at level (debug, info, warn, error). Handle methods
+// are no-ops on a nil handle so callers need not guard optional tracking.
+func (h *Handle) Logf(level, format string, args ...any) {
+	if h == nil {
+		return
+	}
+	h.queue.mu.Lock()
+	defer h.queue.mu.Unlock()
+	if entry, ok := h.queue.entries[h.id]; ok {
+		h.queue.appendLogLocked(entry, level, fmt.Sprintf(format, args...))
+	}
+}
+
+// SetProgress replaces the job's progress payload.
+func (h *Handle) SetProgress(progress any) {
+	if h == nil {
+		return
+	}
+	h.queue.mu.Lock()
+	defer h.queue.mu.Unlock()
+	if entry, ok := h.queue.entries[h.id]; ok {
+		entry.job.Progress = progress
+	}
+}
+
+// DisableCancel drops the job's cancel hook so later Cancel calls fail with
+// ErrJobNotCancelable. Jobs call it before entering a section that must not
+// be interrupted. It reports false if cancellation was already requested.
+func (h *Handle) DisableCancel() bool {
+	if h == nil {
+		return true
+	}
+	h.queue.mu.Lock()
+	defer h.queue.mu.Unlock()
+	entry, ok := h.queue.entries[h.id]
+	if !ok {
+		return true
+	}
+	entry.cancel = nil
+	entry.job.Cancelable = false
+	return entry.ctx == nil || entry.ctx.Err() == nil
+}
+
+// Finish marks the job terminal. Later calls are ignored.
+func (h *Handle) Finish(err error) {
+	if h == nil {
+		return
+	}
+	h.queue.mu.Lock()
+	defer h.queue.mu.Unlock()
+	if entry, ok := h.queue.entries[h.id]; ok {
+		h.queue.finishLocked(entry, err)
+	}
+}
+
+// ParseKind validates a job kind from API input.
+func ParseKind(raw string) (Kind, error) {
+	kind := Kind(strings.ToLower(strings.TrimSpace(raw)))
+	switch kind {
+	case KindResolver, KindPrewarm, KindApply, KindBackupExport, KindBackupImport:
+		return kind, nil
+	}
+	known := []string{string(KindApply), string(KindBackupExport), string(KindBackupImport), string(KindPrewarm), string(KindResolver)}
+	sort.Strings(known)
+	return "", fmt.Errorf("unknown job kind %q (expected one of %s)", raw, strings.Join(known, ", "))
+}
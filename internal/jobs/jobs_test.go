@@ -0,0 +1,205 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func waitForState(t *testing.T, queue *Queue, id int64, want State) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := queue.Get(id)
+		if err != nil {
+			t.Fatalf("get job %d: %v", id, err)
+		}
+		if job.State == want {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	job, _ := queue.Get(id)
+	t.Fatalf("job %d did not reach %s, last state %s", id, want, job.State)
+	return Job{}
+}
+
+func TestQueueRunsSubmittedJobsInOrder(t *testing.T) {
+	queue := NewQueue()
+	queue.Start()
+	defer queue.Stop()
+
+	release := make(chan struct{})
+	order := make(chan int, 2)
+	first, err := queue.Submit(KindApply, "test", func(ctx context.Context, handle *Handle) error {
+		<-release
+		handle.Logf("info", "first done")
+		order <- 1
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("submit first: %v", err)
+	}
+	second, err := queue.Submit(KindApply, "test", func(ctx context.Context, handle *Handle) error {
+		order <- 2
+		return errors.New("apply failed")
+	})
+	if err != nil {
+		t.Fatalf("submit second: %v", err)
+	}
+
+	waitForState(t, queue, first.ID, StateRunning)
+	if job, _ := queue.Get(second.ID); job.State != StateQueued {
+		t.Fatalf("expected second job to wait, got %s", job.State)
+	}
+	close(release)
+
+	waitForState(t, queue, first.ID, StateSucceeded)
+	failed := waitForState(t, queue, second.ID, StateFailed)
+	if failed.Error != "apply failed" {
+		t.Fatalf("unexpected error %q", failed.Error)
+	}
+	if <-order != 1 || <-order != 2 {
+		t.Fatalf("expected jobs to run in submission order")
+	}
+
+	logs, err := queue.Logs(first.ID)
+	if err != nil {
+		t.Fatalf("logs: %v", err)
+	}
+	if len(logs) != 2 || logs[0].Message != "first done" || logs[1].Message != "job succeeded" {
+		t.Fatalf("unexpected logs: %#v", logs)
+	}
+
+	listed := queue.List(KindApply, 0)
+	if len(listed) != 2 || listed[0].ID != second.ID {
+		t.Fatalf("expected newest-first listing, got %#v", listed)
+	}
+}
+
+func TestQueueCancelRunningAndQueuedJobs(t *testing.T) {
+	queue := NewQueue()
+	queue.Start()
+	defer queue.Stop()
+
+	running, err := queue.Submit(KindApply, "", func(ctx context.Context, handle *Handle) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	queued, err := queue.Submit(KindApply, "", func(ctx context.Context, handle *Handle) error {
+		t.Errorf("canceled queued job must not run")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	waitForState(t, queue, running.ID, StateRunning)
+
+	if err := queue.Cancel(queued.ID); err != nil {
+		t.Fatalf("cancel queued: %v", err)
+	}
+	if job, _ := queue.Get(queued.ID); job.State != StateCanceled {
+		t.Fatalf("expected queued job canceled immediately, got %s", job.State)
+	}
+	if err := queue.Cancel(running.ID); err != nil {
+		t.Fatalf("cancel running: %v", err)
+	}
+	waitForState(t, queue, running.ID, StateCanceled)
+	if err := queue.Cancel(running.ID); !errors.Is(err, ErrJobFinished) {
+		t.Fatalf("expected ErrJobFinished, got %v", err)
+	}
+}
+
+func TestDisableCancelProtectsRunningJob(t *testing.T) {
+	queue := NewQueue()
+	queue.Start()
+	defer queue.Stop()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	job, err := queue.Submit(KindApply, "", func(ctx context.Context, handle *Handle) error {
+		if !handle.DisableCancel() {
+			return ctx.Err()
+		}
+		close(entered)
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	<-entered
+	if current, _ := queue.Get(job.ID); current.Cancelable {
+		t.Fatalf("expected running job to report not cancelable")
+	}
+	if err := queue.Cancel(job.ID); !errors.Is(err, ErrJobNotCancelable) {
+		t.Fatalf("expected ErrJobNotCancelable, got %v", err)
+	}
+	close(release)
+	waitForState(t, queue, job.ID, StateSucceeded)
+}
+
+func TestQueueTrackExternalJob(t *testing.T) {
+	queue := NewQueue()
+	canceled := false
+	handle := queue.Track(KindPrewarm, "schedule", func() error {
+		canceled = true
+		return nil
+	})
+	handle.SetProgress(map[string]int{"done": 3})
+	active, ok := queue.Active(KindPrewarm)
+	if !ok || active.ID != handle.ID() || active.State != StateRunning || !active.Cancelable {
+		t.Fatalf("unexpected active job: %#v ok=%v", active, ok)
+	}
+	if err := queue.Cancel(handle.ID()); err != nil || !canceled {
+		t.Fatalf("expected cancel hook to run, err=%v", err)
+	}
+	handle.Finish(context.Canceled)
+	handle.Finish(nil)
+	job, _ := queue.Get(handle.ID())
+	if job.State != StateCanceled || job.Cancelable {
+		t.Fatalf("expected terminal canceled job, got %#v", job)
+	}
+
+	untracked := queue.Track(KindApply, "", nil)
+	if err := queue.Cancel(untracked.ID()); !errors.Is(err, ErrJobNotCancelable) {
+		t.Fatalf("expected ErrJobNotCancelable, got %v", err)
+	}
+
+	var nilHandle *Handle
+	nilHandle.Logf("info", "ignored")
+	nilHandle.Finish(nil)
+}
+
+func TestQueuePrunesFinishedJobs(t *testing.T) {
+	queue := NewQueue()
+	for i := 0; i < maxJobsRetained+10; i++ {
+		queue.Track(KindApply, "", nil).Finish(nil)
+	}
+	if got := len(queue.List("", 0)); got != maxJobsRetained {
+		t.Fatalf("expected %d retained jobs, got %d", maxJobsRetained, got)
+	}
+	if _, err := queue.Get(1); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected oldest job pruned, got %v", err)
+	}
+}
+
+func TestSubmitRequiresStartedQueue(t *testing.T) {
+	queue := NewQueue()
+	if _, err := queue.Submit(KindApply, "", func(context.Context, *Handle) error { return nil }); !errors.Is(err, ErrQueueStopped) {
+		t.Fatalf("expected ErrQueueStopped, got %v", err)
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	if kind, err := ParseKind(" Prewarm "); err != nil || kind != KindPrewarm {
+		t.Fatalf("unexpected parse result %q err=%v", kind, err)
+	}
+	if _, err := ParseKind("reboot"); err == nil {
+		t.Fatalf("expected unknown kind to fail")
+	}
+}
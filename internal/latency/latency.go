@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/netip"
 	"os/exec"
 	"regexp"
 	"sort"
@@ -25,14 +26,23 @@ type Result struct {
 	Error         string    `json:"error,omitempty"`
 	EverSucceeded bool      `json:"everSucceeded"`
 	LastSuccess   time.Time `json:"lastSuccess,omitempty"`
+	Probe         string    `json:"probe,omitempty"`
 }
 
-// Monitor pings configured targets while at least one watcher is active.
+// Target is what the monitor measures for one VPN: the gateway or host to
+// ping, the URL of an HTTPS probe or the resolver of a DNS probe.
 type Target struct {
 	Interface string
 	Address   string
+	// Probe is ProbeICMP (when empty), ProbeHTTPS or ProbeDNS.
+	Probe string
+	// Resolvers resolve an HTTPS probe's host through the tunnel; the
+	// system resolver is used when empty.
+	Resolvers []netip.Addr
 }
 
+// Monitor measures configured targets while at least one watcher is active.
+
 type Monitor struct {
 	mu       sync.RWMutex
 	interval time.Duration
@@ -51,6 +61,31 @@ func NewMonitor(interval time.Duration) *Monitor {
 	}
 }
 
+// Interval returns the current ping interval.
+func (m *Monitor) Interval() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.interval
+}
+
+// SetInterval changes the ping interval, restarting an active loop so the
+// new interval applies immediately. Non-positive values are ignored.
+func (m *Monitor) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.interval == interval {
+		return
+	}
+	m.interval = interval
+	if m.stop != nil {
+		m.stopLoop()
+		m.start()
+	}
+}
+
 // UpdateTargets replaces the targets map.
 func (m *Monitor) UpdateTargets(targets map[string]Target) {
 	m.mu.Lock()
@@ -95,7 +130,7 @@ func (m *Monitor) start() {
 		return
 	}
 	m.stop = make(chan struct{})
-	go m.loop(m.stop)
+	go m.loop(m.stop, m.interval)
 }
 
 func (m *Monitor) stopLoop() {
@@ -105,8 +140,8 @@ func (m *Monitor) stopLoop() {
 	}
 }
 
-func (m *Monitor) loop(stop <-chan struct{}) {
-	ticker := time.NewTicker(m.interval)
+func (m *Monitor) loop(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	m.runOnce()
 	for {
@@ -119,14 +154,28 @@ func (m *Monitor) loop(stop <-chan struct{}) {
 	}
 }
 
+// CheckNow measures every target at once when at least one watcher is
+// active, without waiting for the next interval.
+func (m *Monitor) CheckNow() {
+	m.mu.RLock()
+	active := m.stop != nil
+	m.mu.RUnlock()
+	if active {
+		go m.runOnce()
+	}
+}
+
 func (m *Monitor) runOnce() {
 	targets := m.snapshotTargets()
 	for name, target := range targets {
-		res := pingTarget(name, target)
+		res := probeTarget(name, target)
+		m.mu.RLock()
+		prev, ok := m.results[name]
+		m.mu.RUnlock()
 		if res.Success {
 			res.EverSucceeded = true
 			res.LastSuccess = res.CheckedAt
-		} else if prev, ok := m.results[name]; ok {
+		} else if ok {
 			res.EverSucceeded = prev.EverSucceeded || prev.Success
 			if !prev.LastSuccess.IsZero() {
 				res.LastSuccess = prev.LastSuccess
@@ -2,7 +2,14 @@ package latency
 
 import (
 	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 func TestParseLatency(t *testing.T) {
@@ -29,3 +36,94 @@ func TestSanitizeErrorPrefersTrimmedStderr(t *testing.T) {
 		t.Fatalf("unexpected sanitized error text: %q", text)
 	}
 }
+
+func TestSetIntervalRestartsActiveLoop(t *testing.T) {
+	m := NewMonitor(time.Hour)
+	release := m.Activate()
+	defer release()
+	m.mu.RLock()
+	before := m.stop
+	m.mu.RUnlock()
+
+	m.SetInterval(-time.Second)
+	if got := m.Interval(); got != time.Hour {
+		t.Fatalf("non-positive interval should be ignored, got %s", got)
+	}
+	m.SetInterval(30 * time.Second)
+	if got := m.Interval(); got != 30*time.Second {
+		t.Fatalf("Interval = %s, want 30s", got)
+	}
+	m.mu.RLock()
+	after := m.stop
+	m.mu.RUnlock()
+	if after == nil || after == before {
+		t.Fatalf("expected the active loop to restart with the new interval")
+	}
+}
+
+func TestHTTPSProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	res := probeTarget("wg-fra", Target{Address: server.URL, Probe: ProbeHTTPS})
+	if !res.Success || res.Probe != ProbeHTTPS || res.Target != server.URL {
+		t.Fatalf("expected any HTTP response to count, got %+v", res)
+	}
+	if res.LatencyMS < 20 {
+		t.Fatalf("expected the response delay to be timed, got %v ms", res.LatencyMS)
+	}
+
+	server.Close()
+	if res := probeTarget("wg-fra", Target{Address: server.URL, Probe: ProbeHTTPS}); res.Success || res.Error == "" {
+		t.Fatalf("expected an unreachable URL to fail, got %+v", res)
+	}
+}
+
+func TestDNSProbe(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+	questions := make(chan dnsmessage.Question, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+			return
+		}
+		questions <- query.Questions[0]
+		// A stray reply with another ID must be ignored.
+		stray, _ := (&dnsmessage.Message{Header: dnsmessage.Header{ID: query.ID + 1, Response: true}}).Pack()
+		_, _ = conn.WriteTo(stray, from)
+		reply, _ := (&dnsmessage.Message{Header: dnsmessage.Header{ID: query.ID, Response: true}, Questions: query.Questions}).Pack()
+		_, _ = conn.WriteTo(reply, from)
+	}()
+
+	res := probeTarget("wg-fra", Target{Address: conn.LocalAddr().String(), Probe: ProbeDNS})
+	if !res.Success || res.Probe != ProbeDNS {
+		t.Fatalf("expected DNS probe to succeed, got %+v", res)
+	}
+	question := <-questions
+	if question.Name.String() != "." || question.Type != dnsmessage.TypeNS {
+		t.Fatalf("unexpected probe question %v", question)
+	}
+
+	if res := probeTarget("wg-fra", Target{Address: "resolver.lan", Probe: ProbeDNS}); res.Success || !strings.Contains(res.Error, "invalid resolver address") {
+		t.Fatalf("expected a bad resolver to fail, got %+v", res)
+	}
+}
+
+func TestResolverAddressDefaultsPort(t *testing.T) {
+	got, err := resolverAddress("10.64.0.1")
+	if err != nil || got.String() != "10.64.0.1:53" {
+		t.Fatalf("resolverAddress = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,168 @@
+package latency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"split-vpn-webui/internal/netbind"
+)
+
+// Probe types. ICMP is the default; the others serve providers that drop
+// ICMP inside the tunnel.
+const (
+	ProbeICMP  = "icmp"
+	ProbeHTTPS = "https"
+	ProbeDNS   = "dns"
+)
+
+const probeTimeout = 5 * time.Second
+
+// probeTarget measures one target with its configured probe.
+func probeTarget(name string, target Target) Result {
+	var res Result
+	switch target.Probe {
+	case ProbeHTTPS:
+		res = timedProbe(name, target, httpsLatency)
+	case ProbeDNS:
+		res = timedProbe(name, target, dnsLatency)
+	default:
+		res = pingTarget(name, target)
+	}
+	res.Probe = target.Probe
+	if res.Probe == "" {
+		res.Probe = ProbeICMP
+	}
+	return res
+}
+
+func timedProbe(name string, target Target, probe func(context.Context, Target) (time.Duration, error)) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	elapsed, err := probe(ctx, target)
+	res := Result{
+		Name:      name,
+		Target:    strings.TrimSpace(target.Address),
+		CheckedAt: time.Now(),
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Success = true
+	res.LatencyMS = float64(elapsed.Microseconds()) / 1000
+	return res
+}
+
+// httpsLatency times a GET from the request being written to the first
+// response byte, which leaves out DNS, TCP and TLS setup and so comes
+// closest to one round trip. Any HTTP response counts: the status only
+// says something about the server, not the path to it.
+func httpsLatency(ctx context.Context, target Target) (time.Duration, error) {
+	dialer := netbind.Dialer(probeTimeout, netbind.Egress{Interface: target.Interface, Resolvers: target.Resolvers})
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: probeTimeout,
+		DisableKeepAlives:   true,
+	}
+	defer transport.CloseIdleConnections()
+
+	var wrote, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	request, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, strings.TrimSpace(target.Address), nil)
+	if err != nil {
+		return 0, err
+	}
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	_ = response.Body.Close()
+	if wrote.IsZero() || firstByte.Before(wrote) {
+		return 0, errors.New("no response timing")
+	}
+	return firstByte.Sub(wrote), nil
+}
+
+// dnsLatency times a query for the root NS records, which every resolver
+// answers from cache, through the target's interface.
+func dnsLatency(ctx context.Context, target Target) (time.Duration, error) {
+	server, err := resolverAddress(target.Address)
+	if err != nil {
+		return 0, err
+	}
+	var idBytes [2]byte
+	_, _ = rand.Read(idBytes[:])
+	id := binary.BigEndian.Uint16(idBytes[:])
+	query, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName("."),
+			Type:  dnsmessage.TypeNS,
+			Class: dnsmessage.ClassINET,
+		}},
+	}).Pack()
+	if err != nil {
+		return 0, err
+	}
+
+	dialer := &net.Dialer{Timeout: probeTimeout, Control: netbind.Control(target.Interface)}
+	conn, err := dialer.DialContext(ctx, "udp", server.String())
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	start := time.Now()
+	if _, err := conn.Write(query); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil || header.ID != id || !header.Response {
+			continue
+		}
+		return time.Since(start), nil
+	}
+}
+
+// resolverAddress parses an IP or IP:port resolver, defaulting to port 53.
+func resolverAddress(address string) (netip.AddrPort, error) {
+	address = strings.TrimSpace(address)
+	if addr, err := netip.ParseAddr(address); err == nil {
+		return netip.AddrPortFrom(addr.Unmap(), 53), nil
+	}
+	if addrPort, err := netip.ParseAddrPort(address); err == nil {
+		return addrPort, nil
+	}
+	return netip.AddrPort{}, fmt.Errorf("invalid resolver address %q", address)
+}
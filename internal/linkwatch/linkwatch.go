@@ -0,0 +1,220 @@
+// Package linkwatch follows network interfaces appearing, disappearing and
+// changing state through rtnetlink link notifications, so tunnel hotplug is
+// seen as it happens rather than at the next poll.
+package linkwatch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mdlayher/netlink"
+)
+
+// rtnetlink message types, groups and attributes (linux/rtnetlink.h,
+// linux/if_link.h).
+const (
+	netlinkRoute = 0
+	rtnlGrpLink  = 1
+
+	rtmNewLink = 16
+	rtmDelLink = 17
+
+	iflaIfname = 3
+
+	iffUp = 0x1
+
+	// ifinfomsg is 16 bytes.
+	headerLen = 16
+)
+
+// Bounds of the wait before resyncing after an overrun.
+const (
+	minResyncDelay = 100 * time.Millisecond
+	maxResyncDelay = 30 * time.Second
+)
+
+// Kind says what happened to an interface.
+type Kind string
+
+const (
+	// Added is an interface the watcher had not seen before.
+	Added Kind = "added"
+	// Removed is an interface that was deleted.
+	Removed Kind = "removed"
+	// Changed is a known interface whose flags or state changed.
+	Changed Kind = "changed"
+	// Resync means notifications were lost; every interface may have
+	// changed.
+	Resync Kind = "resync"
+)
+
+// Event is one link notification.
+type Event struct {
+	Kind  Kind
+	Name  string
+	Index int
+	Up    bool
+}
+
+// Watcher receives link notifications and classifies them against the
+// interfaces it already knows.
+type Watcher struct {
+	mu    sync.Mutex
+	known map[int]string
+	conn  *netlink.Conn
+}
+
+// New returns a watcher. It does not open a socket until Run.
+func New() *Watcher {
+	return &Watcher{known: make(map[int]string)}
+}
+
+// Run subscribes to link notifications and calls handle for each until
+// Close is called. The interfaces present when it starts are known, so they
+// are not reported as added.
+func (w *Watcher) Run(handle func(Event)) error {
+	conn, err := netlink.Dial(netlinkRoute, &netlink.Config{Groups: 1 << (rtnlGrpLink - 1)})
+	if err != nil {
+		return fmt.Errorf("subscribe to link events: %w", err)
+	}
+	w.mu.Lock()
+	if w.conn != nil {
+		w.mu.Unlock()
+		_ = conn.Close()
+		return errors.New("link watcher already running")
+	}
+	w.conn = conn
+	w.mu.Unlock()
+	w.seed()
+
+	var delay time.Duration
+	for {
+		messages, err := conn.Receive()
+		if err != nil {
+			w.mu.Lock()
+			closed := w.conn == nil
+			w.mu.Unlock()
+			if closed {
+				return nil
+			}
+			if !errors.Is(err, syscall.ENOBUFS) {
+				_ = w.Close()
+				return fmt.Errorf("receive link events: %w", err)
+			}
+			// Overruns drop notifications; start over from the current
+			// list. Back off while they keep coming so a flood cannot keep
+			// pushing the refresh back.
+			delay = resyncDelay(delay)
+			time.Sleep(delay)
+			w.seed()
+			handle(Event{Kind: Resync})
+			continue
+		}
+		delay = 0
+		for _, message := range messages {
+			if event, ok := w.classify(message); ok {
+				handle(event)
+			}
+		}
+	}
+}
+
+// resyncDelay doubles the wait before the next resync, up to
+// maxResyncDelay.
+func resyncDelay(previous time.Duration) time.Duration {
+	if previous <= 0 {
+		return minResyncDelay
+	}
+	return min(previous*2, maxResyncDelay)
+}
+
+// Close stops Run.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	conn := w.conn
+	w.conn = nil
+	w.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// seed replaces the known interfaces with those present now.
+func (w *Watcher) seed() {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return
+	}
+	known := make(map[int]string, len(interfaces))
+	for _, iface := range interfaces {
+		known[iface.Index] = iface.Name
+	}
+	w.mu.Lock()
+	w.known = known
+	w.mu.Unlock()
+}
+
+// classify turns a link message into an event, updating the known set.
+func (w *Watcher) classify(message netlink.Message) (Event, bool) {
+	if message.Header.Type != rtmNewLink && message.Header.Type != rtmDelLink {
+		return Event{}, false
+	}
+	event, err := decodeLink(message.Data)
+	if err != nil {
+		return Event{}, false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	name, known := w.known[event.Index]
+	if message.Header.Type == rtmDelLink {
+		delete(w.known, event.Index)
+		event.Kind = Removed
+		if event.Name == "" {
+			event.Name = name
+		}
+		event.Up = false
+		return event, event.Name != ""
+	}
+	w.known[event.Index] = event.Name
+	switch {
+	case !known:
+		event.Kind = Added
+	case name != event.Name:
+		// A rename: the old name is gone and the new one has appeared.
+		event.Kind = Added
+	default:
+		event.Kind = Changed
+	}
+	return event, event.Name != ""
+}
+
+// decodeLink parses an ifinfomsg and its name attribute.
+func decodeLink(data []byte) (Event, error) {
+	if len(data) < headerLen {
+		return Event{}, fmt.Errorf("short link message (%d bytes)", len(data))
+	}
+	event := Event{
+		Index: int(int32(binary.NativeEndian.Uint32(data[4:8]))),
+		Up:    binary.NativeEndian.Uint32(data[8:12])&iffUp != 0,
+	}
+	decoder, err := netlink.NewAttributeDecoder(data[headerLen:])
+	if err != nil {
+		return Event{}, err
+	}
+	for decoder.Next() {
+		if decoder.Type() == iflaIfname {
+			event.Name = strings.TrimRight(decoder.String(), "\x00")
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
@@ -0,0 +1,87 @@
+package linkwatch
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/mdlayher/netlink"
+)
+
+func linkMessage(t *testing.T, kind netlink.HeaderType, index int, flags uint32, name string) netlink.Message {
+	t.Helper()
+	data := make([]byte, headerLen)
+	binary.NativeEndian.PutUint32(data[4:8], uint32(int32(index)))
+	binary.NativeEndian.PutUint32(data[8:12], flags)
+	if name != "" {
+		encoder := netlink.NewAttributeEncoder()
+		encoder.String(iflaIfname, name)
+		attrs, err := encoder.Encode()
+		if err != nil {
+			t.Fatalf("encode attributes: %v", err)
+		}
+		data = append(data, attrs...)
+	}
+	return netlink.Message{Header: netlink.Header{Type: kind}, Data: data}
+}
+
+func TestDecodeLink(t *testing.T) {
+	event, err := decodeLink(linkMessage(t, rtmNewLink, 7, iffUp, "wg-sv-fra").Data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if event.Index != 7 || event.Name != "wg-sv-fra" || !event.Up {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if _, err := decodeLink([]byte{1, 2, 3}); err == nil {
+		t.Fatalf("expected short message error")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	w := New()
+	w.known[1] = "lo"
+	w.known[3] = "wg-sv-fra"
+
+	cases := []struct {
+		name    string
+		message netlink.Message
+		want    Event
+		ok      bool
+	}{
+		{"new interface", linkMessage(t, rtmNewLink, 9, iffUp, "wg-sv-nyc"), Event{Kind: Added, Name: "wg-sv-nyc", Index: 9, Up: true}, true},
+		{"known interface", linkMessage(t, rtmNewLink, 3, 0, "wg-sv-fra"), Event{Kind: Changed, Name: "wg-sv-fra", Index: 3}, true},
+		{"rename", linkMessage(t, rtmNewLink, 3, iffUp, "wg-sv-ams"), Event{Kind: Added, Name: "wg-sv-ams", Index: 3, Up: true}, true},
+		{"delete without name", linkMessage(t, rtmDelLink, 9, iffUp, ""), Event{Kind: Removed, Name: "wg-sv-nyc", Index: 9}, true},
+		{"delete unknown", linkMessage(t, rtmDelLink, 42, 0, ""), Event{}, false},
+		{"other message", linkMessage(t, 20, 1, 0, "lo"), Event{}, false},
+	}
+	for _, tc := range cases {
+		event, ok := w.classify(tc.message)
+		if ok != tc.ok {
+			t.Fatalf("%s: ok=%t, want %t", tc.name, ok, tc.ok)
+		}
+		if ok && event != tc.want {
+			t.Fatalf("%s: got %+v, want %+v", tc.name, event, tc.want)
+		}
+	}
+	if _, known := w.known[9]; known {
+		t.Fatalf("deleted interface still known")
+	}
+}
+
+func TestResyncDelayBacksOff(t *testing.T) {
+	delay := resyncDelay(0)
+	if delay != minResyncDelay {
+		t.Fatalf("first delay = %s, want %s", delay, minResyncDelay)
+	}
+	for i := 0; i < 20; i++ {
+		next := resyncDelay(delay)
+		if next < delay || next > maxResyncDelay {
+			t.Fatalf("delay went from %s to %s", delay, next)
+		}
+		delay = next
+	}
+	if delay != maxResyncDelay {
+		t.Fatalf("delay = %s, want it capped at %s", delay, maxResyncDelay)
+	}
+}
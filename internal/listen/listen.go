@@ -0,0 +1,210 @@
+// Package listen turns the listen setting into concrete listener addresses.
+//
+// The setting is a comma-separated list of entries. Each entry names an
+// interface or an IPv4/IPv6 address, optionally with a port and an https://
+// prefix to serve that listener over TLS:
+//
+//	br0, https://br0:8443, 192.168.1.1, [fd00::1]:8091, https://fd00::1
+package listen
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const httpsPrefix = "https://"
+
+var ifacePattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]{1,15}$`)
+
+// Entry is one parsed listen setting entry.
+type Entry struct {
+	// Interface is set for interface entries; IP for address entries.
+	Interface string
+	IP        net.IP
+	// Port is empty when the entry uses the default port.
+	Port string
+	TLS  bool
+}
+
+// String formats the entry in the setting's canonical form.
+func (e Entry) String() string {
+	host := e.Interface
+	if e.IP != nil {
+		host = e.IP.String()
+	}
+	if e.Port != "" {
+		host = net.JoinHostPort(host, e.Port)
+	} else if e.IP != nil && e.IP.To4() == nil && e.TLS {
+		// Keep bare IPv6 readable after the scheme.
+		host = "[" + host + "]"
+	}
+	if e.TLS {
+		return httpsPrefix + host
+	}
+	return host
+}
+
+// Address is a concrete listener.
+type Address struct {
+	Addr string `json:"addr"`
+	TLS  bool   `json:"tls"`
+	// Source is the setting entry the address came from.
+	Source string `json:"source"`
+}
+
+// ParseSpec parses a comma-separated listen setting. An empty spec yields
+// no entries.
+func ParseSpec(spec string) ([]Entry, error) {
+	var entries []Entry
+	for _, raw := range strings.Split(spec, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		entry, err := parseEntry(raw)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// NormalizeSpec validates spec and returns it in canonical form with
+// duplicates removed.
+func NormalizeSpec(spec string) (string, error) {
+	entries, err := ParseSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	seen := make(map[string]struct{}, len(entries))
+	normalized := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		text := entry.String()
+		if _, ok := seen[text]; ok {
+			continue
+		}
+		seen[text] = struct{}{}
+		normalized = append(normalized, text)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+func parseEntry(raw string) (Entry, error) {
+	entry := Entry{}
+	rest := raw
+	if lower := strings.ToLower(rest); strings.HasPrefix(lower, httpsPrefix) {
+		entry.TLS = true
+		rest = rest[len(httpsPrefix):]
+	} else if strings.HasPrefix(lower, "http://") {
+		rest = rest[len("http://"):]
+	}
+	rest = strings.TrimSuffix(rest, "/")
+
+	host := rest
+	if ip := net.ParseIP(strings.Trim(rest, "[]")); ip != nil {
+		// A bare address; IPv6 colons are not a port separator.
+		host = ""
+		entry.IP = ip
+	} else if strings.Contains(rest, ":") {
+		h, port, err := net.SplitHostPort(rest)
+		if err != nil {
+			return Entry{}, fmt.Errorf("invalid listen entry %q: %v", raw, err)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return Entry{}, fmt.Errorf("invalid port in listen entry %q", raw)
+		}
+		host = h
+		entry.Port = port
+	}
+	if host != "" {
+		if ip := net.ParseIP(host); ip != nil {
+			entry.IP = ip
+		} else if ifacePattern.MatchString(host) {
+			entry.Interface = host
+		} else {
+			return Entry{}, fmt.Errorf("listen entry %q is neither an interface nor an IP address", raw)
+		}
+	}
+	if entry.IP == nil && entry.Interface == "" {
+		return Entry{}, fmt.Errorf("listen entry %q has no interface or address", raw)
+	}
+	if v4 := entry.IP.To4(); v4 != nil {
+		entry.IP = v4
+	}
+	return entry, nil
+}
+
+// Resolve expands entries into listener addresses. Interface entries bind
+// the interface's first IPv4 address and every global IPv6 address, using
+// lookup to read them. Entries that cannot be resolved are reported in errs
+// and skipped, so one missing interface does not take down the others.
+func Resolve(entries []Entry, defaultPort string, lookup func(iface string) ([]net.IP, error)) (addrs []Address, errs []error) {
+	seen := make(map[string]struct{})
+	add := func(ip net.IP, entry Entry) {
+		port := entry.Port
+		if port == "" {
+			port = defaultPort
+		}
+		addr := net.JoinHostPort(ip.String(), port)
+		if _, ok := seen[addr]; ok {
+			return
+		}
+		seen[addr] = struct{}{}
+		addrs = append(addrs, Address{Addr: addr, TLS: entry.TLS, Source: entry.String()})
+	}
+	for _, entry := range entries {
+		if entry.IP != nil {
+			add(entry.IP, entry)
+			continue
+		}
+		ips, err := lookup(entry.Interface)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listen interface %s: %w", entry.Interface, err))
+			continue
+		}
+		bound := 0
+		haveV4 := false
+		for _, ip := range ips {
+			if v4 := ip.To4(); v4 != nil {
+				if haveV4 {
+					continue
+				}
+				haveV4 = true
+				add(v4, entry)
+				bound++
+				continue
+			}
+			if ip.IsGlobalUnicast() {
+				add(ip, entry)
+				bound++
+			}
+		}
+		if bound == 0 {
+			errs = append(errs, fmt.Errorf("listen interface %s has no usable address", entry.Interface))
+		}
+	}
+	return addrs, errs
+}
+
+// InterfaceIPs returns the addresses bound to an interface.
+func InterfaceIPs(name string) ([]net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if ip, _, err := net.ParseCIDR(addr.String()); err == nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
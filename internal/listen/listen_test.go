@@ -0,0 +1,94 @@
+package listen
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSpec(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{" br0 ", "br0"},
+		{"br0, https://br0:8443", "br0,https://br0:8443"},
+		{"HTTPS://192.168.1.1", "https://192.168.1.1"},
+		{"http://10.0.0.1:8091/", "10.0.0.1:8091"},
+		{"fd00::1", "fd00::1"},
+		{"[fd00::1]:8443", "[fd00::1]:8443"},
+		{"https://fd00::1", "https://[fd00::1]"},
+		{"br0,br0", "br0"},
+	}
+	for _, tc := range cases {
+		got, err := NormalizeSpec(tc.in)
+		if err != nil {
+			t.Fatalf("NormalizeSpec(%q): %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Fatalf("NormalizeSpec(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseSpecRejectsInvalidEntries(t *testing.T) {
+	for _, spec := range []string{"br0:0", "br0:70000", "br0:http", "not/an iface", "averyveryverylongname", "https://"} {
+		if _, err := ParseSpec(spec); err == nil {
+			t.Fatalf("ParseSpec(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	lookup := func(name string) ([]net.IP, error) {
+		switch name {
+		case "br0":
+			return []net.IP{
+				net.ParseIP("192.168.1.1"),
+				net.ParseIP("192.168.2.1"),
+				net.ParseIP("fe80::1"),
+				net.ParseIP("fd00::1"),
+			}, nil
+		case "lo":
+			return []net.IP{net.ParseIP("fe80::2")}, nil
+		}
+		return nil, errors.New("no such interface")
+	}
+	entries, err := ParseSpec("br0, https://br0:8443, 10.0.0.1, 192.168.1.1, eth9, lo")
+	if err != nil {
+		t.Fatalf("ParseSpec: %v", err)
+	}
+	addrs, errs := Resolve(entries, "8091", lookup)
+	want := []Address{
+		{Addr: "192.168.1.1:8091", Source: "br0"},
+		{Addr: "[fd00::1]:8091", Source: "br0"},
+		{Addr: "192.168.1.1:8443", TLS: true, Source: "https://br0:8443"},
+		{Addr: "[fd00::1]:8443", TLS: true, Source: "https://br0:8443"},
+		{Addr: "10.0.0.1:8091", Source: "10.0.0.1"},
+	}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("Resolve addrs = %+v, want %+v", addrs, want)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected errors for eth9 and lo, got %v", errs)
+	}
+}
+
+func TestLoadOrCreateCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls", "cert.pem")
+	keyPath := filepath.Join(dir, "tls", "key.pem")
+	first, err := LoadOrCreateCertificate(certPath, keyPath, []string{"192.168.1.1", "router.lan"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	second, err := LoadOrCreateCertificate(certPath, keyPath, nil)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reflect.DeepEqual(first.Certificate, second.Certificate) {
+		t.Fatalf("expected existing certificate to be reused")
+	}
+}
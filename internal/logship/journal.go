@@ -0,0 +1,114 @@
+package logship
+
+import (
+	"strings"
+	"time"
+)
+
+// journalLines is how far back each poll reads a unit's journal. Lines
+// beyond it that scrolled past between polls are not shipped.
+const journalLines = 200
+
+// journalTimeLayouts are the timestamps journalctl -o short-iso prints,
+// which vary with the systemd version.
+var journalTimeLayouts = []string{
+	"2006-01-02T15:04:05-0700",
+	time.RFC3339Nano,
+}
+
+// journalCursor remembers the newest journal second shipped for a unit and
+// the lines already shipped within it, since short-iso has no sub-second
+// precision.
+type journalCursor struct {
+	last time.Time
+	seen map[string]struct{}
+}
+
+// PollJournals reads each VPN unit's recent journal and queues the lines
+// logged since the previous poll. A unit seen for the first time only has
+// its position recorded, so a restart does not replay old lines.
+func (s *Shipper) PollJournals() {
+	if s.units == nil || s.journal == nil {
+		return
+	}
+	s.mu.Lock()
+	enabled := s.config.Enabled()
+	s.mu.Unlock()
+	if !enabled {
+		return
+	}
+	units := s.units()
+	active := make(map[string]struct{}, len(units))
+	for _, unit := range units {
+		active[unit.Unit] = struct{}{}
+		lines, err := s.journal(unit.Unit, journalLines)
+		if err != nil {
+			continue
+		}
+		s.mu.Lock()
+		cursor, known := s.cursors[unit.Unit]
+		if !known {
+			cursor = &journalCursor{seen: make(map[string]struct{})}
+			s.cursors[unit.Unit] = cursor
+		}
+		s.mu.Unlock()
+		for _, line := range lines {
+			at, message, ok := parseJournalLine(line)
+			if !ok || at.Before(cursor.last) {
+				continue
+			}
+			if at.After(cursor.last) {
+				cursor.last = at
+				cursor.seen = make(map[string]struct{})
+			}
+			if _, dup := cursor.seen[line]; dup {
+				continue
+			}
+			cursor.seen[line] = struct{}{}
+			if known {
+				s.Enqueue(Record{Time: at, Level: journalLevel(message), Source: SourceVPN, Module: "unit", VPN: unit.VPN, Message: message})
+			}
+		}
+	}
+	s.mu.Lock()
+	for unit := range s.cursors {
+		if _, ok := active[unit]; !ok {
+			delete(s.cursors, unit)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// parseJournalLine splits a short-iso line ("<time> <host> <ident>[pid]:
+// <message>") into its time and the message with the identifier kept.
+func parseJournalLine(line string) (time.Time, string, bool) {
+	stamp, rest, ok := strings.Cut(strings.TrimSpace(line), " ")
+	if !ok {
+		return time.Time{}, "", false
+	}
+	var at time.Time
+	var err error
+	for _, layout := range journalTimeLayouts {
+		if at, err = time.Parse(layout, stamp); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	_, message, ok := strings.Cut(rest, " ")
+	if !ok || strings.TrimSpace(message) == "" {
+		return time.Time{}, "", false
+	}
+	return at.UTC(), strings.TrimSpace(message), true
+}
+
+// journalLevel guesses a level for unit output, which carries none in
+// short-iso: failures and errors are warnings, the rest is info.
+func journalLevel(message string) string {
+	lower := strings.ToLower(message)
+	if strings.Contains(lower, "error") || strings.Contains(lower, "fail") || strings.Contains(lower, "warning") {
+		return "warn"
+	}
+	return "info"
+}
@@ -0,0 +1,371 @@
+// Package logship forwards application log entries and the journal of each
+// VPN unit to a remote syslog server or a Grafana Loki endpoint, labelled
+// with the VPN, routing group and module they concern.
+package logship
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+// Record sources.
+const (
+	SourceApp = "app"
+	SourceVPN = "vpn"
+)
+
+// Levels, lowest first.
+var levels = []string{"debug", "info", "warn", "error"}
+
+const (
+	// QueueSize bounds records waiting to be shipped; newer records are
+	// dropped while the queue is full.
+	QueueSize = 2000
+
+	checkInterval   = 5 * time.Second
+	flushInterval   = 2 * time.Second
+	journalInterval = 10 * time.Second
+	maxBatch        = 500
+	sendTimeout     = 10 * time.Second
+)
+
+// Config is the shipping target taken from settings. An empty URL turns
+// shipping off.
+type Config struct {
+	URL      string
+	MinLevel string
+	Username string
+	Token    string
+}
+
+// Enabled reports whether a target is configured.
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// ConfigFromSettings reads the shipping settings with defaults filled in.
+func ConfigFromSettings(current settings.Settings) Config {
+	config := Config{
+		URL:      strings.TrimSpace(current.LogShipURL),
+		MinLevel: strings.ToLower(strings.TrimSpace(current.LogShipMinLevel)),
+		Username: strings.TrimSpace(current.LogShipUsername),
+		Token:    strings.TrimSpace(current.LogShipToken),
+	}
+	if levelRank(config.MinLevel) < 0 {
+		config.MinLevel = "info"
+	}
+	return config
+}
+
+// ValidateSettings checks the shipping settings.
+func ValidateSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	if target := strings.TrimSpace(current.LogShipURL); target != "" {
+		parsed, err := url.Parse(target)
+		switch {
+		case err != nil || parsed.Host == "":
+			errs.Addf("logShipUrl", "logShipUrl must be a udp://, tcp:// or tls:// syslog address or an http(s) Loki push URL")
+		case parsed.User != nil:
+			errs.Addf("logShipUrl", "logShipUrl must not carry credentials; use the username and token fields")
+		default:
+			switch parsed.Scheme {
+			case "udp", "tcp", "tls":
+				if parsed.Port() == "" {
+					errs.Addf("logShipUrl", "syslog addresses need a port, e.g. udp://logs.lan:514")
+				}
+			case "http", "https":
+			default:
+				errs.Addf("logShipUrl", "logShipUrl must be a udp://, tcp:// or tls:// syslog address or an http(s) Loki push URL")
+			}
+		}
+	}
+	if level := strings.ToLower(strings.TrimSpace(current.LogShipMinLevel)); level != "" && levelRank(level) < 0 {
+		errs.Addf("logShipMinLevel", "logShipMinLevel must be debug, info, warn or error")
+	}
+	return errs.Err()
+}
+
+func levelRank(level string) int {
+	for i, candidate := range levels {
+		if candidate == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// SettingsSource provides the current shipping settings.
+type SettingsSource interface {
+	Get() (settings.Settings, error)
+}
+
+// Record is one log line to ship. VPN, Group and Module become labels.
+type Record struct {
+	Time    time.Time
+	Level   string
+	Source  string
+	Module  string
+	VPN     string
+	Group   string
+	Message string
+}
+
+// Unit is a VPN's systemd unit whose journal is shipped.
+type Unit struct {
+	VPN  string
+	Unit string
+}
+
+// UnitSource lists the VPN units to follow.
+type UnitSource func() []Unit
+
+// JournalReader returns a unit's most recent journal lines in short-iso
+// format, oldest first.
+type JournalReader func(unit string, lines int) ([]string, error)
+
+// Status describes the shipping target and its recent outcome.
+type Status struct {
+	Enabled   bool      `json:"enabled"`
+	URL       string    `json:"url,omitempty"`
+	LastShip  time.Time `json:"lastShip,omitzero"`
+	Shipped   uint64    `json:"shipped"`
+	Dropped   uint64    `json:"dropped"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// sink delivers a batch of records to one target.
+type sink interface {
+	send(ctx context.Context, records []Record) error
+	close()
+}
+
+// Shipper queues records and ships them in batches to the target in
+// settings. Failures are kept in the status rather than logged, since a
+// logged failure would itself be queued for shipping.
+type Shipper struct {
+	settings SettingsSource
+	units    UnitSource
+	journal  JournalReader
+	queue    chan Record
+	host     string
+	now      func() time.Time
+
+	mu         sync.Mutex
+	config     Config
+	sink       sink
+	cursors    map[string]*journalCursor
+	status     Status
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewShipper creates a shipper. units and journal may be nil to ship
+// application logs only.
+func NewShipper(source SettingsSource, units UnitSource, journal JournalReader) (*Shipper, error) {
+	if source == nil {
+		return nil, fmt.Errorf("settings source is required")
+	}
+	host, _ := os.Hostname()
+	return &Shipper{
+		settings: source,
+		units:    units,
+		journal:  journal,
+		queue:    make(chan Record, QueueSize),
+		host:     host,
+		now:      time.Now,
+		cursors:  make(map[string]*journalCursor),
+	}, nil
+}
+
+// Status reports the target and how many records were shipped or dropped.
+func (s *Shipper) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Enabled = s.config.Enabled()
+	if status.Enabled {
+		status.URL = s.config.URL
+	}
+	return status
+}
+
+// Enqueue queues a record without blocking. Records below the configured
+// level, and all records while shipping is off, are discarded.
+func (s *Shipper) Enqueue(record Record) {
+	s.mu.Lock()
+	config := s.config
+	s.mu.Unlock()
+	if !config.Enabled() || levelRank(record.Level) < levelRank(config.MinLevel) {
+		return
+	}
+	if record.Time.IsZero() {
+		record.Time = s.now()
+	}
+	select {
+	case s.queue <- record:
+	default:
+		s.mu.Lock()
+		s.status.Dropped++
+		s.mu.Unlock()
+	}
+}
+
+// Start launches the shipping loop.
+func (s *Shipper) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.started = true
+	s.loopCancel = cancel
+	s.mu.Unlock()
+
+	s.loopWG.Add(1)
+	go func() {
+		defer s.loopWG.Done()
+		s.reload()
+		check := time.NewTicker(checkInterval)
+		defer check.Stop()
+		flush := time.NewTicker(flushInterval)
+		defer flush.Stop()
+		journal := time.NewTicker(journalInterval)
+		defer journal.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				s.Flush(context.Background())
+				return
+			case <-check.C:
+				s.reload()
+			case <-journal.C:
+				s.PollJournals()
+			case <-flush.C:
+				s.Flush(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop ships what is queued and terminates the loop.
+func (s *Shipper) Stop() error {
+	s.mu.Lock()
+	loopCancel := s.loopCancel
+	s.started = false
+	s.loopCancel = nil
+	s.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	s.loopWG.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sink != nil {
+		s.sink.close()
+		s.sink = nil
+	}
+	return nil
+}
+
+// reload follows settings changes, replacing the sink when the target
+// changes.
+func (s *Shipper) reload() {
+	current, err := s.settings.Get()
+	if err != nil {
+		return
+	}
+	config := ConfigFromSettings(current)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if config == s.config {
+		return
+	}
+	if s.sink != nil {
+		s.sink.close()
+		s.sink = nil
+	}
+	s.config = config
+	s.status = Status{}
+	s.cursors = make(map[string]*journalCursor)
+	if config.Enabled() {
+		sink, err := newSink(config, s.host)
+		if err != nil {
+			s.status.LastError = err.Error()
+			return
+		}
+		s.sink = sink
+	}
+}
+
+// Flush ships queued records in batches until the queue is empty. A batch
+// that fails is dropped rather than retried, so a dead target cannot grow
+// memory.
+func (s *Shipper) Flush(ctx context.Context) {
+	for {
+		batch := make([]Record, 0, maxBatch)
+	fill:
+		for len(batch) < maxBatch {
+			select {
+			case record := <-s.queue:
+				batch = append(batch, record)
+			default:
+				break fill
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		s.mu.Lock()
+		sink := s.sink
+		s.mu.Unlock()
+		if sink == nil {
+			s.mu.Lock()
+			s.status.Dropped += uint64(len(batch))
+			s.mu.Unlock()
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := sink.send(sendCtx, batch)
+		cancel()
+		s.mu.Lock()
+		if err != nil {
+			s.status.Dropped += uint64(len(batch))
+			s.status.LastError = err.Error()
+		} else {
+			s.status.Shipped += uint64(len(batch))
+			s.status.LastShip = s.now()
+			s.status.LastError = ""
+		}
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func newSink(config Config, host string) (sink, error) {
+	parsed, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch parsed.Scheme {
+	case "udp", "tcp", "tls":
+		return newSyslogSink(parsed.Scheme, parsed.Host, host), nil
+	case "http", "https":
+		return newLokiSink(config, host), nil
+	default:
+		return nil, fmt.Errorf("unsupported log target %q", parsed.Scheme)
+	}
+}
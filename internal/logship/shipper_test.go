@@ -0,0 +1,228 @@
+package logship
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+type staticSettings struct {
+	value settings.Settings
+}
+
+func (s *staticSettings) Get() (settings.Settings, error) { return s.value, nil }
+
+var testTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestShipper(t *testing.T, current settings.Settings, units UnitSource, journal JournalReader) *Shipper {
+	t.Helper()
+	shipper, err := NewShipper(&staticSettings{value: current}, units, journal)
+	if err != nil {
+		t.Fatalf("NewShipper: %v", err)
+	}
+	shipper.host = "gw"
+	shipper.now = func() time.Time { return testTime }
+	shipper.reload()
+	t.Cleanup(func() { _ = shipper.Stop() })
+	return shipper
+}
+
+func TestFormatSyslog(t *testing.T) {
+	got := formatSyslog(Record{
+		Time:    testTime,
+		Level:   "warn",
+		Source:  SourceApp,
+		Module:  "routing",
+		VPN:     `wg"fra]`,
+		Message: "apply failed\nretrying",
+	}, "gw")
+	want := `<28>1 2026-03-01T12:00:00.000000Z gw split-vpn-webui - app [svpn@32473 module="routing" vpn="wg\"fra\]"] apply failed retrying`
+	if got != want {
+		t.Fatalf("unexpected syslog message:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestShipperSendsSyslogOverTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	received := make(chan string, 4)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			var n int
+			for _, c := range strings.TrimSpace(length) {
+				n = n*10 + int(c-'0')
+			}
+			message := make([]byte, n)
+			if _, err := io.ReadFull(reader, message); err != nil {
+				return
+			}
+			received <- string(message)
+		}
+	}()
+
+	shipper := newTestShipper(t, settings.Settings{LogShipURL: "tcp://" + listener.Addr().String(), LogShipMinLevel: "warn"}, nil, nil)
+	shipper.Enqueue(Record{Level: "info", Source: SourceApp, Message: "below the level"})
+	shipper.Enqueue(Record{Level: "error", Source: SourceApp, Module: "prewarm", Group: "streaming", Message: "prewarm run failed"})
+	shipper.Enqueue(Record{Level: "warn", Source: SourceVPN, VPN: "wg-fra", Message: "handshake did not complete"})
+	shipper.Flush(context.Background())
+
+	for _, want := range []string{
+		`<27>1 2026-03-01T12:00:00.000000Z gw split-vpn-webui - app [svpn@32473 module="prewarm" group="streaming"] prewarm run failed`,
+		`<28>1 2026-03-01T12:00:00.000000Z gw split-vpn-webui - vpn [svpn@32473 vpn="wg-fra"] handshake did not complete`,
+	} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("unexpected message:\n got %s\nwant %s", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for syslog message")
+		}
+	}
+	if status := shipper.Status(); !status.Enabled || status.Shipped != 2 || status.LastError != "" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestShipperPushesToLoki(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []map[string]any
+	var auth string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		defer mu.Unlock()
+		pushes = append(pushes, payload)
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	shipper := newTestShipper(t, settings.Settings{LogShipURL: target.URL + "/loki/api/v1/push", LogShipToken: "secret"}, nil, nil)
+	shipper.Enqueue(Record{Time: testTime.Add(time.Second), Level: "info", Source: SourceApp, Module: "routing", Message: "second"})
+	shipper.Enqueue(Record{Time: testTime, Level: "info", Source: SourceApp, Module: "routing", Message: "first"})
+	shipper.Enqueue(Record{Time: testTime, Level: "info", Source: SourceVPN, VPN: "wg-fra", Module: "unit", Message: "up"})
+	shipper.Flush(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) != 1 || auth != "Bearer secret" {
+		t.Fatalf("unexpected pushes %v auth %q", pushes, auth)
+	}
+	encoded, _ := json.Marshal(pushes[0])
+	want := `{"streams":[` +
+		`{"stream":{"host":"gw","job":"split-vpn-webui","level":"info","module":"routing","source":"app"},"values":[["1772366400000000000","first"],["1772366401000000000","second"]]},` +
+		`{"stream":{"host":"gw","job":"split-vpn-webui","level":"info","module":"unit","source":"vpn","vpn":"wg-fra"},"values":[["1772366400000000000","up"]]}]}`
+	if string(encoded) != want {
+		t.Fatalf("unexpected payload:\n got %s\nwant %s", encoded, want)
+	}
+}
+
+func TestShipperFollowsJournals(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	journal := []string{
+		"2026-03-01T11:59:00+0000 gw wg-quick[10]: old line",
+	}
+	var mu sync.Mutex
+	shipper := newTestShipper(t, settings.Settings{LogShipURL: "udp://" + listener.LocalAddr().String()},
+		func() []Unit { return []Unit{{VPN: "wg-fra", Unit: "svpn-wg-fra.service"}} },
+		func(unit string, lines int) ([]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), journal...), nil
+		})
+
+	// The first poll only records where the journal ends.
+	shipper.PollJournals()
+	mu.Lock()
+	journal = append(journal,
+		"2026-03-01T12:00:00+0000 gw wg-quick[10]: [#] ip link add wg-fra type wireguard",
+		"2026-03-01T12:00:00+00:00 gw wg-quick[10]: RTNETLINK answers: Operation failed",
+	)
+	mu.Unlock()
+	shipper.PollJournals()
+	// Lines already shipped are not shipped again.
+	shipper.PollJournals()
+	shipper.Flush(context.Background())
+
+	var got []string
+	buf := make([]byte, 4096)
+	_ = listener.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	for {
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		got = append(got, string(buf[:n]))
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected two new journal lines, got %q", got)
+	}
+	if !strings.HasPrefix(got[0], `<30>1 2026-03-01T12:00:00.000000Z gw split-vpn-webui - vpn [svpn@32473 module="unit" vpn="wg-fra"] wg-quick[10]: [#] ip link add`) {
+		t.Fatalf("unexpected first line %s", got[0])
+	}
+	if !strings.HasPrefix(got[1], "<28>1 ") {
+		t.Fatalf("expected failure line as a warning, got %s", got[1])
+	}
+}
+
+func TestShipperDropsWhenQueueFull(t *testing.T) {
+	shipper := newTestShipper(t, settings.Settings{LogShipURL: "udp://127.0.0.1:9"}, nil, nil)
+	for i := 0; i < QueueSize+3; i++ {
+		shipper.Enqueue(Record{Level: "info", Message: "line"})
+	}
+	if status := shipper.Status(); status.Dropped != 3 {
+		t.Fatalf("expected overflow to be counted, got %+v", status)
+	}
+
+	off := newTestShipper(t, settings.Settings{}, nil, nil)
+	off.Enqueue(Record{Level: "error", Message: "line"})
+	if len(off.queue) != 0 {
+		t.Fatal("expected records to be discarded while shipping is off")
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	for _, target := range []string{"udp://logs.lan:514", "tls://logs.lan:6514", "https://logs-prod.grafana.net/loki/api/v1/push"} {
+		if err := ValidateSettings(settings.Settings{LogShipURL: target, LogShipMinLevel: "Warn"}); err != nil {
+			t.Fatalf("expected %s to be valid, got %v", target, err)
+		}
+	}
+	for _, target := range []string{"udp://logs.lan", "ftp://logs.lan:21", "https://user:pw@loki.lan/loki/api/v1/push", "logs.lan:514"} {
+		if err := ValidateSettings(settings.Settings{LogShipURL: target}); err == nil {
+			t.Fatalf("expected %s to be rejected", target)
+		}
+	}
+	if err := ValidateSettings(settings.Settings{LogShipMinLevel: "verbose"}); err == nil || !strings.Contains(err.Error(), "logShipMinLevel") {
+		t.Fatalf("expected bad level to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"split-vpn-webui/internal/version"
+)
+
+const (
+	appName = "split-vpn-webui"
+	// syslogFacility is daemon.
+	syslogFacility = 3
+	// syslogEnterpriseID scopes the structured-data element carrying the
+	// labels.
+	syslogEnterpriseID = "svpn@32473"
+	// maxSyslogMessage keeps UDP datagrams within common relay limits.
+	maxSyslogMessage = 2048
+	maxErrorBody     = 512
+)
+
+var syslogSeverities = map[string]int{"debug": 7, "info": 6, "warn": 4, "error": 3}
+
+// formatSyslog renders record as an RFC 5424 message. The labels travel
+// as structured data so relays can index them.
+func formatSyslog(record Record, host string) string {
+	severity, ok := syslogSeverities[record.Level]
+	if !ok {
+		severity = syslogSeverities["info"]
+	}
+	if host == "" {
+		host = "-"
+	}
+	var params []string
+	for _, label := range [][2]string{{"module", record.Module}, {"vpn", record.VPN}, {"group", record.Group}} {
+		if label[1] != "" {
+			params = append(params, label[0]+`="`+syslogParamEscaper.Replace(label[1])+`"`)
+		}
+	}
+	data := "-"
+	if len(params) > 0 {
+		data = "[" + syslogEnterpriseID + " " + strings.Join(params, " ") + "]"
+	}
+	msgID := record.Source
+	if msgID == "" {
+		msgID = "-"
+	}
+	message := fmt.Sprintf("<%d>1 %s %s %s - %s %s %s",
+		syslogFacility*8+severity,
+		record.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		host, appName, msgID, data, strings.ReplaceAll(record.Message, "\n", " "))
+	if len(message) > maxSyslogMessage {
+		message = message[:maxSyslogMessage]
+	}
+	return message
+}
+
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogSink writes to a syslog server over UDP, or over TCP or TLS with
+// octet-counted framing (RFC 6587), redialling after a failed write.
+type syslogSink struct {
+	network string
+	address string
+	host    string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogSink(network, address, host string) *syslogSink {
+	return &syslogSink{network: network, address: address, host: host}
+}
+
+func (s *syslogSink) send(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+	for _, record := range records {
+		message := formatSyslog(record, s.host)
+		var err error
+		if s.network == "udp" {
+			_, err = io.WriteString(s.conn, message)
+		} else {
+			_, err = io.WriteString(s.conn, strconv.Itoa(len(message))+" "+message)
+		}
+		if err != nil {
+			_ = s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) dial(ctx context.Context) (net.Conn, error) {
+	switch s.network {
+	case "tls":
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{}}
+		return dialer.DialContext(ctx, "tcp", s.address)
+	default:
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, s.network, s.address)
+	}
+}
+
+func (s *syslogSink) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// lokiSink posts batches to a Loki push endpoint
+// (/loki/api/v1/push), one stream per label set.
+type lokiSink struct {
+	url      string
+	username string
+	token    string
+	host     string
+	client   *http.Client
+}
+
+func newLokiSink(config Config, host string) *lokiSink {
+	return &lokiSink{
+		url:      config.URL,
+		username: config.Username,
+		token:    config.Token,
+		host:     host,
+		client:   &http.Client{Timeout: sendTimeout},
+	}
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// lokiPayload groups records into streams keyed by their labels, each
+// ordered by time as Loki requires.
+func lokiPayload(records []Record, host string) []byte {
+	ordered := append([]Record(nil), records...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Time.Before(ordered[j].Time) })
+	streams := make(map[string]*lokiStream)
+	keys := make([]string, 0)
+	for _, record := range ordered {
+		labels := map[string]string{"job": appName, "source": record.Source, "level": record.Level}
+		for key, value := range map[string]string{"host": host, "module": record.Module, "vpn": record.VPN, "group": record.Group} {
+			if value != "" {
+				labels[key] = value
+			}
+		}
+		key := fmt.Sprint(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(record.Time.UnixNano(), 10), record.Message})
+	}
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{Streams: make([]*lokiStream, 0, len(keys))}
+	sort.Strings(keys)
+	for _, key := range keys {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+	body, _ := json.Marshal(payload)
+	return body
+}
+
+func (l *lokiSink) send(ctx context.Context, records []Record) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(lokiPayload(records, l.host)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", appName+"/"+version.Current().Version)
+	switch {
+	case l.username != "":
+		req.SetBasicAuth(l.username, l.token)
+	case l.token != "":
+		req.Header.Set("Authorization", "Bearer "+l.token)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		if message := strings.TrimSpace(string(detail)); message != "" {
+			return fmt.Errorf("push rejected: %s: %s", resp.Status, message)
+		}
+		return fmt.Errorf("push rejected: %s", resp.Status)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (l *lokiSink) close() {
+	l.client.CloseIdleConnections()
+}
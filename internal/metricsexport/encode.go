@@ -0,0 +1,148 @@
+package metricsexport
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EncodeLineProtocol renders points as InfluxDB line protocol with
+// nanosecond timestamps. Fields that are not finite are dropped, and so is
+// a point left without fields.
+func EncodeLineProtocol(points []Point) []byte {
+	var b strings.Builder
+	for _, point := range points {
+		fields := make([]string, 0, len(point.Fields))
+		for _, field := range point.Fields {
+			if math.IsNaN(field.Value) || math.IsInf(field.Value, 0) {
+				continue
+			}
+			value := strconv.FormatFloat(field.Value, 'f', -1, 64)
+			if field.Integer {
+				value = strconv.FormatInt(int64(field.Value), 10) + "i"
+			}
+			fields = append(fields, escapeLineKey(field.Name)+"="+value)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		b.WriteString(lineMeasurementEscaper.Replace(point.Measurement))
+		for _, key := range sortedTagKeys(point.Tags) {
+			b.WriteByte(',')
+			b.WriteString(escapeLineKey(key))
+			b.WriteByte('=')
+			b.WriteString(escapeLineKey(point.Tags[key]))
+		}
+		b.WriteByte(' ')
+		b.WriteString(strings.Join(fields, ","))
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(point.Time.UnixNano(), 10))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+var (
+	lineMeasurementEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, " ", `\ `, "\n", `\n`)
+	lineKeyEscaper         = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+func escapeLineKey(value string) string {
+	return lineKeyEscaper.Replace(value)
+}
+
+// sortedTagKeys returns the keys of tags with a value, in the order line
+// protocol and remote write both prefer.
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key, value := range tags {
+		if key != "" && value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EncodeRemoteWrite renders points as a snappy-compressed Prometheus
+// remote-write WriteRequest. Each field is one series, named
+// <measurement>_<field> and labelled with the point's tags.
+func EncodeRemoteWrite(points []Point) []byte {
+	var request []byte
+	for _, point := range points {
+		keys := sortedTagKeys(point.Tags)
+		timestamp := point.Time.UnixMilli()
+		for _, field := range point.Fields {
+			if math.IsNaN(field.Value) || math.IsInf(field.Value, 0) {
+				continue
+			}
+			labels := make([][2]string, 0, len(keys)+1)
+			labels = append(labels, [2]string{"__name__", point.Measurement + "_" + field.Name})
+			for _, key := range keys {
+				labels = append(labels, [2]string{key, point.Tags[key]})
+			}
+			sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+
+			var series []byte
+			for _, label := range labels {
+				var encoded []byte
+				encoded = appendProtoString(encoded, 1, label[0])
+				encoded = appendProtoString(encoded, 2, label[1])
+				series = appendProtoBytes(series, 1, encoded)
+			}
+			var sample []byte
+			sample = appendProtoTag(sample, 1, wireFixed64)
+			sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(field.Value))
+			sample = appendProtoTag(sample, 2, wireVarint)
+			sample = binary.AppendUvarint(sample, uint64(timestamp))
+			series = appendProtoBytes(series, 2, sample)
+			request = appendProtoBytes(request, 1, series)
+		}
+	}
+	return snappyEncode(request)
+}
+
+// Protobuf wire types used by the remote-write messages.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func appendProtoTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendProtoBytes(b []byte, field int, value []byte) []byte {
+	b = appendProtoTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendProtoString(b []byte, field int, value string) []byte {
+	return appendProtoBytes(b, field, []byte(value))
+}
+
+// snappyEncode writes src in the snappy block format using literals only.
+// Remote write requires snappy framing; the payloads are small enough that
+// skipping compression costs little and avoids a dependency.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), 1<<16)
+		length := n - 1
+		switch {
+		case length < 60:
+			dst = append(dst, byte(length<<2))
+		case length < 1<<8:
+			dst = append(dst, 60<<2, byte(length))
+		default:
+			dst = append(dst, 61<<2, byte(length), byte(length>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
@@ -0,0 +1,315 @@
+// Package metricsexport pushes throughput, latency and per-device usage to
+// a long-term metrics store, as InfluxDB line protocol or a Prometheus
+// remote-write request, so history outlives the gateway's own retention.
+package metricsexport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/version"
+)
+
+// Push formats.
+const (
+	FormatInflux      = "influx"
+	FormatRemoteWrite = "prometheus"
+)
+
+const (
+	DefaultInterval    = 60 * time.Second
+	MinIntervalSeconds = 10
+	MaxIntervalSeconds = 60 * 60
+
+	checkInterval  = 5 * time.Second
+	requestTimeout = 15 * time.Second
+	// maxErrorBody bounds how much of a rejected push is kept as the error.
+	maxErrorBody = 512
+)
+
+// Config is the push target taken from settings. An empty URL turns the
+// exporter off.
+type Config struct {
+	URL      string
+	Format   string
+	Username string
+	Token    string
+	Interval time.Duration
+}
+
+// Enabled reports whether a push target is configured.
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// ConfigFromSettings reads the export settings with defaults filled in.
+func ConfigFromSettings(current settings.Settings) Config {
+	config := Config{
+		URL:      strings.TrimSpace(current.MetricsExportURL),
+		Format:   strings.ToLower(strings.TrimSpace(current.MetricsExportFormat)),
+		Username: strings.TrimSpace(current.MetricsExportUsername),
+		Token:    strings.TrimSpace(current.MetricsExportToken),
+		Interval: DefaultInterval,
+	}
+	if config.Format == "" {
+		config.Format = FormatInflux
+	}
+	if current.MetricsExportIntervalSeconds > 0 {
+		config.Interval = time.Duration(current.MetricsExportIntervalSeconds) * time.Second
+	}
+	return config
+}
+
+// ValidateSettings checks the export settings.
+func ValidateSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	if target := strings.TrimSpace(current.MetricsExportURL); target != "" {
+		parsed, err := url.Parse(target)
+		switch {
+		case err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "":
+			errs.Addf("metricsExportUrl", "metricsExportUrl must be an http or https URL")
+		case parsed.User != nil:
+			errs.Addf("metricsExportUrl", "metricsExportUrl must not carry credentials; use the username and token fields")
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(current.MetricsExportFormat)) {
+	case "", FormatInflux, FormatRemoteWrite:
+	default:
+		errs.Addf("metricsExportFormat", "metricsExportFormat must be %q or %q", FormatInflux, FormatRemoteWrite)
+	}
+	if current.MetricsExportIntervalSeconds != 0 && (current.MetricsExportIntervalSeconds < MinIntervalSeconds || current.MetricsExportIntervalSeconds > MaxIntervalSeconds) {
+		errs.Addf("metricsExportIntervalSeconds", "metricsExportIntervalSeconds must be between %d and %d", MinIntervalSeconds, MaxIntervalSeconds)
+	}
+	return errs.Err()
+}
+
+// SettingsSource provides the current export settings.
+type SettingsSource interface {
+	Get() (settings.Settings, error)
+}
+
+// Field is one value of a point. Integer fields are written as integers in
+// line protocol; remote write has only floats.
+type Field struct {
+	Name    string
+	Value   float64
+	Integer bool
+}
+
+// Point is one measurement with its tags. In remote write every field
+// becomes its own series named <measurement>_<field>, labelled with the
+// tags.
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      []Field
+	// Time defaults to the push time.
+	Time time.Time
+}
+
+// Source returns the points to push.
+type Source func(ctx context.Context) []Point
+
+// Status describes the last push.
+type Status struct {
+	Enabled   bool      `json:"enabled"`
+	URL       string    `json:"url,omitempty"`
+	Format    string    `json:"format,omitempty"`
+	LastPush  time.Time `json:"lastPush,omitzero"`
+	Points    int       `json:"points,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// Exporter pushes the source's points to the configured target every
+// interval, following settings changes.
+type Exporter struct {
+	settings SettingsSource
+	points   Source
+	client   *http.Client
+	now      func() time.Time
+
+	tickMu sync.Mutex
+
+	mu         sync.Mutex
+	config     Config
+	lastPush   time.Time
+	status     Status
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewExporter creates an exporter.
+func NewExporter(source SettingsSource, points Source) (*Exporter, error) {
+	if source == nil {
+		return nil, fmt.Errorf("settings source is required")
+	}
+	if points == nil {
+		return nil, fmt.Errorf("point source is required")
+	}
+	return &Exporter{
+		settings: source,
+		points:   points,
+		client:   &http.Client{Timeout: requestTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// Status reports the target and the outcome of the last push.
+func (e *Exporter) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	status := e.status
+	status.Enabled = e.config.Enabled()
+	if status.Enabled {
+		status.URL = e.config.URL
+		status.Format = e.config.Format
+	}
+	return status
+}
+
+// Start launches the push loop.
+func (e *Exporter) Start() error {
+	e.mu.Lock()
+	if e.started {
+		e.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.started = true
+	e.loopCancel = cancel
+	e.mu.Unlock()
+
+	e.loopWG.Add(1)
+	go func() {
+		defer e.loopWG.Done()
+		e.Tick(ctx)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.Tick(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the loop.
+func (e *Exporter) Stop() error {
+	e.mu.Lock()
+	loopCancel := e.loopCancel
+	e.started = false
+	e.loopCancel = nil
+	e.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	e.loopWG.Wait()
+	return nil
+}
+
+// Tick follows settings changes and pushes once the interval has passed.
+// A failed push waits for the next interval rather than retrying sooner.
+func (e *Exporter) Tick(ctx context.Context) {
+	e.tickMu.Lock()
+	defer e.tickMu.Unlock()
+
+	current, err := e.settings.Get()
+	if err != nil {
+		return
+	}
+	config := ConfigFromSettings(current)
+	now := e.now()
+	e.mu.Lock()
+	if config != e.config {
+		e.config = config
+		e.lastPush = time.Time{}
+		e.status = Status{}
+	}
+	due := now.Sub(e.lastPush) >= config.Interval
+	if due && config.Enabled() {
+		e.lastPush = now
+	}
+	e.mu.Unlock()
+	if !config.Enabled() || !due {
+		return
+	}
+
+	points := e.points(ctx)
+	for i := range points {
+		if points[i].Time.IsZero() {
+			points[i].Time = now
+		}
+	}
+	err = e.push(ctx, config, points)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.status.LastError = err.Error()
+		return
+	}
+	e.status.LastPush = now
+	e.status.Points = len(points)
+	e.status.LastError = ""
+}
+
+func (e *Exporter) push(ctx context.Context, config Config, points []Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	var body []byte
+	header := http.Header{}
+	switch config.Format {
+	case FormatRemoteWrite:
+		body = EncodeRemoteWrite(points)
+		header.Set("Content-Type", "application/x-protobuf")
+		header.Set("Content-Encoding", "snappy")
+		header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	default:
+		body = EncodeLineProtocol(points)
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("User-Agent", "split-vpn-webui/"+version.Current().Version)
+	switch {
+	case config.Username != "":
+		req.SetBasicAuth(config.Username, config.Token)
+	case config.Token != "" && config.Format == FormatRemoteWrite:
+		req.Header.Set("Authorization", "Bearer "+config.Token)
+	case config.Token != "":
+		req.Header.Set("Authorization", "Token "+config.Token)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		if message := strings.TrimSpace(string(detail)); message != "" {
+			return fmt.Errorf("push rejected: %s: %s", resp.Status, message)
+		}
+		return fmt.Errorf("push rejected: %s", resp.Status)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
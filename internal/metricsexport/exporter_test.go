@@ -0,0 +1,234 @@
+package metricsexport
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+type staticSettings struct {
+	value settings.Settings
+}
+
+func (s *staticSettings) Get() (settings.Settings, error) { return s.value, nil }
+
+var testTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEncodeLineProtocol(t *testing.T) {
+	got := string(EncodeLineProtocol([]Point{
+		{
+			Measurement: "svpn_vpn",
+			Tags:        map[string]string{"vpn": "wg home,2", "host": "gw", "empty": ""},
+			Fields:      []Field{{Name: "up", Value: 1, Integer: true}, {Name: "latency_ms", Value: 23.5}, {Name: "bad", Value: math.NaN()}},
+			Time:        testTime,
+		},
+		{Measurement: "svpn_vpn", Fields: []Field{{Name: "bad", Value: math.Inf(1)}}, Time: testTime},
+	}))
+	want := `svpn_vpn,host=gw,vpn=wg\ home\,2 up=1i,latency_ms=23.5 1772366400000000000` + "\n"
+	if got != want {
+		t.Fatalf("unexpected line protocol:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestEncodeRemoteWrite(t *testing.T) {
+	body := EncodeRemoteWrite([]Point{{
+		Measurement: "svpn_vpn",
+		Tags:        map[string]string{"vpn": "wg-home"},
+		Fields:      []Field{{Name: "up", Value: 1}, {Name: "latency_ms", Value: 23.5}},
+		Time:        testTime,
+	}})
+	request := snappyDecodeLiterals(t, body)
+
+	series := protoFields(t, request)[1]
+	if len(series) != 2 {
+		t.Fatalf("expected one series per field, got %d", len(series))
+	}
+	fields := protoFields(t, series[1])
+	var labels []string
+	for _, label := range fields[1] {
+		pair := protoFields(t, label)
+		labels = append(labels, string(pair[1][0])+"="+string(pair[2][0]))
+	}
+	if strings.Join(labels, ",") != "__name__=svpn_vpn_latency_ms,vpn=wg-home" {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+	sample := fields[2][0]
+	// Tag, eight little-endian bytes of the double, tag, varint timestamp.
+	if sample[0] != 1<<3|wireFixed64 || math.Float64frombits(binary.LittleEndian.Uint64(sample[1:9])) != 23.5 {
+		t.Fatalf("unexpected sample value in %x", sample)
+	}
+	if ts, _ := binary.Uvarint(sample[10:]); sample[9] != 2<<3|wireVarint || int64(ts) != testTime.UnixMilli() {
+		t.Fatalf("unexpected sample timestamp in %x", sample)
+	}
+}
+
+func TestSnappyEncodeLongLiterals(t *testing.T) {
+	src := []byte(strings.Repeat("x", 70000))
+	if got := snappyDecodeLiterals(t, snappyEncode(src)); string(got) != string(src) {
+		t.Fatalf("round trip lost data: got %d bytes", len(got))
+	}
+}
+
+func TestExporterPushesOnInterval(t *testing.T) {
+	var mu sync.Mutex
+	var received []*http.Request
+	var receivedBodies []string
+	reject := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		receivedBodies = append(receivedBodies, string(body))
+		if reject {
+			http.Error(w, "bucket not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+	pushes := func() ([]*http.Request, []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*http.Request(nil), received...), append([]string(nil), receivedBodies...)
+	}
+	setReject := func(value bool) {
+		mu.Lock()
+		reject = value
+		mu.Unlock()
+	}
+
+	source := &staticSettings{value: settings.Settings{MetricsExportURL: target.URL + "/api/v2/write?bucket=net", MetricsExportToken: "secret"}}
+	now := testTime
+	exporter, err := NewExporter(source, func(context.Context) []Point {
+		return []Point{{Measurement: "svpn_vpn", Tags: map[string]string{"vpn": "wg"}, Fields: []Field{{Name: "up", Value: 1, Integer: true}}}}
+	})
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+	exporter.now = func() time.Time { return now }
+
+	exporter.Tick(context.Background())
+	requests, bodies := pushes()
+	if len(requests) != 1 || requests[0].Header.Get("Authorization") != "Token secret" || requests[0].URL.Query().Get("bucket") != "net" {
+		t.Fatalf("unexpected push %+v", requests)
+	}
+	if bodies[0] != "svpn_vpn,vpn=wg up=1i 1772366400000000000\n" {
+		t.Fatalf("unexpected body %q", bodies[0])
+	}
+	if status := exporter.Status(); !status.Enabled || status.Format != FormatInflux || !status.LastPush.Equal(now) || status.Points != 1 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// Nothing is due before the interval.
+	now = now.Add(DefaultInterval / 2)
+	exporter.Tick(context.Background())
+	if requests, _ = pushes(); len(requests) != 1 {
+		t.Fatalf("expected no push before the interval, got %d", len(requests))
+	}
+
+	now = now.Add(DefaultInterval)
+	setReject(true)
+	exporter.Tick(context.Background())
+	requests, _ = pushes()
+	if status := exporter.Status(); len(requests) != 2 || !strings.Contains(status.LastError, "bucket not found") {
+		t.Fatalf("expected rejected push to be reported, got %+v", status)
+	}
+
+	// Switching format pushes at once, with remote-write headers and basic
+	// auth when a username is set.
+	source.value.MetricsExportFormat = FormatRemoteWrite
+	source.value.MetricsExportUsername = "vm"
+	setReject(false)
+	exporter.Tick(context.Background())
+	if requests, _ = pushes(); len(requests) != 3 {
+		t.Fatalf("expected a push after the settings change, got %d", len(requests))
+	}
+	push := requests[2]
+	user, pass, ok := push.BasicAuth()
+	if push.Header.Get("Content-Encoding") != "snappy" || push.Header.Get("Content-Type") != "application/x-protobuf" || !ok || user != "vm" || pass != "secret" {
+		t.Fatalf("unexpected remote-write request headers %v", push.Header)
+	}
+	if status := exporter.Status(); status.LastError != "" || status.Format != FormatRemoteWrite {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	if err := ValidateSettings(settings.Settings{MetricsExportURL: "https://influx.lan:8086/api/v2/write?bucket=x", MetricsExportFormat: "Influx", MetricsExportIntervalSeconds: 30}); err != nil {
+		t.Fatalf("expected valid settings, got %v", err)
+	}
+	err := ValidateSettings(settings.Settings{MetricsExportURL: "https://user:pw@vm.lan/api/v1/write", MetricsExportFormat: "graphite", MetricsExportIntervalSeconds: 5})
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, field := range []string{"metricsExportUrl", "metricsExportFormat", "metricsExportIntervalSeconds"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Fatalf("expected %s to be rejected, got %v", field, err)
+		}
+	}
+}
+
+// snappyDecodeLiterals reverses snappyEncode; it does not handle copies.
+func snappyDecodeLiterals(t *testing.T, src []byte) []byte {
+	t.Helper()
+	size, n := binary.Uvarint(src)
+	src = src[n:]
+	var out []byte
+	for len(src) > 0 {
+		tag := src[0]
+		if tag&3 != 0 {
+			t.Fatalf("unexpected snappy copy tag %x", tag)
+		}
+		length := int(tag >> 2)
+		src = src[1:]
+		switch length {
+		case 60:
+			length, src = int(src[0]), src[1:]
+		case 61:
+			length, src = int(src[0])|int(src[1])<<8, src[2:]
+		}
+		length++
+		out = append(out, src[:length]...)
+		src = src[length:]
+	}
+	if uint64(len(out)) != size {
+		t.Fatalf("snappy length %d does not match header %d", len(out), size)
+	}
+	return out
+}
+
+// protoFields splits a message into its length-delimited fields by number,
+// skipping the rest.
+func protoFields(t *testing.T, msg []byte) map[int][][]byte {
+	t.Helper()
+	fields := make(map[int][][]byte)
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		msg = msg[n:]
+		switch key & 7 {
+		case wireVarint:
+			_, n = binary.Uvarint(msg)
+			msg = msg[n:]
+		case wireFixed64:
+			msg = msg[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(msg)
+			msg = msg[n:]
+			fields[int(key>>3)] = append(fields[int(key>>3)], msg[:length])
+			msg = msg[length:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,345 @@
+// Package mqtt publishes VPN state to an MQTT broker for home automation.
+// It carries a minimal MQTT 3.1.1 client that only publishes at QoS 0,
+// which is all state reporting needs, and announces entities in the Home
+// Assistant MQTT discovery format.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Packet types.
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetPingReq    = 12
+	packetDisconnect = 14
+)
+
+const (
+	dialTimeout      = 10 * time.Second
+	writeTimeout     = 10 * time.Second
+	defaultKeepAlive = 60 * time.Second
+	maxRemaining     = 268435455
+)
+
+var connAckErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Message is a QoS 0 publish.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Options configure a broker connection.
+type Options struct {
+	// BrokerURL is mqtt://host[:1883] or mqtts://host[:8883].
+	BrokerURL string
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	// Will is published by the broker when the connection drops without a
+	// disconnect.
+	Will *Message
+}
+
+// ParseBrokerURL validates a broker URL and returns its dial address and
+// whether it uses TLS.
+func ParseBrokerURL(raw string) (string, bool, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Hostname() == "" {
+		return "", false, fmt.Errorf("mqttBrokerUrl must look like mqtt://host:1883 or mqtts://host:8883")
+	}
+	var secure bool
+	port := parsed.Port()
+	switch strings.ToLower(parsed.Scheme) {
+	case "mqtt", "tcp":
+		if port == "" {
+			port = "1883"
+		}
+	case "mqtts", "ssl", "tls":
+		secure = true
+		if port == "" {
+			port = "8883"
+		}
+	default:
+		return "", false, fmt.Errorf("mqttBrokerUrl scheme must be mqtt or mqtts")
+	}
+	return net.JoinHostPort(parsed.Hostname(), port), secure, nil
+}
+
+// Client is a connection to a broker.
+type Client struct {
+	conn      net.Conn
+	keepAlive time.Duration
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	lastRead time.Time
+	err      error
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Dial connects and logs in to a broker.
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	address, secure, err := ParseBrokerURL(opts.BrokerURL)
+	if err != nil {
+		return nil, err
+	}
+	keepAlive := opts.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultKeepAlive
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	if secure {
+		host, _, _ := net.SplitHostPort(address)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", address, err)
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+	if _, err := conn.Write(encodeConnect(opts, keepAlive)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("send connect: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+	kind, body, err := readPacket(reader)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("read connack: %w", err)
+	}
+	if kind != packetConnAck || len(body) != 2 {
+		_ = conn.Close()
+		return nil, fmt.Errorf("broker answered connect with packet type %d", kind)
+	}
+	if code := body[1]; code != 0 {
+		_ = conn.Close()
+		reason := connAckErrors[code]
+		if reason == "" {
+			reason = fmt.Sprintf("return code %d", code)
+		}
+		return nil, fmt.Errorf("broker refused connection: %s", reason)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	client := &Client{conn: conn, keepAlive: keepAlive, lastRead: time.Now(), done: make(chan struct{})}
+	go client.readLoop(reader)
+	go client.pingLoop()
+	return client, nil
+}
+
+// Publish sends a QoS 0 message.
+func (c *Client) Publish(msg Message) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	packet, err := encodePublish(msg)
+	if err != nil {
+		return err
+	}
+	return c.write(packet)
+}
+
+// Done is closed when the connection ends.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err reports why the connection ended, or nil while it is open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close disconnects cleanly; the will is not published.
+func (c *Client) Close() error {
+	if c.Err() == nil {
+		_ = c.write([]byte{packetDisconnect << 4, 0})
+	}
+	c.fail(errors.New("connection closed"))
+	return nil
+}
+
+func (c *Client) write(packet []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(packet); err != nil {
+		c.fail(fmt.Errorf("write to broker: %w", err))
+		return err
+	}
+	return nil
+}
+
+func (c *Client) fail(err error) {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		_ = c.conn.Close()
+		close(c.done)
+	})
+}
+
+// readLoop drains the broker's packets; only PINGRESP is expected.
+func (c *Client) readLoop(reader *bufio.Reader) {
+	for {
+		if _, _, err := readPacket(reader); err != nil {
+			c.fail(fmt.Errorf("read from broker: %w", err))
+			return
+		}
+		c.mu.Lock()
+		c.lastRead = time.Now()
+		c.mu.Unlock()
+	}
+}
+
+// pingLoop keeps the session alive and drops it when the broker stops
+// answering pings.
+func (c *Client) pingLoop() {
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			silent := time.Since(c.lastRead)
+			c.mu.Unlock()
+			if silent > c.keepAlive*3/2 {
+				c.fail(errors.New("broker stopped answering pings"))
+				return
+			}
+			_ = c.write([]byte{packetPingReq << 4, 0})
+		}
+	}
+}
+
+func encodeConnect(opts Options, keepAlive time.Duration) []byte {
+	var flags byte = 0x02 // clean session
+	body := appendString(nil, "MQTT")
+	body = append(body, 4)
+	payload := appendString(nil, opts.ClientID)
+	if opts.Will != nil {
+		flags |= 0x04
+		if opts.Will.Retain {
+			flags |= 0x20
+		}
+		payload = appendString(payload, opts.Will.Topic)
+		payload = appendBytes(payload, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= 0x40
+			payload = appendString(payload, opts.Password)
+		}
+	}
+	seconds := keepAlive / time.Second
+	if seconds > 0xffff {
+		seconds = 0xffff
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(seconds))
+	return encodePacket(packetConnect<<4, append(body, payload...))
+}
+
+func encodePublish(msg Message) ([]byte, error) {
+	if msg.Topic == "" || strings.ContainsAny(msg.Topic, "+#") {
+		return nil, fmt.Errorf("invalid publish topic %q", msg.Topic)
+	}
+	header := byte(packetPublish << 4)
+	if msg.Retain {
+		header |= 0x01
+	}
+	body := appendString(nil, msg.Topic)
+	body = append(body, msg.Payload...)
+	if len(body) > maxRemaining {
+		return nil, fmt.Errorf("publish to %s is too large", msg.Topic)
+	}
+	return encodePacket(header, body), nil
+}
+
+func encodePacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header >> 4, body, nil
+}
+
+func appendString(buf []byte, value string) []byte {
+	return appendBytes(buf, []byte(value))
+}
+
+func appendBytes(buf, value []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(value)))
+	return append(buf, value...)
+}
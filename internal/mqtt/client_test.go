@@ -0,0 +1,184 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type receivedPacket struct {
+	kind   byte
+	retain bool
+	topic  string
+	body   []byte
+}
+
+// fakeBroker accepts connections, answers CONNECT with connAckCode and
+// reports every packet it receives.
+type fakeBroker struct {
+	listener    net.Listener
+	connAckCode byte
+	packets     chan receivedPacket
+}
+
+func newFakeBroker(t *testing.T, connAckCode byte) *fakeBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	broker := &fakeBroker{listener: listener, connAckCode: connAckCode, packets: make(chan receivedPacket, 256)}
+	go broker.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return broker
+}
+
+func (b *fakeBroker) url() string {
+	return "mqtt://" + b.listener.Addr().String()
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *fakeBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		header, err := reader.Peek(1)
+		if err != nil {
+			return
+		}
+		retain := header[0]&0x01 != 0
+		kind, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		packet := receivedPacket{kind: kind, retain: retain, body: body}
+		switch kind {
+		case packetConnect:
+			_, _ = conn.Write([]byte{packetConnAck << 4, 2, 0, b.connAckCode})
+		case packetPublish:
+			size := int(binary.BigEndian.Uint16(body))
+			packet.topic = string(body[2 : 2+size])
+			packet.body = body[2+size:]
+		}
+		b.packets <- packet
+	}
+}
+
+func (b *fakeBroker) next(t *testing.T) receivedPacket {
+	t.Helper()
+	select {
+	case packet := <-b.packets:
+		return packet
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a packet")
+		return receivedPacket{}
+	}
+}
+
+func TestParseBrokerURL(t *testing.T) {
+	cases := []struct {
+		raw     string
+		address string
+		secure  bool
+		wantErr bool
+	}{
+		{raw: "mqtt://broker.lan", address: "broker.lan:1883"},
+		{raw: "tcp://10.0.0.2:1884", address: "10.0.0.2:1884"},
+		{raw: "mqtts://broker.lan", address: "broker.lan:8883", secure: true},
+		{raw: "http://broker.lan", wantErr: true},
+		{raw: "broker.lan", wantErr: true},
+	}
+	for _, tc := range cases {
+		address, secure, err := ParseBrokerURL(tc.raw)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseBrokerURL(%q): expected error", tc.raw)
+			}
+			continue
+		}
+		if err != nil || address != tc.address || secure != tc.secure {
+			t.Errorf("ParseBrokerURL(%q) = %q, %v, %v", tc.raw, address, secure, err)
+		}
+	}
+}
+
+func TestDialSendsCredentialsAndWill(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	client, err := Dial(context.Background(), Options{
+		BrokerURL: broker.url(),
+		ClientID:  "gateway",
+		Username:  "ha",
+		Password:  "secret",
+		Will:      &Message{Topic: "svw/status", Payload: []byte("offline"), Retain: true},
+	})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+
+	connect := broker.next(t)
+	if connect.kind != packetConnect {
+		t.Fatalf("expected CONNECT, got packet type %d", connect.kind)
+	}
+	// Protocol name, level, then the flags byte.
+	flags := connect.body[7]
+	if flags != 0x02|0x04|0x20|0x40|0x80 {
+		t.Fatalf("unexpected connect flags %08b", flags)
+	}
+	for _, want := range []string{"gateway", "svw/status", "offline", "ha", "secret"} {
+		if !strings.Contains(string(connect.body), want) {
+			t.Fatalf("connect payload is missing %q", want)
+		}
+	}
+
+	if err := client.Publish(Message{Topic: "svw/vpn/wg/state", Payload: []byte(`{"connected":"ON"}`), Retain: true}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	publish := broker.next(t)
+	if publish.kind != packetPublish || publish.topic != "svw/vpn/wg/state" || !publish.retain || string(publish.body) != `{"connected":"ON"}` {
+		t.Fatalf("unexpected publish %+v", publish)
+	}
+	if err := client.Publish(Message{Topic: "svw/#"}); err == nil {
+		t.Fatal("expected wildcard topic to be rejected")
+	}
+
+	_ = client.Close()
+	if packet := broker.next(t); packet.kind != packetDisconnect {
+		t.Fatalf("expected DISCONNECT, got packet type %d", packet.kind)
+	}
+	if client.Err() == nil {
+		t.Fatal("expected closed client to report an error")
+	}
+}
+
+func TestDialReportsRefusedConnection(t *testing.T) {
+	broker := newFakeBroker(t, 4)
+	_, err := Dial(context.Background(), Options{BrokerURL: broker.url(), ClientID: "gateway"})
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Fatalf("expected refused connection, got %v", err)
+	}
+}
+
+func TestEncodePacketUsesVariableLength(t *testing.T) {
+	packet := encodePacket(packetPublish<<4, make([]byte, 321))
+	if packet[1] != 0xc1 || packet[2] != 0x02 {
+		t.Fatalf("unexpected remaining length bytes %x", packet[1:3])
+	}
+	kind, body, err := readPacket(bufio.NewReader(strings.NewReader(string(packet))))
+	if err != nil || kind != packetPublish || len(body) != 321 {
+		t.Fatalf("readPacket = %d, %d bytes, %v", kind, len(body), err)
+	}
+}
@@ -0,0 +1,93 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"split-vpn-webui/internal/version"
+)
+
+// discoveryMessages are the retained Home Assistant discovery configs of a
+// VPN: connectivity, latency, throughput and an event entity for its
+// timeline.
+func (p *Publisher) discoveryMessages(config Config, name string) []Message {
+	node := Slug(config.TopicPrefix)
+	slug := Slug(name)
+	device := map[string]any{
+		"identifiers":  []string{node},
+		"name":         "Split VPN",
+		"manufacturer": "split-vpn-webui",
+		"sw_version":   version.Current().Version,
+	}
+	stateTopic := vpnTopic(config, name, "state")
+	base := func(kind, suffix, label string) (string, map[string]any) {
+		topic := fmt.Sprintf("%s/%s/%s/%s_%s/config", config.DiscoveryPrefix, kind, node, slug, suffix)
+		return topic, map[string]any{
+			"name":               fmt.Sprintf("%s %s", name, label),
+			"unique_id":          fmt.Sprintf("%s_%s_%s", node, slug, suffix),
+			"availability_topic": availabilityTopic(config),
+			"device":             device,
+		}
+	}
+	entities := make([]Message, 0, 5)
+	add := func(topic string, entity map[string]any) {
+		data, err := json.Marshal(entity)
+		if err == nil {
+			entities = append(entities, Message{Topic: topic, Payload: data, Retain: true})
+		}
+	}
+
+	topic, entity := base("binary_sensor", "connected", "connected")
+	entity["state_topic"] = stateTopic
+	entity["value_template"] = "{{ value_json.connected }}"
+	entity["device_class"] = "connectivity"
+	add(topic, entity)
+
+	topic, entity = base("sensor", "latency", "latency")
+	entity["state_topic"] = stateTopic
+	entity["value_template"] = "{{ value_json.latency_ms }}"
+	entity["unit_of_measurement"] = "ms"
+	entity["state_class"] = "measurement"
+	add(topic, entity)
+
+	for _, direction := range []string{"rx", "tx"} {
+		label := "download"
+		if direction == "tx" {
+			label = "upload"
+		}
+		topic, entity = base("sensor", direction, label)
+		entity["state_topic"] = stateTopic
+		entity["value_template"] = fmt.Sprintf("{{ value_json.%s_mbps }}", direction)
+		entity["unit_of_measurement"] = "Mbit/s"
+		entity["device_class"] = "data_rate"
+		entity["state_class"] = "measurement"
+		add(topic, entity)
+	}
+
+	if len(p.eventTypes) > 0 {
+		topic, entity = base("event", "event", "event")
+		entity["state_topic"] = vpnTopic(config, name, "event")
+		entity["event_types"] = p.eventTypes
+		add(topic, entity)
+	}
+	return entities
+}
+
+// Slug turns a name into a topic level and Home Assistant object id:
+// lower-case letters, digits and underscores.
+func Slug(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			builder.WriteRune(r)
+		default:
+			builder.WriteByte('_')
+		}
+	}
+	if builder.Len() == 0 {
+		return "_"
+	}
+	return builder.String()
+}
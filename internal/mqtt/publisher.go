@@ -0,0 +1,447 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+const (
+	DefaultTopicPrefix     = "split-vpn-webui"
+	DefaultDiscoveryPrefix = "homeassistant"
+	DefaultInterval        = 30 * time.Second
+	MinIntervalSeconds     = 5
+	MaxIntervalSeconds     = 60 * 60
+
+	checkInterval = 5 * time.Second
+	// retryDelay holds off another connect after one failed.
+	retryDelay = 30 * time.Second
+
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+)
+
+// Config is the broker and topic layout taken from settings. An empty
+// broker URL turns publishing off.
+type Config struct {
+	BrokerURL       string
+	Username        string
+	Password        string
+	TopicPrefix     string
+	DiscoveryPrefix string
+	Interval        time.Duration
+}
+
+// Enabled reports whether a broker is configured.
+func (c Config) Enabled() bool {
+	return c.BrokerURL != ""
+}
+
+// ConfigFromSettings reads the MQTT settings with defaults filled in.
+func ConfigFromSettings(current settings.Settings) Config {
+	config := Config{
+		BrokerURL:       strings.TrimSpace(current.MQTTBrokerURL),
+		Username:        strings.TrimSpace(current.MQTTUsername),
+		Password:        current.MQTTPassword,
+		TopicPrefix:     strings.Trim(strings.TrimSpace(current.MQTTTopicPrefix), "/"),
+		DiscoveryPrefix: strings.Trim(strings.TrimSpace(current.MQTTDiscoveryPrefix), "/"),
+		Interval:        DefaultInterval,
+	}
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = DefaultTopicPrefix
+	}
+	if config.DiscoveryPrefix == "" {
+		config.DiscoveryPrefix = DefaultDiscoveryPrefix
+	}
+	if current.MQTTIntervalSeconds > 0 {
+		config.Interval = time.Duration(current.MQTTIntervalSeconds) * time.Second
+	}
+	return config
+}
+
+// ValidateSettings checks the MQTT settings.
+func ValidateSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	if broker := strings.TrimSpace(current.MQTTBrokerURL); broker != "" {
+		_, _, err := ParseBrokerURL(broker)
+		errs.Add("mqttBrokerUrl", err)
+	}
+	if current.MQTTIntervalSeconds != 0 && (current.MQTTIntervalSeconds < MinIntervalSeconds || current.MQTTIntervalSeconds > MaxIntervalSeconds) {
+		errs.Addf("mqttIntervalSeconds", "mqttIntervalSeconds must be between %d and %d", MinIntervalSeconds, MaxIntervalSeconds)
+	}
+	for _, prefix := range []struct{ field, value string }{
+		{"mqttTopicPrefix", current.MQTTTopicPrefix},
+		{"mqttDiscoveryPrefix", current.MQTTDiscoveryPrefix},
+	} {
+		if strings.ContainsAny(prefix.value, "+# ") {
+			errs.Addf(prefix.field, "%s must not contain spaces or the + and # wildcards", prefix.field)
+		}
+	}
+	return errs.Err()
+}
+
+// SettingsSource provides the current MQTT settings.
+type SettingsSource interface {
+	Get() (settings.Settings, error)
+}
+
+// VPNState is what one VPN reports on its state topic.
+type VPNState struct {
+	Name      string
+	Connected bool
+	// LatencyMS is nil when the last latency probe failed or none ran yet.
+	LatencyMS *float64
+	// RxBps and TxBps are the current throughput in bits per second.
+	RxBps float64
+	TxBps float64
+}
+
+// StateSource returns the current state of every VPN.
+type StateSource func() []VPNState
+
+// Event is a VPN timeline event to forward.
+type Event struct {
+	VPN    string
+	Type   string
+	Detail string
+	At     time.Time
+}
+
+// Status describes the broker connection.
+type Status struct {
+	Enabled     bool      `json:"enabled"`
+	Connected   bool      `json:"connected"`
+	Broker      string    `json:"broker,omitempty"`
+	TopicPrefix string    `json:"topicPrefix,omitempty"`
+	LastConnect time.Time `json:"lastConnect,omitzero"`
+	LastPublish time.Time `json:"lastPublish,omitzero"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// Publisher keeps a broker connection per the settings and publishes VPN
+// state every interval, timeline events as they happen, and Home Assistant
+// discovery configs for each VPN.
+type Publisher struct {
+	settings   SettingsSource
+	states     StateSource
+	eventTypes []string
+	dial       func(ctx context.Context, opts Options) (*Client, error)
+	now        func() time.Time
+
+	tickMu sync.Mutex
+
+	mu          sync.Mutex
+	config      Config
+	client      *Client
+	announced   map[string]string
+	retryAfter  time.Time
+	lastPublish time.Time
+	status      Status
+	started     bool
+	loopCancel  context.CancelFunc
+	loopWG      sync.WaitGroup
+}
+
+// NewPublisher creates a publisher. eventTypes lists every timeline event
+// type, for the event entities announced to Home Assistant.
+func NewPublisher(source SettingsSource, states StateSource, eventTypes []string) (*Publisher, error) {
+	if source == nil {
+		return nil, fmt.Errorf("settings source is required")
+	}
+	if states == nil {
+		return nil, fmt.Errorf("state source is required")
+	}
+	return &Publisher{
+		settings:   source,
+		states:     states,
+		eventTypes: append([]string(nil), eventTypes...),
+		dial:       Dial,
+		now:        time.Now,
+		announced:  make(map[string]string),
+	}, nil
+}
+
+// Status reports the broker connection and the last publish.
+func (p *Publisher) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.status
+	status.Enabled = p.config.Enabled()
+	status.Connected = p.client != nil && p.client.Err() == nil
+	if status.Enabled {
+		status.Broker = p.config.BrokerURL
+		status.TopicPrefix = p.config.TopicPrefix
+	}
+	return status
+}
+
+// Start launches the connect and publish loop.
+func (p *Publisher) Start() error {
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.started = true
+	p.loopCancel = cancel
+	p.mu.Unlock()
+
+	p.loopWG.Add(1)
+	go func() {
+		defer p.loopWG.Done()
+		p.Tick(ctx)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.Tick(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the loop and disconnects after marking this node
+// offline.
+func (p *Publisher) Stop() error {
+	p.mu.Lock()
+	loopCancel := p.loopCancel
+	p.started = false
+	p.loopCancel = nil
+	p.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	p.loopWG.Wait()
+
+	p.tickMu.Lock()
+	defer p.tickMu.Unlock()
+	p.disconnect()
+	return nil
+}
+
+// Tick follows settings changes, reconnects when needed and publishes
+// state once the interval has passed.
+func (p *Publisher) Tick(ctx context.Context) {
+	p.tickMu.Lock()
+	defer p.tickMu.Unlock()
+
+	current, err := p.settings.Get()
+	if err != nil {
+		return
+	}
+	config := ConfigFromSettings(current)
+	p.mu.Lock()
+	changed := config != p.config
+	p.mu.Unlock()
+	if changed {
+		p.disconnect()
+		p.mu.Lock()
+		p.config = config
+		p.retryAfter = time.Time{}
+		p.status = Status{}
+		p.mu.Unlock()
+	}
+	if !config.Enabled() {
+		return
+	}
+
+	now := p.now()
+	p.mu.Lock()
+	client := p.client
+	retryAfter := p.retryAfter
+	due := now.Sub(p.lastPublish) >= config.Interval
+	p.mu.Unlock()
+	if client == nil || client.Err() != nil {
+		if now.Before(retryAfter) {
+			return
+		}
+		client, err = p.connect(ctx, config)
+		if err != nil {
+			p.mu.Lock()
+			p.client = nil
+			p.retryAfter = now.Add(retryDelay)
+			p.status.LastError = err.Error()
+			p.mu.Unlock()
+			return
+		}
+		due = true
+	}
+	if due {
+		p.publishStates(client, config, now)
+	}
+}
+
+// PublishEvent forwards a timeline event when the broker is connected.
+func (p *Publisher) PublishEvent(event Event) {
+	p.mu.Lock()
+	client := p.client
+	config := p.config
+	p.mu.Unlock()
+	if client == nil || client.Err() != nil {
+		return
+	}
+	payload, err := json.Marshal(map[string]any{
+		"event_type": event.Type,
+		"vpn":        event.VPN,
+		"detail":     event.Detail,
+		"at":         event.At.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return
+	}
+	p.record(client.Publish(Message{Topic: vpnTopic(config, event.VPN, "event"), Payload: payload}), time.Time{})
+}
+
+func (p *Publisher) connect(ctx context.Context, config Config) (*Client, error) {
+	availability := availabilityTopic(config)
+	client, err := p.dial(ctx, Options{
+		BrokerURL: config.BrokerURL,
+		ClientID:  clientID(config),
+		Username:  config.Username,
+		Password:  config.Password,
+		Will:      &Message{Topic: availability, Payload: []byte(payloadOffline), Retain: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Publish(Message{Topic: availability, Payload: []byte(payloadOnline), Retain: true}); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	p.mu.Lock()
+	p.client = client
+	p.announced = make(map[string]string)
+	p.status.LastConnect = p.now()
+	p.status.LastError = ""
+	p.mu.Unlock()
+	return client, nil
+}
+
+func (p *Publisher) disconnect() {
+	p.mu.Lock()
+	client := p.client
+	config := p.config
+	p.client = nil
+	p.mu.Unlock()
+	if client == nil {
+		return
+	}
+	if client.Err() == nil {
+		_ = client.Publish(Message{Topic: availabilityTopic(config), Payload: []byte(payloadOffline), Retain: true})
+	}
+	_ = client.Close()
+}
+
+// publishStates announces new VPNs, publishes every VPN's state and
+// removes the entities of VPNs that are gone.
+func (p *Publisher) publishStates(client *Client, config Config, now time.Time) {
+	states := p.states()
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	p.mu.Lock()
+	announced := make(map[string]string, len(p.announced))
+	for slug, name := range p.announced {
+		announced[slug] = name
+	}
+	p.mu.Unlock()
+
+	var firstErr error
+	note := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	seen := make(map[string]struct{}, len(states))
+	for _, state := range states {
+		slug := Slug(state.Name)
+		seen[slug] = struct{}{}
+		if announced[slug] != state.Name {
+			for _, msg := range p.discoveryMessages(config, state.Name) {
+				note(client.Publish(msg))
+			}
+			announced[slug] = state.Name
+		}
+		note(client.Publish(Message{Topic: vpnTopic(config, state.Name, "state"), Payload: statePayload(state), Retain: true}))
+	}
+	for slug, name := range announced {
+		if _, ok := seen[slug]; ok {
+			continue
+		}
+		for _, msg := range p.discoveryMessages(config, name) {
+			note(client.Publish(Message{Topic: msg.Topic, Retain: true}))
+		}
+		note(client.Publish(Message{Topic: vpnTopic(config, name, "state"), Retain: true}))
+		delete(announced, slug)
+	}
+
+	p.mu.Lock()
+	p.announced = announced
+	p.lastPublish = now
+	p.mu.Unlock()
+	p.record(firstErr, now)
+}
+
+func (p *Publisher) record(err error, published time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.status.LastError = err.Error()
+		return
+	}
+	if !published.IsZero() {
+		p.status.LastPublish = published
+		p.status.LastError = ""
+	}
+}
+
+func statePayload(state VPNState) []byte {
+	connected := "OFF"
+	if state.Connected {
+		connected = "ON"
+	}
+	payload := map[string]any{
+		"connected":  connected,
+		"latency_ms": nil,
+		"rx_mbps":    roundMbps(state.RxBps),
+		"tx_mbps":    roundMbps(state.TxBps),
+	}
+	if state.LatencyMS != nil {
+		payload["latency_ms"] = float64(int64(*state.LatencyMS*10)) / 10
+	}
+	data, _ := json.Marshal(payload)
+	return data
+}
+
+// roundMbps converts bits per second to megabits per second with two
+// decimals.
+func roundMbps(bitsPerSecond float64) float64 {
+	return float64(int64(bitsPerSecond/1e6*100)) / 100
+}
+
+func availabilityTopic(config Config) string {
+	return config.TopicPrefix + "/status"
+}
+
+func vpnTopic(config Config, name, leaf string) string {
+	return fmt.Sprintf("%s/vpn/%s/%s", config.TopicPrefix, Slug(name), leaf)
+}
+
+func clientID(config Config) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "gateway"
+	}
+	return Slug(config.TopicPrefix + "-" + host)
+}
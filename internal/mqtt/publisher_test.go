@@ -0,0 +1,124 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+type staticSettings struct {
+	value settings.Settings
+}
+
+func (s *staticSettings) Get() (settings.Settings, error) { return s.value, nil }
+
+func collectPublishes(t *testing.T, broker *fakeBroker, count int) map[string]receivedPacket {
+	t.Helper()
+	published := make(map[string]receivedPacket)
+	for len(published) < count {
+		packet := broker.next(t)
+		if packet.kind == packetPublish {
+			published[packet.topic] = packet
+		}
+	}
+	return published
+}
+
+func TestPublisherAnnouncesAndPublishesState(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	source := &staticSettings{value: settings.Settings{MQTTBrokerURL: broker.url(), MQTTTopicPrefix: "svw"}}
+	latencyMS := 23.47
+	states := []VPNState{{Name: "wg-home", Connected: true, LatencyMS: &latencyMS, RxBps: 12_345_678, TxBps: 1_000_000}}
+	publisher, err := NewPublisher(source, func() []VPNState { return states }, []string{"up", "down"})
+	if err != nil {
+		t.Fatalf("NewPublisher: %v", err)
+	}
+	defer publisher.Stop()
+
+	publisher.Tick(context.Background())
+	// Availability, five discovery configs and the state.
+	published := collectPublishes(t, broker, 7)
+
+	if online := published["svw/status"]; string(online.body) != payloadOnline || !online.retain {
+		t.Fatalf("unexpected availability %+v", online)
+	}
+	var state map[string]any
+	if err := json.Unmarshal(published["svw/vpn/wg_home/state"].body, &state); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	if state["connected"] != "ON" || state["latency_ms"] != 23.4 || state["rx_mbps"] != 12.34 || state["tx_mbps"] != 1.0 {
+		t.Fatalf("unexpected state %v", state)
+	}
+	var connectivity map[string]any
+	if err := json.Unmarshal(published["homeassistant/binary_sensor/svw/wg_home_connected/config"].body, &connectivity); err != nil {
+		t.Fatalf("decode discovery: %v", err)
+	}
+	if connectivity["device_class"] != "connectivity" || connectivity["state_topic"] != "svw/vpn/wg_home/state" || connectivity["availability_topic"] != "svw/status" {
+		t.Fatalf("unexpected discovery config %v", connectivity)
+	}
+	if _, ok := published["homeassistant/event/svw/wg_home_event/config"]; !ok {
+		t.Fatal("expected an event entity")
+	}
+	if status := publisher.Status(); !status.Enabled || !status.Connected || status.LastPublish.IsZero() {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	publisher.PublishEvent(Event{VPN: "wg-home", Type: "down", Detail: "link down", At: time.Now()})
+	event := broker.next(t)
+	if event.topic != "svw/vpn/wg_home/event" || event.retain || !strings.Contains(string(event.body), `"event_type":"down"`) {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	// A removed VPN has its discovery configs cleared.
+	states = nil
+	publisher.mu.Lock()
+	publisher.lastPublish = time.Time{}
+	publisher.mu.Unlock()
+	publisher.Tick(context.Background())
+	cleared := collectPublishes(t, broker, 6)
+	for topic, packet := range cleared {
+		if len(packet.body) != 0 || !packet.retain {
+			t.Fatalf("expected %s to be cleared, got %q", topic, packet.body)
+		}
+	}
+}
+
+func TestPublisherDisabledWithoutBroker(t *testing.T) {
+	publisher, err := NewPublisher(&staticSettings{}, func() []VPNState { return nil }, nil)
+	if err != nil {
+		t.Fatalf("NewPublisher: %v", err)
+	}
+	publisher.Tick(context.Background())
+	if status := publisher.Status(); status.Enabled || status.Connected {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   settings.Settings
+		wantErr bool
+	}{
+		{name: "empty", value: settings.Settings{}},
+		{name: "valid", value: settings.Settings{MQTTBrokerURL: "mqtts://broker:8883", MQTTIntervalSeconds: 60, MQTTTopicPrefix: "gw/vpn"}},
+		{name: "bad scheme", value: settings.Settings{MQTTBrokerURL: "https://broker"}, wantErr: true},
+		{name: "short interval", value: settings.Settings{MQTTIntervalSeconds: 1}, wantErr: true},
+		{name: "wildcard prefix", value: settings.Settings{MQTTTopicPrefix: "gw/#"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		if err := ValidateSettings(tc.value); (err != nil) != tc.wantErr {
+			t.Errorf("%s: ValidateSettings error = %v", tc.name, err)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	if got := Slug(" WG Home-1 "); got != "wg_home_1" {
+		t.Fatalf("Slug = %q", got)
+	}
+}
@@ -0,0 +1,188 @@
+package mtr
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+
+	"split-vpn-webui/internal/netbind"
+)
+
+const (
+	icmpv4EchoRequest  = 8
+	icmpv4EchoReply    = 0
+	icmpv4Unreachable  = 3
+	icmpv4TimeExceeded = 11
+
+	icmpv6EchoRequest  = 128
+	icmpv6EchoReply    = 129
+	icmpv6Unreachable  = 1
+	icmpv6TimeExceeded = 3
+
+	ipv6HeaderLen = 40
+)
+
+// icmpTransport sends echo requests on a raw ICMP socket bound to the
+// interface with SO_BINDTODEVICE, so the trace follows that interface even
+// when policy routing would pick another.
+type icmpTransport struct{}
+
+func (icmpTransport) Round(ctx context.Context, iface string, target netip.Addr, ttls []int, timeout time.Duration) (map[int]Reply, error) {
+	network, local := "ip4:icmp", "0.0.0.0"
+	if target.Is6() {
+		network, local = "ip6:ipv6-icmp", "::"
+	}
+	config := net.ListenConfig{Control: netbind.Control(iface)}
+	packetConn, err := config.ListenPacket(ctx, network, local)
+	if err != nil {
+		return nil, err
+	}
+	conn := packetConn.(*net.IPConn)
+	defer conn.Close()
+
+	id := uint16(rand.UintN(1 << 16))
+	destination := &net.IPAddr{IP: net.IP(target.AsSlice())}
+	sentAt := make(map[int]time.Time, len(ttls))
+	for _, ttl := range ttls {
+		if err := setTTL(conn, target.Is6(), ttl); err != nil {
+			return nil, err
+		}
+		packet := echoRequest(target.Is6(), id, uint16(ttl))
+		sentAt[ttl] = time.Now()
+		if _, err := conn.WriteTo(packet, destination); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	replies := make(map[int]Reply, len(ttls))
+	buf := make([]byte, 1500)
+	for len(replies) < len(ttls) && ctx.Err() == nil {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			return nil, err
+		}
+		received := time.Now()
+		seq, reached, ok := parseReply(buf[:n], target, id)
+		if !ok {
+			continue
+		}
+		ttl := int(seq)
+		start, sent := sentAt[ttl]
+		if _, seen := replies[ttl]; !sent || seen {
+			continue
+		}
+		addr, _ := netip.AddrFromSlice(from.(*net.IPAddr).IP)
+		replies[ttl] = Reply{From: addr.Unmap(), RTT: received.Sub(start), Reached: reached}
+	}
+	return replies, nil
+}
+
+func setTTL(conn *net.IPConn, ipv6 bool, ttl int) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var setErr error
+	if err := raw.Control(func(fd uintptr) {
+		if ipv6 {
+			setErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+		} else {
+			setErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+		}
+	}); err != nil {
+		return err
+	}
+	return setErr
+}
+
+// echoRequest builds an ICMP echo request. The kernel fills in the ICMPv6
+// checksum, which covers a pseudo-header only it knows.
+func echoRequest(ipv6 bool, id, seq uint16) []byte {
+	packet := make([]byte, 16)
+	packet[0] = icmpv4EchoRequest
+	if ipv6 {
+		packet[0] = icmpv6EchoRequest
+	}
+	binary.BigEndian.PutUint16(packet[4:6], id)
+	binary.BigEndian.PutUint16(packet[6:8], seq)
+	copy(packet[8:], "svpn-mtr")
+	if !ipv6 {
+		binary.BigEndian.PutUint16(packet[2:4], checksum(packet))
+	}
+	return packet
+}
+
+func checksum(data []byte) uint16 {
+	var sum uint32
+	for idx := 0; idx+1 < len(data); idx += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[idx : idx+2]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// parseReply matches an ICMP message to one of our probes and returns its
+// sequence number. reached is true for echo replies and for errors sent by
+// the target itself.
+func parseReply(msg []byte, target netip.Addr, id uint16) (seq uint16, reached bool, ok bool) {
+	if len(msg) < 8 {
+		return 0, false, false
+	}
+	ipv6 := target.Is6()
+	typ := msg[0]
+	switch {
+	case (!ipv6 && typ == icmpv4EchoReply) || (ipv6 && typ == icmpv6EchoReply):
+		if binary.BigEndian.Uint16(msg[4:6]) != id {
+			return 0, false, false
+		}
+		return binary.BigEndian.Uint16(msg[6:8]), true, true
+	case !ipv6 && (typ == icmpv4TimeExceeded || typ == icmpv4Unreachable):
+		inner := msg[8:]
+		if len(inner) < 20 {
+			return 0, false, false
+		}
+		headerLen := int(inner[0]&0x0f) * 4
+		if len(inner) < headerLen+8 || inner[9] != 1 {
+			return 0, false, false
+		}
+		dst, _ := netip.AddrFromSlice(inner[16:20])
+		return matchInner(inner[headerLen:], dst, target, id, icmpv4EchoRequest, typ == icmpv4Unreachable)
+	case ipv6 && (typ == icmpv6TimeExceeded || typ == icmpv6Unreachable):
+		inner := msg[8:]
+		if len(inner) < ipv6HeaderLen+8 || inner[6] != 58 {
+			return 0, false, false
+		}
+		dst, _ := netip.AddrFromSlice(inner[24:40])
+		return matchInner(inner[ipv6HeaderLen:], dst, target, id, icmpv6EchoRequest, typ == icmpv6Unreachable)
+	}
+	return 0, false, false
+}
+
+func matchInner(echo []byte, dst, target netip.Addr, id uint16, requestType byte, unreachable bool) (uint16, bool, bool) {
+	if dst != target || echo[0] != requestType || binary.BigEndian.Uint16(echo[4:6]) != id {
+		return 0, false, false
+	}
+	// An unreachable from the end of the path still ends the trace.
+	return binary.BigEndian.Uint16(echo[6:8]), unreachable, true
+}
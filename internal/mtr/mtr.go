@@ -0,0 +1,231 @@
+// Package mtr traces the path to a target through one interface MTR-style:
+// every cycle sends one ICMP echo per TTL at once, and the per-hop replies
+// are folded into loss and latency statistics.
+package mtr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultCycles  = 5
+	MaxCycles      = 20
+	DefaultMaxHops = 30
+	MaxHops        = 64
+	// probeTimeout is how long one cycle waits for replies.
+	probeTimeout = time.Second
+	// cycleInterval spaces cycles so rate-limited routers answer each one.
+	cycleInterval = 200 * time.Millisecond
+)
+
+// Reply is the answer to one probe. From is invalid when nothing answered.
+type Reply struct {
+	From    netip.Addr
+	RTT     time.Duration
+	Reached bool
+}
+
+// Transport sends one echo per TTL in ttls to target through iface and
+// returns the replies keyed by TTL, waiting at most timeout.
+type Transport interface {
+	Round(ctx context.Context, iface string, target netip.Addr, ttls []int, timeout time.Duration) (map[int]Reply, error)
+}
+
+// Hop is the accumulated result for one TTL.
+type Hop struct {
+	TTL       int      `json:"ttl"`
+	Address   string   `json:"address,omitempty"`
+	Alternate []string `json:"alternate,omitempty"`
+	Sent      int      `json:"sent"`
+	Received  int      `json:"received"`
+	LossPct   float64  `json:"lossPct"`
+	LastMs    float64  `json:"lastMs,omitempty"`
+	BestMs    float64  `json:"bestMs,omitempty"`
+	AvgMs     float64  `json:"avgMs,omitempty"`
+	WorstMs   float64  `json:"worstMs,omitempty"`
+	StdDevMs  float64  `json:"stdDevMs,omitempty"`
+
+	samples []float64
+}
+
+// Report is the result of one trace.
+type Report struct {
+	Target    string    `json:"target"`
+	Address   string    `json:"address"`
+	Interface string    `json:"interface"`
+	Cycles    int       `json:"cycles"`
+	Reached   bool      `json:"reached"`
+	Hops      []Hop     `json:"hops"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Options selects the interface, target and probe counts of one trace.
+type Options struct {
+	Interface string
+	Target    string
+	Address   netip.Addr
+	Cycles    int
+	MaxHops   int
+}
+
+// Tracer runs traces.
+type Tracer struct {
+	transport Transport
+}
+
+// NewTracer returns a tracer using raw ICMP sockets.
+func NewTracer() *Tracer {
+	return NewTracerWithTransport(icmpTransport{})
+}
+
+// NewTracerWithTransport returns a tracer with a custom transport, for tests.
+func NewTracerWithTransport(transport Transport) *Tracer {
+	return &Tracer{transport: transport}
+}
+
+// Trace probes opts.Address through opts.Interface. Once a TTL reaches the
+// target, later cycles stop probing beyond it.
+func (t *Tracer) Trace(ctx context.Context, opts Options) (Report, error) {
+	iface := strings.TrimSpace(opts.Interface)
+	if iface == "" {
+		return Report{}, fmt.Errorf("interface is required")
+	}
+	if !opts.Address.IsValid() {
+		return Report{}, fmt.Errorf("target address is required")
+	}
+	cycles := opts.Cycles
+	if cycles <= 0 {
+		cycles = DefaultCycles
+	}
+	cycles = min(cycles, MaxCycles)
+	maxHops := opts.MaxHops
+	if maxHops <= 0 {
+		maxHops = DefaultMaxHops
+	}
+	maxHops = min(maxHops, MaxHops)
+
+	target := opts.Address.Unmap()
+	hops := make([]Hop, maxHops)
+	for idx := range hops {
+		hops[idx].TTL = idx + 1
+	}
+	limit := maxHops
+	completed := 0
+	for cycle := 0; cycle < cycles; cycle++ {
+		if cycle > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(cycleInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		ttls := make([]int, limit)
+		for idx := range ttls {
+			ttls[idx] = idx + 1
+		}
+		replies, err := t.transport.Round(ctx, iface, target, ttls, probeTimeout)
+		if err != nil {
+			if completed == 0 {
+				return Report{}, err
+			}
+			break
+		}
+		completed++
+		for _, ttl := range ttls {
+			hop := &hops[ttl-1]
+			hop.Sent++
+			reply, ok := replies[ttl]
+			if !ok || !reply.From.IsValid() {
+				continue
+			}
+			hop.record(reply)
+			if reply.Reached && ttl < limit {
+				limit = ttl
+			}
+		}
+	}
+	if completed == 0 {
+		return Report{}, ctx.Err()
+	}
+
+	report := Report{
+		Target:    strings.TrimSpace(opts.Target),
+		Address:   target.String(),
+		Interface: iface,
+		Cycles:    completed,
+		CheckedAt: time.Now().UTC(),
+	}
+	if report.Target == "" {
+		report.Target = report.Address
+	}
+	last := 0
+	for idx := range hops[:limit] {
+		hops[idx].finish()
+		if hops[idx].Received > 0 {
+			last = idx + 1
+		}
+		if hops[idx].Address == target.String() {
+			report.Reached = true
+		}
+	}
+	// Trailing silent hops after the last answer carry no information.
+	if !report.Reached && last < limit {
+		limit = min(limit, last+1)
+	}
+	report.Hops = append([]Hop(nil), hops[:limit]...)
+	return report, nil
+}
+
+func (h *Hop) record(reply Reply) {
+	from := reply.From.Unmap().String()
+	switch {
+	case h.Address == "":
+		h.Address = from
+	case h.Address != from:
+		known := false
+		for _, alt := range h.Alternate {
+			known = known || alt == from
+		}
+		if !known {
+			h.Alternate = append(h.Alternate, from)
+		}
+	}
+	h.Received++
+	h.samples = append(h.samples, float64(reply.RTT.Microseconds())/1000)
+}
+
+func (h *Hop) finish() {
+	if h.Sent > 0 {
+		h.LossPct = round2(100 * float64(h.Sent-h.Received) / float64(h.Sent))
+	}
+	if len(h.samples) == 0 {
+		return
+	}
+	best, worst, sum := h.samples[0], h.samples[0], 0.0
+	for _, sample := range h.samples {
+		best = math.Min(best, sample)
+		worst = math.Max(worst, sample)
+		sum += sample
+	}
+	avg := sum / float64(len(h.samples))
+	variance := 0.0
+	for _, sample := range h.samples {
+		variance += (sample - avg) * (sample - avg)
+	}
+	h.LastMs = round2(h.samples[len(h.samples)-1])
+	h.BestMs = round2(best)
+	h.WorstMs = round2(worst)
+	h.AvgMs = round2(avg)
+	h.StdDevMs = round2(math.Sqrt(variance / float64(len(h.samples))))
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
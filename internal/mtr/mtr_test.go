@@ -0,0 +1,119 @@
+package mtr
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// pathTransport answers like a three-hop path where hop 2 drops every other
+// probe and TTLs at or beyond the target get an echo reply.
+type pathTransport struct {
+	path   []netip.Addr
+	rounds int
+	probed [][]int
+}
+
+func (p *pathTransport) Round(ctx context.Context, iface string, target netip.Addr, ttls []int, timeout time.Duration) (map[int]Reply, error) {
+	p.rounds++
+	p.probed = append(p.probed, append([]int(nil), ttls...))
+	replies := make(map[int]Reply)
+	for _, ttl := range ttls {
+		if ttl == 2 && p.rounds%2 == 0 {
+			continue
+		}
+		if ttl >= len(p.path) {
+			replies[ttl] = Reply{From: target, RTT: 30 * time.Millisecond, Reached: true}
+			continue
+		}
+		replies[ttl] = Reply{From: p.path[ttl-1], RTT: time.Duration(ttl*10) * time.Millisecond}
+	}
+	return replies, nil
+}
+
+func TestTraceAggregatesLossAndStopsAtTarget(t *testing.T) {
+	target := netip.MustParseAddr("1.1.1.1")
+	transport := &pathTransport{path: []netip.Addr{
+		netip.MustParseAddr("10.2.0.1"),
+		netip.MustParseAddr("198.51.100.1"),
+		target,
+	}}
+	report, err := NewTracerWithTransport(transport).Trace(context.Background(), Options{
+		Interface: "wg-sv-nl",
+		Target:    "one.one.one.one",
+		Address:   target,
+		Cycles:    4,
+		MaxHops:   10,
+	})
+	if err != nil {
+		t.Fatalf("trace: %v", err)
+	}
+	if !report.Reached || len(report.Hops) != 3 || report.Cycles != 4 {
+		t.Fatalf("unexpected report: %#v", report)
+	}
+	if len(transport.probed[0]) != 10 || len(transport.probed[1]) != 3 {
+		t.Fatalf("expected later cycles to stop at the target, probed %v", transport.probed)
+	}
+	hop2 := report.Hops[1]
+	if hop2.Address != "198.51.100.1" || hop2.Sent != 4 || hop2.Received != 2 || hop2.LossPct != 50 {
+		t.Fatalf("unexpected hop 2: %#v", hop2)
+	}
+	if report.Hops[0].AvgMs != 10 || report.Hops[2].Address != "1.1.1.1" {
+		t.Fatalf("unexpected hops: %#v", report.Hops)
+	}
+}
+
+type failingTransport struct{}
+
+func (failingTransport) Round(ctx context.Context, iface string, target netip.Addr, ttls []int, timeout time.Duration) (map[int]Reply, error) {
+	return nil, errors.New("operation not permitted")
+}
+
+func TestTraceReportsTransportFailure(t *testing.T) {
+	_, err := NewTracerWithTransport(failingTransport{}).Trace(context.Background(), Options{
+		Interface: "wg0",
+		Address:   netip.MustParseAddr("1.1.1.1"),
+		Cycles:    1,
+	})
+	if err == nil {
+		t.Fatalf("expected transport error")
+	}
+}
+
+func TestParseReplyMatchesTimeExceededAndEchoReply(t *testing.T) {
+	target := netip.MustParseAddr("1.1.1.1")
+	const id = 0x4242
+
+	echo := echoRequest(false, id, 7)
+	inner := make([]byte, 20)
+	inner[0] = 0x45
+	inner[9] = 1
+	copy(inner[16:20], target.AsSlice())
+	exceeded := append([]byte{icmpv4TimeExceeded, 0, 0, 0, 0, 0, 0, 0}, append(inner, echo[:8]...)...)
+	seq, reached, ok := parseReply(exceeded, target, id)
+	if !ok || seq != 7 || reached {
+		t.Fatalf("time exceeded: seq=%d reached=%v ok=%v", seq, reached, ok)
+	}
+	if _, _, ok := parseReply(exceeded, target, id+1); ok {
+		t.Fatalf("expected another session's probe to be ignored")
+	}
+
+	reply := make([]byte, 16)
+	reply[0] = icmpv4EchoReply
+	binary.BigEndian.PutUint16(reply[4:6], id)
+	binary.BigEndian.PutUint16(reply[6:8], 9)
+	seq, reached, ok = parseReply(reply, target, id)
+	if !ok || seq != 9 || !reached {
+		t.Fatalf("echo reply: seq=%d reached=%v ok=%v", seq, reached, ok)
+	}
+}
+
+func TestEchoRequestChecksumVerifies(t *testing.T) {
+	packet := echoRequest(false, 1, 2)
+	if checksum(packet) != 0 {
+		t.Fatalf("expected checksum over a checksummed packet to be zero")
+	}
+}
@@ -0,0 +1,36 @@
+package netbind
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Egress is where outgoing connections leave: the interface their sockets
+// are bound to and the DNS servers that look host names up through it. The
+// zero value follows the routing table and the system resolver.
+type Egress struct {
+	Interface string
+	Resolvers []netip.Addr
+}
+
+// Dialer returns a dialer whose sockets leave through egress.Interface.
+// With resolvers, host names are looked up at the first of them through
+// the same interface, so a probe or download through a tunnel does not
+// depend on what the WAN resolver answers.
+func Dialer(timeout time.Duration, egress Egress) *net.Dialer {
+	dialer := &net.Dialer{Timeout: timeout, Control: Control(strings.TrimSpace(egress.Interface))}
+	if len(egress.Resolvers) > 0 {
+		server := netip.AddrPortFrom(egress.Resolvers[0], 53).String()
+		resolverDialer := &net.Dialer{Timeout: timeout, Control: dialer.Control}
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return resolverDialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return dialer
+}
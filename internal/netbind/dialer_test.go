@@ -0,0 +1,20 @@
+package netbind
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestDialerResolvesThroughEgressOnlyWithResolvers(t *testing.T) {
+	if dialer := Dialer(time.Second, Egress{Interface: "wg-sv-home"}); dialer.Resolver != nil {
+		t.Fatalf("expected the system resolver without egress resolvers")
+	}
+	dialer := Dialer(time.Second, Egress{Interface: "wg-sv-home", Resolvers: []netip.Addr{netip.MustParseAddr("10.64.0.1")}})
+	if dialer.Resolver == nil || !dialer.Resolver.PreferGo || dialer.Resolver.Dial == nil {
+		t.Fatalf("expected lookups to go through the egress resolver, got %+v", dialer.Resolver)
+	}
+	if dialer.Timeout != time.Second {
+		t.Fatalf("timeout = %s", dialer.Timeout)
+	}
+}
@@ -0,0 +1,20 @@
+package netbind
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPClient returns a client whose connections leave through egress, see
+// Dialer. The zero Egress returns a plain client that follows the routing
+// table.
+func HTTPClient(timeout time.Duration, egress Egress) *http.Client {
+	if strings.TrimSpace(egress.Interface) == "" && len(egress.Resolvers) == 0 {
+		return &http.Client{Timeout: timeout}
+	}
+	dialer := Dialer(timeout, egress)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
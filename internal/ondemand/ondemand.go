@@ -0,0 +1,300 @@
+// Package ondemand starts VPNs when traffic for them appears and stops them
+// again once idle. Demand is read from the packet counters of the mangle
+// rules that mark traffic for a VPN's routing groups: those rules keep
+// matching while the tunnel is down, so a growing counter means a client is
+// trying to use the VPN, and a counter that stops growing means nobody is.
+package ondemand
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event kinds.
+const (
+	KindStart       = "start"
+	KindStop        = "stop"
+	KindStartFailed = "start_failed"
+	KindStopFailed  = "stop_failed"
+)
+
+const (
+	checkInterval = 5 * time.Second
+	// retryDelay holds off another start after one failed.
+	retryDelay = time.Minute
+)
+
+// Target is a VPN in on-demand mode.
+type Target struct {
+	VPN     string
+	Mark    uint32
+	IdleFor time.Duration
+}
+
+// TargetSource lists the VPNs in on-demand mode.
+type TargetSource func() ([]Target, error)
+
+// CounterSource returns the packets marked so far for each fwmark.
+type CounterSource func() (map[uint32]uint64, error)
+
+// UnitController reads and changes the state of a VPN's service unit.
+type UnitController interface {
+	Active(vpn string) (bool, error)
+	Start(vpn string) error
+	Stop(vpn string) error
+}
+
+// Event is a start or stop made by the monitor.
+type Event struct {
+	VPN    string    `json:"vpn"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail"`
+	At     time.Time `json:"at"`
+}
+
+// Status is the monitor's view of one on-demand VPN.
+type Status struct {
+	VPN         string    `json:"vpn"`
+	IdleMinutes int       `json:"idleMinutes"`
+	Active      bool      `json:"active"`
+	StartedAt   time.Time `json:"startedAt,omitzero"`
+	LastDemand  time.Time `json:"lastDemand,omitzero"`
+	IdleSince   time.Time `json:"idleSince,omitzero"`
+	RetryAfter  time.Time `json:"retryAfter,omitzero"`
+}
+
+type vpnState struct {
+	idleFor      time.Duration
+	counterKnown bool
+	lastCount    uint64
+	lastCheck    time.Time
+	active       bool
+	startedAt    time.Time
+	lastDemand   time.Time
+	idleSince    time.Time
+	retryAfter   time.Time
+}
+
+// Monitor polls demand and drives the units of on-demand VPNs.
+type Monitor struct {
+	targets  TargetSource
+	counters CounterSource
+	units    UnitController
+	now      func() time.Time
+
+	checkMu sync.Mutex
+
+	mu         sync.Mutex
+	states     map[string]*vpnState
+	handler    func(Event)
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor.
+func NewMonitor(targets TargetSource, counters CounterSource, units UnitController) (*Monitor, error) {
+	switch {
+	case targets == nil:
+		return nil, fmt.Errorf("target source is required")
+	case counters == nil:
+		return nil, fmt.Errorf("counter source is required")
+	case units == nil:
+		return nil, fmt.Errorf("unit controller is required")
+	}
+	return &Monitor{
+		targets:  targets,
+		counters: counters,
+		units:    units,
+		now:      time.Now,
+		states:   make(map[string]*vpnState),
+	}, nil
+}
+
+// SetHandler registers a callback for starts and stops.
+func (m *Monitor) SetHandler(handler func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// Status lists the on-demand VPNs seen by the last check, by name.
+func (m *Monitor) Status() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Status, 0, len(m.states))
+	for name, state := range m.states {
+		out = append(out, Status{
+			VPN:         name,
+			IdleMinutes: int(state.idleFor / time.Minute),
+			Active:      state.active,
+			StartedAt:   state.startedAt,
+			LastDemand:  state.lastDemand,
+			IdleSince:   state.idleSince,
+			RetryAfter:  state.retryAfter,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VPN < out[j].VPN })
+	return out
+}
+
+// Start launches the periodic check loop.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.started = true
+	m.loopCancel = cancel
+	m.mu.Unlock()
+
+	m.loopWG.Add(1)
+	go func() {
+		defer m.loopWG.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = m.Check()
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the periodic loop. Running VPNs are left as they are.
+func (m *Monitor) Stop() error {
+	m.mu.Lock()
+	loopCancel := m.loopCancel
+	m.started = false
+	m.loopCancel = nil
+	m.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	m.loopWG.Wait()
+	return nil
+}
+
+// Check compares the mark counters with the previous check: a down VPN
+// whose counter grew is started, and an up VPN whose counter has not grown
+// for its idle timeout is stopped. Without readable counters nothing is
+// started or stopped.
+func (m *Monitor) Check() error {
+	m.checkMu.Lock()
+	defer m.checkMu.Unlock()
+
+	targets, err := m.targets()
+	if err != nil {
+		return err
+	}
+	counts, countErr := m.counters()
+	now := m.now()
+
+	events := make([]Event, 0)
+	seen := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		seen[target.VPN] = struct{}{}
+		m.mu.Lock()
+		state, ok := m.states[target.VPN]
+		if !ok {
+			state = &vpnState{}
+			m.states[target.VPN] = state
+		}
+		state.idleFor = target.IdleFor
+		m.mu.Unlock()
+
+		active, err := m.units.Active(target.VPN)
+		if err != nil {
+			continue
+		}
+		if countErr != nil {
+			state.counterKnown = false
+			state.idleSince = time.Time{}
+			m.setActive(state, active)
+			continue
+		}
+		count := counts[target.Mark]
+		demand := state.counterKnown && count > state.lastCount
+		if demand {
+			state.lastDemand = now
+		}
+		if event, ok := m.step(target, state, active, demand, now); ok {
+			events = append(events, event)
+		}
+		state.counterKnown, state.lastCount, state.lastCheck = true, count, now
+	}
+	m.mu.Lock()
+	for name := range m.states {
+		if _, ok := seen[name]; !ok {
+			delete(m.states, name)
+		}
+	}
+	handler := m.handler
+	m.mu.Unlock()
+
+	if handler != nil {
+		for _, event := range events {
+			handler(event)
+		}
+	}
+	return countErr
+}
+
+func (m *Monitor) step(target Target, state *vpnState, active, demand bool, now time.Time) (Event, bool) {
+	if !active {
+		m.setActive(state, false)
+		state.idleSince = time.Time{}
+		if !demand || now.Before(state.retryAfter) {
+			return Event{}, false
+		}
+		if err := m.units.Start(target.VPN); err != nil {
+			state.retryAfter = now.Add(retryDelay)
+			return Event{VPN: target.VPN, Kind: KindStartFailed, Detail: fmt.Sprintf("start on demand failed: %v", err), At: now}, true
+		}
+		m.setActive(state, true)
+		state.startedAt = now
+		state.retryAfter = time.Time{}
+		return Event{VPN: target.VPN, Kind: KindStart, Detail: "started on demand: traffic for its groups appeared", At: now}, true
+	}
+
+	m.setActive(state, true)
+	if demand {
+		state.idleSince = time.Time{}
+		return Event{}, false
+	}
+	if state.idleSince.IsZero() {
+		// Nothing was marked since the previous check, so the VPN has been
+		// idle since then.
+		state.idleSince = now
+		if state.counterKnown && !state.lastCheck.IsZero() {
+			state.idleSince = state.lastCheck
+		}
+	}
+	if now.Sub(state.idleSince) < target.IdleFor {
+		return Event{}, false
+	}
+	if err := m.units.Stop(target.VPN); err != nil {
+		// Try again after another full idle period.
+		state.idleSince = now
+		return Event{VPN: target.VPN, Kind: KindStopFailed, Detail: fmt.Sprintf("stop after idle timeout failed: %v", err), At: now}, true
+	}
+	m.setActive(state, false)
+	state.idleSince = time.Time{}
+	return Event{VPN: target.VPN, Kind: KindStop, Detail: fmt.Sprintf("stopped on demand: no traffic for %s", target.IdleFor), At: now}, true
+}
+
+func (m *Monitor) setActive(state *vpnState, active bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state.active = active
+}
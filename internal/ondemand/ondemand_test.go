@@ -0,0 +1,152 @@
+package ondemand
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeUnits struct {
+	active   map[string]bool
+	startErr error
+	starts   int
+	stops    int
+}
+
+func (f *fakeUnits) Active(vpn string) (bool, error) { return f.active[vpn], nil }
+
+func (f *fakeUnits) Start(vpn string) error {
+	f.starts++
+	if f.startErr != nil {
+		return f.startErr
+	}
+	f.active[vpn] = true
+	return nil
+}
+
+func (f *fakeUnits) Stop(vpn string) error {
+	f.stops++
+	f.active[vpn] = false
+	return nil
+}
+
+type harness struct {
+	monitor *Monitor
+	units   *fakeUnits
+	count   uint64
+	countOK bool
+	now     time.Time
+	events  []Event
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	h := &harness{
+		units:   &fakeUnits{active: map[string]bool{}},
+		countOK: true,
+		now:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	monitor, err := NewMonitor(
+		func() ([]Target, error) {
+			return []Target{{VPN: "wg-a", Mark: 0x169, IdleFor: 10 * time.Minute}}, nil
+		},
+		func() (map[uint32]uint64, error) {
+			if !h.countOK {
+				return nil, errors.New("iptables unavailable")
+			}
+			return map[uint32]uint64{0x169: h.count}, nil
+		},
+		h.units,
+	)
+	if err != nil {
+		t.Fatalf("NewMonitor: %v", err)
+	}
+	monitor.now = func() time.Time { return h.now }
+	monitor.SetHandler(func(event Event) { h.events = append(h.events, event) })
+	h.monitor = monitor
+	return h
+}
+
+func (h *harness) check(advance time.Duration) {
+	h.now = h.now.Add(advance)
+	_ = h.monitor.Check()
+}
+
+func TestMonitorStartsOnDemandAndStopsWhenIdle(t *testing.T) {
+	h := newHarness(t)
+	h.count = 500
+	h.check(0)
+	if h.units.starts != 0 {
+		t.Fatalf("first check only sets the baseline, got %d starts", h.units.starts)
+	}
+	h.check(5 * time.Second)
+	if h.units.starts != 0 {
+		t.Fatalf("expected no start without new traffic")
+	}
+
+	h.count += 3
+	h.check(5 * time.Second)
+	if h.units.starts != 1 || !h.units.active["wg-a"] {
+		t.Fatalf("expected VPN started on demand, starts=%d", h.units.starts)
+	}
+	if len(h.events) != 1 || h.events[0].Kind != KindStart {
+		t.Fatalf("expected start event, got %+v", h.events)
+	}
+
+	h.count += 100
+	h.check(5 * time.Minute)
+	h.check(9 * time.Minute)
+	if h.units.stops != 0 {
+		t.Fatalf("expected VPN kept up before idle timeout")
+	}
+	h.check(time.Minute)
+	if h.units.stops != 1 || h.units.active["wg-a"] {
+		t.Fatalf("expected VPN stopped after idle timeout, stops=%d", h.units.stops)
+	}
+	if last := h.events[len(h.events)-1]; last.Kind != KindStop {
+		t.Fatalf("expected stop event, got %+v", last)
+	}
+}
+
+func TestMonitorRetriesFailedStartAfterDelay(t *testing.T) {
+	h := newHarness(t)
+	h.units.startErr = errors.New("unit failed")
+	h.check(0)
+	h.count++
+	h.check(5 * time.Second)
+	if h.units.starts != 1 || h.events[0].Kind != KindStartFailed {
+		t.Fatalf("expected failed start, starts=%d events=%+v", h.units.starts, h.events)
+	}
+	h.count++
+	h.check(5 * time.Second)
+	if h.units.starts != 1 {
+		t.Fatalf("expected start held off during retry delay")
+	}
+	h.units.startErr = nil
+	h.count++
+	h.check(retryDelay)
+	if h.units.starts != 2 || !h.units.active["wg-a"] {
+		t.Fatalf("expected retried start, starts=%d", h.units.starts)
+	}
+}
+
+func TestMonitorNeverStopsWithoutCounters(t *testing.T) {
+	h := newHarness(t)
+	h.units.active["wg-a"] = true
+	h.countOK = false
+	h.check(0)
+	h.check(time.Hour)
+	h.check(time.Hour)
+	if h.units.stops != 0 {
+		t.Fatalf("expected no stop while counters are unreadable")
+	}
+
+	// Readable again: the first reading is a fresh baseline, not demand.
+	h.countOK = true
+	h.count = 42
+	h.check(5 * time.Second)
+	status := h.monitor.Status()
+	if len(status) != 1 || !status[0].LastDemand.IsZero() {
+		t.Fatalf("expected no demand from new baseline, got %+v", status)
+	}
+}
@@ -0,0 +1,309 @@
+// Package pcap runs bounded tcpdump captures on one interface and streams
+// the resulting pcap file. Captures stop at a duration, byte or packet cap,
+// whichever comes first, and are always cut at a packet boundary.
+package pcap
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultDuration   = 30 * time.Second
+	MaxDuration       = 5 * time.Minute
+	DefaultMaxBytes   = 10 << 20
+	MaxBytesLimit     = 100 << 20
+	DefaultMaxPackets = 100000
+	// snapLen keeps whole packets; tunnel MTUs never exceed it.
+	snapLen = 65535
+
+	globalHeaderLen = 24
+	recordHeaderLen = 16
+)
+
+// Options selects the interface, filter and caps of one capture.
+type Options struct {
+	Interface  string
+	Host       string
+	Port       int
+	Protocol   string
+	Duration   time.Duration
+	MaxBytes   int64
+	MaxPackets int
+}
+
+// Result summarises a finished capture.
+type Result struct {
+	Bytes   int64
+	Packets int
+	// StopReason is "duration", "size", "packets" or "client".
+	StopReason string
+}
+
+// Process is a running capture whose pcap output is read from Stdout.
+type Process interface {
+	Stdout() io.Reader
+	Wait() error
+}
+
+// Runner starts tcpdump with args. The process must stop when ctx ends.
+type Runner interface {
+	Start(ctx context.Context, args []string) (Process, error)
+}
+
+// Capturer runs captures through a Runner.
+type Capturer struct {
+	runner Runner
+}
+
+// NewCapturer returns a capturer using the system tcpdump binary.
+func NewCapturer() *Capturer {
+	return NewCapturerWithRunner(execRunner{})
+}
+
+// NewCapturerWithRunner returns a capturer with a custom runner, for tests.
+func NewCapturerWithRunner(runner Runner) *Capturer {
+	return &Capturer{runner: runner}
+}
+
+// Normalize validates options and fills in defaults.
+func (o Options) Normalize() (Options, error) {
+	o.Interface = strings.TrimSpace(o.Interface)
+	if o.Interface == "" || strings.ContainsAny(o.Interface, " /\t") || strings.HasPrefix(o.Interface, "-") {
+		return Options{}, fmt.Errorf("invalid capture interface %q", o.Interface)
+	}
+	o.Host = strings.TrimSpace(o.Host)
+	if o.Host != "" {
+		if prefix, err := netip.ParsePrefix(o.Host); err == nil {
+			o.Host = prefix.Masked().String()
+		} else if addr, err := netip.ParseAddr(o.Host); err == nil {
+			o.Host = addr.String()
+		} else {
+			return Options{}, fmt.Errorf("capture host must be an IP address or CIDR")
+		}
+	}
+	if o.Port < 0 || o.Port > 65535 {
+		return Options{}, fmt.Errorf("capture port must be between 1 and 65535")
+	}
+	o.Protocol = strings.ToLower(strings.TrimSpace(o.Protocol))
+	switch o.Protocol {
+	case "", "tcp", "udp", "icmp", "icmp6":
+	default:
+		return Options{}, fmt.Errorf("capture protocol must be tcp, udp, icmp or icmp6")
+	}
+	if o.Port > 0 && (o.Protocol == "icmp" || o.Protocol == "icmp6") {
+		return Options{}, fmt.Errorf("capture port cannot be combined with %s", o.Protocol)
+	}
+	switch {
+	case o.Duration <= 0:
+		o.Duration = DefaultDuration
+	case o.Duration > MaxDuration:
+		return Options{}, fmt.Errorf("capture duration must be at most %s", MaxDuration)
+	}
+	switch {
+	case o.MaxBytes <= 0:
+		o.MaxBytes = DefaultMaxBytes
+	case o.MaxBytes > MaxBytesLimit:
+		return Options{}, fmt.Errorf("capture size must be at most %d bytes", MaxBytesLimit)
+	case o.MaxBytes < globalHeaderLen+recordHeaderLen:
+		return Options{}, fmt.Errorf("capture size must be at least %d bytes", globalHeaderLen+recordHeaderLen)
+	}
+	if o.MaxPackets <= 0 || o.MaxPackets > DefaultMaxPackets {
+		o.MaxPackets = DefaultMaxPackets
+	}
+	return o, nil
+}
+
+// Filter returns the pcap filter expression for the options.
+func (o Options) Filter() string {
+	terms := make([]string, 0, 3)
+	if o.Protocol != "" {
+		terms = append(terms, o.Protocol)
+	}
+	if o.Host != "" {
+		if strings.Contains(o.Host, "/") {
+			terms = append(terms, "net "+o.Host)
+		} else {
+			terms = append(terms, "host "+o.Host)
+		}
+	}
+	if o.Port > 0 {
+		terms = append(terms, "port "+strconv.Itoa(o.Port))
+	}
+	return strings.Join(terms, " and ")
+}
+
+// Args returns the tcpdump arguments for normalized options: packet-buffered
+// pcap on stdout, no name resolution, no promiscuous mode.
+func (o Options) Args() []string {
+	args := []string{
+		"-i", o.Interface,
+		"-n", "-p", "-U",
+		"-s", strconv.Itoa(snapLen),
+		"-c", strconv.Itoa(o.MaxPackets),
+		"-w", "-",
+	}
+	if filter := o.Filter(); filter != "" {
+		args = append(args, filter)
+	}
+	return args
+}
+
+// Capture runs tcpdump and copies whole pcap records to dst until a cap is
+// reached or ctx ends. Nothing is written to dst if tcpdump fails before
+// producing its file header, so callers can still report the error.
+func (c *Capturer) Capture(ctx context.Context, opts Options, dst io.Writer) (Result, error) {
+	opts, err := opts.Normalize()
+	if err != nil {
+		return Result{}, err
+	}
+	runCtx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+	proc, err := c.runner.Start(runCtx, opts.Args())
+	if err != nil {
+		return Result{}, fmt.Errorf("start tcpdump: %w", err)
+	}
+	result, copyErr := copyRecords(dst, proc.Stdout(), opts.MaxBytes)
+	if result.StopReason == "size" {
+		cancel()
+	}
+	waitErr := proc.Wait()
+
+	switch {
+	case result.StopReason == "size":
+	case ctx.Err() != nil:
+		result.StopReason = "client"
+	case runCtx.Err() != nil:
+		result.StopReason = "duration"
+	case waitErr == nil:
+		result.StopReason = "packets"
+	}
+	if copyErr != nil && result.StopReason != "client" {
+		return result, copyErr
+	}
+	if waitErr != nil && result.StopReason == "" {
+		return result, waitErr
+	}
+	if result.Bytes == 0 && result.StopReason != "client" {
+		return result, errors.New("tcpdump produced no output")
+	}
+	return result, nil
+}
+
+// copyRecords copies the pcap file header and then whole records while the
+// total stays within maxBytes. The header is buffered until complete so a
+// failing tcpdump leaves dst untouched.
+func copyRecords(dst io.Writer, src io.Reader, maxBytes int64) (Result, error) {
+	var result Result
+	header := make([]byte, globalHeaderLen)
+	if _, err := io.ReadFull(src, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return result, nil
+		}
+		return result, err
+	}
+	var order binary.ByteOrder
+	switch {
+	case bytes.Equal(header[:4], []byte{0xd4, 0xc3, 0xb2, 0xa1}), bytes.Equal(header[:4], []byte{0x4d, 0x3c, 0xb2, 0xa1}):
+		order = binary.LittleEndian
+	case bytes.Equal(header[:4], []byte{0xa1, 0xb2, 0xc3, 0xd4}), bytes.Equal(header[:4], []byte{0xa1, 0xb2, 0x3c, 0x4d}):
+		order = binary.BigEndian
+	default:
+		return result, fmt.Errorf("unexpected pcap magic %x", header[:4])
+	}
+	if _, err := dst.Write(header); err != nil {
+		return result, err
+	}
+	result.Bytes = globalHeaderLen
+
+	record := make([]byte, 0, recordHeaderLen+snapLen)
+	for {
+		record = record[:recordHeaderLen]
+		if _, err := io.ReadFull(src, record); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return result, nil
+			}
+			return result, err
+		}
+		length := order.Uint32(record[8:12])
+		if length > snapLen {
+			return result, fmt.Errorf("pcap record of %d bytes exceeds snap length", length)
+		}
+		if result.Bytes+recordHeaderLen+int64(length) > maxBytes {
+			result.StopReason = "size"
+			return result, nil
+		}
+		record = record[:recordHeaderLen+int(length)]
+		if _, err := io.ReadFull(src, record[recordHeaderLen:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return result, nil
+			}
+			return result, err
+		}
+		if _, err := dst.Write(record); err != nil {
+			return result, err
+		}
+		result.Bytes += int64(len(record))
+		result.Packets++
+	}
+}
+
+// execRunner runs the system tcpdump. It is stopped with SIGTERM so it can
+// flush its last packet before exiting.
+type execRunner struct{}
+
+type execProcess struct {
+	cmd    *exec.Cmd
+	stdout io.Reader
+	stderr *bytes.Buffer
+	ctx    context.Context
+}
+
+func (execRunner) Start(ctx context.Context, args []string) (Process, error) {
+	cmd := exec.CommandContext(ctx, "tcpdump", args...)
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = 3 * time.Second
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &execProcess{cmd: cmd, stdout: stdout, stderr: &stderr, ctx: ctx}, nil
+}
+
+func (p *execProcess) Stdout() io.Reader {
+	return p.stdout
+}
+
+// Wait drains unread output so tcpdump is never blocked on a full pipe, then
+// reports its exit. Exits caused by ctx are not errors.
+func (p *execProcess) Wait() error {
+	_, _ = io.Copy(io.Discard, p.stdout)
+	err := p.cmd.Wait()
+	if err == nil || p.ctx.Err() != nil {
+		return nil
+	}
+	if message := lastLine(p.stderr.String()); message != "" {
+		return fmt.Errorf("tcpdump: %s", message)
+	}
+	return fmt.Errorf("tcpdump: %w", err)
+}
+
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
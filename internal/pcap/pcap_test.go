@@ -0,0 +1,127 @@
+package pcap
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeProcess struct {
+	stdout io.Reader
+	err    error
+}
+
+func (p *fakeProcess) Stdout() io.Reader { return p.stdout }
+func (p *fakeProcess) Wait() error       { return p.err }
+
+type fakeRunner struct {
+	args   []string
+	output []byte
+	err    error
+}
+
+func (r *fakeRunner) Start(ctx context.Context, args []string) (Process, error) {
+	r.args = args
+	return &fakeProcess{stdout: bytes.NewReader(r.output), err: r.err}, nil
+}
+
+func pcapFile(packetSizes ...int) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, globalHeaderLen)
+	binary.LittleEndian.PutUint32(header[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:6], 2)
+	binary.LittleEndian.PutUint16(header[6:8], 4)
+	binary.LittleEndian.PutUint32(header[16:20], snapLen)
+	binary.LittleEndian.PutUint32(header[20:24], 12)
+	buf.Write(header)
+	for _, size := range packetSizes {
+		record := make([]byte, recordHeaderLen)
+		binary.LittleEndian.PutUint32(record[8:12], uint32(size))
+		binary.LittleEndian.PutUint32(record[12:16], uint32(size))
+		buf.Write(record)
+		buf.Write(bytes.Repeat([]byte{0xab}, size))
+	}
+	return buf.Bytes()
+}
+
+func TestOptionsArgsBuildFilter(t *testing.T) {
+	opts, err := Options{Interface: "wg-sv-nl", Host: "203.0.113.9", Port: 443, Protocol: "TCP"}.Normalize()
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	want := []string{"-i", "wg-sv-nl", "-n", "-p", "-U", "-s", "65535", "-c", "100000", "-w", "-", "tcp and host 203.0.113.9 and port 443"}
+	if got := opts.Args(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected args:\n got %#v\nwant %#v", got, want)
+	}
+	if opts.Duration != DefaultDuration || opts.MaxBytes != DefaultMaxBytes {
+		t.Fatalf("expected defaults, got %#v", opts)
+	}
+
+	network, err := Options{Interface: "br0", Host: "192.168.1.77/24"}.Normalize()
+	if err != nil {
+		t.Fatalf("normalize cidr: %v", err)
+	}
+	if filter := network.Filter(); filter != "net 192.168.1.0/24" {
+		t.Fatalf("unexpected cidr filter %q", filter)
+	}
+}
+
+func TestOptionsNormalizeRejectsUnsafeInput(t *testing.T) {
+	cases := []Options{
+		{Interface: ""},
+		{Interface: "-w/tmp/x"},
+		{Interface: "wg0", Host: "example.com or port 22"},
+		{Interface: "wg0", Port: 70000},
+		{Interface: "wg0", Protocol: "arp"},
+		{Interface: "wg0", Protocol: "icmp", Port: 53},
+		{Interface: "wg0", Duration: time.Hour},
+		{Interface: "wg0", MaxBytes: MaxBytesLimit + 1},
+	}
+	for _, opts := range cases {
+		if _, err := opts.Normalize(); err == nil {
+			t.Fatalf("expected %#v to be rejected", opts)
+		}
+	}
+}
+
+func TestCaptureStopsAtPacketBoundaryWhenSizeCapReached(t *testing.T) {
+	runner := &fakeRunner{output: pcapFile(100, 100, 100)}
+	capturer := NewCapturerWithRunner(runner)
+	var out bytes.Buffer
+	limit := int64(globalHeaderLen + 2*(recordHeaderLen+100) + 50)
+	result, err := capturer.Capture(context.Background(), Options{Interface: "wg0", MaxBytes: limit}, &out)
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if result.Packets != 2 || result.StopReason != "size" {
+		t.Fatalf("unexpected result %#v", result)
+	}
+	if int64(out.Len()) != result.Bytes || !bytes.Equal(out.Bytes(), pcapFile(100, 100)) {
+		t.Fatalf("expected exactly two whole records, got %d bytes", out.Len())
+	}
+}
+
+func TestCaptureReportsPacketCapAndFailures(t *testing.T) {
+	runner := &fakeRunner{output: pcapFile(60)}
+	var out bytes.Buffer
+	result, err := NewCapturerWithRunner(runner).Capture(context.Background(), Options{Interface: "wg0"}, &out)
+	if err != nil || result.StopReason != "packets" || result.Packets != 1 {
+		t.Fatalf("unexpected result %#v err=%v", result, err)
+	}
+
+	failing := &fakeRunner{err: errors.New("tcpdump: wg9: No such device exists")}
+	out.Reset()
+	_, err = NewCapturerWithRunner(failing).Capture(context.Background(), Options{Interface: "wg9"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "No such device") {
+		t.Fatalf("expected tcpdump error, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected nothing written on failure, got %d bytes", out.Len())
+	}
+}
@@ -0,0 +1,366 @@
+// Package peersync keeps a standby gateway's VPN profiles and routing groups
+// in step with a leader running the same app. The follower pulls the
+// leader's snapshot over the regular API, authenticated with the leader's
+// API token, and replicates it locally; nothing is pushed.
+package peersync
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/backup"
+	"split-vpn-webui/internal/settings"
+)
+
+// Roles.
+const (
+	RoleLeader   = "leader"
+	RoleFollower = "follower"
+)
+
+// Event kinds.
+const (
+	KindSynced    = "sync_applied"
+	KindFailed    = "sync_failed"
+	KindRecovered = "sync_recovered"
+)
+
+const (
+	// SnapshotPath is the leader endpoint followers pull from.
+	SnapshotPath = "/api/sync/snapshot"
+
+	DefaultInterval    = time.Minute
+	MinIntervalSeconds = 10
+	MaxIntervalSeconds = 24 * 60 * 60
+
+	checkInterval   = 5 * time.Second
+	fetchTimeout    = time.Minute
+	maxSnapshotSize = 128 << 20
+)
+
+// ErrNotFollower is returned when a sync is requested on a node that does
+// not follow a leader.
+var ErrNotFollower = errors.New("this node is not a sync follower")
+
+// ParseRole normalizes a sync role; empty turns sync off.
+func ParseRole(raw string) (string, error) {
+	role := strings.ToLower(strings.TrimSpace(raw))
+	switch role {
+	case "", RoleLeader, RoleFollower:
+		return role, nil
+	}
+	return "", fmt.Errorf("syncRole must be leader, follower or empty")
+}
+
+// NormalizeFingerprint accepts a SHA-256 certificate fingerprint in hex,
+// with or without colons, and returns it lower-case without separators.
+func NormalizeFingerprint(raw string) (string, error) {
+	fingerprint := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(raw), ":", ""))
+	if fingerprint == "" {
+		return "", nil
+	}
+	if decoded, err := hex.DecodeString(fingerprint); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("syncLeaderFingerprint must be a SHA-256 fingerprint in hex")
+	}
+	return fingerprint, nil
+}
+
+// ValidateSettings checks the sync settings. A follower needs the leader's
+// http(s) URL and API token.
+func ValidateSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	role, err := ParseRole(current.SyncRole)
+	errs.Add("syncRole", err)
+	if current.SyncIntervalSeconds != 0 && (current.SyncIntervalSeconds < MinIntervalSeconds || current.SyncIntervalSeconds > MaxIntervalSeconds) {
+		errs.Addf("syncIntervalSeconds", "syncIntervalSeconds must be between %d and %d", MinIntervalSeconds, MaxIntervalSeconds)
+	}
+	_, err = NormalizeFingerprint(current.SyncLeaderFingerprint)
+	errs.Add("syncLeaderFingerprint", err)
+	leaderURL := strings.TrimSpace(current.SyncLeaderURL)
+	if leaderURL != "" {
+		parsed, err := url.Parse(leaderURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs.Addf("syncLeaderUrl", "syncLeaderUrl must be an http or https URL")
+		}
+	}
+	if role == RoleFollower {
+		if leaderURL == "" {
+			errs.Addf("syncLeaderUrl", "syncLeaderUrl is required for a follower")
+		}
+		if strings.TrimSpace(current.SyncToken) == "" {
+			errs.Addf("syncToken", "syncToken is required for a follower")
+		}
+	}
+	return errs.Err()
+}
+
+// Interval returns the configured pull interval.
+func Interval(current settings.Settings) time.Duration {
+	if current.SyncIntervalSeconds <= 0 {
+		return DefaultInterval
+	}
+	return time.Duration(current.SyncIntervalSeconds) * time.Second
+}
+
+// SettingsSource provides the current sync settings.
+type SettingsSource interface {
+	Get() (settings.Settings, error)
+}
+
+// ApplyFunc replicates a leader snapshot and reports whether anything
+// changed.
+type ApplyFunc func(ctx context.Context, snapshot backup.Snapshot) (bool, error)
+
+// Event is an applied change or a change between failing and working syncs.
+type Event struct {
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail"`
+	At     time.Time `json:"at"`
+}
+
+// Status describes this node's sync role and the last pull.
+type Status struct {
+	Role             string    `json:"role"`
+	LeaderURL        string    `json:"leaderUrl,omitempty"`
+	Interval         int       `json:"intervalSeconds"`
+	LastAttempt      time.Time `json:"lastAttempt,omitzero"`
+	LastSuccess      time.Time `json:"lastSuccess,omitzero"`
+	LastChange       time.Time `json:"lastChange,omitzero"`
+	LeaderExportedAt time.Time `json:"leaderExportedAt,omitzero"`
+	LastError        string    `json:"lastError,omitempty"`
+}
+
+// Syncer pulls and applies the leader's snapshot on a follower.
+type Syncer struct {
+	settings SettingsSource
+	apply    ApplyFunc
+	fetch    func(ctx context.Context, current settings.Settings) (backup.Snapshot, error)
+	now      func() time.Time
+
+	runMu sync.Mutex
+
+	mu         sync.Mutex
+	status     Status
+	handler    func(Event)
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewSyncer creates a syncer that applies pulled snapshots with apply.
+func NewSyncer(source SettingsSource, apply ApplyFunc) (*Syncer, error) {
+	if source == nil {
+		return nil, fmt.Errorf("settings source is required")
+	}
+	if apply == nil {
+		return nil, fmt.Errorf("apply function is required")
+	}
+	return &Syncer{
+		settings: source,
+		apply:    apply,
+		fetch:    Fetch,
+		now:      time.Now,
+	}, nil
+}
+
+// SetHandler registers a callback for sync events.
+func (s *Syncer) SetHandler(handler func(Event)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+// Status reports the configured role with the result of the last pull.
+func (s *Syncer) Status() Status {
+	s.mu.Lock()
+	status := s.status
+	s.mu.Unlock()
+	if current, err := s.settings.Get(); err == nil {
+		status.Role, _ = ParseRole(current.SyncRole)
+		status.LeaderURL = ""
+		if status.Role == RoleFollower {
+			status.LeaderURL = strings.TrimSpace(current.SyncLeaderURL)
+		}
+		status.Interval = int(Interval(current) / time.Second)
+	}
+	return status
+}
+
+// Start launches the periodic pull loop. It idles unless the node is a
+// follower.
+func (s *Syncer) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.started = true
+	s.loopCancel = cancel
+	s.mu.Unlock()
+
+	s.loopWG.Add(1)
+	go func() {
+		defer s.loopWG.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.tick(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the periodic loop.
+func (s *Syncer) Stop() error {
+	s.mu.Lock()
+	loopCancel := s.loopCancel
+	s.started = false
+	s.loopCancel = nil
+	s.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	s.loopWG.Wait()
+	return nil
+}
+
+func (s *Syncer) tick(ctx context.Context) {
+	current, err := s.settings.Get()
+	if err != nil || current.SyncRole != RoleFollower {
+		return
+	}
+	s.mu.Lock()
+	lastAttempt := s.status.LastAttempt
+	s.mu.Unlock()
+	if !lastAttempt.IsZero() && s.now().Sub(lastAttempt) < Interval(current) {
+		return
+	}
+	_, _ = s.SyncNow(ctx)
+}
+
+// SyncNow pulls the leader's snapshot and applies it.
+func (s *Syncer) SyncNow(ctx context.Context) (Status, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	current, err := s.settings.Get()
+	if err != nil {
+		return s.Status(), err
+	}
+	if current.SyncRole != RoleFollower {
+		return s.Status(), ErrNotFollower
+	}
+
+	started := s.now()
+	snapshot, err := s.fetch(ctx, current)
+	changed := false
+	if err == nil {
+		changed, err = s.apply(ctx, snapshot)
+	}
+
+	s.mu.Lock()
+	previousErr := s.status.LastError
+	s.status.LastAttempt = started
+	var event *Event
+	if err != nil {
+		s.status.LastError = err.Error()
+		if previousErr == "" {
+			event = &Event{Kind: KindFailed, Detail: fmt.Sprintf("config sync from %s failed: %v", current.SyncLeaderURL, err), At: started}
+		}
+	} else {
+		s.status.LastError = ""
+		s.status.LastSuccess = started
+		s.status.LeaderExportedAt = time.Unix(snapshot.ExportedAt, 0).UTC()
+		switch {
+		case changed:
+			s.status.LastChange = started
+			event = &Event{Kind: KindSynced, Detail: fmt.Sprintf("applied VPN profiles and routing groups from %s", current.SyncLeaderURL), At: started}
+		case previousErr != "":
+			event = &Event{Kind: KindRecovered, Detail: fmt.Sprintf("config sync from %s works again", current.SyncLeaderURL), At: started}
+		}
+	}
+	handler := s.handler
+	s.mu.Unlock()
+
+	if event != nil && handler != nil {
+		handler(*event)
+	}
+	return s.Status(), err
+}
+
+// Fetch downloads the leader's snapshot.
+func Fetch(ctx context.Context, current settings.Settings) (backup.Snapshot, error) {
+	fingerprint, err := NormalizeFingerprint(current.SyncLeaderFingerprint)
+	if err != nil {
+		return backup.Snapshot{}, err
+	}
+	endpoint := strings.TrimRight(strings.TrimSpace(current.SyncLeaderURL), "/") + SnapshotPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return backup.Snapshot{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(current.SyncToken))
+	resp, err := newClient(fingerprint).Do(req)
+	if err != nil {
+		return backup.Snapshot{}, err
+	}
+	defer resp.Body.Close()
+	body := io.LimitReader(resp.Body, maxSnapshotSize)
+	if resp.StatusCode != http.StatusOK {
+		var payload struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(body).Decode(&payload)
+		if payload.Error == "" {
+			payload.Error = resp.Status
+		}
+		return backup.Snapshot{}, fmt.Errorf("leader returned %d: %s", resp.StatusCode, payload.Error)
+	}
+	var snapshot backup.Snapshot
+	if err := json.NewDecoder(body).Decode(&snapshot); err != nil {
+		return backup.Snapshot{}, fmt.Errorf("decode leader snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// newClient trusts the system roots, or only the pinned certificate when a
+// fingerprint is set; the app's own certificate is self-signed.
+func newClient(fingerprint string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if fingerprint != "" {
+		transport.TLSClientConfig = &tls.Config{
+			// Chain verification is replaced by the pin below.
+			InsecureSkipVerify: true,
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 {
+					return fmt.Errorf("leader sent no certificate")
+				}
+				sum := sha256.Sum256(rawCerts[0])
+				if got := hex.EncodeToString(sum[:]); got != fingerprint {
+					return fmt.Errorf("leader certificate fingerprint %s does not match the pinned one", got)
+				}
+				return nil
+			},
+		}
+	}
+	return &http.Client{Timeout: fetchTimeout, Transport: transport}
+}
@@ -0,0 +1,149 @@
+package peersync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/backup"
+	"split-vpn-webui/internal/settings"
+)
+
+type staticSettings struct {
+	value settings.Settings
+}
+
+func (s *staticSettings) Get() (settings.Settings, error) { return s.value, nil }
+
+func leaderServer(t *testing.T, token string, tls bool) *httptest.Server {
+	t.Helper()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != SnapshotPath {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(backup.Snapshot{
+			Format:     backup.FormatName,
+			Version:    backup.CurrentVersion,
+			ExportedAt: 1700000000,
+			VPNs:       []backup.VPNRecord{{Name: "wg-a", Type: "wireguard", Config: "[Interface]\n"}},
+		})
+	})
+	if tls {
+		return httptest.NewTLSServer(handler)
+	}
+	return httptest.NewServer(handler)
+}
+
+func TestSyncerAppliesLeaderSnapshot(t *testing.T) {
+	leader := leaderServer(t, "leader-token", false)
+	defer leader.Close()
+
+	source := &staticSettings{value: settings.Settings{SyncRole: RoleFollower, SyncLeaderURL: leader.URL + "/", SyncToken: "wrong"}}
+	applied := make([]backup.Snapshot, 0)
+	syncer, err := NewSyncer(source, func(_ context.Context, snapshot backup.Snapshot) (bool, error) {
+		applied = append(applied, snapshot)
+		return len(applied) == 1, nil
+	})
+	if err != nil {
+		t.Fatalf("NewSyncer: %v", err)
+	}
+	events := make([]Event, 0)
+	syncer.SetHandler(func(event Event) { events = append(events, event) })
+
+	status, err := syncer.SyncNow(context.Background())
+	if err == nil || !strings.Contains(status.LastError, "unauthorized") {
+		t.Fatalf("expected unauthorized error, got %v (%+v)", err, status)
+	}
+	if _, err := syncer.SyncNow(context.Background()); err == nil {
+		t.Fatalf("expected second failure")
+	}
+	if len(events) != 1 || events[0].Kind != KindFailed {
+		t.Fatalf("expected one failure event, got %+v", events)
+	}
+
+	source.value.SyncToken = "leader-token"
+	status, err = syncer.SyncNow(context.Background())
+	if err != nil {
+		t.Fatalf("SyncNow: %v", err)
+	}
+	if len(applied) != 1 || applied[0].VPNs[0].Name != "wg-a" {
+		t.Fatalf("expected leader snapshot applied, got %+v", applied)
+	}
+	if status.LastError != "" || status.LastChange.IsZero() || status.LeaderExportedAt.Unix() != 1700000000 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if last := events[len(events)-1]; last.Kind != KindSynced {
+		t.Fatalf("expected applied event, got %+v", last)
+	}
+
+	count := len(events)
+	if _, err := syncer.SyncNow(context.Background()); err != nil {
+		t.Fatalf("SyncNow: %v", err)
+	}
+	if len(events) != count {
+		t.Fatalf("expected no event for an unchanged snapshot, got %+v", events[count:])
+	}
+
+	source.value.SyncRole = RoleLeader
+	if _, err := syncer.SyncNow(context.Background()); err != ErrNotFollower {
+		t.Fatalf("expected ErrNotFollower, got %v", err)
+	}
+}
+
+func TestFetchPinsLeaderCertificate(t *testing.T) {
+	leader := leaderServer(t, "token", true)
+	defer leader.Close()
+
+	current := settings.Settings{SyncLeaderURL: leader.URL, SyncToken: "token"}
+	if _, err := Fetch(context.Background(), current); err == nil {
+		t.Fatalf("expected self-signed certificate to be rejected without a pin")
+	}
+
+	sum := sha256.Sum256(leader.Certificate().Raw)
+	current.SyncLeaderFingerprint = strings.ToUpper(hex.EncodeToString(sum[:]))
+	snapshot, err := Fetch(context.Background(), current)
+	if err != nil {
+		t.Fatalf("Fetch with pin: %v", err)
+	}
+	if len(snapshot.VPNs) != 1 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+
+	current.SyncLeaderFingerprint = strings.Repeat("ab", sha256.Size)
+	if _, err := Fetch(context.Background(), current); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("expected fingerprint mismatch, got %v", err)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	cases := []struct {
+		value settings.Settings
+		ok    bool
+	}{
+		{settings.Settings{}, true},
+		{settings.Settings{SyncRole: RoleLeader}, true},
+		{settings.Settings{SyncRole: "primary"}, false},
+		{settings.Settings{SyncRole: RoleFollower, SyncToken: "t"}, false},
+		{settings.Settings{SyncRole: RoleFollower, SyncLeaderURL: "https://udm-a:8091"}, false},
+		{settings.Settings{SyncRole: RoleFollower, SyncLeaderURL: "ftp://udm-a", SyncToken: "t"}, false},
+		{settings.Settings{SyncRole: RoleFollower, SyncLeaderURL: "https://udm-a:8091", SyncToken: "t"}, true},
+		{settings.Settings{SyncIntervalSeconds: 5}, false},
+		{settings.Settings{SyncLeaderFingerprint: "abc"}, false},
+	}
+	for _, tc := range cases {
+		if err := ValidateSettings(tc.value); (err == nil) != tc.ok {
+			t.Fatalf("ValidateSettings(%+v) = %v, want ok=%v", tc.value, err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package pmtu measures the path MTU through a tunnel interface by sending
+// don't-fragment echo requests of decreasing size and derives the TCP MSS
+// clamp that fits it.
+package pmtu
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTargetV4 and DefaultTargetV6 answer ICMP echo from anywhere.
+	DefaultTargetV4 = "1.1.1.1"
+	DefaultTargetV6 = "2606:4700:4700::1111"
+
+	minMTUV4     = 576
+	minMTUV6     = 1280
+	defaultMTU   = 1500
+	headerV4     = 28 // IPv4 + ICMP headers
+	headerV6     = 48 // IPv6 + ICMPv6 headers
+	tcpHeaderV4  = 40 // IPv4 + TCP headers
+	tcpHeaderV6  = 60 // IPv6 + TCP headers
+	pingAttempts = 2
+)
+
+// Pinger sends one don't-fragment echo request of packetSize bytes and
+// returns nil only when a reply arrives.
+type Pinger interface {
+	Ping(ctx context.Context, iface, target string, ipv6 bool, packetSize int) error
+}
+
+// Result is the measured path MTU for one address family.
+type Result struct {
+	Family         string `json:"family"`
+	Target         string `json:"target"`
+	MTU            int    `json:"mtu,omitempty"`
+	RecommendedMSS int    `json:"recommendedMss,omitempty"`
+	Probes         int    `json:"probes"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Report holds the results for both families.
+type Report struct {
+	Interface    string    `json:"interface"`
+	InterfaceMTU int       `json:"interfaceMtu"`
+	IPv4         Result    `json:"ipv4"`
+	IPv6         Result    `json:"ipv6"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// Prober runs path MTU probes.
+type Prober struct {
+	pinger Pinger
+	sysNet string
+}
+
+// NewProber returns a prober using the system ping binary.
+func NewProber() *Prober {
+	return NewProberWithPinger(execPinger{})
+}
+
+// NewProberWithPinger returns a prober with a custom pinger, for tests.
+func NewProberWithPinger(pinger Pinger) *Prober {
+	return &Prober{pinger: pinger, sysNet: "/sys/class/net"}
+}
+
+// Probe measures both families through iface. Empty targets use the
+// defaults. The families are probed concurrently.
+func (p *Prober) Probe(ctx context.Context, iface, targetV4, targetV6 string) (Report, error) {
+	iface = strings.TrimSpace(iface)
+	if iface == "" {
+		return Report{}, fmt.Errorf("interface is required")
+	}
+	if strings.TrimSpace(targetV4) == "" {
+		targetV4 = DefaultTargetV4
+	}
+	if strings.TrimSpace(targetV6) == "" {
+		targetV6 = DefaultTargetV6
+	}
+	report := Report{Interface: iface, InterfaceMTU: p.interfaceMTU(iface)}
+
+	v6Done := make(chan Result, 1)
+	go func() {
+		v6Done <- p.probeFamily(ctx, iface, targetV6, true, report.InterfaceMTU)
+	}()
+	report.IPv4 = p.probeFamily(ctx, iface, targetV4, false, report.InterfaceMTU)
+	report.IPv6 = <-v6Done
+	report.CheckedAt = time.Now().UTC()
+	return report, nil
+}
+
+// probeFamily binary-searches the largest packet that gets a reply, between
+// the family minimum and the interface MTU.
+func (p *Prober) probeFamily(ctx context.Context, iface, target string, ipv6 bool, upper int) Result {
+	result := Result{Family: "ipv4", Target: target}
+	low, header, tcpHeader := minMTUV4, headerV4, tcpHeaderV4
+	if ipv6 {
+		result.Family = "ipv6"
+		low, header, tcpHeader = minMTUV6, headerV6, tcpHeaderV6
+	}
+	if upper < low {
+		result.Error = fmt.Sprintf("interface MTU %d is below the %s minimum %d", upper, result.Family, low)
+		return result
+	}
+
+	ping := func(size int) error {
+		var err error
+		for attempt := 0; attempt < pingAttempts; attempt++ {
+			result.Probes++
+			if err = p.pinger.Ping(ctx, iface, target, ipv6, size-header); err == nil || ctx.Err() != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	if err := ping(upper); err == nil {
+		result.MTU = upper
+	} else if err := ping(low); err != nil {
+		result.Error = fmt.Sprintf("no reply at the minimum MTU %d: %v", low, err)
+		return result
+	} else {
+		// Invariant: low gets a reply, high does not.
+		high := upper
+		for high-low > 1 {
+			if ctx.Err() != nil {
+				result.Error = ctx.Err().Error()
+				return result
+			}
+			mid := (low + high) / 2
+			if ping(mid) == nil {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+		result.MTU = low
+	}
+	result.RecommendedMSS = result.MTU - tcpHeader
+	return result
+}
+
+func (p *Prober) interfaceMTU(iface string) int {
+	raw, err := os.ReadFile(filepath.Join(p.sysNet, filepath.Base(iface), "mtu"))
+	if err != nil {
+		return defaultMTU
+	}
+	mtu, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil || mtu <= 0 {
+		return defaultMTU
+	}
+	return mtu
+}
+
+// execPinger shells out to iputils ping, which supports -M do to forbid
+// fragmentation and -s to set the ICMP payload size.
+type execPinger struct{}
+
+func (execPinger) Ping(ctx context.Context, iface, target string, ipv6 bool, payload int) error {
+	family := "-4"
+	if ipv6 {
+		family = "-6"
+	}
+	args := []string{family, "-M", "do", "-c", "1", "-W", "1", "-s", strconv.Itoa(payload), "-I", iface, target}
+	cmd := exec.CommandContext(ctx, "ping", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%w: %s", err, message)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package pmtu
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// pathPinger replies to packets up to the path MTU of each family.
+type pathPinger struct {
+	mu     sync.Mutex
+	mtuV4  int
+	mtuV6  int
+	ifaces []string
+}
+
+func (p *pathPinger) Ping(ctx context.Context, iface, target string, ipv6 bool, payload int) error {
+	p.mu.Lock()
+	p.ifaces = append(p.ifaces, iface)
+	p.mu.Unlock()
+	mtu, header := p.mtuV4, headerV4
+	if ipv6 {
+		mtu, header = p.mtuV6, headerV6
+	}
+	if payload+header > mtu {
+		return errors.New("no reply")
+	}
+	return nil
+}
+
+func TestProbeFindsPathMTUAndRecommendsMSS(t *testing.T) {
+	pinger := &pathPinger{mtuV4: 1412, mtuV6: 1392}
+	prober := NewProberWithPinger(pinger)
+	prober.sysNet = writeInterfaceMTU(t, "wg-sgp", "1420\n")
+
+	report, err := prober.Probe(context.Background(), "wg-sgp", "", "")
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if report.InterfaceMTU != 1420 {
+		t.Fatalf("expected interface MTU 1420, got %d", report.InterfaceMTU)
+	}
+	if report.IPv4.MTU != 1412 || report.IPv4.RecommendedMSS != 1372 || report.IPv4.Target != DefaultTargetV4 {
+		t.Fatalf("unexpected ipv4 result: %+v", report.IPv4)
+	}
+	if report.IPv6.MTU != 1392 || report.IPv6.RecommendedMSS != 1332 {
+		t.Fatalf("unexpected ipv6 result: %+v", report.IPv6)
+	}
+	for _, iface := range pinger.ifaces {
+		if iface != "wg-sgp" {
+			t.Fatalf("expected all probes bound to the tunnel, got %q", iface)
+		}
+	}
+}
+
+func TestProbeUsesInterfaceMTUWhenPathIsClean(t *testing.T) {
+	pinger := &pathPinger{mtuV4: 1500, mtuV6: 1500}
+	prober := NewProberWithPinger(pinger)
+	prober.sysNet = writeInterfaceMTU(t, "wg-sgp", "1420")
+
+	report, err := prober.Probe(context.Background(), "wg-sgp", "9.9.9.9", "")
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if report.IPv4.MTU != 1420 || report.IPv4.Probes != 1 || report.IPv4.Target != "9.9.9.9" {
+		t.Fatalf("expected a single probe at the interface MTU, got %+v", report.IPv4)
+	}
+}
+
+func TestProbeReportsUnreachableFamily(t *testing.T) {
+	pinger := &pathPinger{mtuV4: 1420, mtuV6: 0}
+	prober := NewProberWithPinger(pinger)
+	prober.sysNet = t.TempDir()
+
+	report, err := prober.Probe(context.Background(), "wg-sgp", "", "")
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if report.InterfaceMTU != defaultMTU || report.IPv4.MTU != 1420 {
+		t.Fatalf("unexpected ipv4 result with default interface MTU: %+v", report)
+	}
+	if report.IPv6.Error == "" || report.IPv6.MTU != 0 || report.IPv6.RecommendedMSS != 0 {
+		t.Fatalf("expected ipv6 probe error, got %+v", report.IPv6)
+	}
+	if _, err := prober.Probe(context.Background(), " ", "", ""); err == nil {
+		t.Fatalf("expected missing interface to be rejected")
+	}
+}
+
+func writeInterfaceMTU(t *testing.T, iface, mtu string) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, iface), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, iface, "mtu"), []byte(mtu), 0o644); err != nil {
+		t.Fatalf("write mtu: %v", err)
+	}
+	return root
+}
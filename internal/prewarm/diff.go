@@ -0,0 +1,225 @@
+package prewarm
+
+import (
+	"context"
+	"sort"
+
+	"split-vpn-webui/internal/routing"
+)
+
+// maxDiffRunsRetained bounds how many runs keep per-prefix diff rows so the
+// table cannot grow without limit on long-lived installs.
+const maxDiffRunsRetained = 30
+
+// SetDiff lists prefixes newly learned or aged out for one destination set.
+type SetDiff struct {
+	SetName      string   `json:"setName"`
+	AddedV4      []string `json:"addedV4"`
+	AddedV6      []string `json:"addedV6"`
+	RemovedV4    []string `json:"removedV4"`
+	RemovedV6    []string `json:"removedV6"`
+	AddedCount   int      `json:"addedCount"`
+	RemovedCount int      `json:"removedCount"`
+}
+
+// RunDiff is returned by the run diff API.
+type RunDiff struct {
+	Run          RunRecord `json:"run"`
+	Sets         []SetDiff `json:"sets"`
+	AddedTotal   int       `json:"addedTotal"`
+	RemovedTotal int       `json:"removedTotal"`
+}
+
+// persistCacheSnapshot upserts the run's discoveries into the shared cache and
+// records which prefixes changed. Diff failures are logged, never fatal.
+func (s *Scheduler) persistCacheSnapshot(stats *RunStats) error {
+	change, err := s.cache.UpsertPrewarmSnapshotCompared(context.Background(), toRoutingCacheSnapshot(stats.CacheSnapshot))
+	if err != nil {
+		return err
+	}
+	if change.CompareErr != nil {
+		s.logWarnf("prewarm diff skipped: %v", change.CompareErr)
+		return nil
+	}
+	stats.Diff = diffCacheSnapshots(change.Before, change.After)
+	return nil
+}
+
+// RunDiff returns the prefixes a persisted run newly learned or aged out.
+func (s *Scheduler) RunDiff(ctx context.Context, runID int64) (*RunDiff, error) {
+	run, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := s.store.LoadRunDiff(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	added, removed := diffTotals(sets)
+	return &RunDiff{
+		Run:          *run,
+		Sets:         sets,
+		AddedTotal:   added,
+		RemovedTotal: removed,
+	}, nil
+}
+
+// diffCacheSnapshots compares the pre-warm cache before and after a run.
+// Sets without changes are omitted; output is sorted by set name.
+func diffCacheSnapshots(before, after map[string]routing.ResolverValues) []SetDiff {
+	names := make(map[string]struct{}, len(before)+len(after))
+	for name := range before {
+		names[name] = struct{}{}
+	}
+	for name := range after {
+		names[name] = struct{}{}
+	}
+	out := make([]SetDiff, 0, len(names))
+	for name := range names {
+		prev := before[name]
+		next := after[name]
+		diff := SetDiff{
+			SetName:   name,
+			AddedV4:   missingFrom(next.V4, prev.V4),
+			AddedV6:   missingFrom(next.V6, prev.V6),
+			RemovedV4: missingFrom(prev.V4, next.V4),
+			RemovedV6: missingFrom(prev.V6, next.V6),
+		}
+		diff.AddedCount = len(diff.AddedV4) + len(diff.AddedV6)
+		diff.RemovedCount = len(diff.RemovedV4) + len(diff.RemovedV6)
+		if diff.AddedCount == 0 && diff.RemovedCount == 0 {
+			continue
+		}
+		out = append(out, diff)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SetName < out[j].SetName })
+	return out
+}
+
+// missingFrom returns sorted values present in values but absent from reference.
+func missingFrom(values, reference []string) []string {
+	known := make(map[string]struct{}, len(reference))
+	for _, value := range reference {
+		known[value] = struct{}{}
+	}
+	out := make([]string, 0)
+	for _, value := range values {
+		if _, ok := known[value]; ok {
+			continue
+		}
+		known[value] = struct{}{}
+		out = append(out, value)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func diffTotals(sets []SetDiff) (added, removed int) {
+	for _, set := range sets {
+		added += set.AddedCount
+		removed += set.RemovedCount
+	}
+	return added, removed
+}
+
+// SaveRunDiff persists per-prefix diff rows for a run and prunes diff rows
+// belonging to runs older than the retention window.
+func (s *Store) SaveRunDiff(ctx context.Context, runID int64, sets []SetDiff) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insert := func(setName, family, change string, cidrs []string) error {
+		for _, cidr := range cidrs {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT OR IGNORE INTO prewarm_run_diffs (run_id, set_name, family, cidr, change)
+				VALUES (?, ?, ?, ?, ?)
+			`, runID, setName, family, cidr, change); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, set := range sets {
+		if err := insert(set.SetName, "inet", "added", set.AddedV4); err != nil {
+			return err
+		}
+		if err := insert(set.SetName, "inet6", "added", set.AddedV6); err != nil {
+			return err
+		}
+		if err := insert(set.SetName, "inet", "removed", set.RemovedV4); err != nil {
+			return err
+		}
+		if err := insert(set.SetName, "inet6", "removed", set.RemovedV6); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM prewarm_run_diffs
+		WHERE run_id NOT IN (
+			SELECT id FROM prewarm_runs ORDER BY id DESC LIMIT ?
+		)
+	`, maxDiffRunsRetained); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadRunDiff returns stored diff rows for a run grouped by destination set.
+func (s *Store) LoadRunDiff(ctx context.Context, runID int64) ([]SetDiff, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT set_name, family, cidr, change
+		FROM prewarm_run_diffs
+		WHERE run_id = ?
+		ORDER BY set_name ASC, change ASC, family ASC, cidr ASC
+	`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bySet := make(map[string]*SetDiff)
+	order := make([]string, 0)
+	for rows.Next() {
+		var setName, family, cidr, change string
+		if err := rows.Scan(&setName, &family, &cidr, &change); err != nil {
+			return nil, err
+		}
+		entry, ok := bySet[setName]
+		if !ok {
+			entry = &SetDiff{
+				SetName:   setName,
+				AddedV4:   []string{},
+				AddedV6:   []string{},
+				RemovedV4: []string{},
+				RemovedV6: []string{},
+			}
+			bySet[setName] = entry
+			order = append(order, setName)
+		}
+		switch {
+		case change == "added" && family == "inet6":
+			entry.AddedV6 = append(entry.AddedV6, cidr)
+			entry.AddedCount++
+		case change == "added":
+			entry.AddedV4 = append(entry.AddedV4, cidr)
+			entry.AddedCount++
+		case family == "inet6":
+			entry.RemovedV6 = append(entry.RemovedV6, cidr)
+			entry.RemovedCount++
+		default:
+			entry.RemovedV4 = append(entry.RemovedV4, cidr)
+			entry.RemovedCount++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]SetDiff, 0, len(order))
+	for _, name := range order {
+		out = append(out, *bySet[name])
+	}
+	return out, nil
+}
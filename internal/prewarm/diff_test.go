@@ -0,0 +1,145 @@
+package prewarm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/routing"
+)
+
+func TestDiffCacheSnapshotsReportsLearnedAndAgedOutPrefixes(t *testing.T) {
+	before := map[string]routing.ResolverValues{
+		"svpn_stream_r1d4": {V4: []string{"203.0.113.1/32", "203.0.113.2/32"}},
+		"svpn_stream_r1d6": {V6: []string{"2001:db8::1/128"}},
+		"svpn_static_r1d4": {V4: []string{"198.51.100.1/32"}},
+	}
+	after := map[string]routing.ResolverValues{
+		"svpn_stream_r1d4": {V4: []string{"203.0.113.2/32", "203.0.113.3/32"}},
+		"svpn_static_r1d4": {V4: []string{"198.51.100.1/32"}},
+		"svpn_new_r1d6":    {V6: []string{"2001:db8::2/128"}},
+	}
+
+	diff := diffCacheSnapshots(before, after)
+	if len(diff) != 3 {
+		t.Fatalf("expected 3 changed sets (unchanged set omitted), got %#v", diff)
+	}
+	if diff[0].SetName != "svpn_new_r1d6" || diff[0].AddedCount != 1 || diff[0].AddedV6[0] != "2001:db8::2/128" {
+		t.Fatalf("unexpected diff for new set: %#v", diff[0])
+	}
+	stream := diff[1]
+	if stream.SetName != "svpn_stream_r1d4" {
+		t.Fatalf("expected stream v4 set second, got %q", stream.SetName)
+	}
+	if len(stream.AddedV4) != 1 || stream.AddedV4[0] != "203.0.113.3/32" {
+		t.Fatalf("unexpected added v4: %#v", stream.AddedV4)
+	}
+	if len(stream.RemovedV4) != 1 || stream.RemovedV4[0] != "203.0.113.1/32" {
+		t.Fatalf("unexpected removed v4: %#v", stream.RemovedV4)
+	}
+	if diff[2].SetName != "svpn_stream_r1d6" || diff[2].RemovedCount != 1 {
+		t.Fatalf("expected aged-out v6 prefix, got %#v", diff[2])
+	}
+	added, removed := diffTotals(diff)
+	if added != 2 || removed != 2 {
+		t.Fatalf("expected totals 2/2, got %d/%d", added, removed)
+	}
+}
+
+func TestStoreRunDiffRoundTrip(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "prewarm-diff.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	ctx := context.Background()
+
+	saved, err := store.SaveRun(ctx, RunRecord{
+		StartedAt:       1_700_000_000,
+		FinishedAt:      1_700_000_010,
+		DurationMS:      10_000,
+		PrefixesAdded:   2,
+		PrefixesRemoved: 1,
+	})
+	if err != nil {
+		t.Fatalf("save run: %v", err)
+	}
+	sets := []SetDiff{{
+		SetName:   "svpn_stream_r1d4",
+		AddedV4:   []string{"203.0.113.3/32"},
+		AddedV6:   []string{"2001:db8::3/128"},
+		RemovedV4: []string{"203.0.113.1/32"},
+	}}
+	if err := store.SaveRunDiff(ctx, saved.ID, sets); err != nil {
+		t.Fatalf("save run diff: %v", err)
+	}
+
+	run, err := store.GetRun(ctx, saved.ID)
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if run.PrefixesAdded != 2 || run.PrefixesRemoved != 1 {
+		t.Fatalf("expected persisted prefix counters, got %#v", run)
+	}
+	loaded, err := store.LoadRunDiff(ctx, saved.ID)
+	if err != nil {
+		t.Fatalf("load run diff: %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("expected one set diff, got %#v", loaded)
+	}
+	got := loaded[0]
+	if got.AddedCount != 2 || got.RemovedCount != 1 {
+		t.Fatalf("unexpected counts: %#v", got)
+	}
+	if got.AddedV6[0] != "2001:db8::3/128" || got.RemovedV4[0] != "203.0.113.1/32" {
+		t.Fatalf("unexpected prefixes: %#v", got)
+	}
+
+	if _, err := store.GetRun(ctx, saved.ID+100); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestStoreRunDiffPrunesOldRuns(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "prewarm-diff-prune.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	ctx := context.Background()
+
+	var firstID int64
+	for i := 0; i < maxDiffRunsRetained+1; i++ {
+		saved, err := store.SaveRun(ctx, RunRecord{StartedAt: int64(1_700_000_000 + i)})
+		if err != nil {
+			t.Fatalf("save run %d: %v", i, err)
+		}
+		if i == 0 {
+			firstID = saved.ID
+		}
+		if err := store.SaveRunDiff(ctx, saved.ID, []SetDiff{{
+			SetName: "svpn_stream_r1d4",
+			AddedV4: []string{"203.0.113.1/32"},
+		}}); err != nil {
+			t.Fatalf("save run diff %d: %v", i, err)
+		}
+	}
+	loaded, err := store.LoadRunDiff(ctx, firstID)
+	if err != nil {
+		t.Fatalf("load pruned diff: %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("expected oldest run diff to be pruned, got %#v", loaded)
+	}
+}
@@ -0,0 +1,273 @@
+package prewarm
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRunErrorsStored bounds how many individual query errors one run
+	// persists; per-provider totals stay exact beyond it.
+	maxRunErrorsStored = 200
+
+	// DefaultRunsPageSize and MaxRunsPageSize bound run history pagination.
+	DefaultRunsPageSize = 20
+	MaxRunsPageSize     = 200
+)
+
+// RunError is one persisted query failure of a run.
+type RunError struct {
+	At        int64  `json:"at"`
+	Stage     string `json:"stage"`
+	Provider  string `json:"provider"`
+	Domain    string `json:"domain,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	Error     string `json:"error"`
+}
+
+// RunSummary is a run with its per-provider error breakdown.
+type RunSummary struct {
+	RunRecord
+	ErrorsTotal      int            `json:"errorsTotal"`
+	ErrorsByProvider map[string]int `json:"errorsByProvider"`
+}
+
+// RunsPage is one page of run history, newest first.
+type RunsPage struct {
+	Runs   []RunSummary `json:"runs"`
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// RunDetail is one run with its stored query errors.
+type RunDetail struct {
+	Run    RunSummary `json:"run"`
+	Errors []RunError `json:"errors"`
+	// Truncated reports that more errors occurred than were stored.
+	Truncated bool `json:"truncated"`
+}
+
+// runErrorLog collects query errors while a run is active. It is called
+// from worker goroutines.
+type runErrorLog struct {
+	now func() time.Time
+
+	mu         sync.Mutex
+	events     []RunError
+	byProvider map[string]int
+}
+
+func newRunErrorLog(now func() time.Time) *runErrorLog {
+	return &runErrorLog{now: now, byProvider: make(map[string]int)}
+}
+
+func (l *runErrorLog) add(event QueryError) {
+	if l == nil || event.Err == nil || errors.Is(event.Err, context.Canceled) {
+		return
+	}
+	provider := strings.TrimSpace(event.Resolver)
+	if provider == "" {
+		provider = "unknown"
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.byProvider[provider]++
+	if len(l.events) >= maxRunErrorsStored {
+		return
+	}
+	l.events = append(l.events, RunError{
+		At:        l.now().Unix(),
+		Stage:     event.Stage,
+		Provider:  provider,
+		Domain:    event.Domain,
+		Interface: event.Interface,
+		Error:     event.Err.Error(),
+	})
+}
+
+func (l *runErrorLog) snapshot() ([]RunError, map[string]int) {
+	if l == nil {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	totals := make(map[string]int, len(l.byProvider))
+	for provider, count := range l.byProvider {
+		totals[provider] = count
+	}
+	return append([]RunError(nil), l.events...), totals
+}
+
+// Runs returns one page of persisted runs with error breakdowns.
+func (s *Scheduler) Runs(ctx context.Context, limit, offset int) (RunsPage, error) {
+	return s.store.ListRuns(ctx, limit, offset)
+}
+
+// Run returns one persisted run and its stored query errors.
+func (s *Scheduler) Run(ctx context.Context, runID int64) (*RunDetail, error) {
+	run, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.store.loadErrorTotals(ctx, []int64{runID})
+	if err != nil {
+		return nil, err
+	}
+	runErrors, err := s.store.LoadRunErrors(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	summary := newRunSummary(*run, totals[runID])
+	return &RunDetail{
+		Run:       summary,
+		Errors:    runErrors,
+		Truncated: summary.ErrorsTotal > len(runErrors),
+	}, nil
+}
+
+func newRunSummary(run RunRecord, totals map[string]int) RunSummary {
+	summary := RunSummary{RunRecord: run, ErrorsByProvider: map[string]int{}}
+	for provider, count := range totals {
+		summary.ErrorsByProvider[provider] = count
+		summary.ErrorsTotal += count
+	}
+	return summary
+}
+
+// SaveRunErrors persists a run's sampled errors and per-provider totals.
+func (s *Store) SaveRunErrors(ctx context.Context, runID int64, events []RunError, totals map[string]int) error {
+	if len(events) == 0 && len(totals) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, event := range events {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO prewarm_run_errors (run_id, at, stage, provider, domain, interface, error)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, runID, event.At, event.Stage, event.Provider, event.Domain, event.Interface, event.Error); err != nil {
+			return err
+		}
+	}
+	for provider, count := range totals {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO prewarm_run_error_totals (run_id, provider, count)
+			VALUES (?, ?, ?)
+			ON CONFLICT(run_id, provider) DO UPDATE SET count = excluded.count
+		`, runID, provider, count); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadRunErrors returns the stored errors of a run in the order they occurred.
+func (s *Store) LoadRunErrors(ctx context.Context, runID int64) ([]RunError, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT at, stage, provider, domain, interface, error
+		FROM prewarm_run_errors
+		WHERE run_id = ?
+		ORDER BY id ASC
+	`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]RunError, 0)
+	for rows.Next() {
+		var event RunError
+		if err := rows.Scan(&event.At, &event.Stage, &event.Provider, &event.Domain, &event.Interface, &event.Error); err != nil {
+			return nil, err
+		}
+		out = append(out, event)
+	}
+	return out, rows.Err()
+}
+
+// ListRuns returns one page of runs, newest first, with error breakdowns.
+func (s *Store) ListRuns(ctx context.Context, limit, offset int) (RunsPage, error) {
+	if limit <= 0 {
+		limit = DefaultRunsPageSize
+	}
+	if limit > MaxRunsPageSize {
+		limit = MaxRunsPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	page := RunsPage{Runs: []RunSummary{}, Limit: limit, Offset: offset}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM prewarm_runs`).Scan(&page.Total); err != nil {
+		return RunsPage{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+runColumns+`
+		FROM prewarm_runs
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return RunsPage{}, err
+	}
+	var runs []RunRecord
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			rows.Close()
+			return RunsPage{}, err
+		}
+		runs = append(runs, *run)
+	}
+	if err := rows.Close(); err != nil {
+		return RunsPage{}, err
+	}
+	ids := make([]int64, 0, len(runs))
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	totals, err := s.loadErrorTotals(ctx, ids)
+	if err != nil {
+		return RunsPage{}, err
+	}
+	for _, run := range runs {
+		page.Runs = append(page.Runs, newRunSummary(run, totals[run.ID]))
+	}
+	return page, nil
+}
+
+func (s *Store) loadErrorTotals(ctx context.Context, runIDs []int64) (map[int64]map[string]int, error) {
+	out := make(map[int64]map[string]int, len(runIDs))
+	if len(runIDs) == 0 {
+		return out, nil
+	}
+	sort.Slice(runIDs, func(i, j int) bool { return runIDs[i] < runIDs[j] })
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT run_id, provider, count
+		FROM prewarm_run_error_totals
+		WHERE run_id BETWEEN ? AND ?
+	`, runIDs[0], runIDs[len(runIDs)-1])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var runID int64
+		var provider string
+		var count int
+		if err := rows.Scan(&runID, &provider, &count); err != nil {
+			return nil, err
+		}
+		if out[runID] == nil {
+			out[runID] = make(map[string]int)
+		}
+		out[runID][provider] = count
+	}
+	return out, rows.Err()
+}
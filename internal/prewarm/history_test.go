@@ -0,0 +1,84 @@
+package prewarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/database"
+)
+
+func TestRunErrorLogBoundsEventsButKeepsExactTotals(t *testing.T) {
+	log := newRunErrorLog(func() time.Time { return time.Unix(1700000000, 0) })
+	for i := 0; i < maxRunErrorsStored+50; i++ {
+		resolver := "cloudflare-doh"
+		if i%2 == 1 {
+			resolver = "wg0:10.0.0.1"
+		}
+		log.add(QueryError{Stage: "a", Domain: fmt.Sprintf("d%d.example", i), Resolver: resolver, Err: errors.New("timeout")})
+	}
+	log.add(QueryError{Stage: "a", Resolver: "cloudflare-doh", Err: context.Canceled})
+
+	events, totals := log.snapshot()
+	if len(events) != maxRunErrorsStored {
+		t.Fatalf("expected %d stored events, got %d", maxRunErrorsStored, len(events))
+	}
+	if totals["cloudflare-doh"] != 125 || totals["wg0:10.0.0.1"] != 125 {
+		t.Fatalf("unexpected totals (cancellation must not count): %#v", totals)
+	}
+}
+
+func TestStoreRunHistoryPagesWithErrorBreakdown(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "prewarm-history.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	ctx := context.Background()
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		saved, err := store.SaveRun(ctx, RunRecord{StartedAt: int64(1000 + i), FinishedAt: int64(1010 + i)})
+		if err != nil {
+			t.Fatalf("save run: %v", err)
+		}
+		ids = append(ids, saved.ID)
+	}
+	events := []RunError{{At: 1001, Stage: "aaaa", Provider: "cloudflare-doh", Domain: "example.com", Error: "timeout"}}
+	if err := store.SaveRunErrors(ctx, ids[1], events, map[string]int{"cloudflare-doh": 4, "crt.sh": 1}); err != nil {
+		t.Fatalf("save run errors: %v", err)
+	}
+
+	page, err := store.ListRuns(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if page.Total != 3 || len(page.Runs) != 2 || page.Runs[0].ID != ids[2] {
+		t.Fatalf("unexpected first page: %#v", page)
+	}
+	if page.Runs[1].ErrorsTotal != 5 || page.Runs[1].ErrorsByProvider["cloudflare-doh"] != 4 {
+		t.Fatalf("unexpected error breakdown: %#v", page.Runs[1])
+	}
+	if page.Runs[0].ErrorsTotal != 0 || page.Runs[0].ErrorsByProvider == nil {
+		t.Fatalf("expected empty breakdown for clean run: %#v", page.Runs[0])
+	}
+
+	scheduler := &Scheduler{store: store}
+	detail, err := scheduler.Run(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("run detail: %v", err)
+	}
+	if len(detail.Errors) != 1 || detail.Errors[0].Domain != "example.com" || !detail.Truncated {
+		t.Fatalf("unexpected run detail: %#v", detail)
+	}
+	if _, err := scheduler.Run(ctx, 9999); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
@@ -46,4 +46,5 @@ type RunStats struct {
 	IPsInserted   int
 	Progress      Progress
 	CacheSnapshot map[string]CachedSetValues
+	Diff          []SetDiff
 }
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
@@ -64,7 +63,7 @@ type Scheduler struct {
 }
 
 type prewarmCacheManager interface {
-	UpsertPrewarmSnapshot(ctx context.Context, snapshot map[string]routing.ResolverValues) error
+	UpsertPrewarmSnapshotCompared(ctx context.Context, snapshot map[string]routing.ResolverValues) (routing.PrewarmCacheChange, error)
 	ClearPrewarmCache(ctx context.Context) error
 }
 
@@ -217,14 +216,6 @@ func (s *Scheduler) TriggerNow() error {
 	s.mu.Unlock()
 
 	s.emitProgress(initial)
-	log.Printf(
-		"prewarm run started: timeout=%ds attempts=%d parallelism=%d extra_nameservers=%d ecs_profiles=%d",
-		int(timeoutFromSettings(current)/time.Second),
-		attemptsFromSettings(current),
-		parallelismFromSettings(current),
-		lenOrZero(current.PrewarmExtraNameservers),
-		lenOrZero(current.PrewarmECSProfiles),
-	)
 	s.logInfof(
 		"prewarm run started interval=%ds timeout=%ds attempts=%d parallelism=%d extra_nameservers=%d ecs_profiles=%d",
 		current.PrewarmIntervalSeconds,
@@ -272,16 +263,17 @@ func (s *Scheduler) CancelRun() error {
 func (s *Scheduler) executeRun(ctx context.Context, current settings.Settings) {
 	defer s.runWG.Done()
 	started := s.now()
+	errLog := newRunErrorLog(s.now)
 
 	timeout := timeoutFromSettings(current)
 	extraNameservers, queryErr := nameserversFromSettings(current)
 	if queryErr != nil {
-		s.finishRun(started, RunStats{}, queryErr)
+		s.finishRun(started, RunStats{}, nil, queryErr)
 		return
 	}
 	ecsProfiles, queryErr := ecsProfilesFromSettings(current)
 	if queryErr != nil {
-		s.finishRun(started, RunStats{}, queryErr)
+		s.finishRun(started, RunStats{}, nil, queryErr)
 		return
 	}
 	doh := NewCloudflareDoHClient(timeout)
@@ -291,8 +283,9 @@ func (s *Scheduler) executeRun(ctx context.Context, current settings.Settings) {
 		Attempts:         attemptsFromSettings(current),
 		ExtraNameservers: extraNameservers,
 		ECSProfiles:      ecsProfiles,
-		WildcardResolver: newCRTSHWildcardResolver(timeout),
+		WildcardResolver: newCRTSHWildcardResolver(timeout, routing.ResolverEgress(s.vpns, current)),
 		ErrorCallback: func(event QueryError) {
+			errLog.add(event)
 			s.logDebugf(
 				"prewarm query error stage=%s iface=%s domain=%s resolver=%s err=%v",
 				event.Stage,
@@ -303,7 +296,6 @@ func (s *Scheduler) executeRun(ctx context.Context, current settings.Settings) {
 			)
 		},
 		ResolverDisabledCallback: func(label string, failures int) {
-			log.Printf("prewarm: disabling resolver %s for this run after %d consecutive failures (unreachable over the active VPN interfaces?)", label, failures)
 			s.logWarnf("prewarm resolver disabled label=%s failures=%d", label, failures)
 		},
 		ProgressCallback: func(progress Progress) {
@@ -325,7 +317,7 @@ func (s *Scheduler) executeRun(ctx context.Context, current settings.Settings) {
 		stats, runErr = worker.Run(ctx)
 	}
 	if worker != nil && s.cache != nil {
-		cacheErr := s.cache.UpsertPrewarmSnapshot(context.Background(), toRoutingCacheSnapshot(stats.CacheSnapshot))
+		cacheErr := s.persistCacheSnapshot(&stats)
 		if cacheErr != nil {
 			if runErr == nil {
 				runErr = cacheErr
@@ -335,10 +327,10 @@ func (s *Scheduler) executeRun(ctx context.Context, current settings.Settings) {
 		}
 	}
 
-	s.finishRun(started, stats, runErr)
+	s.finishRun(started, stats, errLog, runErr)
 }
 
-func (s *Scheduler) finishRun(started time.Time, stats RunStats, runErr error) {
+func (s *Scheduler) finishRun(started time.Time, stats RunStats, errLog *runErrorLog, runErr error) {
 	stats = s.mergeStatsWithCurrentProgress(started, stats)
 	finished := s.now()
 	record := RunRecord{
@@ -349,6 +341,7 @@ func (s *Scheduler) finishRun(started time.Time, stats RunStats, runErr error) {
 		DomainsDone:  stats.DomainsDone,
 		IPsInserted:  stats.IPsInserted,
 	}
+	record.PrefixesAdded, record.PrefixesRemoved = diffTotals(stats.Diff)
 	if runErr != nil {
 		record.Error = runErr.Error()
 	}
@@ -358,6 +351,14 @@ func (s *Scheduler) finishRun(started time.Time, stats RunStats, runErr error) {
 		if saved.Error == "" {
 			saved.Error = saveErr.Error()
 		}
+	} else {
+		if err := s.store.SaveRunDiff(context.Background(), saved.ID, stats.Diff); err != nil {
+			s.logWarnf("prewarm diff persist failed run=%d: %v", saved.ID, err)
+		}
+		events, totals := errLog.snapshot()
+		if err := s.store.SaveRunErrors(context.Background(), saved.ID, events, totals); err != nil {
+			s.logWarnf("prewarm run errors persist failed run=%d: %v", saved.ID, err)
+		}
 	}
 
 	s.mu.Lock()
@@ -377,23 +378,6 @@ func (s *Scheduler) finishRun(started time.Time, stats RunStats, runErr error) {
 	if emit != nil {
 		s.emitProgress(*emit)
 	}
-	outcome := "finished"
-	if runErr != nil {
-		if errors.Is(runErr, context.Canceled) {
-			outcome = "canceled"
-		} else {
-			outcome = "failed"
-		}
-	}
-	log.Printf(
-		"prewarm run %s: duration_ms=%d domains=%d/%d ips=%d errors=%d",
-		outcome,
-		record.DurationMS,
-		record.DomainsDone,
-		record.DomainsTotal,
-		record.IPsInserted,
-		progressErrorCount(stats.Progress),
-	)
 	if runErr != nil {
 		if errors.Is(runErr, context.Canceled) {
 			s.logWarnf(
@@ -418,12 +402,14 @@ func (s *Scheduler) finishRun(started time.Time, stats RunStats, runErr error) {
 		return
 	}
 	s.logInfof(
-		"prewarm run finished duration_ms=%d domains=%d/%d ips=%d errors=%d",
+		"prewarm run finished duration_ms=%d domains=%d/%d ips=%d errors=%d prefixes_added=%d prefixes_removed=%d",
 		record.DurationMS,
 		record.DomainsDone,
 		record.DomainsTotal,
 		record.IPsInserted,
 		progressErrorCount(stats.Progress),
+		record.PrefixesAdded,
+		record.PrefixesRemoved,
 	)
 }
 
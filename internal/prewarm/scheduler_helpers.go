@@ -8,6 +8,31 @@ import (
 	"split-vpn-webui/internal/settings"
 )
 
+// ValidateSettings checks the pre-warm fields of a settings update. Zero
+// keeps each numeric default.
+func ValidateSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	for _, bound := range []struct {
+		field string
+		value int
+		max   int
+	}{
+		{"prewarmParallelism", current.PrewarmParallelism, maxParallelism},
+		{"prewarmDoHTimeoutSeconds", current.PrewarmDoHTimeoutSeconds, maxTimeoutSeconds},
+		{"prewarmQueryAttempts", current.PrewarmQueryAttempts, maxQueryAttempts},
+		{"prewarmIntervalSeconds", current.PrewarmIntervalSeconds, maxIntervalSeconds},
+	} {
+		if bound.value < 0 || bound.value > bound.max {
+			errs.Addf(bound.field, "%s must be between 0 and %d", bound.field, bound.max)
+		}
+	}
+	_, err := ParseNameserverLines(current.PrewarmExtraNameservers)
+	errs.Add("prewarmExtraNameservers", err)
+	_, err = ParseECSProfiles(current.PrewarmECSProfiles)
+	errs.Add("prewarmEcsProfiles", err)
+	return errs.Err()
+}
+
 func validateQuerySettings(current settings.Settings) error {
 	if _, err := nameserversFromSettings(current); err != nil {
 		return err
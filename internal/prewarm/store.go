@@ -7,16 +7,24 @@ import (
 	"fmt"
 )
 
-// RunRecord is a persisted pre-warm run.
+// ErrRunNotFound indicates the requested run id does not exist.
+var ErrRunNotFound = errors.New("prewarm run not found")
+
+const runColumns = `id, started_at, finished_at, duration_ms, domains_total, domains_done, ips_inserted, prefixes_added, prefixes_removed, error`
+
+// RunRecord is a persisted pre-warm run. PrefixesAdded/PrefixesRemoved count
+// cached prefixes newly learned or aged out across all sets during the run.
 type RunRecord struct {
-	ID           int64  `json:"id"`
-	StartedAt    int64  `json:"startedAt"`
-	FinishedAt   int64  `json:"finishedAt,omitempty"`
-	DurationMS   int64  `json:"durationMs,omitempty"`
-	DomainsTotal int    `json:"domainsTotal"`
-	DomainsDone  int    `json:"domainsDone"`
-	IPsInserted  int    `json:"ipsInserted"`
-	Error        string `json:"error,omitempty"`
+	ID              int64  `json:"id"`
+	StartedAt       int64  `json:"startedAt"`
+	FinishedAt      int64  `json:"finishedAt,omitempty"`
+	DurationMS      int64  `json:"durationMs,omitempty"`
+	DomainsTotal    int    `json:"domainsTotal"`
+	DomainsDone     int    `json:"domainsDone"`
+	IPsInserted     int    `json:"ipsInserted"`
+	PrefixesAdded   int    `json:"prefixesAdded"`
+	PrefixesRemoved int    `json:"prefixesRemoved"`
+	Error           string `json:"error,omitempty"`
 }
 
 // Store persists pre-warm run metadata to SQLite.
@@ -48,9 +56,11 @@ func (s *Store) SaveRun(ctx context.Context, run RunRecord) (*RunRecord, error)
 	}
 	result, err := s.db.ExecContext(ctx, `
 		INSERT INTO prewarm_runs (
-			started_at, finished_at, duration_ms, domains_total, domains_done, ips_inserted, error
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, run.StartedAt, finishedAt, durationMS, run.DomainsTotal, run.DomainsDone, run.IPsInserted, runErr)
+			started_at, finished_at, duration_ms, domains_total, domains_done, ips_inserted,
+			prefixes_added, prefixes_removed, error
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.StartedAt, finishedAt, durationMS, run.DomainsTotal, run.DomainsDone, run.IPsInserted,
+		run.PrefixesAdded, run.PrefixesRemoved, runErr)
 	if err != nil {
 		return nil, err
 	}
@@ -65,12 +75,38 @@ func (s *Store) SaveRun(ctx context.Context, run RunRecord) (*RunRecord, error)
 // LastRun returns the newest run row, or nil when no runs exist.
 func (s *Store) LastRun(ctx context.Context) (*RunRecord, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, started_at, finished_at, duration_ms, domains_total, domains_done, ips_inserted, error
+		SELECT `+runColumns+`
 		FROM prewarm_runs
 		ORDER BY id DESC
 		LIMIT 1
 	`)
+	run, err := scanRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return run, err
+}
+
+// GetRun returns one run row by id.
+func (s *Store) GetRun(ctx context.Context, id int64) (*RunRecord, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+runColumns+`
+		FROM prewarm_runs
+		WHERE id = ?
+	`, id)
+	run, err := scanRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRunNotFound
+	}
+	return run, err
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
+func scanRun(row rowScanner) (*RunRecord, error) {
 	var run RunRecord
 	var finishedAt sql.NullInt64
 	var durationMS sql.NullInt64
@@ -83,11 +119,10 @@ func (s *Store) LastRun(ctx context.Context) (*RunRecord, error) {
 		&run.DomainsTotal,
 		&run.DomainsDone,
 		&run.IPsInserted,
+		&run.PrefixesAdded,
+		&run.PrefixesRemoved,
 		&runErr,
 	); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
 		return nil, err
 	}
 	if finishedAt.Valid {
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"split-vpn-webui/internal/netbind"
 	"split-vpn-webui/internal/vpn"
 )
 
@@ -25,13 +26,13 @@ type crtSHPayloadEntry struct {
 	NameValue string `json:"name_value"`
 }
 
-func newCRTSHWildcardResolver(timeout time.Duration) *crtSHWildcardResolver {
+func newCRTSHWildcardResolver(timeout time.Duration, egress netbind.Egress) *crtSHWildcardResolver {
 	if timeout <= 0 {
 		timeout = defaultDoHTimeout
 	}
 	return &crtSHWildcardResolver{
 		baseURL: prewarmWildcardEndpoint,
-		client:  &http.Client{Timeout: timeout},
+		client:  netbind.HTTPClient(timeout, egress),
 	}
 }
 
@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"split-vpn-webui/internal/netbind"
 	"split-vpn-webui/internal/routing"
 	"split-vpn-webui/internal/util"
 	"split-vpn-webui/internal/vpn"
@@ -159,7 +160,7 @@ func NewWorker(groups GroupSource, vpns VPNSource, doh DoHClient, ipset routing.
 	}
 	wildcard := opts.WildcardResolver
 	if wildcard == nil {
-		wildcard = newCRTSHWildcardResolver(defaultDoHTimeout)
+		wildcard = newCRTSHWildcardResolver(defaultDoHTimeout, netbind.Egress{})
 	}
 	return &Worker{
 		groups:           groups,
@@ -0,0 +1,100 @@
+package privhelper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+// ExitError reports a command the helper ran that exited non-zero.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Client sends commands to the helper. It satisfies routing.Executor,
+// systemd.CommandRunner and systemd.UnitLinker, so it can stand in for
+// direct execution, and vpn.UnitWriter for writing VPN units.
+type Client struct {
+	socketPath string
+	timeout    time.Duration
+}
+
+// NewClient creates a client for the helper listening on socketPath.
+func NewClient(socketPath string) *Client {
+	return &Client{socketPath: socketPath, timeout: connTimeout}
+}
+
+// Run runs a command through the helper.
+func (c *Client) Run(name string, args ...string) error {
+	_, err := c.call(request{Name: name, Args: args})
+	return err
+}
+
+// Output runs a command through the helper and returns its combined output.
+func (c *Client) Output(name string, args ...string) ([]byte, error) {
+	return c.call(request{Name: name, Args: args})
+}
+
+// RunWithInput runs a command with input piped to stdin.
+func (c *Client) RunWithInput(input []byte, name string, args ...string) error {
+	output, err := c.call(request{Name: name, Args: args, Input: input})
+	if err != nil {
+		if detail := strings.TrimSpace(string(output)); detail != "" {
+			return fmt.Errorf("%w: %s", err, detail)
+		}
+		return err
+	}
+	return nil
+}
+
+// WriteVPNUnit asks the helper to install the unit for spec along with the
+// VPN's files.
+func (c *Client) WriteVPNUnit(spec vpn.UnitSpec, files map[string][]byte) error {
+	_, err := c.call(request{Name: opWriteVPNUnit, Unit: &spec, Files: files})
+	return err
+}
+
+// RemoveVPNUnit asks the helper to remove a VPN's unit and installed files.
+func (c *Client) RemoveVPNUnit(name string) error {
+	_, err := c.call(request{Name: opRemoveVPNUnit, Args: []string{name}})
+	return err
+}
+
+// LinkUnit asks the helper to restore the symlink of a canonical unit.
+func (c *Client) LinkUnit(unitName string) error {
+	_, err := c.call(request{Name: opLinkUnit, Args: []string{unitName}})
+	return err
+}
+
+func (c *Client) call(req request) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", c.socketPath, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("privilege helper unavailable: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("privilege helper: send %s: %w", req.Name, err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("privilege helper: read %s result: %w", req.Name, err)
+	}
+	switch {
+	case resp.Error != "":
+		return resp.Output, errors.New(resp.Error)
+	case resp.ExitCode != 0:
+		return resp.Output, &ExitError{Code: resp.ExitCode}
+	}
+	return resp.Output, nil
+}
@@ -0,0 +1,78 @@
+package privhelper
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func startTestServer(t *testing.T, allowed []uint32, run runFunc) *Client {
+	t.Helper()
+	return startTestServerWithUnits(t, allowed, run, nil)
+}
+
+func startTestServerWithUnits(t *testing.T, allowed []uint32, run runFunc, units *Units) *Client {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "helper.sock")
+	listener, err := Listen(socket, -1)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	server := NewServer(NewPolicy(), units, allowed)
+	server.run = run
+	server.logf = t.Logf
+	go server.Serve(listener)
+	return NewClient(socket)
+}
+
+func TestClientRunsAllowedCommands(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only read on linux")
+	}
+	var gotInput string
+	client := startTestServer(t, []uint32{uint32(os.Getuid())}, func(_ context.Context, name string, args []string, input []byte) ([]byte, int, error) {
+		gotInput = string(input)
+		if name == "ipset" && args[0] == "list" {
+			return []byte("svpn_g1_v4\n"), 0, nil
+		}
+		if name == "systemctl" {
+			return []byte("inactive\n"), 3, nil
+		}
+		return nil, 0, nil
+	})
+
+	output, err := client.Output("ipset", "list", "-name")
+	if err != nil || string(output) != "svpn_g1_v4\n" {
+		t.Fatalf("unexpected output %q: %v", output, err)
+	}
+	if err := client.RunWithInput([]byte("flush svpn_g1_v4\n"), "ipset", "restore", "-exist"); err != nil || gotInput != "flush svpn_g1_v4\n" {
+		t.Fatalf("expected restore input to reach the command, got %q: %v", gotInput, err)
+	}
+	output, err = client.Output("systemctl", "is-active", "svpn-office.service")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 || string(output) != "inactive\n" {
+		t.Fatalf("expected exit status 3 with output, got %q: %v", output, err)
+	}
+	if err := client.Run("iptables", "-t", "filter", "-F"); err == nil || !strings.Contains(err.Error(), ErrDenied.Error()) {
+		t.Fatalf("expected denied command to fail, got %v", err)
+	}
+}
+
+func TestServerRefusesUnlistedPeers(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Getuid() == 0 {
+		t.Skip("needs a non-root caller on linux")
+	}
+	ran := false
+	client := startTestServer(t, nil, func(context.Context, string, []string, []byte) ([]byte, int, error) {
+		ran = true
+		return nil, 0, nil
+	})
+	if err := client.Run("ip", "rule", "show"); err == nil || ran {
+		t.Fatalf("expected unlisted uid to be refused, got %v (ran=%v)", err, ran)
+	}
+}
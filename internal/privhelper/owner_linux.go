@@ -0,0 +1,17 @@
+//go:build linux
+
+package privhelper
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the uid that owns a file.
+func fileOwner(info os.FileInfo) (uint32, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return stat.Uid, true
+}
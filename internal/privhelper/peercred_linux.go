@@ -0,0 +1,29 @@
+//go:build linux
+
+package privhelper
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerUID returns the uid of the process on the other end of a unix socket
+// connection, read from SO_PEERCRED.
+func peerUID(conn net.Conn) (uint32, bool) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, false
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil || credErr != nil || cred == nil {
+		return 0, false
+	}
+	return cred.Uid, true
+}
@@ -0,0 +1,391 @@
+package privhelper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrDenied marks a command the policy does not allow.
+var ErrDenied = errors.New("command not allowed")
+
+var (
+	managedUnitPattern = regexp.MustCompile(`^(svpn-[A-Za-z0-9_.-]+|split-vpn-webui(-[a-z]+)?)\.service$`)
+	numberPattern      = regexp.MustCompile(`^[0-9]+$`)
+)
+
+const (
+	managedSetPrefix   = "svpn_"
+	managedChainPrefix = "SVPN"
+	// managedRuleComment prefixes the comment on every generated iptables
+	// rule; rules carrying it are removed from built-in chains as drift.
+	managedRuleComment = "svpn:"
+	// updaterUnit runs as root and replaces the binary with one the web UI
+	// downloaded, so it is never started on the web UI's behalf.
+	updaterUnit = "split-vpn-webui-updater"
+	// minManagedTable and maxManagedTable bound the routing tables the VPN
+	// allocator hands out, and minManagedMark the fwmarks.
+	minManagedTable = 200
+	maxManagedTable = 65535
+	minManagedMark  = 200
+	// managedRulePriority is the preference of the routing package's fwmark
+	// rules.
+	managedRulePriority = "100"
+)
+
+var (
+	iptablesTables   = map[string]bool{"filter": true, "mangle": true, "nat": true, "raw": true}
+	builtinChains    = map[string]bool{"PREROUTING": true, "INPUT": true, "FORWARD": true, "OUTPUT": true, "POSTROUTING": true}
+	ipsetRestoreOps  = map[string]bool{"create": true, "add": true, "del": true, "flush": true, "destroy": true}
+	systemctlActions = map[string]bool{
+		"start": true, "stop": true, "restart": true, "reload": true,
+		"enable": true, "disable": true, "is-active": true, "is-enabled": true, "status": true,
+	}
+	// reservedTables are the kernel's default, main and local tables.
+	reservedTables = map[int]bool{253: true, 254: true, 255: true}
+)
+
+type validator func(args []string, input []byte) error
+
+// Policy is the allowlist of commands the helper runs. Every command the
+// web UI needs is listed with the argument shapes it uses; anything else,
+// including the same tool touching state the service does not own, is
+// denied.
+type Policy struct {
+	commands map[string]validator
+	// procComm reads /proc/<pid>/comm, for tests.
+	procComm func(pid string) (string, error)
+}
+
+// NewPolicy returns the allowlist. Unit files are not linked through it:
+// the helper writes and links VPN units itself (see Units).
+func NewPolicy() *Policy {
+	p := &Policy{
+		procComm: func(pid string) (string, error) {
+			data, err := os.ReadFile(filepath.Join("/proc", pid, "comm"))
+			return strings.TrimSpace(string(data)), err
+		},
+	}
+	p.commands = map[string]validator{
+		"ipset":      checkIPSet,
+		"iptables":   checkIPTables,
+		"ip6tables":  checkIPTables,
+		"ip":         checkIP,
+		"systemctl":  p.checkSystemctl,
+		"journalctl": checkJournalctl,
+		"pidof":      exactArgs("dnsmasq"),
+		"kill":       p.checkKill,
+		"pihole":     exactArgs("restartdns"),
+		// The AdGuard Home backend falls back to the service's own control
+		// command when it is not installed as a systemd unit.
+		"/opt/AdGuardHome/AdGuardHome": exactArgs("-s", "restart"),
+	}
+	return p
+}
+
+// Check reports whether name may run with args and input, wrapping
+// ErrDenied with the reason when it may not.
+func (p *Policy) Check(name string, args []string, input []byte) error {
+	check, ok := p.commands[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDenied, name)
+	}
+	if err := check(args, input); err != nil {
+		return fmt.Errorf("%w: %s %s: %v", ErrDenied, name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func checkIPSet(args []string, input []byte) error {
+	if len(args) == 0 {
+		return errors.New("missing subcommand")
+	}
+	if len(input) > 0 && args[0] != "restore" {
+		return errors.New("only restore reads input")
+	}
+	switch args[0] {
+	case "list":
+		for _, arg := range args[1:] {
+			if arg != "-name" && arg != "-n" && arg != "-terse" && arg != "-t" {
+				if err := managedSet(arg); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case "save", "flush", "destroy":
+		if len(args) != 2 {
+			return errors.New("exactly one set is required")
+		}
+		return managedSet(args[1])
+	case "create", "add", "del", "test":
+		if len(args) < 2 {
+			return errors.New("a set is required")
+		}
+		return managedSet(args[1])
+	case "swap":
+		if len(args) != 3 {
+			return errors.New("two sets are required")
+		}
+		if err := managedSet(args[1]); err != nil {
+			return err
+		}
+		return managedSet(args[2])
+	case "restore":
+		if len(args) > 2 || (len(args) == 2 && args[1] != "-exist") {
+			return errors.New("unexpected restore flags")
+		}
+		return checkIPSetScript(input)
+	}
+	return fmt.Errorf("subcommand %q", args[0])
+}
+
+// checkIPSetScript validates an `ipset restore` script line by line.
+func checkIPSetScript(input []byte) error {
+	for _, line := range strings.Split(string(input), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0] == "swap" && len(fields) == 3:
+			if err := managedSet(fields[1]); err != nil {
+				return err
+			}
+			if err := managedSet(fields[2]); err != nil {
+				return err
+			}
+		case ipsetRestoreOps[fields[0]] && len(fields) >= 2:
+			if err := managedSet(fields[1]); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("restore line %q", line)
+		}
+	}
+	return nil
+}
+
+func managedSet(name string) error {
+	if !strings.HasPrefix(name, managedSetPrefix) {
+		return fmt.Errorf("set %q is not managed by split-vpn-webui", name)
+	}
+	return nil
+}
+
+// checkIPTables allows listing any chain, any change inside SVPN chains,
+// and on built-in chains only the jumps into them and the removal of
+// rules carrying the managed comment.
+func checkIPTables(args []string, input []byte) error {
+	if len(input) > 0 {
+		return errors.New("input is not accepted")
+	}
+	if len(args) < 3 || args[0] != "-t" || !iptablesTables[args[1]] {
+		return errors.New("expected -t <table> first")
+	}
+	op, rest := args[2], args[3:]
+	switch op {
+	case "-S":
+		for _, arg := range rest {
+			if strings.HasPrefix(arg, "-") && arg != "-v" {
+				return fmt.Errorf("flag %q", arg)
+			}
+		}
+		return nil
+	case "-N", "-F", "-X":
+		if len(rest) != 1 {
+			return errors.New("exactly one chain is required")
+		}
+		return managedChain(rest[0])
+	case "-A", "-I", "-C", "-D":
+		if len(rest) == 0 {
+			return errors.New("a chain is required")
+		}
+		chain, rule := rest[0], rest[1:]
+		if strings.HasPrefix(chain, managedChainPrefix) || (op == "-D" && hasManagedComment(rule)) {
+			return nil
+		}
+		if !builtinChains[chain] {
+			return managedChain(chain)
+		}
+		if op == "-I" && len(rule) > 0 && numberPattern.MatchString(rule[0]) {
+			rule = rule[1:]
+		}
+		if len(rule) == 2 && rule[0] == "-j" && strings.HasPrefix(rule[1], managedChainPrefix) {
+			return nil
+		}
+		return fmt.Errorf("only jumps into SVPN chains may be changed in %s", chain)
+	}
+	return fmt.Errorf("operation %q", op)
+}
+
+func managedChain(chain string) error {
+	if !strings.HasPrefix(chain, managedChainPrefix) {
+		return fmt.Errorf("chain %q is not managed by split-vpn-webui", chain)
+	}
+	return nil
+}
+
+func hasManagedComment(rule []string) bool {
+	for i := 0; i+1 < len(rule); i++ {
+		if rule[i] == "--comment" && strings.HasPrefix(strings.Trim(rule[i+1], `"`), managedRuleComment) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIP allows reading policy rules and routes, and adding or deleting
+// the routing package's fwmark rules: a managed mark pointing at a table
+// VPNs are allocated, at the managed rule priority, and nothing else.
+func checkIP(args []string, input []byte) error {
+	if len(input) > 0 {
+		return errors.New("input is not accepted")
+	}
+	for len(args) > 0 && (args[0] == "-4" || args[0] == "-6" || args[0] == "-j" || args[0] == "-json") {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return errors.New("missing object or command")
+	}
+	switch {
+	case (args[0] == "rule" || args[0] == "route") && (args[1] == "show" || args[1] == "list"):
+		return nil
+	case args[0] == "rule" && (args[1] == "add" || args[1] == "del"):
+		return checkIPRule(args[2:])
+	}
+	return fmt.Errorf("ip %s %s", args[0], args[1])
+}
+
+func checkIPRule(args []string) error {
+	if len(args)%2 != 0 {
+		return errors.New("every selector needs a value")
+	}
+	seen := make(map[string]bool, 3)
+	for i := 0; i < len(args); i += 2 {
+		key, value := args[i], args[i+1]
+		switch key {
+		case "fwmark":
+			mark, err := strconv.ParseUint(value, 0, 32)
+			if err != nil || mark < minManagedMark {
+				return fmt.Errorf("fwmark %q is not managed by split-vpn-webui", value)
+			}
+		case "table", "lookup":
+			key = "table"
+			table, err := strconv.Atoi(value)
+			if err != nil || table < minManagedTable || table > maxManagedTable || reservedTables[table] {
+				return fmt.Errorf("table %q is not managed by split-vpn-webui", value)
+			}
+		case "priority", "pref", "preference":
+			key = "priority"
+			if value != managedRulePriority {
+				return fmt.Errorf("priority %q is not the managed rule priority %s", value, managedRulePriority)
+			}
+		default:
+			return fmt.Errorf("selector %q", key)
+		}
+		if seen[key] {
+			return fmt.Errorf("%s given twice", key)
+		}
+		seen[key] = true
+	}
+	if !seen["fwmark"] || !seen["table"] || !seen["priority"] {
+		return errors.New("fwmark, table and priority are required")
+	}
+	return nil
+}
+
+// checkSystemctl allows lifecycle actions on managed units, reloading
+// dnsmasq, and restarting AdGuard Home. Linking goes through Units instead.
+func (p *Policy) checkSystemctl(args []string, input []byte) error {
+	if len(input) > 0 {
+		return errors.New("input is not accepted")
+	}
+	if len(args) == 1 && args[0] == "daemon-reload" {
+		return nil
+	}
+	if len(args) != 2 {
+		return errors.New("expected an action and one unit")
+	}
+	action, unit := args[0], args[1]
+	if strings.TrimSuffix(filepath.Base(unit), ".service") == updaterUnit {
+		return fmt.Errorf("unit %q installs a binary the web UI staged", unit)
+	}
+	if !systemctlActions[action] {
+		return fmt.Errorf("action %q", action)
+	}
+	if (unit == "dnsmasq" || unit == "dnsmasq.service") && (action == "reload" || action == "restart") {
+		return nil
+	}
+	if (unit == "AdGuardHome" || unit == "AdGuardHome.service") && action == "restart" {
+		return nil
+	}
+	if !managedUnitPattern.MatchString(unit) {
+		return fmt.Errorf("unit %q is not managed by split-vpn-webui", unit)
+	}
+	return nil
+}
+
+func checkJournalctl(args []string, input []byte) error {
+	if len(input) > 0 {
+		return errors.New("input is not accepted")
+	}
+	var unit bool
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--no-pager":
+		case "-u", "-n", "-o":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s needs a value", args[i])
+			}
+			if args[i] == "-u" {
+				if !managedUnitPattern.MatchString(args[i+1]) {
+					return fmt.Errorf("unit %q is not managed by split-vpn-webui", args[i+1])
+				}
+				unit = true
+			}
+			i++
+		default:
+			return fmt.Errorf("argument %q", args[i])
+		}
+	}
+	if !unit {
+		return errors.New("a unit is required")
+	}
+	return nil
+}
+
+// checkKill allows the HUP that makes dnsmasq re-read its config, and
+// nothing else.
+func (p *Policy) checkKill(args []string, input []byte) error {
+	if len(input) > 0 {
+		return errors.New("input is not accepted")
+	}
+	if len(args) != 2 || args[0] != "-HUP" || !numberPattern.MatchString(args[1]) {
+		return errors.New("only kill -HUP <pid> is allowed")
+	}
+	comm, err := p.procComm(args[1])
+	if err != nil {
+		return fmt.Errorf("read process %s: %v", args[1], err)
+	}
+	if comm != "dnsmasq" {
+		return fmt.Errorf("process %s is %q, not dnsmasq", args[1], comm)
+	}
+	return nil
+}
+
+func exactArgs(expected ...string) validator {
+	return func(args []string, input []byte) error {
+		if len(input) > 0 {
+			return errors.New("input is not accepted")
+		}
+		if strings.Join(args, "\x00") != strings.Join(expected, "\x00") {
+			return fmt.Errorf("expected %q", strings.Join(expected, " "))
+		}
+		return nil
+	}
+}
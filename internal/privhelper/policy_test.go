@@ -0,0 +1,88 @@
+package privhelper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPolicyAllowsRoutingAndUnitCommands(t *testing.T) {
+	p := NewPolicy()
+	p.procComm = func(pid string) (string, error) { return "dnsmasq", nil }
+	for _, command := range []string{
+		"ipset create svpn_g1_v4 hash:net family inet timeout 86400 -exist",
+		"ipset list -name",
+		"ipset swap svpn_g1_v4 svpn_g1_v4_tmp",
+		"iptables -t mangle -S",
+		"iptables -t mangle -S -v",
+		"iptables -t mangle -N SVPN_MARK_1",
+		"iptables -t mangle -A SVPN_MARK_1 -m set --match-set svpn_g1_v4 dst -j MARK --set-mark 0x169",
+		"iptables -t mangle -C PREROUTING -j SVPN_MARK",
+		"iptables -t nat -I SVPN_NAT 1 -j SVPN_NAT_2",
+		"ip6tables -t mangle -D PREROUTING -m comment --comment svpn:1:2 -j MARK --set-mark 0x169",
+		"ip -6 rule add fwmark 0x169 table 201 priority 100",
+		"ip rule del fwmark 0x169 lookup 300 pref 100",
+		"ip rule show",
+		"systemctl daemon-reload",
+		"systemctl restart svpn-office.service",
+		"systemctl restart AdGuardHome",
+		"/opt/AdGuardHome/AdGuardHome -s restart",
+		"systemctl reload dnsmasq",
+		"journalctl -u svpn-office.service -n 20 --no-pager -o short-iso",
+		"pidof dnsmasq",
+		"kill -HUP 1234",
+	} {
+		fields := strings.Fields(command)
+		if err := p.Check(fields[0], fields[1:], nil); err != nil {
+			t.Errorf("expected %q to be allowed: %v", command, err)
+		}
+	}
+	script := []byte("create svpn_g1_v4 hash:net family inet\nadd svpn_g1_v4 10.0.0.0/8 timeout 300\nswap svpn_g1_v4 svpn_g1_v4_tmp\n")
+	if err := p.Check("ipset", []string{"restore", "-exist"}, script); err != nil {
+		t.Errorf("expected managed restore script to be allowed: %v", err)
+	}
+}
+
+func TestPolicyDeniesUnmanagedState(t *testing.T) {
+	p := NewPolicy()
+	p.procComm = func(pid string) (string, error) { return "sshd", nil }
+	for _, command := range []string{
+		"sh -c id",
+		"ipset flush",
+		"ipset destroy UBIOS_ADDRv4",
+		"iptables -t filter -F",
+		"iptables -t filter -P INPUT ACCEPT",
+		"iptables -t mangle -A PREROUTING -j ACCEPT",
+		"iptables -t nat -F UBIOS_POSTROUTING",
+		"ip rule add from all table 100",
+		"ip rule add fwmark 0x169 table 254 priority 100",
+		"ip rule add fwmark 0x169 table 199 priority 100",
+		"ip rule add fwmark 0x1 table 201 priority 100",
+		"ip rule add fwmark 0x169 table 201 priority 0",
+		"ip rule add fwmark 0x169 table 201",
+		"ip rule add fwmark 0x169 table 201 priority 100 from 10.0.0.0/8",
+		"ip rule add fwmark 0x169 table 201 priority 100 table 254",
+		"ip rule del fwmark 0x169 table 201 priority 100 blackhole",
+		"ip route add default via 10.0.0.1",
+		"ip link set eth0 down",
+		"systemctl stop ssh.service",
+		"systemctl start split-vpn-webui-updater.service",
+		"systemctl link /data/split-vpn-webui/units/split-vpn-webui-updater.service",
+		"systemctl stop AdGuardHome",
+		"/opt/AdGuardHome/AdGuardHome -s uninstall",
+		"systemctl link /data/split-vpn-webui/units/svpn-office.service",
+		"systemctl link /etc/passwd",
+		"systemctl link /tmp/svpn-evil.service",
+		"journalctl -u ssh.service",
+		"kill -HUP 1234",
+		"kill -9 1",
+	} {
+		fields := strings.Fields(command)
+		if err := p.Check(fields[0], fields[1:], nil); !errors.Is(err, ErrDenied) {
+			t.Errorf("expected %q to be denied, got %v", command, err)
+		}
+	}
+	if err := p.Check("ipset", []string{"restore"}, []byte("flush UBIOS_ADDRv4\n")); !errors.Is(err, ErrDenied) {
+		t.Errorf("expected unmanaged restore script to be denied, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+// Package privhelper runs the privileged commands routing and VPN
+// management need (ipset, iptables, ip, systemctl) on behalf of an
+// unprivileged web UI process. The helper runs as root, listens on a unix
+// socket, checks each caller's uid, and only executes commands its Policy
+// allows. VPN units it writes itself, from a validated spec (see Units).
+package privhelper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+const (
+	// DefaultSocketName is the helper socket in the data directory.
+	DefaultSocketName = "privhelper.sock"
+	// maxRequestBytes bounds a request; ipset restore scripts for large
+	// domain groups are the biggest payloads.
+	maxRequestBytes = 16 << 20
+	commandTimeout  = 2 * time.Minute
+	connTimeout     = commandTimeout + 30*time.Second
+)
+
+// request is one command or unit operation, sent as a single JSON
+// document per connection.
+type request struct {
+	Name  string            `json:"name"`
+	Args  []string          `json:"args"`
+	Input []byte            `json:"input,omitempty"`
+	Unit  *vpn.UnitSpec     `json:"unit,omitempty"`
+	Files map[string][]byte `json:"files,omitempty"`
+}
+
+// response carries the command's combined output. Error is set when the
+// command was denied or could not be started; ExitCode when it ran and
+// failed.
+type response struct {
+	Output   []byte `json:"output,omitempty"`
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+}
+
+// runFunc executes a command and returns its combined output and exit code.
+type runFunc func(ctx context.Context, name string, args []string, input []byte) ([]byte, int, error)
+
+// Server executes allowlisted commands for permitted peers.
+type Server struct {
+	policy  *Policy
+	units   *Units
+	allowed map[uint32]bool
+	run     runFunc
+	logf    func(format string, args ...any)
+}
+
+// NewServer creates a helper that serves root and the given uids and
+// installs VPN units with units.
+func NewServer(policy *Policy, units *Units, allowedUIDs []uint32) *Server {
+	allowed := map[uint32]bool{0: true}
+	for _, uid := range allowedUIDs {
+		allowed[uid] = true
+	}
+	return &Server{policy: policy, units: units, allowed: allowed, run: runCommand, logf: log.Printf}
+}
+
+// Listen opens the helper socket, replacing a stale one, and hands it to
+// owner so only that uid (and root) can connect.
+func Listen(path string, owner int) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if owner > 0 {
+		if err := os.Chown(path, owner, -1); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	return listener, nil
+}
+
+// Serve handles connections until the listener is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(connTimeout))
+
+	uid, ok := peerUID(conn)
+	if !ok || !s.allowed[uid] {
+		s.logf("privhelper: refused connection from uid %d", uid)
+		_ = json.NewEncoder(conn).Encode(response{ExitCode: -1, Error: "caller is not allowed to use the privilege helper"})
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(io.LimitReader(conn, maxRequestBytes)).Decode(&req); err != nil {
+		_ = json.NewEncoder(conn).Encode(response{ExitCode: -1, Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	if handled, err := s.handleUnitRequest(req); handled {
+		resp := response{}
+		if err != nil {
+			s.logf("privhelper: %s for uid %d failed: %v", req.Name, uid, err)
+			resp.ExitCode, resp.Error = -1, err.Error()
+		}
+		_ = json.NewEncoder(conn).Encode(resp)
+		return
+	}
+	if err := s.policy.Check(req.Name, req.Args, req.Input); err != nil {
+		s.logf("privhelper: denied uid %d: %v", uid, err)
+		_ = json.NewEncoder(conn).Encode(response{ExitCode: -1, Error: err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	output, code, err := s.run(ctx, req.Name, req.Args, req.Input)
+	resp := response{Output: output, ExitCode: code}
+	if err != nil {
+		resp.ExitCode = -1
+		resp.Error = err.Error()
+	}
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+func runCommand(ctx context.Context, name string, args []string, input []byte) ([]byte, int, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if len(input) > 0 {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return output, exitErr.ExitCode(), nil
+	}
+	return output, 0, err
+}
@@ -0,0 +1,237 @@
+package privhelper
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"split-vpn-webui/internal/systemd"
+	"split-vpn-webui/internal/vpn"
+)
+
+// Unit operations the helper carries out itself instead of running a
+// command.
+const (
+	opWriteVPNUnit  = "write-vpn-unit"
+	opRemoveVPNUnit = "remove-vpn-unit"
+	opLinkUnit      = "link-unit"
+)
+
+// unitInstaller writes, removes and links canonical units;
+// *systemd.Manager is one.
+type unitInstaller interface {
+	WriteUnit(unitName, content string) error
+	RemoveUnit(unitName string) error
+	LinkUnit(unitName string) error
+}
+
+// Units installs VPN units for the web UI. Each unit is rendered from a
+// validated vpn.UnitSpec and starts the tunnel from root-owned copies of
+// the VPN's files, so root never runs or reads anything the web UI can
+// still change.
+type Units struct {
+	dataDir  string
+	unitsDir string
+	vpnsDir  string
+	systemd  unitInstaller
+}
+
+// NewUnits returns the installer for the units directory in dataDir.
+func NewUnits(dataDir string) *Units {
+	return newUnits(dataDir, systemd.NewManager(dataDir))
+}
+
+func newUnits(dataDir string, installer unitInstaller) *Units {
+	unitsDir := filepath.Join(dataDir, "units")
+	return &Units{
+		dataDir:  dataDir,
+		unitsDir: unitsDir,
+		vpnsDir:  filepath.Join(unitsDir, "vpns"),
+		systemd:  installer,
+	}
+}
+
+// Write validates spec and the tunnel config, installs copies of the files
+// the tunnel needs and writes the unit that runs from them.
+func (u *Units) Write(spec vpn.UnitSpec, files map[string][]byte) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	config, ok := files[spec.ConfigFile]
+	if !ok {
+		return fmt.Errorf("config file %s is missing", spec.ConfigFile)
+	}
+	required, err := vpn.ValidateTunnelConfig(spec.Type, string(config))
+	if err != nil {
+		return err
+	}
+	install := map[string][]byte{spec.ConfigFile: config}
+	if conf, ok := files["vpn.conf"]; ok {
+		install["vpn.conf"] = conf
+	}
+	for _, name := range required {
+		content, ok := files[name]
+		if !ok {
+			return fmt.Errorf("supporting file %s is missing", name)
+		}
+		install[name] = content
+	}
+	content, err := vpn.RenderUnit(spec, u.dataDir, u.vpnsDir)
+	if err != nil {
+		return err
+	}
+	if err := u.installFiles(spec.Name, install); err != nil {
+		return err
+	}
+	unitName := vpnUnitName(spec.Name)
+	if existing, err := os.ReadFile(filepath.Join(u.unitsDir, unitName)); err == nil && string(existing) == content {
+		return u.systemd.LinkUnit(unitName)
+	}
+	return u.systemd.WriteUnit(unitName, content)
+}
+
+// Remove removes a VPN's unit and its installed files.
+func (u *Units) Remove(name string) error {
+	if err := vpn.ValidateName(name); err != nil {
+		return err
+	}
+	if err := u.systemd.RemoveUnit(vpnUnitName(name)); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(u.vpnsDir, name))
+}
+
+// Link restores the symlink of a managed canonical unit. Only unit files
+// that root owns and alone can write are linked.
+func (u *Units) Link(unitName string) error {
+	if !managedUnitPattern.MatchString(unitName) || strings.TrimSuffix(unitName, ".service") == updaterUnit {
+		return fmt.Errorf("unit %q is not managed by split-vpn-webui", unitName)
+	}
+	info, err := checkRootOwned(filepath.Join(u.unitsDir, unitName), false)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("unit file %s is not a regular file", unitName)
+	}
+	return u.systemd.LinkUnit(unitName)
+}
+
+// installFiles replaces a VPN's installed copy, staging the new one beside
+// it so a unit never starts from a half-written directory.
+func (u *Units) installFiles(name string, files map[string][]byte) error {
+	if err := os.MkdirAll(u.vpnsDir, 0o700); err != nil {
+		return err
+	}
+	if err := os.Chmod(u.vpnsDir, 0o700); err != nil {
+		return err
+	}
+	staging := filepath.Join(u.vpnsDir, "."+name+".new")
+	if err := os.RemoveAll(staging); err != nil {
+		return err
+	}
+	if err := os.Mkdir(staging, 0o700); err != nil {
+		return err
+	}
+	for fileName, content := range files {
+		if err := os.WriteFile(filepath.Join(staging, fileName), content, 0o600); err != nil {
+			_ = os.RemoveAll(staging)
+			return err
+		}
+	}
+	dir := filepath.Join(u.vpnsDir, name)
+	if err := os.RemoveAll(dir); err != nil {
+		_ = os.RemoveAll(staging)
+		return err
+	}
+	return os.Rename(staging, dir)
+}
+
+// handleUnitRequest performs a unit operation. It reports false for
+// requests that name a command instead.
+func (s *Server) handleUnitRequest(req request) (bool, error) {
+	switch req.Name {
+	case opWriteVPNUnit, opRemoveVPNUnit, opLinkUnit:
+	default:
+		return false, nil
+	}
+	if s.units == nil {
+		return true, errors.New("unit operations are not available")
+	}
+	if req.Name == opWriteVPNUnit {
+		if req.Unit == nil {
+			return true, errors.New("unit spec is required")
+		}
+		return true, s.units.Write(*req.Unit, req.Files)
+	}
+	if len(req.Args) != 1 {
+		return true, errors.New("expected one name")
+	}
+	if req.Name == opRemoveVPNUnit {
+		return true, s.units.Remove(req.Args[0])
+	}
+	return true, s.units.Link(req.Args[0])
+}
+
+// CheckInstall verifies that the web UI cannot change what the helper runs
+// as root. The data directory, the running binary, the one units start and
+// everything under the units directory must be owned by root and writable
+// only by root. Directories holding a binary may still be writable by the
+// web UI's group when the sticky bit is set, which stops it from renaming
+// or replacing root's entries.
+func CheckInstall(dataDir, binary string) error {
+	binaries := []string{binary}
+	if installed := filepath.Join(dataDir, "split-vpn-webui"); installed != binary {
+		if _, err := os.Lstat(installed); err == nil {
+			binaries = append(binaries, installed)
+		}
+	}
+	if _, err := checkRootOwned(dataDir, true); err != nil {
+		return err
+	}
+	for _, path := range binaries {
+		if _, err := checkRootOwned(filepath.Dir(path), true); err != nil {
+			return err
+		}
+		if _, err := checkRootOwned(path, false); err != nil {
+			return err
+		}
+	}
+	unitsDir := filepath.Join(dataDir, "units")
+	if err := os.MkdirAll(unitsDir, 0o755); err != nil {
+		return err
+	}
+	return filepath.WalkDir(unitsDir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		_, err = checkRootOwned(path, false)
+		return err
+	})
+}
+
+func checkRootOwned(path string, stickyOK bool) (os.FileInfo, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	uid, ok := fileOwner(info)
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("cannot read the owner of %s", path)
+	case uid != 0:
+		return nil, fmt.Errorf("%s must be owned by root, not uid %d", path, uid)
+	case info.Mode()&os.ModeSymlink != 0:
+		return nil, fmt.Errorf("%s must not be a symlink", path)
+	case info.Mode().Perm()&0o022 != 0 && !(stickyOK && info.Mode()&os.ModeSticky != 0):
+		return nil, fmt.Errorf("%s must not be writable by group or others", path)
+	}
+	return info, nil
+}
+
+func vpnUnitName(name string) string {
+	return "svpn-" + name + ".service"
+}
@@ -0,0 +1,235 @@
+package privhelper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+type testInstaller struct {
+	unitsDir string
+	writes   int
+	links    []string
+	removed  []string
+}
+
+func (i *testInstaller) WriteUnit(unitName, content string) error {
+	i.writes++
+	if err := os.MkdirAll(i.unitsDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(i.unitsDir, unitName), []byte(content), 0o644)
+}
+
+func (i *testInstaller) RemoveUnit(unitName string) error {
+	i.removed = append(i.removed, unitName)
+	return nil
+}
+
+func (i *testInstaller) LinkUnit(unitName string) error {
+	i.links = append(i.links, unitName)
+	return nil
+}
+
+const testWireGuardConfig = "[Interface]\nPrivateKey = test\nAddress = 10.0.0.2/32\n[Peer]\nPublicKey = peer\nAllowedIPs = 0.0.0.0/0\nEndpoint = office:51820\n"
+
+func newTestUnits(t *testing.T) (*Units, *testInstaller) {
+	t.Helper()
+	dataDir := t.TempDir()
+	installer := &testInstaller{unitsDir: filepath.Join(dataDir, "units")}
+	return newUnits(dataDir, installer), installer
+}
+
+func testSpec() vpn.UnitSpec {
+	return vpn.UnitSpec{Name: "wg-office", Type: "wireguard", ConfigFile: "wg-sv-office.conf", Interface: "wg-sv-office"}
+}
+
+func TestUnitsWriteInstallsCopiesTheUnitRunsFrom(t *testing.T) {
+	units, installer := newTestUnits(t)
+	files := map[string][]byte{
+		"wg-sv-office.conf": []byte(testWireGuardConfig),
+		"vpn.conf":          []byte("VPN_TYPE=external\n"),
+		"notes.txt":         []byte("not needed by the tunnel"),
+	}
+	if err := units.Write(testSpec(), files); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	copyDir := filepath.Join(units.vpnsDir, "wg-office")
+	entries, err := os.ReadDir(copyDir)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected the config and vpn.conf to be installed, got %v: %v", entries, err)
+	}
+	info, err := os.Stat(filepath.Join(copyDir, "wg-sv-office.conf"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("unexpected installed config %v: %v", info, err)
+	}
+	unit, err := os.ReadFile(filepath.Join(units.unitsDir, "svpn-wg-office.service"))
+	if err != nil || !strings.Contains(string(unit), filepath.Join(copyDir, "wg-sv-office.conf")) {
+		t.Fatalf("unit does not run from the installed copy:\n%s (%v)", unit, err)
+	}
+
+	// An unchanged unit is only relinked.
+	if err := units.Write(testSpec(), files); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if installer.writes != 1 || len(installer.links) != 1 {
+		t.Fatalf("expected one write and one link, got %d writes and links %v", installer.writes, installer.links)
+	}
+}
+
+func TestUnitsWriteRejectsUnsafeTunnels(t *testing.T) {
+	units, installer := newTestUnits(t)
+	hook := strings.Replace(testWireGuardConfig, "[Peer]", "PostUp = cp /bin/sh /tmp/sh\n[Peer]", 1)
+	if err := units.Write(testSpec(), map[string][]byte{"wg-sv-office.conf": []byte(hook)}); err == nil {
+		t.Fatalf("expected PostUp hook to be rejected")
+	}
+	ovpn := vpn.UnitSpec{Name: "ovpn-office", Type: "openvpn", ConfigFile: "office.ovpn", Interface: "tun0"}
+	config := []byte("client\nremote 198.51.100.1 1194\ndev tun\nca ca.crt\n")
+	if err := units.Write(ovpn, map[string][]byte{"office.ovpn": config}); err == nil {
+		t.Fatalf("expected missing supporting file to be rejected")
+	}
+	spec := testSpec()
+	spec.ConfigFile = "../../../etc/shadow"
+	if err := units.Write(spec, map[string][]byte{spec.ConfigFile: []byte(testWireGuardConfig)}); err == nil {
+		t.Fatalf("expected config path outside the vpn directory to be rejected")
+	}
+	if installer.writes != 0 {
+		t.Fatalf("rejected tunnels wrote %d units", installer.writes)
+	}
+	if _, err := os.Stat(units.vpnsDir); !os.IsNotExist(err) {
+		t.Fatalf("rejected tunnels installed files: %v", err)
+	}
+}
+
+func TestUnitsRemoveDropsInstalledCopy(t *testing.T) {
+	units, installer := newTestUnits(t)
+	if err := units.Write(testSpec(), map[string][]byte{"wg-sv-office.conf": []byte(testWireGuardConfig)}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := units.Remove("wg-office"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if len(installer.removed) != 1 || installer.removed[0] != "svpn-wg-office.service" {
+		t.Fatalf("unexpected removals %v", installer.removed)
+	}
+	if _, err := os.Stat(filepath.Join(units.vpnsDir, "wg-office")); !os.IsNotExist(err) {
+		t.Fatalf("installed copy left behind: %v", err)
+	}
+	if err := units.Remove("../units"); err == nil {
+		t.Fatalf("expected invalid name to be rejected")
+	}
+}
+
+func TestUnitsLinkOnlyRootOnlyUnitFiles(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Getuid() != 0 {
+		t.Skip("needs root on linux to own the unit files")
+	}
+	units, installer := newTestUnits(t)
+	if err := os.MkdirAll(units.unitsDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	unitPath := filepath.Join(units.unitsDir, "svpn-wg-office.service")
+	if err := os.WriteFile(unitPath, []byte("[Unit]\n"), 0o644); err != nil {
+		t.Fatalf("write unit: %v", err)
+	}
+	if err := units.Link("svpn-wg-office.service"); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if err := os.Chmod(unitPath, 0o666); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if err := units.Link("svpn-wg-office.service"); err == nil {
+		t.Fatalf("expected world-writable unit to be refused")
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(units.unitsDir, "svpn-evil.service")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	for _, unit := range []string{"svpn-evil.service", "split-vpn-webui-updater.service", "ssh.service"} {
+		if err := units.Link(unit); err == nil {
+			t.Errorf("expected %s to be refused", unit)
+		}
+	}
+	if len(installer.links) != 1 {
+		t.Fatalf("unexpected links %v", installer.links)
+	}
+}
+
+func TestCheckInstallRequiresRootOnlyFiles(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Getuid() != 0 {
+		t.Skip("needs root on linux to own the install")
+	}
+	dataDir := t.TempDir()
+	binary := filepath.Join(dataDir, "split-vpn-webui")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write binary: %v", err)
+	}
+	if err := CheckInstall(dataDir, binary); err != nil {
+		t.Fatalf("expected root-only install to pass: %v", err)
+	}
+
+	// A group-writable data directory needs the sticky bit.
+	if err := os.Chmod(dataDir, 0o775); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if err := CheckInstall(dataDir, binary); err == nil {
+		t.Fatalf("expected group-writable data directory to be refused")
+	}
+	if err := os.Chmod(dataDir, 0o775|os.ModeSticky); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if err := CheckInstall(dataDir, binary); err != nil {
+		t.Fatalf("expected sticky data directory to pass: %v", err)
+	}
+
+	unit := filepath.Join(dataDir, "units", "svpn-wg-office.service")
+	if err := os.WriteFile(unit, []byte("[Unit]\n"), 0o644); err != nil {
+		t.Fatalf("write unit: %v", err)
+	}
+	if err := os.Chown(unit, 1000, 1000); err != nil {
+		t.Fatalf("chown: %v", err)
+	}
+	if err := CheckInstall(dataDir, binary); err == nil {
+		t.Fatalf("expected unit owned by another user to be refused")
+	}
+	if err := os.Remove(unit); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := os.Chmod(binary, 0o775); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if err := CheckInstall(dataDir, binary); err == nil {
+		t.Fatalf("expected group-writable binary to be refused")
+	}
+}
+
+func TestClientWritesUnitsThroughHelper(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only read on linux")
+	}
+	units, installer := newTestUnits(t)
+	client := startTestServerWithUnits(t, []uint32{uint32(os.Getuid())}, func(context.Context, string, []string, []byte) ([]byte, int, error) {
+		t.Fatalf("unit operations must not run commands")
+		return nil, 0, nil
+	}, units)
+	if err := client.WriteVPNUnit(testSpec(), map[string][]byte{"wg-sv-office.conf": []byte(testWireGuardConfig)}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if installer.writes != 1 {
+		t.Fatalf("expected the helper to write the unit, got %d writes", installer.writes)
+	}
+	hook := strings.Replace(testWireGuardConfig, "[Peer]", "PreUp = id\n[Peer]", 1)
+	if err := client.WriteVPNUnit(testSpec(), map[string][]byte{"wg-sv-office.conf": []byte(hook)}); err == nil || !strings.Contains(err.Error(), "PreUp") {
+		t.Fatalf("expected hook to be refused, got %v", err)
+	}
+	if err := client.RemoveVPNUnit("wg-office"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if len(installer.removed) != 1 {
+		t.Fatalf("unexpected removals %v", installer.removed)
+	}
+}
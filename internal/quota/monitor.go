@@ -0,0 +1,355 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Event kinds.
+const (
+	KindWarning  = "quota_warning"
+	KindExceeded = "quota_exceeded"
+	KindReset    = "quota_reset"
+)
+
+const checkInterval = time.Minute
+
+// Sample is the cumulative transfer of one VPN interface as read by the stats
+// collector. Counters restart when the interface is recreated.
+type Sample struct {
+	VPN       string
+	Available bool
+	RxBytes   uint64
+	TxBytes   uint64
+}
+
+// SampleSource returns the current counters of every VPN.
+type SampleSource func() []Sample
+
+// Event is a crossed threshold or the start of a new period.
+type Event struct {
+	VPN         string    `json:"vpn"`
+	Kind        string    `json:"kind"`
+	Detail      string    `json:"detail"`
+	At          time.Time `json:"at"`
+	Percent     int       `json:"percent"`
+	UsedBytes   uint64    `json:"usedBytes"`
+	LimitBytes  uint64    `json:"limitBytes"`
+	Action      string    `json:"action,omitempty"`
+	FailoverVPN string    `json:"failoverVpn,omitempty"`
+}
+
+// Status is a quota with the usage of its current period.
+type Status struct {
+	Quota
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Usage       Usage     `json:"usage"`
+	UsedBytes   uint64    `json:"usedBytes"`
+	Percent     float64   `json:"percent"`
+	Exceeded    bool      `json:"exceeded"`
+}
+
+type counters struct {
+	rx uint64
+	tx uint64
+}
+
+// Monitor adds counter deltas to each VPN's current period and raises events
+// when a quota threshold is crossed.
+type Monitor struct {
+	store   *Store
+	samples SampleSource
+	now     func() time.Time
+
+	checkMu sync.Mutex
+	last    map[string]counters
+	pending map[string]counters
+	periods map[string]time.Time
+
+	mu         sync.Mutex
+	handler    func(Event)
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor that counts samples into store.
+func NewMonitor(store *Store, samples SampleSource) (*Monitor, error) {
+	if store == nil {
+		return nil, fmt.Errorf("quota store is required")
+	}
+	if samples == nil {
+		return nil, fmt.Errorf("sample source is required")
+	}
+	return &Monitor{
+		store:   store,
+		samples: samples,
+		now:     time.Now,
+		last:    make(map[string]counters),
+		pending: make(map[string]counters),
+		periods: make(map[string]time.Time),
+	}, nil
+}
+
+// Store returns the backing store.
+func (m *Monitor) Store() *Store {
+	return m.store
+}
+
+// SetHandler registers a callback for raised events.
+func (m *Monitor) SetHandler(handler func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// Start launches the periodic check loop.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.started = true
+	m.loopCancel = cancel
+	m.mu.Unlock()
+
+	m.loopWG.Add(1)
+	go func() {
+		defer m.loopWG.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Count what the last interval carried before exiting.
+				_ = m.Check(context.Background())
+				return
+			case <-ticker.C:
+				_ = m.Check(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the periodic loop after a final check.
+func (m *Monitor) Stop() error {
+	m.mu.Lock()
+	loopCancel := m.loopCancel
+	m.started = false
+	m.loopCancel = nil
+	m.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	m.loopWG.Wait()
+	return nil
+}
+
+// Check counts the transfer since the previous check and evaluates every
+// quota. Transfer that could not be stored is retried on the next check.
+func (m *Monitor) Check(ctx context.Context) error {
+	m.checkMu.Lock()
+	defer m.checkMu.Unlock()
+
+	now := m.now()
+	for _, sample := range m.samples() {
+		if !sample.Available {
+			continue
+		}
+		current := counters{rx: sample.RxBytes, tx: sample.TxBytes}
+		previous, seen := m.last[sample.VPN]
+		m.last[sample.VPN] = current
+		if !seen {
+			// The first sample only sets the baseline: the counters are
+			// cumulative and may include transfer from before this process
+			// started, which earlier runs have already counted.
+			continue
+		}
+		// A counter that went backwards was reset; everything it shows now
+		// is new transfer.
+		delta := current
+		if current.rx >= previous.rx {
+			delta.rx = current.rx - previous.rx
+		}
+		if current.tx >= previous.tx {
+			delta.tx = current.tx - previous.tx
+		}
+		pending := m.pending[sample.VPN]
+		pending.rx += delta.rx
+		pending.tx += delta.tx
+		m.pending[sample.VPN] = pending
+	}
+
+	quotas, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	resetDays := make(map[string]int, len(quotas))
+	for _, quota := range quotas {
+		resetDays[quota.VPN] = quota.ResetDay
+	}
+	var firstErr error
+	for vpn, pending := range m.pending {
+		if pending.rx == 0 && pending.tx == 0 {
+			delete(m.pending, vpn)
+			continue
+		}
+		if err := m.store.AddUsage(ctx, vpn, PeriodStart(now, resetDays[vpn]), pending.rx, pending.tx); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		delete(m.pending, vpn)
+	}
+
+	events := make([]Event, 0)
+	for _, quota := range quotas {
+		quotaEvents, err := m.evaluate(ctx, quota, now)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		events = append(events, quotaEvents...)
+	}
+
+	m.mu.Lock()
+	handler := m.handler
+	m.mu.Unlock()
+	if handler != nil {
+		for _, event := range events {
+			handler(event)
+		}
+	}
+	return firstErr
+}
+
+func (m *Monitor) evaluate(ctx context.Context, quota Quota, now time.Time) ([]Event, error) {
+	start := PeriodStart(now, quota.ResetDay)
+	events := make([]Event, 0)
+	if previous, ok := m.periods[quota.VPN]; ok && !previous.Equal(start) {
+		if usage, err := m.store.Usage(ctx, quota.VPN, previous); err == nil && !usage.ExceededAt.IsZero() {
+			events = append(events, Event{
+				VPN:        quota.VPN,
+				Kind:       KindReset,
+				Detail:     fmt.Sprintf("new quota period started on %s; the %s action is not undone automatically", start.Format("2006-01-02"), quota.Action),
+				At:         now,
+				LimitBytes: quota.LimitBytes,
+				Action:     quota.Action,
+			})
+		}
+	}
+	m.periods[quota.VPN] = start
+
+	usage, err := m.store.Usage(ctx, quota.VPN, start)
+	if err != nil {
+		return events, err
+	}
+	used := usage.TotalBytes()
+	percent := int(used * 100 / quota.LimitBytes)
+	warned := usage.WarnedPercent
+	for _, threshold := range quota.WarnPercents {
+		if percent >= threshold && threshold > warned {
+			warned = threshold
+		}
+	}
+	exceededAt := usage.ExceededAt
+	base := Event{
+		VPN:         quota.VPN,
+		At:          now,
+		Percent:     percent,
+		UsedBytes:   used,
+		LimitBytes:  quota.LimitBytes,
+		Action:      quota.Action,
+		FailoverVPN: quota.FailoverVPN,
+	}
+	switch {
+	case used >= quota.LimitBytes && exceededAt.IsZero():
+		exceededAt = now
+		event := base
+		event.Kind = KindExceeded
+		event.Detail = fmt.Sprintf("monthly quota of %s used (%s)", formatBytes(quota.LimitBytes), formatBytes(used))
+		events = append(events, event)
+	case warned > usage.WarnedPercent && exceededAt.IsZero():
+		event := base
+		event.Kind = KindWarning
+		event.Detail = fmt.Sprintf("%d%% of the monthly quota used (%s of %s)", percent, formatBytes(used), formatBytes(quota.LimitBytes))
+		events = append(events, event)
+	default:
+		return events, nil
+	}
+	if err := m.store.MarkNotified(ctx, quota.VPN, start, warned, exceededAt); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Status reports every quota with its current period's usage, including
+// transfer counted since the last check but not stored yet.
+func (m *Monitor) Status(ctx context.Context) ([]Status, error) {
+	quotas, err := m.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	m.checkMu.Lock()
+	pending := make(map[string]counters, len(m.pending))
+	for vpn, value := range m.pending {
+		pending[vpn] = value
+	}
+	m.checkMu.Unlock()
+
+	statuses := make([]Status, 0, len(quotas))
+	for _, quota := range quotas {
+		start := PeriodStart(now, quota.ResetDay)
+		usage, err := m.store.Usage(ctx, quota.VPN, start)
+		if err != nil {
+			return nil, err
+		}
+		usage.RxBytes += pending[quota.VPN].rx
+		usage.TxBytes += pending[quota.VPN].tx
+		used := usage.TotalBytes()
+		statuses = append(statuses, Status{
+			Quota:       quota,
+			PeriodStart: start,
+			PeriodEnd:   PeriodEnd(start),
+			Usage:       usage,
+			UsedBytes:   used,
+			Percent:     float64(used) * 100 / float64(quota.LimitBytes),
+			Exceeded:    !usage.ExceededAt.IsZero(),
+		})
+	}
+	return statuses, nil
+}
+
+// ResetUsage clears the current period of a VPN, e.g. after the provider
+// topped up the allowance.
+func (m *Monitor) ResetUsage(ctx context.Context, vpn string) error {
+	quota, err := m.store.Get(ctx, vpn)
+	if err != nil {
+		return err
+	}
+	m.checkMu.Lock()
+	defer m.checkMu.Unlock()
+	delete(m.pending, quota.VPN)
+	return m.store.ResetUsage(ctx, quota.VPN, PeriodStart(m.now(), quota.ResetDay))
+}
+
+func formatBytes(value uint64) string {
+	const unit = 1000
+	if value < unit {
+		return fmt.Sprintf("%d B", value)
+	}
+	div, exp := uint64(unit), 0
+	for n := value / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(value)/float64(div), "kMGTP"[exp])
+}
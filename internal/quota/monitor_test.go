@@ -0,0 +1,96 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMonitorCountsDeltasAndRaisesThresholdsOnce(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	if _, err := store.Set(ctx, Quota{VPN: "wg-fra", LimitBytes: 1000, WarnPercents: []int{50, 80}, Action: ActionStop}); err != nil {
+		t.Fatalf("set quota: %v", err)
+	}
+	samples := []Sample{{VPN: "wg-fra", Available: true, RxBytes: 300, TxBytes: 100}}
+	monitor, err := NewMonitor(store, func() []Sample { return samples })
+	if err != nil {
+		t.Fatalf("new monitor: %v", err)
+	}
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+	events := make([]Event, 0)
+	monitor.SetHandler(func(event Event) { events = append(events, event) })
+
+	// The first sample is the baseline, not usage: the kernel counters
+	// already hold transfer from before the process started.
+	if err := monitor.Check(ctx); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	statuses, err := monitor.Status(ctx)
+	if err != nil || len(statuses) != 1 || statuses[0].UsedBytes != 0 {
+		t.Fatalf("expected the first sample not to count, got %+v err=%v", statuses, err)
+	}
+
+	samples[0].RxBytes, samples[0].TxBytes = 600, 200
+	_ = monitor.Check(ctx)
+	if len(events) != 0 {
+		t.Fatalf("expected no event at 40%%, got %+v", events)
+	}
+
+	// 450 new bytes: 85% crosses both warnings but raises one event.
+	samples[0].RxBytes = 1050
+	_ = monitor.Check(ctx)
+	if len(events) != 1 || events[0].Kind != KindWarning || events[0].Percent != 85 {
+		t.Fatalf("expected one warning at 85%%, got %+v", events)
+	}
+	_ = monitor.Check(ctx)
+	if len(events) != 1 {
+		t.Fatalf("expected the warning not to repeat, got %+v", events)
+	}
+
+	// The interface restarted: counters went back to zero and then grew.
+	samples[0] = Sample{VPN: "wg-fra", Available: true, RxBytes: 200}
+	_ = monitor.Check(ctx)
+	if len(events) != 2 || events[1].Kind != KindExceeded || events[1].Action != ActionStop || events[1].UsedBytes != 1050 {
+		t.Fatalf("expected the quota to be exceeded, got %+v", events)
+	}
+	_ = monitor.Check(ctx)
+	if len(events) != 2 {
+		t.Fatalf("expected the exceeded event not to repeat, got %+v", events)
+	}
+
+	now = time.Date(2026, 4, 1, 0, 5, 0, 0, time.UTC)
+	_ = monitor.Check(ctx)
+	if len(events) != 3 || events[2].Kind != KindReset {
+		t.Fatalf("expected a new period event, got %+v", events)
+	}
+	statuses, err = monitor.Status(ctx)
+	if err != nil || len(statuses) != 1 || statuses[0].UsedBytes != 0 || statuses[0].Exceeded {
+		t.Fatalf("expected an empty new period, got %+v err=%v", statuses, err)
+	}
+}
+
+func TestMonitorResetUsageClearsCurrentPeriod(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	if _, err := store.Set(ctx, Quota{VPN: "wg-fra", LimitBytes: 100}); err != nil {
+		t.Fatalf("set quota: %v", err)
+	}
+	samples := []Sample{{VPN: "wg-fra", Available: true, RxBytes: 50}}
+	monitor, _ := NewMonitor(store, func() []Sample { return samples })
+	_ = monitor.Check(ctx)
+	samples[0].RxBytes = 200
+	_ = monitor.Check(ctx)
+	statuses, _ := monitor.Status(ctx)
+	if len(statuses) != 1 || !statuses[0].Exceeded {
+		t.Fatalf("expected an exceeded quota, got %+v", statuses)
+	}
+	if err := monitor.ResetUsage(ctx, "wg-fra"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	statuses, _ = monitor.Status(ctx)
+	if statuses[0].UsedBytes != 0 || statuses[0].Exceeded {
+		t.Fatalf("expected a cleared period, got %+v", statuses)
+	}
+}
@@ -0,0 +1,349 @@
+// Package quota tracks cumulative transfer per VPN against user-set monthly
+// quotas, for providers that cap bandwidth. Usage is counted per billing
+// period from the stats collector's interface counters; crossing a warning
+// threshold or the quota itself raises an event, and an exhausted quota can
+// stop the tunnel or move its routing groups to another VPN.
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions taken when a quota is exhausted.
+const (
+	ActionNone     = "none"
+	ActionStop     = "stop"
+	ActionFailover = "failover"
+)
+
+const (
+	// MaxResetDay keeps the reset day valid in every month.
+	MaxResetDay = 28
+	// keepPeriods bounds the usage rows kept per VPN.
+	keepPeriods = 24
+)
+
+// ErrNotFound is returned when a VPN has no quota.
+var ErrNotFound = errors.New("quota not found")
+
+// Quota is the monthly allowance of one VPN.
+type Quota struct {
+	VPN        string `json:"vpn"`
+	LimitBytes uint64 `json:"limitBytes"`
+	// WarnPercents are the usage percentages that raise a warning, ascending.
+	WarnPercents []int `json:"warnPercents"`
+	// ResetDay is the day of the month a new period starts (1-28).
+	ResetDay int    `json:"resetDay"`
+	Action   string `json:"action"`
+	// FailoverVPN receives the routing groups of the VPN when Action is
+	// ActionFailover.
+	FailoverVPN string    `json:"failoverVpn,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Usage is the transfer of one VPN in one period.
+type Usage struct {
+	VPN           string    `json:"vpn"`
+	PeriodStart   time.Time `json:"periodStart"`
+	RxBytes       uint64    `json:"rxBytes"`
+	TxBytes       uint64    `json:"txBytes"`
+	WarnedPercent int       `json:"warnedPercent"`
+	ExceededAt    time.Time `json:"exceededAt,omitzero"`
+}
+
+// TotalBytes is the transfer counted against the quota.
+func (u Usage) TotalBytes() uint64 {
+	return u.RxBytes + u.TxBytes
+}
+
+// Normalize validates q and fills defaults.
+func (q Quota) Normalize() (Quota, error) {
+	q.VPN = strings.TrimSpace(q.VPN)
+	if q.VPN == "" {
+		return Quota{}, fmt.Errorf("quota vpn is required")
+	}
+	if q.LimitBytes == 0 {
+		return Quota{}, fmt.Errorf("quota limitBytes must be positive")
+	}
+	if q.ResetDay == 0 {
+		q.ResetDay = 1
+	}
+	if q.ResetDay < 1 || q.ResetDay > MaxResetDay {
+		return Quota{}, fmt.Errorf("quota resetDay must be between 1 and %d", MaxResetDay)
+	}
+	percents := make([]int, 0, len(q.WarnPercents))
+	seen := make(map[int]struct{}, len(q.WarnPercents))
+	for _, percent := range q.WarnPercents {
+		if percent < 1 || percent > 99 {
+			return Quota{}, fmt.Errorf("quota warnPercents must be between 1 and 99")
+		}
+		if _, ok := seen[percent]; ok {
+			continue
+		}
+		seen[percent] = struct{}{}
+		percents = append(percents, percent)
+	}
+	sort.Ints(percents)
+	q.WarnPercents = percents
+	q.Action = strings.ToLower(strings.TrimSpace(q.Action))
+	q.FailoverVPN = strings.TrimSpace(q.FailoverVPN)
+	switch q.Action {
+	case "", ActionNone:
+		q.Action = ActionNone
+		q.FailoverVPN = ""
+	case ActionStop:
+		q.FailoverVPN = ""
+	case ActionFailover:
+		if q.FailoverVPN == "" {
+			return Quota{}, fmt.Errorf("quota failoverVpn is required for the failover action")
+		}
+		if q.FailoverVPN == q.VPN {
+			return Quota{}, fmt.Errorf("quota failoverVpn must differ from the vpn")
+		}
+	default:
+		return Quota{}, fmt.Errorf("unknown quota action %q", q.Action)
+	}
+	return q, nil
+}
+
+// PeriodStart returns the start of the billing period containing now: local
+// midnight on resetDay of this month, or of last month before that day.
+func PeriodStart(now time.Time, resetDay int) time.Time {
+	if resetDay < 1 || resetDay > MaxResetDay {
+		resetDay = 1
+	}
+	start := time.Date(now.Year(), now.Month(), resetDay, 0, 0, 0, 0, now.Location())
+	if now.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
+
+// PeriodEnd returns the start of the period after the one starting at start.
+func PeriodEnd(start time.Time) time.Time {
+	return start.AddDate(0, 1, 0)
+}
+
+// Store persists quotas and usage in the vpn_quotas and vpn_usage tables.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a store backed by db.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	return &Store{db: db}, nil
+}
+
+// List returns every quota ordered by VPN name.
+func (s *Store) List(ctx context.Context) ([]Quota, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT vpn, limit_bytes, warn_percents, reset_day, action, failover_vpn, updated_at
+		FROM vpn_quotas ORDER BY vpn
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	quotas := make([]Quota, 0)
+	for rows.Next() {
+		quota, err := scanQuota(rows)
+		if err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, quota)
+	}
+	return quotas, rows.Err()
+}
+
+// Get returns the quota of a VPN, or ErrNotFound.
+func (s *Store) Get(ctx context.Context, vpn string) (Quota, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT vpn, limit_bytes, warn_percents, reset_day, action, failover_vpn, updated_at
+		FROM vpn_quotas WHERE vpn = ?
+	`, strings.TrimSpace(vpn))
+	quota, err := scanQuota(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Quota{}, ErrNotFound
+	}
+	return quota, err
+}
+
+// Set creates or replaces the quota of a VPN.
+func (s *Store) Set(ctx context.Context, quota Quota) (Quota, error) {
+	quota, err := quota.Normalize()
+	if err != nil {
+		return Quota{}, err
+	}
+	quota.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	percents := make([]string, 0, len(quota.WarnPercents))
+	for _, percent := range quota.WarnPercents {
+		percents = append(percents, strconv.Itoa(percent))
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO vpn_quotas (vpn, limit_bytes, warn_percents, reset_day, action, failover_vpn, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (vpn) DO UPDATE SET
+			limit_bytes = excluded.limit_bytes,
+			warn_percents = excluded.warn_percents,
+			reset_day = excluded.reset_day,
+			action = excluded.action,
+			failover_vpn = excluded.failover_vpn,
+			updated_at = excluded.updated_at
+	`, quota.VPN, int64(quota.LimitBytes), strings.Join(percents, ","), quota.ResetDay,
+		quota.Action, quota.FailoverVPN, quota.UpdatedAt.Unix()); err != nil {
+		return Quota{}, err
+	}
+	return quota, nil
+}
+
+// Delete removes the quota of a VPN. Its usage history is kept.
+func (s *Store) Delete(ctx context.Context, vpn string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM vpn_quotas WHERE vpn = ?`, strings.TrimSpace(vpn))
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddUsage adds transfer to a VPN's period and prunes its oldest periods.
+func (s *Store) AddUsage(ctx context.Context, vpn string, periodStart time.Time, rx, tx uint64) error {
+	vpn = strings.TrimSpace(vpn)
+	if vpn == "" {
+		return fmt.Errorf("usage vpn is required")
+	}
+	now := time.Now().UTC().Unix()
+	txn, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = txn.Rollback() }()
+	if _, err := txn.ExecContext(ctx, `
+		INSERT INTO vpn_usage (vpn, period_start, rx_bytes, tx_bytes, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (vpn, period_start) DO UPDATE SET
+			rx_bytes = rx_bytes + excluded.rx_bytes,
+			tx_bytes = tx_bytes + excluded.tx_bytes,
+			updated_at = excluded.updated_at
+	`, vpn, periodStart.Unix(), int64(rx), int64(tx), now); err != nil {
+		return err
+	}
+	if _, err := txn.ExecContext(ctx, `
+		DELETE FROM vpn_usage
+		WHERE vpn = ? AND period_start NOT IN (
+			SELECT period_start FROM vpn_usage WHERE vpn = ? ORDER BY period_start DESC LIMIT ?
+		)
+	`, vpn, vpn, keepPeriods); err != nil {
+		return fmt.Errorf("prune vpn usage: %w", err)
+	}
+	return txn.Commit()
+}
+
+// Usage returns a VPN's transfer in the period starting at periodStart; a
+// period without transfer is returned empty.
+func (s *Store) Usage(ctx context.Context, vpn string, periodStart time.Time) (Usage, error) {
+	usage := Usage{VPN: vpn, PeriodStart: periodStart}
+	var rx, tx, exceededAt int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT rx_bytes, tx_bytes, warned_percent, exceeded_at
+		FROM vpn_usage WHERE vpn = ? AND period_start = ?
+	`, vpn, periodStart.Unix()).Scan(&rx, &tx, &usage.WarnedPercent, &exceededAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return usage, nil
+	}
+	if err != nil {
+		return Usage{}, err
+	}
+	usage.RxBytes, usage.TxBytes = uint64(rx), uint64(tx)
+	if exceededAt > 0 {
+		usage.ExceededAt = time.Unix(exceededAt, 0).UTC()
+	}
+	return usage, nil
+}
+
+// History returns a VPN's recorded periods, newest first.
+func (s *Store) History(ctx context.Context, vpn string) ([]Usage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT period_start, rx_bytes, tx_bytes, warned_percent, exceeded_at
+		FROM vpn_usage WHERE vpn = ? ORDER BY period_start DESC
+	`, vpn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	history := make([]Usage, 0)
+	for rows.Next() {
+		var start, rx, tx, exceededAt int64
+		usage := Usage{VPN: vpn}
+		if err := rows.Scan(&start, &rx, &tx, &usage.WarnedPercent, &exceededAt); err != nil {
+			return nil, err
+		}
+		usage.PeriodStart = time.Unix(start, 0)
+		usage.RxBytes, usage.TxBytes = uint64(rx), uint64(tx)
+		if exceededAt > 0 {
+			usage.ExceededAt = time.Unix(exceededAt, 0).UTC()
+		}
+		history = append(history, usage)
+	}
+	return history, rows.Err()
+}
+
+// MarkNotified records the highest warning raised in a period and, when
+// exceededAt is non-zero, that the quota was exhausted.
+func (s *Store) MarkNotified(ctx context.Context, vpn string, periodStart time.Time, warnedPercent int, exceededAt time.Time) error {
+	var exceeded int64
+	if !exceededAt.IsZero() {
+		exceeded = exceededAt.Unix()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO vpn_usage (vpn, period_start, warned_percent, exceeded_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (vpn, period_start) DO UPDATE SET
+			warned_percent = excluded.warned_percent,
+			exceeded_at = excluded.exceeded_at
+	`, vpn, periodStart.Unix(), warnedPercent, exceeded, time.Now().UTC().Unix())
+	return err
+}
+
+// ResetUsage clears a VPN's transfer and notifications in a period.
+func (s *Store) ResetUsage(ctx context.Context, vpn string, periodStart time.Time) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM vpn_usage WHERE vpn = ? AND period_start = ?`, vpn, periodStart.Unix())
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanQuota(row rowScanner) (Quota, error) {
+	var (
+		quota     Quota
+		limit     int64
+		percents  string
+		updatedAt int64
+	)
+	if err := row.Scan(&quota.VPN, &limit, &percents, &quota.ResetDay, &quota.Action, &quota.FailoverVPN, &updatedAt); err != nil {
+		return Quota{}, err
+	}
+	quota.LimitBytes = uint64(limit)
+	quota.WarnPercents = make([]int, 0)
+	for _, field := range strings.Split(percents, ",") {
+		if percent, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+			quota.WarnPercents = append(quota.WarnPercents, percent)
+		}
+	}
+	quota.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return quota, nil
+}
@@ -0,0 +1,96 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/database"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "quota.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return store
+}
+
+func TestStoreSetNormalizesAndLists(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	saved, err := store.Set(ctx, Quota{VPN: "wg-fra", LimitBytes: 100e9, WarnPercents: []int{90, 75, 90}})
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if saved.ResetDay != 1 || saved.Action != ActionNone || len(saved.WarnPercents) != 2 || saved.WarnPercents[0] != 75 {
+		t.Fatalf("unexpected normalized quota %+v", saved)
+	}
+	if _, err := store.Set(ctx, Quota{VPN: "wg-sgp", LimitBytes: 1, Action: ActionFailover}); err == nil {
+		t.Fatalf("expected failover without a target to fail")
+	}
+	if _, err := store.Set(ctx, Quota{VPN: "wg-sgp", LimitBytes: 1, ResetDay: 31}); err == nil {
+		t.Fatalf("expected reset day 31 to fail")
+	}
+
+	quotas, err := store.List(ctx)
+	if err != nil || len(quotas) != 1 || quotas[0].VPN != "wg-fra" || quotas[0].WarnPercents[1] != 90 {
+		t.Fatalf("unexpected quotas %+v err=%v", quotas, err)
+	}
+	if err := store.Delete(ctx, "wg-fra"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "wg-fra"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStoreAccumulatesUsagePerPeriod(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+
+	for _, add := range []struct {
+		period time.Time
+		rx, tx uint64
+	}{{march, 100, 10}, {march, 50, 5}, {april, 7, 3}} {
+		if err := store.AddUsage(ctx, "wg-fra", add.period, add.rx, add.tx); err != nil {
+			t.Fatalf("add usage: %v", err)
+		}
+	}
+	usage, err := store.Usage(ctx, "wg-fra", march)
+	if err != nil || usage.RxBytes != 150 || usage.TxBytes != 15 {
+		t.Fatalf("unexpected march usage %+v err=%v", usage, err)
+	}
+	history, err := store.History(ctx, "wg-fra")
+	if err != nil || len(history) != 2 || history[0].TotalBytes() != 10 {
+		t.Fatalf("unexpected history %+v err=%v", history, err)
+	}
+}
+
+func TestPeriodStartFollowsResetDay(t *testing.T) {
+	for _, tc := range []struct {
+		now      time.Time
+		resetDay int
+		want     time.Time
+	}{
+		{time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), 1, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), 20, time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), 10, time.Date(2025, 12, 10, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC), 20, time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)},
+	} {
+		if got := PeriodStart(tc.now, tc.resetDay); !got.Equal(tc.want) {
+			t.Fatalf("PeriodStart(%s, %d) = %s, want %s", tc.now, tc.resetDay, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAbuseIPDBBaseURL = "https://api.abuseipdb.com/api/v2"
+	// DefaultAbuseIPDBMinScore is the abuse confidence score at or above which
+	// an address is flagged when no explicit threshold is configured.
+	DefaultAbuseIPDBMinScore = 50
+	abuseIPDBMaxAgeDays      = 90
+)
+
+// HTTPDoer allows tests to stub HTTP transport.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// AbuseIPDB queries the AbuseIPDB v2 check endpoint.
+type AbuseIPDB struct {
+	apiKey   string
+	minScore int
+	baseURL  string
+	client   HTTPDoer
+}
+
+type abuseIPDBResponse struct {
+	Data struct {
+		AbuseConfidenceScore int    `json:"abuseConfidenceScore"`
+		TotalReports         int    `json:"totalReports"`
+		UsageType            string `json:"usageType"`
+		ISP                  string `json:"isp"`
+		IsWhitelisted        *bool  `json:"isWhitelisted"`
+	} `json:"data"`
+	Errors []struct {
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// NewAbuseIPDB creates an AbuseIPDB source. minScore <= 0 selects the default.
+func NewAbuseIPDB(apiKey string, minScore int, doer HTTPDoer) *AbuseIPDB {
+	if minScore <= 0 || minScore > 100 {
+		minScore = DefaultAbuseIPDBMinScore
+	}
+	if doer == nil {
+		doer = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &AbuseIPDB{
+		apiKey:   strings.TrimSpace(apiKey),
+		minScore: minScore,
+		baseURL:  defaultAbuseIPDBBaseURL,
+		client:   doer,
+	}
+}
+
+// Name implements Source.
+func (a *AbuseIPDB) Name() string { return "abuseipdb" }
+
+// Lookup implements Source.
+func (a *AbuseIPDB) Lookup(ctx context.Context, addr netip.Addr) (Verdict, error) {
+	query := url.Values{}
+	query.Set("ipAddress", addr.String())
+	query.Set("maxAgeInDays", fmt.Sprintf("%d", abuseIPDBMaxAgeDays))
+	endpoint := strings.TrimRight(a.baseURL, "/") + "/check?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Key", a.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "split-vpn-webui-reputation")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return Verdict{}, err
+	}
+	var parsed abuseIPDBResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return Verdict{}, fmt.Errorf("abuseipdb: decode response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(parsed.Errors) > 0 && parsed.Errors[0].Detail != "" {
+			return Verdict{}, fmt.Errorf("abuseipdb: %s", parsed.Errors[0].Detail)
+		}
+		return Verdict{}, fmt.Errorf("abuseipdb: unexpected status %d", resp.StatusCode)
+	}
+	score := parsed.Data.AbuseConfidenceScore
+	whitelisted := parsed.Data.IsWhitelisted != nil && *parsed.Data.IsWhitelisted
+	detailParts := []string{fmt.Sprintf("%d reports", parsed.Data.TotalReports)}
+	if isp := strings.TrimSpace(parsed.Data.ISP); isp != "" {
+		detailParts = append(detailParts, isp)
+	}
+	if usage := strings.TrimSpace(parsed.Data.UsageType); usage != "" {
+		detailParts = append(detailParts, usage)
+	}
+	return Verdict{
+		Listed: !whitelisted && score >= a.minScore,
+		Score:  score,
+		Detail: strings.Join(detailParts, ", "),
+	}, nil
+}
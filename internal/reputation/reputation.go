@@ -0,0 +1,251 @@
+package reputation
+
+import (
+	"context"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheTTL      = 24 * time.Hour
+	defaultErrorCacheTTL = 15 * time.Minute
+	defaultLookupTimeout = 10 * time.Second
+	defaultMaxEntries    = 4096
+	// defaultConcurrency is deliberately low: public reputation APIs have
+	// small free-tier quotas and the flow inspector can surface many IPs.
+	defaultConcurrency = 2
+)
+
+// Source looks up one address against a single reputation provider.
+type Source interface {
+	Name() string
+	Lookup(ctx context.Context, addr netip.Addr) (Verdict, error)
+}
+
+// Verdict is one provider's opinion about an address.
+type Verdict struct {
+	Source string `json:"source"`
+	Listed bool   `json:"listed"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Result aggregates all provider verdicts for an address.
+type Result struct {
+	IP        string    `json:"ip"`
+	Flagged   bool      `json:"flagged"`
+	Score     int       `json:"score"`
+	Verdicts  []Verdict `json:"verdicts"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+type cacheEntry struct {
+	result    Result
+	expiresAt time.Time
+	alerted   bool
+}
+
+// Checker caches reputation results and performs lookups in the background so
+// callers on hot paths (flow inspector polls) never block on remote providers.
+type Checker struct {
+	mu          sync.Mutex
+	sources     []Source
+	cache       map[netip.Addr]*cacheEntry
+	pending     map[netip.Addr]struct{}
+	sem         chan struct{}
+	ttl         time.Duration
+	errorTTL    time.Duration
+	timeout     time.Duration
+	maxEntries  int
+	generation  uint64
+	now         func() time.Time
+	lookupGroup sync.WaitGroup
+}
+
+// NewChecker creates a checker with no sources; it is inert until Configure
+// installs at least one source.
+func NewChecker() *Checker {
+	return &Checker{
+		cache:      make(map[netip.Addr]*cacheEntry),
+		pending:    make(map[netip.Addr]struct{}),
+		sem:        make(chan struct{}, defaultConcurrency),
+		ttl:        defaultCacheTTL,
+		errorTTL:   defaultErrorCacheTTL,
+		timeout:    defaultLookupTimeout,
+		maxEntries: defaultMaxEntries,
+		now:        time.Now,
+	}
+}
+
+// Configure replaces the active sources. Cached results are dropped because
+// they were produced by a different provider set.
+func (c *Checker) Configure(sources []Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources = append([]Source(nil), sources...)
+	c.cache = make(map[netip.Addr]*cacheEntry)
+	c.pending = make(map[netip.Addr]struct{})
+	c.generation++
+}
+
+// Enabled reports whether any source is configured.
+func (c *Checker) Enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sources) > 0
+}
+
+// Peek returns a cached, unexpired result without triggering a lookup.
+func (c *Checker) Peek(ip string) (Result, bool) {
+	addr, ok := eligibleAddr(ip)
+	if !ok {
+		return Result{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[addr]
+	if !ok || c.now().After(entry.expiresAt) {
+		return Result{}, false
+	}
+	return entry.result, true
+}
+
+// Prefetch schedules background lookups for public addresses that are not
+// cached or already in flight. It never blocks on the network.
+func (c *Checker) Prefetch(ips []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.sources) == 0 {
+		return
+	}
+	now := c.now()
+	for _, ip := range ips {
+		addr, ok := eligibleAddr(ip)
+		if !ok {
+			continue
+		}
+		if entry, cached := c.cache[addr]; cached && now.Before(entry.expiresAt) {
+			continue
+		}
+		if _, inFlight := c.pending[addr]; inFlight {
+			continue
+		}
+		c.pending[addr] = struct{}{}
+		sources := c.sources
+		generation := c.generation
+		c.lookupGroup.Add(1)
+		go c.lookup(addr, sources, generation)
+	}
+}
+
+// MarkAlerted records that a flagged result has been alerted on and reports
+// whether this is the first alert for the cached result.
+func (c *Checker) MarkAlerted(ip string) bool {
+	addr, ok := eligibleAddr(ip)
+	if !ok {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[addr]
+	if !ok || !entry.result.Flagged || entry.alerted {
+		return false
+	}
+	entry.alerted = true
+	return true
+}
+
+// Wait blocks until all scheduled lookups have finished.
+func (c *Checker) Wait() {
+	c.lookupGroup.Wait()
+}
+
+func (c *Checker) lookup(addr netip.Addr, sources []Source, generation uint64) {
+	defer c.lookupGroup.Done()
+	c.sem <- struct{}{}
+	defer func() { <-c.sem }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	result := Result{IP: addr.String(), Verdicts: make([]Verdict, 0, len(sources))}
+	failed := false
+	for _, source := range sources {
+		verdict, err := source.Lookup(ctx, addr)
+		verdict.Source = source.Name()
+		if err != nil {
+			verdict = Verdict{Source: source.Name(), Error: err.Error()}
+			failed = true
+		}
+		if verdict.Listed {
+			result.Flagged = true
+		}
+		if verdict.Score > result.Score {
+			result.Score = verdict.Score
+		}
+		result.Verdicts = append(result.Verdicts, verdict)
+	}
+	sort.Slice(result.Verdicts, func(i, j int) bool { return result.Verdicts[i].Source < result.Verdicts[j].Source })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	delete(c.pending, addr)
+	now := c.now()
+	result.CheckedAt = now.UTC()
+	ttl := c.ttl
+	if failed && !result.Flagged {
+		ttl = c.errorTTL
+	}
+	c.evictLocked(now)
+	c.cache[addr] = &cacheEntry{result: result, expiresAt: now.Add(ttl)}
+}
+
+// evictLocked drops expired entries and, if the cache is still full, the
+// entries closest to expiry.
+func (c *Checker) evictLocked(now time.Time) {
+	if len(c.cache) < c.maxEntries {
+		return
+	}
+	for addr, entry := range c.cache {
+		if now.After(entry.expiresAt) {
+			delete(c.cache, addr)
+		}
+	}
+	if len(c.cache) < c.maxEntries {
+		return
+	}
+	type aged struct {
+		addr      netip.Addr
+		expiresAt time.Time
+	}
+	entries := make([]aged, 0, len(c.cache))
+	for addr, entry := range c.cache {
+		entries = append(entries, aged{addr: addr, expiresAt: entry.expiresAt})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].expiresAt.Before(entries[j].expiresAt) })
+	for _, entry := range entries[:len(c.cache)-c.maxEntries+1] {
+		delete(c.cache, entry.addr)
+	}
+}
+
+// eligibleAddr parses ip and rejects addresses that public reputation
+// providers cannot know about (private, loopback, link-local, multicast).
+func eligibleAddr(ip string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return netip.Addr{}, false
+	}
+	if addr.Is4() && netip.MustParsePrefix("100.64.0.0/10").Contains(addr) {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
@@ -0,0 +1,180 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type stubSource struct {
+	name    string
+	verdict Verdict
+	err     error
+	calls   atomic.Int32
+}
+
+func (s *stubSource) Name() string { return s.name }
+
+func (s *stubSource) Lookup(context.Context, netip.Addr) (Verdict, error) {
+	s.calls.Add(1)
+	return s.verdict, s.err
+}
+
+type stubDoer struct {
+	status int
+	body   string
+	req    *http.Request
+}
+
+func (d *stubDoer) Do(req *http.Request) (*http.Response, error) {
+	d.req = req
+	return &http.Response{
+		StatusCode: d.status,
+		Body:       io.NopCloser(strings.NewReader(d.body)),
+	}, nil
+}
+
+type stubResolver struct {
+	answers []string
+	err     error
+	query   string
+}
+
+func (r *stubResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.query = host
+	return r.answers, r.err
+}
+
+func TestCheckerPrefetchCachesAndAlertsOnce(t *testing.T) {
+	bad := &stubSource{name: "bad", verdict: Verdict{Listed: true, Score: 90}}
+	broken := &stubSource{name: "broken", err: errors.New("quota exceeded")}
+	checker := NewChecker()
+	checker.Configure([]Source{bad, broken})
+
+	checker.Prefetch([]string{"203.0.113.7", "203.0.113.7", "192.168.1.10", "not-an-ip"})
+	checker.Wait()
+
+	if calls := bad.calls.Load(); calls != 1 {
+		t.Fatalf("expected one lookup for deduplicated public IP, got %d", calls)
+	}
+	result, ok := checker.Peek("203.0.113.7")
+	if !ok {
+		t.Fatalf("expected cached result")
+	}
+	if !result.Flagged || result.Score != 90 || len(result.Verdicts) != 2 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	if result.Verdicts[1].Source != "broken" || result.Verdicts[1].Error == "" {
+		t.Fatalf("expected failing source recorded with error, got %#v", result.Verdicts)
+	}
+	if _, ok := checker.Peek("192.168.1.10"); ok {
+		t.Fatalf("private addresses must never be looked up")
+	}
+
+	if !checker.MarkAlerted("203.0.113.7") {
+		t.Fatalf("expected first alert to be reported")
+	}
+	if checker.MarkAlerted("203.0.113.7") {
+		t.Fatalf("expected repeat alert to be suppressed")
+	}
+
+	checker.Prefetch([]string{"203.0.113.7"})
+	checker.Wait()
+	if calls := bad.calls.Load(); calls != 1 {
+		t.Fatalf("expected cached IP not to be looked up again, got %d calls", calls)
+	}
+}
+
+func TestCheckerConfigureNilDisablesLookups(t *testing.T) {
+	source := &stubSource{name: "bad", verdict: Verdict{Listed: true}}
+	checker := NewChecker()
+	checker.Configure([]Source{source})
+	checker.Configure(nil)
+	if checker.Enabled() {
+		t.Fatalf("expected checker to be disabled")
+	}
+	checker.Prefetch([]string{"203.0.113.7"})
+	checker.Wait()
+	if source.calls.Load() != 0 {
+		t.Fatalf("expected no lookups when disabled")
+	}
+}
+
+func TestAbuseIPDBLookupAppliesThreshold(t *testing.T) {
+	doer := &stubDoer{status: http.StatusOK, body: `{"data":{"abuseConfidenceScore":75,"totalReports":12,"isp":"Example ISP","isWhitelisted":false}}`}
+	source := NewAbuseIPDB("secret", 80, doer)
+
+	verdict, err := source.Lookup(context.Background(), netip.MustParseAddr("203.0.113.7"))
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if verdict.Listed || verdict.Score != 75 {
+		t.Fatalf("expected score below threshold to be unlisted, got %#v", verdict)
+	}
+	if doer.req.Header.Get("Key") != "secret" || doer.req.URL.Query().Get("ipAddress") != "203.0.113.7" {
+		t.Fatalf("unexpected request: %s %v", doer.req.URL, doer.req.Header)
+	}
+
+	doer.body = `{"data":{"abuseConfidenceScore":100,"totalReports":300}}`
+	verdict, err = source.Lookup(context.Background(), netip.MustParseAddr("203.0.113.7"))
+	if err != nil || !verdict.Listed {
+		t.Fatalf("expected listed verdict, got %#v err=%v", verdict, err)
+	}
+}
+
+func TestAbuseIPDBLookupSurfacesAPIErrors(t *testing.T) {
+	doer := &stubDoer{status: http.StatusTooManyRequests, body: `{"errors":[{"detail":"Daily rate limit of 1000 requests exceeded"}]}`}
+	source := NewAbuseIPDB("secret", 0, doer)
+	_, err := source.Lookup(context.Background(), netip.MustParseAddr("203.0.113.7"))
+	if err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+}
+
+func TestSpamhausLookup(t *testing.T) {
+	resolver := &stubResolver{answers: []string{"127.0.0.2", "127.0.0.10", "127.0.0.4"}}
+	source := NewSpamhaus(resolver)
+	verdict, err := source.Lookup(context.Background(), netip.MustParseAddr("203.0.113.7"))
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if resolver.query != "7.113.0.203.zen.spamhaus.org" {
+		t.Fatalf("unexpected query name %q", resolver.query)
+	}
+	if !verdict.Listed || verdict.Detail != "SBL, XBL" {
+		t.Fatalf("unexpected verdict: %#v", verdict)
+	}
+
+	resolver.answers = []string{"127.0.0.11"}
+	verdict, err = source.Lookup(context.Background(), netip.MustParseAddr("203.0.113.7"))
+	if err != nil || verdict.Listed {
+		t.Fatalf("expected PBL-only answer to be unlisted, got %#v err=%v", verdict, err)
+	}
+
+	resolver.answers = nil
+	resolver.err = &net.DNSError{Err: "no such host", IsNotFound: true}
+	verdict, err = source.Lookup(context.Background(), netip.MustParseAddr("203.0.113.7"))
+	if err != nil || verdict.Listed {
+		t.Fatalf("expected NXDOMAIN to be unlisted, got %#v err=%v", verdict, err)
+	}
+
+	resolver.err = nil
+	resolver.answers = []string{"127.255.255.254"}
+	if _, err := source.Lookup(context.Background(), netip.MustParseAddr("203.0.113.7")); err == nil {
+		t.Fatalf("expected refused query to be an error")
+	}
+}
+
+func TestDNSBLQueryNameIPv6(t *testing.T) {
+	name := dnsblQueryName(netip.MustParseAddr("2001:db8::1"), "zen.spamhaus.org")
+	want := "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.zen.spamhaus.org"
+	if name != want {
+		t.Fatalf("expected %q, got %q", want, name)
+	}
+}
@@ -0,0 +1,100 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+const defaultSpamhausZone = "zen.spamhaus.org"
+
+// spamhausCodes maps ZEN return codes to list names. PBL codes (127.0.0.10/11)
+// are intentionally absent: they describe end-user address space, which says
+// nothing about whether a destination is malicious.
+var spamhausCodes = map[string]string{
+	"127.0.0.2": "SBL",
+	"127.0.0.3": "SBL CSS",
+	"127.0.0.4": "XBL",
+	"127.0.0.5": "XBL",
+	"127.0.0.6": "XBL",
+	"127.0.0.7": "XBL",
+	"127.0.0.9": "DROP",
+}
+
+// HostResolver resolves DNSBL query names; *net.Resolver satisfies it.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Spamhaus queries the Spamhaus ZEN DNS blocklist.
+type Spamhaus struct {
+	zone     string
+	resolver HostResolver
+}
+
+// NewSpamhaus creates a Spamhaus DNSBL source. A nil resolver uses the
+// system resolver.
+func NewSpamhaus(resolver HostResolver) *Spamhaus {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &Spamhaus{zone: defaultSpamhausZone, resolver: resolver}
+}
+
+// Name implements Source.
+func (s *Spamhaus) Name() string { return "spamhaus" }
+
+// Lookup implements Source.
+func (s *Spamhaus) Lookup(ctx context.Context, addr netip.Addr) (Verdict, error) {
+	query := dnsblQueryName(addr, s.zone)
+	answers, err := s.resolver.LookupHost(ctx, query)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return Verdict{}, nil
+		}
+		return Verdict{}, err
+	}
+	lists := make(map[string]struct{})
+	for _, answer := range answers {
+		if strings.HasPrefix(answer, "127.255.255.") {
+			// Spamhaus signals refused/over-quota queries (usually via public
+			// resolvers) with 127.255.255.x rather than an NXDOMAIN.
+			return Verdict{}, fmt.Errorf("spamhaus: query refused (%s)", answer)
+		}
+		if name, ok := spamhausCodes[answer]; ok {
+			lists[name] = struct{}{}
+		}
+	}
+	if len(lists) == 0 {
+		return Verdict{}, nil
+	}
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return Verdict{Listed: true, Score: 100, Detail: strings.Join(names, ", ")}, nil
+}
+
+// dnsblQueryName reverses an address into DNSBL query form: octets for IPv4,
+// nibbles for IPv6.
+func dnsblQueryName(addr netip.Addr, zone string) string {
+	var parts []string
+	if addr.Is4() {
+		octets := addr.As4()
+		for i := len(octets) - 1; i >= 0; i-- {
+			parts = append(parts, fmt.Sprintf("%d", octets[i]))
+		}
+	} else {
+		bytes := addr.As16()
+		for i := len(bytes) - 1; i >= 0; i-- {
+			parts = append(parts, fmt.Sprintf("%x", bytes[i]&0x0f), fmt.Sprintf("%x", bytes[i]>>4))
+		}
+	}
+	return strings.Join(parts, ".") + "." + zone
+}
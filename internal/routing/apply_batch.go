@@ -0,0 +1,192 @@
+package routing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultApplyDebounce is how long a requested apply waits for further
+	// requests before it runs.
+	defaultApplyDebounce = 250 * time.Millisecond
+	// applyDebounceMaxWaitFactor caps how long a stream of requests can keep
+	// postponing an apply, as a multiple of the debounce window.
+	applyDebounceMaxWaitFactor = 4
+)
+
+// applyKind is the amount of work a batched apply does. A full apply also
+// refreshes destination sets, so it subsumes a sets-only refresh.
+type applyKind int
+
+const (
+	applyKindSets applyKind = iota + 1
+	applyKindFull
+)
+
+// applyBatch is one pending apply shared by every request that joined it.
+type applyBatch struct {
+	kind     applyKind
+	requests int
+	firstAt  time.Time
+	lastAt   time.Time
+	done     chan struct{}
+	err      error
+}
+
+// applyBatcher coalesces apply requests. The first request opens a batch;
+// requests arriving within the debounce window, or while an earlier apply
+// still holds the manager lock, join it and share its result.
+type applyBatcher struct {
+	mu      sync.Mutex
+	window  time.Duration
+	pending *applyBatch
+}
+
+// ApplyKindStats summarises the applies of one kind.
+type ApplyKindStats struct {
+	Count          int64  `json:"count"`
+	Failures       int64  `json:"failures"`
+	LastDurationMS int64  `json:"lastDurationMs"`
+	AvgDurationMS  int64  `json:"avgDurationMs"`
+	MaxDurationMS  int64  `json:"maxDurationMs"`
+	LastAt         int64  `json:"lastAt,omitempty"`
+	LastError      string `json:"lastError,omitempty"`
+
+	totalDuration time.Duration
+}
+
+// ApplyStats reports apply counts and durations since the manager started.
+// Requests counts every apply asked for through the batcher; Coalesced is
+// how many of them were folded into another request's apply.
+type ApplyStats struct {
+	DebounceMS int64            `json:"debounceMs"`
+	Requests   int64            `json:"requests"`
+	Coalesced  int64            `json:"coalesced"`
+	Full       ApplyKindStats   `json:"full"`
+	Sets       ApplyKindStats   `json:"sets"`
+	IPSets     IPSetUpdateStats `json:"ipsets"`
+}
+
+type applyStatsRecorder struct {
+	mu    sync.Mutex
+	stats ApplyStats
+}
+
+func (r *applyStatsRecorder) record(kind applyKind, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	target := &r.stats.Sets
+	if kind == applyKindFull {
+		target = &r.stats.Full
+	}
+	target.Count++
+	target.totalDuration += duration
+	target.LastDurationMS = duration.Milliseconds()
+	target.AvgDurationMS = (target.totalDuration / time.Duration(target.Count)).Milliseconds()
+	if target.LastDurationMS > target.MaxDurationMS {
+		target.MaxDurationMS = target.LastDurationMS
+	}
+	target.LastAt = time.Now().Unix()
+	target.LastError = ""
+	if err != nil {
+		target.Failures++
+		target.LastError = err.Error()
+	}
+}
+
+func (r *applyStatsRecorder) recordBatch(requests int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Requests += int64(requests)
+	r.stats.Coalesced += int64(requests - 1)
+}
+
+// SetApplyDebounce sets how long group edits and cache refreshes wait for
+// further changes before applying. Zero applies as soon as the manager lock
+// is free; requests queued behind a running apply are still merged.
+func (m *Manager) SetApplyDebounce(window time.Duration) {
+	if window < 0 {
+		window = 0
+	}
+	m.batcher.mu.Lock()
+	m.batcher.window = window
+	m.batcher.mu.Unlock()
+}
+
+// ApplyStats returns apply counts and duration metrics.
+func (m *Manager) ApplyStats() ApplyStats {
+	m.batcher.mu.Lock()
+	window := m.batcher.window
+	m.batcher.mu.Unlock()
+
+	m.applyStats.mu.Lock()
+	defer m.applyStats.mu.Unlock()
+	stats := m.applyStats.stats
+	stats.DebounceMS = window.Milliseconds()
+	return stats
+}
+
+// requestApply queues an apply of kind and waits for the batch it joined.
+// It must be called without m.mu held. The apply itself is not tied to ctx;
+// a cancelled caller stops waiting but the batch still runs for the others.
+func (m *Manager) requestApply(ctx context.Context, kind applyKind) error {
+	now := time.Now()
+	m.batcher.mu.Lock()
+	batch := m.batcher.pending
+	if batch == nil {
+		batch = &applyBatch{kind: kind, firstAt: now, done: make(chan struct{})}
+		m.batcher.pending = batch
+		go m.runApplyBatch(batch)
+	}
+	if kind > batch.kind {
+		batch.kind = kind
+	}
+	batch.requests++
+	batch.lastAt = now
+	m.batcher.mu.Unlock()
+
+	select {
+	case <-batch.done:
+		return batch.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Manager) runApplyBatch(batch *applyBatch) {
+	for {
+		m.batcher.mu.Lock()
+		window := m.batcher.window
+		wake := batch.lastAt.Add(window)
+		if deadline := batch.firstAt.Add(window * applyDebounceMaxWaitFactor); wake.After(deadline) {
+			wake = deadline
+		}
+		m.batcher.mu.Unlock()
+		delay := time.Until(wake)
+		if window <= 0 || delay <= 0 {
+			break
+		}
+		time.Sleep(delay)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Close the batch only once the lock is ours, so requests made while a
+	// previous apply was running are folded into this one.
+	m.batcher.mu.Lock()
+	if m.batcher.pending == batch {
+		m.batcher.pending = nil
+	}
+	kind, requests := batch.kind, batch.requests
+	m.batcher.mu.Unlock()
+
+	ctx := context.Background()
+	if kind == applyKindFull {
+		batch.err = m.applyLocked(ctx)
+	} else {
+		batch.err = m.applyCachedDestinationSetsLocked(ctx)
+	}
+	m.applyStats.recordBatch(requests)
+	close(batch.done)
+}
@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestManagerCoalescesRapidGroupEditsIntoOneApply(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, rules := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{
+		{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"},
+	}})
+	manager.SetApplyDebounce(100 * time.Millisecond)
+
+	const edits = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, edits)
+	for i := 0; i < edits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := manager.CreateGroup(ctx, DomainGroup{
+				Name:      fmt.Sprintf("Group-%d", i),
+				EgressVPN: "wg-sgp",
+				Rules:     []RoutingRule{{Domains: []string{fmt.Sprintf("site%d.example", i)}}},
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("CreateGroup failed: %v", err)
+		}
+	}
+
+	if rules.applyCount != 1 {
+		t.Fatalf("expected one consolidated apply, got %d", rules.applyCount)
+	}
+	if len(rules.bindings) != edits {
+		t.Fatalf("expected the apply to include all %d groups, got %d bindings", edits, len(rules.bindings))
+	}
+	stats := manager.ApplyStats()
+	if stats.Requests != edits || stats.Coalesced != edits-1 || stats.Full.Count != 1 || stats.DebounceMS != 100 {
+		t.Fatalf("unexpected apply stats: %+v", stats)
+	}
+}
+
+func TestManagerApplyRecordsDurationAndFailures(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, rules := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{
+		{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"},
+	}})
+	if _, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Domains: []string{"max.com"}}},
+	}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	generation := manager.Generation()
+	rules.err = fmt.Errorf("iptables unavailable")
+	if err := manager.Apply(ctx); err == nil {
+		t.Fatalf("expected apply error")
+	}
+	if manager.Generation() != generation+1 {
+		t.Fatalf("expected a failed apply to advance the generation past %d, got %d", generation, manager.Generation())
+	}
+
+	stats := manager.ApplyStats()
+	if stats.Full.Count != 2 || stats.Full.Failures != 1 || stats.Full.LastError != "iptables unavailable" || stats.Full.LastAt == 0 {
+		t.Fatalf("unexpected full apply stats: %+v", stats.Full)
+	}
+	if stats.Requests != 2 || stats.Coalesced != 0 {
+		t.Fatalf("unexpected request counts: %+v", stats)
+	}
+}
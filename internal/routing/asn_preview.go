@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"split-vpn-webui/internal/netbind"
 )
 
 // ASNPreviewItem captures per-ASN prefix and collapsed ipset entry counts.
@@ -27,9 +29,10 @@ type ASNPreviewResult struct {
 	ResolvedSelector int              `json:"resolvedSelectors"`
 }
 
-// PreviewASNEntries resolves ASN prefixes and computes collapsed ipset entry counts.
-func PreviewASNEntries(ctx context.Context, asns []string, timeout time.Duration) (ASNPreviewResult, error) {
-	return PreviewASNEntriesWithResolver(ctx, asns, newRIPEASNResolver(timeout))
+// PreviewASNEntries resolves ASN prefixes and computes collapsed ipset entry
+// counts. A non-empty iface binds the lookups to that interface.
+func PreviewASNEntries(ctx context.Context, asns []string, timeout time.Duration, egress netbind.Egress) (ASNPreviewResult, error) {
+	return PreviewASNEntriesWithResolver(ctx, asns, newRIPEASNResolver(timeout, egress))
 }
 
 // PreviewASNEntriesWithResolver is the testable resolver-injected variant.
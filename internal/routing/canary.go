@@ -0,0 +1,304 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+var (
+	// ErrCanaryActive indicates another canary is already staged.
+	ErrCanaryActive = fmt.Errorf("a canary is already active")
+	// ErrNoCanary indicates no canary is staged.
+	ErrNoCanary = fmt.Errorf("no canary is active")
+)
+
+// Canary is an edited group staged for one source device. While it is
+// active the device is excluded from the live group and routed by a
+// high-priority clone of the proposed rules instead; everyone else keeps the
+// live policy until the canary is promoted or rolled back.
+//
+// Canaries are held in memory only: a restart falls back to the live policy.
+type Canary struct {
+	GroupID   int64       `json:"groupId"`
+	GroupName string      `json:"groupName"`
+	Device    string      `json:"device"`
+	Proposed  DomainGroup `json:"proposed"`
+	StartedAt int64       `json:"startedAt"`
+}
+
+// StartCanary stages proposed as the new policy of group id for device only.
+func (m *Manager) StartCanary(ctx context.Context, id int64, device string, proposed DomainGroup) (*Canary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.canary != nil {
+		return nil, ErrCanaryActive
+	}
+	addr, err := parseCanaryDevice(device)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.validateEgressVPN(proposed.EgressVPN); err != nil {
+		return nil, err
+	}
+	live, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if live.Managed != "" {
+		return nil, fmt.Errorf("%w: %s cannot be canaried", ErrGroupManaged, live.Name)
+	}
+	normalized, err := NormalizeAndValidate(proposed)
+	if err != nil {
+		return nil, err
+	}
+	normalized.ID = live.ID
+
+	m.canary = &Canary{
+		GroupID:   live.ID,
+		GroupName: live.Name,
+		Device:    addr.String(),
+		Proposed:  normalized,
+		StartedAt: time.Now().Unix(),
+	}
+	if err := m.applyLocked(ctx); err != nil {
+		m.canary = nil
+		if restoreErr := m.applyLocked(ctx); restoreErr != nil {
+			return nil, fmt.Errorf("%v; restoring live policy failed: %w", err, restoreErr)
+		}
+		return nil, err
+	}
+	current := *m.canary
+	return &current, nil
+}
+
+// Canary returns the staged canary, if any.
+func (m *Manager) Canary() (*Canary, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.canary == nil {
+		return nil, false
+	}
+	current := *m.canary
+	return &current, true
+}
+
+// PromoteCanary persists the staged policy for everyone and drops the canary.
+func (m *Manager) PromoteCanary(ctx context.Context) (*DomainGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.canary == nil {
+		return nil, ErrNoCanary
+	}
+	if err := m.validateEgressVPN(m.canary.Proposed.EgressVPN); err != nil {
+		return nil, err
+	}
+	updated, err := m.store.Update(ctx, m.canary.GroupID, m.canary.Proposed)
+	if err != nil {
+		return nil, err
+	}
+	m.canary = nil
+	if err := m.applyLocked(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// RollbackCanary drops the staged policy and returns the device to the live group.
+func (m *Manager) RollbackCanary(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.canary == nil {
+		return ErrNoCanary
+	}
+	m.canary = nil
+	return m.applyLocked(ctx)
+}
+
+// canaryForGroupsLocked returns the staged canary while its live group still
+// exists. A canary whose group has been deleted is dropped.
+func (m *Manager) canaryForGroupsLocked(groups []DomainGroup) *Canary {
+	m.canary = m.canaryForGroups(groups)
+	return m.canary
+}
+
+// canaryForGroups returns the staged canary unless its group is gone.
+func (m *Manager) canaryForGroups(groups []DomainGroup) *Canary {
+	if m.canary == nil {
+		return nil
+	}
+	for _, group := range groups {
+		if group.ID == m.canary.GroupID {
+			return m.canary
+		}
+	}
+	return nil
+}
+
+func (m *Manager) buildCanaryBindings(
+	canary *Canary,
+	vpnByName map[string]*vpn.VPNProfile,
+	resolved map[ResolverSelector]ResolverValues,
+	prewarmed map[string]ResolverValues,
+	activeSets map[string]struct{},
+	desiredSets map[string]desiredSetDefinition,
+	delegated delegatedPrefixes,
+	networks lanNetworks,
+	devices deviceGroupIndex,
+) ([]RouteBinding, error) {
+	if canary == nil {
+		return nil, nil
+	}
+	profile, err := groupProfile(canary.Proposed, vpnByName)
+	if err != nil {
+		return nil, err
+	}
+	device, err := parseCanaryDevice(canary.Device)
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]RouteBinding, 0, len(canary.Proposed.Rules))
+	for ruleIndex, rule := range canary.Proposed.Rules {
+		narrowed, ok := canaryRule(expandRuleSources(rule, delegated, networks), device)
+		if !ok {
+			continue
+		}
+		pair := canaryRuleSetNames(canary.GroupID, ruleIndex)
+		binding, err := m.buildBinding(canary.Proposed, narrowed, ruleIndex, pair, profile, resolved, prewarmed, activeSets, desiredSets, delegated, devices)
+		if err != nil {
+			return nil, err
+		}
+		binding.Canary = true
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+// canaryRuleSetNames names the ipsets of a canary rule. Live set bases never
+// start with an underscore, so the "svpn__" prefix cannot collide with them
+// while still being picked up by stale-set cleanup.
+func canaryRuleSetNames(groupID int64, ruleIndex int) RuleSetPair {
+	if ruleIndex < 0 {
+		ruleIndex = 0
+	}
+	base := fmt.Sprintf("%s_c%d_r%d", setPrefix, groupID, ruleIndex+1)
+	return RuleSetPair{
+		SourceV4:              base + "s4",
+		SourceV6:              base + "s6",
+		ExcludedSourceV4:      base + "xs4",
+		ExcludedSourceV6:      base + "xs6",
+		SourceDeviceV4:        base + "g4",
+		SourceDeviceV6:        base + "g6",
+		DestinationV4:         base + "d4",
+		DestinationV6:         base + "d6",
+		ExcludedDestinationV4: base + "xd4",
+		ExcludedDestinationV6: base + "xd6",
+	}
+}
+
+// excludeCanaryDevice removes the canary device from every active rule of the
+// live group so only the canary bindings decide how its traffic is routed.
+func excludeCanaryDevice(group DomainGroup, device string) DomainGroup {
+	addr, err := parseCanaryDevice(device)
+	if err != nil {
+		return group
+	}
+	host := netip.PrefixFrom(addr, addr.BitLen()).String()
+	rules := make([]RoutingRule, len(group.Rules))
+	for idx, rule := range group.Rules {
+		if ruleHasSelectors(rule) {
+			rule.ExcludedSourceCIDRs = append(append([]string(nil), rule.ExcludedSourceCIDRs...), host)
+		}
+		rules[idx] = rule
+	}
+	group.Rules = rules
+	return group
+}
+
+// canaryRule narrows a proposed rule to the canary device. Rules whose source
+// selectors can never match the device produce no canary binding.
+func canaryRule(rule RoutingRule, device netip.Addr) (RoutingRule, bool) {
+	if !ruleHasSelectors(rule) || !device.IsValid() {
+		return rule, false
+	}
+	if len(rule.SourceCIDRs) > 0 && !cidrsContain(rule.SourceCIDRs, device) {
+		return rule, false
+	}
+	if cidrsContain(rule.ExcludedSourceCIDRs, device) {
+		return rule, false
+	}
+	rule.SourceCIDRs = []string{netip.PrefixFrom(device, device.BitLen()).String()}
+	rule.ExcludedSourceCIDRs = nil
+	return rule, true
+}
+
+// canaryDnsmasqLines feeds the canary destination sets for domains the live
+// groups do not already cover. Shared domains keep their live dnsmasq line
+// and reach the canary sets through the resolver cache.
+func canaryDnsmasqLines(canary *Canary, groups []DomainGroup, delegated delegatedPrefixes, networks lanNetworks) string {
+	if canary == nil {
+		return ""
+	}
+	live := make(map[string]struct{})
+	for _, group := range groups {
+		for _, domain := range RuleDomains(group) {
+			live[domain] = struct{}{}
+		}
+	}
+	device, _ := parseCanaryDevice(canary.Device)
+	seen := make(map[string]struct{})
+	var builder strings.Builder
+	for ruleIndex, rule := range canary.Proposed.Rules {
+		if _, ok := canaryRule(expandRuleSources(rule, delegated, networks), device); !ok {
+			continue
+		}
+		pair := canaryRuleSetNames(canary.GroupID, ruleIndex)
+		domains := append(append([]string(nil), rule.Domains...), rule.WildcardDomains...)
+		for _, domain := range domains {
+			trimmed := strings.TrimSpace(strings.TrimPrefix(strings.ToLower(domain), "*."))
+			if _, covered := live[trimmed]; covered {
+				continue
+			}
+			line := dnsmasqLine(domain, pair.DestinationV4, pair.DestinationV6)
+			if _, dup := seen[line]; line == "" || dup {
+				continue
+			}
+			seen[line] = struct{}{}
+			builder.WriteString(line)
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
+
+func parseCanaryDevice(raw string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(raw))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%w: canary device must be a single IP address", ErrGroupValidation)
+	}
+	addr = addr.Unmap().WithZone("")
+	if addr.IsUnspecified() || addr.IsMulticast() || addr.IsLoopback() {
+		return netip.Addr{}, fmt.Errorf("%w: canary device %s is not a client address", ErrGroupValidation, addr)
+	}
+	return addr, nil
+}
+
+func cidrsContain(values []string, addr netip.Addr) bool {
+	for _, value := range values {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			continue
+		}
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func newCanaryTestManager(t *testing.T) (*Manager, *MockIPSet, *mockDNSManager, *mockRuleApplier) {
+	t.Helper()
+	return newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{
+		{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"},
+		{Name: "wg-us", RouteTable: 202, FWMark: 0x16a, InterfaceName: "wg-us"},
+	}})
+}
+
+func TestManagerStartCanaryStagesPolicyForDevice(t *testing.T) {
+	ctx := context.Background()
+	manager, ipset, dns, rules := newCanaryTestManager(t)
+	group, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Domains: []string{"max.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+
+	canary, err := manager.StartCanary(ctx, group.ID, "192.168.1.50", DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-us",
+		Rules: []RoutingRule{
+			{Domains: []string{"max.com", "netflix.com"}},
+			{SourceCIDRs: []string{"10.0.0.0/8"}, Domains: []string{"hulu.com"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("StartCanary failed: %v", err)
+	}
+	if canary.GroupID != group.ID || canary.Device != "192.168.1.50" {
+		t.Fatalf("unexpected canary: %+v", canary)
+	}
+	if _, err := manager.StartCanary(ctx, group.ID, "192.168.1.51", canary.Proposed); !errors.Is(err, ErrCanaryActive) {
+		t.Fatalf("expected ErrCanaryActive, got %v", err)
+	}
+
+	if len(rules.bindings) != 2 {
+		t.Fatalf("expected live + one canary binding, got %+v", rules.bindings)
+	}
+	live, staged := rules.bindings[0], rules.bindings[1]
+	if live.Canary || !live.HasExcludedSource || live.Mark != 0x169 {
+		t.Fatalf("expected live binding to exclude the device, got %+v", live)
+	}
+	if ipset.Sets[live.ExcludedSourceSetV4] == "" {
+		t.Fatalf("expected excluded source set %s", live.ExcludedSourceSetV4)
+	}
+	if !staged.Canary || !staged.HasSource || staged.Mark != 0x16a || !strings.HasPrefix(staged.SourceSetV4, "svpn__") {
+		t.Fatalf("unexpected canary binding: %+v", staged)
+	}
+	if !strings.Contains(dns.lastWritten, "ipset=/netflix.com/"+staged.DestinationSetV4) {
+		t.Fatalf("expected dnsmasq line for new canary domain, got %q", dns.lastWritten)
+	}
+	if strings.Contains(dns.lastWritten, "ipset=/max.com/"+staged.DestinationSetV4) {
+		t.Fatalf("shared domain must keep its live dnsmasq line, got %q", dns.lastWritten)
+	}
+
+	if err := manager.RollbackCanary(ctx); err != nil {
+		t.Fatalf("RollbackCanary failed: %v", err)
+	}
+	if len(rules.bindings) != 1 || rules.bindings[0].HasExcludedSource {
+		t.Fatalf("expected live policy only after rollback, got %+v", rules.bindings)
+	}
+	if _, ok := ipset.Sets[staged.SourceSetV4]; ok {
+		t.Fatalf("expected canary sets to be cleaned up")
+	}
+	if err := manager.RollbackCanary(ctx); !errors.Is(err, ErrNoCanary) {
+		t.Fatalf("expected ErrNoCanary, got %v", err)
+	}
+}
+
+func TestManagerPromoteCanaryPersistsProposedGroup(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, rules := newCanaryTestManager(t)
+	group, err := manager.CreateGroup(ctx, DomainGroup{Name: "Streaming", EgressVPN: "wg-sgp", Domains: []string{"max.com"}})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if _, err := manager.StartCanary(ctx, group.ID, "fd00::50", DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-us",
+		Domains:   []string{"max.com"},
+	}); err != nil {
+		t.Fatalf("StartCanary failed: %v", err)
+	}
+
+	promoted, err := manager.PromoteCanary(ctx)
+	if err != nil {
+		t.Fatalf("PromoteCanary failed: %v", err)
+	}
+	if promoted.EgressVPN != "wg-us" {
+		t.Fatalf("expected promoted group to use wg-us, got %+v", promoted)
+	}
+	if _, ok := manager.Canary(); ok {
+		t.Fatalf("expected canary to be cleared after promotion")
+	}
+	if len(rules.bindings) != 1 || rules.bindings[0].Canary || rules.bindings[0].Mark != 0x16a {
+		t.Fatalf("expected promoted live binding, got %+v", rules.bindings)
+	}
+}
+
+func TestManagerDeleteGroupDropsCanary(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, _ := newCanaryTestManager(t)
+	group, err := manager.CreateGroup(ctx, DomainGroup{Name: "Streaming", EgressVPN: "wg-sgp", Domains: []string{"max.com"}})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if _, err := manager.StartCanary(ctx, group.ID, "not-an-ip", *group); !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected validation error for bad device, got %v", err)
+	}
+	if _, err := manager.StartCanary(ctx, group.ID, "192.168.1.50", *group); err != nil {
+		t.Fatalf("StartCanary failed: %v", err)
+	}
+	if err := manager.DeleteGroup(ctx, group.ID); err != nil {
+		t.Fatalf("DeleteGroup failed: %v", err)
+	}
+	if _, ok := manager.Canary(); ok {
+		t.Fatalf("expected canary of a deleted group to be dropped")
+	}
+}
+
+func TestCanaryRuleNarrowsSourceSelectors(t *testing.T) {
+	device, err := parseCanaryDevice("192.168.1.50")
+	if err != nil {
+		t.Fatalf("parse device: %v", err)
+	}
+	if _, ok := canaryRule(RoutingRule{SourceCIDRs: []string{"10.0.0.0/8"}, Domains: []string{"a.com"}}, device); ok {
+		t.Fatalf("expected rule for another subnet to be skipped")
+	}
+	if _, ok := canaryRule(RoutingRule{ExcludedSourceCIDRs: []string{"192.168.1.0/24"}, Domains: []string{"a.com"}}, device); ok {
+		t.Fatalf("expected rule excluding the device to be skipped")
+	}
+	narrowed, ok := canaryRule(RoutingRule{SourceCIDRs: []string{"192.168.1.0/24"}, Domains: []string{"a.com"}}, device)
+	if !ok || len(narrowed.SourceCIDRs) != 1 || narrowed.SourceCIDRs[0] != "192.168.1.50/32" {
+		t.Fatalf("unexpected narrowed rule: %+v ok=%v", narrowed, ok)
+	}
+}
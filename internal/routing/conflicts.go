@@ -0,0 +1,448 @@
+package routing
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Conflict kinds reported by DetectConflicts.
+const (
+	ConflictOverlap         = "overlap"
+	ConflictDuplicateDomain = "duplicate_domain"
+	ConflictShadowed        = "shadowed"
+)
+
+// Conflict is one problem found across the configured groups. Conflicts are
+// warnings only: apply still installs every rule and the binding order
+// decides which rule claims contested traffic.
+type Conflict struct {
+	Kind    string        `json:"kind"`
+	Message string        `json:"message"`
+	Rules   []ConflictRef `json:"rules"`
+	// Winner names the group whose VPN carries the contested traffic.
+	Winner string `json:"winner,omitempty"`
+}
+
+// ConflictRef identifies one rule involved in a conflict.
+type ConflictRef struct {
+	Group     string `json:"group"`
+	RuleIndex int    `json:"ruleIndex"`
+	RuleName  string `json:"ruleName,omitempty"`
+	EgressVPN string `json:"egressVpn"`
+}
+
+type conflictRule struct {
+	group    *DomainGroup
+	index    int
+	rule     RoutingRule
+	srcCIDRs []netip.Prefix
+	dstCIDRs []netip.Prefix
+}
+
+// DetectConflicts statically compares the rules of the given groups. It
+// reports rule pairs in different groups that can match the same traffic but
+// use different VPNs, domains listed by more than one group, and rules that
+// never match because a rule installed after them covers all their traffic.
+//
+// Selectors that cannot be compared without live state, such as a MAC rule
+// against a CIDR rule or a domain against a CIDR, are treated as possibly
+// overlapping for sources and as disjoint for destinations. Monitor-only rules
+// do not route and are skipped.
+func DetectConflicts(groups []DomainGroup) []Conflict {
+	rules := make([]conflictRule, 0)
+	for gi := range groups {
+		group := &groups[gi]
+		for ri, rule := range group.Rules {
+			if rule.MonitorOnly {
+				continue
+			}
+			rules = append(rules, conflictRule{
+				group:    group,
+				index:    ri,
+				rule:     rule,
+				srcCIDRs: parseConflictPrefixes(rule.SourceCIDRs),
+				dstCIDRs: parseConflictPrefixes(rule.DestinationCIDRs),
+			})
+		}
+	}
+
+	conflicts := make([]Conflict, 0)
+	for i := range rules {
+		for j := i + 1; j < len(rules); j++ {
+			winner, loser := rules[i], rules[j]
+			if !conflictRuleWins(winner, loser) {
+				winner, loser = loser, winner
+			}
+			if conflictRuleCovers(winner, loser) {
+				conflicts = append(conflicts, Conflict{
+					Kind: ConflictShadowed,
+					Message: fmt.Sprintf(
+						"%s never matches: %s is installed after it and covers all of its traffic",
+						describeConflictRule(loser), describeConflictRule(winner),
+					),
+					Rules:  []ConflictRef{conflictRef(loser), conflictRef(winner)},
+					Winner: winner.group.Name,
+				})
+				continue
+			}
+			if winner.group == loser.group || winner.group.EgressVPN == loser.group.EgressVPN {
+				continue
+			}
+			// Destination groups overriding a full tunnel is the point of
+			// full tunnel groups, not a conflict.
+			if loser.group.FullTunnel && !winner.group.FullTunnel {
+				continue
+			}
+			if !conflictRulesOverlap(winner, loser) {
+				continue
+			}
+			conflicts = append(conflicts, Conflict{
+				Kind: ConflictOverlap,
+				Message: fmt.Sprintf(
+					"%s and %s can match the same traffic; %s wins and routes it via %s",
+					describeConflictRule(loser), describeConflictRule(winner),
+					describeConflictRule(winner), winner.group.EgressVPN,
+				),
+				Rules:  []ConflictRef{conflictRef(loser), conflictRef(winner)},
+				Winner: winner.group.Name,
+			})
+		}
+	}
+	return append(conflicts, duplicateDomainConflicts(rules)...)
+}
+
+// duplicateDomainConflicts reports domains and wildcards listed by rules in
+// more than one group.
+func duplicateDomainConflicts(rules []conflictRule) []Conflict {
+	owners := make(map[string][]conflictRule)
+	for _, entry := range rules {
+		for _, domain := range append(append([]string(nil), entry.rule.Domains...), entry.rule.WildcardDomains...) {
+			owners[domain] = append(owners[domain], entry)
+		}
+	}
+	domains := make([]string, 0, len(owners))
+	for domain, entries := range owners {
+		groups := make(map[*DomainGroup]struct{}, len(entries))
+		for _, entry := range entries {
+			groups[entry.group] = struct{}{}
+		}
+		if len(groups) > 1 {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+
+	conflicts := make([]Conflict, 0, len(domains))
+	for _, domain := range domains {
+		entries := owners[domain]
+		refs := make([]ConflictRef, 0, len(entries))
+		names := make([]string, 0, len(entries))
+		seen := make(map[string]struct{}, len(entries))
+		vpns := make(map[string]struct{}, len(entries))
+		winner := entries[0]
+		for _, entry := range entries {
+			refs = append(refs, conflictRef(entry))
+			if _, ok := seen[entry.group.Name]; !ok {
+				seen[entry.group.Name] = struct{}{}
+				names = append(names, fmt.Sprintf("%q", entry.group.Name))
+			}
+			vpns[entry.group.EgressVPN] = struct{}{}
+			if conflictRuleWins(entry, winner) {
+				winner = entry
+			}
+		}
+		message := fmt.Sprintf("%s is listed by %s", domain, strings.Join(names, ", "))
+		if len(vpns) > 1 {
+			message += fmt.Sprintf("; %q wins and routes it via %s", winner.group.Name, winner.group.EgressVPN)
+		}
+		conflicts = append(conflicts, Conflict{
+			Kind:    ConflictDuplicateDomain,
+			Message: message,
+			Rules:   refs,
+			Winner:  winner.group.Name,
+		})
+	}
+	return conflicts
+}
+
+// conflictRuleWins reports whether a's binding is installed after b's, which
+// makes a's mark the one that sticks. It mirrors the ordering in ApplyRules.
+func conflictRuleWins(a, b conflictRule) bool {
+	if a.group.FullTunnel != b.group.FullTunnel {
+		return b.group.FullTunnel
+	}
+	if a.group.Priority != b.group.Priority {
+		return a.group.Priority > b.group.Priority
+	}
+	if a.group.Name != b.group.Name {
+		return a.group.Name > b.group.Name
+	}
+	return a.index > b.index
+}
+
+// conflictRulesOverlap reports whether two rules can match the same packet.
+func conflictRulesOverlap(a, b conflictRule) bool {
+	return conflictSourcesOverlap(a, b) &&
+		conflictDestinationsOverlap(a, b) &&
+		portsOverlap(a.rule.DestinationPorts, b.rule.DestinationPorts)
+}
+
+func conflictSourcesOverlap(a, b conflictRule) bool {
+	if len(a.rule.SourceInterfaces) > 0 && len(b.rule.SourceInterfaces) > 0 &&
+		!stringsIntersect(a.rule.SourceInterfaces, b.rule.SourceInterfaces) {
+		return false
+	}
+	if len(a.srcCIDRs) > 0 && len(b.srcCIDRs) > 0 && !prefixesIntersect(a.srcCIDRs, b.srcCIDRs) {
+		return false
+	}
+	aDevices := len(a.rule.SourceMACs) > 0 || len(a.rule.SourceDeviceGroups) > 0
+	bDevices := len(b.rule.SourceMACs) > 0 || len(b.rule.SourceDeviceGroups) > 0
+	if aDevices && bDevices && len(a.rule.SourceDeviceGroups) == 0 && len(b.rule.SourceDeviceGroups) == 0 &&
+		!stringsIntersect(a.rule.SourceMACs, b.rule.SourceMACs) {
+		return false
+	}
+	return true
+}
+
+func conflictDestinationsOverlap(a, b conflictRule) bool {
+	if conflictMatchesAnyDestination(a) || conflictMatchesAnyDestination(b) {
+		return true
+	}
+	if prefixesIntersect(a.dstCIDRs, b.dstCIDRs) || stringsIntersect(a.rule.DestinationASNs, b.rule.DestinationASNs) {
+		return true
+	}
+	for _, left := range conflictDomainPatterns(a.rule) {
+		for _, right := range conflictDomainPatterns(b.rule) {
+			if domainCovers(left, right) || domainCovers(right, left) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conflictRuleCovers reports whether every packet matched by inner is also
+// matched by outer. Exclusions on outer make coverage unprovable.
+func conflictRuleCovers(outer, inner conflictRule) bool {
+	rule := outer.rule
+	if len(rule.ExcludedSourceCIDRs) > 0 || len(rule.ExcludedDestinationCIDRs) > 0 ||
+		len(rule.ExcludedDestinationPorts) > 0 || len(rule.ExcludedDestinationASNs) > 0 ||
+		outer.group.InvertDestinations {
+		return false
+	}
+	if !stringsSubset(inner.rule.SourceInterfaces, rule.SourceInterfaces) ||
+		!stringsSubset(inner.rule.SourceMACs, rule.SourceMACs) ||
+		!stringsSubset(inner.rule.SourceDeviceGroups, rule.SourceDeviceGroups) ||
+		!prefixesSubset(inner.srcCIDRs, outer.srcCIDRs) {
+		return false
+	}
+	if !portsSubset(inner.rule.DestinationPorts, rule.DestinationPorts) {
+		return false
+	}
+	if conflictMatchesAnyDestination(outer) {
+		return true
+	}
+	if conflictMatchesAnyDestination(inner) {
+		return false
+	}
+	if !prefixesSubset(inner.dstCIDRs, outer.dstCIDRs) || !stringsSubset(inner.rule.DestinationASNs, rule.DestinationASNs) {
+		return false
+	}
+	outerDomains := conflictDomainPatterns(rule)
+	for _, domain := range conflictDomainPatterns(inner.rule) {
+		covered := false
+		for _, candidate := range outerDomains {
+			if domainCovers(candidate, domain) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// conflictMatchesAnyDestination reports whether a rule matches every
+// destination: it has no destination selectors, or its group inverts them.
+func conflictMatchesAnyDestination(entry conflictRule) bool {
+	if entry.group.InvertDestinations {
+		return true
+	}
+	return len(entry.rule.DestinationCIDRs) == 0 &&
+		len(entry.rule.DestinationASNs) == 0 &&
+		len(entry.rule.Domains) == 0 &&
+		len(entry.rule.WildcardDomains) == 0
+}
+
+// conflictDomainPatterns returns a rule's domains as suffixes. dnsmasq
+// matches a listed domain and its subdomains, so plain domains and wildcards
+// behave alike here.
+func conflictDomainPatterns(rule RoutingRule) []string {
+	out := make([]string, 0, len(rule.Domains)+len(rule.WildcardDomains))
+	out = append(out, rule.Domains...)
+	for _, domain := range rule.WildcardDomains {
+		out = append(out, strings.TrimPrefix(domain, "*."))
+	}
+	return out
+}
+
+func domainCovers(parent, child string) bool {
+	return parent == child || strings.HasSuffix(child, "."+parent)
+}
+
+func describeConflictRule(entry conflictRule) string {
+	if name := strings.TrimSpace(entry.rule.Name); name != "" {
+		return fmt.Sprintf("group %q rule %q", entry.group.Name, name)
+	}
+	return fmt.Sprintf("group %q rule %d", entry.group.Name, entry.index+1)
+}
+
+func conflictRef(entry conflictRule) ConflictRef {
+	return ConflictRef{
+		Group:     entry.group.Name,
+		RuleIndex: entry.index,
+		RuleName:  entry.rule.Name,
+		EgressVPN: entry.group.EgressVPN,
+	}
+}
+
+func parseConflictPrefixes(values []string) []netip.Prefix {
+	out := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if prefix, ok := parsePrefixOrAddr(value); ok {
+			out = append(out, prefix)
+		}
+	}
+	return out
+}
+
+// parsePrefixOrAddr parses a CIDR, or a bare address as a host prefix.
+func parsePrefixOrAddr(value string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.Masked(), true
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
+
+func prefixesIntersect(a, b []netip.Prefix) bool {
+	for _, left := range a {
+		for _, right := range b {
+			if left.Overlaps(right) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prefixesSubset reports whether every inner prefix lies inside an outer one.
+// An empty outer list matches everything.
+func prefixesSubset(inner, outer []netip.Prefix) bool {
+	if len(outer) == 0 {
+		return true
+	}
+	if len(inner) == 0 {
+		return false
+	}
+	for _, prefix := range inner {
+		contained := false
+		for _, candidate := range outer {
+			if candidate.Bits() <= prefix.Bits() && candidate.Contains(prefix.Addr()) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			return false
+		}
+	}
+	return true
+}
+
+func stringsIntersect(a, b []string) bool {
+	for _, left := range a {
+		for _, right := range b {
+			if left == right {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stringsSubset reports whether inner only holds values from outer. An empty
+// outer list matches everything.
+func stringsSubset(inner, outer []string) bool {
+	if len(outer) == 0 {
+		return true
+	}
+	if len(inner) == 0 {
+		return false
+	}
+	for _, value := range inner {
+		if !stringsIntersect([]string{value}, outer) {
+			return false
+		}
+	}
+	return true
+}
+
+func portsOverlap(a, b []PortRange) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, left := range a {
+		for _, right := range b {
+			if portRangeOverlaps(left, right) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// portsSubset reports whether every inner range lies inside an outer range of
+// a compatible protocol. An empty outer list matches every port.
+func portsSubset(inner, outer []PortRange) bool {
+	if len(outer) == 0 {
+		return true
+	}
+	if len(inner) == 0 {
+		return false
+	}
+	for _, port := range inner {
+		contained := false
+		for _, candidate := range outer {
+			protocolOK := candidate.Protocol == "both" || candidate.Protocol == port.Protocol
+			if protocolOK && candidate.Start <= port.Start && portRangeEnd(candidate) >= portRangeEnd(port) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			return false
+		}
+	}
+	return true
+}
+
+func portRangeOverlaps(a, b PortRange) bool {
+	if a.Protocol != b.Protocol && a.Protocol != "both" && b.Protocol != "both" {
+		return false
+	}
+	return a.Start <= portRangeEnd(b) && b.Start <= portRangeEnd(a)
+}
+
+func portRangeEnd(port PortRange) int {
+	if port.End < port.Start {
+		return port.Start
+	}
+	return port.End
+}
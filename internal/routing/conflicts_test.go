@@ -0,0 +1,112 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+)
+
+func conflictKinds(conflicts []Conflict) map[string]int {
+	kinds := make(map[string]int)
+	for _, conflict := range conflicts {
+		kinds[conflict.Kind]++
+	}
+	return kinds
+}
+
+func TestDetectConflictsReportsOverlapAcrossVPNs(t *testing.T) {
+	conflicts := DetectConflicts([]DomainGroup{
+		{
+			Name:      "Streaming",
+			EgressVPN: "wg-us",
+			Rules:     []RoutingRule{{SourceCIDRs: []string{"10.0.0.0/24"}, WildcardDomains: []string{"*.example.com"}}},
+		},
+		{
+			Name:      "Work",
+			EgressVPN: "wg-de",
+			Priority:  5,
+			Rules:     []RoutingRule{{SourceCIDRs: []string{"10.0.0.20/32"}, Domains: []string{"api.example.com"}, DestinationPorts: []PortRange{{Protocol: "tcp", Start: 443, End: 443}}}},
+		},
+		{
+			Name:      "Office",
+			EgressVPN: "wg-fr",
+			Rules:     []RoutingRule{{SourceCIDRs: []string{"10.0.1.0/24"}, Domains: []string{"example.com"}}},
+		},
+	})
+	kinds := conflictKinds(conflicts)
+	if kinds[ConflictOverlap] != 1 || len(conflicts) != 1 {
+		t.Fatalf("expected exactly one overlap conflict, got %#v", conflicts)
+	}
+	if conflicts[0].Winner != "Work" {
+		t.Fatalf("expected higher priority group to win, got %q", conflicts[0].Winner)
+	}
+	if !strings.Contains(conflicts[0].Message, "wg-de") {
+		t.Fatalf("expected message to name the winning VPN, got %q", conflicts[0].Message)
+	}
+}
+
+func TestDetectConflictsReportsShadowedRule(t *testing.T) {
+	conflicts := DetectConflicts([]DomainGroup{
+		{
+			Name:      "Alpha",
+			EgressVPN: "wg-us",
+			Rules:     []RoutingRule{{SourceMACs: []string{"00:11:22:33:44:55"}, Domains: []string{"video.example.com"}}},
+		},
+		{
+			Name:      "Beta",
+			EgressVPN: "wg-de",
+			Rules:     []RoutingRule{{Domains: []string{"example.com"}}},
+		},
+	})
+	if len(conflicts) != 1 || conflicts[0].Kind != ConflictShadowed {
+		t.Fatalf("expected one shadowed conflict, got %#v", conflicts)
+	}
+	if conflicts[0].Rules[0].Group != "Alpha" || conflicts[0].Winner != "Beta" {
+		t.Fatalf("expected Alpha to be shadowed by Beta, got %#v", conflicts[0])
+	}
+}
+
+func TestDetectConflictsSkipsExclusionsAndFullTunnelOverrides(t *testing.T) {
+	conflicts := DetectConflicts([]DomainGroup{
+		{
+			Name:      "Alpha",
+			EgressVPN: "wg-us",
+			Rules:     []RoutingRule{{SourceMACs: []string{"00:11:22:33:44:55"}, Domains: []string{"video.example.com"}}},
+		},
+		{
+			Name:      "Beta",
+			EgressVPN: "wg-de",
+			Rules:     []RoutingRule{{Domains: []string{"example.com"}, ExcludedSourceCIDRs: []string{"10.0.0.9/32"}}},
+		},
+		{
+			Name:       "Home",
+			EgressVPN:  "wg-fr",
+			FullTunnel: true,
+			Rules:      []RoutingRule{{SourceMACs: []string{"00:11:22:33:44:55"}}},
+		},
+	})
+	kinds := conflictKinds(conflicts)
+	if kinds[ConflictShadowed] != 0 || kinds[ConflictOverlap] != 1 || len(conflicts) != 1 {
+		t.Fatalf("expected only the Alpha/Beta overlap, got %#v", conflicts)
+	}
+}
+
+func TestDetectConflictsReportsDuplicateDomains(t *testing.T) {
+	conflicts := DetectConflicts([]DomainGroup{
+		{
+			Name:      "Alpha",
+			EgressVPN: "wg-us",
+			Rules:     []RoutingRule{{SourceInterfaces: []string{"br0"}, Domains: []string{"example.com"}}},
+		},
+		{
+			Name:      "Beta",
+			EgressVPN: "wg-de",
+			Rules:     []RoutingRule{{SourceInterfaces: []string{"br6"}, Domains: []string{"example.com"}}},
+		},
+	})
+	if len(conflicts) != 1 || conflicts[0].Kind != ConflictDuplicateDomain {
+		t.Fatalf("expected one duplicate domain conflict, got %#v", conflicts)
+	}
+	if len(conflicts[0].Rules) != 2 || conflicts[0].Winner != "Beta" {
+		t.Fatalf("unexpected duplicate domain conflict: %#v", conflicts[0])
+	}
+}
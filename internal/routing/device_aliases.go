@@ -0,0 +1,61 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const maxDeviceAliasLength = 64
+
+// ErrDeviceAliasNotFound indicates no alias is stored for the requested MAC.
+var ErrDeviceAliasNotFound = fmt.Errorf("device alias not found")
+
+// DeviceAlias is a user-assigned device name that overrides discovered names.
+type DeviceAlias struct {
+	MAC       string `json:"mac"`
+	Name      string `json:"name"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// NormalizeDeviceAlias validates an alias and canonicalizes its MAC.
+func NormalizeDeviceAlias(alias DeviceAlias) (DeviceAlias, error) {
+	mac, err := NormalizeDeviceMAC(alias.MAC)
+	if err != nil {
+		return DeviceAlias{}, err
+	}
+	name := strings.TrimSpace(alias.Name)
+	if name == "" {
+		return DeviceAlias{}, fmt.Errorf("%w: device alias name is required", ErrGroupValidation)
+	}
+	if len(name) > maxDeviceAliasLength || strings.ContainsAny(name, "\r\n\t") {
+		return DeviceAlias{}, fmt.Errorf("%w: device alias name %q is invalid", ErrGroupValidation, alias.Name)
+	}
+	alias.MAC = mac
+	alias.Name = name
+	return alias, nil
+}
+
+// NormalizeDeviceMAC returns the lowercase colon form of a 48-bit MAC.
+func NormalizeDeviceMAC(raw string) (string, error) {
+	hw, err := net.ParseMAC(strings.TrimSpace(raw))
+	if err != nil || len(hw) != 6 {
+		return "", fmt.Errorf("%w: invalid device mac %q", ErrGroupValidation, raw)
+	}
+	return strings.ToLower(hw.String()), nil
+}
+
+func (m *Manager) ListDeviceAliases(ctx context.Context) ([]DeviceAlias, error) {
+	return m.store.ListDeviceAliases(ctx)
+}
+
+// SetDeviceAlias creates or renames the alias for a MAC. Aliases only affect
+// naming, so routing is not re-applied.
+func (m *Manager) SetDeviceAlias(ctx context.Context, alias DeviceAlias) (*DeviceAlias, error) {
+	return m.store.UpsertDeviceAlias(ctx, alias)
+}
+
+func (m *Manager) DeleteDeviceAlias(ctx context.Context, mac string) error {
+	return m.store.DeleteDeviceAlias(ctx, mac)
+}
@@ -0,0 +1,189 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const deviceSyncInterval = 5 * time.Minute
+
+// SyncDeviceGroups refreshes synced members from discovered devices and
+// re-applies routing when any group changed. It returns the changed group
+// names. An empty device list is treated as a failed discovery and ignored.
+func (m *Manager) SyncDeviceGroups(ctx context.Context, devices []SyncDevice) ([]string, error) {
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups, err := m.store.ListDeviceGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0)
+	for _, group := range groups {
+		if len(group.SyncNames) == 0 {
+			continue
+		}
+		macs, cidrs := matchSyncedMembers(group.SyncNames, devices)
+		if strings.Join(macs, ",") == strings.Join(group.SyncedMACs, ",") &&
+			strings.Join(cidrs, ",") == strings.Join(group.SyncedCIDRs, ",") {
+			continue
+		}
+		if err := m.store.ReplaceSyncedMembers(ctx, group.ID, macs, cidrs); err != nil {
+			if errors.Is(err, ErrDeviceGroupNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		changed = append(changed, group.Name)
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	if err := m.applyLocked(ctx); err != nil {
+		return changed, err
+	}
+	return changed, nil
+}
+
+// matchSyncedMembers returns the sorted MACs of devices whose name, or
+// UniFi network for "network:" patterns, matches any of the group's sync
+// patterns, and the host CIDRs of their fixed-IP reservations.
+func matchSyncedMembers(patterns []string, devices []SyncDevice) ([]string, []string) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	macs := make([]string, 0)
+	cidrs := make([]string, 0)
+	for _, device := range devices {
+		if !syncPatternsMatch(patterns, device) {
+			continue
+		}
+		macs = append(macs, strings.ToLower(strings.TrimSpace(device.MAC)))
+		if addr, err := netip.ParseAddr(strings.TrimSpace(device.FixedIP)); err == nil {
+			cidrs = append(cidrs, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()).String())
+		}
+	}
+	normalized, err := normalizeMACs(macs)
+	if err != nil || len(normalized) == 0 {
+		return nil, nil
+	}
+	sort.Strings(normalized)
+	return normalized, dedupeSortedStrings(cidrs)
+}
+
+func syncPatternsMatch(patterns []string, device SyncDevice) bool {
+	name := strings.ToLower(strings.TrimSpace(device.Name))
+	network := strings.ToLower(strings.TrimSpace(device.Network))
+	for _, pattern := range patterns {
+		subject := name
+		if strings.HasPrefix(pattern, syncNetworkPrefix) {
+			pattern = strings.TrimPrefix(pattern, syncNetworkPrefix)
+			subject = network
+		}
+		if subject == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceSyncWatcher periodically refreshes device groups with sync patterns
+// from the discovered client list.
+type DeviceSyncWatcher struct {
+	manager  *Manager
+	discover func(ctx context.Context) []SyncDevice
+
+	mu         sync.Mutex
+	started    bool
+	handler    func(changed []string, err error)
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewDeviceSyncWatcher creates a watcher that syncs from discover.
+func NewDeviceSyncWatcher(manager *Manager, discover func(ctx context.Context) []SyncDevice) (*DeviceSyncWatcher, error) {
+	if manager == nil {
+		return nil, fmt.Errorf("routing manager is required")
+	}
+	if discover == nil {
+		return nil, fmt.Errorf("device discovery is required")
+	}
+	return &DeviceSyncWatcher{manager: manager, discover: discover}, nil
+}
+
+// SetHandler registers a callback for changed groups and sync failures.
+func (w *DeviceSyncWatcher) SetHandler(handler func(changed []string, err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handler = handler
+}
+
+// Start launches the sync loop.
+func (w *DeviceSyncWatcher) Start() error {
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.started = true
+	w.loopCancel = cancel
+	w.mu.Unlock()
+
+	w.loopWG.Add(1)
+	go func() {
+		defer w.loopWG.Done()
+		ticker := time.NewTicker(deviceSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.Sync(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the sync loop.
+func (w *DeviceSyncWatcher) Stop() error {
+	w.mu.Lock()
+	loopCancel := w.loopCancel
+	w.started = false
+	w.loopCancel = nil
+	w.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	w.loopWG.Wait()
+	return nil
+}
+
+// Sync runs one discovery and sync, reporting changes or failures to the
+// handler.
+func (w *DeviceSyncWatcher) Sync(ctx context.Context) ([]string, error) {
+	changed, err := w.manager.SyncDeviceGroups(ctx, w.discover(ctx))
+	w.mu.Lock()
+	handler := w.handler
+	w.mu.Unlock()
+	if handler != nil && (len(changed) > 0 || err != nil) {
+		handler(changed, err)
+	}
+	return changed, err
+}
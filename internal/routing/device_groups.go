@@ -0,0 +1,255 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	deviceMemberMAC       = "mac"
+	deviceMemberCIDR      = "cidr"
+	deviceMemberSync      = "sync"
+	deviceMemberSyncedMAC = "synced_mac"
+	// deviceMemberSyncedCIDR holds the reserved addresses of synced devices.
+	deviceMemberSyncedCIDR = "synced_cidr"
+
+	// syncNetworkPrefix marks a sync pattern matched against the device's
+	// UniFi network name instead of its device name.
+	syncNetworkPrefix = "network:"
+)
+
+var (
+	// ErrDeviceGroupNotFound indicates the requested device group id does not exist.
+	ErrDeviceGroupNotFound = fmt.Errorf("device group not found")
+	// ErrDeviceGroupInUse indicates a device group is still referenced by rules.
+	ErrDeviceGroupInUse = fmt.Errorf("device group is in use")
+)
+
+// DeviceGroup is a named set of source devices that routing rules reference
+// by name. Members are MACs and CIDRs; SyncNames are device-name patterns
+// matched against DHCP leases and UniFi clients to fill SyncedMACs, or
+// "network:<pattern>" to match every client of a UniFi network. Matched
+// clients with a UniFi fixed-IP reservation also fill SyncedCIDRs, so they
+// match by address where their MAC is not visible.
+type DeviceGroup struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	MACs        []string `json:"macs,omitempty"`
+	CIDRs       []string `json:"cidrs,omitempty"`
+	SyncNames   []string `json:"syncNames,omitempty"`
+	SyncedMACs  []string `json:"syncedMacs,omitempty"`
+	SyncedCIDRs []string `json:"syncedCidrs,omitempty"`
+	CreatedAt   int64    `json:"createdAt"`
+	UpdatedAt   int64    `json:"updatedAt"`
+}
+
+// SyncDevice is one discovered client offered to device group sync.
+// Network and FixedIP come from the UniFi controller when one is configured.
+type SyncDevice struct {
+	MAC     string
+	Name    string
+	Network string
+	FixedIP string
+}
+
+// deviceGroupMembers is the resolved membership of one device group.
+type deviceGroupMembers struct {
+	macs  []string
+	cidrs []string
+}
+
+// deviceGroupIndex maps device group names to their resolved members.
+type deviceGroupIndex map[string]deviceGroupMembers
+
+// NormalizeDeviceGroup validates a device group and returns a canonical
+// version. Synced members are owned by sync and are not taken from input.
+func NormalizeDeviceGroup(group DeviceGroup) (DeviceGroup, error) {
+	name := strings.TrimSpace(group.Name)
+	if name == "" {
+		return DeviceGroup{}, fmt.Errorf("%w: device group name is required", ErrGroupValidation)
+	}
+	if !groupNamePattern.MatchString(name) {
+		return DeviceGroup{}, fmt.Errorf("%w: device group name %q is invalid", ErrGroupValidation, group.Name)
+	}
+	macs, err := normalizeMACs(group.MACs)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	cidrs, err := normalizeCIDRs(group.CIDRs, "device group")
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	patterns, err := normalizeSyncNames(group.SyncNames)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	if len(macs) == 0 && len(cidrs) == 0 && len(patterns) == 0 {
+		return DeviceGroup{}, fmt.Errorf("%w: device group %q needs at least one MAC, CIDR or sync pattern", ErrGroupValidation, name)
+	}
+	group.Name = name
+	group.MACs = macs
+	group.CIDRs = cidrs
+	group.SyncNames = patterns
+	return group, nil
+}
+
+func normalizeSyncNames(raw []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(raw))
+	out := make([]string, 0, len(raw))
+	for _, entry := range raw {
+		pattern := strings.ToLower(strings.TrimSpace(entry))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: invalid device name pattern %q", ErrGroupValidation, entry)
+		}
+		if _, exists := seen[pattern]; exists {
+			continue
+		}
+		seen[pattern] = struct{}{}
+		out = append(out, pattern)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func normalizeDeviceGroupRefs(raw []string) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]struct{}, len(raw))
+	out := make([]string, 0, len(raw))
+	for _, entry := range raw {
+		trimmed := strings.TrimSpace(entry)
+		if trimmed == "" {
+			continue
+		}
+		if !groupNamePattern.MatchString(trimmed) {
+			return nil, fmt.Errorf("%w: invalid source device group selector %q", ErrGroupValidation, entry)
+		}
+		if _, exists := seen[trimmed]; exists {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		out = append(out, trimmed)
+	}
+	return out, nil
+}
+
+func indexDeviceGroups(groups []DeviceGroup) deviceGroupIndex {
+	index := make(deviceGroupIndex, len(groups))
+	for _, group := range groups {
+		index[group.Name] = deviceGroupMembers{
+			macs:  dedupeSortedStrings(append(append([]string(nil), group.MACs...), group.SyncedMACs...)),
+			cidrs: dedupeSortedStrings(append(append([]string(nil), group.CIDRs...), group.SyncedCIDRs...)),
+		}
+	}
+	return index
+}
+
+// members merges the MACs and CIDRs of the named groups. Unknown names
+// contribute nothing, so a rule referencing them matches no device.
+func (index deviceGroupIndex) members(names []string) ([]string, []string) {
+	macs := make([]string, 0)
+	cidrs := make([]string, 0)
+	for _, name := range names {
+		group := index[name]
+		macs = append(macs, group.macs...)
+		cidrs = append(cidrs, group.cidrs...)
+	}
+	return dedupeSortedStrings(macs), dedupeSortedStrings(cidrs)
+}
+
+// DeviceGroupMembers returns the merged MACs and CIDRs of the named groups.
+func DeviceGroupMembers(groups []DeviceGroup, names []string) ([]string, []string) {
+	return indexDeviceGroups(groups).members(names)
+}
+
+func (m *Manager) ListDeviceGroups(ctx context.Context) ([]DeviceGroup, error) {
+	return m.store.ListDeviceGroups(ctx)
+}
+
+func (m *Manager) GetDeviceGroup(ctx context.Context, id int64) (*DeviceGroup, error) {
+	return m.store.GetDeviceGroup(ctx, id)
+}
+
+func (m *Manager) CreateDeviceGroup(ctx context.Context, group DeviceGroup) (*DeviceGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	created, err := m.store.CreateDeviceGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.applyLocked(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateDeviceGroup overwrites a device group. Renaming it rewrites every
+// rule reference so rules keep following the group.
+func (m *Manager) UpdateDeviceGroup(ctx context.Context, id int64, group DeviceGroup) (*DeviceGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated, err := m.store.UpdateDeviceGroup(ctx, id, group)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.applyLocked(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteDeviceGroup removes a device group that no rule references.
+func (m *Manager) DeleteDeviceGroup(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.store.DeleteDeviceGroup(ctx, id); err != nil {
+		return err
+	}
+	return m.applyLocked(ctx)
+}
+
+// ReplaceDeviceGroups replaces all device groups without applying; callers
+// restoring a backup follow it with ReplaceState, which applies once.
+func (m *Manager) ReplaceDeviceGroups(ctx context.Context, groups []DeviceGroup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store.ReplaceDeviceGroups(ctx, groups)
+}
+
+// validateDeviceGroupRefs rejects rules that reference unknown device groups.
+func (m *Manager) validateDeviceGroupRefs(ctx context.Context, group DomainGroup) error {
+	refs := make([]string, 0)
+	for _, rule := range group.Rules {
+		names, err := normalizeDeviceGroupRefs(append(append([]string(nil), rule.SourceDeviceGroups...),
+			selectorValuesFromRaw(normalizeRuleRawSelectors(rule.RawSelectors).SourceDeviceGroups)...))
+		if err != nil {
+			return err
+		}
+		refs = append(refs, names...)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	known, err := m.store.ListDeviceGroups(ctx)
+	if err != nil {
+		return err
+	}
+	index := indexDeviceGroups(known)
+	for _, name := range refs {
+		if _, ok := index[name]; !ok {
+			return fmt.Errorf("%w: unknown device group %q", ErrGroupValidation, name)
+		}
+	}
+	return nil
+}
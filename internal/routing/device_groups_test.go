@@ -0,0 +1,222 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestManagerDeviceGroupRenameRewritesRuleReferences(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, rules := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{{
+		Name:          "wg-sgp",
+		RouteTable:    201,
+		FWMark:        0x169,
+		InterfaceName: "wg-sgp",
+	}}})
+
+	devices, err := manager.CreateDeviceGroup(ctx, DeviceGroup{
+		Name:  "Kids",
+		MACs:  []string{"00:30:93:10:0A:12"},
+		CIDRs: []string{"10.0.5.0/24"},
+	})
+	if err != nil {
+		t.Fatalf("CreateDeviceGroup failed: %v", err)
+	}
+	if len(devices.MACs) != 1 || devices.MACs[0] != "00:30:93:10:0a:12" {
+		t.Fatalf("expected normalized MAC, got %#v", devices.MACs)
+	}
+
+	group, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Kids-VPN",
+		EgressVPN: "wg-sgp",
+		Rules: []RoutingRule{{
+			Name:               "Kids",
+			SourceDeviceGroups: []string{"Kids"},
+			DestinationCIDRs:   []string{"1.1.1.0/24"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if len(rules.bindings) != 1 {
+		t.Fatalf("expected one binding, got %d", len(rules.bindings))
+	}
+	binding := rules.bindings[0]
+	if !binding.HasSourceDevices || !binding.HasSourceDeviceSet {
+		t.Fatalf("expected device selectors in binding: %+v", binding)
+	}
+	if len(binding.SourceDeviceMACs) != 1 || binding.SourceDeviceMACs[0] != "00:30:93:10:0a:12" {
+		t.Fatalf("unexpected device MACs: %#v", binding.SourceDeviceMACs)
+	}
+
+	if _, err := manager.UpdateDeviceGroup(ctx, devices.ID, DeviceGroup{
+		Name: "Children",
+		MACs: []string{"00:30:93:10:0a:12"},
+	}); err != nil {
+		t.Fatalf("UpdateDeviceGroup failed: %v", err)
+	}
+	stored, err := manager.GetGroup(ctx, group.ID)
+	if err != nil {
+		t.Fatalf("GetGroup failed: %v", err)
+	}
+	refs := stored.Rules[0].SourceDeviceGroups
+	if len(refs) != 1 || refs[0] != "Children" {
+		t.Fatalf("expected rule reference to follow rename, got %#v", refs)
+	}
+
+	err = manager.DeleteDeviceGroup(ctx, devices.ID)
+	if !errors.Is(err, ErrDeviceGroupInUse) {
+		t.Fatalf("expected ErrDeviceGroupInUse, got %v", err)
+	}
+}
+
+func TestManagerCreateGroupRejectsUnknownDeviceGroup(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, _ := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{{
+		Name:          "wg-sgp",
+		RouteTable:    201,
+		FWMark:        0x169,
+		InterfaceName: "wg-sgp",
+	}}})
+
+	_, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Kids-VPN",
+		EgressVPN: "wg-sgp",
+		Rules: []RoutingRule{{
+			Name:               "Kids",
+			SourceDeviceGroups: []string{"Missing"},
+			DestinationCIDRs:   []string{"1.1.1.0/24"},
+		}},
+	})
+	if !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+func TestManagerSyncDeviceGroupsMatchesNamePatterns(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, _ := newRoutingTestManager(t, &mockVPNLister{})
+
+	if _, err := manager.CreateDeviceGroup(ctx, DeviceGroup{
+		Name:      "Kids",
+		SyncNames: []string{"Kids-*"},
+	}); err != nil {
+		t.Fatalf("CreateDeviceGroup failed: %v", err)
+	}
+
+	devices := []SyncDevice{
+		{MAC: "00:30:93:10:0a:12", Name: "kids-ipad"},
+		{MAC: "00:30:93:10:0a:13", Name: "Office-PC"},
+	}
+	changed, err := manager.SyncDeviceGroups(ctx, devices)
+	if err != nil {
+		t.Fatalf("SyncDeviceGroups failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "Kids" {
+		t.Fatalf("expected Kids to change, got %#v", changed)
+	}
+	groups, err := manager.ListDeviceGroups(ctx)
+	if err != nil {
+		t.Fatalf("ListDeviceGroups failed: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].SyncedMACs) != 1 || groups[0].SyncedMACs[0] != "00:30:93:10:0a:12" {
+		t.Fatalf("unexpected synced MACs: %#v", groups)
+	}
+
+	changed, err = manager.SyncDeviceGroups(ctx, devices)
+	if err != nil {
+		t.Fatalf("second SyncDeviceGroups failed: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("expected no changes on repeated sync, got %#v", changed)
+	}
+	if changed, err := manager.SyncDeviceGroups(ctx, nil); err != nil || len(changed) != 0 {
+		t.Fatalf("expected empty discovery to be ignored, changed=%#v err=%v", changed, err)
+	}
+}
+
+func TestManagerSyncDeviceGroupsMatchesNetworksAndReservations(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, _ := newRoutingTestManager(t, &mockVPNLister{})
+
+	if _, err := manager.CreateDeviceGroup(ctx, DeviceGroup{
+		Name:      "IoT",
+		SyncNames: []string{"network:IoT*"},
+	}); err != nil {
+		t.Fatalf("CreateDeviceGroup failed: %v", err)
+	}
+
+	changed, err := manager.SyncDeviceGroups(ctx, []SyncDevice{
+		{MAC: "00:30:93:10:0a:20", Name: "thermostat", Network: "IoT Devices", FixedIP: "192.168.30.20"},
+		{MAC: "00:30:93:10:0a:21", Name: "camera", Network: "IoT Devices"},
+		{MAC: "00:30:93:10:0a:22", Name: "iot-named-laptop", Network: "Default", FixedIP: "192.168.1.50"},
+	})
+	if err != nil {
+		t.Fatalf("SyncDeviceGroups failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "IoT" {
+		t.Fatalf("expected IoT to change, got %#v", changed)
+	}
+	groups, err := manager.ListDeviceGroups(ctx)
+	if err != nil {
+		t.Fatalf("ListDeviceGroups failed: %v", err)
+	}
+	group := groups[0]
+	if strings.Join(group.SyncedMACs, ",") != "00:30:93:10:0a:20,00:30:93:10:0a:21" {
+		t.Fatalf("unexpected synced MACs: %#v", group.SyncedMACs)
+	}
+	if strings.Join(group.SyncedCIDRs, ",") != "192.168.30.20/32" {
+		t.Fatalf("unexpected synced CIDRs: %#v", group.SyncedCIDRs)
+	}
+	macs, cidrs := DeviceGroupMembers(groups, []string{"IoT"})
+	if len(macs) != 2 || len(cidrs) != 1 || cidrs[0] != "192.168.30.20/32" {
+		t.Fatalf("unexpected members: %#v %#v", macs, cidrs)
+	}
+
+	// Dropping the pattern clears both kinds of synced members.
+	updated, err := manager.UpdateDeviceGroup(ctx, group.ID, DeviceGroup{Name: "IoT", MACs: []string{"00:30:93:10:0a:30"}})
+	if err != nil {
+		t.Fatalf("UpdateDeviceGroup failed: %v", err)
+	}
+	if len(updated.SyncedMACs) != 0 || len(updated.SyncedCIDRs) != 0 {
+		t.Fatalf("expected synced members to be cleared, got %#v", updated)
+	}
+}
+
+func TestApplyRulesMatchesSourceDeviceMACsOrSet(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+
+	bindings := []RouteBinding{{
+		GroupName:          "Kids",
+		RuleIndex:          0,
+		SourceDeviceMACs:   []string{"00:30:93:10:0a:12"},
+		SourceDeviceSetV4:  "svpn_kids_r1g4",
+		SourceDeviceSetV6:  "svpn_kids_r1g6",
+		HasSourceDevices:   true,
+		HasSourceDeviceSet: true,
+		DestinationSetV4:   "svpn_kids_r1d4",
+		DestinationSetV6:   "svpn_kids_r1d6",
+		HasDestination:     true,
+		Mark:               0x171,
+		RouteTable:         203,
+		Interface:          "wg-kids",
+	}}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	calls := joinCalls(mock.RunCalls)
+	for _, expected := range []string{
+		"iptables -t mangle -A SVPNA_001_4 -m set --match-set svpn_kids_r1d4 dst -m mac --mac-source 00:30:93:10:0a:12 -j MARK --set-mark 0x171",
+		"iptables -t mangle -A SVPNA_001_4 -m set --match-set svpn_kids_r1d4 dst -m set --match-set svpn_kids_r1g4 src -j MARK --set-mark 0x171",
+	} {
+		if !containsCall(calls, expected) {
+			t.Fatalf("expected call %q in %#v", expected, calls)
+		}
+	}
+}
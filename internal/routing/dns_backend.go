@@ -0,0 +1,371 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+// DNS backends that populate the routing ipsets from DNS answers.
+const (
+	DNSBackendDnsmasq = "dnsmasq"
+	DNSBackendAdGuard = "adguard"
+	DNSBackendPihole  = "pihole"
+)
+
+const (
+	// adGuardIPSetFileName is the AdGuard ipset file in the data directory
+	// when no path is set.
+	adGuardIPSetFileName = "adguard.ipset"
+
+	adGuardRulesBegin = "! split-vpn-webui begin (managed, do not edit)"
+	adGuardRulesEnd   = "! split-vpn-webui end"
+	adGuardAPITimeout = 10 * time.Second
+)
+
+// DNSBackendConfig selects and configures the DNS backend.
+type DNSBackendConfig struct {
+	Backend string
+	// ConfigPath overrides where the backend's config is written. "" uses
+	// the backend default; Pi-hole has none, since its config directory is
+	// outside the data directory and writing there must be chosen.
+	ConfigPath string
+	// AdGuard Home API, used to keep routed domains allowlisted in the
+	// custom filtering rules. Optional.
+	AdGuardURL      string
+	AdGuardUsername string
+	AdGuardPassword string
+}
+
+// DNSBackendConfigFromSettings reads the DNS backend settings.
+func DNSBackendConfigFromSettings(current settings.Settings) DNSBackendConfig {
+	backend, err := ParseDNSBackend(current.DNSBackend)
+	if err != nil {
+		backend = DNSBackendDnsmasq
+	}
+	return DNSBackendConfig{
+		Backend:         backend,
+		ConfigPath:      strings.TrimSpace(current.DNSBackendConfigPath),
+		AdGuardURL:      strings.TrimSpace(current.AdGuardURL),
+		AdGuardUsername: current.AdGuardUsername,
+		AdGuardPassword: current.AdGuardPassword,
+	}
+}
+
+// ParseDNSBackend normalizes a DNS backend name; "" is dnsmasq.
+func ParseDNSBackend(raw string) (string, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(raw)); backend {
+	case "", DNSBackendDnsmasq:
+		return DNSBackendDnsmasq, nil
+	case DNSBackendAdGuard, DNSBackendPihole:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("dns backend must be %q, %q or %q", DNSBackendDnsmasq, DNSBackendAdGuard, DNSBackendPihole)
+	}
+}
+
+// NewDNSBackend creates the DNS manager for cfg. dataDir holds the AdGuard
+// ipset file when cfg names no path.
+func NewDNSBackend(cfg DNSBackendConfig, dataDir string, exec Executor) (DNSManager, error) {
+	backend, err := ParseDNSBackend(cfg.Backend)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateDNSBackendConfigPath(backend, cfg.ConfigPath); err != nil {
+		return nil, err
+	}
+	switch backend {
+	case DNSBackendAdGuard:
+		path := cfg.ConfigPath
+		if path == "" {
+			if strings.TrimSpace(dataDir) == "" {
+				return nil, fmt.Errorf("adguard ipset file path is required")
+			}
+			path = filepath.Join(dataDir, adGuardIPSetFileName)
+		}
+		return NewAdGuardManager(path, cfg.AdGuardURL, cfg.AdGuardUsername, cfg.AdGuardPassword, exec), nil
+	case DNSBackendPihole:
+		m := NewDnsmasqManagerWithPath(cfg.ConfigPath, exec)
+		// Pi-hole's FTL embeds dnsmasq and only re-reads ipset lines on a
+		// full restart.
+		m.reloadCommand = []string{"pihole", "restartdns"}
+		return m, nil
+	default:
+		if cfg.ConfigPath != "" {
+			return NewDnsmasqManagerWithPath(cfg.ConfigPath, exec), nil
+		}
+		return NewDnsmasqManager(exec)
+	}
+}
+
+// ValidateDNSBackendConfigPath checks the config path for backend: it must
+// be absolute, and Pi-hole needs one, e.g.
+// /etc/dnsmasq.d/split-vpn-webui.conf.
+func ValidateDNSBackendConfigPath(backend, path string) error {
+	path = strings.TrimSpace(path)
+	if path != "" && !filepath.IsAbs(path) {
+		return fmt.Errorf("dns backend config path must be absolute")
+	}
+	if backend == DNSBackendPihole && path == "" {
+		return fmt.Errorf("the pihole backend needs a config path, e.g. /etc/dnsmasq.d/%s", dnsmasqConfigFileName)
+	}
+	return nil
+}
+
+// SetDNSBackend swaps the DNS manager. The old backend's config is emptied
+// first so it stops filling sets; a failure there is returned but the swap
+// still happens. Callers apply routing afterwards to write the new config.
+func (m *Manager) SetDNSBackend(dns DNSManager) error {
+	if dns == nil {
+		return fmt.Errorf("dns manager is required")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old := m.dnsmasq
+	m.dnsmasq = dns
+	if old == nil {
+		return nil
+	}
+	if err := old.WriteDnsmasqConf(old.GenerateDnsmasqConf(nil)); err != nil {
+		return fmt.Errorf("clear previous dns backend config: %w", err)
+	}
+	if fragments, ok := old.(DnsmasqFragmentManager); ok {
+		if err := fragments.WriteDnsmasqFragments(nil); err != nil {
+			return fmt.Errorf("clear previous dns backend fragments: %w", err)
+		}
+	}
+	if err := old.ReloadDnsmasq(); err != nil {
+		return fmt.Errorf("reload previous dns backend: %w", err)
+	}
+	return nil
+}
+
+// AdGuardManager pushes domain to ipset mappings to AdGuard Home through
+// its ipset_file, and optionally allowlists the routed domains in the
+// custom filtering rules so blocklists cannot stop the sets filling.
+type AdGuardManager struct {
+	exec       Executor
+	configPath string
+	apiURL     string
+	username   string
+	password   string
+	client     *http.Client
+}
+
+// NewAdGuardManager creates an AdGuard Home backend writing to configPath,
+// which AdGuardHome.yaml must name as dns.ipset_file. apiURL may be empty.
+func NewAdGuardManager(configPath, apiURL, username, password string, exec Executor) *AdGuardManager {
+	if exec == nil {
+		exec = osExec{}
+	}
+	return &AdGuardManager{
+		exec:       exec,
+		configPath: configPath,
+		apiURL:     strings.TrimRight(apiURL, "/"),
+		username:   username,
+		password:   password,
+		client:     &http.Client{Timeout: adGuardAPITimeout},
+	}
+}
+
+func (m *AdGuardManager) ConfigPath() string {
+	return m.configPath
+}
+
+// GenerateDnsmasqConf renders ipset_file lines ("domain/set4,set6") for all
+// groups. AdGuard has no per-domain upstreams in this file, so group
+// upstream overrides and isolation only apply to dnsmasq.
+func (m *AdGuardManager) GenerateDnsmasqConf(groups []DomainGroup) string {
+	lines := []string{"# Generated by split-vpn-webui. Do not edit."}
+	seen := map[string]struct{}{}
+	add := func(domain, v4Set, v6Set string) {
+		trimmed := strings.TrimSpace(strings.TrimPrefix(strings.ToLower(domain), "*."))
+		if trimmed == "" {
+			return
+		}
+		line := fmt.Sprintf("%s/%s,%s", trimmed, v4Set, v6Set)
+		if _, exists := seen[line]; exists {
+			return
+		}
+		seen[line] = struct{}{}
+		lines = append(lines, line)
+	}
+
+	sortedGroups := make([]DomainGroup, len(groups))
+	copy(sortedGroups, groups)
+	sort.Slice(sortedGroups, func(i, j int) bool { return sortedGroups[i].Name < sortedGroups[j].Name })
+	for _, group := range sortedGroups {
+		if len(group.Rules) == 0 {
+			v4Set, v6Set := GroupSetNames(group.Name)
+			for _, domain := range group.Domains {
+				add(domain, v4Set, v6Set)
+			}
+			continue
+		}
+		for idx, rule := range group.Rules {
+			sets := RuleSetNames(group.Name, idx)
+			domains := append([]string(nil), rule.Domains...)
+			domains = append(domains, rule.WildcardDomains...)
+			sort.Strings(domains)
+			for _, domain := range domains {
+				add(domain, sets.DestinationV4, sets.DestinationV6)
+			}
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ReadDnsmasqConf returns the current ipset file, or "" when none exists.
+func (m *AdGuardManager) ReadDnsmasqConf() (string, error) {
+	data, err := os.ReadFile(m.configPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read adguard ipset file: %w", err)
+	}
+	return string(data), nil
+}
+
+// WriteDnsmasqConf writes the ipset file and syncs the allowlist rules when
+// the API is configured.
+func (m *AdGuardManager) WriteDnsmasqConf(content string) error {
+	if strings.TrimSpace(m.configPath) == "" {
+		return fmt.Errorf("adguard ipset file path is required")
+	}
+	if err := writeDnsmasqFile(m.configPath, content); err != nil {
+		return err
+	}
+	if m.apiURL == "" {
+		return nil
+	}
+	return m.syncFilteringRules(adGuardIPSetDomains(content))
+}
+
+// ReloadDnsmasq restarts AdGuard Home, which only reads ipset_file on start.
+func (m *AdGuardManager) ReloadDnsmasq() error {
+	systemdErr := m.exec.Run("systemctl", "restart", "AdGuardHome")
+	if systemdErr == nil {
+		return nil
+	}
+	serviceErr := m.exec.Run("/opt/AdGuardHome/AdGuardHome", "-s", "restart")
+	if serviceErr == nil {
+		return nil
+	}
+	return fmt.Errorf("adguard home restart failed (systemctl: %v, AdGuardHome -s restart: %w)", systemdErr, serviceErr)
+}
+
+func adGuardIPSetDomains(content string) []string {
+	seen := map[string]struct{}{}
+	var domains []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hosts, _, ok := strings.Cut(line, "/")
+		if !ok {
+			continue
+		}
+		for _, host := range strings.Split(hosts, ",") {
+			host = strings.TrimSpace(host)
+			if _, exists := seen[host]; host == "" || exists {
+				continue
+			}
+			seen[host] = struct{}{}
+			domains = append(domains, host)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// syncFilteringRules replaces the managed block in AdGuard's custom
+// filtering rules, keeping the user's own rules.
+func (m *AdGuardManager) syncFilteringRules(domains []string) error {
+	var status struct {
+		UserRules []string `json:"user_rules"`
+	}
+	if err := m.adGuardRequest(http.MethodGet, "/control/filtering/status", nil, &status); err != nil {
+		return err
+	}
+	rules := mergeAdGuardRules(status.UserRules, domains)
+	return m.adGuardRequest(http.MethodPost, "/control/filtering/set_rules", map[string]any{"rules": rules}, nil)
+}
+
+func mergeAdGuardRules(existing, domains []string) []string {
+	rules := make([]string, 0, len(existing)+len(domains)+2)
+	inBlock := false
+	for _, rule := range existing {
+		switch {
+		case rule == adGuardRulesBegin:
+			inBlock = true
+		case rule == adGuardRulesEnd:
+			inBlock = false
+		case !inBlock:
+			rules = append(rules, rule)
+		}
+	}
+	if len(domains) == 0 {
+		return rules
+	}
+	rules = append(rules, adGuardRulesBegin)
+	for _, domain := range domains {
+		rules = append(rules, "@@||"+domain+"^$important")
+	}
+	return append(rules, adGuardRulesEnd)
+}
+
+func (m *AdGuardManager) adGuardRequest(method, path string, body any, out any) error {
+	endpoint, err := url.JoinPath(m.apiURL, path)
+	if err != nil {
+		return fmt.Errorf("adguard api url: %w", err)
+	}
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), adGuardAPITimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if m.username != "" || m.password != "" {
+		req.SetBasicAuth(m.username, m.password)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("adguard api %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("adguard api %s: %s: %s", path, resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode adguard api %s: %w", path, err)
+	}
+	return nil
+}
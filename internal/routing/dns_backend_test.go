@@ -0,0 +1,112 @@
+package routing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdGuardManagerWritesIPSetFileAndSyncsRules(t *testing.T) {
+	var saved []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/control/filtering/status":
+			_ = json.NewEncoder(w).Encode(map[string]any{"user_rules": []string{
+				"||ads.example^",
+				adGuardRulesBegin,
+				"@@||old.example^$important",
+				adGuardRulesEnd,
+			}})
+		case "/control/filtering/set_rules":
+			var body struct {
+				Rules []string `json:"rules"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			saved = body.Rules
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	mock := &MockExec{}
+	path := filepath.Join(t.TempDir(), "split-vpn-webui.ipset")
+	m := NewAdGuardManager(path, server.URL, "admin", "secret", mock)
+	groups := []DomainGroup{
+		{Name: "Streaming", Domains: []string{"*.hbo.com", "max.com"}, DnsmasqIsolated: true},
+	}
+	content := m.GenerateDnsmasqConf(groups)
+	v4Set, v6Set := GroupSetNames("Streaming")
+	if !strings.Contains(content, "hbo.com/"+v4Set+","+v6Set) || !strings.Contains(content, "max.com/"+v4Set+","+v6Set) {
+		t.Fatalf("unexpected ipset file\n%s", content)
+	}
+
+	if err := m.WriteDnsmasqConf(content); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != content {
+		t.Fatalf("unexpected ipset file on disk %q (%v)", string(data), err)
+	}
+	want := []string{"||ads.example^", adGuardRulesBegin, "@@||hbo.com^$important", "@@||max.com^$important", adGuardRulesEnd}
+	if strings.Join(saved, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected filtering rules %#v", saved)
+	}
+
+	if err := m.ReloadDnsmasq(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(mock.RunCalls) != 1 || strings.Join(mock.RunCalls[0], " ") != "systemctl restart AdGuardHome" {
+		t.Fatalf("unexpected reload commands %#v", mock.RunCalls)
+	}
+}
+
+func TestNewDNSBackendPihole(t *testing.T) {
+	mock := &MockExec{}
+	path := filepath.Join(t.TempDir(), "split-vpn-webui.conf")
+	backend, err := NewDNSBackend(DNSBackendConfig{Backend: "Pihole", ConfigPath: path}, t.TempDir(), mock)
+	if err != nil {
+		t.Fatalf("new backend: %v", err)
+	}
+	if err := backend.ReloadDnsmasq(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(mock.RunCalls) != 1 || strings.Join(mock.RunCalls[0], " ") != "pihole restartdns" {
+		t.Fatalf("unexpected reload commands %#v", mock.RunCalls)
+	}
+	if _, err := NewDNSBackend(DNSBackendConfig{Backend: "bind"}, t.TempDir(), mock); err == nil {
+		t.Fatal("expected unknown backend to be rejected")
+	}
+	if _, err := NewDNSBackend(DNSBackendConfig{Backend: DNSBackendAdGuard, ConfigPath: "relative.ipset"}, t.TempDir(), mock); err == nil {
+		t.Fatal("expected relative config path to be rejected")
+	}
+	// Pi-hole's config directory is outside the data directory, so it is
+	// only written when a path is set.
+	if _, err := NewDNSBackend(DNSBackendConfig{Backend: DNSBackendPihole}, t.TempDir(), mock); err == nil {
+		t.Fatal("expected pihole without a config path to be rejected")
+	}
+}
+
+func TestNewDNSBackendAdGuardDefaultsToDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	backend, err := NewDNSBackend(DNSBackendConfig{Backend: DNSBackendAdGuard}, dataDir, &MockExec{})
+	if err != nil {
+		t.Fatalf("new backend: %v", err)
+	}
+	if got := backend.(*AdGuardManager).ConfigPath(); got != filepath.Join(dataDir, adGuardIPSetFileName) {
+		t.Fatalf("ipset file = %q, want it in the data directory", got)
+	}
+	if _, err := NewDNSBackend(DNSBackendConfig{Backend: DNSBackendAdGuard}, "", &MockExec{}); err == nil {
+		t.Fatal("expected adguard without a path or data directory to be rejected")
+	}
+}
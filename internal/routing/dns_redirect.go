@@ -0,0 +1,78 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+// DNS redirect modes for DomainGroup.DNSRedirect.
+const (
+	DNSRedirectNone  = ""
+	DNSRedirectVPN   = "vpn"
+	DNSRedirectLocal = "local"
+)
+
+// rulesHaveSourceSelectors reports whether every rule selects clients by
+// source. DNS redirection without a source selector would capture the DNS of
+// the whole LAN, so it is only allowed on source-scoped groups.
+func rulesHaveSourceSelectors(rules []RoutingRule) bool {
+	for _, rule := range rules {
+		if len(rule.SourceInterfaces) == 0 &&
+			len(rule.SourceCIDRs) == 0 &&
+			len(rule.SourceMACs) == 0 &&
+			len(rule.SourceDeviceGroups) == 0 {
+			return false
+		}
+	}
+	return len(rules) > 0
+}
+
+// vpnDNSServers splits the resolvers pushed by a VPN profile by family.
+func vpnDNSServers(profile *vpn.VPNProfile) (v4 []string, v6 []string) {
+	for _, addr := range profile.DNSServers() {
+		if addr.Is4() {
+			v4 = append(v4, addr.String())
+		} else {
+			v6 = append(v6, addr.String())
+		}
+	}
+	return v4, v6
+}
+
+// validateDNSRedirect rejects "vpn" redirection to a profile that has no
+// resolvers to redirect to.
+func (m *Manager) validateDNSRedirect(group DomainGroup) error {
+	if strings.ToLower(strings.TrimSpace(group.DNSRedirect)) != DNSRedirectVPN {
+		return nil
+	}
+	egress := strings.TrimSpace(group.EgressVPN)
+	vpns, err := m.vpnLister.List()
+	if err != nil {
+		return err
+	}
+	for _, profile := range vpns {
+		if profile == nil || profile.Name != egress {
+			continue
+		}
+		v4, v6 := vpnDNSServers(profile)
+		if len(v4) == 0 && len(v6) == 0 {
+			return fmt.Errorf("%w: egress vpn %q has no DNS servers to redirect to", ErrGroupValidation, egress)
+		}
+		return nil
+	}
+	return nil
+}
+
+// validateGroupRefs runs the checks that need state outside the group itself.
+func (m *Manager) validateGroupRefs(ctx context.Context, group DomainGroup) error {
+	if err := m.validateEgressVPN(group.EgressVPN); err != nil {
+		return err
+	}
+	if err := m.validateDeviceGroupRefs(ctx, group); err != nil {
+		return err
+	}
+	return m.validateDNSRedirect(group)
+}
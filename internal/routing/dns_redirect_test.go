@@ -0,0 +1,144 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestNormalizeAndValidateDNSRedirect(t *testing.T) {
+	group, err := NormalizeAndValidate(DomainGroup{
+		Name:        "Kids",
+		EgressVPN:   "wg-sgp",
+		DNSRedirect: " VPN ",
+		Rules:       []RoutingRule{{SourceMACs: []string{"aa:bb:cc:dd:ee:ff"}}},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeAndValidate failed: %v", err)
+	}
+	if group.DNSRedirect != DNSRedirectVPN {
+		t.Fatalf("expected normalized dns redirect %q, got %q", DNSRedirectVPN, group.DNSRedirect)
+	}
+
+	for _, invalid := range []DomainGroup{
+		{Name: "Kids", EgressVPN: "wg-sgp", DNSRedirect: "google", Rules: []RoutingRule{{SourceMACs: []string{"aa:bb:cc:dd:ee:ff"}}}},
+		{Name: "Kids", EgressVPN: "wg-sgp", DNSRedirect: "local", Rules: []RoutingRule{{Domains: []string{"example.com"}}}},
+	} {
+		if _, err := NormalizeAndValidate(invalid); !errors.Is(err, ErrGroupValidation) {
+			t.Fatalf("expected ErrGroupValidation for %+v, got %v", invalid, err)
+		}
+	}
+}
+
+func TestStorePersistsDNSRedirect(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	created, err := store.Create(ctx, DomainGroup{
+		Name:        "Kids",
+		EgressVPN:   "wg-sgp",
+		DNSRedirect: DNSRedirectLocal,
+		Rules:       []RoutingRule{{SourceInterfaces: []string{"br40"}}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.DNSRedirect != DNSRedirectLocal {
+		t.Fatalf("expected persisted dns redirect, got %q", created.DNSRedirect)
+	}
+	created.DNSRedirect = DNSRedirectNone
+	updated, err := store.Update(ctx, created.ID, *created)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.DNSRedirect != DNSRedirectNone {
+		t.Fatalf("expected cleared dns redirect, got %q", updated.DNSRedirect)
+	}
+}
+
+func TestManagerDNSRedirectRequiresVPNResolvers(t *testing.T) {
+	ctx := context.Background()
+	profile := &vpn.VPNProfile{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"}
+	manager, _, _, rules := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{profile}})
+	group := DomainGroup{
+		Name:        "Kids",
+		EgressVPN:   "wg-sgp",
+		DNSRedirect: DNSRedirectVPN,
+		Rules:       []RoutingRule{{SourceCIDRs: []string{"10.0.40.0/24"}}},
+	}
+
+	if _, err := manager.CreateGroup(ctx, group); !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected ErrGroupValidation without vpn dns, got %v", err)
+	}
+
+	profile.WireGuard = &vpn.WireGuardConfig{Interface: vpn.WireGuardInterface{DNS: []string{"10.2.0.1", "fd00::1", "vpn.lan"}}}
+	if _, err := manager.CreateGroup(ctx, group); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if len(rules.bindings) != 1 {
+		t.Fatalf("expected one binding, got %d", len(rules.bindings))
+	}
+	binding := rules.bindings[0]
+	if binding.DNSRedirect != DNSRedirectVPN ||
+		strings.Join(binding.DNSServersV4, ",") != "10.2.0.1" ||
+		strings.Join(binding.DNSServersV6, ",") != "fd00::1" {
+		t.Fatalf("unexpected dns redirect binding: %+v", binding)
+	}
+}
+
+func TestApplyRulesAddsDNSRedirectRules(t *testing.T) {
+	mock := &MockExec{}
+	bindings := []RouteBinding{
+		{
+			GroupName:           "Kids",
+			SourceSetV4:         "svpn_kids_r1s4",
+			SourceSetV6:         "svpn_kids_r1s6",
+			ExcludedSourceSetV4: "svpn_kids_r1xs4",
+			ExcludedSourceSetV6: "svpn_kids_r1xs6",
+			HasSource:           true,
+			HasExcludedSource:   true,
+			DNSRedirect:         DNSRedirectVPN,
+			DNSServersV4:        []string{"10.2.0.1"},
+			Mark:                0x169,
+			RouteTable:          201,
+			Interface:           "wg-sgp",
+		},
+		{
+			GroupName:        "Guests",
+			SourceInterfaces: []string{"br50"},
+			DNSRedirect:      DNSRedirectLocal,
+			Mark:             0x170,
+			RouteTable:       202,
+			Interface:        "wg-guest",
+		},
+	}
+	if err := NewRuleManager(mock).ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	calls := joinCalls(mock.RunCalls)
+	for _, expected := range []string{
+		"iptables -t nat -C PREROUTING -j SVPN_DNS",
+		"iptables -t nat -A SVPN_DNS_A -m set --match-set svpn_kids_r1s4 src -m set ! --match-set svpn_kids_r1xs4 src -p udp --dport 53 -j DNAT --to-destination 10.2.0.1",
+		"iptables -t nat -A SVPN_DNS_A -m set --match-set svpn_kids_r1s4 src -m set ! --match-set svpn_kids_r1xs4 src -p tcp --dport 853 -j DNAT --to-destination 10.2.0.1",
+		"iptables -t mangle -A SVPNA_002_4 -m set --match-set svpn_kids_r1s4 src -m set ! --match-set svpn_kids_r1xs4 src -p udp --dport 53 -j MARK --set-mark 0x169",
+		"ip6tables -t mangle -A SVPNA_002_6 -m set --match-set svpn_kids_r1s6 src -m set ! --match-set svpn_kids_r1xs6 src -p tcp --dport 53 -j MARK --set-mark 0x169",
+		"iptables -t nat -A SVPN_DNS_A -i br50 -p udp --dport 53 -j REDIRECT",
+		"ip6tables -t nat -A SVPN_DNS_A -i br50 -p tcp --dport 53 -j REDIRECT",
+	} {
+		if !containsCall(calls, expected) {
+			t.Fatalf("expected call %q in %#v", expected, calls)
+		}
+	}
+	for _, call := range calls {
+		if strings.HasPrefix(call, "ip6tables -t nat -A SVPN_DNS_A -m set") {
+			t.Fatalf("expected no ipv6 DNAT without an ipv6 resolver, got %q", call)
+		}
+		if strings.Contains(call, "--match-set svpn_kids_r1s4") && strings.Contains(call, "-i br50") {
+			t.Fatalf("unexpected cross-binding rule %q", call)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,6 +16,11 @@ const dnsmasqConfigFileName = "split-vpn-webui.conf"
 type DnsmasqManager struct {
 	exec       Executor
 	configPath string
+	// procRoot is where instance detection reads processes; "" is /proc.
+	procRoot string
+	// reloadCommand replaces the HUP/systemctl reload chain when set, e.g.
+	// for Pi-hole.
+	reloadCommand []string
 }
 
 func NewDnsmasqManager(exec Executor) (*DnsmasqManager, error) {
@@ -61,7 +67,8 @@ func detectDnsmasqConfDir(candidates []string) (string, error) {
 	return primary, nil
 }
 
-// GenerateDnsmasqConf renders config lines for all group domains.
+// GenerateDnsmasqConf renders config lines for all group domains. Isolated
+// groups are left to their own fragments (GenerateDnsmasqFragments).
 func (m *DnsmasqManager) GenerateDnsmasqConf(groups []DomainGroup) string {
 	lines := []string{
 		"# Generated by split-vpn-webui. Do not edit.",
@@ -72,40 +79,54 @@ func (m *DnsmasqManager) GenerateDnsmasqConf(groups []DomainGroup) string {
 	copy(sortedGroups, groups)
 	sort.Slice(sortedGroups, func(i, j int) bool { return sortedGroups[i].Name < sortedGroups[j].Name })
 	for _, group := range sortedGroups {
-		if len(group.Rules) == 0 {
-			v4Set, v6Set := GroupSetNames(group.Name)
-			for _, domain := range group.Domains {
-				line := dnsmasqLine(domain, v4Set, v6Set)
-				if line == "" {
-					continue
-				}
-				if _, exists := seenLines[line]; exists {
-					continue
-				}
-				seenLines[line] = struct{}{}
-				lines = append(lines, line)
-			}
+		if group.DnsmasqIsolated {
 			continue
 		}
-		for idx, rule := range group.Rules {
-			sets := RuleSetNames(group.Name, idx)
-			domains := append([]string(nil), rule.Domains...)
-			domains = append(domains, rule.WildcardDomains...)
-			sort.Strings(domains)
-			for _, domain := range domains {
-				line := dnsmasqLine(domain, sets.DestinationV4, sets.DestinationV6)
-				if line == "" {
-					continue
-				}
-				if _, exists := seenLines[line]; exists {
-					continue
-				}
-				seenLines[line] = struct{}{}
-				lines = append(lines, line)
+		lines = appendGroupDnsmasqLines(lines, group, seenLines)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// appendGroupDnsmasqLines adds the group's ipset and upstream server lines,
+// skipping lines already in seenLines.
+func appendGroupDnsmasqLines(lines []string, group DomainGroup, seenLines map[string]struct{}) []string {
+	add := func(line string) {
+		if line == "" {
+			return
+		}
+		if _, exists := seenLines[line]; exists {
+			return
+		}
+		seenLines[line] = struct{}{}
+		lines = append(lines, line)
+	}
+	if len(group.Rules) == 0 {
+		v4Set, v6Set := GroupSetNames(group.Name)
+		for _, domain := range group.Domains {
+			add(dnsmasqLine(domain, v4Set, v6Set))
+		}
+		for _, domain := range group.Domains {
+			for _, upstream := range group.DnsmasqUpstreams {
+				add(dnsmasqServerLine(domain, upstream))
+			}
+		}
+		return lines
+	}
+	for idx, rule := range group.Rules {
+		sets := RuleSetNames(group.Name, idx)
+		domains := append([]string(nil), rule.Domains...)
+		domains = append(domains, rule.WildcardDomains...)
+		sort.Strings(domains)
+		for _, domain := range domains {
+			add(dnsmasqLine(domain, sets.DestinationV4, sets.DestinationV6))
+		}
+		for _, domain := range domains {
+			for _, upstream := range group.DnsmasqUpstreams {
+				add(dnsmasqServerLine(domain, upstream))
 			}
 		}
 	}
-	return strings.Join(lines, "\n") + "\n"
+	return lines
 }
 
 func dnsmasqLine(domain, v4Set, v6Set string) string {
@@ -116,15 +137,31 @@ func dnsmasqLine(domain, v4Set, v6Set string) string {
 	return fmt.Sprintf("ipset=/%s/%s,%s", trimmed, v4Set, v6Set)
 }
 
+// ReadDnsmasqConf returns the current config file, or "" when none exists.
+func (m *DnsmasqManager) ReadDnsmasqConf() (string, error) {
+	data, err := os.ReadFile(m.configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read dnsmasq config: %w", err)
+	}
+	return string(data), nil
+}
+
 // WriteDnsmasqConf writes config atomically.
 func (m *DnsmasqManager) WriteDnsmasqConf(content string) error {
 	if strings.TrimSpace(m.configPath) == "" {
 		return fmt.Errorf("dnsmasq config path is required")
 	}
-	if err := os.MkdirAll(filepath.Dir(m.configPath), 0o755); err != nil {
+	return writeDnsmasqFile(m.configPath, content)
+}
+
+func writeDnsmasqFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
-	tmpPath := m.configPath + ".tmp"
+	tmpPath := path + ".tmp"
 	if err := os.WriteFile(tmpPath, []byte(content), 0o644); err != nil {
 		return err
 	}
@@ -132,7 +169,7 @@ func (m *DnsmasqManager) WriteDnsmasqConf(content string) error {
 		_ = os.Remove(tmpPath)
 		return err
 	}
-	if err := os.Rename(tmpPath, m.configPath); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		_ = os.Remove(tmpPath)
 		return err
 	}
@@ -141,6 +178,12 @@ func (m *DnsmasqManager) WriteDnsmasqConf(content string) error {
 
 // ReloadDnsmasq applies config updates with minimal interruption.
 func (m *DnsmasqManager) ReloadDnsmasq() error {
+	if len(m.reloadCommand) > 0 {
+		if err := m.exec.Run(m.reloadCommand[0], m.reloadCommand[1:]...); err != nil {
+			return fmt.Errorf("dnsmasq reload failed (%s: %w)", strings.Join(m.reloadCommand, " "), err)
+		}
+		return nil
+	}
 	var hupErr error
 	pidBytes, pidErr := m.exec.Output("pidof", "dnsmasq")
 	if pidErr == nil {
@@ -0,0 +1,141 @@
+package routing
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// dnsmasqFragmentPrefix names per-group conf fragments, written next to
+	// the shared config file.
+	dnsmasqFragmentPrefix = "split-vpn-webui-group-"
+
+	minIPSetTimeoutSeconds = 60
+	maxIPSetTimeoutSeconds = 7 * 86400
+)
+
+// DnsmasqFragmentManager is an optional DNSManager extension that writes
+// isolated groups to their own conf fragments.
+type DnsmasqFragmentManager interface {
+	GenerateDnsmasqFragments(groups []DomainGroup) map[string]string
+	WriteDnsmasqFragments(fragments map[string]string) error
+}
+
+// GenerateDnsmasqFragments renders one conf fragment per isolated group,
+// keyed by file name.
+func (m *DnsmasqManager) GenerateDnsmasqFragments(groups []DomainGroup) map[string]string {
+	fragments := make(map[string]string)
+	sortedGroups := make([]DomainGroup, len(groups))
+	copy(sortedGroups, groups)
+	sort.Slice(sortedGroups, func(i, j int) bool { return sortedGroups[i].Name < sortedGroups[j].Name })
+	for _, group := range sortedGroups {
+		if !group.DnsmasqIsolated {
+			continue
+		}
+		name := dnsmasqFragmentName(group.Name)
+		for suffix := 2; ; suffix++ {
+			if _, taken := fragments[name]; !taken {
+				break
+			}
+			name = dnsmasqFragmentName(group.Name + "-" + strconv.Itoa(suffix))
+		}
+		lines := []string{fmt.Sprintf("# Generated by split-vpn-webui for group %q. Do not edit.", group.Name)}
+		lines = appendGroupDnsmasqLines(lines, group, map[string]struct{}{})
+		fragments[name] = strings.Join(lines, "\n") + "\n"
+	}
+	return fragments
+}
+
+// WriteDnsmasqFragments writes fragments atomically and removes fragments
+// of groups that are no longer isolated.
+func (m *DnsmasqManager) WriteDnsmasqFragments(fragments map[string]string) error {
+	if strings.TrimSpace(m.configPath) == "" {
+		return fmt.Errorf("dnsmasq config path is required")
+	}
+	dir := filepath.Dir(m.configPath)
+	existing, err := filepath.Glob(filepath.Join(dir, dnsmasqFragmentPrefix+"*.conf"))
+	if err != nil {
+		return err
+	}
+	for _, path := range existing {
+		if _, keep := fragments[filepath.Base(path)]; keep {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove dnsmasq fragment: %w", err)
+		}
+	}
+	names := make([]string, 0, len(fragments))
+	for name := range fragments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeDnsmasqFile(filepath.Join(dir, name), fragments[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dnsmasqFragmentName derives a file name from a group name.
+func dnsmasqFragmentName(groupName string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(groupName) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			slug.WriteRune(r)
+		default:
+			slug.WriteByte('-')
+		}
+	}
+	return dnsmasqFragmentPrefix + strings.Trim(slug.String(), "-") + ".conf"
+}
+
+func dnsmasqServerLine(domain, upstream string) string {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(strings.ToLower(domain), "*."))
+	if trimmed == "" {
+		return ""
+	}
+	return fmt.Sprintf("server=/%s/%s", trimmed, upstream)
+}
+
+// normalizeDnsmasqUpstreams validates upstream servers in dnsmasq's
+// ip[#port] form and removes duplicates.
+func normalizeDnsmasqUpstreams(raw []string) ([]string, error) {
+	out := make([]string, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, entry := range raw {
+		trimmed := strings.TrimSpace(entry)
+		if trimmed == "" {
+			continue
+		}
+		host, port, hasPort := strings.Cut(trimmed, "#")
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: invalid dnsmasq upstream %q (want ip or ip#port)", ErrGroupValidation, entry)
+		}
+		normalized := ip.String()
+		if hasPort {
+			value, err := strconv.Atoi(port)
+			if err != nil || value < 1 || value > 65535 {
+				return nil, fmt.Errorf("%w: invalid dnsmasq upstream port in %q", ErrGroupValidation, entry)
+			}
+			normalized += "#" + strconv.Itoa(value)
+		}
+		if _, exists := seen[normalized]; exists {
+			continue
+		}
+		seen[normalized] = struct{}{}
+		out = append(out, normalized)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
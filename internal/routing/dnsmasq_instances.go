@@ -0,0 +1,275 @@
+package routing
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DNS instance kinds reported by DetectDnsmasqInstances.
+const (
+	DnsmasqKindUniFi  = "unifi"
+	DnsmasqKindCustom = "custom"
+	// DnsmasqKindPihole is Pi-hole's FTL, which embeds dnsmasq.
+	DnsmasqKindPihole = "pihole"
+)
+
+// ErrDnsmasqInspectUnsupported is returned when the DNS manager cannot
+// inspect running dnsmasq processes.
+var ErrDnsmasqInspectUnsupported = errors.New("dnsmasq instance detection is not supported")
+
+// DnsmasqInspector is an optional DNSManager extension that reports which
+// dnsmasq processes run and whether they load the generated config.
+type DnsmasqInspector interface {
+	DetectDnsmasqInstances() (DnsmasqStatus, error)
+}
+
+// DnsmasqInstance is one running dnsmasq process.
+type DnsmasqInstance struct {
+	PID       int      `json:"pid"`
+	Kind      string   `json:"kind"`
+	Cmdline   []string `json:"cmdline"`
+	ConfDirs  []string `json:"confDirs,omitempty"`
+	ConfFiles []string `json:"confFiles,omitempty"`
+	// ServesDNS reports that the process owns a port 53 socket, i.e. it
+	// answers clients rather than running with DNS disabled.
+	ServesDNS bool `json:"servesDns"`
+	// LoadsConfig reports that the process reads the directory the
+	// generated config and fragments are written to.
+	LoadsConfig bool `json:"loadsConfig"`
+}
+
+// DnsmasqStatus describes where config is written and which dnsmasq
+// instances pick it up.
+type DnsmasqStatus struct {
+	ConfigPath string            `json:"configPath"`
+	Fragments  []string          `json:"fragments"`
+	Instances  []DnsmasqInstance `json:"instances"`
+	Warnings   []string          `json:"warnings,omitempty"`
+}
+
+// DnsmasqStatus reports running dnsmasq instances and config fragments.
+func (m *Manager) DnsmasqStatus() (DnsmasqStatus, error) {
+	inspector, ok := m.dnsmasq.(DnsmasqInspector)
+	if !ok {
+		return DnsmasqStatus{}, ErrDnsmasqInspectUnsupported
+	}
+	return inspector.DetectDnsmasqInstances()
+}
+
+// DetectDnsmasqInstances scans /proc for dnsmasq processes.
+func (m *DnsmasqManager) DetectDnsmasqInstances() (DnsmasqStatus, error) {
+	procRoot := m.procRoot
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	configDir := filepath.Clean(filepath.Dir(m.configPath))
+	status := DnsmasqStatus{ConfigPath: m.configPath, Fragments: []string{}, Instances: []DnsmasqInstance{}}
+	if fragments, err := filepath.Glob(filepath.Join(configDir, dnsmasqFragmentPrefix+"*.conf")); err == nil {
+		for _, fragment := range fragments {
+			status.Fragments = append(status.Fragments, filepath.Base(fragment))
+		}
+	}
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return DnsmasqStatus{}, fmt.Errorf("read %s: %w", procRoot, err)
+	}
+	dnsInodes := listeningSocketInodes(procRoot, 53)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "cmdline"))
+		if err != nil || len(raw) == 0 {
+			continue
+		}
+		args := strings.Split(strings.TrimRight(string(raw), "\x00"), "\x00")
+		defaultConf := "/etc/dnsmasq.conf"
+		switch filepath.Base(args[0]) {
+		case "dnsmasq":
+		case "pihole-FTL":
+			defaultConf = "/etc/pihole/dnsmasq.conf"
+		default:
+			continue
+		}
+		instance := DnsmasqInstance{PID: pid, Cmdline: args}
+		instance.ConfDirs, instance.ConfFiles = dnsmasqConfigSources(args, defaultConf)
+		instance.Kind = dnsmasqInstanceKind(instance)
+		for _, dir := range instance.ConfDirs {
+			if filepath.Clean(dir) == configDir {
+				instance.LoadsConfig = true
+			}
+		}
+		instance.ServesDNS = processOwnsSocket(procRoot, entry.Name(), dnsInodes)
+		status.Instances = append(status.Instances, instance)
+	}
+	sort.Slice(status.Instances, func(i, j int) bool { return status.Instances[i].PID < status.Instances[j].PID })
+	status.Warnings = dnsmasqWarnings(status, configDir)
+	return status, nil
+}
+
+// dnsmasqConfigSources returns the conf dirs and files named on the command
+// line, plus conf-dir lines inside those files. defaultConf is read when no
+// file is named.
+func dnsmasqConfigSources(args []string, defaultConf string) ([]string, []string) {
+	var dirs, files []string
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		var isDir bool
+		switch {
+		case strings.HasPrefix(arg, "--conf-dir="):
+			value, isDir = strings.TrimPrefix(arg, "--conf-dir="), true
+		case strings.HasPrefix(arg, "--conf-file="):
+			value = strings.TrimPrefix(arg, "--conf-file=")
+		case arg == "-7" || arg == "-C":
+			if i+1 < len(args) {
+				value, isDir = args[i+1], arg == "-7"
+				i++
+			}
+		case strings.HasPrefix(arg, "-7"):
+			value, isDir = strings.TrimPrefix(arg, "-7"), true
+		case strings.HasPrefix(arg, "-C"):
+			value = strings.TrimPrefix(arg, "-C")
+		default:
+			continue
+		}
+		// conf-dir accepts ",ext" filters after the path.
+		value, _, _ = strings.Cut(value, ",")
+		if value == "" {
+			continue
+		}
+		if isDir {
+			dirs = append(dirs, value)
+		} else {
+			files = append(files, value)
+		}
+	}
+	if len(files) == 0 {
+		files = append(files, defaultConf)
+	}
+	for _, file := range files {
+		dirs = append(dirs, confDirsFromFile(file)...)
+	}
+	return dirs, files
+}
+
+func confDirsFromFile(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if value, ok := strings.CutPrefix(line, "conf-dir="); ok {
+			value, _, _ = strings.Cut(value, ",")
+			if value = strings.TrimSpace(value); value != "" {
+				dirs = append(dirs, value)
+			}
+		}
+	}
+	return dirs
+}
+
+// dnsmasqInstanceKind tells UniFi's dnsmasq, which reads its runtime config
+// from /run/dnsmasq*, from one started by the user.
+func dnsmasqInstanceKind(instance DnsmasqInstance) string {
+	if filepath.Base(instance.Cmdline[0]) == "pihole-FTL" {
+		return DnsmasqKindPihole
+	}
+	for _, path := range append(append([]string(nil), instance.ConfDirs...), instance.ConfFiles...) {
+		if strings.HasPrefix(filepath.Clean(path), "/run/dnsmasq") {
+			return DnsmasqKindUniFi
+		}
+	}
+	return DnsmasqKindCustom
+}
+
+// listeningSocketInodes returns inodes of TCP listeners and bound UDP
+// sockets on port.
+func listeningSocketInodes(procRoot string, port int) map[string]struct{} {
+	inodes := make(map[string]struct{})
+	hexPort := fmt.Sprintf(":%04X", port)
+	for _, name := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		file, err := os.Open(filepath.Join(procRoot, "net", name))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || !strings.HasSuffix(fields[1], hexPort) {
+				continue
+			}
+			// 0A is LISTEN for TCP; UDP sockets are unconnected (07).
+			if strings.HasPrefix(name, "tcp") && fields[3] != "0A" {
+				continue
+			}
+			inodes[fields[9]] = struct{}{}
+		}
+		file.Close()
+	}
+	return inodes
+}
+
+func processOwnsSocket(procRoot, pid string, inodes map[string]struct{}) bool {
+	if len(inodes) == 0 {
+		return false
+	}
+	fdDir := filepath.Join(procRoot, pid, "fd")
+	fds, err := os.ReadDir(fdDir)
+	if err != nil {
+		return false
+	}
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if err != nil {
+			continue
+		}
+		inode, ok := strings.CutPrefix(target, "socket:[")
+		if !ok {
+			continue
+		}
+		if _, found := inodes[strings.TrimSuffix(inode, "]")]; found {
+			return true
+		}
+	}
+	return false
+}
+
+func dnsmasqWarnings(status DnsmasqStatus, configDir string) []string {
+	if len(status.Instances) == 0 {
+		return []string{"no dnsmasq process is running; domain selectors will not populate ipsets"}
+	}
+	var warnings []string
+	loaded := false
+	serving := false
+	for _, instance := range status.Instances {
+		loaded = loaded || instance.LoadsConfig
+		if !instance.ServesDNS {
+			continue
+		}
+		serving = true
+		if !instance.LoadsConfig {
+			warnings = append(warnings, fmt.Sprintf("dnsmasq pid %d (%s) answers DNS on port 53 but does not read %s", instance.PID, instance.Kind, configDir))
+		}
+	}
+	if !loaded {
+		warnings = append(warnings, fmt.Sprintf("no running dnsmasq reads %s", configDir))
+	}
+	if !serving {
+		warnings = append(warnings, "no dnsmasq process owns a port 53 socket; clients may be served by another resolver")
+	}
+	return warnings
+}
@@ -91,3 +91,107 @@ func TestWriteAndReloadDnsmasq(t *testing.T) {
 		t.Fatalf("expected first reload command kill -HUP 1234, got %#v", mock.RunCalls)
 	}
 }
+
+func TestDnsmasqFragmentsIsolateGroups(t *testing.T) {
+	dir := t.TempDir()
+	m := NewDnsmasqManagerWithPath(filepath.Join(dir, "split-vpn-webui.conf"), nil)
+	groups := []DomainGroup{
+		{Name: "Gaming", Domains: []string{"rbxcdn.com"}},
+		{
+			Name:             "Streaming SG",
+			Domains:          []string{"hbo.com"},
+			DnsmasqIsolated:  true,
+			DnsmasqUpstreams: []string{"10.64.0.1"},
+		},
+	}
+	shared := m.GenerateDnsmasqConf(groups)
+	if strings.Contains(shared, "hbo.com") || !strings.Contains(shared, "ipset=/rbxcdn.com/") {
+		t.Fatalf("expected isolated group outside the shared config\n%s", shared)
+	}
+	fragments := m.GenerateDnsmasqFragments(groups)
+	fragment, ok := fragments["split-vpn-webui-group-streaming-sg.conf"]
+	if !ok || len(fragments) != 1 {
+		t.Fatalf("unexpected fragments: %#v", fragments)
+	}
+	for _, expected := range []string{"ipset=/hbo.com/", "server=/hbo.com/10.64.0.1"} {
+		if !strings.Contains(fragment, expected) {
+			t.Fatalf("expected fragment to contain %q\n%s", expected, fragment)
+		}
+	}
+
+	stale := filepath.Join(dir, "split-vpn-webui-group-old.conf")
+	if err := os.WriteFile(stale, []byte("# old\n"), 0o644); err != nil {
+		t.Fatalf("write stale fragment: %v", err)
+	}
+	if err := m.WriteDnsmasqFragments(fragments); err != nil {
+		t.Fatalf("write fragments: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected stale fragment removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "split-vpn-webui-group-streaming-sg.conf")); err != nil {
+		t.Fatalf("expected fragment written: %v", err)
+	}
+}
+
+func TestNormalizeDnsmasqUpstreams(t *testing.T) {
+	got, err := normalizeDnsmasqUpstreams([]string{" 10.64.0.1 ", "1.1.1.1#5353", "10.64.0.1", ""})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if strings.Join(got, ",") != "10.64.0.1,1.1.1.1#5353" {
+		t.Fatalf("unexpected upstreams %v", got)
+	}
+	for _, invalid := range []string{"dns.example", "1.1.1.1#0", "1.1.1.1#x"} {
+		if _, err := normalizeDnsmasqUpstreams([]string{invalid}); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestDetectDnsmasqInstancesFindsServingInstance(t *testing.T) {
+	base := t.TempDir()
+	confDir := filepath.Join(base, "run", "dnsmasq.d")
+	procRoot := filepath.Join(base, "proc")
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	writeFile(filepath.Join(procRoot, "100", "cmdline"), "/usr/sbin/dnsmasq\x00--conf-dir="+confDir+",*.conf\x00--conf-file=/dev/null\x00")
+	writeFile(filepath.Join(procRoot, "200", "cmdline"), "dnsmasq\x00-C\x00/dev/null\x00--port=0\x00")
+	writeFile(filepath.Join(procRoot, "300", "cmdline"), "/usr/bin/sleep\x0010\x00")
+	writeFile(filepath.Join(procRoot, "net", "udp"),
+		"  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"+
+			"   1: 00000000:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 4242 2 0000000000000000 0\n")
+	if err := os.MkdirAll(filepath.Join(procRoot, "100", "fd"), 0o755); err != nil {
+		t.Fatalf("mkdir fd: %v", err)
+	}
+	if err := os.Symlink("socket:[4242]", filepath.Join(procRoot, "100", "fd", "5")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	m := NewDnsmasqManagerWithPath(filepath.Join(confDir, "split-vpn-webui.conf"), nil)
+	m.procRoot = procRoot
+	status, err := m.DetectDnsmasqInstances()
+	if err != nil {
+		t.Fatalf("detect: %v", err)
+	}
+	if len(status.Instances) != 2 {
+		t.Fatalf("expected two dnsmasq instances, got %#v", status.Instances)
+	}
+	serving := status.Instances[0]
+	if serving.PID != 100 || !serving.ServesDNS || !serving.LoadsConfig {
+		t.Fatalf("unexpected serving instance %#v", serving)
+	}
+	if other := status.Instances[1]; other.ServesDNS || other.LoadsConfig || other.Kind != DnsmasqKindCustom {
+		t.Fatalf("unexpected second instance %#v", other)
+	}
+	if len(status.Warnings) != 0 {
+		t.Fatalf("expected no warnings, got %v", status.Warnings)
+	}
+}
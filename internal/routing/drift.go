@@ -0,0 +1,244 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+// Drift modes.
+const (
+	DriftModeOff    = "off"
+	DriftModeAlert  = "alert"
+	DriftModeRepair = "repair"
+)
+
+const (
+	defaultDriftIntervalSeconds = 300
+	minDriftIntervalSeconds     = 30
+	maxDriftIntervalSeconds     = 24 * 3600
+)
+
+// DriftReport is the outcome of one comparison of live routing state with
+// the state computed from the store.
+type DriftReport struct {
+	CheckedAt       time.Time `json:"checkedAt"`
+	Mode            string    `json:"mode"`
+	Drifted         bool      `json:"drifted"`
+	MissingSets     []string  `json:"missingSets,omitempty"`
+	MissingRules    []string  `json:"missingRules,omitempty"`
+	UnexpectedRules []string  `json:"unexpectedRules,omitempty"`
+	DnsmasqChanged  bool      `json:"dnsmasqChanged,omitempty"`
+	Repaired        bool      `json:"repaired,omitempty"`
+	RepairError     string    `json:"repairError,omitempty"`
+	// Error is set when live state could not be read; drift is then unknown
+	// and no repair is attempted.
+	Error string `json:"error,omitempty"`
+}
+
+// ParseDriftMode validates a drift mode setting. Empty selects alert.
+func ParseDriftMode(raw string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(raw))
+	switch mode {
+	case "":
+		return DriftModeAlert, nil
+	case DriftModeOff, DriftModeAlert, DriftModeRepair:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("drift mode must be off, alert or repair")
+	}
+}
+
+// DetectDrift compares live ipsets, iptables/ip rules and dnsmasq config with
+// the desired state. Set membership is not compared: dnsmasq adds resolved
+// addresses to domain sets at runtime, so only missing sets count as drift.
+func (m *Manager) DetectDrift(ctx context.Context) (DriftReport, error) {
+	result, err := m.DryRunApply(ctx)
+	if err != nil {
+		return DriftReport{}, err
+	}
+	report := DriftReport{}
+	if len(result.Warnings) > 0 {
+		report.Error = strings.Join(result.Warnings, "; ")
+		return report, nil
+	}
+	for _, set := range result.Sets {
+		if set.Action == SetActionCreate {
+			report.MissingSets = append(report.MissingSets, set.Name)
+		}
+	}
+	report.MissingRules = result.Rules.Added
+	report.UnexpectedRules = result.Rules.Removed
+	report.DnsmasqChanged = result.Dnsmasq.Changed
+	report.Drifted = len(report.MissingSets) > 0 || result.Rules.Changed || report.DnsmasqChanged
+	return report, nil
+}
+
+// DriftMonitor periodically checks for routing drift, e.g. chains wiped by
+// UniFi provisioning, and alerts or re-applies depending on the drift mode.
+type DriftMonitor struct {
+	manager  *Manager
+	settings *settings.Manager
+	now      func() time.Time
+
+	mu         sync.Mutex
+	started    bool
+	last       *DriftReport
+	handler    func(DriftReport)
+	repair     func(ctx context.Context) error
+	loopCancel context.CancelFunc
+	checkMu    sync.Mutex
+
+	loopWG sync.WaitGroup
+}
+
+// NewDriftMonitor creates a drift monitor that repairs with manager.Apply.
+func NewDriftMonitor(manager *Manager, settingsManager *settings.Manager) (*DriftMonitor, error) {
+	if manager == nil {
+		return nil, fmt.Errorf("routing manager is required")
+	}
+	if settingsManager == nil {
+		return nil, fmt.Errorf("settings manager is required")
+	}
+	return &DriftMonitor{
+		manager:  manager,
+		settings: settingsManager,
+		now:      time.Now,
+		repair:   manager.Apply,
+	}, nil
+}
+
+// SetHandler registers a callback for reports that show drift, a repair, or
+// the return to a clean state.
+func (d *DriftMonitor) SetHandler(handler func(DriftReport)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handler = handler
+}
+
+// SetRepairer replaces the function used to re-apply routing state.
+func (d *DriftMonitor) SetRepairer(repair func(ctx context.Context) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if repair != nil {
+		d.repair = repair
+	}
+}
+
+// Last returns the most recent report, or nil before the first check.
+func (d *DriftMonitor) Last() *DriftReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.last == nil {
+		return nil
+	}
+	copied := *d.last
+	return &copied
+}
+
+// Start launches the periodic drift loop.
+func (d *DriftMonitor) Start() error {
+	d.mu.Lock()
+	if d.started {
+		d.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.started = true
+	d.loopCancel = cancel
+	d.mu.Unlock()
+
+	d.loopWG.Add(1)
+	go func() {
+		defer d.loopWG.Done()
+		for {
+			current, _ := d.settings.Get()
+			timer := time.NewTimer(driftIntervalFromSettings(current))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				if mode, err := ParseDriftMode(current.DriftMode); err == nil && mode != DriftModeOff {
+					_, _ = d.Check(ctx)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the periodic loop.
+func (d *DriftMonitor) Stop() error {
+	d.mu.Lock()
+	loopCancel := d.loopCancel
+	d.started = false
+	d.loopCancel = nil
+	d.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	d.loopWG.Wait()
+	return nil
+}
+
+// Check runs one drift check, repairing when the mode is repair.
+func (d *DriftMonitor) Check(ctx context.Context) (DriftReport, error) {
+	d.checkMu.Lock()
+	defer d.checkMu.Unlock()
+
+	current, err := d.settings.Get()
+	if err != nil {
+		return DriftReport{}, err
+	}
+	mode, err := ParseDriftMode(current.DriftMode)
+	if err != nil {
+		mode = DriftModeAlert
+	}
+
+	report, err := d.manager.DetectDrift(ctx)
+	if err != nil {
+		return DriftReport{}, err
+	}
+	report.CheckedAt = d.now().UTC()
+	report.Mode = mode
+	if report.Drifted && mode == DriftModeRepair {
+		d.mu.Lock()
+		repair := d.repair
+		d.mu.Unlock()
+		if err := repair(ctx); err != nil {
+			report.RepairError = err.Error()
+		} else {
+			report.Repaired = true
+		}
+	}
+
+	d.mu.Lock()
+	wasDrifted := d.last != nil && d.last.Drifted && !d.last.Repaired
+	d.last = &report
+	handler := d.handler
+	d.mu.Unlock()
+	if handler != nil && (report.Drifted || (wasDrifted && report.Error == "")) {
+		handler(report)
+	}
+	return report, nil
+}
+
+func driftIntervalFromSettings(current settings.Settings) time.Duration {
+	seconds := current.DriftIntervalSeconds
+	if seconds <= 0 {
+		seconds = defaultDriftIntervalSeconds
+	}
+	if seconds < minDriftIntervalSeconds {
+		seconds = minDriftIntervalSeconds
+	}
+	if seconds > maxDriftIntervalSeconds {
+		seconds = maxDriftIntervalSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,135 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/vpn"
+)
+
+func newDriftTestManager(t *testing.T) (*Manager, *MockExec, RuleSetPair) {
+	t.Helper()
+	ctx := context.Background()
+	exec := &MockExec{}
+	manager := newRoutingTestManagerWithDeps(t,
+		NewIPSetManager(exec),
+		NewDnsmasqManagerWithPath(filepath.Join(t.TempDir(), "split-vpn-webui.conf"), exec),
+		NewRuleManager(exec),
+		&mockVPNLister{profiles: []*vpn.VPNProfile{{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"}}},
+	)
+	if _, err := manager.store.Create(ctx, DomainGroup{
+		Name:      "Media",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Name: "cdn", DestinationCIDRs: []string{"10.0.0.1/32"}}},
+	}); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	return manager, exec, RuleSetNames("Media", 0)
+}
+
+// wipedRoutingOutputs lists an empty system, as after UniFi provisioning.
+func wipedRoutingOutputs() map[string][]byte {
+	return map[string][]byte{
+		"ipset list -name":       []byte(""),
+		"iptables -t mangle -S":  []byte(""),
+		"iptables -t nat -S":     []byte(""),
+		"ip6tables -t mangle -S": []byte(""),
+		"ip6tables -t nat -S":    []byte(""),
+		"ip rule show":           []byte(""),
+		"ip -6 rule show":        []byte(""),
+	}
+}
+
+func TestParseDriftMode(t *testing.T) {
+	cases := map[string]string{"": DriftModeAlert, "Repair": DriftModeRepair, " off ": DriftModeOff, "alert": DriftModeAlert}
+	for raw, want := range cases {
+		got, err := ParseDriftMode(raw)
+		if err != nil || got != want {
+			t.Fatalf("ParseDriftMode(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, err := ParseDriftMode("fix"); err == nil {
+		t.Fatalf("expected invalid drift mode to fail")
+	}
+}
+
+func TestDetectDriftReportsWipedState(t *testing.T) {
+	manager, exec, pair := newDriftTestManager(t)
+	exec.Outputs = wipedRoutingOutputs()
+
+	report, err := manager.DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift failed: %v", err)
+	}
+	if report.Error != "" {
+		t.Fatalf("unexpected error: %s", report.Error)
+	}
+	if !report.Drifted || !containsString(report.MissingSets, pair.DestinationV4) || len(report.MissingRules) == 0 || !report.DnsmasqChanged {
+		t.Fatalf("expected wiped state to be reported as drift, got %+v", report)
+	}
+	if len(exec.RunCalls) != 0 {
+		t.Fatalf("expected drift detection to run no mutating commands, got %#v", exec.RunCalls)
+	}
+}
+
+func TestDetectDriftReportsUnreadableStateAsError(t *testing.T) {
+	manager, exec, _ := newDriftTestManager(t)
+	exec.OutputErrors = map[string]error{"ipset list -name": errors.New("ipset: not permitted")}
+
+	report, err := manager.DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift failed: %v", err)
+	}
+	if report.Drifted || report.Error == "" {
+		t.Fatalf("expected unreadable state to be an error rather than drift, got %+v", report)
+	}
+}
+
+func TestDriftMonitorRepairsOnlyInRepairMode(t *testing.T) {
+	manager, exec, _ := newDriftTestManager(t)
+	exec.Outputs = wipedRoutingOutputs()
+	settingsManager := settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	if err := settingsManager.Save(settings.Settings{DriftMode: DriftModeRepair}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	monitor, err := NewDriftMonitor(manager, settingsManager)
+	if err != nil {
+		t.Fatalf("NewDriftMonitor failed: %v", err)
+	}
+	repairs := 0
+	monitor.SetRepairer(func(context.Context) error {
+		repairs++
+		return nil
+	})
+	reports := make([]DriftReport, 0)
+	monitor.SetHandler(func(report DriftReport) { reports = append(reports, report) })
+
+	report, err := monitor.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !report.Drifted || !report.Repaired || repairs != 1 || report.Mode != DriftModeRepair {
+		t.Fatalf("expected drift to be repaired, got %+v (repairs=%d)", report, repairs)
+	}
+	if len(reports) != 1 || monitor.Last() == nil || !monitor.Last().Repaired {
+		t.Fatalf("expected handler and last report to see the repair, got %+v", reports)
+	}
+
+	if err := settingsManager.Save(settings.Settings{DriftMode: DriftModeAlert}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	monitor.SetRepairer(func(context.Context) error {
+		repairs++
+		return errors.New("should not repair in alert mode")
+	})
+	report, err = monitor.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !report.Drifted || report.Repaired || report.RepairError != "" || repairs != 1 {
+		t.Fatalf("expected alert mode to report without repairing, got %+v", report)
+	}
+}
@@ -1,11 +1,18 @@
 package routing
 
-import "os/exec"
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
 
 // Executor abstracts command execution for ipset/dnsmasq/iptables operations.
 type Executor interface {
 	Run(name string, args ...string) error
 	Output(name string, args ...string) ([]byte, error)
+	// RunWithInput runs a command with input piped to stdin, e.g. ipset restore.
+	RunWithInput(input []byte, name string, args ...string) error
 }
 
 type osExec struct{}
@@ -17,3 +24,16 @@ func (osExec) Run(name string, args ...string) error {
 func (osExec) Output(name string, args ...string) ([]byte, error) {
 	return exec.Command(name, args...).CombinedOutput()
 }
+
+func (osExec) RunWithInput(input []byte, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if detail := strings.TrimSpace(string(output)); detail != "" {
+			return fmt.Errorf("%w: %s", err, detail)
+		}
+		return err
+	}
+	return nil
+}
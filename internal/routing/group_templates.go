@@ -0,0 +1,138 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ErrGroupTemplateNotFound indicates the template id does not exist.
+var ErrGroupTemplateNotFound = fmt.Errorf("group template not found")
+
+const maxGroupTemplateDescription = 256
+
+// GroupTemplate is a reusable group definition. Group carries the rules and
+// options but no id, name or egress VPN; those are chosen when the template
+// is instantiated.
+type GroupTemplate struct {
+	ID          int64       `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Group       DomainGroup `json:"group"`
+	CreatedAt   int64       `json:"createdAt"`
+	UpdatedAt   int64       `json:"updatedAt"`
+}
+
+// NormalizeGroupTemplate validates a template and strips the group fields a
+// template does not carry.
+func NormalizeGroupTemplate(template GroupTemplate) (GroupTemplate, error) {
+	name := strings.TrimSpace(template.Name)
+	if name == "" {
+		return GroupTemplate{}, fmt.Errorf("%w: template name is required", ErrGroupValidation)
+	}
+	if !groupNamePattern.MatchString(name) {
+		return GroupTemplate{}, fmt.Errorf("%w: template name %q is invalid", ErrGroupValidation, template.Name)
+	}
+	description := strings.TrimSpace(template.Description)
+	if len(description) > maxGroupTemplateDescription {
+		return GroupTemplate{}, fmt.Errorf("%w: template description is longer than %d characters", ErrGroupValidation, maxGroupTemplateDescription)
+	}
+	// Templates have no name or VPN of their own; validate everything else
+	// with placeholders.
+	probe := template.Group
+	probe.Name = name
+	probe.EgressVPN = "template"
+	group, err := NormalizeAndValidate(probe)
+	if err != nil {
+		return GroupTemplate{}, err
+	}
+	template.Name = name
+	template.Description = description
+	template.Group = detachGroup(group)
+	template.Group.EgressVPN = ""
+	return template, nil
+}
+
+// detachGroup returns a copy of group without the identifiers and names that
+// tie it to stored rows, ready to be saved as a new group. Copies of managed
+// groups are ordinary groups.
+func detachGroup(group DomainGroup) DomainGroup {
+	out := group
+	out.ID = 0
+	out.Name = ""
+	out.Managed = ""
+	out.CreatedAt = 0
+	out.UpdatedAt = 0
+	out.Domains = nil
+	out.Rules = make([]RoutingRule, 0, len(group.Rules))
+	for _, rule := range group.Rules {
+		rule.ID = 0
+		out.Rules = append(out.Rules, rule)
+	}
+	return out
+}
+
+// DuplicateGroup copies a group's rules and options into a new group. An
+// empty egressVPN keeps the source group's VPN; an empty name derives one
+// from the source name and the VPN.
+func (m *Manager) DuplicateGroup(ctx context.Context, id int64, name, egressVPN string) (*DomainGroup, error) {
+	source, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	copied := detachGroup(*source)
+	copied.EgressVPN = strings.TrimSpace(egressVPN)
+	if copied.EgressVPN == "" {
+		copied.EgressVPN = source.EgressVPN
+	}
+	copied.Name = strings.TrimSpace(name)
+	if copied.Name == "" {
+		suffix := "copy"
+		if copied.EgressVPN != source.EgressVPN {
+			suffix = copied.EgressVPN
+		}
+		copied.Name = source.Name + "-" + suffix
+	}
+	return m.CreateGroup(ctx, copied)
+}
+
+// ListGroupTemplates returns all templates ordered by name.
+func (m *Manager) ListGroupTemplates(ctx context.Context) ([]GroupTemplate, error) {
+	return m.store.ListGroupTemplates(ctx)
+}
+
+// CreateGroupTemplate saves a template.
+func (m *Manager) CreateGroupTemplate(ctx context.Context, template GroupTemplate) (*GroupTemplate, error) {
+	return m.store.CreateGroupTemplate(ctx, template)
+}
+
+// SaveGroupAsTemplate stores an existing group's rules and options as a
+// template.
+func (m *Manager) SaveGroupAsTemplate(ctx context.Context, groupID int64, name, description string) (*GroupTemplate, error) {
+	group, err := m.store.Get(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.CreateGroupTemplate(ctx, GroupTemplate{
+		Name:        name,
+		Description: description,
+		Group:       *group,
+	})
+}
+
+// DeleteGroupTemplate removes a template. Groups created from it are kept.
+func (m *Manager) DeleteGroupTemplate(ctx context.Context, id int64) error {
+	return m.store.DeleteGroupTemplate(ctx, id)
+}
+
+// InstantiateGroupTemplate creates and applies a group from a template.
+func (m *Manager) InstantiateGroupTemplate(ctx context.Context, id int64, name, egressVPN string) (*DomainGroup, error) {
+	template, err := m.store.GetGroupTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	group := detachGroup(template.Group)
+	group.Name = strings.TrimSpace(name)
+	group.EgressVPN = strings.TrimSpace(egressVPN)
+	return m.CreateGroup(ctx, group)
+}
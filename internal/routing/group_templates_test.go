@@ -0,0 +1,99 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func newTemplateTestManager(t *testing.T) *Manager {
+	t.Helper()
+	manager, _, _, _ := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{
+		{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"},
+		{Name: "wg-us", RouteTable: 202, FWMark: 0x16a, InterfaceName: "wg-us"},
+	}})
+	return manager
+}
+
+func TestManagerDuplicateGroupCopiesRulesToAnotherVPN(t *testing.T) {
+	ctx := context.Background()
+	manager := newTemplateTestManager(t)
+	source, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-sgp",
+		Priority:  3,
+		Rules: []RoutingRule{
+			{Name: "Video", Domains: []string{"max.com", "hbo.com"}},
+			{Name: "CDN", DestinationCIDRs: []string{"203.0.113.0/24"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+
+	copied, err := manager.DuplicateGroup(ctx, source.ID, "", "wg-us")
+	if err != nil {
+		t.Fatalf("DuplicateGroup failed: %v", err)
+	}
+	if copied.ID == source.ID || copied.Name != "Streaming-wg-us" || copied.EgressVPN != "wg-us" || copied.Priority != 3 {
+		t.Fatalf("unexpected copy: %+v", copied)
+	}
+	if len(copied.Rules) != 2 || copied.Rules[0].ID == source.Rules[0].ID || len(copied.Rules[0].Domains) != 2 {
+		t.Fatalf("expected rules to be copied into new rows, got %+v", copied.Rules)
+	}
+
+	if _, err := manager.DuplicateGroup(ctx, source.ID, "Streaming-wg-us", ""); err == nil {
+		t.Fatalf("expected duplicate name to be rejected")
+	}
+	if _, err := manager.DuplicateGroup(ctx, 9999, "", ""); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("expected ErrGroupNotFound, got %v", err)
+	}
+}
+
+func TestManagerGroupTemplatesRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	manager := newTemplateTestManager(t)
+	source, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Name: "Video", Domains: []string{"max.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+
+	template, err := manager.SaveGroupAsTemplate(ctx, source.ID, "streaming-set", "Video services")
+	if err != nil {
+		t.Fatalf("SaveGroupAsTemplate failed: %v", err)
+	}
+	if template.Group.Name != "" || template.Group.EgressVPN != "" || template.Group.ID != 0 {
+		t.Fatalf("expected template group to be detached, got %+v", template.Group)
+	}
+	if len(template.Group.Rules) != 1 || template.Group.Rules[0].ID != 0 {
+		t.Fatalf("unexpected template rules: %+v", template.Group.Rules)
+	}
+
+	created, err := manager.InstantiateGroupTemplate(ctx, template.ID, "Streaming-US", "wg-us")
+	if err != nil {
+		t.Fatalf("InstantiateGroupTemplate failed: %v", err)
+	}
+	if created.EgressVPN != "wg-us" || len(created.Rules) != 1 || created.Rules[0].Domains[0] != "max.com" {
+		t.Fatalf("unexpected instantiated group: %+v", created)
+	}
+	if _, err := manager.InstantiateGroupTemplate(ctx, template.ID, "", "wg-us"); !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected a name to be required, got %v", err)
+	}
+
+	templates, err := manager.ListGroupTemplates(ctx)
+	if err != nil || len(templates) != 1 || templates[0].Description != "Video services" {
+		t.Fatalf("unexpected templates: %+v err=%v", templates, err)
+	}
+	if err := manager.DeleteGroupTemplate(ctx, template.ID); err != nil {
+		t.Fatalf("DeleteGroupTemplate failed: %v", err)
+	}
+	if err := manager.DeleteGroupTemplate(ctx, template.ID); !errors.Is(err, ErrGroupTemplateNotFound) {
+		t.Fatalf("expected ErrGroupTemplateNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrGroupTrashNotFound indicates the trash entry id does not exist.
+var ErrGroupTrashNotFound = fmt.Errorf("trashed group not found")
+
+// TrashedGroup is a deleted group kept in the recycle bin. Group holds the
+// full definition as it was at deletion time.
+type TrashedGroup struct {
+	ID        int64       `json:"id"`
+	GroupID   int64       `json:"groupId"`
+	Name      string      `json:"name"`
+	EgressVPN string      `json:"egressVpn"`
+	Group     DomainGroup `json:"group"`
+	DeletedAt int64       `json:"deletedAt"`
+}
+
+// ListGroupTrash returns trashed groups, most recently deleted first.
+func (m *Manager) ListGroupTrash(ctx context.Context) ([]TrashedGroup, error) {
+	return m.store.ListTrash(ctx)
+}
+
+// RestoreGroup recreates a trashed group under its original name and egress
+// VPN, applies it and removes the trash entry. It fails if a group with the
+// same name exists or the VPN is gone.
+func (m *Manager) RestoreGroup(ctx context.Context, trashID int64) (*DomainGroup, error) {
+	entry, err := m.store.GetTrash(ctx, trashID)
+	if err != nil {
+		return nil, err
+	}
+	group := detachGroup(entry.Group)
+	group.Name = entry.Name
+	group.EgressVPN = entry.EgressVPN
+	restored, err := m.CreateGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.DeleteTrash(ctx, trashID); err != nil {
+		return restored, err
+	}
+	return restored, nil
+}
+
+// DeleteGroupTrash permanently removes a trashed group.
+func (m *Manager) DeleteGroupTrash(ctx context.Context, trashID int64) error {
+	return m.store.DeleteTrash(ctx, trashID)
+}
@@ -0,0 +1,53 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestManagerDeleteGroupMovesToTrashAndRestores(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, _ := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{
+		{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"},
+	}})
+	group, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-sgp",
+		Priority:  2,
+		Rules:     []RoutingRule{{Name: "Video", Domains: []string{"max.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if err := manager.DeleteGroup(ctx, group.ID); err != nil {
+		t.Fatalf("DeleteGroup failed: %v", err)
+	}
+	if _, err := manager.GetGroup(ctx, group.ID); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("expected ErrGroupNotFound after delete, got %v", err)
+	}
+
+	trash, err := manager.ListGroupTrash(ctx)
+	if err != nil {
+		t.Fatalf("ListGroupTrash failed: %v", err)
+	}
+	if len(trash) != 1 || trash[0].GroupID != group.ID || trash[0].Name != "Streaming" || trash[0].EgressVPN != "wg-sgp" || trash[0].DeletedAt == 0 {
+		t.Fatalf("unexpected trash: %+v", trash)
+	}
+
+	restored, err := manager.RestoreGroup(ctx, trash[0].ID)
+	if err != nil {
+		t.Fatalf("RestoreGroup failed: %v", err)
+	}
+	if restored.Name != "Streaming" || restored.Priority != 2 || len(restored.Rules) != 1 || restored.Rules[0].Domains[0] != "max.com" {
+		t.Fatalf("unexpected restored group: %+v", restored)
+	}
+	if trash, err := manager.ListGroupTrash(ctx); err != nil || len(trash) != 0 {
+		t.Fatalf("expected empty trash after restore, got %+v err=%v", trash, err)
+	}
+	if _, err := manager.RestoreGroup(ctx, trash[0].ID); !errors.Is(err, ErrGroupTrashNotFound) {
+		t.Fatalf("expected ErrGroupTrashNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ManagedGuestSafeMode marks the group generated by guest safe mode.
+	ManagedGuestSafeMode = "guest-safe-mode"
+	// GuestSafeModeGroupName names the generated group.
+	GuestSafeModeGroupName = "Guest-Safe-Mode"
+)
+
+// GuestSafeMode is the one-toggle "guest Wi-Fi always via VPN" policy. While
+// enabled, a managed full tunnel group sends every client of Network through
+// EgressVPN, redirects their DNS to the VPN's resolvers and drops their
+// traffic while the VPN is down. The group itself is the policy's state.
+type GuestSafeMode struct {
+	Enabled bool `json:"enabled"`
+	// Network is a "vlan:<id>" or "network:<name>" selector. A bare VLAN ID
+	// is accepted as shorthand.
+	Network   string `json:"network"`
+	EgressVPN string `json:"egressVpn"`
+	GroupID   int64  `json:"groupId,omitempty"`
+}
+
+// guestSafeModeGroup builds the managed group for a policy.
+func guestSafeModeGroup(policy GuestSafeMode) DomainGroup {
+	return DomainGroup{
+		Name:        GuestSafeModeGroupName,
+		EgressVPN:   policy.EgressVPN,
+		DNSRedirect: DNSRedirectVPN,
+		FullTunnel:  true,
+		KillSwitch:  true,
+		Managed:     ManagedGuestSafeMode,
+		Rules: []RoutingRule{{
+			Name:             "Guest network",
+			SourceInterfaces: []string{policy.Network},
+		}},
+	}
+}
+
+// normalizeGuestNetwork canonicalizes the policy's network selector.
+func normalizeGuestNetwork(value string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+	if _, err := strconv.Atoi(trimmed); err == nil {
+		trimmed = vlanSelectorPrefix + trimmed
+	}
+	selector, ok, err := parseNetworkSelector(trimmed)
+	if !ok {
+		return "", fmt.Errorf("%w: guest network must be a VLAN ID, vlan:<id> or network:<name>", ErrGroupValidation)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: guest network: %v", ErrGroupValidation, err)
+	}
+	return selector.String(), nil
+}
+
+// findGuestSafeModeGroup returns the managed group, if any.
+func findGuestSafeModeGroup(groups []DomainGroup) *DomainGroup {
+	for idx := range groups {
+		if groups[idx].Managed == ManagedGuestSafeMode {
+			return &groups[idx]
+		}
+	}
+	return nil
+}
+
+// GuestSafeMode reports the policy as described by its managed group.
+func (m *Manager) GuestSafeMode(ctx context.Context) (GuestSafeMode, error) {
+	groups, err := m.store.List(ctx)
+	if err != nil {
+		return GuestSafeMode{}, err
+	}
+	group := findGuestSafeModeGroup(groups)
+	if group == nil {
+		return GuestSafeMode{}, nil
+	}
+	policy := GuestSafeMode{Enabled: true, EgressVPN: group.EgressVPN, GroupID: group.ID}
+	if len(group.Rules) > 0 && len(group.Rules[0].SourceInterfaces) > 0 {
+		policy.Network = group.Rules[0].SourceInterfaces[0]
+	}
+	return policy, nil
+}
+
+// SetGuestSafeMode creates, updates or removes the managed group so routing
+// matches policy, and returns the resulting policy.
+func (m *Manager) SetGuestSafeMode(ctx context.Context, policy GuestSafeMode) (GuestSafeMode, error) {
+	groups, err := m.store.List(ctx)
+	if err != nil {
+		return GuestSafeMode{}, err
+	}
+	existing := findGuestSafeModeGroup(groups)
+	if !policy.Enabled {
+		if existing != nil {
+			if err := m.deleteGroup(ctx, existing.ID, ManagedGuestSafeMode); err != nil {
+				return GuestSafeMode{}, err
+			}
+		}
+		return GuestSafeMode{}, nil
+	}
+
+	network, err := normalizeGuestNetwork(policy.Network)
+	if err != nil {
+		return GuestSafeMode{}, err
+	}
+	policy.Network = network
+	group := guestSafeModeGroup(policy)
+	if existing != nil {
+		group.Priority = existing.Priority
+		if _, err := m.UpdateGroup(ctx, existing.ID, group); err != nil {
+			return GuestSafeMode{}, err
+		}
+		return m.GuestSafeMode(ctx)
+	}
+	for _, other := range groups {
+		if strings.EqualFold(other.Name, GuestSafeModeGroupName) {
+			return GuestSafeMode{}, fmt.Errorf("%w: rename or delete the existing group %q first", ErrGroupValidation, other.Name)
+		}
+	}
+	if _, err := m.CreateGroup(ctx, group); err != nil {
+		return GuestSafeMode{}, err
+	}
+	return m.GuestSafeMode(ctx)
+}
@@ -0,0 +1,72 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestGuestSafeModeManagesItsGroup(t *testing.T) {
+	ctx := context.Background()
+	profiles := []*vpn.VPNProfile{
+		{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp",
+			WireGuard: &vpn.WireGuardConfig{Interface: vpn.WireGuardInterface{DNS: []string{"10.2.0.1"}}}},
+		{Name: "wg-fra", RouteTable: 202, FWMark: 0x16a, InterfaceName: "wg-fra",
+			WireGuard: &vpn.WireGuardConfig{Interface: vpn.WireGuardInterface{DNS: []string{"10.3.0.1"}}}},
+	}
+	manager, _, _, rules := newRoutingTestManager(t, &mockVPNLister{profiles: profiles})
+	manager.networkLookup = func(vlan int) (string, []netip.Prefix, error) {
+		return bridgeForVLAN(vlan), []netip.Prefix{netip.MustParsePrefix("10.0.30.0/24")}, nil
+	}
+
+	if _, err := manager.SetGuestSafeMode(ctx, GuestSafeMode{Enabled: true, Network: "guests", EgressVPN: "wg-sgp"}); !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected a bare name to be rejected, got %v", err)
+	}
+	policy, err := manager.SetGuestSafeMode(ctx, GuestSafeMode{Enabled: true, Network: "30", EgressVPN: "wg-sgp"})
+	if err != nil {
+		t.Fatalf("SetGuestSafeMode failed: %v", err)
+	}
+	if !policy.Enabled || policy.Network != "vlan:30" || policy.EgressVPN != "wg-sgp" || policy.GroupID == 0 {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+	if len(rules.bindings) != 1 {
+		t.Fatalf("expected one binding, got %d", len(rules.bindings))
+	}
+	binding := rules.bindings[0]
+	if !binding.FullTunnel || !binding.KillSwitch || binding.DNSRedirect != DNSRedirectVPN ||
+		len(binding.SourceInterfaces) != 1 || binding.SourceInterfaces[0] != "br30" {
+		t.Fatalf("unexpected guest binding: %+v", binding)
+	}
+
+	group, err := manager.GetGroup(ctx, policy.GroupID)
+	if err != nil {
+		t.Fatalf("GetGroup failed: %v", err)
+	}
+	if _, err := manager.UpdateGroup(ctx, group.ID, DomainGroup{Name: group.Name, EgressVPN: "wg-fra", Rules: group.Rules}); !errors.Is(err, ErrGroupManaged) {
+		t.Fatalf("expected direct edits to be rejected, got %v", err)
+	}
+	if err := manager.DeleteGroup(ctx, group.ID); !errors.Is(err, ErrGroupManaged) {
+		t.Fatalf("expected direct deletes to be rejected, got %v", err)
+	}
+
+	policy, err = manager.SetGuestSafeMode(ctx, GuestSafeMode{Enabled: true, Network: "vlan:30", EgressVPN: "wg-fra"})
+	if err != nil {
+		t.Fatalf("SetGuestSafeMode retarget failed: %v", err)
+	}
+	if policy.GroupID != group.ID || policy.EgressVPN != "wg-fra" || rules.bindings[0].Interface != "wg-fra" {
+		t.Fatalf("expected the managed group to follow the new VPN: %+v", policy)
+	}
+
+	if policy, err = manager.SetGuestSafeMode(ctx, GuestSafeMode{}); err != nil || policy.Enabled {
+		t.Fatalf("expected guest safe mode off, got %+v, %v", policy, err)
+	}
+	if rules.flushCount == 0 {
+		t.Fatalf("expected rules to be flushed after turning guest safe mode off")
+	}
+	if policy, err = manager.GuestSafeMode(ctx); err != nil || policy.Enabled {
+		t.Fatalf("expected guest safe mode to read back off, got %+v, %v", policy, err)
+	}
+}
@@ -0,0 +1,149 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestIPSetManagerAddIPsUsesSingleRestore(t *testing.T) {
+	exec := &MockExec{}
+	manager := NewIPSetManager(exec)
+
+	if err := manager.AddIPs("svpn_media_r1d4_n", []string{"1.1.1.1", " 10.0.0.0/8 "}, 0); err != nil {
+		t.Fatalf("AddIPs failed: %v", err)
+	}
+	if len(exec.RunCalls) != 1 || strings.Join(exec.RunCalls[0], " ") != "ipset restore -exist" {
+		t.Fatalf("expected one ipset restore call, got %#v", exec.RunCalls)
+	}
+	want := "add svpn_media_r1d4_n 1.1.1.1 timeout 86400\n" +
+		"add svpn_media_r1d4_n 10.0.0.0/8 timeout 86400\n"
+	if got := string(exec.Inputs[0]); got != want {
+		t.Fatalf("unexpected restore script:\n%s", got)
+	}
+}
+
+func TestIPSetManagerAddIPsRejectsInvalidValues(t *testing.T) {
+	exec := &MockExec{}
+	manager := NewIPSetManager(exec)
+
+	if err := manager.AddIPs("svpn_media_r1d4_n", []string{"1.1.1.1", "1.1.1.1\nflush svpn_other"}, 0); err == nil {
+		t.Fatalf("expected invalid value to be rejected")
+	}
+	if len(exec.RunCalls) != 0 {
+		t.Fatalf("expected no restore call for invalid batch, got %#v", exec.RunCalls)
+	}
+	if err := manager.AddIPs("svpn_media_r1d4_n", nil, 0); err != nil || len(exec.RunCalls) != 0 {
+		t.Fatalf("expected empty batch to be a no-op, err=%v calls=%#v", err, exec.RunCalls)
+	}
+}
+
+func TestIPSetManagerPrefersNetlinkAndFallsBackToCommand(t *testing.T) {
+	exec := &MockExec{Outputs: map[string][]byte{"ipset list -name": []byte("svpn_a\nother\n")}}
+	client := &fakeIPSetClient{names: []string{"svpn_media_r1d4", "svpn_media_r1d4_n", "unrelated"}}
+	manager := &IPSetManager{exec: exec, netlink: client}
+
+	if err := manager.AddIPs("svpn_media_r1d4_n", []string{" 1.1.1.1 ", "10.0.0.0/8"}, 0); err != nil {
+		t.Fatalf("AddIPs failed: %v", err)
+	}
+	if err := manager.SwapSets("svpn_media_r1d4", "svpn_media_r1d4_n"); err != nil {
+		t.Fatalf("SwapSets failed: %v", err)
+	}
+	sets, err := manager.ListSets("svpn_")
+	if err != nil || strings.Join(sets, ",") != "svpn_media_r1d4,svpn_media_r1d4_n" {
+		t.Fatalf("unexpected netlink set list %v err=%v", sets, err)
+	}
+	if len(exec.RunCalls) != 0 || len(exec.OutputCalls) != 0 {
+		t.Fatalf("expected no ipset commands while netlink works, got %#v %#v", exec.RunCalls, exec.OutputCalls)
+	}
+	if got := strings.Join(client.added, ","); got != "svpn_media_r1d4_n 1.1.1.1 86400,svpn_media_r1d4_n 10.0.0.0/8 86400" {
+		t.Fatalf("unexpected netlink adds %q", got)
+	}
+
+	client.err = errors.New("netlink unavailable")
+	if err := manager.FlushSet("svpn_media_r1d4_n"); err != nil {
+		t.Fatalf("FlushSet failed: %v", err)
+	}
+	if len(exec.RunCalls) != 1 || strings.Join(exec.RunCalls[0], " ") != "ipset flush svpn_media_r1d4_n" {
+		t.Fatalf("expected ipset command fallback, got %#v", exec.RunCalls)
+	}
+	if sets, err := manager.ListSets("svpn_"); err != nil || strings.Join(sets, ",") != "svpn_a" {
+		t.Fatalf("unexpected fallback set list %v err=%v", sets, err)
+	}
+}
+
+type fakeIPSetClient struct {
+	err     error
+	names   []string
+	added   []string
+	deleted []string
+}
+
+func (f *fakeIPSetClient) Create(name, family string, timeoutSeconds int) error { return f.err }
+
+func (f *fakeIPSetClient) Add(name string, values []string, timeoutSeconds int) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, value := range values {
+		f.added = append(f.added, fmt.Sprintf("%s %s %d", name, value, timeoutSeconds))
+	}
+	return nil
+}
+
+func (f *fakeIPSetClient) Del(name string, values []string) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, value := range values {
+		f.deleted = append(f.deleted, name+" "+value)
+	}
+	return nil
+}
+
+func (f *fakeIPSetClient) Flush(name string) error      { return f.err }
+func (f *fakeIPSetClient) Swap(setA, setB string) error { return f.err }
+func (f *fakeIPSetClient) Destroy(name string) error    { return f.err }
+func (f *fakeIPSetClient) ListNames() ([]string, error) { return f.names, f.err }
+func (f *fakeIPSetClient) ListMembers(name string) ([]string, error) {
+	return nil, f.err
+}
+
+func TestApplySetAtomicallyPrefersBatchLoader(t *testing.T) {
+	exec := &MockExec{Outputs: map[string][]byte{"ipset list -name": []byte("")}}
+	manager := &Manager{ipset: NewIPSetManager(exec)}
+
+	if err := manager.applySetAtomically("svpn_media_r1d4", "inet", []string{"1.1.1.1", "8.8.8.8"}, 0); err != nil {
+		t.Fatalf("applySetAtomically failed: %v", err)
+	}
+	calls := make([]string, 0, len(exec.RunCalls))
+	for _, call := range exec.RunCalls {
+		calls = append(calls, strings.Join(call[:2], " "))
+	}
+	want := []string{"ipset create", "ipset create", "ipset flush", "ipset restore", "ipset swap", "ipset destroy"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected ipset call sequence: %v", calls)
+	}
+}
+
+func TestIPSetManagerDelIPsUsesNetlinkThenRestore(t *testing.T) {
+	exec := &MockExec{}
+	client := &fakeIPSetClient{}
+	manager := &IPSetManager{exec: exec, netlink: client}
+
+	if err := manager.DelIPs("svpn_media_r1d4", []string{" 1.1.1.1 ", "10.0.0.0/8"}); err != nil {
+		t.Fatalf("DelIPs failed: %v", err)
+	}
+	if got := strings.Join(client.deleted, ","); got != "svpn_media_r1d4 1.1.1.1,svpn_media_r1d4 10.0.0.0/8" || len(exec.RunCalls) != 0 {
+		t.Fatalf("unexpected netlink deletes %q calls=%#v", got, exec.RunCalls)
+	}
+
+	client.err = errors.New("netlink unavailable")
+	if err := manager.DelIPs("svpn_media_r1d4", []string{"1.1.1.1"}); err != nil {
+		t.Fatalf("DelIPs fallback failed: %v", err)
+	}
+	if len(exec.RunCalls) != 1 || strings.Join(exec.RunCalls[0], " ") != "ipset restore -exist" || string(exec.Inputs[0]) != "del svpn_media_r1d4 1.1.1.1\n" {
+		t.Fatalf("unexpected fallback %#v %q", exec.RunCalls, exec.Inputs)
+	}
+}
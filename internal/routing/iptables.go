@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"split-vpn-webui/internal/iprule"
 )
 
 const (
 	markChainName = "SVPN_MARK"
 	natChainName  = "SVPN_NAT"
 	mssChainName  = "SVPN_MSS"
+	dnsChainName  = "SVPN_DNS"
+	qosChainName  = "SVPN_QOS"
+	killChainName = "SVPN_KILL"
 
 	markChainA = "SVPN_MARK_A"
 	markChainB = "SVPN_MARK_B"
@@ -17,6 +22,12 @@ const (
 	natChainB  = "SVPN_NAT_B"
 	mssChainA  = "SVPN_MSS_A"
 	mssChainB  = "SVPN_MSS_B"
+	dnsChainA  = "SVPN_DNS_A"
+	dnsChainB  = "SVPN_DNS_B"
+	qosChainA  = "SVPN_QOS_A"
+	qosChainB  = "SVPN_QOS_B"
+	killChainA = "SVPN_KILL_A"
+	killChainB = "SVPN_KILL_B"
 
 	rulePriority    = "100"
 	deleteLoopLimit = 64
@@ -25,11 +36,14 @@ const (
 // RuleManager applies iptables/ip6tables and ip rule state.
 type RuleManager struct {
 	exec Executor
+	// ipRules manages policy rules over rtnetlink. It is only set for the
+	// system executor; injected executors keep the `ip rule` command path.
+	ipRules ipRuleClient
 }
 
 func NewRuleManager(exec Executor) *RuleManager {
 	if exec == nil {
-		exec = osExec{}
+		return &RuleManager{exec: osExec{}, ipRules: iprule.New()}
 	}
 	return &RuleManager{exec: exec}
 }
@@ -38,6 +52,15 @@ func NewRuleManager(exec Executor) *RuleManager {
 func (m *RuleManager) ApplyRules(bindings []RouteBinding) error {
 	sorted := append([]RouteBinding(nil), bindings...)
 	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Canary != sorted[j].Canary {
+			return sorted[j].Canary
+		}
+		if sorted[i].FullTunnel != sorted[j].FullTunnel {
+			return sorted[i].FullTunnel
+		}
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority < sorted[j].Priority
+		}
 		if sorted[i].GroupName == sorted[j].GroupName {
 			return sorted[i].RuleIndex < sorted[j].RuleIndex
 		}
@@ -46,6 +69,9 @@ func (m *RuleManager) ApplyRules(bindings []RouteBinding) error {
 
 	activeVariant := m.detectActiveVariant()
 	workingMark, workingNAT, workingMSS, staleMark, staleNAT, staleMSS := selectWorkingVariant(activeVariant)
+	workingDNS, staleDNS := selectWorkingDNSVariant(activeVariant)
+	workingQoS, staleQoS := selectWorkingQoSVariant(activeVariant)
+	workingKill, staleKill := selectWorkingKillVariant(activeVariant)
 	for _, prep := range []struct {
 		tool       string
 		table      string
@@ -56,9 +82,15 @@ func (m *RuleManager) ApplyRules(bindings []RouteBinding) error {
 		{tool: "iptables", table: "mangle", root: markChainName, parent: "PREROUTING", generation: workingMark},
 		{tool: "iptables", table: "mangle", root: mssChainName, parent: "FORWARD", generation: workingMSS},
 		{tool: "iptables", table: "nat", root: natChainName, parent: "POSTROUTING", generation: workingNAT},
+		{tool: "iptables", table: "nat", root: dnsChainName, parent: "PREROUTING", generation: workingDNS},
+		{tool: "iptables", table: "mangle", root: qosChainName, parent: "FORWARD", generation: workingQoS},
+		{tool: "iptables", table: "mangle", root: killChainName, parent: "FORWARD", generation: workingKill},
 		{tool: "ip6tables", table: "mangle", root: markChainName, parent: "PREROUTING", generation: workingMark},
 		{tool: "ip6tables", table: "mangle", root: mssChainName, parent: "FORWARD", generation: workingMSS},
 		{tool: "ip6tables", table: "nat", root: natChainName, parent: "POSTROUTING", generation: workingNAT},
+		{tool: "ip6tables", table: "nat", root: dnsChainName, parent: "PREROUTING", generation: workingDNS},
+		{tool: "ip6tables", table: "mangle", root: qosChainName, parent: "FORWARD", generation: workingQoS},
+		{tool: "ip6tables", table: "mangle", root: killChainName, parent: "FORWARD", generation: workingKill},
 	} {
 		if err := m.prepareGenerationChain(prep.tool, prep.table, prep.root, prep.parent, prep.generation); err != nil {
 			return err
@@ -75,9 +107,15 @@ func (m *RuleManager) ApplyRules(bindings []RouteBinding) error {
 			{tool: "iptables", table: "mangle", chain: markChainName},
 			{tool: "iptables", table: "mangle", chain: mssChainName},
 			{tool: "iptables", table: "nat", chain: natChainName},
+			{tool: "iptables", table: "nat", chain: dnsChainName},
+			{tool: "iptables", table: "mangle", chain: qosChainName},
+			{tool: "iptables", table: "mangle", chain: killChainName},
 			{tool: "ip6tables", table: "mangle", chain: markChainName},
 			{tool: "ip6tables", table: "mangle", chain: mssChainName},
 			{tool: "ip6tables", table: "nat", chain: natChainName},
+			{tool: "ip6tables", table: "nat", chain: dnsChainName},
+			{tool: "ip6tables", table: "mangle", chain: qosChainName},
+			{tool: "ip6tables", table: "mangle", chain: killChainName},
 		} {
 			if err := m.exec.Run(root.tool, "-t", root.table, "-F", root.chain); err != nil {
 				return fmt.Errorf("flush %s/%s chain %s during migration: %w", root.tool, root.table, root.chain, err)
@@ -99,6 +137,16 @@ func (m *RuleManager) ApplyRules(bindings []RouteBinding) error {
 			return fmt.Errorf("missing interface for group %s", binding.GroupName)
 		}
 
+		markHex := fmt.Sprintf("0x%x", binding.Mark)
+		if binding.MonitorOnly {
+			// Monitor-only bindings just log; they get no ip rule, NAT,
+			// DNS redirect, policing, kill switch or MSS clamp.
+			if err := m.addMarkRules(binding, bindingIndex, workingMark, markHex); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if existingTable, exists := desiredRules[binding.Mark]; exists && existingTable != binding.RouteTable {
 			return fmt.Errorf(
 				"conflicting route table for fwmark 0x%x: %d and %d",
@@ -109,15 +157,26 @@ func (m *RuleManager) ApplyRules(bindings []RouteBinding) error {
 		}
 		desiredRules[binding.Mark] = binding.RouteTable
 
-		markHex := fmt.Sprintf("0x%x", binding.Mark)
 		if err := m.addMarkRules(binding, bindingIndex, workingMark, markHex); err != nil {
 			return err
 		}
+		if err := m.addDNSRedirectRules(binding, workingDNS); err != nil {
+			return err
+		}
+		if err := m.addDownloadLimitRules(binding, workingQoS); err != nil {
+			return err
+		}
+		if err := m.addKillSwitchRules(binding, workingKill, markHex); err != nil {
+			return err
+		}
 
 		if clamp := (mssClamp{v4: binding.MSSClampV4, v6: binding.MSSClampV6}); clamp.enabled() {
 			// Interface maps 1:1 to a VPN, so every binding sharing an interface
-			// carries identical clamp settings; last write is a harmless no-op.
-			mssByInterface[binding.Interface] = clamp
+			// carries identical clamp settings; the first one owns the rules.
+			if _, seen := mssByInterface[binding.Interface]; !seen {
+				clamp.comment = ruleComment(binding)
+				mssByInterface[binding.Interface] = clamp
+			}
 		}
 
 		natKey := markHex + ":" + binding.Interface
@@ -125,10 +184,10 @@ func (m *RuleManager) ApplyRules(bindings []RouteBinding) error {
 			continue
 		}
 		seenNATRules[natKey] = struct{}{}
-		if err := m.addNATRule("iptables", workingNAT, markHex, binding.Interface, binding.GroupName); err != nil {
+		if err := m.addNATRule("iptables", workingNAT, markHex, binding); err != nil {
 			return err
 		}
-		if err := m.addNATRule("ip6tables", workingNAT, markHex, binding.Interface, binding.GroupName); err != nil {
+		if err := m.addIPv6NATRules(workingNAT, markHex, binding); err != nil {
 			return err
 		}
 	}
@@ -149,15 +208,24 @@ func (m *RuleManager) ApplyRules(bindings []RouteBinding) error {
 		{tool: "iptables", table: "mangle", root: markChainName, next: workingMark, stale: staleMark},
 		{tool: "iptables", table: "mangle", root: mssChainName, next: workingMSS, stale: staleMSS},
 		{tool: "iptables", table: "nat", root: natChainName, next: workingNAT, stale: staleNAT},
+		{tool: "iptables", table: "nat", root: dnsChainName, next: workingDNS, stale: staleDNS},
+		{tool: "iptables", table: "mangle", root: qosChainName, next: workingQoS, stale: staleQoS},
+		{tool: "iptables", table: "mangle", root: killChainName, next: workingKill, stale: staleKill},
 		{tool: "ip6tables", table: "mangle", root: markChainName, next: workingMark, stale: staleMark},
 		{tool: "ip6tables", table: "mangle", root: mssChainName, next: workingMSS, stale: staleMSS},
 		{tool: "ip6tables", table: "nat", root: natChainName, next: workingNAT, stale: staleNAT},
+		{tool: "ip6tables", table: "nat", root: dnsChainName, next: workingDNS, stale: staleDNS},
+		{tool: "ip6tables", table: "mangle", root: qosChainName, next: workingQoS, stale: staleQoS},
+		{tool: "ip6tables", table: "mangle", root: killChainName, next: workingKill, stale: staleKill},
 	} {
 		if err := m.switchRootJump(sw.tool, sw.table, sw.root, sw.next, sw.stale); err != nil {
 			return err
 		}
 	}
 
+	if err := m.removeOrphanedRules(); err != nil {
+		return err
+	}
 	if err := m.reconcileManagedIPRules(desiredRules, false); err != nil {
 		return err
 	}
@@ -182,12 +250,42 @@ func selectWorkingVariant(active string) (workingMark, workingNAT, workingMSS, s
 	return markChainA, natChainA, mssChainA, markChainB, natChainB, mssChainB
 }
 
+// selectWorkingDNSVariant pairs the DNS redirect generation with the mark
+// generation, which is the one detectActiveVariant reads.
+func selectWorkingDNSVariant(active string) (workingDNS, staleDNS string) {
+	if active == markChainA {
+		return dnsChainB, dnsChainA
+	}
+	return dnsChainA, dnsChainB
+}
+
+// selectWorkingQoSVariant pairs the download limit generation with the mark
+// generation, like selectWorkingDNSVariant.
+func selectWorkingQoSVariant(active string) (workingQoS, staleQoS string) {
+	if active == markChainA {
+		return qosChainB, qosChainA
+	}
+	return qosChainA, qosChainB
+}
+
+// selectWorkingKillVariant pairs the kill switch generation with the mark
+// generation, like selectWorkingDNSVariant.
+func selectWorkingKillVariant(active string) (workingKill, staleKill string) {
+	if active == markChainA {
+		return killChainB, killChainA
+	}
+	return killChainA, killChainB
+}
+
 // mssClamp holds the per-family MSS clamp settings for a tunnel interface.
 // A value of "" disables clamping for that family, "pmtu" clamps to the path
 // MTU, and any other value is a fixed MSS passed to --set-mss.
 type mssClamp struct {
 	v4 string
 	v6 string
+	// comment attributes the clamp rules to the first binding using the
+	// interface.
+	comment string
 }
 
 func (c mssClamp) enabled() bool {
@@ -217,25 +315,28 @@ func sortedInterfaces(byInterface map[string]mssClamp) []string {
 // output-route based and would be a no-op on an ingress rule anyway.
 func (m *RuleManager) addMSSRules(chain, iface string, clamp mssClamp) error {
 	if v4 := strings.TrimSpace(clamp.v4); v4 != "" {
-		if err := m.addMSSRuleForFamily("iptables", chain, iface, v4); err != nil {
+		if err := m.addMSSRuleForFamily("iptables", chain, iface, v4, clamp.comment); err != nil {
 			return err
 		}
 	}
 	if v6 := strings.TrimSpace(clamp.v6); v6 != "" {
-		if err := m.addMSSRuleForFamily("ip6tables", chain, iface, v6); err != nil {
+		if err := m.addMSSRuleForFamily("ip6tables", chain, iface, v6, clamp.comment); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (m *RuleManager) addMSSRuleForFamily(tool, chain, iface, value string) error {
-	args := []string{
-		"-t", "mangle", "-A", chain,
+func (m *RuleManager) addMSSRuleForFamily(tool, chain, iface, value, comment string) error {
+	args := []string{"-t", "mangle", "-A", chain}
+	if comment != "" {
+		args = append(args, ruleCommentArgs(comment)...)
+	}
+	args = append(args,
 		"-o", iface,
 		"-p", "tcp", "-m", "tcp", "--tcp-flags", "SYN,RST", "SYN",
 		"-j", "TCPMSS",
-	}
+	)
 	if strings.EqualFold(value, "pmtu") {
 		args = append(args, "--clamp-mss-to-pmtu")
 	} else {
@@ -328,13 +429,17 @@ func (m *RuleManager) switchRootJump(tool, table, root, next, stale string) erro
 	return nil
 }
 
-func (m *RuleManager) addNATRule(tool, chain, markHex, iface, groupName string) error {
-	if err := m.exec.Run(tool, "-t", "nat", "-A", chain, "-m", "mark", "--mark", markHex, "-o", iface, "-j", "MASQUERADE"); err != nil {
+// addNATRule masquerades marked traffic leaving the binding's interface. The
+// rule is shared by every binding with the same mark and interface and is
+// attributed to the first of them.
+func (m *RuleManager) addNATRule(tool, chain, markHex string, binding RouteBinding) error {
+	args := append(ruleHead("nat", chain, binding), "-m", "mark", "--mark", markHex, "-o", binding.Interface, "-j", "MASQUERADE")
+	if err := m.exec.Run(tool, args...); err != nil {
 		family := "ipv4"
 		if tool == "ip6tables" {
 			family = "ipv6"
 		}
-		return fmt.Errorf("add %s nat rule for %s: %w", family, groupName, err)
+		return fmt.Errorf("add %s nat rule for %s: %w", family, binding.GroupName, err)
 	}
 	return nil
 }
@@ -350,21 +455,39 @@ func (m *RuleManager) FlushRules() error {
 		{tool: "iptables", table: "mangle", chain: markChainName, parent: "PREROUTING"},
 		{tool: "iptables", table: "mangle", chain: mssChainName, parent: "FORWARD"},
 		{tool: "iptables", table: "nat", chain: natChainName, parent: "POSTROUTING"},
+		{tool: "iptables", table: "nat", chain: dnsChainName, parent: "PREROUTING"},
+		{tool: "iptables", table: "mangle", chain: qosChainName, parent: "FORWARD"},
+		{tool: "iptables", table: "mangle", chain: killChainName, parent: "FORWARD"},
 		{tool: "ip6tables", table: "mangle", chain: markChainName, parent: "PREROUTING"},
 		{tool: "ip6tables", table: "mangle", chain: mssChainName, parent: "FORWARD"},
 		{tool: "ip6tables", table: "nat", chain: natChainName, parent: "POSTROUTING"},
+		{tool: "ip6tables", table: "nat", chain: dnsChainName, parent: "PREROUTING"},
+		{tool: "ip6tables", table: "mangle", chain: qosChainName, parent: "FORWARD"},
+		{tool: "ip6tables", table: "mangle", chain: killChainName, parent: "FORWARD"},
 		{tool: "iptables", table: "mangle", chain: markChainA},
 		{tool: "iptables", table: "mangle", chain: markChainB},
 		{tool: "iptables", table: "mangle", chain: mssChainA},
 		{tool: "iptables", table: "mangle", chain: mssChainB},
 		{tool: "iptables", table: "nat", chain: natChainA},
 		{tool: "iptables", table: "nat", chain: natChainB},
+		{tool: "iptables", table: "nat", chain: dnsChainA},
+		{tool: "iptables", table: "nat", chain: dnsChainB},
+		{tool: "iptables", table: "mangle", chain: qosChainA},
+		{tool: "iptables", table: "mangle", chain: qosChainB},
+		{tool: "iptables", table: "mangle", chain: killChainA},
+		{tool: "iptables", table: "mangle", chain: killChainB},
 		{tool: "ip6tables", table: "mangle", chain: markChainA},
 		{tool: "ip6tables", table: "mangle", chain: markChainB},
 		{tool: "ip6tables", table: "mangle", chain: mssChainA},
 		{tool: "ip6tables", table: "mangle", chain: mssChainB},
 		{tool: "ip6tables", table: "nat", chain: natChainA},
 		{tool: "ip6tables", table: "nat", chain: natChainB},
+		{tool: "ip6tables", table: "nat", chain: dnsChainA},
+		{tool: "ip6tables", table: "nat", chain: dnsChainB},
+		{tool: "ip6tables", table: "mangle", chain: qosChainA},
+		{tool: "ip6tables", table: "mangle", chain: qosChainB},
+		{tool: "ip6tables", table: "mangle", chain: killChainA},
+		{tool: "ip6tables", table: "mangle", chain: killChainB},
 	} {
 		m.cleanupChain(command.tool, command.table, command.chain, command.parent)
 	}
@@ -380,6 +503,9 @@ func (m *RuleManager) FlushRules() error {
 	} {
 		_ = m.cleanupGenerationRuleChains(command.tool, command.table, command.generation)
 	}
+	if err := m.removeOrphanedRules(); err != nil {
+		return err
+	}
 	if err := m.flushManagedIPRules(false); err != nil {
 		return err
 	}
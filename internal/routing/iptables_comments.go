@@ -0,0 +1,95 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ruleCommentPrefix starts the comment on every mangle and nat rule this
+// package generates, as "svpn:<group>:<rule>" with a 1-based rule number.
+// Admins can attribute rules in `iptables -S`, and rules carrying the prefix
+// outside the SVPN* chains are recognized as orphans.
+const ruleCommentPrefix = "svpn:"
+
+var orphanScanTables = []struct {
+	tool  string
+	table string
+}{
+	{tool: "iptables", table: "mangle"},
+	{tool: "iptables", table: "nat"},
+	{tool: "ip6tables", table: "mangle"},
+	{tool: "ip6tables", table: "nat"},
+}
+
+func ruleComment(binding RouteBinding) string {
+	return fmt.Sprintf("%s%s:%d", ruleCommentPrefix, binding.GroupName, binding.RuleIndex+1)
+}
+
+func ruleCommentArgs(comment string) []string {
+	return []string{"-m", "comment", "--comment", comment}
+}
+
+// ruleHead starts an "-A chain" rule annotated with the binding's comment.
+func ruleHead(table, chain string, binding RouteBinding) []string {
+	return append([]string{"-t", table, "-A", chain}, ruleCommentArgs(ruleComment(binding))...)
+}
+
+// isManagedChain reports whether chain is one of this package's root,
+// generation or per-binding chains, which are flushed wholesale.
+func isManagedChain(chain string) bool {
+	return strings.HasPrefix(chain, "SVPN")
+}
+
+// unquoteRuleComment strips the quotes `iptables -S` puts around comments
+// containing characters such as ':' so listed rules match generated ones.
+func unquoteRuleComment(fields []string) []string {
+	out := append([]string(nil), fields...)
+	for i := 0; i+1 < len(out); i++ {
+		if out[i] == "--comment" {
+			out[i+1] = strings.Trim(out[i+1], `"`)
+		}
+	}
+	return out
+}
+
+func hasRuleComment(fields []string) bool {
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "--comment" && strings.HasPrefix(fields[i+1], ruleCommentPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// orphanedRules lists the "-A chain ..." specs of rules that carry this
+// application's comment but live outside its chains, e.g. left in a built-in
+// chain by an older release or a hand-made copy. Unreadable tables have none.
+func (m *RuleManager) orphanedRules(tool, table string) [][]string {
+	output, err := m.exec.Output(tool, "-t", table, "-S")
+	if err != nil {
+		return nil
+	}
+	orphans := make([][]string, 0)
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := unquoteRuleComment(strings.Fields(line))
+		if len(fields) < 2 || fields[0] != "-A" || isManagedChain(fields[1]) || !hasRuleComment(fields) {
+			continue
+		}
+		orphans = append(orphans, fields)
+	}
+	return orphans
+}
+
+// removeOrphanedRules deletes every orphaned rule in the mangle and nat
+// tables of both families.
+func (m *RuleManager) removeOrphanedRules() error {
+	for _, scan := range orphanScanTables {
+		for _, fields := range m.orphanedRules(scan.tool, scan.table) {
+			args := append([]string{"-t", scan.table, "-D"}, fields[1:]...)
+			if err := m.exec.Run(scan.tool, args...); err != nil {
+				return fmt.Errorf("remove orphaned %s/%s rule in %s: %w", scan.tool, scan.table, fields[1], err)
+			}
+		}
+	}
+	return nil
+}
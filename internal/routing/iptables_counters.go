@@ -0,0 +1,91 @@
+package routing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MarkCounter is an optional RuleApplier extension that reports how many
+// packets the managed mangle chains have marked for each fwmark. The counters
+// grow whenever a client sends traffic a group routes into a VPN, whether or
+// not the tunnel is up, which makes them a cheap demand signal.
+type MarkCounter interface {
+	MarkPacketCounts() (map[uint32]uint64, error)
+}
+
+// MarkPacketCounts sums the packet counters of the MARK rules in the managed
+// mangle chains of both families, by mark. Counters restart when an apply
+// swaps chain generations.
+func (m *RuleManager) MarkPacketCounts() (map[uint32]uint64, error) {
+	counts := make(map[uint32]uint64)
+	read := 0
+	var firstErr error
+	for _, tool := range []string{"iptables", "ip6tables"} {
+		output, err := m.exec.Output(tool, "-t", "mangle", "-S", "-v")
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("list %s mangle counters: %w", tool, err)
+			}
+			continue
+		}
+		read++
+		for _, line := range strings.Split(string(output), "\n") {
+			mark, packets, ok := parseMarkRuleCounter(strings.Fields(line))
+			if ok {
+				counts[mark] += packets
+			}
+		}
+	}
+	if read == 0 {
+		return nil, firstErr
+	}
+	return counts, nil
+}
+
+// parseMarkRuleCounter reads "-A <managed chain> ... -c <packets> <bytes>
+// -j MARK --set-xmark <mark>/<mask>" as printed by `iptables -S -v`.
+func parseMarkRuleCounter(fields []string) (uint32, uint64, bool) {
+	if len(fields) < 2 || fields[0] != "-A" || !isManagedChain(fields[1]) {
+		return 0, 0, false
+	}
+	var (
+		packets    uint64
+		hasCounter bool
+		mark       uint32
+		hasMark    bool
+	)
+	for i := 2; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "-c":
+			value, err := strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return 0, 0, false
+			}
+			packets, hasCounter = value, true
+		case "--set-xmark", "--set-mark":
+			raw, _, _ := strings.Cut(fields[i+1], "/")
+			value, err := strconv.ParseUint(raw, 0, 32)
+			if err != nil {
+				return 0, 0, false
+			}
+			mark, hasMark = uint32(value), true
+		}
+	}
+	if !hasCounter || !hasMark || mark == 0 {
+		return 0, 0, false
+	}
+	return mark, packets, true
+}
+
+// MarkPacketCounts reports the packets marked per fwmark, or an error when
+// the rule applier cannot read counters.
+func (m *Manager) MarkPacketCounts() (map[uint32]uint64, error) {
+	counter, ok := m.rules.(MarkCounter)
+	if !ok {
+		return nil, fmt.Errorf("rule applier does not report mark counters")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return counter.MarkPacketCounts()
+}
@@ -0,0 +1,24 @@
+package routing
+
+import "testing"
+
+func TestMarkPacketCountsSumsManagedMarkRules(t *testing.T) {
+	exec := &MockExec{Outputs: map[string][]byte{
+		"iptables -t mangle -S -v": []byte(`-P PREROUTING ACCEPT -c 900 80000
+-A PREROUTING -c 900 80000 -j SVPN_MARK
+-A SVPNA_001_4 -m set --match-set svpn_media_r1d4 dst -c 12 960 -j MARK --set-xmark 0xc9/0xffffffff
+-A SVPNA_002_4 -m set --match-set svpn_work_r1s4 src -c 3 180 -j MARK --set-xmark 0xca/0xffffffff
+-A SVPNA_001_4 -c 12 960 -j CONNMARK --save-mark
+-A OTHER -c 50 4000 -j MARK --set-xmark 0xc9/0xffffffff
+`),
+		"ip6tables -t mangle -S -v": []byte(`-A SVPNA_001_6 -m set --match-set svpn_media_r1d6 dst -c 5 400 -j MARK --set-xmark 0xc9/0xffffffff
+`),
+	}}
+	counts, err := NewRuleManager(exec).MarkPacketCounts()
+	if err != nil {
+		t.Fatalf("MarkPacketCounts: %v", err)
+	}
+	if counts[0xc9] != 17 || counts[0xca] != 3 || len(counts) != 2 {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+}
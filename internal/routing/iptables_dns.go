@@ -0,0 +1,119 @@
+package routing
+
+import "fmt"
+
+// dnsRedirectPorts are the ports captured for redirected clients: plain DNS
+// over both protocols and DNS-over-TLS.
+var dnsRedirectPorts = []PortRange{
+	{Protocol: "udp", Start: 53},
+	{Protocol: "tcp", Start: 53},
+	{Protocol: "tcp", Start: 853},
+}
+
+// addDNSRedirectRules rewrites DNS from the binding's source clients in the
+// nat PREROUTING generation chain. "vpn" mode DNATs it to the egress VPN's
+// resolver; "local" mode REDIRECTs it to the router's own resolver. Nothing
+// listens for DoT locally, so redirected DoT is refused and clients fall
+// back to plain DNS, which is captured.
+func (m *RuleManager) addDNSRedirectRules(binding RouteBinding, chain string) error {
+	if binding.DNSRedirect != DNSRedirectVPN && binding.DNSRedirect != DNSRedirectLocal {
+		return nil
+	}
+	for _, tool := range []string{"iptables", "ip6tables"} {
+		isIPv6 := tool == "ip6tables"
+		var target []string
+		if binding.DNSRedirect == DNSRedirectVPN {
+			servers := binding.DNSServersV4
+			if isIPv6 {
+				servers = binding.DNSServersV6
+			}
+			if len(servers) == 0 {
+				// No resolver for this family; addDNSMarkRules still sends
+				// its DNS through the tunnel.
+				continue
+			}
+			target = []string{"-j", "DNAT", "--to-destination", servers[0]}
+		} else {
+			target = []string{"-j", "REDIRECT"}
+		}
+		for _, match := range dnsSourceMatches(binding, isIPv6) {
+			for _, port := range dnsRedirectPorts {
+				args := append(ruleHead("nat", chain, binding), match...)
+				args = append(args, "-p", port.Protocol, "--dport", formatPortRange(port))
+				args = append(args, target...)
+				if err := m.exec.Run(tool, args...); err != nil {
+					return fmt.Errorf("add %s dns redirect rule for %s: %w", dnsFamily(isIPv6), binding.GroupName, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addDNSMarkRules marks DNS from the binding's source clients regardless of
+// destination so that, in "vpn" mode, DNATed queries leave through the
+// tunnel instead of the WAN.
+func (m *RuleManager) addDNSMarkRules(tool, ruleChain string, binding RouteBinding, markHex string) error {
+	if binding.MonitorOnly || binding.DNSRedirect != DNSRedirectVPN {
+		return nil
+	}
+	isIPv6 := tool == "ip6tables"
+	for _, match := range dnsSourceMatches(binding, isIPv6) {
+		for _, port := range dnsRedirectPorts {
+			args := append(ruleHead("mangle", ruleChain, binding), match...)
+			args = append(args, "-p", port.Protocol, "--dport", formatPortRange(port))
+			args = append(args, markTargetArgs(tool, binding, markHex)...)
+			if err := m.exec.Run(tool, args...); err != nil {
+				return fmt.Errorf("add %s dns mark rule for %s: %w", dnsFamily(isIPv6), binding.GroupName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dnsSourceMatches returns the match alternatives selecting the binding's
+// source clients, ignoring its destination selectors.
+func dnsSourceMatches(binding RouteBinding, isIPv6 bool) [][]string {
+	var sourceSet, excludedSet string
+	if binding.HasSource {
+		sourceSet = binding.SourceSetV4
+		if isIPv6 {
+			sourceSet = binding.SourceSetV6
+		}
+	}
+	if binding.HasExcludedSource {
+		excludedSet = binding.ExcludedSourceSetV4
+		if isIPv6 {
+			excludedSet = binding.ExcludedSourceSetV6
+		}
+	}
+	matches := make([][]string, 0)
+	for _, sourceIface := range expandSelectorValues(binding.SourceInterfaces) {
+		for _, sourceMAC := range expandSelectorValues(binding.SourceMACs) {
+			for _, deviceMatch := range sourceDeviceMatches(binding, isIPv6) {
+				match := make([]string, 0, 16)
+				if sourceSet != "" {
+					match = append(match, "-m", "set", "--match-set", sourceSet, "src")
+				}
+				if excludedSet != "" {
+					match = append(match, "-m", "set", "!", "--match-set", excludedSet, "src")
+				}
+				if sourceIface != "" {
+					match = append(match, "-i", sourceIface)
+				}
+				if sourceMAC != "" {
+					match = append(match, "-m", "mac", "--mac-source", sourceMAC)
+				}
+				matches = append(matches, append(match, deviceMatch...))
+			}
+		}
+	}
+	return matches
+}
+
+func dnsFamily(isIPv6 bool) string {
+	if isIPv6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
@@ -1,19 +1,31 @@
 package routing
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+
+	"split-vpn-webui/internal/iprule"
 )
 
+// ipRuleClient is the rtnetlink surface RuleManager needs; *iprule.Client
+// satisfies it.
+type ipRuleClient interface {
+	List(ipv6 bool) ([]iprule.Rule, error)
+	Add(rule iprule.Rule, ipv6 bool) error
+	Delete(rule iprule.Rule, ipv6 bool) error
+}
+
 func (m *RuleManager) reconcileManagedIPRules(desired map[uint32]int, ipv6 bool) error {
 	existing, loaded := m.loadManagedIPRules(ipv6)
 	if !loaded {
 		// Fallback path when we cannot inspect current state: keep old delete+add behavior.
 		marks := sortedMarks(desired)
 		for _, mark := range marks {
-			if err := m.refreshIPRule(fmt.Sprintf("0x%x", mark), desired[mark], ipv6); err != nil {
+			if err := m.refreshIPRule(mark, desired[mark], ipv6); err != nil {
 				return err
 			}
 		}
@@ -27,16 +39,8 @@ func (m *RuleManager) reconcileManagedIPRules(desired map[uint32]int, ipv6 bool)
 		if _, ok := existing[key]; ok {
 			continue
 		}
-		args := []string{"rule", "add", "fwmark", fmt.Sprintf("0x%x", mark), "table", strconv.Itoa(table), "priority", rulePriority}
-		if ipv6 {
-			args = append([]string{"-6"}, args...)
-		}
-		if err := m.exec.Run("ip", args...); err != nil {
-			family := "ipv4"
-			if ipv6 {
-				family = "ipv6"
-			}
-			return fmt.Errorf("add %s ip rule for mark 0x%x table %d: %w", family, mark, table, err)
+		if err := m.addIPRule(mark, table, ipv6); err != nil {
+			return err
 		}
 	}
 
@@ -54,24 +58,7 @@ func (m *RuleManager) reconcileManagedIPRules(desired map[uint32]int, ipv6 bool)
 		return stale[i].Mark < stale[j].Mark
 	})
 	for _, pair := range stale {
-		deleteArgs := []string{
-			"rule",
-			"del",
-			"fwmark",
-			fmt.Sprintf("0x%x", pair.Mark),
-			"table",
-			strconv.Itoa(pair.Table),
-			"priority",
-			rulePriority,
-		}
-		if ipv6 {
-			deleteArgs = append([]string{"-6"}, deleteArgs...)
-		}
-		for i := 0; i < deleteLoopLimit; i++ {
-			if err := m.exec.Run("ip", deleteArgs...); err != nil {
-				break
-			}
-		}
+		m.deleteIPRule(pair.Mark, pair.Table, ipv6)
 	}
 	return nil
 }
@@ -95,6 +82,19 @@ func ipRulePairKey(mark uint32, table int) string {
 }
 
 func (m *RuleManager) loadManagedIPRules(ipv6 bool) (map[string]ipRulePair, bool) {
+	if m.ipRules != nil {
+		if listed, err := m.ipRules.List(ipv6); err == nil {
+			rules := make(map[string]ipRulePair)
+			for _, rule := range listed {
+				if strconv.FormatUint(uint64(rule.Priority), 10) != rulePriority || rule.Table < 200 || rule.Mark < 200 {
+					continue
+				}
+				pair := ipRulePair{Mark: rule.Mark, Table: rule.Table}
+				rules[ipRulePairKey(pair.Mark, pair.Table)] = pair
+			}
+			return rules, true
+		}
+	}
 	args := []string{"rule", "show"}
 	if ipv6 {
 		args = append([]string{"-6"}, args...)
@@ -123,28 +123,65 @@ func (m *RuleManager) loadManagedIPRules(ipv6 bool) (map[string]ipRulePair, bool
 	return rules, true
 }
 
-func (m *RuleManager) refreshIPRule(markHex string, routeTable int, ipv6 bool) error {
-	argsDelete := []string{"rule", "del", "fwmark", markHex, "table", strconv.Itoa(routeTable), "priority", rulePriority}
-	argsAdd := []string{"rule", "add", "fwmark", markHex, "table", strconv.Itoa(routeTable), "priority", rulePriority}
-	if ipv6 {
-		argsDelete = append([]string{"-6"}, argsDelete...)
-		argsAdd = append([]string{"-6"}, argsAdd...)
-	}
-	for i := 0; i < deleteLoopLimit; i++ {
-		if err := m.exec.Run("ip", argsDelete...); err != nil {
-			break
+func (m *RuleManager) refreshIPRule(mark uint32, routeTable int, ipv6 bool) error {
+	m.deleteIPRule(mark, routeTable, ipv6)
+	return m.addIPRule(mark, routeTable, ipv6)
+}
+
+// addIPRule installs the fwmark rule over rtnetlink, falling back to the
+// `ip rule` command when netlink is unavailable.
+func (m *RuleManager) addIPRule(mark uint32, routeTable int, ipv6 bool) error {
+	if m.ipRules != nil {
+		if err := m.ipRules.Add(managedIPRule(mark, routeTable), ipv6); err == nil {
+			return nil
 		}
 	}
-	if err := m.exec.Run("ip", argsAdd...); err != nil {
+	args := []string{"rule", "add", "fwmark", fmt.Sprintf("0x%x", mark), "table", strconv.Itoa(routeTable), "priority", rulePriority}
+	if ipv6 {
+		args = append([]string{"-6"}, args...)
+	}
+	if err := m.exec.Run("ip", args...); err != nil {
 		family := "ipv4"
 		if ipv6 {
 			family = "ipv6"
 		}
-		return fmt.Errorf("add %s ip rule for mark %s table %d: %w", family, markHex, routeTable, err)
+		return fmt.Errorf("add %s ip rule for mark 0x%x table %d: %w", family, mark, routeTable, err)
 	}
 	return nil
 }
 
+// deleteIPRule removes every duplicate of the fwmark rule. Failures are not
+// reported: a missing rule is the desired end state.
+func (m *RuleManager) deleteIPRule(mark uint32, routeTable int, ipv6 bool) {
+	if m.ipRules != nil {
+		rule := managedIPRule(mark, routeTable)
+		for i := 0; i < deleteLoopLimit; i++ {
+			err := m.ipRules.Delete(rule, ipv6)
+			if err == nil {
+				continue
+			}
+			if errors.Is(err, syscall.ENOENT) {
+				return
+			}
+			break
+		}
+	}
+	args := []string{"rule", "del", "fwmark", fmt.Sprintf("0x%x", mark), "table", strconv.Itoa(routeTable), "priority", rulePriority}
+	if ipv6 {
+		args = append([]string{"-6"}, args...)
+	}
+	for i := 0; i < deleteLoopLimit; i++ {
+		if err := m.exec.Run("ip", args...); err != nil {
+			break
+		}
+	}
+}
+
+func managedIPRule(mark uint32, routeTable int) iprule.Rule {
+	priority, _ := strconv.ParseUint(rulePriority, 10, 32)
+	return iprule.Rule{Priority: uint32(priority), Mark: mark, Table: routeTable}
+}
+
 func (m *RuleManager) flushManagedIPRules(ipv6 bool) error {
 	existing, loaded := m.loadManagedIPRules(ipv6)
 	if !loaded {
@@ -161,24 +198,7 @@ func (m *RuleManager) flushManagedIPRules(ipv6 bool) error {
 		return stale[i].Mark < stale[j].Mark
 	})
 	for _, pair := range stale {
-		deleteArgs := []string{
-			"rule",
-			"del",
-			"fwmark",
-			fmt.Sprintf("0x%x", pair.Mark),
-			"table",
-			strconv.Itoa(pair.Table),
-			"priority",
-			rulePriority,
-		}
-		if ipv6 {
-			deleteArgs = append([]string{"-6"}, deleteArgs...)
-		}
-		for i := 0; i < deleteLoopLimit; i++ {
-			if delErr := m.exec.Run("ip", deleteArgs...); delErr != nil {
-				break
-			}
-		}
+		m.deleteIPRule(pair.Mark, pair.Table, ipv6)
 	}
 	return nil
 }
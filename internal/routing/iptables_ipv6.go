@@ -0,0 +1,39 @@
+package routing
+
+import (
+	"fmt"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+// markTargetArgs returns the jump target for a binding's mark rules. Under
+// the drop IPv6 policy matched IPv6 traffic is dropped instead of marked, so
+// it cannot leave through the WAN when the provider has no IPv6.
+func markTargetArgs(tool string, binding RouteBinding, markHex string) []string {
+	if binding.MonitorOnly {
+		return monitorTargetArgs(binding)
+	}
+	if tool == "ip6tables" && binding.IPv6Policy == vpn.IPv6PolicyDrop {
+		return []string{"-j", "DROP"}
+	}
+	return []string{"-j", "MARK", "--set-mark", markHex}
+}
+
+// addIPv6NATRules installs the binding's ip6tables NAT rules according to its
+// VPN's IPv6 policy. The default masquerades like IPv4; drop needs no NAT
+// because the traffic never gets marked; native skips NAT for sources inside
+// the provider-routed prefix and masquerades everything else.
+func (m *RuleManager) addIPv6NATRules(chain, markHex string, binding RouteBinding) error {
+	switch binding.IPv6Policy {
+	case vpn.IPv6PolicyDrop:
+		return nil
+	case vpn.IPv6PolicyNative:
+		if binding.IPv6Prefix != "" {
+			args := append(ruleHead("nat", chain, binding), "-m", "mark", "--mark", markHex, "-o", binding.Interface, "-s", binding.IPv6Prefix, "-j", "RETURN")
+			if err := m.exec.Run("ip6tables", args...); err != nil {
+				return fmt.Errorf("add ipv6 native prefix rule for %s: %w", binding.GroupName, err)
+			}
+		}
+	}
+	return m.addNATRule("ip6tables", chain, markHex, binding)
+}
@@ -0,0 +1,53 @@
+package routing
+
+import "fmt"
+
+// addKillSwitchRules drops forwarded packets a kill switch binding marked for
+// its VPN that are about to leave through any other interface. While the
+// tunnel is up the mark routes them into it; once it goes down its route
+// table stops resolving, the lookup falls through to the main table and the
+// packets would otherwise leave unencrypted through the WAN. The rules repeat
+// the binding's client selectors so other groups sharing the VPN keep their
+// fallback.
+func (m *RuleManager) addKillSwitchRules(binding RouteBinding, chain, markHex string) error {
+	if !binding.KillSwitch || binding.Interface == "" {
+		return nil
+	}
+	for _, tool := range []string{"iptables", "ip6tables"} {
+		if err := m.addKillSwitchRulesByFamily(tool, chain, binding, markHex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *RuleManager) addKillSwitchRulesByFamily(tool, chain string, binding RouteBinding, markHex string) error {
+	isIPv6 := tool == "ip6tables"
+	base := append(ruleHead("mangle", chain, binding), "-m", "mark", "--mark", markHex, "!", "-o", binding.Interface)
+	if binding.HasSource {
+		setName := binding.SourceSetV4
+		if isIPv6 {
+			setName = binding.SourceSetV6
+		}
+		base = append(base, "-m", "set", "--match-set", setName, "src")
+	}
+	for _, sourceIface := range expandSelectorValues(binding.SourceInterfaces) {
+		for _, sourceMAC := range expandSelectorValues(binding.SourceMACs) {
+			for _, deviceMatch := range sourceDeviceMatches(binding, isIPv6) {
+				args := append([]string(nil), base...)
+				if sourceIface != "" {
+					args = append(args, "-i", sourceIface)
+				}
+				if sourceMAC != "" {
+					args = append(args, "-m", "mac", "--mac-source", sourceMAC)
+				}
+				args = append(args, deviceMatch...)
+				args = append(args, "-j", "DROP")
+				if err := m.exec.Run(tool, args...); err != nil {
+					return fmt.Errorf("add kill switch for %s: %w", binding.GroupName, err)
+				}
+			}
+		}
+	}
+	return nil
+}
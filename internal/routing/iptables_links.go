@@ -0,0 +1,84 @@
+package routing
+
+import "fmt"
+
+// ChainLinker is an optional RuleApplier extension that inspects and restores
+// the jumps from built-in chains into this application's root chains. UniFi
+// provisioning rewrites the built-in chains and drops those jumps, which
+// silently unroutes traffic even though the SVPN_* chains may survive.
+type ChainLinker interface {
+	LinkedChains() []string
+	RelinkChains() error
+}
+
+type chainLink struct {
+	tool   string
+	table  string
+	parent string
+	root   string
+}
+
+func (l chainLink) String() string {
+	return fmt.Sprintf("%s/%s %s->%s", l.tool, l.table, l.parent, l.root)
+}
+
+var rootChainLinks = []chainLink{
+	{tool: "iptables", table: "mangle", parent: "PREROUTING", root: markChainName},
+	{tool: "iptables", table: "mangle", parent: "FORWARD", root: mssChainName},
+	{tool: "iptables", table: "nat", parent: "POSTROUTING", root: natChainName},
+	{tool: "iptables", table: "nat", parent: "PREROUTING", root: dnsChainName},
+	{tool: "iptables", table: "mangle", parent: "FORWARD", root: qosChainName},
+	{tool: "iptables", table: "mangle", parent: "FORWARD", root: killChainName},
+	{tool: "ip6tables", table: "mangle", parent: "PREROUTING", root: markChainName},
+	{tool: "ip6tables", table: "mangle", parent: "FORWARD", root: mssChainName},
+	{tool: "ip6tables", table: "nat", parent: "POSTROUTING", root: natChainName},
+	{tool: "ip6tables", table: "nat", parent: "PREROUTING", root: dnsChainName},
+	{tool: "ip6tables", table: "mangle", parent: "FORWARD", root: qosChainName},
+	{tool: "ip6tables", table: "mangle", parent: "FORWARD", root: killChainName},
+}
+
+// LinkedChains lists the built-in -> root chain jumps that are installed.
+func (m *RuleManager) LinkedChains() []string {
+	linked := make([]string, 0, len(rootChainLinks))
+	for _, link := range rootChainLinks {
+		if err := m.exec.Run(link.tool, "-t", link.table, "-C", link.parent, "-j", link.root); err == nil {
+			linked = append(linked, link.String())
+		}
+	}
+	return linked
+}
+
+// RelinkChains restores missing jumps into root chains that still exist and
+// points each root chain back at the active generation. It does not rebuild
+// rules; chains wiped entirely need a full ApplyRules.
+func (m *RuleManager) RelinkChains() error {
+	active := m.detectActiveVariant()
+	if active == "" {
+		return nil
+	}
+	// The "stale" generation of the next apply is the one currently live.
+	_, _, _, liveMark, liveNAT, liveMSS := selectWorkingVariant(active)
+	_, liveDNS := selectWorkingDNSVariant(active)
+	_, liveQoS := selectWorkingQoSVariant(active)
+	_, liveKill := selectWorkingKillVariant(active)
+	generations := map[string]string{markChainName: liveMark, natChainName: liveNAT, mssChainName: liveMSS, dnsChainName: liveDNS, qosChainName: liveQoS, killChainName: liveKill}
+
+	var firstErr error
+	for _, link := range rootChainLinks {
+		if err := m.exec.Run(link.tool, "-t", link.table, "-S", link.root); err != nil {
+			continue
+		}
+		if err := m.exec.Run(link.tool, "-t", link.table, "-C", link.parent, "-j", link.root); err != nil {
+			if addErr := m.exec.Run(link.tool, "-t", link.table, "-A", link.parent, "-j", link.root); addErr != nil && firstErr == nil {
+				firstErr = fmt.Errorf("relink %s: %w", link, addErr)
+			}
+		}
+		generation := generations[link.root]
+		if err := m.exec.Run(link.tool, "-t", link.table, "-C", link.root, "-j", generation); err != nil {
+			if addErr := m.exec.Run(link.tool, "-t", link.table, "-I", link.root, "1", "-j", generation); addErr != nil && firstErr == nil {
+				firstErr = fmt.Errorf("relink %s/%s %s->%s: %w", link.tool, link.table, link.root, generation, addErr)
+			}
+		}
+	}
+	return firstErr
+}
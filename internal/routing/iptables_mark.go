@@ -18,6 +18,11 @@ func (m *RuleManager) addMarkRules(binding RouteBinding, bindingIndex int, chain
 			return fmt.Errorf("missing excluded source set names for group %s rule %d", binding.GroupName, binding.RuleIndex+1)
 		}
 	}
+	if binding.HasSourceDeviceSet {
+		if strings.TrimSpace(binding.SourceDeviceSetV4) == "" || strings.TrimSpace(binding.SourceDeviceSetV6) == "" {
+			return fmt.Errorf("missing source device set names for group %s rule %d", binding.GroupName, binding.RuleIndex+1)
+		}
+	}
 	if binding.HasDestination {
 		if strings.TrimSpace(binding.DestinationSetV4) == "" || strings.TrimSpace(binding.DestinationSetV6) == "" {
 			return fmt.Errorf("missing destination set names for group %s rule %d", binding.GroupName, binding.RuleIndex+1)
@@ -53,28 +58,43 @@ func (m *RuleManager) addMarkRulesByFamily(
 	if err := m.exec.Run(tool, "-t", "mangle", "-F", ruleChain); err != nil {
 		return fmt.Errorf("flush %s rule chain %s: %w", tool, ruleChain, err)
 	}
-	if err := m.exec.Run(tool, "-t", "mangle", "-A", chain, "-j", ruleChain); err != nil {
+	if err := m.exec.Run(tool, append(ruleHead("mangle", chain, binding), "-j", ruleChain)...); err != nil {
 		return fmt.Errorf("link %s chain %s -> %s: %w", tool, chain, ruleChain, err)
 	}
+	for _, iface := range binding.ExcludedInputInterfaces {
+		if err := m.exec.Run(tool, append(ruleHead("mangle", ruleChain, binding), "-i", iface, "-j", "RETURN")...); err != nil {
+			return fmt.Errorf("exclude %s uplink interface %s in %s: %w", tool, iface, ruleChain, err)
+		}
+	}
+	if err := m.addDNSMarkRules(tool, ruleChain, binding, markHex); err != nil {
+		return err
+	}
 
 	ports := expandPortSelectors(binding.DestinationPorts)
 	excludedPorts := expandPortSelectors(binding.ExcludedDestinationPorts)
 	sourceInterfaces := expandSelectorValues(binding.SourceInterfaces)
 	sourceMACs := expandSelectorValues(binding.SourceMACs)
+	deviceMatches := sourceDeviceMatches(binding, isIPv6)
 	for _, sourceIface := range sourceInterfaces {
 		for _, sourceMAC := range sourceMACs {
-			for _, port := range ports {
-				baseArgs := m.baseMarkRuleArgs(tool, ruleChain, binding, port, sourceIface, sourceMAC)
-				if err := m.addExclusionRulesByFamily(tool, binding, port, excludedPorts, baseArgs); err != nil {
-					return err
-				}
-				markArgs := append(append([]string(nil), baseArgs...), "-j", "MARK", "--set-mark", markHex)
-				if err := m.exec.Run(tool, markArgs...); err != nil {
-					family := "ipv4"
-					if isIPv6 {
-						family = "ipv6"
+			for _, deviceMatch := range deviceMatches {
+				for _, port := range ports {
+					baseArgs := m.baseMarkRuleArgs(tool, ruleChain, binding, port, sourceIface, sourceMAC)
+					baseArgs = append(baseArgs, deviceMatch...)
+					if err := m.addExclusionRulesByFamily(tool, binding, port, excludedPorts, baseArgs); err != nil {
+						return err
+					}
+					if err := m.addUploadLimitRule(tool, binding, baseArgs); err != nil {
+						return err
+					}
+					markArgs := append(append([]string(nil), baseArgs...), markTargetArgs(tool, binding, markHex)...)
+					if err := m.exec.Run(tool, markArgs...); err != nil {
+						family := "ipv4"
+						if isIPv6 {
+							family = "ipv6"
+						}
+						return fmt.Errorf("add %s mark rule for %s: %w", family, binding.GroupName, err)
 					}
-					return fmt.Errorf("add %s mark rule for %s: %w", family, binding.GroupName, err)
 				}
 			}
 		}
@@ -82,6 +102,31 @@ func (m *RuleManager) addMarkRulesByFamily(
 	return nil
 }
 
+// sourceDeviceMatches returns the alternative match arguments for a binding's
+// device groups: one per member MAC plus one for the member CIDR set. A
+// binding without device groups has a single empty alternative; one whose
+// groups are empty has none and so installs no mark rules.
+func sourceDeviceMatches(binding RouteBinding, isIPv6 bool) [][]string {
+	if !binding.HasSourceDevices {
+		return [][]string{nil}
+	}
+	matches := make([][]string, 0, len(binding.SourceDeviceMACs)+1)
+	for _, mac := range expandSelectorValues(binding.SourceDeviceMACs) {
+		if mac == "" {
+			continue
+		}
+		matches = append(matches, []string{"-m", "mac", "--mac-source", mac})
+	}
+	if binding.HasSourceDeviceSet {
+		setName := binding.SourceDeviceSetV4
+		if isIPv6 {
+			setName = binding.SourceDeviceSetV6
+		}
+		matches = append(matches, []string{"-m", "set", "--match-set", setName, "src"})
+	}
+	return matches
+}
+
 func (m *RuleManager) addExclusionRulesByFamily(
 	tool string,
 	binding RouteBinding,
@@ -126,6 +171,19 @@ func (m *RuleManager) addExclusionRulesByFamily(
 			return fmt.Errorf("exclude destination set for %s: %w", binding.GroupName, err)
 		}
 	}
+	if binding.HasDestination && binding.InvertDestination {
+		setName := binding.DestinationSetV4
+		if isIPv6 {
+			setName = binding.DestinationSetV6
+		}
+		args := append(append([]string(nil), baseArgs...),
+			"-m", "set", "--match-set", setName, "dst",
+			"-j", "RETURN",
+		)
+		if err := m.exec.Run(tool, args...); err != nil {
+			return fmt.Errorf("exclude inverted destination set for %s: %w", binding.GroupName, err)
+		}
+	}
 	for _, excludedPort := range excludedPorts {
 		if !portsOverlapForExclusion(includePort, excludedPort) {
 			continue
@@ -151,7 +209,7 @@ func (m *RuleManager) baseMarkRuleArgs(
 	sourceMAC string,
 ) []string {
 	isIPv6 := tool == "ip6tables"
-	args := []string{"-t", "mangle", "-A", chain}
+	args := ruleHead("mangle", chain, binding)
 	if binding.HasSource {
 		setName := binding.SourceSetV4
 		if isIPv6 {
@@ -159,7 +217,7 @@ func (m *RuleManager) baseMarkRuleArgs(
 		}
 		args = append(args, "-m", "set", "--match-set", setName, "src")
 	}
-	if binding.HasDestination {
+	if binding.HasDestination && !binding.InvertDestination {
 		setName := binding.DestinationSetV4
 		if isIPv6 {
 			setName = binding.DestinationSetV6
@@ -0,0 +1,28 @@
+package routing
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MonitorNFLOGGroup is the nflog group monitor-only rules log to. Watch it
+// with `tcpdump -i nflog:77` or an ulogd stack.
+const MonitorNFLOGGroup = 77
+
+// maxNFLOGPrefix is the kernel's nflog prefix limit, less the terminator.
+const maxNFLOGPrefix = 63
+
+// monitorTargetArgs logs the first packet of each connection a monitor-only
+// binding would mark, tagged with the rule, and leaves the packet unmarked.
+func monitorTargetArgs(binding RouteBinding) []string {
+	prefix := fmt.Sprintf("svpn-mon %s:%d", binding.GroupName, binding.RuleIndex+1)
+	if len(prefix) > maxNFLOGPrefix {
+		prefix = prefix[:maxNFLOGPrefix]
+	}
+	return []string{
+		"-m", "conntrack", "--ctstate", "NEW",
+		"-j", "NFLOG",
+		"--nflog-group", strconv.Itoa(MonitorNFLOGGroup),
+		"--nflog-prefix", prefix,
+	}
+}
@@ -0,0 +1,216 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RulePlanner is an optional RuleApplier extension used by apply dry-runs.
+// Both methods return rules in the same canonical form so they can be
+// compared line by line.
+type RulePlanner interface {
+	PlanRules(bindings []RouteBinding) ([]string, error)
+	LiveRules() ([]string, error)
+}
+
+// ruleRecorder captures the commands ApplyRules would run. Every command
+// succeeds and every read fails, so planning always targets generation A
+// against an empty system.
+type ruleRecorder struct {
+	calls [][]string
+}
+
+func (r *ruleRecorder) Run(name string, args ...string) error {
+	r.calls = append(r.calls, append([]string{name}, args...))
+	return nil
+}
+
+func (r *ruleRecorder) Output(name string, args ...string) ([]byte, error) {
+	return nil, errors.New("planning: no live state")
+}
+
+func (r *ruleRecorder) RunWithInput(input []byte, name string, args ...string) error {
+	return r.Run(name, args...)
+}
+
+// PlanRules returns the iptables rules and ip rules ApplyRules would install
+// for bindings, without running any command.
+func (m *RuleManager) PlanRules(bindings []RouteBinding) ([]string, error) {
+	recorder := &ruleRecorder{}
+	if err := NewRuleManager(recorder).ApplyRules(bindings); err != nil {
+		return nil, err
+	}
+	rules := make([]string, 0, len(recorder.calls))
+	for _, call := range recorder.calls {
+		switch call[0] {
+		case "iptables", "ip6tables":
+			if len(call) < 5 || call[1] != "-t" || call[3] != "-A" {
+				continue
+			}
+			rules = append(rules, canonicalIPTablesRule(call[0], call[2], call[3:]))
+		case "ip":
+			args := call[1:]
+			ipv6 := len(args) > 0 && args[0] == "-6"
+			if ipv6 {
+				args = args[1:]
+			}
+			if len(args) < 2 || args[0] != "rule" || args[1] != "add" {
+				continue
+			}
+			rules = append(rules, ipRuleLine(ipv6, strings.Join(args[2:], " ")))
+		}
+	}
+	return dedupeSortedStrings(rules), nil
+}
+
+// LiveRules returns the rules of the active chain generation, any orphaned
+// rules carrying this application's comment, and the managed ip rules
+// currently installed. Generation B names are rewritten to their generation A
+// equivalents to match PlanRules; orphans keep their chain and so always
+// show up as unexpected.
+func (m *RuleManager) LiveRules() ([]string, error) {
+	active := m.detectActiveVariant()
+	chains := map[string]struct{}{markChainA: {}, natChainA: {}, mssChainA: {}, dnsChainA: {}, qosChainA: {}, killChainA: {}}
+	if active == markChainB {
+		chains = map[string]struct{}{markChainB: {}, natChainB: {}, mssChainB: {}, dnsChainB: {}, qosChainB: {}, killChainB: {}}
+	}
+	rulePrefix := generationRuleChainPrefix(active)
+	rules := make([]string, 0)
+	for _, tool := range []string{"iptables", "ip6tables"} {
+		for _, table := range []string{"mangle", "nat"} {
+			output, err := m.exec.Output(tool, "-t", table, "-S")
+			if err != nil {
+				return nil, fmt.Errorf("%s -t %s -S: %w", tool, table, err)
+			}
+			for _, line := range strings.Split(string(output), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) < 2 || fields[0] != "-A" {
+					continue
+				}
+				if !isManagedChain(fields[1]) {
+					if hasRuleComment(unquoteRuleComment(fields)) {
+						rules = append(rules, canonicalIPTablesRule(tool, table, fields))
+					}
+					continue
+				}
+				if active == "" {
+					continue
+				}
+				if _, ok := chains[fields[1]]; !ok && !strings.HasPrefix(fields[1], rulePrefix) {
+					continue
+				}
+				rules = append(rules, canonicalIPTablesRule(tool, table, generationAFields(fields)))
+			}
+		}
+	}
+	for _, ipv6 := range []bool{false, true} {
+		existing, loaded := m.loadManagedIPRules(ipv6)
+		if !loaded {
+			return nil, fmt.Errorf("read managed ip rules")
+		}
+		for _, pair := range existing {
+			rules = append(rules, ipRuleLine(ipv6, fmt.Sprintf("fwmark 0x%x table %d priority %s", pair.Mark, pair.Table, rulePriority)))
+		}
+	}
+	return dedupeSortedStrings(rules), nil
+}
+
+func ipRuleLine(ipv6 bool, spec string) string {
+	if ipv6 {
+		return "ip -6 rule " + spec
+	}
+	return "ip rule " + spec
+}
+
+func generationAFields(fields []string) []string {
+	out := make([]string, len(fields))
+	for i, field := range fields {
+		switch {
+		case field == markChainB:
+			field = markChainA
+		case field == natChainB:
+			field = natChainA
+		case field == mssChainB:
+			field = mssChainA
+		case field == dnsChainB:
+			field = dnsChainA
+		case field == qosChainB:
+			field = qosChainA
+		case field == killChainB:
+			field = killChainA
+		case strings.HasPrefix(field, "SVPNB_"):
+			field = "SVPNA_" + strings.TrimPrefix(field, "SVPNB_")
+		}
+		out[i] = field
+	}
+	return out
+}
+
+// canonicalIPTablesRule renders an "-A chain ..." rule so that the arguments
+// this package passes and the `iptables -S` listing of the same rule compare
+// equal: implicit protocol matches are folded into -p, MARK uses its listed
+// --set-xmark form, MACs are upper-cased and match clauses are sorted.
+func canonicalIPTablesRule(tool, table string, fields []string) string {
+	cleaned := make([]string, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case field == "--set-mark" && i+1 < len(fields):
+			cleaned = append(cleaned, "--set-xmark", fields[i+1]+"/0xffffffff")
+			i++
+			continue
+		case field == "--comment" && i+1 < len(fields):
+			cleaned = append(cleaned, field, strings.Trim(fields[i+1], `"`))
+			i++
+			continue
+		case field == "--mac-source" && i+1 < len(fields):
+			cleaned = append(cleaned, field, strings.ToUpper(fields[i+1]))
+			i++
+			continue
+		}
+		cleaned = append(cleaned, field)
+	}
+
+	grouped := make([][]string, 0)
+	for _, field := range cleaned {
+		if len(grouped) == 0 || (strings.HasPrefix(field, "-") && !strings.HasPrefix(field, "--")) {
+			grouped = append(grouped, nil)
+		}
+		grouped[len(grouped)-1] = append(grouped[len(grouped)-1], field)
+	}
+
+	var head, target string
+	protocol := -1
+	clauses := make([]string, 0, len(grouped))
+	protocolOptions := make([]string, 0)
+	for _, clause := range grouped {
+		switch {
+		case clause[0] == "-A":
+			head = strings.Join(clause, " ")
+		case clause[0] == "-j":
+			target = strings.Join(clause, " ")
+		case clause[0] == "-m" && len(clause) > 1 && (clause[1] == "tcp" || clause[1] == "udp") && protocol >= 0:
+			// The listing spells out the implicit "-m tcp" that -p loads;
+			// fold its options into the -p clause.
+			protocolOptions = append(protocolOptions, clause[2:]...)
+		default:
+			if clause[0] == "-p" {
+				protocol = len(clauses)
+			}
+			clauses = append(clauses, strings.Join(clause, " "))
+		}
+	}
+	if protocol >= 0 && len(protocolOptions) > 0 {
+		clauses[protocol] += " " + strings.Join(protocolOptions, " ")
+	}
+	sort.Strings(clauses)
+
+	parts := []string{tool, "-t", table, head}
+	parts = append(parts, clauses...)
+	if target != "" {
+		parts = append(parts, target)
+	}
+	return strings.Join(parts, " ")
+}
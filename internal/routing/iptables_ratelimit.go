@@ -0,0 +1,133 @@
+package routing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+// maxRateLimitKbit bounds per-rule bandwidth limits at 10 Gbit/s.
+const maxRateLimitKbit = 10_000_000
+
+func normalizeRateLimit(value int, direction string, idx int) (int, error) {
+	if value < 0 || value > maxRateLimitKbit {
+		return 0, fmt.Errorf("%w: rule %d %s limit must be between 0 and %d kbit/s", ErrGroupValidation, idx+1, direction, maxRateLimitKbit)
+	}
+	return value, nil
+}
+
+// rateLimitName returns the hashlimit table name for one direction of a
+// binding. Every mark rule of the binding shares the table, so the limit
+// applies to the rule's traffic as a whole. Names are capped at 15 bytes.
+func rateLimitName(binding RouteBinding, direction string) string {
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(binding.GroupName))
+	_, _ = hasher.Write([]byte{0})
+	_, _ = hasher.Write([]byte(strconv.Itoa(binding.RuleIndex)))
+	if binding.Canary {
+		_, _ = hasher.Write([]byte("canary"))
+	}
+	return fmt.Sprintf("svq%08x%s", hasher.Sum32(), direction)
+}
+
+// rateLimitArgs polices traffic above kbit with a shared hashlimit bucket
+// that allows one second of burst.
+func rateLimitArgs(name string, kbit int) []string {
+	bytesPerSecond := kbit * 1000 / 8
+	burstKB := bytesPerSecond / 1024
+	if burstKB < 1 {
+		burstKB = 1
+	}
+	return []string{
+		"-m", "hashlimit",
+		"--hashlimit-name", name,
+		"--hashlimit-above", strconv.Itoa(bytesPerSecond) + "b/s",
+		"--hashlimit-burst", strconv.Itoa(burstKB) + "kb",
+		"-j", "DROP",
+	}
+}
+
+// addUploadLimitRule drops client packets matched by baseArgs once the
+// binding's upload limit is exceeded.
+func (m *RuleManager) addUploadLimitRule(tool string, binding RouteBinding, baseArgs []string) error {
+	if binding.UploadLimitKbit <= 0 || binding.MonitorOnly {
+		return nil
+	}
+	args := append(append([]string(nil), baseArgs...), rateLimitArgs(rateLimitName(binding, "u"), binding.UploadLimitKbit)...)
+	if err := m.exec.Run(tool, args...); err != nil {
+		return fmt.Errorf("add upload limit for %s: %w", binding.GroupName, err)
+	}
+	return nil
+}
+
+// addDownloadLimitRules polices replies arriving on the egress VPN interface.
+// The rules live in mangle FORWARD: in PREROUTING, replies to masqueraded
+// flows still carry the tunnel address as their destination because nat
+// PREROUTING has not reversed the NAT yet, so client selectors never match.
+// After de-NAT, reply packets carry the rule's selectors mirrored: clients
+// are the destination and the rule's destinations are the source. Source
+// interfaces become the output interface. MACs are not visible on replies,
+// so rules matching clients only by MAC are limited across every client
+// they could cover.
+func (m *RuleManager) addDownloadLimitRules(binding RouteBinding, chain string) error {
+	if binding.DownloadLimitKbit <= 0 || binding.Interface == "" {
+		return nil
+	}
+	for _, tool := range []string{"iptables", "ip6tables"} {
+		if err := m.addDownloadLimitRulesByFamily(tool, chain, binding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *RuleManager) addDownloadLimitRulesByFamily(tool string, chain string, binding RouteBinding) error {
+	isIPv6 := tool == "ip6tables"
+	base := append(ruleHead("mangle", chain, binding), "-i", binding.Interface, "-m", "conntrack", "--ctdir", "REPLY")
+	if binding.HasSource {
+		setName := binding.SourceSetV4
+		if isIPv6 {
+			setName = binding.SourceSetV6
+		}
+		base = append(base, "-m", "set", "--match-set", setName, "dst")
+	}
+	if binding.HasDestination {
+		setName := binding.DestinationSetV4
+		if isIPv6 {
+			setName = binding.DestinationSetV6
+		}
+		if binding.InvertDestination {
+			base = append(base, "-m", "set", "!", "--match-set", setName, "src")
+		} else {
+			base = append(base, "-m", "set", "--match-set", setName, "src")
+		}
+	}
+	clients := [][]string{nil}
+	if binding.HasSourceDeviceSet {
+		setName := binding.SourceDeviceSetV4
+		if isIPv6 {
+			setName = binding.SourceDeviceSetV6
+		}
+		clients = [][]string{{"-m", "set", "--match-set", setName, "dst"}}
+	}
+	name := rateLimitName(binding, "d")
+	for _, outIface := range expandSelectorValues(binding.SourceInterfaces) {
+		for _, client := range clients {
+			for _, port := range expandPortSelectors(binding.DestinationPorts) {
+				args := append([]string(nil), base...)
+				if outIface != "" {
+					args = append(args, "-o", outIface)
+				}
+				args = append(args, client...)
+				if port.Protocol != "" {
+					args = append(args, "-p", port.Protocol, "--sport", formatPortRange(port))
+				}
+				args = append(args, rateLimitArgs(name, binding.DownloadLimitKbit)...)
+				if err := m.exec.Run(tool, args...); err != nil {
+					return fmt.Errorf("add download limit for %s: %w", binding.GroupName, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -1,9 +1,13 @@
 package routing
 
 import (
+	"errors"
 	"reflect"
 	"strings"
+	"syscall"
 	"testing"
+
+	"split-vpn-webui/internal/iprule"
 )
 
 func TestApplyRulesIncludesIPv4AndIPv6Commands(t *testing.T) {
@@ -206,6 +210,275 @@ func TestApplyRulesAppliesExclusionsAndMulticastByRule(t *testing.T) {
 	}
 }
 
+func TestApplyRulesInvertedDestinationsExcludeDestinationSet(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+
+	bindings := []RouteBinding{
+		{
+			GroupName:         "TV",
+			RuleIndex:         0,
+			SourceMACs:        []string{"00:30:93:10:0a:12"},
+			DestinationSetV4:  "svpn_tv_r1d4",
+			DestinationSetV6:  "svpn_tv_r1d6",
+			HasDestination:    true,
+			InvertDestination: true,
+			Mark:              0x173,
+			RouteTable:        206,
+			Interface:         "wg-tv",
+		},
+	}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	calls := joinCalls(mock.RunCalls)
+	for _, expected := range []string{
+		"iptables -t mangle -A SVPNA_001_4 -m mac --mac-source 00:30:93:10:0a:12 -m set --match-set svpn_tv_r1d4 dst -j RETURN",
+		"iptables -t mangle -A SVPNA_001_4 -m mac --mac-source 00:30:93:10:0a:12 -j MARK --set-mark 0x173",
+		"ip6tables -t mangle -A SVPNA_001_6 -m mac --mac-source 00:30:93:10:0a:12 -m set --match-set svpn_tv_r1d6 dst -j RETURN",
+		"ip6tables -t mangle -A SVPNA_001_6 -m mac --mac-source 00:30:93:10:0a:12 -j MARK --set-mark 0x173",
+	} {
+		if !containsCall(calls, expected) {
+			t.Fatalf("expected call %q in %#v", expected, calls)
+		}
+	}
+}
+
+func TestApplyRulesOrdersFullTunnelBeforeDestinationBindings(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+
+	bindings := []RouteBinding{
+		{
+			GroupName:        "A-Streaming",
+			DestinationSetV4: "svpn_a_r1d4",
+			DestinationSetV6: "svpn_a_r1d6",
+			HasDestination:   true,
+			Mark:             0x174,
+			RouteTable:       207,
+			Interface:        "wg-a",
+		},
+		{
+			GroupName:  "B-Laptop",
+			SourceMACs: []string{"00:30:93:10:0a:13"},
+			FullTunnel: true,
+			Mark:       0x175,
+			RouteTable: 208,
+			Interface:  "wg-b",
+		},
+	}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	calls := joinCalls(mock.RunCalls)
+	fullTunnelAt, streamingAt := -1, -1
+	for i, call := range calls {
+		switch call {
+		case "iptables -t mangle -A SVPNA_001_4 -m mac --mac-source 00:30:93:10:0a:13 -j MARK --set-mark 0x175":
+			fullTunnelAt = i
+		case "iptables -t mangle -A SVPNA_002_4 -m set --match-set svpn_a_r1d4 dst -j MARK --set-mark 0x174":
+			streamingAt = i
+		}
+	}
+	if fullTunnelAt < 0 || streamingAt < 0 || fullTunnelAt > streamingAt {
+		t.Fatalf("full tunnel binding must be marked before destination bindings: %#v", calls)
+	}
+}
+
+func TestApplyRulesSkipsTrafficFromUplinkInterfaces(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+
+	bindings := []RouteBinding{
+		{
+			GroupName:               "Nested",
+			RuleIndex:               0,
+			DestinationSetV4:        "svpn_nested_r1d4",
+			DestinationSetV6:        "svpn_nested_r1d6",
+			HasDestination:          true,
+			Mark:                    0xcb,
+			RouteTable:              205,
+			Interface:               "wg-sv-inner",
+			ExcludedInputInterfaces: []string{"wg-sv-outer"},
+		},
+	}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	calls := joinCalls(mock.RunCalls)
+	for _, expected := range []string{
+		"iptables -t mangle -A SVPNA_001_4 -i wg-sv-outer -j RETURN",
+		"ip6tables -t mangle -A SVPNA_001_6 -i wg-sv-outer -j RETURN",
+	} {
+		if !containsCall(calls, expected) {
+			t.Fatalf("expected call %q in %#v", expected, calls)
+		}
+	}
+	returnAt, markAt := -1, -1
+	for i, call := range calls {
+		switch call {
+		case "iptables -t mangle -A SVPNA_001_4 -i wg-sv-outer -j RETURN":
+			returnAt = i
+		case "iptables -t mangle -A SVPNA_001_4 -m set --match-set svpn_nested_r1d4 dst -j MARK --set-mark 0xcb":
+			markAt = i
+		}
+	}
+	if returnAt < 0 || markAt < 0 || returnAt > markAt {
+		t.Fatalf("uplink exclusion must precede the mark rule: %#v", calls)
+	}
+}
+
+func TestApplyRulesMonitorOnlyLogsInsteadOfMarking(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+
+	bindings := []RouteBinding{
+		{
+			GroupName:       "Trial",
+			RuleIndex:       0,
+			SourceSetV4:     "svpn_trial_r1s4",
+			SourceSetV6:     "svpn_trial_r1s6",
+			HasSource:       true,
+			Mark:            0x169,
+			RouteTable:      201,
+			Interface:       "wg-sv-trial",
+			DNSRedirect:     DNSRedirectVPN,
+			DNSServersV4:    []string{"10.64.0.1"},
+			UploadLimitKbit: 2000,
+			MonitorOnly:     true,
+		},
+	}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	calls := joinCalls(mock.RunCalls)
+	expected := "iptables -t mangle -A SVPNA_001_4 -m set --match-set svpn_trial_r1s4 src -m conntrack --ctstate NEW -j NFLOG --nflog-group 77 --nflog-prefix svpn-mon Trial:1"
+	if !containsCall(calls, expected) {
+		t.Fatalf("expected call %q in %#v", expected, calls)
+	}
+	for _, call := range calls {
+		for _, forbidden := range []string{"--set-mark", "MASQUERADE", "DNAT", "hashlimit", "fwmark"} {
+			if strings.Contains(call, forbidden) {
+				t.Fatalf("monitor-only binding must not install %q, got %q", forbidden, call)
+			}
+		}
+	}
+}
+
+func TestApplyRulesEmitsBandwidthLimits(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+
+	bindings := []RouteBinding{
+		{
+			GroupName:         "Kids",
+			RuleIndex:         0,
+			SourceSetV4:       "svpn_kids_r1s4",
+			SourceSetV6:       "svpn_kids_r1s6",
+			HasSource:         true,
+			DestinationPorts:  []PortRange{{Protocol: "tcp", Start: 443}},
+			Mark:              0xcc,
+			RouteTable:        206,
+			Interface:         "wg-sv-kids",
+			UploadLimitKbit:   2000,
+			DownloadLimitKbit: 20000,
+		},
+	}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	up := rateLimitName(bindings[0], "u")
+	down := rateLimitName(bindings[0], "d")
+	if len(up) > 15 || up == down {
+		t.Fatalf("unexpected hashlimit names %q / %q", up, down)
+	}
+	calls := joinCalls(mock.RunCalls)
+	for _, expected := range []string{
+		"iptables -t mangle -A SVPNA_001_4 -m set --match-set svpn_kids_r1s4 src -p tcp --dport 443 -m hashlimit --hashlimit-name " + up + " --hashlimit-above 250000b/s --hashlimit-burst 244kb -j DROP",
+		"iptables -t mangle -C FORWARD -j SVPN_QOS",
+		"iptables -t mangle -A SVPN_QOS_A -i wg-sv-kids -m conntrack --ctdir REPLY -m set --match-set svpn_kids_r1s4 dst -p tcp --sport 443 -m hashlimit --hashlimit-name " + down + " --hashlimit-above 2500000b/s --hashlimit-burst 2441kb -j DROP",
+		"ip6tables -t mangle -A SVPN_QOS_A -i wg-sv-kids -m conntrack --ctdir REPLY -m set --match-set svpn_kids_r1s6 dst -p tcp --sport 443 -m hashlimit --hashlimit-name " + down + " --hashlimit-above 2500000b/s --hashlimit-burst 2441kb -j DROP",
+	} {
+		if !containsCall(calls, expected) {
+			t.Fatalf("expected call %q in %#v", expected, calls)
+		}
+	}
+	// Replies only match client selectors after nat PREROUTING has reversed
+	// the masquerade, so the download limit must never be installed in the
+	// PREROUTING mark chains.
+	for _, call := range calls {
+		if strings.Contains(call, "--hashlimit-name "+down) && !strings.Contains(call, "-A SVPN_QOS_A ") {
+			t.Fatalf("download limit installed outside mangle FORWARD: %q", call)
+		}
+	}
+}
+
+func TestApplyRulesLimitsDownloadPerSourceInterface(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+	binding := RouteBinding{
+		GroupName:         "Guests",
+		SourceInterfaces:  []string{"br50"},
+		Mark:              0xcd,
+		RouteTable:        207,
+		Interface:         "wg-sv-guest",
+		DownloadLimitKbit: 8000,
+	}
+	if err := manager.ApplyRules([]RouteBinding{binding}); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+	expected := "iptables -t mangle -A SVPN_QOS_A -i wg-sv-guest -m conntrack --ctdir REPLY -o br50 -m hashlimit --hashlimit-name " + rateLimitName(binding, "d") + " --hashlimit-above 1000000b/s --hashlimit-burst 976kb -j DROP"
+	if calls := joinCalls(mock.RunCalls); !containsCall(calls, expected) {
+		t.Fatalf("expected call %q in %#v", expected, calls)
+	}
+}
+
+func TestApplyRulesKillSwitchDropsTrafficOutsideTheTunnel(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+	bindings := []RouteBinding{
+		{
+			GroupName:        "Guests",
+			SourceInterfaces: []string{"br50"},
+			Mark:             0xcd,
+			RouteTable:       207,
+			Interface:        "wg-sv-guest",
+			FullTunnel:       true,
+			KillSwitch:       true,
+		},
+		{
+			GroupName:        "Office",
+			SourceInterfaces: []string{"br0"},
+			Mark:             0xcd,
+			RouteTable:       207,
+			Interface:        "wg-sv-guest",
+		},
+	}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+	calls := joinCalls(mock.RunCalls)
+	for _, expected := range []string{
+		"iptables -t mangle -C FORWARD -j SVPN_KILL",
+		"iptables -t mangle -A SVPN_KILL_A -m mark --mark 0xcd ! -o wg-sv-guest -i br50 -j DROP",
+		"ip6tables -t mangle -A SVPN_KILL_A -m mark --mark 0xcd ! -o wg-sv-guest -i br50 -j DROP",
+	} {
+		if !containsCall(calls, expected) {
+			t.Fatalf("expected call %q in %#v", expected, calls)
+		}
+	}
+	for _, call := range calls {
+		if strings.Contains(call, "-A SVPN_KILL_A") && strings.Contains(call, "br0") {
+			t.Fatalf("kill switch covers a group without one: %q", call)
+		}
+	}
+}
+
 func TestApplyRulesEmitsMSSClampRules(t *testing.T) {
 	mock := &MockExec{}
 	manager := NewRuleManager(mock)
@@ -317,6 +590,10 @@ func TestFlushRulesRemovesChainsAndManagedRules(t *testing.T) {
 		"iptables -t mangle -F SVPN_MSS_B",
 		"ip6tables -t mangle -F SVPN_MSS_A",
 		"ip6tables -t mangle -F SVPN_MSS_B",
+		"iptables -t mangle -D FORWARD -j SVPN_QOS",
+		"ip6tables -t mangle -F SVPN_QOS_B",
+		"iptables -t mangle -D FORWARD -j SVPN_KILL",
+		"ip6tables -t mangle -F SVPN_KILL_B",
 		"iptables -t nat -F SVPN_NAT",
 		"ip6tables -t nat -F SVPN_NAT",
 		"ip rule del fwmark 0xc9 table 201 priority 100",
@@ -328,10 +605,24 @@ func TestFlushRulesRemovesChainsAndManagedRules(t *testing.T) {
 	}
 }
 
+// joinCalls renders calls with their rule comments removed so assertions
+// focus on matching; TestApplyRulesAnnotatesRulesWithComments covers them.
 func joinCalls(calls [][]string) []string {
 	out := make([]string, 0, len(calls))
 	for _, call := range calls {
-		out = append(out, strings.Join(call, " "))
+		out = append(out, strings.Join(withoutRuleComment(call), " "))
+	}
+	return out
+}
+
+func withoutRuleComment(call []string) []string {
+	out := make([]string, 0, len(call))
+	for i := 0; i < len(call); i++ {
+		if call[i] == "-m" && i+3 < len(call) && call[i+1] == "comment" && call[i+2] == "--comment" {
+			i += 3
+			continue
+		}
+		out = append(out, call[i])
 	}
 	return out
 }
@@ -344,3 +635,186 @@ func containsCall(calls []string, needle string) bool {
 	}
 	return false
 }
+
+type fakeIPRuleClient struct {
+	rules   []iprule.Rule
+	added   []iprule.Rule
+	deleted []iprule.Rule
+}
+
+func (f *fakeIPRuleClient) List(ipv6 bool) ([]iprule.Rule, error) {
+	if ipv6 {
+		return nil, errors.New("netlink unavailable")
+	}
+	return append([]iprule.Rule(nil), f.rules...), nil
+}
+
+func (f *fakeIPRuleClient) Add(rule iprule.Rule, ipv6 bool) error {
+	if ipv6 {
+		return errors.New("netlink unavailable")
+	}
+	f.added = append(f.added, rule)
+	f.rules = append(f.rules, rule)
+	return nil
+}
+
+func (f *fakeIPRuleClient) Delete(rule iprule.Rule, ipv6 bool) error {
+	if ipv6 {
+		return errors.New("netlink unavailable")
+	}
+	for i, existing := range f.rules {
+		if existing == rule {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			f.deleted = append(f.deleted, rule)
+			return nil
+		}
+	}
+	return syscall.ENOENT
+}
+
+func TestReconcileIPRulesPrefersNetlinkAndFallsBackToCommand(t *testing.T) {
+	mock := &MockExec{
+		Outputs: map[string][]byte{"ip -6 rule show": []byte("100: from all fwmark 0xc9 lookup 201\n")},
+	}
+	netlinkRules := &fakeIPRuleClient{rules: []iprule.Rule{
+		{Priority: 100, Mark: 0xc9, Table: 201},
+		{Priority: 32766, Table: 254},
+	}}
+	manager := &RuleManager{exec: mock, ipRules: netlinkRules}
+
+	desired := map[uint32]int{0x169: 202}
+	if err := manager.reconcileManagedIPRules(desired, false); err != nil {
+		t.Fatalf("reconcile ipv4 failed: %v", err)
+	}
+	if err := manager.reconcileManagedIPRules(desired, true); err != nil {
+		t.Fatalf("reconcile ipv6 failed: %v", err)
+	}
+
+	want := iprule.Rule{Priority: 100, Mark: 0x169, Table: 202}
+	if len(netlinkRules.added) != 1 || netlinkRules.added[0] != want {
+		t.Fatalf("expected netlink add of %+v, got %+v", want, netlinkRules.added)
+	}
+	if len(netlinkRules.deleted) != 1 || netlinkRules.deleted[0].Mark != 0xc9 {
+		t.Fatalf("expected stale netlink rule to be deleted, got %+v", netlinkRules.deleted)
+	}
+	calls := joinCalls(mock.RunCalls)
+	for _, call := range calls {
+		if strings.HasPrefix(call, "ip rule") {
+			t.Fatalf("expected ipv4 rules to use netlink only, got %#v", calls)
+		}
+	}
+	for _, expected := range []string{
+		"ip -6 rule add fwmark 0x169 table 202 priority 100",
+		"ip -6 rule del fwmark 0xc9 table 201 priority 100",
+	} {
+		if !containsCall(calls, expected) {
+			t.Fatalf("expected fallback call %q in %#v", expected, calls)
+		}
+	}
+}
+
+func TestApplyRulesHonoursIPv6Policy(t *testing.T) {
+	mock := &MockExec{}
+	manager := NewRuleManager(mock)
+
+	bindings := []RouteBinding{
+		{
+			GroupName:        "NoV6",
+			RuleIndex:        0,
+			DestinationSetV4: "svpn_nov6_r1d4",
+			DestinationSetV6: "svpn_nov6_r1d6",
+			HasDestination:   true,
+			Mark:             0xcd,
+			RouteTable:       207,
+			Interface:        "wg-sv-nov6",
+			IPv6Policy:       "drop",
+		},
+		{
+			GroupName:        "Native",
+			RuleIndex:        0,
+			DestinationSetV4: "svpn_native_r1d4",
+			DestinationSetV6: "svpn_native_r1d6",
+			HasDestination:   true,
+			Mark:             0xce,
+			RouteTable:       208,
+			Interface:        "wg-sv-native",
+			IPv6Policy:       "native",
+			IPv6Prefix:       "2001:db8:1234::/48",
+		},
+	}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	calls := joinCalls(mock.RunCalls)
+	for _, expected := range []string{
+		"iptables -t mangle -A SVPNA_002_4 -m set --match-set svpn_nov6_r1d4 dst -j MARK --set-mark 0xcd",
+		"ip6tables -t mangle -A SVPNA_002_6 -m set --match-set svpn_nov6_r1d6 dst -j DROP",
+		"iptables -t nat -A SVPN_NAT_A -m mark --mark 0xcd -o wg-sv-nov6 -j MASQUERADE",
+		"ip6tables -t nat -A SVPN_NAT_A -m mark --mark 0xce -o wg-sv-native -s 2001:db8:1234::/48 -j RETURN",
+		"ip6tables -t nat -A SVPN_NAT_A -m mark --mark 0xce -o wg-sv-native -j MASQUERADE",
+	} {
+		if !containsCall(calls, expected) {
+			t.Fatalf("expected call %q in %#v", expected, calls)
+		}
+	}
+	for _, call := range calls {
+		if strings.HasPrefix(call, "ip6tables -t nat -A SVPN_NAT_A -m mark --mark 0xcd") {
+			t.Fatalf("drop policy must not install ipv6 nat: %q", call)
+		}
+	}
+}
+
+func TestApplyRulesAnnotatesRulesWithComments(t *testing.T) {
+	mock := &MockExec{Outputs: map[string][]byte{
+		"iptables -t mangle -S": []byte("-A PREROUTING -j SVPN_MARK\n" +
+			"-A PREROUTING -i br9 -j MARK --set-xmark 0x1/0xffffffff\n" +
+			"-A PREROUTING -m comment --comment \"svpn:Old:2\" -j MARK --set-xmark 0x170/0xffffffff\n"),
+	}}
+	manager := NewRuleManager(mock)
+
+	bindings := []RouteBinding{
+		{
+			GroupName:        "Media",
+			RuleIndex:        1,
+			SourceSetV4:      "svpn_media_r2s4",
+			SourceSetV6:      "svpn_media_r2s6",
+			HasSource:        true,
+			ExcludeMulticast: true,
+			Mark:             0x169,
+			RouteTable:       201,
+			Interface:        "wg-sgp",
+			DNSRedirect:      DNSRedirectLocal,
+			MSSClampV4:       "pmtu",
+			UploadLimitKbit:  1000,
+		},
+	}
+	if err := manager.ApplyRules(bindings); err != nil {
+		t.Fatalf("ApplyRules failed: %v", err)
+	}
+
+	appended := 0
+	for _, call := range mock.RunCalls {
+		if len(call) < 5 || call[3] != "-A" {
+			continue
+		}
+		appended++
+		joined := strings.Join(call, " ")
+		if !strings.Contains(joined, " -m comment --comment svpn:Media:2 ") {
+			t.Fatalf("rule without attribution comment: %q", joined)
+		}
+	}
+	if appended == 0 {
+		t.Fatalf("expected appended rules, got %#v", mock.RunCalls)
+	}
+
+	calls := joinCalls(mock.RunCalls)
+	if !containsCall(calls, "iptables -t mangle -D PREROUTING -j MARK --set-xmark 0x170/0xffffffff") {
+		t.Fatalf("expected orphaned rule to be deleted, got %#v", calls)
+	}
+	for _, call := range calls {
+		if strings.Contains(call, "-D PREROUTING -i br9") || strings.Contains(call, "-D PREROUTING -j SVPN_MARK") {
+			t.Fatalf("deleted a rule the app does not own: %q", call)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	vlanSelectorPrefix    = "vlan:"
+	networkSelectorPrefix = "network:"
+	maxNetworkNameLength  = 64
+)
+
+// networkSelector is a source selector naming a UniFi network rather than
+// its bridge or subnet: "vlan:20" by VLAN ID or "network:IoT" by name. As a
+// source interface it expands to the network's bridge, as a source CIDR to
+// the subnets on that bridge, both read at every apply so rules keep working
+// when the network's addressing changes.
+type networkSelector struct {
+	vlan int
+	name string
+}
+
+func (s networkSelector) String() string {
+	if s.name != "" {
+		return networkSelectorPrefix + s.name
+	}
+	return vlanSelectorPrefix + strconv.Itoa(s.vlan)
+}
+
+// parseNetworkSelector parses a VLAN or network-name selector. ok is false
+// when value does not use either prefix.
+func parseNetworkSelector(value string) (networkSelector, bool, error) {
+	trimmed := strings.TrimSpace(value)
+	lower := strings.ToLower(trimmed)
+	switch {
+	case strings.HasPrefix(lower, vlanSelectorPrefix):
+		id, err := strconv.Atoi(strings.TrimSpace(trimmed[len(vlanSelectorPrefix):]))
+		if err != nil || id < 1 || id > 4094 {
+			return networkSelector{}, true, fmt.Errorf("VLAN ID must be between 1 and 4094")
+		}
+		return networkSelector{vlan: id}, true, nil
+	case strings.HasPrefix(lower, networkSelectorPrefix):
+		name := strings.TrimSpace(trimmed[len(networkSelectorPrefix):])
+		if name == "" || len(name) > maxNetworkNameLength || strings.ContainsAny(name, ",\n\t") {
+			return networkSelector{}, true, fmt.Errorf("network name must be 1-%d characters", maxNetworkNameLength)
+		}
+		return networkSelector{name: name}, true, nil
+	default:
+		return networkSelector{}, false, nil
+	}
+}
+
+// LANNetwork is a network selector resolved to the bridge and subnets it
+// currently stands for. Error explains why a selector matches nothing.
+type LANNetwork struct {
+	Selector  string   `json:"selector"`
+	Name      string   `json:"name,omitempty"`
+	VLAN      int      `json:"vlan,omitempty"`
+	Interface string   `json:"interface,omitempty"`
+	Subnets   []string `json:"subnets,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// NetworkDirectory maps UniFi network names to VLAN IDs, e.g. from the
+// controller's network config. The default network is VLAN 1.
+type NetworkDirectory func(ctx context.Context) (map[string]int, error)
+
+// lanNetworks holds resolved network selectors by canonical selector.
+type lanNetworks map[string]LANNetwork
+
+// SetNetworkDirectory registers the lookup "network:<name>" selectors are
+// resolved with. Without one only "vlan:<id>" selectors resolve.
+func (m *Manager) SetNetworkDirectory(directory NetworkDirectory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.networkDirectory = directory
+}
+
+// networkSelectorsIn lists the canonical network selectors among values.
+func networkSelectorsIn(values []string) []string {
+	out := make([]string, 0)
+	for _, value := range values {
+		if selector, ok, err := parseNetworkSelector(value); ok && err == nil {
+			out = append(out, selector.String())
+		}
+	}
+	return out
+}
+
+// groupNetworkSelectors lists the network selectors used by the source
+// selectors of groups and the canary proposal.
+func groupNetworkSelectors(groups []DomainGroup, canary *Canary) []string {
+	selectors := make([]string, 0)
+	collect := func(rules []RoutingRule) {
+		for _, rule := range rules {
+			selectors = append(selectors, networkSelectorsIn(rule.SourceInterfaces)...)
+			selectors = append(selectors, networkSelectorsIn(rule.SourceCIDRs)...)
+			selectors = append(selectors, networkSelectorsIn(rule.ExcludedSourceCIDRs)...)
+		}
+	}
+	for _, group := range groups {
+		collect(group.Rules)
+	}
+	if canary != nil {
+		collect(canary.Proposed.Rules)
+	}
+	return dedupeSortedStrings(selectors)
+}
+
+// resolveNetworksLocked resolves network selectors to their bridges and
+// subnets. The network directory is only consulted for name selectors.
+func (m *Manager) resolveNetworksLocked(ctx context.Context, selectors []string) lanNetworks {
+	out := make(lanNetworks, len(selectors))
+	var vlanByName map[string]int
+	var directoryErr error
+	directoryLoaded := false
+	for _, value := range selectors {
+		selector, ok, err := parseNetworkSelector(value)
+		if !ok || err != nil {
+			continue
+		}
+		network := LANNetwork{Selector: selector.String(), Name: selector.name, VLAN: selector.vlan}
+		if selector.name != "" {
+			if !directoryLoaded {
+				directoryLoaded = true
+				vlanByName, directoryErr = m.loadNetworkDirectory(ctx)
+			}
+			vlan, found := lookupNetworkVLAN(vlanByName, selector.name)
+			switch {
+			case directoryErr != nil:
+				network.Error = directoryErr.Error()
+			case !found:
+				network.Error = fmt.Sprintf("unknown network %q", selector.name)
+			}
+			network.VLAN = vlan
+		}
+		if network.Error == "" {
+			lookup := m.networkLookup
+			if lookup == nil {
+				lookup = localVLANNetwork
+			}
+			iface, subnets, err := lookup(network.VLAN)
+			if err != nil {
+				network.Error = err.Error()
+			} else {
+				network.Interface = iface
+				for _, prefix := range subnets {
+					network.Subnets = append(network.Subnets, prefix.String())
+				}
+			}
+		}
+		out[network.Selector] = network
+	}
+	return out
+}
+
+func (m *Manager) loadNetworkDirectory(ctx context.Context) (map[string]int, error) {
+	if m.networkDirectory == nil {
+		return nil, fmt.Errorf("network names need a UniFi controller API key")
+	}
+	return m.networkDirectory(ctx)
+}
+
+func lookupNetworkVLAN(vlanByName map[string]int, name string) (int, bool) {
+	if vlan, ok := vlanByName[name]; ok {
+		return vlan, true
+	}
+	for candidate, vlan := range vlanByName {
+		if strings.EqualFold(candidate, name) {
+			return vlan, true
+		}
+	}
+	return 0, false
+}
+
+// bridgeForVLAN names the bridge UniFi OS creates for a VLAN: br0 for the
+// untagged default network and br<id> for the others.
+func bridgeForVLAN(vlan int) string {
+	if vlan <= 1 {
+		return "br0"
+	}
+	return "br" + strconv.Itoa(vlan)
+}
+
+// localVLANNetwork reads a VLAN's bridge and the IPv4, unique local and
+// global IPv6 subnets configured on it.
+func localVLANNetwork(vlan int) (string, []netip.Prefix, error) {
+	name := bridgeForVLAN(vlan)
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", nil, fmt.Errorf("bridge %s for VLAN %d not found", name, vlan)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", nil, err
+	}
+	prefixes := make([]netip.Prefix, 0, len(addrs))
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		ip = ip.Unmap()
+		bits, _ := ipNet.Mask.Size()
+		if prefix := netip.PrefixFrom(ip, bits); prefix.IsValid() {
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+	return name, sortedPrefixes(prefixes), nil
+}
+
+// expandRuleNetworks replaces network selectors in rule's source selectors
+// with the bridges and subnets they resolved to. ok is false when a source
+// selector list held only networks that resolved to nothing: the rule must
+// then match no traffic instead of dropping the selector and matching all.
+func expandRuleNetworks(rule RoutingRule, networks lanNetworks) (RoutingRule, bool) {
+	var ok bool
+	rule.SourceInterfaces, ok = expandNetworkValues(rule.SourceInterfaces, networks, func(network LANNetwork) []string {
+		if network.Interface == "" {
+			return nil
+		}
+		return []string{network.Interface}
+	})
+	if !ok {
+		return rule, false
+	}
+	subnets := func(network LANNetwork) []string { return network.Subnets }
+	rule.SourceCIDRs, ok = expandNetworkValues(rule.SourceCIDRs, networks, subnets)
+	if !ok {
+		return rule, false
+	}
+	rule.ExcludedSourceCIDRs, _ = expandNetworkValues(rule.ExcludedSourceCIDRs, networks, subnets)
+	return rule, true
+}
+
+func expandNetworkValues(values []string, networks lanNetworks, expand func(LANNetwork) []string) ([]string, bool) {
+	if len(values) == 0 {
+		return values, true
+	}
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		selector, ok, err := parseNetworkSelector(value)
+		if !ok {
+			out = append(out, value)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		out = append(out, expand(networks[selector.String()])...)
+	}
+	out = dedupeSortedStrings(out)
+	return out, len(out) > 0
+}
+
+// ExpandNetworkSelectors returns groups with network source selectors
+// replaced by what the last apply resolved them to, for code matching
+// traffic against rules outside of iptables. Rules whose networks resolved
+// to nothing keep no selectors at all and so never match.
+func (m *Manager) ExpandNetworkSelectors(groups []DomainGroup) []DomainGroup {
+	m.mu.Lock()
+	networks := m.networks
+	m.mu.Unlock()
+	out := make([]DomainGroup, len(groups))
+	for idx, group := range groups {
+		rules := make([]RoutingRule, len(group.Rules))
+		for ruleIndex, rule := range group.Rules {
+			expanded, ok := expandRuleNetworks(rule, networks)
+			if !ok {
+				expanded = RoutingRule{ID: rule.ID, Name: rule.Name, RawSelectors: rule.RawSelectors}
+			}
+			rules[ruleIndex] = expanded
+		}
+		group.Rules = rules
+		out[idx] = group
+	}
+	return out
+}
+
+// LANNetworks resolves every network selector used by the saved groups and,
+// with a network directory, every network it knows, sorted by selector.
+func (m *Manager) LANNetworks(ctx context.Context) ([]LANNetwork, error) {
+	groups, err := m.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	selectors := groupNetworkSelectors(groups, nil)
+	if m.networkDirectory != nil {
+		if vlanByName, err := m.networkDirectory(ctx); err == nil {
+			used := make(map[string]struct{}, len(selectors))
+			for _, selector := range selectors {
+				used[strings.ToLower(selector)] = struct{}{}
+			}
+			for name := range vlanByName {
+				selector := networkSelector{name: name}.String()
+				if _, ok := used[strings.ToLower(selector)]; !ok {
+					selectors = append(selectors, selector)
+				}
+			}
+		}
+	}
+	resolved := m.resolveNetworksLocked(ctx, dedupeSortedStrings(selectors))
+	out := make([]LANNetwork, 0, len(resolved))
+	for _, network := range resolved {
+		out = append(out, network)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Selector < out[j].Selector })
+	return out, nil
+}
+
+// changedNetworks lists selectors whose resolution differs between two
+// snapshots.
+func changedNetworks(previous, current lanNetworks) []string {
+	changed := make([]string, 0)
+	for selector, network := range current {
+		before, ok := previous[selector]
+		if !ok || before.Interface != network.Interface || strings.Join(before.Subnets, ",") != strings.Join(network.Subnets, ",") {
+			changed = append(changed, selector)
+		}
+	}
+	for selector := range previous {
+		if _, ok := current[selector]; !ok {
+			changed = append(changed, selector)
+		}
+	}
+	return dedupeSortedStrings(changed)
+}
@@ -0,0 +1,97 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestNormalizeNetworkSelectors(t *testing.T) {
+	ifaces, err := normalizeInterfaces([]string{"VLAN:20", "network: IoT ", "br0", "vlan:020"})
+	if err != nil {
+		t.Fatalf("normalizeInterfaces failed: %v", err)
+	}
+	if strings.Join(ifaces, ",") != "vlan:20,network:IoT,br0" {
+		t.Fatalf("unexpected interfaces: %#v", ifaces)
+	}
+	cidrs, err := normalizeSourceSelectors([]string{"network:Kids", "10.0.0.7", "vlan:30"}, "source")
+	if err != nil {
+		t.Fatalf("normalizeSourceSelectors failed: %v", err)
+	}
+	if strings.Join(cidrs, ",") != "10.0.0.7/32,network:Kids,vlan:30" {
+		t.Fatalf("unexpected source selectors: %#v", cidrs)
+	}
+	for _, invalid := range []string{"vlan:0", "vlan:4095", "vlan:x", "network:"} {
+		if _, err := normalizeInterfaces([]string{invalid}); !errors.Is(err, ErrGroupValidation) {
+			t.Fatalf("expected %q to fail validation, got %v", invalid, err)
+		}
+	}
+}
+
+func TestApplyResolvesNetworkSelectors(t *testing.T) {
+	ctx := context.Background()
+	ipset := &MockIPSet{}
+	rules := &mockRuleApplier{}
+	manager := newRoutingTestManagerWithDeps(t, ipset, &mockDNSManager{}, rules, &mockVPNLister{
+		profiles: []*vpn.VPNProfile{{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"}},
+	})
+	subnet := netip.MustParsePrefix("10.0.20.0/24")
+	manager.networkLookup = func(vlan int) (string, []netip.Prefix, error) {
+		if vlan != 20 {
+			return "", nil, fmt.Errorf("bridge br%d for VLAN %d not found", vlan, vlan)
+		}
+		return "br20", []netip.Prefix{subnet}, nil
+	}
+	manager.SetNetworkDirectory(func(context.Context) (map[string]int, error) {
+		return map[string]int{"IoT": 20}, nil
+	})
+	if _, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Lab",
+		EgressVPN: "wg-sgp",
+		Rules: []RoutingRule{
+			{Name: "iot", SourceInterfaces: []string{"network:iot"}, Domains: []string{"example.com"}},
+			{Name: "subnet", SourceCIDRs: []string{"vlan:20"}, Domains: []string{"example.org"}},
+			{Name: "missing", SourceInterfaces: []string{"vlan:30"}, Domains: []string{"example.net"}},
+		},
+	}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if len(rules.bindings) != 2 {
+		t.Fatalf("expected the unresolved network rule to install nothing, got %d bindings", len(rules.bindings))
+	}
+	if got := strings.Join(rules.bindings[0].SourceInterfaces, ","); got != "br20" {
+		t.Fatalf("expected network name to resolve to its bridge, got %q", got)
+	}
+	if got := strings.Join(ipset.IPs[RuleSetNames("Lab", 1).SourceV4], ","); got != "10.0.20.0/24" {
+		t.Fatalf("expected VLAN source set to hold the subnet, got %q", got)
+	}
+
+	networks, err := manager.LANNetworks(ctx)
+	if err != nil {
+		t.Fatalf("LANNetworks failed: %v", err)
+	}
+	if len(networks) != 3 || networks[0].Selector != "network:iot" || networks[1].Selector != "vlan:20" ||
+		networks[2].Selector != "vlan:30" || networks[2].Error == "" {
+		t.Fatalf("unexpected networks: %+v", networks)
+	}
+
+	if change, err := manager.RefreshDelegatedPrefixes(ctx); err != nil || change != nil {
+		t.Fatalf("expected no change before the subnet moves, got %+v, %v", change, err)
+	}
+	subnet = netip.MustParsePrefix("10.0.21.0/24")
+	change, err := manager.RefreshDelegatedPrefixes(ctx)
+	if err != nil {
+		t.Fatalf("RefreshDelegatedPrefixes failed: %v", err)
+	}
+	if change == nil || len(change.Networks) != 2 {
+		t.Fatalf("expected both VLAN 20 selectors to change, got %+v", change)
+	}
+	if got := strings.Join(ipset.IPs[RuleSetNames("Lab", 1).SourceV4], ","); got != "10.0.21.0/24" {
+		t.Fatalf("expected VLAN source set to follow the subnet, got %q", got)
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"database/sql"
 	"fmt"
 	"net"
+	"net/netip"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"split-vpn-webui/internal/vpn"
 )
@@ -37,26 +40,53 @@ type Manager struct {
 	dnsmasq   DNSManager
 	rules     RuleApplier
 	vpnLister VPNLister
-	mu        sync.Mutex
+	canary    *Canary
+	// delegated holds the IPv6 prefix delegations the last apply expanded
+	// interface+host-suffix selectors with.
+	delegated    delegatedPrefixes
+	prefixLookup func(iface string) ([]netip.Prefix, error)
+	// networks holds the network selectors the last apply resolved;
+	// networkDirectory and networkLookup resolve names and VLANs.
+	networks         lanNetworks
+	networkDirectory NetworkDirectory
+	networkLookup    func(vlan int) (string, []netip.Prefix, error)
+	// appliedSets is the membership each set was last loaded with, so set
+	// refreshes can apply deltas instead of rebuilding.
+	appliedSets map[string]appliedSet
+	batcher     applyBatcher
+	applyStats  applyStatsRecorder
+	// generation counts applies so readers can cache what they derive from
+	// routing state.
+	generation atomic.Uint64
+	mu         sync.Mutex
 }
 
 // NewManager creates a routing manager with concrete dependencies.
 func NewManager(db *sql.DB, vpnLister VPNLister) (*Manager, error) {
+	return NewManagerWithExecutor(db, vpnLister, nil)
+}
+
+// NewManagerWithExecutor creates a manager whose ipset, iptables, ip and
+// dnsmasq commands run through exec, e.g. the privilege helper. A nil exec
+// runs them directly.
+func NewManagerWithExecutor(db *sql.DB, vpnLister VPNLister, exec Executor) (*Manager, error) {
 	store, err := NewStore(db)
 	if err != nil {
 		return nil, err
 	}
-	dnsmasq, err := NewDnsmasqManager(nil)
+	dnsmasq, err := NewDnsmasqManager(exec)
 	if err != nil {
 		return nil, err
 	}
-	return &Manager{
+	manager := &Manager{
 		store:     store,
-		ipset:     NewIPSetManager(nil),
+		ipset:     NewIPSetManager(exec),
 		dnsmasq:   dnsmasq,
-		rules:     NewRuleManager(nil),
+		rules:     NewRuleManager(exec),
 		vpnLister: vpnLister,
-	}, nil
+	}
+	manager.SetApplyDebounce(defaultApplyDebounce)
+	return manager, nil
 }
 
 // NewManagerWithDeps creates a manager with injected dependencies for tests.
@@ -96,15 +126,23 @@ func (m *Manager) ReplaceResolverSnapshot(ctx context.Context, snapshot map[Reso
 // UpsertResolverSnapshot refreshes resolver cache rows and applies destination set updates.
 func (m *Manager) UpsertResolverSnapshot(ctx context.Context, snapshot map[ResolverSelector]ResolverValues) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	return m.applyResolverSnapshotLocked(ctx, snapshot)
+	err := m.store.UpsertResolverSnapshot(ctx, snapshot)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return m.requestApply(ctx, applyKindSets)
 }
 
 // UpsertPrewarmSnapshot refreshes pre-warm cache rows and applies destination set updates.
 func (m *Manager) UpsertPrewarmSnapshot(ctx context.Context, snapshot map[string]ResolverValues) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	return m.applyPrewarmSnapshotLocked(ctx, snapshot)
+	err := m.store.UpsertPrewarmSnapshot(ctx, snapshot)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return m.requestApply(ctx, applyKindSets)
 }
 
 // ClearResolverCache removes cached resolver rows and reapplies destination sets.
@@ -127,64 +165,115 @@ func (m *Manager) ClearPrewarmCache(ctx context.Context) error {
 	return m.applyCachedDestinationSetsLocked(ctx)
 }
 
-func (m *Manager) GetGroup(ctx context.Context, id int64) (*DomainGroup, error) {
-	return m.store.Get(ctx, id)
-}
-
-func (m *Manager) CreateGroup(ctx context.Context, group DomainGroup) (*DomainGroup, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if err := m.validateEgressVPN(group.EgressVPN); err != nil {
+// Conflicts reports overlapping, duplicate and shadowed rules across the
+// stored groups. A non-nil candidate is validated and checked as if saved:
+// it replaces the stored group with its ID, or is added when the ID is 0.
+func (m *Manager) Conflicts(ctx context.Context, candidate *DomainGroup) ([]Conflict, error) {
+	groups, err := m.store.List(ctx)
+	if err != nil {
 		return nil, err
 	}
-
-	created, err := m.store.Create(ctx, group)
+	if candidate == nil {
+		return DetectConflicts(groups), nil
+	}
+	normalized, err := NormalizeAndValidate(*candidate)
 	if err != nil {
 		return nil, err
 	}
-	if err := m.applyLocked(ctx); err != nil {
-		return nil, err
+	merged := make([]DomainGroup, 0, len(groups)+1)
+	for _, group := range groups {
+		if normalized.ID == 0 || group.ID != normalized.ID {
+			merged = append(merged, group)
+		}
 	}
-	return created, nil
+	return DetectConflicts(append(merged, normalized)), nil
 }
 
-func (m *Manager) UpdateGroup(ctx context.Context, id int64, group DomainGroup) (*DomainGroup, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *Manager) GetGroup(ctx context.Context, id int64) (*DomainGroup, error) {
+	return m.store.Get(ctx, id)
+}
 
-	if err := m.validateEgressVPN(group.EgressVPN); err != nil {
+func (m *Manager) CreateGroup(ctx context.Context, group DomainGroup) (*DomainGroup, error) {
+	var created *DomainGroup
+	err := m.commitGroupEdit(ctx, func() (bool, error) {
+		if err := m.validateGroupRefs(ctx, group); err != nil {
+			return false, err
+		}
+		var err error
+		created, err = m.store.Create(ctx, group)
+		return err == nil, err
+	})
+	if err != nil {
 		return nil, err
 	}
+	return created, nil
+}
 
-	updated, err := m.store.Update(ctx, id, group)
+func (m *Manager) UpdateGroup(ctx context.Context, id int64, group DomainGroup) (*DomainGroup, error) {
+	var updated *DomainGroup
+	err := m.commitGroupEdit(ctx, func() (bool, error) {
+		if err := m.validateGroupRefs(ctx, group); err != nil {
+			return false, err
+		}
+		if err := m.checkManagedGroup(ctx, id, group.Managed); err != nil {
+			return false, err
+		}
+		var err error
+		updated, err = m.store.Update(ctx, id, group)
+		return err == nil, err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if err := m.applyLocked(ctx); err != nil {
-		return nil, err
-	}
 	return updated, nil
 }
 
+// ReorderGroups sets group precedence from ids, highest first, and
+// re-applies the rules.
+func (m *Manager) ReorderGroups(ctx context.Context, ids []int64) error {
+	return m.commitGroupEdit(ctx, func() (bool, error) {
+		err := m.store.Reorder(ctx, ids)
+		return err == nil, err
+	})
+}
+
+// DeleteGroup moves a group to the trash and applies the remaining groups.
 func (m *Manager) DeleteGroup(ctx context.Context, id int64) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.deleteGroup(ctx, id, "")
+}
+
+// deleteGroup trashes a group generated by managed, or an unmanaged group
+// when managed is empty.
+func (m *Manager) deleteGroup(ctx context.Context, id int64, managed string) error {
+	return m.commitGroupEdit(ctx, func() (bool, error) {
+		if err := m.checkManagedGroup(ctx, id, managed); err != nil {
+			return false, err
+		}
+		_, err := m.store.Trash(ctx, id)
+		return err == nil, err
+	})
+}
 
-	if err := m.store.Delete(ctx, id); err != nil {
+// checkManagedGroup rejects edits to a managed group from anything but the
+// feature that generated it.
+func (m *Manager) checkManagedGroup(ctx context.Context, id int64, managed string) error {
+	existing, err := m.store.Get(ctx, id)
+	if err != nil {
 		return err
 	}
-	if err := m.applyLocked(ctx); err != nil {
-		return err
+	if existing.Managed != managed {
+		if existing.Managed == ManagedGuestSafeMode {
+			return fmt.Errorf("%w: %s is managed by guest safe mode", ErrGroupManaged, existing.Name)
+		}
+		return fmt.Errorf("%w: %s", ErrGroupManaged, existing.Name)
 	}
 	return nil
 }
 
-// Apply makes runtime routing state match the persisted groups.
+// Apply makes runtime routing state match the persisted groups. Concurrent
+// calls and pending group edits are merged into one apply.
 func (m *Manager) Apply(ctx context.Context) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	return m.applyLocked(ctx)
+	return m.requestApply(ctx, applyKindFull)
 }
 
 // ReplaceState replaces persisted groups and resolver snapshot, then applies runtime state once.
@@ -204,124 +293,126 @@ func (m *Manager) ReplaceState(
 	if err := m.store.ReplaceAll(ctx, groups, snapshot); err != nil {
 		return err
 	}
+	// An import replaces the published set too; there is nothing left to
+	// publish afterwards.
+	if err := m.republishLocked(ctx); err != nil {
+		return err
+	}
+	// Group ids are reassigned by the import, so a staged canary no longer
+	// refers to the group it was started for.
+	m.canary = nil
 	return m.applyLocked(ctx)
 }
 
+// applyLocked makes runtime state match the runtime groups and records how
+// long it took.
 func (m *Manager) applyLocked(ctx context.Context) error {
-	groups, err := m.store.List(ctx)
+	started := time.Now()
+	err := m.applyRuntimeLocked(ctx)
+	m.applyStats.record(applyKindFull, time.Since(started), err)
+	m.generation.Add(1)
+	return err
+}
+
+// Generation returns a counter that increases after every apply, successful
+// or not. Anything derived from groups, resolver caches or set contents is
+// stale once it changes.
+func (m *Manager) Generation() uint64 {
+	return m.generation.Load()
+}
+
+func (m *Manager) applyRuntimeLocked(ctx context.Context) error {
+	if err := m.store.PurgeExpiredResolverCache(ctx); err != nil {
+		return err
+	}
+	if err := m.store.PurgeExpiredPrewarmCache(ctx); err != nil {
+		return err
+	}
+	plan, err := m.planLocked(ctx)
 	if err != nil {
 		return err
 	}
+	m.canary = plan.canary
 
-	if len(groups) == 0 {
+	if len(plan.groups) == 0 {
 		if err := m.rules.FlushRules(); err != nil {
 			return err
 		}
-		if err := m.cleanupStaleSets(map[string]struct{}{}); err != nil {
+		if err := m.cleanupStaleSets(plan.activeSets); err != nil {
 			return err
 		}
-		content := m.dnsmasq.GenerateDnsmasqConf(groups)
-		if err := m.dnsmasq.WriteDnsmasqConf(content); err != nil {
+		if err := m.dnsmasq.WriteDnsmasqConf(plan.dnsmasqConf); err != nil {
+			return err
+		}
+		if err := m.writeDnsmasqFragments(plan); err != nil {
 			return err
 		}
 		if err := m.dnsmasq.ReloadDnsmasq(); err != nil {
 			return err
 		}
+		m.delegated = plan.delegated
+		m.networks = plan.networks
 		return nil
 	}
 
-	profiles, err := m.vpnLister.List()
-	if err != nil {
+	if err := m.applyDesiredSets(plan.desiredSets); err != nil {
 		return err
 	}
-	vpnByName := make(map[string]*vpn.VPNProfile, len(profiles))
-	for _, profile := range profiles {
-		if profile == nil {
-			continue
-		}
-		vpnByName[profile.Name] = profile
-	}
-
-	if err := m.store.PurgeExpiredResolverCache(ctx); err != nil {
+	if err := m.dnsmasq.WriteDnsmasqConf(plan.dnsmasqConf); err != nil {
 		return err
 	}
-	if err := m.store.PurgeExpiredPrewarmCache(ctx); err != nil {
+	if err := m.writeDnsmasqFragments(plan); err != nil {
 		return err
 	}
-
-	resolved, err := m.store.LoadResolverSnapshot(ctx)
-	if err != nil {
+	if err := m.dnsmasq.ReloadDnsmasq(); err != nil {
 		return err
 	}
-	prewarmed, err := m.store.LoadPrewarmSnapshot(ctx)
-	if err != nil {
+	if err := m.rules.ApplyRules(plan.bindings); err != nil {
 		return err
 	}
-
-	activeSets := make(map[string]struct{})
-	desiredSets := make(map[string]desiredSetDefinition)
-	bindings := make([]RouteBinding, 0)
-	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
-	for _, group := range groups {
-		profile, ok := vpnByName[group.EgressVPN]
-		if !ok {
-			return fmt.Errorf("group %q references missing egress vpn %q", group.Name, group.EgressVPN)
-		}
-		if profile.RouteTable < 200 {
-			return fmt.Errorf("group %q references vpn %q with invalid route table %d", group.Name, profile.Name, profile.RouteTable)
-		}
-		if profile.FWMark < 200 {
-			return fmt.Errorf("group %q references vpn %q with invalid fwmark %d", group.Name, profile.Name, profile.FWMark)
-		}
-		if strings.TrimSpace(profile.InterfaceName) == "" {
-			return fmt.Errorf("group %q references vpn %q with empty interface", group.Name, profile.Name)
-		}
-
-		for ruleIndex, rule := range group.Rules {
-			if !ruleHasSelectors(rule) {
-				// Comment-only or disabled rule: persist for editing, but do not
-				// create runtime bindings.
-				continue
-			}
-			binding, err := m.buildBinding(group, rule, ruleIndex, profile, resolved, prewarmed, activeSets, desiredSets)
-			if err != nil {
-				return err
-			}
-			bindings = append(bindings, binding)
-		}
-	}
-	if err := m.applyDesiredSets(desiredSets); err != nil {
+	if err := m.cleanupStaleSets(plan.activeSets); err != nil {
 		return err
 	}
+	m.delegated = plan.delegated
+	m.networks = plan.networks
+	return nil
+}
 
-	content := m.dnsmasq.GenerateDnsmasqConf(groups)
-	if err := m.dnsmasq.WriteDnsmasqConf(content); err != nil {
-		return err
+func groupProfile(group DomainGroup, vpnByName map[string]*vpn.VPNProfile) (*vpn.VPNProfile, error) {
+	profile, ok := vpnByName[group.EgressVPN]
+	if !ok {
+		return nil, fmt.Errorf("group %q references missing egress vpn %q", group.Name, group.EgressVPN)
 	}
-	if err := m.dnsmasq.ReloadDnsmasq(); err != nil {
-		return err
+	if profile.RouteTable < 200 {
+		return nil, fmt.Errorf("group %q references vpn %q with invalid route table %d", group.Name, profile.Name, profile.RouteTable)
 	}
-	if err := m.rules.ApplyRules(bindings); err != nil {
-		return err
+	if profile.FWMark < 200 {
+		return nil, fmt.Errorf("group %q references vpn %q with invalid fwmark %d", group.Name, profile.Name, profile.FWMark)
 	}
-	if err := m.cleanupStaleSets(activeSets); err != nil {
-		return err
+	if strings.TrimSpace(profile.InterfaceName) == "" {
+		return nil, fmt.Errorf("group %q references vpn %q with empty interface", group.Name, profile.Name)
 	}
-	return nil
+	return profile, nil
 }
 
+// buildBinding derives runtime state for one rule. pair names the ipsets the
+// binding uses; pre-warm entries are always looked up under the group's own
+// set names.
 func (m *Manager) buildBinding(
 	group DomainGroup,
 	rule RoutingRule,
 	ruleIndex int,
+	pair RuleSetPair,
 	profile *vpn.VPNProfile,
 	resolved map[ResolverSelector]ResolverValues,
 	prewarmed map[string]ResolverValues,
 	activeSets map[string]struct{},
 	desiredSets map[string]desiredSetDefinition,
+	delegated delegatedPrefixes,
+	devices deviceGroupIndex,
 ) (RouteBinding, error) {
-	pair := RuleSetNames(group.Name, ruleIndex)
 	needsSource := len(rule.SourceCIDRs) > 0
+	needsSourceDevices := len(rule.SourceDeviceGroups) > 0
 	needsExcludedSource := len(rule.ExcludedSourceCIDRs) > 0
 	needsDestination := len(rule.DestinationCIDRs) > 0 ||
 		len(rule.DestinationASNs) > 0 ||
@@ -331,19 +422,31 @@ func (m *Manager) buildBinding(
 		len(rule.ExcludedDestinationASNs) > 0
 
 	if needsSource {
-		sourceV4, sourceV6 := splitCIDRsByFamily(rule.SourceCIDRs)
+		sourceV4, sourceV6 := splitCIDRsByFamily(expandDelegatedSelectors(rule.SourceCIDRs, delegated))
 		queueDesiredSet(desiredSets, activeSets, pair.SourceV4, "inet", sourceV4)
 		queueDesiredSet(desiredSets, activeSets, pair.SourceV6, "inet6", sourceV6)
 	}
 	if needsExcludedSource {
-		sourceV4, sourceV6 := splitCIDRsByFamily(rule.ExcludedSourceCIDRs)
+		sourceV4, sourceV6 := splitCIDRsByFamily(expandDelegatedSelectors(rule.ExcludedSourceCIDRs, delegated))
 		queueDesiredSet(desiredSets, activeSets, pair.ExcludedSourceV4, "inet", sourceV4)
 		queueDesiredSet(desiredSets, activeSets, pair.ExcludedSourceV6, "inet6", sourceV6)
 	}
+	var deviceMACs []string
+	needsSourceDeviceSet := false
+	if needsSourceDevices {
+		var deviceCIDRs []string
+		deviceMACs, deviceCIDRs = devices.members(rule.SourceDeviceGroups)
+		needsSourceDeviceSet = len(deviceCIDRs) > 0
+		if needsSourceDeviceSet {
+			deviceV4, deviceV6 := splitCIDRsByFamily(deviceCIDRs)
+			queueDesiredSet(desiredSets, activeSets, pair.SourceDeviceV4, "inet", deviceV4)
+			queueDesiredSet(desiredSets, activeSets, pair.SourceDeviceV6, "inet6", deviceV6)
+		}
+	}
 
 	if needsDestination {
 		destEntries := mergeResolvedDestinations(rule, resolved)
-		destEntries = append(destEntries, mergePrewarmedDestinations(pair, prewarmed)...)
+		destEntries = append(destEntries, mergePrewarmedDestinations(RuleSetNames(group.Name, ruleIndex), prewarmed)...)
 		destEntries = dedupeSortedStrings(destEntries)
 		destV4, destV6 := splitCIDRsByFamily(destEntries)
 		queueDesiredSet(desiredSets, activeSets, pair.DestinationV4, "inet", destV4)
@@ -357,6 +460,11 @@ func (m *Manager) buildBinding(
 		queueDesiredSet(desiredSets, activeSets, pair.ExcludedDestinationV6, "inet6", destV6)
 	}
 
+	var dnsV4, dnsV6 []string
+	if group.DNSRedirect == DNSRedirectVPN {
+		dnsV4, dnsV6 = vpnDNSServers(profile)
+	}
+
 	return RouteBinding{
 		GroupName:                group.Name,
 		RuleIndex:                ruleIndex,
@@ -367,6 +475,9 @@ func (m *Manager) buildBinding(
 		ExcludedSourceSetV4:      pair.ExcludedSourceV4,
 		ExcludedSourceSetV6:      pair.ExcludedSourceV6,
 		SourceMACs:               append([]string(nil), rule.SourceMACs...),
+		SourceDeviceMACs:         deviceMACs,
+		SourceDeviceSetV4:        pair.SourceDeviceV4,
+		SourceDeviceSetV6:        pair.SourceDeviceV6,
 		DestinationSetV4:         pair.DestinationV4,
 		DestinationSetV6:         pair.DestinationV6,
 		ExcludedDestinationSetV4: pair.ExcludedDestinationV4,
@@ -375,6 +486,12 @@ func (m *Manager) buildBinding(
 		HasExcludedSource:        needsExcludedSource,
 		HasDestination:           needsDestination,
 		HasExcludedDestination:   needsExcludedDestination,
+		InvertDestination:        needsDestination && group.InvertDestinations,
+		FullTunnel:               group.FullTunnel,
+		KillSwitch:               group.KillSwitch,
+		Priority:                 group.Priority,
+		HasSourceDevices:         needsSourceDevices,
+		HasSourceDeviceSet:       needsSourceDeviceSet,
 		DestinationPorts:         append([]PortRange(nil), rule.DestinationPorts...),
 		ExcludedDestinationPorts: append([]PortRange(nil), rule.ExcludedDestinationPorts...),
 		ExcludeMulticast:         RuleExcludeMulticastEnabled(rule),
@@ -382,8 +499,16 @@ func (m *Manager) buildBinding(
 		RouteTable:               profile.RouteTable,
 		Interface:                profile.InterfaceName,
 		EgressVPN:                group.EgressVPN,
+		DNSRedirect:              group.DNSRedirect,
+		DNSServersV4:             dnsV4,
+		DNSServersV6:             dnsV6,
 		MSSClampV4:               profile.MSSClampV4,
 		MSSClampV6:               profile.MSSClampV6,
+		IPv6Policy:               profile.IPv6Policy,
+		IPv6Prefix:               profile.IPv6Prefix,
+		UploadLimitKbit:          rule.UploadLimitKbit,
+		DownloadLimitKbit:        rule.DownloadLimitKbit,
+		MonitorOnly:              rule.MonitorOnly,
 	}, nil
 }
 
@@ -399,6 +524,7 @@ func (m *Manager) cleanupStaleSets(active map[string]struct{}) error {
 		if err := m.ipset.DestroySet(setName); err != nil {
 			return err
 		}
+		delete(m.appliedSets, setName)
 	}
 	return nil
 }
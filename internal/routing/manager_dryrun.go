@@ -0,0 +1,281 @@
+package routing
+
+import (
+	"context"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// ApplyDryRun is the outcome of planning an apply without running it: the
+// bindings, ipsets, iptables/ip rules and dnsmasq config an apply would
+// produce, diffed against what is currently installed.
+type ApplyDryRun struct {
+	Bindings []BindingPlan `json:"bindings"`
+	Sets     []SetDiff     `json:"sets"`
+	Rules    LineDiff      `json:"rules"`
+	Dnsmasq  LineDiff      `json:"dnsmasq"`
+	// Warnings lists live state that could not be read; the matching diff
+	// treats it as empty.
+	Warnings []string `json:"warnings,omitempty"`
+	// Conflicts lists overlapping, duplicate and shadowed rules across the
+	// planned groups.
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// BindingPlan summarizes one planned route binding.
+type BindingPlan struct {
+	Group      string   `json:"group"`
+	RuleIndex  int      `json:"ruleIndex"`
+	RuleName   string   `json:"ruleName,omitempty"`
+	EgressVPN  string   `json:"egressVpn"`
+	Interface  string   `json:"interface"`
+	Mark       uint32   `json:"mark"`
+	RouteTable int      `json:"routeTable"`
+	Sets       []string `json:"sets"`
+	Canary     bool     `json:"canary,omitempty"`
+	FullTunnel bool     `json:"fullTunnel,omitempty"`
+}
+
+// SetDiff describes how one ipset would change.
+type SetDiff struct {
+	Name    string   `json:"name"`
+	Family  string   `json:"family,omitempty"`
+	Action  string   `json:"action"`
+	Entries int      `json:"entries"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// LineDiff compares planned lines with the lines currently installed.
+type LineDiff struct {
+	Changed bool     `json:"changed"`
+	Planned []string `json:"planned"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// DnsmasqReader is an optional DNSManager extension that reads the installed
+// config for apply dry-runs.
+type DnsmasqReader interface {
+	ReadDnsmasqConf() (string, error)
+}
+
+// SetDiff actions.
+const (
+	SetActionCreate    = "create"
+	SetActionUpdate    = "update"
+	SetActionDestroy   = "destroy"
+	SetActionUnchanged = "unchanged"
+)
+
+// DryRunApply computes what Apply would install and diffs it against live
+// state. Nothing is written: expired cache rows are skipped rather than
+// purged, and no ipset, iptables, ip rule or dnsmasq command is run. With
+// staging on it previews the saved edits, i.e. what a publish would install.
+func (m *Manager) DryRunApply(ctx context.Context) (*ApplyDryRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups, err := m.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := m.planGroupsLocked(ctx, groups)
+	if err != nil {
+		return nil, err
+	}
+	result := &ApplyDryRun{
+		Bindings:  make([]BindingPlan, 0, len(plan.bindings)),
+		Conflicts: DetectConflicts(plan.groups),
+	}
+	for _, binding := range plan.bindings {
+		result.Bindings = append(result.Bindings, bindingPlan(binding))
+	}
+
+	sets, err := m.diffSets(plan.desiredSets, result)
+	if err != nil {
+		return nil, err
+	}
+	result.Sets = sets
+
+	planned := make([]string, 0)
+	live := make([]string, 0)
+	if planner, ok := m.rules.(RulePlanner); ok {
+		if len(plan.groups) > 0 {
+			if planned, err = planner.PlanRules(plan.bindings); err != nil {
+				return nil, err
+			}
+		}
+		if live, err = planner.LiveRules(); err != nil {
+			result.Warnings = append(result.Warnings, "live rules: "+err.Error())
+			live = nil
+		}
+	} else {
+		result.Warnings = append(result.Warnings, "rule planning unavailable")
+	}
+	result.Rules = diffLines(planned, live)
+
+	current := ""
+	if reader, ok := m.dnsmasq.(DnsmasqReader); ok {
+		if current, err = reader.ReadDnsmasqConf(); err != nil {
+			result.Warnings = append(result.Warnings, "live dnsmasq config: "+err.Error())
+		}
+	} else {
+		result.Warnings = append(result.Warnings, "dnsmasq config unavailable")
+	}
+	result.Dnsmasq = diffLines(configLines(plan.dnsmasqConf), configLines(current))
+	return result, nil
+}
+
+func (m *Manager) diffSets(desiredSets map[string]desiredSetDefinition, result *ApplyDryRun) ([]SetDiff, error) {
+	liveNames, err := m.ipset.ListSets(setPrefix)
+	if err != nil {
+		result.Warnings = append(result.Warnings, "live ipsets: "+err.Error())
+		liveNames = nil
+	}
+	live := make(map[string]struct{}, len(liveNames))
+	for _, name := range liveNames {
+		live[name] = struct{}{}
+	}
+	reader, canRead := m.ipset.(IPSetReader)
+	if !canRead && len(liveNames) > 0 {
+		result.Warnings = append(result.Warnings, "ipset members unavailable")
+	}
+	members := func(name string) []string {
+		if !canRead {
+			return nil
+		}
+		values, err := reader.ListMembers(name)
+		if err != nil {
+			result.Warnings = append(result.Warnings, "ipset "+name+": "+err.Error())
+			return nil
+		}
+		return canonicalSetEntries(values)
+	}
+
+	names := make([]string, 0, len(desiredSets)+len(liveNames))
+	for name := range desiredSets {
+		names = append(names, name)
+	}
+	for _, name := range liveNames {
+		if _, desired := desiredSets[name]; !desired {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diffs := make([]SetDiff, 0, len(names))
+	for _, name := range names {
+		def, desired := desiredSets[name]
+		if !desired {
+			diffs = append(diffs, SetDiff{Name: name, Action: SetActionDestroy, Removed: members(name)})
+			continue
+		}
+		family, entries, err := desiredSetEntries(name, def)
+		if err != nil {
+			return nil, err
+		}
+		entries = canonicalSetEntries(entries)
+		diff := SetDiff{Name: name, Family: family, Entries: len(entries)}
+		if _, exists := live[name]; !exists {
+			diff.Action = SetActionCreate
+			diff.Added = entries
+		} else {
+			lines := diffLines(entries, members(name))
+			diff.Added, diff.Removed = lines.Added, lines.Removed
+			diff.Action = SetActionUnchanged
+			if lines.Changed {
+				diff.Action = SetActionUpdate
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+func bindingPlan(binding RouteBinding) BindingPlan {
+	sets := make([]string, 0, 10)
+	for _, set := range []struct {
+		enabled bool
+		v4, v6  string
+	}{
+		{binding.HasSource, binding.SourceSetV4, binding.SourceSetV6},
+		{binding.HasExcludedSource, binding.ExcludedSourceSetV4, binding.ExcludedSourceSetV6},
+		{binding.HasSourceDeviceSet, binding.SourceDeviceSetV4, binding.SourceDeviceSetV6},
+		{binding.HasDestination, binding.DestinationSetV4, binding.DestinationSetV6},
+		{binding.HasExcludedDestination, binding.ExcludedDestinationSetV4, binding.ExcludedDestinationSetV6},
+	} {
+		if set.enabled {
+			sets = append(sets, set.v4, set.v6)
+		}
+	}
+	return BindingPlan{
+		Group:      binding.GroupName,
+		RuleIndex:  binding.RuleIndex,
+		RuleName:   binding.RuleName,
+		EgressVPN:  binding.EgressVPN,
+		Interface:  binding.Interface,
+		Mark:       binding.Mark,
+		RouteTable: binding.RouteTable,
+		Sets:       sets,
+		Canary:     binding.Canary,
+		FullTunnel: binding.FullTunnel,
+	}
+}
+
+// canonicalSetEntries prints host prefixes as bare addresses, the way
+// `ipset save` lists hash:net members.
+func canonicalSetEntries(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		trimmed := strings.TrimSpace(value)
+		if prefix, err := netip.ParsePrefix(trimmed); err == nil {
+			prefix = prefix.Masked()
+			if prefix.IsSingleIP() {
+				trimmed = prefix.Addr().String()
+			} else {
+				trimmed = prefix.String()
+			}
+		} else if addr, err := netip.ParseAddr(trimmed); err == nil {
+			trimmed = addr.String()
+		}
+		out = append(out, trimmed)
+	}
+	return dedupeSortedStrings(out)
+}
+
+func configLines(content string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(content, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			lines = append(lines, trimmed)
+		}
+	}
+	return lines
+}
+
+// diffLines compares two line sets; order and duplicates are ignored.
+func diffLines(planned, live []string) LineDiff {
+	planned = dedupeSortedStrings(planned)
+	live = dedupeSortedStrings(live)
+	liveSet := make(map[string]struct{}, len(live))
+	for _, line := range live {
+		liveSet[line] = struct{}{}
+	}
+	plannedSet := make(map[string]struct{}, len(planned))
+	diff := LineDiff{Planned: planned}
+	for _, line := range planned {
+		plannedSet[line] = struct{}{}
+		if _, ok := liveSet[line]; !ok {
+			diff.Added = append(diff.Added, line)
+		}
+	}
+	for _, line := range live {
+		if _, ok := plannedSet[line]; !ok {
+			diff.Removed = append(diff.Removed, line)
+		}
+	}
+	diff.Changed = len(diff.Added) > 0 || len(diff.Removed) > 0
+	return diff
+}
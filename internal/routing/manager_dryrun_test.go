@@ -0,0 +1,110 @@
+package routing
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestDryRunApplyDiffsWithoutMutating(t *testing.T) {
+	ctx := context.Background()
+	exec := &MockExec{}
+	confPath := filepath.Join(t.TempDir(), "split-vpn-webui.conf")
+	manager := newRoutingTestManagerWithDeps(t,
+		NewIPSetManager(exec),
+		NewDnsmasqManagerWithPath(confPath, exec),
+		NewRuleManager(exec),
+		&mockVPNLister{profiles: []*vpn.VPNProfile{{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"}}},
+	)
+	if _, err := manager.store.Create(ctx, DomainGroup{
+		Name:      "Media",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Name: "cdn", DestinationCIDRs: []string{"10.0.0.1/32", "10.1.0.0/16"}}},
+	}); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+
+	pair := RuleSetNames("Media", 0)
+	exec.Outputs = map[string][]byte{
+		"ipset list -name":                 []byte(pair.DestinationV4 + "\nsvpn_old_r1d4\n"),
+		"ipset save " + pair.DestinationV4: []byte("create x hash:net\nadd " + pair.DestinationV4 + " 10.0.0.1 timeout 600\nadd " + pair.DestinationV4 + " 10.9.0.0/16 timeout 600\n"),
+		"ipset save svpn_old_r1d4":         []byte("add svpn_old_r1d4 192.0.2.1 timeout 600\n"),
+		"iptables -t mangle -S SVPN_MARK":  []byte("-N SVPN_MARK\n-A SVPN_MARK -j SVPN_MARK_B\n"),
+		"iptables -t mangle -S": []byte("-A PREROUTING -j SVPN_MARK\n" +
+			"-A PREROUTING -i br9 -j MARK --set-xmark 0x1/0xffffffff\n" +
+			"-A PREROUTING -m comment --comment \"svpn:Old:1\" -j MARK --set-xmark 0x170/0xffffffff\n" +
+			"-A SVPN_MARK_B -m comment --comment \"svpn:Media:1\" -j SVPNB_001_4\n" +
+			"-A SVPNB_001_4 -d 224.0.0.0/4 -m comment --comment \"svpn:Media:1\" -m set --match-set " + pair.DestinationV4 + " dst -j RETURN\n" +
+			"-A SVPNB_001_4 -m comment --comment \"svpn:Media:1\" -m set --match-set " + pair.DestinationV4 + " dst -j MARK --set-xmark 0x169/0xffffffff\n" +
+			"-A SVPNB_001_4 -m comment --comment \"svpn:Media:1\" -m set --match-set svpn_old_r1d4 dst -j MARK --set-xmark 0x169/0xffffffff\n"),
+		"iptables -t nat -S":     []byte("-A SVPN_NAT_B -o wg-sgp -m mark --mark 0x169 -m comment --comment \"svpn:Media:1\" -j MASQUERADE\n"),
+		"ip6tables -t mangle -S": []byte(""),
+		"ip6tables -t nat -S":    []byte(""),
+		"ip rule show":           []byte("100:\tfrom all fwmark 0x169 lookup 201\n"),
+		"ip -6 rule show":        []byte(""),
+	}
+
+	result, err := manager.DryRunApply(ctx)
+	if err != nil {
+		t.Fatalf("DryRunApply failed: %v", err)
+	}
+	if len(exec.RunCalls) != 0 {
+		t.Fatalf("expected dry run to run no mutating commands, got %#v", exec.RunCalls)
+	}
+	if len(result.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", result.Warnings)
+	}
+	if len(result.Bindings) != 1 || result.Bindings[0].Mark != 0x169 || result.Bindings[0].Group != "Media" {
+		t.Fatalf("unexpected bindings: %+v", result.Bindings)
+	}
+
+	actions := map[string]SetDiff{}
+	for _, diff := range result.Sets {
+		actions[diff.Name] = diff
+	}
+	dest := actions[pair.DestinationV4]
+	if dest.Action != SetActionUpdate || strings.Join(dest.Added, ",") != "10.1.0.0/16" || strings.Join(dest.Removed, ",") != "10.9.0.0/16" {
+		t.Fatalf("unexpected destination set diff: %+v", dest)
+	}
+	if actions[pair.DestinationV6].Action != SetActionCreate {
+		t.Fatalf("expected v6 destination set to be created: %+v", actions[pair.DestinationV6])
+	}
+	if old := actions["svpn_old_r1d4"]; old.Action != SetActionDestroy || strings.Join(old.Removed, ",") != "192.0.2.1" {
+		t.Fatalf("unexpected stale set diff: %+v", old)
+	}
+
+	staleRule := "iptables -t mangle -A SVPNA_001_4 -m comment --comment svpn:Media:1 -m set --match-set svpn_old_r1d4 dst -j MARK --set-xmark 0x169/0xffffffff"
+	if !containsString(result.Rules.Removed, staleRule) {
+		t.Fatalf("expected stale mark rule to be removed, got %#v", result.Rules.Removed)
+	}
+	orphan := "iptables -t mangle -A PREROUTING -m comment --comment svpn:Old:1 -j MARK --set-xmark 0x170/0xffffffff"
+	if !containsString(result.Rules.Removed, orphan) {
+		t.Fatalf("expected orphaned commented rule to be reported, got %#v", result.Rules.Removed)
+	}
+	for _, rule := range result.Rules.Removed {
+		if strings.Contains(rule, "-i br9") {
+			t.Fatalf("uncommented foreign rule reported as drift: %q", rule)
+		}
+	}
+	for _, rule := range result.Rules.Added {
+		if strings.HasPrefix(rule, "iptables ") || strings.HasPrefix(rule, "ip rule ") {
+			t.Fatalf("expected live ipv4 rules to match the plan, got added %#v", result.Rules.Added)
+		}
+	}
+	if !result.Dnsmasq.Changed && len(result.Dnsmasq.Planned) > 0 {
+		t.Fatalf("expected dnsmasq diff against missing config")
+	}
+}
+
+func TestCanonicalIPTablesRuleMatchesListedForm(t *testing.T) {
+	planned := canonicalIPTablesRule("iptables", "mangle", strings.Fields(
+		"-A SVPNA_001_4 -m set --match-set svpn_a_r1d4 dst -i br0 -m mac --mac-source aa:bb:cc:dd:ee:ff -p tcp --dport 443 -j MARK --set-mark 0x169"))
+	listed := canonicalIPTablesRule("iptables", "mangle", strings.Fields(
+		"-A SVPNA_001_4 -i br0 -p tcp -m set --match-set svpn_a_r1d4 dst -m mac --mac-source AA:BB:CC:DD:EE:FF -m tcp --dport 443 -j MARK --set-xmark 0x169/0xffffffff"))
+	if planned != listed {
+		t.Fatalf("canonical forms differ:\n%s\n%s", planned, listed)
+	}
+}
@@ -0,0 +1,191 @@
+package routing
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+// applyPlan is the runtime state an apply would converge to.
+type applyPlan struct {
+	groups      []DomainGroup
+	canary      *Canary
+	bindings    []RouteBinding
+	desiredSets map[string]desiredSetDefinition
+	activeSets  map[string]struct{}
+	delegated   delegatedPrefixes
+	networks    lanNetworks
+	dnsmasqConf string
+	// dnsmasqFragments holds isolated groups' conf fragments by file name;
+	// nil when the DNS manager does not support fragments.
+	dnsmasqFragments map[string]string
+}
+
+// planLocked derives bindings, ipset contents and dnsmasq config from the
+// runtime groups and caches without touching system state. With staging on,
+// the runtime groups are the published set rather than the saved edits.
+func (m *Manager) planLocked(ctx context.Context) (*applyPlan, error) {
+	groups, err := m.runtimeGroupsLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.planGroupsLocked(ctx, groups)
+}
+
+// planGroupsLocked plans runtime state for the given groups. Expired rules
+// install nothing.
+func (m *Manager) planGroupsLocked(ctx context.Context, groups []DomainGroup) (*applyPlan, error) {
+	groups = withoutExpiredRules(groups, time.Now())
+	plan := &applyPlan{
+		groups:      groups,
+		canary:      m.canaryForGroups(groups),
+		bindings:    make([]RouteBinding, 0),
+		desiredSets: make(map[string]desiredSetDefinition),
+		activeSets:  make(map[string]struct{}),
+	}
+	if len(groups) == 0 {
+		plan.dnsmasqConf = m.dnsmasq.GenerateDnsmasqConf(groups)
+		plan.dnsmasqFragments = m.generateDnsmasqFragments(groups)
+		return plan, nil
+	}
+
+	profiles, err := m.vpnLister.List()
+	if err != nil {
+		return nil, err
+	}
+	vpnByName := make(map[string]*vpn.VPNProfile, len(profiles))
+	for _, profile := range profiles {
+		if profile == nil {
+			continue
+		}
+		vpnByName[profile.Name] = profile
+	}
+
+	resolved, err := m.store.LoadResolverSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prewarmed, err := m.store.LoadPrewarmSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceGroups, err := m.store.ListDeviceGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	devices := indexDeviceGroups(deviceGroups)
+
+	canary := plan.canary
+	plan.delegated = m.lookupDelegatedPrefixes(groupDelegatedInterfaces(groups, canary))
+	plan.networks = m.resolveNetworksLocked(ctx, groupNetworkSelectors(groups, canary))
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	for _, group := range groups {
+		profile, err := groupProfile(group, vpnByName)
+		if err != nil {
+			return nil, err
+		}
+		if canary != nil && group.ID == canary.GroupID {
+			group = excludeCanaryDevice(group, canary.Device)
+		}
+
+		for ruleIndex, rule := range group.Rules {
+			if !ruleHasSelectors(rule) {
+				// Comment-only or disabled rule: persist for editing, but do not
+				// create runtime bindings.
+				continue
+			}
+			rule, ok := expandRuleNetworks(rule, plan.networks)
+			if !ok {
+				// Every network the rule selects sources by is gone.
+				continue
+			}
+			pair := RuleSetNames(group.Name, ruleIndex)
+			binding, err := m.buildBinding(group, rule, ruleIndex, pair, profile, resolved, prewarmed, plan.activeSets, plan.desiredSets, plan.delegated, devices)
+			if err != nil {
+				return nil, err
+			}
+			plan.bindings = append(plan.bindings, binding)
+		}
+		applyGroupIPSetTimeout(group, plan.desiredSets)
+	}
+	canaryBindings, err := m.buildCanaryBindings(canary, vpnByName, resolved, prewarmed, plan.activeSets, plan.desiredSets, plan.delegated, plan.networks, devices)
+	if err != nil {
+		return nil, err
+	}
+	plan.bindings = append(plan.bindings, canaryBindings...)
+	if uplinks := uplinkInterfaces(profiles); len(uplinks) > 0 {
+		for i := range plan.bindings {
+			plan.bindings[i].ExcludedInputInterfaces = uplinks
+		}
+	}
+	plan.dnsmasqConf = m.dnsmasq.GenerateDnsmasqConf(groups) + canaryDnsmasqLines(canary, groups, plan.delegated, plan.networks)
+	plan.dnsmasqFragments = m.generateDnsmasqFragments(groups)
+	return plan, nil
+}
+
+func (m *Manager) generateDnsmasqFragments(groups []DomainGroup) map[string]string {
+	fragmenter, ok := m.dnsmasq.(DnsmasqFragmentManager)
+	if !ok {
+		return nil
+	}
+	return fragmenter.GenerateDnsmasqFragments(groups)
+}
+
+// writeDnsmasqFragments writes the plan's fragments when the DNS manager
+// supports them.
+func (m *Manager) writeDnsmasqFragments(plan *applyPlan) error {
+	fragmenter, ok := m.dnsmasq.(DnsmasqFragmentManager)
+	if !ok {
+		return nil
+	}
+	return fragmenter.WriteDnsmasqFragments(plan.dnsmasqFragments)
+}
+
+// applyGroupIPSetTimeout carries the group's timeout override to its
+// destination sets.
+func applyGroupIPSetTimeout(group DomainGroup, desiredSets map[string]desiredSetDefinition) {
+	if group.IPSetTimeoutSeconds <= 0 {
+		return
+	}
+	for ruleIndex := range group.Rules {
+		pair := RuleSetNames(group.Name, ruleIndex)
+		for _, name := range []string{pair.DestinationV4, pair.DestinationV6} {
+			if def, ok := desiredSets[name]; ok {
+				def.TimeoutSeconds = group.IPSetTimeoutSeconds
+				desiredSets[name] = def
+			}
+		}
+	}
+}
+
+// uplinkInterfaces returns the interfaces of VPNs that other VPNs use as
+// their uplink.
+func uplinkInterfaces(profiles []*vpn.VPNProfile) []string {
+	byName := make(map[string]string, len(profiles))
+	for _, profile := range profiles {
+		if profile != nil {
+			byName[profile.Name] = profile.InterfaceName
+		}
+	}
+	seen := map[string]struct{}{}
+	ifaces := make([]string, 0)
+	for _, profile := range profiles {
+		if profile == nil || profile.UplinkVPN == "" {
+			continue
+		}
+		iface := byName[profile.UplinkVPN]
+		if iface == "" {
+			continue
+		}
+		if _, ok := seen[iface]; ok {
+			continue
+		}
+		seen[iface] = struct{}{}
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+	return ifaces
+}
@@ -0,0 +1,155 @@
+package routing
+
+import (
+	"time"
+)
+
+// appliedSet is the membership the manager last loaded into a set.
+type appliedSet struct {
+	family  string
+	timeout int
+	entries []string
+	// renewedAt is when every entry was last (re)added, which restarts
+	// their timeouts.
+	renewedAt time.Time
+}
+
+// IPSetUpdateStats counts how set refreshes were carried out. Rebuilt sets
+// were reloaded and swapped in, patched sets only had entries added or
+// removed, and unchanged sets were left alone.
+type IPSetUpdateStats struct {
+	Rebuilt        int64 `json:"rebuilt"`
+	Patched        int64 `json:"patched"`
+	Unchanged      int64 `json:"unchanged"`
+	EntriesAdded   int64 `json:"entriesAdded"`
+	EntriesRemoved int64 `json:"entriesRemoved"`
+}
+
+func (r *applyStatsRecorder) recordSetUpdate(update IPSetUpdateStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.IPSets.Rebuilt += update.Rebuilt
+	r.stats.IPSets.Patched += update.Patched
+	r.stats.IPSets.Unchanged += update.Unchanged
+	r.stats.IPSets.EntriesAdded += update.EntriesAdded
+	r.stats.IPSets.EntriesRemoved += update.EntriesRemoved
+}
+
+// applySetDelta brings a set from its previously applied membership to
+// entries without rebuilding it. It reports false when the set has to be
+// rebuilt instead: the operator cannot delete entries, the set was never
+// loaded by this process or no longer exists, its family or timeout
+// changed, or its entries are due for a timeout renewal. Entries are added
+// before stale ones are removed, so a prefix that gets re-aggregated stays
+// covered throughout. Entries dnsmasq added on its own are left in place.
+func (m *Manager) applySetDelta(setName, family string, entries []string, timeoutSeconds int, live map[string]struct{}) (IPSetUpdateStats, bool) {
+	deleter, ok := m.ipset.(IPSetDeleter)
+	if !ok || live == nil {
+		return IPSetUpdateStats{}, false
+	}
+	previous, ok := m.appliedSets[setName]
+	if !ok || previous.family != family || previous.timeout != timeoutSeconds {
+		return IPSetUpdateStats{}, false
+	}
+	if _, exists := live[setName]; !exists {
+		return IPSetUpdateStats{}, false
+	}
+	if time.Since(previous.renewedAt) >= time.Duration(timeoutSeconds)*time.Second/2 {
+		return IPSetUpdateStats{}, false
+	}
+
+	added, removed := diffSetEntries(previous.entries, entries)
+	if len(added) == 0 && len(removed) == 0 {
+		return IPSetUpdateStats{Unchanged: 1}, true
+	}
+	if len(added) > 0 {
+		if err := m.addSetEntries(setName, added, timeoutSeconds); err != nil {
+			return IPSetUpdateStats{}, false
+		}
+	}
+	if len(removed) > 0 {
+		if err := deleter.DelIPs(setName, removed); err != nil {
+			return IPSetUpdateStats{}, false
+		}
+	}
+	m.appliedSets[setName] = appliedSet{
+		family:    family,
+		timeout:   timeoutSeconds,
+		entries:   entries,
+		renewedAt: previous.renewedAt,
+	}
+	return IPSetUpdateStats{
+		Patched:        1,
+		EntriesAdded:   int64(len(added)),
+		EntriesRemoved: int64(len(removed)),
+	}, true
+}
+
+// rebuildSet loads entries into a set through a staged swap and remembers
+// them as the set's applied membership.
+func (m *Manager) rebuildSet(setName, family string, entries []string, timeoutSeconds int) error {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultIPSetTimeoutSeconds
+	}
+	if m.appliedSets == nil {
+		m.appliedSets = make(map[string]appliedSet)
+	}
+	if err := m.applySetAtomically(setName, family, entries, timeoutSeconds); err != nil {
+		delete(m.appliedSets, setName)
+		return err
+	}
+	m.appliedSets[setName] = appliedSet{family: family, timeout: timeoutSeconds, entries: entries, renewedAt: time.Now()}
+	return nil
+}
+
+func (m *Manager) addSetEntries(setName string, entries []string, timeoutSeconds int) error {
+	if loader, ok := m.ipset.(IPSetBatchLoader); ok {
+		return loader.AddIPs(setName, entries, timeoutSeconds)
+	}
+	for _, entry := range entries {
+		if err := m.ipset.AddIP(setName, entry, timeoutSeconds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// liveManagedSets lists the app-managed sets that exist, or nil when there
+// is nothing to patch or the list cannot be read.
+func (m *Manager) liveManagedSets() map[string]struct{} {
+	if len(m.appliedSets) == 0 {
+		return nil
+	}
+	if _, ok := m.ipset.(IPSetDeleter); !ok {
+		return nil
+	}
+	names, err := m.ipset.ListSets(setPrefix)
+	if err != nil {
+		return nil
+	}
+	live := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		live[name] = struct{}{}
+	}
+	return live
+}
+
+func diffSetEntries(previous, next []string) (added, removed []string) {
+	known := make(map[string]struct{}, len(previous))
+	for _, entry := range previous {
+		known[entry] = struct{}{}
+	}
+	wanted := make(map[string]struct{}, len(next))
+	for _, entry := range next {
+		wanted[entry] = struct{}{}
+		if _, ok := known[entry]; !ok {
+			added = append(added, entry)
+		}
+	}
+	for _, entry := range previous {
+		if _, ok := wanted[entry]; !ok {
+			removed = append(removed, entry)
+		}
+	}
+	return added, removed
+}
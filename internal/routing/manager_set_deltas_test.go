@@ -0,0 +1,106 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type deletingIPSetMock struct {
+	*MockIPSet
+}
+
+func (m deletingIPSetMock) DelIPs(setName string, values []string) error {
+	for _, value := range values {
+		m.Calls = append(m.Calls, "del:"+setName+":"+value)
+		kept := m.IPs[setName][:0]
+		for _, existing := range m.IPs[setName] {
+			if existing != value {
+				kept = append(kept, existing)
+			}
+		}
+		m.IPs[setName] = kept
+	}
+	return nil
+}
+
+func TestApplyDesiredSetsPatchesChangedMembership(t *testing.T) {
+	ipset := deletingIPSetMock{MockIPSet: &MockIPSet{Sets: map[string]string{}}}
+	manager := &Manager{ipset: ipset}
+	const name = "svpn_media_r1d4"
+	desired := func(entries ...string) map[string]desiredSetDefinition {
+		return map[string]desiredSetDefinition{name: {Family: "inet", Entries: entries}}
+	}
+
+	if err := manager.applyDesiredSets(desired("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("first apply failed: %v", err)
+	}
+	if !strings.Contains(strings.Join(ipset.Calls, ","), "swap:"+name) {
+		t.Fatalf("expected the first apply to rebuild the set, got %v", ipset.Calls)
+	}
+
+	ipset.Calls = nil
+	if err := manager.applyDesiredSets(desired("1.1.1.1", "3.3.3.3")); err != nil {
+		t.Fatalf("second apply failed: %v", err)
+	}
+	want := "list:svpn_,add:" + name + ":3.3.3.3/32:86400,del:" + name + ":2.2.2.2/32"
+	if got := strings.Join(ipset.Calls, ","); got != want {
+		t.Fatalf("expected only deltas on the live set\nwant %s\ngot  %s", want, got)
+	}
+	if got := strings.Join(ipset.IPs[name], ","); got != "1.1.1.1/32,3.3.3.3/32" {
+		t.Fatalf("unexpected live members %q", got)
+	}
+
+	ipset.Calls = nil
+	if err := manager.applyDesiredSets(desired("3.3.3.3", "1.1.1.1")); err != nil {
+		t.Fatalf("third apply failed: %v", err)
+	}
+	if got := strings.Join(ipset.Calls, ","); got != "list:svpn_" {
+		t.Fatalf("expected an unchanged set to be left alone, got %s", got)
+	}
+
+	// Entries close to their timeout are renewed with a rebuild.
+	applied := manager.appliedSets[name]
+	applied.renewedAt = time.Now().Add(-13 * time.Hour)
+	manager.appliedSets[name] = applied
+	ipset.Calls = nil
+	if err := manager.applyDesiredSets(desired("1.1.1.1", "3.3.3.3")); err != nil {
+		t.Fatalf("renewal apply failed: %v", err)
+	}
+	if !strings.Contains(strings.Join(ipset.Calls, ","), "swap:"+name) {
+		t.Fatalf("expected a renewal rebuild, got %v", ipset.Calls)
+	}
+
+	stats := manager.ApplyStats().IPSets
+	if stats.Rebuilt != 2 || stats.Patched != 1 || stats.Unchanged != 1 || stats.EntriesAdded != 1 || stats.EntriesRemoved != 1 {
+		t.Fatalf("unexpected ipset update stats: %+v", stats)
+	}
+}
+
+func TestApplyDesiredSetsRebuildsWithoutDeleterOrWhenSetIsGone(t *testing.T) {
+	ipset := &MockIPSet{Sets: map[string]string{}}
+	manager := &Manager{ipset: ipset}
+	const name = "svpn_media_r1d4"
+	desired := map[string]desiredSetDefinition{name: {Family: "inet", Entries: []string{"1.1.1.1"}}}
+
+	for i := 0; i < 2; i++ {
+		ipset.Calls = nil
+		if err := manager.applyDesiredSets(desired); err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+		if !strings.Contains(strings.Join(ipset.Calls, ","), "swap:"+name) {
+			t.Fatalf("expected a rebuild without delete support, got %v", ipset.Calls)
+		}
+	}
+
+	deleting := deletingIPSetMock{MockIPSet: ipset}
+	manager.ipset = deleting
+	delete(ipset.Sets, name)
+	ipset.Calls = nil
+	if err := manager.applyDesiredSets(desired); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if !strings.Contains(strings.Join(ipset.Calls, ","), "swap:"+name) {
+		t.Fatalf("expected a missing set to be rebuilt, got %v", ipset.Calls)
+	}
+}
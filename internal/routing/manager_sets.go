@@ -6,28 +6,75 @@ import (
 	"hash/fnv"
 	"sort"
 	"strings"
+	"time"
 )
 
 type desiredSetDefinition struct {
 	Family  string
 	Entries []string
+	// TimeoutSeconds overrides the set's default entry timeout; 0 keeps
+	// defaultIPSetTimeoutSeconds.
+	TimeoutSeconds int
 }
 
-func (m *Manager) applyResolverSnapshotLocked(ctx context.Context, snapshot map[ResolverSelector]ResolverValues) error {
-	if err := m.store.UpsertResolverSnapshot(ctx, snapshot); err != nil {
-		return err
+// PrewarmCacheChange is the pre-warm cache as seen immediately before and
+// after one upsert. CompareErr is set when either side could not be loaded;
+// the upsert itself still happened.
+type PrewarmCacheChange struct {
+	Before     map[string]ResolverValues
+	After      map[string]ResolverValues
+	CompareErr error
+}
+
+// UpsertPrewarmSnapshotCompared behaves like UpsertPrewarmSnapshot and also
+// returns the cache before and after the write. Both reads happen under the
+// manager lock so a concurrent cache clear cannot land between them.
+func (m *Manager) UpsertPrewarmSnapshotCompared(ctx context.Context, snapshot map[string]ResolverValues) (PrewarmCacheChange, error) {
+	change, err := m.upsertPrewarmSnapshotCompared(ctx, snapshot)
+	if err != nil {
+		return PrewarmCacheChange{}, err
 	}
-	return m.applyCachedDestinationSetsLocked(ctx)
+	if err := m.requestApply(ctx, applyKindSets); err != nil {
+		return PrewarmCacheChange{}, err
+	}
+	return change, nil
 }
 
-func (m *Manager) applyPrewarmSnapshotLocked(ctx context.Context, snapshot map[string]ResolverValues) error {
+func (m *Manager) upsertPrewarmSnapshotCompared(ctx context.Context, snapshot map[string]ResolverValues) (PrewarmCacheChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var change PrewarmCacheChange
+	before, err := m.store.LoadPrewarmSnapshot(ctx)
+	if err != nil {
+		change.CompareErr = fmt.Errorf("load previous cache: %w", err)
+	}
 	if err := m.store.UpsertPrewarmSnapshot(ctx, snapshot); err != nil {
-		return err
+		return PrewarmCacheChange{}, err
+	}
+	if change.CompareErr != nil {
+		return change, nil
+	}
+	after, err := m.store.LoadPrewarmSnapshot(ctx)
+	if err != nil {
+		change.CompareErr = fmt.Errorf("load updated cache: %w", err)
+		return change, nil
 	}
-	return m.applyCachedDestinationSetsLocked(ctx)
+	change.Before = before
+	change.After = after
+	return change, nil
 }
 
+// applyCachedDestinationSetsLocked refreshes destination sets from the
+// resolver and pre-warm caches and records how long it took.
 func (m *Manager) applyCachedDestinationSetsLocked(ctx context.Context) error {
+	started := time.Now()
+	err := m.refreshCachedDestinationSetsLocked(ctx)
+	m.applyStats.record(applyKindSets, time.Since(started), err)
+	m.generation.Add(1)
+	return err
+}
+
+func (m *Manager) refreshCachedDestinationSetsLocked(ctx context.Context) error {
 	if err := m.store.PurgeExpiredResolverCache(ctx); err != nil {
 		return err
 	}
@@ -35,7 +82,7 @@ func (m *Manager) applyCachedDestinationSetsLocked(ctx context.Context) error {
 		return err
 	}
 
-	groups, err := m.store.List(ctx)
+	groups, err := m.runtimeGroupsLocked(ctx)
 	if err != nil {
 		return err
 	}
@@ -56,32 +103,51 @@ func (m *Manager) applyCachedDestinationSetsLocked(ctx context.Context) error {
 	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
 	for _, group := range groups {
 		for ruleIndex, rule := range group.Rules {
-			if !ruleNeedsDestinationSet(rule) {
-				if !ruleNeedsExcludedDestinationSet(rule) {
-					continue
-				}
-			}
 			pair := RuleSetNames(group.Name, ruleIndex)
-			if ruleNeedsDestinationSet(rule) {
-				destEntries := mergeResolvedDestinations(rule, resolved)
-				destEntries = append(destEntries, mergePrewarmedDestinations(pair, prewarmed)...)
-				destEntries = dedupeSortedStrings(destEntries)
-				destV4, destV6 := splitCIDRsByFamily(destEntries)
-				queueDesiredSet(desiredSets, nil, pair.DestinationV4, "inet", destV4)
-				queueDesiredSet(desiredSets, nil, pair.DestinationV6, "inet6", destV6)
-			}
-			if ruleNeedsExcludedDestinationSet(rule) {
-				destEntries := mergeResolvedDestinationExclusions(rule, resolved)
-				destEntries = dedupeSortedStrings(destEntries)
-				destV4, destV6 := splitCIDRsByFamily(destEntries)
-				queueDesiredSet(desiredSets, nil, pair.ExcludedDestinationV4, "inet", destV4)
-				queueDesiredSet(desiredSets, nil, pair.ExcludedDestinationV6, "inet6", destV6)
+			queueCachedDestinationSets(desiredSets, rule, pair, pair, resolved, prewarmed)
+		}
+	}
+	if canary := m.canaryForGroupsLocked(groups); canary != nil {
+		device, _ := parseCanaryDevice(canary.Device)
+		for ruleIndex, rule := range canary.Proposed.Rules {
+			narrowed, ok := canaryRule(expandRuleSources(rule, m.delegated, m.networks), device)
+			if !ok {
+				continue
 			}
+			pair := canaryRuleSetNames(canary.GroupID, ruleIndex)
+			queueCachedDestinationSets(desiredSets, narrowed, pair, RuleSetNames(canary.Proposed.Name, ruleIndex), resolved, prewarmed)
 		}
 	}
 	return m.applyDesiredSets(desiredSets)
 }
 
+// queueCachedDestinationSets queues a rule's destination sets from cached
+// resolver and pre-warm values. Pre-warm rows are keyed by prewarmPair.
+func queueCachedDestinationSets(
+	desiredSets map[string]desiredSetDefinition,
+	rule RoutingRule,
+	pair RuleSetPair,
+	prewarmPair RuleSetPair,
+	resolved map[ResolverSelector]ResolverValues,
+	prewarmed map[string]ResolverValues,
+) {
+	if ruleNeedsDestinationSet(rule) {
+		destEntries := mergeResolvedDestinations(rule, resolved)
+		destEntries = append(destEntries, mergePrewarmedDestinations(prewarmPair, prewarmed)...)
+		destEntries = dedupeSortedStrings(destEntries)
+		destV4, destV6 := splitCIDRsByFamily(destEntries)
+		queueDesiredSet(desiredSets, nil, pair.DestinationV4, "inet", destV4)
+		queueDesiredSet(desiredSets, nil, pair.DestinationV6, "inet6", destV6)
+	}
+	if ruleNeedsExcludedDestinationSet(rule) {
+		destEntries := mergeResolvedDestinationExclusions(rule, resolved)
+		destEntries = dedupeSortedStrings(destEntries)
+		destV4, destV6 := splitCIDRsByFamily(destEntries)
+		queueDesiredSet(desiredSets, nil, pair.ExcludedDestinationV4, "inet", destV4)
+		queueDesiredSet(desiredSets, nil, pair.ExcludedDestinationV6, "inet6", destV6)
+	}
+}
+
 func mergePrewarmedDestinations(pair RuleSetPair, prewarmed map[string]ResolverValues) []string {
 	out := make([]string, 0)
 	if values, ok := prewarmed[pair.DestinationV4]; ok {
@@ -105,41 +171,86 @@ func (m *Manager) applyDesiredSets(desiredSets map[string]desiredSetDefinition)
 	}
 	sort.Strings(setNames)
 
+	live := m.liveManagedSets()
+	var update IPSetUpdateStats
+	defer func() { m.applyStats.recordSetUpdate(update) }()
 	for _, setName := range setNames {
-		def := desiredSets[setName]
-		family := strings.ToLower(strings.TrimSpace(def.Family))
-		switch family {
-		case "inet", "inet6":
-		default:
-			return fmt.Errorf("invalid set family %q for %s", def.Family, setName)
-		}
-		entries := dedupeSortedStrings(def.Entries)
-		entries, err := collapseSetEntries(entries, family)
+		family, entries, err := desiredSetEntries(setName, desiredSets[setName])
 		if err != nil {
-			return fmt.Errorf("collapse entries for %s: %w", setName, err)
+			return err
 		}
-		if err := m.applySetAtomically(setName, family, entries); err != nil {
+		timeoutSeconds := desiredSets[setName].TimeoutSeconds
+		if timeoutSeconds <= 0 {
+			timeoutSeconds = defaultIPSetTimeoutSeconds
+		}
+		if delta, ok := m.applySetDelta(setName, family, entries, timeoutSeconds, live); ok {
+			update.Patched += delta.Patched
+			update.Unchanged += delta.Unchanged
+			update.EntriesAdded += delta.EntriesAdded
+			update.EntriesRemoved += delta.EntriesRemoved
+			continue
+		}
+		if err := m.rebuildSet(setName, family, entries, timeoutSeconds); err != nil {
 			return err
 		}
+		update.Rebuilt++
 	}
 	return nil
 }
 
-func (m *Manager) applySetAtomically(setName, family string, entries []string) error {
+// desiredSetEntries returns the family and the deduplicated, collapsed
+// entries a set is loaded with.
+func desiredSetEntries(setName string, def desiredSetDefinition) (string, []string, error) {
+	family := strings.ToLower(strings.TrimSpace(def.Family))
+	switch family {
+	case "inet", "inet6":
+	default:
+		return "", nil, fmt.Errorf("invalid set family %q for %s", def.Family, setName)
+	}
+	entries, err := collapseSetEntries(dedupeSortedStrings(def.Entries), family)
+	if err != nil {
+		return "", nil, fmt.Errorf("collapse entries for %s: %w", setName, err)
+	}
+	return family, entries, nil
+}
+
+// applySetAtomically loads entries into a staged set and swaps it in. The
+// staged set carries the timeout, so swapping also changes the default
+// timeout dnsmasq-added entries get.
+func (m *Manager) applySetAtomically(setName, family string, entries []string, timeoutSeconds int) error {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultIPSetTimeoutSeconds
+	}
 	if err := m.ipset.EnsureSet(setName, family); err != nil {
-		return err
+		// A live set created with another timeout cannot be re-created;
+		// the swap below replaces it.
+		if !m.ipsetExists(setName) {
+			return err
+		}
 	}
 	stagedSet := stagedSetName(setName)
-	if err := m.ipset.EnsureSet(stagedSet, family); err != nil {
-		return err
+	if err := m.ensureSetWithTimeout(stagedSet, family, timeoutSeconds); err != nil {
+		// A staged set left over with another timeout is recreated.
+		if destroyErr := m.ipset.DestroySet(stagedSet); destroyErr != nil {
+			return err
+		}
+		if err := m.ensureSetWithTimeout(stagedSet, family, timeoutSeconds); err != nil {
+			return err
+		}
 	}
 	if err := m.ipset.FlushSet(stagedSet); err != nil {
 		return err
 	}
-	for _, entry := range entries {
-		if err := m.ipset.AddIP(stagedSet, entry, defaultIPSetTimeoutSeconds); err != nil {
+	if loader, ok := m.ipset.(IPSetBatchLoader); ok {
+		if err := loader.AddIPs(stagedSet, entries, timeoutSeconds); err != nil {
 			return err
 		}
+	} else {
+		for _, entry := range entries {
+			if err := m.ipset.AddIP(stagedSet, entry, timeoutSeconds); err != nil {
+				return err
+			}
+		}
 	}
 	if err := m.ipset.SwapSets(setName, stagedSet); err != nil {
 		return err
@@ -150,6 +261,28 @@ func (m *Manager) applySetAtomically(setName, family string, entries []string) e
 	return nil
 }
 
+func (m *Manager) ensureSetWithTimeout(name, family string, timeoutSeconds int) error {
+	if timeoutSeconds != defaultIPSetTimeoutSeconds {
+		if creator, ok := m.ipset.(IPSetTimeoutCreator); ok {
+			return creator.EnsureSetWithTimeout(name, family, timeoutSeconds)
+		}
+	}
+	return m.ipset.EnsureSet(name, family)
+}
+
+func (m *Manager) ipsetExists(name string) bool {
+	names, err := m.ipset.ListSets(name)
+	if err != nil {
+		return false
+	}
+	for _, existing := range names {
+		if existing == name {
+			return true
+		}
+	}
+	return false
+}
+
 func queueDesiredSet(
 	desiredSets map[string]desiredSetDefinition,
 	activeSets map[string]struct{},
@@ -199,6 +332,9 @@ func mergeResolvedDestinations(rule RoutingRule, resolved map[ResolverSelector]R
 		destEntries = append(destEntries, entry.V4...)
 		destEntries = append(destEntries, entry.V6...)
 	}
+	for _, host := range rule.StaticHosts {
+		destEntries = append(destEntries, host.CIDRs...)
+	}
 	return destEntries
 }
 
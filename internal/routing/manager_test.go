@@ -460,3 +460,39 @@ func TestManagerUpsertPrewarmSnapshotUpdatesDestinationSetsWithoutRuleReapply(t
 		t.Fatalf("unexpected loaded prewarm snapshot: %#v", loaded[sets.DestinationV4].V4)
 	}
 }
+
+func TestManagerUpsertPrewarmSnapshotComparedReturnsBeforeAndAfter(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, _ := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{{
+		Name:          "wg-sgp",
+		RouteTable:    201,
+		FWMark:        0x169,
+		InterfaceName: "wg-sgp",
+	}}})
+	if _, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Domains: []string{"example.com"}}},
+	}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	sets := RuleSetNames("Streaming", 0)
+	if err := manager.UpsertPrewarmSnapshot(ctx, map[string]ResolverValues{
+		sets.DestinationV4: {V4: []string{"203.0.113.10/32"}},
+	}); err != nil {
+		t.Fatalf("UpsertPrewarmSnapshot failed: %v", err)
+	}
+
+	change, err := manager.UpsertPrewarmSnapshotCompared(ctx, map[string]ResolverValues{
+		sets.DestinationV4: {V4: []string{"203.0.113.20/32"}},
+	})
+	if err != nil || change.CompareErr != nil {
+		t.Fatalf("UpsertPrewarmSnapshotCompared failed: err=%v compare=%v", err, change.CompareErr)
+	}
+	if got := change.Before[sets.DestinationV4].V4; len(got) != 1 || got[0] != "203.0.113.10/32" {
+		t.Fatalf("unexpected before snapshot: %#v", got)
+	}
+	if got := change.After[sets.DestinationV4].V4; len(got) != 2 {
+		t.Fatalf("expected after snapshot to hold both prefixes, got %#v", got)
+	}
+}
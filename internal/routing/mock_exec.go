@@ -12,6 +12,8 @@ type MockExec struct {
 
 	RunCalls    [][]string
 	OutputCalls [][]string
+	// Inputs records stdin passed to RunWithInput, indexed like RunCalls.
+	Inputs map[int][]byte
 
 	RunErrors    map[string]error
 	OutputErrors map[string]error
@@ -45,3 +47,19 @@ func (m *MockExec) Output(name string, args ...string) ([]byte, error) {
 	}
 	return out, nil
 }
+
+func (m *MockExec) RunWithInput(input []byte, name string, args ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call := append([]string{name}, args...)
+	if m.Inputs == nil {
+		m.Inputs = map[int][]byte{}
+	}
+	m.Inputs[len(m.RunCalls)] = append([]byte(nil), input...)
+	m.RunCalls = append(m.RunCalls, call)
+	key := strings.Join(call, " ")
+	if err, ok := m.RunErrors[key]; ok {
+		return err
+	}
+	return nil
+}
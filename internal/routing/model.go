@@ -2,7 +2,6 @@ package routing
 
 import (
 	"fmt"
-	"hash/fnv"
 	"regexp"
 	"sort"
 	"strings"
@@ -10,11 +9,11 @@ import (
 	"split-vpn-webui/internal/vpn"
 )
 
+// Group priorities are bounded so reordering can always hand out distinct
+// values.
 const (
-	setPrefix       = "svpn_"
-	setSuffixV4     = "_v4"
-	setSuffixV6     = "_v6"
-	maxIPSetNameLen = 31
+	minGroupPriority = -1000
+	maxGroupPriority = 1000
 )
 
 var (
@@ -25,14 +24,50 @@ var (
 	ErrGroupNotFound = fmt.Errorf("domain group not found")
 	// ErrGroupValidation indicates invalid input payload.
 	ErrGroupValidation = fmt.Errorf("domain group validation failed")
+	// ErrGroupManaged indicates an edit to a group owned by another feature.
+	ErrGroupManaged = fmt.Errorf("domain group is managed")
 )
 
 // DomainGroup is a persisted routing group assigned to one egress VPN.
 type DomainGroup struct {
-	ID        int64         `json:"id"`
-	Name      string        `json:"name"`
-	EgressVPN string        `json:"egressVpn"`
-	Rules     []RoutingRule `json:"rules"`
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	EgressVPN string `json:"egressVpn"`
+	// DNSRedirect forces DNS from the group's source-matched clients to the
+	// egress VPN's resolvers ("vpn") or to the local resolver ("local").
+	DNSRedirect string `json:"dnsRedirect,omitempty"`
+	// DnsmasqIsolated writes the group's dnsmasq lines to its own conf
+	// fragment instead of the shared file.
+	DnsmasqIsolated bool `json:"dnsmasqIsolated,omitempty"`
+	// DnsmasqUpstreams forwards the group's domains to these servers
+	// (ip or ip#port) instead of dnsmasq's default upstreams.
+	DnsmasqUpstreams []string `json:"dnsmasqUpstreams,omitempty"`
+	// IPSetTimeoutSeconds overrides how long entries stay in the group's
+	// destination sets, including entries dnsmasq adds. 0 keeps the default.
+	IPSetTimeoutSeconds int `json:"ipsetTimeoutSeconds,omitempty"`
+	// InvertDestinations turns the group into an "everything except" group:
+	// each rule routes all traffic from its sources except to the rule's
+	// destination selectors, which act as exclusions.
+	InvertDestinations bool `json:"invertDestinations,omitempty"`
+	// FullTunnel makes the group a default route for its sources: rules
+	// select clients only, and the group's bindings rank below every
+	// destination-based group so domain rules still take precedence.
+	FullTunnel bool `json:"fullTunnel,omitempty"`
+	// KillSwitch drops the group's client traffic instead of letting it
+	// fall back to the WAN while the egress VPN is down.
+	KillSwitch bool `json:"killSwitch,omitempty"`
+	// Managed names the feature that generated the group, e.g.
+	// ManagedGuestSafeMode. Managed groups are only changed through it.
+	Managed string `json:"managed,omitempty"`
+	// Notes and Tags document the group; they do not affect routing.
+	Notes string   `json:"notes,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// Priority decides between groups whose rules claim the same packet:
+	// the highest priority wins, and equal priorities fall back to name
+	// order (the later name wins). Rules in one group share its VPN, so
+	// only groups carry a priority.
+	Priority int           `json:"priority,omitempty"`
+	Rules    []RoutingRule `json:"rules"`
 	// Domains is a legacy compatibility field. New clients should use Rules.
 	Domains   []string `json:"domains,omitempty"`
 	CreatedAt int64    `json:"createdAt"`
@@ -41,22 +76,40 @@ type DomainGroup struct {
 
 // RoutingRule defines one AND-combined selector rule inside a group.
 type RoutingRule struct {
-	ID                       int64             `json:"id,omitempty"`
-	Name                     string            `json:"name,omitempty"`
-	SourceInterfaces         []string          `json:"sourceInterfaces,omitempty"`
-	SourceCIDRs              []string          `json:"sourceCidrs,omitempty"`
-	ExcludedSourceCIDRs      []string          `json:"excludedSourceCidrs,omitempty"`
-	SourceMACs               []string          `json:"sourceMacs,omitempty"`
-	DestinationCIDRs         []string          `json:"destinationCidrs,omitempty"`
-	ExcludedDestinationCIDRs []string          `json:"excludedDestinationCidrs,omitempty"`
-	DestinationPorts         []PortRange       `json:"destinationPorts,omitempty"`
-	ExcludedDestinationPorts []PortRange       `json:"excludedDestinationPorts,omitempty"`
-	DestinationASNs          []string          `json:"destinationAsns,omitempty"`
-	ExcludedDestinationASNs  []string          `json:"excludedDestinationAsns,omitempty"`
-	ExcludeMulticast         *bool             `json:"excludeMulticast,omitempty"`
-	Domains                  []string          `json:"domains,omitempty"`
-	WildcardDomains          []string          `json:"wildcardDomains,omitempty"`
-	RawSelectors             *RuleRawSelectors `json:"rawSelectors,omitempty"`
+	ID                       int64       `json:"id,omitempty"`
+	Name                     string      `json:"name,omitempty"`
+	SourceInterfaces         []string    `json:"sourceInterfaces,omitempty"`
+	SourceCIDRs              []string    `json:"sourceCidrs,omitempty"`
+	ExcludedSourceCIDRs      []string    `json:"excludedSourceCidrs,omitempty"`
+	SourceMACs               []string    `json:"sourceMacs,omitempty"`
+	SourceDeviceGroups       []string    `json:"sourceDeviceGroups,omitempty"`
+	DestinationCIDRs         []string    `json:"destinationCidrs,omitempty"`
+	ExcludedDestinationCIDRs []string    `json:"excludedDestinationCidrs,omitempty"`
+	DestinationPorts         []PortRange `json:"destinationPorts,omitempty"`
+	ExcludedDestinationPorts []PortRange `json:"excludedDestinationPorts,omitempty"`
+	DestinationASNs          []string    `json:"destinationAsns,omitempty"`
+	ExcludedDestinationASNs  []string    `json:"excludedDestinationAsns,omitempty"`
+	ExcludeMulticast         *bool       `json:"excludeMulticast,omitempty"`
+	UploadLimitKbit          int         `json:"uploadLimitKbit,omitempty"`
+	DownloadLimitKbit        int         `json:"downloadLimitKbit,omitempty"`
+	MonitorOnly              bool        `json:"monitorOnly,omitempty"`
+	Notes                    string      `json:"notes,omitempty"`
+	Tags                     []string    `json:"tags,omitempty"`
+	// ExpiresAt is when the rule stops routing, in Unix seconds; 0 never
+	// expires. Expired rules stay in the group but install nothing.
+	ExpiresAt       int64               `json:"expiresAt,omitempty"`
+	Domains         []string            `json:"domains,omitempty"`
+	WildcardDomains []string            `json:"wildcardDomains,omitempty"`
+	StaticHosts     []StaticHostMapping `json:"staticHosts,omitempty"`
+	RawSelectors    *RuleRawSelectors   `json:"rawSelectors,omitempty"`
+}
+
+// StaticHostMapping is a manual override that adds CIDRs to the destination
+// sets of a rule with the domain, whatever the resolvers find. It serves
+// hosts whose addresses DNS never returns to the router.
+type StaticHostMapping struct {
+	Domain string   `json:"domain"`
+	CIDRs  []string `json:"cidrs"`
 }
 
 // RuleRawSelectors preserves user-entered selector lines (including comments).
@@ -65,6 +118,7 @@ type RuleRawSelectors struct {
 	SourceCIDRs              []string `json:"sourceCidrs,omitempty"`
 	ExcludedSourceCIDRs      []string `json:"excludedSourceCidrs,omitempty"`
 	SourceMACs               []string `json:"sourceMacs,omitempty"`
+	SourceDeviceGroups       []string `json:"sourceDeviceGroups,omitempty"`
 	DestinationCIDRs         []string `json:"destinationCidrs,omitempty"`
 	ExcludedDestinationCIDRs []string `json:"excludedDestinationCidrs,omitempty"`
 	DestinationPorts         []string `json:"destinationPorts,omitempty"`
@@ -73,6 +127,7 @@ type RuleRawSelectors struct {
 	ExcludedDestinationASNs  []string `json:"excludedDestinationAsns,omitempty"`
 	Domains                  []string `json:"domains,omitempty"`
 	WildcardDomains          []string `json:"wildcardDomains,omitempty"`
+	StaticHosts              []string `json:"staticHosts,omitempty"`
 }
 
 // PortRange matches one destination port/range for a specific L4 protocol.
@@ -93,6 +148,9 @@ type RouteBinding struct {
 	ExcludedSourceSetV4      string
 	ExcludedSourceSetV6      string
 	SourceMACs               []string
+	SourceDeviceMACs         []string
+	SourceDeviceSetV4        string
+	SourceDeviceSetV6        string
 	DestinationSetV4         string
 	DestinationSetV6         string
 	ExcludedDestinationSetV4 string
@@ -101,6 +159,13 @@ type RouteBinding struct {
 	HasExcludedSource        bool
 	HasDestination           bool
 	HasExcludedDestination   bool
+	// InvertDestination matches traffic outside the destination sets
+	// instead of inside them (see DomainGroup.InvertDestinations).
+	InvertDestination bool
+	// HasSourceDevices restricts the binding to members of its device groups:
+	// any SourceDeviceMACs entry or, with HasSourceDeviceSet, the device set.
+	HasSourceDevices         bool
+	HasSourceDeviceSet       bool
 	DestinationPorts         []PortRange
 	ExcludedDestinationPorts []PortRange
 	ExcludeMulticast         bool
@@ -108,8 +173,43 @@ type RouteBinding struct {
 	RouteTable               int
 	Interface                string
 	EgressVPN                string
-	MSSClampV4               string
-	MSSClampV6               string
+	// DNSRedirect is the group's DNS redirect mode. In "vpn" mode DNS is
+	// sent to DNSServersV4/V6, falling back to tunnel routing for a family
+	// without a server.
+	DNSRedirect  string
+	DNSServersV4 []string
+	DNSServersV6 []string
+	MSSClampV4   string
+	MSSClampV6   string
+	// IPv6Policy and IPv6Prefix carry the VPN's IPv6 policy (see
+	// vpn.ValidateIPv6Policy); an empty policy masquerades like IPv4.
+	IPv6Policy string
+	IPv6Prefix string
+	// UploadLimitKbit and DownloadLimitKbit police the rule's traffic with
+	// a hashlimit bucket shared by all of the binding's mark rules.
+	UploadLimitKbit   int
+	DownloadLimitKbit int
+	// MonitorOnly bindings log matching new connections to NFLOG instead of
+	// marking them, so no traffic is diverted.
+	MonitorOnly bool
+	// Priority is the group's priority. Bindings are installed in
+	// ascending priority because the last mark set on a packet decides its
+	// VPN; the fwmark ip rules all share one preference since a packet only
+	// carries one mark.
+	Priority int
+	// FullTunnel bindings route everything from their sources. They are
+	// applied before all other bindings so any other match overrides them.
+	FullTunnel bool
+	// KillSwitch bindings drop marked traffic that would leave through
+	// anything but the VPN interface.
+	KillSwitch bool
+	// Canary marks a binding staged for a single test device. Canary bindings
+	// are applied after all live bindings so their marks take precedence.
+	Canary bool
+	// ExcludedInputInterfaces lists VPN interfaces that carry another VPN's
+	// tunnel. Packets arriving on them belong to the nested tunnel and are
+	// never re-marked.
+	ExcludedInputInterfaces []string
 }
 
 // NormalizeAndValidate validates a group and returns a canonical version.
@@ -139,144 +239,70 @@ func NormalizeAndValidate(group DomainGroup) (DomainGroup, error) {
 		return DomainGroup{}, err
 	}
 
-	group.Name = trimmedName
-	group.EgressVPN = egress
-	group.Rules = normalizedRules
-	group.Domains = legacyDomainsFromRules(normalizedRules)
-	return group, nil
-}
-
-func normalizeRules(raw []RoutingRule) ([]RoutingRule, error) {
-	out := make([]RoutingRule, 0, len(raw))
-	for idx, entry := range raw {
-		rule, err := normalizeRule(entry, idx)
-		if err != nil {
-			return nil, err
+	dnsRedirect := strings.ToLower(strings.TrimSpace(group.DNSRedirect))
+	switch dnsRedirect {
+	case DNSRedirectNone:
+	case DNSRedirectVPN, DNSRedirectLocal:
+		if !rulesHaveSourceSelectors(normalizedRules) {
+			return DomainGroup{}, fmt.Errorf("%w: dns redirect requires every rule to select source clients", ErrGroupValidation)
 		}
-		out = append(out, rule)
-	}
-	return out, nil
-}
-
-func normalizeRule(raw RoutingRule, idx int) (RoutingRule, error) {
-	rawSelectors := normalizeRuleRawSelectors(raw.RawSelectors)
-	rawSelectors = hydrateRuleRawSelectorsFromRule(rawSelectors, raw)
-	var err error
-	rule := RoutingRule{
-		ID:   raw.ID,
-		Name: strings.TrimSpace(raw.Name),
-	}
-	if rule.Name == "" {
-		rule.Name = fmt.Sprintf("Rule %d", idx+1)
-	}
-	sourceInterfaces := selectorValuesFromRaw(rawSelectors.SourceInterfaces)
-	rule.SourceInterfaces, err = normalizeInterfaces(sourceInterfaces)
-	if err != nil {
-		return RoutingRule{}, err
+	default:
+		return DomainGroup{}, fmt.Errorf("%w: invalid dns redirect %q", ErrGroupValidation, group.DNSRedirect)
 	}
-	sourceCIDRs := selectorValuesFromRaw(rawSelectors.SourceCIDRs)
-	rule.SourceCIDRs, err = normalizeCIDRs(sourceCIDRs, "source")
-	if err != nil {
-		return RoutingRule{}, err
+	if group.InvertDestinations && !rulesHaveSourceSelectors(normalizedRules) {
+		return DomainGroup{}, fmt.Errorf("%w: inverted destinations require every rule to select source clients", ErrGroupValidation)
 	}
-	excludedSourceCIDRs := selectorValuesFromRaw(rawSelectors.ExcludedSourceCIDRs)
-	rule.ExcludedSourceCIDRs, err = normalizeCIDRs(excludedSourceCIDRs, "excluded source")
-	if err != nil {
-		return RoutingRule{}, err
+	if group.KillSwitch && !rulesHaveSourceSelectors(normalizedRules) {
+		return DomainGroup{}, fmt.Errorf("%w: kill switch requires every rule to select source clients", ErrGroupValidation)
 	}
-	sourceMACs := selectorValuesFromRaw(rawSelectors.SourceMACs)
-	rule.SourceMACs, err = normalizeMACs(sourceMACs)
-	if err != nil {
-		return RoutingRule{}, err
-	}
-	destinationCIDRs := selectorValuesFromRaw(rawSelectors.DestinationCIDRs)
-	rule.DestinationCIDRs, err = normalizeCIDRs(destinationCIDRs, "destination")
-	if err != nil {
-		return RoutingRule{}, err
+	if group.Priority < minGroupPriority || group.Priority > maxGroupPriority {
+		return DomainGroup{}, fmt.Errorf("%w: priority must be between %d and %d", ErrGroupValidation, minGroupPriority, maxGroupPriority)
 	}
-	excludedDestinationCIDRs := selectorValuesFromRaw(rawSelectors.ExcludedDestinationCIDRs)
-	rule.ExcludedDestinationCIDRs, err = normalizeCIDRs(excludedDestinationCIDRs, "excluded destination")
-	if err != nil {
-		return RoutingRule{}, err
-	}
-	destinationPorts := append([]PortRange(nil), raw.DestinationPorts...)
-	if len(destinationPorts) == 0 {
-		destinationPorts, err = parsePortSelectorStrings(selectorValuesFromRaw(rawSelectors.DestinationPorts))
-		if err != nil {
-			return RoutingRule{}, err
+	if group.FullTunnel {
+		if group.InvertDestinations {
+			return DomainGroup{}, fmt.Errorf("%w: a full tunnel group cannot invert destinations", ErrGroupValidation)
 		}
-	}
-	rule.DestinationPorts, err = normalizePorts(destinationPorts)
-	if err != nil {
-		return RoutingRule{}, err
-	}
-	excludedDestinationPorts := append([]PortRange(nil), raw.ExcludedDestinationPorts...)
-	if len(excludedDestinationPorts) == 0 {
-		excludedDestinationPorts, err = parsePortSelectorStrings(selectorValuesFromRaw(rawSelectors.ExcludedDestinationPorts))
-		if err != nil {
-			return RoutingRule{}, err
+		if !rulesHaveSourceSelectors(normalizedRules) {
+			return DomainGroup{}, fmt.Errorf("%w: full tunnel requires every rule to select source clients", ErrGroupValidation)
+		}
+		for idx, rule := range normalizedRules {
+			if ruleHasDestinationSelectors(rule) {
+				return DomainGroup{}, fmt.Errorf("%w: full tunnel rule %d cannot have destination selectors", ErrGroupValidation, idx+1)
+			}
 		}
 	}
-	rule.ExcludedDestinationPorts, err = normalizePorts(excludedDestinationPorts)
+
+	upstreams, err := normalizeDnsmasqUpstreams(group.DnsmasqUpstreams)
 	if err != nil {
-		return RoutingRule{}, err
+		return DomainGroup{}, err
 	}
-	destinationASNs := selectorValuesFromRaw(rawSelectors.DestinationASNs)
-	rule.DestinationASNs, err = normalizeASNs(destinationASNs)
-	if err != nil {
-		return RoutingRule{}, err
+	if group.IPSetTimeoutSeconds != 0 && (group.IPSetTimeoutSeconds < minIPSetTimeoutSeconds || group.IPSetTimeoutSeconds > maxIPSetTimeoutSeconds) {
+		return DomainGroup{}, fmt.Errorf("%w: ipset timeout must be 0 or %d-%d seconds", ErrGroupValidation, minIPSetTimeoutSeconds, maxIPSetTimeoutSeconds)
 	}
-	excludedDestinationASNs := selectorValuesFromRaw(rawSelectors.ExcludedDestinationASNs)
-	rule.ExcludedDestinationASNs, err = normalizeASNs(excludedDestinationASNs)
-	if err != nil {
-		return RoutingRule{}, err
+
+	managed := strings.TrimSpace(group.Managed)
+	if managed != "" && managed != ManagedGuestSafeMode {
+		return DomainGroup{}, fmt.Errorf("%w: unknown managed group kind %q", ErrGroupValidation, group.Managed)
 	}
-	domains := selectorValuesFromRaw(rawSelectors.Domains)
-	rule.Domains, err = normalizeDomains(domains, false)
+	notes, err := normalizeNotes(group.Notes, "group")
 	if err != nil {
-		return RoutingRule{}, err
+		return DomainGroup{}, err
 	}
-	wildcards := selectorValuesFromRaw(rawSelectors.WildcardDomains)
-	rule.WildcardDomains, err = normalizeDomains(wildcards, true)
+	tags, err := normalizeTags(group.Tags, "group")
 	if err != nil {
-		return RoutingRule{}, err
-	}
-	rule.ExcludeMulticast = boolPointer(true)
-	if raw.ExcludeMulticast != nil {
-		rule.ExcludeMulticast = boolPointer(*raw.ExcludeMulticast)
-	}
-	rawSelectors = finalizeRuleRawSelectors(rawSelectors, rule)
-	if !ruleHasSelectors(rule) && !rawSelectors.hasAnyLine() {
-		return RoutingRule{}, fmt.Errorf(
-			"%w: rule %d must include at least one selector or comment line",
-			ErrGroupValidation,
-			idx+1,
-		)
+		return DomainGroup{}, err
 	}
-	rule.RawSelectors = &rawSelectors
-	return rule, nil
-}
 
-func legacyDomainsFromRules(rules []RoutingRule) []string {
-	seen := make(map[string]struct{})
-	out := make([]string, 0)
-	for _, rule := range rules {
-		for _, domain := range rule.Domains {
-			if _, exists := seen[domain]; exists {
-				continue
-			}
-			seen[domain] = struct{}{}
-			out = append(out, domain)
-		}
-		for _, wildcard := range rule.WildcardDomains {
-			if _, exists := seen[wildcard]; exists {
-				continue
-			}
-			seen[wildcard] = struct{}{}
-			out = append(out, wildcard)
-		}
-	}
-	return out
+	group.Name = trimmedName
+	group.EgressVPN = egress
+	group.Managed = managed
+	group.Notes = notes
+	group.Tags = tags
+	group.DNSRedirect = dnsRedirect
+	group.DnsmasqUpstreams = upstreams
+	group.Rules = normalizedRules
+	group.Domains = legacyDomainsFromRules(normalizedRules)
+	return group, nil
 }
 
 // RuleDomains returns exact + wildcard domains for resolver pipelines.
@@ -320,90 +346,7 @@ func RuleDomains(group DomainGroup) []string {
 	return out
 }
 
-// GroupSetNames derives deterministic ipset names for a group.
-func GroupSetNames(groupName string) (string, string) {
-	rule := RuleSetNames(groupName, 0)
-	return rule.DestinationV4, rule.DestinationV6
-}
-
-// RuleSetPair is deterministic per-group per-rule source+destination ipset names.
-type RuleSetPair struct {
-	SourceV4              string
-	SourceV6              string
-	ExcludedSourceV4      string
-	ExcludedSourceV6      string
-	DestinationV4         string
-	DestinationV6         string
-	ExcludedDestinationV4 string
-	ExcludedDestinationV6 string
-}
-
-// RuleSetNames returns deterministic source/destination set names for one rule.
-func RuleSetNames(groupName string, ruleIndex int) RuleSetPair {
-	base := normalizeSetBase(groupName)
-	if ruleIndex < 0 {
-		ruleIndex = 0
-	}
-	seed := strings.ToLower(fmt.Sprintf("%s:%d", groupName, ruleIndex))
-	return RuleSetPair{
-		SourceV4:              compactSetName(base, fmt.Sprintf("r%ds4", ruleIndex+1), seed+":src4"),
-		SourceV6:              compactSetName(base, fmt.Sprintf("r%ds6", ruleIndex+1), seed+":src6"),
-		ExcludedSourceV4:      compactSetName(base, fmt.Sprintf("r%dxs4", ruleIndex+1), seed+":xsrc4"),
-		ExcludedSourceV6:      compactSetName(base, fmt.Sprintf("r%dxs6", ruleIndex+1), seed+":xsrc6"),
-		DestinationV4:         compactSetName(base, fmt.Sprintf("r%dd4", ruleIndex+1), seed+":dst4"),
-		DestinationV6:         compactSetName(base, fmt.Sprintf("r%dd6", ruleIndex+1), seed+":dst6"),
-		ExcludedDestinationV4: compactSetName(base, fmt.Sprintf("r%dxd4", ruleIndex+1), seed+":xdst4"),
-		ExcludedDestinationV6: compactSetName(base, fmt.Sprintf("r%dxd6", ruleIndex+1), seed+":xdst6"),
-	}
-}
-
 func boolPointer(value bool) *bool {
 	v := value
 	return &v
 }
-
-func compactSetName(base, suffix, seed string) string {
-	name := setPrefix + base + "_" + suffix
-	if len(name) <= maxIPSetNameLen {
-		return name
-	}
-	h := fnv.New32a()
-	_, _ = h.Write([]byte(seed))
-	hash := fmt.Sprintf("%08x", h.Sum32())
-	maxBase := maxIPSetNameLen - len(setPrefix) - len(suffix) - len(hash) - 2
-	if maxBase < 3 {
-		maxBase = 3
-	}
-	shortBase := base
-	if len(shortBase) > maxBase {
-		shortBase = shortBase[:maxBase]
-	}
-	return setPrefix + shortBase + "_" + hash + "_" + suffix
-}
-
-func normalizeSetBase(groupName string) string {
-	input := strings.ToLower(strings.TrimSpace(groupName))
-	if input == "" {
-		return "group"
-	}
-	builder := strings.Builder{}
-	builder.Grow(len(input))
-	lastUnderscore := false
-	for _, r := range input {
-		isAlnum := (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
-		if isAlnum {
-			builder.WriteRune(r)
-			lastUnderscore = false
-			continue
-		}
-		if !lastUnderscore {
-			builder.WriteRune('_')
-			lastUnderscore = true
-		}
-	}
-	base := strings.Trim(builder.String(), "_")
-	if base == "" {
-		base = "group"
-	}
-	return base
-}
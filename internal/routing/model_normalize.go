@@ -44,7 +44,12 @@ func normalizeInterfaces(raw []string) ([]string, error) {
 		if trimmed == "" {
 			continue
 		}
-		if !ifaceNamePattern.MatchString(trimmed) {
+		if selector, isNetwork, err := parseNetworkSelector(entry); isNetwork {
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid source interface selector %q: %v", ErrGroupValidation, entry, err)
+			}
+			trimmed = selector.String()
+		} else if !ifaceNamePattern.MatchString(trimmed) {
 			return nil, fmt.Errorf("%w: invalid source interface selector %q", ErrGroupValidation, entry)
 		}
 		if _, exists := seen[trimmed]; exists {
@@ -191,6 +196,7 @@ func ruleHasSelectors(rule RoutingRule) bool {
 		len(rule.SourceCIDRs) > 0 ||
 		len(rule.ExcludedSourceCIDRs) > 0 ||
 		len(rule.SourceMACs) > 0 ||
+		len(rule.SourceDeviceGroups) > 0 ||
 		len(rule.DestinationCIDRs) > 0 ||
 		len(rule.ExcludedDestinationCIDRs) > 0 ||
 		len(rule.DestinationPorts) > 0 ||
@@ -201,6 +207,17 @@ func ruleHasSelectors(rule RoutingRule) bool {
 		len(rule.WildcardDomains) > 0
 }
 
+// ruleHasDestinationSelectors reports whether a rule narrows its traffic by
+// destination. Exclusions do not count.
+func ruleHasDestinationSelectors(rule RoutingRule) bool {
+	return len(rule.DestinationCIDRs) > 0 ||
+		len(rule.DestinationPorts) > 0 ||
+		len(rule.DestinationASNs) > 0 ||
+		len(rule.Domains) > 0 ||
+		len(rule.WildcardDomains) > 0 ||
+		len(rule.StaticHosts) > 0
+}
+
 // RuleExcludeMulticastEnabled returns whether multicast traffic should be excluded for a rule.
 // Nil means enabled by default for backward compatibility and safer behavior.
 func RuleExcludeMulticastEnabled(rule RoutingRule) bool {
@@ -209,3 +226,221 @@ func RuleExcludeMulticastEnabled(rule RoutingRule) bool {
 	}
 	return *rule.ExcludeMulticast
 }
+
+// normalizeStaticHosts validates static host mappings against the rule's
+// domain selectors. A mapping must name one of the rule's domains or
+// wildcards; mappings for the same domain are merged.
+func normalizeStaticHosts(raw []StaticHostMapping, rule RoutingRule) ([]StaticHostMapping, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]struct{}, len(rule.Domains)+len(rule.WildcardDomains))
+	for _, domain := range rule.Domains {
+		known[domain] = struct{}{}
+	}
+	for _, domain := range rule.WildcardDomains {
+		known[domain] = struct{}{}
+	}
+	index := make(map[string]int, len(raw))
+	out := make([]StaticHostMapping, 0, len(raw))
+	for _, entry := range raw {
+		domain := strings.ToLower(strings.TrimSpace(entry.Domain))
+		if domain == "" {
+			return nil, fmt.Errorf("%w: static host selector requires a domain", ErrGroupValidation)
+		}
+		if _, ok := known[domain]; !ok {
+			if _, ok := known["*."+domain]; !ok || strings.HasPrefix(domain, "*.") {
+				return nil, fmt.Errorf("%w: static host domain %q is not one of the rule's domains", ErrGroupValidation, entry.Domain)
+			}
+			domain = "*." + domain
+		}
+		cidrs, err := normalizeCIDRs(entry.CIDRs, "static host")
+		if err != nil {
+			return nil, err
+		}
+		if len(cidrs) == 0 {
+			return nil, fmt.Errorf("%w: static host %q requires at least one address", ErrGroupValidation, entry.Domain)
+		}
+		if existing, ok := index[domain]; ok {
+			merged, err := normalizeCIDRs(append(out[existing].CIDRs, cidrs...), "static host")
+			if err != nil {
+				return nil, err
+			}
+			out[existing].CIDRs = merged
+			continue
+		}
+		index[domain] = len(out)
+		out = append(out, StaticHostMapping{Domain: domain, CIDRs: cidrs})
+	}
+	return out, nil
+}
+
+func normalizeRules(raw []RoutingRule) ([]RoutingRule, error) {
+	out := make([]RoutingRule, 0, len(raw))
+	for idx, entry := range raw {
+		rule, err := normalizeRule(entry, idx)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+func normalizeRule(raw RoutingRule, idx int) (RoutingRule, error) {
+	rawSelectors := normalizeRuleRawSelectors(raw.RawSelectors)
+	rawSelectors = hydrateRuleRawSelectorsFromRule(rawSelectors, raw)
+	var err error
+	rule := RoutingRule{
+		ID:   raw.ID,
+		Name: strings.TrimSpace(raw.Name),
+	}
+	if rule.Name == "" {
+		rule.Name = fmt.Sprintf("Rule %d", idx+1)
+	}
+	sourceInterfaces := selectorValuesFromRaw(rawSelectors.SourceInterfaces)
+	rule.SourceInterfaces, err = normalizeInterfaces(sourceInterfaces)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	sourceCIDRs := selectorValuesFromRaw(rawSelectors.SourceCIDRs)
+	rule.SourceCIDRs, err = normalizeSourceSelectors(sourceCIDRs, "source")
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	excludedSourceCIDRs := selectorValuesFromRaw(rawSelectors.ExcludedSourceCIDRs)
+	rule.ExcludedSourceCIDRs, err = normalizeSourceSelectors(excludedSourceCIDRs, "excluded source")
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	sourceMACs := selectorValuesFromRaw(rawSelectors.SourceMACs)
+	rule.SourceMACs, err = normalizeMACs(sourceMACs)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	sourceDeviceGroups := selectorValuesFromRaw(rawSelectors.SourceDeviceGroups)
+	rule.SourceDeviceGroups, err = normalizeDeviceGroupRefs(sourceDeviceGroups)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	destinationCIDRs := selectorValuesFromRaw(rawSelectors.DestinationCIDRs)
+	rule.DestinationCIDRs, err = normalizeCIDRs(destinationCIDRs, "destination")
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	excludedDestinationCIDRs := selectorValuesFromRaw(rawSelectors.ExcludedDestinationCIDRs)
+	rule.ExcludedDestinationCIDRs, err = normalizeCIDRs(excludedDestinationCIDRs, "excluded destination")
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	destinationPorts := append([]PortRange(nil), raw.DestinationPorts...)
+	if len(destinationPorts) == 0 {
+		destinationPorts, err = parsePortSelectorStrings(selectorValuesFromRaw(rawSelectors.DestinationPorts))
+		if err != nil {
+			return RoutingRule{}, err
+		}
+	}
+	rule.DestinationPorts, err = normalizePorts(destinationPorts)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	excludedDestinationPorts := append([]PortRange(nil), raw.ExcludedDestinationPorts...)
+	if len(excludedDestinationPorts) == 0 {
+		excludedDestinationPorts, err = parsePortSelectorStrings(selectorValuesFromRaw(rawSelectors.ExcludedDestinationPorts))
+		if err != nil {
+			return RoutingRule{}, err
+		}
+	}
+	rule.ExcludedDestinationPorts, err = normalizePorts(excludedDestinationPorts)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	destinationASNs := selectorValuesFromRaw(rawSelectors.DestinationASNs)
+	rule.DestinationASNs, err = normalizeASNs(destinationASNs)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	excludedDestinationASNs := selectorValuesFromRaw(rawSelectors.ExcludedDestinationASNs)
+	rule.ExcludedDestinationASNs, err = normalizeASNs(excludedDestinationASNs)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	domains := selectorValuesFromRaw(rawSelectors.Domains)
+	rule.Domains, err = normalizeDomains(domains, false)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	wildcards := selectorValuesFromRaw(rawSelectors.WildcardDomains)
+	rule.WildcardDomains, err = normalizeDomains(wildcards, true)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	staticHosts := append([]StaticHostMapping(nil), raw.StaticHosts...)
+	if len(staticHosts) == 0 {
+		staticHosts, err = parseStaticHostSelectorStrings(selectorValuesFromRaw(rawSelectors.StaticHosts))
+		if err != nil {
+			return RoutingRule{}, err
+		}
+	}
+	rule.StaticHosts, err = normalizeStaticHosts(staticHosts, rule)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	rule.ExcludeMulticast = boolPointer(true)
+	if raw.ExcludeMulticast != nil {
+		rule.ExcludeMulticast = boolPointer(*raw.ExcludeMulticast)
+	}
+	rule.UploadLimitKbit, err = normalizeRateLimit(raw.UploadLimitKbit, "upload", idx)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	rule.DownloadLimitKbit, err = normalizeRateLimit(raw.DownloadLimitKbit, "download", idx)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	rule.MonitorOnly = raw.MonitorOnly
+	rule.Notes, err = normalizeNotes(raw.Notes, fmt.Sprintf("rule %d", idx+1))
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	rule.Tags, err = normalizeTags(raw.Tags, fmt.Sprintf("rule %d", idx+1))
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	if raw.ExpiresAt < 0 {
+		return RoutingRule{}, fmt.Errorf("%w: rule %d expiry must not be negative", ErrGroupValidation, idx+1)
+	}
+	rule.ExpiresAt = raw.ExpiresAt
+	rawSelectors = finalizeRuleRawSelectors(rawSelectors, rule)
+	if !ruleHasSelectors(rule) && !rawSelectors.hasAnyLine() {
+		return RoutingRule{}, fmt.Errorf(
+			"%w: rule %d must include at least one selector or comment line",
+			ErrGroupValidation,
+			idx+1,
+		)
+	}
+	rule.RawSelectors = &rawSelectors
+	return rule, nil
+}
+
+func legacyDomainsFromRules(rules []RoutingRule) []string {
+	seen := make(map[string]struct{})
+	out := make([]string, 0)
+	for _, rule := range rules {
+		for _, domain := range rule.Domains {
+			if _, exists := seen[domain]; exists {
+				continue
+			}
+			seen[domain] = struct{}{}
+			out = append(out, domain)
+		}
+		for _, wildcard := range rule.WildcardDomains {
+			if _, exists := seen[wildcard]; exists {
+				continue
+			}
+			seen[wildcard] = struct{}{}
+			out = append(out, wildcard)
+		}
+	}
+	return out
+}
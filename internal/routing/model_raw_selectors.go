@@ -12,6 +12,7 @@ func (raw RuleRawSelectors) hasAnyLine() bool {
 		raw.SourceCIDRs,
 		raw.ExcludedSourceCIDRs,
 		raw.SourceMACs,
+		raw.SourceDeviceGroups,
 		raw.DestinationCIDRs,
 		raw.ExcludedDestinationCIDRs,
 		raw.DestinationPorts,
@@ -20,6 +21,7 @@ func (raw RuleRawSelectors) hasAnyLine() bool {
 		raw.ExcludedDestinationASNs,
 		raw.Domains,
 		raw.WildcardDomains,
+		raw.StaticHosts,
 	} {
 		for _, line := range list {
 			if strings.TrimSpace(line) != "" {
@@ -39,6 +41,7 @@ func normalizeRuleRawSelectors(in *RuleRawSelectors) RuleRawSelectors {
 		SourceCIDRs:              normalizeRawLines(in.SourceCIDRs),
 		ExcludedSourceCIDRs:      normalizeRawLines(in.ExcludedSourceCIDRs),
 		SourceMACs:               normalizeRawLines(in.SourceMACs),
+		SourceDeviceGroups:       normalizeRawLines(in.SourceDeviceGroups),
 		DestinationCIDRs:         normalizeRawLines(in.DestinationCIDRs),
 		ExcludedDestinationCIDRs: normalizeRawLines(in.ExcludedDestinationCIDRs),
 		DestinationPorts:         normalizeRawLines(in.DestinationPorts),
@@ -47,6 +50,7 @@ func normalizeRuleRawSelectors(in *RuleRawSelectors) RuleRawSelectors {
 		ExcludedDestinationASNs:  normalizeRawLines(in.ExcludedDestinationASNs),
 		Domains:                  normalizeRawLines(in.Domains),
 		WildcardDomains:          normalizeRawLines(in.WildcardDomains),
+		StaticHosts:              normalizeRawLines(in.StaticHosts),
 	}
 }
 
@@ -63,6 +67,9 @@ func hydrateRuleRawSelectorsFromRule(rawSelectors RuleRawSelectors, rule Routing
 	if len(rawSelectors.SourceMACs) == 0 {
 		rawSelectors.SourceMACs = cloneSelectorLines(rule.SourceMACs)
 	}
+	if len(rawSelectors.SourceDeviceGroups) == 0 {
+		rawSelectors.SourceDeviceGroups = cloneSelectorLines(rule.SourceDeviceGroups)
+	}
 	if len(rawSelectors.DestinationCIDRs) == 0 {
 		rawSelectors.DestinationCIDRs = cloneSelectorLines(rule.DestinationCIDRs)
 	}
@@ -87,6 +94,9 @@ func hydrateRuleRawSelectorsFromRule(rawSelectors RuleRawSelectors, rule Routing
 	if len(rawSelectors.WildcardDomains) == 0 {
 		rawSelectors.WildcardDomains = cloneSelectorLines(rule.WildcardDomains)
 	}
+	if len(rawSelectors.StaticHosts) == 0 {
+		rawSelectors.StaticHosts = formatStaticHostSelectorLines(rule.StaticHosts)
+	}
 	return rawSelectors
 }
 
@@ -103,6 +113,9 @@ func finalizeRuleRawSelectors(raw RuleRawSelectors, rule RoutingRule) RuleRawSel
 	if len(raw.SourceMACs) == 0 {
 		raw.SourceMACs = cloneSelectorLines(rule.SourceMACs)
 	}
+	if len(raw.SourceDeviceGroups) == 0 {
+		raw.SourceDeviceGroups = cloneSelectorLines(rule.SourceDeviceGroups)
+	}
 	if len(raw.DestinationCIDRs) == 0 {
 		raw.DestinationCIDRs = cloneSelectorLines(rule.DestinationCIDRs)
 	}
@@ -127,6 +140,9 @@ func finalizeRuleRawSelectors(raw RuleRawSelectors, rule RoutingRule) RuleRawSel
 	if len(raw.WildcardDomains) == 0 {
 		raw.WildcardDomains = cloneSelectorLines(rule.WildcardDomains)
 	}
+	if len(raw.StaticHosts) == 0 {
+		raw.StaticHosts = formatStaticHostSelectorLines(rule.StaticHosts)
+	}
 	return raw
 }
 
@@ -248,3 +264,40 @@ func parsePortSelectorStrings(values []string) ([]PortRange, error) {
 	}
 	return out, nil
 }
+
+func formatStaticHostSelectorLines(hosts []StaticHostMapping) []string {
+	if len(hosts) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		domain := strings.TrimSpace(host.Domain)
+		if domain == "" || len(host.CIDRs) == 0 {
+			continue
+		}
+		out = append(out, domain+" "+strings.Join(host.CIDRs, " "))
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// parseStaticHostSelectorStrings reads "domain ip/cidr [ip/cidr...]" lines;
+// addresses may be separated by spaces or commas.
+func parseStaticHostSelectorStrings(values []string) ([]StaticHostMapping, error) {
+	out := make([]StaticHostMapping, 0, len(values))
+	for _, raw := range values {
+		fields := strings.FieldsFunc(raw, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%w: invalid static host selector %q: expected a domain followed by addresses", ErrGroupValidation, raw)
+		}
+		out = append(out, StaticHostMapping{Domain: fields[0], CIDRs: fields[1:]})
+	}
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package routing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+const (
+	setPrefix       = "svpn_"
+	setSuffixV4     = "_v4"
+	setSuffixV6     = "_v6"
+	maxIPSetNameLen = 31
+)
+
+// GroupSetNames derives deterministic ipset names for a group.
+func GroupSetNames(groupName string) (string, string) {
+	rule := RuleSetNames(groupName, 0)
+	return rule.DestinationV4, rule.DestinationV6
+}
+
+// RuleSetPair is deterministic per-group per-rule source+destination ipset names.
+type RuleSetPair struct {
+	SourceV4              string
+	SourceV6              string
+	ExcludedSourceV4      string
+	ExcludedSourceV6      string
+	SourceDeviceV4        string
+	SourceDeviceV6        string
+	DestinationV4         string
+	DestinationV6         string
+	ExcludedDestinationV4 string
+	ExcludedDestinationV6 string
+}
+
+// RuleSetNames returns deterministic source/destination set names for one rule.
+func RuleSetNames(groupName string, ruleIndex int) RuleSetPair {
+	base := normalizeSetBase(groupName)
+	if ruleIndex < 0 {
+		ruleIndex = 0
+	}
+	seed := strings.ToLower(fmt.Sprintf("%s:%d", groupName, ruleIndex))
+	return RuleSetPair{
+		SourceV4:              compactSetName(base, fmt.Sprintf("r%ds4", ruleIndex+1), seed+":src4"),
+		SourceV6:              compactSetName(base, fmt.Sprintf("r%ds6", ruleIndex+1), seed+":src6"),
+		ExcludedSourceV4:      compactSetName(base, fmt.Sprintf("r%dxs4", ruleIndex+1), seed+":xsrc4"),
+		ExcludedSourceV6:      compactSetName(base, fmt.Sprintf("r%dxs6", ruleIndex+1), seed+":xsrc6"),
+		SourceDeviceV4:        compactSetName(base, fmt.Sprintf("r%dg4", ruleIndex+1), seed+":dev4"),
+		SourceDeviceV6:        compactSetName(base, fmt.Sprintf("r%dg6", ruleIndex+1), seed+":dev6"),
+		DestinationV4:         compactSetName(base, fmt.Sprintf("r%dd4", ruleIndex+1), seed+":dst4"),
+		DestinationV6:         compactSetName(base, fmt.Sprintf("r%dd6", ruleIndex+1), seed+":dst6"),
+		ExcludedDestinationV4: compactSetName(base, fmt.Sprintf("r%dxd4", ruleIndex+1), seed+":xdst4"),
+		ExcludedDestinationV6: compactSetName(base, fmt.Sprintf("r%dxd6", ruleIndex+1), seed+":xdst6"),
+	}
+}
+
+func compactSetName(base, suffix, seed string) string {
+	name := setPrefix + base + "_" + suffix
+	if len(name) <= maxIPSetNameLen {
+		return name
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(seed))
+	hash := fmt.Sprintf("%08x", h.Sum32())
+	maxBase := maxIPSetNameLen - len(setPrefix) - len(suffix) - len(hash) - 2
+	if maxBase < 3 {
+		maxBase = 3
+	}
+	shortBase := base
+	if len(shortBase) > maxBase {
+		shortBase = shortBase[:maxBase]
+	}
+	return setPrefix + shortBase + "_" + hash + "_" + suffix
+}
+
+func normalizeSetBase(groupName string) string {
+	input := strings.ToLower(strings.TrimSpace(groupName))
+	if input == "" {
+		return "group"
+	}
+	builder := strings.Builder{}
+	builder.Grow(len(input))
+	lastUnderscore := false
+	for _, r := range input {
+		isAlnum := (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+		if isAlnum {
+			builder.WriteRune(r)
+			lastUnderscore = false
+			continue
+		}
+		if !lastUnderscore {
+			builder.WriteRune('_')
+			lastUnderscore = true
+		}
+	}
+	base := strings.Trim(builder.String(), "_")
+	if base == "" {
+		base = "group"
+	}
+	return base
+}
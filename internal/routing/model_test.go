@@ -2,6 +2,7 @@ package routing
 
 import (
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -134,3 +135,121 @@ func TestNormalizeAndValidateExclusionsDefaultMulticast(t *testing.T) {
 		t.Fatalf("expected excludeMulticast default to true")
 	}
 }
+
+func TestNormalizeAndValidateRejectsInvalidRateLimits(t *testing.T) {
+	for _, limit := range []int{-1, maxRateLimitKbit + 1} {
+		_, err := NormalizeAndValidate(DomainGroup{
+			Name:      "Capped",
+			EgressVPN: "wg-sgp",
+			Rules: []RoutingRule{{
+				SourceCIDRs:       []string{"10.0.0.0/24"},
+				DownloadLimitKbit: limit,
+			}},
+		})
+		if !errors.Is(err, ErrGroupValidation) || !strings.Contains(err.Error(), "download limit") {
+			t.Fatalf("expected download limit %d to be rejected, got %v", limit, err)
+		}
+	}
+}
+
+func TestNormalizeAndValidateStaticHosts(t *testing.T) {
+	group, err := NormalizeAndValidate(DomainGroup{
+		Name:      "Pinned",
+		EgressVPN: "wg-sgp",
+		Rules: []RoutingRule{
+			{
+				Name: "Rule 1",
+				RawSelectors: &RuleRawSelectors{
+					Domains:         []string{"api.example.com"},
+					WildcardDomains: []string{"*.apple.com"},
+					StaticHosts: []string{
+						"API.example.com 203.0.113.5, 198.51.100.0/24#office",
+						"api.example.com 203.0.113.5 2001:db8::1",
+						"apple.com 17.0.0.0/8",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeAndValidate failed: %v", err)
+	}
+	rule := group.Rules[0]
+	if len(rule.StaticHosts) != 2 {
+		t.Fatalf("unexpected static hosts: %#v", rule.StaticHosts)
+	}
+	if host := rule.StaticHosts[0]; host.Domain != "api.example.com" || strings.Join(host.CIDRs, ",") != "203.0.113.5/32,198.51.100.0/24,2001:db8::1/128" {
+		t.Fatalf("unexpected merged static host: %#v", host)
+	}
+	if host := rule.StaticHosts[1]; host.Domain != "*.apple.com" || strings.Join(host.CIDRs, ",") != "17.0.0.0/8" {
+		t.Fatalf("unexpected wildcard static host: %#v", host)
+	}
+	destinations := mergeResolvedDestinations(rule, nil)
+	if len(destinations) != 4 {
+		t.Fatalf("expected static hosts in destination entries, got %#v", destinations)
+	}
+
+	for _, line := range []string{"other.example.com 203.0.113.5", "api.example.com", "api.example.com not-an-ip"} {
+		_, err := NormalizeAndValidate(DomainGroup{
+			Name:      "Pinned",
+			EgressVPN: "wg-sgp",
+			Rules: []RoutingRule{{
+				Name:         "Rule 1",
+				RawSelectors: &RuleRawSelectors{Domains: []string{"api.example.com"}, StaticHosts: []string{line}},
+			}},
+		})
+		if !errors.Is(err, ErrGroupValidation) {
+			t.Fatalf("expected ErrGroupValidation for %q, got %v", line, err)
+		}
+	}
+}
+
+func TestNormalizeAndValidateInvertedDestinationsRequireSources(t *testing.T) {
+	_, err := NormalizeAndValidate(DomainGroup{
+		Name:               "Everything-but-Netflix",
+		EgressVPN:          "wg-sgp",
+		InvertDestinations: true,
+		Rules:              []RoutingRule{{Name: "no source", Domains: []string{"netflix.com"}}},
+	})
+	if !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected ErrGroupValidation, got %v", err)
+	}
+	group, err := NormalizeAndValidate(DomainGroup{
+		Name:               "Everything-but-Netflix",
+		EgressVPN:          "wg-sgp",
+		InvertDestinations: true,
+		Rules: []RoutingRule{{
+			Name:       "TV",
+			SourceMACs: []string{"00:30:93:10:0a:12"},
+			Domains:    []string{"netflix.com"},
+		}},
+	})
+	if err != nil || !group.InvertDestinations {
+		t.Fatalf("expected inverted group to validate, got %#v (%v)", group, err)
+	}
+}
+
+func TestNormalizeAndValidateFullTunnelRejectsDestinations(t *testing.T) {
+	for _, rule := range []RoutingRule{
+		{Name: "no source", ExcludedDestinationCIDRs: []string{"192.168.0.0/16"}},
+		{Name: "domain", SourceMACs: []string{"00:30:93:10:0a:12"}, Domains: []string{"netflix.com"}},
+	} {
+		_, err := NormalizeAndValidate(DomainGroup{Name: "Laptop", EgressVPN: "wg-sgp", FullTunnel: true, Rules: []RoutingRule{rule}})
+		if !errors.Is(err, ErrGroupValidation) {
+			t.Fatalf("expected ErrGroupValidation for %q, got %v", rule.Name, err)
+		}
+	}
+	group, err := NormalizeAndValidate(DomainGroup{
+		Name:       "Laptop",
+		EgressVPN:  "wg-sgp",
+		FullTunnel: true,
+		Rules: []RoutingRule{{
+			Name:                     "Laptop",
+			SourceMACs:               []string{"00:30:93:10:0a:12"},
+			ExcludedDestinationCIDRs: []string{"192.168.0.0/16"},
+		}},
+	})
+	if err != nil || !group.FullTunnel {
+		t.Fatalf("expected full tunnel group to validate, got %#v (%v)", group, err)
+	}
+}
@@ -0,0 +1,56 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxNotesLength = 4000
+	maxTagLength   = 32
+	maxTags        = 16
+)
+
+// normalizeNotes trims notes and bounds their length. owner names the group
+// or rule in errors.
+func normalizeNotes(raw, owner string) (string, error) {
+	notes := strings.TrimSpace(strings.ReplaceAll(raw, "\r\n", "\n"))
+	if utf8.RuneCountInString(notes) > maxNotesLength {
+		return "", fmt.Errorf("%w: %s notes must be at most %d characters", ErrGroupValidation, owner, maxNotesLength)
+	}
+	return notes, nil
+}
+
+// normalizeTags trims tags and drops empty and duplicate ones, compared
+// without case; the first spelling is kept. Tags are stored comma-separated,
+// so they cannot contain commas.
+func normalizeTags(raw []string, owner string) ([]string, error) {
+	seen := make(map[string]struct{}, len(raw))
+	out := make([]string, 0, len(raw))
+	for _, entry := range raw {
+		tag := strings.Join(strings.Fields(entry), " ")
+		if tag == "" {
+			continue
+		}
+		if strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("%w: %s tag %q cannot contain a comma", ErrGroupValidation, owner, tag)
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: %s tag %q is longer than %d characters", ErrGroupValidation, owner, tag, maxTagLength)
+		}
+		key := strings.ToLower(tag)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, tag)
+	}
+	if len(out) > maxTags {
+		return nil, fmt.Errorf("%w: %s can have at most %d tags", ErrGroupValidation, owner, maxTags)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
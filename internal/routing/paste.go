@@ -0,0 +1,218 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+// PasteReport classifies a pasted blob of destination selectors. Accepted
+// values are normalized; entries the rule already has, or that repeat within
+// the paste, are listed once under Duplicates and not appended again.
+type PasteReport struct {
+	Domains          []string     `json:"domains"`
+	WildcardDomains  []string     `json:"wildcardDomains"`
+	DestinationCIDRs []string     `json:"destinationCidrs"`
+	DestinationASNs  []string     `json:"destinationAsns"`
+	Duplicates       []string     `json:"duplicates"`
+	Invalid          []PasteIssue `json:"invalid"`
+	Comments         int          `json:"comments"`
+	// Applied is true when the accepted selectors were saved to the rule.
+	Applied bool `json:"applied"`
+}
+
+// PasteIssue explains why one pasted entry was rejected.
+type PasteIssue struct {
+	Line   int    `json:"line"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// Accepted returns how many new selectors the paste adds.
+func (r PasteReport) Accepted() int {
+	return len(r.Domains) + len(r.WildcardDomains) + len(r.DestinationCIDRs) + len(r.DestinationASNs)
+}
+
+// hostsFileAddresses are the sinkhole addresses that lead blocklist lines
+// such as "0.0.0.0 ads.example.com"; the address is dropped, not routed.
+var hostsFileAddresses = map[string]struct{}{
+	"0.0.0.0":   {},
+	"127.0.0.1": {},
+	"::":        {},
+	"::1":       {},
+}
+
+// ClassifySelectorPaste splits freeform text into domains, wildcards, IPs or
+// CIDRs and ASNs. Entries may be separated by newlines, commas or spaces;
+// "#" starts a comment. URLs contribute their host, a leading "." or "*."
+// makes a wildcard, and hosts-file lines contribute their names.
+func ClassifySelectorPaste(text string) PasteReport {
+	report := PasteReport{
+		Domains:          []string{},
+		WildcardDomains:  []string{},
+		DestinationCIDRs: []string{},
+		DestinationASNs:  []string{},
+		Duplicates:       []string{},
+		Invalid:          []PasteIssue{},
+	}
+	seen := make(map[string]struct{})
+	for idx, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			report.Comments++
+			continue
+		}
+		value, ok := activeSelectorValue(trimmed)
+		if !ok {
+			continue
+		}
+		tokens := strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\t'
+		})
+		if len(tokens) > 1 {
+			if _, ok := hostsFileAddresses[tokens[0]]; ok {
+				tokens = tokens[1:]
+			}
+		}
+		for _, token := range tokens {
+			kind, normalized, err := classifySelectorToken(token)
+			if err != nil {
+				report.Invalid = append(report.Invalid, PasteIssue{Line: idx + 1, Value: token, Reason: err.Error()})
+				continue
+			}
+			if _, exists := seen[normalized]; exists {
+				report.Duplicates = append(report.Duplicates, normalized)
+				continue
+			}
+			seen[normalized] = struct{}{}
+			switch kind {
+			case selectorDomains:
+				report.Domains = append(report.Domains, normalized)
+			case selectorWildcardDomains:
+				report.WildcardDomains = append(report.WildcardDomains, normalized)
+			case selectorDestinationCIDRs:
+				report.DestinationCIDRs = append(report.DestinationCIDRs, normalized)
+			case selectorDestinationASNs:
+				report.DestinationASNs = append(report.DestinationASNs, normalized)
+			}
+		}
+	}
+	return report
+}
+
+func classifySelectorToken(token string) (string, string, error) {
+	value := strings.TrimSpace(token)
+	if strings.Contains(value, "://") {
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Hostname() == "" {
+			return "", "", fmt.Errorf("not a valid URL")
+		}
+		value = parsed.Hostname()
+	}
+	upper := strings.ToUpper(value)
+	if strings.HasPrefix(upper, "AS") {
+		if number, err := strconv.Atoi(upper[2:]); err == nil {
+			if number <= 0 {
+				return "", "", fmt.Errorf("ASN must be a positive number")
+			}
+			return selectorDestinationASNs, "AS" + strconv.Itoa(number), nil
+		}
+	}
+	if canonical, err := canonicalCIDROrIP(value); err == nil {
+		return selectorDestinationCIDRs, canonical, nil
+	} else if strings.Contains(value, "/") || strings.Count(value, ":") > 1 {
+		return "", "", fmt.Errorf("invalid IP or CIDR: %v", err)
+	}
+	domain := strings.TrimSuffix(strings.ToLower(value), ".")
+	wildcard := false
+	switch {
+	case strings.HasPrefix(domain, "*."):
+		domain, wildcard = domain[2:], true
+	case strings.HasPrefix(domain, "."):
+		domain, wildcard = domain[1:], true
+	}
+	if err := vpn.ValidateDomain(domain); err != nil {
+		return "", "", err
+	}
+	if wildcard {
+		return selectorWildcardDomains, "*." + domain, nil
+	}
+	return selectorDomains, domain, nil
+}
+
+// PasteSelectors classifies a pasted blob and appends the accepted selectors
+// to one rule of a group in a single update and apply. With dryRun, or when
+// nothing new was accepted, the group is left unchanged.
+func (m *Manager) PasteSelectors(ctx context.Context, groupID int64, ruleIndex int, text string, dryRun bool) (*PasteReport, *DomainGroup, error) {
+	var (
+		report *PasteReport
+		group  *DomainGroup
+	)
+	err := m.commitGroupEdit(ctx, func() (bool, error) {
+		var err error
+		report, group, err = m.pasteSelectorsLocked(ctx, groupID, ruleIndex, text, dryRun)
+		return err == nil && report.Applied, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return report, group, nil
+}
+
+func (m *Manager) pasteSelectorsLocked(ctx context.Context, groupID int64, ruleIndex int, text string, dryRun bool) (*PasteReport, *DomainGroup, error) {
+	group, err := m.store.Get(ctx, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ruleIndex < 0 || ruleIndex >= len(group.Rules) {
+		return nil, nil, fmt.Errorf("%w: rule %d does not exist", ErrGroupValidation, ruleIndex+1)
+	}
+	rule := group.Rules[ruleIndex]
+	pasted := ClassifySelectorPaste(text)
+	report := pasted
+	report.Domains = dropExistingSelectors(pasted.Domains, rule.Domains, &report.Duplicates)
+	report.WildcardDomains = dropExistingSelectors(pasted.WildcardDomains, rule.WildcardDomains, &report.Duplicates)
+	report.DestinationCIDRs = dropExistingSelectors(pasted.DestinationCIDRs, rule.DestinationCIDRs, &report.Duplicates)
+	report.DestinationASNs = dropExistingSelectors(pasted.DestinationASNs, rule.DestinationASNs, &report.Duplicates)
+	if dryRun || report.Accepted() == 0 {
+		return &report, group, nil
+	}
+
+	raw := hydrateRuleRawSelectorsFromRule(normalizeRuleRawSelectors(rule.RawSelectors), rule)
+	raw.Domains = append(raw.Domains, report.Domains...)
+	raw.WildcardDomains = append(raw.WildcardDomains, report.WildcardDomains...)
+	raw.DestinationCIDRs = append(raw.DestinationCIDRs, report.DestinationCIDRs...)
+	raw.DestinationASNs = append(raw.DestinationASNs, report.DestinationASNs...)
+	rule.RawSelectors = &raw
+	group.Rules[ruleIndex] = rule
+
+	if err := m.validateGroupRefs(ctx, *group); err != nil {
+		return nil, nil, err
+	}
+	updated, err := m.store.Update(ctx, groupID, *group)
+	if err != nil {
+		return nil, nil, err
+	}
+	report.Applied = true
+	return &report, updated, nil
+}
+
+func dropExistingSelectors(values, existing []string, duplicates *[]string) []string {
+	known := make(map[string]struct{}, len(existing))
+	for _, value := range existing {
+		known[value] = struct{}{}
+	}
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if _, ok := known[value]; ok {
+			*duplicates = append(*duplicates, value)
+			continue
+		}
+		out = append(out, value)
+	}
+	return out
+}
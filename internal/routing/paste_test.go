@@ -0,0 +1,94 @@
+package routing
+
+import (
+	"context"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestClassifySelectorPasteSortsMixedEntries(t *testing.T) {
+	report := ClassifySelectorPaste(`# streaming
+netflix.com, *.nflxvideo.net
+.nflximg.net
+https://www.netflix.com/browse
+0.0.0.0 ads.example.com
+45.57.0.0/17 198.38.96.1 # edge
+AS2906
+netflix.com
+not_a domain!
+10.0.0.0/33
+`)
+	if len(report.Domains) != 3 || report.Domains[0] != "netflix.com" || report.Domains[1] != "www.netflix.com" || report.Domains[2] != "ads.example.com" {
+		t.Fatalf("unexpected domains: %#v", report.Domains)
+	}
+	if len(report.WildcardDomains) != 2 || report.WildcardDomains[0] != "*.nflxvideo.net" || report.WildcardDomains[1] != "*.nflximg.net" {
+		t.Fatalf("unexpected wildcards: %#v", report.WildcardDomains)
+	}
+	if len(report.DestinationCIDRs) != 2 || report.DestinationCIDRs[0] != "45.57.0.0/17" || report.DestinationCIDRs[1] != "198.38.96.1/32" {
+		t.Fatalf("unexpected cidrs: %#v", report.DestinationCIDRs)
+	}
+	if len(report.DestinationASNs) != 1 || report.DestinationASNs[0] != "AS2906" {
+		t.Fatalf("unexpected asns: %#v", report.DestinationASNs)
+	}
+	if len(report.Duplicates) != 1 || report.Duplicates[0] != "netflix.com" {
+		t.Fatalf("unexpected duplicates: %#v", report.Duplicates)
+	}
+	if report.Comments != 1 {
+		t.Fatalf("expected one comment line, got %d", report.Comments)
+	}
+	if len(report.Invalid) != 3 {
+		t.Fatalf("expected three invalid entries, got %#v", report.Invalid)
+	}
+	if report.Invalid[2].Line != 10 || report.Invalid[2].Value != "10.0.0.0/33" || report.Invalid[2].Reason == "" {
+		t.Fatalf("unexpected invalid entry: %#v", report.Invalid[2])
+	}
+}
+
+func TestManagerPasteSelectorsAppendsToRule(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, rules := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{{
+		Name:          "wg-sgp",
+		RouteTable:    201,
+		FWMark:        0x169,
+		InterfaceName: "wg-sgp",
+	}}})
+	group, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming-SG",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Domains: []string{"max.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+
+	report, _, err := manager.PasteSelectors(ctx, group.ID, 0, "max.com\nhbo.com\n1.2.3.0/24", true)
+	if err != nil {
+		t.Fatalf("PasteSelectors dry run failed: %v", err)
+	}
+	if report.Applied || report.Accepted() != 2 || len(report.Duplicates) != 1 {
+		t.Fatalf("unexpected dry run report: %#v", report)
+	}
+	if rules.applyCount != 1 {
+		t.Fatalf("expected dry run not to apply, got %d applies", rules.applyCount)
+	}
+
+	report, updated, err := manager.PasteSelectors(ctx, group.ID, 0, "max.com\nhbo.com\n1.2.3.0/24", false)
+	if err != nil {
+		t.Fatalf("PasteSelectors failed: %v", err)
+	}
+	if !report.Applied || rules.applyCount != 2 {
+		t.Fatalf("expected paste to apply once, report=%#v applies=%d", report, rules.applyCount)
+	}
+	rule := updated.Rules[0]
+	if len(rule.Domains) != 2 || rule.Domains[1] != "hbo.com" {
+		t.Fatalf("unexpected domains after paste: %#v", rule.Domains)
+	}
+	if len(rule.DestinationCIDRs) != 1 || rule.DestinationCIDRs[0] != "1.2.3.0/24" {
+		t.Fatalf("unexpected cidrs after paste: %#v", rule.DestinationCIDRs)
+	}
+
+	if _, _, err := manager.PasteSelectors(ctx, group.ID, 3, "hbo.com", false); err == nil {
+		t.Fatalf("expected error for unknown rule index")
+	}
+}
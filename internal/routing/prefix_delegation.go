@@ -0,0 +1,464 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const delegationPollInterval = 30 * time.Second
+
+// delegatedPrefixes maps a LAN interface to its current delegated IPv6
+// prefixes.
+type delegatedPrefixes map[string][]netip.Prefix
+
+// delegatedSelector is a source selector written as "<iface>+<suffix>[/len]",
+// e.g. "br0+::10" for host ::10 in whatever prefix br0 is currently
+// delegated, or "br0+::/64" for the whole delegated LAN prefix.
+type delegatedSelector struct {
+	iface  string
+	suffix netip.Addr
+	bits   int
+}
+
+func (s delegatedSelector) String() string {
+	return s.iface + "+" + s.suffix.String() + "/" + strconv.Itoa(s.bits)
+}
+
+// parseDelegatedSelector parses an interface+host-suffix selector. ok is
+// false when value does not use the selector syntax at all.
+func parseDelegatedSelector(value string) (delegatedSelector, bool, error) {
+	iface, suffix, found := strings.Cut(strings.TrimSpace(value), "+")
+	if !found {
+		return delegatedSelector{}, false, nil
+	}
+	iface = strings.ToLower(strings.TrimSpace(iface))
+	if !ifaceNamePattern.MatchString(iface) {
+		return delegatedSelector{}, true, fmt.Errorf("invalid interface %q", iface)
+	}
+	bits := 128
+	if rawAddr, rawBits, hasBits := strings.Cut(suffix, "/"); hasBits {
+		parsed, err := strconv.Atoi(strings.TrimSpace(rawBits))
+		if err != nil || parsed < 1 || parsed > 128 {
+			return delegatedSelector{}, true, fmt.Errorf("invalid prefix length %q", rawBits)
+		}
+		bits = parsed
+		suffix = rawAddr
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(suffix))
+	if err != nil || !addr.Is6() || addr.Is4In6() || addr.Zone() != "" {
+		return delegatedSelector{}, true, fmt.Errorf("host suffix must be an IPv6 address such as ::10")
+	}
+	return delegatedSelector{iface: iface, suffix: addr, bits: bits}, true, nil
+}
+
+// normalizeSourceSelectors canonicalizes source CIDRs, also accepting
+// interface+host-suffix selectors for delegated IPv6 prefixes and network
+// selectors for a UniFi network's subnets.
+func normalizeSourceSelectors(raw []string, label string) ([]string, error) {
+	plain := make([]string, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	out := make([]string, 0, len(raw))
+	for _, entry := range raw {
+		if network, isNetwork, err := parseNetworkSelector(entry); isNetwork {
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid %s selector %q: %v", ErrGroupValidation, label, entry, err)
+			}
+			canonical := network.String()
+			if _, exists := seen[canonical]; !exists {
+				seen[canonical] = struct{}{}
+				out = append(out, canonical)
+			}
+			continue
+		}
+		selector, isDelegated, err := parseDelegatedSelector(entry)
+		if !isDelegated {
+			plain = append(plain, entry)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %s selector %q: %v", ErrGroupValidation, label, entry, err)
+		}
+		canonical := selector.String()
+		if _, exists := seen[canonical]; !exists {
+			seen[canonical] = struct{}{}
+			out = append(out, canonical)
+		}
+	}
+	cidrs, err := normalizeCIDRs(plain, label)
+	if err != nil {
+		return nil, err
+	}
+	out = append(cidrs, out...)
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// delegatedInterfaces lists the interfaces referenced by delegated selectors.
+func delegatedInterfaces(values []string) []string {
+	names := make([]string, 0)
+	for _, value := range values {
+		if selector, ok, err := parseDelegatedSelector(value); ok && err == nil {
+			names = append(names, selector.iface)
+		}
+	}
+	return names
+}
+
+// expandDelegatedSelectors replaces interface+host-suffix selectors with
+// concrete prefixes under each interface's current delegation. Selectors
+// whose interface has no delegated prefix expand to nothing.
+func expandDelegatedSelectors(values []string, delegated delegatedPrefixes) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		selector, ok, err := parseDelegatedSelector(value)
+		if !ok {
+			out = append(out, value)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		for _, prefix := range delegated[selector.iface] {
+			out = append(out, applyHostSuffix(prefix, selector).String())
+		}
+	}
+	return out
+}
+
+// expandRuleSources returns rule with delegated and network source selectors
+// expanded. A rule whose networks resolved to nothing comes back without
+// selectors, so it matches nothing.
+func expandRuleSources(rule RoutingRule, delegated delegatedPrefixes, networks lanNetworks) RoutingRule {
+	rule, ok := expandRuleNetworks(rule, networks)
+	if !ok {
+		return RoutingRule{ID: rule.ID, Name: rule.Name}
+	}
+	rule.SourceCIDRs = expandDelegatedSelectors(rule.SourceCIDRs, delegated)
+	rule.ExcludedSourceCIDRs = expandDelegatedSelectors(rule.ExcludedSourceCIDRs, delegated)
+	return rule
+}
+
+// applyHostSuffix keeps the network bits of prefix and takes the remaining
+// bits from the selector's suffix.
+func applyHostSuffix(prefix netip.Prefix, selector delegatedSelector) netip.Prefix {
+	network := prefix.Masked().Addr().As16()
+	suffix := selector.suffix.As16()
+	var combined [16]byte
+	for i := range combined {
+		networkBits := prefix.Bits() - i*8
+		var mask byte
+		switch {
+		case networkBits >= 8:
+			mask = 0xff
+		case networkBits > 0:
+			mask = byte(0xff << (8 - networkBits))
+		}
+		combined[i] = network[i]&mask | suffix[i]&^mask
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(combined), selector.bits).Masked()
+}
+
+// groupDelegatedInterfaces lists the interfaces referenced by delegated
+// source selectors of groups and the canary proposal.
+func groupDelegatedInterfaces(groups []DomainGroup, canary *Canary) []string {
+	names := make([]string, 0)
+	collect := func(rules []RoutingRule) {
+		for _, rule := range rules {
+			names = append(names, delegatedInterfaces(rule.SourceCIDRs)...)
+			names = append(names, delegatedInterfaces(rule.ExcludedSourceCIDRs)...)
+		}
+	}
+	for _, group := range groups {
+		collect(group.Rules)
+	}
+	if canary != nil {
+		collect(canary.Proposed.Rules)
+	}
+	return dedupeSortedStrings(names)
+}
+
+// lookupDelegatedPrefixes reads the current prefixes of the given interfaces.
+func (m *Manager) lookupDelegatedPrefixes(ifaces []string) delegatedPrefixes {
+	lookup := m.prefixLookup
+	if lookup == nil {
+		lookup = interfaceDelegatedPrefixes
+	}
+	out := make(delegatedPrefixes, len(ifaces))
+	for _, iface := range ifaces {
+		prefixes, err := lookup(iface)
+		if err != nil {
+			continue
+		}
+		out[iface] = prefixes
+	}
+	return out
+}
+
+// interfaceDelegatedPrefixes returns the global unicast IPv6 prefixes on an
+// interface. Unique local and link-local prefixes are not delegated and are
+// skipped.
+func interfaceDelegatedPrefixes(name string) ([]netip.Prefix, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	ula := netip.MustParsePrefix("fc00::/7")
+	prefixes := make([]netip.Prefix, 0, len(addrs))
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() != nil {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok || !ip.IsGlobalUnicast() || ula.Contains(ip) {
+			continue
+		}
+		bits, _ := ipNet.Mask.Size()
+		prefixes = append(prefixes, netip.PrefixFrom(ip, bits).Masked())
+	}
+	return sortedPrefixes(prefixes), nil
+}
+
+func sortedPrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	seen := make(map[netip.Prefix]struct{}, len(prefixes))
+	out := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if _, ok := seen[prefix]; ok {
+			continue
+		}
+		seen[prefix] = struct{}{}
+		out = append(out, prefix)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].String() < out[j].String() })
+	return out
+}
+
+// changedDelegations lists interfaces whose prefixes differ between two
+// snapshots.
+func changedDelegations(previous, current delegatedPrefixes) []string {
+	changed := make([]string, 0)
+	for iface, prefixes := range current {
+		if !equalPrefixes(previous[iface], prefixes) {
+			changed = append(changed, iface)
+		}
+	}
+	for iface, prefixes := range previous {
+		if _, ok := current[iface]; !ok && len(prefixes) > 0 {
+			changed = append(changed, iface)
+		}
+	}
+	return dedupeSortedStrings(changed)
+}
+
+func equalPrefixes(a, b []netip.Prefix) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DelegationChange reports re-applied source sets after a prefix delegation
+// changed, or the network selectors whose bridge or subnets moved.
+type DelegationChange struct {
+	Interfaces []string            `json:"interfaces"`
+	Prefixes   map[string][]string `json:"prefixes"`
+	Sets       []string            `json:"sets"`
+	Networks   []LANNetwork        `json:"networks,omitempty"`
+}
+
+// RefreshDelegatedPrefixes re-reads delegated prefixes of interfaces used by
+// source selectors and, when one changed since the last apply, reloads only
+// the source ipsets of the affected rules. A network selector that resolves
+// differently than at the last apply triggers a full apply instead, since
+// its bridge is matched by iptables rules. It returns nil when nothing
+// changed.
+func (m *Manager) RefreshDelegatedPrefixes(ctx context.Context) (*DelegationChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	plan, err := m.planLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if networks := changedNetworks(m.networks, plan.networks); len(networks) > 0 {
+		change := &DelegationChange{Interfaces: []string{}, Prefixes: map[string][]string{}, Sets: []string{}}
+		for _, selector := range networks {
+			if network, ok := plan.networks[selector]; ok {
+				change.Networks = append(change.Networks, network)
+			} else {
+				change.Networks = append(change.Networks, LANNetwork{Selector: selector})
+			}
+		}
+		if err := m.applyLocked(ctx); err != nil {
+			return nil, err
+		}
+		return change, nil
+	}
+	changed := changedDelegations(m.delegated, plan.delegated)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	affected := make(map[string]struct{}, len(changed))
+	for _, iface := range changed {
+		affected[iface] = struct{}{}
+	}
+	references := func(values []string) bool {
+		for _, iface := range delegatedInterfaces(values) {
+			if _, ok := affected[iface]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	setNames := make([]string, 0)
+	queueRules := func(rules []RoutingRule, pairFor func(int) RuleSetPair) {
+		for ruleIndex, rule := range rules {
+			pair := pairFor(ruleIndex)
+			if references(rule.SourceCIDRs) {
+				setNames = append(setNames, pair.SourceV4, pair.SourceV6)
+			}
+			if references(rule.ExcludedSourceCIDRs) {
+				setNames = append(setNames, pair.ExcludedSourceV4, pair.ExcludedSourceV6)
+			}
+		}
+	}
+	for _, group := range plan.groups {
+		groupName := group.Name
+		queueRules(group.Rules, func(ruleIndex int) RuleSetPair { return RuleSetNames(groupName, ruleIndex) })
+	}
+	if plan.canary != nil {
+		groupID := plan.canary.GroupID
+		queueRules(plan.canary.Proposed.Rules, func(ruleIndex int) RuleSetPair { return canaryRuleSetNames(groupID, ruleIndex) })
+	}
+
+	change := &DelegationChange{Interfaces: changed, Prefixes: make(map[string][]string, len(changed)), Sets: make([]string, 0)}
+	for _, iface := range changed {
+		values := make([]string, 0, len(plan.delegated[iface]))
+		for _, prefix := range plan.delegated[iface] {
+			values = append(values, prefix.String())
+		}
+		change.Prefixes[iface] = values
+	}
+	for _, name := range dedupeSortedStrings(setNames) {
+		def, ok := plan.desiredSets[name]
+		if !ok {
+			continue
+		}
+		family, entries, err := desiredSetEntries(name, def)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.rebuildSet(name, family, entries, def.TimeoutSeconds); err != nil {
+			return nil, err
+		}
+		change.Sets = append(change.Sets, name)
+	}
+	m.delegated = plan.delegated
+	return change, nil
+}
+
+// DelegationWatcher polls LAN interfaces for IPv6 prefix delegation changes
+// and reloads the affected source ipsets.
+type DelegationWatcher struct {
+	manager *Manager
+
+	mu         sync.Mutex
+	started    bool
+	handler    func(DelegationChange, error)
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewDelegationWatcher creates a prefix delegation watcher.
+func NewDelegationWatcher(manager *Manager) (*DelegationWatcher, error) {
+	if manager == nil {
+		return nil, fmt.Errorf("routing manager is required")
+	}
+	return &DelegationWatcher{manager: manager}, nil
+}
+
+// SetHandler registers a callback for re-applied delegations and failures.
+func (w *DelegationWatcher) SetHandler(handler func(DelegationChange, error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handler = handler
+}
+
+// Start launches the polling loop.
+func (w *DelegationWatcher) Start() error {
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.started = true
+	w.loopCancel = cancel
+	w.mu.Unlock()
+
+	w.loopWG.Add(1)
+	go func() {
+		defer w.loopWG.Done()
+		ticker := time.NewTicker(delegationPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.Check(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the polling loop.
+func (w *DelegationWatcher) Stop() error {
+	w.mu.Lock()
+	loopCancel := w.loopCancel
+	w.started = false
+	w.loopCancel = nil
+	w.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	w.loopWG.Wait()
+	return nil
+}
+
+// Check runs one refresh and reports changes or failures to the handler.
+func (w *DelegationWatcher) Check(ctx context.Context) (*DelegationChange, error) {
+	change, err := w.manager.RefreshDelegatedPrefixes(ctx)
+	w.mu.Lock()
+	handler := w.handler
+	w.mu.Unlock()
+	if handler != nil && (change != nil || err != nil) {
+		var reported DelegationChange
+		if change != nil {
+			reported = *change
+		}
+		handler(reported, err)
+	}
+	return change, err
+}
@@ -0,0 +1,86 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestNormalizeSourceSelectorsAcceptsDelegatedSuffixes(t *testing.T) {
+	got, err := normalizeSourceSelectors([]string{"BR0+::10", "10.0.0.7", "br0+::/64", "br0+::10/128"}, "source")
+	if err != nil {
+		t.Fatalf("normalizeSourceSelectors failed: %v", err)
+	}
+	if strings.Join(got, ",") != "10.0.0.7/32,br0+::10/128,br0+::/64" {
+		t.Fatalf("unexpected normalized selectors: %#v", got)
+	}
+	for _, invalid := range []string{"br0+10.0.0.1", "br0+::1/0", "bad iface+::1", "br0+fe80::1%br0"} {
+		if _, err := normalizeSourceSelectors([]string{invalid}, "source"); !errors.Is(err, ErrGroupValidation) {
+			t.Fatalf("expected %q to fail validation, got %v", invalid, err)
+		}
+	}
+}
+
+func TestExpandDelegatedSelectorsUsesCurrentPrefix(t *testing.T) {
+	delegated := delegatedPrefixes{"br0": {netip.MustParsePrefix("2001:db8:1:2::/64")}}
+	got := expandDelegatedSelectors([]string{"br0+::10/128", "br0+::/64", "br0+::1:0:0:0:10/128", "br6+::10/128", "10.0.0.0/24"}, delegated)
+	want := "2001:db8:1:2::10/128,2001:db8:1:2::/64,2001:db8:1:2::10/128,10.0.0.0/24"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("unexpected expansion:\n got %s\nwant %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestRefreshDelegatedPrefixesReloadsAffectedSourceSets(t *testing.T) {
+	ctx := context.Background()
+	ipset := &MockIPSet{}
+	manager := newRoutingTestManagerWithDeps(t, ipset, &mockDNSManager{}, &mockRuleApplier{}, &mockVPNLister{
+		profiles: []*vpn.VPNProfile{{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"}},
+	})
+	current := netip.MustParsePrefix("2001:db8:1:2::/64")
+	manager.prefixLookup = func(iface string) ([]netip.Prefix, error) {
+		if iface != "br0" {
+			return nil, errors.New("no such interface")
+		}
+		return []netip.Prefix{current}, nil
+	}
+	if _, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Lab",
+		EgressVPN: "wg-sgp",
+		Rules: []RoutingRule{
+			{Name: "printer", SourceCIDRs: []string{"br0+::10"}, Domains: []string{"example.com"}},
+			{Name: "static", SourceCIDRs: []string{"10.0.0.0/24"}, Domains: []string{"example.org"}},
+		},
+	}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	delegatedPair := RuleSetNames("Lab", 0)
+	if got := strings.Join(ipset.IPs[delegatedPair.SourceV6], ","); got != "2001:db8:1:2::10/128" {
+		t.Fatalf("expected source set to use the current prefix, got %q", got)
+	}
+
+	if change, err := manager.RefreshDelegatedPrefixes(ctx); err != nil || change != nil {
+		t.Fatalf("expected no change before the delegation moves, got %+v, %v", change, err)
+	}
+
+	current = netip.MustParsePrefix("2001:db8:9:2::/64")
+	change, err := manager.RefreshDelegatedPrefixes(ctx)
+	if err != nil {
+		t.Fatalf("RefreshDelegatedPrefixes failed: %v", err)
+	}
+	if change == nil || strings.Join(change.Interfaces, ",") != "br0" || strings.Join(change.Prefixes["br0"], ",") != "2001:db8:9:2::/64" {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	if strings.Join(change.Sets, ",") != strings.Join(dedupeSortedStrings([]string{delegatedPair.SourceV4, delegatedPair.SourceV6}), ",") {
+		t.Fatalf("expected only the delegated rule's source sets to reload, got %#v", change.Sets)
+	}
+	if got := strings.Join(ipset.IPs[delegatedPair.SourceV6], ","); got != "2001:db8:9:2::10/128" {
+		t.Fatalf("expected source set to follow the new prefix, got %q", got)
+	}
+	if change, err := manager.RefreshDelegatedPrefixes(ctx); err != nil || change != nil {
+		t.Fatalf("expected refresh to be idempotent, got %+v, %v", change, err)
+	}
+}
@@ -0,0 +1,298 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+const (
+	provisionPollInterval = 10 * time.Second
+	// udapiStatePath is rewritten by ubios-udapi-server on every controller
+	// provisioning push.
+	udapiStatePath = "/data/udapi-config/ubios-udapi-server/ubios-udapi-server.state"
+)
+
+// ProvisionEvent describes one detected UniFi reprovisioning and the repair
+// that followed it.
+type ProvisionEvent struct {
+	DetectedAt   time.Time `json:"detectedAt"`
+	Reasons      []string  `json:"reasons"`
+	RepairedAt   time.Time `json:"repairedAt"`
+	RepairError  string    `json:"repairError,omitempty"`
+	RelinkError  string    `json:"relinkError,omitempty"`
+	ChainsLinked []string  `json:"chainsLinked,omitempty"`
+}
+
+// provisionFingerprint is the state compared between polls.
+type provisionFingerprint struct {
+	udapiState string
+	interfaces string
+	links      map[string]struct{}
+}
+
+// ProvisionWatcher re-applies routing after UniFi network reprovisioning.
+// It polls for a rewritten udapi state file, churn of non-VPN interfaces and
+// dropped chain jumps, waits until a poll sees no further change so the
+// whole push has landed, then relinks chains and runs a full Apply.
+type ProvisionWatcher struct {
+	manager  *Manager
+	settings *settings.Manager
+	now      func() time.Time
+
+	statePath  string
+	interfaces func() ([]net.Interface, error)
+
+	mu         sync.Mutex
+	started    bool
+	baseline   *provisionFingerprint
+	pending    []string
+	pendingAt  time.Time
+	last       *ProvisionEvent
+	handler    func(ProvisionEvent)
+	repair     func(ctx context.Context) error
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewProvisionWatcher creates a watcher that repairs with manager.Apply.
+func NewProvisionWatcher(manager *Manager, settingsManager *settings.Manager) (*ProvisionWatcher, error) {
+	if manager == nil {
+		return nil, fmt.Errorf("routing manager is required")
+	}
+	if settingsManager == nil {
+		return nil, fmt.Errorf("settings manager is required")
+	}
+	return &ProvisionWatcher{
+		manager:    manager,
+		settings:   settingsManager,
+		now:        time.Now,
+		statePath:  udapiStatePath,
+		interfaces: net.Interfaces,
+		repair:     manager.Apply,
+	}, nil
+}
+
+// SetHandler registers a callback invoked after every repair.
+func (w *ProvisionWatcher) SetHandler(handler func(ProvisionEvent)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handler = handler
+}
+
+// SetRepairer replaces the function used to re-apply routing state.
+func (w *ProvisionWatcher) SetRepairer(repair func(ctx context.Context) error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if repair != nil {
+		w.repair = repair
+	}
+}
+
+// Last returns the most recent provisioning event, or nil if none was seen.
+func (w *ProvisionWatcher) Last() *ProvisionEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.last == nil {
+		return nil
+	}
+	copied := *w.last
+	return &copied
+}
+
+// Start launches the polling loop.
+func (w *ProvisionWatcher) Start() error {
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.started = true
+	w.loopCancel = cancel
+	w.mu.Unlock()
+
+	w.loopWG.Add(1)
+	go func() {
+		defer w.loopWG.Done()
+		ticker := time.NewTicker(provisionPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.Poll(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the polling loop.
+func (w *ProvisionWatcher) Stop() error {
+	w.mu.Lock()
+	loopCancel := w.loopCancel
+	w.started = false
+	w.loopCancel = nil
+	w.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	w.loopWG.Wait()
+	return nil
+}
+
+// Poll runs one detection step. It returns the repair event when this poll
+// repaired routing, and nil otherwise.
+func (w *ProvisionWatcher) Poll(ctx context.Context) *ProvisionEvent {
+	current := w.fingerprint()
+	enabled := true
+	if loaded, err := w.settings.Get(); err == nil && loaded.ProvisionWatchEnabled != nil {
+		enabled = *loaded.ProvisionWatchEnabled
+	}
+
+	w.mu.Lock()
+	previous := w.baseline
+	w.baseline = &current
+	if !enabled || previous == nil {
+		w.pending = nil
+		w.mu.Unlock()
+		return nil
+	}
+	reasons := provisionChanges(*previous, current)
+	if len(reasons) > 0 {
+		// Still changing; wait for the push to settle before repairing.
+		if len(w.pending) == 0 {
+			w.pendingAt = w.now().UTC()
+		}
+		w.pending = dedupeSortedStrings(append(w.pending, reasons...))
+		w.mu.Unlock()
+		return nil
+	}
+	if len(w.pending) == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	event := ProvisionEvent{DetectedAt: w.pendingAt, Reasons: w.pending}
+	w.pending = nil
+	repair := w.repair
+	w.mu.Unlock()
+
+	if err := w.manager.RelinkChains(); err != nil {
+		event.RelinkError = err.Error()
+	}
+	if err := repair(ctx); err != nil {
+		event.RepairError = err.Error()
+	}
+	event.RepairedAt = w.now().UTC()
+
+	// Our own repair relinks chains; re-baseline so it is not seen as churn.
+	repaired := w.fingerprint()
+	event.ChainsLinked = sortedKeys(repaired.links)
+	w.mu.Lock()
+	w.baseline = &repaired
+	w.last = &event
+	handler := w.handler
+	w.mu.Unlock()
+	if handler != nil {
+		handler(event)
+	}
+	return &event
+}
+
+func (w *ProvisionWatcher) fingerprint() provisionFingerprint {
+	fp := provisionFingerprint{links: map[string]struct{}{}}
+	if info, err := os.Stat(w.statePath); err == nil {
+		fp.udapiState = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+	}
+	if ifaces, err := w.interfaces(); err == nil {
+		vpnIfaces := w.manager.vpnInterfaceNames()
+		parts := make([]string, 0, len(ifaces))
+		for _, iface := range ifaces {
+			if _, isVPN := vpnIfaces[iface.Name]; isVPN {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%s:%d", iface.Name, iface.Index))
+		}
+		sort.Strings(parts)
+		fp.interfaces = strings.Join(parts, ",")
+	}
+	for _, link := range w.manager.LinkedChains() {
+		fp.links[link] = struct{}{}
+	}
+	return fp
+}
+
+// provisionChanges lists why current differs from previous. Chain links only
+// count when they disappear: links appearing are our own applies.
+func provisionChanges(previous, current provisionFingerprint) []string {
+	reasons := make([]string, 0)
+	if previous.udapiState != current.udapiState && current.udapiState != "" {
+		reasons = append(reasons, "udapi config rewritten")
+	}
+	if previous.interfaces != current.interfaces {
+		reasons = append(reasons, "network interfaces changed")
+	}
+	for link := range previous.links {
+		if _, ok := current.links[link]; !ok {
+			reasons = append(reasons, "chain jump removed: "+link)
+		}
+	}
+	return reasons
+}
+
+// LinkedChains lists installed built-in -> root chain jumps, or nil when the
+// rule applier cannot report them.
+func (m *Manager) LinkedChains() []string {
+	linker, ok := m.rules.(ChainLinker)
+	if !ok {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return linker.LinkedChains()
+}
+
+// RelinkChains restores dropped jumps into the managed chains without a full
+// apply, so traffic is routed again even if the following Apply fails.
+func (m *Manager) RelinkChains() error {
+	linker, ok := m.rules.(ChainLinker)
+	if !ok {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return linker.RelinkChains()
+}
+
+func (m *Manager) vpnInterfaceNames() map[string]struct{} {
+	names := make(map[string]struct{})
+	profiles, err := m.vpnLister.List()
+	if err != nil {
+		return names
+	}
+	for _, profile := range profiles {
+		if profile != nil && profile.InterfaceName != "" {
+			names[profile.InterfaceName] = struct{}{}
+		}
+	}
+	return names
+}
+
+func sortedKeys(values map[string]struct{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/vpn"
+)
+
+func newProvisionTestWatcher(t *testing.T, enabled bool) (*ProvisionWatcher, *MockExec, *[]net.Interface, *int) {
+	t.Helper()
+	exec := &MockExec{Outputs: map[string][]byte{
+		"iptables -t mangle -S SVPN_MARK": []byte("-N SVPN_MARK\n-A SVPN_MARK -j SVPN_MARK_A\n"),
+	}}
+	manager := newRoutingTestManagerWithDeps(t,
+		NewIPSetManager(exec),
+		NewDnsmasqManagerWithPath(filepath.Join(t.TempDir(), "split-vpn-webui.conf"), exec),
+		NewRuleManager(exec),
+		&mockVPNLister{profiles: []*vpn.VPNProfile{{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"}}},
+	)
+	settingsManager := settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	if err := settingsManager.Save(settings.Settings{ProvisionWatchEnabled: &enabled}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	watcher, err := NewProvisionWatcher(manager, settingsManager)
+	if err != nil {
+		t.Fatalf("NewProvisionWatcher failed: %v", err)
+	}
+	watcher.statePath = filepath.Join(t.TempDir(), "ubios-udapi-server.state")
+	ifaces := []net.Interface{{Index: 2, Name: "br0"}, {Index: 9, Name: "wg-sgp"}}
+	watcher.interfaces = func() ([]net.Interface, error) { return ifaces, nil }
+	repairs := 0
+	watcher.SetRepairer(func(context.Context) error {
+		repairs++
+		return nil
+	})
+	return watcher, exec, &ifaces, &repairs
+}
+
+func TestProvisionWatcherRepairsAfterChainJumpIsDropped(t *testing.T) {
+	ctx := context.Background()
+	watcher, exec, _, repairs := newProvisionTestWatcher(t, true)
+	var handled []ProvisionEvent
+	watcher.SetHandler(func(event ProvisionEvent) { handled = append(handled, event) })
+
+	if event := watcher.Poll(ctx); event != nil {
+		t.Fatalf("expected first poll to only record a baseline, got %+v", event)
+	}
+	exec.RunErrors = map[string]error{"iptables -t nat -C POSTROUTING -j SVPN_NAT": errors.New("missing")}
+	if event := watcher.Poll(ctx); event != nil {
+		t.Fatalf("expected repair to wait for provisioning to settle, got %+v", event)
+	}
+	event := watcher.Poll(ctx)
+	if event == nil || *repairs != 1 {
+		t.Fatalf("expected one repair once settled, got %+v (repairs=%d)", event, *repairs)
+	}
+	if strings.Join(event.Reasons, ",") != "chain jump removed: iptables/nat POSTROUTING->SVPN_NAT" {
+		t.Fatalf("unexpected reasons: %#v", event.Reasons)
+	}
+	relinked := false
+	for _, call := range exec.RunCalls {
+		if strings.Join(call, " ") == "iptables -t nat -A POSTROUTING -j SVPN_NAT" {
+			relinked = true
+		}
+	}
+	if !relinked {
+		t.Fatalf("expected NAT chain to be relinked, got %#v", exec.RunCalls)
+	}
+	if len(handled) != 1 || watcher.Last() == nil {
+		t.Fatalf("expected handler and Last to see the repair, got %+v", handled)
+	}
+	if event := watcher.Poll(ctx); event != nil || *repairs != 1 {
+		t.Fatalf("expected no further repair without changes, got %+v", event)
+	}
+}
+
+func TestProvisionWatcherDetectsUDAPIRewriteAndIgnoresVPNChurn(t *testing.T) {
+	ctx := context.Background()
+	watcher, _, ifaces, repairs := newProvisionTestWatcher(t, true)
+
+	watcher.Poll(ctx)
+	(*ifaces)[1].Index = 12
+	watcher.Poll(ctx)
+	if event := watcher.Poll(ctx); event != nil || *repairs != 0 {
+		t.Fatalf("expected VPN interface churn to be ignored, got %+v", event)
+	}
+
+	if err := os.WriteFile(watcher.statePath, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	watcher.Poll(ctx)
+	// A second write during the same push keeps the repair pending.
+	if err := os.Chtimes(watcher.statePath, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("touch state: %v", err)
+	}
+	if event := watcher.Poll(ctx); event != nil {
+		t.Fatalf("expected repair to wait while udapi keeps writing, got %+v", event)
+	}
+	event := watcher.Poll(ctx)
+	if event == nil || *repairs != 1 || strings.Join(event.Reasons, ",") != "udapi config rewritten" {
+		t.Fatalf("expected one repair for the udapi rewrite, got %+v (repairs=%d)", event, *repairs)
+	}
+}
+
+func TestProvisionWatcherDisabledOnlyTracksBaseline(t *testing.T) {
+	ctx := context.Background()
+	watcher, _, ifaces, repairs := newProvisionTestWatcher(t, false)
+
+	watcher.Poll(ctx)
+	(*ifaces)[0].Index = 5
+	watcher.Poll(ctx)
+	if event := watcher.Poll(ctx); event != nil || *repairs != 0 {
+		t.Fatalf("expected disabled watcher not to repair, got %+v", event)
+	}
+}
@@ -0,0 +1,91 @@
+package routing
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"strings"
+)
+
+// writeRemoteFileScript replaces $2 atomically with stdin, creating its
+// directory $1 first.
+const writeRemoteFileScript = `mkdir -p "$1" && cat > "$2.tmp" && chmod 644 "$2.tmp" && mv "$2.tmp" "$2"`
+
+// Remote returns a manager that converges the same groups on another device,
+// running every ipset, iptables, ip rule and dnsmasq command through exec.
+// It shares this manager's store and VPN profiles, so the remote must run
+// tunnels with the same interface names, marks and tables. The remote's
+// dnsmasq config is written into dnsmasqConfDir there. Remote managers skip
+// isolated-group dnsmasq fragments, canaries and delegated-prefix expansion,
+// which depend on local state; network selectors resolve to the remote's
+// VLAN bridge by UniFi naming, but not to subnets.
+func (m *Manager) Remote(exec Executor, dnsmasqConfDir string) (*Manager, error) {
+	if exec == nil {
+		return nil, fmt.Errorf("remote executor is required")
+	}
+	dir := strings.TrimSpace(dnsmasqConfDir)
+	if !path.IsAbs(dir) {
+		return nil, fmt.Errorf("remote dnsmasq directory must be an absolute path")
+	}
+	remote, err := NewManagerWithDeps(
+		m.store,
+		NewIPSetManager(exec),
+		&remoteDnsmasq{
+			local: NewDnsmasqManagerWithPath(path.Join(dir, dnsmasqConfigFileName), exec),
+			exec:  exec,
+		},
+		NewRuleManager(exec),
+		m.vpnLister,
+	)
+	if err != nil {
+		return nil, err
+	}
+	remote.prefixLookup = func(string) ([]netip.Prefix, error) {
+		return nil, fmt.Errorf("prefix delegation is not read from remote devices")
+	}
+	remote.networkDirectory = m.networkDirectory
+	remote.networkLookup = func(vlan int) (string, []netip.Prefix, error) {
+		return bridgeForVLAN(vlan), nil, nil
+	}
+	return remote, nil
+}
+
+// Clear removes every managed rule, set and dnsmasq entry from the device,
+// e.g. before a remote is forgotten.
+func (m *Manager) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.rules.FlushRules(); err != nil {
+		return err
+	}
+	if err := m.cleanupStaleSets(map[string]struct{}{}); err != nil {
+		return err
+	}
+	if err := m.dnsmasq.WriteDnsmasqConf(""); err != nil {
+		return err
+	}
+	return m.dnsmasq.ReloadDnsmasq()
+}
+
+// remoteDnsmasq generates config like the local manager but writes it
+// through the executor instead of the local filesystem.
+type remoteDnsmasq struct {
+	local *DnsmasqManager
+	exec  Executor
+}
+
+func (r *remoteDnsmasq) GenerateDnsmasqConf(groups []DomainGroup) string {
+	return r.local.GenerateDnsmasqConf(groups)
+}
+
+func (r *remoteDnsmasq) WriteDnsmasqConf(content string) error {
+	target := r.local.ConfigPath()
+	if err := r.exec.RunWithInput([]byte(content), "sh", "-c", writeRemoteFileScript, "sh", path.Dir(target), target); err != nil {
+		return fmt.Errorf("write remote dnsmasq config: %w", err)
+	}
+	return nil
+}
+
+func (r *remoteDnsmasq) ReloadDnsmasq() error {
+	return r.local.ReloadDnsmasq()
+}
@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestRemoteManagerAppliesThroughExecutor(t *testing.T) {
+	ctx := context.Background()
+	manager, localIPSet, _, _ := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{{
+		Name:          "wg-sgp",
+		RouteTable:    201,
+		FWMark:        0x169,
+		InterfaceName: "wg-sgp",
+	}}})
+	if _, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming-SG",
+		EgressVPN: "wg-sgp",
+		Rules: []RoutingRule{{
+			Domains:          []string{"max.com"},
+			DestinationCIDRs: []string{"203.0.113.0/24"},
+		}},
+	}); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	localCalls := len(localIPSet.Sets)
+
+	if _, err := manager.Remote(&MockExec{}, "relative/dir"); err == nil {
+		t.Fatalf("expected relative dnsmasq directory to be rejected")
+	}
+	exec := &MockExec{Outputs: map[string][]byte{"ipset list -name": {}}}
+	remote, err := manager.Remote(exec, "/etc/dnsmasq.d")
+	if err != nil {
+		t.Fatalf("remote: %v", err)
+	}
+	if err := remote.Apply(ctx); err != nil {
+		t.Fatalf("remote apply: %v", err)
+	}
+	if len(localIPSet.Sets) != localCalls {
+		t.Fatalf("remote apply touched local sets")
+	}
+
+	var sawIPSet, sawIPTables bool
+	confIndex := -1
+	for i, call := range exec.RunCalls {
+		switch call[0] {
+		case "ipset":
+			sawIPSet = true
+		case "iptables", "iptables-restore":
+			sawIPTables = true
+		case "sh":
+			if call[len(call)-1] == "/etc/dnsmasq.d/split-vpn-webui.conf" {
+				confIndex = i
+			}
+		}
+	}
+	if !sawIPSet || !sawIPTables {
+		t.Fatalf("expected ipset and iptables commands on the remote, got %v", exec.RunCalls)
+	}
+	if confIndex < 0 {
+		t.Fatalf("expected dnsmasq config to be written on the remote, got %v", exec.RunCalls)
+	}
+	if !strings.Contains(string(exec.Inputs[confIndex]), "max.com") {
+		t.Fatalf("expected remote dnsmasq config to list the group's domains, got %q", exec.Inputs[confIndex])
+	}
+
+	before := len(exec.RunCalls)
+	if err := remote.Clear(); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	cleared := false
+	for i := before; i < len(exec.RunCalls); i++ {
+		if exec.RunCalls[i][0] == "sh" && len(exec.Inputs[i]) == 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Fatalf("expected clear to empty the remote dnsmasq config, got %v", exec.RunCalls[before:])
+	}
+}
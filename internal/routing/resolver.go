@@ -2,11 +2,13 @@ package routing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"split-vpn-webui/internal/netbind"
 	"split-vpn-webui/internal/settings"
 )
 
@@ -42,6 +44,7 @@ type ResolverScheduler struct {
 	loopCancel      context.CancelFunc
 	runCancel       context.CancelFunc
 	progressHandler func(ResolverProgress)
+	limiters        map[string]*providerLimiter
 
 	loopWG sync.WaitGroup
 	runWG  sync.WaitGroup
@@ -76,9 +79,9 @@ func NewResolverScheduler(manager *Manager, settingsManager *settings.Manager) (
 	return &ResolverScheduler{
 		manager:          manager,
 		settings:         settingsManager,
-		domainResolver:   newDoHDomainResolver(resolverDomainTimeoutFromSettings(current)),
-		asnResolver:      newRIPEASNResolver(resolverASNTimeoutFromSettings(current)),
-		wildcardResolver: newCRTSHWildcardResolver(resolverWildcardTimeoutFromSettings(current)),
+		domainResolver:   newDoHDomainResolver(resolverDomainTimeoutFromSettings(current), netbind.Egress{}),
+		asnResolver:      newRIPEASNResolver(resolverASNTimeoutFromSettings(current), netbind.Egress{}),
+		wildcardResolver: newCRTSHWildcardResolver(resolverWildcardTimeoutFromSettings(current), netbind.Egress{}),
 		now:              time.Now,
 		defaultInterval:  resolverIntervalFromSettings(current),
 		lastRun:          lastRun,
@@ -171,6 +174,12 @@ func (s *ResolverScheduler) Stop() error {
 
 // TriggerNow starts one resolver run in the background.
 func (s *ResolverScheduler) TriggerNow() error {
+	return s.startRun(false)
+}
+
+// startRun starts a fresh run, or with resume one that skips checkpointed
+// selectors.
+func (s *ResolverScheduler) startRun(resume bool) error {
 	current, err := s.settings.Get()
 	if err != nil {
 		return err
@@ -182,7 +191,7 @@ func (s *ResolverScheduler) TriggerNow() error {
 		return ErrResolverRunInProgress
 	}
 	runCtx, runCancel := context.WithCancel(context.Background())
-	initial := ResolverProgress{StartedAt: s.now().Unix()}
+	initial := ResolverProgress{StartedAt: s.now().Unix(), Resumed: resume}
 	s.running = true
 	s.progress = &initial
 	s.runCancel = runCancel
@@ -190,7 +199,7 @@ func (s *ResolverScheduler) TriggerNow() error {
 	s.mu.Unlock()
 
 	s.emitProgress(initial)
-	go s.executeRun(runCtx, current)
+	go s.executeRun(runCtx, current, resume)
 	return nil
 }
 
@@ -208,6 +217,19 @@ func (s *ResolverScheduler) ClearCacheAndRun() error {
 	return s.TriggerNow()
 }
 
+// CancelRun stops the active resolver run while keeping periodic scheduling.
+func (s *ResolverScheduler) CancelRun() error {
+	s.mu.RLock()
+	running := s.running
+	runCancel := s.runCancel
+	s.mu.RUnlock()
+	if !running || runCancel == nil {
+		return ErrResolverRunNotActive
+	}
+	runCancel()
+	return nil
+}
+
 // Status returns live and historical resolver status.
 func (s *ResolverScheduler) Status(ctx context.Context) (ResolverStatus, error) {
 	s.mu.RLock()
@@ -229,9 +251,17 @@ func (s *ResolverScheduler) Status(ctx context.Context) (ResolverStatus, error)
 		}
 	}
 
+	checkpointed, err := s.manager.store.CountResolverCheckpoint(ctx)
+	if err != nil {
+		return ResolverStatus{}, err
+	}
 	status := ResolverStatus{
-		Running: running,
-		LastRun: cloneResolverRun(lastRun),
+		Running:   running,
+		LastRun:   cloneResolverRun(lastRun),
+		Providers: s.providerHealth(),
+	}
+	if !running {
+		status.CheckpointedSelectors = checkpointed
 	}
 	if progress != nil {
 		cloned := progress.Clone()
@@ -240,11 +270,17 @@ func (s *ResolverScheduler) Status(ctx context.Context) (ResolverStatus, error)
 	return status, nil
 }
 
-func (s *ResolverScheduler) executeRun(ctx context.Context, current settings.Settings) {
+func (s *ResolverScheduler) executeRun(ctx context.Context, current settings.Settings, resume bool) {
 	defer s.runWG.Done()
 	started := s.now()
 
-	stats, runErr := s.resolveSelectors(ctx, current)
+	stats, runErr := s.resolveSelectors(ctx, current, resume)
+	// Failed selectors keep the checkpoint so a resume retries only them.
+	if runErr == nil && stats.SelectorsFailed == 0 {
+		// Best effort: a stale checkpoint only lets a later resume skip
+		// selectors that are already fresh.
+		_ = s.manager.store.ClearResolverCheckpoint(context.Background())
+	}
 	finished := s.now()
 	record := ResolverRunRecord{
 		StartedAt:        started.Unix(),
@@ -252,6 +288,7 @@ func (s *ResolverScheduler) executeRun(ctx context.Context, current settings.Set
 		DurationMS:       finished.Sub(started).Milliseconds(),
 		SelectorsTotal:   stats.SelectorsTotal,
 		SelectorsDone:    stats.SelectorsDone,
+		SelectorsFailed:  stats.SelectorsFailed,
 		PrefixesResolved: stats.PrefixesResolved,
 	}
 	if runErr != nil {
@@ -263,6 +300,9 @@ func (s *ResolverScheduler) executeRun(ctx context.Context, current settings.Set
 		if saved.Error == "" {
 			saved.Error = saveErr.Error()
 		}
+	} else {
+		// Error detail is best effort; the run row itself is already saved.
+		_ = s.manager.store.SaveResolverRunErrors(context.Background(), saved.ID, stats.Errors, stats.ErrorsByProvider)
 	}
 
 	s.mu.Lock()
@@ -273,15 +313,18 @@ func (s *ResolverScheduler) executeRun(ctx context.Context, current settings.Set
 		StartedAt:        started.Unix(),
 		SelectorsTotal:   stats.SelectorsTotal,
 		SelectorsDone:    stats.SelectorsDone,
+		SelectorsFailed:  stats.SelectorsFailed,
+		SelectorsSkipped: stats.SelectorsSkipped,
 		PrefixesResolved: stats.PrefixesResolved,
 		PerProvider:      stats.PerProvider,
+		Resumed:          resume,
 	}
 	s.progress = &finalProgress
 	s.mu.Unlock()
 	s.emitProgress(finalProgress)
 }
 
-func (s *ResolverScheduler) resolveSelectors(ctx context.Context, current settings.Settings) (resolverStats, error) {
+func (s *ResolverScheduler) resolveSelectors(ctx context.Context, current settings.Settings, resume bool) (resolverStats, error) {
 	enabled := resolverProviderFlagsFromSettings(current)
 	resolvers := s.resolversForRun(current, enabled)
 	groups, err := s.manager.store.List(ctx)
@@ -289,19 +332,42 @@ func (s *ResolverScheduler) resolveSelectors(ctx context.Context, current settin
 		return resolverStats{}, err
 	}
 	jobs := collectResolverJobs(groups, enabled)
+	var done map[ResolverSelector]struct{}
+	if resume {
+		done, err = s.manager.store.LoadResolverCheckpoint(ctx)
+	} else {
+		err = s.manager.store.ClearResolverCheckpoint(ctx)
+	}
+	if err != nil {
+		return resolverStats{}, err
+	}
 	progress := ResolverProgress{
 		StartedAt:      s.now().Unix(),
 		SelectorsTotal: len(jobs),
 		PerProvider:    make(map[string]ResolverProviderProgress),
+		Resumed:        resume,
 	}
 	for _, job := range jobs {
 		entry := progress.PerProvider[job.Selector.Type]
 		entry.SelectorsTotal++
 		progress.PerProvider[job.Selector.Type] = entry
 	}
+	jobs, skipped := filterCheckpointedJobs(jobs, done)
+	for _, job := range skipped {
+		entry := progress.PerProvider[job.Selector.Type]
+		entry.SelectorsDone++
+		progress.PerProvider[job.Selector.Type] = entry
+	}
+	progress.SelectorsDone = len(skipped)
+	progress.SelectorsSkipped = len(skipped)
 	s.emitProgress(progress)
 	if len(jobs) == 0 {
-		return resolverStats{PerProvider: cloneResolverProviderProgress(progress.PerProvider)}, nil
+		return resolverStats{
+			SelectorsTotal:   progress.SelectorsTotal,
+			SelectorsDone:    progress.SelectorsDone,
+			SelectorsSkipped: progress.SelectorsSkipped,
+			PerProvider:      cloneResolverProviderProgress(progress.PerProvider),
+		}, nil
 	}
 
 	parallelism := resolverParallelismFromSettings(current)
@@ -343,14 +409,21 @@ func (s *ResolverScheduler) resolveSelectors(ctx context.Context, current settin
 		workers.Wait()
 	}()
 
-	snapshot := make(map[ResolverSelector]ResolverValues, len(jobs))
-	var firstErr error
+	// A failed selector does not fail the run: it is recorded and the
+	// others carry on. Resolved selectors are upserted and checkpointed in
+	// batches, so an interrupted run keeps its progress and can be resumed.
+	batch := make(map[ResolverSelector]ResolverValues, resolverCheckpointBatch)
+	var failures resolverStats
+	var flushErr error
 	for result := range resultCh {
-		if result.err != nil && firstErr == nil {
-			firstErr = result.err
-		}
+		failed := result.err != nil && !errors.Is(result.err, context.Canceled)
+		failures.recordError(s.now().Unix(), result.job, result.err)
 		if result.err == nil {
-			snapshot[result.job.Selector] = result.values
+			batch[result.job.Selector] = result.values
+		}
+		if len(batch) >= resolverCheckpointBatch && flushErr == nil {
+			flushErr = s.flushResolverBatch(ctx, batch)
+			batch = make(map[ResolverSelector]ResolverValues, resolverCheckpointBatch)
 		}
 
 		progress.SelectorsDone++
@@ -360,26 +433,33 @@ func (s *ResolverScheduler) resolveSelectors(ctx context.Context, current settin
 		providerProgress := progress.PerProvider[result.job.Selector.Type]
 		providerProgress.SelectorsDone++
 		providerProgress.PrefixesResolved += resolvedCount
+		if failed {
+			progress.SelectorsFailed++
+			providerProgress.SelectorsFailed++
+		}
 		progress.PerProvider[result.job.Selector.Type] = providerProgress
 		s.emitProgress(progress)
 	}
 
-	if len(snapshot) > 0 {
-		if err := s.manager.UpsertResolverSnapshot(ctx, snapshot); err != nil {
-			return resolverStats{}, err
-		}
+	if flushErr == nil {
+		flushErr = s.flushResolverBatch(ctx, batch)
+	}
+	if flushErr != nil {
+		return resolverStats{}, flushErr
 	}
 
 	stats := resolverStats{
 		SelectorsTotal:   progress.SelectorsTotal,
 		SelectorsDone:    progress.SelectorsDone,
+		SelectorsFailed:  progress.SelectorsFailed,
+		SelectorsSkipped: progress.SelectorsSkipped,
 		PrefixesResolved: progress.PrefixesResolved,
 		PerProvider:      cloneResolverProviderProgress(progress.PerProvider),
+		Errors:           failures.Errors,
+		ErrorsByProvider: failures.ErrorsByProvider,
 	}
-	if firstErr != nil {
-		return stats, firstErr
-	}
-	return stats, nil
+	// Cancellation is the only selector outcome that fails the run.
+	return stats, ctx.Err()
 }
 
 func (s *ResolverScheduler) resolveJob(ctx context.Context, job resolverJob, resolvers runResolvers) (ResolverValues, error) {
@@ -426,17 +506,27 @@ func (s *ResolverScheduler) resolveJob(ctx context.Context, job resolverJob, res
 }
 
 func (s *ResolverScheduler) resolversForRun(current settings.Settings, enabled resolverProviderFlags) runResolvers {
-	// Non-custom resolvers are rebuilt per run so timeout setting changes are
-	// applied immediately without requiring a process restart.
+	// Non-custom resolvers are rebuilt per run so timeout and egress setting
+	// changes are applied immediately without requiring a process restart.
+	// Limiters persist across runs so rate budgets and open breakers carry
+	// over.
+	limiters := s.providerLimiters(current)
+	egress := ResolverEgress(s.manager.vpnLister, current)
 	result := runResolvers{}
 	if enabled.Domain || enabled.Wildcard {
-		result.domain = newDoHDomainResolver(resolverDomainTimeoutFromSettings(current))
+		domain := newDoHDomainResolver(resolverDomainTimeoutFromSettings(current), egress)
+		domain.limiter = limiters["domain"]
+		result.domain = domain
 	}
 	if enabled.ASN {
-		result.asn = newRIPEASNResolver(resolverASNTimeoutFromSettings(current))
+		asn := newRIPEASNResolver(resolverASNTimeoutFromSettings(current), egress)
+		asn.limiter = limiters["asn"]
+		result.asn = asn
 	}
 	if enabled.Wildcard {
-		result.wildcard = newCRTSHWildcardResolver(resolverWildcardTimeoutFromSettings(current))
+		wildcard := newCRTSHWildcardResolver(resolverWildcardTimeoutFromSettings(current), egress)
+		wildcard.limiter = limiters["wildcard"]
+		result.wildcard = wildcard
 	}
 
 	s.mu.RLock()
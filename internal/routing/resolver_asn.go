@@ -12,6 +12,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"split-vpn-webui/internal/netbind"
 )
 
 const resolverASNEndpoint = "https://stat.ripe.net/data/announced-prefixes/data.json"
@@ -19,6 +21,9 @@ const resolverASNEndpoint = "https://stat.ripe.net/data/announced-prefixes/data.
 type ripeASNResolver struct {
 	baseURL string
 	client  *http.Client
+	// limiter paces requests and backs off on throttling; nil sends
+	// requests directly.
+	limiter *providerLimiter
 }
 
 type ripeResponse struct {
@@ -29,13 +34,13 @@ type ripeResponse struct {
 	} `json:"data"`
 }
 
-func newRIPEASNResolver(timeout time.Duration) *ripeASNResolver {
+func newRIPEASNResolver(timeout time.Duration, egress netbind.Egress) *ripeASNResolver {
 	if timeout <= 0 {
 		timeout = 10 * time.Second
 	}
 	return &ripeASNResolver{
 		baseURL: resolverASNEndpoint,
-		client:  &http.Client{Timeout: timeout},
+		client:  netbind.HTTPClient(timeout, egress),
 	}
 }
 
@@ -56,7 +61,7 @@ func (r *ripeASNResolver) Resolve(ctx context.Context, asn string) (ResolverValu
 	if err != nil {
 		return ResolverValues{}, err
 	}
-	resp, err := r.client.Do(req)
+	resp, err := r.limiter.do(ctx, r.client, req)
 	if err != nil {
 		return ResolverValues{}, err
 	}
@@ -0,0 +1,122 @@
+package routing
+
+import (
+	"context"
+	"errors"
+)
+
+// resolverCheckpointBatch is how many resolved selectors are upserted and
+// checkpointed together while a run is in progress.
+const resolverCheckpointBatch = 25
+
+// ErrResolverNothingToResume indicates no interrupted resolver run left
+// checkpointed selectors behind.
+var ErrResolverNothingToResume = errors.New("no interrupted resolver run to resume")
+
+// ResumeRun starts a resolver run that skips selectors completed by the last
+// interrupted run.
+func (s *ResolverScheduler) ResumeRun() error {
+	s.mu.RLock()
+	running := s.running
+	s.mu.RUnlock()
+	if running {
+		return ErrResolverRunInProgress
+	}
+	count, err := s.manager.store.CountResolverCheckpoint(context.Background())
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrResolverNothingToResume
+	}
+	return s.startRun(true)
+}
+
+// filterCheckpointedJobs drops jobs completed by an earlier run.
+func filterCheckpointedJobs(jobs []resolverJob, done map[ResolverSelector]struct{}) ([]resolverJob, []resolverJob) {
+	if len(done) == 0 {
+		return jobs, nil
+	}
+	pending := make([]resolverJob, 0, len(jobs))
+	skipped := make([]resolverJob, 0, len(done))
+	for _, job := range jobs {
+		if _, ok := done[job.Selector]; ok {
+			skipped = append(skipped, job)
+			continue
+		}
+		pending = append(pending, job)
+	}
+	return pending, skipped
+}
+
+// flushResolverBatch upserts a batch of resolved selectors and then records
+// them in the checkpoint. It ignores run cancellation so work finished
+// before a cancel survives for the resume.
+func (s *ResolverScheduler) flushResolverBatch(ctx context.Context, batch map[ResolverSelector]ResolverValues) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+	if err := s.manager.UpsertResolverSnapshot(ctx, batch); err != nil {
+		return err
+	}
+	selectors := make([]ResolverSelector, 0, len(batch))
+	for selector := range batch {
+		selectors = append(selectors, selector)
+	}
+	return s.manager.store.SaveResolverCheckpoint(ctx, selectors, s.now().Unix())
+}
+
+// SaveResolverCheckpoint marks selectors as completed by the current run.
+func (s *Store) SaveResolverCheckpoint(ctx context.Context, selectors []ResolverSelector, at int64) error {
+	if len(selectors) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, selector := range selectors {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO resolver_checkpoint (selector_type, selector_key, completed_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT(selector_type, selector_key) DO UPDATE SET completed_at = excluded.completed_at
+		`, selector.Type, selector.Key, at); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadResolverCheckpoint returns the selectors completed by the last
+// interrupted run.
+func (s *Store) LoadResolverCheckpoint(ctx context.Context) (map[ResolverSelector]struct{}, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT selector_type, selector_key FROM resolver_checkpoint`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[ResolverSelector]struct{})
+	for rows.Next() {
+		var selector ResolverSelector
+		if err := rows.Scan(&selector.Type, &selector.Key); err != nil {
+			return nil, err
+		}
+		out[selector] = struct{}{}
+	}
+	return out, rows.Err()
+}
+
+// CountResolverCheckpoint returns how many selectors are checkpointed.
+func (s *Store) CountResolverCheckpoint(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM resolver_checkpoint`).Scan(&count)
+	return count, err
+}
+
+// ClearResolverCheckpoint forgets all checkpointed selectors.
+func (s *Store) ClearResolverCheckpoint(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM resolver_checkpoint`)
+	return err
+}
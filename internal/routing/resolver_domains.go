@@ -11,6 +11,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"split-vpn-webui/internal/netbind"
 )
 
 const resolverCloudflareDoHURL = "https://cloudflare-dns.com/dns-query"
@@ -18,6 +20,9 @@ const resolverCloudflareDoHURL = "https://cloudflare-dns.com/dns-query"
 type dohDomainResolver struct {
 	baseURL string
 	client  *http.Client
+	// limiter paces requests and backs off on throttling; nil sends
+	// requests directly.
+	limiter *providerLimiter
 }
 
 type dohAnswer struct {
@@ -30,13 +35,13 @@ type dohPayload struct {
 	Answer []dohAnswer `json:"Answer"`
 }
 
-func newDoHDomainResolver(timeout time.Duration) *dohDomainResolver {
+func newDoHDomainResolver(timeout time.Duration, egress netbind.Egress) *dohDomainResolver {
 	if timeout <= 0 {
 		timeout = 10 * time.Second
 	}
 	return &dohDomainResolver{
 		baseURL: resolverCloudflareDoHURL,
-		client:  &http.Client{Timeout: timeout},
+		client:  netbind.HTTPClient(timeout, egress),
 	}
 }
 
@@ -93,7 +98,7 @@ func (r *dohDomainResolver) query(ctx context.Context, domain, qType string, wan
 	}
 	req.Header.Set("accept", "application/dns-json")
 
-	resp, err := r.client.Do(req)
+	resp, err := r.limiter.do(ctx, r.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -5,7 +5,9 @@ import (
 	"strings"
 	"time"
 
+	"split-vpn-webui/internal/netbind"
 	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/vpn"
 )
 
 type resolverProviderFlags struct {
@@ -105,6 +107,57 @@ func (s *ResolverScheduler) emitProgress(progress ResolverProgress) {
 	}
 }
 
+// ValidateResolverSettings checks the policy resolver fields of a settings
+// update. Zero keeps each default.
+func ValidateResolverSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	for _, bound := range []struct {
+		field string
+		value int
+		max   int
+	}{
+		{"resolverParallelism", current.ResolverParallelism, maxResolverParallelism},
+		{"resolverTimeoutSeconds", current.ResolverTimeoutSeconds, maxResolverTimeoutSeconds},
+		{"resolverIntervalSeconds", current.ResolverIntervalSeconds, maxResolverIntervalSeconds},
+		{"resolverDomainTimeoutSeconds", current.ResolverDomainTimeoutSeconds, maxResolverTimeoutSeconds},
+		{"resolverAsnTimeoutSeconds", current.ResolverASNTimeoutSeconds, maxResolverTimeoutSeconds},
+		{"resolverWildcardTimeoutSeconds", current.ResolverWildcardTimeoutSeconds, maxResolverTimeoutSeconds},
+		{"resolverDomainRatePerMinute", current.ResolverDomainRatePerMinute, MaxResolverRatePerMinute},
+		{"resolverAsnRatePerMinute", current.ResolverASNRatePerMinute, MaxResolverRatePerMinute},
+		{"resolverWildcardRatePerMinute", current.ResolverWildcardRatePerMinute, MaxResolverRatePerMinute},
+	} {
+		if bound.value < 0 || bound.value > bound.max {
+			errs.Addf(bound.field, "%s must be between 0 and %d", bound.field, bound.max)
+		}
+	}
+	if egress := strings.TrimSpace(current.ResolverEgress); egress != "" && vpn.ValidateName(egress) != nil {
+		errs.Addf("resolverEgress", "resolverEgress must be a VPN or interface name")
+	}
+	return errs.Err()
+}
+
+// ResolverEgress returns where resolver and pre-warm HTTP lookups leave:
+// the interface and DNS servers of the VPN ResolverEgress names, so host
+// names are resolved through the tunnel too, or the setting itself as a
+// bare interface name with the system resolver. Empty follows the routing
+// table.
+func ResolverEgress(vpns VPNLister, current settings.Settings) netbind.Egress {
+	egress := netbind.Egress{Interface: strings.TrimSpace(current.ResolverEgress)}
+	if egress.Interface == "" || vpns == nil {
+		return egress
+	}
+	profiles, err := vpns.List()
+	if err != nil {
+		return egress
+	}
+	for _, profile := range profiles {
+		if profile != nil && profile.Name == egress.Interface && profile.InterfaceName != "" {
+			return netbind.Egress{Interface: profile.InterfaceName, Resolvers: profile.DNSServers()}
+		}
+	}
+	return egress
+}
+
 func resolverIntervalFromSettings(current settings.Settings) time.Duration {
 	seconds := current.ResolverIntervalSeconds
 	if seconds <= 0 {
@@ -0,0 +1,246 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+const (
+	// maxResolverRunErrorsStored bounds how many individual selector
+	// failures one resolver run persists; per-provider totals stay exact.
+	maxResolverRunErrorsStored = 200
+
+	// DefaultResolverRunsPageSize and MaxResolverRunsPageSize bound run
+	// history pagination.
+	DefaultResolverRunsPageSize = 20
+	MaxResolverRunsPageSize     = 200
+)
+
+// ErrResolverRunNotFound indicates the requested resolver run does not exist.
+var ErrResolverRunNotFound = errors.New("resolver run not found")
+
+// ResolverRunError is one persisted selector failure of a resolver run.
+type ResolverRunError struct {
+	At       int64  `json:"at"`
+	Provider string `json:"provider"`
+	Selector string `json:"selector"`
+	Error    string `json:"error"`
+}
+
+// ResolverRunSummary is a resolver run with its per-provider error breakdown.
+type ResolverRunSummary struct {
+	ResolverRunRecord
+	ErrorsTotal      int            `json:"errorsTotal"`
+	ErrorsByProvider map[string]int `json:"errorsByProvider"`
+}
+
+// ResolverRunsPage is one page of resolver run history, newest first.
+type ResolverRunsPage struct {
+	Runs   []ResolverRunSummary `json:"runs"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+// ResolverRunDetail is one resolver run with its stored selector failures.
+type ResolverRunDetail struct {
+	Run    ResolverRunSummary `json:"run"`
+	Errors []ResolverRunError `json:"errors"`
+	// Truncated reports that more errors occurred than were stored.
+	Truncated bool `json:"truncated"`
+}
+
+// recordError adds a failed selector to the run stats. Cancellation is not
+// a selector failure and is skipped.
+func (stats *resolverStats) recordError(at int64, job resolverJob, err error) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	if stats.ErrorsByProvider == nil {
+		stats.ErrorsByProvider = make(map[string]int)
+	}
+	stats.ErrorsByProvider[job.Selector.Type]++
+	if len(stats.Errors) >= maxResolverRunErrorsStored {
+		return
+	}
+	stats.Errors = append(stats.Errors, ResolverRunError{
+		At:       at,
+		Provider: job.Selector.Type,
+		Selector: job.Label,
+		Error:    err.Error(),
+	})
+}
+
+// Runs returns one page of persisted resolver runs with error breakdowns.
+func (s *ResolverScheduler) Runs(ctx context.Context, limit, offset int) (ResolverRunsPage, error) {
+	return s.manager.store.ListResolverRuns(ctx, limit, offset)
+}
+
+// Run returns one persisted resolver run and its stored selector failures.
+func (s *ResolverScheduler) Run(ctx context.Context, runID int64) (*ResolverRunDetail, error) {
+	store := s.manager.store
+	run, err := store.GetResolverRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := store.loadResolverErrorTotals(ctx, []int64{runID})
+	if err != nil {
+		return nil, err
+	}
+	runErrors, err := store.LoadResolverRunErrors(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	summary := newResolverRunSummary(*run, totals[runID])
+	return &ResolverRunDetail{
+		Run:       summary,
+		Errors:    runErrors,
+		Truncated: summary.ErrorsTotal > len(runErrors),
+	}, nil
+}
+
+func newResolverRunSummary(run ResolverRunRecord, totals map[string]int) ResolverRunSummary {
+	summary := ResolverRunSummary{ResolverRunRecord: run, ErrorsByProvider: map[string]int{}}
+	for provider, count := range totals {
+		summary.ErrorsByProvider[provider] = count
+		summary.ErrorsTotal += count
+	}
+	return summary
+}
+
+// SaveResolverRunErrors persists a run's sampled failures and per-provider
+// totals.
+func (s *Store) SaveResolverRunErrors(ctx context.Context, runID int64, events []ResolverRunError, totals map[string]int) error {
+	if len(events) == 0 && len(totals) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, event := range events {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO resolver_run_errors (run_id, at, provider, selector, error)
+			VALUES (?, ?, ?, ?, ?)
+		`, runID, event.At, event.Provider, event.Selector, event.Error); err != nil {
+			return err
+		}
+	}
+	for provider, count := range totals {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO resolver_run_error_totals (run_id, provider, count)
+			VALUES (?, ?, ?)
+			ON CONFLICT(run_id, provider) DO UPDATE SET count = excluded.count
+		`, runID, provider, count); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadResolverRunErrors returns the stored failures of a run in the order
+// they occurred.
+func (s *Store) LoadResolverRunErrors(ctx context.Context, runID int64) ([]ResolverRunError, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT at, provider, selector, error
+		FROM resolver_run_errors
+		WHERE run_id = ?
+		ORDER BY id ASC
+	`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]ResolverRunError, 0)
+	for rows.Next() {
+		var event ResolverRunError
+		if err := rows.Scan(&event.At, &event.Provider, &event.Selector, &event.Error); err != nil {
+			return nil, err
+		}
+		out = append(out, event)
+	}
+	return out, rows.Err()
+}
+
+// ListResolverRuns returns one page of resolver runs, newest first, with
+// error breakdowns.
+func (s *Store) ListResolverRuns(ctx context.Context, limit, offset int) (ResolverRunsPage, error) {
+	if limit <= 0 {
+		limit = DefaultResolverRunsPageSize
+	}
+	if limit > MaxResolverRunsPageSize {
+		limit = MaxResolverRunsPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	page := ResolverRunsPage{Runs: []ResolverRunSummary{}, Limit: limit, Offset: offset}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM resolver_runs`).Scan(&page.Total); err != nil {
+		return ResolverRunsPage{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+resolverRunColumns+`
+		FROM resolver_runs
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return ResolverRunsPage{}, err
+	}
+	var runs []ResolverRunRecord
+	for rows.Next() {
+		run, err := scanResolverRun(rows)
+		if err != nil {
+			rows.Close()
+			return ResolverRunsPage{}, err
+		}
+		runs = append(runs, *run)
+	}
+	if err := rows.Close(); err != nil {
+		return ResolverRunsPage{}, err
+	}
+	ids := make([]int64, 0, len(runs))
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	totals, err := s.loadResolverErrorTotals(ctx, ids)
+	if err != nil {
+		return ResolverRunsPage{}, err
+	}
+	for _, run := range runs {
+		page.Runs = append(page.Runs, newResolverRunSummary(run, totals[run.ID]))
+	}
+	return page, nil
+}
+
+func (s *Store) loadResolverErrorTotals(ctx context.Context, runIDs []int64) (map[int64]map[string]int, error) {
+	out := make(map[int64]map[string]int, len(runIDs))
+	if len(runIDs) == 0 {
+		return out, nil
+	}
+	sort.Slice(runIDs, func(i, j int) bool { return runIDs[i] < runIDs[j] })
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT run_id, provider, count
+		FROM resolver_run_error_totals
+		WHERE run_id BETWEEN ? AND ?
+	`, runIDs[0], runIDs[len(runIDs)-1])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var runID int64
+		var provider string
+		var count int
+		if err := rows.Scan(&runID, &provider, &count); err != nil {
+			return nil, err
+		}
+		if out[runID] == nil {
+			out[runID] = make(map[string]int)
+		}
+		out[runID][provider] = count
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,280 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+const (
+	// Default request budgets per provider. crt.sh bans aggressively, RIPEstat
+	// asks clients to stay well below its per-IP limits, and Cloudflare DoH is
+	// generous.
+	defaultResolverDomainRatePerMinute   = 600
+	defaultResolverASNRatePerMinute      = 120
+	defaultResolverWildcardRatePerMinute = 20
+	// MaxResolverRatePerMinute bounds the configurable provider budgets.
+	MaxResolverRatePerMinute = 6000
+
+	providerMaxAttempts      = 4
+	providerBackoffBase      = time.Second
+	providerBackoffMax       = 30 * time.Second
+	providerBreakerThreshold = 5
+	providerBreakerCooldown  = 5 * time.Minute
+)
+
+// ErrResolverProviderUnavailable is returned without contacting a provider
+// while its circuit breaker is open.
+var ErrResolverProviderUnavailable = errors.New("resolver provider circuit open")
+
+// ResolverProviderHealth describes one provider's rate limit and breaker.
+type ResolverProviderHealth struct {
+	RatePerMinute       int        `json:"ratePerMinute"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	CircuitOpenUntil    *time.Time `json:"circuitOpenUntil,omitempty"`
+}
+
+// providerLimiter is a token bucket with retry backoff and a circuit breaker
+// shared by every request to one resolver provider. It outlives single runs
+// so a provider that tripped the breaker stays paused across the next run.
+type providerLimiter struct {
+	name  string
+	now   func() time.Time
+	sleep func(ctx context.Context, delay time.Duration) error
+
+	mu            sync.Mutex
+	ratePerMinute int
+	tokens        float64
+	refilledAt    time.Time
+	failures      int
+	openUntil     time.Time
+}
+
+func newProviderLimiter(name string, ratePerMinute int) *providerLimiter {
+	limiter := &providerLimiter{name: name, now: time.Now, sleep: sleepContext}
+	limiter.setRate(ratePerMinute)
+	limiter.tokens = limiter.burstLocked()
+	return limiter
+}
+
+func (l *providerLimiter) setRate(ratePerMinute int) {
+	if ratePerMinute <= 0 {
+		ratePerMinute = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ratePerMinute = ratePerMinute
+	if burst := l.burstLocked(); l.tokens > burst {
+		l.tokens = burst
+	}
+}
+
+// burstLocked allows one second of budget at once, and at least one request.
+func (l *providerLimiter) burstLocked() float64 {
+	burst := float64(l.ratePerMinute) / 60
+	if burst < 1 {
+		return 1
+	}
+	return burst
+}
+
+// wait blocks until a token is available or fails fast while the breaker
+// is open.
+func (l *providerLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := l.now()
+		if now.Before(l.openUntil) {
+			until := l.openUntil
+			l.mu.Unlock()
+			return fmt.Errorf("%w: %s paused until %s", ErrResolverProviderUnavailable, l.name, until.Format(time.RFC3339))
+		}
+		if !l.refilledAt.IsZero() {
+			l.tokens += float64(now.Sub(l.refilledAt)) * float64(l.ratePerMinute) / float64(time.Minute)
+			if burst := l.burstLocked(); l.tokens > burst {
+				l.tokens = burst
+			}
+		}
+		l.refilledAt = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration(math.Ceil((1 - l.tokens) * float64(time.Minute) / float64(l.ratePerMinute)))
+		l.mu.Unlock()
+		if err := l.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// do sends req within the provider's budget. Transport errors, 429 and 5xx
+// responses are retried with exponential backoff, honouring Retry-After;
+// a Retry-After longer than the backoff cap trips the breaker instead.
+// Exhausted retries count towards the breaker. The last response is
+// returned as is so callers keep their own status handling.
+func (l *providerLimiter) do(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	if l == nil {
+		return client.Do(req)
+	}
+	for attempt := 1; ; attempt++ {
+		if err := l.wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			l.recordResult(true, time.Time{})
+			return resp, nil
+		}
+		var retryAfter time.Duration
+		if resp != nil {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), l.now())
+		}
+		if retryAfter > providerBackoffMax {
+			l.recordResult(false, l.now().Add(retryAfter))
+			return resp, err
+		}
+		if attempt >= providerMaxAttempts {
+			l.recordResult(false, time.Time{})
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		delay := providerBackoffBase << (attempt - 1)
+		if delay > providerBackoffMax {
+			delay = providerBackoffMax
+		}
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if err := l.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// recordResult updates the breaker. openUntil forces the breaker open, e.g.
+// for a long Retry-After.
+func (l *providerLimiter) recordResult(ok bool, openUntil time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ok {
+		l.failures = 0
+		l.openUntil = time.Time{}
+		return
+	}
+	l.failures++
+	if l.failures >= providerBreakerThreshold {
+		openUntil = laterTime(openUntil, l.now().Add(providerBreakerCooldown))
+	}
+	if openUntil.After(l.openUntil) {
+		l.openUntil = openUntil
+	}
+}
+
+func (l *providerLimiter) health() ResolverProviderHealth {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	health := ResolverProviderHealth{RatePerMinute: l.ratePerMinute, ConsecutiveFailures: l.failures}
+	if l.now().Before(l.openUntil) {
+		until := l.openUntil.UTC()
+		health.CircuitOpenUntil = &until
+	}
+	return health
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// parseRetryAfter reads delay-seconds or an HTTP date.
+func parseRetryAfter(raw string, now time.Time) time.Duration {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(raw); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(raw); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+func laterTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func resolverRatePerMinute(configured, fallback int) int {
+	if configured <= 0 {
+		return fallback
+	}
+	if configured > MaxResolverRatePerMinute {
+		return MaxResolverRatePerMinute
+	}
+	return configured
+}
+
+// providerLimiters returns the scheduler's limiters with rates from
+// current applied.
+func (s *ResolverScheduler) providerLimiters(current settings.Settings) map[string]*providerLimiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limiters == nil {
+		s.limiters = map[string]*providerLimiter{
+			"domain":   newProviderLimiter("domain", defaultResolverDomainRatePerMinute),
+			"asn":      newProviderLimiter("asn", defaultResolverASNRatePerMinute),
+			"wildcard": newProviderLimiter("wildcard", defaultResolverWildcardRatePerMinute),
+		}
+	}
+	s.limiters["domain"].setRate(resolverRatePerMinute(current.ResolverDomainRatePerMinute, defaultResolverDomainRatePerMinute))
+	s.limiters["asn"].setRate(resolverRatePerMinute(current.ResolverASNRatePerMinute, defaultResolverASNRatePerMinute))
+	s.limiters["wildcard"].setRate(resolverRatePerMinute(current.ResolverWildcardRatePerMinute, defaultResolverWildcardRatePerMinute))
+	return s.limiters
+}
+
+func (s *ResolverScheduler) providerHealth() map[string]ResolverProviderHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.limiters) == 0 {
+		return nil
+	}
+	out := make(map[string]ResolverProviderHealth, len(s.limiters))
+	for name, limiter := range s.limiters {
+		out[name] = limiter.health()
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestProviderLimiter(ratePerMinute int) (*providerLimiter, *time.Time, *[]time.Duration) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	slept := []time.Duration{}
+	limiter := newProviderLimiter("test", ratePerMinute)
+	limiter.now = func() time.Time { return now }
+	limiter.sleep = func(ctx context.Context, delay time.Duration) error {
+		slept = append(slept, delay)
+		now = now.Add(delay)
+		return nil
+	}
+	limiter.refilledAt = time.Time{}
+	limiter.tokens = limiter.burstLocked()
+	return limiter, &now, &slept
+}
+
+func TestProviderLimiterRetriesThenSucceeds(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	limiter, _, slept := newTestProviderLimiter(6000)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := limiter.do(context.Background(), server.Client(), req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	if len(*slept) != 2 || (*slept)[0] != time.Second || (*slept)[1] != 2*time.Second {
+		t.Fatalf("unexpected backoff delays %v", *slept)
+	}
+	if health := limiter.health(); health.ConsecutiveFailures != 0 || health.CircuitOpenUntil != nil {
+		t.Fatalf("expected healthy provider, got %+v", health)
+	}
+}
+
+func TestProviderLimiterOpensBreakerAfterRepeatedFailures(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	limiter, _, _ := newTestProviderLimiter(6000)
+	for i := 0; i < providerBreakerThreshold; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := limiter.do(context.Background(), server.Client(), req)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("expected last 429 to be returned, got %d", resp.StatusCode)
+		}
+	}
+	if want := int32(providerBreakerThreshold * providerMaxAttempts); calls != want {
+		t.Fatalf("expected %d attempts, got %d", want, calls)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	if _, err := limiter.do(context.Background(), server.Client(), req); !errors.Is(err, ErrResolverProviderUnavailable) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	if want := int32(providerBreakerThreshold * providerMaxAttempts); calls != want {
+		t.Fatalf("expected no request while circuit open, got %d", calls)
+	}
+	if health := limiter.health(); health.CircuitOpenUntil == nil {
+		t.Fatalf("expected circuit open in health, got %+v", health)
+	}
+}
+
+func TestProviderLimiterLongRetryAfterPausesProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	limiter, now, slept := newTestProviderLimiter(6000)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := limiter.do(context.Background(), server.Client(), req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if len(*slept) != 0 {
+		t.Fatalf("expected no sleep for long Retry-After, got %v", *slept)
+	}
+	if err := limiter.wait(context.Background()); !errors.Is(err, ErrResolverProviderUnavailable) {
+		t.Fatalf("expected provider paused, got %v", err)
+	}
+	*now = now.Add(11 * time.Minute)
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatalf("expected provider available after Retry-After, got %v", err)
+	}
+}
+
+func TestProviderLimiterWaitsForTokens(t *testing.T) {
+	limiter, _, slept := newTestProviderLimiter(60)
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatalf("wait %d: %v", i, err)
+		}
+	}
+	if len(*slept) != 2 {
+		t.Fatalf("expected two waits at one request per second, got %v", *slept)
+	}
+	for _, delay := range *slept {
+		if delay != time.Second {
+			t.Fatalf("expected one second waits, got %v", *slept)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"junk":                          0,
+		"-5":                            0,
+		"7":                             7 * time.Second,
+		"Thu, 01 Jan 2026 00:02:00 GMT": 2 * time.Minute,
+		"Wed, 31 Dec 2025 23:00:00 GMT": 0,
+	}
+	for raw, want := range cases {
+		if got := parseRetryAfter(raw, now); got != want {
+			t.Fatalf("parseRetryAfter(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package routing
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/netip"
 	"path/filepath"
 	"testing"
 	"time"
@@ -162,6 +165,74 @@ func TestResolverSchedulerRunUpdatesSnapshotAndReappliesRouting(t *testing.T) {
 	}
 }
 
+type failingASNResolver struct{}
+
+func (failingASNResolver) Resolve(ctx context.Context, asn string) (ResolverValues, error) {
+	return ResolverValues{}, errors.New("ripe unavailable")
+}
+
+func TestResolverRunKeepsSuccessfulSelectorsWhenOneFails(t *testing.T) {
+	manager, _, _, _ := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{{
+		Name:          "wg-sgp",
+		RouteTable:    201,
+		FWMark:        0x169,
+		InterfaceName: "wg-sgp",
+	}}})
+
+	ctx := context.Background()
+	if _, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-sgp",
+		Rules: []RoutingRule{{
+			Domains:         []string{"example.com", "example.org"},
+			DestinationASNs: []string{"AS13335"},
+		}},
+	}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+
+	settingsManager := settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	scheduler, err := NewResolverSchedulerWithDeps(
+		manager,
+		settingsManager,
+		&fakeDomainResolver{values: map[string]ResolverValues{
+			"example.com": {V4: []string{"1.1.1.1/32"}},
+			"example.org": {V4: []string{"1.0.0.1/32"}},
+		}},
+		failingASNResolver{},
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("new scheduler: %v", err)
+	}
+	if err := scheduler.TriggerNow(); err != nil {
+		t.Fatalf("TriggerNow failed: %v", err)
+	}
+	waitResolverIdle(t, scheduler)
+
+	status, err := scheduler.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	run := status.LastRun
+	if run == nil || run.Error != "" {
+		t.Fatalf("expected run without a run-level error, got %#v", run)
+	}
+	if run.SelectorsTotal != 3 || run.SelectorsDone != 3 || run.SelectorsFailed != 1 {
+		t.Fatalf("unexpected selector counts: %#v", run)
+	}
+	snapshot, err := manager.store.LoadResolverSnapshot(ctx)
+	if err != nil {
+		t.Fatalf("LoadResolverSnapshot failed: %v", err)
+	}
+	if len(snapshot) != 2 {
+		t.Fatalf("expected the two domain selectors in snapshot, got %d", len(snapshot))
+	}
+	if status.CheckpointedSelectors != 2 {
+		t.Fatalf("expected checkpoint kept for resume, got %d", status.CheckpointedSelectors)
+	}
+}
+
 func waitResolverIdle(t *testing.T, scheduler *ResolverScheduler) {
 	t.Helper()
 	deadline := time.Now().Add(3 * time.Second)
@@ -214,3 +285,152 @@ func TestResolverSnapshotUpsertKeepsPriorValuesUntilTTLExpiry(t *testing.T) {
 		t.Fatalf("expected updated selector to be present")
 	}
 }
+
+func TestResolverRunHistoryRecordsSelectorErrors(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "resolver-history.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	ctx := context.Background()
+
+	var stats resolverStats
+	job := resolverJob{Selector: ResolverSelector{Type: "asn", Key: "AS13335"}, Label: "AS13335"}
+	for i := 0; i < maxResolverRunErrorsStored+1; i++ {
+		stats.recordError(1000, job, errors.New("ripe unavailable"))
+	}
+	stats.recordError(1000, job, context.Canceled)
+
+	saved, err := store.SaveResolverRun(ctx, ResolverRunRecord{StartedAt: 1000, FinishedAt: 1001, Error: "ripe unavailable"})
+	if err != nil {
+		t.Fatalf("save run: %v", err)
+	}
+	if err := store.SaveResolverRunErrors(ctx, saved.ID, stats.Errors, stats.ErrorsByProvider); err != nil {
+		t.Fatalf("save run errors: %v", err)
+	}
+
+	page, err := store.ListResolverRuns(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if page.Total != 1 || page.Limit != DefaultResolverRunsPageSize || len(page.Runs) != 1 {
+		t.Fatalf("unexpected page: %#v", page)
+	}
+	if got := page.Runs[0].ErrorsByProvider["asn"]; got != maxResolverRunErrorsStored+1 {
+		t.Fatalf("expected exact asn error total, got %d", got)
+	}
+	stored, err := store.LoadResolverRunErrors(ctx, saved.ID)
+	if err != nil {
+		t.Fatalf("load run errors: %v", err)
+	}
+	if len(stored) != maxResolverRunErrorsStored || stored[0].Selector != "AS13335" {
+		t.Fatalf("unexpected stored errors: %d", len(stored))
+	}
+	if _, err := store.GetResolverRun(ctx, saved.ID+1); !errors.Is(err, ErrResolverRunNotFound) {
+		t.Fatalf("expected ErrResolverRunNotFound, got %v", err)
+	}
+}
+
+func TestResolverCheckpointSkipsCompletedSelectors(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "resolver-checkpoint.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	ctx := context.Background()
+
+	done := ResolverSelector{Type: "wildcard", Key: "*.apple.com"}
+	if err := store.SaveResolverCheckpoint(ctx, []ResolverSelector{done}, 1000); err != nil {
+		t.Fatalf("save checkpoint: %v", err)
+	}
+	if count, err := store.CountResolverCheckpoint(ctx); err != nil || count != 1 {
+		t.Fatalf("expected one checkpointed selector, got %d (%v)", count, err)
+	}
+	checkpoint, err := store.LoadResolverCheckpoint(ctx)
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	jobs := []resolverJob{
+		{Selector: ResolverSelector{Type: "domain", Key: "example.com"}},
+		{Selector: done},
+	}
+	pending, skipped := filterCheckpointedJobs(jobs, checkpoint)
+	if len(pending) != 1 || pending[0].Selector.Key != "example.com" || len(skipped) != 1 {
+		t.Fatalf("unexpected resume split: pending=%v skipped=%v", pending, skipped)
+	}
+
+	if err := store.ClearResolverCheckpoint(ctx); err != nil {
+		t.Fatalf("clear checkpoint: %v", err)
+	}
+	if count, err := store.CountResolverCheckpoint(ctx); err != nil || count != 0 {
+		t.Fatalf("expected empty checkpoint, got %d (%v)", count, err)
+	}
+}
+
+func TestResolverEgress(t *testing.T) {
+	lister := &mockVPNLister{profiles: []*vpn.VPNProfile{{
+		Name:          "home",
+		InterfaceName: "wg-sv-home",
+		WireGuard:     &vpn.WireGuardConfig{Interface: vpn.WireGuardInterface{DNS: []string{"10.64.0.1", "home.lan"}}},
+	}}}
+	cases := map[string]string{
+		"":      "",
+		"home":  "wg-sv-home",
+		"eth8":  "eth8",
+		" home": "wg-sv-home",
+	}
+	for egress, want := range cases {
+		if got := ResolverEgress(lister, settings.Settings{ResolverEgress: egress}); got.Interface != want {
+			t.Fatalf("ResolverEgress(%q) interface = %q, want %q", egress, got.Interface, want)
+		}
+	}
+	if got := ResolverEgress(lister, settings.Settings{ResolverEgress: "home"}); len(got.Resolvers) != 1 || got.Resolvers[0] != netip.MustParseAddr("10.64.0.1") {
+		t.Fatalf("expected the VPN's DNS server, got %v", got.Resolvers)
+	}
+	if got := ResolverEgress(lister, settings.Settings{ResolverEgress: "eth8"}); len(got.Resolvers) != 0 {
+		t.Fatalf("expected the system resolver for a bare interface, got %v", got.Resolvers)
+	}
+	if got := ResolverEgress(&mockVPNLister{err: errors.New("boom")}, settings.Settings{ResolverEgress: "home"}); got.Interface != "home" {
+		t.Fatalf("expected the setting itself when VPNs cannot be listed, got %q", got.Interface)
+	}
+
+	if err := ValidateResolverSettings(settings.Settings{ResolverEgress: "wg-sv-home"}); err != nil {
+		t.Fatalf("valid egress rejected: %v", err)
+	}
+	if err := ValidateResolverSettings(settings.Settings{ResolverEgress: "../eth0"}); err == nil {
+		t.Fatalf("expected invalid egress to be rejected")
+	}
+}
+
+func TestResolversForRunBindToEgress(t *testing.T) {
+	manager, _, _, _ := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{{Name: "home", InterfaceName: "wg-sv-home"}}})
+	settingsManager := settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	scheduler, err := NewResolverScheduler(manager, settingsManager)
+	if err != nil {
+		t.Fatalf("new scheduler: %v", err)
+	}
+	all := resolverProviderFlags{Domain: true, ASN: true, Wildcard: true}
+
+	unbound := scheduler.resolversForRun(settings.Settings{}, all)
+	if unbound.domain.(*dohDomainResolver).client.Transport != nil {
+		t.Fatalf("expected the default transport without an egress")
+	}
+	bound := scheduler.resolversForRun(settings.Settings{ResolverEgress: "home"}, all)
+	for name, client := range map[string]*http.Client{
+		"domain":   bound.domain.(*dohDomainResolver).client,
+		"asn":      bound.asn.(*ripeASNResolver).client,
+		"wildcard": bound.wildcard.(*crtSHWildcardResolver).client,
+	} {
+		if client.Transport == nil {
+			t.Fatalf("%s resolver client is not bound to the egress interface", name)
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 var (
 	// ErrResolverRunInProgress indicates one resolver run is already active.
 	ErrResolverRunInProgress = errors.New("resolver run already in progress")
+	// ErrResolverRunNotActive indicates there is no active resolver run to cancel.
+	ErrResolverRunNotActive = errors.New("resolver run is not active")
 )
 
 // DomainResolver resolves one domain to IPv4/IPv6 prefixes.
@@ -29,6 +31,7 @@ type WildcardResolver interface {
 type ResolverProviderProgress struct {
 	SelectorsTotal   int `json:"selectorsTotal"`
 	SelectorsDone    int `json:"selectorsDone"`
+	SelectorsFailed  int `json:"selectorsFailed,omitempty"`
 	PrefixesResolved int `json:"prefixesResolved"`
 }
 
@@ -37,9 +40,14 @@ type ResolverProgress struct {
 	StartedAt        int64                               `json:"startedAt"`
 	SelectorsTotal   int                                 `json:"selectorsTotal"`
 	SelectorsDone    int                                 `json:"selectorsDone"`
+	SelectorsFailed  int                                 `json:"selectorsFailed,omitempty"`
 	PrefixesResolved int                                 `json:"prefixesResolved"`
 	CurrentSelector  string                              `json:"currentSelector,omitempty"`
 	PerProvider      map[string]ResolverProviderProgress `json:"perProvider,omitempty"`
+	// Resumed runs count checkpointed selectors as done and report them
+	// in SelectorsSkipped.
+	Resumed          bool `json:"resumed,omitempty"`
+	SelectorsSkipped int  `json:"selectorsSkipped,omitempty"`
 }
 
 // Clone returns a deep copy safe for cross-goroutine publication.
@@ -48,8 +56,11 @@ func (p ResolverProgress) Clone() ResolverProgress {
 		StartedAt:        p.StartedAt,
 		SelectorsTotal:   p.SelectorsTotal,
 		SelectorsDone:    p.SelectorsDone,
+		SelectorsFailed:  p.SelectorsFailed,
 		PrefixesResolved: p.PrefixesResolved,
 		CurrentSelector:  p.CurrentSelector,
+		Resumed:          p.Resumed,
+		SelectorsSkipped: p.SelectorsSkipped,
 	}
 	if len(p.PerProvider) > 0 {
 		cloned.PerProvider = make(map[string]ResolverProviderProgress, len(p.PerProvider))
@@ -65,13 +76,23 @@ type ResolverStatus struct {
 	Running  bool               `json:"running"`
 	LastRun  *ResolverRunRecord `json:"lastRun,omitempty"`
 	Progress *ResolverProgress  `json:"progress,omitempty"`
+	// Providers reports rate limits and circuit breakers once a run has
+	// used them.
+	Providers map[string]ResolverProviderHealth `json:"providers,omitempty"`
+	// CheckpointedSelectors counts selectors an interrupted run completed;
+	// a resume skips them.
+	CheckpointedSelectors int `json:"checkpointedSelectors,omitempty"`
 }
 
 type resolverStats struct {
 	SelectorsTotal   int
 	SelectorsDone    int
+	SelectorsFailed  int
+	SelectorsSkipped int
 	PrefixesResolved int
 	PerProvider      map[string]ResolverProviderProgress
+	Errors           []ResolverRunError
+	ErrorsByProvider map[string]int
 }
 
 type runResolvers struct {
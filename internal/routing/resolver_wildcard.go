@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"split-vpn-webui/internal/netbind"
 	"split-vpn-webui/internal/vpn"
 )
 
@@ -19,19 +20,22 @@ const resolverWildcardEndpoint = "https://crt.sh/"
 type crtSHWildcardResolver struct {
 	baseURL string
 	client  *http.Client
+	// limiter paces requests and backs off on throttling; nil sends
+	// requests directly.
+	limiter *providerLimiter
 }
 
 type crtSHEntry struct {
 	NameValue string `json:"name_value"`
 }
 
-func newCRTSHWildcardResolver(timeout time.Duration) *crtSHWildcardResolver {
+func newCRTSHWildcardResolver(timeout time.Duration, egress netbind.Egress) *crtSHWildcardResolver {
 	if timeout <= 0 {
 		timeout = 10 * time.Second
 	}
 	return &crtSHWildcardResolver{
 		baseURL: resolverWildcardEndpoint,
-		client:  &http.Client{Timeout: timeout},
+		client:  netbind.HTTPClient(timeout, egress),
 	}
 }
 
@@ -57,7 +61,7 @@ func (r *crtSHWildcardResolver) Resolve(ctx context.Context, wildcard string) ([
 	if err != nil {
 		return nil, err
 	}
-	resp, err := r.client.Do(req)
+	resp, err := r.limiter.do(ctx, r.client, req)
 	if err != nil {
 		return nil, err
 	}
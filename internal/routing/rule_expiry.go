@@ -0,0 +1,180 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const ruleExpiryInterval = 30 * time.Second
+
+// ExpiredRule identifies a rule whose expiry has passed.
+type ExpiredRule struct {
+	GroupID   int64  `json:"groupId"`
+	Group     string `json:"group"`
+	EgressVPN string `json:"egressVpn"`
+	RuleIndex int    `json:"ruleIndex"`
+	RuleName  string `json:"ruleName,omitempty"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// RuleExpired reports whether rule has expired at now.
+func RuleExpired(rule RoutingRule, now time.Time) bool {
+	return rule.ExpiresAt > 0 && rule.ExpiresAt <= now.Unix()
+}
+
+// withoutExpiredRules returns copies of groups whose expired rules are
+// replaced by selector-less placeholders. Keeping the positions keeps the
+// set names of the remaining rules stable.
+func withoutExpiredRules(groups []DomainGroup, now time.Time) []DomainGroup {
+	out := make([]DomainGroup, len(groups))
+	for idx, group := range groups {
+		out[idx] = group
+		copied := false
+		for ruleIndex, rule := range group.Rules {
+			if !RuleExpired(rule, now) {
+				continue
+			}
+			if !copied {
+				out[idx].Rules = append([]RoutingRule(nil), group.Rules...)
+				copied = true
+			}
+			out[idx].Rules[ruleIndex] = RoutingRule{ID: rule.ID, Name: rule.Name, ExpiresAt: rule.ExpiresAt}
+		}
+	}
+	return out
+}
+
+// rulesExpiredBetween lists the rules whose expiry falls in (after, until].
+func rulesExpiredBetween(groups []DomainGroup, after, until time.Time) []ExpiredRule {
+	expired := make([]ExpiredRule, 0)
+	for _, group := range groups {
+		for ruleIndex, rule := range group.Rules {
+			if rule.ExpiresAt <= after.Unix() || !RuleExpired(rule, until) {
+				continue
+			}
+			expired = append(expired, ExpiredRule{
+				GroupID:   group.ID,
+				Group:     group.Name,
+				EgressVPN: group.EgressVPN,
+				RuleIndex: ruleIndex,
+				RuleName:  rule.Name,
+				ExpiresAt: rule.ExpiresAt,
+			})
+		}
+	}
+	return expired
+}
+
+// ExpireRules re-applies routing when a rule of the runtime groups expired
+// after since and up to now, and returns those rules. Applies already skip
+// expired rules; this removes them from live state when they lapse.
+func (m *Manager) ExpireRules(ctx context.Context, since, now time.Time) ([]ExpiredRule, error) {
+	m.mu.Lock()
+	groups, err := m.runtimeGroupsLocked(ctx)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	expired := rulesExpiredBetween(groups, since, now)
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	return expired, m.requestApply(ctx, applyKindFull)
+}
+
+// RuleExpiryWatcher disables rules as they expire and reports them.
+// Rules that expired while the service was stopped are skipped by the
+// startup apply without being reported.
+type RuleExpiryWatcher struct {
+	manager *Manager
+	now     func() time.Time
+
+	mu         sync.Mutex
+	since      time.Time
+	started    bool
+	handler    func(expired []ExpiredRule, err error)
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewRuleExpiryWatcher creates a watcher for manager's rules.
+func NewRuleExpiryWatcher(manager *Manager) (*RuleExpiryWatcher, error) {
+	if manager == nil {
+		return nil, fmt.Errorf("routing manager is required")
+	}
+	return &RuleExpiryWatcher{manager: manager, now: time.Now, since: time.Now()}, nil
+}
+
+// SetHandler registers a callback for expired rules and apply failures.
+func (w *RuleExpiryWatcher) SetHandler(handler func(expired []ExpiredRule, err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handler = handler
+}
+
+// Start launches the expiry loop.
+func (w *RuleExpiryWatcher) Start() error {
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.started = true
+	w.loopCancel = cancel
+	w.mu.Unlock()
+
+	w.loopWG.Add(1)
+	go func() {
+		defer w.loopWG.Done()
+		ticker := time.NewTicker(ruleExpiryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.Check(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the expiry loop.
+func (w *RuleExpiryWatcher) Stop() error {
+	w.mu.Lock()
+	loopCancel := w.loopCancel
+	w.started = false
+	w.loopCancel = nil
+	w.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	w.loopWG.Wait()
+	return nil
+}
+
+// Check disables the rules that expired since the previous check and
+// reports them to the handler.
+func (w *RuleExpiryWatcher) Check(ctx context.Context) ([]ExpiredRule, error) {
+	now := w.now()
+	w.mu.Lock()
+	since := w.since
+	w.mu.Unlock()
+
+	expired, err := w.manager.ExpireRules(ctx, since, now)
+	w.mu.Lock()
+	if err == nil {
+		w.since = now
+	}
+	handler := w.handler
+	w.mu.Unlock()
+	if handler != nil && (len(expired) > 0 || err != nil) {
+		handler(expired, err)
+	}
+	return expired, err
+}
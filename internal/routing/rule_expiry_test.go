@@ -0,0 +1,77 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestRuleExpiryDisablesExpiredRules(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, rules := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{{
+		Name:          "wg-sgp",
+		RouteTable:    201,
+		FWMark:        0x169,
+		InterfaceName: "wg-sgp",
+	}}})
+
+	now := time.Now()
+	expiresAt := now.Add(time.Hour).Unix()
+	group, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Laptop",
+		EgressVPN: "wg-sgp",
+		Rules: []RoutingRule{
+			{Name: "Work laptop", SourceMACs: []string{"00:11:22:33:44:55"}, ExpiresAt: expiresAt},
+			{Name: "Trial", Domains: []string{"trial.example"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if group.Rules[0].ExpiresAt != expiresAt {
+		t.Fatalf("expected expiry to persist, got %+v", group.Rules[0])
+	}
+	if len(rules.bindings) != 2 {
+		t.Fatalf("expected both rules to bind before expiry, got %d", len(rules.bindings))
+	}
+
+	watcher, err := NewRuleExpiryWatcher(manager)
+	if err != nil {
+		t.Fatalf("NewRuleExpiryWatcher failed: %v", err)
+	}
+	watcher.since = now
+	watcher.now = func() time.Time { return now.Add(30 * time.Minute) }
+	if expired, err := watcher.Check(ctx); err != nil || len(expired) != 0 {
+		t.Fatalf("expected nothing to expire yet, got %+v (%v)", expired, err)
+	}
+
+	// Apply compares against the wall clock, so move the expiry into the
+	// past rather than the watcher's clock into the future.
+	group.Rules[0].ExpiresAt = now.Add(-time.Minute).Unix()
+	if _, err := manager.store.Update(ctx, group.ID, *group); err != nil {
+		t.Fatalf("store.Update failed: %v", err)
+	}
+	watcher.since = now.Add(-time.Hour)
+	watcher.now = func() time.Time { return now }
+	var reported []ExpiredRule
+	watcher.SetHandler(func(expired []ExpiredRule, err error) { reported = expired })
+	expired, err := watcher.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0].Group != "Laptop" || expired[0].RuleIndex != 0 || expired[0].RuleName != "Work laptop" {
+		t.Fatalf("unexpected expired rules: %+v", expired)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("expected the handler to be notified, got %+v", reported)
+	}
+	if len(rules.bindings) != 1 || rules.bindings[0].RuleIndex != 1 {
+		t.Fatalf("expected only the unexpired rule to bind, got %+v", rules.bindings)
+	}
+
+	if expired, err := watcher.Check(ctx); err != nil || len(expired) != 0 {
+		t.Fatalf("expected an expired rule to be reported once, got %+v (%v)", expired, err)
+	}
+}
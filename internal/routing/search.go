@@ -0,0 +1,228 @@
+package routing
+
+import (
+	"context"
+	"strings"
+)
+
+// SelectorMatch is one place a searched selector lives.
+type SelectorMatch struct {
+	GroupID   int64  `json:"groupId"`
+	Group     string `json:"group"`
+	EgressVPN string `json:"egressVpn"`
+	// RuleIndex is -1 for matches on the group's own notes or tags.
+	RuleIndex int    `json:"ruleIndex"`
+	RuleName  string `json:"ruleName,omitempty"`
+	// Field is the rule field holding the value, using the rule's JSON
+	// names, or "resolved"/"resolvedExcluded" for resolver-derived prefixes.
+	// Notes and tags match as "notes"/"tags", with the matching note line
+	// as the value.
+	Field string `json:"field"`
+	Value string `json:"value"`
+	// Via names the domain, wildcard or ASN a resolved prefix came from.
+	Via string `json:"via,omitempty"`
+}
+
+// SelectorSearchResult lists matches in group order.
+type SelectorSearchResult struct {
+	Query     string          `json:"query"`
+	Matches   []SelectorMatch `json:"matches"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// SearchSelectors finds every rule selector matching query. Text matches are
+// case-insensitive substrings, so "netflix" finds netflix.com and
+// *.netflix.net; MACs also match with '-' separators. A query that parses as
+// an IP or CIDR additionally matches CIDR selectors and resolver-derived
+// prefixes that overlap it. Group and rule notes and tags match as text;
+// "tag:<name>" matches only groups and rules carrying that tag. limit <= 0
+// means no limit.
+func SearchSelectors(groups []DomainGroup, resolved map[ResolverSelector]ResolverValues, query string, limit int) SelectorSearchResult {
+	needle := strings.ToLower(strings.TrimSpace(query))
+	result := SelectorSearchResult{Query: needle, Matches: []SelectorMatch{}}
+	if needle == "" {
+		return result
+	}
+	if tag, ok := strings.CutPrefix(needle, "tag:"); ok {
+		return searchTags(groups, strings.TrimSpace(tag), result, limit)
+	}
+	macNeedle := strings.ReplaceAll(needle, "-", ":")
+	prefix, hasPrefix := parsePrefixOrAddr(needle)
+
+	add := func(group DomainGroup, index int, rule RoutingRule, field, value, via string) bool {
+		if limit > 0 && len(result.Matches) >= limit {
+			result.Truncated = true
+			return false
+		}
+		result.Matches = append(result.Matches, SelectorMatch{
+			GroupID:   group.ID,
+			Group:     group.Name,
+			EgressVPN: group.EgressVPN,
+			RuleIndex: index,
+			RuleName:  rule.Name,
+			Field:     field,
+			Value:     value,
+			Via:       via,
+		})
+		return true
+	}
+	textMatches := func(value string) bool {
+		return strings.Contains(strings.ToLower(value), needle)
+	}
+	cidrMatches := func(value string) bool {
+		if textMatches(value) {
+			return true
+		}
+		if !hasPrefix {
+			return false
+		}
+		candidate, ok := parsePrefixOrAddr(value)
+		return ok && candidate.Overlaps(prefix)
+	}
+
+	// annotations adds the notes lines and tags that contain the needle.
+	annotations := func(group DomainGroup, index int, rule RoutingRule, notes string, tags []string) bool {
+		for _, tag := range tags {
+			if textMatches(tag) && !add(group, index, rule, "tags", tag, "") {
+				return false
+			}
+		}
+		for _, line := range strings.Split(notes, "\n") {
+			if textMatches(line) && !add(group, index, rule, "notes", strings.TrimSpace(line), "") {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, group := range groups {
+		if !annotations(group, -1, RoutingRule{}, group.Notes, group.Tags) {
+			return result
+		}
+		for index, rule := range group.Rules {
+			if !annotations(group, index, rule, rule.Notes, rule.Tags) {
+				return result
+			}
+			fields := []struct {
+				name   string
+				values []string
+				match  func(string) bool
+			}{
+				{"domains", rule.Domains, textMatches},
+				{"wildcardDomains", rule.WildcardDomains, textMatches},
+				{"sourceInterfaces", rule.SourceInterfaces, textMatches},
+				{"sourceCidrs", rule.SourceCIDRs, cidrMatches},
+				{"excludedSourceCidrs", rule.ExcludedSourceCIDRs, cidrMatches},
+				{"sourceMacs", rule.SourceMACs, func(value string) bool { return strings.Contains(value, macNeedle) }},
+				{"sourceDeviceGroups", rule.SourceDeviceGroups, textMatches},
+				{"destinationCidrs", rule.DestinationCIDRs, cidrMatches},
+				{"excludedDestinationCidrs", rule.ExcludedDestinationCIDRs, cidrMatches},
+				{"destinationAsns", rule.DestinationASNs, textMatches},
+				{"excludedDestinationAsns", rule.ExcludedDestinationASNs, textMatches},
+			}
+			for _, field := range fields {
+				for _, value := range field.values {
+					if field.match(value) && !add(group, index, rule, field.name, value, "") {
+						return result
+					}
+				}
+			}
+			for _, host := range rule.StaticHosts {
+				for _, cidr := range host.CIDRs {
+					if (textMatches(host.Domain) || cidrMatches(cidr)) && !add(group, index, rule, "staticHosts", cidr, host.Domain) {
+						return result
+					}
+				}
+			}
+			if !hasPrefix {
+				continue
+			}
+			for _, source := range searchResolvedSources(rule) {
+				values := resolved[source.selector]
+				for _, value := range append(append([]string(nil), values.V4...), values.V6...) {
+					candidate, ok := parsePrefixOrAddr(value)
+					if !ok || !candidate.Overlaps(prefix) {
+						continue
+					}
+					if !add(group, index, rule, source.field, value, source.selector.Key) {
+						return result
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// searchTags lists the groups and rules tagged tag, compared without case.
+func searchTags(groups []DomainGroup, tag string, result SelectorSearchResult, limit int) SelectorSearchResult {
+	if tag == "" {
+		return result
+	}
+	for _, group := range groups {
+		// Index -1 stands for the group itself.
+		for index := -1; index < len(group.Rules); index++ {
+			tags, ruleName := group.Tags, ""
+			if index >= 0 {
+				tags, ruleName = group.Rules[index].Tags, group.Rules[index].Name
+			}
+			for _, value := range tags {
+				if !strings.EqualFold(value, tag) {
+					continue
+				}
+				if limit > 0 && len(result.Matches) >= limit {
+					result.Truncated = true
+					return result
+				}
+				result.Matches = append(result.Matches, SelectorMatch{
+					GroupID:   group.ID,
+					Group:     group.Name,
+					EgressVPN: group.EgressVPN,
+					RuleIndex: index,
+					RuleName:  ruleName,
+					Field:     "tags",
+					Value:     value,
+				})
+			}
+		}
+	}
+	return result
+}
+
+type searchResolvedSource struct {
+	field    string
+	selector ResolverSelector
+}
+
+// searchResolvedSources lists the resolver selectors feeding a rule's
+// destination and excluded destination sets, as mergeResolvedDestinations
+// and mergeResolvedDestinationExclusions read them.
+func searchResolvedSources(rule RoutingRule) []searchResolvedSource {
+	out := make([]searchResolvedSource, 0, len(rule.Domains)+len(rule.WildcardDomains)+len(rule.DestinationASNs)+len(rule.ExcludedDestinationASNs))
+	for _, domain := range rule.Domains {
+		out = append(out, searchResolvedSource{"resolved", ResolverSelector{Type: "domain", Key: domain}})
+	}
+	for _, wildcard := range rule.WildcardDomains {
+		out = append(out, searchResolvedSource{"resolved", ResolverSelector{Type: "wildcard", Key: wildcard}})
+	}
+	for _, asn := range rule.DestinationASNs {
+		out = append(out, searchResolvedSource{"resolved", ResolverSelector{Type: "asn", Key: asn}})
+	}
+	for _, asn := range rule.ExcludedDestinationASNs {
+		out = append(out, searchResolvedSource{"resolvedExcluded", ResolverSelector{Type: "asn", Key: asn}})
+	}
+	return out
+}
+
+// SearchSelectors searches the stored groups and resolver cache.
+func (m *Manager) SearchSelectors(ctx context.Context, query string, limit int) (SelectorSearchResult, error) {
+	groups, err := m.store.List(ctx)
+	if err != nil {
+		return SelectorSearchResult{}, err
+	}
+	resolved, err := m.store.LoadResolverSnapshot(ctx)
+	if err != nil {
+		return SelectorSearchResult{}, err
+	}
+	return SearchSelectors(groups, resolved, query, limit), nil
+}
@@ -0,0 +1,93 @@
+package routing
+
+import "testing"
+
+func TestSearchSelectorsMatchesTextCIDRsAndResolvedPrefixes(t *testing.T) {
+	groups := []DomainGroup{
+		{
+			ID:        1,
+			Name:      "Streaming",
+			EgressVPN: "wg-us",
+			Rules: []RoutingRule{{
+				Name:            "Netflix",
+				Domains:         []string{"netflix.com"},
+				WildcardDomains: []string{"*.nflxvideo.net"},
+				DestinationASNs: []string{"AS2906"},
+			}},
+		},
+		{
+			ID:        2,
+			Name:      "Devices",
+			EgressVPN: "wg-de",
+			Rules: []RoutingRule{{
+				SourceMACs:       []string{"00:11:22:33:44:55"},
+				DestinationCIDRs: []string{"45.57.0.0/16"},
+			}},
+		},
+	}
+	resolved := map[ResolverSelector]ResolverValues{
+		{Type: "asn", Key: "AS2906"}:               {V4: []string{"45.57.0.0/17"}},
+		{Type: "wildcard", Key: "*.nflxvideo.net"}: {V4: []string{"198.38.96.0/24"}},
+	}
+
+	result := SearchSelectors(groups, resolved, "NETFLIX", 0)
+	if len(result.Matches) != 1 || result.Matches[0].Field != "domains" || result.Matches[0].Group != "Streaming" {
+		t.Fatalf("unexpected text matches: %#v", result.Matches)
+	}
+
+	result = SearchSelectors(groups, resolved, "00-11-22", 0)
+	if len(result.Matches) != 1 || result.Matches[0].Field != "sourceMacs" || result.Matches[0].GroupID != 2 {
+		t.Fatalf("unexpected mac matches: %#v", result.Matches)
+	}
+
+	result = SearchSelectors(groups, resolved, "45.57.1.10", 0)
+	if len(result.Matches) != 2 {
+		t.Fatalf("expected resolved and cidr matches, got %#v", result.Matches)
+	}
+	if result.Matches[0].Field != "resolved" || result.Matches[0].Via != "AS2906" || result.Matches[0].Value != "45.57.0.0/17" {
+		t.Fatalf("unexpected resolved match: %#v", result.Matches[0])
+	}
+	if result.Matches[1].Field != "destinationCidrs" || result.Matches[1].Group != "Devices" {
+		t.Fatalf("unexpected cidr match: %#v", result.Matches[1])
+	}
+
+	result = SearchSelectors(groups, resolved, "45.57.1.10", 1)
+	if len(result.Matches) != 1 || !result.Truncated {
+		t.Fatalf("expected truncated result, got %#v", result)
+	}
+}
+
+func TestSearchSelectorsMatchesNotesAndTags(t *testing.T) {
+	groups := []DomainGroup{{
+		ID:        1,
+		Name:      "Streaming",
+		EgressVPN: "wg-us",
+		Notes:     "Living room TV.\nOwned by the media team.",
+		Tags:      []string{"Media"},
+		Rules: []RoutingRule{{
+			Name:    "Netflix",
+			Domains: []string{"netflix.com"},
+			Notes:   "CDN prefixes come from the ASN.",
+			Tags:    []string{"media", "cdn"},
+		}},
+	}}
+
+	result := SearchSelectors(groups, nil, "media team", 0)
+	if len(result.Matches) != 1 || result.Matches[0].Field != "notes" || result.Matches[0].RuleIndex != -1 ||
+		result.Matches[0].Value != "Owned by the media team." {
+		t.Fatalf("unexpected notes matches: %#v", result.Matches)
+	}
+
+	result = SearchSelectors(groups, nil, "cdn", 0)
+	if len(result.Matches) != 2 || result.Matches[0].Field != "tags" || result.Matches[1].Field != "notes" || result.Matches[1].RuleIndex != 0 {
+		t.Fatalf("unexpected rule annotation matches: %#v", result.Matches)
+	}
+
+	result = SearchSelectors(groups, nil, "tag:MEDIA", 0)
+	if len(result.Matches) != 2 || result.Matches[0].RuleIndex != -1 || result.Matches[1].RuleName != "Netflix" {
+		t.Fatalf("unexpected tag matches: %#v", result.Matches)
+	}
+	if result = SearchSelectors(groups, nil, "tag:med", 0); len(result.Matches) != 0 {
+		t.Fatalf("expected tag: queries to match whole tags only, got %#v", result.Matches)
+	}
+}
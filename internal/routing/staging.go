@@ -0,0 +1,270 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrStagingDisabled indicates a publish or discard while staging is off.
+var ErrStagingDisabled = fmt.Errorf("change staging is not enabled")
+
+// Group change actions.
+const (
+	GroupChangeCreate = "create"
+	GroupChangeUpdate = "update"
+	GroupChangeDelete = "delete"
+)
+
+// GroupChange is one pending difference between the saved groups and the
+// published set. Diff compares the group's JSON lines, staged against
+// published; it is empty for creates and deletes.
+type GroupChange struct {
+	Action    string   `json:"action"`
+	GroupID   int64    `json:"groupId"`
+	Name      string   `json:"name"`
+	EgressVPN string   `json:"egressVpn"`
+	Diff      LineDiff `json:"diff"`
+}
+
+// StagingStatus reports whether staging is on and what publishing would
+// change.
+type StagingStatus struct {
+	Enabled     bool          `json:"enabled"`
+	EnabledAt   int64         `json:"enabledAt,omitempty"`
+	PublishedAt int64         `json:"publishedAt,omitempty"`
+	Changes     []GroupChange `json:"changes"`
+}
+
+// DiffGroups lists the changes that turn published into staged, matching
+// groups by id. Timestamps and rule ids are ignored.
+func DiffGroups(published, staged []DomainGroup) []GroupChange {
+	changes := make([]GroupChange, 0)
+	before := make(map[int64]DomainGroup, len(published))
+	for _, group := range published {
+		before[group.ID] = group
+	}
+	seen := make(map[int64]struct{}, len(staged))
+	for _, group := range staged {
+		seen[group.ID] = struct{}{}
+		old, ok := before[group.ID]
+		if !ok {
+			changes = append(changes, GroupChange{Action: GroupChangeCreate, GroupID: group.ID, Name: group.Name, EgressVPN: group.EgressVPN})
+			continue
+		}
+		diff := diffLines(groupDiffLines(group), groupDiffLines(old))
+		if diff.Changed {
+			changes = append(changes, GroupChange{Action: GroupChangeUpdate, GroupID: group.ID, Name: group.Name, EgressVPN: group.EgressVPN, Diff: diff})
+		}
+	}
+	for _, group := range published {
+		if _, ok := seen[group.ID]; ok {
+			continue
+		}
+		changes = append(changes, GroupChange{Action: GroupChangeDelete, GroupID: group.ID, Name: group.Name, EgressVPN: group.EgressVPN})
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// groupDiffLines renders a group as indented JSON lines prefixed with their
+// field path, so the unordered line diff still says where a value lives.
+func groupDiffLines(group DomainGroup) []string {
+	detached := detachGroup(group)
+	detached.Name = group.Name
+	detached.EgressVPN = group.EgressVPN
+	for i := range detached.Rules {
+		detached.Rules[i].RawSelectors = nil
+	}
+	encoded, err := json.Marshal(detached)
+	if err != nil {
+		return nil
+	}
+	var tree any
+	if err := json.Unmarshal(encoded, &tree); err != nil {
+		return nil
+	}
+	lines := make([]string, 0)
+	flattenDiffLines("", tree, &lines)
+	return lines
+}
+
+func flattenDiffLines(path string, value any, lines *[]string) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, child := range typed {
+			flattenDiffLines(strings.TrimPrefix(path+"."+key, "."), child, lines)
+		}
+	case []any:
+		for idx, child := range typed {
+			flattenDiffLines(fmt.Sprintf("%s[%d]", path, idx), child, lines)
+		}
+	default:
+		encoded, _ := json.Marshal(typed)
+		*lines = append(*lines, path+" = "+string(encoded))
+	}
+}
+
+// runtimeGroupsLocked returns the groups runtime state follows: the
+// published set while staging is on, otherwise the saved groups.
+func (m *Manager) runtimeGroupsLocked(ctx context.Context) ([]DomainGroup, error) {
+	state, err := m.store.LoadStaging(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		return state.published, nil
+	}
+	return m.store.List(ctx)
+}
+
+// commitGroupEdit runs edit under the manager lock and, when it reports a
+// change, applies it through the batcher so rapid edits share one apply.
+// Staging holds the apply back until the next publish instead.
+func (m *Manager) commitGroupEdit(ctx context.Context, edit func() (bool, error)) error {
+	m.mu.Lock()
+	changed, err := edit()
+	staged := false
+	if err == nil && changed {
+		var state *stagingState
+		state, err = m.store.LoadStaging(ctx)
+		staged = state != nil
+	}
+	m.mu.Unlock()
+	if err != nil || !changed || staged {
+		return err
+	}
+	return m.requestApply(ctx, applyKindFull)
+}
+
+// republishLocked makes the saved groups the published set when staging is
+// on. It does not apply.
+func (m *Manager) republishLocked(ctx context.Context) error {
+	state, err := m.store.LoadStaging(ctx)
+	if err != nil || state == nil {
+		return err
+	}
+	groups, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	return m.store.SaveStaging(ctx, groups)
+}
+
+// StagingStatus reports the staging mode and the pending changes.
+func (m *Manager) StagingStatus(ctx context.Context) (*StagingStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stagingStatusLocked(ctx)
+}
+
+func (m *Manager) stagingStatusLocked(ctx context.Context) (*StagingStatus, error) {
+	state, err := m.store.LoadStaging(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &StagingStatus{Changes: []GroupChange{}}
+	if state == nil {
+		return status, nil
+	}
+	groups, err := m.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Enabled = true
+	status.EnabledAt = state.enabledAt
+	status.PublishedAt = state.publishedAt
+	status.Changes = DiffGroups(state.published, groups)
+	return status, nil
+}
+
+// SetStaging turns change staging on or off. Turning it on publishes the
+// current groups as they are; turning it off publishes any pending changes
+// with one apply.
+func (m *Manager) SetStaging(ctx context.Context, enabled bool) (*StagingStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, err := m.store.LoadStaging(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case enabled && state == nil:
+		groups, err := m.store.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.store.SaveStaging(ctx, groups); err != nil {
+			return nil, err
+		}
+	case !enabled && state != nil:
+		groups, err := m.store.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.store.DeleteStaging(ctx); err != nil {
+			return nil, err
+		}
+		if len(DiffGroups(state.published, groups)) > 0 {
+			if err := m.applyLocked(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m.stagingStatusLocked(ctx)
+}
+
+// PublishStaged applies all pending changes at once and makes them the
+// published set. If the apply fails the previous published set is restored
+// and applied again.
+func (m *Manager) PublishStaged(ctx context.Context) (*StagingStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, err := m.store.LoadStaging(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ErrStagingDisabled
+	}
+	groups, err := m.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.SaveStaging(ctx, groups); err != nil {
+		return nil, err
+	}
+	if err := m.applyLocked(ctx); err != nil {
+		if restoreErr := m.store.SaveStaging(ctx, state.published); restoreErr != nil {
+			return nil, fmt.Errorf("%v; restoring published groups failed: %w", err, restoreErr)
+		}
+		if restoreErr := m.applyLocked(ctx); restoreErr != nil {
+			return nil, fmt.Errorf("%v; restoring published policy failed: %w", err, restoreErr)
+		}
+		return nil, err
+	}
+	return m.stagingStatusLocked(ctx)
+}
+
+// DiscardStaged reverts the saved groups to the published set. Runtime state
+// already follows the published set, so nothing is applied.
+func (m *Manager) DiscardStaged(ctx context.Context) (*StagingStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, err := m.store.LoadStaging(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ErrStagingDisabled
+	}
+	if err := m.store.ReplaceGroups(ctx, state.published); err != nil {
+		return nil, err
+	}
+	return m.stagingStatusLocked(ctx)
+}
@@ -0,0 +1,110 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+func TestDiffGroupsReportsCreateUpdateDelete(t *testing.T) {
+	published := []DomainGroup{
+		{ID: 1, Name: "Streaming", EgressVPN: "wg-sgp", Rules: []RoutingRule{{ID: 10, Domains: []string{"max.com"}}}},
+		{ID: 2, Name: "Gaming", EgressVPN: "wg-sgp", Rules: []RoutingRule{{ID: 11, Domains: []string{"steam.com"}}}},
+	}
+	staged := []DomainGroup{
+		{ID: 1, Name: "Streaming", EgressVPN: "wg-sgp", UpdatedAt: 99, Rules: []RoutingRule{{ID: 12, Domains: []string{"max.com", "hbo.com"}}}},
+		{ID: 3, Name: "Work", EgressVPN: "wg-sgp", Rules: []RoutingRule{{Domains: []string{"example.com"}}}},
+	}
+	changes := DiffGroups(published, staged)
+	if len(changes) != 3 {
+		t.Fatalf("expected three changes, got %+v", changes)
+	}
+	if changes[0].Action != GroupChangeDelete || changes[0].Name != "Gaming" {
+		t.Fatalf("unexpected first change: %+v", changes[0])
+	}
+	if changes[1].Action != GroupChangeUpdate || changes[1].Name != "Streaming" {
+		t.Fatalf("unexpected second change: %+v", changes[1])
+	}
+	if len(changes[1].Diff.Added) != 1 || changes[1].Diff.Added[0] != `rules[0].domains[1] = "hbo.com"` || len(changes[1].Diff.Removed) != 0 {
+		t.Fatalf("unexpected update diff: %+v", changes[1].Diff)
+	}
+	if changes[2].Action != GroupChangeCreate || changes[2].Name != "Work" {
+		t.Fatalf("unexpected third change: %+v", changes[2])
+	}
+	if unchanged := DiffGroups(published, published); len(unchanged) != 0 {
+		t.Fatalf("expected no changes, got %+v", unchanged)
+	}
+}
+
+func TestManagerStagingHoldsEditsUntilPublish(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, rules := newRoutingTestManager(t, &mockVPNLister{profiles: []*vpn.VPNProfile{
+		{Name: "wg-sgp", RouteTable: 201, FWMark: 0x169, InterfaceName: "wg-sgp"},
+	}})
+	group, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Domains: []string{"max.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if _, err := manager.PublishStaged(ctx); !errors.Is(err, ErrStagingDisabled) {
+		t.Fatalf("expected ErrStagingDisabled, got %v", err)
+	}
+	if status, err := manager.SetStaging(ctx, true); err != nil || !status.Enabled || len(status.Changes) != 0 {
+		t.Fatalf("unexpected staging status %+v err=%v", status, err)
+	}
+	applies := rules.applyCount
+
+	group.Rules[0].Domains = append(group.Rules[0].Domains, "hbo.com")
+	group.Rules[0].RawSelectors = nil
+	if _, err := manager.UpdateGroup(ctx, group.ID, *group); err != nil {
+		t.Fatalf("UpdateGroup failed: %v", err)
+	}
+	if _, err := manager.CreateGroup(ctx, DomainGroup{
+		Name:      "Work",
+		EgressVPN: "wg-sgp",
+		Rules:     []RoutingRule{{Domains: []string{"example.com"}}},
+	}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if rules.applyCount != applies {
+		t.Fatalf("expected staged edits not to apply, got %d applies", rules.applyCount-applies)
+	}
+	if err := manager.Apply(ctx); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(rules.bindings) != 1 || rules.bindings[0].GroupName != "Streaming" {
+		t.Fatalf("expected apply to keep the published groups, got %+v", rules.bindings)
+	}
+	applies = rules.applyCount
+
+	status, err := manager.StagingStatus(ctx)
+	if err != nil || len(status.Changes) != 2 {
+		t.Fatalf("expected two pending changes, got %+v err=%v", status, err)
+	}
+	if status, err = manager.PublishStaged(ctx); err != nil || len(status.Changes) != 0 {
+		t.Fatalf("unexpected publish result %+v err=%v", status, err)
+	}
+	if rules.applyCount != applies+1 || len(rules.bindings) != 2 {
+		t.Fatalf("expected one apply with both groups, got %d applies and %+v", rules.applyCount-applies, rules.bindings)
+	}
+
+	if err := manager.DeleteGroup(ctx, group.ID); err != nil {
+		t.Fatalf("DeleteGroup failed: %v", err)
+	}
+	if status, err = manager.DiscardStaged(ctx); err != nil || len(status.Changes) != 0 {
+		t.Fatalf("unexpected discard result %+v err=%v", status, err)
+	}
+	restored, err := manager.GetGroup(ctx, group.ID)
+	if err != nil || len(restored.Rules[0].Domains) != 2 {
+		t.Fatalf("expected discard to bring back the published group, got %+v err=%v", restored, err)
+	}
+
+	if status, err = manager.SetStaging(ctx, false); err != nil || status.Enabled {
+		t.Fatalf("unexpected status after disabling %+v err=%v", status, err)
+	}
+}
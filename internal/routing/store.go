@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 )
 
 // Store persists routing groups and resolver cache rows in SQLite.
@@ -35,9 +36,9 @@ func (s *Store) Create(ctx context.Context, group DomainGroup) (*DomainGroup, er
 	defer tx.Rollback()
 
 	result, err := tx.ExecContext(ctx, `
-		INSERT INTO domain_groups (name, egress_vpn)
-		VALUES (?, ?)
-	`, normalized.Name, normalized.EgressVPN)
+		INSERT INTO domain_groups (name, egress_vpn, dns_redirect, dnsmasq_isolated, dnsmasq_upstreams, ipset_timeout_seconds, invert_destinations, full_tunnel, kill_switch, managed, notes, tags, priority)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, normalized.Name, normalized.EgressVPN, normalized.DNSRedirect, boolToInt(normalized.DnsmasqIsolated), strings.Join(normalized.DnsmasqUpstreams, ","), normalized.IPSetTimeoutSeconds, boolToInt(normalized.InvertDestinations), boolToInt(normalized.FullTunnel), boolToInt(normalized.KillSwitch), normalized.Managed, normalized.Notes, strings.Join(normalized.Tags, ","), normalized.Priority)
 	if err != nil {
 		return nil, err
 	}
@@ -77,9 +78,10 @@ func (s *Store) Update(ctx context.Context, id int64, group DomainGroup) (*Domai
 
 	result, err := tx.ExecContext(ctx, `
 		UPDATE domain_groups
-		SET name = ?, egress_vpn = ?, updated_at = strftime('%s','now')
+		SET name = ?, egress_vpn = ?, dns_redirect = ?, dnsmasq_isolated = ?, dnsmasq_upstreams = ?, ipset_timeout_seconds = ?, invert_destinations = ?, full_tunnel = ?, kill_switch = ?, managed = ?, notes = ?, tags = ?, priority = ?,
+			updated_at = strftime('%s','now')
 		WHERE id = ?
-	`, normalized.Name, normalized.EgressVPN, id)
+	`, normalized.Name, normalized.EgressVPN, normalized.DNSRedirect, boolToInt(normalized.DnsmasqIsolated), strings.Join(normalized.DnsmasqUpstreams, ","), normalized.IPSetTimeoutSeconds, boolToInt(normalized.InvertDestinations), boolToInt(normalized.FullTunnel), boolToInt(normalized.KillSwitch), normalized.Managed, normalized.Notes, strings.Join(normalized.Tags, ","), normalized.Priority, id)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +106,44 @@ func (s *Store) Update(ctx context.Context, id int64, group DomainGroup) (*Domai
 	return s.Get(ctx, id)
 }
 
+const groupColumns = `id, name, egress_vpn, dns_redirect, dnsmasq_isolated, dnsmasq_upstreams, ipset_timeout_seconds, invert_destinations, full_tunnel, kill_switch, managed, notes, tags, priority, created_at, updated_at`
+
+func scanGroup(row interface{ Scan(dest ...any) error }, group *DomainGroup) error {
+	var isolated, inverted, fullTunnel, killSwitch int
+	var upstreams, tags string
+	if err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&group.EgressVPN,
+		&group.DNSRedirect,
+		&isolated,
+		&upstreams,
+		&group.IPSetTimeoutSeconds,
+		&inverted,
+		&fullTunnel,
+		&killSwitch,
+		&group.Managed,
+		&group.Notes,
+		&tags,
+		&group.Priority,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	group.DnsmasqIsolated = isolated != 0
+	group.InvertDestinations = inverted != 0
+	group.FullTunnel = fullTunnel != 0
+	group.KillSwitch = killSwitch != 0
+	if upstreams != "" {
+		group.DnsmasqUpstreams = strings.Split(upstreams, ",")
+	}
+	if tags != "" {
+		group.Tags = strings.Split(tags, ",")
+	}
+	return nil
+}
+
 // Delete removes a group and all dependent rows.
 func (s *Store) Delete(ctx context.Context, id int64) error {
 	if id <= 0 {
@@ -123,6 +163,54 @@ func (s *Store) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
+// Reorder assigns descending priorities to the listed groups, first one
+// highest. Groups not listed keep their priority.
+func (s *Store) Reorder(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("%w: group order is empty", ErrGroupValidation)
+	}
+	if len(ids) > maxGroupPriority {
+		return fmt.Errorf("%w: too many groups to order", ErrGroupValidation)
+	}
+	seen := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			return fmt.Errorf("%w: invalid group id", ErrGroupValidation)
+		}
+		if _, exists := seen[id]; exists {
+			return fmt.Errorf("%w: group %d listed twice", ErrGroupValidation, id)
+		}
+		seen[id] = struct{}{}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Count down from len(ids) so the default priority 0 stays below every
+	// ordered group.
+	for idx, id := range ids {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE domain_groups
+			SET priority = ?, updated_at = strftime('%s','now')
+			WHERE id = ?
+		`, len(ids)-idx, id)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrGroupNotFound
+		}
+	}
+	return tx.Commit()
+}
+
 // Get returns a single group by id.
 func (s *Store) Get(ctx context.Context, id int64) (*DomainGroup, error) {
 	if id <= 0 {
@@ -130,11 +218,11 @@ func (s *Store) Get(ctx context.Context, id int64) (*DomainGroup, error) {
 	}
 	var group DomainGroup
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, name, egress_vpn, created_at, updated_at
+		SELECT `+groupColumns+`
 		FROM domain_groups
 		WHERE id = ?
 	`, id)
-	if err := row.Scan(&group.ID, &group.Name, &group.EgressVPN, &group.CreatedAt, &group.UpdatedAt); err != nil {
+	if err := scanGroup(row, &group); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrGroupNotFound
 		}
@@ -159,12 +247,12 @@ func (s *Store) Get(ctx context.Context, id int64) (*DomainGroup, error) {
 	return &group, nil
 }
 
-// List returns all groups ordered by name.
+// List returns all groups, highest priority first, then by name.
 func (s *Store) List(ctx context.Context) ([]DomainGroup, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, name, egress_vpn, created_at, updated_at
+		SELECT `+groupColumns+`
 		FROM domain_groups
-		ORDER BY name ASC
+		ORDER BY priority DESC, name ASC
 	`)
 	if err != nil {
 		return nil, err
@@ -175,7 +263,7 @@ func (s *Store) List(ctx context.Context) ([]DomainGroup, error) {
 	groupIDs := make([]int64, 0)
 	for rows.Next() {
 		var group DomainGroup
-		if err := rows.Scan(&group.ID, &group.Name, &group.EgressVPN, &group.CreatedAt, &group.UpdatedAt); err != nil {
+		if err := scanGroup(rows, &group); err != nil {
 			return nil, err
 		}
 		groups = append(groups, group)
@@ -204,7 +292,6 @@ func (s *Store) List(ctx context.Context) ([]DomainGroup, error) {
 		groups[i].Rules = rules
 		groups[i].Domains = legacyDomainsFromRules(rules)
 	}
-	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
 	return groups, nil
 }
 
@@ -242,9 +329,9 @@ func (s *Store) ReplaceAll(
 
 	for _, group := range normalizedGroups {
 		result, err := tx.ExecContext(ctx, `
-			INSERT INTO domain_groups (name, egress_vpn)
-			VALUES (?, ?)
-		`, group.Name, group.EgressVPN)
+			INSERT INTO domain_groups (name, egress_vpn, dns_redirect, dnsmasq_isolated, dnsmasq_upstreams, ipset_timeout_seconds, invert_destinations, full_tunnel, kill_switch, managed, notes, tags, priority)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, group.Name, group.EgressVPN, group.DNSRedirect, boolToInt(group.DnsmasqIsolated), strings.Join(group.DnsmasqUpstreams, ","), group.IPSetTimeoutSeconds, boolToInt(group.InvertDestinations), boolToInt(group.FullTunnel), boolToInt(group.KillSwitch), group.Managed, group.Notes, strings.Join(group.Tags, ","), group.Priority)
 		if err != nil {
 			return err
 		}
@@ -277,7 +364,7 @@ func (s *Store) listRulesByGroup(ctx context.Context, groupID int64) ([]RoutingR
 func (s *Store) listRulesForGroups(ctx context.Context) (map[int64][]RoutingRule, error) {
 	rulesByGroup := make(map[int64][]RoutingRule)
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, group_id, name, position, exclude_multicast
+		SELECT id, group_id, name, position, exclude_multicast, upload_limit_kbit, download_limit_kbit, monitor_only, notes, tags, expires_at
 		FROM routing_rules
 		ORDER BY group_id ASC, position ASC, id ASC
 	`)
@@ -297,11 +384,17 @@ func (s *Store) listRulesForGroups(ctx context.Context) (map[int64][]RoutingRule
 		var entry storedRule
 		var position int
 		var excludeMulticast int
-		if err := rows.Scan(&entry.ruleID, &entry.groupID, &entry.rule.Name, &position, &excludeMulticast); err != nil {
+		var monitorOnly int
+		var tags string
+		if err := rows.Scan(&entry.ruleID, &entry.groupID, &entry.rule.Name, &position, &excludeMulticast, &entry.rule.UploadLimitKbit, &entry.rule.DownloadLimitKbit, &monitorOnly, &entry.rule.Notes, &tags, &entry.rule.ExpiresAt); err != nil {
 			return nil, err
 		}
 		entry.rule.ID = entry.ruleID
 		entry.rule.ExcludeMulticast = boolPointer(excludeMulticast != 0)
+		entry.rule.MonitorOnly = monitorOnly != 0
+		if tags != "" {
+			entry.rule.Tags = strings.Split(tags, ",")
+		}
 		stored = append(stored, entry)
 		ruleIDs = append(ruleIDs, entry.ruleID)
 	}
@@ -328,6 +421,10 @@ func (s *Store) listRulesForGroups(ctx context.Context) (map[int64][]RoutingRule
 	if err != nil {
 		return nil, err
 	}
+	sourceDeviceGroupsByRule, err := listRuleSourceDeviceGroups(ctx, s.db, ruleIDs)
+	if err != nil {
+		return nil, err
+	}
 	destByRule, err := listRuleCIDRs(ctx, s.db, "routing_rule_destination_cidrs", ruleIDs)
 	if err != nil {
 		return nil, err
@@ -356,6 +453,10 @@ func (s *Store) listRulesForGroups(ctx context.Context) (map[int64][]RoutingRule
 	if err != nil {
 		return nil, err
 	}
+	staticHostsByRule, err := listRuleStaticHosts(ctx, s.db, ruleIDs)
+	if err != nil {
+		return nil, err
+	}
 	rawSelectorsByRule, err := listRuleRawSelectors(ctx, s.db, ruleIDs)
 	if err != nil {
 		return nil, err
@@ -367,6 +468,7 @@ func (s *Store) listRulesForGroups(ctx context.Context) (map[int64][]RoutingRule
 		rule.SourceCIDRs = append([]string(nil), sourceByRule[entry.ruleID]...)
 		rule.ExcludedSourceCIDRs = append([]string(nil), excludedSourceByRule[entry.ruleID]...)
 		rule.SourceMACs = append([]string(nil), sourceMACsByRule[entry.ruleID]...)
+		rule.SourceDeviceGroups = append([]string(nil), sourceDeviceGroupsByRule[entry.ruleID]...)
 		rule.DestinationCIDRs = append([]string(nil), destByRule[entry.ruleID]...)
 		rule.ExcludedDestinationCIDRs = append([]string(nil), excludedDestByRule[entry.ruleID]...)
 		rule.DestinationPorts = append([]PortRange(nil), portsByRule[entry.ruleID]...)
@@ -375,6 +477,7 @@ func (s *Store) listRulesForGroups(ctx context.Context) (map[int64][]RoutingRule
 		rule.ExcludedDestinationASNs = append([]string(nil), excludedASNByRule[entry.ruleID]...)
 		rule.Domains = append([]string(nil), domainsByRule[entry.ruleID]...)
 		rule.WildcardDomains = append([]string(nil), wildcardsByRule[entry.ruleID]...)
+		rule.StaticHosts = staticHostsByRule[entry.ruleID]
 		rawSelectors := rawSelectorsByRule[entry.ruleID]
 		rawSelectors = hydrateRuleRawSelectorsFromRule(rawSelectors, rule)
 		rawSelectors = finalizeRuleRawSelectors(rawSelectors, rule)
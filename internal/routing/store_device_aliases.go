@@ -0,0 +1,66 @@
+package routing
+
+import "context"
+
+// ListDeviceAliases returns all device aliases ordered by name.
+func (s *Store) ListDeviceAliases(ctx context.Context) ([]DeviceAlias, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT mac, name, updated_at
+		FROM device_aliases
+		ORDER BY name COLLATE NOCASE ASC, mac ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make([]DeviceAlias, 0)
+	for rows.Next() {
+		var alias DeviceAlias
+		if err := rows.Scan(&alias.MAC, &alias.Name, &alias.UpdatedAt); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, rows.Err()
+}
+
+// UpsertDeviceAlias stores the alias for a MAC, replacing any previous name.
+func (s *Store) UpsertDeviceAlias(ctx context.Context, alias DeviceAlias) (*DeviceAlias, error) {
+	normalized, err := NormalizeDeviceAlias(alias)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO device_aliases (mac, name) VALUES (?, ?)
+		ON CONFLICT(mac) DO UPDATE SET name = excluded.name, updated_at = strftime('%s','now')
+	`, normalized.MAC, normalized.Name); err != nil {
+		return nil, err
+	}
+	row := s.db.QueryRowContext(ctx, `SELECT mac, name, updated_at FROM device_aliases WHERE mac = ?`, normalized.MAC)
+	var stored DeviceAlias
+	if err := row.Scan(&stored.MAC, &stored.Name, &stored.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// DeleteDeviceAlias removes the alias for a MAC.
+func (s *Store) DeleteDeviceAlias(ctx context.Context, mac string) error {
+	normalized, err := NormalizeDeviceMAC(mac)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM device_aliases WHERE mac = ?`, normalized)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrDeviceAliasNotFound
+	}
+	return nil
+}
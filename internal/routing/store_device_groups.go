@@ -0,0 +1,367 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ListDeviceGroups returns all device groups ordered by name.
+func (s *Store) ListDeviceGroups(ctx context.Context) ([]DeviceGroup, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, created_at, updated_at
+		FROM device_groups
+		ORDER BY name ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]DeviceGroup, 0)
+	for rows.Next() {
+		var group DeviceGroup
+		if err := rows.Scan(&group.ID, &group.Name, &group.CreatedAt, &group.UpdatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return groups, nil
+	}
+
+	members, err := s.listDeviceGroupMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		fillDeviceGroupMembers(&groups[i], members[groups[i].ID])
+	}
+	return groups, nil
+}
+
+// GetDeviceGroup returns a single device group by id.
+func (s *Store) GetDeviceGroup(ctx context.Context, id int64) (*DeviceGroup, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid device group id", ErrGroupValidation)
+	}
+	var group DeviceGroup
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, name, created_at, updated_at
+		FROM device_groups
+		WHERE id = ?
+	`, id)
+	if err := row.Scan(&group.ID, &group.Name, &group.CreatedAt, &group.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeviceGroupNotFound
+		}
+		return nil, err
+	}
+	members, err := s.listDeviceGroupMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fillDeviceGroupMembers(&group, members[group.ID])
+	return &group, nil
+}
+
+// CreateDeviceGroup inserts a device group and its members.
+func (s *Store) CreateDeviceGroup(ctx context.Context, group DeviceGroup) (*DeviceGroup, error) {
+	normalized, err := NormalizeDeviceGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO device_groups (name) VALUES (?)`, normalized.Name)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	if err := replaceDeviceGroupMembersTx(ctx, tx, id, normalized, false); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetDeviceGroup(ctx, id)
+}
+
+// UpdateDeviceGroup overwrites a device group's name and configured members.
+// Synced MACs are kept; a rename also rewrites rule references.
+func (s *Store) UpdateDeviceGroup(ctx context.Context, id int64, group DeviceGroup) (*DeviceGroup, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid device group id", ErrGroupValidation)
+	}
+	normalized, err := NormalizeDeviceGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var previousName string
+	if err := tx.QueryRowContext(ctx, `SELECT name FROM device_groups WHERE id = ?`, id).Scan(&previousName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeviceGroupNotFound
+		}
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE device_groups
+		SET name = ?, updated_at = strftime('%s','now')
+		WHERE id = ?
+	`, normalized.Name, id); err != nil {
+		return nil, err
+	}
+	if err := replaceDeviceGroupMembersTx(ctx, tx, id, normalized, true); err != nil {
+		return nil, err
+	}
+	if previousName != normalized.Name {
+		if err := renameDeviceGroupRefsTx(ctx, tx, previousName, normalized.Name); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetDeviceGroup(ctx, id)
+}
+
+// DeleteDeviceGroup removes a device group unless a rule still references it.
+func (s *Store) DeleteDeviceGroup(ctx context.Context, id int64) error {
+	group, err := s.GetDeviceGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT g.name
+		FROM routing_rule_source_device_groups d
+		JOIN routing_rules r ON r.id = d.rule_id
+		JOIN domain_groups g ON g.id = r.group_id
+		WHERE d.device_group = ?
+		ORDER BY g.name ASC
+	`, group.Name)
+	if err != nil {
+		return err
+	}
+	users := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return fmt.Errorf("%w: referenced by %s", ErrDeviceGroupInUse, strings.Join(users, ", "))
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM device_groups WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrDeviceGroupNotFound
+	}
+	return nil
+}
+
+// ReplaceDeviceGroups replaces every device group in one transaction.
+func (s *Store) ReplaceDeviceGroups(ctx context.Context, groups []DeviceGroup) error {
+	normalized := make([]DeviceGroup, 0, len(groups))
+	for _, group := range groups {
+		value, err := NormalizeDeviceGroup(group)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, value)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM device_groups`); err != nil {
+		return err
+	}
+	for _, group := range normalized {
+		result, err := tx.ExecContext(ctx, `INSERT INTO device_groups (name) VALUES (?)`, group.Name)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if err := replaceDeviceGroupMembersTx(ctx, tx, id, group, false); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ReplaceSyncedMembers stores the MACs and reserved addresses last matched
+// by a group's sync patterns.
+func (s *Store) ReplaceSyncedMembers(ctx context.Context, id int64, macs, cidrs []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE device_groups SET updated_at = strftime('%s','now') WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrDeviceGroupNotFound
+	}
+	if err := replaceDeviceMembersOfKindTx(ctx, tx, id, deviceMemberSyncedMAC, macs); err != nil {
+		return err
+	}
+	if err := replaceDeviceMembersOfKindTx(ctx, tx, id, deviceMemberSyncedCIDR, cidrs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) listDeviceGroupMembers(ctx context.Context) (map[int64]map[string][]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT group_id, kind, value
+		FROM device_group_members
+		ORDER BY group_id ASC, id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64]map[string][]string)
+	for rows.Next() {
+		var groupID int64
+		var kind, value string
+		if err := rows.Scan(&groupID, &kind, &value); err != nil {
+			return nil, err
+		}
+		if result[groupID] == nil {
+			result[groupID] = make(map[string][]string)
+		}
+		result[groupID][kind] = append(result[groupID][kind], value)
+	}
+	return result, rows.Err()
+}
+
+func fillDeviceGroupMembers(group *DeviceGroup, members map[string][]string) {
+	group.MACs = append([]string(nil), members[deviceMemberMAC]...)
+	group.CIDRs = append([]string(nil), members[deviceMemberCIDR]...)
+	group.SyncNames = append([]string(nil), members[deviceMemberSync]...)
+	group.SyncedMACs = append([]string(nil), members[deviceMemberSyncedMAC]...)
+	sort.Strings(group.SyncedMACs)
+	group.SyncedCIDRs = append([]string(nil), members[deviceMemberSyncedCIDR]...)
+	sort.Strings(group.SyncedCIDRs)
+}
+
+func replaceDeviceGroupMembersTx(ctx context.Context, tx *sql.Tx, id int64, group DeviceGroup, keepSynced bool) error {
+	if err := replaceDeviceMembersOfKindTx(ctx, tx, id, deviceMemberMAC, group.MACs); err != nil {
+		return err
+	}
+	if err := replaceDeviceMembersOfKindTx(ctx, tx, id, deviceMemberCIDR, group.CIDRs); err != nil {
+		return err
+	}
+	if err := replaceDeviceMembersOfKindTx(ctx, tx, id, deviceMemberSync, group.SyncNames); err != nil {
+		return err
+	}
+	if len(group.SyncNames) == 0 || !keepSynced {
+		// Without sync patterns there is nothing to keep synced members current.
+		if err := replaceDeviceMembersOfKindTx(ctx, tx, id, deviceMemberSyncedMAC, nil); err != nil {
+			return err
+		}
+		return replaceDeviceMembersOfKindTx(ctx, tx, id, deviceMemberSyncedCIDR, nil)
+	}
+	return nil
+}
+
+func replaceDeviceMembersOfKindTx(ctx context.Context, tx *sql.Tx, id int64, kind string, values []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM device_group_members WHERE group_id = ? AND kind = ?`, id, kind); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO device_group_members (group_id, kind, value) VALUES (?, ?, ?)
+		`, id, kind, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameDeviceGroupRefsTx points rule references and their raw selector
+// lines at a renamed device group, keeping any trailing comments.
+func renameDeviceGroupRefsTx(ctx context.Context, tx *sql.Tx, from, to string) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE routing_rule_source_device_groups SET device_group = ? WHERE device_group = ?
+	`, to, from); err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, line FROM routing_rule_selector_lines WHERE selector = ?
+	`, selectorSourceDeviceGroups)
+	if err != nil {
+		return err
+	}
+	updates := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var line string
+		if err := rows.Scan(&id, &line); err != nil {
+			rows.Close()
+			return err
+		}
+		if value, ok := activeSelectorValue(line); ok && value == from {
+			updates[id] = strings.Replace(line, from, to, 1)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, line := range updates {
+		if _, err := tx.ExecContext(ctx, `UPDATE routing_rule_selector_lines SET line = ? WHERE id = ?`, line, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
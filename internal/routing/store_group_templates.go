@@ -0,0 +1,110 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const groupTemplateColumns = `id, name, description, definition, created_at, updated_at`
+
+// ListGroupTemplates returns all group templates ordered by name.
+func (s *Store) ListGroupTemplates(ctx context.Context) ([]GroupTemplate, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+groupTemplateColumns+` FROM group_templates ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]GroupTemplate, 0)
+	for rows.Next() {
+		template, err := scanGroupTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// GetGroupTemplate returns a single template by id.
+func (s *Store) GetGroupTemplate(ctx context.Context, id int64) (*GroupTemplate, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid template id", ErrGroupValidation)
+	}
+	row := s.db.QueryRowContext(ctx, `SELECT `+groupTemplateColumns+` FROM group_templates WHERE id = ?`, id)
+	template, err := scanGroupTemplate(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// CreateGroupTemplate validates and inserts a template.
+func (s *Store) CreateGroupTemplate(ctx context.Context, template GroupTemplate) (*GroupTemplate, error) {
+	normalized, err := NormalizeGroupTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := json.Marshal(normalized.Group)
+	if err != nil {
+		return nil, fmt.Errorf("encode template: %w", err)
+	}
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO group_templates (name, description, definition)
+		VALUES (?, ?, ?)
+	`, normalized.Name, normalized.Description, string(definition))
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroupTemplate(ctx, id)
+}
+
+// DeleteGroupTemplate removes a template by id.
+func (s *Store) DeleteGroupTemplate(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("%w: invalid template id", ErrGroupValidation)
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM group_templates WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrGroupTemplateNotFound
+	}
+	return nil
+}
+
+func scanGroupTemplate(scanner interface{ Scan(...any) error }) (GroupTemplate, error) {
+	var (
+		template   GroupTemplate
+		definition string
+	)
+	if err := scanner.Scan(
+		&template.ID,
+		&template.Name,
+		&template.Description,
+		&definition,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	); err != nil {
+		return GroupTemplate{}, err
+	}
+	if err := json.Unmarshal([]byte(definition), &template.Group); err != nil {
+		return GroupTemplate{}, fmt.Errorf("decode template %q: %w", template.Name, err)
+	}
+	return template, nil
+}
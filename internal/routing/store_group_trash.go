@@ -0,0 +1,130 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const groupTrashColumns = `id, group_id, name, egress_vpn, definition, deleted_at`
+
+// Trash moves a group into the recycle bin: the definition is copied into
+// group_trash and the group with its rules is deleted in one transaction.
+func (s *Store) Trash(ctx context.Context, id int64) (*TrashedGroup, error) {
+	group, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := json.Marshal(group)
+	if err != nil {
+		return nil, fmt.Errorf("encode group: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO group_trash (group_id, name, egress_vpn, definition)
+		VALUES (?, ?, ?, ?)
+	`, group.ID, group.Name, group.EgressVPN, string(definition))
+	if err != nil {
+		return nil, err
+	}
+	trashID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := tx.ExecContext(ctx, `DELETE FROM domain_groups WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := deleted.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, ErrGroupNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetTrash(ctx, trashID)
+}
+
+// ListTrash returns trashed groups, most recently deleted first.
+func (s *Store) ListTrash(ctx context.Context) ([]TrashedGroup, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+groupTrashColumns+` FROM group_trash ORDER BY deleted_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]TrashedGroup, 0)
+	for rows.Next() {
+		entry, err := scanTrashedGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetTrash returns a single trash entry by id.
+func (s *Store) GetTrash(ctx context.Context, id int64) (*TrashedGroup, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid trash id", ErrGroupValidation)
+	}
+	row := s.db.QueryRowContext(ctx, `SELECT `+groupTrashColumns+` FROM group_trash WHERE id = ?`, id)
+	entry, err := scanTrashedGroup(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupTrashNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// DeleteTrash permanently removes a trash entry by id.
+func (s *Store) DeleteTrash(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("%w: invalid trash id", ErrGroupValidation)
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM group_trash WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrGroupTrashNotFound
+	}
+	return nil
+}
+
+func scanTrashedGroup(scanner interface{ Scan(...any) error }) (TrashedGroup, error) {
+	var (
+		entry      TrashedGroup
+		definition string
+	)
+	if err := scanner.Scan(
+		&entry.ID,
+		&entry.GroupID,
+		&entry.Name,
+		&entry.EgressVPN,
+		&definition,
+		&entry.DeletedAt,
+	); err != nil {
+		return TrashedGroup{}, err
+	}
+	if err := json.Unmarshal([]byte(definition), &entry.Group); err != nil {
+		return TrashedGroup{}, fmt.Errorf("decode trashed group %q: %w", entry.Name, err)
+	}
+	return entry, nil
+}
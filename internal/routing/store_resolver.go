@@ -26,6 +26,7 @@ type ResolverRunRecord struct {
 	DurationMS       int64  `json:"durationMs,omitempty"`
 	SelectorsTotal   int    `json:"selectorsTotal"`
 	SelectorsDone    int    `json:"selectorsDone"`
+	SelectorsFailed  int    `json:"selectorsFailed"`
 	PrefixesResolved int    `json:"prefixesResolved"`
 	Error            string `json:"error,omitempty"`
 }
@@ -171,9 +172,9 @@ func (s *Store) SaveResolverRun(ctx context.Context, run ResolverRunRecord) (*Re
 	}
 	result, err := s.db.ExecContext(ctx, `
 		INSERT INTO resolver_runs (
-			started_at, finished_at, duration_ms, selectors_total, selectors_done, prefixes_resolved, error
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, run.StartedAt, finishedAt, durationMS, run.SelectorsTotal, run.SelectorsDone, run.PrefixesResolved, runErr)
+			started_at, finished_at, duration_ms, selectors_total, selectors_done, selectors_failed, prefixes_resolved, error
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.StartedAt, finishedAt, durationMS, run.SelectorsTotal, run.SelectorsDone, run.SelectorsFailed, run.PrefixesResolved, runErr)
 	if err != nil {
 		return nil, err
 	}
@@ -185,15 +186,38 @@ func (s *Store) SaveResolverRun(ctx context.Context, run ResolverRunRecord) (*Re
 	return &run, nil
 }
 
+const resolverRunColumns = `id, started_at, finished_at, duration_ms, selectors_total, selectors_done, selectors_failed, prefixes_resolved, error`
+
 // LastResolverRun returns the newest resolver run row or nil.
 func (s *Store) LastResolverRun(ctx context.Context) (*ResolverRunRecord, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, started_at, finished_at, duration_ms, selectors_total, selectors_done, prefixes_resolved, error
+		SELECT `+resolverRunColumns+`
 		FROM resolver_runs
 		ORDER BY id DESC
 		LIMIT 1
 	`)
+	run, err := scanResolverRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return run, err
+}
 
+// GetResolverRun returns one resolver run row by id.
+func (s *Store) GetResolverRun(ctx context.Context, id int64) (*ResolverRunRecord, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+resolverRunColumns+`
+		FROM resolver_runs
+		WHERE id = ?
+	`, id)
+	run, err := scanResolverRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrResolverRunNotFound
+	}
+	return run, err
+}
+
+func scanResolverRun(row interface{ Scan(dest ...any) error }) (*ResolverRunRecord, error) {
 	var run ResolverRunRecord
 	var finishedAt sql.NullInt64
 	var durationMS sql.NullInt64
@@ -205,12 +229,10 @@ func (s *Store) LastResolverRun(ctx context.Context) (*ResolverRunRecord, error)
 		&durationMS,
 		&run.SelectorsTotal,
 		&run.SelectorsDone,
+		&run.SelectorsFailed,
 		&run.PrefixesResolved,
 		&runErr,
 	); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
 		return nil, err
 	}
 	if finishedAt.Valid {
@@ -10,6 +10,7 @@ const (
 	selectorSourceCIDRs              = "source_cidrs"
 	selectorExcludedSourceCIDRs      = "excluded_source_cidrs"
 	selectorSourceMACs               = "source_macs"
+	selectorSourceDeviceGroups       = "source_device_groups"
 	selectorDestinationCIDRs         = "destination_cidrs"
 	selectorExcludedDestinationCIDRs = "excluded_destination_cidrs"
 	selectorDestinationPorts         = "destination_ports"
@@ -18,6 +19,7 @@ const (
 	selectorExcludedDestinationASNs  = "excluded_destination_asns"
 	selectorDomains                  = "domains"
 	selectorWildcardDomains          = "wildcard_domains"
+	selectorStaticHosts              = "static_hosts"
 )
 
 func insertRuleRawSelectorsTx(ctx context.Context, tx *sql.Tx, ruleID int64, raw *RuleRawSelectors) error {
@@ -27,6 +29,7 @@ func insertRuleRawSelectorsTx(ctx context.Context, tx *sql.Tx, ruleID int64, raw
 		selectorSourceCIDRs:              normalized.SourceCIDRs,
 		selectorExcludedSourceCIDRs:      normalized.ExcludedSourceCIDRs,
 		selectorSourceMACs:               normalized.SourceMACs,
+		selectorSourceDeviceGroups:       normalized.SourceDeviceGroups,
 		selectorDestinationCIDRs:         normalized.DestinationCIDRs,
 		selectorExcludedDestinationCIDRs: normalized.ExcludedDestinationCIDRs,
 		selectorDestinationPorts:         normalized.DestinationPorts,
@@ -35,6 +38,7 @@ func insertRuleRawSelectorsTx(ctx context.Context, tx *sql.Tx, ruleID int64, raw
 		selectorExcludedDestinationASNs:  normalized.ExcludedDestinationASNs,
 		selectorDomains:                  normalized.Domains,
 		selectorWildcardDomains:          normalized.WildcardDomains,
+		selectorStaticHosts:              normalized.StaticHosts,
 	}
 	for selector, lines := range linesBySelector {
 		for position, line := range lines {
@@ -78,6 +82,8 @@ func listRuleRawSelectors(ctx context.Context, db *sql.DB, ruleIDs []int64) (map
 			raw.ExcludedSourceCIDRs = append(raw.ExcludedSourceCIDRs, line)
 		case selectorSourceMACs:
 			raw.SourceMACs = append(raw.SourceMACs, line)
+		case selectorSourceDeviceGroups:
+			raw.SourceDeviceGroups = append(raw.SourceDeviceGroups, line)
 		case selectorDestinationCIDRs:
 			raw.DestinationCIDRs = append(raw.DestinationCIDRs, line)
 		case selectorExcludedDestinationCIDRs:
@@ -94,6 +100,8 @@ func listRuleRawSelectors(ctx context.Context, db *sql.DB, ruleIDs []int64) (map
 			raw.Domains = append(raw.Domains, line)
 		case selectorWildcardDomains:
 			raw.WildcardDomains = append(raw.WildcardDomains, line)
+		case selectorStaticHosts:
+			raw.StaticHosts = append(raw.StaticHosts, line)
 		}
 		result[ruleID] = raw
 	}
@@ -71,6 +71,29 @@ func listRuleSourceMACs(ctx context.Context, db *sql.DB, ruleIDs []int64) (map[i
 	return result, rows.Err()
 }
 
+func listRuleSourceDeviceGroups(ctx context.Context, db *sql.DB, ruleIDs []int64) (map[int64][]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT rule_id, device_group
+		FROM routing_rule_source_device_groups
+		ORDER BY rule_id ASC, id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64][]string)
+	for rows.Next() {
+		var ruleID int64
+		var name string
+		if err := rows.Scan(&ruleID, &name); err != nil {
+			return nil, err
+		}
+		result[ruleID] = append(result[ruleID], name)
+	}
+	return result, rows.Err()
+}
+
 func listRulePorts(ctx context.Context, db *sql.DB, ruleIDs []int64) (map[int64][]PortRange, error) {
 	rows, err := db.QueryContext(ctx, `
 		SELECT rule_id, protocol, start_port, end_port
@@ -198,3 +221,31 @@ func listRuleDomains(ctx context.Context, db *sql.DB, ruleIDs []int64) (map[int6
 	}
 	return domains, wildcards, nil
 }
+
+func listRuleStaticHosts(ctx context.Context, db *sql.DB, ruleIDs []int64) (map[int64][]StaticHostMapping, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT rule_id, domain, cidr
+		FROM routing_rule_static_hosts
+		ORDER BY rule_id ASC, id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64][]StaticHostMapping)
+	for rows.Next() {
+		var ruleID int64
+		var domain, cidr string
+		if err := rows.Scan(&ruleID, &domain, &cidr); err != nil {
+			return nil, err
+		}
+		hosts := result[ruleID]
+		if len(hosts) > 0 && hosts[len(hosts)-1].Domain == domain {
+			hosts[len(hosts)-1].CIDRs = append(hosts[len(hosts)-1].CIDRs, cidr)
+			continue
+		}
+		result[ruleID] = append(hosts, StaticHostMapping{Domain: domain, CIDRs: []string{cidr}})
+	}
+	return result, rows.Err()
+}
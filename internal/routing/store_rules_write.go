@@ -17,9 +17,9 @@ func replaceRulesTx(ctx context.Context, tx *sql.Tx, groupID int64, rules []Rout
 			excludeMulticast = *rule.ExcludeMulticast
 		}
 		result, err := tx.ExecContext(ctx, `
-			INSERT INTO routing_rules (group_id, name, position, exclude_multicast)
-			VALUES (?, ?, ?, ?)
-		`, groupID, rule.Name, idx, boolToInt(excludeMulticast))
+			INSERT INTO routing_rules (group_id, name, position, exclude_multicast, upload_limit_kbit, download_limit_kbit, monitor_only, notes, tags, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, groupID, rule.Name, idx, boolToInt(excludeMulticast), rule.UploadLimitKbit, rule.DownloadLimitKbit, boolToInt(rule.MonitorOnly), rule.Notes, strings.Join(rule.Tags, ","), rule.ExpiresAt)
 		if err != nil {
 			return err
 		}
@@ -55,6 +55,13 @@ func replaceRulesTx(ctx context.Context, tx *sql.Tx, groupID int64, rules []Rout
 				return err
 			}
 		}
+		for _, name := range rule.SourceDeviceGroups {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO routing_rule_source_device_groups (rule_id, device_group) VALUES (?, ?)
+			`, ruleID, name); err != nil {
+				return err
+			}
+		}
 		for _, cidr := range rule.DestinationCIDRs {
 			if _, err := tx.ExecContext(ctx, `
 				INSERT INTO routing_rule_destination_cidrs (rule_id, cidr) VALUES (?, ?)
@@ -115,6 +122,16 @@ func replaceRulesTx(ctx context.Context, tx *sql.Tx, groupID int64, rules []Rout
 				return err
 			}
 		}
+		for _, host := range rule.StaticHosts {
+			for _, cidr := range host.CIDRs {
+				if _, err := tx.ExecContext(ctx, `
+					INSERT INTO routing_rule_static_hosts (rule_id, domain, cidr)
+					VALUES (?, ?, ?)
+				`, ruleID, host.Domain, cidr); err != nil {
+					return err
+				}
+			}
+		}
 		if err := insertRuleRawSelectorsTx(ctx, tx, ruleID, rule.RawSelectors); err != nil {
 			return err
 		}
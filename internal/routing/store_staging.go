@@ -0,0 +1,108 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// stagingState is the persisted change-staging row.
+type stagingState struct {
+	published   []DomainGroup
+	enabledAt   int64
+	publishedAt int64
+}
+
+// LoadStaging returns the staging row, or nil when staging is off.
+func (s *Store) LoadStaging(ctx context.Context) (*stagingState, error) {
+	var (
+		state     stagingState
+		published string
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT published, enabled_at, published_at FROM routing_staging WHERE id = 1
+	`).Scan(&published, &state.enabledAt, &state.publishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(published), &state.published); err != nil {
+		return nil, fmt.Errorf("decode published groups: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveStaging stores groups as the published set, turning staging on if it
+// was off.
+func (s *Store) SaveStaging(ctx context.Context, published []DomainGroup) error {
+	if published == nil {
+		published = []DomainGroup{}
+	}
+	encoded, err := json.Marshal(published)
+	if err != nil {
+		return fmt.Errorf("encode published groups: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO routing_staging (id, published) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET published = excluded.published, published_at = strftime('%s','now')
+	`, string(encoded))
+	return err
+}
+
+// DeleteStaging turns staging off.
+func (s *Store) DeleteStaging(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM routing_staging`)
+	return err
+}
+
+// ReplaceGroups atomically replaces all groups, keeping their ids. Resolver
+// and pre-warm caches are left alone.
+func (s *Store) ReplaceGroups(ctx context.Context, groups []DomainGroup) error {
+	normalizedGroups := make([]DomainGroup, 0, len(groups))
+	for _, group := range groups {
+		normalized, err := NormalizeAndValidate(group)
+		if err != nil {
+			return err
+		}
+		normalizedGroups = append(normalizedGroups, normalized)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM domain_groups`); err != nil {
+		return err
+	}
+	for _, group := range normalizedGroups {
+		var id any
+		if group.ID > 0 {
+			id = group.ID
+		}
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO domain_groups (id, name, egress_vpn, dns_redirect, dnsmasq_isolated, dnsmasq_upstreams, ipset_timeout_seconds, invert_destinations, full_tunnel, kill_switch, managed, notes, tags, priority)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, group.Name, group.EgressVPN, group.DNSRedirect, boolToInt(group.DnsmasqIsolated), strings.Join(group.DnsmasqUpstreams, ","), group.IPSetTimeoutSeconds, boolToInt(group.InvertDestinations), boolToInt(group.FullTunnel), boolToInt(group.KillSwitch), group.Managed, group.Notes, strings.Join(group.Tags, ","), group.Priority)
+		if err != nil {
+			return err
+		}
+		groupID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if err := replaceRulesTx(ctx, tx, groupID, group.Rules); err != nil {
+			return err
+		}
+		if err := replaceLegacyDomainsTx(ctx, tx, groupID, group.Domains); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -81,6 +81,36 @@ func TestStoreCRUD(t *testing.T) {
 	}
 }
 
+func TestStoreReorderSetsPriorityAndListOrder(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	ids := make([]int64, 0, 3)
+	for _, name := range []string{"Alpha", "Bravo", "Charlie"} {
+		created, err := store.Create(ctx, DomainGroup{Name: name, EgressVPN: "wg-sgp", Domains: []string{"example.com"}})
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		ids = append(ids, created.ID)
+	}
+	if err := store.Reorder(ctx, []int64{ids[2], ids[0]}); err != nil {
+		t.Fatalf("reorder: %v", err)
+	}
+	groups, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("list groups: %v", err)
+	}
+	if len(groups) != 3 || groups[0].Name != "Charlie" || groups[0].Priority != 2 || groups[1].Name != "Alpha" || groups[1].Priority != 1 || groups[2].Name != "Bravo" || groups[2].Priority != 0 {
+		t.Fatalf("unexpected order %+v", groups)
+	}
+	if err := store.Reorder(ctx, []int64{ids[0], ids[0]}); !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected ErrGroupValidation for duplicate ids, got %v", err)
+	}
+	if err := store.Reorder(ctx, []int64{ids[0], 9999}); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("expected ErrGroupNotFound for unknown id, got %v", err)
+	}
+}
+
 func TestStoreValidationAndNotFound(t *testing.T) {
 	ctx := context.Background()
 	store := newTestStore(t)
@@ -173,6 +203,49 @@ func TestStorePersistsSourceInterfaceAndMACSelectors(t *testing.T) {
 	}
 }
 
+func TestStorePersistsNotesAndTags(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	created, err := store.Create(ctx, DomainGroup{
+		Name:      "Streaming",
+		EgressVPN: "wg-us",
+		Notes:     "  US catalogue for the living room TV.\r\nAsk before changing.  ",
+		Tags:      []string{" streaming ", "TV", "Streaming", ""},
+		Rules: []RoutingRule{{
+			Domains: []string{"netflix.com"},
+			Notes:   "Netflix needs its CDN ASN as well.",
+			Tags:    []string{"netflix"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	fetched, err := store.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get group: %v", err)
+	}
+	if fetched.Notes != "US catalogue for the living room TV.\nAsk before changing." {
+		t.Fatalf("unexpected group notes: %q", fetched.Notes)
+	}
+	if len(fetched.Tags) != 2 || fetched.Tags[0] != "streaming" || fetched.Tags[1] != "TV" {
+		t.Fatalf("unexpected group tags: %#v", fetched.Tags)
+	}
+	rule := fetched.Rules[0]
+	if rule.Notes != "Netflix needs its CDN ASN as well." || len(rule.Tags) != 1 || rule.Tags[0] != "netflix" {
+		t.Fatalf("unexpected rule annotations: %q %#v", rule.Notes, rule.Tags)
+	}
+
+	if _, err := store.Create(ctx, DomainGroup{
+		Name:      "Bad",
+		EgressVPN: "wg-us",
+		Tags:      []string{"a,b"},
+		Rules:     []RoutingRule{{Domains: []string{"example.com"}}},
+	}); !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected a tag with a comma to be rejected, got %v", err)
+	}
+}
+
 func TestStoreAllowsExactAndWildcardSelectorsInSameRule(t *testing.T) {
 	ctx := context.Background()
 	store := newTestStore(t)
@@ -281,6 +354,9 @@ func TestStorePersistsExclusionSelectorsAndMulticastFlag(t *testing.T) {
 				DestinationASNs:          []string{"AS15169"},
 				ExcludedDestinationASNs:  []string{"AS13335"},
 				ExcludeMulticast:         &disabled,
+				UploadLimitKbit:          5000,
+				DownloadLimitKbit:        20000,
+				MonitorOnly:              true,
 				RawSelectors: &RuleRawSelectors{
 					ExcludedSourceCIDRs:      []string{"10.0.0.10/32#bypass host"},
 					ExcludedDestinationCIDRs: []string{"17.0.0.0/8#bypass apple"},
@@ -317,7 +393,45 @@ func TestStorePersistsExclusionSelectorsAndMulticastFlag(t *testing.T) {
 	if RuleExcludeMulticastEnabled(rule) {
 		t.Fatalf("expected excludeMulticast to persist disabled")
 	}
+	if rule.UploadLimitKbit != 5000 || rule.DownloadLimitKbit != 20000 {
+		t.Fatalf("unexpected bandwidth limits: up %d down %d", rule.UploadLimitKbit, rule.DownloadLimitKbit)
+	}
+	if !rule.MonitorOnly {
+		t.Fatalf("expected monitorOnly to persist")
+	}
 	if rule.RawSelectors == nil || len(rule.RawSelectors.ExcludedDestinationPorts) != 1 || rule.RawSelectors.ExcludedDestinationPorts[0] != "udp:5353#mdns" {
 		t.Fatalf("unexpected raw excluded destination port lines: %#v", rule.RawSelectors)
 	}
 }
+
+func TestStoreDeviceAliasUpsertAndDelete(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	if _, err := store.UpsertDeviceAlias(ctx, DeviceAlias{MAC: "AA-BB-CC-DD-EE-FF", Name: "TV"}); err != nil {
+		t.Fatalf("UpsertDeviceAlias failed: %v", err)
+	}
+	updated, err := store.UpsertDeviceAlias(ctx, DeviceAlias{MAC: "aa:bb:cc:dd:ee:ff", Name: " Living Room TV "})
+	if err != nil {
+		t.Fatalf("second UpsertDeviceAlias failed: %v", err)
+	}
+	if updated.MAC != "aa:bb:cc:dd:ee:ff" || updated.Name != "Living Room TV" {
+		t.Fatalf("unexpected alias: %+v", updated)
+	}
+	aliases, err := store.ListDeviceAliases(ctx)
+	if err != nil {
+		t.Fatalf("ListDeviceAliases failed: %v", err)
+	}
+	if len(aliases) != 1 {
+		t.Fatalf("expected one alias after upsert, got %#v", aliases)
+	}
+	if _, err := store.UpsertDeviceAlias(ctx, DeviceAlias{MAC: "not-a-mac", Name: "x"}); !errors.Is(err, ErrGroupValidation) {
+		t.Fatalf("expected validation error for bad mac, got %v", err)
+	}
+	if err := store.DeleteDeviceAlias(ctx, "aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Fatalf("DeleteDeviceAlias failed: %v", err)
+	}
+	if err := store.DeleteDeviceAlias(ctx, "aa:bb:cc:dd:ee:ff"); !errors.Is(err, ErrDeviceAliasNotFound) {
+		t.Fatalf("expected ErrDeviceAliasNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"runtime"
+	"time"
+
+	"split-vpn-webui/internal/stats"
+)
+
+const (
+	// adaptivePollInterval is how often load and stream clients are checked.
+	adaptivePollInterval = 15 * time.Second
+	// idlePollFactor stretches the stats poll while no stream client is
+	// connected; history, alerts and exports still get samples.
+	idlePollFactor = 4
+	// loadPollFactor stretches polls and broadcasts while the 1-minute load
+	// per CPU is at or above highLoadPerCPU.
+	loadPollFactor = 2
+	highLoadPerCPU = 1.5
+	// maxAdaptivePoll caps a stretched stats poll; a configured interval
+	// above it is left alone.
+	maxAdaptivePoll = 30 * time.Second
+)
+
+// pollBackoff is how far adaptive polling has stretched the configured
+// intervals.
+type pollBackoff struct {
+	idle     bool
+	highLoad bool
+}
+
+// statsFactor returns the multiplier for the stats poll interval.
+func (b pollBackoff) statsFactor() int {
+	factor := 1
+	if b.idle {
+		factor *= idlePollFactor
+	}
+	if b.highLoad {
+		factor *= loadPollFactor
+	}
+	return factor
+}
+
+// broadcastFactor returns the multiplier for the stream broadcast interval.
+// Without clients nothing is broadcast, so only load counts.
+func (b pollBackoff) broadcastFactor() int {
+	if b.highLoad {
+		return loadPollFactor
+	}
+	return 1
+}
+
+// stretchInterval applies factor to base without exceeding maxAdaptivePoll,
+// unless base already does.
+func stretchInterval(base time.Duration, factor int) time.Duration {
+	stretched := base * time.Duration(factor)
+	if limit := max(base, maxAdaptivePoll); stretched > limit {
+		return limit
+	}
+	return stretched
+}
+
+// highLoad reports whether the 1-minute load per CPU calls for backing off.
+func highLoad(load *stats.LoadAverage, cpus int) bool {
+	if load == nil || cpus <= 0 {
+		return false
+	}
+	return load.Load1/float64(cpus) >= highLoadPerCPU
+}
+
+func (s *Server) watcherCount() int {
+	s.watchersMu.Lock()
+	defer s.watchersMu.Unlock()
+	return len(s.watchers)
+}
+
+// setConfiguredPoll records the stats poll interval from settings and
+// applies it with the current backoff. It reports whether it changed.
+func (s *Server) setConfiguredPoll(poll time.Duration) bool {
+	s.pollMu.Lock()
+	defer s.pollMu.Unlock()
+	current := s.configuredPoll
+	if current == 0 {
+		current = s.stats.PollInterval()
+	}
+	s.configuredPoll = poll
+	s.stats.SetPollInterval(stretchInterval(poll, s.backoff.statsFactor()))
+	return poll != current
+}
+
+// currentBroadcastInterval returns the stream broadcast interval with the
+// current backoff applied.
+func (s *Server) currentBroadcastInterval() time.Duration {
+	s.pollMu.Lock()
+	defer s.pollMu.Unlock()
+	return stretchInterval(s.broadcastInterval, s.backoff.broadcastFactor())
+}
+
+// adaptPolling re-evaluates the backoff from stream clients and load and
+// applies the resulting stats poll interval.
+func (s *Server) adaptPolling() {
+	if s.stats == nil {
+		return
+	}
+	next := pollBackoff{
+		idle:     s.watcherCount() == 0,
+		highLoad: highLoad(s.stats.LoadAverage(), runtime.NumCPU()),
+	}
+	s.pollMu.Lock()
+	changed := next != s.backoff
+	s.backoff = next
+	configured := s.configuredPoll
+	if configured == 0 {
+		configured = s.defaultPoll
+	}
+	poll := stretchInterval(configured, next.statsFactor())
+	broadcast := stretchInterval(s.broadcastInterval, next.broadcastFactor())
+	if changed && configured > 0 {
+		s.stats.SetPollInterval(poll)
+	}
+	s.pollMu.Unlock()
+	if changed && s.diagLog != nil {
+		s.diagLog.Infof("adaptive_poll stats=%s broadcast=%s idle=%t high_load=%t", poll, broadcast, next.idle, next.highLoad)
+	}
+}
+
+// wakeAdaptivePolling asks the adaptive loop to re-evaluate now, used when
+// the first stream client attaches so it gets the configured rate at once.
+func (s *Server) wakeAdaptivePolling() {
+	select {
+	case s.pollWake <- struct{}{}:
+	default:
+	}
+}
+
+// runAdaptivePolling keeps the stats poll and broadcast intervals matched
+// to load and stream clients until stop is closed.
+func (s *Server) runAdaptivePolling(stop <-chan struct{}) {
+	if s.stats == nil {
+		return
+	}
+	ticker := time.NewTicker(adaptivePollInterval)
+	defer ticker.Stop()
+	s.adaptPolling()
+	for {
+		select {
+		case <-ticker.C:
+			s.adaptPolling()
+		case <-s.pollWake:
+			s.adaptPolling()
+		case <-stop:
+			return
+		}
+	}
+}
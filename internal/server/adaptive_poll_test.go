@@ -0,0 +1,63 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/stats"
+)
+
+func TestAdaptivePollingBacksOffWithoutClients(t *testing.T) {
+	s := &Server{
+		stats:             stats.NewCollector("", 2*time.Second, 10),
+		watchers:          make(map[chan streamMessage]struct{}),
+		broadcastInterval: 2 * time.Second,
+		defaultPoll:       2 * time.Second,
+		pollWake:          make(chan struct{}, 1),
+	}
+	s.adaptPolling()
+	if got := s.stats.PollInterval(); got != 8*time.Second {
+		t.Fatalf("expected idle poll of 8s, got %s", got)
+	}
+	if got := s.currentBroadcastInterval(); got != 2*time.Second {
+		t.Fatalf("expected broadcast to stay at 2s without load, got %s", got)
+	}
+
+	// A settings change while idle keeps the backoff.
+	if !s.setConfiguredPoll(5 * time.Second) {
+		t.Fatal("expected the configured poll change to be reported")
+	}
+	if got := s.stats.PollInterval(); got != 20*time.Second {
+		t.Fatalf("expected stretched poll of 20s, got %s", got)
+	}
+
+	// The first client wakes the loop and restores the configured rate.
+	ch := make(chan streamMessage, 1)
+	s.addWatcher(ch)
+	select {
+	case <-s.pollWake:
+	default:
+		t.Fatal("expected the first client to wake adaptive polling")
+	}
+	s.adaptPolling()
+	if got := s.stats.PollInterval(); got != 5*time.Second {
+		t.Fatalf("expected configured poll with a client, got %s", got)
+	}
+	s.removeWatcher(ch)
+}
+
+func TestAdaptivePollingLimits(t *testing.T) {
+	if got := stretchInterval(10*time.Second, idlePollFactor*loadPollFactor); got != maxAdaptivePoll {
+		t.Fatalf("expected stretched poll to be capped, got %s", got)
+	}
+	if got := stretchInterval(time.Minute, idlePollFactor); got != time.Minute {
+		t.Fatalf("expected a long configured poll to be left alone, got %s", got)
+	}
+	if !highLoad(&stats.LoadAverage{Load1: 6.2}, 4) || highLoad(&stats.LoadAverage{Load1: 3.9}, 4) || highLoad(nil, 4) {
+		t.Fatal("unexpected high-load threshold")
+	}
+	busy := pollBackoff{highLoad: true}
+	if busy.statsFactor() != loadPollFactor || busy.broadcastFactor() != loadPollFactor {
+		t.Fatalf("unexpected factors under load: %d %d", busy.statsFactor(), busy.broadcastFactor())
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import "time"
+
+// StartBackground launches the broadcaster loop.
+func (s *Server) StartBackground(stop <-chan struct{}) {
+	s.restoreConntrackAccounting()
+	if s.jobs != nil {
+		s.jobs.Start()
+		defer s.jobs.Stop()
+	}
+	if s.drift != nil {
+		_ = s.drift.Start()
+		defer func() { _ = s.drift.Stop() }()
+	}
+	if s.provision != nil {
+		_ = s.provision.Start()
+		defer func() { _ = s.provision.Stop() }()
+	}
+	if s.delegation != nil {
+		_ = s.delegation.Start()
+		defer func() { _ = s.delegation.Stop() }()
+	}
+	if s.deviceSync != nil {
+		_ = s.deviceSync.Start()
+		defer func() { _ = s.deviceSync.Stop() }()
+	}
+	if s.ruleExpiry != nil {
+		_ = s.ruleExpiry.Start()
+		defer func() { _ = s.ruleExpiry.Stop() }()
+	}
+	if s.wanFailover != nil {
+		_ = s.wanFailover.Start()
+		defer func() { _ = s.wanFailover.Stop() }()
+	}
+	if s.vpnTracker != nil {
+		_ = s.vpnTracker.Start()
+		defer func() { _ = s.vpnTracker.Stop() }()
+	}
+	if s.anomalies != nil {
+		_ = s.anomalies.Start()
+		defer func() { _ = s.anomalies.Stop() }()
+	}
+	if s.quotas != nil {
+		_ = s.quotas.Start()
+		defer func() { _ = s.quotas.Stop() }()
+	}
+	if s.onDemand != nil {
+		_ = s.onDemand.Start()
+		defer func() { _ = s.onDemand.Stop() }()
+	}
+	if s.endpoints != nil {
+		_ = s.endpoints.Start()
+		defer func() { _ = s.endpoints.Stop() }()
+	}
+	if s.unblock != nil {
+		_ = s.unblock.Start()
+		defer func() { _ = s.unblock.Stop() }()
+	}
+	if s.peerSync != nil {
+		_ = s.peerSync.Start()
+		defer func() { _ = s.peerSync.Stop() }()
+	}
+	if s.agents != nil {
+		_ = s.agents.Start()
+		defer func() { _ = s.agents.Stop() }()
+	}
+	if s.mqtt != nil {
+		_ = s.mqtt.Start()
+		defer func() { _ = s.mqtt.Stop() }()
+	}
+	if s.metricsExport != nil {
+		_ = s.metricsExport.Start()
+		defer func() { _ = s.metricsExport.Stop() }()
+	}
+	if s.logShip != nil {
+		_ = s.logShip.Start()
+		defer func() { _ = s.logShip.Stop() }()
+		if s.diagLog != nil {
+			entries, cancel := s.diagLog.Subscribe()
+			go s.forwardDiagLogs(entries)
+			defer cancel()
+		}
+	}
+	if s.ipfix != nil {
+		defer s.ipfix.Close()
+	}
+	if s.dbMaint != nil {
+		_ = s.dbMaint.Start()
+		defer func() { _ = s.dbMaint.Stop() }()
+	}
+	if s.hostnames != nil {
+		_ = s.hostnames.Start()
+		defer func() { _ = s.hostnames.Stop() }()
+	}
+	if s.autoUpdate != nil {
+		_ = s.autoUpdate.Start()
+		defer func() { _ = s.autoUpdate.Stop() }()
+	}
+	go s.runFlowHistoryRecorder(stop)
+	go s.runAdaptivePolling(stop)
+	go s.runLinkWatch(stop)
+	interval := s.currentBroadcastInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.broadcastUpdate(nil)
+			if next := s.currentBroadcastInterval(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -20,20 +20,43 @@ type deviceDirectory struct {
 	macByIP map[string]string
 	order   []string
 	seenMAC map[string]struct{}
+	sources map[string]map[string]struct{}
+	aliases map[string]string
+	// networks and fixedIPs hold each MAC's UniFi network name and fixed-IP
+	// reservation, when the controller reports them.
+	networks map[string]string
+	fixedIPs map[string]string
+	// source tags MACs added while one loader runs, e.g. "dhcp".
+	source string
 }
 
-func loadDeviceDirectory(ctx context.Context) deviceDirectory {
+func buildDeviceDirectory(ctx context.Context, options deviceDirectoryOptions) deviceDirectory {
 	directory := deviceDirectory{
-		byMAC:   make(map[string]string),
-		byIP:    make(map[string]string),
-		ipsMAC:  make(map[string]map[string]struct{}),
-		macByIP: make(map[string]string),
-		order:   make([]string, 0),
-		seenMAC: make(map[string]struct{}),
-	}
+		byMAC:    make(map[string]string),
+		byIP:     make(map[string]string),
+		ipsMAC:   make(map[string]map[string]struct{}),
+		macByIP:  make(map[string]string),
+		order:    make([]string, 0),
+		seenMAC:  make(map[string]struct{}),
+		sources:  make(map[string]map[string]struct{}),
+		aliases:  make(map[string]string),
+		networks: make(map[string]string),
+		fixedIPs: make(map[string]string),
+	}
+	directory.source = deviceSourceDHCP
 	loadDHCPLeaseDeviceNames(&directory)
+	directory.source = deviceSourceNeighbor
 	loadIPNeighborDeviceMACs(ctx, &directory)
+	directory.source = deviceSourceUDAPI
 	loadUDAPIClientDeviceNames(ctx, &directory)
+	if options.controller != nil {
+		directory.source = deviceSourceController
+		loadUniFiControllerDeviceNames(ctx, options.controller, &directory)
+	}
+	directory.applyDiscoveredHostnames(options.hostnames)
+	directory.source = deviceSourceAlias
+	directory.applyAliases(options.aliases)
+	directory.source = ""
 	return directory
 }
 
@@ -45,6 +68,7 @@ func (d *deviceDirectory) addMACName(mac, name string) {
 		return
 	}
 	d.ensureOrderedMAC(normalizedMAC)
+	d.markSource(normalizedMAC)
 	if normalizedName == "" {
 		return
 	}
@@ -69,6 +93,7 @@ func (d *deviceDirectory) addMACIP(mac, ip string) {
 		return
 	}
 	d.ensureOrderedMAC(normalizedMAC)
+	d.markSource(normalizedMAC)
 	bucket := d.ipsMAC[normalizedMAC]
 	if bucket == nil {
 		bucket = make(map[string]struct{})
@@ -106,6 +131,18 @@ func (d *deviceDirectory) ensureMaps() {
 	if d.seenMAC == nil {
 		d.seenMAC = make(map[string]struct{})
 	}
+	if d.sources == nil {
+		d.sources = make(map[string]map[string]struct{})
+	}
+	if d.aliases == nil {
+		d.aliases = make(map[string]string)
+	}
+	if d.networks == nil {
+		d.networks = make(map[string]string)
+	}
+	if d.fixedIPs == nil {
+		d.fixedIPs = make(map[string]string)
+	}
 }
 
 func (d *deviceDirectory) lookupMAC(mac string) (string, []string) {
@@ -138,6 +175,10 @@ type discoveredDevice struct {
 	MAC        string   `json:"mac"`
 	Name       string   `json:"name,omitempty"`
 	IPHints    []string `json:"ipHints,omitempty"`
+	Alias      string   `json:"alias,omitempty"`
+	Network    string   `json:"network,omitempty"`
+	FixedIP    string   `json:"fixedIp,omitempty"`
+	Sources    []string `json:"sources,omitempty"`
 	SearchText string   `json:"searchText,omitempty"`
 }
 
@@ -156,10 +197,17 @@ func (d *deviceDirectory) listDevices() []discoveredDevice {
 			searchParts = append(searchParts, name)
 		}
 		searchParts = append(searchParts, ips...)
+		if network := d.networks[mac]; network != "" {
+			searchParts = append(searchParts, network)
+		}
 		devices = append(devices, discoveredDevice{
 			MAC:        mac,
 			Name:       name,
 			IPHints:    ips,
+			Alias:      d.aliases[mac],
+			Network:    d.networks[mac],
+			FixedIP:    d.fixedIPs[mac],
+			Sources:    d.sourcesFor(mac),
 			SearchText: strings.ToLower(strings.Join(searchParts, " ")),
 		})
 	}
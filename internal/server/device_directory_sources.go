@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/hostnames"
+	"split-vpn-webui/internal/routing"
+	"split-vpn-webui/internal/settings"
+)
+
+const (
+	deviceSourceDHCP       = "dhcp"
+	deviceSourceNeighbor   = "neighbor"
+	deviceSourceUDAPI      = "udapi"
+	deviceSourceController = "controller"
+	deviceSourceAlias      = "alias"
+
+	defaultUniFiControllerURL  = "https://127.0.0.1"
+	defaultUniFiControllerSite = "default"
+	unifiControllerTimeout     = 3 * time.Second
+	unifiControllerMaxBody     = 8 << 20
+)
+
+// deviceDirectoryOptions carries the name sources that need configuration or
+// storage, merged after local lease, neighbor and UDAPI discovery.
+type deviceDirectoryOptions struct {
+	controller *unifiController
+	hostnames  map[string]hostnames.Name
+	aliases    []routing.DeviceAlias
+}
+
+// unifiController is a UniFi Network application reachable with an API key.
+type unifiController struct {
+	baseURL string
+	site    string
+	apiKey  string
+	client  *http.Client
+}
+
+// loadDeviceDirectory merges every configured device name source. Later
+// sources win, so aliases override controller names, which override leases.
+func (s *Server) loadDeviceDirectory(ctx context.Context) deviceDirectory {
+	options := deviceDirectoryOptions{}
+	if s.settings != nil {
+		if current, err := s.settings.Get(); err == nil {
+			options.controller = newUniFiController(current)
+		}
+	}
+	if s.hostnames != nil {
+		options.hostnames = s.hostnames.Names()
+	}
+	if s.routingManager != nil {
+		aliases, err := s.routingManager.ListDeviceAliases(ctx)
+		if err != nil {
+			if s.diagLog != nil {
+				s.diagLog.Warnf("device directory aliases unavailable: %v", err)
+			}
+		} else {
+			options.aliases = aliases
+		}
+	}
+	return buildDeviceDirectory(ctx, options)
+}
+
+// newUniFiController returns nil unless an API key is configured.
+func newUniFiController(current settings.Settings) *unifiController {
+	apiKey := strings.TrimSpace(current.UniFiControllerAPIKey)
+	if apiKey == "" {
+		return nil
+	}
+	baseURL := strings.TrimRight(strings.TrimSpace(current.UniFiControllerURL), "/")
+	if baseURL == "" {
+		baseURL = defaultUniFiControllerURL
+	}
+	site := strings.TrimSpace(current.UniFiControllerSite)
+	if site == "" {
+		site = defaultUniFiControllerSite
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if isLoopbackControllerURL(baseURL) {
+		// The console's own controller serves a self-signed certificate.
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &unifiController{
+		baseURL: baseURL,
+		site:    site,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: unifiControllerTimeout, Transport: transport},
+	}
+}
+
+// validateUniFiControllerURL accepts an empty value or an http(s) base URL.
+func validateUniFiControllerURL(raw string) error {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return nil
+	}
+	parsed, err := url.Parse(trimmed)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("unifiControllerUrl must be an http or https URL")
+	}
+	return nil
+}
+
+func isLoopbackControllerURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// loadUniFiControllerDeviceNames reads active and known clients from the
+// controller. Known clients carry user-assigned names for offline devices;
+// both carry the client's network and any fixed-IP reservation.
+func loadUniFiControllerDeviceNames(ctx context.Context, controller *unifiController, directory *deviceDirectory) {
+	networks := controller.networkNames(ctx)
+	for _, endpoint := range []string{"stat/sta", "rest/user"} {
+		payload, err := controller.get(ctx, endpoint)
+		if err != nil {
+			continue
+		}
+		ingestDevicePayload(payload, directory)
+		ingestUniFiClientDetails(payload, networks, directory)
+	}
+}
+
+// networkNames maps network ids to names; clients of known users only
+// carry the id.
+func (c *unifiController) networkNames(ctx context.Context) map[string]string {
+	names := make(map[string]string)
+	payload, err := c.get(ctx, "rest/networkconf")
+	if err != nil {
+		return names
+	}
+	for _, entry := range unifiDataEntries(payload) {
+		id := strings.TrimSpace(stringValue(entry["_id"]))
+		name := normalizeDeviceName(stringValue(entry["name"]))
+		if id != "" && name != "" {
+			names[id] = name
+		}
+	}
+	return names
+}
+
+// networkVLANs maps LAN network names to VLAN IDs. The untagged default
+// network has no VLAN and counts as VLAN 1; WAN and VPN networks are skipped.
+func (c *unifiController) networkVLANs(ctx context.Context) (map[string]int, error) {
+	payload, err := c.get(ctx, "rest/networkconf")
+	if err != nil {
+		return nil, err
+	}
+	vlans := make(map[string]int)
+	for _, entry := range unifiDataEntries(payload) {
+		name := normalizeDeviceName(stringValue(entry["name"]))
+		purpose := strings.ToLower(strings.TrimSpace(stringValue(entry["purpose"])))
+		if name == "" || (purpose != "" && purpose != "corporate" && purpose != "guest") {
+			continue
+		}
+		vlan := 1
+		if enabled, _ := entry["vlan_enabled"].(bool); enabled {
+			parsed, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(entry["vlan"])))
+			if err != nil || parsed < 1 || parsed > 4094 {
+				continue
+			}
+			vlan = parsed
+		}
+		vlans[name] = vlan
+	}
+	return vlans, nil
+}
+
+// ingestUniFiClientDetails records each client's network and, when the
+// reservation is switched on, its fixed IP.
+func ingestUniFiClientDetails(payload any, networks map[string]string, directory *deviceDirectory) {
+	directory.ensureMaps()
+	for _, entry := range unifiDataEntries(payload) {
+		mac := normalizeMAC(stringValue(entry["mac"]))
+		if mac == "" {
+			continue
+		}
+		network := networks[strings.TrimSpace(stringValue(entry["network_id"]))]
+		if network == "" {
+			network = normalizeDeviceName(stringValue(entry["network"]))
+		}
+		if network != "" {
+			directory.networks[mac] = network
+		}
+		if useFixedIP, _ := entry["use_fixedip"].(bool); useFixedIP {
+			if ip := normalizeIP(stringValue(entry["fixed_ip"])); ip != "" {
+				directory.fixedIPs[mac] = ip
+			}
+		}
+	}
+}
+
+// unifiDataEntries returns the objects of a controller response's data list.
+func unifiDataEntries(payload any) []map[string]any {
+	root, ok := payload.(map[string]any)
+	if !ok {
+		return nil
+	}
+	data, _ := root["data"].([]any)
+	entries := make([]map[string]any, 0, len(data))
+	for _, value := range data {
+		if entry, ok := value.(map[string]any); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (c *unifiController) get(ctx context.Context, endpoint string) (any, error) {
+	target := fmt.Sprintf("%s/proxy/network/api/s/%s/%s", c.baseURL, url.PathEscape(c.site), endpoint)
+	runCtx, cancel := context.WithTimeout(ctx, unifiControllerTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-API-KEY", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("controller %s returned %s", endpoint, resp.Status)
+	}
+	var decoded any
+	if err := json.NewDecoder(io.LimitReader(resp.Body, unifiControllerMaxBody)).Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+func (d *deviceDirectory) markSource(mac string) {
+	if d.source == "" {
+		return
+	}
+	bucket := d.sources[mac]
+	if bucket == nil {
+		bucket = make(map[string]struct{})
+		d.sources[mac] = bucket
+	}
+	bucket[d.source] = struct{}{}
+}
+
+func (d *deviceDirectory) sourcesFor(mac string) []string {
+	bucket := d.sources[mac]
+	if len(bucket) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(bucket))
+	for source := range bucket {
+		out = append(out, source)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// applyDiscoveredHostnames fills names only where no other source named the
+// address or its MAC; probed hostnames are the least authoritative source.
+func (d *deviceDirectory) applyDiscoveredHostnames(names map[string]hostnames.Name) {
+	d.ensureMaps()
+	previous := d.source
+	defer func() { d.source = previous }()
+	for ip, name := range names {
+		normalizedIP := normalizeIP(ip)
+		if normalizedIP == "" {
+			continue
+		}
+		d.source = name.Source
+		if d.byIP[normalizedIP] == "" {
+			d.addIPName(normalizedIP, name.Hostname)
+		}
+		if mac := d.macByIP[normalizedIP]; mac != "" && d.byMAC[mac] == "" {
+			d.addMACName(mac, name.Hostname)
+		}
+	}
+}
+
+// hostnameTargets lists neighbor addresses that leases do not already name.
+// Discovery is skipped when disabled in settings.
+func (s *Server) hostnameTargets(ctx context.Context) []netip.Addr {
+	if s.settings != nil {
+		if current, err := s.settings.Get(); err == nil &&
+			current.HostnameDiscoveryEnabled != nil && !*current.HostnameDiscoveryEnabled {
+			return nil
+		}
+	}
+	directory := deviceDirectory{}
+	loadDHCPLeaseDeviceNames(&directory)
+	loadIPNeighborDeviceMACs(ctx, &directory)
+	targets := make([]netip.Addr, 0, len(directory.macByIP))
+	for ip, mac := range directory.macByIP {
+		if directory.byIP[ip] != "" || directory.byMAC[mac] != "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			targets = append(targets, addr)
+		}
+	}
+	return targets
+}
+
+// applyAliases overrides discovered names, including the names of every IP
+// seen for an aliased MAC.
+func (d *deviceDirectory) applyAliases(aliases []routing.DeviceAlias) {
+	d.ensureMaps()
+	for _, alias := range aliases {
+		mac := normalizeMAC(alias.MAC)
+		name := normalizeDeviceName(alias.Name)
+		if mac == "" || name == "" {
+			continue
+		}
+		d.addMACName(mac, name)
+		d.aliases[mac] = name
+		for ip := range d.ipsMAC[mac] {
+			d.byIP[ip] = name
+		}
+	}
+}
@@ -1,6 +1,15 @@
 package server
 
-import "testing"
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"split-vpn-webui/internal/hostnames"
+	"split-vpn-webui/internal/routing"
+	"split-vpn-webui/internal/settings"
+)
 
 func TestParseDHCPLeaseRows(t *testing.T) {
 	raw := `
@@ -138,3 +147,132 @@ func TestParseDeviceIdentifierMAC(t *testing.T) {
 		})
 	}
 }
+
+func TestDeviceDirectoryAliasesOverrideNamesAndTrackSources(t *testing.T) {
+	directory := deviceDirectory{}
+	directory.source = deviceSourceDHCP
+	directory.addMACName("00:11:22:33:44:55", "android-1234")
+	directory.addIPName("10.0.1.10", "android-1234")
+	directory.addMACIP("00:11:22:33:44:55", "10.0.1.10")
+	directory.source = deviceSourceAlias
+	directory.applyAliases([]routing.DeviceAlias{
+		{MAC: "00:11:22:33:44:55", Name: "Kids Tablet"},
+		{MAC: "00:11:22:33:44:66", Name: "Offline Console"},
+	})
+
+	list := directory.listDevices()
+	if len(list) != 2 {
+		t.Fatalf("expected discovered and alias-only devices, got %#v", list)
+	}
+	if list[0].Name != "Kids Tablet" || list[0].Alias != "Kids Tablet" {
+		t.Fatalf("expected alias to override lease name, got %#v", list[0])
+	}
+	if len(list[0].Sources) != 2 || list[0].Sources[0] != deviceSourceAlias || list[0].Sources[1] != deviceSourceDHCP {
+		t.Fatalf("unexpected sources: %#v", list[0].Sources)
+	}
+	if directory.lookupIP("10.0.1.10") != "Kids Tablet" {
+		t.Fatalf("expected alias to rename the device's IPs")
+	}
+}
+
+func TestLoadUniFiControllerDeviceNamesUsesAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-KEY") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/proxy/network/api/s/home/stat/sta":
+			_, _ = w.Write([]byte(`{"data":[{"mac":"AA:BB:CC:DD:EE:01","hostname":"ipad","ip":"10.0.1.40","network":"Kids"}]}`))
+		case "/proxy/network/api/s/home/rest/user":
+			_, _ = w.Write([]byte(`{"data":[{"mac":"aa:bb:cc:dd:ee:02","name":"Printer","network_id":"n2","use_fixedip":true,"fixed_ip":"10.0.20.5"},{"mac":"aa:bb:cc:dd:ee:03","name":"Laptop","use_fixedip":false,"fixed_ip":"10.0.1.99"}]}`))
+		case "/proxy/network/api/s/home/rest/networkconf":
+			_, _ = w.Write([]byte(`{"data":[{"_id":"n2","name":"IoT"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	controller := newUniFiController(settings.Settings{
+		UniFiControllerURL:    server.URL + "/",
+		UniFiControllerSite:   "home",
+		UniFiControllerAPIKey: "secret",
+	})
+	directory := deviceDirectory{source: deviceSourceController}
+	loadUniFiControllerDeviceNames(context.Background(), controller, &directory)
+
+	if name, hints := directory.lookupMAC("aa:bb:cc:dd:ee:01"); name != "ipad" || len(hints) != 1 {
+		t.Fatalf("unexpected active client mapping: %q %#v", name, hints)
+	}
+	if name, _ := directory.lookupMAC("aa:bb:cc:dd:ee:02"); name != "Printer" {
+		t.Fatalf("unexpected known client name: %q", name)
+	}
+	devices := map[string]discoveredDevice{}
+	for _, device := range directory.listDevices() {
+		devices[device.MAC] = device
+	}
+	if got := devices["aa:bb:cc:dd:ee:01"]; got.Network != "Kids" || got.FixedIP != "" {
+		t.Fatalf("unexpected active client details: %#v", got)
+	}
+	if got := devices["aa:bb:cc:dd:ee:02"]; got.Network != "IoT" || got.FixedIP != "10.0.20.5" {
+		t.Fatalf("unexpected reserved client details: %#v", got)
+	}
+	if got := devices["aa:bb:cc:dd:ee:03"]; got.FixedIP != "" {
+		t.Fatalf("expected disabled reservation to be ignored: %#v", got)
+	}
+	if newUniFiController(settings.Settings{UniFiControllerURL: server.URL}) != nil {
+		t.Fatalf("expected no controller without an API key")
+	}
+}
+
+func TestDeviceDirectoryDiscoveredHostnamesOnlyFillGaps(t *testing.T) {
+	directory := deviceDirectory{}
+	directory.addMACName("00:11:22:33:44:55", "lease-name")
+	directory.addMACIP("00:11:22:33:44:55", "10.0.1.10")
+	directory.addMACIP("00:11:22:33:44:66", "10.0.1.20")
+
+	directory.applyDiscoveredHostnames(map[string]hostnames.Name{
+		"10.0.1.10": {Hostname: "probed-a", Source: "mdns"},
+		"10.0.1.20": {Hostname: "DESKTOP-7Q2", Source: "netbios"},
+	})
+
+	if name, _ := directory.lookupMAC("00:11:22:33:44:55"); name != "lease-name" {
+		t.Fatalf("expected lease name to win over probed name, got %q", name)
+	}
+	if name, _ := directory.lookupMAC("00:11:22:33:44:66"); name != "DESKTOP-7Q2" {
+		t.Fatalf("expected probed name for unnamed device, got %q", name)
+	}
+	if directory.lookupIP("10.0.1.20") != "DESKTOP-7Q2" {
+		t.Fatalf("expected probed name for unnamed IP")
+	}
+	sources := directory.sourcesFor("00:11:22:33:44:66")
+	if len(sources) != 1 || sources[0] != "netbios" {
+		t.Fatalf("expected netbios source, got %#v", sources)
+	}
+}
+
+func TestUniFiControllerNetworkVLANs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/proxy/network/api/s/default/rest/networkconf" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[
+			{"name":"Default","purpose":"corporate"},
+			{"name":"IoT","purpose":"corporate","vlan_enabled":true,"vlan":20},
+			{"name":"Guests","purpose":"guest","vlan_enabled":true,"vlan":"30"},
+			{"name":"Internet 1","purpose":"wan"}
+		]}`))
+	}))
+	defer server.Close()
+
+	controller := newUniFiController(settings.Settings{UniFiControllerURL: server.URL, UniFiControllerAPIKey: "secret"})
+	vlans, err := controller.networkVLANs(context.Background())
+	if err != nil {
+		t.Fatalf("networkVLANs failed: %v", err)
+	}
+	if len(vlans) != 3 || vlans["Default"] != 1 || vlans["IoT"] != 20 || vlans["Guests"] != 30 {
+		t.Fatalf("unexpected network VLANs: %#v", vlans)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/flowhistory"
+)
+
+// flowHistoryInterval is how often the recorder samples conntrack. Closed TCP
+// connections stay in conntrack for two minutes (TIME_WAIT) and UDP ones for
+// at least 30 seconds, so most short-lived flows are caught even while no
+// inspector session is open.
+const flowHistoryInterval = 60 * time.Second
+
+// flowHistoryFlows converts inspector samples to history rows.
+func flowHistoryFlows(samples []flowInspectorSample) []flowhistory.Flow {
+	flows := make([]flowhistory.Flow, 0, len(samples))
+	for _, sample := range samples {
+		flows = append(flows, flowhistory.Flow{
+			Key:               sample.Key,
+			Protocol:          sample.Protocol,
+			SourceIP:          sample.SourceIP,
+			SourceMAC:         sample.SourceMAC,
+			SourceDevice:      sample.SourceDeviceName,
+			DestinationIP:     sample.DestinationIP,
+			DestinationPort:   sample.DestinationPort,
+			DestinationDomain: sample.DestinationDomain,
+			UploadBytes:       sample.UploadBytes,
+			DownloadBytes:     sample.DownloadBytes,
+		})
+	}
+	return flows
+}
+
+// recordFlowHistory stores sampled flows. Failures only reach the
+// diagnostics log; the live inspector view does not depend on history.
+func (s *Server) recordFlowHistory(ctx context.Context, vpnName string, samples []flowInspectorSample) {
+	if s.flowHistory == nil || len(samples) == 0 {
+		return
+	}
+	if err := s.flowHistory.Record(ctx, vpnName, flowHistoryFlows(samples)); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("flow_history record failed vpn=%s err=%v", vpnName, err)
+	}
+}
+
+// runFlowHistoryRecorder samples the flows of every VPN used as a routing
+// group egress until stop is closed, for history and IPFIX export.
+func (s *Server) runFlowHistoryRecorder(stop <-chan struct{}) {
+	if (s.flowHistory == nil && s.ipfix == nil) || s.routingManager == nil {
+		return
+	}
+	ticker := time.NewTicker(flowHistoryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sampleFlowHistory(context.Background())
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Server) sampleFlowHistory(ctx context.Context) {
+	groups, err := s.routingManager.ListGroups(ctx)
+	if err != nil {
+		if s.diagLog != nil {
+			s.diagLog.Warnf("flow_history list groups failed err=%v", err)
+		}
+		return
+	}
+	seen := make(map[string]struct{}, len(groups))
+	for _, group := range groups {
+		vpnName := strings.TrimSpace(group.EgressVPN)
+		if vpnName == "" {
+			continue
+		}
+		if _, dup := seen[vpnName]; dup {
+			continue
+		}
+		seen[vpnName] = struct{}{}
+		samples, _, err := s.collectVPNFlowSamples(ctx, vpnName)
+		if err != nil {
+			if s.diagLog != nil {
+				s.diagLog.Warnf("flow_history sample failed vpn=%s err=%v", vpnName, err)
+			}
+			continue
+		}
+		s.recordFlowHistory(ctx, vpnName, samples)
+		s.exportIPFIX(ctx, vpnName, samples)
+	}
+}
+
+func (s *Server) handleVPNFlowHistory(w http.ResponseWriter, r *http.Request) {
+	if s.flowHistory == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "flow history unavailable"})
+		return
+	}
+	vpnName, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	windowSeconds, ok := parseNonNegativeQuery(w, r, "window", int(flowhistory.DefaultWindow.Seconds()))
+	if !ok {
+		return
+	}
+	limit, ok := parseNonNegativeQuery(w, r, "limit", flowhistory.DefaultLimit)
+	if !ok {
+		return
+	}
+	sortBy := strings.TrimSpace(r.URL.Query().Get("sort"))
+	summary, err := s.flowHistory.Summarize(r.Context(), vpnName, time.Duration(windowSeconds)*time.Second, sortBy, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
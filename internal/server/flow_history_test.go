@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/flowhistory"
+)
+
+func TestVPNFlowHistoryHandler(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := flowhistory.NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	s := &Server{flowHistory: store}
+	s.recordFlowHistory(context.Background(), "wg-fra", []flowInspectorSample{
+		{Key: "a", Protocol: "tcp", SourceIP: "10.0.0.5", DestinationIP: "1.1.1.1", DestinationPort: 443, DestinationDomain: "big.example", DownloadBytes: 900},
+		{Key: "b", Protocol: "tcp", SourceIP: "10.0.0.5", DestinationIP: "2.2.2.2", DestinationPort: 443, DownloadBytes: 10},
+		{Key: "c", Protocol: "tcp", SourceIP: "10.0.0.6", DestinationIP: "2.2.2.2", DestinationPort: 80, UploadBytes: 10},
+	})
+	router := chi.NewRouter()
+	router.Get("/api/vpns/{name}/flow-inspector/history", s.handleVPNFlowHistory)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/vpns/wg-fra/flow-inspector/history?sort=flows&limit=1")
+	var summary flowhistory.Summary
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &summary) != nil {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+	if summary.Flows != 3 || summary.Sort != flowhistory.SortFlows || summary.WindowSeconds != 3600 ||
+		len(summary.Destinations) != 1 || summary.Destinations[0].Destination != "2.2.2.2" {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	if rec := get("/api/vpns/wg-fra/flow-inspector/history?window=-1"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative window, got %d", rec.Code)
+	}
+	unavailable := &Server{}
+	rec = httptest.NewRecorder()
+	unavailable.handleVPNFlowHistory(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without store, got %d", rec.Code)
+	}
+}
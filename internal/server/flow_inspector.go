@@ -8,6 +8,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"split-vpn-webui/internal/reputation"
 )
 
 const (
@@ -34,6 +36,14 @@ type flowInspectorSample struct {
 	DestinationDomain string
 	UploadBytes       uint64
 	DownloadBytes     uint64
+	UploadPackets     uint64
+	DownloadPackets   uint64
+	// GroupName is the routing group whose rule matched; empty for flows
+	// matched only by the VPN's firewall mark.
+	GroupName string
+	// MonitorOnly flags flows matched only by a monitor-only rule: they
+	// would use this VPN but are not routed through it.
+	MonitorOnly bool
 }
 
 type flowInspectorSnapshot struct {
@@ -46,6 +56,17 @@ type flowInspectorSnapshot struct {
 	FlowCount            int                `json:"flowCount"`
 	Totals               flowInspectorTotal `json:"totals"`
 	Flows                []flowInspectorRow `json:"flows"`
+	// Total is the number of rows before Offset and Limit were applied;
+	// with GroupBy it counts destinations rather than flows.
+	Total   int    `json:"total"`
+	Offset  int    `json:"offset"`
+	Limit   int    `json:"limit"`
+	Sort    string `json:"sort,omitempty"`
+	Order   string `json:"order,omitempty"`
+	GroupBy string `json:"groupBy,omitempty"`
+	// Accounting reports nf_conntrack_acct; without it conntrack has no
+	// counters and every rate reads zero.
+	Accounting conntrackAccountingStatus `json:"accounting"`
 }
 
 type flowInspectorTotal struct {
@@ -55,22 +76,30 @@ type flowInspectorTotal struct {
 }
 
 type flowInspectorRow struct {
-	Key               string    `json:"key"`
-	Protocol          string    `json:"protocol"`
-	SourceIP          string    `json:"sourceIp"`
-	SourcePort        int       `json:"sourcePort"`
-	SourceMAC         string    `json:"sourceMac,omitempty"`
-	SourceDeviceName  string    `json:"sourceDeviceName,omitempty"`
-	SourceInterface   string    `json:"sourceInterface,omitempty"`
-	DestinationIP     string    `json:"destinationIp"`
-	DestinationPort   int       `json:"destinationPort"`
-	DestinationDomain string    `json:"destinationDomain,omitempty"`
-	UploadBps         float64   `json:"uploadBps"`
-	DownloadBps       float64   `json:"downloadBps"`
-	UploadBytes       uint64    `json:"uploadBytes"`
-	DownloadBytes     uint64    `json:"downloadBytes"`
-	TotalBytes        uint64    `json:"totalBytes"`
-	LastSeen          time.Time `json:"lastSeen"`
+	Key               string             `json:"key"`
+	Protocol          string             `json:"protocol"`
+	SourceIP          string             `json:"sourceIp"`
+	SourcePort        int                `json:"sourcePort"`
+	SourceMAC         string             `json:"sourceMac,omitempty"`
+	SourceDeviceName  string             `json:"sourceDeviceName,omitempty"`
+	SourceInterface   string             `json:"sourceInterface,omitempty"`
+	DestinationIP     string             `json:"destinationIp"`
+	DestinationPort   int                `json:"destinationPort"`
+	DestinationDomain string             `json:"destinationDomain,omitempty"`
+	UploadBps         float64            `json:"uploadBps"`
+	DownloadBps       float64            `json:"downloadBps"`
+	UploadPps         float64            `json:"uploadPps"`
+	DownloadPps       float64            `json:"downloadPps"`
+	UploadBytes       uint64             `json:"uploadBytes"`
+	DownloadBytes     uint64             `json:"downloadBytes"`
+	TotalBytes        uint64             `json:"totalBytes"`
+	UploadPackets     uint64             `json:"uploadPackets"`
+	DownloadPackets   uint64             `json:"downloadPackets"`
+	LastSeen          time.Time          `json:"lastSeen"`
+	MonitorOnly       bool               `json:"monitorOnly,omitempty"`
+	Reputation        *reputation.Result `json:"reputation,omitempty"`
+	// Flows is the number of connections merged into an aggregated row.
+	Flows int `json:"flows,omitempty"`
 }
 
 type vpnFlowInspector struct {
@@ -100,14 +129,21 @@ type vpnFlowRecord struct {
 	DestinationIP     string
 	DestinationPort   int
 	DestinationDomain string
+	MonitorOnly       bool
 	LastSeen          time.Time
 	LastSampleAt      time.Time
 	LastUploadBytes   uint64
 	LastDownloadBytes uint64
+	LastUploadPkts    uint64
+	LastDownloadPkts  uint64
 	UploadBps         float64
 	DownloadBps       float64
+	UploadPps         float64
+	DownloadPps       float64
 	UploadTotal       uint64
 	DownloadTotal     uint64
+	UploadPktTotal    uint64
+	DownloadPktTotal  uint64
 }
 
 func newVPNFlowInspector() *vpnFlowInspector {
@@ -186,10 +222,13 @@ func (i *vpnFlowInspector) updateAndSnapshot(vpnName string, sessionID string, s
 				DestinationIP:     sample.DestinationIP,
 				DestinationPort:   sample.DestinationPort,
 				DestinationDomain: sample.DestinationDomain,
+				MonitorOnly:       sample.MonitorOnly,
 				LastSeen:          now,
 				LastSampleAt:      now,
 				LastUploadBytes:   sample.UploadBytes,
 				LastDownloadBytes: sample.DownloadBytes,
+				LastUploadPkts:    sample.UploadPackets,
+				LastDownloadPkts:  sample.DownloadPackets,
 			}
 			seen[key] = struct{}{}
 			continue
@@ -199,8 +238,10 @@ func (i *vpnFlowInspector) updateAndSnapshot(vpnName string, sessionID string, s
 		if elapsed <= 0 {
 			elapsed = float64(flowInspectorPollIntervalSeconds)
 		}
-		uploadDelta := monotonicDelta(sample.UploadBytes, record.LastUploadBytes)
-		downloadDelta := monotonicDelta(sample.DownloadBytes, record.LastDownloadBytes)
+		uploadDelta := counterDelta(sample.UploadBytes, record.LastUploadBytes)
+		downloadDelta := counterDelta(sample.DownloadBytes, record.LastDownloadBytes)
+		uploadPktDelta := counterDelta(sample.UploadPackets, record.LastUploadPkts)
+		downloadPktDelta := counterDelta(sample.DownloadPackets, record.LastDownloadPkts)
 		record.Protocol = sample.Protocol
 		record.SourceIP = sample.SourceIP
 		record.SourcePort = sample.SourcePort
@@ -210,12 +251,19 @@ func (i *vpnFlowInspector) updateAndSnapshot(vpnName string, sessionID string, s
 		record.DestinationIP = sample.DestinationIP
 		record.DestinationPort = sample.DestinationPort
 		record.DestinationDomain = sample.DestinationDomain
+		record.MonitorOnly = sample.MonitorOnly
 		record.UploadBps = float64(uploadDelta*8) / elapsed
 		record.DownloadBps = float64(downloadDelta*8) / elapsed
+		record.UploadPps = float64(uploadPktDelta) / elapsed
+		record.DownloadPps = float64(downloadPktDelta) / elapsed
 		record.UploadTotal += uploadDelta
 		record.DownloadTotal += downloadDelta
+		record.UploadPktTotal += uploadPktDelta
+		record.DownloadPktTotal += downloadPktDelta
 		record.LastUploadBytes = sample.UploadBytes
 		record.LastDownloadBytes = sample.DownloadBytes
+		record.LastUploadPkts = sample.UploadPackets
+		record.LastDownloadPkts = sample.DownloadPackets
 		record.LastSampleAt = now
 		record.LastSeen = now
 		session.TotalUpload += uploadDelta
@@ -233,6 +281,8 @@ func (i *vpnFlowInspector) updateAndSnapshot(vpnName string, sessionID string, s
 		}
 		record.UploadBps = 0
 		record.DownloadBps = 0
+		record.UploadPps = 0
+		record.DownloadPps = 0
 	}
 
 	rows := make([]flowInspectorRow, 0, len(session.FlowByKey))
@@ -248,11 +298,16 @@ func (i *vpnFlowInspector) updateAndSnapshot(vpnName string, sessionID string, s
 			DestinationIP:     record.DestinationIP,
 			DestinationPort:   record.DestinationPort,
 			DestinationDomain: record.DestinationDomain,
+			MonitorOnly:       record.MonitorOnly,
 			UploadBps:         record.UploadBps,
 			DownloadBps:       record.DownloadBps,
+			UploadPps:         record.UploadPps,
+			DownloadPps:       record.DownloadPps,
 			UploadBytes:       record.UploadTotal,
 			DownloadBytes:     record.DownloadTotal,
 			TotalBytes:        record.UploadTotal + record.DownloadTotal,
+			UploadPackets:     record.UploadPktTotal,
+			DownloadPackets:   record.DownloadPktTotal,
 			LastSeen:          record.LastSeen,
 		})
 	}
@@ -290,11 +345,15 @@ func (i *vpnFlowInspector) cleanupExpiredSessionsLocked(now time.Time) {
 	}
 }
 
-func monotonicDelta(current uint64, previous uint64) uint64 {
+// counterDelta returns how far a conntrack counter moved since the previous
+// sample. A counter below the previous value means the entry was destroyed and
+// recreated under the same tuple (or accounting was toggled), so it restarted
+// from zero and everything it holds is new.
+func counterDelta(current uint64, previous uint64) uint64 {
 	if current >= previous {
 		return current - previous
 	}
-	return 0
+	return current
 }
 
 func newFlowInspectorSessionID() (string, error) {
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// conntrackAcctPath is the sysctl that makes conntrack keep per-flow packet
+// and byte counters. Variable so tests can point it at a temp file.
+var conntrackAcctPath = "/proc/sys/net/netfilter/nf_conntrack_acct"
+
+type conntrackAccountingStatus struct {
+	// Available is false when the sysctl cannot be read, e.g. nf_conntrack
+	// is not loaded.
+	Available bool `json:"available"`
+	Enabled   bool `json:"enabled"`
+	// Persistent reports that the setting is re-applied on every start.
+	Persistent bool `json:"persistent"`
+}
+
+func readConntrackAccounting() (enabled bool, available bool) {
+	raw, err := os.ReadFile(conntrackAcctPath)
+	if err != nil {
+		return false, false
+	}
+	return strings.TrimSpace(string(raw)) == "1", true
+}
+
+func writeConntrackAccounting(enabled bool) error {
+	value := "0\n"
+	if enabled {
+		value = "1\n"
+	}
+	if err := os.WriteFile(conntrackAcctPath, []byte(value), 0o644); err != nil {
+		return fmt.Errorf("set nf_conntrack_acct: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) conntrackAccounting() conntrackAccountingStatus {
+	enabled, available := readConntrackAccounting()
+	status := conntrackAccountingStatus{Available: available, Enabled: enabled}
+	if s.settings != nil {
+		if current, err := s.settings.Get(); err == nil && current.ConntrackAccountingEnabled != nil {
+			status.Persistent = *current.ConntrackAccountingEnabled
+		}
+	}
+	return status
+}
+
+// restoreConntrackAccounting re-enables accounting at startup when it was
+// turned on from the flow inspector; the sysctl resets on reboot.
+func (s *Server) restoreConntrackAccounting() {
+	status := s.conntrackAccounting()
+	if !status.Persistent || !status.Available || status.Enabled {
+		return
+	}
+	if err := writeConntrackAccounting(true); err != nil {
+		if s.diagLog != nil {
+			s.diagLog.Warnf("flow_inspector restore conntrack accounting failed err=%v", err)
+		}
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("flow_inspector conntrack accounting re-enabled")
+	}
+}
+
+func (s *Server) handleGetConntrackAccounting(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.conntrackAccounting())
+}
+
+// handleSetConntrackAccounting turns nf_conntrack_acct on or off and
+// remembers the choice. Only flows created after enabling carry counters.
+func (s *Server) handleSetConntrackAccounting(w http.ResponseWriter, r *http.Request) {
+	if s.settings == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "settings unavailable"})
+		return
+	}
+	var payload struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	if payload.Enabled == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "enabled is required"})
+		return
+	}
+	enabled := *payload.Enabled
+	if _, available := readConntrackAccounting(); !available {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "conntrack accounting is not available on this system"})
+		return
+	}
+	if err := writeConntrackAccounting(enabled); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	current, err := s.settings.Get()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	current.ConntrackAccountingEnabled = &enabled
+	if err := s.settings.Save(current); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("flow_inspector conntrack accounting enabled=%t remote=%s", enabled, r.RemoteAddr)
+	}
+	writeJSON(w, http.StatusOK, s.conntrackAccounting())
+}
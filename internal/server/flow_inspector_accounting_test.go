@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/settings"
+)
+
+func TestConntrackAccountingEnableAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	previous := conntrackAcctPath
+	conntrackAcctPath = filepath.Join(dir, "nf_conntrack_acct")
+	t.Cleanup(func() { conntrackAcctPath = previous })
+	if err := os.WriteFile(conntrackAcctPath, []byte("0\n"), 0o644); err != nil {
+		t.Fatalf("write sysctl: %v", err)
+	}
+	s := &Server{settings: settings.NewManager(filepath.Join(dir, "settings.json"))}
+
+	rec := httptest.NewRecorder()
+	s.handleGetConntrackAccounting(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var status conntrackAccountingStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || !status.Available || status.Enabled || status.Persistent {
+		t.Fatalf("unexpected initial status: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleSetConntrackAccounting(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"enabled":true}`)))
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || rec.Code != http.StatusOK || !status.Enabled || !status.Persistent {
+		t.Fatalf("unexpected enable response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A reboot resets the sysctl; the next start turns it back on.
+	if err := os.WriteFile(conntrackAcctPath, []byte("0\n"), 0o644); err != nil {
+		t.Fatalf("reset sysctl: %v", err)
+	}
+	s.restoreConntrackAccounting()
+	if enabled, _ := readConntrackAccounting(); !enabled {
+		t.Fatalf("expected accounting to be restored")
+	}
+
+	// Turning it off clears the remembered setting so restarts leave it alone.
+	rec = httptest.NewRecorder()
+	s.handleSetConntrackAccounting(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"enabled":false}`)))
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || rec.Code != http.StatusOK || status.Enabled || status.Persistent {
+		t.Fatalf("unexpected disable response %d: %s", rec.Code, rec.Body.String())
+	}
+	s.restoreConntrackAccounting()
+	if enabled, _ := readConntrackAccounting(); enabled {
+		t.Fatalf("expected accounting to stay off after disable")
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleSetConntrackAccounting(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without enabled, got %d", rec.Code)
+	}
+
+	conntrackAcctPath = filepath.Join(dir, "missing")
+	rec = httptest.NewRecorder()
+	s.handleSetConntrackAccounting(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"enabled":true}`)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 without conntrack, got %d", rec.Code)
+	}
+}
@@ -26,6 +26,8 @@ type conntrackFlowSample struct {
 	DestinationPort int
 	UploadBytes     uint64
 	DownloadBytes   uint64
+	UploadPackets   uint64
+	DownloadPackets uint64
 	Mark            uint32
 }
 
@@ -81,9 +83,11 @@ func parseConntrackLine(rawLine string) (conntrackFlowSample, bool) {
 	if !sourcePortOK || !destinationPortOK || sourcePort <= 0 || destinationPort <= 0 {
 		return conntrackFlowSample{}, false
 	}
+	uploadPackets, _ := parseUintStrict(tuples[0][5])
 	uploadBytes, _ := parseUintStrict(tuples[0][6])
-	downloadBytes := uint64(0)
+	downloadPackets, downloadBytes := uint64(0), uint64(0)
 	if len(tuples) > 1 {
+		downloadPackets, _ = parseUintStrict(tuples[1][5])
 		downloadBytes, _ = parseUintStrict(tuples[1][6])
 	}
 
@@ -112,6 +116,8 @@ func parseConntrackLine(rawLine string) (conntrackFlowSample, bool) {
 		DestinationPort: destinationPort,
 		UploadBytes:     uploadBytes,
 		DownloadBytes:   downloadBytes,
+		UploadPackets:   uploadPackets,
+		DownloadPackets: downloadPackets,
 		Mark:            mark,
 	}, true
 }
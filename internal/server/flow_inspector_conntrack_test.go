@@ -94,3 +94,16 @@ func TestParseConntrackMark(t *testing.T) {
 		t.Fatalf("expected invalid mark parse to fail")
 	}
 }
+
+func TestParseConntrackLineParsesPacketCounters(t *testing.T) {
+	line := "tcp      6 431999 ESTABLISHED src=10.0.1.10 dst=142.250.74.14 sport=50432 dport=443 packets=30 bytes=10240 src=142.250.74.14 dst=10.0.1.10 sport=443 dport=50432 packets=26 bytes=20480 [ASSURED] mark=0x1a"
+	sample, ok := parseConntrackLine(line)
+	if !ok || sample.UploadPackets != 30 || sample.DownloadPackets != 26 {
+		t.Fatalf("unexpected packet counters: %#v", sample)
+	}
+	withoutAcct := "tcp      6 431999 ESTABLISHED src=10.0.1.10 dst=142.250.74.14 sport=50432 dport=443 src=142.250.74.14 dst=10.0.1.10 sport=443 dport=50432 [ASSURED] mark=0x1a"
+	sample, ok = parseConntrackLine(withoutAcct)
+	if !ok || sample.UploadPackets != 0 || sample.UploadBytes != 0 {
+		t.Fatalf("expected zero counters without accounting: %#v ok=%v", sample, ok)
+	}
+}
@@ -16,16 +16,23 @@ import (
 const flowInspectorIPSetTimeout = 4 * time.Second
 
 type compiledFlowRule struct {
-	SourcePrefixes                    []netip.Prefix
-	ExcludedSourcePrefixes            []netip.Prefix
-	DestinationPrefixes               []netip.Prefix
-	ExcludedDestinationPrefixes       []netip.Prefix
+	GroupName                         string
+	RuleIndex                         int
+	MonitorOnly                       bool
+	FullTunnel                        bool
+	SourcePrefixes                    prefixSet
+	ExcludedSourcePrefixes            prefixSet
+	DestinationPrefixes               prefixSet
+	ExcludedDestinationPrefixes       prefixSet
 	SourceInterfaces                  map[string]struct{}
 	SourceMACs                        map[string]struct{}
+	SourceDevicePrefixes              prefixSet
+	SourceDeviceMACs                  map[string]struct{}
 	DestinationPorts                  []routing.PortRange
 	ExcludedDestinationPorts          []routing.PortRange
 	ExcludeMulticast                  bool
 	RequiresSourcePrefix              bool
+	RequiresSourceDevice              bool
 	RequiresExcludedSourcePrefix      bool
 	RequiresDestinationPrefix         bool
 	RequiresExcludedDestinationPrefix bool
@@ -49,6 +56,7 @@ const (
 	flowNoMatchSourcePrefix      flowNoMatchReason = "source-prefix"
 	flowNoMatchSourceInterface   flowNoMatchReason = "source-interface"
 	flowNoMatchSourceMAC         flowNoMatchReason = "source-mac"
+	flowNoMatchSourceDevice      flowNoMatchReason = "source-device"
 	flowNoMatchDestinationPrefix flowNoMatchReason = "destination-prefix"
 	flowNoMatchDestinationPort   flowNoMatchReason = "destination-port"
 	flowNoMatchExcluded          flowNoMatchReason = "excluded"
@@ -58,19 +66,7 @@ func (s *Server) collectVPNFlowSamples(ctx context.Context, vpnName string) ([]f
 	if s.routingManager == nil || s.flowRunner == nil {
 		return nil, "", nil
 	}
-	groups, err := s.routingManager.ListGroups(ctx)
-	if err != nil {
-		return nil, "", err
-	}
-	resolved, err := s.routingManager.LoadResolverSnapshot(ctx)
-	if err != nil {
-		return nil, "", err
-	}
-	prewarmed, err := s.routingManager.LoadPrewarmSnapshot(ctx)
-	if err != nil {
-		return nil, "", err
-	}
-	setSnapshots, err := readIPSetSnapshots(flowInspectorIPSetTimeout)
+	compiledRules, domainHints, err := s.compiledFlowRules(ctx, vpnName)
 	if err != nil {
 		return nil, "", err
 	}
@@ -79,7 +75,7 @@ func (s *Server) collectVPNFlowSamples(ctx context.Context, vpnName string) ([]f
 		return nil, "", err
 	}
 	if s.diagLog != nil {
-		s.diagLog.Debugf("flow_inspector collect snapshot vpn=%s conntrack_flows=%d groups=%d", vpnName, len(conntrackFlows), len(groups))
+		s.diagLog.Debugf("flow_inspector collect snapshot vpn=%s conntrack_flows=%d", vpnName, len(conntrackFlows))
 	}
 
 	interfaceName := ""
@@ -92,16 +88,14 @@ func (s *Server) collectVPNFlowSamples(ctx context.Context, vpnName string) ([]f
 			vpnMark = profile.FWMark
 		}
 	}
-	compiledRules := compileFlowRules(vpnName, groups, setSnapshots, resolved, prewarmed)
 	if len(compiledRules) == 0 {
 		if s.diagLog != nil {
 			s.diagLog.Warnf("flow_inspector collect vpn=%s has no compiled routing rules", vpnName)
 		}
 		return nil, interfaceName, nil
 	}
-	domainHints := buildDomainPrefixHints(resolved)
 	localInterfacePrefixes := listLocalInterfacePrefixes()
-	devices := loadDeviceDirectory(ctx)
+	devices := s.loadDeviceDirectory(ctx)
 	result := make([]flowInspectorSample, 0, len(conntrackFlows))
 	seen := make(map[string]struct{}, len(conntrackFlows))
 	sourceParsed := 0
@@ -152,6 +146,10 @@ func (s *Server) collectVPNFlowSamples(ctx context.Context, vpnName string) ([]f
 			continue
 		}
 		seen[flow.Key] = struct{}{}
+		groupName := ""
+		if matchedRule != nil {
+			groupName = matchedRule.GroupName
+		}
 		result = append(result, flowInspectorSample{
 			Key:               flow.Key,
 			Protocol:          flow.Protocol,
@@ -165,6 +163,10 @@ func (s *Server) collectVPNFlowSamples(ctx context.Context, vpnName string) ([]f
 			DestinationDomain: destinationDomain,
 			UploadBytes:       flow.UploadBytes,
 			DownloadBytes:     flow.DownloadBytes,
+			UploadPackets:     flow.UploadPackets,
+			DownloadPackets:   flow.DownloadPackets,
+			GroupName:         groupName,
+			MonitorOnly:       matchedRule != nil && matchedRule.MonitorOnly && !flowMarkMatchesVPN(flow.Mark, vpnMark),
 		})
 	}
 	if s.diagLog != nil {
@@ -202,6 +204,7 @@ func (s *Server) collectVPNFlowSamples(ctx context.Context, vpnName string) ([]f
 func compileFlowRules(
 	vpnName string,
 	groups []routing.DomainGroup,
+	deviceGroups []routing.DeviceGroup,
 	snapshots map[string]ipsetSnapshot,
 	resolved map[routing.ResolverSelector]routing.ResolverValues,
 	prewarmed map[string]routing.ResolverValues,
@@ -217,16 +220,17 @@ func compileFlowRules(
 			}
 			pair := routing.RuleSetNames(group.Name, ruleIndex)
 			compiled := compiledFlowRule{
-				SourcePrefixes:                    nil,
-				ExcludedSourcePrefixes:            nil,
-				DestinationPrefixes:               nil,
-				ExcludedDestinationPrefixes:       nil,
+				GroupName:                         group.Name,
+				RuleIndex:                         ruleIndex,
+				MonitorOnly:                       rule.MonitorOnly,
+				FullTunnel:                        group.FullTunnel,
 				SourceInterfaces:                  makeSelectorSet(rule.SourceInterfaces),
 				SourceMACs:                        makeMACSet(rule.SourceMACs),
 				DestinationPorts:                  append([]routing.PortRange(nil), rule.DestinationPorts...),
 				ExcludedDestinationPorts:          append([]routing.PortRange(nil), rule.ExcludedDestinationPorts...),
 				ExcludeMulticast:                  routing.RuleExcludeMulticastEnabled(rule),
 				RequiresSourcePrefix:              len(rule.SourceCIDRs) > 0,
+				RequiresSourceDevice:              len(rule.SourceDeviceGroups) > 0,
 				RequiresExcludedSourcePrefix:      len(rule.ExcludedSourceCIDRs) > 0,
 				RequiresDestinationPrefix:         len(rule.DestinationCIDRs) > 0 || len(rule.DestinationASNs) > 0 || len(rule.Domains) > 0 || len(rule.WildcardDomains) > 0,
 				RequiresExcludedDestinationPrefix: len(rule.ExcludedDestinationCIDRs) > 0 || len(rule.ExcludedDestinationASNs) > 0,
@@ -238,31 +242,49 @@ func compileFlowRules(
 			if len(sourceCandidates) == 0 {
 				sourceCandidates = append(sourceCandidates, rule.SourceCIDRs...)
 			}
-			compiled.SourcePrefixes = parsePrefixList(sourceCandidates)
+			compiled.SourcePrefixes = newPrefixSet(parsePrefixList(sourceCandidates))
+
+			if compiled.RequiresSourceDevice {
+				deviceMACs, deviceCIDRs := routing.DeviceGroupMembers(deviceGroups, rule.SourceDeviceGroups)
+				compiled.SourceDeviceMACs = makeMACSet(deviceMACs)
+				compiled.SourceDevicePrefixes = newPrefixSet(parsePrefixList(deviceCIDRs))
+			}
 
 			excludedSourceCandidates := append([]string(nil), snapshots[pair.ExcludedSourceV4].Members...)
 			excludedSourceCandidates = append(excludedSourceCandidates, snapshots[pair.ExcludedSourceV6].Members...)
 			if len(excludedSourceCandidates) == 0 {
 				excludedSourceCandidates = append(excludedSourceCandidates, rule.ExcludedSourceCIDRs...)
 			}
-			compiled.ExcludedSourcePrefixes = parsePrefixList(excludedSourceCandidates)
+			compiled.ExcludedSourcePrefixes = newPrefixSet(parsePrefixList(excludedSourceCandidates))
 
 			destinationCandidates := append([]string(nil), snapshots[pair.DestinationV4].Members...)
 			destinationCandidates = append(destinationCandidates, snapshots[pair.DestinationV6].Members...)
 			if len(destinationCandidates) == 0 {
 				destinationCandidates = append(destinationCandidates, destinationRawMembers(rule, pair, resolved, prewarmed)...)
 			}
-			compiled.DestinationPrefixes = parsePrefixList(destinationCandidates)
+			destinationPrefixes := parsePrefixList(destinationCandidates)
 
 			excludedDestinationCandidates := append([]string(nil), snapshots[pair.ExcludedDestinationV4].Members...)
 			excludedDestinationCandidates = append(excludedDestinationCandidates, snapshots[pair.ExcludedDestinationV6].Members...)
 			if len(excludedDestinationCandidates) == 0 {
 				excludedDestinationCandidates = append(excludedDestinationCandidates, destinationExcludedRawMembers(rule, resolved)...)
 			}
-			compiled.ExcludedDestinationPrefixes = parsePrefixList(excludedDestinationCandidates)
+			excludedDestinationPrefixes := parsePrefixList(excludedDestinationCandidates)
+			if group.InvertDestinations && compiled.RequiresDestinationPrefix {
+				// Inverted groups match everything outside the destination set.
+				excludedDestinationPrefixes = append(excludedDestinationPrefixes, destinationPrefixes...)
+				destinationPrefixes = nil
+				compiled.RequiresDestinationPrefix = false
+				compiled.RequiresExcludedDestinationPrefix = true
+				compiled.DomainHints = nil
+			}
+			compiled.DestinationPrefixes = newPrefixSet(destinationPrefixes)
+			compiled.ExcludedDestinationPrefixes = newPrefixSet(excludedDestinationPrefixes)
 			rules = append(rules, compiled)
 		}
 	}
+	// Full tunnel bindings yield to every other match, so try them last.
+	sort.SliceStable(rules, func(i, j int) bool { return !rules[i].FullTunnel && rules[j].FullTunnel })
 	return rules
 }
 
@@ -271,6 +293,7 @@ func ruleHasAnySelectors(rule routing.RoutingRule) bool {
 		len(rule.SourceCIDRs) > 0 ||
 		len(rule.ExcludedSourceCIDRs) > 0 ||
 		len(rule.SourceMACs) > 0 ||
+		len(rule.SourceDeviceGroups) > 0 ||
 		len(rule.DestinationCIDRs) > 0 ||
 		len(rule.DestinationPorts) > 0 ||
 		len(rule.ExcludedDestinationCIDRs) > 0 ||
@@ -281,6 +304,17 @@ func ruleHasAnySelectors(rule routing.RoutingRule) bool {
 		len(rule.WildcardDomains) > 0
 }
 
+// matchSourceDevice reports whether the flow source is a member of the
+// rule's device groups, by MAC or by address.
+func matchSourceDevice(rule *compiledFlowRule, sourceAddr netip.Addr, sourceMAC string) bool {
+	if sourceMAC != "" {
+		if _, ok := rule.SourceDeviceMACs[sourceMAC]; ok {
+			return true
+		}
+	}
+	return rule.SourceDevicePrefixes.Contains(sourceAddr)
+}
+
 func makeSelectorSet(values []string) map[string]struct{} {
 	if len(values) == 0 {
 		return nil
@@ -438,7 +472,7 @@ func matchFlowRule(
 	sourceInterface = strings.ToLower(strings.TrimSpace(sourceInterface))
 	for i := range rules {
 		rule := &rules[i]
-		if rule.RequiresSourcePrefix && !prefixContains(rule.SourcePrefixes, sourceAddr) {
+		if rule.RequiresSourcePrefix && !rule.SourcePrefixes.Contains(sourceAddr) {
 			continue
 		}
 		if len(rule.SourceInterfaces) > 0 {
@@ -457,16 +491,19 @@ func matchFlowRule(
 				continue
 			}
 		}
-		if rule.RequiresExcludedSourcePrefix && prefixContains(rule.ExcludedSourcePrefixes, sourceAddr) {
+		if rule.RequiresSourceDevice && !matchSourceDevice(rule, sourceAddr, sourceMAC) {
 			continue
 		}
-		if rule.RequiresDestinationPrefix && !prefixContains(rule.DestinationPrefixes, destinationAddr) {
+		if rule.RequiresExcludedSourcePrefix && rule.ExcludedSourcePrefixes.Contains(sourceAddr) {
+			continue
+		}
+		if rule.RequiresDestinationPrefix && !rule.DestinationPrefixes.Contains(destinationAddr) {
 			continue
 		}
 		if len(rule.DestinationPorts) > 0 && !matchDestinationPort(rule.DestinationPorts, flow.Protocol, flow.DestinationPort) {
 			continue
 		}
-		if rule.RequiresExcludedDestinationPrefix && prefixContains(rule.ExcludedDestinationPrefixes, destinationAddr) {
+		if rule.RequiresExcludedDestinationPrefix && rule.ExcludedDestinationPrefixes.Contains(destinationAddr) {
 			continue
 		}
 		if len(rule.ExcludedDestinationPorts) > 0 && matchDestinationPort(rule.ExcludedDestinationPorts, flow.Protocol, flow.DestinationPort) {
@@ -492,7 +529,7 @@ func detectFlowNoMatchReason(
 	sourceInterface = strings.ToLower(strings.TrimSpace(sourceInterface))
 	counts := map[flowNoMatchReason]int{}
 	for _, rule := range rules {
-		if rule.RequiresSourcePrefix && !prefixContains(rule.SourcePrefixes, sourceAddr) {
+		if rule.RequiresSourcePrefix && !rule.SourcePrefixes.Contains(sourceAddr) {
 			counts[flowNoMatchSourcePrefix]++
 			continue
 		}
@@ -516,11 +553,15 @@ func detectFlowNoMatchReason(
 				continue
 			}
 		}
-		if rule.RequiresExcludedSourcePrefix && prefixContains(rule.ExcludedSourcePrefixes, sourceAddr) {
+		if rule.RequiresSourceDevice && !matchSourceDevice(&rule, sourceAddr, sourceMAC) {
+			counts[flowNoMatchSourceDevice]++
+			continue
+		}
+		if rule.RequiresExcludedSourcePrefix && rule.ExcludedSourcePrefixes.Contains(sourceAddr) {
 			counts[flowNoMatchExcluded]++
 			continue
 		}
-		if rule.RequiresDestinationPrefix && !prefixContains(rule.DestinationPrefixes, destinationAddr) {
+		if rule.RequiresDestinationPrefix && !rule.DestinationPrefixes.Contains(destinationAddr) {
 			counts[flowNoMatchDestinationPrefix]++
 			continue
 		}
@@ -528,7 +569,7 @@ func detectFlowNoMatchReason(
 			counts[flowNoMatchDestinationPort]++
 			continue
 		}
-		if rule.RequiresExcludedDestinationPrefix && prefixContains(rule.ExcludedDestinationPrefixes, destinationAddr) {
+		if rule.RequiresExcludedDestinationPrefix && rule.ExcludedDestinationPrefixes.Contains(destinationAddr) {
 			counts[flowNoMatchExcluded]++
 			continue
 		}
@@ -557,6 +598,7 @@ func dominantFlowNoMatchReason(counts map[flowNoMatchReason]int) flowNoMatchReas
 		flowNoMatchSourcePrefix,
 		flowNoMatchSourceInterface,
 		flowNoMatchSourceMAC,
+		flowNoMatchSourceDevice,
 		flowNoMatchDestinationPrefix,
 		flowNoMatchDestinationPort,
 		flowNoMatchExcluded,
@@ -628,15 +670,6 @@ func flowMarkMask(vpnMark uint32) uint32 {
 	}
 }
 
-func prefixContains(prefixes []netip.Prefix, address netip.Addr) bool {
-	for _, prefix := range prefixes {
-		if prefix.Contains(address) {
-			return true
-		}
-	}
-	return false
-}
-
 func matchDestinationPort(ports []routing.PortRange, protocol string, destinationPort int) bool {
 	if destinationPort <= 0 {
 		return false
@@ -662,7 +695,7 @@ func matchDestinationPort(ports []routing.PortRange, protocol string, destinatio
 	return false
 }
 
-func buildDomainPrefixHints(snapshot map[routing.ResolverSelector]routing.ResolverValues) []domainPrefixHint {
+func buildDomainPrefixHints(snapshot map[routing.ResolverSelector]routing.ResolverValues) *prefixTrie[string] {
 	hints := make([]domainPrefixHint, 0, len(snapshot))
 	seen := make(map[string]struct{})
 	for selector, values := range snapshot {
@@ -699,25 +732,26 @@ func buildDomainPrefixHints(snapshot map[routing.ResolverSelector]routing.Resolv
 			hints = append(hints, domainPrefixHint{Prefix: prefix, Domain: label})
 		}
 	}
+	// Sorting first makes the alphabetically first domain win when several
+	// resolve to the same prefix.
 	sort.Slice(hints, func(i, j int) bool {
-		if hints[i].Prefix.Bits() == hints[j].Prefix.Bits() {
-			if hints[i].Prefix.String() == hints[j].Prefix.String() {
-				return hints[i].Domain < hints[j].Domain
-			}
-			return hints[i].Prefix.String() < hints[j].Prefix.String()
+		if hints[i].Prefix == hints[j].Prefix {
+			return hints[i].Domain < hints[j].Domain
 		}
-		return hints[i].Prefix.Bits() > hints[j].Prefix.Bits()
+		return hints[i].Prefix.String() < hints[j].Prefix.String()
 	})
-	return hints
-}
-
-func lookupDestinationDomain(hints []domainPrefixHint, destination netip.Addr) string {
+	index := &prefixTrie[string]{}
 	for _, hint := range hints {
-		if hint.Prefix.Contains(destination) {
-			return hint.Domain
-		}
+		index.insert(hint.Prefix, hint.Domain)
 	}
-	return ""
+	return index
+}
+
+// lookupDestinationDomain returns the domain of the most specific resolved
+// prefix containing destination.
+func lookupDestinationDomain(hints *prefixTrie[string], destination netip.Addr) string {
+	domain, _ := hints.lookup(destination)
+	return domain
 }
 
 func listLocalInterfacePrefixes() []interfacePrefix {
@@ -56,7 +56,7 @@ func TestDetectFlowNoMatchReason(t *testing.T) {
 			DestinationPorts: []routing.PortRange{
 				{Protocol: "tcp", Start: 443, End: 443},
 			},
-			DestinationPrefixes:       []netip.Prefix{netip.MustParsePrefix("142.250.74.0/24")},
+			DestinationPrefixes:       newPrefixSet([]netip.Prefix{netip.MustParsePrefix("142.250.74.0/24")}),
 			RequiresDestinationPrefix: true,
 		},
 	}
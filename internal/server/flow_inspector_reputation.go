@@ -0,0 +1,87 @@
+package server
+
+import (
+	"split-vpn-webui/internal/reputation"
+	"split-vpn-webui/internal/settings"
+)
+
+// reputationAlert is broadcast on the SSE stream the first time a flow to a
+// flagged destination is observed.
+type reputationAlert struct {
+	VPNName           string            `json:"vpnName"`
+	SourceIP          string            `json:"sourceIp"`
+	SourceDeviceName  string            `json:"sourceDeviceName,omitempty"`
+	DestinationIP     string            `json:"destinationIp"`
+	DestinationPort   int               `json:"destinationPort"`
+	DestinationDomain string            `json:"destinationDomain,omitempty"`
+	Reputation        reputation.Result `json:"reputation"`
+}
+
+// configureReputation installs the reputation sources selected in settings.
+// With lookups disabled, or no source configured, the checker stays inert.
+func (s *Server) configureReputation(current settings.Settings) {
+	if s.reputation == nil {
+		return
+	}
+	if current.ReputationEnabled == nil || !*current.ReputationEnabled {
+		s.reputation.Configure(nil)
+		return
+	}
+	sources := make([]reputation.Source, 0, 2)
+	if current.ReputationAbuseIPDBKey != "" {
+		sources = append(sources, reputation.NewAbuseIPDB(current.ReputationAbuseIPDBKey, current.ReputationAbuseIPDBMinScore, nil))
+	}
+	if current.ReputationSpamhausEnabled != nil && *current.ReputationSpamhausEnabled {
+		sources = append(sources, reputation.NewSpamhaus(nil))
+	}
+	s.reputation.Configure(sources)
+}
+
+// annotateFlowReputation attaches cached reputation results to snapshot rows,
+// schedules background lookups for unseen destinations and raises an alert
+// once per flagged destination.
+func (s *Server) annotateFlowReputation(snapshot *flowInspectorSnapshot) {
+	if s.reputation == nil || !s.reputation.Enabled() {
+		return
+	}
+	misses := make([]string, 0)
+	for idx := range snapshot.Flows {
+		row := &snapshot.Flows[idx]
+		if row.DestinationIP == "" {
+			continue
+		}
+		result, ok := s.reputation.Peek(row.DestinationIP)
+		if !ok {
+			misses = append(misses, row.DestinationIP)
+			continue
+		}
+		row.Reputation = &result
+		if !result.Flagged || !s.reputation.MarkAlerted(row.DestinationIP) {
+			continue
+		}
+		alert := reputationAlert{
+			VPNName:           snapshot.VPNName,
+			SourceIP:          row.SourceIP,
+			SourceDeviceName:  row.SourceDeviceName,
+			DestinationIP:     row.DestinationIP,
+			DestinationPort:   row.DestinationPort,
+			DestinationDomain: row.DestinationDomain,
+			Reputation:        result,
+		}
+		if s.diagLog != nil {
+			s.diagLog.Warnf(
+				"flow_inspector reputation flagged vpn=%s src=%s dst=%s:%d domain=%s score=%d",
+				alert.VPNName,
+				alert.SourceIP,
+				alert.DestinationIP,
+				alert.DestinationPort,
+				alert.DestinationDomain,
+				result.Score,
+			)
+		}
+		s.broadcastEvent("reputation", alert)
+	}
+	if len(misses) > 0 {
+		s.reputation.Prefetch(misses)
+	}
+}
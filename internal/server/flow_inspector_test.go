@@ -101,3 +101,35 @@ func TestVPNFlowInspectorSessionMismatch(t *testing.T) {
 		t.Fatalf("expected vpn mismatch error, got %v", err)
 	}
 }
+
+func TestVPNFlowInspectorHandlesCounterResetsAndPackets(t *testing.T) {
+	base := time.Date(2026, time.February, 24, 12, 0, 0, 0, time.UTC)
+	current := base
+	inspector := newVPNFlowInspector()
+	inspector.now = func() time.Time { return current }
+	sessionID, err := inspector.startSession("wg-sgp", "wg-sv-sgp")
+	if err != nil {
+		t.Fatalf("startSession failed: %v", err)
+	}
+	sample := flowInspectorSample{Key: "udp|10.0.1.10|5000|1.1.1.1|443", Protocol: "udp", UploadBytes: 5000, DownloadBytes: 9000, UploadPackets: 10, DownloadPackets: 20}
+	if _, err := inspector.updateAndSnapshot("wg-sgp", sessionID, []flowInspectorSample{sample}); err != nil {
+		t.Fatalf("first update failed: %v", err)
+	}
+
+	// The entry expired and was recreated under the same tuple, so its
+	// counters restarted below the previous sample.
+	current = current.Add(2 * time.Second)
+	sample.UploadBytes, sample.DownloadBytes = 100, 400
+	sample.UploadPackets, sample.DownloadPackets = 2, 4
+	snapshot, err := inspector.updateAndSnapshot("wg-sgp", sessionID, []flowInspectorSample{sample})
+	if err != nil {
+		t.Fatalf("second update failed: %v", err)
+	}
+	row := snapshot.Flows[0]
+	if row.UploadBytes != 100 || row.DownloadBytes != 400 || row.UploadBps != 400 || row.DownloadBps != 1600 {
+		t.Fatalf("expected reset counters to count as new traffic: %#v", row)
+	}
+	if row.UploadPackets != 2 || row.DownloadPackets != 4 || row.UploadPps != 1 || row.DownloadPps != 2 {
+		t.Fatalf("unexpected packet deltas: %#v", row)
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"cmp"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// flowInspectorDefaultLimit is the page size when a request names none.
+	flowInspectorDefaultLimit = 200
+	// flowInspectorMaxLimit caps one page so a busy router never returns
+	// thousands of rows in one response.
+	flowInspectorMaxLimit = 2000
+)
+
+var flowInspectorSortKeys = map[string]bool{
+	"rate":        true,
+	"total":       true,
+	"download":    true,
+	"upload":      true,
+	"source":      true,
+	"destination": true,
+	"lastSeen":    true,
+	"flows":       true,
+}
+
+// flowInspectorView selects, orders and pages the rows of a snapshot.
+type flowInspectorView struct {
+	Sort    string
+	Order   string
+	Limit   int
+	Offset  int
+	GroupBy string
+}
+
+// parseFlowInspectorView reads limit, offset, sort, order and groupBy query
+// parameters. Text columns sort ascending and numeric ones descending unless
+// order says otherwise.
+func parseFlowInspectorView(w http.ResponseWriter, r *http.Request) (flowInspectorView, bool) {
+	query := r.URL.Query()
+	view := flowInspectorView{
+		Sort:    strings.TrimSpace(query.Get("sort")),
+		Order:   strings.ToLower(strings.TrimSpace(query.Get("order"))),
+		GroupBy: strings.TrimSpace(query.Get("groupBy")),
+	}
+	if view.Sort == "" {
+		view.Sort = "rate"
+	}
+	if !flowInspectorSortKeys[view.Sort] {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sort must be one of rate, total, download, upload, source, destination, lastSeen or flows"})
+		return flowInspectorView{}, false
+	}
+	switch view.Order {
+	case "":
+		view.Order = "desc"
+		if view.Sort == "source" || view.Sort == "destination" {
+			view.Order = "asc"
+		}
+	case "asc", "desc":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "order must be asc or desc"})
+		return flowInspectorView{}, false
+	}
+	switch view.GroupBy {
+	case "", "destination":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "groupBy must be destination"})
+		return flowInspectorView{}, false
+	}
+	limit, ok := parseNonNegativeQuery(w, r, "limit", flowInspectorDefaultLimit)
+	if !ok {
+		return flowInspectorView{}, false
+	}
+	if limit == 0 || limit > flowInspectorMaxLimit {
+		limit = flowInspectorMaxLimit
+	}
+	view.Limit = limit
+	offset, ok := parseNonNegativeQuery(w, r, "offset", 0)
+	if !ok {
+		return flowInspectorView{}, false
+	}
+	view.Offset = offset
+	return view, true
+}
+
+// apply aggregates, sorts and pages snapshot.Flows in place. FlowCount keeps
+// the number of connections; Total is the number of rows before paging.
+func (v flowInspectorView) apply(snapshot *flowInspectorSnapshot) {
+	rows := snapshot.Flows
+	if v.GroupBy == "destination" {
+		rows = aggregateFlowsByDestination(rows)
+	}
+	sortFlowInspectorRows(rows, v.Sort, v.Order == "asc")
+
+	snapshot.Total = len(rows)
+	snapshot.Sort = v.Sort
+	snapshot.Order = v.Order
+	snapshot.Limit = v.Limit
+	snapshot.Offset = v.Offset
+	snapshot.GroupBy = v.GroupBy
+	start := min(v.Offset, len(rows))
+	end := min(start+v.Limit, len(rows))
+	snapshot.Flows = rows[start:end]
+}
+
+// aggregateFlowsByDestination merges flows to the same destination domain,
+// or IP when the domain is unknown, summing their rates and counters.
+func aggregateFlowsByDestination(rows []flowInspectorRow) []flowInspectorRow {
+	byKey := make(map[string]*flowInspectorRow)
+	order := make([]string, 0)
+	for _, row := range rows {
+		key := "ip:" + row.DestinationIP
+		if row.DestinationDomain != "" {
+			key = "domain:" + row.DestinationDomain
+		}
+		merged, ok := byKey[key]
+		if !ok {
+			byKey[key] = &flowInspectorRow{
+				Key:               key,
+				Protocol:          row.Protocol,
+				DestinationIP:     row.DestinationIP,
+				DestinationPort:   row.DestinationPort,
+				DestinationDomain: row.DestinationDomain,
+				UploadBps:         row.UploadBps,
+				DownloadBps:       row.DownloadBps,
+				UploadPps:         row.UploadPps,
+				DownloadPps:       row.DownloadPps,
+				UploadBytes:       row.UploadBytes,
+				DownloadBytes:     row.DownloadBytes,
+				TotalBytes:        row.TotalBytes,
+				UploadPackets:     row.UploadPackets,
+				DownloadPackets:   row.DownloadPackets,
+				LastSeen:          row.LastSeen,
+				MonitorOnly:       row.MonitorOnly,
+				Flows:             1,
+			}
+			order = append(order, key)
+			continue
+		}
+		// Fields that differ between the merged flows are cleared rather
+		// than showing one arbitrary flow's value.
+		if merged.Protocol != row.Protocol {
+			merged.Protocol = ""
+		}
+		if merged.DestinationIP != row.DestinationIP {
+			merged.DestinationIP = ""
+		}
+		if merged.DestinationPort != row.DestinationPort {
+			merged.DestinationPort = 0
+		}
+		merged.UploadBps += row.UploadBps
+		merged.DownloadBps += row.DownloadBps
+		merged.UploadPps += row.UploadPps
+		merged.DownloadPps += row.DownloadPps
+		merged.UploadBytes += row.UploadBytes
+		merged.DownloadBytes += row.DownloadBytes
+		merged.TotalBytes += row.TotalBytes
+		merged.UploadPackets += row.UploadPackets
+		merged.DownloadPackets += row.DownloadPackets
+		if row.LastSeen.After(merged.LastSeen) {
+			merged.LastSeen = row.LastSeen
+		}
+		merged.MonitorOnly = merged.MonitorOnly && row.MonitorOnly
+		merged.Flows++
+	}
+	out := make([]flowInspectorRow, 0, len(order))
+	for _, key := range order {
+		out = append(out, *byKey[key])
+	}
+	return out
+}
+
+func sortFlowInspectorRows(rows []flowInspectorRow, sortKey string, ascending bool) {
+	sort.SliceStable(rows, func(left, right int) bool {
+		a, b := rows[left], rows[right]
+		var order int
+		switch sortKey {
+		case "source":
+			order = strings.Compare(flowSourceSortKey(a), flowSourceSortKey(b))
+		case "destination":
+			order = strings.Compare(flowDestinationSortKey(a), flowDestinationSortKey(b))
+		case "total":
+			order = cmp.Compare(a.TotalBytes, b.TotalBytes)
+		case "download":
+			order = cmp.Compare(a.DownloadBps, b.DownloadBps)
+		case "upload":
+			order = cmp.Compare(a.UploadBps, b.UploadBps)
+		case "lastSeen":
+			order = a.LastSeen.Compare(b.LastSeen)
+		case "flows":
+			order = cmp.Compare(a.Flows, b.Flows)
+		default:
+			order = cmp.Compare(a.UploadBps+a.DownloadBps, b.UploadBps+b.DownloadBps)
+		}
+		if order == 0 {
+			return a.Key < b.Key
+		}
+		if ascending {
+			return order < 0
+		}
+		return order > 0
+	})
+}
+
+func flowSourceSortKey(row flowInspectorRow) string {
+	return strings.ToLower(row.SourceDeviceName + " " + row.SourceIP)
+}
+
+func flowDestinationSortKey(row flowInspectorRow) string {
+	return strings.ToLower(row.DestinationDomain + " " + row.DestinationIP)
+}
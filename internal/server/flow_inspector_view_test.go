@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFlowInspectorViewSortsAndPages(t *testing.T) {
+	snapshot := flowInspectorSnapshot{FlowCount: 3, Flows: []flowInspectorRow{
+		{Key: "a", DestinationIP: "203.0.113.1", TotalBytes: 10, DownloadBps: 5},
+		{Key: "b", DestinationIP: "203.0.113.2", TotalBytes: 30, DownloadBps: 1},
+		{Key: "c", DestinationIP: "203.0.113.3", TotalBytes: 20, DownloadBps: 9},
+	}}
+	request := httptest.NewRequest(http.MethodGet, "/?sort=total&limit=2&offset=1", nil)
+	view, ok := parseFlowInspectorView(httptest.NewRecorder(), request)
+	if !ok {
+		t.Fatalf("expected valid view")
+	}
+	view.apply(&snapshot)
+
+	if snapshot.Total != 3 || snapshot.FlowCount != 3 || snapshot.Limit != 2 || snapshot.Offset != 1 || snapshot.Order != "desc" {
+		t.Fatalf("unexpected paging metadata: %+v", snapshot)
+	}
+	if len(snapshot.Flows) != 2 || snapshot.Flows[0].Key != "c" || snapshot.Flows[1].Key != "a" {
+		t.Fatalf("unexpected page %+v", snapshot.Flows)
+	}
+}
+
+func TestFlowInspectorViewAggregatesByDestination(t *testing.T) {
+	now := time.Now()
+	snapshot := flowInspectorSnapshot{FlowCount: 3, Flows: []flowInspectorRow{
+		{Key: "a", SourceIP: "192.168.1.10", DestinationDomain: "netflix.com", DestinationIP: "203.0.113.1", DestinationPort: 443, TotalBytes: 10, DownloadBps: 5, LastSeen: now.Add(-time.Minute)},
+		{Key: "b", SourceIP: "192.168.1.11", DestinationDomain: "netflix.com", DestinationIP: "203.0.113.2", DestinationPort: 443, TotalBytes: 30, DownloadBps: 1, LastSeen: now},
+		{Key: "c", SourceIP: "192.168.1.10", DestinationIP: "198.51.100.7", DestinationPort: 53, TotalBytes: 5},
+	}}
+	request := httptest.NewRequest(http.MethodGet, "/?groupBy=destination&sort=flows", nil)
+	view, ok := parseFlowInspectorView(httptest.NewRecorder(), request)
+	if !ok {
+		t.Fatalf("expected valid view")
+	}
+	view.apply(&snapshot)
+
+	if snapshot.Total != 2 || len(snapshot.Flows) != 2 {
+		t.Fatalf("expected two destinations, got %+v", snapshot.Flows)
+	}
+	merged := snapshot.Flows[0]
+	if merged.DestinationDomain != "netflix.com" || merged.Flows != 2 || merged.TotalBytes != 40 || merged.DownloadBps != 6 {
+		t.Fatalf("unexpected merged row %+v", merged)
+	}
+	if merged.DestinationIP != "" || merged.DestinationPort != 443 || merged.SourceIP != "" || !merged.LastSeen.Equal(now) {
+		t.Fatalf("unexpected merged fields %+v", merged)
+	}
+}
+
+func TestParseFlowInspectorViewRejectsInvalidParameters(t *testing.T) {
+	for _, query := range []string{"?sort=bogus", "?order=up", "?groupBy=source", "?limit=-1", "?offset=x"} {
+		recorder := httptest.NewRecorder()
+		if _, ok := parseFlowInspectorView(recorder, httptest.NewRequest(http.MethodGet, "/"+query, nil)); ok || recorder.Code != http.StatusBadRequest {
+			t.Fatalf("expected %s to be rejected, got ok=%t code=%d", query, ok, recorder.Code)
+		}
+	}
+	view, ok := parseFlowInspectorView(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?limit=0&sort=destination", nil))
+	if !ok || view.Limit != flowInspectorMaxLimit || view.Order != "asc" {
+		t.Fatalf("unexpected defaults %+v", view)
+	}
+}
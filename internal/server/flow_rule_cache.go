@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/routing"
+)
+
+// flowRuleInputs is the routing state flow matching is compiled from.
+type flowRuleInputs struct {
+	groups       []routing.DomainGroup
+	deviceGroups []routing.DeviceGroup
+	resolved     map[routing.ResolverSelector]routing.ResolverValues
+	prewarmed    map[string]routing.ResolverValues
+	domainHints  *prefixTrie[string]
+}
+
+// flowRuleCache keeps compiled flow rules between inspector polls and
+// history samples. Inputs are reloaded when the routing generation changes;
+// per-VPN rules are also recompiled when a new ipset listing arrives, since
+// dnsmasq adds set members between applies.
+type flowRuleCache struct {
+	mu            sync.Mutex
+	generation    uint64
+	inputs        *flowRuleInputs
+	setsFetchedAt time.Time
+	rules         map[string][]compiledFlowRule
+}
+
+// compiledFlowRules returns the flow rules for vpnName and the destination
+// domain hints, compiling them only when routing state or set contents have
+// changed since the last call.
+func (s *Server) compiledFlowRules(ctx context.Context, vpnName string) ([]compiledFlowRule, *prefixTrie[string], error) {
+	generation := s.routingManager.Generation()
+	snapshots, fetchedAt, err := s.ipsetCache.getWithTime(flowInspectorIPSetTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.flowRules.get(vpnName, generation, snapshots, fetchedAt, func() (*flowRuleInputs, error) {
+		return s.loadFlowRuleInputs(ctx)
+	})
+}
+
+func (c *flowRuleCache) get(
+	vpnName string,
+	generation uint64,
+	snapshots map[string]ipsetSnapshot,
+	fetchedAt time.Time,
+	load func() (*flowRuleInputs, error),
+) ([]compiledFlowRule, *prefixTrie[string], error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inputs == nil || c.generation != generation {
+		inputs, err := load()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.inputs = inputs
+		c.generation = generation
+		c.rules = nil
+	}
+	if c.rules == nil || !c.setsFetchedAt.Equal(fetchedAt) {
+		c.rules = make(map[string][]compiledFlowRule)
+		c.setsFetchedAt = fetchedAt
+	}
+	rules, ok := c.rules[vpnName]
+	if !ok {
+		rules = compileFlowRules(vpnName, c.inputs.groups, c.inputs.deviceGroups, snapshots, c.inputs.resolved, c.inputs.prewarmed)
+		c.rules[vpnName] = rules
+	}
+	return rules, c.inputs.domainHints, nil
+}
+
+func (s *Server) loadFlowRuleInputs(ctx context.Context) (*flowRuleInputs, error) {
+	groups, err := s.routingManager.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups = s.routingManager.ExpandNetworkSelectors(groups)
+	deviceGroups, err := s.routingManager.ListDeviceGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := s.routingManager.LoadResolverSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prewarmed, err := s.routingManager.LoadPrewarmSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &flowRuleInputs{
+		groups:       groups,
+		deviceGroups: deviceGroups,
+		resolved:     resolved,
+		prewarmed:    prewarmed,
+		domainHints:  buildDomainPrefixHints(resolved),
+	}, nil
+}
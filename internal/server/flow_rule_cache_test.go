@@ -0,0 +1,43 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/routing"
+)
+
+func TestFlowRuleCacheReusesRulesUntilGenerationOrSetsChange(t *testing.T) {
+	loads := 0
+	load := func() (*flowRuleInputs, error) {
+		loads++
+		return &flowRuleInputs{groups: []routing.DomainGroup{{
+			Name:      "Media",
+			EgressVPN: "wg-sgp",
+			Rules:     []routing.RoutingRule{{DestinationCIDRs: []string{"203.0.113.0/24"}}},
+		}}}, nil
+	}
+	pair := routing.RuleSetNames("Media", 0)
+	snapshots := map[string]ipsetSnapshot{pair.DestinationV4: {Members: []string{"203.0.113.0/24"}}}
+	listedAt := time.Unix(1_700_000_000, 0)
+	cache := &flowRuleCache{}
+
+	first, _, err := cache.get("wg-sgp", 1, snapshots, listedAt, load)
+	if err != nil || len(first) != 1 || first[0].DestinationPrefixes.Len() != 1 {
+		t.Fatalf("unexpected rules %+v err=%v", first, err)
+	}
+	again, _, _ := cache.get("wg-sgp", 1, snapshots, listedAt, load)
+	if loads != 1 || &again[0] != &first[0] {
+		t.Fatalf("expected cached rules, loads=%d", loads)
+	}
+
+	snapshots = map[string]ipsetSnapshot{pair.DestinationV4: {Members: []string{"203.0.113.0/24", "198.51.100.7"}}}
+	refreshed, _, _ := cache.get("wg-sgp", 1, snapshots, listedAt.Add(3*time.Second), load)
+	if loads != 1 || refreshed[0].DestinationPrefixes.Len() != 2 {
+		t.Fatalf("expected a recompile from the new listing without a reload, loads=%d rules=%+v", loads, refreshed)
+	}
+
+	if _, _, err := cache.get("wg-sgp", 2, snapshots, listedAt.Add(3*time.Second), load); err != nil || loads != 2 {
+		t.Fatalf("expected a reload after the generation changed, loads=%d err=%v", loads, err)
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/agent"
+)
+
+const hostKeyScanTimeout = 15 * time.Second
+
+// configureAgents logs and streams remote agent apply failures and
+// recoveries.
+func (s *Server) configureAgents(manager *agent.Manager) {
+	s.agents = manager
+	manager.SetHandler(func(event agent.Event) {
+		if s.diagLog != nil {
+			if event.Kind == agent.KindFailed {
+				s.diagLog.Warnf("remote agent agent=%s kind=%s detail=%q", event.Agent, event.Kind, event.Detail)
+			} else {
+				s.diagLog.Infof("remote agent agent=%s kind=%s detail=%q", event.Agent, event.Kind, event.Detail)
+			}
+		}
+		s.broadcastEvent("agents", event)
+	})
+}
+
+func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
+	if s.agents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "remote agents unavailable"})
+		return
+	}
+	agents, err := s.agents.Store().List(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	statuses, err := s.agents.Status(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"agents":    agents,
+		"statuses":  statuses,
+		"publicKey": s.agents.PublicKey(),
+	})
+}
+
+func (s *Server) handleCreateAgent(w http.ResponseWriter, r *http.Request) {
+	if s.agents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "remote agents unavailable"})
+		return
+	}
+	var payload agent.Agent
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	created, err := s.agents.Store().Create(r.Context(), payload)
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+	s.applyAgentAsync(created)
+	writeJSON(w, http.StatusCreated, map[string]any{"agent": created})
+}
+
+func (s *Server) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
+	if s.agents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "remote agents unavailable"})
+		return
+	}
+	id, err := parseAgentID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var payload agent.Agent
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	updated, err := s.agents.Store().Update(r.Context(), id, payload)
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+	s.applyAgentAsync(updated)
+	writeJSON(w, http.StatusOK, map[string]any{"agent": updated})
+}
+
+// handleDeleteAgent clears the agent's device before forgetting it;
+// ?force=1 deletes it even when the device cannot be reached.
+func (s *Server) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
+	if s.agents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "remote agents unavailable"})
+		return
+	}
+	id, err := parseAgentID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	if err := s.agents.Remove(r.Context(), id, force); err != nil {
+		if errors.Is(err, agent.ErrNotFound) {
+			writeAgentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (s *Server) handleApplyAgent(w http.ResponseWriter, r *http.Request) {
+	if s.agents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "remote agents unavailable"})
+		return
+	}
+	id, err := parseAgentID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	status, err := s.agents.ApplyNow(context.WithoutCancel(r.Context()), id)
+	if err != nil {
+		if errors.Is(err, agent.ErrNotFound) {
+			writeAgentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusBadGateway, map[string]any{"error": err.Error(), "status": status})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": status})
+}
+
+// handleScanAgentHostKey reports the SSH host key fingerprint a device
+// presents, to be checked against the device before it is pinned.
+func (s *Server) handleScanAgentHostKey(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	host := strings.TrimSpace(payload.Host)
+	if host == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "host is required"})
+		return
+	}
+	if payload.Port == 0 {
+		payload.Port = 22
+	}
+	if payload.Port < 1 || payload.Port > 65535 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "port must be between 1 and 65535"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), hostKeyScanTimeout)
+	defer cancel()
+	address := (agent.Agent{Host: host, Port: payload.Port}).Address()
+	fingerprint, err := agent.ScanHostKey(ctx, address)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("scan %s: %v", address, err)})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"hostKey": fingerprint})
+}
+
+// applyAgentAsync pushes routing to a new or edited agent without holding
+// up the response; the result arrives with the next status poll.
+func (s *Server) applyAgentAsync(saved agent.Agent) {
+	if !saved.Enabled {
+		return
+	}
+	go func() {
+		if _, err := s.agents.ApplyNow(context.Background(), saved.ID); err != nil && s.diagLog != nil {
+			s.diagLog.Warnf("remote agent agent=%s apply failed: %v", saved.Name, err)
+		}
+		s.broadcastEvent("agents", map[string]any{"agentId": saved.ID, "agent": saved.Name})
+	}()
+}
+
+func parseAgentID(raw string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid agent id")
+	}
+	return id, nil
+}
+
+func writeAgentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, agent.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, agent.ErrNameTaken):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"split-vpn-webui/internal/anomaly"
+	"split-vpn-webui/internal/stats"
+)
+
+// configureAnomalyMonitor records raised and cleared traffic anomalies on
+// the VPN timeline and streams them over SSE.
+func (s *Server) configureAnomalyMonitor(monitor *anomaly.Monitor) {
+	s.anomalies = monitor
+	monitor.SetHandler(func(event anomaly.Event) {
+		if s.diagLog != nil {
+			switch event.Kind {
+			case anomaly.KindHigh, anomaly.KindStall:
+				s.diagLog.Warnf("traffic anomaly vpn=%s kind=%s detail=%q", event.VPN, event.Kind, event.Detail)
+			default:
+				s.diagLog.Infof("traffic anomaly cleared vpn=%s kind=%s detail=%q", event.VPN, event.Kind, event.Detail)
+			}
+		}
+		s.recordVPNEvent(context.Background(), event.VPN, event.Kind, event.Detail)
+		s.broadcastEvent("anomaly", event)
+	})
+}
+
+// anomalySamples reports the current throughput of every tracked VPN.
+func (s *Server) anomalySamples() []anomaly.Sample {
+	snapshot := s.stats.Snapshot()
+	samples := make([]anomaly.Sample, 0, len(snapshot.Interfaces))
+	for _, iface := range snapshot.Interfaces {
+		if iface.Type != stats.InterfaceVPN {
+			continue
+		}
+		samples = append(samples, anomaly.Sample{
+			VPN:           iface.Name,
+			Available:     iface.Available,
+			ThroughputBps: iface.CurrentThroughput,
+		})
+	}
+	return samples
+}
+
+// countVPNFlows counts the conntrack flows carrying each VPN's mark.
+func (s *Server) countVPNFlows(ctx context.Context, vpns []string) (map[string]int, error) {
+	if s.vpnManager == nil || s.flowRunner == nil {
+		return nil, nil
+	}
+	marks := make(map[string]uint32, len(vpns))
+	for _, name := range vpns {
+		if profile, err := s.vpnManager.Get(name); err == nil && profile != nil {
+			marks[name] = profile.FWMark
+		}
+	}
+	if len(marks) == 0 {
+		return nil, nil
+	}
+	flows, err := s.flowRunner.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(marks))
+	for _, flow := range flows {
+		for name, mark := range marks {
+			if flowMarkMatchesVPN(flow.Mark, mark) {
+				counts[name]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+func (s *Server) handleAnomalies(w http.ResponseWriter, r *http.Request) {
+	if s.anomalies == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "anomaly monitor unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"active": s.anomalies.Active()})
+}
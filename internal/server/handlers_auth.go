@@ -2,15 +2,18 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
-)
+	"time"
 
-const sessionCookieName = "svpn_session"
+	"split-vpn-webui/internal/auth"
+	"split-vpn-webui/internal/settingsrevisions"
+)
 
 func (s *Server) handleLoginGet(w http.ResponseWriter, r *http.Request) {
 	// Already authenticated — redirect to dashboard.
-	if cookie, err := r.Cookie("svpn_session"); err == nil && s.auth.ValidateToken(cookie.Value) {
+	if s.auth.HasSession(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -26,32 +29,31 @@ func (s *Server) handleLoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	password := r.FormValue("password")
-	if !s.auth.CheckPassword(password) {
+	ok, wait := s.auth.Login(r, password)
+	if !ok {
+		message := "Invalid password. Please try again."
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if wait > 0 {
+			message = fmt.Sprintf("Too many attempts. Try again in %s.", wait.Round(time.Second))
+			w.Header().Set("Retry-After", auth.RetryAfterSeconds(wait))
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
 		if err := s.templates.ExecuteTemplate(w, "login.html", map[string]any{
-			"Error": "Invalid password. Please try again.",
+			"Error": message,
 		}); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
-	token, err := s.auth.GetToken()
-	if err != nil || token == "" {
+	if err := s.auth.StartSession(w, r); err != nil {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	setSessionCookie(w, token)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     sessionCookieName,
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	})
+	s.auth.EndSession(w, r)
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
@@ -65,13 +67,13 @@ func (s *Server) handleGetAuthToken(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleRegenerateAuthToken(w http.ResponseWriter, r *http.Request) {
+	before := s.currentSettings()
 	token, err := s.auth.RegenerateToken()
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	// Keep browser session alive after token rotation.
-	setSessionCookie(w, token)
+	s.recordSettingsRevision(r, settingsrevisions.ActionToken, before)
 	writeJSON(w, http.StatusOK, map[string]string{"token": token})
 }
 
@@ -88,24 +90,25 @@ func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "currentPassword and newPassword are required"})
 		return
 	}
-	if !s.auth.CheckPassword(payload.CurrentPassword) {
+	if ok, wait := s.auth.ConfirmPassword(r, payload.CurrentPassword); !ok {
+		if wait > 0 {
+			w.Header().Set("Retry-After", auth.RetryAfterSeconds(wait))
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": fmt.Sprintf("too many attempts; retry in %s", wait.Round(time.Second))})
+			return
+		}
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "current password is incorrect"})
 		return
 	}
+	before := s.currentSettings()
 	if err := s.auth.SetPassword(payload.NewPassword); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
+	s.recordSettingsRevision(r, settingsrevisions.ActionPassword, before)
+	// Whoever knew the old password may still hold a session elsewhere.
+	if _, err := s.auth.RevokeOtherSessions(r); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "password changed, but signing out other sessions failed: " + err.Error()})
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
-
-func setSessionCookie(w http.ResponseWriter, token string) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     sessionCookieName,
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   30 * 24 * 60 * 60, // 30 days
-	})
-}
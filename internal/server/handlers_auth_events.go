@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"split-vpn-webui/internal/auth"
+	"split-vpn-webui/internal/authevents"
+)
+
+const authEventTimeout = 5 * time.Second
+
+// recordAuthEvent stores an authentication audit event and mirrors
+// failures to the diagnostics log. It runs inside the request being
+// audited, so a slow write is bounded rather than tied to the client.
+func (s *Server) recordAuthEvent(event auth.Event) {
+	if s.diagLog != nil && event.Type != auth.EventLoginSucceeded {
+		s.diagLog.Warnf("auth %s from %s: %s", event.Type, event.Remote, event.Detail)
+	}
+	if s.authEvents == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), authEventTimeout)
+	defer cancel()
+	if _, err := s.authEvents.Record(ctx, authevents.Event{Type: event.Type, Remote: event.Remote, Detail: event.Detail}); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("record auth event %s failed: %v", event.Type, err)
+	}
+}
+
+func (s *Server) handleListAuthEvents(w http.ResponseWriter, r *http.Request) {
+	if s.authEvents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "auth event store unavailable"})
+		return
+	}
+	limit, ok := parseNonNegativeQuery(w, r, "limit", authevents.DefaultPageSize)
+	if !ok {
+		return
+	}
+	offset, ok := parseNonNegativeQuery(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	page, err := s.authEvents.List(r.Context(), limit, offset)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
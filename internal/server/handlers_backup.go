@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"split-vpn-webui/internal/backup"
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/settingsrevisions"
+	"split-vpn-webui/internal/vpnrevisions"
 )
 
 const (
@@ -21,7 +24,9 @@ func (s *Server) handleExportBackup(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "backup manager unavailable"})
 		return
 	}
+	job := s.trackJob(jobs.KindBackupExport, "api")
 	snapshot, err := s.backup.Export(r.Context())
+	job.Finish(err)
 	if err != nil {
 		writeBackupError(w, err)
 		return
@@ -49,13 +54,25 @@ func (s *Server) handleImportBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	job := s.trackJob(jobs.KindBackupImport, "api")
 	resume, err := s.pauseSchedulers()
 	if err != nil {
+		job.Finish(err)
 		writeBackupError(w, err)
 		return
 	}
+	job.Logf("info", "schedulers paused; importing backup")
+	settingsBefore := s.currentSettings()
 	result, importErr := s.backup.Import(r.Context(), snapshot)
 	resumeErr := resume()
+	for _, warning := range result.Warnings {
+		job.Logf("warn", "%s", warning)
+	}
+	if importErr != nil {
+		job.Finish(combineImportAndResumeError(importErr, resumeErr))
+	} else {
+		job.Finish(resumeErr)
+	}
 	if importErr != nil {
 		writeBackupError(w, combineImportAndResumeError(importErr, resumeErr))
 		return
@@ -65,6 +82,14 @@ func (s *Server) handleImportBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.vpnRevisions != nil && s.vpnManager != nil {
+		if profiles, err := s.vpnManager.List(); err == nil {
+			for _, profile := range profiles {
+				s.recordVPNRevision(r, vpnrevisions.ActionRestore, nil, profile)
+			}
+		}
+	}
+	s.recordSettingsRevision(r, settingsrevisions.ActionImport, settingsBefore)
 	if err := s.refreshState(); err != nil {
 		writeBackupError(w, err)
 		return
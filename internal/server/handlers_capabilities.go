@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"split-vpn-webui/internal/capabilities"
+)
+
+// handleSystemCapabilities reports the tools and kernel modules routing
+// depends on, with versions and remediation hints.
+func (s *Server) handleSystemCapabilities(w http.ResponseWriter, r *http.Request) {
+	if s.capabilities == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "capability checks unavailable"})
+		return
+	}
+	report := s.capabilities.Check(r.Context())
+	if s.diagLog != nil && !report.Passed {
+		for _, check := range report.Checks {
+			if check.Status == capabilities.StatusFail {
+				s.diagLog.Warnf("capability check failed %s=%s: %s", check.Kind, check.Name, check.Detail)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"split-vpn-webui/internal/vpnrevisions"
+)
+
+// handleReadConfig returns the profile's WireGuard/OpenVPN config for the
+// live editor.
+func (s *Server) handleReadConfig(w http.ResponseWriter, r *http.Request) {
+	if s.vpnManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn manager unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	profile, err := s.vpnManager.Get(name)
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"content":    profile.RawConfig,
+		"configFile": profile.ConfigFile,
+		"type":       profile.Type,
+	})
+}
+
+// handleWriteConfig validates and saves edited config content. Parse and
+// allocation errors come back as 400/409 with the validation message so the
+// editor can show them inline; the saved config is added to the revision
+// history.
+func (s *Server) handleWriteConfig(w http.ResponseWriter, r *http.Request) {
+	if s.vpnManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn manager unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	if strings.TrimSpace(payload.Content) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "content must not be empty"})
+		return
+	}
+	before := s.currentVPNProfile(name)
+	profile, err := s.vpnManager.WriteConfig(name, payload.Content)
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	s.recordVPNRevision(r, vpnrevisions.ActionEdit, before, profile)
+	s.afterConfigChange(w, r, profile.Name)
+}
+
+// afterConfigChange refreshes state and routing like a profile update, then
+// returns the saved profile.
+func (s *Server) afterConfigChange(w http.ResponseWriter, r *http.Request, name string) {
+	if err := s.refreshState(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.routingManager != nil {
+		if err := s.routingManager.Apply(r.Context()); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	s.broadcastUpdate(nil)
+	profile, err := s.vpnManager.Get(name)
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"vpn": profile})
+}
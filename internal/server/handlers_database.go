@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/dbmaint"
+)
+
+// configureDatabaseMaintenance defers checkpoints and vacuums while the
+// resolver or pre-warm is writing, purges expired VPN trash and logs each
+// maintenance pass.
+func (s *Server) configureDatabaseMaintenance(maintainer *dbmaint.Maintainer) {
+	s.dbMaint = maintainer
+	maintainer.SetIdleCheck(s.databaseIdle)
+	maintainer.SetHandler(func(result dbmaint.Result) {
+		s.purgeVPNTrash(result)
+		if s.diagLog == nil {
+			return
+		}
+		if result.Error != "" {
+			s.diagLog.Errorf("database maintenance failed: %s", result.Error)
+			return
+		}
+		s.diagLog.Debugf("database maintenance pruned=%d checkpointed=%t vacuumed=%t deferred=%t",
+			result.Pruned.Total(), result.Checkpointed, result.Vacuumed, result.Deferred)
+	})
+}
+
+// databaseIdle reports whether no resolver or pre-warm run is in progress.
+func (s *Server) databaseIdle() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if s.resolver != nil {
+		if status, err := s.resolver.Status(ctx); err != nil || status.Running {
+			return false
+		}
+	}
+	if s.prewarm != nil {
+		if status, err := s.prewarm.Status(ctx); err != nil || status.Running {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) handleDatabaseStatus(w http.ResponseWriter, r *http.Request) {
+	if s.dbMaint == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "database maintenance unavailable"})
+		return
+	}
+	status, err := s.dbMaint.Inspect(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"database":    status,
+		"maintenance": s.dbMaint.Status(),
+	})
+}
+
+// handleHealth reports whether the database is reachable and which schema
+// version it carries next to the newest one this build knows.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	payload := map[string]any{
+		"status":       "ok",
+		"schemaLatest": database.LatestSchemaVersion(),
+	}
+	if s.dbMaint == nil {
+		payload["status"] = "error"
+		payload["error"] = "database unavailable"
+		writeJSON(w, http.StatusServiceUnavailable, payload)
+		return
+	}
+	version, err := s.dbMaint.SchemaVersion(r.Context())
+	if err != nil {
+		payload["status"] = "error"
+		payload["error"] = err.Error()
+		writeJSON(w, http.StatusServiceUnavailable, payload)
+		return
+	}
+	payload["schemaVersion"] = version
+	writeJSON(w, http.StatusOK, payload)
+}
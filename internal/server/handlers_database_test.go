@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/dbmaint"
+	"split-vpn-webui/internal/settings"
+)
+
+func TestHandleHealthReportsSchemaVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stats.db")
+	db, err := database.Open(path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	maintainer, err := dbmaint.NewMaintainer(db, path, settings.NewManager(filepath.Join(dir, "settings.json")))
+	if err != nil {
+		t.Fatalf("new maintainer: %v", err)
+	}
+	s := &Server{dbMaint: maintainer}
+
+	recorder := httptest.NewRecorder()
+	s.handleHealth(recorder, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var payload struct {
+		Status        string `json:"status"`
+		SchemaVersion int    `json:"schemaVersion"`
+		SchemaLatest  int    `json:"schemaLatest"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload.Status != "ok" || payload.SchemaVersion != database.LatestSchemaVersion() || payload.SchemaLatest != payload.SchemaVersion {
+		t.Fatalf("unexpected health payload: %+v", payload)
+	}
+}
+
+func TestHandleHealthWithoutDatabase(t *testing.T) {
+	s := &Server{}
+	recorder := httptest.NewRecorder()
+	s.handleHealth(recorder, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", recorder.Code)
+	}
+}
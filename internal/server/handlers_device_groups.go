@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/routing"
+)
+
+type deviceGroupUpsertPayload struct {
+	Name      string   `json:"name"`
+	MACs      []string `json:"macs,omitempty"`
+	CIDRs     []string `json:"cidrs,omitempty"`
+	SyncNames []string `json:"syncNames,omitempty"`
+}
+
+func (s *Server) handleListDeviceGroups(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	groups, err := s.routingManager.ListDeviceGroups(r.Context())
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deviceGroups": groups})
+}
+
+func (s *Server) handleCreateDeviceGroup(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	payload, err := decodeDeviceGroupPayload(r)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "device group create")
+	created, err := s.routingManager.CreateDeviceGroup(r.Context(), payload)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.syncDeviceGroups(r.Context())
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusCreated, map[string]any{"deviceGroup": created})
+}
+
+func (s *Server) handleUpdateDeviceGroup(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	payload, err := decodeDeviceGroupPayload(r)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "device group update")
+	updated, err := s.routingManager.UpdateDeviceGroup(r.Context(), id, payload)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.syncDeviceGroups(r.Context())
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]any{"deviceGroup": updated})
+}
+
+func (s *Server) handleDeleteDeviceGroup(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "device group delete")
+	err = s.routingManager.DeleteDeviceGroup(r.Context(), id)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (s *Server) handleSyncDeviceGroups(w http.ResponseWriter, r *http.Request) {
+	if s.deviceSync == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "device group sync unavailable"})
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "device group sync")
+	changed, err := s.deviceSync.Sync(r.Context())
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	if len(changed) > 0 {
+		s.broadcastUpdate(nil)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"changed": changed})
+}
+
+// syncDeviceGroups fills synced MACs right after an edit instead of waiting
+// for the next periodic sync.
+func (s *Server) syncDeviceGroups(ctx context.Context) {
+	if s.deviceSync == nil {
+		return
+	}
+	_, _ = s.deviceSync.Sync(ctx)
+}
+
+// configureDeviceSyncWatcher logs device groups whose synced members changed.
+func (s *Server) configureDeviceSyncWatcher(watcher *routing.DeviceSyncWatcher) {
+	s.deviceSync = watcher
+	watcher.SetHandler(func(changed []string, err error) {
+		if s.diagLog == nil {
+			return
+		}
+		if err != nil {
+			s.diagLog.Errorf("device group sync failed: %v", err)
+			return
+		}
+		s.diagLog.Infof("device group members synced groups=%s", strings.Join(changed, ","))
+	})
+}
+
+// discoverSyncDevices lists named clients from the merged device directory.
+func (s *Server) discoverSyncDevices(ctx context.Context) []routing.SyncDevice {
+	directory := s.loadDeviceDirectory(ctx)
+	devices := make([]routing.SyncDevice, 0)
+	for _, device := range directory.listDevices() {
+		if strings.TrimSpace(device.Name) == "" {
+			continue
+		}
+		devices = append(devices, routing.SyncDevice{
+			MAC:     device.MAC,
+			Name:    device.Name,
+			Network: device.Network,
+			FixedIP: device.FixedIP,
+		})
+	}
+	return devices
+}
+
+func decodeDeviceGroupPayload(r *http.Request) (routing.DeviceGroup, error) {
+	var payload deviceGroupUpsertPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return routing.DeviceGroup{}, fmt.Errorf("%w: invalid JSON body", routing.ErrGroupValidation)
+	}
+	return routing.NormalizeDeviceGroup(routing.DeviceGroup{
+		Name:      payload.Name,
+		MACs:      payload.MACs,
+		CIDRs:     payload.CIDRs,
+		SyncNames: payload.SyncNames,
+	})
+}
@@ -1,10 +1,67 @@
 package server
 
-import "net/http"
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/routing"
+)
 
 func (s *Server) handleListDevices(w http.ResponseWriter, r *http.Request) {
-	directory := loadDeviceDirectory(r.Context())
+	directory := s.loadDeviceDirectory(r.Context())
 	writeJSON(w, http.StatusOK, map[string]any{
 		"devices": directory.listDevices(),
 	})
 }
+
+func (s *Server) handleListDeviceAliases(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	aliases, err := s.routingManager.ListDeviceAliases(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"aliases": aliases})
+}
+
+func (s *Server) handleSetDeviceAlias(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	alias, err := s.routingManager.SetDeviceAlias(r.Context(), routing.DeviceAlias{
+		MAC:  chi.URLParam(r, "mac"),
+		Name: payload.Name,
+	})
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]any{"alias": alias})
+}
+
+func (s *Server) handleDeleteDeviceAlias(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	if err := s.routingManager.DeleteDeviceAlias(r.Context(), chi.URLParam(r, "mac")); err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"split-vpn-webui/internal/diagbundle"
+	"split-vpn-webui/internal/version"
+)
+
+// diagnosticsLogTailBytes bounds the diagnostics log copied into a bundle.
+const diagnosticsLogTailBytes = 4 << 20
+
+// bundleVPN describes a VPN profile for a bundle without its config, which
+// holds keys and credentials.
+type bundleVPN struct {
+	Name           string   `json:"name"`
+	Type           string   `json:"type"`
+	InterfaceName  string   `json:"interfaceName"`
+	RouteTable     int      `json:"routeTable"`
+	FWMark         string   `json:"fwMark"`
+	Gateway        string   `json:"gateway,omitempty"`
+	BoundInterface string   `json:"boundInterface,omitempty"`
+	DependsOn      []string `json:"dependsOn,omitempty"`
+	UplinkVPN      string   `json:"uplinkVpn,omitempty"`
+	MSSClampV4     string   `json:"mssClampV4,omitempty"`
+	MSSClampV6     string   `json:"mssClampV6,omitempty"`
+	IPv6Policy     string   `json:"ipv6Policy,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+// handleDiagnosticsBundle streams a tar.gz of sanitized settings, routing
+// state, firewall and ipset dumps, recent logs and version info to attach
+// to bug reports. Parts that cannot be collected are listed in the
+// bundle's manifest rather than failing the download.
+func (s *Server) handleDiagnosticsBundle(w http.ResponseWriter, r *http.Request) {
+	filename := fmt.Sprintf("split-vpn-webui-diagnostics-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	var bundle *diagbundle.Writer
+	if s.bundleRunner != nil {
+		bundle = diagbundle.NewWriterWithRunner(w, s.bundleRunner)
+	} else {
+		bundle = diagbundle.NewWriter(w)
+	}
+	if err := s.writeDiagnosticsBundle(r.Context(), bundle); err != nil {
+		if s.diagLog != nil {
+			s.diagLog.Warnf("diagnostics bundle aborted: %v", err)
+		}
+		return
+	}
+	if err := bundle.Close(); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("diagnostics bundle close failed: %v", err)
+	}
+}
+
+func (s *Server) writeDiagnosticsBundle(ctx context.Context, bundle *diagbundle.Writer) error {
+	if err := bundle.AddJSON("version.json", map[string]any{
+		"build":          version.Current(),
+		"go":             runtime.Version(),
+		"os":             runtime.GOOS,
+		"arch":           runtime.GOARCH,
+		"systemdManaged": s.systemdManaged,
+	}); err != nil {
+		return err
+	}
+	if s.settings != nil {
+		if current, err := s.settings.Get(); err != nil {
+			bundle.AddError("settings.json", err)
+		} else if err := bundle.AddJSON("settings.json", publicSettings(current)); err != nil {
+			return err
+		}
+	}
+	if s.vpnManager != nil {
+		if err := s.addBundleVPNs(bundle); err != nil {
+			return err
+		}
+	}
+	if s.routingManager != nil {
+		if err := s.addBundleRouting(ctx, bundle); err != nil {
+			return err
+		}
+	}
+	if s.capabilities != nil {
+		if err := bundle.AddJSON("system/capabilities.json", s.capabilities.Check(ctx)); err != nil {
+			return err
+		}
+	}
+	if s.jobs != nil {
+		if err := bundle.AddJSON("jobs.json", s.jobs.List("", 50)); err != nil {
+			return err
+		}
+	}
+	if s.diagLog != nil {
+		if tail, err := s.diagLog.Tail(diagnosticsLogTailBytes); err != nil {
+			bundle.AddError("logs/diagnostics.log", err)
+		} else if err := bundle.AddFile("logs/diagnostics.log", tail); err != nil {
+			return err
+		}
+	}
+	for _, command := range diagbundle.DefaultCommands {
+		if err := bundle.AddCommand(ctx, command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) addBundleVPNs(bundle *diagbundle.Writer) error {
+	profiles, err := s.vpnManager.List()
+	if err != nil {
+		bundle.AddError("vpns.json", err)
+		return nil
+	}
+	vpns := make([]bundleVPN, 0, len(profiles))
+	for _, profile := range profiles {
+		vpns = append(vpns, bundleVPN{
+			Name:           profile.Name,
+			Type:           profile.Type,
+			InterfaceName:  profile.InterfaceName,
+			RouteTable:     profile.RouteTable,
+			FWMark:         fmt.Sprintf("0x%x", profile.FWMark),
+			Gateway:        profile.Gateway,
+			BoundInterface: profile.BoundInterface,
+			DependsOn:      profile.DependsOn,
+			UplinkVPN:      profile.UplinkVPN,
+			MSSClampV4:     profile.MSSClampV4,
+			MSSClampV6:     profile.MSSClampV6,
+			IPv6Policy:     profile.IPv6Policy,
+			Warnings:       profile.Warnings,
+		})
+	}
+	return bundle.AddJSON("vpns.json", vpns)
+}
+
+func (s *Server) addBundleRouting(ctx context.Context, bundle *diagbundle.Writer) error {
+	if groups, err := s.routingManager.ListGroups(ctx); err != nil {
+		bundle.AddError("routing/groups.json", err)
+	} else if err := bundle.AddJSON("routing/groups.json", groups); err != nil {
+		return err
+	}
+	if deviceGroups, err := s.routingManager.ListDeviceGroups(ctx); err != nil {
+		bundle.AddError("routing/device-groups.json", err)
+	} else if err := bundle.AddJSON("routing/device-groups.json", deviceGroups); err != nil {
+		return err
+	}
+	// The dry-run diff shows both the desired rules and sets and how far the
+	// live system has drifted from them.
+	if plan, err := s.routingManager.DryRunApply(ctx); err != nil {
+		bundle.AddError("routing/apply-plan.json", err)
+	} else if err := bundle.AddJSON("routing/apply-plan.json", plan); err != nil {
+		return err
+	}
+	return bundle.AddJSON("routing/apply-stats.json", s.routingManager.ApplyStats())
+}
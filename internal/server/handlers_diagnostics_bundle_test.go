@@ -0,0 +1,56 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeBundleRunner struct{}
+
+func (fakeBundleRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if name == "wg" {
+		return []byte("interface: wg-sv-nl\n  private key: (hidden)\n"), nil
+	}
+	return []byte(name + " output\n"), nil
+}
+
+func TestHandleDiagnosticsBundleStreamsTarball(t *testing.T) {
+	s := &Server{bundleRunner: fakeBundleRunner{}}
+	rec := httptest.NewRecorder()
+	s.handleDiagnosticsBundle(rec, httptest.NewRequest(http.MethodGet, "/api/diagnostics/bundle", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Disposition"), ".tar.gz") {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		files[strings.TrimPrefix(header.Name, "split-vpn-webui-diagnostics/")] = string(body)
+	}
+	for _, name := range []string{"version.json", "system/iptables-save.txt", "system/ipset-list.txt", "logs/journal.txt", "manifest.json"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("missing %s in bundle: %v", name, files)
+		}
+	}
+	if !strings.Contains(files["system/wg-show.txt"], "private key: <redacted>") {
+		t.Fatalf("expected wg output to be redacted: %q", files["system/wg-show.txt"])
+	}
+}
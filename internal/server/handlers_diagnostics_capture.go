@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/pcap"
+	"split-vpn-webui/internal/util"
+)
+
+type packetCapturer interface {
+	Capture(ctx context.Context, opts pcap.Options, dst io.Writer) (pcap.Result, error)
+}
+
+// captureInterface is one interface a capture may run on.
+type captureInterface struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	VPN  string `json:"vpn,omitempty"`
+}
+
+// handleListCaptureInterfaces returns the VPN and bridge interfaces a
+// capture may be started on.
+func (s *Server) handleListCaptureInterfaces(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"interfaces": s.captureInterfaces(),
+		"limits": map[string]any{
+			"defaultDurationSeconds": int(pcap.DefaultDuration / time.Second),
+			"maxDurationSeconds":     int(pcap.MaxDuration / time.Second),
+			"defaultMaxBytes":        pcap.DefaultMaxBytes,
+			"maxBytes":               pcap.MaxBytesLimit,
+		},
+	})
+}
+
+// handleCapture runs a bounded tcpdump on a VPN or bridge interface and
+// streams the pcap as a download. Only one capture runs at a time.
+func (s *Server) handleCapture(w http.ResponseWriter, r *http.Request) {
+	if s.capture == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "packet capture unavailable"})
+		return
+	}
+	opts, err := parseCaptureQuery(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	allowed := false
+	for _, candidate := range s.captureInterfaces() {
+		if candidate.Name == opts.Interface {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "capture interface must be a VPN or bridge interface"})
+		return
+	}
+	if !s.captureActive.CompareAndSwap(false, true) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "another capture is already running"})
+		return
+	}
+	defer s.captureActive.Store(false)
+
+	filename := fmt.Sprintf("capture-%s-%s.pcap", opts.Interface, time.Now().UTC().Format("20060102-150405"))
+	out := &captureResponseWriter{w: w, filename: filename}
+	started := time.Now()
+	result, err := s.capture.Capture(r.Context(), opts, out)
+	if s.diagLog != nil {
+		if err != nil {
+			s.diagLog.Warnf("packet capture iface=%s filter=%q failed: %v", opts.Interface, opts.Filter(), err)
+		} else {
+			s.diagLog.Infof("packet capture iface=%s filter=%q packets=%d bytes=%d stop=%s duration_ms=%d",
+				opts.Interface, opts.Filter(), result.Packets, result.Bytes, result.StopReason, time.Since(started).Milliseconds())
+		}
+	}
+	if err != nil && !out.started {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+	}
+}
+
+// captureInterfaces lists managed VPN interfaces followed by bridges.
+func (s *Server) captureInterfaces() []captureInterface {
+	out := make([]captureInterface, 0)
+	seen := make(map[string]struct{})
+	if s.vpnManager != nil {
+		if profiles, err := s.vpnManager.List(); err == nil {
+			for _, profile := range profiles {
+				iface := strings.TrimSpace(profile.InterfaceName)
+				if iface == "" {
+					continue
+				}
+				seen[iface] = struct{}{}
+				out = append(out, captureInterface{Name: iface, Kind: "vpn", VPN: profile.Name})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	if infos, err := util.InterfacesWithAddrs(); err == nil {
+		for _, info := range infos {
+			if _, ok := seen[info.Name]; ok || !strings.HasPrefix(info.Name, "br") {
+				continue
+			}
+			out = append(out, captureInterface{Name: info.Name, Kind: "bridge"})
+		}
+	}
+	return out
+}
+
+func parseCaptureQuery(r *http.Request) (pcap.Options, error) {
+	query := r.URL.Query()
+	opts := pcap.Options{
+		Interface: query.Get("interface"),
+		Host:      query.Get("host"),
+		Protocol:  query.Get("protocol"),
+	}
+	intParam := func(name string) (int64, error) {
+		raw := strings.TrimSpace(query.Get(name))
+		if raw == "" {
+			return 0, nil
+		}
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("%s must be a non-negative integer", name)
+		}
+		return value, nil
+	}
+	port, err := intParam("port")
+	if err != nil {
+		return pcap.Options{}, err
+	}
+	seconds, err := intParam("duration")
+	if err != nil {
+		return pcap.Options{}, err
+	}
+	maxBytes, err := intParam("maxBytes")
+	if err != nil {
+		return pcap.Options{}, err
+	}
+	packets, err := intParam("maxPackets")
+	if err != nil {
+		return pcap.Options{}, err
+	}
+	if port > 65535 || seconds > int64(pcap.MaxDuration/time.Second) || packets > pcap.DefaultMaxPackets {
+		return pcap.Options{}, fmt.Errorf("capture parameter out of range")
+	}
+	opts.Port = int(port)
+	opts.Duration = time.Duration(seconds) * time.Second
+	opts.MaxBytes = maxBytes
+	opts.MaxPackets = int(packets)
+	return opts.Normalize()
+}
+
+// captureResponseWriter sends download headers with the first pcap bytes,
+// so a capture that fails before producing output can still answer JSON.
+type captureResponseWriter struct {
+	w        http.ResponseWriter
+	filename string
+	started  bool
+}
+
+func (c *captureResponseWriter) Write(p []byte) (int, error) {
+	if !c.started {
+		c.started = true
+		c.w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+		c.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, c.filename))
+		c.w.Header().Set("Cache-Control", "no-store")
+		c.w.WriteHeader(http.StatusOK)
+	}
+	n, err := c.w.Write(p)
+	if flusher, ok := c.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/pcap"
+)
+
+type fakeCapturer struct {
+	called bool
+}
+
+func (f *fakeCapturer) Capture(ctx context.Context, opts pcap.Options, dst io.Writer) (pcap.Result, error) {
+	f.called = true
+	return pcap.Result{}, errors.New("unexpected capture")
+}
+
+func TestParseCaptureQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/diagnostics/capture?interface=wg-sv-nl&host=203.0.113.9&port=443&protocol=tcp&duration=20&maxBytes=2048", nil)
+	opts, err := parseCaptureQuery(req)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if opts.Interface != "wg-sv-nl" || opts.Port != 443 || opts.Duration != 20*time.Second || opts.MaxBytes != 2048 {
+		t.Fatalf("unexpected options %#v", opts)
+	}
+
+	for _, query := range []string{
+		"interface=wg0&port=abc",
+		"interface=wg0&duration=100000",
+		"interface=wg0&host=example.com",
+		"interface=wg0&protocol=arp",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/diagnostics/capture?"+query, nil)
+		if _, err := parseCaptureQuery(req); err == nil {
+			t.Fatalf("expected %q to be rejected", query)
+		}
+	}
+}
+
+func TestHandleCaptureRejectsUnknownInterface(t *testing.T) {
+	capturer := &fakeCapturer{}
+	s := &Server{capture: capturer}
+	req := httptest.NewRequest(http.MethodGet, "/api/diagnostics/capture?interface=eth-not-managed", nil)
+	rec := httptest.NewRecorder()
+	s.handleCapture(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "VPN or bridge") {
+		t.Fatalf("expected 400 for unmanaged interface, got %d %s", rec.Code, rec.Body.String())
+	}
+	if capturer.called {
+		t.Fatalf("capture must not run for an unmanaged interface")
+	}
+}
+
+func TestCaptureResponseWriterSendsHeadersWithFirstBytes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	out := &captureResponseWriter{w: rec, filename: "capture-wg0.pcap"}
+	if out.started {
+		t.Fatalf("expected no headers before output")
+	}
+	if _, err := out.Write([]byte{0xd4, 0xc3, 0xb2, 0xa1}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="capture-wg0.pcap"` {
+		t.Fatalf("unexpected disposition %q", got)
+	}
+	if rec.Header().Get("Content-Type") != "application/vnd.tcpdump.pcap" || rec.Body.Len() != 4 {
+		t.Fatalf("unexpected response %v %d", rec.Header(), rec.Body.Len())
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/mtr"
+	"split-vpn-webui/internal/util"
+)
+
+// mtrTimeout covers the largest trace: one-second cycles plus spacing.
+const mtrTimeout = 40 * time.Second
+
+type pathTracer interface {
+	Trace(ctx context.Context, opts mtr.Options) (mtr.Report, error)
+}
+
+type mtrPayload struct {
+	Target  string `json:"target"`
+	VPN     string `json:"vpn"`
+	Family  string `json:"family"`
+	Cycles  int    `json:"cycles"`
+	MaxHops int    `json:"maxHops"`
+}
+
+// handleMTR traces the path to a target through a VPN tunnel, or through
+// the WAN when no VPN is named, and returns per-hop loss and latency.
+func (s *Server) handleMTR(w http.ResponseWriter, r *http.Request) {
+	if s.tracer == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "path tracer unavailable"})
+		return
+	}
+	var payload mtrPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	target := strings.TrimSpace(payload.Target)
+	if target == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "target is required"})
+		return
+	}
+	if payload.Cycles < 0 || payload.Cycles > mtr.MaxCycles || payload.MaxHops < 0 || payload.MaxHops > mtr.MaxHops {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cycles or maxHops out of range"})
+		return
+	}
+	family := strings.ToLower(strings.TrimSpace(payload.Family))
+	if family != "" && family != "ipv4" && family != "ipv6" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "family must be ipv4 or ipv6"})
+		return
+	}
+
+	iface, via, err := s.mtrInterface(strings.TrimSpace(payload.VPN))
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), mtrTimeout)
+	defer cancel()
+	addr, err := resolveMTRTarget(ctx, target, family)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	report, err := s.tracer.Trace(ctx, mtr.Options{
+		Interface: iface,
+		Target:    target,
+		Address:   addr,
+		Cycles:    payload.Cycles,
+		MaxHops:   payload.MaxHops,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("mtr via=%s iface=%s target=%s hops=%d reached=%t", via, iface, report.Address, len(report.Hops), report.Reached)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"via": via, "mtr": report})
+}
+
+// mtrInterface returns the interface for a named VPN, or the WAN interface.
+func (s *Server) mtrInterface(vpnName string) (string, string, error) {
+	if vpnName == "" {
+		iface, err := util.DetectWANInterface()
+		if err != nil {
+			return "", "", err
+		}
+		return iface, "wan", nil
+	}
+	if s.vpnManager == nil {
+		return "", "", errors.New("vpn manager unavailable")
+	}
+	profile, err := s.vpnManager.Get(vpnName)
+	if err != nil {
+		return "", "", err
+	}
+	iface := strings.TrimSpace(profile.InterfaceName)
+	if iface == "" {
+		return "", "", errors.New("vpn has no interface")
+	}
+	return iface, vpnName, nil
+}
+
+// resolveMTRTarget accepts a literal address or resolves a hostname,
+// preferring IPv4 unless family asks for IPv6.
+func resolveMTRTarget(ctx context.Context, target, family string) (netip.Addr, error) {
+	if addr, err := netip.ParseAddr(target); err == nil {
+		addr = addr.Unmap()
+		if (family == "ipv4" && !addr.Is4()) || (family == "ipv6" && !addr.Is6()) {
+			return netip.Addr{}, errors.New("target address does not match the requested family")
+		}
+		return addr, nil
+	}
+	network := "ip4"
+	if family == "ipv6" {
+		network = "ip6"
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, network, target)
+	if err != nil || len(addrs) == 0 {
+		return netip.Addr{}, errors.New("could not resolve target " + target)
+	}
+	return addrs[0].Unmap(), nil
+}
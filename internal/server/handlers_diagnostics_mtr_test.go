@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/mtr"
+)
+
+type fakeTracer struct {
+	calls int
+}
+
+func (f *fakeTracer) Trace(ctx context.Context, opts mtr.Options) (mtr.Report, error) {
+	f.calls++
+	return mtr.Report{Address: opts.Address.String(), Interface: opts.Interface}, nil
+}
+
+func TestHandleMTRValidatesPayload(t *testing.T) {
+	tracer := &fakeTracer{}
+	s := &Server{tracer: tracer}
+	for _, body := range []string{
+		`{`,
+		`{"target":""}`,
+		`{"target":"1.1.1.1","cycles":500}`,
+		`{"target":"1.1.1.1","family":"ipx"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/diagnostics/mtr", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleMTR(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if tracer.calls != 0 {
+		t.Fatalf("tracer must not run for invalid payloads")
+	}
+}
+
+func TestResolveMTRTargetChecksFamily(t *testing.T) {
+	addr, err := resolveMTRTarget(context.Background(), "::ffff:1.1.1.1", "ipv4")
+	if err != nil || addr.String() != "1.1.1.1" {
+		t.Fatalf("expected mapped address to unmap, got %v %v", addr, err)
+	}
+	if _, err := resolveMTRTarget(context.Background(), "1.1.1.1", "ipv6"); err == nil {
+		t.Fatalf("expected family mismatch to fail")
+	}
+}
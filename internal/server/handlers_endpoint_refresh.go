@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/endpointrefresh"
+)
+
+// configureEndpointRefresh records endpoint moves on the VPN timeline and
+// streams them over SSE.
+func (s *Server) configureEndpointRefresh(monitor *endpointrefresh.Monitor) {
+	s.endpoints = monitor
+	monitor.SetHandler(func(event endpointrefresh.Event) {
+		if s.diagLog != nil {
+			switch event.Kind {
+			case endpointrefresh.KindFailed:
+				s.diagLog.Warnf("vpn endpoint refresh vpn=%s kind=%s detail=%q", event.VPN, event.Kind, event.Detail)
+			default:
+				s.diagLog.Infof("vpn endpoint refresh vpn=%s kind=%s detail=%q", event.VPN, event.Kind, event.Detail)
+			}
+		}
+		s.recordVPNEvent(context.Background(), event.VPN, event.Kind, event.Detail)
+		s.broadcastEvent("endpoint-refresh", event)
+	})
+}
+
+// endpointRefreshTargets lists the WireGuard-style VPNs with a re-resolution
+// interval and their peers whose endpoint is a host name.
+func (s *Server) endpointRefreshTargets() ([]endpointrefresh.Target, error) {
+	profiles, err := s.vpnManager.List()
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]endpointrefresh.Target, 0)
+	for _, profile := range profiles {
+		if profile == nil || profile.EndpointRefreshSeconds <= 0 || profile.WireGuard == nil || profile.InterfaceName == "" {
+			continue
+		}
+		tool := "wg"
+		if profile.Type == "amneziawg" {
+			tool = "awg"
+		}
+		peers := make([]endpointrefresh.Peer, 0, len(profile.WireGuard.Peers))
+		for _, peer := range profile.WireGuard.Peers {
+			endpoint := strings.TrimSpace(peer.Endpoint)
+			host, _, err := net.SplitHostPort(endpoint)
+			if err != nil || peer.PublicKey == "" {
+				continue
+			}
+			if _, err := netip.ParseAddr(host); err == nil {
+				continue
+			}
+			peers = append(peers, endpointrefresh.Peer{PublicKey: peer.PublicKey, Endpoint: endpoint})
+		}
+		if len(peers) == 0 {
+			continue
+		}
+		targets = append(targets, endpointrefresh.Target{
+			VPN:       profile.Name,
+			Interface: profile.InterfaceName,
+			Tool:      tool,
+			Interval:  time.Duration(profile.EndpointRefreshSeconds) * time.Second,
+			Peers:     peers,
+		})
+	}
+	return targets, nil
+}
+
+func (s *Server) handleEndpointRefreshStatus(w http.ResponseWriter, r *http.Request) {
+	if s.endpoints == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "endpoint refresh monitor unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"vpns": s.endpoints.Status()})
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/routing"
+)
+
+// groupTemplatePayload creates a template from an existing group (groupId)
+// or from an inline group definition (group).
+type groupTemplatePayload struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	GroupID     int64               `json:"groupId,omitempty"`
+	Group       *groupUpsertPayload `json:"group,omitempty"`
+}
+
+// groupCopyPayload names the group created by a duplicate or instantiation.
+type groupCopyPayload struct {
+	Name      string `json:"name,omitempty"`
+	EgressVPN string `json:"egressVpn,omitempty"`
+}
+
+func (s *Server) handleDuplicateGroup(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	payload, err := decodeGroupCopyPayload(r)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "group duplicate")
+	created, err := s.routingManager.DuplicateGroup(r.Context(), id, payload.Name, payload.EgressVPN)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusCreated, map[string]any{"group": created})
+}
+
+func (s *Server) handleListGroupTemplates(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	templates, err := s.routingManager.ListGroupTemplates(r.Context())
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"templates": templates})
+}
+
+func (s *Server) handleCreateGroupTemplate(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	var payload groupTemplatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	var (
+		created *routing.GroupTemplate
+		err     error
+	)
+	switch {
+	case payload.GroupID > 0 && payload.Group != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "set either groupId or group, not both"})
+		return
+	case payload.GroupID > 0:
+		created, err = s.routingManager.SaveGroupAsTemplate(r.Context(), payload.GroupID, payload.Name, payload.Description)
+	case payload.Group != nil:
+		group, convErr := groupFromPayload(*payload.Group)
+		if convErr != nil {
+			writeRoutingError(w, convErr)
+			return
+		}
+		created, err = s.routingManager.CreateGroupTemplate(r.Context(), routing.GroupTemplate{
+			Name:        payload.Name,
+			Description: payload.Description,
+			Group:       group,
+		})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "groupId or group is required"})
+		return
+	}
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"template": created})
+}
+
+func (s *Server) handleDeleteGroupTemplate(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupTemplateID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := s.routingManager.DeleteGroupTemplate(r.Context(), id); err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (s *Server) handleInstantiateGroupTemplate(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupTemplateID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	payload, err := decodeGroupCopyPayload(r)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "group from template")
+	created, err := s.routingManager.InstantiateGroupTemplate(r.Context(), id, payload.Name, payload.EgressVPN)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusCreated, map[string]any{"group": created})
+}
+
+// decodeGroupCopyPayload reads an optional body; an empty body keeps the
+// defaults.
+func decodeGroupCopyPayload(r *http.Request) (groupCopyPayload, error) {
+	var payload groupCopyPayload
+	if r.ContentLength == 0 {
+		return payload, nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return groupCopyPayload{}, fmt.Errorf("%w: invalid JSON body", routing.ErrGroupValidation)
+	}
+	return payload, nil
+}
+
+func parseGroupTemplateID(raw string) (int64, error) {
+	id, err := parseGroupID(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid template id")
+	}
+	return id, nil
+}
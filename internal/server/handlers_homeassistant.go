@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"split-vpn-webui/internal/mqtt"
+)
+
+const homeAssistantSwitchBodyLimit = 1 << 10
+
+// homeAssistantVPN is one VPN shaped for Home Assistant's RESTful platforms:
+// booleans for binary sensors and the switch, plain numbers for sensors.
+type homeAssistantVPN struct {
+	Name      string   `json:"name"`
+	Connected bool     `json:"connected"`
+	Running   bool     `json:"running"`
+	UnitState string   `json:"unitState,omitempty"`
+	LatencyMS *float64 `json:"latencyMs"`
+	RxMbps    float64  `json:"rxMbps"`
+	TxMbps    float64  `json:"txMbps"`
+}
+
+// handleHomeAssistantVPNs lists every VPN, plus whether all of them are up.
+func (s *Server) handleHomeAssistantVPNs(w http.ResponseWriter, r *http.Request) {
+	if s.configManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "config manager unavailable"})
+		return
+	}
+	states := s.liveVPNStates()
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	vpns := make([]homeAssistantVPN, 0, len(states))
+	allUp := len(states) > 0
+	for _, state := range states {
+		vpns = append(vpns, s.homeAssistantVPN(state))
+		allUp = allUp && state.Connected
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{"allUp": allUp, "vpns": vpns})
+}
+
+// handleHomeAssistantVPN reports one VPN; it is also the state resource of
+// the RESTful switch posted to below.
+func (s *Server) handleHomeAssistantVPN(w http.ResponseWriter, r *http.Request) {
+	state, ok := s.homeAssistantState(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, s.homeAssistantVPN(state))
+}
+
+// handleHomeAssistantSwitch starts or stops a VPN. The body is the RESTful
+// switch's body_on/body_off ("ON"/"OFF" by default) or {"state": "ON"}.
+// A VPN already in the requested state is left alone.
+func (s *Server) handleHomeAssistantSwitch(w http.ResponseWriter, r *http.Request) {
+	if s.systemd == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "systemd manager unavailable"})
+		return
+	}
+	state, ok := s.homeAssistantState(w, r)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, homeAssistantSwitchBodyLimit))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		return
+	}
+	on, err := parseHomeAssistantSwitch(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	current := s.homeAssistantVPN(state)
+	if current.Running != on {
+		action := s.vpnStopAction()
+		if on {
+			action = s.vpnStartAction()
+		}
+		unitState, journal, err := s.runVPNControl(r.Context(), state.Name, action, "Home Assistant")
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"error":     err.Error(),
+				"unitState": unitState,
+				"journal":   journal,
+			})
+			return
+		}
+		for _, refreshed := range s.liveVPNStates() {
+			if refreshed.Name == state.Name {
+				state = refreshed
+				break
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, s.homeAssistantVPN(state))
+}
+
+func (s *Server) handleGetHomeAssistantToken(w http.ResponseWriter, r *http.Request) {
+	token, err := s.auth.GetHomeAssistantToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func (s *Server) handleRegenerateHomeAssistantToken(w http.ResponseWriter, r *http.Request) {
+	token, err := s.auth.RegenerateHomeAssistantToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func (s *Server) handleRevokeHomeAssistantToken(w http.ResponseWriter, r *http.Request) {
+	if err := s.auth.RevokeHomeAssistantToken(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// homeAssistantState looks up the live state of the VPN named in the URL.
+func (s *Server) homeAssistantState(w http.ResponseWriter, r *http.Request) (mqtt.VPNState, bool) {
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return mqtt.VPNState{}, false
+	}
+	if s.configManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "config manager unavailable"})
+		return mqtt.VPNState{}, false
+	}
+	for _, state := range s.liveVPNStates() {
+		if state.Name == name {
+			return state, true
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"error": "vpn " + name + " not found"})
+	return mqtt.VPNState{}, false
+}
+
+// homeAssistantVPN adds the unit state to a VPN's live state. Without
+// systemd the switch follows the link.
+func (s *Server) homeAssistantVPN(state mqtt.VPNState) homeAssistantVPN {
+	vpn := homeAssistantVPN{
+		Name:      state.Name,
+		Connected: state.Connected,
+		Running:   state.Connected,
+		LatencyMS: state.LatencyMS,
+		RxMbps:    math.Round(state.RxBps/1e4) / 100,
+		TxMbps:    math.Round(state.TxBps/1e4) / 100,
+	}
+	if s.systemd != nil {
+		unitState, _ := s.systemd.Status(vpnServiceUnitName(state.Name))
+		vpn.UnitState = unitState
+		vpn.Running = unitState == "active" || unitState == "activating"
+	}
+	return vpn
+}
+
+func parseHomeAssistantSwitch(body []byte) (bool, error) {
+	value := strings.TrimSpace(string(body))
+	if strings.HasPrefix(value, "{") {
+		var payload struct {
+			State any `json:"state"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return false, errors.New("invalid JSON body")
+		}
+		switch state := payload.State.(type) {
+		case bool:
+			return state, nil
+		case string:
+			value = state
+		default:
+			return false, errors.New(`state must be "ON" or "OFF"`)
+		}
+	}
+	switch strings.ToLower(strings.Trim(value, `"`)) {
+	case "on", "true", "1", "start":
+		return true, nil
+	case "off", "false", "0", "stop":
+		return false, nil
+	default:
+		return false, errors.New(`body must be "ON" or "OFF"`)
+	}
+}
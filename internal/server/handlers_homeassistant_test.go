@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/systemd"
+)
+
+func serveHomeAssistantSwitch(t *testing.T, s *Server, name, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/ha/vpns/"+name, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("name", name)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	s.handleHomeAssistantSwitch(rec, req)
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v (%s)", err, rec.Body.String())
+	}
+	return rec, payload
+}
+
+func TestHomeAssistantSwitchStartsStoppedVPN(t *testing.T) {
+	previous := vpnControlSettleStep
+	vpnControlSettleStep = time.Millisecond
+	t.Cleanup(func() { vpnControlSettleStep = previous })
+
+	unitState := "inactive"
+	var started string
+	mock := &systemd.MockManager{
+		StartFunc: func(unit string) error {
+			started = unit
+			unitState = "active"
+			return nil
+		},
+		StatusFunc: func(string) (string, error) { return unitState, nil },
+	}
+	s := newControlTestServer(t, mock)
+
+	rec, payload := serveHomeAssistantSwitch(t, s, "wg-fra", "ON")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if started != "svpn-wg-fra.service" {
+		t.Fatalf("unexpected unit started: %q", started)
+	}
+	if payload["name"] != "wg-fra" || payload["running"] != true || payload["unitState"] != "active" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+
+	// Switching on again leaves the running unit alone.
+	started = ""
+	rec, _ = serveHomeAssistantSwitch(t, s, "wg-fra", `{"state":"on"}`)
+	if rec.Code != http.StatusOK || started != "" {
+		t.Fatalf("expected no-op for running VPN, got %d and start of %q", rec.Code, started)
+	}
+}
+
+func TestHomeAssistantSwitchRejectsUnknownCommand(t *testing.T) {
+	s := newControlTestServer(t, &systemd.MockManager{})
+	rec, payload := serveHomeAssistantSwitch(t, s, "wg-fra", "toggle")
+	if rec.Code != http.StatusBadRequest || payload["error"] != `body must be "ON" or "OFF"` {
+		t.Fatalf("unexpected response %d: %#v", rec.Code, payload)
+	}
+	rec, _ = serveHomeAssistantSwitch(t, s, "wg-missing", "ON")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown vpn, got %d", rec.Code)
+	}
+}
+
+func TestParseHomeAssistantSwitch(t *testing.T) {
+	cases := map[string]bool{
+		"ON":                 true,
+		"off":                false,
+		`"ON"`:               true,
+		`{"state":"OFF"}`:    false,
+		`{"state":true}`:     true,
+		" true\n":            true,
+		`{"state": "start"}`: true,
+	}
+	for body, want := range cases {
+		got, err := parseHomeAssistantSwitch([]byte(body))
+		if err != nil || got != want {
+			t.Errorf("parseHomeAssistantSwitch(%q) = %v, %v", body, got, err)
+		}
+	}
+	if _, err := parseHomeAssistantSwitch([]byte(`{"state":1}`)); err == nil {
+		t.Error("expected numeric state to be rejected")
+	}
+}
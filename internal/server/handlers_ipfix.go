@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"split-vpn-webui/internal/ipfix"
+)
+
+// configureIPFIX keeps the exporter that sends matched flows to a collector.
+func (s *Server) configureIPFIX(exporter *ipfix.Exporter) {
+	s.ipfix = exporter
+}
+
+// exportIPFIX sends a VPN's sampled flows to the IPFIX collector. Flows
+// matched only by a monitor-only rule do not use the VPN and are left out.
+func (s *Server) exportIPFIX(ctx context.Context, vpnName string, samples []flowInspectorSample) {
+	if s.ipfix == nil {
+		return
+	}
+	flows := make([]ipfix.Flow, 0, len(samples))
+	for _, sample := range samples {
+		if sample.MonitorOnly {
+			continue
+		}
+		flows = append(flows, ipfix.Flow{
+			Key:             sample.Key,
+			Group:           sample.GroupName,
+			Protocol:        sample.Protocol,
+			SourceIP:        sample.SourceIP,
+			SourcePort:      sample.SourcePort,
+			DestinationIP:   sample.DestinationIP,
+			DestinationPort: sample.DestinationPort,
+			UploadBytes:     sample.UploadBytes,
+			DownloadBytes:   sample.DownloadBytes,
+			UploadPackets:   sample.UploadPackets,
+			DownloadPackets: sample.DownloadPackets,
+		})
+	}
+	if err := s.ipfix.Export(ctx, vpnName, flows); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("ipfix export failed vpn=%s err=%v", vpnName, err)
+	}
+}
+
+func (s *Server) handleIPFIXStatus(w http.ResponseWriter, r *http.Request) {
+	if s.ipfix == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "ipfix export unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.ipfix.Status())
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/prewarm"
+	"split-vpn-webui/internal/routing"
+)
+
+const defaultJobListLimit = 50
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	if s.jobs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "job queue unavailable"})
+		return
+	}
+	var kind jobs.Kind
+	if raw := strings.TrimSpace(r.URL.Query().Get("kind")); raw != "" {
+		parsed, err := jobs.ParseKind(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		kind = parsed
+	}
+	limit := defaultJobListLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+	response := map[string]any{"jobs": s.jobs.List(kind, limit)}
+	// Job history is in memory; the schedulers' persisted last-run summary
+	// is included so clients see it across restarts.
+	if lastRun, ok := s.schedulerLastRun(r.Context(), kind); ok {
+		response["lastRun"] = lastRun
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// schedulerLastRun returns the persisted last run of the resolver or
+// pre-warm scheduler for kind.
+func (s *Server) schedulerLastRun(ctx context.Context, kind jobs.Kind) (any, bool) {
+	switch kind {
+	case jobs.KindResolver:
+		if s.resolver == nil {
+			return nil, false
+		}
+		status, err := s.resolver.Status(ctx)
+		if err != nil || status.LastRun == nil {
+			return nil, false
+		}
+		return status.LastRun, true
+	case jobs.KindPrewarm:
+		if s.prewarm == nil {
+			return nil, false
+		}
+		status, err := s.prewarm.Status(ctx)
+		if err != nil || status.LastRun == nil {
+			return nil, false
+		}
+		return status.LastRun, true
+	}
+	return nil, false
+}
+
+// handleCreateJob starts a resolver run, pre-warm run or routing apply.
+// Backup jobs are created by the backup endpoints, which carry the payload.
+func (s *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	if s.jobs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "job queue unavailable"})
+		return
+	}
+	var payload struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	kind, err := jobs.ParseKind(payload.Kind)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch kind {
+	case jobs.KindResolver:
+		if s.resolver == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "resolver scheduler unavailable"})
+			return
+		}
+		err = triggerTracked(s.resolverJobs, "api", s.resolver.TriggerNow)
+	case jobs.KindPrewarm:
+		if s.prewarm == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "prewarm scheduler unavailable"})
+			return
+		}
+		err = triggerTracked(s.prewarmJobs, "api", s.prewarm.TriggerNow)
+	case jobs.KindApply:
+		if s.routingManager == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+			return
+		}
+		var job jobs.Job
+		job, err = s.jobs.Submit(jobs.KindApply, "api", func(ctx context.Context, handle *jobs.Handle) error {
+			// An apply can be canceled while queued, but once it starts
+			// swapping ipsets and chains it runs to completion: stopping
+			// halfway would leave the dataplane partly applied.
+			if !handle.DisableCancel() {
+				return ctx.Err()
+			}
+			handle.Logf("info", "applying routing state")
+			return s.routingManager.Apply(context.WithoutCancel(ctx))
+		})
+		if err == nil {
+			writeJSON(w, http.StatusAccepted, map[string]any{"job": job})
+			return
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "jobs of kind " + string(kind) + " are started by their own endpoint"})
+		return
+	}
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	job, ok := s.jobs.Active(kind)
+	if !ok {
+		// The run finished before it could be looked up; report the latest.
+		if recent := s.jobs.List(kind, 1); len(recent) > 0 {
+			job = recent[0]
+		}
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"job": job})
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.requireJobID(w, r)
+	if !ok {
+		return
+	}
+	job, err := s.jobs.Get(id)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"job": job})
+}
+
+func (s *Server) handleJobLogs(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.requireJobID(w, r)
+	if !ok {
+		return
+	}
+	logs, err := s.jobs.Logs(id)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"logs": logs})
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.requireJobID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.jobs.Cancel(id); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "canceling"})
+}
+
+func (s *Server) requireJobID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if s.jobs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "job queue unavailable"})
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(chi.URLParam(r, "id")), 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid job id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, jobs.ErrJobFinished),
+		errors.Is(err, jobs.ErrJobNotCancelable),
+		errors.Is(err, jobs.ErrQueueFull),
+		errors.Is(err, routing.ErrResolverRunInProgress),
+		errors.Is(err, routing.ErrResolverRunNotActive),
+		errors.Is(err, prewarm.ErrRunInProgress),
+		errors.Is(err, prewarm.ErrRunNotActive):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, jobs.ErrQueueStopped):
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
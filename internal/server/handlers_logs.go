@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"split-vpn-webui/internal/diaglog"
+)
+
+const defaultLogListLimit = 500
+
+// logFilterFromRequest reads level, module, since and limit query params.
+// An empty level shows everything the logger buffered.
+func logFilterFromRequest(r *http.Request) (diaglog.Filter, error) {
+	query := r.URL.Query()
+	filter := diaglog.Filter{
+		MinLevel: diaglog.LevelDebug,
+		Module:   strings.TrimSpace(query.Get("module")),
+		Limit:    defaultLogListLimit,
+	}
+	if raw := strings.TrimSpace(query.Get("level")); raw != "" {
+		filter.MinLevel = diaglog.ParseLevel(raw)
+	}
+	if raw := strings.TrimSpace(query.Get("since")); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("since must be a log entry id")
+		}
+		filter.SinceID = parsed
+	}
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return filter, fmt.Errorf("limit must be a positive integer")
+		}
+		filter.Limit = parsed
+	}
+	return filter, nil
+}
+
+func (s *Server) handleListLogs(w http.ResponseWriter, r *http.Request) {
+	if s.diagLog == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "application log unavailable"})
+		return
+	}
+	filter, err := logFilterFromRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"entries": s.diagLog.Entries(filter),
+		"modules": s.diagLog.Modules(),
+	})
+}
+
+// handleStreamLogs tails the application log over SSE. Each event carries
+// the entry ID so a reconnecting EventSource resumes via Last-Event-ID
+// without gaps, as long as the entries are still buffered.
+func (s *Server) handleStreamLogs(w http.ResponseWriter, r *http.Request) {
+	if s.diagLog == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "application log unavailable"})
+		return
+	}
+	filter, err := logFilterFromRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if raw := strings.TrimSpace(r.Header.Get("Last-Event-ID")); raw != "" {
+		if parsed, err := strconv.ParseUint(raw, 10, 64); err == nil {
+			filter.SinceID = parsed
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable nginx buffering
+
+	// Subscribe before reading the backlog so nothing logged in between is
+	// lost; entries already sent are skipped by ID.
+	updates, cancel := s.diagLog.Subscribe()
+	defer cancel()
+
+	fmt.Fprintf(w, "retry: 5000\n\n")
+	lastID := filter.SinceID
+	for _, entry := range s.diagLog.Entries(filter) {
+		writeLogEvent(w, entry)
+		lastID = entry.ID
+	}
+	flusher.Flush()
+
+	live := diaglog.Filter{MinLevel: filter.MinLevel, Module: filter.Module}
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-updates:
+			if !ok {
+				return
+			}
+			if entry.ID <= lastID || !logEntryMatches(entry, live) {
+				continue
+			}
+			lastID = entry.ID
+			writeLogEvent(w, entry)
+			flusher.Flush()
+		}
+	}
+}
+
+func logEntryMatches(entry diaglog.Entry, filter diaglog.Filter) bool {
+	if diaglog.ParseLevel(entry.Level) < filter.MinLevel {
+		return false
+	}
+	return filter.Module == "" || strings.EqualFold(entry.Module, filter.Module)
+}
+
+func writeLogEvent(w http.ResponseWriter, entry diaglog.Entry) {
+	data, _ := json.Marshal(entry)
+	fmt.Fprintf(w, "id: %d\ndata: %s\n\n", entry.ID, data)
+}
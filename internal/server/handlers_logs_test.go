@@ -0,0 +1,43 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"split-vpn-webui/internal/diaglog"
+)
+
+func TestHandleListLogsFiltersByLevelAndModule(t *testing.T) {
+	logger := diaglog.New("")
+	logger.Infof("prewarm run started timeout=10s")
+	logger.Warnf("prewarm resolver disabled label=cloudflare failures=3")
+	logger.Warnf("routing apply failed group=Streaming")
+	s := &Server{diagLog: logger}
+
+	rec := httptest.NewRecorder()
+	s.handleListLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs?level=warn&module=prewarm", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Entries []diaglog.Entry `json:"entries"`
+		Modules []string        `json:"modules"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Entries) != 1 || body.Entries[0].Fields["label"] != "cloudflare" {
+		t.Fatalf("unexpected entries %#v", body.Entries)
+	}
+	if len(body.Modules) != 2 || body.Modules[0] != "prewarm" || body.Modules[1] != "routing" {
+		t.Fatalf("unexpected modules %v", body.Modules)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleListLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for zero limit, got %d", rec.Code)
+	}
+}
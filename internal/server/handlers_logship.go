@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+
+	"split-vpn-webui/internal/diaglog"
+	"split-vpn-webui/internal/logship"
+)
+
+// configureLogShipping keeps the shipper that forwards logs off-box.
+func (s *Server) configureLogShipping(shipper *logship.Shipper) {
+	s.logShip = shipper
+}
+
+// logShipUnits lists the unit of every VPN profile, for journal shipping.
+func (s *Server) logShipUnits() []logship.Unit {
+	if s.configManager == nil {
+		return nil
+	}
+	configs, err := s.configManager.List()
+	if err != nil {
+		return nil
+	}
+	units := make([]logship.Unit, 0, len(configs))
+	for _, cfg := range configs {
+		units = append(units, logship.Unit{VPN: cfg.Name, Unit: vpnServiceUnitName(cfg.Name)})
+	}
+	return units
+}
+
+// forwardDiagLogs queues application log entries for shipping until
+// entries is closed. The vpn and group labels come from the entry's
+// key=value fields.
+func (s *Server) forwardDiagLogs(entries <-chan diaglog.Entry) {
+	for entry := range entries {
+		s.logShip.Enqueue(logship.Record{
+			Time:    entry.Time,
+			Level:   entry.Level,
+			Source:  logship.SourceApp,
+			Module:  entry.Module,
+			VPN:     entry.Fields["vpn"],
+			Group:   entry.Fields["group"],
+			Message: entry.Message,
+		})
+	}
+}
+
+func (s *Server) handleLogShipStatus(w http.ResponseWriter, r *http.Request) {
+	if s.logShip == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "log shipping unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.logShip.Status())
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"split-vpn-webui/internal/flowhistory"
+	"split-vpn-webui/internal/metricsexport"
+)
+
+// maxExportedDevices bounds the per-device series in each push, keeping
+// the busiest clients, so a flood of short-lived sources cannot blow up
+// the store's cardinality.
+const maxExportedDevices = 200
+
+// configureMetricsExport keeps the exporter that pushes metrics off-box.
+func (s *Server) configureMetricsExport(exporter *metricsexport.Exporter) {
+	s.metricsExport = exporter
+}
+
+// metricsExportPoints gathers interface throughput and byte counters, VPN
+// link state and latency, and each client's traffic over the last hour.
+func (s *Server) metricsExportPoints(ctx context.Context) []metricsexport.Point {
+	host, _ := os.Hostname()
+	var points []metricsexport.Point
+	if s.stats != nil {
+		for _, iface := range s.stats.Snapshot().Interfaces {
+			if iface == nil || !iface.Available {
+				continue
+			}
+			points = append(points, metricsexport.Point{
+				Measurement: "svpn_interface",
+				Tags: map[string]string{
+					"host":      host,
+					"interface": iface.Name,
+					"type":      string(iface.Type),
+					"vpn_type":  iface.VPNType,
+				},
+				Fields: []metricsexport.Field{
+					{Name: "rx_bps", Value: iface.CurrentRxThroughput},
+					{Name: "tx_bps", Value: iface.CurrentTxThroughput},
+					{Name: "rx_bytes", Value: float64(iface.RxBytes), Integer: true},
+					{Name: "tx_bytes", Value: float64(iface.TxBytes), Integer: true},
+				},
+			})
+		}
+	}
+	for _, state := range s.liveVPNStates() {
+		up := 0.0
+		if state.Connected {
+			up = 1
+		}
+		fields := []metricsexport.Field{{Name: "up", Value: up, Integer: true}}
+		if state.LatencyMS != nil {
+			fields = append(fields, metricsexport.Field{Name: "latency_ms", Value: *state.LatencyMS})
+		}
+		points = append(points, metricsexport.Point{
+			Measurement: "svpn_vpn",
+			Tags:        map[string]string{"host": host, "vpn": state.Name},
+			Fields:      fields,
+		})
+	}
+	if s.flowHistory != nil {
+		if devices, err := s.flowHistory.Devices(ctx, flowhistory.DefaultWindow); err == nil {
+			if len(devices) > maxExportedDevices {
+				devices = devices[:maxExportedDevices]
+			}
+			for _, device := range devices {
+				points = append(points, metricsexport.Point{
+					Measurement: "svpn_device",
+					Tags:        map[string]string{"host": host, "vpn": device.VPN, "device": device.Device},
+					Fields: []metricsexport.Field{
+						{Name: "upload_bytes_1h", Value: float64(device.UploadBytes), Integer: true},
+						{Name: "download_bytes_1h", Value: float64(device.DownloadBytes), Integer: true},
+					},
+				})
+			}
+		}
+	}
+	return points
+}
+
+func (s *Server) handleMetricsExportStatus(w http.ResponseWriter, r *http.Request) {
+	if s.metricsExport == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "metrics exporter unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.metricsExport.Status())
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+
+	"split-vpn-webui/internal/anomaly"
+	"split-vpn-webui/internal/endpointrefresh"
+	"split-vpn-webui/internal/latency"
+	"split-vpn-webui/internal/mqtt"
+	"split-vpn-webui/internal/quota"
+	"split-vpn-webui/internal/stats"
+	"split-vpn-webui/internal/util"
+	"split-vpn-webui/internal/vpnevents"
+)
+
+// mqttEventTypes are the timeline event types announced to Home Assistant.
+var mqttEventTypes = []string{
+	vpnevents.TypeUp,
+	vpnevents.TypeDown,
+	vpnevents.TypeHandshakeStale,
+	vpnevents.TypeHandshakeRestored,
+	vpnevents.TypeStart,
+	vpnevents.TypeStop,
+	vpnevents.TypeRestart,
+	vpnevents.TypeWatchdog,
+	anomaly.KindHigh,
+	anomaly.KindHighCleared,
+	anomaly.KindStall,
+	anomaly.KindStallCleared,
+	quota.KindWarning,
+	quota.KindExceeded,
+	quota.KindReset,
+	endpointrefresh.KindChanged,
+	endpointrefresh.KindFailed,
+}
+
+// configureMQTT keeps the publisher that mirrors VPN state to a broker.
+func (s *Server) configureMQTT(publisher *mqtt.Publisher) {
+	s.mqtt = publisher
+}
+
+// publishMQTTEvent forwards a timeline event to the broker, if one is set.
+func (s *Server) publishMQTTEvent(event vpnevents.Event) {
+	if s.mqtt == nil {
+		return
+	}
+	s.mqtt.PublishEvent(mqtt.Event{VPN: event.VPN, Type: event.Type, Detail: event.Detail, At: event.At})
+}
+
+// liveVPNStates reports link state, latency and throughput of every VPN, for
+// MQTT and the Home Assistant endpoints.
+func (s *Server) liveVPNStates() []mqtt.VPNState {
+	if s.configManager == nil {
+		return nil
+	}
+	configs, err := s.configManager.List()
+	if err != nil {
+		return nil
+	}
+	latencies := make(map[string]latency.Result)
+	if s.latency != nil {
+		for _, result := range s.latency.Results() {
+			latencies[result.Name] = result
+		}
+	}
+	throughput := make(map[string]*stats.InterfaceStats)
+	if s.stats != nil {
+		for _, iface := range s.stats.Snapshot().Interfaces {
+			if iface != nil && iface.Type == stats.InterfaceVPN {
+				throughput[iface.Name] = iface
+			}
+		}
+	}
+	states := make([]mqtt.VPNState, 0, len(configs))
+	for _, cfg := range configs {
+		connected, _, _ := util.InterfaceOperState(cfg.InterfaceName)
+		state := mqtt.VPNState{Name: cfg.Name, Connected: connected}
+		if result, ok := latencies[cfg.Name]; ok && result.Success {
+			latencyMS := result.LatencyMS
+			state.LatencyMS = &latencyMS
+		}
+		if iface, ok := throughput[cfg.Name]; ok && iface.Available {
+			state.RxBps = iface.CurrentRxThroughput
+			state.TxBps = iface.CurrentTxThroughput
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+func (s *Server) handleMQTTStatus(w http.ResponseWriter, r *http.Request) {
+	if s.mqtt == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "mqtt publisher unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.mqtt.Status())
+}
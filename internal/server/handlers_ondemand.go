@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"split-vpn-webui/internal/ondemand"
+	"split-vpn-webui/internal/vpnevents"
+)
+
+// configureOnDemand records on-demand starts and stops on the VPN timeline
+// and streams them over SSE.
+func (s *Server) configureOnDemand(monitor *ondemand.Monitor) {
+	s.onDemand = monitor
+	monitor.SetHandler(func(event ondemand.Event) {
+		eventType := vpnevents.TypeStart
+		if event.Kind == ondemand.KindStop || event.Kind == ondemand.KindStopFailed {
+			eventType = vpnevents.TypeStop
+		}
+		if s.diagLog != nil {
+			switch event.Kind {
+			case ondemand.KindStartFailed, ondemand.KindStopFailed:
+				s.diagLog.Warnf("vpn on-demand vpn=%s kind=%s detail=%q", event.VPN, event.Kind, event.Detail)
+			default:
+				s.diagLog.Infof("vpn on-demand vpn=%s kind=%s detail=%q", event.VPN, event.Kind, event.Detail)
+			}
+		}
+		s.recordVPNEvent(context.Background(), event.VPN, eventType, event.Detail)
+		s.broadcastEvent("on-demand", event)
+		s.refreshAfterControl()
+	})
+}
+
+// onDemandTargets lists the VPNs with an idle timeout and the fwmark their
+// routing groups are tagged with.
+func (s *Server) onDemandTargets() ([]ondemand.Target, error) {
+	profiles, err := s.vpnManager.List()
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]ondemand.Target, 0)
+	for _, profile := range profiles {
+		if profile == nil || profile.OnDemandIdleMinutes <= 0 || profile.FWMark == 0 {
+			continue
+		}
+		targets = append(targets, ondemand.Target{
+			VPN:     profile.Name,
+			Mark:    profile.FWMark,
+			IdleFor: time.Duration(profile.OnDemandIdleMinutes) * time.Minute,
+		})
+	}
+	return targets, nil
+}
+
+// onDemandUnits drives VPN service units for the on-demand monitor.
+type onDemandUnits struct {
+	server *Server
+}
+
+func (u onDemandUnits) Active(vpn string) (bool, error) {
+	state, err := u.server.systemd.Status(vpnServiceUnitName(vpn))
+	switch state {
+	case "":
+		if err == nil {
+			err = fmt.Errorf("unit state unknown")
+		}
+		return false, err
+	case "inactive", "failed":
+		return false, nil
+	}
+	// Units that are still starting or stopping count as up so that they
+	// are not started twice.
+	return true, nil
+}
+
+func (u onDemandUnits) Start(vpn string) error {
+	return u.server.systemd.Start(vpnServiceUnitName(vpn))
+}
+
+func (u onDemandUnits) Stop(vpn string) error {
+	return u.server.systemd.Stop(vpnServiceUnitName(vpn))
+}
+
+func (s *Server) handleOnDemandStatus(w http.ResponseWriter, r *http.Request) {
+	if s.onDemand == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "on-demand monitor unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"vpns": s.onDemand.Status()})
+}
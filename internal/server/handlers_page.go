@@ -1,10 +1,18 @@
 package server
 
-import "net/http"
+import (
+	"net/http"
+
+	"split-vpn-webui/internal/auth"
+)
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	// The UI echoes the session's CSRF token on every mutating request.
+	session, _ := auth.CurrentSession(r)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, "layout.html", nil); err != nil {
+	if err := s.templates.ExecuteTemplate(w, "layout.html", map[string]any{
+		"CSRFToken": session.CSRFToken,
+	}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -3,6 +3,10 @@ package server
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
 
 	"split-vpn-webui/internal/prewarm"
 )
@@ -12,7 +16,7 @@ func (s *Server) handlePrewarmRun(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "prewarm scheduler unavailable"})
 		return
 	}
-	if err := s.prewarm.TriggerNow(); err != nil {
+	if err := triggerTracked(s.prewarmJobs, "api", s.prewarm.TriggerNow); err != nil {
 		switch {
 		case errors.Is(err, prewarm.ErrRunInProgress):
 			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
@@ -24,25 +28,12 @@ func (s *Server) handlePrewarmRun(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
 }
 
-func (s *Server) handlePrewarmStatus(w http.ResponseWriter, r *http.Request) {
-	if s.prewarm == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "prewarm scheduler unavailable"})
-		return
-	}
-	status, err := s.prewarm.Status(r.Context())
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-	writeJSON(w, http.StatusOK, status)
-}
-
 func (s *Server) handlePrewarmClearRun(w http.ResponseWriter, r *http.Request) {
 	if s.prewarm == nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "prewarm scheduler unavailable"})
 		return
 	}
-	if err := s.prewarm.ClearCacheAndRun(); err != nil {
+	if err := triggerTracked(s.prewarmJobs, "api: clear cache", s.prewarm.ClearCacheAndRun); err != nil {
 		switch {
 		case errors.Is(err, prewarm.ErrRunInProgress):
 			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
@@ -70,3 +61,70 @@ func (s *Server) handlePrewarmStop(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "stopping"})
 }
+
+func (s *Server) handlePrewarmRunDiff(w http.ResponseWriter, r *http.Request) {
+	if s.prewarm == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "prewarm scheduler unavailable"})
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(chi.URLParam(r, "id")), 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid run id"})
+		return
+	}
+	diff, err := s.prewarm.RunDiff(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, prewarm.ErrRunNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, diff)
+}
+
+func (s *Server) handlePrewarmRuns(w http.ResponseWriter, r *http.Request) {
+	if s.prewarm == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "prewarm scheduler unavailable"})
+		return
+	}
+	limit, ok := parseNonNegativeQuery(w, r, "limit", prewarm.DefaultRunsPageSize)
+	if !ok {
+		return
+	}
+	offset, ok := parseNonNegativeQuery(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	page, err := s.prewarm.Runs(r.Context(), limit, offset)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handlePrewarmRunDetail(w http.ResponseWriter, r *http.Request) {
+	if s.prewarm == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "prewarm scheduler unavailable"})
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(chi.URLParam(r, "id")), 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid run id"})
+		return
+	}
+	detail, err := s.prewarm.Run(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, prewarm.ErrRunNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
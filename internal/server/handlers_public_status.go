@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"split-vpn-webui/internal/latency"
+	"split-vpn-webui/internal/util"
+)
+
+// PublicStatus is the privacy-filtered payload served without authentication.
+// It intentionally carries no config paths, gateways, interface names, or any
+// information about LAN devices.
+type PublicStatus struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	AllUp       bool              `json:"allUp"`
+	VPNs        []PublicVPNStatus `json:"vpns"`
+}
+
+// PublicVPNStatus reports whether a single VPN is up and how it is responding.
+type PublicVPNStatus struct {
+	Name           string     `json:"name"`
+	Up             bool       `json:"up"`
+	LatencyMS      *float64   `json:"latencyMs,omitempty"`
+	LatencyHealthy bool       `json:"latencyHealthy"`
+	CheckedAt      *time.Time `json:"checkedAt,omitempty"`
+}
+
+func (s *Server) publicStatusEnabled() bool {
+	if s.settings == nil {
+		return false
+	}
+	current, err := s.settings.Get()
+	if err != nil || current.PublicStatusEnabled == nil {
+		return false
+	}
+	return *current.PublicStatusEnabled
+}
+
+func (s *Server) handlePublicStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.publicStatusEnabled() {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "public status page is disabled"})
+		return
+	}
+	if s.configManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "config manager unavailable"})
+		return
+	}
+	configs, err := s.configManager.List()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "status unavailable"})
+		return
+	}
+	up := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		connected, _, _ := util.InterfaceOperState(cfg.InterfaceName)
+		up[cfg.Name] = connected
+	}
+	var results []latency.Result
+	if s.latency != nil {
+		results = s.latency.Results()
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, buildPublicStatus(up, results, time.Now().UTC()))
+}
+
+// buildPublicStatus merges link state with the latest latency samples. Latency
+// results for names not present in up are dropped.
+func buildPublicStatus(up map[string]bool, results []latency.Result, now time.Time) PublicStatus {
+	byName := make(map[string]latency.Result, len(results))
+	for _, result := range results {
+		byName[result.Name] = result
+	}
+	status := PublicStatus{
+		GeneratedAt: now,
+		AllUp:       len(up) > 0,
+		VPNs:        make([]PublicVPNStatus, 0, len(up)),
+	}
+	for name, connected := range up {
+		entry := PublicVPNStatus{Name: name, Up: connected}
+		if result, ok := byName[name]; ok && !result.CheckedAt.IsZero() {
+			checkedAt := result.CheckedAt.UTC()
+			entry.CheckedAt = &checkedAt
+			entry.LatencyHealthy = result.Success
+			if result.Success {
+				latencyMS := result.LatencyMS
+				entry.LatencyMS = &latencyMS
+			}
+		}
+		if !connected {
+			status.AllUp = false
+		}
+		status.VPNs = append(status.VPNs, entry)
+	}
+	sort.Slice(status.VPNs, func(i, j int) bool { return status.VPNs[i].Name < status.VPNs[j].Name })
+	return status
+}
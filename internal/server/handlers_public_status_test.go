@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/config"
+	"split-vpn-webui/internal/latency"
+	"split-vpn-webui/internal/settings"
+)
+
+func TestBuildPublicStatusFiltersAndMergesLatency(t *testing.T) {
+	checked := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := checked.Add(time.Minute)
+	status := buildPublicStatus(
+		map[string]bool{"wg-sgp": true, "ovpn-us": false},
+		[]latency.Result{
+			{Name: "wg-sgp", Target: "10.64.0.1", LatencyMS: 42.5, Success: true, CheckedAt: checked},
+			{Name: "ovpn-us", Target: "10.8.0.1", Success: false, CheckedAt: checked, Error: "timeout"},
+			{Name: "removed", Target: "10.9.0.1", Success: true, CheckedAt: checked},
+		},
+		now,
+	)
+	if status.AllUp {
+		t.Fatalf("expected allUp=false when one VPN is down")
+	}
+	if len(status.VPNs) != 2 {
+		t.Fatalf("expected 2 VPNs, got %#v", status.VPNs)
+	}
+	down, up := status.VPNs[0], status.VPNs[1]
+	if down.Name != "ovpn-us" || down.Up || down.LatencyHealthy || down.LatencyMS != nil {
+		t.Fatalf("unexpected down entry: %#v", down)
+	}
+	if up.Name != "wg-sgp" || !up.Up || !up.LatencyHealthy || up.LatencyMS == nil || *up.LatencyMS != 42.5 {
+		t.Fatalf("unexpected up entry: %#v", up)
+	}
+
+	encoded, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, leaked := range []string{"10.64.0.1", "10.8.0.1", "timeout", "target"} {
+		if strings.Contains(string(encoded), leaked) {
+			t.Fatalf("public payload leaked %q: %s", leaked, encoded)
+		}
+	}
+}
+
+func TestHandlePublicStatusDisabledByDefault(t *testing.T) {
+	s := &Server{
+		configManager: config.NewManager(t.TempDir()),
+		settings:      settings.NewManager(filepath.Join(t.TempDir(), "settings.json")),
+	}
+	router := chi.NewRouter()
+	router.Get("/api/public/status", s.handlePublicStatus)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/public/status", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when disabled, got %d", recorder.Code)
+	}
+}
+
+func TestHandlePublicStatusEnabledBypassesProtectedAPI(t *testing.T) {
+	settingsManager := settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	enabled := true
+	if err := settingsManager.Save(settings.Settings{PublicStatusEnabled: &enabled}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	s := &Server{
+		configManager: config.NewManager(t.TempDir()),
+		settings:      settingsManager,
+	}
+	router := chi.NewRouter()
+	router.Get("/api/public/status", s.handlePublicStatus)
+	router.Group(func(protected chi.Router) {
+		protected.Use(func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+		})
+		protected.Route("/api", func(api chi.Router) {
+			api.Get("/status", func(http.ResponseWriter, *http.Request) {})
+		})
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/public/status", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var payload PublicStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload.VPNs == nil || len(payload.VPNs) != 0 {
+		t.Fatalf("expected empty VPN list, got %#v", payload.VPNs)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected protected API to stay guarded, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/quota"
+	"split-vpn-webui/internal/stats"
+	"split-vpn-webui/internal/vpnevents"
+)
+
+// configureQuotaMonitor records quota events on the VPN timeline, streams
+// them over SSE and runs the configured action once a quota is exhausted.
+func (s *Server) configureQuotaMonitor(monitor *quota.Monitor) {
+	s.quotas = monitor
+	monitor.SetHandler(func(event quota.Event) {
+		if s.diagLog != nil {
+			s.diagLog.Warnf("vpn quota vpn=%s kind=%s detail=%q", event.VPN, event.Kind, event.Detail)
+		}
+		ctx := context.Background()
+		s.recordVPNEvent(ctx, event.VPN, event.Kind, event.Detail)
+		if event.Kind == quota.KindExceeded {
+			if err := s.runQuotaAction(ctx, event); err != nil {
+				if s.diagLog != nil {
+					s.diagLog.Warnf("vpn quota action vpn=%s action=%s failed: %v", event.VPN, event.Action, err)
+				}
+				s.recordVPNEvent(ctx, event.VPN, event.Kind, fmt.Sprintf("%s action failed: %v", event.Action, err))
+			}
+		}
+		s.broadcastEvent("quota", event)
+	})
+}
+
+// runQuotaAction stops the VPN or moves its routing groups to the failover
+// VPN. Neither is undone when the next period starts.
+func (s *Server) runQuotaAction(ctx context.Context, event quota.Event) error {
+	switch event.Action {
+	case quota.ActionStop:
+		if s.systemd == nil {
+			return fmt.Errorf("systemd manager unavailable")
+		}
+		if err := s.systemd.Stop(vpnServiceUnitName(event.VPN)); err != nil {
+			return err
+		}
+		s.recordVPNEvent(ctx, event.VPN, vpnevents.TypeStop, "stopped: monthly quota used")
+		s.refreshAfterControl()
+		return nil
+	case quota.ActionFailover:
+		if s.routingManager == nil {
+			return fmt.Errorf("routing manager unavailable")
+		}
+		if _, err := s.vpnManager.Get(event.FailoverVPN); err != nil {
+			return fmt.Errorf("failover vpn %s: %w", event.FailoverVPN, err)
+		}
+		groups, err := s.routingManager.ListGroups(ctx)
+		if err != nil {
+			return err
+		}
+		moved := make([]string, 0)
+		job := s.trackJob(jobs.KindApply, "quota failover")
+		var moveErr error
+		for _, group := range groups {
+			if group.EgressVPN != event.VPN {
+				continue
+			}
+			group.EgressVPN = event.FailoverVPN
+			if _, err := s.routingManager.UpdateGroup(ctx, group.ID, group); err != nil {
+				moveErr = fmt.Errorf("move group %s: %w", group.Name, err)
+				break
+			}
+			moved = append(moved, group.Name)
+		}
+		job.Finish(moveErr)
+		if len(moved) > 0 {
+			s.recordVPNEvent(ctx, event.VPN, event.Kind, fmt.Sprintf("moved groups %s to %s", strings.Join(moved, ", "), event.FailoverVPN))
+			s.broadcastUpdate(nil)
+		}
+		return moveErr
+	}
+	return nil
+}
+
+// quotaSamples reports the interface counters of every tracked VPN.
+func (s *Server) quotaSamples() []quota.Sample {
+	snapshot := s.stats.Snapshot()
+	samples := make([]quota.Sample, 0, len(snapshot.Interfaces))
+	for _, iface := range snapshot.Interfaces {
+		if iface.Type != stats.InterfaceVPN {
+			continue
+		}
+		samples = append(samples, quota.Sample{
+			VPN:       iface.Name,
+			Available: iface.Available,
+			RxBytes:   iface.RxBytes,
+			TxBytes:   iface.TxBytes,
+		})
+	}
+	return samples
+}
+
+func (s *Server) handleListQuotas(w http.ResponseWriter, r *http.Request) {
+	if s.quotas == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "quota monitor unavailable"})
+		return
+	}
+	statuses, err := s.quotas.Status(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"quotas": statuses})
+}
+
+func (s *Server) handleVPNUsage(w http.ResponseWriter, r *http.Request) {
+	if s.quotas == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "quota monitor unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	history, err := s.quotas.Store().History(r.Context(), name)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"vpn": name, "periods": history})
+}
+
+func (s *Server) handleSetVPNQuota(w http.ResponseWriter, r *http.Request) {
+	if s.quotas == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "quota monitor unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	var payload quota.Quota
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	payload.VPN = name
+	if _, err := s.vpnManager.Get(name); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(payload.FailoverVPN) != "" {
+		if _, err := s.vpnManager.Get(strings.TrimSpace(payload.FailoverVPN)); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("failover vpn: %v", err)})
+			return
+		}
+	}
+	saved, err := s.quotas.Store().Set(r.Context(), payload)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"quota": saved})
+}
+
+func (s *Server) handleDeleteVPNQuota(w http.ResponseWriter, r *http.Request) {
+	if s.quotas == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "quota monitor unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	if err := s.quotas.Store().Delete(r.Context(), name); err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (s *Server) handleResetVPNQuota(w http.ResponseWriter, r *http.Request) {
+	if s.quotas == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "quota monitor unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	if err := s.quotas.ResetUsage(r.Context(), name); err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+	s.recordVPNEvent(r.Context(), name, quota.KindReset, "usage of the current period reset from the web UI")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+}
+
+func writeQuotaError(w http.ResponseWriter, err error) {
+	if errors.Is(err, quota.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
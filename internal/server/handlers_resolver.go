@@ -3,6 +3,10 @@ package server
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
 
 	"split-vpn-webui/internal/routing"
 )
@@ -12,7 +16,7 @@ func (s *Server) handleResolverRun(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "resolver scheduler unavailable"})
 		return
 	}
-	if err := s.resolver.TriggerNow(); err != nil {
+	if err := triggerTracked(s.resolverJobs, "api", s.resolver.TriggerNow); err != nil {
 		switch {
 		case errors.Is(err, routing.ErrResolverRunInProgress):
 			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
@@ -24,27 +28,31 @@ func (s *Server) handleResolverRun(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
 }
 
-func (s *Server) handleResolverStatus(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleResolverClearRun(w http.ResponseWriter, r *http.Request) {
 	if s.resolver == nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "resolver scheduler unavailable"})
 		return
 	}
-	status, err := s.resolver.Status(r.Context())
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := triggerTracked(s.resolverJobs, "api: clear cache", s.resolver.ClearCacheAndRun); err != nil {
+		switch {
+		case errors.Is(err, routing.ErrResolverRunInProgress):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
 		return
 	}
-	writeJSON(w, http.StatusOK, status)
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
 }
 
-func (s *Server) handleResolverClearRun(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleResolverResume(w http.ResponseWriter, r *http.Request) {
 	if s.resolver == nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "resolver scheduler unavailable"})
 		return
 	}
-	if err := s.resolver.ClearCacheAndRun(); err != nil {
+	if err := triggerTracked(s.resolverJobs, "api: resume", s.resolver.ResumeRun); err != nil {
 		switch {
-		case errors.Is(err, routing.ErrResolverRunInProgress):
+		case errors.Is(err, routing.ErrResolverRunInProgress), errors.Is(err, routing.ErrResolverNothingToResume):
 			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
 		default:
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
@@ -53,3 +61,47 @@ func (s *Server) handleResolverClearRun(w http.ResponseWriter, r *http.Request)
 	}
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
 }
+
+func (s *Server) handleResolverRuns(w http.ResponseWriter, r *http.Request) {
+	if s.resolver == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "resolver scheduler unavailable"})
+		return
+	}
+	limit, ok := parseNonNegativeQuery(w, r, "limit", routing.DefaultResolverRunsPageSize)
+	if !ok {
+		return
+	}
+	offset, ok := parseNonNegativeQuery(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	page, err := s.resolver.Runs(r.Context(), limit, offset)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleResolverRunDetail(w http.ResponseWriter, r *http.Request) {
+	if s.resolver == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "resolver scheduler unavailable"})
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(chi.URLParam(r, "id")), 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid run id"})
+		return
+	}
+	detail, err := s.resolver.Run(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, routing.ErrResolverRunNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
@@ -10,14 +10,25 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"split-vpn-webui/internal/jobs"
 	"split-vpn-webui/internal/routing"
 )
 
 type groupUpsertPayload struct {
-	Name      string              `json:"name"`
-	EgressVPN string              `json:"egressVpn"`
-	Domains   []string            `json:"domains,omitempty"`
-	Rules     []ruleUpsertPayload `json:"rules,omitempty"`
+	Name                string              `json:"name"`
+	EgressVPN           string              `json:"egressVpn"`
+	DNSRedirect         string              `json:"dnsRedirect,omitempty"`
+	DnsmasqIsolated     bool                `json:"dnsmasqIsolated,omitempty"`
+	DnsmasqUpstreams    []string            `json:"dnsmasqUpstreams,omitempty"`
+	IPSetTimeoutSeconds int                 `json:"ipsetTimeoutSeconds,omitempty"`
+	InvertDestinations  bool                `json:"invertDestinations,omitempty"`
+	FullTunnel          bool                `json:"fullTunnel,omitempty"`
+	KillSwitch          bool                `json:"killSwitch,omitempty"`
+	Priority            int                 `json:"priority,omitempty"`
+	Notes               string              `json:"notes,omitempty"`
+	Tags                []string            `json:"tags,omitempty"`
+	Domains             []string            `json:"domains,omitempty"`
+	Rules               []ruleUpsertPayload `json:"rules,omitempty"`
 }
 
 type ruleUpsertPayload struct {
@@ -26,6 +37,7 @@ type ruleUpsertPayload struct {
 	SourceCIDRs              []string                `json:"sourceCidrs,omitempty"`
 	ExcludedSourceCIDRs      []string                `json:"excludedSourceCidrs,omitempty"`
 	SourceMACs               []string                `json:"sourceMacs,omitempty"`
+	SourceDeviceGroups       []string                `json:"sourceDeviceGroups,omitempty"`
 	DestinationCIDRs         []string                `json:"destinationCidrs,omitempty"`
 	ExcludedDestinationCIDRs []string                `json:"excludedDestinationCidrs,omitempty"`
 	DestinationPorts         []portUpsertPayload     `json:"destinationPorts,omitempty"`
@@ -33,8 +45,15 @@ type ruleUpsertPayload struct {
 	DestinationASNs          []string                `json:"destinationAsns,omitempty"`
 	ExcludedDestinationASNs  []string                `json:"excludedDestinationAsns,omitempty"`
 	ExcludeMulticast         *bool                   `json:"excludeMulticast,omitempty"`
+	UploadLimitKbit          int                     `json:"uploadLimitKbit,omitempty"`
+	DownloadLimitKbit        int                     `json:"downloadLimitKbit,omitempty"`
+	MonitorOnly              bool                    `json:"monitorOnly,omitempty"`
+	Notes                    string                  `json:"notes,omitempty"`
+	Tags                     []string                `json:"tags,omitempty"`
+	ExpiresAt                int64                   `json:"expiresAt,omitempty"`
 	Domains                  []string                `json:"domains,omitempty"`
 	WildcardDomains          []string                `json:"wildcardDomains,omitempty"`
+	StaticHosts              []staticHostPayload     `json:"staticHosts,omitempty"`
 	RawSelectors             ruleRawSelectorsPayload `json:"rawSelectors,omitempty"`
 }
 
@@ -43,6 +62,7 @@ type ruleRawSelectorsPayload struct {
 	SourceCIDRs              []string `json:"sourceCidrs,omitempty"`
 	ExcludedSourceCIDRs      []string `json:"excludedSourceCidrs,omitempty"`
 	SourceMACs               []string `json:"sourceMacs,omitempty"`
+	SourceDeviceGroups       []string `json:"sourceDeviceGroups,omitempty"`
 	DestinationCIDRs         []string `json:"destinationCidrs,omitempty"`
 	ExcludedDestinationCIDRs []string `json:"excludedDestinationCidrs,omitempty"`
 	DestinationPorts         []string `json:"destinationPorts,omitempty"`
@@ -51,6 +71,7 @@ type ruleRawSelectorsPayload struct {
 	ExcludedDestinationASNs  []string `json:"excludedDestinationAsns,omitempty"`
 	Domains                  []string `json:"domains,omitempty"`
 	WildcardDomains          []string `json:"wildcardDomains,omitempty"`
+	StaticHosts              []string `json:"staticHosts,omitempty"`
 }
 
 type portUpsertPayload struct {
@@ -59,6 +80,11 @@ type portUpsertPayload struct {
 	End      int    `json:"end,omitempty"`
 }
 
+type staticHostPayload struct {
+	Domain string   `json:"domain"`
+	CIDRs  []string `json:"cidrs"`
+}
+
 func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
 	if s.routingManager == nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
@@ -100,7 +126,9 @@ func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 		writeRoutingError(w, err)
 		return
 	}
+	job := s.trackJob(jobs.KindApply, "group create")
 	created, err := s.routingManager.CreateGroup(r.Context(), payload)
+	job.Finish(err)
 	if err != nil {
 		writeRoutingError(w, err)
 		return
@@ -124,7 +152,9 @@ func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
 		writeRoutingError(w, err)
 		return
 	}
+	job := s.trackJob(jobs.KindApply, "group update")
 	updated, err := s.routingManager.UpdateGroup(r.Context(), id, payload)
+	job.Finish(err)
 	if err != nil {
 		writeRoutingError(w, err)
 		return
@@ -133,6 +163,36 @@ func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"group": updated})
 }
 
+// handleReorderGroups sets group precedence from an ordered id list,
+// highest priority first.
+func (s *Server) handleReorderGroups(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	var payload struct {
+		GroupIDs []int64 `json:"groupIds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "group reorder")
+	err := s.routingManager.ReorderGroups(r.Context(), payload.GroupIDs)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	groups, err := s.routingManager.ListGroups(r.Context())
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]any{"groups": groups})
+}
+
 func (s *Server) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
 	if s.routingManager == nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
@@ -143,7 +203,10 @@ func (s *Server) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	if err := s.routingManager.DeleteGroup(r.Context(), id); err != nil {
+	job := s.trackJob(jobs.KindApply, "group delete")
+	err = s.routingManager.DeleteGroup(r.Context(), id)
+	job.Finish(err)
+	if err != nil {
 		writeRoutingError(w, err)
 		return
 	}
@@ -156,6 +219,10 @@ func decodeGroupPayload(r *http.Request) (routing.DomainGroup, error) {
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		return routing.DomainGroup{}, fmt.Errorf("%w: invalid JSON body", routing.ErrGroupValidation)
 	}
+	return groupFromPayload(payload)
+}
+
+func groupFromPayload(payload groupUpsertPayload) (routing.DomainGroup, error) {
 	rules := make([]routing.RoutingRule, 0, len(payload.Rules))
 	for _, rule := range payload.Rules {
 		ports := make([]routing.PortRange, 0, len(rule.DestinationPorts))
@@ -174,12 +241,20 @@ func decodeGroupPayload(r *http.Request) (routing.DomainGroup, error) {
 				End:      port.End,
 			})
 		}
+		staticHosts := make([]routing.StaticHostMapping, 0, len(rule.StaticHosts))
+		for _, host := range rule.StaticHosts {
+			staticHosts = append(staticHosts, routing.StaticHostMapping{
+				Domain: host.Domain,
+				CIDRs:  append([]string(nil), host.CIDRs...),
+			})
+		}
 		rules = append(rules, routing.RoutingRule{
 			Name:                     rule.Name,
 			SourceInterfaces:         append([]string(nil), rule.SourceInterfaces...),
 			SourceCIDRs:              append([]string(nil), rule.SourceCIDRs...),
 			ExcludedSourceCIDRs:      append([]string(nil), rule.ExcludedSourceCIDRs...),
 			SourceMACs:               append([]string(nil), rule.SourceMACs...),
+			SourceDeviceGroups:       append([]string(nil), rule.SourceDeviceGroups...),
 			DestinationCIDRs:         append([]string(nil), rule.DestinationCIDRs...),
 			ExcludedDestinationCIDRs: append([]string(nil), rule.ExcludedDestinationCIDRs...),
 			DestinationPorts:         ports,
@@ -187,13 +262,21 @@ func decodeGroupPayload(r *http.Request) (routing.DomainGroup, error) {
 			DestinationASNs:          append([]string(nil), rule.DestinationASNs...),
 			ExcludedDestinationASNs:  append([]string(nil), rule.ExcludedDestinationASNs...),
 			ExcludeMulticast:         rule.ExcludeMulticast,
+			UploadLimitKbit:          rule.UploadLimitKbit,
+			DownloadLimitKbit:        rule.DownloadLimitKbit,
+			MonitorOnly:              rule.MonitorOnly,
+			Notes:                    rule.Notes,
+			Tags:                     append([]string(nil), rule.Tags...),
+			ExpiresAt:                rule.ExpiresAt,
 			Domains:                  append([]string(nil), rule.Domains...),
 			WildcardDomains:          append([]string(nil), rule.WildcardDomains...),
+			StaticHosts:              staticHosts,
 			RawSelectors: &routing.RuleRawSelectors{
 				SourceInterfaces:         append([]string(nil), rule.RawSelectors.SourceInterfaces...),
 				SourceCIDRs:              append([]string(nil), rule.RawSelectors.SourceCIDRs...),
 				ExcludedSourceCIDRs:      append([]string(nil), rule.RawSelectors.ExcludedSourceCIDRs...),
 				SourceMACs:               append([]string(nil), rule.RawSelectors.SourceMACs...),
+				SourceDeviceGroups:       append([]string(nil), rule.RawSelectors.SourceDeviceGroups...),
 				DestinationCIDRs:         append([]string(nil), rule.RawSelectors.DestinationCIDRs...),
 				ExcludedDestinationCIDRs: append([]string(nil), rule.RawSelectors.ExcludedDestinationCIDRs...),
 				DestinationPorts:         append([]string(nil), rule.RawSelectors.DestinationPorts...),
@@ -202,14 +285,25 @@ func decodeGroupPayload(r *http.Request) (routing.DomainGroup, error) {
 				ExcludedDestinationASNs:  append([]string(nil), rule.RawSelectors.ExcludedDestinationASNs...),
 				Domains:                  append([]string(nil), rule.RawSelectors.Domains...),
 				WildcardDomains:          append([]string(nil), rule.RawSelectors.WildcardDomains...),
+				StaticHosts:              append([]string(nil), rule.RawSelectors.StaticHosts...),
 			},
 		})
 	}
 	return routing.NormalizeAndValidate(routing.DomainGroup{
-		Name:      payload.Name,
-		EgressVPN: payload.EgressVPN,
-		Domains:   payload.Domains,
-		Rules:     rules,
+		Name:                payload.Name,
+		EgressVPN:           payload.EgressVPN,
+		DNSRedirect:         payload.DNSRedirect,
+		DnsmasqIsolated:     payload.DnsmasqIsolated,
+		DnsmasqUpstreams:    payload.DnsmasqUpstreams,
+		IPSetTimeoutSeconds: payload.IPSetTimeoutSeconds,
+		InvertDestinations:  payload.InvertDestinations,
+		FullTunnel:          payload.FullTunnel,
+		KillSwitch:          payload.KillSwitch,
+		Priority:            payload.Priority,
+		Notes:               payload.Notes,
+		Tags:                payload.Tags,
+		Domains:             payload.Domains,
+		Rules:               rules,
 	})
 }
 
@@ -225,8 +319,12 @@ func writeRoutingError(w http.ResponseWriter, err error) {
 	switch {
 	case errors.Is(err, routing.ErrGroupValidation):
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-	case errors.Is(err, routing.ErrGroupNotFound):
+	case errors.Is(err, routing.ErrGroupNotFound), errors.Is(err, routing.ErrDeviceGroupNotFound), errors.Is(err, routing.ErrDeviceAliasNotFound),
+		errors.Is(err, routing.ErrGroupTemplateNotFound), errors.Is(err, routing.ErrGroupTrashNotFound):
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, routing.ErrCanaryActive), errors.Is(err, routing.ErrNoCanary), errors.Is(err, routing.ErrDeviceGroupInUse),
+		errors.Is(err, routing.ErrStagingDisabled), errors.Is(err, routing.ErrGroupManaged):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
 	case strings.Contains(strings.ToLower(err.Error()), "unique"):
 		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
 	default:
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"split-vpn-webui/internal/jobs"
+)
+
+// handleRoutingApply re-applies routing state. With ?dryRun=true it instead
+// returns the planned bindings, ipsets, rules and dnsmasq config diffed
+// against live state, without changing anything.
+func (s *Server) handleRoutingApply(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	dryRun := false
+	if raw := strings.TrimSpace(r.URL.Query().Get("dryRun")); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "dryRun must be true or false"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	if dryRun {
+		result, err := s.routingManager.DryRunApply(r.Context())
+		if err != nil {
+			writeRoutingError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"dryRun": true, "result": result})
+		return
+	}
+
+	job := s.trackJob(jobs.KindApply, "manual apply")
+	err := s.routingManager.Apply(r.Context())
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]any{"dryRun": false, "status": "applied"})
+}
+
+// handleRoutingApplyStats reports apply counts, coalescing and durations.
+func (s *Server) handleRoutingApplyStats(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"stats": s.routingManager.ApplyStats()})
+}
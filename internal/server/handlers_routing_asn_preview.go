@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"split-vpn-webui/internal/netbind"
 	"split-vpn-webui/internal/routing"
 	"split-vpn-webui/internal/settings"
 )
@@ -42,7 +43,7 @@ func (s *Server) handleASNPreview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	timeout := asnPreviewTimeout(s.settings)
-	result, err := previewASNEntries(r.Context(), asns, timeout)
+	result, err := previewASNEntries(r.Context(), asns, timeout, s.resolverEgress())
 	if err != nil {
 		if isASNPreviewValidationError(err) {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
@@ -93,6 +94,22 @@ func asnPreviewTimeout(manager *settings.Manager) time.Duration {
 	return time.Duration(seconds) * time.Second
 }
 
+// resolverEgress returns where resolver lookups made outside the resolver
+// scheduler leave.
+func (s *Server) resolverEgress() netbind.Egress {
+	if s.settings == nil {
+		return netbind.Egress{}
+	}
+	current, err := s.settings.Get()
+	if err != nil {
+		return netbind.Egress{}
+	}
+	if s.vpnManager == nil {
+		return routing.ResolverEgress(nil, current)
+	}
+	return routing.ResolverEgress(s.vpnManager, current)
+}
+
 func defaultASNPreviewTimeout() time.Duration {
 	return time.Duration(defaultASNPreviewTimeoutSeconds) * time.Second
 }
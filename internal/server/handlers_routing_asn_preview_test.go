@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"split-vpn-webui/internal/netbind"
 	"split-vpn-webui/internal/routing"
 )
 
@@ -39,7 +40,7 @@ func TestHandleASNPreviewUsesSanitizedSelectors(t *testing.T) {
 
 	var capturedASNs []string
 	var capturedTimeout time.Duration
-	previewASNEntries = func(ctx context.Context, asns []string, timeout time.Duration) (routing.ASNPreviewResult, error) {
+	previewASNEntries = func(ctx context.Context, asns []string, timeout time.Duration, egress netbind.Egress) (routing.ASNPreviewResult, error) {
 		capturedASNs = append([]string(nil), asns...)
 		capturedTimeout = timeout
 		return routing.ASNPreviewResult{
@@ -80,7 +81,7 @@ func TestHandleASNPreviewUsesSanitizedSelectors(t *testing.T) {
 func TestHandleASNPreviewValidationError(t *testing.T) {
 	original := previewASNEntries
 	defer func() { previewASNEntries = original }()
-	previewASNEntries = func(ctx context.Context, asns []string, timeout time.Duration) (routing.ASNPreviewResult, error) {
+	previewASNEntries = func(ctx context.Context, asns []string, timeout time.Duration, egress netbind.Egress) (routing.ASNPreviewResult, error) {
 		return routing.ASNPreviewResult{}, errors.New("invalid ASN \"ASBAD\"")
 	}
 
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/routing"
+)
+
+const canaryProbeMaxDestinations = 20
+
+// canaryProbe summarizes the canary device's live conntrack flows by the
+// fwmark they carry, showing whether its traffic actually leaves through the
+// proposed egress VPN.
+type canaryProbe struct {
+	CheckedAt    time.Time `json:"checkedAt"`
+	EgressVPN    string    `json:"egressVpn"`
+	Flows        int       `json:"flows"`
+	ViaEgress    int       `json:"viaEgress"`
+	OtherMark    int       `json:"otherMark"`
+	Unmarked     int       `json:"unmarked"`
+	Destinations []string  `json:"destinations,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+type canaryStartPayload struct {
+	Device string             `json:"device"`
+	Group  groupUpsertPayload `json:"group"`
+}
+
+func (s *Server) handleGetCanary(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	canary, ok := s.routingManager.Canary()
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{"canary": nil})
+		return
+	}
+	probe := s.probeCanary(r.Context(), canary)
+	writeJSON(w, http.StatusOK, map[string]any{"canary": canary, "probe": probe})
+}
+
+func (s *Server) handleStartCanary(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var payload canaryStartPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeRoutingError(w, fmt.Errorf("%w: invalid JSON body", routing.ErrGroupValidation))
+		return
+	}
+	proposed, err := groupFromPayload(payload.Group)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "canary start")
+	canary, err := s.routingManager.StartCanary(r.Context(), id, payload.Device, proposed)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("routing canary started group=%s device=%s egress=%s", canary.GroupName, canary.Device, canary.Proposed.EgressVPN)
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusCreated, map[string]any{"canary": canary})
+}
+
+func (s *Server) handlePromoteCanary(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "canary promote")
+	group, err := s.routingManager.PromoteCanary(r.Context())
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("routing canary promoted group=%s", group.Name)
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]any{"group": group})
+}
+
+func (s *Server) handleRollbackCanary(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "canary rollback")
+	err := s.routingManager.RollbackCanary(r.Context())
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("routing canary rolled back")
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "rolled back"})
+}
+
+func (s *Server) probeCanary(ctx context.Context, canary *routing.Canary) canaryProbe {
+	probe := canaryProbe{CheckedAt: time.Now().UTC(), EgressVPN: canary.Proposed.EgressVPN}
+	device, err := netip.ParseAddr(canary.Device)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	if s.flowRunner == nil || s.vpnManager == nil {
+		probe.Error = "conntrack probe unavailable"
+		return probe
+	}
+	profile, err := s.vpnManager.Get(canary.Proposed.EgressVPN)
+	if err != nil || profile == nil {
+		probe.Error = fmt.Sprintf("egress vpn %q unavailable", canary.Proposed.EgressVPN)
+		return probe
+	}
+	flows, err := s.flowRunner.Snapshot(ctx)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	summarizeCanaryFlows(&probe, flows, device, profile.FWMark)
+	return probe
+}
+
+func summarizeCanaryFlows(probe *canaryProbe, flows []conntrackFlowSample, device netip.Addr, egressMark uint32) {
+	destinations := make(map[string]struct{})
+	for _, flow := range flows {
+		source, ok := parseIPToAddr(flow.SourceIP)
+		if !ok || source != device {
+			continue
+		}
+		probe.Flows++
+		switch {
+		case flowMarkMatchesVPN(flow.Mark, egressMark):
+			probe.ViaEgress++
+			destinations[flow.DestinationIP] = struct{}{}
+		case flow.Mark != 0:
+			probe.OtherMark++
+		default:
+			probe.Unmarked++
+		}
+	}
+	probe.Destinations = make([]string, 0, len(destinations))
+	for destination := range destinations {
+		probe.Destinations = append(probe.Destinations, destination)
+	}
+	sort.Strings(probe.Destinations)
+	if len(probe.Destinations) > canaryProbeMaxDestinations {
+		probe.Destinations = probe.Destinations[:canaryProbeMaxDestinations]
+	}
+}
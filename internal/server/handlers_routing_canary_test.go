@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestSummarizeCanaryFlowsCountsByMark(t *testing.T) {
+	device := netip.MustParseAddr("192.168.1.50")
+	flows := []conntrackFlowSample{
+		{SourceIP: "192.168.1.50", DestinationIP: "203.0.113.10", Mark: 0x16a},
+		{SourceIP: "192.168.1.50", DestinationIP: "203.0.113.10", Mark: 0x16a},
+		{SourceIP: "192.168.1.50", DestinationIP: "198.51.100.7", Mark: 0x169},
+		{SourceIP: "192.168.1.50", DestinationIP: "1.1.1.1"},
+		{SourceIP: "192.168.1.51", DestinationIP: "203.0.113.11", Mark: 0x16a},
+	}
+	var probe canaryProbe
+	summarizeCanaryFlows(&probe, flows, device, 0x16a)
+	if probe.Flows != 4 || probe.ViaEgress != 2 || probe.OtherMark != 1 || probe.Unmarked != 1 {
+		t.Fatalf("unexpected probe counts: %+v", probe)
+	}
+	if len(probe.Destinations) != 1 || probe.Destinations[0] != "203.0.113.10" {
+		t.Fatalf("unexpected egress destinations: %#v", probe.Destinations)
+	}
+}
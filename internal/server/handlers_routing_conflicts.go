@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// handleRoutingConflicts reports overlapping, duplicate and shadowed rules
+// across the stored groups.
+func (s *Server) handleRoutingConflicts(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	conflicts, err := s.routingManager.Conflicts(r.Context(), nil)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"conflicts": conflicts})
+}
+
+// handleCheckRoutingConflicts reports the conflicts a group payload would
+// introduce before it is saved and applied. ?groupId= checks an edit of an
+// existing group; without it the payload is checked as a new group.
+func (s *Server) handleCheckRoutingConflicts(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	candidate, err := decodeGroupPayload(r)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	if raw := strings.TrimSpace(r.URL.Query().Get("groupId")); raw != "" {
+		id, err := parseGroupID(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		candidate.ID = id
+	}
+	conflicts, err := s.routingManager.Conflicts(r.Context(), &candidate)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"conflicts": conflicts})
+}
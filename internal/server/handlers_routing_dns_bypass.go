@@ -0,0 +1,294 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultDNSBypassInterface = "br0"
+
+// DNS bypass kinds: plain DNS to a foreign resolver, DNS over TLS, and DNS
+// over HTTPS (or HTTP/3) to a well-known public resolver.
+const (
+	dnsBypassPlain = "dns"
+	dnsBypassDoT   = "dot"
+	dnsBypassDoH   = "doh"
+)
+
+// knownDoHEndpoints maps public resolver anycast addresses to providers. A
+// client talking HTTPS to one of them is almost certainly resolving there.
+var knownDoHEndpoints = map[netip.Addr]string{
+	netip.MustParseAddr("8.8.8.8"):              "Google",
+	netip.MustParseAddr("8.8.4.4"):              "Google",
+	netip.MustParseAddr("2001:4860:4860::8888"): "Google",
+	netip.MustParseAddr("2001:4860:4860::8844"): "Google",
+	netip.MustParseAddr("1.1.1.1"):              "Cloudflare",
+	netip.MustParseAddr("1.0.0.1"):              "Cloudflare",
+	netip.MustParseAddr("2606:4700:4700::1111"): "Cloudflare",
+	netip.MustParseAddr("2606:4700:4700::1001"): "Cloudflare",
+	netip.MustParseAddr("9.9.9.9"):              "Quad9",
+	netip.MustParseAddr("149.112.112.112"):      "Quad9",
+	netip.MustParseAddr("2620:fe::fe"):          "Quad9",
+	netip.MustParseAddr("208.67.222.222"):       "OpenDNS",
+	netip.MustParseAddr("208.67.220.220"):       "OpenDNS",
+	netip.MustParseAddr("94.140.14.14"):         "AdGuard",
+	netip.MustParseAddr("94.140.15.15"):         "AdGuard",
+	netip.MustParseAddr("45.90.28.0"):           "NextDNS",
+	netip.MustParseAddr("45.90.30.0"):           "NextDNS",
+}
+
+// dnsBypassResolver is one resolver a client talks to directly.
+type dnsBypassResolver struct {
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Kind     string `json:"kind"`
+	Provider string `json:"provider,omitempty"`
+	Flows    int    `json:"flows"`
+}
+
+// dnsBypassClient is a LAN client whose DNS skips the router's resolver, so
+// domain-based routing never sees its lookups.
+type dnsBypassClient struct {
+	IP        string              `json:"ip"`
+	MAC       string              `json:"mac,omitempty"`
+	Name      string              `json:"name,omitempty"`
+	Kinds     []string            `json:"kinds"`
+	Flows     int                 `json:"flows"`
+	Resolvers []dnsBypassResolver `json:"resolvers"`
+}
+
+// handleRoutingDNSBypass reports LAN clients with live conntrack flows to
+// foreign resolvers on port 53, DNS over TLS on 853, or HTTPS to known DoH
+// endpoints.
+func (s *Server) handleRoutingDNSBypass(w http.ResponseWriter, r *http.Request) {
+	if s.flowRunner == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "conntrack unavailable"})
+		return
+	}
+	samples, err := s.flowRunner.Snapshot(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	clients := classifyDNSBypass(samples, localAddrSet())
+	if len(clients) > 0 {
+		devices := s.loadDeviceDirectory(r.Context())
+		for idx := range clients {
+			clients[idx].MAC = devices.lookupIPMAC(clients[idx].IP)
+			clients[idx].Name = devices.lookupIP(clients[idx].IP)
+		}
+	}
+	if s.diagLog != nil {
+		s.diagLog.Debugf("dns bypass report samples=%d clients=%d", len(samples), len(clients))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"clients":     clients,
+		"generatedAt": time.Now().UTC(),
+	})
+}
+
+// handleRoutingDNSBypassRules renders iptables commands that redirect plain
+// DNS from the LAN interface to the router and reject DoT and known DoH
+// endpoints. Nothing is applied; the rules are for the user to review.
+func (s *Server) handleRoutingDNSBypassRules(w http.ResponseWriter, r *http.Request) {
+	iface := strings.TrimSpace(r.URL.Query().Get("interface"))
+	if iface == "" {
+		iface = defaultDNSBypassInterface
+	}
+	var clients []netip.Addr
+	for _, raw := range r.URL.Query()["client"] {
+		addr, ok := parseIPToAddr(raw)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid client address %q", raw)})
+			return
+		}
+		clients = append(clients, addr)
+	}
+	// Resolving the interface also rejects names that do not exist.
+	router, err := interfaceIPv4(iface)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"interface": iface,
+		"router":    router.String(),
+		"rules":     dnsRedirectRules(iface, router, clients),
+	})
+}
+
+// classifyDNSBypass groups DNS-looking flows by LAN client. Flows to or from
+// the router itself and to private resolvers (e.g. a LAN Pi-hole) are not
+// bypasses.
+func classifyDNSBypass(samples []conntrackFlowSample, local map[netip.Addr]struct{}) []dnsBypassClient {
+	type resolverKey struct {
+		addr netip.Addr
+		port int
+	}
+	byClient := make(map[netip.Addr]map[resolverKey]*dnsBypassResolver)
+	for _, sample := range samples {
+		source, sourceOK := parseIPToAddr(sample.SourceIP)
+		destination, destinationOK := parseIPToAddr(sample.DestinationIP)
+		if !sourceOK || !destinationOK {
+			continue
+		}
+		if _, isLocal := local[source]; isLocal {
+			continue
+		}
+		if _, isLocal := local[destination]; isLocal {
+			continue
+		}
+		if !source.IsPrivate() && !(source.Is6() && source.IsGlobalUnicast()) {
+			continue
+		}
+		if destination.IsPrivate() || destination.IsLoopback() || destination.IsLinkLocalUnicast() {
+			continue
+		}
+		provider := knownDoHEndpoints[destination]
+		var kind string
+		switch {
+		case sample.DestinationPort == 53:
+			kind = dnsBypassPlain
+		case sample.DestinationPort == 853:
+			kind = dnsBypassDoT
+		case sample.DestinationPort == 443 && provider != "":
+			kind = dnsBypassDoH
+		default:
+			continue
+		}
+		resolvers := byClient[source]
+		if resolvers == nil {
+			resolvers = make(map[resolverKey]*dnsBypassResolver)
+			byClient[source] = resolvers
+		}
+		key := resolverKey{addr: destination, port: sample.DestinationPort}
+		entry := resolvers[key]
+		if entry == nil {
+			entry = &dnsBypassResolver{Address: destination.String(), Port: sample.DestinationPort, Kind: kind, Provider: provider}
+			resolvers[key] = entry
+		}
+		entry.Flows++
+	}
+
+	clients := make([]dnsBypassClient, 0, len(byClient))
+	for source, resolvers := range byClient {
+		client := dnsBypassClient{IP: source.String(), Kinds: []string{}, Resolvers: make([]dnsBypassResolver, 0, len(resolvers))}
+		kinds := map[string]struct{}{}
+		for _, resolver := range resolvers {
+			client.Resolvers = append(client.Resolvers, *resolver)
+			client.Flows += resolver.Flows
+			kinds[resolver.Kind] = struct{}{}
+		}
+		for kind := range kinds {
+			client.Kinds = append(client.Kinds, kind)
+		}
+		sort.Strings(client.Kinds)
+		sort.Slice(client.Resolvers, func(i, j int) bool {
+			if client.Resolvers[i].Flows != client.Resolvers[j].Flows {
+				return client.Resolvers[i].Flows > client.Resolvers[j].Flows
+			}
+			return client.Resolvers[i].Address < client.Resolvers[j].Address
+		})
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].Flows != clients[j].Flows {
+			return clients[i].Flows > clients[j].Flows
+		}
+		return clients[i].IP < clients[j].IP
+	})
+	return clients
+}
+
+// dnsRedirectRules builds the redirect rules. With clients set, only those
+// sources are matched; a family without listed clients gets no rules.
+func dnsRedirectRules(iface string, router netip.Addr, clients []netip.Addr) []string {
+	var v4Sources, v6Sources []string
+	for _, client := range clients {
+		if client.Is4() {
+			v4Sources = append(v4Sources, "-s "+client.String()+" ")
+		} else {
+			v6Sources = append(v6Sources, "-s "+client.String()+" ")
+		}
+	}
+	if len(clients) == 0 {
+		v4Sources, v6Sources = []string{""}, []string{""}
+	}
+
+	var endpoints4, endpoints6 []netip.Addr
+	for addr := range knownDoHEndpoints {
+		if addr.Is4() {
+			endpoints4 = append(endpoints4, addr)
+		} else {
+			endpoints6 = append(endpoints6, addr)
+		}
+	}
+	sort.Slice(endpoints4, func(i, j int) bool { return endpoints4[i].Less(endpoints4[j]) })
+	sort.Slice(endpoints6, func(i, j int) bool { return endpoints6[i].Less(endpoints6[j]) })
+
+	rules := []string{"# Redirect plain DNS to the router's resolver."}
+	for _, src := range v4Sources {
+		for _, proto := range []string{"udp", "tcp"} {
+			rules = append(rules, fmt.Sprintf("iptables -t nat -I PREROUTING -i %s %s-p %s --dport 53 ! -d %s -j DNAT --to-destination %s:53", iface, src, proto, router, router))
+		}
+	}
+	rules = append(rules, "# Reject DNS over TLS and DNS over HTTPS to well-known resolvers so clients fall back to plain DNS.")
+	for _, family := range []struct {
+		command   string
+		sources   []string
+		endpoints []netip.Addr
+	}{
+		{"iptables", v4Sources, endpoints4},
+		{"ip6tables", v6Sources, endpoints6},
+	} {
+		for _, src := range family.sources {
+			rules = append(rules, fmt.Sprintf("%s -I FORWARD -i %s %s-p tcp --dport 853 -j REJECT --reject-with tcp-reset", family.command, iface, src))
+			for _, endpoint := range family.endpoints {
+				rules = append(rules,
+					fmt.Sprintf("%s -I FORWARD -i %s %s-d %s -p tcp --dport 443 -j REJECT --reject-with tcp-reset", family.command, iface, src, endpoint),
+					fmt.Sprintf("%s -I FORWARD -i %s %s-d %s -p udp --dport 443 -j REJECT", family.command, iface, src, endpoint),
+				)
+			}
+		}
+	}
+	return rules
+}
+
+func localAddrSet() map[netip.Addr]struct{} {
+	local := make(map[netip.Addr]struct{})
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return local
+	}
+	for _, addr := range addrs {
+		prefix, err := netip.ParsePrefix(addr.String())
+		if err != nil {
+			continue
+		}
+		local[prefix.Addr().Unmap()] = struct{}{}
+	}
+	return local
+}
+
+func interfaceIPv4(name string) (netip.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("interface %s: %w", name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("interface %s addresses: %w", name, err)
+	}
+	for _, addr := range addrs {
+		prefix, err := netip.ParsePrefix(addr.String())
+		if err == nil && prefix.Addr().Unmap().Is4() {
+			return prefix.Addr().Unmap(), nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("interface %s has no IPv4 address", name)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestClassifyDNSBypass(t *testing.T) {
+	local := map[netip.Addr]struct{}{netip.MustParseAddr("192.168.1.1"): {}}
+	samples := []conntrackFlowSample{
+		{Protocol: "udp", SourceIP: "192.168.1.20", SourcePort: 5000, DestinationIP: "8.8.8.8", DestinationPort: 53},
+		{Protocol: "udp", SourceIP: "192.168.1.20", SourcePort: 5001, DestinationIP: "8.8.8.8", DestinationPort: 53},
+		{Protocol: "tcp", SourceIP: "192.168.1.20", SourcePort: 5002, DestinationIP: "1.1.1.1", DestinationPort: 443},
+		{Protocol: "tcp", SourceIP: "192.168.1.30", SourcePort: 6000, DestinationIP: "203.0.113.9", DestinationPort: 853},
+		// Router's own resolver, LAN resolvers and ordinary HTTPS are fine.
+		{Protocol: "udp", SourceIP: "192.168.1.40", SourcePort: 7000, DestinationIP: "192.168.1.1", DestinationPort: 53},
+		{Protocol: "udp", SourceIP: "192.168.1.40", SourcePort: 7001, DestinationIP: "192.168.1.5", DestinationPort: 53},
+		{Protocol: "tcp", SourceIP: "192.168.1.40", SourcePort: 7002, DestinationIP: "203.0.113.10", DestinationPort: 443},
+		{Protocol: "udp", SourceIP: "192.168.1.1", SourcePort: 7003, DestinationIP: "9.9.9.9", DestinationPort: 53},
+	}
+	clients := classifyDNSBypass(samples, local)
+	if len(clients) != 2 {
+		t.Fatalf("expected two bypassing clients, got %#v", clients)
+	}
+	first := clients[0]
+	if first.IP != "192.168.1.20" || first.Flows != 3 || strings.Join(first.Kinds, ",") != "dns,doh" {
+		t.Fatalf("unexpected first client %#v", first)
+	}
+	if len(first.Resolvers) != 2 || first.Resolvers[0].Address != "8.8.8.8" || first.Resolvers[0].Flows != 2 || first.Resolvers[1].Provider != "Cloudflare" {
+		t.Fatalf("unexpected resolvers %#v", first.Resolvers)
+	}
+	if second := clients[1]; second.IP != "192.168.1.30" || strings.Join(second.Kinds, ",") != dnsBypassDoT {
+		t.Fatalf("unexpected second client %#v", second)
+	}
+}
+
+func TestDNSRedirectRules(t *testing.T) {
+	router := netip.MustParseAddr("192.168.1.1")
+	rules := dnsRedirectRules("br0", router, []netip.Addr{netip.MustParseAddr("192.168.1.20")})
+	joined := strings.Join(rules, "\n")
+	for _, expected := range []string{
+		"iptables -t nat -I PREROUTING -i br0 -s 192.168.1.20 -p udp --dport 53 ! -d 192.168.1.1 -j DNAT --to-destination 192.168.1.1:53",
+		"iptables -I FORWARD -i br0 -s 192.168.1.20 -p tcp --dport 853 -j REJECT --reject-with tcp-reset",
+		"iptables -I FORWARD -i br0 -s 192.168.1.20 -d 1.1.1.1 -p tcp --dport 443 -j REJECT --reject-with tcp-reset",
+	} {
+		if !strings.Contains(joined, expected) {
+			t.Fatalf("expected rule %q in\n%s", expected, joined)
+		}
+	}
+	if strings.Contains(joined, "ip6tables") {
+		t.Fatalf("expected no IPv6 rules for an IPv4-only client list\n%s", joined)
+	}
+
+	all := strings.Join(dnsRedirectRules("br0", router, nil), "\n")
+	if !strings.Contains(all, "ip6tables -I FORWARD -i br0 -p tcp --dport 853") || strings.Contains(all, "-s ") {
+		t.Fatalf("unexpected rules without a client filter\n%s", all)
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"split-vpn-webui/internal/routing"
+)
+
+func (s *Server) handleRoutingDnsmasq(w http.ResponseWriter, r *http.Request) {
+	status, err := s.routingManager.DnsmasqStatus()
+	if err != nil {
+		switch {
+		case errors.Is(err, routing.ErrDnsmasqInspectUnsupported):
+			writeJSON(w, http.StatusNotImplemented, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
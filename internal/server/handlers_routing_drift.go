@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/routing"
+)
+
+// configureDriftMonitor wires drift reports into the SSE stream and routes
+// repairs through the job queue.
+func (s *Server) configureDriftMonitor(monitor *routing.DriftMonitor) {
+	s.drift = monitor
+	monitor.SetRepairer(func(ctx context.Context) error {
+		job := s.trackJob(jobs.KindApply, "drift repair")
+		err := s.routingManager.Apply(ctx)
+		job.Finish(err)
+		if err == nil {
+			s.broadcastUpdate(nil)
+		}
+		return err
+	})
+	monitor.SetHandler(func(report routing.DriftReport) {
+		if s.diagLog != nil {
+			switch {
+			case report.RepairError != "":
+				s.diagLog.Errorf("routing drift repair failed: %s", report.RepairError)
+			case report.Drifted:
+				s.diagLog.Warnf(
+					"routing drift detected mode=%s missing_sets=%d missing_rules=%d unexpected_rules=%d dnsmasq_changed=%t repaired=%t",
+					report.Mode,
+					len(report.MissingSets),
+					len(report.MissingRules),
+					len(report.UnexpectedRules),
+					report.DnsmasqChanged,
+					report.Repaired,
+				)
+				if len(report.MissingSets) > 0 {
+					s.diagLog.Debugf("routing drift missing sets: %s", strings.Join(report.MissingSets, ", "))
+				}
+			default:
+				s.diagLog.Infof("routing drift cleared")
+			}
+		}
+		s.broadcastEvent("drift", report)
+	})
+}
+
+func (s *Server) handleRoutingDrift(w http.ResponseWriter, r *http.Request) {
+	if s.drift == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "drift monitor unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"report": s.drift.Last()})
+}
+
+func (s *Server) handleRoutingDriftCheck(w http.ResponseWriter, r *http.Request) {
+	if s.drift == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "drift monitor unavailable"})
+		return
+	}
+	report, err := s.drift.Check(r.Context())
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"report": report})
+}
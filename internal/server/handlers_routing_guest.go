@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/routing"
+)
+
+// handleGuestSafeMode reports the guest safe mode policy:
+// GET /api/routing/guest-safe-mode.
+func (s *Server) handleGuestSafeMode(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	policy, err := s.routingManager.GuestSafeMode(r.Context())
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"guestSafeMode": policy})
+}
+
+// handleSetGuestSafeMode turns guest safe mode on, retargets it or turns it
+// off, regenerating its managed group: PUT /api/routing/guest-safe-mode.
+func (s *Server) handleSetGuestSafeMode(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	var payload routing.GuestSafeMode
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "guest safe mode")
+	policy, err := s.routingManager.SetGuestSafeMode(r.Context(), payload)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusOK, map[string]any{"guestSafeMode": policy})
+}
@@ -37,6 +37,9 @@ type routingInspectorRule struct {
 	ExcludedDestinationASNs  []string                    `json:"excludedDestinationAsns,omitempty"`
 	ExcludedDestinationCIDRs []string                    `json:"excludedDestinationCidrs,omitempty"`
 	ExcludeMulticast         bool                        `json:"excludeMulticast"`
+	UploadLimitKbit          int                         `json:"uploadLimitKbit,omitempty"`
+	DownloadLimitKbit        int                         `json:"downloadLimitKbit,omitempty"`
+	MonitorOnly              bool                        `json:"monitorOnly,omitempty"`
 	Domains                  []string                    `json:"domains,omitempty"`
 	WildcardDomains          []string                    `json:"wildcardDomains,omitempty"`
 	SourceSetV4              routingInspectorSetSnapshot `json:"sourceSetV4,omitempty"`
@@ -99,11 +102,11 @@ func (s *Server) buildVPNRoutingInspector(r *http.Request, vpnName string) (*rou
 	if err != nil {
 		return nil, err
 	}
-	setSnapshots, err := readIPSetSnapshots(routingInspectorIPSetTimeout)
+	setSnapshots, err := s.ipsetSnapshots(routingInspectorIPSetTimeout)
 	if err != nil {
 		return nil, err
 	}
-	devices := loadDeviceDirectory(ctx)
+	devices := s.loadDeviceDirectory(ctx)
 
 	response := &routingInspectorResponse{
 		VPNName:     vpnName,
@@ -134,6 +137,9 @@ func (s *Server) buildVPNRoutingInspector(r *http.Request, vpnName string) (*rou
 				ExcludedDestinationASNs:  append([]string(nil), rule.ExcludedDestinationASNs...),
 				ExcludedDestinationCIDRs: append([]string(nil), rule.ExcludedDestinationCIDRs...),
 				ExcludeMulticast:         routing.RuleExcludeMulticastEnabled(rule),
+				UploadLimitKbit:          rule.UploadLimitKbit,
+				DownloadLimitKbit:        rule.DownloadLimitKbit,
+				MonitorOnly:              rule.MonitorOnly,
 				Domains:                  append([]string(nil), rule.Domains...),
 				WildcardDomains:          append([]string(nil), rule.WildcardDomains...),
 			}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// handleRoutingNetworks lists UniFi networks and the network source
+// selectors in use with the bridge and subnets each resolves to:
+// GET /api/routing/networks.
+func (s *Server) handleRoutingNetworks(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	networks, err := s.routingManager.LANNetworks(r.Context())
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"networks": networks})
+}
+
+// unifiNetworkVLANs resolves "network:<name>" source selectors through the
+// UniFi controller's network config.
+func (s *Server) unifiNetworkVLANs(ctx context.Context) (map[string]int, error) {
+	if s.settings == nil {
+		return nil, errors.New("settings unavailable")
+	}
+	current, err := s.settings.Get()
+	if err != nil {
+		return nil, err
+	}
+	controller := newUniFiController(current)
+	if controller == nil {
+		return nil, errors.New("network names need a UniFi controller API key")
+	}
+	return controller.networkVLANs(ctx)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/jobs"
+)
+
+// maxSelectorPasteBytes bounds a pasted selector blob; large blocklists
+// belong in several rules or an ASN selector.
+const maxSelectorPasteBytes = 1 << 20
+
+// handlePasteGroupSelectors classifies a freeform pasted blob and appends the
+// accepted destination selectors to one rule of the group. dryRun returns
+// the report without saving.
+func (s *Server) handlePasteGroupSelectors(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var payload struct {
+		RuleIndex int    `json:"ruleIndex"`
+		Text      string `json:"text"`
+		DryRun    bool   `json:"dryRun"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSelectorPasteBytes)).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+
+	var job *jobs.Handle
+	if !payload.DryRun {
+		job = s.trackJob(jobs.KindApply, "selector paste")
+	}
+	report, group, err := s.routingManager.PasteSelectors(r.Context(), id, payload.RuleIndex, payload.Text, payload.DryRun)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	if report.Applied {
+		s.broadcastUpdate(nil)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"report": report, "group": group})
+}
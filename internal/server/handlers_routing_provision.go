@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/routing"
+)
+
+// configureProvisionWatcher routes provisioning repairs through the job queue
+// and reports them in the diagnostics log and SSE stream.
+func (s *Server) configureProvisionWatcher(watcher *routing.ProvisionWatcher) {
+	s.provision = watcher
+	watcher.SetRepairer(func(ctx context.Context) error {
+		job := s.trackJob(jobs.KindApply, "unifi provisioning")
+		err := s.routingManager.Apply(ctx)
+		job.Finish(err)
+		if err == nil {
+			s.broadcastUpdate(nil)
+		}
+		return err
+	})
+	watcher.SetHandler(func(event routing.ProvisionEvent) {
+		if s.diagLog != nil {
+			reasons := strings.Join(event.Reasons, "; ")
+			switch {
+			case event.RepairError != "":
+				s.diagLog.Errorf("routing re-apply after unifi provisioning failed reasons=%q: %s", reasons, event.RepairError)
+			case event.RelinkError != "":
+				s.diagLog.Warnf("routing re-applied after unifi provisioning reasons=%q; chain relink failed: %s", reasons, event.RelinkError)
+			default:
+				s.diagLog.Infof("routing re-applied after unifi provisioning reasons=%q", reasons)
+			}
+		}
+		s.broadcastEvent("provision", event)
+	})
+}
+
+func (s *Server) handleRoutingProvision(w http.ResponseWriter, r *http.Request) {
+	if s.provision == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "provisioning watcher unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"event": s.provision.Last()})
+}
+
+// configureDelegationWatcher logs IPv6 prefix delegation changes and the
+// source sets reloaded for them, and network selectors that moved.
+func (s *Server) configureDelegationWatcher(watcher *routing.DelegationWatcher) {
+	s.delegation = watcher
+	watcher.SetHandler(func(change routing.DelegationChange, err error) {
+		if err != nil {
+			if s.diagLog != nil {
+				s.diagLog.Errorf("ipv6 prefix delegation refresh failed: %v", err)
+			}
+			return
+		}
+		if s.diagLog != nil {
+			for _, network := range change.Networks {
+				s.diagLog.Infof("network selector changed selector=%s iface=%s subnets=%s error=%q",
+					network.Selector, network.Interface, strings.Join(network.Subnets, ","), network.Error)
+			}
+			for _, iface := range change.Interfaces {
+				s.diagLog.Infof("ipv6 prefix delegation changed iface=%s prefixes=%s", iface, strings.Join(change.Prefixes[iface], ","))
+			}
+			if len(change.Sets) > 0 {
+				s.diagLog.Debugf("reloaded source sets after prefix delegation change: %s", strings.Join(change.Sets, ", "))
+			}
+		}
+		s.broadcastEvent("delegation", change)
+	})
+}
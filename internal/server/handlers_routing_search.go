@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSelectorSearchLimit = 200
+	maxSelectorSearchLimit     = 1000
+)
+
+// handleRoutingSearch finds the groups and rules holding a selector:
+// GET /api/routing/search?q=netflix[&limit=200].
+func (s *Server) handleRoutingSearch(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q is required"})
+		return
+	}
+	limit := defaultSelectorSearchLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxSelectorSearchLimit {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be 1-" + strconv.Itoa(maxSelectorSearchLimit)})
+			return
+		}
+		limit = parsed
+	}
+	result, err := s.routingManager.SearchSelectors(r.Context(), query, limit)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/routing"
+)
+
+const routeTraceCommandTimeout = 5 * time.Second
+
+// routeLookup asks the kernel how it would route a packet.
+type routeLookup interface {
+	RouteGet(ctx context.Context, args []string) (string, error)
+}
+
+type ipRouteLookup struct{}
+
+func (ipRouteLookup) RouteGet(ctx context.Context, args []string) (string, error) {
+	runCtx, cancel := context.WithTimeout(ctx, routeTraceCommandTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(runCtx, "ip", args...).CombinedOutput()
+	if err != nil {
+		if detail := strings.TrimSpace(string(output)); detail != "" {
+			return "", fmt.Errorf("ip %s: %s", strings.Join(args, " "), detail)
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+type routeTracePayload struct {
+	Source          string `json:"source"`
+	Destination     string `json:"destination"`
+	Protocol        string `json:"protocol"`
+	Port            int    `json:"port"`
+	SourceInterface string `json:"sourceInterface"`
+	SourceMAC       string `json:"sourceMac"`
+}
+
+// routeTraceBinding is the app's view of which rule claims a packet.
+type routeTraceBinding struct {
+	VPN        string `json:"vpn"`
+	Group      string `json:"group"`
+	Rule       int    `json:"rule"`
+	Interface  string `json:"interface"`
+	RouteTable int    `json:"routeTable"`
+	Mark       string `json:"mark"`
+	// MonitorOnly rules only log the packet, so they never decide its route.
+	MonitorOnly bool `json:"monitorOnly,omitempty"`
+	// FullTunnel rules yield to any other match.
+	FullTunnel bool `json:"fullTunnel,omitempty"`
+}
+
+// routeDecision is the parsed output of `ip route get`.
+type routeDecision struct {
+	Command   string `json:"command"`
+	Raw       string `json:"raw"`
+	Interface string `json:"interface,omitempty"`
+	Gateway   string `json:"gateway,omitempty"`
+	Table     string `json:"table,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+type routeTraceResponse struct {
+	Source          string              `json:"source"`
+	Destination     string              `json:"destination"`
+	SourceInterface string              `json:"sourceInterface,omitempty"`
+	SourceMAC       string              `json:"sourceMac,omitempty"`
+	Expected        *routeTraceBinding  `json:"expected,omitempty"`
+	Matches         []routeTraceBinding `json:"matches"`
+	Kernel          routeDecision       `json:"kernel"`
+	Mismatch        bool                `json:"mismatch"`
+	Reason          string              `json:"reason,omitempty"`
+}
+
+// handleRouteTrace reports which routing rule the app expects to claim a
+// packet and what the kernel actually does with it, marked accordingly.
+func (s *Server) handleRouteTrace(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil || s.vpnManager == nil || s.routes == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing trace unavailable"})
+		return
+	}
+	var payload routeTracePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	source, sourceOK := parseIPToAddr(payload.Source)
+	destination, destinationOK := parseIPToAddr(payload.Destination)
+	if !sourceOK || !destinationOK {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "source and destination must be IP addresses"})
+		return
+	}
+	if source.Is4() != destination.Is4() {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "source and destination must be the same address family"})
+		return
+	}
+	protocol := strings.ToLower(strings.TrimSpace(payload.Protocol))
+	if protocol != "" && protocol != "tcp" && protocol != "udp" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "protocol must be tcp or udp"})
+		return
+	}
+	if payload.Port < 0 || payload.Port > 65535 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "port must be between 1 and 65535"})
+		return
+	}
+
+	response, err := s.traceRoute(r.Context(), payload, source, destination, protocol)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("route trace src=%s dst=%s kernel_dev=%s mismatch=%t", response.Source, response.Destination, response.Kernel.Interface, response.Mismatch)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"trace": response})
+}
+
+func (s *Server) traceRoute(ctx context.Context, payload routeTracePayload, source, destination netip.Addr, protocol string) (*routeTraceResponse, error) {
+	groups, err := s.routingManager.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups = s.routingManager.ExpandNetworkSelectors(groups)
+	deviceGroups, err := s.routingManager.ListDeviceGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := s.routingManager.LoadResolverSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prewarmed, err := s.routingManager.LoadPrewarmSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.ipsetSnapshots(flowInspectorIPSetTimeout)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := s.vpnManager.List()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &routeTraceResponse{
+		Source:          source.String(),
+		Destination:     destination.String(),
+		SourceInterface: strings.TrimSpace(payload.SourceInterface),
+		SourceMAC:       strings.ToLower(strings.TrimSpace(payload.SourceMAC)),
+		Matches:         make([]routeTraceBinding, 0),
+	}
+	if response.SourceInterface == "" {
+		response.SourceInterface = resolveSourceInterface(listLocalInterfacePrefixes(), source)
+	}
+	if response.SourceMAC == "" {
+		devices := s.loadDeviceDirectory(ctx)
+		response.SourceMAC = strings.ToLower(strings.TrimSpace(devices.lookupIPMAC(source.String())))
+	}
+
+	flow := conntrackFlowSample{
+		Protocol:        protocol,
+		SourceIP:        source.String(),
+		DestinationIP:   destination.String(),
+		DestinationPort: payload.Port,
+	}
+	vpnInterfaces := make(map[string]string, len(profiles))
+	for _, profile := range profiles {
+		vpnInterfaces[strings.TrimSpace(profile.InterfaceName)] = profile.Name
+		rules := compileFlowRules(profile.Name, groups, deviceGroups, snapshots, resolved, prewarmed)
+		for idx := range rules {
+			if matchFlowRule(rules[idx:idx+1], flow, source, destination, response.SourceMAC, response.SourceInterface) == nil {
+				continue
+			}
+			response.Matches = append(response.Matches, routeTraceBinding{
+				VPN:         profile.Name,
+				Group:       rules[idx].GroupName,
+				Rule:        rules[idx].RuleIndex + 1,
+				Interface:   strings.TrimSpace(profile.InterfaceName),
+				RouteTable:  profile.RouteTable,
+				Mark:        fmt.Sprintf("0x%x", profile.FWMark),
+				MonitorOnly: rules[idx].MonitorOnly,
+				FullTunnel:  rules[idx].FullTunnel,
+			})
+		}
+	}
+	orderRouteTraceMatches(response.Matches, groups)
+	for _, match := range response.Matches {
+		if !match.MonitorOnly {
+			expected := match
+			response.Expected = &expected
+			break
+		}
+	}
+
+	mark := "0"
+	if response.Expected != nil {
+		mark = response.Expected.Mark
+	}
+	args := routeGetArgs(source, destination, response.SourceInterface, mark)
+	raw, err := s.routes.RouteGet(ctx, args)
+	response.Kernel = routeDecision{Command: "ip " + strings.Join(args, " ")}
+	if err != nil {
+		response.Kernel.Raw = err.Error()
+		response.Mismatch = true
+		response.Reason = "kernel route lookup failed"
+		return response, nil
+	}
+	response.Kernel = parseRouteGet(raw, response.Kernel.Command)
+
+	switch {
+	case response.Expected != nil && response.Kernel.Interface != response.Expected.Interface:
+		response.Mismatch = true
+		response.Reason = fmt.Sprintf("expected egress via %s (%s), kernel routes via %s", response.Expected.Interface, response.Expected.VPN, describeRouteDevice(response.Kernel.Interface))
+	case response.Expected == nil && vpnInterfaces[response.Kernel.Interface] != "":
+		response.Mismatch = true
+		response.Reason = fmt.Sprintf("no rule claims this packet, but the kernel routes it via VPN %s", vpnInterfaces[response.Kernel.Interface])
+	}
+	return response, nil
+}
+
+// orderRouteTraceMatches sorts matches into the order groups are listed,
+// which is the order their mark rules are installed, with full tunnel
+// matches last.
+func orderRouteTraceMatches(matches []routeTraceBinding, groups []routing.DomainGroup) {
+	position := make(map[string]int, len(groups))
+	for idx, group := range groups {
+		position[group.Name] = idx
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].FullTunnel != matches[j].FullTunnel {
+			return matches[j].FullTunnel
+		}
+		if position[matches[i].Group] != position[matches[j].Group] {
+			return position[matches[i].Group] < position[matches[j].Group]
+		}
+		return matches[i].Rule < matches[j].Rule
+	})
+}
+
+// routeGetArgs simulates a forwarded packet when the ingress interface is
+// known, otherwise a locally originated one.
+func routeGetArgs(source, destination netip.Addr, iif, mark string) []string {
+	args := make([]string, 0, 10)
+	if destination.Is6() {
+		args = append(args, "-6")
+	}
+	args = append(args, "route", "get", destination.String())
+	if iif != "" {
+		args = append(args, "from", source.String(), "iif", iif)
+	}
+	return append(args, "mark", mark)
+}
+
+// parseRouteGet reads the first line of `ip route get` output, e.g.
+// "1.1.1.1 from 192.168.1.10 via 10.2.0.1 dev wg-sv-nl table 201 mark 0x169".
+func parseRouteGet(raw, command string) routeDecision {
+	decision := routeDecision{Command: command, Raw: strings.TrimSpace(raw)}
+	line := strings.SplitN(decision.Raw, "\n", 2)[0]
+	fields := strings.Fields(line)
+	if len(fields) > 0 {
+		switch fields[0] {
+		case "unreachable", "prohibit", "blackhole", "local", "broadcast", "multicast", "throw":
+			decision.Type = fields[0]
+		}
+	}
+	for idx := 0; idx+1 < len(fields); idx++ {
+		switch fields[idx] {
+		case "dev":
+			decision.Interface = fields[idx+1]
+		case "via":
+			decision.Gateway = fields[idx+1]
+		case "table":
+			decision.Table = fields[idx+1]
+		case "src":
+			decision.Source = fields[idx+1]
+		}
+	}
+	if decision.Type == "" {
+		decision.Type = "unicast"
+	}
+	if decision.Table == "" && decision.Type == "unicast" {
+		decision.Table = "main"
+	}
+	return decision
+}
+
+func describeRouteDevice(iface string) string {
+	if iface == "" {
+		return "no interface"
+	}
+	return iface
+}
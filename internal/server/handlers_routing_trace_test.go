@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"split-vpn-webui/internal/routing"
+)
+
+func TestParseRouteGet(t *testing.T) {
+	raw := "1.1.1.1 from 192.168.1.10 via 10.2.0.1 dev wg-sv-nl table 201 mark 0x169 uid 0 \n    cache iif br0 \n"
+	decision := parseRouteGet(raw, "ip route get 1.1.1.1")
+	if decision.Interface != "wg-sv-nl" || decision.Gateway != "10.2.0.1" || decision.Table != "201" || decision.Type != "unicast" {
+		t.Fatalf("unexpected decision %#v", decision)
+	}
+
+	main := parseRouteGet("8.8.8.8 via 203.0.113.1 dev eth8 src 203.0.113.20 uid 0\n", "")
+	if main.Table != "main" || main.Source != "203.0.113.20" {
+		t.Fatalf("expected main-table route, got %#v", main)
+	}
+
+	unreachable := parseRouteGet("unreachable 10.9.9.9 table 201 mark 0x169\n", "")
+	if unreachable.Type != "unreachable" || unreachable.Interface != "" {
+		t.Fatalf("expected unreachable route, got %#v", unreachable)
+	}
+}
+
+func TestRouteGetArgs(t *testing.T) {
+	src := netip.MustParseAddr("192.168.1.10")
+	dst := netip.MustParseAddr("1.1.1.1")
+	want := []string{"route", "get", "1.1.1.1", "from", "192.168.1.10", "iif", "br0", "mark", "0x169"}
+	if got := routeGetArgs(src, dst, "br0", "0x169"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected args %v", got)
+	}
+	got := routeGetArgs(netip.MustParseAddr("fd00::10"), netip.MustParseAddr("2606:4700::1111"), "", "0")
+	want = []string{"-6", "route", "get", "2606:4700::1111", "mark", "0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected v6 args %v", got)
+	}
+}
+
+func TestOrderRouteTraceMatchesFollowsGroupOrder(t *testing.T) {
+	groups := []routing.DomainGroup{{Name: "Streaming"}, {Name: "Gaming"}}
+	matches := []routeTraceBinding{
+		{VPN: "sgp", Group: "Gaming", Rule: 1},
+		{VPN: "nl", Group: "Streaming", Rule: 2},
+		{VPN: "nl", Group: "Streaming", Rule: 1},
+	}
+	orderRouteTraceMatches(matches, groups)
+	if matches[0].Group != "Streaming" || matches[0].Rule != 1 || matches[2].Group != "Gaming" {
+		t.Fatalf("unexpected order %#v", matches)
+	}
+}
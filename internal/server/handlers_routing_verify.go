@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/egresscheck"
+	"split-vpn-webui/internal/routing"
+)
+
+const (
+	// egressCheckTimeout bounds a whole verification; each fetch has its
+	// own shorter timeout.
+	egressCheckTimeout  = 60 * time.Second
+	defaultVerifySample = 5
+	maxVerifySample     = 20
+)
+
+type egressCheckRunner interface {
+	Run(ctx context.Context, opts egresscheck.Options) (egresscheck.Result, error)
+}
+
+// handleVerifyGroup fetches a sample of the group's domains through its VPN
+// and through the WAN and reports reachability and geo blocks on each path:
+// POST /api/routing/groups/{id}/verify with an optional {"sample": N}.
+func (s *Server) handleVerifyGroup(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil || s.vpnManager == nil || s.egressCheck == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "egress check unavailable"})
+		return
+	}
+	id, err := parseGroupID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var payload struct {
+		Sample int `json:"sample"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	sample := payload.Sample
+	if sample <= 0 {
+		sample = defaultVerifySample
+	}
+	if sample > maxVerifySample {
+		sample = maxVerifySample
+	}
+
+	group, err := s.routingManager.GetGroup(r.Context(), id)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	domains := sampleGroupDomains(*group, sample)
+	if len(domains) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "group has no domains to verify"})
+		return
+	}
+	profile, err := s.vpnManager.Get(group.EgressVPN)
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	iface := strings.TrimSpace(profile.InterfaceName)
+	if iface == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "vpn has no interface"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), egressCheckTimeout)
+	defer cancel()
+	result, err := s.egressCheck.Run(ctx, egresscheck.Options{
+		VPN:       group.EgressVPN,
+		Interface: iface,
+		Resolvers: profile.DNSServers(),
+		Domains:   domains,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("egress check group=%s vpn=%s iface=%s sites=%d", group.Name, group.EgressVPN, iface, len(result.Sites))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"group": group.Name, "result": result})
+}
+
+// sampleGroupDomains picks up to limit distinct domains from the group's
+// rules in rule order. Wildcards contribute their base domain.
+func sampleGroupDomains(group routing.DomainGroup, limit int) []string {
+	seen := make(map[string]struct{})
+	out := make([]string, 0, limit)
+	add := func(domain string) {
+		domain = strings.TrimPrefix(strings.TrimSpace(domain), "*.")
+		if domain == "" || len(out) >= limit {
+			return
+		}
+		if _, ok := seen[domain]; ok {
+			return
+		}
+		seen[domain] = struct{}{}
+		out = append(out, domain)
+	}
+	for _, rule := range group.Rules {
+		for _, domain := range rule.Domains {
+			add(domain)
+		}
+		for _, wildcard := range rule.WildcardDomains {
+			add(wildcard)
+		}
+	}
+	return out
+}
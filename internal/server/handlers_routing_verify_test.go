@@ -0,0 +1,21 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/routing"
+)
+
+func TestSampleGroupDomains(t *testing.T) {
+	group := routing.DomainGroup{Rules: []routing.RoutingRule{
+		{Domains: []string{"netflix.com", "nflxvideo.net"}, WildcardDomains: []string{"*.netflix.com"}},
+		{Domains: []string{"hulu.com"}, WildcardDomains: []string{"*.disneyplus.com"}},
+	}}
+	if got := strings.Join(sampleGroupDomains(group, 10), ","); got != "netflix.com,nflxvideo.net,hulu.com,disneyplus.com" {
+		t.Fatalf("unexpected sample: %s", got)
+	}
+	if got := strings.Join(sampleGroupDomains(group, 2), ","); got != "netflix.com,nflxvideo.net" {
+		t.Fatalf("unexpected limited sample: %s", got)
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"time"
+
+	"split-vpn-webui/internal/routing"
+)
+
+// configureRuleExpiryWatcher logs expired rules and announces them on the
+// SSE stream so the group list refreshes.
+func (s *Server) configureRuleExpiryWatcher(watcher *routing.RuleExpiryWatcher) {
+	s.ruleExpiry = watcher
+	watcher.SetHandler(func(expired []routing.ExpiredRule, err error) {
+		if s.diagLog != nil {
+			for _, rule := range expired {
+				s.diagLog.Infof(
+					"routing rule expired group=%s rule=%q expired_at=%s",
+					rule.Group,
+					rule.RuleName,
+					time.Unix(rule.ExpiresAt, 0).UTC().Format(time.RFC3339),
+				)
+			}
+			if err != nil {
+				s.diagLog.Errorf("apply after rule expiry failed: %v", err)
+			}
+		}
+		if len(expired) > 0 {
+			s.broadcastEvent("rule-expiry", expired)
+		}
+	})
+}
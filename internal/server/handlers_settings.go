@@ -2,14 +2,12 @@ package server
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
-	"os/exec"
 	"strings"
 	"time"
 
-	"split-vpn-webui/internal/prewarm"
 	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/settingsrevisions"
 	"split-vpn-webui/internal/util"
 )
 
@@ -23,10 +21,31 @@ func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		interfaces = nil
 	}
-	// Scrub auth fields — never expose hash or token via settings API.
-	safe := settings.Settings{
+	safe := publicSettings(current)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"settings":   safe,
+		"interfaces": interfaces,
+		// The AbuseIPDB key is a credential; only report whether one is set.
+		"reputationAbuseIpdbKeyConfigured": strings.TrimSpace(current.ReputationAbuseIPDBKey) != "",
+		"unifiControllerApiKeyConfigured":  strings.TrimSpace(current.UniFiControllerAPIKey) != "",
+		"adguardPasswordConfigured":        current.AdGuardPassword != "",
+		"syncTokenConfigured":              strings.TrimSpace(current.SyncToken) != "",
+		"mqttPasswordConfigured":           current.MQTTPassword != "",
+		"metricsExportTokenConfigured":     strings.TrimSpace(current.MetricsExportToken) != "",
+		"logShipTokenConfigured":           strings.TrimSpace(current.LogShipToken) != "",
+	})
+}
+
+// publicSettings scrubs credentials — the auth hash and token and API keys
+// — so settings can be returned by the API or shared in a bug report.
+func publicSettings(current settings.Settings) settings.Settings {
+	return settings.Settings{
 		ListenInterface:                current.ListenInterface,
 		WANInterface:                   current.WANInterface,
+		WANPriority:                    current.WANPriority,
+		StatsPollSeconds:               current.StatsPollSeconds,
+		StatsDisabledInterfaces:        current.StatsDisabledInterfaces,
+		LatencyIntervalSeconds:         current.LatencyIntervalSeconds,
 		PrewarmParallelism:             current.PrewarmParallelism,
 		PrewarmDoHTimeoutSeconds:       current.PrewarmDoHTimeoutSeconds,
 		PrewarmQueryAttempts:           current.PrewarmQueryAttempts,
@@ -42,96 +61,114 @@ func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 		ResolverDomainEnabled:          current.ResolverDomainEnabled,
 		ResolverASNEnabled:             current.ResolverASNEnabled,
 		ResolverWildcardEnabled:        current.ResolverWildcardEnabled,
+		ResolverDomainRatePerMinute:    current.ResolverDomainRatePerMinute,
+		ResolverASNRatePerMinute:       current.ResolverASNRatePerMinute,
+		ResolverWildcardRatePerMinute:  current.ResolverWildcardRatePerMinute,
+		ResolverEgress:                 current.ResolverEgress,
 		DebugLogEnabled:                current.DebugLogEnabled,
 		DebugLogLevel:                  current.DebugLogLevel,
+		PublicStatusEnabled:            current.PublicStatusEnabled,
+		KioskListen:                    current.KioskListen,
+		ReputationEnabled:              current.ReputationEnabled,
+		ReputationSpamhausEnabled:      current.ReputationSpamhausEnabled,
+		ReputationAbuseIPDBMinScore:    current.ReputationAbuseIPDBMinScore,
+		ConntrackAccountingEnabled:     current.ConntrackAccountingEnabled,
+		DriftMode:                      current.DriftMode,
+		DriftIntervalSeconds:           current.DriftIntervalSeconds,
+		AnomalyHighMbps:                current.AnomalyHighMbps,
+		AnomalyHighMinutes:             current.AnomalyHighMinutes,
+		AnomalyStallMinutes:            current.AnomalyStallMinutes,
+		UnblockCheckIntervalHours:      current.UnblockCheckIntervalHours,
+		ProvisionWatchEnabled:          current.ProvisionWatchEnabled,
+		DNSBackend:                     current.DNSBackend,
+		DNSBackendConfigPath:           current.DNSBackendConfigPath,
+		AdGuardURL:                     current.AdGuardURL,
+		AdGuardUsername:                current.AdGuardUsername,
+		UniFiControllerURL:             current.UniFiControllerURL,
+		UniFiControllerSite:            current.UniFiControllerSite,
+		HostnameDiscoveryEnabled:       current.HostnameDiscoveryEnabled,
+		StatsRetentionDays:             current.StatsRetentionDays,
+		RunRetentionDays:               current.RunRetentionDays,
+		EventRetentionDays:             current.EventRetentionDays,
+		TrashRetentionDays:             current.TrashRetentionDays,
+		SyncRole:                       current.SyncRole,
+		SyncLeaderURL:                  current.SyncLeaderURL,
+		SyncLeaderFingerprint:          current.SyncLeaderFingerprint,
+		SyncIntervalSeconds:            current.SyncIntervalSeconds,
+		MQTTBrokerURL:                  current.MQTTBrokerURL,
+		MQTTUsername:                   current.MQTTUsername,
+		MQTTTopicPrefix:                current.MQTTTopicPrefix,
+		MQTTDiscoveryPrefix:            current.MQTTDiscoveryPrefix,
+		MQTTIntervalSeconds:            current.MQTTIntervalSeconds,
+		MetricsExportURL:               current.MetricsExportURL,
+		MetricsExportFormat:            current.MetricsExportFormat,
+		MetricsExportUsername:          current.MetricsExportUsername,
+		MetricsExportIntervalSeconds:   current.MetricsExportIntervalSeconds,
+		LogShipURL:                     current.LogShipURL,
+		LogShipMinLevel:                current.LogShipMinLevel,
+		LogShipUsername:                current.LogShipUsername,
+		IPFIXCollector:                 current.IPFIXCollector,
+		IPFIXObservationDomain:         current.IPFIXObservationDomain,
+		UpdateChannel:                  current.UpdateChannel,
+		UpdateBackupEnabled:            current.UpdateBackupEnabled,
+		AutoUpdateEnabled:              current.AutoUpdateEnabled,
+		AutoUpdateSchedule:             current.AutoUpdateSchedule,
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
-		"settings":   safe,
-		"interfaces": interfaces,
-	})
+}
+
+// settingsPayload is the public, user-editable part of the settings. Each
+// area decodes into its own embedded struct and applies its own fields.
+type settingsPayload struct {
+	generalSettingsPayload
+	routingSettingsPayload
+	integrationSettingsPayload
 }
 
 func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
-	// Decode only the public, user-editable fields.
-	var payload struct {
-		ListenInterface                string `json:"listenInterface"`
-		WANInterface                   string `json:"wanInterface"`
-		PrewarmParallelism             int    `json:"prewarmParallelism"`
-		PrewarmDoHTimeoutSeconds       int    `json:"prewarmDoHTimeoutSeconds"`
-		PrewarmQueryAttempts           int    `json:"prewarmQueryAttempts"`
-		PrewarmIntervalSeconds         int    `json:"prewarmIntervalSeconds"`
-		PrewarmExtraNameservers        string `json:"prewarmExtraNameservers"`
-		PrewarmECSProfiles             string `json:"prewarmEcsProfiles"`
-		ResolverParallelism            int    `json:"resolverParallelism"`
-		ResolverTimeoutSeconds         int    `json:"resolverTimeoutSeconds"`
-		ResolverIntervalSeconds        int    `json:"resolverIntervalSeconds"`
-		ResolverDomainTimeoutSeconds   int    `json:"resolverDomainTimeoutSeconds"`
-		ResolverASNTimeoutSeconds      int    `json:"resolverAsnTimeoutSeconds"`
-		ResolverWildcardTimeoutSeconds int    `json:"resolverWildcardTimeoutSeconds"`
-		ResolverDomainEnabled          *bool  `json:"resolverDomainEnabled"`
-		ResolverASNEnabled             *bool  `json:"resolverAsnEnabled"`
-		ResolverWildcardEnabled        *bool  `json:"resolverWildcardEnabled"`
-		DebugLogEnabled                *bool  `json:"debugLogEnabled"`
-		DebugLogLevel                  string `json:"debugLogLevel"`
-	}
+	var payload settingsPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
 		return
 	}
-	normalizedNameservers := prewarm.NormalizeMultilineSetting(payload.PrewarmExtraNameservers)
-	normalizedECSProfiles := prewarm.NormalizeMultilineSetting(payload.PrewarmECSProfiles)
-	if _, err := prewarm.ParseNameserverLines(normalizedNameservers); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-	if _, err := prewarm.ParseECSProfiles(normalizedECSProfiles); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-
 	current, err := s.settings.Get()
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
+	// Every invalid field is collected so the UI can mark them all at once.
+	var errs settings.ValidationError
+
 	// Preserve auth fields when saving; only update network fields.
 	updated := current
-	updated.ListenInterface = payload.ListenInterface
-	updated.WANInterface = payload.WANInterface
-	updated.PrewarmParallelism = payload.PrewarmParallelism
-	updated.PrewarmDoHTimeoutSeconds = payload.PrewarmDoHTimeoutSeconds
-	updated.PrewarmQueryAttempts = payload.PrewarmQueryAttempts
-	updated.PrewarmIntervalSeconds = payload.PrewarmIntervalSeconds
-	updated.PrewarmExtraNameservers = normalizedNameservers
-	updated.PrewarmECSProfiles = normalizedECSProfiles
-	updated.ResolverParallelism = payload.ResolverParallelism
-	updated.ResolverTimeoutSeconds = payload.ResolverTimeoutSeconds
-	updated.ResolverIntervalSeconds = payload.ResolverIntervalSeconds
-	updated.ResolverDomainTimeoutSeconds = payload.ResolverDomainTimeoutSeconds
-	updated.ResolverASNTimeoutSeconds = payload.ResolverASNTimeoutSeconds
-	updated.ResolverWildcardTimeoutSeconds = payload.ResolverWildcardTimeoutSeconds
-	updated.ResolverDomainEnabled = payload.ResolverDomainEnabled
-	updated.ResolverASNEnabled = payload.ResolverASNEnabled
-	updated.ResolverWildcardEnabled = payload.ResolverWildcardEnabled
-	if payload.DebugLogEnabled != nil {
-		updated.DebugLogEnabled = payload.DebugLogEnabled
-	}
-	if payload.DebugLogLevel != "" {
-		updated.DebugLogLevel = strings.ToLower(strings.TrimSpace(payload.DebugLogLevel))
+	payload.generalSettingsPayload.apply(&updated, &errs)
+	payload.routingSettingsPayload.apply(&updated, &errs)
+	payload.integrationSettingsPayload.apply(&updated, &errs)
+	validateSettingsInterfaces(&errs, current, updated)
+	s.validateResolverEgress(&errs, current, updated)
+	if err := errs.Err(); err != nil {
+		writeSettingsValidationError(w, err)
+		return
 	}
 
 	if err := s.settings.Save(updated); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
+	s.recordSettingsRevision(r, settingsrevisions.ActionSave, &current)
+	s.applySavedSettings(w, current, updated)
+}
+
+// applySavedSettings brings the running service in line with settings that
+// were just saved and writes the reload result.
+func (s *Server) applySavedSettings(w http.ResponseWriter, current, updated settings.Settings) {
 	if s.diagLog != nil {
 		enabled := false
 		if updated.DebugLogEnabled != nil {
 			enabled = *updated.DebugLogEnabled
 		}
 		if err := s.diagLog.Configure(enabled, updated.DebugLogLevel); err != nil {
-			log.Printf("diagnostics logging configure warning: %v", err)
+			s.diagLog.Warnf("diagnostics logging configure failed: %v", err)
 		}
 	}
 	if err := s.refreshState(); err != nil {
@@ -139,11 +176,13 @@ func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.broadcastUpdate(nil)
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 
-	changed := current.ListenInterface != updated.ListenInterface ||
-		current.WANInterface != updated.WANInterface
-	if s.systemdManaged && changed {
+	result := s.applyRuntimeSettings(current, updated)
+	if result.RestartRequired && s.systemdManaged {
+		result.Restarting = true
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "reload": result})
+	if result.Restarting {
 		s.scheduleRestart()
 	}
 }
@@ -157,14 +196,22 @@ func (s *Server) handleSystemRestart(w http.ResponseWriter, r *http.Request) {
 	s.scheduleRestart()
 }
 
+// scheduleRestart restarts the service through the systemd manager, so an
+// unprivileged web UI asks the privilege helper instead of running systemctl.
 func (s *Server) scheduleRestart() {
+	if s.systemd == nil {
+		return
+	}
 	go func() {
 		time.Sleep(500 * time.Millisecond)
-		cmd := exec.Command("systemctl", "restart", "split-vpn-webui.service")
-		if err := cmd.Run(); err != nil {
-			log.Printf("systemd restart failed: %v", err)
+		if err := s.systemd.Restart("split-vpn-webui.service"); err != nil {
+			if s.diagLog != nil {
+				s.diagLog.Errorf("systemd restart failed: %v", err)
+			}
 			return
 		}
-		log.Printf("requested systemd restart of split-vpn-webui.service")
+		if s.diagLog != nil {
+			s.diagLog.Infof("systemd restart requested unit=split-vpn-webui.service")
+		}
 	}()
 }
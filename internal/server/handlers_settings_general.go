@@ -0,0 +1,130 @@
+package server
+
+import (
+	"strings"
+
+	"split-vpn-webui/internal/dbmaint"
+	"split-vpn-webui/internal/listen"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/stats"
+	"split-vpn-webui/internal/update"
+	"split-vpn-webui/internal/wan"
+)
+
+// generalSettingsPayload covers listening, the WAN, runtime intervals,
+// logging, retention and updates.
+type generalSettingsPayload struct {
+	ListenInterface         string  `json:"listenInterface"`
+	WANInterface            string  `json:"wanInterface"`
+	WANPriority             *string `json:"wanPriority"`
+	StatsPollSeconds        *int    `json:"statsPollSeconds"`
+	StatsDisabledInterfaces *string `json:"statsDisabledInterfaces"`
+	LatencyIntervalSeconds  *int    `json:"latencyIntervalSeconds"`
+	DebugLogEnabled         *bool   `json:"debugLogEnabled"`
+	DebugLogLevel           string  `json:"debugLogLevel"`
+	PublicStatusEnabled     *bool   `json:"publicStatusEnabled"`
+	KioskListen             *string `json:"kioskListen"`
+	StatsRetentionDays      *int    `json:"statsRetentionDays"`
+	RunRetentionDays        *int    `json:"runRetentionDays"`
+	EventRetentionDays      *int    `json:"eventRetentionDays"`
+	TrashRetentionDays      *int    `json:"trashRetentionDays"`
+	UpdateChannel           *string `json:"updateChannel"`
+	UpdateBackupEnabled     *bool   `json:"updateBackupEnabled"`
+	AutoUpdateEnabled       *bool   `json:"autoUpdateEnabled"`
+	AutoUpdateSchedule      *string `json:"autoUpdateSchedule"`
+}
+
+func (p generalSettingsPayload) apply(updated *settings.Settings, errs *settings.ValidationError) {
+	if listenSpec, err := listen.NormalizeSpec(p.ListenInterface); err != nil {
+		errs.Add("listenInterface", err)
+	} else {
+		updated.ListenInterface = listenSpec
+	}
+	updated.WANInterface = strings.TrimSpace(p.WANInterface)
+	if p.DebugLogEnabled != nil {
+		updated.DebugLogEnabled = p.DebugLogEnabled
+	}
+	if p.DebugLogLevel != "" {
+		updated.DebugLogLevel = strings.ToLower(strings.TrimSpace(p.DebugLogLevel))
+	}
+	if p.PublicStatusEnabled != nil {
+		updated.PublicStatusEnabled = p.PublicStatusEnabled
+	}
+	if p.KioskListen != nil {
+		if kioskSpec, err := listen.NormalizeSpec(*p.KioskListen); err != nil {
+			errs.Add("kioskListen", err)
+		} else {
+			updated.KioskListen = kioskSpec
+		}
+	}
+	for _, retention := range []struct {
+		key    string
+		value  *int
+		target *int
+	}{
+		{"statsRetentionDays", p.StatsRetentionDays, &updated.StatsRetentionDays},
+		{"runRetentionDays", p.RunRetentionDays, &updated.RunRetentionDays},
+		{"eventRetentionDays", p.EventRetentionDays, &updated.EventRetentionDays},
+		{"trashRetentionDays", p.TrashRetentionDays, &updated.TrashRetentionDays},
+	} {
+		if retention.value == nil {
+			continue
+		}
+		if *retention.value < 0 || *retention.value > dbmaint.MaxRetentionDays {
+			errs.Addf(retention.key, "%s must be between 0 and %d", retention.key, dbmaint.MaxRetentionDays)
+			continue
+		}
+		*retention.target = *retention.value
+	}
+	for _, interval := range []struct {
+		key    string
+		value  *int
+		target *int
+	}{
+		{"statsPollSeconds", p.StatsPollSeconds, &updated.StatsPollSeconds},
+		{"latencyIntervalSeconds", p.LatencyIntervalSeconds, &updated.LatencyIntervalSeconds},
+	} {
+		if interval.value == nil {
+			continue
+		}
+		if *interval.value < 0 || *interval.value > maxRuntimeIntervalSeconds {
+			errs.Addf(interval.key, "%s must be between 0 and %d", interval.key, maxRuntimeIntervalSeconds)
+			continue
+		}
+		*interval.target = *interval.value
+	}
+	if p.UpdateChannel != nil {
+		if channel, err := update.ParseChannel(*p.UpdateChannel); err != nil {
+			errs.Add("updateChannel", err)
+		} else {
+			updated.UpdateChannel = channel
+		}
+	}
+	if p.UpdateBackupEnabled != nil {
+		updated.UpdateBackupEnabled = p.UpdateBackupEnabled
+	}
+	if p.AutoUpdateEnabled != nil {
+		updated.AutoUpdateEnabled = p.AutoUpdateEnabled
+	}
+	if p.AutoUpdateSchedule != nil {
+		if schedule, err := update.ParseSchedule(*p.AutoUpdateSchedule); err != nil {
+			errs.Addf("autoUpdateSchedule", "autoUpdateSchedule: %v", err)
+		} else {
+			updated.AutoUpdateSchedule = schedule.String()
+		}
+	}
+	if p.WANPriority != nil {
+		if priority, err := wan.NormalizePriority(*p.WANPriority); err != nil {
+			errs.Add("wanPriority", err)
+		} else {
+			updated.WANPriority = priority
+		}
+	}
+	if p.StatsDisabledInterfaces != nil {
+		if disabled, err := stats.NormalizeInterfaceList(*p.StatsDisabledInterfaces); err != nil {
+			errs.Addf("statsDisabledInterfaces", "statsDisabledInterfaces: %v", err)
+		} else {
+			updated.StatsDisabledInterfaces = disabled
+		}
+	}
+}
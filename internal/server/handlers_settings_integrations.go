@@ -0,0 +1,116 @@
+package server
+
+import (
+	"strings"
+
+	"split-vpn-webui/internal/ipfix"
+	"split-vpn-webui/internal/logship"
+	"split-vpn-webui/internal/metricsexport"
+	"split-vpn-webui/internal/mqtt"
+	"split-vpn-webui/internal/peersync"
+	"split-vpn-webui/internal/settings"
+)
+
+// integrationSettingsPayload covers peer sync and the MQTT, metrics,
+// log shipping and IPFIX exporters.
+type integrationSettingsPayload struct {
+	SyncRole                     *string `json:"syncRole"`
+	SyncLeaderURL                *string `json:"syncLeaderUrl"`
+	SyncLeaderFingerprint        *string `json:"syncLeaderFingerprint"`
+	SyncToken                    *string `json:"syncToken"`
+	SyncIntervalSeconds          *int    `json:"syncIntervalSeconds"`
+	MQTTBrokerURL                *string `json:"mqttBrokerUrl"`
+	MQTTUsername                 *string `json:"mqttUsername"`
+	MQTTPassword                 *string `json:"mqttPassword"`
+	MQTTTopicPrefix              *string `json:"mqttTopicPrefix"`
+	MQTTDiscoveryPrefix          *string `json:"mqttDiscoveryPrefix"`
+	MQTTIntervalSeconds          *int    `json:"mqttIntervalSeconds"`
+	MetricsExportURL             *string `json:"metricsExportUrl"`
+	MetricsExportFormat          *string `json:"metricsExportFormat"`
+	MetricsExportUsername        *string `json:"metricsExportUsername"`
+	MetricsExportToken           *string `json:"metricsExportToken"`
+	MetricsExportIntervalSeconds *int    `json:"metricsExportIntervalSeconds"`
+	LogShipURL                   *string `json:"logShipUrl"`
+	LogShipMinLevel              *string `json:"logShipMinLevel"`
+	LogShipUsername              *string `json:"logShipUsername"`
+	LogShipToken                 *string `json:"logShipToken"`
+	IPFIXCollector               *string `json:"ipfixCollector"`
+	IPFIXObservationDomain       *int    `json:"ipfixObservationDomain"`
+}
+
+func (p integrationSettingsPayload) apply(updated *settings.Settings, errs *settings.ValidationError) {
+	if p.SyncRole != nil {
+		updated.SyncRole = strings.ToLower(strings.TrimSpace(*p.SyncRole))
+	}
+	if p.SyncLeaderURL != nil {
+		updated.SyncLeaderURL = strings.TrimSpace(*p.SyncLeaderURL)
+	}
+	if p.SyncLeaderFingerprint != nil {
+		updated.SyncLeaderFingerprint = *p.SyncLeaderFingerprint
+		if fingerprint, err := peersync.NormalizeFingerprint(updated.SyncLeaderFingerprint); err == nil {
+			updated.SyncLeaderFingerprint = fingerprint
+		}
+	}
+	if p.SyncToken != nil {
+		updated.SyncToken = strings.TrimSpace(*p.SyncToken)
+	}
+	if p.SyncIntervalSeconds != nil {
+		updated.SyncIntervalSeconds = *p.SyncIntervalSeconds
+	}
+	errs.Add("sync", peersync.ValidateSettings(*updated))
+	if p.MQTTBrokerURL != nil {
+		updated.MQTTBrokerURL = strings.TrimSpace(*p.MQTTBrokerURL)
+	}
+	if p.MQTTUsername != nil {
+		updated.MQTTUsername = strings.TrimSpace(*p.MQTTUsername)
+	}
+	if p.MQTTPassword != nil {
+		updated.MQTTPassword = *p.MQTTPassword
+	}
+	if p.MQTTTopicPrefix != nil {
+		updated.MQTTTopicPrefix = strings.Trim(strings.TrimSpace(*p.MQTTTopicPrefix), "/")
+	}
+	if p.MQTTDiscoveryPrefix != nil {
+		updated.MQTTDiscoveryPrefix = strings.Trim(strings.TrimSpace(*p.MQTTDiscoveryPrefix), "/")
+	}
+	if p.MQTTIntervalSeconds != nil {
+		updated.MQTTIntervalSeconds = *p.MQTTIntervalSeconds
+	}
+	errs.Add("mqtt", mqtt.ValidateSettings(*updated))
+	if p.MetricsExportURL != nil {
+		updated.MetricsExportURL = strings.TrimSpace(*p.MetricsExportURL)
+	}
+	if p.MetricsExportFormat != nil {
+		updated.MetricsExportFormat = strings.ToLower(strings.TrimSpace(*p.MetricsExportFormat))
+	}
+	if p.MetricsExportUsername != nil {
+		updated.MetricsExportUsername = strings.TrimSpace(*p.MetricsExportUsername)
+	}
+	if p.MetricsExportToken != nil {
+		updated.MetricsExportToken = strings.TrimSpace(*p.MetricsExportToken)
+	}
+	if p.MetricsExportIntervalSeconds != nil {
+		updated.MetricsExportIntervalSeconds = *p.MetricsExportIntervalSeconds
+	}
+	errs.Add("metricsExport", metricsexport.ValidateSettings(*updated))
+	if p.LogShipURL != nil {
+		updated.LogShipURL = strings.TrimSpace(*p.LogShipURL)
+	}
+	if p.LogShipMinLevel != nil {
+		updated.LogShipMinLevel = strings.ToLower(strings.TrimSpace(*p.LogShipMinLevel))
+	}
+	if p.LogShipUsername != nil {
+		updated.LogShipUsername = strings.TrimSpace(*p.LogShipUsername)
+	}
+	if p.LogShipToken != nil {
+		updated.LogShipToken = strings.TrimSpace(*p.LogShipToken)
+	}
+	errs.Add("logShip", logship.ValidateSettings(*updated))
+	if p.IPFIXCollector != nil {
+		updated.IPFIXCollector = strings.TrimSpace(*p.IPFIXCollector)
+	}
+	if p.IPFIXObservationDomain != nil {
+		updated.IPFIXObservationDomain = *p.IPFIXObservationDomain
+	}
+	errs.Add("ipfix", ipfix.ValidateSettings(*updated))
+}
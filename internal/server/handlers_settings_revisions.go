@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/auth"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/settingsrevisions"
+)
+
+// recordSettingsRevision adds the saved settings to the history, first
+// keeping before as a baseline when there is no history yet. Failures only
+// reach the diagnostics log; the change already succeeded.
+func (s *Server) recordSettingsRevision(r *http.Request, action string, before *settings.Settings) {
+	if s.settingsRevs == nil || s.settings == nil {
+		return
+	}
+	ctx := r.Context()
+	if before != nil {
+		if err := s.settingsRevs.EnsureBaseline(ctx, *before); err != nil && s.diagLog != nil {
+			s.diagLog.Warnf("record settings baseline failed: %v", err)
+		}
+	}
+	after, err := s.settings.Get()
+	if err == nil {
+		_, _, err = s.settingsRevs.Record(ctx, settingsrevisions.Revision{
+			Action:   action,
+			Actor:    auth.Actor(r),
+			Settings: after,
+		})
+	}
+	if err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("record settings revision action=%s failed: %v", action, err)
+	}
+}
+
+// currentSettings returns the settings before a change, or nil.
+func (s *Server) currentSettings() *settings.Settings {
+	if s.settingsRevs == nil || s.settings == nil {
+		return nil
+	}
+	current, err := s.settings.Get()
+	if err != nil {
+		return nil
+	}
+	return &current
+}
+
+func (s *Server) handleListSettingsRevisions(w http.ResponseWriter, r *http.Request) {
+	if s.settingsRevs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "settings revision store unavailable"})
+		return
+	}
+	limit, ok := parseNonNegativeQuery(w, r, "limit", settingsrevisions.DefaultPageSize)
+	if !ok {
+		return
+	}
+	offset, ok := parseNonNegativeQuery(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	page, err := s.settingsRevs.List(r.Context(), limit, offset)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleGetSettingsRevision returns a revision with its credentials
+// scrubbed, and the fields a rollback to it would change.
+func (s *Server) handleGetSettingsRevision(w http.ResponseWriter, r *http.Request) {
+	if s.settingsRevs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "settings revision store unavailable"})
+		return
+	}
+	id, err := parseRevisionID(chi.URLParam(r, "revision"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	revision, err := s.settingsRevs.Get(r.Context(), id)
+	if err != nil {
+		writeSettingsRevisionError(w, err)
+		return
+	}
+	current, err := s.settings.Get()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	changes, err := settingsrevisions.ChangedFields(current, settingsForRollback(current, revision.Settings))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"revision": revision,
+		"settings": publicSettings(revision.Settings),
+		"changes":  changes,
+	})
+}
+
+// handleRollbackSettingsRevision saves a previous revision as the current
+// settings and applies it like a regular save.
+func (s *Server) handleRollbackSettingsRevision(w http.ResponseWriter, r *http.Request) {
+	if s.settingsRevs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "settings revision store unavailable"})
+		return
+	}
+	id, err := parseRevisionID(chi.URLParam(r, "revision"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	revision, err := s.settingsRevs.Get(r.Context(), id)
+	if err != nil {
+		writeSettingsRevisionError(w, err)
+		return
+	}
+	current, err := s.settings.Get()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	restored := settingsForRollback(current, revision.Settings)
+	if err := s.settings.Save(restored); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.recordSettingsRevision(r, settingsrevisions.ActionRollback, &current)
+	if s.diagLog != nil {
+		s.diagLog.Infof("settings rollback revision=%d actor=%s", id, auth.Actor(r))
+	}
+	s.applySavedSettings(w, current, restored)
+}
+
+// settingsForRollback returns target with the current API and Home
+// Assistant tokens. Rotating a token revokes the old one, and a rollback
+// must not bring it back or sign out the caller.
+func settingsForRollback(current, target settings.Settings) settings.Settings {
+	target.AuthToken = current.AuthToken
+	target.HomeAssistantToken = current.HomeAssistantToken
+	return target
+}
+
+func writeSettingsRevisionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, settingsrevisions.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/config"
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/latency"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/settingsrevisions"
+	"split-vpn-webui/internal/stats"
+)
+
+func TestSettingsRollbackRestoresRevisionAndKeepsTokens(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.Open(filepath.Join(dir, "app.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := settingsrevisions.NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	manager := settings.NewManager(filepath.Join(dir, "settings.json"))
+	if err := manager.Save(settings.Settings{StatsPollSeconds: 5, AuthPasswordHash: "old-hash", AuthToken: "old-token"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	s := &Server{
+		settings:      manager,
+		settingsRevs:  store,
+		configManager: config.NewManager(dir),
+		stats:         stats.NewCollector("eth0", time.Second, 10),
+		latency:       latency.NewMonitor(time.Second),
+		watchers:      make(map[chan streamMessage]struct{}),
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handleSaveSettings(recorder, httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"statsPollSeconds": 30}`)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("save: %d %s", recorder.Code, recorder.Body.String())
+	}
+	current, _ := manager.Get()
+	current.AuthPasswordHash = "new-hash"
+	current.AuthToken = "new-token"
+	if err := manager.Save(current); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	recorder = httptest.NewRecorder()
+	s.handleListSettingsRevisions(recorder, httptest.NewRequest(http.MethodGet, "/api/settings/revisions", nil))
+	var page settingsrevisions.Page
+	if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if page.Total != 2 || page.Revisions[0].Action != settingsrevisions.ActionSave || page.Revisions[1].Action != settingsrevisions.ActionBaseline {
+		t.Fatalf("unexpected revisions: %+v", page)
+	}
+	if strings.Contains(recorder.Body.String(), "old-hash") {
+		t.Fatalf("revision list leaked credentials: %s", recorder.Body.String())
+	}
+	baselineID := strconv.FormatInt(page.Revisions[1].ID, 10)
+
+	request := func(method string) *http.Request {
+		req := httptest.NewRequest(method, "/api/settings/revisions/"+baselineID, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("revision", baselineID)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	recorder = httptest.NewRecorder()
+	s.handleGetSettingsRevision(recorder, request(http.MethodGet))
+	if recorder.Code != http.StatusOK || strings.Contains(recorder.Body.String(), "old-hash") {
+		t.Fatalf("get: %d %s", recorder.Code, recorder.Body.String())
+	}
+	var detail struct {
+		Changes []string `json:"changes"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("decode revision: %v", err)
+	}
+	if strings.Join(detail.Changes, ",") != "authPasswordHash,statsPollSeconds" {
+		t.Fatalf("expected the rollback changes to be listed, got %v", detail.Changes)
+	}
+
+	recorder = httptest.NewRecorder()
+	s.handleRollbackSettingsRevision(recorder, request(http.MethodPost))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", recorder.Code, recorder.Body.String())
+	}
+	restored, _ := manager.Get()
+	if restored.StatsPollSeconds != 5 || restored.AuthPasswordHash != "old-hash" || restored.AuthToken != "new-token" {
+		t.Fatalf("unexpected restored settings: %+v", restored)
+	}
+	latest, err := store.List(context.Background(), 1, 0)
+	if err != nil || latest.Revisions[0].Action != settingsrevisions.ActionRollback {
+		t.Fatalf("expected the rollback to be recorded, got %+v %v", latest, err)
+	}
+}
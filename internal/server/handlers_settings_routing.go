@@ -0,0 +1,193 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"split-vpn-webui/internal/anomaly"
+	"split-vpn-webui/internal/prewarm"
+	"split-vpn-webui/internal/routing"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/unblock"
+)
+
+// routingSettingsPayload covers pre-warming and resolving, reputation,
+// the routing monitors, the DNS backend and device discovery.
+type routingSettingsPayload struct {
+	PrewarmParallelism             int     `json:"prewarmParallelism"`
+	PrewarmDoHTimeoutSeconds       int     `json:"prewarmDoHTimeoutSeconds"`
+	PrewarmQueryAttempts           int     `json:"prewarmQueryAttempts"`
+	PrewarmIntervalSeconds         int     `json:"prewarmIntervalSeconds"`
+	PrewarmExtraNameservers        string  `json:"prewarmExtraNameservers"`
+	PrewarmECSProfiles             string  `json:"prewarmEcsProfiles"`
+	ResolverParallelism            int     `json:"resolverParallelism"`
+	ResolverTimeoutSeconds         int     `json:"resolverTimeoutSeconds"`
+	ResolverIntervalSeconds        int     `json:"resolverIntervalSeconds"`
+	ResolverDomainTimeoutSeconds   int     `json:"resolverDomainTimeoutSeconds"`
+	ResolverASNTimeoutSeconds      int     `json:"resolverAsnTimeoutSeconds"`
+	ResolverWildcardTimeoutSeconds int     `json:"resolverWildcardTimeoutSeconds"`
+	ResolverDomainEnabled          *bool   `json:"resolverDomainEnabled"`
+	ResolverASNEnabled             *bool   `json:"resolverAsnEnabled"`
+	ResolverWildcardEnabled        *bool   `json:"resolverWildcardEnabled"`
+	ResolverDomainRatePerMinute    *int    `json:"resolverDomainRatePerMinute"`
+	ResolverASNRatePerMinute       *int    `json:"resolverAsnRatePerMinute"`
+	ResolverWildcardRatePerMinute  *int    `json:"resolverWildcardRatePerMinute"`
+	ResolverEgress                 *string `json:"resolverEgress"`
+	ReputationEnabled              *bool   `json:"reputationEnabled"`
+	ReputationSpamhausEnabled      *bool   `json:"reputationSpamhausEnabled"`
+	ReputationAbuseIPDBKey         *string `json:"reputationAbuseIpdbKey"`
+	ReputationAbuseIPDBMinScore    *int    `json:"reputationAbuseIpdbMinScore"`
+	DriftMode                      *string `json:"driftMode"`
+	DriftIntervalSeconds           *int    `json:"driftIntervalSeconds"`
+	AnomalyHighMbps                *int    `json:"anomalyHighMbps"`
+	AnomalyHighMinutes             *int    `json:"anomalyHighMinutes"`
+	AnomalyStallMinutes            *int    `json:"anomalyStallMinutes"`
+	UnblockCheckIntervalHours      *int    `json:"unblockCheckIntervalHours"`
+	ProvisionWatchEnabled          *bool   `json:"provisionWatchEnabled"`
+	DNSBackend                     *string `json:"dnsBackend"`
+	DNSBackendConfigPath           *string `json:"dnsBackendConfigPath"`
+	AdGuardURL                     *string `json:"adguardUrl"`
+	AdGuardUsername                *string `json:"adguardUsername"`
+	AdGuardPassword                *string `json:"adguardPassword"`
+	UniFiControllerURL             *string `json:"unifiControllerUrl"`
+	UniFiControllerSite            *string `json:"unifiControllerSite"`
+	UniFiControllerAPIKey          *string `json:"unifiControllerApiKey"`
+	HostnameDiscoveryEnabled       *bool   `json:"hostnameDiscoveryEnabled"`
+}
+
+func (p routingSettingsPayload) apply(updated *settings.Settings, errs *settings.ValidationError) {
+	updated.PrewarmParallelism = p.PrewarmParallelism
+	updated.PrewarmDoHTimeoutSeconds = p.PrewarmDoHTimeoutSeconds
+	updated.PrewarmQueryAttempts = p.PrewarmQueryAttempts
+	updated.PrewarmIntervalSeconds = p.PrewarmIntervalSeconds
+	updated.PrewarmExtraNameservers = prewarm.NormalizeMultilineSetting(p.PrewarmExtraNameservers)
+	updated.PrewarmECSProfiles = prewarm.NormalizeMultilineSetting(p.PrewarmECSProfiles)
+	errs.Add("prewarm", prewarm.ValidateSettings(*updated))
+	updated.ResolverParallelism = p.ResolverParallelism
+	updated.ResolverTimeoutSeconds = p.ResolverTimeoutSeconds
+	updated.ResolverIntervalSeconds = p.ResolverIntervalSeconds
+	updated.ResolverDomainTimeoutSeconds = p.ResolverDomainTimeoutSeconds
+	updated.ResolverASNTimeoutSeconds = p.ResolverASNTimeoutSeconds
+	updated.ResolverWildcardTimeoutSeconds = p.ResolverWildcardTimeoutSeconds
+	updated.ResolverDomainEnabled = p.ResolverDomainEnabled
+	updated.ResolverASNEnabled = p.ResolverASNEnabled
+	updated.ResolverWildcardEnabled = p.ResolverWildcardEnabled
+	for _, rate := range []struct {
+		value  *int
+		target *int
+	}{
+		{p.ResolverDomainRatePerMinute, &updated.ResolverDomainRatePerMinute},
+		{p.ResolverASNRatePerMinute, &updated.ResolverASNRatePerMinute},
+		{p.ResolverWildcardRatePerMinute, &updated.ResolverWildcardRatePerMinute},
+	} {
+		if rate.value != nil {
+			*rate.target = *rate.value
+		}
+	}
+	if p.ResolverEgress != nil {
+		updated.ResolverEgress = strings.TrimSpace(*p.ResolverEgress)
+	}
+	errs.Add("resolver", routing.ValidateResolverSettings(*updated))
+	if p.ReputationEnabled != nil {
+		updated.ReputationEnabled = p.ReputationEnabled
+	}
+	if p.ReputationSpamhausEnabled != nil {
+		updated.ReputationSpamhausEnabled = p.ReputationSpamhausEnabled
+	}
+	if p.ReputationAbuseIPDBKey != nil {
+		updated.ReputationAbuseIPDBKey = strings.TrimSpace(*p.ReputationAbuseIPDBKey)
+	}
+	if p.ReputationAbuseIPDBMinScore != nil {
+		if *p.ReputationAbuseIPDBMinScore < 0 || *p.ReputationAbuseIPDBMinScore > 100 {
+			errs.Addf("reputationAbuseIpdbMinScore", "reputationAbuseIpdbMinScore must be between 0 and 100")
+		} else {
+			updated.ReputationAbuseIPDBMinScore = *p.ReputationAbuseIPDBMinScore
+		}
+	}
+	if p.DriftMode != nil {
+		if mode, err := routing.ParseDriftMode(*p.DriftMode); err != nil {
+			errs.Add("driftMode", err)
+		} else {
+			updated.DriftMode = mode
+		}
+	}
+	if p.DriftIntervalSeconds != nil {
+		if *p.DriftIntervalSeconds < 0 {
+			errs.Addf("driftIntervalSeconds", "driftIntervalSeconds must not be negative")
+		} else {
+			updated.DriftIntervalSeconds = *p.DriftIntervalSeconds
+		}
+	}
+	if p.AnomalyHighMbps != nil {
+		updated.AnomalyHighMbps = *p.AnomalyHighMbps
+	}
+	if p.AnomalyHighMinutes != nil {
+		updated.AnomalyHighMinutes = *p.AnomalyHighMinutes
+	}
+	if p.AnomalyStallMinutes != nil {
+		updated.AnomalyStallMinutes = *p.AnomalyStallMinutes
+	}
+	errs.Add("anomaly", anomaly.ValidateSettings(*updated))
+	if p.UnblockCheckIntervalHours != nil {
+		updated.UnblockCheckIntervalHours = *p.UnblockCheckIntervalHours
+	}
+	errs.Add("unblockCheckIntervalHours", unblock.ValidateSettings(*updated))
+	if p.ProvisionWatchEnabled != nil {
+		updated.ProvisionWatchEnabled = p.ProvisionWatchEnabled
+	}
+	if p.DNSBackend != nil {
+		if backend, err := routing.ParseDNSBackend(*p.DNSBackend); err != nil {
+			errs.Add("dnsBackend", err)
+		} else {
+			updated.DNSBackend = backend
+		}
+	}
+	if p.DNSBackendConfigPath != nil {
+		updated.DNSBackendConfigPath = strings.TrimSpace(*p.DNSBackendConfigPath)
+	}
+	if p.DNSBackend != nil || p.DNSBackendConfigPath != nil {
+		errs.Add("dnsBackendConfigPath", routing.ValidateDNSBackendConfigPath(updated.DNSBackend, updated.DNSBackendConfigPath))
+	}
+	if p.AdGuardURL != nil {
+		if err := validateAdGuardURL(*p.AdGuardURL); err != nil {
+			errs.Add("adguardUrl", err)
+		} else {
+			updated.AdGuardURL = strings.TrimSpace(*p.AdGuardURL)
+		}
+	}
+	if p.AdGuardUsername != nil {
+		updated.AdGuardUsername = strings.TrimSpace(*p.AdGuardUsername)
+	}
+	if p.AdGuardPassword != nil {
+		updated.AdGuardPassword = *p.AdGuardPassword
+	}
+	if p.UniFiControllerURL != nil {
+		if err := validateUniFiControllerURL(*p.UniFiControllerURL); err != nil {
+			errs.Add("unifiControllerUrl", err)
+		} else {
+			updated.UniFiControllerURL = strings.TrimSpace(*p.UniFiControllerURL)
+		}
+	}
+	if p.UniFiControllerSite != nil {
+		updated.UniFiControllerSite = strings.TrimSpace(*p.UniFiControllerSite)
+	}
+	if p.UniFiControllerAPIKey != nil {
+		updated.UniFiControllerAPIKey = strings.TrimSpace(*p.UniFiControllerAPIKey)
+	}
+	if p.HostnameDiscoveryEnabled != nil {
+		updated.HostnameDiscoveryEnabled = p.HostnameDiscoveryEnabled
+	}
+}
+
+func validateAdGuardURL(raw string) error {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return nil
+	}
+	parsed, err := url.Parse(trimmed)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("adguardUrl must be an http or https URL")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net"
+	"net/http"
+
+	"split-vpn-webui/internal/listen"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/wan"
+)
+
+// interfaceExists reports whether a network interface is present; tests
+// replace it.
+var interfaceExists = func(name string) bool {
+	_, err := net.InterfaceByName(name)
+	return err == nil
+}
+
+// validateSettingsInterfaces rejects interface names a save introduces that
+// do not exist. Names that are already saved are kept even while their
+// interface is gone, e.g. a VPN that is down.
+func validateSettingsInterfaces(errs *settings.ValidationError, current, updated settings.Settings) {
+	if name := updated.WANInterface; name != "" && name != current.WANInterface && !interfaceExists(name) {
+		errs.Addf("wanInterface", "wanInterface %q does not exist", name)
+	}
+	validateListenInterfaces(errs, "listenInterface", current.ListenInterface, updated.ListenInterface)
+	validateListenInterfaces(errs, "kioskListen", current.KioskListen, updated.KioskListen)
+	saved := make(map[string]bool)
+	for _, name := range wan.ParsePriority(current.WANPriority) {
+		saved[name] = true
+	}
+	for _, name := range wan.ParsePriority(updated.WANPriority) {
+		if !saved[name] && !interfaceExists(name) {
+			errs.Addf("wanPriority", "WAN interface %q does not exist", name)
+		}
+	}
+}
+
+// validateListenInterfaces rejects interfaces a listen setting newly names
+// that do not exist.
+func validateListenInterfaces(errs *settings.ValidationError, field, current, updated string) {
+	known := make(map[string]bool)
+	if entries, err := listen.ParseSpec(current); err == nil {
+		for _, entry := range entries {
+			known[entry.Interface] = true
+		}
+	}
+	if entries, err := listen.ParseSpec(updated); err == nil {
+		for _, entry := range entries {
+			if entry.Interface != "" && !known[entry.Interface] && !interfaceExists(entry.Interface) {
+				errs.Addf(field, "listen interface %q does not exist", entry.Interface)
+			}
+		}
+	}
+}
+
+// writeSettingsValidationError reports invalid settings with one entry per
+// field, so the settings form can mark each input.
+func writeSettingsValidationError(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"error":       err.Error(),
+		"fieldErrors": settings.FieldErrors(err),
+	})
+}
+
+// validateResolverEgress rejects a newly entered resolver egress that is
+// neither a VPN nor an existing interface.
+func (s *Server) validateResolverEgress(errs *settings.ValidationError, current, updated settings.Settings) {
+	name := updated.ResolverEgress
+	if name == "" || name == current.ResolverEgress || interfaceExists(name) {
+		return
+	}
+	if s.vpnManager != nil {
+		if _, err := s.vpnManager.Get(name); err == nil {
+			return
+		}
+	}
+	errs.Addf("resolverEgress", "resolverEgress %q is not a VPN or interface", name)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/settings"
+)
+
+func TestHandleSaveSettingsReportsFieldErrors(t *testing.T) {
+	original := interfaceExists
+	interfaceExists = func(name string) bool { return name == "eth8" }
+	t.Cleanup(func() { interfaceExists = original })
+
+	manager := settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	if err := manager.Save(settings.Settings{WANInterface: "eth9"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	s := &Server{settings: manager}
+
+	body := `{
+		"wanInterface": "eth7",
+		"listenInterface": "br0:99999",
+		"prewarmParallelism": 500,
+		"prewarmExtraNameservers": "1.1.1.1\nnot-an-ip",
+		"resolverTimeoutSeconds": -1,
+		"resolverEgress": "wg-nowhere",
+		"anomalyHighMbps": -5,
+		"anomalyStallMinutes": -1,
+		"mqttBrokerUrl": "ftp://",
+		"dnsBackend": "pihole"
+	}`
+	recorder := httptest.NewRecorder()
+	s.handleSaveSettings(recorder, httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(body)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Error       string                `json:"error"`
+		FieldErrors []settings.FieldError `json:"fieldErrors"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	fields := make(map[string]string)
+	for _, fieldErr := range response.FieldErrors {
+		fields[fieldErr.Field] = fieldErr.Message
+	}
+	for _, field := range []string{
+		"wanInterface",
+		"listenInterface",
+		"prewarmParallelism",
+		"prewarmExtraNameservers",
+		"resolverTimeoutSeconds",
+		"resolverEgress",
+		"anomalyHighMbps",
+		"anomalyStallMinutes",
+		"mqttBrokerUrl",
+		"dnsBackendConfigPath",
+	} {
+		if fields[field] == "" {
+			t.Fatalf("expected a field error for %s, got %+v", field, response.FieldErrors)
+		}
+	}
+	if response.Error == "" {
+		t.Fatalf("expected a summary error")
+	}
+	if saved, _ := manager.Get(); saved.WANInterface != "eth9" {
+		t.Fatalf("expected nothing to be saved, got wanInterface %q", saved.WANInterface)
+	}
+}
+
+func TestValidateSettingsInterfacesKeepsSavedNames(t *testing.T) {
+	original := interfaceExists
+	interfaceExists = func(name string) bool { return name == "eth8" }
+	t.Cleanup(func() { interfaceExists = original })
+
+	current := settings.Settings{WANInterface: "eth9", ListenInterface: "wg0", WANPriority: "eth9"}
+	var errs settings.ValidationError
+	validateSettingsInterfaces(&errs, current, settings.Settings{
+		WANInterface:    "eth9",
+		ListenInterface: "wg0,eth8:8443,10.0.0.1",
+		WANPriority:     "eth8,eth9",
+	})
+	if err := errs.Err(); err != nil {
+		t.Fatalf("expected saved and present interfaces to pass, got %v", err)
+	}
+	validateSettingsInterfaces(&errs, current, settings.Settings{WANPriority: "eth9,ppp0"})
+	if fields := settings.FieldErrors(errs.Err()); len(fields) != 1 || fields[0].Field != "wanPriority" {
+		t.Fatalf("expected one wanPriority error, got %+v", fields)
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/stats"
+)
+
+// handleStatsQuery returns the retained throughput history resampled into
+// buckets of interval seconds over the last window seconds. Both default to
+// the poll interval and the whole retained history; interface narrows the
+// result to a comma-separated list of names.
+func (s *Server) handleStatsQuery(w http.ResponseWriter, r *http.Request) {
+	if s.stats == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "statistics unavailable"})
+		return
+	}
+	interval, ok := parseNonNegativeQuery(w, r, "interval", 0)
+	if !ok {
+		return
+	}
+	window, ok := parseNonNegativeQuery(w, r, "window", 0)
+	if !ok {
+		return
+	}
+	result := s.stats.Query(stats.QueryOptions{
+		Interval:   time.Duration(interval) * time.Second,
+		Window:     time.Duration(window) * time.Second,
+		Interfaces: stats.ParseInterfaceList(strings.TrimSpace(r.URL.Query().Get("interface"))),
+	})
+	writeJSON(w, http.StatusOK, result)
+}
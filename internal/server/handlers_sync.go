@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"split-vpn-webui/internal/backup"
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/peersync"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/vpnrevisions"
+)
+
+// configurePeerSync logs and streams config sync events.
+func (s *Server) configurePeerSync(syncer *peersync.Syncer) {
+	s.peerSync = syncer
+	syncer.SetHandler(func(event peersync.Event) {
+		if s.diagLog != nil {
+			if event.Kind == peersync.KindFailed {
+				s.diagLog.Warnf("config sync kind=%s detail=%q", event.Kind, event.Detail)
+			} else {
+				s.diagLog.Infof("config sync kind=%s detail=%q", event.Kind, event.Detail)
+			}
+		}
+		s.broadcastEvent("sync", event)
+	})
+}
+
+// applySyncSnapshot replicates a leader snapshot. Schedulers are only paused
+// when the snapshot differs from local state, so an idle pull costs one
+// export.
+func (s *Server) applySyncSnapshot(ctx context.Context, snapshot backup.Snapshot) (bool, error) {
+	differs, err := s.backup.Differs(ctx, snapshot)
+	if err != nil || !differs {
+		return false, err
+	}
+
+	job := s.trackJob(jobs.KindBackupImport, "sync")
+	resume, err := s.pauseSchedulers()
+	if err != nil {
+		job.Finish(err)
+		return false, err
+	}
+	job.Logf("info", "schedulers paused; applying leader snapshot")
+	result, syncErr := s.backup.Sync(ctx, snapshot)
+	resumeErr := resume()
+	for _, warning := range result.Warnings {
+		job.Logf("warn", "%s", warning)
+	}
+	if syncErr != nil {
+		err := combineImportAndResumeError(syncErr, resumeErr)
+		job.Finish(err)
+		return false, err
+	}
+	job.Finish(resumeErr)
+
+	if s.vpnRevisions != nil && s.vpnManager != nil {
+		if profiles, err := s.vpnManager.List(); err == nil {
+			for _, profile := range profiles {
+				if _, _, err := s.vpnRevisions.Record(ctx, vpnrevisions.Revision{
+					VPN:     profile.Name,
+					Action:  vpnrevisions.ActionSync,
+					Actor:   "sync",
+					Content: profile.RawConfig,
+				}); err != nil && s.diagLog != nil {
+					s.diagLog.Warnf("record config revision vpn=%s action=sync failed: %v", profile.Name, err)
+				}
+			}
+		}
+	}
+	if err := s.refreshState(); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("refresh state after config sync failed: %v", err)
+	}
+	s.broadcastUpdate(nil)
+	return result.Changed, resumeErr
+}
+
+// handleSyncSnapshot serves this node's VPN profiles and routing groups to
+// followers. Settings are left out; followers keep their own.
+func (s *Server) handleSyncSnapshot(w http.ResponseWriter, r *http.Request) {
+	if s.backup == nil || s.settings == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "backup manager unavailable"})
+		return
+	}
+	current, err := s.settings.Get()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if current.SyncRole != peersync.RoleLeader {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "this node is not a sync leader"})
+		return
+	}
+	snapshot, err := s.backup.Export(r.Context())
+	if err != nil {
+		writeBackupError(w, err)
+		return
+	}
+	snapshot.Settings = settings.Settings{}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *Server) handleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	if s.peerSync == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "config sync unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sync": s.peerSync.Status()})
+}
+
+func (s *Server) handleSyncRun(w http.ResponseWriter, r *http.Request) {
+	if s.peerSync == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "config sync unavailable"})
+		return
+	}
+	// A client that disconnects must not abort a half-applied snapshot.
+	status, err := s.peerSync.SyncNow(context.WithoutCancel(r.Context()))
+	if errors.Is(err, peersync.ErrNotFollower) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]any{"error": err.Error(), "sync": status})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sync": status})
+}
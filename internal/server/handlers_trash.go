@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/dbmaint"
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/vpnrevisions"
+)
+
+func (s *Server) handleListTrash(w http.ResponseWriter, r *http.Request) {
+	response := map[string]any{}
+	if s.routingManager != nil {
+		groups, err := s.routingManager.ListGroupTrash(r.Context())
+		if err != nil {
+			writeRoutingError(w, err)
+			return
+		}
+		response["groups"] = groups
+	}
+	if s.vpnManager != nil {
+		vpns, err := s.vpnManager.ListTrash()
+		if err != nil {
+			writeVPNError(w, err)
+			return
+		}
+		response["vpns"] = vpns
+	}
+	if current, err := s.settings.Get(); err == nil {
+		response["retentionDays"] = int(dbmaint.RetentionFromSettings(current).Trash.Hours() / 24)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleRestoreTrashedGroup(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid trash id"})
+		return
+	}
+	job := s.trackJob(jobs.KindApply, "group restore")
+	restored, err := s.routingManager.RestoreGroup(r.Context(), id)
+	job.Finish(err)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusCreated, map[string]any{"group": restored})
+}
+
+func (s *Server) handleDeleteTrashedGroup(w http.ResponseWriter, r *http.Request) {
+	if s.routingManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "routing manager unavailable"})
+		return
+	}
+	id, err := parseGroupID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid trash id"})
+		return
+	}
+	if err := s.routingManager.DeleteGroupTrash(r.Context(), id); err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (s *Server) handleRestoreTrashedVPN(w http.ResponseWriter, r *http.Request) {
+	if s.vpnManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn manager unavailable"})
+		return
+	}
+	profile, err := s.vpnManager.RestoreTrash(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	s.recordVPNRevision(r, vpnrevisions.ActionCreate, nil, profile)
+	if err := s.refreshState(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.routingManager != nil {
+		if err := s.routingManager.Apply(r.Context()); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	s.broadcastUpdate(nil)
+	writeJSON(w, http.StatusCreated, map[string]any{"vpn": profile})
+}
+
+func (s *Server) handleDeleteTrashedVPN(w http.ResponseWriter, r *http.Request) {
+	if s.vpnManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn manager unavailable"})
+		return
+	}
+	if err := s.vpnManager.DeleteTrash(strings.TrimSpace(chi.URLParam(r, "id"))); err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// purgeVPNTrash removes trashed VPN profiles past the trash retention. Group
+// trash rows are pruned by the database maintenance pass itself.
+func (s *Server) purgeVPNTrash(result dbmaint.Result) {
+	if s.vpnManager == nil {
+		return
+	}
+	current, err := s.settings.Get()
+	if err != nil {
+		return
+	}
+	purged, err := s.vpnManager.PurgeTrash(result.At.Add(-dbmaint.RetentionFromSettings(current).Trash))
+	if s.diagLog == nil {
+		return
+	}
+	if err != nil {
+		s.diagLog.Warnf("purge vpn trash failed: %v", err)
+	} else if purged > 0 {
+		s.diagLog.Infof("purged %d trashed vpn profile(s)", purged)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/unblock"
+	"split-vpn-webui/internal/util"
+)
+
+// unblockCheckTimeout bounds an on-demand run across every VPN.
+const unblockCheckTimeout = 2 * time.Minute
+
+// configureUnblockMonitor streams each run's results over SSE.
+func (s *Server) configureUnblockMonitor(monitor *unblock.Monitor) {
+	s.unblock = monitor
+	monitor.SetHandler(func(results []unblock.Result) {
+		if s.diagLog != nil {
+			for _, result := range results {
+				s.diagLog.Infof("unblock check vpn=%s services=%s", result.VPN, unblockSummary(result))
+			}
+		}
+		s.broadcastEvent("unblock", results)
+	})
+}
+
+// unblockTargets lists the VPNs whose interface is up.
+func (s *Server) unblockTargets() ([]unblock.Target, error) {
+	profiles, err := s.vpnManager.List()
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]unblock.Target, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile == nil {
+			continue
+		}
+		iface := strings.TrimSpace(profile.InterfaceName)
+		if iface == "" {
+			continue
+		}
+		if up, _, _ := util.InterfaceOperState(iface); !up {
+			continue
+		}
+		targets = append(targets, unblock.Target{
+			VPN:       profile.Name,
+			Interface: iface,
+			Resolvers: profile.DNSServers(),
+		})
+	}
+	return targets, nil
+}
+
+func unblockSummary(result unblock.Result) string {
+	parts := make([]string, 0, len(result.Services))
+	for _, service := range result.Services {
+		part := service.Service + ":" + service.Status
+		if service.Region != "" {
+			part += "/" + service.Region
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s *Server) handleUnblockResults(w http.ResponseWriter, r *http.Request) {
+	if s.unblock == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "unblock checks unavailable"})
+		return
+	}
+	intervalHours := 0
+	if s.settings != nil {
+		if current, err := s.settings.Get(); err == nil {
+			intervalHours = current.UnblockCheckIntervalHours
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": s.unblock.Results(), "intervalHours": intervalHours})
+}
+
+// handleRunUnblockCheck probes the streaming services through every
+// connected VPN, or through one with {"vpn": "name"}.
+func (s *Server) handleRunUnblockCheck(w http.ResponseWriter, r *http.Request) {
+	if s.unblock == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "unblock checks unavailable"})
+		return
+	}
+	var payload struct {
+		VPN string `json:"vpn"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), unblockCheckTimeout)
+	defer cancel()
+	if _, err := s.unblock.Run(ctx, strings.TrimSpace(payload.VPN)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, unblock.ErrNotConnected) {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": s.unblock.Results()})
+}
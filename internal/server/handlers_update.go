@@ -8,12 +8,44 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/update"
 )
 
 const (
 	updateRequestTimeout = 3 * time.Minute
 )
 
+// configureAutoUpdate applies the updater preferences, registers the
+// pre-update backup export and wires the auto-update scheduler's results
+// into the live stream and the application log.
+func (s *Server) configureAutoUpdate(current settings.Settings, scheduler *update.Scheduler) {
+	s.updater.Configure(update.PreferencesFromSettings(current))
+	if s.backup != nil {
+		s.updater.SetBackupExporter(func(ctx context.Context) ([]byte, error) {
+			snapshot, err := s.backup.Export(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return json.MarshalIndent(snapshot, "", "  ")
+		})
+	}
+	s.autoUpdate = scheduler
+	scheduler.SetHandler(func(status update.Status, err error) {
+		if s.diagLog != nil {
+			if err != nil {
+				s.diagLog.Errorf("update auto-run failed: %v", err)
+			} else {
+				s.diagLog.Infof("update auto-run result=%q", status.AutoUpdate.LastResult)
+			}
+		}
+		if err == nil {
+			s.broadcastEvent("update", status)
+		}
+	})
+}
+
 func (s *Server) handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
 	if s.updater == nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "updater unavailable"})
@@ -67,6 +99,8 @@ func (s *Server) handleApplyUpdate(w http.ResponseWriter, r *http.Request) {
 			code = http.StatusGatewayTimeout
 		} else if strings.Contains(strings.ToLower(err.Error()), "already in progress") {
 			code = http.StatusConflict
+		} else if strings.Contains(strings.ToLower(err.Error()), "preflight failed") {
+			code = http.StatusPreconditionFailed
 		} else if strings.Contains(strings.ToLower(err.Error()), "missing checksum") || strings.Contains(strings.ToLower(err.Error()), "checksum") {
 			code = http.StatusBadGateway
 		}
@@ -77,6 +111,68 @@ func (s *Server) handleApplyUpdate(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusAccepted, status)
 }
 
+// handlePreflightUpdate checks the router against a release's requirements
+// without downloading or staging anything.
+func (s *Server) handlePreflightUpdate(w http.ResponseWriter, r *http.Request) {
+	if s.updater == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "updater unavailable"})
+		return
+	}
+	requestedTag, err := decodeUpdateTag(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), updateRequestTimeout)
+	defer cancel()
+	report, err := s.updater.Preflight(ctx, requestedTag)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	if status, err := s.updater.Status(); err == nil {
+		s.broadcastEvent("update", status)
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleRollbackUpdate schedules a downgrade to a version retained in the
+// updates directory, through the same updater unit as an upgrade.
+func (s *Server) handleRollbackUpdate(w http.ResponseWriter, r *http.Request) {
+	if s.updater == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "updater unavailable"})
+		return
+	}
+	requestedTag, err := decodeUpdateTag(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if requestedTag == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "version is required"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), updateRequestTimeout)
+	defer cancel()
+	status, err := s.updater.StartRollback(ctx, requestedTag)
+	if err != nil {
+		code := http.StatusInternalServerError
+		lower := strings.ToLower(err.Error())
+		if strings.Contains(lower, "already in progress") {
+			code = http.StatusConflict
+		} else if strings.Contains(lower, "not retained") || strings.Contains(lower, "already running") || strings.Contains(lower, "version tag") {
+			code = http.StatusBadRequest
+		}
+		writeJSON(w, code, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Warnf("update rollback scheduled version=%s", requestedTag)
+	}
+	s.broadcastEvent("update", status)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
 func decodeUpdateTag(r *http.Request) (string, error) {
 	var payload struct {
 		Version string `json:"version"`
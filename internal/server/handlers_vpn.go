@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"split-vpn-webui/internal/vpn"
+	"split-vpn-webui/internal/vpnrevisions"
 )
 
 func (s *Server) handleListVPNs(w http.ResponseWriter, r *http.Request) {
@@ -53,6 +54,7 @@ func (s *Server) handleCreateVPN(w http.ResponseWriter, r *http.Request) {
 		writeVPNError(w, err)
 		return
 	}
+	s.recordVPNRevision(r, vpnrevisions.ActionCreate, nil, profile)
 	if err := s.refreshState(); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -81,11 +83,13 @@ func (s *Server) handleUpdateVPN(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
 		return
 	}
+	before := s.currentVPNProfile(name)
 	profile, err := s.vpnManager.Update(name, payload)
 	if err != nil {
 		writeVPNError(w, err)
 		return
 	}
+	s.recordVPNRevision(r, vpnrevisions.ActionUpdate, before, profile)
 	if err := s.refreshState(); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -113,6 +117,19 @@ func (s *Server) handleDeleteVPN(w http.ResponseWriter, r *http.Request) {
 		writeVPNError(w, err)
 		return
 	}
+	if s.vpnRevisions != nil {
+		if err := s.vpnRevisions.Delete(r.Context(), name); err != nil && s.diagLog != nil {
+			s.diagLog.Warnf("delete config history vpn=%s failed: %v", name, err)
+		}
+	}
+	if s.flowHistory != nil {
+		if err := s.flowHistory.Delete(r.Context(), name); err != nil && s.diagLog != nil {
+			s.diagLog.Warnf("delete flow history vpn=%s failed: %v", name, err)
+		}
+	}
+	if s.ipfix != nil {
+		s.ipfix.Forget(name)
+	}
 	if err := s.refreshState(); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -131,7 +148,7 @@ func writeVPNError(w http.ResponseWriter, err error) {
 	switch {
 	case errors.Is(err, vpn.ErrVPNValidation):
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-	case errors.Is(err, vpn.ErrVPNNotFound):
+	case errors.Is(err, vpn.ErrVPNNotFound), errors.Is(err, vpn.ErrTrashedVPNNotFound):
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
 	case errors.Is(err, vpn.ErrVPNAlreadyExists), errors.Is(err, vpn.ErrAllocationConflict):
 		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
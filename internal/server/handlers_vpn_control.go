@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"split-vpn-webui/internal/vpnevents"
+)
+
+const vpnControlJournalLines = 20
+
+// vpnControlSettleTimeout bounds how long a control request waits for a unit
+// that is still activating or deactivating to reach a final state.
+var (
+	vpnControlSettleTimeout = 5 * time.Second
+	vpnControlSettleStep    = 250 * time.Millisecond
+)
+
+type vpnControlAction struct {
+	verb      string
+	done      string
+	eventType string
+	run       func(unitName string) error
+	wantUp    bool
+}
+
+func (s *Server) vpnStartAction() vpnControlAction {
+	return vpnControlAction{verb: "start", done: "started", eventType: vpnevents.TypeStart, run: s.systemd.Start, wantUp: true}
+}
+
+func (s *Server) vpnStopAction() vpnControlAction {
+	return vpnControlAction{verb: "stop", done: "stopped", eventType: vpnevents.TypeStop, run: s.systemd.Stop}
+}
+
+func (s *Server) handleStartVPN(w http.ResponseWriter, r *http.Request) {
+	if s.systemd == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "systemd manager unavailable"})
+		return
+	}
+	s.controlVPNUnit(w, r, s.vpnStartAction())
+}
+
+func (s *Server) handleStopVPN(w http.ResponseWriter, r *http.Request) {
+	if s.systemd == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "systemd manager unavailable"})
+		return
+	}
+	s.controlVPNUnit(w, r, s.vpnStopAction())
+}
+
+func (s *Server) handleRestartVPN(w http.ResponseWriter, r *http.Request) {
+	if s.systemd == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "systemd manager unavailable"})
+		return
+	}
+	s.controlVPNUnit(w, r, vpnControlAction{verb: "restart", done: "restarted", eventType: vpnevents.TypeRestart, run: s.systemd.Restart, wantUp: true})
+}
+
+// controlVPNUnit runs a systemctl action against svpn-<name>.service, waits for
+// the unit to settle and reports its resulting state. Failures carry the unit's
+// recent journal so the cause is visible without a shell.
+func (s *Server) controlVPNUnit(w http.ResponseWriter, r *http.Request, action vpnControlAction) {
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	cfg, err := s.configManager.Get(name)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	state, journal, err := s.runVPNControl(r.Context(), cfg.Name, action, "the web UI")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":     err.Error(),
+			"unitState": state,
+			"journal":   journal,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": action.done, "unitState": state})
+}
+
+// runVPNControl applies action to a VPN's unit and records the outcome on its
+// timeline, crediting origin. On failure it returns the unit's recent journal.
+func (s *Server) runVPNControl(ctx context.Context, name string, action vpnControlAction, origin string) (string, []string, error) {
+	unit := vpnServiceUnitName(name)
+	runErr := action.run(unit)
+	state := s.settledUnitState(unit)
+	if runErr == nil && unitStateMatches(state, action.wantUp) {
+		s.recordVPNEvent(ctx, name, action.eventType, action.done+" from "+origin)
+		s.refreshAfterControl()
+		return state, nil, nil
+	}
+
+	message := fmt.Sprintf("%s did not %s: unit is %s", unit, action.verb, stateOrUnknown(state))
+	if runErr != nil {
+		message = runErr.Error()
+	}
+	journal, journalErr := s.systemd.Journal(unit, vpnControlJournalLines)
+	if journalErr != nil && s.diagLog != nil {
+		s.diagLog.Warnf("read journal for %s failed: %v", unit, journalErr)
+	}
+	if s.diagLog != nil {
+		s.diagLog.Warnf("vpn %s %s failed: %s", name, action.verb, message)
+	}
+	s.recordVPNEvent(ctx, name, action.eventType, action.verb+" from "+origin+" failed: "+message)
+	s.refreshAfterControl()
+	if journal == nil {
+		journal = []string{}
+	}
+	return state, journal, errors.New(message)
+}
+
+// settledUnitState polls `systemctl is-active` until the unit leaves a
+// transitional state or the settle timeout expires.
+func (s *Server) settledUnitState(unit string) string {
+	deadline := time.Now().Add(vpnControlSettleTimeout)
+	for {
+		state, _ := s.systemd.Status(unit)
+		switch state {
+		case "activating", "deactivating", "reloading":
+			if time.Now().Before(deadline) {
+				time.Sleep(vpnControlSettleStep)
+				continue
+			}
+		}
+		return state
+	}
+}
+
+func (s *Server) refreshAfterControl() {
+	if err := s.refreshState(); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("refresh state after vpn control failed: %v", err)
+	}
+	s.broadcastUpdate(nil)
+}
+
+func unitStateMatches(state string, wantUp bool) bool {
+	switch state {
+	case "active":
+		return wantUp
+	case "activating", "deactivating", "reloading":
+		return false
+	default:
+		return !wantUp
+	}
+}
+
+func stateOrUnknown(state string) string {
+	if state == "" {
+		return "unknown"
+	}
+	return state
+}
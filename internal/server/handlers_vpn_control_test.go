@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/config"
+	"split-vpn-webui/internal/latency"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/stats"
+	"split-vpn-webui/internal/systemd"
+)
+
+func newControlTestServer(t *testing.T, mock *systemd.MockManager) *Server {
+	t.Helper()
+	base := t.TempDir()
+	vpnDir := filepath.Join(base, "wg-fra")
+	if err := os.MkdirAll(vpnDir, 0o700); err != nil {
+		t.Fatalf("mkdir vpn dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vpnDir, "vpn.conf"), []byte("DEV=wg-sv-fra\nWAN_INTERFACE=eth8\n"), 0o644); err != nil {
+		t.Fatalf("write vpn.conf: %v", err)
+	}
+	cm := config.NewManager(base)
+	if _, err := cm.Discover(); err != nil {
+		t.Fatalf("discover configs: %v", err)
+	}
+	return &Server{
+		configManager: cm,
+		systemd:       mock,
+		settings:      settings.NewManager(filepath.Join(base, "settings.json")),
+		stats:         stats.NewCollector("", 2*time.Second, 10),
+		latency:       latency.NewMonitor(10 * time.Second),
+	}
+}
+
+func serveControl(t *testing.T, handler http.HandlerFunc, name string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/vpns/"+name+"/start", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("name", name)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v (%s)", err, rec.Body.String())
+	}
+	return rec, payload
+}
+
+func TestStartVPNReportsSettledUnitState(t *testing.T) {
+	previous := vpnControlSettleStep
+	vpnControlSettleStep = time.Millisecond
+	t.Cleanup(func() { vpnControlSettleStep = previous })
+
+	var started string
+	polls := 0
+	mock := &systemd.MockManager{
+		StartFunc: func(unit string) error {
+			started = unit
+			return nil
+		},
+		StatusFunc: func(string) (string, error) {
+			polls++
+			if polls < 3 {
+				return "activating", errors.New("exit 3")
+			}
+			return "active", nil
+		},
+		JournalFunc: func(string, int) ([]string, error) {
+			t.Fatalf("journal should not be read on success")
+			return nil, nil
+		},
+	}
+	s := newControlTestServer(t, mock)
+
+	rec, payload := serveControl(t, s.handleStartVPN, "wg-fra")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if started != "svpn-wg-fra.service" {
+		t.Fatalf("unexpected unit started: %q", started)
+	}
+	if payload["status"] != "started" || payload["unitState"] != "active" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestStartVPNFailureIncludesJournal(t *testing.T) {
+	mock := &systemd.MockManager{
+		StartFunc: func(string) error {
+			return errors.New("systemctl start svpn-wg-fra.service: Job failed: exit status 1")
+		},
+		StatusFunc: func(string) (string, error) {
+			return "failed", errors.New("exit 3")
+		},
+		JournalFunc: func(unit string, lines int) ([]string, error) {
+			if unit != "svpn-wg-fra.service" || lines != vpnControlJournalLines {
+				t.Fatalf("unexpected journal request: %s %d", unit, lines)
+			}
+			return []string{"wg-quick[42]: RTNETLINK answers: File exists"}, nil
+		},
+	}
+	s := newControlTestServer(t, mock)
+
+	rec, payload := serveControl(t, s.handleStartVPN, "wg-fra")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d: %s", rec.Code, rec.Body.String())
+	}
+	journal, _ := payload["journal"].([]any)
+	if payload["unitState"] != "failed" || len(journal) != 1 || journal[0] != "wg-quick[42]: RTNETLINK answers: File exists" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestStopVPNFailsWhenUnitStaysActive(t *testing.T) {
+	mock := &systemd.MockManager{
+		StatusFunc: func(string) (string, error) { return "active", nil },
+	}
+	s := newControlTestServer(t, mock)
+
+	rec, payload := serveControl(t, s.handleStopVPN, "wg-fra")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if payload["error"] != "svpn-wg-fra.service did not stop: unit is active" {
+		t.Fatalf("unexpected error: %#v", payload)
+	}
+	if journal, ok := payload["journal"].([]any); !ok || len(journal) != 0 {
+		t.Fatalf("expected empty journal array, got %#v", payload["journal"])
+	}
+}
+
+func TestControlVPNUnknownProfile(t *testing.T) {
+	s := newControlTestServer(t, &systemd.MockManager{})
+	rec, _ := serveControl(t, s.handleRestartVPN, "wg-missing")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/dnsleak"
+)
+
+// dnsLeakTestTimeout bounds a whole test; each query has its own shorter
+// timeout, so an unreachable resolver only costs that query.
+const dnsLeakTestTimeout = 30 * time.Second
+
+type dnsLeakRunner interface {
+	Run(ctx context.Context, opts dnsleak.Options) (dnsleak.Result, error)
+}
+
+// handleVPNDNSLeakTest resolves leak-test names through the VPN's resolvers
+// and the router's resolver and reports which resolvers answered and whether
+// they share the tunnel exit's ASN.
+func (s *Server) handleVPNDNSLeakTest(w http.ResponseWriter, r *http.Request) {
+	if s.dnsLeak == nil || s.vpnManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "dns leak test unavailable"})
+		return
+	}
+	vpnName, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	profile, err := s.vpnManager.Get(vpnName)
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	iface := strings.TrimSpace(profile.InterfaceName)
+	if iface == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "vpn has no interface"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dnsLeakTestTimeout)
+	defer cancel()
+	result, err := s.dnsLeak.Run(ctx, dnsleak.Options{
+		VPN:       vpnName,
+		Interface: iface,
+		Resolvers: profile.DNSServers(),
+	})
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("dns leak test vpn=%s iface=%s exit=%s asns=%v leak=%t paths=%d",
+			vpnName, iface, result.ExitIP, result.VPNASNs, result.Leak, len(result.Paths))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"result": result})
+}
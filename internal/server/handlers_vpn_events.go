@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/vpnevents"
+)
+
+// vpnUptime is the share of each window a VPN's interface was up. A window
+// is omitted when no link transition is known for it.
+type vpnUptime struct {
+	Day  *float64 `json:"day,omitempty"`
+	Week *float64 `json:"week,omitempty"`
+}
+
+// configureVPNEventTracker streams recorded timeline events over SSE and
+// forwards them to the MQTT broker.
+func (s *Server) configureVPNEventTracker(tracker *vpnevents.Tracker) {
+	s.vpnTracker = tracker
+	tracker.SetHandler(func(event vpnevents.Event) {
+		if s.diagLog != nil {
+			s.diagLog.Debugf("vpn event vpn=%s type=%s detail=%q", event.VPN, event.Type, event.Detail)
+		}
+		s.broadcastEvent("vpn-event", event)
+		s.publishMQTTEvent(event)
+	})
+}
+
+// recordVPNEvent adds a user action to a VPN's timeline. Failures only reach
+// the diagnostics log; the action itself already succeeded.
+func (s *Server) recordVPNEvent(ctx context.Context, name, eventType, detail string) {
+	if s.vpnTracker == nil {
+		return
+	}
+	if _, err := s.vpnTracker.Record(ctx, name, eventType, detail); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("record vpn event vpn=%s type=%s failed: %v", name, eventType, err)
+	}
+}
+
+func (s *Server) handleVPNEvents(w http.ResponseWriter, r *http.Request) {
+	if s.vpnEvents == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn event store unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	limit, ok := parseNonNegativeQuery(w, r, "limit", vpnevents.DefaultPageSize)
+	if !ok {
+		return
+	}
+	offset, ok := parseNonNegativeQuery(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	page, err := s.vpnEvents.List(r.Context(), name, limit, offset)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	var uptime vpnUptime
+	for _, window := range []struct {
+		span   time.Duration
+		target **float64
+	}{
+		{24 * time.Hour, &uptime.Day},
+		{7 * 24 * time.Hour, &uptime.Week},
+	} {
+		percent, known, err := s.vpnEvents.Uptime(r.Context(), name, now.Add(-window.span), now)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if known {
+			*window.target = &percent
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"events": page.Events,
+		"total":  page.Total,
+		"limit":  page.Limit,
+		"offset": page.Offset,
+		"uptime": uptime,
+	})
+}
+
+func parseNonNegativeQuery(w http.ResponseWriter, r *http.Request, key string, fallback int) (int, bool) {
+	raw := strings.TrimSpace(r.URL.Query().Get(key))
+	if raw == "" {
+		return fallback, true
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": key + " must be a non-negative integer"})
+		return 0, false
+	}
+	return parsed, true
+}
@@ -24,6 +24,10 @@ func (s *Server) handleStartVPNFlowInspector(w http.ResponseWriter, r *http.Requ
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
 		return
 	}
+	view, ok := parseFlowInspectorView(w, r)
+	if !ok {
+		return
+	}
 	samples, interfaceName, err := s.collectVPNFlowSamples(r.Context(), vpnName)
 	if err != nil {
 		if s.diagLog != nil {
@@ -48,6 +52,10 @@ func (s *Server) handleStartVPNFlowInspector(w http.ResponseWriter, r *http.Requ
 		writeFlowInspectorError(w, err)
 		return
 	}
+	s.recordFlowHistory(r.Context(), vpnName, samples)
+	view.apply(&snapshot)
+	s.annotateFlowReputation(&snapshot)
+	snapshot.Accounting = s.conntrackAccounting()
 	if s.diagLog != nil {
 		s.diagLog.Infof(
 			"flow_inspector session started vpn=%s session=%s iface=%s samples=%d flows=%d",
@@ -84,6 +92,10 @@ func (s *Server) handlePollVPNFlowInspector(w http.ResponseWriter, r *http.Reque
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing flow inspector session id"})
 		return
 	}
+	view, ok := parseFlowInspectorView(w, r)
+	if !ok {
+		return
+	}
 	if s.diagLog != nil {
 		s.diagLog.Debugf("flow_inspector poll request vpn=%s session=%s", vpnName, sessionID)
 	}
@@ -103,6 +115,10 @@ func (s *Server) handlePollVPNFlowInspector(w http.ResponseWriter, r *http.Reque
 		writeFlowInspectorError(w, err)
 		return
 	}
+	s.recordFlowHistory(r.Context(), vpnName, samples)
+	view.apply(&snapshot)
+	s.annotateFlowReputation(&snapshot)
+	snapshot.Accounting = s.conntrackAccounting()
 	if s.diagLog != nil {
 		s.diagLog.Debugf(
 			"flow_inspector poll ok vpn=%s session=%s samples=%d flows=%d totals=%d",
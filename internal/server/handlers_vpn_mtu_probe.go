@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/pmtu"
+)
+
+// mtuProbeTimeout bounds both families' binary searches, which run
+// concurrently at roughly one second per step.
+const mtuProbeTimeout = 45 * time.Second
+
+type mtuProber interface {
+	Probe(ctx context.Context, iface, targetV4, targetV6 string) (pmtu.Report, error)
+}
+
+type mtuProbePayload struct {
+	TargetV4 string `json:"targetV4"`
+	TargetV6 string `json:"targetV6"`
+}
+
+// handleVPNMTUProbe measures the path MTU through a VPN tunnel and returns
+// the MSS clamp values that fit it. It does not change the profile.
+func (s *Server) handleVPNMTUProbe(w http.ResponseWriter, r *http.Request) {
+	if s.mtuProbe == nil || s.vpnManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "mtu probe unavailable"})
+		return
+	}
+	vpnName, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	var payload mtuProbePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	targetV4, err := parseMTUProbeTarget(payload.TargetV4, false)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	targetV6, err := parseMTUProbeTarget(payload.TargetV6, true)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	profile, err := s.vpnManager.Get(vpnName)
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	iface := strings.TrimSpace(profile.InterfaceName)
+	if iface == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "vpn has no interface"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), mtuProbeTimeout)
+	defer cancel()
+	report, err := s.mtuProbe.Probe(ctx, iface, targetV4, targetV6)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Infof("mtu probe vpn=%s iface=%s iface_mtu=%d v4_mtu=%d v6_mtu=%d",
+			vpnName, iface, report.InterfaceMTU, report.IPv4.MTU, report.IPv6.MTU)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"probe": report})
+}
+
+// parseMTUProbeTarget accepts an empty value (use the default) or a literal
+// address of the expected family; it is passed straight to ping.
+func parseMTUProbeTarget(raw string, ipv6 bool) (string, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return "", nil
+	}
+	addr, err := netip.ParseAddr(trimmed)
+	if err != nil || addr.Is6() != ipv6 || addr.Is4In6() {
+		family := "IPv4"
+		if ipv6 {
+			family = "IPv6"
+		}
+		return "", errors.New("mtu probe target must be an " + family + " address")
+	}
+	return addr.String(), nil
+}
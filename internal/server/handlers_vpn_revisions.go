@@ -0,0 +1,210 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/auth"
+	"split-vpn-webui/internal/vpn"
+	"split-vpn-webui/internal/vpnrevisions"
+)
+
+// recordVPNRevision adds the config after a change to the VPN's history,
+// first keeping before as a baseline when the VPN has no history yet.
+// Failures only reach the diagnostics log; the change already succeeded.
+func (s *Server) recordVPNRevision(r *http.Request, action string, before, after *vpn.VPNProfile) {
+	if s.vpnRevisions == nil || after == nil {
+		return
+	}
+	ctx := r.Context()
+	if before != nil {
+		if err := s.vpnRevisions.EnsureBaseline(ctx, after.Name, before.RawConfig); err != nil && s.diagLog != nil {
+			s.diagLog.Warnf("record config baseline vpn=%s failed: %v", after.Name, err)
+		}
+	}
+	_, _, err := s.vpnRevisions.Record(ctx, vpnrevisions.Revision{
+		VPN:     after.Name,
+		Action:  action,
+		Actor:   auth.Actor(r),
+		Content: after.RawConfig,
+	})
+	if err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("record config revision vpn=%s action=%s failed: %v", after.Name, action, err)
+	}
+}
+
+// currentVPNProfile returns the profile before a change, or nil.
+func (s *Server) currentVPNProfile(name string) *vpn.VPNProfile {
+	if s.vpnRevisions == nil {
+		return nil
+	}
+	profile, err := s.vpnManager.Get(name)
+	if err != nil {
+		return nil
+	}
+	return profile
+}
+
+func (s *Server) handleListVPNRevisions(w http.ResponseWriter, r *http.Request) {
+	if s.vpnRevisions == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn revision store unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	limit, ok := parseNonNegativeQuery(w, r, "limit", vpnrevisions.DefaultPageSize)
+	if !ok {
+		return
+	}
+	offset, ok := parseNonNegativeQuery(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	page, err := s.vpnRevisions.List(r.Context(), name, limit, offset)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleGetVPNRevision(w http.ResponseWriter, r *http.Request) {
+	if s.vpnRevisions == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn revision store unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseRevisionID(chi.URLParam(r, "revision"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	revision, err := s.vpnRevisions.Get(r.Context(), name, id)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"revision": revision})
+}
+
+// handleDiffVPNRevisions compares two revisions. to defaults to the
+// profile's current config.
+func (s *Server) handleDiffVPNRevisions(w http.ResponseWriter, r *http.Request) {
+	if s.vpnRevisions == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn revision store unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	fromID, err := parseRevisionID(r.URL.Query().Get("from"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from: " + err.Error()})
+		return
+	}
+	from, err := s.vpnRevisions.Get(r.Context(), name, fromID)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	toLabel := "current"
+	var toContent string
+	if raw := strings.TrimSpace(r.URL.Query().Get("to")); raw != "" && raw != "current" {
+		toID, err := parseRevisionID(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to: " + err.Error()})
+			return
+		}
+		to, err := s.vpnRevisions.Get(r.Context(), name, toID)
+		if err != nil {
+			writeRevisionError(w, err)
+			return
+		}
+		toLabel = fmt.Sprintf("revision %d", to.ID)
+		toContent = to.Content
+	} else {
+		if s.vpnManager == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn manager unavailable"})
+			return
+		}
+		profile, err := s.vpnManager.Get(name)
+		if err != nil {
+			writeVPNError(w, err)
+			return
+		}
+		toContent = profile.RawConfig
+	}
+	lines, err := vpnrevisions.Diff(from.Content, toContent)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	fromLabel := fmt.Sprintf("revision %d", from.ID)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"from":    fromLabel,
+		"to":      toLabel,
+		"lines":   lines,
+		"unified": vpnrevisions.Unified(lines, fromLabel, toLabel, 3),
+	})
+}
+
+// handleRevertVPNRevision writes a previous revision back as the current
+// config, with the same validation as an edit.
+func (s *Server) handleRevertVPNRevision(w http.ResponseWriter, r *http.Request) {
+	if s.vpnRevisions == nil || s.vpnManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "vpn revision store unavailable"})
+		return
+	}
+	name, ok := s.requireVPNNameParam(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseRevisionID(chi.URLParam(r, "revision"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	revision, err := s.vpnRevisions.Get(r.Context(), name, id)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	before := s.currentVPNProfile(name)
+	profile, err := s.vpnManager.WriteConfig(name, revision.Content)
+	if err != nil {
+		writeVPNError(w, err)
+		return
+	}
+	s.recordVPNRevision(r, vpnrevisions.ActionRevert, before, profile)
+	if s.diagLog != nil {
+		s.diagLog.Infof("config revert vpn=%s revision=%d", profile.Name, id)
+	}
+	s.afterConfigChange(w, r, profile.Name)
+}
+
+func parseRevisionID(raw string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid revision id %q", raw)
+	}
+	return id, nil
+}
+
+func writeRevisionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, vpnrevisions.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
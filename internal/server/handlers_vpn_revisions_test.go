@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/vpnrevisions"
+)
+
+func newRevisionTestRouter(t *testing.T) (http.Handler, *vpnrevisions.Store) {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := vpnrevisions.NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	s := &Server{vpnRevisions: store}
+	router := chi.NewRouter()
+	router.Get("/api/vpns/{name}/revisions", s.handleListVPNRevisions)
+	router.Get("/api/vpns/{name}/revisions/diff", s.handleDiffVPNRevisions)
+	router.Get("/api/vpns/{name}/revisions/{revision}", s.handleGetVPNRevision)
+	return router, store
+}
+
+func TestVPNRevisionHandlers(t *testing.T) {
+	router, store := newRevisionTestRouter(t)
+	ctx := context.Background()
+	first, _, err := store.Record(ctx, vpnrevisions.Revision{VPN: "wg-fra", Action: vpnrevisions.ActionCreate, Actor: "web session", Content: "[Peer]\nEndpoint = a:1\n"})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	second, _, err := store.Record(ctx, vpnrevisions.Revision{VPN: "wg-fra", Action: vpnrevisions.ActionEdit, Content: "[Peer]\nEndpoint = b:1\n"})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/vpns/wg-fra/revisions")
+	var page vpnrevisions.Page
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &page) != nil || page.Total != 2 || page.Revisions[0].ID != second.ID {
+		t.Fatalf("unexpected list response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = get("/api/vpns/wg-fra/revisions/diff?from=" + strconv.FormatInt(first.ID, 10) + "&to=" + strconv.FormatInt(second.ID, 10))
+	var diff struct {
+		Unified string `json:"unified"`
+	}
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &diff) != nil {
+		t.Fatalf("unexpected diff response %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(diff.Unified, "-Endpoint = a:1\n+Endpoint = b:1\n") {
+		t.Fatalf("unexpected unified diff:\n%s", diff.Unified)
+	}
+
+	if rec := get("/api/vpns/wg-fra/revisions/" + strconv.FormatInt(first.ID, 10)); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "a:1") {
+		t.Fatalf("unexpected get response %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/api/vpns/wg-other/revisions/" + strconv.FormatInt(first.ID, 10)); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another vpn's revision, got %d", rec.Code)
+	}
+	if rec := get("/api/vpns/wg-fra/revisions/diff?from=abc"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad revision id, got %d", rec.Code)
+	}
+	if rec := get("/api/vpns/wg-fra/revisions/diff?from=" + strconv.FormatInt(first.ID, 10)); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 diffing against current without a vpn manager, got %d", rec.Code)
+	}
+}
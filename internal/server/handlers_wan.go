@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"split-vpn-webui/internal/wan"
+)
+
+// configureWANFailover reports active WAN and endpoint route changes in the
+// diagnostics log and SSE stream, and moves the throughput correction to the
+// active uplink.
+func (s *Server) configureWANFailover(monitor *wan.Monitor) {
+	s.wanFailover = monitor
+	monitor.SetHandler(func(event wan.Event) {
+		if s.stats != nil {
+			s.stats.SetActiveWAN(event.Active)
+		}
+		if s.diagLog != nil {
+			switch {
+			case event.Previous != "" && event.Active != event.Previous:
+				s.diagLog.Warnf("wan failover from=%s to=%s pinned=%d", event.Previous, event.Active, len(event.Pinned))
+			case event.Active != event.Previous:
+				s.diagLog.Infof("active wan iface=%s pinned=%d", event.Active, len(event.Pinned))
+			default:
+				s.diagLog.Infof("vpn endpoint routes updated pinned=%d removed=%d", len(event.Pinned), len(event.Removed))
+			}
+			if len(event.Errors) > 0 {
+				s.diagLog.Errorf("wan endpoint route errors: %s", strings.Join(event.Errors, "; "))
+			}
+		}
+		s.broadcastEvent("wan", event)
+	})
+}
+
+func (s *Server) handleWANStatus(w http.ResponseWriter, r *http.Request) {
+	if s.wanFailover == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "wan failover monitor unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"wan": s.wanFailover.Status()})
+}
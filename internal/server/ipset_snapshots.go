@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ipsetSnapshotTTL is how long a set listing is reused by the routing
+	// and flow inspectors, the route trace and the state broadcast.
+	ipsetSnapshotTTL = 3 * time.Second
+	// ipsetListConcurrency bounds the parallel `ipset list <set>` calls.
+	ipsetListConcurrency = 8
+	// ipsetSetPrefix limits listings to the app-managed sets; UniFi's own
+	// sets can be large and are never read.
+	ipsetSetPrefix = "svpn_"
+)
+
+// ipsetSnapshotCache lists the app-managed ipsets one set at a time in
+// parallel and shares the result between callers for ipsetSnapshotTTL.
+// Concurrent callers wait for the same listing instead of starting their
+// own.
+type ipsetSnapshotCache struct {
+	mu        sync.Mutex
+	snapshots map[string]ipsetSnapshot
+	fetchedAt time.Time
+	inflight  *ipsetSnapshotFetch
+	// run executes the ipset command; nil uses the system binary.
+	run func(ctx context.Context, args ...string) ([]byte, error)
+	now func() time.Time
+}
+
+type ipsetSnapshotFetch struct {
+	done      chan struct{}
+	snapshots map[string]ipsetSnapshot
+	fetchedAt time.Time
+	err       error
+}
+
+// ipsetSnapshots returns the members and counts of the app-managed ipsets,
+// from cache when a listing is fresh enough.
+func (s *Server) ipsetSnapshots(timeout time.Duration) (map[string]ipsetSnapshot, error) {
+	snapshots, _, err := s.ipsetCache.getWithTime(timeout)
+	return snapshots, err
+}
+
+func (c *ipsetSnapshotCache) get(timeout time.Duration) (map[string]ipsetSnapshot, error) {
+	snapshots, _, err := c.getWithTime(timeout)
+	return snapshots, err
+}
+
+// getWithTime also returns when the listing was taken, which identifies it
+// for callers that cache what they derive from it.
+func (c *ipsetSnapshotCache) getWithTime(timeout time.Duration) (map[string]ipsetSnapshot, time.Time, error) {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	c.mu.Lock()
+	if c.snapshots != nil && c.clock().Sub(c.fetchedAt) < ipsetSnapshotTTL {
+		snapshots, fetchedAt := c.snapshots, c.fetchedAt
+		c.mu.Unlock()
+		return snapshots, fetchedAt, nil
+	}
+	fetch := c.inflight
+	if fetch == nil {
+		fetch = &ipsetSnapshotFetch{done: make(chan struct{})}
+		c.inflight = fetch
+		go c.fetch(fetch, timeout)
+	}
+	c.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-fetch.done:
+		return fetch.snapshots, fetch.fetchedAt, fetch.err
+	case <-timer.C:
+		return nil, time.Time{}, fmt.Errorf("ipset list timed out after %s", timeout)
+	}
+}
+
+func (c *ipsetSnapshotCache) fetch(fetch *ipsetSnapshotFetch, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	fetch.snapshots, fetch.err = c.list(ctx)
+
+	c.mu.Lock()
+	if fetch.err == nil {
+		fetch.fetchedAt = c.clock()
+		c.snapshots = fetch.snapshots
+		c.fetchedAt = fetch.fetchedAt
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+	close(fetch.done)
+}
+
+func (c *ipsetSnapshotCache) list(ctx context.Context) (map[string]ipsetSnapshot, error) {
+	out, err := c.command(ctx, "list", "-name")
+	if err != nil {
+		return nil, fmt.Errorf("ipset list -name failed: %w", err)
+	}
+	names := make([]string, 0)
+	for _, line := range strings.Split(string(out), "\n") {
+		name := strings.TrimSpace(line)
+		if strings.HasPrefix(name, ipsetSetPrefix) {
+			names = append(names, name)
+		}
+	}
+
+	result := make(map[string]ipsetSnapshot, len(names))
+	sem := make(chan struct{}, ipsetListConcurrency)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, name := range names {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			out, err := c.command(ctx, "list", name)
+			if err == nil {
+				var parsed map[string]ipsetSnapshot
+				if parsed, err = parseIPSetSnapshots(string(out)); err == nil {
+					mu.Lock()
+					for setName, snapshot := range parsed {
+						result[setName] = snapshot
+					}
+					mu.Unlock()
+					return
+				}
+			}
+			if ctx.Err() == nil && strings.Contains(err.Error(), "does not exist") {
+				// Removed by an apply between the name listing and now.
+				return
+			}
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("ipset list %s failed: %w", name, err)
+			}
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+func (c *ipsetSnapshotCache) command(ctx context.Context, args ...string) ([]byte, error) {
+	if c.run != nil {
+		return c.run(ctx, args...)
+	}
+	cmd := exec.CommandContext(ctx, "ipset", args...)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return out, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+func (c *ipsetSnapshotCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIPSetSnapshotCacheListsManagedSetsAndReusesResult(t *testing.T) {
+	var calls atomic.Int32
+	var mu sync.Mutex
+	listed := make([]string, 0)
+	now := time.Unix(1_700_000_000, 0)
+	cache := &ipsetSnapshotCache{
+		now: func() time.Time { return now },
+		run: func(ctx context.Context, args ...string) ([]byte, error) {
+			calls.Add(1)
+			if strings.Join(args, " ") == "list -name" {
+				return []byte("svpn_media_r1d4\nubios_lan\nsvpn_media_r1d6\nsvpn_gone_r1d4\n"), nil
+			}
+			mu.Lock()
+			listed = append(listed, args[1])
+			mu.Unlock()
+			switch args[1] {
+			case "svpn_media_r1d4":
+				return []byte("Name: svpn_media_r1d4\nNumber of entries: 2\nMembers:\n1.1.1.1 timeout 600\n10.0.0.0/8 timeout 600\n"), nil
+			case "svpn_media_r1d6":
+				return []byte("Name: svpn_media_r1d6\nNumber of entries: 0\nMembers:\n"), nil
+			default:
+				return nil, errors.New("exit status 1: ipset v7.15: The set with the given name does not exist")
+			}
+		},
+	}
+
+	snapshots, err := cache.get(time.Second)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots["svpn_media_r1d4"].Count != 2 || strings.Join(snapshots["svpn_media_r1d4"].Members, ",") != "1.1.1.1,10.0.0.0/8" {
+		t.Fatalf("unexpected snapshots %+v", snapshots)
+	}
+	for _, name := range listed {
+		if !strings.HasPrefix(name, "svpn_") {
+			t.Fatalf("listed an unmanaged set %q", name)
+		}
+	}
+
+	before := calls.Load()
+	if _, err := cache.get(time.Second); err != nil || calls.Load() != before {
+		t.Fatalf("expected a cached result, err=%v calls %d -> %d", err, before, calls.Load())
+	}
+	now = now.Add(ipsetSnapshotTTL)
+	if _, err := cache.get(time.Second); err != nil || calls.Load() == before {
+		t.Fatalf("expected a fresh listing after the TTL, err=%v", err)
+	}
+}
+
+func TestIPSetSnapshotCacheSharesInflightListing(t *testing.T) {
+	release := make(chan struct{})
+	var nameListings atomic.Int32
+	cache := &ipsetSnapshotCache{
+		run: func(ctx context.Context, args ...string) ([]byte, error) {
+			if strings.Join(args, " ") == "list -name" {
+				nameListings.Add(1)
+				<-release
+				return []byte("svpn_media_r1d4\n"), nil
+			}
+			return []byte("Name: svpn_media_r1d4\nNumber of entries: 0\nMembers:\n"), nil
+		},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.get(time.Second)
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+	}
+	if nameListings.Load() != 1 {
+		t.Fatalf("expected one shared listing, got %d", nameListings.Load())
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"split-vpn-webui/internal/jobs"
+	"split-vpn-webui/internal/prewarm"
+	"split-vpn-webui/internal/routing"
+)
+
+// schedulerJobTracker mirrors one scheduler's runs into the job queue. The
+// schedulers own their run loops; the tracker only observes progress events.
+type schedulerJobTracker struct {
+	mu     sync.Mutex
+	kind   jobs.Kind
+	handle *jobs.Handle
+	// trigger labels the next tracked run; runs without one were started by
+	// the scheduler's own timer.
+	trigger string
+}
+
+// runOutcome reports whether the scheduler's current run has finished and,
+// if so, the persisted error text of its last run.
+type runOutcome func() (finished bool, errText string)
+
+// expect labels the next run observed by the tracker.
+func (t *schedulerJobTracker) expect(trigger string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trigger = trigger
+}
+
+func (t *schedulerJobTracker) current() *jobs.Handle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.handle
+}
+
+// observe is called for every progress event. Schedulers emit a final event
+// after clearing their running flag, which is when the job is finished.
+func (t *schedulerJobTracker) observe(queue *jobs.Queue, progress any, cancel func() error, outcome runOutcome) {
+	if queue == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handle == nil {
+		trigger := t.trigger
+		if trigger == "" {
+			trigger = "schedule"
+		}
+		t.trigger = ""
+		t.handle = queue.Track(t.kind, trigger, cancel)
+		t.handle.Logf("info", "%s run started", t.kind)
+	}
+	t.handle.SetProgress(progress)
+	if finished, errText := outcome(); finished {
+		if errText != "" {
+			t.handle.Logf("error", "%s run finished with error: %s", t.kind, errText)
+		} else {
+			t.handle.Logf("info", "%s run finished", t.kind)
+		}
+		t.handle.Finish(runError(errText))
+		t.handle = nil
+	}
+}
+
+// runError converts a persisted run error back into an error, preserving
+// cancellation so the job ends in the canceled state.
+func runError(errText string) error {
+	switch {
+	case errText == "":
+		return nil
+	case strings.Contains(errText, context.Canceled.Error()):
+		return context.Canceled
+	default:
+		return errors.New(errText)
+	}
+}
+
+func (s *Server) observeResolverProgress(progress routing.ResolverProgress) {
+	if s.resolver == nil {
+		return
+	}
+	s.resolverJobs.observe(s.jobs, progress, s.resolver.CancelRun, func() (bool, string) {
+		status, err := s.resolver.Status(context.Background())
+		if err != nil || status.Running {
+			return false, ""
+		}
+		if status.LastRun == nil {
+			return true, ""
+		}
+		return true, status.LastRun.Error
+	})
+}
+
+func (s *Server) observePrewarmProgress(progress prewarm.Progress) {
+	if s.prewarm == nil {
+		return
+	}
+	s.prewarmJobs.observe(s.jobs, progress, s.prewarm.CancelRun, func() (bool, string) {
+		status, err := s.prewarm.Status(context.Background())
+		if err != nil || status.Running {
+			return false, ""
+		}
+		if status.LastRun == nil {
+			return true, ""
+		}
+		return true, status.LastRun.Error
+	})
+}
+
+// triggerTracked starts a scheduler run, labelling the resulting job with
+// trigger. The label is discarded if the run does not start.
+func triggerTracked(tracker *schedulerJobTracker, trigger string, start func() error) error {
+	if tracker != nil {
+		tracker.expect(trigger)
+	}
+	err := start()
+	if err != nil && tracker != nil {
+		tracker.expect("")
+	}
+	return err
+}
+
+// trackJob records a synchronous operation (group apply, backup) as a running
+// job. The returned handle is nil-safe when no queue is configured.
+func (s *Server) trackJob(kind jobs.Kind, trigger string) *jobs.Handle {
+	if s.jobs == nil {
+		return nil
+	}
+	return s.jobs.Track(kind, trigger, nil)
+}
+
+// prewarmJobLogger forwards pre-warm log lines to the diagnostics log and
+// copies info-and-above lines into the active pre-warm job's log.
+type prewarmJobLogger struct {
+	next    prewarm.Logger
+	tracker *schedulerJobTracker
+}
+
+func (l prewarmJobLogger) Debugf(format string, args ...any) {
+	if l.next != nil {
+		l.next.Debugf(format, args...)
+	}
+}
+
+func (l prewarmJobLogger) Infof(format string, args ...any) {
+	if l.next != nil {
+		l.next.Infof(format, args...)
+	}
+	l.tracker.current().Logf("info", format, args...)
+}
+
+func (l prewarmJobLogger) Warnf(format string, args ...any) {
+	if l.next != nil {
+		l.next.Warnf(format, args...)
+	}
+	l.tracker.current().Logf("warn", format, args...)
+}
+
+func (l prewarmJobLogger) Errorf(format string, args ...any) {
+	if l.next != nil {
+		l.next.Errorf(format, args...)
+	}
+	l.tracker.current().Logf("error", format, args...)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"split-vpn-webui/internal/jobs"
+)
+
+func TestSchedulerJobTrackerFollowsRunLifecycle(t *testing.T) {
+	queue := jobs.NewQueue()
+	tracker := &schedulerJobTracker{kind: jobs.KindResolver}
+	running := func() (bool, string) { return false, "" }
+
+	tracker.expect("api")
+	tracker.observe(queue, map[string]int{"done": 0}, nil, running)
+	tracker.observe(queue, map[string]int{"done": 1}, nil, running)
+
+	active, ok := queue.Active(jobs.KindResolver)
+	if !ok || active.Trigger != "api" {
+		t.Fatalf("expected one active api-triggered job, got %#v ok=%v", active, ok)
+	}
+	if got := len(queue.List(jobs.KindResolver, 0)); got != 1 {
+		t.Fatalf("expected progress events to update one job, got %d jobs", got)
+	}
+
+	tracker.observe(queue, map[string]int{"done": 2}, nil, func() (bool, string) {
+		return true, "resolve example.com: " + context.Canceled.Error()
+	})
+	job, err := queue.Get(active.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if job.State != jobs.StateCanceled {
+		t.Fatalf("expected canceled job, got %#v", job)
+	}
+
+	tracker.observe(queue, nil, nil, func() (bool, string) { return true, "" })
+	latest := queue.List(jobs.KindResolver, 1)[0]
+	if latest.ID == active.ID || latest.Trigger != "schedule" || latest.State != jobs.StateSucceeded {
+		t.Fatalf("expected a new scheduled job, got %#v", latest)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"split-vpn-webui/ui"
+)
+
+// DefaultKioskPort is used by kiosk listen entries that name no port.
+const DefaultKioskPort = "8092"
+
+// KioskRouter serves the read-only dashboard for wall-mounted screens on
+// the kiosk listeners. It has no login: every route is a read of status,
+// statistics or the inspectors, and anything but GET is refused, so the
+// port shows VPN health without exposing control. Profiles, settings, logs
+// and tokens are not served.
+func (s *Server) KioskRouter() (http.Handler, error) {
+	r := chi.NewRouter()
+	r.Use(middleware.RealIP)
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+	r.Use(kioskReadOnly)
+
+	staticFS, err := fs.Sub(ui.Assets, "web/static")
+	if err != nil {
+		return nil, err
+	}
+	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))))
+	r.Get("/", s.handleKiosk)
+
+	r.Route("/api", func(api chi.Router) {
+		api.Get("/health", s.handleHealth)
+		api.Get("/stream", s.handleStream)
+		api.Get("/stats", s.handleStats)
+		api.Get("/stats/query", s.handleStatsQuery)
+		api.Get("/wan", s.handleWANStatus)
+		api.Get("/anomalies", s.handleAnomalies)
+		api.Get("/quotas", s.handleListQuotas)
+		api.Get("/vpns/{name}/events", s.handleVPNEvents)
+		api.Get("/vpns/{name}/usage", s.handleVPNUsage)
+		api.Get("/vpns/{name}/routing-inspector", s.handleVPNRoutingInspector)
+		api.Get("/vpns/{name}/flow-inspector/history", s.handleVPNFlowHistory)
+	})
+	return r, nil
+}
+
+func (s *Server) handleKiosk(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "kiosk.html", nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// kioskReadOnly refuses every method that could change state, whatever
+// route it targets.
+func kioskReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "the kiosk is read-only"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKioskRouterIsReadOnly(t *testing.T) {
+	router, err := (&Server{}).KioskRouter()
+	if err != nil {
+		t.Fatalf("KioskRouter: %v", err)
+	}
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		// Mutations are refused before routing, even on read routes.
+		{http.MethodPost, "/api/vpns/wg0/start", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/api/settings", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/vpns/wg0/quota", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/stats", http.StatusMethodNotAllowed},
+		// Reads that reveal configuration or credentials are not served.
+		{http.MethodGet, "/api/settings", http.StatusNotFound},
+		{http.MethodGet, "/api/vpns", http.StatusNotFound},
+		{http.MethodGet, "/api/auth/token", http.StatusNotFound},
+		{http.MethodGet, "/api/logs", http.StatusNotFound},
+		{http.MethodGet, "/api/backup/export", http.StatusNotFound},
+		{http.MethodGet, "/static/js/app-kiosk.js", http.StatusOK},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/linkwatch"
+)
+
+// linkSettleDelay batches the burst of notifications a tunnel start or stop
+// produces (create, address, up) and gives the gateway route time to appear.
+const linkSettleDelay = 2 * time.Second
+
+// linkEventState batches link events for VPN interfaces until they settle.
+// apply is set when a tunnel interface appeared, so routing is re-applied.
+type linkEventState struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	apply bool
+}
+
+// configureLinkWatch keeps the watcher that reports interface hotplug.
+func (s *Server) configureLinkWatch(watcher *linkwatch.Watcher) {
+	s.linkWatch = watcher
+}
+
+// runLinkWatch follows link notifications until stop is closed. Without
+// rtnetlink the periodic refresh is all there is.
+func (s *Server) runLinkWatch(stop <-chan struct{}) {
+	if s.linkWatch == nil {
+		return
+	}
+	go func() {
+		<-stop
+		_ = s.linkWatch.Close()
+	}()
+	if err := s.linkWatch.Run(s.handleLinkEvent); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("link_watch disabled: %v", err)
+	}
+}
+
+// handleLinkEvent schedules a refresh for events on VPN interfaces, pushing
+// it back while more arrive.
+func (s *Server) handleLinkEvent(event linkwatch.Event) {
+	if event.Kind != linkwatch.Resync && !s.isVPNInterface(event.Name) {
+		return
+	}
+	if s.diagLog != nil {
+		s.diagLog.Debugf("link_watch %s interface=%s up=%t", event.Kind, event.Name, event.Up)
+	}
+	s.linkEvents.mu.Lock()
+	defer s.linkEvents.mu.Unlock()
+	if event.Kind == linkwatch.Added {
+		s.linkEvents.apply = true
+	}
+	if s.linkEvents.timer == nil {
+		s.linkEvents.timer = time.AfterFunc(linkSettleDelay, s.settleLinkEvents)
+		return
+	}
+	s.linkEvents.timer.Reset(linkSettleDelay)
+}
+
+func (s *Server) isVPNInterface(name string) bool {
+	if name == "" || s.configManager == nil {
+		return false
+	}
+	configs, err := s.configManager.List()
+	if err != nil {
+		return false
+	}
+	for _, cfg := range configs {
+		if cfg.InterfaceName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// settleLinkEvents brings status, latency targets and stats up to date
+// after VPN interfaces changed, and re-applies routing when a tunnel
+// appeared so its rules and routes point at the new interface.
+func (s *Server) settleLinkEvents() {
+	s.linkEvents.mu.Lock()
+	apply := s.linkEvents.apply
+	s.linkEvents.apply = false
+	s.linkEvents.mu.Unlock()
+
+	if err := s.refreshState(); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("link_watch refresh failed: %v", err)
+	}
+	if s.stats != nil {
+		s.stats.PollNow()
+	}
+	if s.latency != nil {
+		s.latency.CheckNow()
+	}
+	s.broadcastUpdate(nil)
+	if apply && s.routingManager != nil {
+		if err := s.routingManager.Apply(context.Background()); err != nil && s.diagLog != nil {
+			s.diagLog.Warnf("link_watch apply failed: %v", err)
+		}
+	}
+}
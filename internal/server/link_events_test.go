@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+
+	"split-vpn-webui/internal/linkwatch"
+	"split-vpn-webui/internal/systemd"
+)
+
+func TestHandleLinkEventIgnoresOtherInterfaces(t *testing.T) {
+	srv := newControlTestServer(t, &systemd.MockManager{})
+	srv.handleLinkEvent(linkwatch.Event{Kind: linkwatch.Added, Name: "eth0", Up: true})
+	if srv.linkEvents.timer != nil || srv.linkEvents.apply {
+		t.Fatalf("non-VPN interface scheduled a refresh")
+	}
+}
+
+func TestHandleLinkEventSchedulesRefreshAndApply(t *testing.T) {
+	srv := newControlTestServer(t, &systemd.MockManager{})
+	srv.handleLinkEvent(linkwatch.Event{Kind: linkwatch.Changed, Name: "wg-sv-fra"})
+	srv.linkEvents.mu.Lock()
+	if srv.linkEvents.timer == nil {
+		srv.linkEvents.mu.Unlock()
+		t.Fatalf("expected a refresh to be scheduled")
+	}
+	if srv.linkEvents.apply {
+		srv.linkEvents.mu.Unlock()
+		t.Fatalf("state change alone should not re-apply routing")
+	}
+	srv.linkEvents.mu.Unlock()
+
+	srv.handleLinkEvent(linkwatch.Event{Kind: linkwatch.Added, Name: "wg-sv-fra", Up: true})
+	srv.linkEvents.mu.Lock()
+	srv.linkEvents.timer.Stop()
+	apply := srv.linkEvents.apply
+	srv.linkEvents.mu.Unlock()
+	if !apply {
+		t.Fatalf("expected routing re-apply after the tunnel reappeared")
+	}
+
+	srv.settleLinkEvents()
+	if srv.linkEvents.apply {
+		t.Fatalf("apply flag not cleared after settling")
+	}
+}
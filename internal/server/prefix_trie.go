@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net/netip"
+)
+
+// prefixTrie is a path-compressed binary radix tree of prefixes, one tree
+// per address family, answering longest-prefix matches in time bounded by
+// the address length rather than the number of prefixes. When the same
+// prefix is inserted twice the first value is kept.
+type prefixTrie[V any] struct {
+	v4   *prefixTrieNode[V]
+	v6   *prefixTrieNode[V]
+	size int
+}
+
+type prefixTrieNode[V any] struct {
+	prefix   netip.Prefix
+	hasValue bool
+	value    V
+	child    [2]*prefixTrieNode[V]
+}
+
+// prefixSet is a prefixTrie used only for membership.
+type prefixSet = prefixTrie[struct{}]
+
+func newPrefixSet(prefixes []netip.Prefix) prefixSet {
+	var set prefixSet
+	for _, prefix := range prefixes {
+		set.insert(prefix, struct{}{})
+	}
+	return set
+}
+
+// Len returns the number of distinct prefixes in the trie.
+func (t *prefixTrie[V]) Len() int {
+	return t.size
+}
+
+// Contains reports whether any prefix in the trie contains addr.
+func (t *prefixTrie[V]) Contains(addr netip.Addr) bool {
+	_, ok := t.lookup(addr)
+	return ok
+}
+
+func (t *prefixTrie[V]) insert(prefix netip.Prefix, value V) {
+	if !prefix.IsValid() {
+		return
+	}
+	prefix = prefix.Masked()
+	link := &t.v6
+	if prefix.Addr().Is4() {
+		link = &t.v4
+	}
+	for {
+		node := *link
+		if node == nil {
+			*link = &prefixTrieNode[V]{prefix: prefix, hasValue: true, value: value}
+			t.size++
+			return
+		}
+		common := commonPrefixBits(node.prefix, prefix)
+		switch {
+		case common == node.prefix.Bits() && common == prefix.Bits():
+			if !node.hasValue {
+				node.hasValue = true
+				node.value = value
+				t.size++
+			}
+			return
+		case common == node.prefix.Bits():
+			link = &node.child[addrBit(prefix.Addr(), common)]
+			continue
+		case common == prefix.Bits():
+			parent := &prefixTrieNode[V]{prefix: prefix, hasValue: true, value: value}
+			parent.child[addrBit(node.prefix.Addr(), common)] = node
+			*link = parent
+			t.size++
+			return
+		default:
+			branch := &prefixTrieNode[V]{prefix: netip.PrefixFrom(prefix.Addr(), common).Masked()}
+			branch.child[addrBit(node.prefix.Addr(), common)] = node
+			branch.child[addrBit(prefix.Addr(), common)] = &prefixTrieNode[V]{prefix: prefix, hasValue: true, value: value}
+			*link = branch
+			t.size++
+			return
+		}
+	}
+}
+
+// lookup returns the value of the longest prefix containing addr.
+func (t *prefixTrie[V]) lookup(addr netip.Addr) (V, bool) {
+	var (
+		best  V
+		found bool
+	)
+	if t == nil || !addr.IsValid() {
+		return best, false
+	}
+	node := t.v6
+	if addr.Is4() {
+		node = t.v4
+	}
+	for node != nil && node.prefix.Contains(addr) {
+		if node.hasValue {
+			best, found = node.value, true
+		}
+		if node.prefix.Bits() == addr.BitLen() {
+			break
+		}
+		node = node.child[addrBit(addr, node.prefix.Bits())]
+	}
+	return best, found
+}
+
+// commonPrefixBits returns how many leading bits two same-family prefixes
+// share, up to the shorter prefix length.
+func commonPrefixBits(left, right netip.Prefix) int {
+	limit := min(left.Bits(), right.Bits())
+	for bit := 0; bit < limit; bit++ {
+		if addrBit(left.Addr(), bit) != addrBit(right.Addr(), bit) {
+			return bit
+		}
+	}
+	return limit
+}
+
+func addrBit(addr netip.Addr, bit int) int {
+	if addr.Is4() {
+		bytes := addr.As4()
+		return int(bytes[bit/8]>>(7-bit%8)) & 1
+	}
+	bytes := addr.As16()
+	return int(bytes[bit/8]>>(7-bit%8)) & 1
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestPrefixTrieLongestMatch(t *testing.T) {
+	index := &prefixTrie[string]{}
+	for _, entry := range []struct{ prefix, value string }{
+		{"10.0.0.0/8", "ten"},
+		{"10.1.0.0/16", "ten-one"},
+		{"10.1.2.3/32", "host"},
+		{"10.128.0.0/9", "upper"},
+		{"10.1.0.0/16", "duplicate"},
+		{"2001:db8::/32", "doc"},
+		{"2001:db8:1::/48", "doc-one"},
+	} {
+		index.insert(netip.MustParsePrefix(entry.prefix), entry.value)
+	}
+	if index.Len() != 6 {
+		t.Fatalf("expected 6 distinct prefixes, got %d", index.Len())
+	}
+
+	for _, tc := range []struct{ addr, want string }{
+		{"10.1.2.3", "host"},
+		{"10.1.2.4", "ten-one"},
+		{"10.200.0.1", "upper"},
+		{"10.2.0.1", "ten"},
+		{"11.0.0.1", ""},
+		{"2001:db8:1::5", "doc-one"},
+		{"2001:db8:2::5", "doc"},
+		{"2001:db9::1", ""},
+	} {
+		got, _ := index.lookup(netip.MustParseAddr(tc.addr))
+		if got != tc.want {
+			t.Fatalf("lookup(%s) = %q, want %q", tc.addr, got, tc.want)
+		}
+	}
+}
+
+func TestPrefixSetMatchesLinearScan(t *testing.T) {
+	prefixes := make([]netip.Prefix, 0, 512)
+	for i := 0; i < 512; i++ {
+		bits := 16 + i%17
+		addr := netip.AddrFrom4([4]byte{byte(i * 37), byte(i * 11), byte(i), byte(i * 3)})
+		prefixes = append(prefixes, netip.PrefixFrom(addr, bits).Masked())
+	}
+	set := newPrefixSet(prefixes)
+	for i := 0; i < 4096; i++ {
+		addr := netip.AddrFrom4([4]byte{byte(i * 37), byte(i * 13), byte(i * 7), byte(i)})
+		want := false
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				want = true
+				break
+			}
+		}
+		if got := set.Contains(addr); got != want {
+			t.Fatalf("Contains(%s) = %v, linear scan says %v", addr, got, want)
+		}
+	}
+}
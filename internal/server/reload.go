@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"split-vpn-webui/internal/listen"
+	"split-vpn-webui/internal/routing"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/stats"
+	"split-vpn-webui/internal/update"
+)
+
+// maxRuntimeIntervalSeconds bounds the poll and latency interval settings.
+const maxRuntimeIntervalSeconds = 3600
+
+// ListenReloader re-binds the HTTP listeners for a listen setting and
+// returns the addresses now being served.
+type ListenReloader func(spec string) ([]listen.Address, error)
+
+// DNSBackendFactory builds the routing DNS backend for a settings change,
+// with the data directory and command executor the process runs with.
+type DNSBackendFactory func(cfg routing.DNSBackendConfig) (routing.DNSManager, error)
+
+// ReloadResult reports how a settings save was applied.
+type ReloadResult struct {
+	// Applied lists the settings that took effect in-process.
+	Applied   []string         `json:"applied,omitempty"`
+	Listeners []listen.Address `json:"listeners,omitempty"`
+	// KioskListeners is set when the kiosk listeners were re-bound; empty
+	// after the kiosk was turned off.
+	KioskListeners []listen.Address `json:"kioskListeners,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	// RestartRequired is set when a change could only apply after a
+	// restart; Restarting when one was scheduled.
+	RestartRequired bool `json:"restartRequired"`
+	Restarting      bool `json:"restarting"`
+}
+
+// SetListenReloader lets the server re-bind listeners when the listen setting
+// changes. Without one, listen changes fall back to a service restart.
+func (s *Server) SetListenReloader(reload ListenReloader) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.listenReload = reload
+}
+
+// SetKioskListenReloader lets the server re-bind the read-only kiosk
+// listeners when the kiosk setting changes.
+func (s *Server) SetKioskListenReloader(reload ListenReloader) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.kioskReload = reload
+}
+
+// SetDNSBackendFactory sets how DNS backend changes build the new backend.
+// Without one, an AdGuard backend needs an explicit ipset file path.
+func (s *Server) SetDNSBackendFactory(factory DNSBackendFactory) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.dnsBackends = factory
+}
+
+// applyRuntimeSettings reconfigures running components for settings that
+// changed between prev and next. Listen changes re-bind in-process and only
+// require a restart when no reloader is set or the reload fails.
+func (s *Server) applyRuntimeSettings(prev, next settings.Settings) ReloadResult {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	var result ReloadResult
+
+	if s.applyIntervals(next) {
+		result.Applied = append(result.Applied, "intervals")
+	}
+
+	if s.updater != nil {
+		s.updater.Configure(update.PreferencesFromSettings(next))
+	}
+
+	if prev.StatsDisabledInterfaces != next.StatsDisabledInterfaces && s.stats != nil {
+		s.stats.SetDisabledInterfaces(stats.ParseInterfaceList(next.StatsDisabledInterfaces))
+		result.Applied = append(result.Applied, "stats interfaces")
+	}
+
+	if prev.WANInterface != next.WANInterface || prev.WANPriority != next.WANPriority {
+		// refreshState already picked up an explicit WAN; a cleared one has
+		// to drop the old choice before auto-detection runs again.
+		if next.WANInterface == "" && s.stats != nil {
+			s.stats.SetWANInterface("")
+			if err := s.refreshState(); err != nil && s.diagLog != nil {
+				s.diagLog.Warnf("settings reload: refresh state failed: %v", err)
+			}
+		}
+		result.Applied = append(result.Applied, "wan")
+	}
+
+	if s.routingManager != nil && routing.DNSBackendConfigFromSettings(prev) != routing.DNSBackendConfigFromSettings(next) {
+		if err := s.applyDNSBackend(next); err != nil {
+			result.Error = fmt.Sprintf("switch dns backend: %v", err)
+			if s.diagLog != nil {
+				s.diagLog.Errorf("settings reload: %s", result.Error)
+			}
+		} else {
+			result.Applied = append(result.Applied, "dns backend")
+		}
+	}
+
+	if prev.ListenInterface != next.ListenInterface {
+		if s.listenReload == nil {
+			result.RestartRequired = true
+		} else if addrs, err := s.listenReload(next.ListenInterface); err != nil {
+			result.Error = fmt.Sprintf("re-bind listeners: %v", err)
+			result.RestartRequired = true
+			if s.diagLog != nil {
+				s.diagLog.Errorf("settings reload: %s", result.Error)
+			}
+		} else {
+			result.Applied = append(result.Applied, "listen")
+			result.Listeners = addrs
+			if s.diagLog != nil {
+				s.diagLog.Infof("settings reload: now listening on %d address(es)", len(addrs))
+			}
+		}
+	}
+
+	if prev.KioskListen != next.KioskListen {
+		if s.kioskReload == nil {
+			result.RestartRequired = true
+		} else if addrs, err := s.kioskReload(next.KioskListen); err != nil {
+			result.Error = fmt.Sprintf("re-bind kiosk listeners: %v", err)
+			result.RestartRequired = true
+			if s.diagLog != nil {
+				s.diagLog.Errorf("settings reload: %s", result.Error)
+			}
+		} else {
+			result.Applied = append(result.Applied, "kiosk")
+			result.KioskListeners = addrs
+			if s.diagLog != nil {
+				s.diagLog.Infof("settings reload: kiosk listening on %d address(es)", len(addrs))
+			}
+		}
+	}
+	return result
+}
+
+// applyDNSBackend swaps the routing DNS backend and re-applies routing so
+// the new backend's config is written.
+func (s *Server) applyDNSBackend(current settings.Settings) error {
+	cfg := routing.DNSBackendConfigFromSettings(current)
+	var backend routing.DNSManager
+	var err error
+	if s.dnsBackends != nil {
+		backend, err = s.dnsBackends(cfg)
+	} else {
+		backend, err = routing.NewDNSBackend(cfg, "", nil)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.routingManager.SetDNSBackend(backend); err != nil && s.diagLog != nil {
+		s.diagLog.Warnf("settings reload: %v", err)
+	}
+	return s.routingManager.Apply(context.Background())
+}
+
+// applyIntervals sets the collector and latency intervals from settings,
+// falling back to the values the process started with; adaptive polling may
+// stretch the collector's. It reports whether anything changed.
+func (s *Server) applyIntervals(current settings.Settings) bool {
+	changed := false
+	if s.stats != nil {
+		poll := s.defaultPoll
+		if current.StatsPollSeconds > 0 {
+			poll = time.Duration(current.StatsPollSeconds) * time.Second
+		}
+		if poll > 0 && s.setConfiguredPoll(poll) {
+			changed = true
+		}
+	}
+	if s.latency != nil {
+		interval := s.defaultLatency
+		if current.LatencyIntervalSeconds > 0 {
+			interval = time.Duration(current.LatencyIntervalSeconds) * time.Second
+		}
+		if interval > 0 && interval != s.latency.Interval() {
+			s.latency.SetInterval(interval)
+			changed = true
+		}
+	}
+	return changed
+}
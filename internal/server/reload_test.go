@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/latency"
+	"split-vpn-webui/internal/listen"
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/stats"
+)
+
+func TestApplyRuntimeSettingsIntervals(t *testing.T) {
+	s := &Server{
+		stats:          stats.NewCollector("", 2*time.Second, 10),
+		latency:        latency.NewMonitor(10 * time.Second),
+		defaultPoll:    2 * time.Second,
+		defaultLatency: 10 * time.Second,
+	}
+	result := s.applyRuntimeSettings(settings.Settings{}, settings.Settings{StatsPollSeconds: 5, LatencyIntervalSeconds: 30})
+	if len(result.Applied) != 1 || result.Applied[0] != "intervals" || result.RestartRequired {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if s.stats.PollInterval() != 5*time.Second || s.latency.Interval() != 30*time.Second {
+		t.Fatalf("intervals not applied: poll=%s latency=%s", s.stats.PollInterval(), s.latency.Interval())
+	}
+
+	// Clearing the settings returns to the flag values.
+	s.applyRuntimeSettings(settings.Settings{StatsPollSeconds: 5}, settings.Settings{})
+	if s.stats.PollInterval() != 2*time.Second || s.latency.Interval() != 10*time.Second {
+		t.Fatalf("defaults not restored: poll=%s latency=%s", s.stats.PollInterval(), s.latency.Interval())
+	}
+}
+
+func TestApplyRuntimeSettingsListen(t *testing.T) {
+	s := &Server{}
+	result := s.applyRuntimeSettings(settings.Settings{}, settings.Settings{ListenInterface: "br0"})
+	if !result.RestartRequired {
+		t.Fatalf("expected restart without a listen reloader: %+v", result)
+	}
+
+	var got string
+	s.SetListenReloader(func(spec string) ([]listen.Address, error) {
+		got = spec
+		return []listen.Address{{Addr: "192.168.1.1:8091", Source: "br0"}}, nil
+	})
+	result = s.applyRuntimeSettings(settings.Settings{}, settings.Settings{ListenInterface: "br0"})
+	if got != "br0" || result.RestartRequired || len(result.Listeners) != 1 {
+		t.Fatalf("expected in-process re-bind, got spec=%q result=%+v", got, result)
+	}
+
+	s.SetListenReloader(func(string) ([]listen.Address, error) {
+		return nil, errors.New("address in use")
+	})
+	result = s.applyRuntimeSettings(settings.Settings{}, settings.Settings{ListenInterface: "br1"})
+	if !result.RestartRequired || result.Error == "" {
+		t.Fatalf("expected failed reload to require a restart: %+v", result)
+	}
+}
+
+func TestApplyRuntimeSettingsKiosk(t *testing.T) {
+	s := &Server{}
+	var got []string
+	s.SetKioskListenReloader(func(spec string) ([]listen.Address, error) {
+		got = append(got, spec)
+		if spec == "" {
+			return nil, nil
+		}
+		return []listen.Address{{Addr: "192.168.1.1:8092", Source: spec}}, nil
+	})
+	result := s.applyRuntimeSettings(settings.Settings{}, settings.Settings{KioskListen: "br0"})
+	if result.RestartRequired || len(result.KioskListeners) != 1 || len(result.Applied) != 1 || result.Applied[0] != "kiosk" {
+		t.Fatalf("expected kiosk listeners to re-bind, got %+v", result)
+	}
+	result = s.applyRuntimeSettings(settings.Settings{KioskListen: "br0"}, settings.Settings{})
+	if result.RestartRequired || len(result.KioskListeners) != 0 || len(got) != 2 || got[1] != "" {
+		t.Fatalf("expected kiosk to turn off, got spec=%q result=%+v", got, result)
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"split-vpn-webui/internal/auth"
+	"split-vpn-webui/ui"
+)
+
+// Router constructs the http.Handler with all routes.
+func (s *Server) Router() (http.Handler, error) {
+	if err := s.refreshState(); err != nil {
+		return nil, err
+	}
+	s.applyAutostart()
+
+	r := chi.NewRouter()
+	r.Use(auth.RecordPeer)
+	r.Use(middleware.RealIP)
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	// Static assets — public, needed by the login page.
+	staticFS, err := fs.Sub(ui.Assets, "web/static")
+	if err != nil {
+		return nil, err
+	}
+	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))))
+
+	// Auth endpoints — always public.
+	r.Get("/login", s.handleLoginGet)
+	r.Post("/login", s.handleLoginPost)
+	r.Post("/logout", s.handleLogout)
+
+	// Privacy-filtered VPN status — public, but 404s unless enabled in settings.
+	r.Get("/api/public/status", s.handlePublicStatus)
+
+	// Liveness and schema version for monitoring — public.
+	r.Get("/api/health", s.handleHealth)
+
+	// Home Assistant RESTful sensors and switches — the API token or the
+	// narrower Home Assistant token.
+	r.Group(func(ha chi.Router) {
+		ha.Use(s.auth.HomeAssistantMiddleware)
+		ha.Get("/api/ha/vpns", s.handleHomeAssistantVPNs)
+		ha.Get("/api/ha/vpns/{name}", s.handleHomeAssistantVPN)
+		ha.Post("/api/ha/vpns/{name}", s.handleHomeAssistantSwitch)
+	})
+
+	// All remaining routes require authentication.
+	r.Group(func(protected chi.Router) {
+		protected.Use(s.auth.Middleware)
+
+		protected.Get("/", s.handleIndex)
+
+		protected.Route("/api", func(api chi.Router) {
+			s.routingRoutes(api)
+			s.vpnRoutes(api)
+			s.authRoutes(api)
+			s.systemRoutes(api)
+		})
+	})
+
+	return r, nil
+}
+
+// routingRoutes registers domain groups and their templates, the trash,
+// device groups, the routing tools, WAN status, and the resolver, pre-warm
+// and job schedulers.
+func (s *Server) routingRoutes(api chi.Router) {
+	api.Get("/groups", s.handleListGroups)
+	api.Post("/groups", s.handleCreateGroup)
+	api.Put("/groups/order", s.handleReorderGroups)
+	api.Get("/groups/{id}", s.handleGetGroup)
+	api.Put("/groups/{id}", s.handleUpdateGroup)
+	api.Post("/groups/{id}/paste", s.handlePasteGroupSelectors)
+	api.Post("/groups/{id}/duplicate", s.handleDuplicateGroup)
+	api.Delete("/groups/{id}", s.handleDeleteGroup)
+	api.Get("/groups/canary", s.handleGetCanary)
+	api.Post("/groups/canary/promote", s.handlePromoteCanary)
+	api.Post("/groups/canary/rollback", s.handleRollbackCanary)
+	api.Post("/groups/{id}/canary", s.handleStartCanary)
+	api.Get("/group-templates", s.handleListGroupTemplates)
+	api.Post("/group-templates", s.handleCreateGroupTemplate)
+	api.Delete("/group-templates/{id}", s.handleDeleteGroupTemplate)
+	api.Post("/group-templates/{id}/instantiate", s.handleInstantiateGroupTemplate)
+	api.Get("/trash", s.handleListTrash)
+	api.Post("/trash/groups/{id}/restore", s.handleRestoreTrashedGroup)
+	api.Delete("/trash/groups/{id}", s.handleDeleteTrashedGroup)
+	api.Post("/trash/vpns/{id}/restore", s.handleRestoreTrashedVPN)
+	api.Delete("/trash/vpns/{id}", s.handleDeleteTrashedVPN)
+	api.Get("/device-groups", s.handleListDeviceGroups)
+	api.Post("/device-groups", s.handleCreateDeviceGroup)
+	api.Post("/device-groups/sync", s.handleSyncDeviceGroups)
+	api.Put("/device-groups/{id}", s.handleUpdateDeviceGroup)
+	api.Delete("/device-groups/{id}", s.handleDeleteDeviceGroup)
+	api.Post("/routing/asn-preview", s.handleASNPreview)
+	api.Post("/routing/apply", s.handleRoutingApply)
+	api.Get("/routing/apply/stats", s.handleRoutingApplyStats)
+	api.Get("/routing/staging", s.handleRoutingStaging)
+	api.Put("/routing/staging", s.handleSetRoutingStaging)
+	api.Post("/routing/staging/publish", s.handlePublishRoutingStaging)
+	api.Post("/routing/staging/discard", s.handleDiscardRoutingStaging)
+	api.Get("/routing/drift", s.handleRoutingDrift)
+	api.Post("/routing/drift/check", s.handleRoutingDriftCheck)
+	api.Get("/routing/provision", s.handleRoutingProvision)
+	api.Get("/routing/dnsmasq", s.handleRoutingDnsmasq)
+	api.Get("/routing/dns-bypass", s.handleRoutingDNSBypass)
+	api.Get("/routing/dns-bypass/rules", s.handleRoutingDNSBypassRules)
+	api.Post("/routing/trace", s.handleRouteTrace)
+	api.Get("/routing/conflicts", s.handleRoutingConflicts)
+	api.Post("/routing/conflicts", s.handleCheckRoutingConflicts)
+	api.Get("/routing/search", s.handleRoutingSearch)
+	api.Get("/routing/networks", s.handleRoutingNetworks)
+	api.Get("/routing/guest-safe-mode", s.handleGuestSafeMode)
+	api.Put("/routing/guest-safe-mode", s.handleSetGuestSafeMode)
+	api.Post("/routing/groups/{id}/verify", s.handleVerifyGroup)
+	api.Get("/wan", s.handleWANStatus)
+	api.Post("/resolver/run", s.handleResolverRun)
+	api.Post("/resolver/clear-run", s.handleResolverClearRun)
+	api.Post("/resolver/resume", s.handleResolverResume)
+	api.Get("/resolver/runs", s.handleResolverRuns)
+	api.Get("/resolver/runs/{id}", s.handleResolverRunDetail)
+	api.Post("/prewarm/run", s.handlePrewarmRun)
+	api.Post("/prewarm/clear-run", s.handlePrewarmClearRun)
+	api.Post("/prewarm/stop", s.handlePrewarmStop)
+	api.Get("/prewarm/runs", s.handlePrewarmRuns)
+	api.Get("/prewarm/runs/{id}", s.handlePrewarmRunDetail)
+	api.Get("/prewarm/runs/{id}/diff", s.handlePrewarmRunDiff)
+	api.Get("/jobs", s.handleListJobs)
+	api.Post("/jobs", s.handleCreateJob)
+	api.Get("/jobs/{id}", s.handleGetJob)
+	api.Get("/jobs/{id}/logs", s.handleJobLogs)
+	api.Post("/jobs/{id}/cancel", s.handleCancelJob)
+}
+
+// vpnRoutes registers VPN profiles and their config files, lifecycle,
+// history and diagnostics, the flow inspector, logs and devices.
+func (s *Server) vpnRoutes(api chi.Router) {
+	api.Get("/vpns", s.handleListVPNs)
+	api.Post("/vpns", s.handleCreateVPN)
+	api.Get("/vpns/{name}", s.handleGetVPN)
+	api.Put("/vpns/{name}", s.handleUpdateVPN)
+	api.Delete("/vpns/{name}", s.handleDeleteVPN)
+	api.Post("/vpns/{name}/start", s.handleStartVPN)
+	api.Post("/vpns/{name}/stop", s.handleStopVPN)
+	api.Post("/vpns/{name}/restart", s.handleRestartVPN)
+	api.Get("/vpns/{name}/events", s.handleVPNEvents)
+	api.Get("/vpns/{name}/usage", s.handleVPNUsage)
+	api.Put("/vpns/{name}/quota", s.handleSetVPNQuota)
+	api.Delete("/vpns/{name}/quota", s.handleDeleteVPNQuota)
+	api.Post("/vpns/{name}/quota/reset", s.handleResetVPNQuota)
+	api.Get("/vpns/{name}/revisions", s.handleListVPNRevisions)
+	api.Get("/vpns/{name}/revisions/diff", s.handleDiffVPNRevisions)
+	api.Get("/vpns/{name}/revisions/{revision}", s.handleGetVPNRevision)
+	api.Post("/vpns/{name}/revisions/{revision}/revert", s.handleRevertVPNRevision)
+	api.Post("/vpns/{name}/dns-leak-test", s.handleVPNDNSLeakTest)
+	api.Post("/vpns/{name}/mtu-probe", s.handleVPNMTUProbe)
+	api.Get("/vpns/{name}/routing-inspector", s.handleVPNRoutingInspector)
+	api.Get("/diagnostics/capture/interfaces", s.handleListCaptureInterfaces)
+	api.Get("/diagnostics/capture", s.handleCapture)
+	api.Post("/diagnostics/mtr", s.handleMTR)
+	api.Get("/diagnostics/bundle", s.handleDiagnosticsBundle)
+	api.Get("/logs", s.handleListLogs)
+	api.Get("/logs/stream", s.handleStreamLogs)
+	api.Post("/vpns/{name}/flow-inspector/start", s.handleStartVPNFlowInspector)
+	api.Get("/vpns/{name}/flow-inspector/history", s.handleVPNFlowHistory)
+	api.Get("/flow-inspector/accounting", s.handleGetConntrackAccounting)
+	api.Post("/flow-inspector/accounting", s.handleSetConntrackAccounting)
+	api.Get("/vpns/{name}/flow-inspector/{sessionID}", s.handlePollVPNFlowInspector)
+	api.Post("/vpns/{name}/flow-inspector/{sessionID}/stop", s.handleStopVPNFlowInspector)
+	api.Get("/devices", s.handleListDevices)
+	api.Get("/device-aliases", s.handleListDeviceAliases)
+	api.Put("/device-aliases/{mac}", s.handleSetDeviceAlias)
+	api.Delete("/device-aliases/{mac}", s.handleDeleteDeviceAlias)
+	api.Get("/configs", s.handleListConfigs)
+	api.Get("/configs/{name}/file", s.handleReadConfig)
+	api.Put("/configs/{name}/file", s.handleWriteConfig)
+	// The editor's original revision routes, now served from the
+	// SQLite history; rollback is revert.
+	api.Get("/configs/{name}/revisions", s.handleListVPNRevisions)
+	api.Get("/configs/{name}/revisions/{revision}", s.handleGetVPNRevision)
+	api.Post("/configs/{name}/revisions/{revision}/rollback", s.handleRevertVPNRevision)
+	api.Post("/configs/{name}/start", s.handleStartVPN)
+	api.Post("/configs/{name}/stop", s.handleStopVPN)
+	api.Post("/configs/{name}/autostart", s.handleAutostart)
+}
+
+// authRoutes registers tokens, password changes, auth events and sessions.
+func (s *Server) authRoutes(api chi.Router) {
+	api.Get("/auth/token", s.handleGetAuthToken)
+	api.Post("/auth/token", s.handleRegenerateAuthToken)
+	api.Get("/auth/ha-token", s.handleGetHomeAssistantToken)
+	api.Post("/auth/ha-token", s.handleRegenerateHomeAssistantToken)
+	api.Delete("/auth/ha-token", s.handleRevokeHomeAssistantToken)
+	api.Post("/auth/password", s.handleChangePassword)
+	api.Get("/auth/events", s.handleListAuthEvents)
+	api.Get("/auth/sessions", s.handleListSessions)
+	api.Post("/auth/sessions/revoke-others", s.handleRevokeOtherSessions)
+	api.Delete("/auth/sessions/{id}", s.handleRevokeSession)
+}
+
+// systemRoutes registers process control, stats, monitors, peer sync and
+// agents, integration status, the live streams, settings, updates and
+// backups.
+func (s *Server) systemRoutes(api chi.Router) {
+	api.Post("/reload", s.handleReload)
+	api.Post("/system/restart", s.handleSystemRestart)
+	api.Get("/system/capabilities", s.handleSystemCapabilities)
+	api.Get("/database/status", s.handleDatabaseStatus)
+	api.Get("/stats", s.handleStats)
+	api.Get("/stats/query", s.handleStatsQuery)
+	api.Get("/anomalies", s.handleAnomalies)
+	api.Get("/quotas", s.handleListQuotas)
+	api.Get("/unblock", s.handleUnblockResults)
+	api.Post("/unblock/run", s.handleRunUnblockCheck)
+	api.Get("/on-demand", s.handleOnDemandStatus)
+	api.Get("/endpoint-refresh", s.handleEndpointRefreshStatus)
+	api.Get("/sync/snapshot", s.handleSyncSnapshot)
+	api.Get("/sync/status", s.handleSyncStatus)
+	api.Post("/sync/run", s.handleSyncRun)
+	api.Get("/agents", s.handleListAgents)
+	api.Post("/agents", s.handleCreateAgent)
+	api.Post("/agents/host-key", s.handleScanAgentHostKey)
+	api.Put("/agents/{id}", s.handleUpdateAgent)
+	api.Delete("/agents/{id}", s.handleDeleteAgent)
+	api.Post("/agents/{id}/apply", s.handleApplyAgent)
+	api.Get("/mqtt/status", s.handleMQTTStatus)
+	api.Get("/metrics-export/status", s.handleMetricsExportStatus)
+	api.Get("/log-shipping/status", s.handleLogShipStatus)
+	api.Get("/ipfix/status", s.handleIPFIXStatus)
+	api.Get("/stream", s.handleStream)
+	api.Get("/speedtest/stream", s.handleSpeedtestStream)
+	api.Get("/settings", s.handleGetSettings)
+	api.Put("/settings", s.handleSaveSettings)
+	api.Get("/settings/revisions", s.handleListSettingsRevisions)
+	api.Get("/settings/revisions/{revision}", s.handleGetSettingsRevision)
+	api.Post("/settings/revisions/{revision}/rollback", s.handleRollbackSettingsRevision)
+	api.Get("/update/status", s.handleUpdateStatus)
+	api.Post("/update/check", s.handleCheckUpdates)
+	api.Post("/update/apply", s.handleApplyUpdate)
+	api.Post("/update/rollback", s.handleRollbackUpdate)
+	api.Post("/update/preflight", s.handlePreflightUpdate)
+	api.Get("/backup/export", s.handleExportBackup)
+	api.Post("/backup/import", s.handleImportBackup)
+}
@@ -3,7 +3,6 @@ package server
 import (
 	"context"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -33,10 +32,14 @@ func (s *Server) collectRoutingSizes(ctx context.Context) (map[string]vpnRouting
 		return map[string]vpnRoutingSizes{}, nil
 	}
 
-	allSetSizes, err := readIPSetSizes(5 * time.Second)
+	snapshots, err := s.ipsetSnapshots(5 * time.Second)
 	if err != nil {
 		return nil, err
 	}
+	allSetSizes := make(map[string]int, len(snapshots))
+	for name, snapshot := range snapshots {
+		allSetSizes[name] = snapshot.Count
+	}
 
 	out := make(map[string]vpnRoutingSizes)
 	for _, group := range groups {
@@ -89,31 +92,6 @@ func ruleNeedsExcludedDestinationSet(rule routing.RoutingRule) bool {
 		len(rule.ExcludedDestinationASNs) > 0
 }
 
-func readIPSetSizes(timeout time.Duration) (map[string]int, error) {
-	snapshots, err := readIPSetSnapshots(timeout)
-	if err != nil {
-		return nil, err
-	}
-	result := make(map[string]int, len(snapshots))
-	for name, snapshot := range snapshots {
-		result[name] = snapshot.Count
-	}
-	return result, nil
-}
-
-func readIPSetSnapshots(timeout time.Duration) (map[string]ipsetSnapshot, error) {
-	if timeout <= 0 {
-		timeout = 5 * time.Second
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	out, err := exec.CommandContext(ctx, "ipset", "list").Output()
-	if err != nil {
-		return nil, fmt.Errorf("ipset list failed: %w", err)
-	}
-	return parseIPSetSnapshots(string(out))
-}
-
 func parseIPSetSizes(raw string) (map[string]int, error) {
 	snapshots, err := parseIPSetSnapshots(raw)
 	if err != nil {
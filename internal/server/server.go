@@ -3,27 +3,51 @@ package server
 import (
 	"fmt"
 	"html/template"
-	"io/fs"
-	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-
+	"split-vpn-webui/internal/agent"
+	"split-vpn-webui/internal/anomaly"
 	"split-vpn-webui/internal/auth"
+	"split-vpn-webui/internal/authevents"
 	"split-vpn-webui/internal/backup"
+	"split-vpn-webui/internal/capabilities"
 	"split-vpn-webui/internal/config"
+	"split-vpn-webui/internal/dbmaint"
+	"split-vpn-webui/internal/diagbundle"
 	"split-vpn-webui/internal/diaglog"
+	"split-vpn-webui/internal/dnsleak"
+	"split-vpn-webui/internal/egresscheck"
+	"split-vpn-webui/internal/endpointrefresh"
+	"split-vpn-webui/internal/flowhistory"
+	"split-vpn-webui/internal/hostnames"
+	"split-vpn-webui/internal/ipfix"
+	"split-vpn-webui/internal/jobs"
 	"split-vpn-webui/internal/latency"
+	"split-vpn-webui/internal/linkwatch"
+	"split-vpn-webui/internal/logship"
+	"split-vpn-webui/internal/metricsexport"
+	"split-vpn-webui/internal/mqtt"
+	"split-vpn-webui/internal/mtr"
+	"split-vpn-webui/internal/ondemand"
+	"split-vpn-webui/internal/pcap"
+	"split-vpn-webui/internal/peersync"
+	"split-vpn-webui/internal/pmtu"
 	"split-vpn-webui/internal/prewarm"
+	"split-vpn-webui/internal/quota"
+	"split-vpn-webui/internal/reputation"
 	"split-vpn-webui/internal/routing"
 	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/settingsrevisions"
 	"split-vpn-webui/internal/stats"
 	"split-vpn-webui/internal/systemd"
+	"split-vpn-webui/internal/unblock"
 	"split-vpn-webui/internal/update"
 	"split-vpn-webui/internal/vpn"
+	"split-vpn-webui/internal/vpnevents"
+	"split-vpn-webui/internal/vpnrevisions"
+	"split-vpn-webui/internal/wan"
 	"split-vpn-webui/ui"
 )
 
@@ -64,22 +88,85 @@ type Server struct {
 	auth           *auth.Manager
 	backup         *backup.Manager
 	updater        *update.Manager
+	autoUpdate     *update.Scheduler
 	templates      *template.Template
 
 	systemdManaged bool
 	flowInspector  *vpnFlowInspector
 	flowRunner     conntrackRunner
+	reputation     *reputation.Checker
+	dnsLeak        dnsLeakRunner
+	egressCheck    egressCheckRunner
+	mtuProbe       mtuProber
+	capture        packetCapturer
+	tracer         pathTracer
+	routes         routeLookup
+	bundleRunner   diagbundle.Runner
+	capabilities   *capabilities.Checker
+	drift          *routing.DriftMonitor
+	quotas         *quota.Monitor
+	anomalies      *anomaly.Monitor
+	onDemand       *ondemand.Monitor
+	endpoints      *endpointrefresh.Monitor
+	unblock        *unblock.Monitor
+	peerSync       *peersync.Syncer
+	agents         *agent.Manager
+	mqtt           *mqtt.Publisher
+	metricsExport  *metricsexport.Exporter
+	logShip        *logship.Shipper
+	ipfix          *ipfix.Exporter
+	linkWatch      *linkwatch.Watcher
+	provision      *routing.ProvisionWatcher
+	delegation     *routing.DelegationWatcher
+	deviceSync     *routing.DeviceSyncWatcher
+	ruleExpiry     *routing.RuleExpiryWatcher
+	wanFailover    *wan.Monitor
+	vpnEvents      *vpnevents.Store
+	vpnTracker     *vpnevents.Tracker
+	vpnRevisions   *vpnrevisions.Store
+	settingsRevs   *settingsrevisions.Store
+	authEvents     *authevents.Store
+	flowHistory    *flowhistory.Store
+	dbMaint        *dbmaint.Maintainer
+	hostnames      *hostnames.Discoverer
+	jobs           *jobs.Queue
+	resolverJobs   *schedulerJobTracker
+	prewarmJobs    *schedulerJobTracker
+	ipsetCache     ipsetSnapshotCache
+	flowRules      flowRuleCache
 
 	watchersMu sync.Mutex
 	watchers   map[chan streamMessage]struct{}
 
+	linkEvents linkEventState
+
 	// speedtestActive guards against concurrent speed tests, which would
 	// contend for bandwidth and corrupt each other's measurements.
 	speedtestActive atomic.Bool
+	// captureActive allows one packet capture at a time.
+	captureActive atomic.Bool
 
 	broadcastInterval time.Duration
 	gatewayMu         sync.RWMutex
 	gateways          map[string]string
+
+	// reloadMu serialises in-process settings reloads. defaultPoll and
+	// defaultLatency are the flag intervals used when settings leave them 0.
+	reloadMu       sync.Mutex
+	listenReload   ListenReloader
+	kioskReload    ListenReloader
+	dnsBackends    DNSBackendFactory
+	defaultPoll    time.Duration
+	defaultLatency time.Duration
+
+	// pollMu guards adaptive polling: configuredPoll is the stats poll
+	// interval from settings and backoff how far it and the broadcast
+	// interval are currently stretched. pollWake re-evaluates the backoff
+	// when the first stream client attaches.
+	pollMu         sync.Mutex
+	configuredPoll time.Duration
+	backoff        pollBackoff
+	pollWake       chan struct{}
 }
 
 // New creates an HTTP server.
@@ -97,6 +184,14 @@ func New(
 	authManager *auth.Manager,
 	backupManager *backup.Manager,
 	updateManager *update.Manager,
+	eventStore *vpnevents.Store,
+	dbMaintainer *dbmaint.Maintainer,
+	revisionStore *vpnrevisions.Store,
+	settingsRevisionStore *settingsrevisions.Store,
+	authEventStore *authevents.Store,
+	flowStore *flowhistory.Store,
+	quotaStore *quota.Store,
+	agentManager *agent.Manager,
 	systemdManaged bool,
 ) (*Server, error) {
 	tmpl, err := template.ParseFS(ui.Assets, "web/templates/*.html")
@@ -117,125 +212,159 @@ func New(
 		auth:              authManager,
 		backup:            backupManager,
 		updater:           updateManager,
+		vpnEvents:         eventStore,
+		vpnRevisions:      revisionStore,
+		settingsRevs:      settingsRevisionStore,
+		authEvents:        authEventStore,
+		flowHistory:       flowStore,
 		templates:         tmpl,
 		systemdManaged:    systemdManaged,
 		flowInspector:     newVPNFlowInspector(),
 		flowRunner:        conntrackCLIRunner{},
+		reputation:        reputation.NewChecker(),
+		dnsLeak:           dnsleak.NewTester(),
+		egressCheck:       egresscheck.NewChecker(),
+		capabilities:      capabilities.NewChecker(),
+		mtuProbe:          pmtu.NewProber(),
+		capture:           pcap.NewCapturer(),
+		tracer:            mtr.NewTracer(),
+		routes:            ipRouteLookup{},
+		jobs:              jobs.NewQueue(),
+		resolverJobs:      &schedulerJobTracker{kind: jobs.KindResolver},
+		prewarmJobs:       &schedulerJobTracker{kind: jobs.KindPrewarm},
 		watchers:          make(map[chan streamMessage]struct{}),
 		broadcastInterval: 2 * time.Second,
+		pollWake:          make(chan struct{}, 1),
 		gateways:          make(map[string]string),
 	}
+	if statsCollector != nil {
+		server.defaultPoll = statsCollector.PollInterval()
+	}
+	if authManager != nil {
+		authManager.SetAuditHook(server.recordAuthEvent)
+	}
+	if latencyMonitor != nil {
+		server.defaultLatency = latencyMonitor.Interval()
+	}
+	if settingsManager != nil {
+		if current, err := settingsManager.Get(); err == nil {
+			server.configureReputation(current)
+			server.applyIntervals(current)
+		}
+	}
 	if prewarmScheduler != nil {
+		logger := prewarmJobLogger{tracker: server.prewarmJobs}
 		if diagLogger != nil {
-			prewarmScheduler.SetLogger(diagLogger)
+			logger.next = diagLogger
 		}
+		prewarmScheduler.SetLogger(logger)
 		prewarmScheduler.SetProgressHandler(func(progress prewarm.Progress) {
+			server.observePrewarmProgress(progress)
 			server.broadcastEvent("prewarm", progress)
 		})
 	}
+	if routingManager != nil && settingsManager != nil {
+		if monitor, err := routing.NewDriftMonitor(routingManager, settingsManager); err == nil {
+			server.configureDriftMonitor(monitor)
+		}
+		if watcher, err := routing.NewProvisionWatcher(routingManager, settingsManager); err == nil {
+			server.configureProvisionWatcher(watcher)
+		}
+	}
+	if routingManager != nil {
+		routingManager.SetNetworkDirectory(server.unifiNetworkVLANs)
+		if watcher, err := routing.NewDelegationWatcher(routingManager); err == nil {
+			server.configureDelegationWatcher(watcher)
+		}
+		if watcher, err := routing.NewDeviceSyncWatcher(routingManager, server.discoverSyncDevices); err == nil {
+			server.configureDeviceSyncWatcher(watcher)
+		}
+		if watcher, err := routing.NewRuleExpiryWatcher(routingManager); err == nil {
+			server.configureRuleExpiryWatcher(watcher)
+		}
+	}
+	if vpnManager != nil && settingsManager != nil {
+		if monitor, err := wan.NewMonitor(settingsManager, vpnManager); err == nil {
+			server.configureWANFailover(monitor)
+		}
+	}
+	if eventStore != nil && vpnManager != nil {
+		if tracker, err := vpnevents.NewTracker(eventStore, vpnManager); err == nil {
+			server.configureVPNEventTracker(tracker)
+		}
+	}
+	if statsCollector != nil && settingsManager != nil {
+		if monitor, err := anomaly.NewMonitor(settingsManager, server.anomalySamples, server.countVPNFlows); err == nil {
+			server.configureAnomalyMonitor(monitor)
+		}
+	}
+	if quotaStore != nil && statsCollector != nil && vpnManager != nil {
+		if monitor, err := quota.NewMonitor(quotaStore, server.quotaSamples); err == nil {
+			server.configureQuotaMonitor(monitor)
+		}
+	}
+	if vpnManager != nil && routingManager != nil && systemdManager != nil {
+		if monitor, err := ondemand.NewMonitor(server.onDemandTargets, routingManager.MarkPacketCounts, onDemandUnits{server: server}); err == nil {
+			server.configureOnDemand(monitor)
+		}
+	}
+	if vpnManager != nil {
+		if monitor, err := endpointrefresh.NewMonitor(server.endpointRefreshTargets, endpointrefresh.CommandDevice{}); err == nil {
+			server.configureEndpointRefresh(monitor)
+		}
+	}
+	if backupManager != nil && settingsManager != nil {
+		if syncer, err := peersync.NewSyncer(settingsManager, server.applySyncSnapshot); err == nil {
+			server.configurePeerSync(syncer)
+		}
+	}
+	if agentManager != nil {
+		server.configureAgents(agentManager)
+	}
+	if vpnManager != nil && settingsManager != nil {
+		if monitor, err := unblock.NewMonitor(unblock.NewChecker(), settingsManager, server.unblockTargets); err == nil {
+			server.configureUnblockMonitor(monitor)
+		}
+	}
+	if settingsManager != nil {
+		if publisher, err := mqtt.NewPublisher(settingsManager, server.liveVPNStates, mqttEventTypes); err == nil {
+			server.configureMQTT(publisher)
+		}
+		if exporter, err := metricsexport.NewExporter(settingsManager, server.metricsExportPoints); err == nil {
+			server.configureMetricsExport(exporter)
+		}
+		var journal logship.JournalReader
+		if systemdManager != nil {
+			journal = systemdManager.Journal
+		}
+		if shipper, err := logship.NewShipper(settingsManager, server.logShipUnits, journal); err == nil {
+			server.configureLogShipping(shipper)
+		}
+		if exporter, err := ipfix.NewExporter(settingsManager); err == nil {
+			server.configureIPFIX(exporter)
+		}
+	}
+	if dbMaintainer != nil {
+		server.configureDatabaseMaintenance(dbMaintainer)
+	}
+	if cfgManager != nil {
+		server.configureLinkWatch(linkwatch.New())
+	}
+	if updateManager != nil && settingsManager != nil {
+		if current, err := settingsManager.Get(); err == nil {
+			if scheduler, err := update.NewScheduler(updateManager, settingsManager); err == nil {
+				server.configureAutoUpdate(current, scheduler)
+			}
+		}
+	}
+	if discoverer, err := hostnames.NewDiscoverer(server.hostnameTargets); err == nil {
+		server.hostnames = discoverer
+	}
 	if resolverScheduler != nil {
 		resolverScheduler.SetProgressHandler(func(progress routing.ResolverProgress) {
+			server.observeResolverProgress(progress)
 			server.broadcastEvent("resolver", progress)
 		})
 	}
 	return server, nil
 }
-
-// Router constructs the http.Handler with all routes.
-func (s *Server) Router() (http.Handler, error) {
-	if err := s.refreshState(); err != nil {
-		return nil, err
-	}
-	s.applyAutostart()
-
-	r := chi.NewRouter()
-	r.Use(middleware.RealIP)
-	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-
-	// Static assets — public, needed by the login page.
-	staticFS, err := fs.Sub(ui.Assets, "web/static")
-	if err != nil {
-		return nil, err
-	}
-	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))))
-
-	// Auth endpoints — always public.
-	r.Get("/login", s.handleLoginGet)
-	r.Post("/login", s.handleLoginPost)
-	r.Post("/logout", s.handleLogout)
-
-	// All remaining routes require authentication.
-	r.Group(func(protected chi.Router) {
-		protected.Use(s.auth.Middleware)
-
-		protected.Get("/", s.handleIndex)
-
-		protected.Route("/api", func(api chi.Router) {
-			api.Get("/groups", s.handleListGroups)
-			api.Post("/groups", s.handleCreateGroup)
-			api.Get("/groups/{id}", s.handleGetGroup)
-			api.Put("/groups/{id}", s.handleUpdateGroup)
-			api.Delete("/groups/{id}", s.handleDeleteGroup)
-			api.Post("/routing/asn-preview", s.handleASNPreview)
-			api.Get("/resolver/status", s.handleResolverStatus)
-			api.Post("/resolver/run", s.handleResolverRun)
-			api.Post("/resolver/clear-run", s.handleResolverClearRun)
-			api.Get("/prewarm/status", s.handlePrewarmStatus)
-			api.Post("/prewarm/run", s.handlePrewarmRun)
-			api.Post("/prewarm/clear-run", s.handlePrewarmClearRun)
-			api.Post("/prewarm/stop", s.handlePrewarmStop)
-			api.Get("/auth/token", s.handleGetAuthToken)
-			api.Post("/auth/token", s.handleRegenerateAuthToken)
-			api.Post("/auth/password", s.handleChangePassword)
-
-			api.Get("/vpns", s.handleListVPNs)
-			api.Post("/vpns", s.handleCreateVPN)
-			api.Get("/vpns/{name}", s.handleGetVPN)
-			api.Put("/vpns/{name}", s.handleUpdateVPN)
-			api.Delete("/vpns/{name}", s.handleDeleteVPN)
-			api.Post("/vpns/{name}/restart", s.handleRestartVPN)
-			api.Get("/vpns/{name}/routing-inspector", s.handleVPNRoutingInspector)
-			api.Post("/vpns/{name}/flow-inspector/start", s.handleStartVPNFlowInspector)
-			api.Get("/vpns/{name}/flow-inspector/{sessionID}", s.handlePollVPNFlowInspector)
-			api.Post("/vpns/{name}/flow-inspector/{sessionID}/stop", s.handleStopVPNFlowInspector)
-			api.Get("/devices", s.handleListDevices)
-
-			api.Get("/configs", s.handleListConfigs)
-			api.Get("/configs/{name}/file", s.handleReadConfig)
-			api.Put("/configs/{name}/file", s.handleWriteConfig)
-			api.Post("/configs/{name}/start", s.handleStartVPN)
-			api.Post("/configs/{name}/stop", s.handleStopVPN)
-			api.Post("/configs/{name}/autostart", s.handleAutostart)
-			api.Post("/reload", s.handleReload)
-			api.Post("/system/restart", s.handleSystemRestart)
-			api.Get("/stats", s.handleStats)
-			api.Get("/stream", s.handleStream)
-			api.Get("/speedtest/stream", s.handleSpeedtestStream)
-			api.Get("/settings", s.handleGetSettings)
-			api.Put("/settings", s.handleSaveSettings)
-			api.Get("/update/status", s.handleUpdateStatus)
-			api.Post("/update/check", s.handleCheckUpdates)
-			api.Post("/update/apply", s.handleApplyUpdate)
-			api.Get("/backup/export", s.handleExportBackup)
-			api.Post("/backup/import", s.handleImportBackup)
-		})
-	})
-
-	return r, nil
-}
-
-// StartBackground launches the broadcaster loop.
-func (s *Server) StartBackground(stop <-chan struct{}) {
-	ticker := time.NewTicker(s.broadcastInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ticker.C:
-			s.broadcastUpdate(nil)
-		case <-stop:
-			return
-		}
-	}
-}
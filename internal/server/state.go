@@ -2,7 +2,6 @@ package server
 
 import (
 	"context"
-	"log"
 	"sort"
 	"strings"
 	"time"
@@ -12,12 +11,16 @@ import (
 	"split-vpn-webui/internal/settings"
 	"split-vpn-webui/internal/stats"
 	"split-vpn-webui/internal/util"
+	"split-vpn-webui/internal/vpnevents"
+	"split-vpn-webui/internal/wan"
 )
 
 func (s *Server) refreshState() error {
 	if _, err := s.configManager.Discover(); err != nil {
 		// Non-fatal: directory may not exist yet on first boot.
-		log.Printf("config discovery warning: %v", err)
+		if s.diagLog != nil {
+			s.diagLog.Warnf("config discovery failed: %v", err)
+		}
 	}
 	configs, err := s.configManager.List()
 	if err != nil {
@@ -36,11 +39,8 @@ func (s *Server) refreshState() error {
 		}
 		resolved := s.resolveGateway(cfg)
 		resolvedGateways[cfg.Name] = resolved
-		if resolved != "" {
-			latencyTargets[cfg.Name] = latency.Target{
-				Interface: cfg.InterfaceName,
-				Address:   resolved,
-			}
+		if target := s.latencyTarget(cfg, resolved); target.Address != "" {
+			latencyTargets[cfg.Name] = target
 		}
 		if wan := cfg.RawValues["WAN_INTERFACE"]; wan != "" {
 			wanCandidates[wan]++
@@ -58,22 +58,32 @@ func (s *Server) refreshState() error {
 		storedSettings = settings.Settings{}
 	}
 
-	wan := storedSettings.WANInterface
-	if wan == "" {
-		wan = s.statsWAN()
+	// A failover priority list names every WAN; its first entry is primary.
+	wans := wan.ParsePriority(storedSettings.WANPriority)
+	primary := storedSettings.WANInterface
+	if len(wans) > 0 {
+		primary = wans[0]
+	}
+	if primary == "" {
+		primary = s.statsWAN()
 	}
-	if wan == "" {
-		wan = dominantKey(wanCandidates)
+	if primary == "" {
+		primary = dominantKey(wanCandidates)
 	}
-	if wan == "" {
+	if primary == "" {
 		if detected, err := util.DetectWANInterface(); err == nil {
-			wan = detected
+			primary = detected
 		}
 	}
+	if len(wans) == 0 && primary != "" {
+		wans = []string{primary}
+	}
 
-	s.stats.ConfigureInterfaces(wan, vpnInterfaces, vpnTypes)
+	s.stats.ConfigureInterfaces("", vpnInterfaces, vpnTypes)
+	s.stats.ConfigureWANs(wans)
+	s.stats.SetDisabledInterfaces(stats.ParseInterfaceList(storedSettings.StatsDisabledInterfaces))
 	if storedSettings.WANInterface == "" {
-		s.stats.SetWANInterface(wan)
+		s.stats.SetWANInterface(primary)
 	}
 	return nil
 }
@@ -95,6 +105,38 @@ func (s *Server) resolveGateway(cfg *config.VPNConfig) string {
 	return gateway
 }
 
+// latencyTarget picks what the latency monitor measures for a VPN: its
+// gateway by default, or the probe configured on the profile. A DNS probe
+// without a target queries the profile's first DNS server.
+func (s *Server) latencyTarget(cfg *config.VPNConfig, gateway string) latency.Target {
+	target := latency.Target{Interface: cfg.InterfaceName, Address: gateway}
+	if s.vpnManager == nil {
+		return target
+	}
+	profile, err := s.vpnManager.Get(cfg.Name)
+	if err != nil || profile == nil {
+		return target
+	}
+	resolvers := profile.DNSServers()
+	switch profile.LatencyProbe {
+	case latency.ProbeHTTPS:
+		target.Probe = latency.ProbeHTTPS
+		target.Address = profile.LatencyProbeTarget
+		target.Resolvers = resolvers
+	case latency.ProbeDNS:
+		target.Probe = latency.ProbeDNS
+		target.Address = profile.LatencyProbeTarget
+		if target.Address == "" && len(resolvers) > 0 {
+			target.Address = resolvers[0].String()
+		}
+	default:
+		if profile.LatencyProbeTarget != "" {
+			target.Address = profile.LatencyProbeTarget
+		}
+	}
+	return target
+}
+
 func (s *Server) statsWAN() string {
 	snap := s.stats.Snapshot()
 	for _, iface := range snap.Interfaces {
@@ -197,6 +239,7 @@ func (s *Server) applyAutostart() {
 	if err != nil {
 		return
 	}
+	pending := make([]*config.VPNConfig, 0, len(configs))
 	for _, cfg := range configs {
 		enabled, err := s.configManager.AutostartEnabled(cfg.Name)
 		if err != nil || !enabled {
@@ -204,7 +247,47 @@ func (s *Server) applyAutostart() {
 		}
 		connected, _, _ := util.InterfaceOperState(cfg.InterfaceName)
 		if !connected {
-			go s.startVPN(cfg)
+			pending = append(pending, cfg)
 		}
 	}
+	if len(pending) == 0 {
+		return
+	}
+	s.sortByStartOrder(pending)
+	// Bring VPNs up one at a time so a tunnel is only started once the VPNs
+	// it depends on have been started.
+	go func() {
+		for _, cfg := range pending {
+			s.recordVPNEvent(context.Background(), cfg.Name, vpnevents.TypeStart, "autostart")
+			s.startVPN(cfg)
+		}
+	}()
+}
+
+// sortByStartOrder orders configs by VPN dependencies, falling back to name
+// order when the dependency graph cannot be resolved.
+func (s *Server) sortByStartOrder(configs []*config.VPNConfig) {
+	sort.SliceStable(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	if s.vpnManager == nil {
+		return
+	}
+	order, err := s.vpnManager.StartOrder()
+	if err != nil {
+		if s.diagLog != nil {
+			s.diagLog.Warnf("vpn start order unavailable, using name order: %v", err)
+		}
+		return
+	}
+	rank := make(map[string]int, len(order))
+	for idx, name := range order {
+		rank[name] = idx
+	}
+	sort.SliceStable(configs, func(i, j int) bool {
+		left, leftOK := rank[configs[i].Name]
+		right, rightOK := rank[configs[j].Name]
+		if leftOK != rightOK {
+			return leftOK
+		}
+		return left < right
+	})
 }
@@ -60,8 +60,12 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) addWatcher(ch chan streamMessage) {
 	s.watchersMu.Lock()
-	defer s.watchersMu.Unlock()
 	s.watchers[ch] = struct{}{}
+	first := len(s.watchers) == 1
+	s.watchersMu.Unlock()
+	if first {
+		s.wakeAdaptivePolling()
+	}
 }
 
 func (s *Server) removeWatcher(ch chan streamMessage) {
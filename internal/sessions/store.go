@@ -0,0 +1,224 @@
+// Package sessions keeps server-side browser sessions. Each login gets its
+// own random cookie token and CSRF token, so one device can be signed out
+// without touching the others or the API token used by scripts.
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// Lifetime is how long a session lasts after login, however active.
+	Lifetime = 30 * 24 * time.Hour
+	// IdleTimeout ends a session that has not been used for this long.
+	IdleTimeout = 7 * 24 * time.Hour
+	// touchInterval limits last_seen writes to one per session per minute.
+	touchInterval = time.Minute
+	// MaxSessions caps stored sessions; the least recently used are dropped.
+	MaxSessions = 100
+)
+
+// ErrNotFound is returned for unknown, expired or revoked sessions.
+var ErrNotFound = errors.New("session not found")
+
+// Session is one signed-in browser.
+type Session struct {
+	ID        string    `json:"id"`
+	CSRFToken string    `json:"-"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Remote    string    `json:"remote,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Store persists sessions in the sessions table.
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewStore creates a store backed by db.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	return &Store{db: db, now: time.Now}, nil
+}
+
+// Create starts a session and returns it with the cookie token, which is
+// only ever stored hashed.
+func (s *Store) Create(ctx context.Context, userAgent, remote string) (Session, string, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return Session{}, "", err
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return Session{}, "", err
+	}
+	csrf, err := randomHex(32)
+	if err != nil {
+		return Session{}, "", err
+	}
+	if len(userAgent) > 256 {
+		userAgent = userAgent[:256]
+	}
+	now := s.now().UTC().Truncate(time.Second)
+	session := Session{
+		ID:        id,
+		CSRFToken: csrf,
+		UserAgent: userAgent,
+		Remote:    remote,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(Lifetime),
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, token_hash, csrf_token, user_agent, remote, created_at, last_seen, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, session.ID, hashToken(token), session.CSRFToken, session.UserAgent, session.Remote,
+		session.CreatedAt.Unix(), session.LastSeen.Unix(), session.ExpiresAt.Unix()); err != nil {
+		return Session{}, "", err
+	}
+	if err := s.prune(ctx, now); err != nil {
+		return Session{}, "", err
+	}
+	return session, token, nil
+}
+
+// Lookup returns the live session for a cookie token, recording the use.
+func (s *Store) Lookup(ctx context.Context, token string) (Session, error) {
+	if token == "" {
+		return Session{}, ErrNotFound
+	}
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, csrf_token, user_agent, remote, created_at, last_seen, expires_at
+		FROM sessions
+		WHERE token_hash = ?
+	`, hashToken(token))
+	session, err := scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, ErrNotFound
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	now := s.now().UTC().Truncate(time.Second)
+	if expired(session, now) {
+		_, _ = s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, session.ID)
+		return Session{}, ErrNotFound
+	}
+	if now.Sub(session.LastSeen) >= touchInterval {
+		if _, err := s.db.ExecContext(ctx, `UPDATE sessions SET last_seen = ? WHERE id = ?`, now.Unix(), session.ID); err != nil {
+			return Session{}, err
+		}
+		session.LastSeen = now
+	}
+	return session, nil
+}
+
+// List returns live sessions, most recently used first.
+func (s *Store) List(ctx context.Context) ([]Session, error) {
+	now := s.now().UTC().Truncate(time.Second)
+	if err := s.prune(ctx, now); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, csrf_token, user_agent, remote, created_at, last_seen, expires_at
+		FROM sessions
+		ORDER BY last_seen DESC, created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := []Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke ends one session.
+func (s *Store) Revoke(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeAll ends every session except keepID, which may be empty, and
+// returns how many were ended.
+func (s *Store) RevokeAll(ctx context.Context, keepID string) (int, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id != ?`, keepID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// prune deletes expired sessions and the least recently used beyond
+// MaxSessions.
+func (s *Store) prune(ctx context.Context, now time.Time) error {
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM sessions WHERE expires_at <= ? OR last_seen <= ?`,
+		now.Unix(), now.Add(-IdleTimeout).Unix(),
+	); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM sessions
+		WHERE id NOT IN (SELECT id FROM sessions ORDER BY last_seen DESC, created_at DESC LIMIT ?)
+	`, MaxSessions)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row rowScanner) (Session, error) {
+	var session Session
+	var created, lastSeen, expires int64
+	if err := row.Scan(&session.ID, &session.CSRFToken, &session.UserAgent, &session.Remote, &created, &lastSeen, &expires); err != nil {
+		return Session{}, err
+	}
+	session.CreatedAt = time.Unix(created, 0).UTC()
+	session.LastSeen = time.Unix(lastSeen, 0).UTC()
+	session.ExpiresAt = time.Unix(expires, 0).UTC()
+	return session, nil
+}
+
+func expired(session Session, now time.Time) bool {
+	return !now.Before(session.ExpiresAt) || !now.Before(session.LastSeen.Add(IdleTimeout))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
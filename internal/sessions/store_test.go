@@ -0,0 +1,124 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/database"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return store
+}
+
+func TestStoreLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	laptop, laptopToken, err := store.Create(ctx, "Firefox", "192.168.1.10")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	phone, phoneToken, err := store.Create(ctx, "Safari", "192.168.1.11")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if laptopToken == phoneToken || laptop.CSRFToken == "" || laptop.CSRFToken == phone.CSRFToken {
+		t.Fatal("expected distinct tokens per session")
+	}
+
+	got, err := store.Lookup(ctx, laptopToken)
+	if err != nil || got.ID != laptop.ID || got.CSRFToken != laptop.CSRFToken {
+		t.Fatalf("lookup: got %+v, %v", got, err)
+	}
+	if _, err := store.Lookup(ctx, "not-a-token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected unknown token to be rejected, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := store.Lookup(ctx, phoneToken); err != nil {
+		t.Fatalf("lookup phone: %v", err)
+	}
+	list, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != phone.ID {
+		t.Fatalf("expected most recently used first, got %+v", list)
+	}
+
+	if err := store.Revoke(ctx, phone.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := store.Lookup(ctx, phoneToken); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected revoked session to be rejected, got %v", err)
+	}
+	if err := store.Revoke(ctx, phone.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected second revoke to report not found, got %v", err)
+	}
+
+	if _, _, err := store.Create(ctx, "Chrome", "192.168.1.12"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if ended, err := store.RevokeAll(ctx, laptop.ID); err != nil || ended != 1 {
+		t.Fatalf("revoke others: ended %d, %v", ended, err)
+	}
+	if _, err := store.Lookup(ctx, laptopToken); err != nil {
+		t.Fatalf("expected kept session to survive, got %v", err)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	_, idleToken, err := store.Create(ctx, "", "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_, activeToken, err := store.Create(ctx, "", "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	// Keep one session in use; the other goes idle.
+	for elapsed := time.Duration(0); elapsed < IdleTimeout; elapsed += 24 * time.Hour {
+		now = now.Add(24 * time.Hour)
+		if _, err := store.Lookup(ctx, activeToken); err != nil {
+			t.Fatalf("lookup active after %s: %v", elapsed, err)
+		}
+	}
+	if _, err := store.Lookup(ctx, idleToken); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected idle session to expire, got %v", err)
+	}
+
+	// Activity does not extend a session past its lifetime.
+	for now.Before(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC).Add(Lifetime)) {
+		if _, err := store.Lookup(ctx, activeToken); err != nil {
+			t.Fatalf("lookup active at %s: %v", now, err)
+		}
+		now = now.Add(24 * time.Hour)
+	}
+	if _, err := store.Lookup(ctx, activeToken); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected session to end after its lifetime, got %v", err)
+	}
+	if list, err := store.List(ctx); err != nil || len(list) != 0 {
+		t.Fatalf("expected expired sessions to be pruned, got %+v, %v", list, err)
+	}
+}
@@ -11,8 +11,20 @@ import (
 // Settings captures user preferences and auth credentials persisted across restarts.
 type Settings struct {
 	// Network
+	// ListenInterface lists listen interfaces/addresses, comma-separated; see
+	// package listen for the entry syntax. Empty uses the -addr flag.
 	ListenInterface string `json:"listenInterface"`
 	WANInterface    string `json:"wanInterface"`
+	// WANPriority lists uplinks in failover order, comma-separated. When set,
+	// VPN endpoint routes follow the first uplink that is up.
+	WANPriority string `json:"wanPriority,omitempty"`
+	// Statistics poll and latency ping intervals; zero keeps the -poll and
+	// -latency-interval flag values. Both apply without a restart.
+	StatsPollSeconds       int `json:"statsPollSeconds,omitempty"`
+	LatencyIntervalSeconds int `json:"latencyIntervalSeconds,omitempty"`
+	// StatsDisabledInterfaces lists interfaces the statistics collector skips,
+	// comma-separated, by device name or tracked name ("WAN2", a VPN name).
+	StatsDisabledInterfaces string `json:"statsDisabledInterfaces,omitempty"`
 	// DNS pre-warm
 	PrewarmParallelism       int    `json:"prewarmParallelism,omitempty"`
 	PrewarmDoHTimeoutSeconds int    `json:"prewarmDoHTimeoutSeconds,omitempty"`
@@ -30,15 +42,133 @@ type Settings struct {
 	ResolverDomainEnabled          *bool `json:"resolverDomainEnabled,omitempty"`
 	ResolverASNEnabled             *bool `json:"resolverAsnEnabled,omitempty"`
 	ResolverWildcardEnabled        *bool `json:"resolverWildcardEnabled,omitempty"`
+	// Per-provider request budgets; 0 uses the built-in default.
+	ResolverDomainRatePerMinute   int `json:"resolverDomainRatePerMinute,omitempty"`
+	ResolverASNRatePerMinute      int `json:"resolverAsnRatePerMinute,omitempty"`
+	ResolverWildcardRatePerMinute int `json:"resolverWildcardRatePerMinute,omitempty"`
+	// ResolverEgress sends the resolver's DoH, RIPE and crt.sh requests and
+	// pre-warm's crt.sh lookups out one VPN, by name, or network interface,
+	// for ISPs that block or poison those services. Empty follows the
+	// routing table.
+	ResolverEgress string `json:"resolverEgress,omitempty"`
 	// Diagnostics logging
 	DebugLogEnabled *bool  `json:"debugLogEnabled,omitempty"`
 	DebugLogLevel   string `json:"debugLogLevel,omitempty"`
+	// Public status page (unauthenticated, privacy-filtered)
+	PublicStatusEnabled *bool `json:"publicStatusEnabled,omitempty"`
+	// KioskListen lists addresses for the unauthenticated read-only
+	// dashboard, in the ListenInterface syntax; entries without a port use
+	// 8092. Empty turns the kiosk off.
+	KioskListen string `json:"kioskListen,omitempty"`
+	// Flow inspector IP reputation lookups
+	ReputationEnabled           *bool  `json:"reputationEnabled,omitempty"`
+	ReputationSpamhausEnabled   *bool  `json:"reputationSpamhausEnabled,omitempty"`
+	ReputationAbuseIPDBKey      string `json:"reputationAbuseIpdbKey,omitempty"`
+	ReputationAbuseIPDBMinScore int    `json:"reputationAbuseIpdbMinScore,omitempty"`
+	// Re-enable nf_conntrack_acct on start; set from the flow inspector.
+	ConntrackAccountingEnabled *bool `json:"conntrackAccountingEnabled,omitempty"`
+	// Routing drift detection: "off", "alert" (default) or "repair".
+	DriftMode            string `json:"driftMode,omitempty"`
+	DriftIntervalSeconds int    `json:"driftIntervalSeconds,omitempty"`
+	// Traffic anomaly alerts: a VPN above AnomalyHighMbps for
+	// AnomalyHighMinutes (default 5), or idle for AnomalyStallMinutes while
+	// conntrack still holds flows marked for it. Zero disables each check.
+	AnomalyHighMbps     int `json:"anomalyHighMbps,omitempty"`
+	AnomalyHighMinutes  int `json:"anomalyHighMinutes,omitempty"`
+	AnomalyStallMinutes int `json:"anomalyStallMinutes,omitempty"`
+	// Streaming unblock checks through every connected VPN every
+	// UnblockCheckIntervalHours; zero runs them on demand only.
+	UnblockCheckIntervalHours int `json:"unblockCheckIntervalHours,omitempty"`
+	// Re-apply routing after UniFi reprovisioning (default on).
+	ProvisionWatchEnabled *bool `json:"provisionWatchEnabled,omitempty"`
+	// DNS backend that fills the routing ipsets: "dnsmasq" (default),
+	// "adguard" or "pihole". The config path overrides the backend's default
+	// file. The AdGuard Home API is optional; its password is a credential
+	// and is never returned by the settings API.
+	DNSBackend           string `json:"dnsBackend,omitempty"`
+	DNSBackendConfigPath string `json:"dnsBackendConfigPath,omitempty"`
+	AdGuardURL           string `json:"adguardUrl,omitempty"`
+	AdGuardUsername      string `json:"adguardUsername,omitempty"`
+	AdGuardPassword      string `json:"adguardPassword,omitempty"`
+	// UniFi Network controller used as a device name source. The API key is a
+	// credential and is never returned by the settings API.
+	UniFiControllerURL    string `json:"unifiControllerUrl,omitempty"`
+	UniFiControllerSite   string `json:"unifiControllerSite,omitempty"`
+	UniFiControllerAPIKey string `json:"unifiControllerApiKey,omitempty"`
+	// Probe LAN devices over mDNS, LLMNR and NetBIOS for names (default on).
+	HostnameDiscoveryEnabled *bool `json:"hostnameDiscoveryEnabled,omitempty"`
+	// Database retention in days; zero keeps the defaults (7 days of stats,
+	// 30 days of run records and connection events).
+	StatsRetentionDays int `json:"statsRetentionDays,omitempty"`
+	RunRetentionDays   int `json:"runRetentionDays,omitempty"`
+	EventRetentionDays int `json:"eventRetentionDays,omitempty"`
+	// Days deleted groups and VPN profiles stay restorable in the trash;
+	// zero keeps the default of 7 days.
+	TrashRetentionDays int `json:"trashRetentionDays,omitempty"`
+	// Config sync between two gateways: a "leader" serves its VPN profiles
+	// and routing groups, a "follower" pulls them from SyncLeaderURL every
+	// SyncIntervalSeconds (default 60) with the leader's API token. The token
+	// is a credential and is never returned by the settings API. The
+	// fingerprint pins the leader's self-signed certificate (SHA-256, hex).
+	SyncRole              string `json:"syncRole,omitempty"`
+	SyncLeaderURL         string `json:"syncLeaderUrl,omitempty"`
+	SyncLeaderFingerprint string `json:"syncLeaderFingerprint,omitempty"`
+	SyncToken             string `json:"syncToken,omitempty"`
+	SyncIntervalSeconds   int    `json:"syncIntervalSeconds,omitempty"`
+	// MQTT publishing of VPN state and events for home automation; an empty
+	// broker URL (mqtt:// or mqtts://) turns it off. Topics live under
+	// MQTTTopicPrefix (default "split-vpn-webui") and Home Assistant
+	// discovery configs under MQTTDiscoveryPrefix (default "homeassistant").
+	// State is published every MQTTIntervalSeconds (default 30). The
+	// password is a credential and is never returned by the settings API.
+	MQTTBrokerURL       string `json:"mqttBrokerUrl,omitempty"`
+	MQTTUsername        string `json:"mqttUsername,omitempty"`
+	MQTTPassword        string `json:"mqttPassword,omitempty"`
+	MQTTTopicPrefix     string `json:"mqttTopicPrefix,omitempty"`
+	MQTTDiscoveryPrefix string `json:"mqttDiscoveryPrefix,omitempty"`
+	MQTTIntervalSeconds int    `json:"mqttIntervalSeconds,omitempty"`
+	// Metrics push to a long-term store; an empty URL turns it off. The
+	// format is "influx" (line protocol, the default) or "prometheus"
+	// (remote write), pushed every MetricsExportIntervalSeconds (default
+	// 60). With a username the token is sent as its basic-auth password,
+	// otherwise as an Influx token or bearer token. The token is a
+	// credential and is never returned by the settings API.
+	MetricsExportURL             string `json:"metricsExportUrl,omitempty"`
+	MetricsExportFormat          string `json:"metricsExportFormat,omitempty"`
+	MetricsExportUsername        string `json:"metricsExportUsername,omitempty"`
+	MetricsExportToken           string `json:"metricsExportToken,omitempty"`
+	MetricsExportIntervalSeconds int    `json:"metricsExportIntervalSeconds,omitempty"`
+	// Log shipping of application logs and VPN unit journals to a syslog
+	// server (udp://, tcp:// or tls://host:port, RFC 5424) or a Loki push
+	// URL; an empty URL turns it off. Entries below LogShipMinLevel
+	// (default "info") stay local. With a username the token is the
+	// basic-auth password, otherwise a bearer token. The token is a
+	// credential and is never returned by the settings API.
+	LogShipURL      string `json:"logShipUrl,omitempty"`
+	LogShipMinLevel string `json:"logShipMinLevel,omitempty"`
+	LogShipUsername string `json:"logShipUsername,omitempty"`
+	LogShipToken    string `json:"logShipToken,omitempty"`
+	// IPFIX export of group-matched flows to a collector (host or
+	// host:port, default port 4739, UDP); empty turns it off. The
+	// observation domain ID tells this gateway apart at the collector.
+	IPFIXCollector         string `json:"ipfixCollector,omitempty"`
+	IPFIXObservationDomain int    `json:"ipfixObservationDomain,omitempty"`
+	// Updates: release channel ("stable" or "beta"), a backup export before
+	// each update (default on) and an optional weekly auto-update window
+	// such as "sun 04:00"; see update.ParseSchedule.
+	UpdateChannel       string `json:"updateChannel,omitempty"`
+	UpdateBackupEnabled *bool  `json:"updateBackupEnabled,omitempty"`
+	AutoUpdateEnabled   *bool  `json:"autoUpdateEnabled,omitempty"`
+	AutoUpdateSchedule  string `json:"autoUpdateSchedule,omitempty"`
 
 	// Auth — stored as bcrypt hash and random token.
 	// These fields are omitted from JSON output on API responses;
 	// only the settings Manager reads/writes them directly.
 	AuthPasswordHash string `json:"authPasswordHash,omitempty"`
 	AuthToken        string `json:"authToken,omitempty"`
+	// HomeAssistantToken only opens the /api/ha endpoints, so a Home
+	// Assistant install never holds the full API token. Empty disables it.
+	HomeAssistantToken string `json:"homeAssistantToken,omitempty"`
 }
 
 // Manager handles persistence of Settings on disk.
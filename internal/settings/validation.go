@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldError reports one invalid setting by its JSON field name. Message is
+// a complete sentence, usually naming the field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationError collects every invalid field of a settings update, so a
+// save can report them all at once.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add records err against field. Errors that already name their fields,
+// FieldError and ValidationError, keep them. A nil err is ignored.
+func (e *ValidationError) Add(field string, err error) {
+	if err == nil {
+		return
+	}
+	var nested *ValidationError
+	if errors.As(err, &nested) {
+		e.Fields = append(e.Fields, nested.Fields...)
+		return
+	}
+	var fieldErr FieldError
+	if errors.As(err, &fieldErr) {
+		e.Fields = append(e.Fields, fieldErr)
+		return
+	}
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: err.Error()})
+}
+
+// Addf records a formatted message against field.
+func (e *ValidationError) Addf(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns the collected errors, or nil when every field is valid.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// FieldErrors returns the field errors err carries, or nil when it names no
+// field.
+func FieldErrors(err error) []FieldError {
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		return validation.Fields
+	}
+	var fieldErr FieldError
+	if errors.As(err, &fieldErr) {
+		return []FieldError{fieldErr}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+// Package settingsrevisions keeps the history of saved settings — who
+// changed them, when and which fields — so a bad listen or auth change can
+// be rolled back from the UI or API instead of over SSH.
+package settingsrevisions
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+// Revision actions.
+const (
+	// ActionBaseline records the settings from before the first tracked
+	// change.
+	ActionBaseline = "baseline"
+	ActionSave     = "save"
+	ActionPassword = "password"
+	ActionToken    = "token"
+	ActionImport   = "import"
+	ActionRollback = "rollback"
+)
+
+const (
+	// DefaultPageSize and MaxPageSize bound List pagination.
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+	// MaxRevisions caps the revisions kept; the oldest are pruned.
+	MaxRevisions = 100
+)
+
+// ErrNotFound indicates a missing revision.
+var ErrNotFound = errors.New("settings revision not found")
+
+// Revision is one saved settings file. Fields lists the JSON names of the
+// settings that differ from the previous revision. Settings holds
+// credentials, so it is never serialized; it is only loaded by Get.
+type Revision struct {
+	ID        int64             `json:"id"`
+	Action    string            `json:"action"`
+	Actor     string            `json:"actor,omitempty"`
+	Fields    []string          `json:"fields"`
+	Settings  settings.Settings `json:"-"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Page is one page of revisions, newest first.
+type Page struct {
+	Revisions []Revision `json:"revisions"`
+	Total     int        `json:"total"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+}
+
+// Store persists revisions in the settings_revisions table.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a store backed by db.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	return &Store{db: db}, nil
+}
+
+// Record saves a revision. Settings identical to the latest revision are
+// not stored again; ok reports whether a row was written. A zero CreatedAt
+// is set to the current time.
+func (s *Store) Record(ctx context.Context, rev Revision) (saved Revision, ok bool, err error) {
+	rev.Action = strings.TrimSpace(rev.Action)
+	if rev.Action == "" {
+		return Revision{}, false, fmt.Errorf("revision action is required")
+	}
+	content, err := json.Marshal(rev.Settings)
+	if err != nil {
+		return Revision{}, false, err
+	}
+	latest, err := s.latest(ctx)
+	if err != nil {
+		return Revision{}, false, err
+	}
+	rev.Fields = []string{}
+	if latest != nil {
+		previous, err := json.Marshal(latest.Settings)
+		if err != nil {
+			return Revision{}, false, err
+		}
+		if bytes.Equal(previous, content) {
+			return *latest, false, nil
+		}
+		if rev.Fields, err = ChangedFields(latest.Settings, rev.Settings); err != nil {
+			return Revision{}, false, err
+		}
+	}
+	if rev.CreatedAt.IsZero() {
+		rev.CreatedAt = time.Now()
+	}
+	rev.CreatedAt = rev.CreatedAt.UTC().Truncate(time.Second)
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO settings_revisions (action, actor, fields, content, created_at) VALUES (?, ?, ?, ?, ?)`,
+		rev.Action, rev.Actor, strings.Join(rev.Fields, ","), string(content), rev.CreatedAt.Unix(),
+	)
+	if err != nil {
+		return Revision{}, false, err
+	}
+	if rev.ID, err = result.LastInsertId(); err != nil {
+		return Revision{}, false, err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM settings_revisions
+		WHERE id NOT IN (SELECT id FROM settings_revisions ORDER BY id DESC LIMIT ?)
+	`, MaxRevisions); err != nil {
+		return Revision{}, false, err
+	}
+	return rev, true, nil
+}
+
+// EnsureBaseline records current as the baseline when there is no history
+// yet, so the settings from before the first tracked change can still be
+// restored.
+func (s *Store) EnsureBaseline(ctx context.Context, current settings.Settings) error {
+	latest, err := s.latest(ctx)
+	if err != nil || latest != nil {
+		return err
+	}
+	_, _, err = s.Record(ctx, Revision{Action: ActionBaseline, Settings: current})
+	return err
+}
+
+// List returns one page of revisions without their settings, newest first.
+func (s *Store) List(ctx context.Context, limit, offset int) (Page, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	page := Page{Revisions: []Revision{}, Limit: limit, Offset: offset}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM settings_revisions`).Scan(&page.Total); err != nil {
+		return Page{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, action, actor, fields, created_at
+		FROM settings_revisions
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rev Revision
+		var fields string
+		var createdAt int64
+		if err := rows.Scan(&rev.ID, &rev.Action, &rev.Actor, &fields, &createdAt); err != nil {
+			return Page{}, err
+		}
+		rev.Fields = splitFields(fields)
+		rev.CreatedAt = time.Unix(createdAt, 0).UTC()
+		page.Revisions = append(page.Revisions, rev)
+	}
+	return page, rows.Err()
+}
+
+// Get returns one revision with its settings.
+func (s *Store) Get(ctx context.Context, id int64) (Revision, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, action, actor, fields, content, created_at
+		FROM settings_revisions
+		WHERE id = ?
+	`, id)
+	rev, err := scanRevision(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Revision{}, fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+	return rev, err
+}
+
+// ChangedFields lists the JSON names of the settings that differ between
+// from and to, sorted.
+func ChangedFields(from, to settings.Settings) ([]string, error) {
+	before, err := fieldValues(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := fieldValues(to)
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0)
+	for name, value := range after {
+		if !bytes.Equal(before[name], value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func fieldValues(current settings.Settings) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (s *Store) latest(ctx context.Context) (*Revision, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, action, actor, fields, content, created_at
+		FROM settings_revisions
+		ORDER BY id DESC
+		LIMIT 1
+	`)
+	rev, err := scanRevision(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func scanRevision(row *sql.Row) (Revision, error) {
+	var rev Revision
+	var fields, content string
+	var createdAt int64
+	if err := row.Scan(&rev.ID, &rev.Action, &rev.Actor, &fields, &content, &createdAt); err != nil {
+		return Revision{}, err
+	}
+	if err := json.Unmarshal([]byte(content), &rev.Settings); err != nil {
+		return Revision{}, fmt.Errorf("decode settings revision %d: %w", rev.ID, err)
+	}
+	rev.Fields = splitFields(fields)
+	rev.CreatedAt = time.Unix(createdAt, 0).UTC()
+	return rev, nil
+}
+
+func splitFields(raw string) []string {
+	if raw == "" {
+		return []string{}
+	}
+	return strings.Split(raw, ",")
+}
@@ -0,0 +1,87 @@
+package settingsrevisions
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"split-vpn-webui/internal/database"
+	"split-vpn-webui/internal/settings"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "revisions.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return store
+}
+
+func TestStoreRecordsChangedFields(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	if err := store.EnsureBaseline(ctx, settings.Settings{ListenInterface: "br0", AuthPasswordHash: "old"}); err != nil {
+		t.Fatalf("baseline: %v", err)
+	}
+	if err := store.EnsureBaseline(ctx, settings.Settings{ListenInterface: "ignored"}); err != nil {
+		t.Fatalf("second baseline: %v", err)
+	}
+	saved, ok, err := store.Record(ctx, Revision{
+		Action:   ActionSave,
+		Actor:    "web session from 10.0.0.2",
+		Settings: settings.Settings{ListenInterface: "eth9", WANPriority: "eth8", AuthPasswordHash: "old"},
+	})
+	if err != nil || !ok {
+		t.Fatalf("record: ok=%v err=%v", ok, err)
+	}
+	if !reflect.DeepEqual(saved.Fields, []string{"listenInterface", "wanPriority"}) {
+		t.Fatalf("unexpected changed fields: %v", saved.Fields)
+	}
+	if _, ok, err := store.Record(ctx, Revision{Action: ActionSave, Settings: saved.Settings}); err != nil || ok {
+		t.Fatalf("unchanged settings should not be stored: ok=%v err=%v", ok, err)
+	}
+
+	page, err := store.List(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if page.Total != 2 || page.Revisions[0].ID != saved.ID || page.Revisions[1].Action != ActionBaseline {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	baseline, err := store.Get(ctx, page.Revisions[1].ID)
+	if err != nil || baseline.Settings.ListenInterface != "br0" || baseline.Settings.AuthPasswordHash != "old" {
+		t.Fatalf("get baseline: %+v %v", baseline, err)
+	}
+	if _, err := store.Get(ctx, saved.ID+10); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStorePrunesOldestRevisions(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	for i := 0; i < MaxRevisions+5; i++ {
+		if _, _, err := store.Record(ctx, Revision{Action: ActionSave, Settings: settings.Settings{StatsPollSeconds: i + 1}}); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+	page, err := store.List(ctx, MaxPageSize, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if page.Total != MaxRevisions {
+		t.Fatalf("expected %d revisions, got %d", MaxRevisions, page.Total)
+	}
+	oldest, err := store.Get(ctx, page.Revisions[len(page.Revisions)-1].ID)
+	if err != nil || oldest.Settings.StatsPollSeconds != 6 {
+		t.Fatalf("expected the oldest kept revision to be the sixth, got %+v %v", oldest.Settings.StatsPollSeconds, err)
+	}
+}
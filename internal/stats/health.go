@@ -0,0 +1,144 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// HealthStatus says why an interface has, or lacks, fresh counters.
+type HealthStatus string
+
+const (
+	// HealthPending means the interface has not been polled yet.
+	HealthPending HealthStatus = "pending"
+	// HealthOK means the last poll read the counters.
+	HealthOK HealthStatus = "ok"
+	// HealthDown means the counters were read but the link is down.
+	HealthDown HealthStatus = "down"
+	// HealthMissing means the interface does not exist, e.g. a stopped tunnel.
+	HealthMissing HealthStatus = "missing"
+	// HealthReadError means the interface exists but its counters could not
+	// be read; this is a collector failure rather than an interface state.
+	HealthReadError HealthStatus = "read-error"
+	// HealthDisabled means polling is turned off for the interface.
+	HealthDisabled HealthStatus = "disabled"
+)
+
+// gapFactor is how many poll intervals may pass between two polls of an
+// interface before the collector counts a sampling gap.
+const gapFactor = 2
+
+var statsIfaceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.:@-]{1,32}$`)
+
+// InterfaceHealth is the collector's error budget for one interface.
+type InterfaceHealth struct {
+	Status HealthStatus `json:"status"`
+	// ReadErrors counts polls that failed to read the counters of an
+	// interface that exists; ConsecutiveErrors resets on the next good read.
+	ReadErrors        uint64    `json:"readErrors"`
+	ConsecutiveErrors int       `json:"consecutiveErrors"`
+	LastError         string    `json:"lastError,omitempty"`
+	LastErrorAt       time.Time `json:"lastErrorAt,omitzero"`
+	// MissingCounters is set when the interface exists but exposes no
+	// statistics files.
+	MissingCounters bool `json:"missingCounters,omitempty"`
+	// SamplingGaps counts polls that came more than two intervals after the
+	// previous one, e.g. while the process was stalled.
+	SamplingGaps   uint64  `json:"samplingGaps"`
+	LastGapSeconds float64 `json:"lastGapSeconds,omitempty"`
+}
+
+// SetDisabledInterfaces stops polling the listed interfaces. Entries match
+// either the tracked name ("WAN2", a VPN name) or the device name. Disabled
+// interfaces keep their history and are reported with HealthDisabled.
+func (c *Collector) SetDisabledInterfaces(names []string) {
+	disabled := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if trimmed := strings.TrimSpace(name); trimmed != "" {
+			disabled[trimmed] = struct{}{}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled = disabled
+}
+
+func (c *Collector) isDisabledLocked(stats *InterfaceStats) bool {
+	if _, ok := c.disabled[stats.Name]; ok {
+		return true
+	}
+	_, ok := c.disabled[stats.Interface]
+	return ok && stats.Interface != ""
+}
+
+// notePollLocked counts a sampling gap when the previous poll of stats is
+// further back than gapFactor intervals.
+func (c *Collector) notePollLocked(stats *InterfaceStats, now time.Time) {
+	if !stats.lastPollAt.IsZero() && c.pollInterval > 0 {
+		if elapsed := now.Sub(stats.lastPollAt); elapsed > gapFactor*c.pollInterval {
+			stats.Health.SamplingGaps++
+			stats.Health.LastGapSeconds = elapsed.Seconds()
+		}
+	}
+	stats.lastPollAt = now
+}
+
+// noteReadFailureLocked classifies a failed counter read: a missing
+// interface is a state, anything else is a collector error.
+func (c *Collector) noteReadFailureLocked(stats *InterfaceStats, now time.Time, err error) {
+	if !interfaceExists(c.sysClassNetRoot, stats.Interface) {
+		stats.Health.Status = HealthMissing
+		stats.Health.MissingCounters = false
+		stats.Health.ConsecutiveErrors = 0
+		return
+	}
+	stats.Health.Status = HealthReadError
+	stats.Health.ReadErrors++
+	stats.Health.ConsecutiveErrors++
+	stats.Health.LastError = err.Error()
+	stats.Health.LastErrorAt = now
+	stats.Health.MissingCounters = errors.Is(err, fs.ErrNotExist)
+}
+
+func (c *Collector) noteReadSuccessLocked(stats *InterfaceStats) {
+	stats.Health.Status = HealthOK
+	if stats.OperState == "down" {
+		stats.Health.Status = HealthDown
+	}
+	stats.Health.ConsecutiveErrors = 0
+	stats.Health.MissingCounters = false
+}
+
+// ParseInterfaceList splits a comma- or whitespace-separated interface list,
+// dropping blanks and duplicates.
+func ParseInterfaceList(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '\n' || r == ' ' || r == '\t' || r == '\r'
+	})
+	seen := make(map[string]struct{}, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		names = append(names, field)
+	}
+	return names
+}
+
+// NormalizeInterfaceList validates an interface list setting and returns it
+// comma-separated.
+func NormalizeInterfaceList(raw string) (string, error) {
+	names := ParseInterfaceList(raw)
+	for _, name := range names {
+		if !statsIfaceNamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid interface name %q", name)
+		}
+	}
+	return strings.Join(names, ","), nil
+}
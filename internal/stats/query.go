@@ -0,0 +1,175 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// MaxQueryBuckets caps the buckets per interface a query returns; coarser
+// intervals are used when a window would need more.
+const MaxQueryBuckets = 1000
+
+// QueryOptions selects and resamples the retained history.
+type QueryOptions struct {
+	// Interval is the bucket width; zero uses the poll interval.
+	Interval time.Duration
+	// Window is how far back from now to read; zero reads all history.
+	Window time.Duration
+	// Interfaces limits the result to these tracked or device names.
+	Interfaces []string
+}
+
+// SeriesStats summarises the throughput samples of one bucket in bits/s.
+type SeriesStats struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// QueryBucket is one resampled interval. Buckets without samples are left
+// out, so gaps in collection stay visible.
+type QueryBucket struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Samples   int         `json:"samples"`
+	Rx        SeriesStats `json:"rx"`
+	Tx        SeriesStats `json:"tx"`
+	Total     SeriesStats `json:"total"`
+}
+
+// InterfaceSeries is the resampled history of one interface.
+type InterfaceSeries struct {
+	Name      string        `json:"name"`
+	Interface string        `json:"interface"`
+	Type      InterfaceType `json:"type"`
+	Buckets   []QueryBucket `json:"buckets"`
+}
+
+// QueryResult is the response to Query. IntervalSeconds may be larger than
+// requested when the window would exceed MaxQueryBuckets.
+type QueryResult struct {
+	IntervalSeconds int64             `json:"intervalSeconds"`
+	WindowSeconds   int64             `json:"windowSeconds"`
+	From            time.Time         `json:"from"`
+	To              time.Time         `json:"to"`
+	Interfaces      []InterfaceSeries `json:"interfaces"`
+}
+
+// Query resamples the retained per-interface history into fixed buckets
+// aligned to the interval, with the minimum, maximum and average throughput
+// of each.
+func (c *Collector) Query(opts QueryOptions) QueryResult {
+	return c.query(opts, time.Now())
+}
+
+func (c *Collector) query(opts QueryOptions, now time.Time) QueryResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var from time.Time
+	if opts.Window > 0 {
+		from = now.Add(-opts.Window)
+	} else {
+		for _, stats := range c.interfaces {
+			if len(stats.History) > 0 && (from.IsZero() || stats.History[0].Timestamp.Before(from)) {
+				from = stats.History[0].Timestamp
+			}
+		}
+		if from.IsZero() {
+			from = now
+		}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = c.pollInterval
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if span := now.Sub(from); span/interval > MaxQueryBuckets {
+		interval = time.Duration(math.Ceil(float64(span)/MaxQueryBuckets/float64(time.Second))) * time.Second
+	}
+
+	wanted := make(map[string]struct{}, len(opts.Interfaces))
+	for _, name := range opts.Interfaces {
+		wanted[name] = struct{}{}
+	}
+	result := QueryResult{
+		IntervalSeconds: int64(interval / time.Second),
+		WindowSeconds:   int64(now.Sub(from) / time.Second),
+		From:            from,
+		To:              now,
+		Interfaces:      make([]InterfaceSeries, 0, len(c.interfaces)),
+	}
+	for _, stats := range c.interfaces {
+		if len(wanted) > 0 {
+			_, byName := wanted[stats.Name]
+			_, byDevice := wanted[stats.Interface]
+			if !byName && !byDevice {
+				continue
+			}
+		}
+		result.Interfaces = append(result.Interfaces, InterfaceSeries{
+			Name:      stats.Name,
+			Interface: stats.Interface,
+			Type:      stats.Type,
+			Buckets:   resampleHistory(stats.History, from, interval),
+		})
+	}
+	sort.SliceStable(result.Interfaces, func(i, j int) bool {
+		if result.Interfaces[i].Type != result.Interfaces[j].Type {
+			return result.Interfaces[i].Type == InterfaceWAN
+		}
+		return result.Interfaces[i].Name < result.Interfaces[j].Name
+	})
+	return result
+}
+
+// resampleHistory buckets points at or after from into interval-wide
+// buckets aligned to the Unix epoch. History is in time order.
+func resampleHistory(history []datapoint, from time.Time, interval time.Duration) []QueryBucket {
+	buckets := make([]QueryBucket, 0)
+	var current *QueryBucket
+	var sumRx, sumTx, sumTotal float64
+	flush := func() {
+		if current == nil {
+			return
+		}
+		n := float64(current.Samples)
+		current.Rx.Avg = sumRx / n
+		current.Tx.Avg = sumTx / n
+		current.Total.Avg = sumTotal / n
+		buckets = append(buckets, *current)
+		current = nil
+		sumRx, sumTx, sumTotal = 0, 0, 0
+	}
+	for _, point := range history {
+		if point.Timestamp.Before(from) {
+			continue
+		}
+		start := point.Timestamp.Truncate(interval)
+		if current != nil && !current.Timestamp.Equal(start) {
+			flush()
+		}
+		if current == nil {
+			current = &QueryBucket{
+				Timestamp: start,
+				Rx:        SeriesStats{Min: point.RxThroughput, Max: point.RxThroughput},
+				Tx:        SeriesStats{Min: point.TxThroughput, Max: point.TxThroughput},
+				Total:     SeriesStats{Min: point.TotalThroughput, Max: point.TotalThroughput},
+			}
+		}
+		current.Samples++
+		current.Rx.Min = min(current.Rx.Min, point.RxThroughput)
+		current.Rx.Max = max(current.Rx.Max, point.RxThroughput)
+		current.Tx.Min = min(current.Tx.Min, point.TxThroughput)
+		current.Tx.Max = max(current.Tx.Max, point.TxThroughput)
+		current.Total.Min = min(current.Total.Min, point.TotalThroughput)
+		current.Total.Max = max(current.Total.Max, point.TotalThroughput)
+		sumRx += point.RxThroughput
+		sumTx += point.TxThroughput
+		sumTotal += point.TotalThroughput
+	}
+	flush()
+	return buckets
+}
@@ -45,11 +45,17 @@ type InterfaceStats struct {
 	Available           bool          `json:"available"`
 	LastUpdated         time.Time     `json:"lastUpdated"`
 	OperState           string        `json:"operState,omitempty"`
+	// Active marks the WAN currently carrying tunnel traffic.
+	Active bool `json:"active,omitempty"`
+	// Enabled is false when polling is turned off for the interface.
+	Enabled bool            `json:"enabled"`
+	Health  InterfaceHealth `json:"health"`
 
 	baseRx          uint64
 	baseTx          uint64
 	lastCPUTimeNS   uint64
 	lastCPUSampleAt time.Time
+	lastPollAt      time.Time
 }
 
 // Snapshot contains the latest statistics for all monitored interfaces.
@@ -59,6 +65,7 @@ type Snapshot struct {
 	LoadAverage            *LoadAverage      `json:"loadAverage,omitempty"`
 	WanCorrectedThroughput float64           `json:"wanCorrectedThroughput"`
 	WanCorrectedBytes      uint64            `json:"wanCorrectedBytes"`
+	ActiveWAN              string            `json:"activeWan,omitempty"`
 }
 
 // Collector monitors interface statistics.
@@ -68,11 +75,14 @@ type Collector struct {
 	pendingHistory  map[string][]historyRow
 	historyLength   int
 	pollInterval    time.Duration
+	pollReset       chan struct{}
 	wanInterface    string
+	activeWAN       string
 	loadAverage     *LoadAverage
 	loadAvgPath     string
 	cgroupRoot      string
 	sysClassNetRoot string
+	disabled        map[string]struct{}
 }
 
 // NewCollector instantiates a collector.
@@ -82,6 +92,7 @@ func NewCollector(wanInterface string, pollInterval time.Duration, historyLength
 		pendingHistory:  make(map[string][]historyRow),
 		historyLength:   historyLength,
 		pollInterval:    pollInterval,
+		pollReset:       make(chan struct{}, 1),
 		wanInterface:    wanInterface,
 		loadAvgPath:     defaultLoadAvgPath,
 		cgroupRoot:      defaultCgroupRoot,
@@ -96,6 +107,42 @@ func (c *Collector) SetWANInterface(name string) {
 	c.wanInterface = name
 }
 
+// SetActiveWAN records which tracked WAN interface currently carries the
+// tunnels; VPN traffic is subtracted from that WAN only. An empty name falls
+// back to the primary WAN.
+func (c *Collector) SetActiveWAN(iface string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.activeWAN = iface
+}
+
+// ConfigureWANs tracks each interface of a multi-WAN setup separately, in
+// priority order: the first is named "WAN", the rest "WAN2", "WAN3" and so
+// on. WANs no longer listed are dropped.
+func (c *Collector) ConfigureWANs(wans []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keep := make(map[string]struct{}, len(wans))
+	for idx, iface := range wans {
+		name := "WAN"
+		if idx > 0 {
+			name = fmt.Sprintf("WAN%d", idx+1)
+		} else {
+			c.wanInterface = iface
+		}
+		keep[name] = struct{}{}
+		c.ensureInterface(name, iface, InterfaceWAN)
+	}
+	for key, iface := range c.interfaces {
+		if iface.Type != InterfaceWAN {
+			continue
+		}
+		if _, ok := keep[key]; !ok {
+			delete(c.interfaces, key)
+		}
+	}
+}
+
 // ConfigureInterfaces ensures the collector is tracking the provided interface names.
 func (c *Collector) ConfigureInterfaces(wan string, vpnInterfaces map[string]string, vpnTypes ...map[string]string) {
 	c.mu.Lock()
@@ -137,6 +184,8 @@ func (c *Collector) ensureInterface(name, iface string, ifaceType InterfaceType)
 			existing.CurrentRxThroughput = 0
 			existing.CurrentTxThroughput = 0
 			existing.History = existing.History[:0]
+			existing.Health = InterfaceHealth{Status: HealthPending}
+			existing.lastPollAt = time.Time{}
 		}
 		existing.Type = ifaceType
 		if pending, hasPending := c.pendingHistory[name]; hasPending {
@@ -149,6 +198,8 @@ func (c *Collector) ensureInterface(name, iface string, ifaceType InterfaceType)
 		Name:      name,
 		Interface: iface,
 		Type:      ifaceType,
+		Enabled:   true,
+		Health:    InterfaceHealth{Status: HealthPending},
 		History:   make([]datapoint, 0, c.historyLength),
 	}
 	if pending, hasPending := c.pendingHistory[name]; hasPending {
@@ -157,26 +208,78 @@ func (c *Collector) ensureInterface(name, iface string, ifaceType InterfaceType)
 	}
 }
 
+// PollInterval returns the current polling interval.
+func (c *Collector) PollInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.pollInterval
+}
+
+// SetPollInterval changes the polling interval of a running collector
+// without losing history. Non-positive values are ignored.
+func (c *Collector) SetPollInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	c.mu.Lock()
+	changed := c.pollInterval != interval
+	c.pollInterval = interval
+	if changed {
+		// Gaps are measured against the interval; restart the measurement.
+		for _, stats := range c.interfaces {
+			stats.lastPollAt = time.Time{}
+		}
+	}
+	c.mu.Unlock()
+	if !changed {
+		return
+	}
+	select {
+	case c.pollReset <- struct{}{}:
+	default:
+	}
+}
+
 // Start begins the polling loop.
 func (c *Collector) Start(stop <-chan struct{}) {
-	ticker := time.NewTicker(c.pollInterval)
+	ticker := time.NewTicker(c.PollInterval())
 	defer ticker.Stop()
 	c.update(time.Now())
 	for {
 		select {
 		case t := <-ticker.C:
 			c.update(t)
+		case <-c.pollReset:
+			ticker.Reset(c.PollInterval())
 		case <-stop:
 			return
 		}
 	}
 }
 
+// PollNow samples every interface immediately, for callers that know an
+// interface just appeared or went away.
+func (c *Collector) PollNow() {
+	c.update(time.Now())
+}
+
 func (c *Collector) update(now time.Time) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.updateLoadAverageLocked()
 	for _, stats := range c.interfaces {
+		if c.isDisabledLocked(stats) {
+			stats.Enabled = false
+			stats.Health.Status = HealthDisabled
+			stats.Available = false
+			stats.CurrentThroughput = 0
+			stats.CurrentRxThroughput = 0
+			stats.CurrentTxThroughput = 0
+			stats.lastPollAt = time.Time{}
+			continue
+		}
+		stats.Enabled = true
+		c.notePollLocked(stats, now)
 		if _, state, err := util.InterfaceOperState(stats.Interface); err == nil {
 			stats.OperState = state
 		} else {
@@ -184,12 +287,13 @@ func (c *Collector) update(now time.Time) {
 		}
 		c.updateCPUUsageLocked(now, stats)
 
-		rx, tx, err := readInterfaceBytes(stats.Interface)
+		rx, tx, err := readInterfaceBytes(c.sysClassNetRoot, stats.Interface)
 		if err != nil {
 			stats.Available = false
 			stats.CurrentThroughput = 0
 			stats.CurrentRxThroughput = 0
 			stats.CurrentTxThroughput = 0
+			c.noteReadFailureLocked(stats, now, err)
 			continue
 		}
 		if stats.baseRx == 0 && stats.baseTx == 0 && !stats.Available {
@@ -255,6 +359,7 @@ func (c *Collector) update(now time.Time) {
 		}
 		stats.LastUpdated = now
 		stats.Available = true
+		c.noteReadSuccessLocked(stats)
 	}
 }
 
@@ -265,6 +370,7 @@ func (c *Collector) Snapshot() Snapshot {
 
 	copies := make([]*InterfaceStats, 0, len(c.interfaces))
 	var wanStats *InterfaceStats
+	var primaryWAN *InterfaceStats
 	var vpnTotalsRxThroughput float64
 	var vpnTotalsTxThroughput float64
 	var vpnTotalsRxBytes uint64
@@ -275,7 +381,12 @@ func (c *Collector) Snapshot() Snapshot {
 		clone.History = append([]datapoint(nil), stats.History...)
 		copies = append(copies, &clone)
 		if stats.Type == InterfaceWAN {
-			wanStats = &clone
+			if stats.Name == "WAN" {
+				primaryWAN = &clone
+			}
+			if c.activeWAN != "" && stats.Interface == c.activeWAN {
+				wanStats = &clone
+			}
 		} else if stats.Type == InterfaceVPN {
 			vpnTotalsRxThroughput += stats.CurrentRxThroughput
 			vpnTotalsTxThroughput += stats.CurrentTxThroughput
@@ -290,7 +401,13 @@ func (c *Collector) Snapshot() Snapshot {
 		LoadAverage: cloneLoadAverage(c.loadAverage),
 	}
 
+	if wanStats == nil {
+		wanStats = primaryWAN
+	}
+
 	if wanStats != nil {
+		wanStats.Active = true
+		snap.ActiveWAN = wanStats.Interface
 		correctedRx := wanStats.CurrentRxThroughput - vpnTotalsRxThroughput
 		if correctedRx < 0 {
 			correctedRx = 0
@@ -332,11 +449,11 @@ func (c *Collector) Snapshot() Snapshot {
 	return snap
 }
 
-func readInterfaceBytes(iface string) (uint64, uint64, error) {
+func readInterfaceBytes(sysClassNetRoot, iface string) (uint64, uint64, error) {
 	if iface == "" {
 		return 0, 0, errors.New("interface not specified")
 	}
-	base := filepath.Join("/sys/class/net", iface, "statistics")
+	base := filepath.Join(sysClassNetRoot, iface, "statistics")
 	rx, err := readUintFromFile(filepath.Join(base, "rx_bytes"))
 	if err != nil {
 		return 0, 0, fmt.Errorf("rx_bytes: %w", err)
@@ -0,0 +1,176 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigureWANsTracksEachUplink(t *testing.T) {
+	collector := NewCollector("", time.Second, 10)
+	collector.ConfigureWANs([]string{"eth8", "eth9", "ppp0"})
+	collector.ConfigureWANs([]string{"eth8", "eth9"})
+
+	snap := collector.Snapshot()
+	names := map[string]string{}
+	for _, iface := range snap.Interfaces {
+		if iface.Type == InterfaceWAN {
+			names[iface.Name] = iface.Interface
+		}
+	}
+	if len(names) != 2 || names["WAN"] != "eth8" || names["WAN2"] != "eth9" {
+		t.Fatalf("unexpected WANs %v", names)
+	}
+	if snap.ActiveWAN != "eth8" {
+		t.Fatalf("expected the primary WAN to be active by default, got %q", snap.ActiveWAN)
+	}
+}
+
+func TestSnapshotCorrectsOnlyTheActiveWAN(t *testing.T) {
+	collector := NewCollector("", time.Second, 10)
+	collector.ConfigureWANs([]string{"eth8", "eth9"})
+	collector.ConfigureInterfaces("", map[string]string{"vpn-a": "wg0"})
+	collector.interfaces["WAN"].CurrentRxThroughput = 100
+	collector.interfaces["WAN2"].CurrentRxThroughput = 500
+	collector.interfaces["vpn-a"].CurrentRxThroughput = 200
+
+	collector.SetActiveWAN("eth9")
+	snap := collector.Snapshot()
+	for _, iface := range snap.Interfaces {
+		switch iface.Name {
+		case "WAN":
+			if iface.Active || iface.CurrentRxThroughput != 100 {
+				t.Fatalf("standby WAN should be reported as measured: %+v", iface)
+			}
+		case "WAN2":
+			if !iface.Active || iface.CurrentRxThroughput != 300 {
+				t.Fatalf("active WAN should have VPN traffic subtracted: %+v", iface)
+			}
+		}
+	}
+	if snap.ActiveWAN != "eth9" || snap.WanCorrectedThroughput != 300 {
+		t.Fatalf("unexpected snapshot totals: active=%q corrected=%v", snap.ActiveWAN, snap.WanCorrectedThroughput)
+	}
+}
+
+func TestSetPollIntervalSignalsRunningLoop(t *testing.T) {
+	c := NewCollector("", time.Hour, 10)
+	c.SetPollInterval(0)
+	if got := c.PollInterval(); got != time.Hour {
+		t.Fatalf("non-positive interval should be ignored, got %s", got)
+	}
+	c.SetPollInterval(5 * time.Second)
+	if got := c.PollInterval(); got != 5*time.Second {
+		t.Fatalf("PollInterval = %s, want 5s", got)
+	}
+	select {
+	case <-c.pollReset:
+	default:
+		t.Fatalf("expected the polling loop to be signalled")
+	}
+	c.SetPollInterval(5 * time.Second)
+	select {
+	case <-c.pollReset:
+		t.Fatalf("unchanged interval should not signal the loop")
+	default:
+	}
+}
+
+func TestUpdateReportsInterfaceHealth(t *testing.T) {
+	root := t.TempDir()
+	writeCounters := func(iface string, rx, tx string) {
+		dir := filepath.Join(root, iface, "statistics")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "rx_bytes"), []byte(rx), 0o644); err != nil {
+			t.Fatalf("write rx_bytes: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "tx_bytes"), []byte(tx), 0o644); err != nil {
+			t.Fatalf("write tx_bytes: %v", err)
+		}
+	}
+	writeCounters("wg-ok", "100\n", "50\n")
+	writeCounters("wg-broken", "garbage\n", "50\n")
+	writeCounters("wg-off", "100\n", "50\n")
+
+	collector := NewCollector("", time.Second, 10)
+	collector.sysClassNetRoot = root
+	collector.cgroupRoot = t.TempDir()
+	collector.ConfigureInterfaces("", map[string]string{
+		"ok":      "wg-ok",
+		"broken":  "wg-broken",
+		"stopped": "wg-stopped",
+		"off":     "wg-off",
+	})
+	collector.SetDisabledInterfaces([]string{"wg-off"})
+
+	start := time.Unix(1_700_000_000, 0)
+	collector.update(start)
+	collector.update(start.Add(time.Second))
+	collector.update(start.Add(10 * time.Second))
+
+	health := map[string]*InterfaceStats{}
+	for _, iface := range collector.Snapshot().Interfaces {
+		health[iface.Name] = iface
+	}
+	if got := health["ok"]; !got.Available || got.Health.Status != HealthOK || got.Health.ReadErrors != 0 || got.Health.SamplingGaps != 1 {
+		t.Fatalf("unexpected healthy interface: %+v", got.Health)
+	}
+	if got := health["broken"]; got.Available || got.Health.Status != HealthReadError || got.Health.ReadErrors != 3 || got.Health.ConsecutiveErrors != 3 || got.Health.LastError == "" {
+		t.Fatalf("unexpected unreadable interface: %+v", got.Health)
+	}
+	if got := health["stopped"]; got.Health.Status != HealthMissing || got.Health.ReadErrors != 0 {
+		t.Fatalf("a missing interface is not a collector error: %+v", got.Health)
+	}
+	if got := health["off"]; got.Enabled || got.Available || got.Health.Status != HealthDisabled {
+		t.Fatalf("unexpected disabled interface: enabled=%v %+v", got.Enabled, got.Health)
+	}
+}
+
+func TestNormalizeInterfaceList(t *testing.T) {
+	got, err := NormalizeInterfaceList(" eth9, WAN2\nwg-sgp eth9 ")
+	if err != nil || got != "eth9,WAN2,wg-sgp" {
+		t.Fatalf("NormalizeInterfaceList = %q, %v", got, err)
+	}
+	if _, err := NormalizeInterfaceList("eth9, ../etc"); err == nil {
+		t.Fatalf("expected an invalid name to be rejected")
+	}
+}
+
+func TestQueryResamplesHistoryIntoBuckets(t *testing.T) {
+	collector := NewCollector("", time.Second, 100)
+	collector.ConfigureInterfaces("eth8", map[string]string{"vpn-a": "wg0"})
+	base := time.Unix(1_700_000_000, 0)
+	for i, rate := range []float64{10, 30, 20, 40, 100} {
+		collector.interfaces["vpn-a"].History = append(collector.interfaces["vpn-a"].History, datapoint{
+			Timestamp:       base.Add(time.Duration(i*20) * time.Second),
+			RxThroughput:    rate,
+			TotalThroughput: rate,
+		})
+	}
+
+	result := collector.query(QueryOptions{Interval: time.Minute, Window: 10 * time.Minute, Interfaces: []string{"wg0"}}, base.Add(5*time.Minute))
+	if len(result.Interfaces) != 1 || result.Interfaces[0].Name != "vpn-a" {
+		t.Fatalf("expected only vpn-a, got %+v", result.Interfaces)
+	}
+	buckets := result.Interfaces[0].Buckets
+	if result.IntervalSeconds != 60 || len(buckets) != 2 {
+		t.Fatalf("unexpected buckets (interval %d): %+v", result.IntervalSeconds, buckets)
+	}
+	// base is 20s past a minute boundary: 0s, 20s in the first bucket and
+	// 40s, 60s, 80s in the second.
+	first, second := buckets[0], buckets[1]
+	if first.Samples != 2 || first.Rx.Min != 10 || first.Rx.Max != 30 || first.Rx.Avg != 20 {
+		t.Fatalf("unexpected first bucket %+v", first)
+	}
+	if second.Samples != 3 || second.Rx.Min != 20 || second.Rx.Max != 100 || second.Total.Avg != 160.0/3 {
+		t.Fatalf("unexpected second bucket %+v", second)
+	}
+
+	wide := collector.query(QueryOptions{Interval: time.Second, Window: 24 * time.Hour}, base.Add(5*time.Minute))
+	if wide.IntervalSeconds*MaxQueryBuckets < 24*3600 {
+		t.Fatalf("expected the interval to widen to cap the bucket count, got %ds", wide.IntervalSeconds)
+	}
+}
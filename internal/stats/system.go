@@ -38,6 +38,18 @@ type CPUUsage struct {
 	Message   string  `json:"message,omitempty"`
 }
 
+// LoadAverage returns the load averages read at the last poll, or nil when
+// /proc/loadavg could not be read.
+func (c *Collector) LoadAverage() *LoadAverage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.loadAverage == nil {
+		return nil
+	}
+	load := *c.loadAverage
+	return &load
+}
+
 func (c *Collector) updateLoadAverageLocked() {
 	load, err := readLoadAverage(c.loadAvgPath)
 	if err != nil {
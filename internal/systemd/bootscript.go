@@ -45,9 +45,10 @@ SYSTEMD_DIR=%q
 
 mkdir -p "${UNITS_DIR}"
 
-if [ -f "${UNITS_DIR}/split-vpn-webui.service" ]; then
-    ln -sf "${UNITS_DIR}/split-vpn-webui.service" "${SYSTEMD_DIR}/split-vpn-webui.service"
-fi
+for name in split-vpn-webui.service split-vpn-webui-restore.service; do
+    [ -f "${UNITS_DIR}/${name}" ] || continue
+    ln -sf "${UNITS_DIR}/${name}" "${SYSTEMD_DIR}/${name}"
+done
 
 for unit in "${UNITS_DIR}"/svpn-*.service; do
     [ -f "${unit}" ] || continue
@@ -56,6 +57,15 @@ done
 
 systemctl daemon-reload
 systemctl enable split-vpn-webui.service 2>/dev/null || true
+if [ -f "${UNITS_DIR}/split-vpn-webui-restore.service" ]; then
+    systemctl enable split-vpn-webui-restore.service 2>/dev/null || true
+fi
 systemctl restart split-vpn-webui.service
+
+# Later boot scripts may drive the CLI; give the control socket time to appear.
+for _ in $(seq 1 30); do
+    [ -S "${DATA_DIR}/control.sock" ] && break
+    sleep 1
+done
 `, m.dataDir, m.systemdDir)
 }
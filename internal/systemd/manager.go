@@ -1,11 +1,14 @@
 package systemd
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -29,6 +32,13 @@ func (execRunner) Output(name string, args ...string) ([]byte, error) {
 	return cmd.CombinedOutput()
 }
 
+// UnitLinker links a canonical unit into the systemd directory for a
+// manager that cannot write there itself. The privilege helper client is
+// one; it only links unit files the helper wrote.
+type UnitLinker interface {
+	LinkUnit(unitName string) error
+}
+
 // ServiceManager defines systemd operations needed by other packages.
 type ServiceManager interface {
 	WriteUnit(unitName, content string) error
@@ -39,6 +49,7 @@ type ServiceManager interface {
 	Enable(unitName string) error
 	Disable(unitName string) error
 	Status(unitName string) (string, error)
+	Journal(unitName string, lines int) ([]string, error)
 	WriteBootHook() error
 }
 
@@ -53,16 +64,26 @@ type Manager struct {
 
 // NewManager creates a manager using default UniFi paths.
 func NewManager(dataDir string) *Manager {
+	return NewManagerWithRunner(dataDir, nil)
+}
+
+// NewManagerWithRunner creates a manager using default UniFi paths whose
+// systemctl and journalctl commands go through runner, e.g. the privilege
+// helper.
+func NewManagerWithRunner(dataDir string, runner CommandRunner) *Manager {
 	trimmed := strings.TrimSpace(dataDir)
 	if trimmed == "" {
 		trimmed = "/data/split-vpn-webui"
 	}
+	if runner == nil {
+		runner = execRunner{}
+	}
 	return &Manager{
 		dataDir:      trimmed,
 		unitsDir:     filepath.Join(trimmed, "units"),
 		systemdDir:   "/etc/systemd/system",
 		bootHookPath: "/data/on_boot.d/10-split-vpn-webui.sh",
-		runner:       execRunner{},
+		runner:       runner,
 	}
 }
 
@@ -100,7 +121,7 @@ func (m *Manager) WriteUnit(unitName, content string) error {
 	if err := writeFileAtomic(canonicalPath, []byte(content), 0o644); err != nil {
 		return err
 	}
-	if err := ensureSymlink(canonicalPath, filepath.Join(m.systemdDir, resolved)); err != nil {
+	if err := m.linkUnit(canonicalPath, filepath.Join(m.systemdDir, resolved)); err != nil {
 		return err
 	}
 	return m.daemonReload()
@@ -115,10 +136,16 @@ func (m *Manager) RemoveUnit(unitName string) error {
 	canonicalPath := filepath.Join(m.unitsDir, resolved)
 	symlinkPath := filepath.Join(m.systemdDir, resolved)
 
-	if err := os.Remove(canonicalPath); err != nil && !os.IsNotExist(err) {
-		return err
-	}
 	if err := os.Remove(symlinkPath); err != nil && !os.IsNotExist(err) {
+		if !errors.Is(err, fs.ErrPermission) {
+			return err
+		}
+		// Unprivileged: disable also removes links made by systemctl link.
+		if disableErr := m.runner.Run("systemctl", "disable", resolved); disableErr != nil {
+			return fmt.Errorf("%w (systemctl disable: %v)", err, disableErr)
+		}
+	}
+	if err := os.Remove(canonicalPath); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 	return m.daemonReload()
@@ -163,6 +190,34 @@ func (m *Manager) Status(unitName string) (string, error) {
 	return status, nil
 }
 
+// Journal returns the most recent journal lines logged by a unit, oldest first.
+func (m *Manager) Journal(unitName string, lines int) ([]string, error) {
+	resolved, err := normalizeUnitName(unitName)
+	if err != nil {
+		return nil, err
+	}
+	if lines <= 0 {
+		lines = 20
+	}
+	out, runErr := m.runner.Output("journalctl", "-u", resolved, "-n", strconv.Itoa(lines), "--no-pager", "-o", "short-iso")
+	if runErr != nil {
+		details := strings.TrimSpace(string(out))
+		if details == "" {
+			return nil, fmt.Errorf("journalctl -u %s: %w", resolved, runErr)
+		}
+		return nil, fmt.Errorf("journalctl -u %s: %s: %w", resolved, details, runErr)
+	}
+	entries := make([]string, 0, lines)
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimRight(line, "\r ")
+		if line == "" || strings.HasPrefix(line, "-- ") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, nil
+}
+
 func (m *Manager) runSystemctl(action, unitName string) error {
 	resolved, err := normalizeUnitName(unitName)
 	if err != nil {
@@ -189,6 +244,16 @@ func (m *Manager) daemonReload() error {
 	return nil
 }
 
+// LinkUnit restores the /etc/systemd/system symlink of a canonical unit if
+// it is missing or points elsewhere.
+func (m *Manager) LinkUnit(unitName string) error {
+	resolved, err := normalizeUnitName(unitName)
+	if err != nil {
+		return err
+	}
+	return m.ensureLinkedUnit(resolved)
+}
+
 func (m *Manager) ensureLinkedUnit(resolvedUnit string) error {
 	// For managed canonical units, restore missing /etc/systemd/system symlink
 	// before issuing runtime actions. This recovers from disable/link drift.
@@ -204,33 +269,29 @@ func (m *Manager) ensureLinkedUnit(resolvedUnit string) error {
 		return fmt.Errorf("canonical unit path is a directory: %s", canonicalPath)
 	}
 	symlinkPath := filepath.Join(m.systemdDir, resolvedUnit)
-	linkFixed := false
-	if existingTarget, linkErr := os.Readlink(symlinkPath); linkErr == nil {
-		if existingTarget != canonicalPath {
-			if err := os.Remove(symlinkPath); err != nil {
-				return err
-			}
-			if err := os.Symlink(canonicalPath, symlinkPath); err != nil {
-				return err
-			}
-			linkFixed = true
-		}
-	} else if os.IsNotExist(linkErr) {
-		if err := os.Symlink(canonicalPath, symlinkPath); err != nil {
-			return err
-		}
-		linkFixed = true
-	} else {
-		if err := os.Remove(symlinkPath); err != nil {
-			return err
-		}
-		if err := os.Symlink(canonicalPath, symlinkPath); err != nil {
-			return err
-		}
-		linkFixed = true
+	if existingTarget, err := os.Readlink(symlinkPath); err == nil && existingTarget == canonicalPath {
+		return nil
+	}
+	if err := m.linkUnit(canonicalPath, symlinkPath); err != nil {
+		return err
+	}
+	return m.daemonReload()
+}
+
+// linkUnit symlinks a canonical unit into the systemd directory. Without
+// write access there, as when running unprivileged behind the privilege
+// helper, a runner that is a UnitLinker creates the symlink instead.
+func (m *Manager) linkUnit(canonicalPath, linkPath string) error {
+	err := ensureSymlink(canonicalPath, linkPath)
+	if err == nil || !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	linker, ok := m.runner.(UnitLinker)
+	if !ok {
+		return err
 	}
-	if linkFixed {
-		return m.daemonReload()
+	if linkErr := linker.LinkUnit(filepath.Base(canonicalPath)); linkErr != nil {
+		return fmt.Errorf("%w (privilege helper link: %v)", err, linkErr)
 	}
 	return nil
 }
@@ -227,6 +227,8 @@ func TestWriteBootHook(t *testing.T) {
 		"svpn-*.service",
 		"systemctl daemon-reload",
 		"systemctl restart split-vpn-webui.service",
+		"/control.sock",
+		"systemctl enable split-vpn-webui-restore.service",
 	} {
 		if !strings.Contains(text, expected) {
 			t.Fatalf("boot hook missing %q", expected)
@@ -246,6 +248,60 @@ func TestWriteBootHook(t *testing.T) {
 	}
 }
 
+func TestWriteRestoreUnit(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "data")
+	unitsDir := filepath.Join(tempDir, "units")
+	systemdDir := filepath.Join(tempDir, "etc-systemd")
+	runner := &recordingRunner{}
+	m := NewManagerWithDeps(dataDir, unitsDir, systemdDir, filepath.Join(tempDir, "on_boot.sh"), runner)
+
+	if err := m.WriteRestoreUnit(); err != nil {
+		t.Fatalf("WriteRestoreUnit failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(unitsDir, RestoreUnitName))
+	if err != nil {
+		t.Fatalf("failed reading restore unit: %v", err)
+	}
+	for _, expected := range []string{
+		"DefaultDependencies=no",
+		"Before=network-pre.target split-vpn-webui.service",
+		"Type=oneshot",
+		"ExecStart=" + filepath.Join(dataDir, "split-vpn-webui") + " --restore-routing --data-dir " + dataDir,
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("restore unit missing %q:\n%s", expected, content)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(systemdDir, RestoreUnitName)); err != nil || target != filepath.Join(unitsDir, RestoreUnitName) {
+		t.Fatalf("expected restore unit symlink, got %q (%v)", target, err)
+	}
+	calls := make([]string, 0, len(runner.calls))
+	for _, call := range runner.calls {
+		calls = append(calls, joinCall(call))
+	}
+	if got := strings.Join(calls, "; "); got != "systemctl daemon-reload; systemctl enable "+RestoreUnitName {
+		t.Fatalf("unexpected calls: %s", got)
+	}
+
+	// Once current and enabled, rewriting it is a no-op.
+	wantsDir := filepath.Join(systemdDir, "multi-user.target.wants")
+	if err := os.MkdirAll(wantsDir, 0o755); err != nil {
+		t.Fatalf("mkdir wants dir: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(systemdDir, RestoreUnitName), filepath.Join(wantsDir, RestoreUnitName)); err != nil {
+		t.Fatalf("symlink wants: %v", err)
+	}
+	runner.calls = nil
+	if err := m.WriteRestoreUnit(); err != nil {
+		t.Fatalf("second WriteRestoreUnit failed: %v", err)
+	}
+	if len(runner.calls) != 0 {
+		t.Fatalf("expected no systemctl calls for a current unit, got %v", runner.calls)
+	}
+}
+
 func TestStatusReturnsOutputOnFailure(t *testing.T) {
 	runner := &recordingRunner{
 		outputErrs: map[string]error{"systemctl is-active broken.service": errors.New("exit 3")},
@@ -277,3 +333,22 @@ func TestStartIncludesSystemctlOutputOnFailure(t *testing.T) {
 		t.Fatalf("expected systemctl output in error, got %v", err)
 	}
 }
+
+func TestJournalReturnsRecentLines(t *testing.T) {
+	call := "journalctl -u svpn-wg.service -n 5 --no-pager -o short-iso"
+	runner := &recordingRunner{
+		outputs: map[string][]byte{call: []byte("-- Boot 1a2b --\n2026-01-02T03:04:05+0000 host wg-quick[12]: RTNETLINK answers: File exists\n\n2026-01-02T03:04:05+0000 host systemd[1]: svpn-wg.service: Failed.\n")},
+	}
+	m := NewManagerWithDeps("/data", "/data/units", "/etc/systemd/system", "/data/on_boot.d/10-split-vpn-webui.sh", runner)
+
+	lines, err := m.Journal("svpn-wg", 5)
+	if err != nil {
+		t.Fatalf("Journal failed: %v", err)
+	}
+	if len(lines) != 2 || !strings.Contains(lines[0], "RTNETLINK") || !strings.Contains(lines[1], "Failed.") {
+		t.Fatalf("unexpected journal lines: %#v", lines)
+	}
+	if len(runner.calls) != 1 || joinCall(runner.calls[0]) != call {
+		t.Fatalf("unexpected runner calls: %#v", runner.calls)
+	}
+}
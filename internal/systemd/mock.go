@@ -10,6 +10,7 @@ type MockManager struct {
 	EnableFunc     func(unitName string) error
 	DisableFunc    func(unitName string) error
 	StatusFunc     func(unitName string) (string, error)
+	JournalFunc    func(unitName string, lines int) ([]string, error)
 	WriteBootFunc  func() error
 }
 
@@ -69,6 +70,13 @@ func (m *MockManager) Status(unitName string) (string, error) {
 	return "", nil
 }
 
+func (m *MockManager) Journal(unitName string, lines int) ([]string, error) {
+	if m != nil && m.JournalFunc != nil {
+		return m.JournalFunc(unitName, lines)
+	}
+	return nil, nil
+}
+
 func (m *MockManager) WriteBootHook() error {
 	if m != nil && m.WriteBootFunc != nil {
 		return m.WriteBootFunc()
@@ -0,0 +1,68 @@
+package systemd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RestoreUnitName is the oneshot unit that re-applies persisted routing state
+// early in boot, before the web UI service starts.
+const RestoreUnitName = "split-vpn-webui-restore.service"
+
+// WriteRestoreUnit writes/updates the boot-time routing restore unit and
+// enables it. Nothing is reloaded or re-enabled when it is already current.
+func (m *Manager) WriteRestoreUnit() error {
+	if strings.TrimSpace(m.dataDir) == "" {
+		return fmt.Errorf("data directory is required")
+	}
+	content := []byte(m.restoreUnitContent())
+	canonicalPath := filepath.Join(m.unitsDir, RestoreUnitName)
+	existing, err := os.ReadFile(canonicalPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !bytes.Equal(existing, content) {
+		if err := m.WriteUnit(RestoreUnitName, string(content)); err != nil {
+			return err
+		}
+	} else if err := m.ensureLinkedUnit(RestoreUnitName); err != nil {
+		return err
+	}
+
+	wantsLink := filepath.Join(m.systemdDir, "multi-user.target.wants", RestoreUnitName)
+	if _, err := os.Lstat(wantsLink); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return m.Enable(RestoreUnitName)
+}
+
+func (m *Manager) restoreUnitContent() string {
+	binaryPath := filepath.Join(m.dataDir, "split-vpn-webui")
+	logPath := filepath.Join(m.dataDir, "logs", "split-vpn-webui.log")
+	return fmt.Sprintf(`[Unit]
+Description=Split VPN Web UI routing restore
+# Runs before the network comes up so marked traffic never leaves through
+# the WAN while the web UI is still starting.
+DefaultDependencies=no
+After=local-fs.target
+RequiresMountsFor=%s
+Wants=network-pre.target
+Before=network-pre.target split-vpn-webui.service
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=%s --restore-routing --data-dir %s
+TimeoutStartSec=90s
+StandardOutput=append:%s
+StandardError=append:%s
+
+[Install]
+WantedBy=multi-user.target
+`, m.dataDir, binaryPath, m.dataDir, logPath, logPath)
+}
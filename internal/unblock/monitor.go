@@ -0,0 +1,203 @@
+package unblock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+const (
+	// MaxIntervalHours bounds the schedule at one check a week.
+	MaxIntervalHours = 7 * 24
+	// initialDelay lets tunnels come up before the first scheduled check.
+	initialDelay = 2 * time.Minute
+	scheduleTick = time.Minute
+)
+
+// ErrNotConnected is returned when a single-VPN run names a VPN that is not
+// among the current targets.
+var ErrNotConnected = errors.New("vpn is not connected")
+
+// TargetSource returns the tunnels that can be checked now.
+type TargetSource func() ([]Target, error)
+
+// ValidateSettings checks the unblock schedule setting.
+func ValidateSettings(current settings.Settings) error {
+	var errs settings.ValidationError
+	if current.UnblockCheckIntervalHours < 0 || current.UnblockCheckIntervalHours > MaxIntervalHours {
+		errs.Addf("unblockCheckIntervalHours", "unblockCheckIntervalHours must be between 0 and %d", MaxIntervalHours)
+	}
+	return errs.Err()
+}
+
+// Monitor keeps the latest result per VPN and re-checks every tunnel on the
+// configured schedule.
+type Monitor struct {
+	checker  *Checker
+	settings *settings.Manager
+	targets  TargetSource
+	now      func() time.Time
+
+	runMu sync.Mutex
+
+	mu         sync.Mutex
+	results    map[string]Result
+	lastRun    time.Time
+	startedAt  time.Time
+	handler    func([]Result)
+	started    bool
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor that checks the tunnels targets returns.
+func NewMonitor(checker *Checker, settingsManager *settings.Manager, targets TargetSource) (*Monitor, error) {
+	if checker == nil {
+		return nil, fmt.Errorf("unblock checker is required")
+	}
+	if settingsManager == nil {
+		return nil, fmt.Errorf("settings manager is required")
+	}
+	if targets == nil {
+		return nil, fmt.Errorf("target source is required")
+	}
+	return &Monitor{
+		checker:  checker,
+		settings: settingsManager,
+		targets:  targets,
+		now:      time.Now,
+		results:  make(map[string]Result),
+	}, nil
+}
+
+// SetHandler registers a callback for the results of each run.
+func (m *Monitor) SetHandler(handler func([]Result)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// Results returns the latest result of every checked VPN, sorted by name.
+func (m *Monitor) Results() []Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	results := make([]Result, 0, len(m.results))
+	for _, result := range m.results {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].VPN < results[j].VPN })
+	return results
+}
+
+// Run checks one VPN, or every target when vpn is empty. A full run drops
+// the results of VPNs that are no longer targets.
+func (m *Monitor) Run(ctx context.Context, vpn string) ([]Result, error) {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	targets, err := m.targets()
+	if err != nil {
+		return nil, err
+	}
+	if vpn != "" {
+		selected := targets[:0:0]
+		for _, target := range targets {
+			if target.VPN == vpn {
+				selected = append(selected, target)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNotConnected, vpn)
+		}
+		targets = selected
+	}
+	results := m.checker.CheckAll(ctx, targets)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	m.mu.Lock()
+	if vpn == "" {
+		m.results = make(map[string]Result, len(results))
+		m.lastRun = m.now()
+	}
+	for _, result := range results {
+		m.results[result.VPN] = result
+	}
+	handler := m.handler
+	m.mu.Unlock()
+
+	if handler != nil {
+		handler(results)
+	}
+	return results, nil
+}
+
+// Start launches the scheduled checks.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.started = true
+	m.startedAt = m.now()
+	m.loopCancel = cancel
+	m.mu.Unlock()
+
+	m.loopWG.Add(1)
+	go func() {
+		defer m.loopWG.Done()
+		ticker := time.NewTicker(scheduleTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if m.due() {
+					_, _ = m.Run(ctx, "")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the scheduled checks.
+func (m *Monitor) Stop() error {
+	m.mu.Lock()
+	loopCancel := m.loopCancel
+	m.started = false
+	m.loopCancel = nil
+	m.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	m.loopWG.Wait()
+	return nil
+}
+
+// due reports whether the schedule calls for a full run. Manual full runs
+// restart the interval.
+func (m *Monitor) due() bool {
+	current, err := m.settings.Get()
+	if err != nil || current.UnblockCheckIntervalHours <= 0 {
+		return false
+	}
+	interval := time.Duration(min(current.UnblockCheckIntervalHours, MaxIntervalHours)) * time.Hour
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lastRun.IsZero() {
+		return now.Sub(m.startedAt) >= initialDelay
+	}
+	return now.Sub(m.lastRun) >= interval
+}
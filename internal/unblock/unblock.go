@@ -0,0 +1,272 @@
+// Package unblock checks which streaming services each VPN tunnel unblocks
+// by probing the services' region-sensitive pages through the tunnel.
+package unblock
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/egresscheck"
+)
+
+const (
+	defaultProbeTimeout = 15 * time.Second
+	// maxConcurrentVPNs bounds how many tunnels are checked at once.
+	maxConcurrentVPNs = 4
+)
+
+// Service IDs.
+const (
+	ServiceNetflix    = "netflix"
+	ServiceDisneyPlus = "disney-plus"
+	ServiceHBOMax     = "hbo-max"
+)
+
+// Statuses describe what a service offers through a tunnel.
+const (
+	// StatusUnblocked means the service serves its catalogue.
+	StatusUnblocked = "unblocked"
+	// StatusOriginalsOnly means Netflix only serves its own productions,
+	// which it does for addresses it recognizes as VPN or proxy exits.
+	StatusOriginalsOnly = "originals-only"
+	// StatusBlocked means the service answered but refuses the region or
+	// the network.
+	StatusBlocked = "blocked"
+	// StatusUnreachable means the service did not answer through the tunnel.
+	StatusUnreachable = "unreachable"
+)
+
+// Target is a tunnel to check.
+type Target struct {
+	VPN       string
+	Interface string
+	// Resolvers are the VPN-pushed resolvers; lookups use them so CDNs
+	// answer for the tunnel's region.
+	Resolvers []netip.Addr
+}
+
+// ServiceResult is what one service offers through one tunnel.
+type ServiceResult struct {
+	Service string `json:"service"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	// Region is the ISO country code the service assigned, when it told.
+	Region string `json:"region,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Result is the outcome of checking one tunnel.
+type Result struct {
+	VPN       string          `json:"vpn"`
+	Interface string          `json:"interface"`
+	Services  []ServiceResult `json:"services"`
+	CheckedAt time.Time       `json:"checkedAt"`
+}
+
+type getFunc func(ctx context.Context, url string) (egresscheck.Response, error)
+
+type service struct {
+	id    string
+	name  string
+	check func(ctx context.Context, get getFunc) ServiceResult
+}
+
+// services are checked in this order and reported in it.
+var services = []service{
+	{id: ServiceNetflix, name: "Netflix", check: checkNetflix},
+	{id: ServiceDisneyPlus, name: "Disney+", check: checkDisneyPlus},
+	{id: ServiceHBOMax, name: "HBO Max", check: checkHBOMax},
+}
+
+// Checker probes streaming services through a tunnel.
+type Checker struct {
+	fetch egresscheck.Fetcher
+}
+
+// NewChecker returns a checker fetching over HTTPS.
+func NewChecker() *Checker {
+	return NewCheckerWith(egresscheck.NewHTTPFetcher(defaultProbeTimeout))
+}
+
+// NewCheckerWith returns a checker with a custom fetcher, for tests.
+func NewCheckerWith(fetch egresscheck.Fetcher) *Checker {
+	return &Checker{fetch: fetch}
+}
+
+// Check probes every service through the target's interface.
+func (c *Checker) Check(ctx context.Context, target Target) (Result, error) {
+	iface := strings.TrimSpace(target.Interface)
+	if iface == "" {
+		return Result{}, fmt.Errorf("vpn interface is required")
+	}
+	get := func(ctx context.Context, url string) (egresscheck.Response, error) {
+		return c.fetch.Fetch(ctx, iface, target.Resolvers, url)
+	}
+	result := Result{
+		VPN:       target.VPN,
+		Interface: iface,
+		Services:  make([]ServiceResult, len(services)),
+		CheckedAt: time.Now().UTC(),
+	}
+	var wg sync.WaitGroup
+	for idx, svc := range services {
+		wg.Add(1)
+		go func(idx int, svc service) {
+			defer wg.Done()
+			outcome := svc.check(ctx, get)
+			outcome.Service = svc.id
+			outcome.Name = svc.name
+			result.Services[idx] = outcome
+		}(idx, svc)
+	}
+	wg.Wait()
+	return result, nil
+}
+
+// CheckAll checks the targets, a few at a time, and returns the results
+// sorted by VPN name. Targets that fail to check are left out.
+func (c *Checker) CheckAll(ctx context.Context, targets []Target) []Result {
+	results := make([]Result, 0, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentVPNs)
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			result, err := c.Check(ctx, target)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}(target)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].VPN < results[j].VPN })
+	return results
+}
+
+// Netflix answers 404 for titles outside the requested catalogue. The first
+// title is licensed in most regions; the second is a Netflix original that
+// is also served to recognized VPN exits.
+const (
+	netflixLicensedURL  = "https://www.netflix.com/title/70143836"
+	netflixOriginalsURL = "https://www.netflix.com/title/80018499"
+)
+
+var (
+	netflixURLRegion  = regexp.MustCompile(`netflix\.com/([a-z]{2})(?:-[a-z]{2})?/title`)
+	netflixBodyRegion = regexp.MustCompile(`"requestCountry":\{"id":"([A-Z]{2})"`)
+)
+
+func checkNetflix(ctx context.Context, get getFunc) ServiceResult {
+	response, err := get(ctx, netflixLicensedURL)
+	if err != nil {
+		return ServiceResult{Status: StatusUnreachable, Detail: err.Error()}
+	}
+	switch {
+	case response.StatusCode == 200:
+		return ServiceResult{Status: StatusUnblocked, Region: netflixRegion(response)}
+	case response.StatusCode != 404:
+		return blockedStatus(response)
+	}
+	originals, err := get(ctx, netflixOriginalsURL)
+	if err != nil {
+		return ServiceResult{Status: StatusUnreachable, Detail: err.Error()}
+	}
+	if originals.StatusCode == 200 {
+		return ServiceResult{
+			Status: StatusOriginalsOnly,
+			Region: netflixRegion(originals),
+			Detail: "only Netflix originals are available",
+		}
+	}
+	return blockedStatus(originals)
+}
+
+func netflixRegion(response egresscheck.Response) string {
+	if match := netflixURLRegion.FindStringSubmatch(response.FinalURL); match != nil {
+		return strings.ToUpper(match[1])
+	}
+	if match := netflixBodyRegion.FindSubmatch(response.Body); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// Disney+ redirects unsupported regions to an "unavailable" page or its
+// pre-launch preview site.
+const disneyPlusURL = "https://www.disneyplus.com/"
+
+var (
+	disneyPlusURLRegion  = regexp.MustCompile(`disneyplus\.com/[a-z]{2}-([a-z]{2})(?:/|$)`)
+	disneyPlusBodyRegion = regexp.MustCompile(`"region":"([A-Z]{2})"`)
+)
+
+func checkDisneyPlus(ctx context.Context, get getFunc) ServiceResult {
+	response, err := get(ctx, disneyPlusURL)
+	if err != nil {
+		return ServiceResult{Status: StatusUnreachable, Detail: err.Error()}
+	}
+	finalURL := strings.ToLower(response.FinalURL)
+	if strings.Contains(finalURL, "unavailable") || strings.Contains(finalURL, "preview") {
+		return ServiceResult{Status: StatusBlocked, Detail: "Disney+ is not offered in this region"}
+	}
+	if response.StatusCode != 200 {
+		return blockedStatus(response)
+	}
+	region := ""
+	if match := disneyPlusURLRegion.FindStringSubmatch(finalURL); match != nil {
+		region = strings.ToUpper(match[1])
+	} else if match := disneyPlusBodyRegion.FindSubmatch(response.Body); match != nil {
+		region = string(match[1])
+	}
+	return ServiceResult{Status: StatusUnblocked, Region: region}
+}
+
+// HBO Max redirects unsupported regions to a geo-availability page and
+// supported ones to a country path such as /se/en.
+const hboMaxURL = "https://www.max.com/"
+
+var (
+	hboMaxURLRegion  = regexp.MustCompile(`max\.com/([a-z]{2})(?:/|$)`)
+	hboMaxBodyRegion = regexp.MustCompile(`"countryCode":"([A-Z]{2})"`)
+)
+
+func checkHBOMax(ctx context.Context, get getFunc) ServiceResult {
+	response, err := get(ctx, hboMaxURL)
+	if err != nil {
+		return ServiceResult{Status: StatusUnreachable, Detail: err.Error()}
+	}
+	finalURL := strings.ToLower(response.FinalURL)
+	if strings.Contains(finalURL, "geo-availability") || strings.Contains(finalURL, "unavailable") {
+		return ServiceResult{Status: StatusBlocked, Detail: "HBO Max is not offered in this region"}
+	}
+	if response.StatusCode != 200 {
+		return blockedStatus(response)
+	}
+	region := ""
+	if match := hboMaxURLRegion.FindStringSubmatch(finalURL); match != nil {
+		region = strings.ToUpper(match[1])
+	} else if match := hboMaxBodyRegion.FindSubmatch(response.Body); match != nil {
+		region = string(match[1])
+	}
+	return ServiceResult{Status: StatusUnblocked, Region: region}
+}
+
+func blockedStatus(response egresscheck.Response) ServiceResult {
+	if response.StatusCode == 403 {
+		return ServiceResult{Status: StatusBlocked, Detail: "HTTP 403: the service refuses this network"}
+	}
+	return ServiceResult{Status: StatusBlocked, Detail: fmt.Sprintf("HTTP %d", response.StatusCode)}
+}
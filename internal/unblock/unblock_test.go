@@ -0,0 +1,151 @@
+package unblock
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/egresscheck"
+	"split-vpn-webui/internal/settings"
+)
+
+type fakeFetcher struct {
+	mu        sync.Mutex
+	responses map[string]egresscheck.Response
+	calls     int
+}
+
+func (f *fakeFetcher) Fetch(_ context.Context, iface string, _ []netip.Addr, url string) (egresscheck.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	response, ok := f.responses[iface+" "+url]
+	if !ok {
+		return egresscheck.Response{}, errors.New("connection timed out")
+	}
+	if response.FinalURL == "" {
+		response.FinalURL = url
+	}
+	return response, nil
+}
+
+func TestCheckClassifiesServices(t *testing.T) {
+	fetcher := &fakeFetcher{responses: map[string]egresscheck.Response{
+		// wg-gbr: full Netflix library, Disney+ and HBO Max in their regions.
+		"wg-gbr " + netflixLicensedURL: {StatusCode: 200, FinalURL: "https://www.netflix.com/gb-en/title/70143836"},
+		"wg-gbr " + disneyPlusURL:      {StatusCode: 200, FinalURL: "https://www.disneyplus.com/en-gb/home"},
+		"wg-gbr " + hboMaxURL:          {StatusCode: 200, Body: []byte(`{"countryCode":"GB"}`)},
+		// wg-dc: a datacenter exit Netflix recognizes, outside Disney+ and
+		// HBO Max regions.
+		"wg-dc " + netflixLicensedURL:  {StatusCode: 404},
+		"wg-dc " + netflixOriginalsURL: {StatusCode: 200, Body: []byte(`"requestCountry":{"id":"SG"`)},
+		"wg-dc " + disneyPlusURL:       {StatusCode: 200, FinalURL: "https://www.disneyplus.com/unavailable"},
+		"wg-dc " + hboMaxURL:           {StatusCode: 403},
+	}}
+	checker := NewCheckerWith(fetcher)
+
+	gbr, err := checker.Check(context.Background(), Target{VPN: "wg-gbr", Interface: "wg-gbr"})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	assertService(t, gbr.Services[0], ServiceNetflix, StatusUnblocked, "GB")
+	assertService(t, gbr.Services[1], ServiceDisneyPlus, StatusUnblocked, "GB")
+	assertService(t, gbr.Services[2], ServiceHBOMax, StatusUnblocked, "GB")
+
+	dc, err := checker.Check(context.Background(), Target{VPN: "wg-dc", Interface: "wg-dc"})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	assertService(t, dc.Services[0], ServiceNetflix, StatusOriginalsOnly, "SG")
+	assertService(t, dc.Services[1], ServiceDisneyPlus, StatusBlocked, "")
+	assertService(t, dc.Services[2], ServiceHBOMax, StatusBlocked, "")
+
+	down, err := checker.Check(context.Background(), Target{VPN: "wg-down", Interface: "wg-down"})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	for _, service := range down.Services {
+		if service.Status != StatusUnreachable || service.Detail == "" {
+			t.Fatalf("expected %s to be unreachable with a reason, got %+v", service.Service, service)
+		}
+	}
+
+	if _, err := checker.Check(context.Background(), Target{VPN: "wg-gbr"}); err == nil {
+		t.Fatalf("expected a missing interface to fail")
+	}
+}
+
+func assertService(t *testing.T, got ServiceResult, service, status, region string) {
+	t.Helper()
+	if got.Service != service || got.Status != status || got.Region != region || got.Name == "" {
+		t.Fatalf("expected %s %s region %q, got %+v", service, status, region, got)
+	}
+}
+
+func TestMonitorKeepsLatestResultsAndSchedules(t *testing.T) {
+	settingsManager := settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	fetcher := &fakeFetcher{responses: map[string]egresscheck.Response{
+		"wg-gbr " + netflixLicensedURL: {StatusCode: 200},
+	}}
+	targets := []Target{{VPN: "wg-gbr", Interface: "wg-gbr"}, {VPN: "wg-dc", Interface: "wg-dc"}}
+	monitor, err := NewMonitor(NewCheckerWith(fetcher), settingsManager, func() ([]Target, error) {
+		return targets, nil
+	})
+	if err != nil {
+		t.Fatalf("NewMonitor failed: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+	monitor.startedAt = now
+
+	if _, err := monitor.Run(context.Background(), "wg-missing"); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected an unknown VPN to be rejected, got %v", err)
+	}
+	if _, err := monitor.Run(context.Background(), "wg-dc"); err != nil {
+		t.Fatalf("single run failed: %v", err)
+	}
+	if results := monitor.Results(); len(results) != 1 || results[0].VPN != "wg-dc" {
+		t.Fatalf("expected only wg-dc to be checked, got %+v", results)
+	}
+
+	if monitor.due() {
+		t.Fatalf("expected no scheduled run while the interval is off")
+	}
+	if err := settingsManager.Save(settings.Settings{UnblockCheckIntervalHours: 6}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if monitor.due() {
+		t.Fatalf("expected the first scheduled run to wait for the initial delay")
+	}
+	now = now.Add(initialDelay)
+	if !monitor.due() {
+		t.Fatalf("expected a scheduled run after the initial delay")
+	}
+
+	targets = targets[:1]
+	results, err := monitor.Run(context.Background(), "")
+	if err != nil {
+		t.Fatalf("full run failed: %v", err)
+	}
+	if len(results) != 1 || results[0].VPN != "wg-gbr" || results[0].Services[0].Status != StatusUnblocked {
+		t.Fatalf("unexpected full run results: %+v", results)
+	}
+	if stored := monitor.Results(); len(stored) != 1 || stored[0].VPN != "wg-gbr" {
+		t.Fatalf("expected the full run to drop VPNs that are gone, got %+v", stored)
+	}
+	if monitor.due() {
+		t.Fatalf("expected the full run to restart the interval")
+	}
+	now = now.Add(6 * time.Hour)
+	if !monitor.due() {
+		t.Fatalf("expected a scheduled run once the interval passed")
+	}
+
+	if err := ValidateSettings(settings.Settings{UnblockCheckIntervalHours: MaxIntervalHours + 1}); err == nil {
+		t.Fatalf("expected an interval above a week to be rejected")
+	}
+}
@@ -0,0 +1,172 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+const (
+	autoPollInterval = time.Minute
+	autoRunTimeout   = 10 * time.Minute
+)
+
+// AutoUpdate checks the configured channel and, when a newer release is
+// available, starts an update exactly as the API does, pre-update backup
+// included. The outcome is recorded in the persisted status.
+func (m *Manager) AutoUpdate(ctx context.Context) (Status, error) {
+	status, err := m.Status()
+	if err != nil {
+		return Status{}, err
+	}
+	result := ""
+	switch {
+	case status.Unsupported != "":
+		result = "skipped: " + status.Unsupported
+	case status.InProgress:
+		result = "skipped: an update is already in progress"
+	default:
+		status, err = m.Check(ctx, "")
+	}
+	switch {
+	case result != "":
+	case err != nil:
+		result = "check failed: " + err.Error()
+	case !status.UpdateAvailable:
+		result = fmt.Sprintf("up to date on %s", status.Current.Version)
+	default:
+		target := status.LatestVersion
+		status, err = m.StartUpdate(ctx, "")
+		if err != nil {
+			result = fmt.Sprintf("update to %s failed: %v", target, err)
+		} else {
+			result = fmt.Sprintf("update to %s scheduled", target)
+		}
+	}
+	if recordErr := m.updateStatusLocked(func(stored *persistedStatus) {
+		stored.LastAutoRunAt = m.now().UTC().Unix()
+		stored.LastAutoResult = result
+	}); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	if err != nil {
+		return Status{}, err
+	}
+	return m.Status()
+}
+
+// Scheduler runs AutoUpdate in the configured weekly window. It re-reads
+// settings every minute, so schedule and channel changes apply without a
+// restart.
+type Scheduler struct {
+	manager  *Manager
+	settings *settings.Manager
+	now      func() time.Time
+
+	mu         sync.Mutex
+	started    bool
+	handler    func(Status, error)
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewScheduler creates an auto-update scheduler for manager.
+func NewScheduler(manager *Manager, settingsManager *settings.Manager) (*Scheduler, error) {
+	if manager == nil {
+		return nil, fmt.Errorf("update manager is required")
+	}
+	if settingsManager == nil {
+		return nil, fmt.Errorf("settings manager is required")
+	}
+	return &Scheduler{manager: manager, settings: settingsManager, now: time.Now}, nil
+}
+
+// SetHandler registers a callback invoked after every automatic run.
+func (s *Scheduler) SetHandler(handler func(Status, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+// Start launches the scheduling loop.
+func (s *Scheduler) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.started = true
+	s.loopCancel = cancel
+	s.mu.Unlock()
+
+	s.loopWG.Add(1)
+	go func() {
+		defer s.loopWG.Done()
+		ticker := time.NewTicker(autoPollInterval)
+		defer ticker.Stop()
+		var next time.Time
+		for {
+			next = s.poll(ctx, next)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the scheduling loop.
+func (s *Scheduler) Stop() error {
+	s.mu.Lock()
+	loopCancel := s.loopCancel
+	s.started = false
+	s.loopCancel = nil
+	s.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	s.loopWG.Wait()
+	return nil
+}
+
+// poll applies the current preferences and runs an update when next has
+// passed. It returns the next scheduled run, or zero when auto-update is
+// off. A window is computed strictly after the time it was set, so a
+// restart inside the window does not update twice.
+func (s *Scheduler) poll(ctx context.Context, next time.Time) time.Time {
+	current, err := s.settings.Get()
+	if err != nil {
+		return next
+	}
+	prefs := PreferencesFromSettings(current)
+	s.manager.Configure(prefs)
+	if !prefs.AutoUpdate {
+		return time.Time{}
+	}
+	now := s.now()
+	scheduled := prefs.Schedule.Next(now)
+	if next.IsZero() || scheduled.Before(next) || !prefs.Schedule.Next(next.Add(-time.Second)).Equal(next) {
+		// First poll, or the schedule changed since next was computed.
+		return scheduled
+	}
+	if now.Before(next) {
+		return next
+	}
+	runCtx, cancel := context.WithTimeout(ctx, autoRunTimeout)
+	status, runErr := s.manager.AutoUpdate(runCtx)
+	cancel()
+	s.mu.Lock()
+	handler := s.handler
+	s.mu.Unlock()
+	if handler != nil {
+		handler(status, runErr)
+	}
+	return scheduled
+}
@@ -0,0 +1,102 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"split-vpn-webui/internal/version"
+)
+
+// preUpdateBackupsKept bounds how many pre-update backups stay on disk.
+const preUpdateBackupsKept = 5
+
+// BackupExporter returns a full backup export, as served by the backup API.
+type BackupExporter func(ctx context.Context) ([]byte, error)
+
+// SetBackupExporter registers the export written before each update.
+func (m *Manager) SetBackupExporter(exporter BackupExporter) {
+	m.prefsMu.Lock()
+	defer m.prefsMu.Unlock()
+	m.exportBackup = exporter
+}
+
+// backupBeforeUpdate writes a backup export to the data directory so a bad
+// update can be undone by restoring it. It is skipped when disabled or when
+// no exporter is registered.
+func (m *Manager) backupBeforeUpdate(ctx context.Context, targetTag string) error {
+	m.prefsMu.RLock()
+	enabled := m.prefs.BackupBeforeUpdate
+	exporter := m.exportBackup
+	m.prefsMu.RUnlock()
+	if !enabled || exporter == nil {
+		return nil
+	}
+	if err := m.updateStatusLocked(func(stored *persistedStatus) {
+		stored.State = "backing-up"
+		stored.Message = "exporting pre-update backup"
+	}); err != nil {
+		return err
+	}
+	data, err := exporter(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.backupsDir, 0o700); err != nil {
+		return err
+	}
+	now := m.now().UTC()
+	name := fmt.Sprintf(
+		"pre-update-%s-to-%s-%s.json",
+		backupTagComponent(version.Current().Version),
+		backupTagComponent(targetTag),
+		now.Format("20060102-150405"),
+	)
+	path := filepath.Join(m.backupsDir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	m.prunePreUpdateBackups()
+	return m.updateStatusLocked(func(stored *persistedStatus) {
+		stored.LastBackupPath = path
+		stored.LastBackupAt = now.Unix()
+	})
+}
+
+func (m *Manager) prunePreUpdateBackups() {
+	matches, err := filepath.Glob(filepath.Join(m.backupsDir, "pre-update-*.json"))
+	if err != nil || len(matches) <= preUpdateBackupsKept {
+		return
+	}
+	// Names end in a sortable timestamp; sort by it, oldest first.
+	sort.Slice(matches, func(i, j int) bool {
+		return backupTimestamp(matches[i]) < backupTimestamp(matches[j])
+	})
+	for _, stale := range matches[:len(matches)-preUpdateBackupsKept] {
+		_ = os.Remove(stale)
+	}
+}
+
+func backupTimestamp(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), ".json")
+	if len(base) < len("20060102-150405") {
+		return base
+	}
+	return base[len(base)-len("20060102-150405"):]
+}
+
+func backupTagComponent(tag string) string {
+	normalized, err := normalizeTag(tag)
+	if err != nil {
+		return "unknown"
+	}
+	return normalized
+}
@@ -35,6 +35,7 @@ type releaseAPIResponse struct {
 	TagName     string            `json:"tag_name"`
 	Name        string            `json:"name"`
 	Prerelease  bool              `json:"prerelease"`
+	Draft       bool              `json:"draft"`
 	PublishedAt string            `json:"published_at"`
 	Assets      []releaseAPIAsset `json:"assets"`
 }
@@ -90,6 +91,48 @@ func (c *githubClient) releaseByPath(ctx context.Context, path string) (ReleaseM
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
 		return ReleaseMetadata{}, fmt.Errorf("decode github release response: %w", err)
 	}
+	return releaseFromAPI(payload)
+}
+
+// listReleases returns the most recent published releases, including
+// prereleases, newest first as GitHub orders them.
+func (c *githubClient) listReleases(ctx context.Context) ([]ReleaseMetadata, error) {
+	url := strings.TrimRight(c.baseURL, "/") + "/repos/" + c.repo + "/releases?per_page=30"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "split-vpn-webui-updater")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github release request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("github release request returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload []releaseAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decode github release list: %w", err)
+	}
+	releases := make([]ReleaseMetadata, 0, len(payload))
+	for _, item := range payload {
+		if item.Draft {
+			continue
+		}
+		meta, err := releaseFromAPI(item)
+		if err != nil {
+			continue
+		}
+		releases = append(releases, meta)
+	}
+	return releases, nil
+}
+
+func releaseFromAPI(payload releaseAPIResponse) (ReleaseMetadata, error) {
 	meta := ReleaseMetadata{
 		Tag:        strings.TrimSpace(payload.TagName),
 		Name:       strings.TrimSpace(payload.Name),
@@ -8,8 +8,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"split-vpn-webui/internal/settings"
 	"split-vpn-webui/internal/version"
 )
 
@@ -31,8 +33,16 @@ type Manager struct {
 	statusLock  string
 	jobPath     string
 	updatesDir  string
+	backupsDir  string
+	unsupported string
+
+	prefsMu       sync.RWMutex
+	prefs         Preferences
+	exportBackup  BackupExporter
+	lastPreflight *PreflightReport
 
 	systemd UnitController
+	probe   SystemProbe
 	github  *githubClient
 	now     func() time.Time
 }
@@ -74,9 +84,15 @@ func NewManager(opts Options) (*Manager, error) {
 		statusLock:  filepath.Join(dataDir, "update-status.lock"),
 		jobPath:     filepath.Join(dataDir, "update-job.json"),
 		updatesDir:  filepath.Join(dataDir, "updates"),
+		backupsDir:  filepath.Join(dataDir, "backups"),
+		prefs:       PreferencesFromSettings(settings.Settings{}),
 		systemd:     opts.Systemd,
 		github:      newGitHubClient(repo, opts.HTTPClient),
 		now:         time.Now,
+		unsupported: strings.TrimSpace(opts.Unsupported),
+	}
+	if m.probe = opts.Probe; m.probe == nil {
+		m.probe = systemProbe{}
 	}
 	if err := m.reconcileStatus(); err != nil {
 		return nil, err
@@ -106,7 +122,41 @@ func (m *Manager) Status() (Status, error) {
 	if err != nil {
 		return Status{}, err
 	}
-	return toPublicStatus(stored, version.Current()), nil
+	status := toPublicStatus(stored, version.Current())
+	prefs := m.preferences()
+	status.Channel = prefs.Channel
+	status.BackupBeforeUpdate = prefs.BackupBeforeUpdate
+	status.AutoUpdate.Enabled = prefs.AutoUpdate
+	status.AutoUpdate.Schedule = prefs.Schedule.String()
+	status.RollbackTargets = m.RollbackTargets()
+	status.Unsupported = m.unsupported
+	m.prefsMu.RLock()
+	status.Preflight = m.lastPreflight
+	m.prefsMu.RUnlock()
+	if prefs.AutoUpdate {
+		if next := prefs.Schedule.Next(m.now()); !next.IsZero() {
+			status.AutoUpdate.NextRunAt = &next
+		}
+	}
+	return status, nil
+}
+
+// Configure replaces the updater preferences.
+func (m *Manager) Configure(prefs Preferences) {
+	if channel, err := ParseChannel(prefs.Channel); err == nil {
+		prefs.Channel = channel
+	} else {
+		prefs.Channel = ChannelStable
+	}
+	m.prefsMu.Lock()
+	defer m.prefsMu.Unlock()
+	m.prefs = prefs
+}
+
+func (m *Manager) preferences() Preferences {
+	m.prefsMu.RLock()
+	defer m.prefsMu.RUnlock()
+	return m.prefs
 }
 
 // Check fetches release metadata from GitHub and updates persisted status.
@@ -139,6 +189,9 @@ func (m *Manager) Check(ctx context.Context, tag string) (Status, error) {
 
 // StartUpdate downloads, verifies, and schedules an update job via systemd.
 func (m *Manager) StartUpdate(ctx context.Context, requestedTag string) (Status, error) {
+	if m.unsupported != "" {
+		return Status{}, fmt.Errorf("self-update unavailable: %s", m.unsupported)
+	}
 	if m.systemd == nil {
 		return Status{}, fmt.Errorf("systemd manager unavailable")
 	}
@@ -187,6 +240,18 @@ func (m *Manager) StartUpdate(ctx context.Context, requestedTag string) (Status,
 		return Status{}, err
 	}
 
+	if err := m.updateStatusLocked(func(stored *persistedStatus) {
+		stored.State = "preflight"
+		stored.Message = fmt.Sprintf("checking router compatibility with %s", release.Tag)
+		stored.LastAttemptedVersion = release.Tag
+	}); err != nil {
+		return Status{}, err
+	}
+	if err := m.preflightRelease(ctx, release).Err(); err != nil {
+		_ = m.failAttempt(err.Error())
+		return Status{}, err
+	}
+
 	if err := m.updateStatusLocked(func(stored *persistedStatus) {
 		stored.State = "downloading"
 		stored.Message = fmt.Sprintf("downloading %s", binaryAsset.Name)
@@ -246,6 +311,22 @@ func (m *Manager) StartUpdate(ctx context.Context, requestedTag string) (Status,
 		_ = m.failAttempt("failed to chmod staged binary: " + err.Error())
 		return Status{}, err
 	}
+	if err := writeStagedRelease(tagDir, stagedRelease{
+		Version:  filepath.Base(tagDir),
+		Binary:   filepath.Base(stagedPath),
+		SHA256:   actualHash,
+		StagedAt: m.now().UTC().Unix(),
+	}); err != nil {
+		_ = os.Remove(stagedPath)
+		_ = m.failAttempt("failed to record staged binary: " + err.Error())
+		return Status{}, err
+	}
+
+	if err := m.backupBeforeUpdate(ctx, release.Tag); err != nil {
+		_ = os.Remove(stagedPath)
+		_ = m.failAttempt("pre-update backup failed: " + err.Error())
+		return Status{}, err
+	}
 
 	job := Job{
 		TargetVersion:  release.Tag,
@@ -286,11 +367,37 @@ func (m *Manager) StartUpdate(ctx context.Context, requestedTag string) (Status,
 func (m *Manager) resolveRelease(ctx context.Context, tag string) (ReleaseMetadata, error) {
 	trimmed := strings.TrimSpace(tag)
 	if trimmed == "" {
+		if m.preferences().Channel == ChannelBeta {
+			return m.newestRelease(ctx)
+		}
 		return m.github.latestRelease(ctx)
 	}
 	return m.github.releaseByTag(ctx, trimmed)
 }
 
+// newestRelease picks the highest version among recent releases,
+// prereleases included, for the beta channel.
+func (m *Manager) newestRelease(ctx context.Context) (ReleaseMetadata, error) {
+	releases, err := m.github.listReleases(ctx)
+	if err != nil {
+		return ReleaseMetadata{}, err
+	}
+	if len(releases) == 0 {
+		return ReleaseMetadata{}, fmt.Errorf("no published releases found")
+	}
+	newest := releases[0]
+	for _, release := range releases[1:] {
+		if isNewerVersion(newest.Tag, release.Tag) && isNewerVersion(release.Tag, newest.Tag) {
+			// Non-semver tags compare as "different"; keep GitHub's order.
+			continue
+		}
+		if isNewerVersion(newest.Tag, release.Tag) {
+			newest = release
+		}
+	}
+	return newest, nil
+}
+
 func (m *Manager) prepareTagDirectory(tag string) (string, error) {
 	normalized, err := normalizeTag(tag)
 	if err != nil {
@@ -134,6 +134,90 @@ func TestStartUpdateStagesBinaryAndSchedulesUnit(t *testing.T) {
 	}
 }
 
+func TestCheckBetaChannelPicksNewestPrerelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/foo/bar/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{"tag_name": "v1.4.0-beta.1", "prerelease": true, "draft": true},
+			{"tag_name": "v1.3.0-beta.2", "prerelease": true},
+			{"tag_name": "v1.2.3"},
+			{"tag_name": "v1.3.0-beta.1", "prerelease": true},
+		})
+	}))
+	defer server.Close()
+
+	mgr := newTestManager(t, server, nil)
+	mgr.Configure(Preferences{Channel: ChannelBeta})
+	status, err := mgr.Check(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if status.LatestVersion != "v1.3.0-beta.2" || status.Channel != ChannelBeta {
+		t.Fatalf("expected newest non-draft prerelease, got %q on %q", status.LatestVersion, status.Channel)
+	}
+}
+
+func TestStartUpdateWritesPreUpdateBackup(t *testing.T) {
+	arch := "amd64"
+	if runtime.GOARCH == "arm64" {
+		arch = "arm64"
+	}
+	binaryName := "split-vpn-webui-linux-" + arch
+	binaryContent := []byte("new-binary")
+	sum := sha256.Sum256(binaryContent)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/foo/bar/releases/latest":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"tag_name": "v1.2.3",
+				"assets": []map[string]any{
+					{"name": binaryName, "browser_download_url": server.URL + "/assets/" + binaryName},
+					{"name": "SHA256SUMS", "browser_download_url": server.URL + "/assets/SHA256SUMS"},
+				},
+			})
+		case "/assets/" + binaryName:
+			_, _ = w.Write(binaryContent)
+		case "/assets/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), binaryName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	mgr := newTestManager(t, server, &fakeUnitController{})
+	mgr.SetBackupExporter(func(ctx context.Context) ([]byte, error) {
+		return []byte(`{"version":1}`), nil
+	})
+	origVersion := version.AppVersion
+	version.AppVersion = "v1.0.0"
+	t.Cleanup(func() { version.AppVersion = origVersion })
+
+	status, err := mgr.StartUpdate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("StartUpdate failed: %v", err)
+	}
+	if status.LastBackupPath == "" || !strings.Contains(filepath.Base(status.LastBackupPath), "pre-update-v1.0.0-to-v1.2.3-") {
+		t.Fatalf("unexpected backup path %q", status.LastBackupPath)
+	}
+	data, err := os.ReadFile(status.LastBackupPath)
+	if err != nil || string(data) != `{"version":1}` {
+		t.Fatalf("unexpected backup content %q %v", data, err)
+	}
+
+	mgr.SetBackupExporter(func(ctx context.Context) ([]byte, error) {
+		return nil, fmt.Errorf("export failed")
+	})
+	_ = mgr.updateStatusLocked(func(stored *persistedStatus) { stored.InProgress = false })
+	if _, err := mgr.StartUpdate(context.Background(), ""); err == nil {
+		t.Fatalf("expected a failed backup to abort the update")
+	}
+}
+
 func TestStartUpdateRejectsMissingChecksumAsset(t *testing.T) {
 	controller := &fakeUnitController{}
 	var server *httptest.Server
@@ -161,6 +245,31 @@ func TestStartUpdateRejectsMissingChecksumAsset(t *testing.T) {
 	}
 }
 
+func TestUnsupportedInstallRefusesUpdates(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	controller := &fakeUnitController{}
+	mgr := newTestManager(t, server, controller)
+	mgr.unsupported = "the web UI runs unprivileged"
+
+	if _, err := mgr.StartUpdate(context.Background(), "v1.2.3"); err == nil || !strings.Contains(err.Error(), "unprivileged") {
+		t.Fatalf("expected update to be refused, got %v", err)
+	}
+	if _, err := mgr.StartRollback(context.Background(), "v1.0.0"); err == nil {
+		t.Fatalf("expected rollback to be refused")
+	}
+	status, err := mgr.AutoUpdate(context.Background())
+	if err != nil {
+		t.Fatalf("AutoUpdate failed: %v", err)
+	}
+	if status.Unsupported == "" || !strings.HasPrefix(status.AutoUpdate.LastResult, "skipped:") {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if len(controller.written) != 0 || len(controller.started) != 0 {
+		t.Fatalf("updater unit touched: %+v", controller)
+	}
+}
+
 func newTestManager(t *testing.T, server *httptest.Server, controller UnitController) *Manager {
 	t.Helper()
 	dataDir := t.TempDir()
@@ -174,6 +283,7 @@ func newTestManager(t *testing.T, server *httptest.Server, controller UnitContro
 		BinaryPath: binaryPath,
 		Systemd:    controller,
 		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		Probe:      newFakeProbe(),
 	})
 	if err != nil {
 		t.Fatalf("NewManager failed: %v", err)
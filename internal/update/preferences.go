@@ -0,0 +1,148 @@
+package update
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+const (
+	// ChannelStable follows GitHub's latest release and skips prereleases.
+	ChannelStable = "stable"
+	// ChannelBeta follows the newest release, prereleases included.
+	ChannelBeta = "beta"
+
+	// DefaultAutoUpdateSchedule is used when auto-update is enabled without
+	// a schedule: Sundays at 04:00 router time.
+	DefaultAutoUpdateSchedule = "sun 04:00"
+)
+
+// Preferences are the user-configurable updater settings.
+type Preferences struct {
+	Channel            string
+	BackupBeforeUpdate bool
+	AutoUpdate         bool
+	Schedule           Schedule
+}
+
+// PreferencesFromSettings reads updater preferences, applying defaults:
+// the stable channel, backups on, auto-update off.
+func PreferencesFromSettings(current settings.Settings) Preferences {
+	prefs := Preferences{Channel: ChannelStable, BackupBeforeUpdate: true}
+	if channel, err := ParseChannel(current.UpdateChannel); err == nil {
+		prefs.Channel = channel
+	}
+	if current.UpdateBackupEnabled != nil {
+		prefs.BackupBeforeUpdate = *current.UpdateBackupEnabled
+	}
+	if current.AutoUpdateEnabled != nil {
+		prefs.AutoUpdate = *current.AutoUpdateEnabled
+	}
+	schedule, err := ParseSchedule(current.AutoUpdateSchedule)
+	if err != nil {
+		schedule, _ = ParseSchedule(DefaultAutoUpdateSchedule)
+	}
+	prefs.Schedule = schedule
+	return prefs
+}
+
+// ParseChannel validates a release channel; empty means stable.
+func ParseChannel(raw string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", ChannelStable:
+		return ChannelStable, nil
+	case ChannelBeta:
+		return ChannelBeta, nil
+	default:
+		return "", fmt.Errorf("update channel must be %q or %q", ChannelStable, ChannelBeta)
+	}
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Schedule is a weekly auto-update window in the router's local time.
+type Schedule struct {
+	days   [7]bool
+	hour   int
+	minute int
+}
+
+// ParseSchedule parses "<days> HH:MM" where days is "daily" or a comma
+// separated list of three-letter weekdays, e.g. "sun 04:00" or
+// "mon,thu 03:30". Empty uses DefaultAutoUpdateSchedule.
+func ParseSchedule(raw string) (Schedule, error) {
+	trimmed := strings.ToLower(strings.TrimSpace(raw))
+	if trimmed == "" {
+		trimmed = DefaultAutoUpdateSchedule
+	}
+	fields := strings.Fields(trimmed)
+	if len(fields) != 2 {
+		return Schedule{}, fmt.Errorf("schedule must look like \"sun 04:00\" or \"daily 03:30\"")
+	}
+	var schedule Schedule
+	if fields[0] == "daily" {
+		for i := range schedule.days {
+			schedule.days[i] = true
+		}
+	} else {
+		for _, day := range strings.Split(fields[0], ",") {
+			index := -1
+			for i, name := range weekdayNames {
+				if strings.HasPrefix(strings.TrimSpace(day), name) {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				return Schedule{}, fmt.Errorf("unknown weekday %q in schedule", day)
+			}
+			schedule.days[index] = true
+		}
+	}
+	hourRaw, minuteRaw, ok := strings.Cut(fields[1], ":")
+	if !ok {
+		return Schedule{}, fmt.Errorf("schedule time must be HH:MM")
+	}
+	hour, err := strconv.Atoi(hourRaw)
+	if err != nil || hour < 0 || hour > 23 {
+		return Schedule{}, fmt.Errorf("schedule hour must be between 0 and 23")
+	}
+	minute, err := strconv.Atoi(minuteRaw)
+	if err != nil || minute < 0 || minute > 59 {
+		return Schedule{}, fmt.Errorf("schedule minute must be between 0 and 59")
+	}
+	schedule.hour = hour
+	schedule.minute = minute
+	return schedule, nil
+}
+
+// String renders the schedule in the form ParseSchedule accepts.
+func (s Schedule) String() string {
+	days := make([]string, 0, len(weekdayNames))
+	for i, enabled := range s.days {
+		if enabled {
+			days = append(days, weekdayNames[i])
+		}
+	}
+	dayPart := strings.Join(days, ",")
+	if len(days) == len(weekdayNames) {
+		dayPart = "daily"
+	}
+	return fmt.Sprintf("%s %02d:%02d", dayPart, s.hour, s.minute)
+}
+
+// Next returns the first scheduled time strictly after after, in after's
+// location, or the zero time for an empty schedule.
+func (s Schedule) Next(after time.Time) time.Time {
+	start := time.Date(after.Year(), after.Month(), after.Day(), s.hour, s.minute, 0, 0, after.Location())
+	for offset := 0; offset <= 7; offset++ {
+		candidate := start.AddDate(0, 0, offset)
+		if s.days[candidate.Weekday()] && candidate.After(after) {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
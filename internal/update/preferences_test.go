@@ -0,0 +1,89 @@
+package update
+
+import (
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+)
+
+func TestParseScheduleAndNext(t *testing.T) {
+	schedule, err := ParseSchedule("Sun 04:00")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+	if schedule.String() != "sun 04:00" {
+		t.Fatalf("unexpected schedule string %q", schedule.String())
+	}
+	// 2026-10-16 is a Friday.
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if next := schedule.Next(friday); !next.Equal(time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected next run %v", next)
+	}
+	sundayWindow := time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)
+	if next := schedule.Next(sundayWindow); !next.Equal(sundayWindow.AddDate(0, 0, 7)) {
+		t.Fatalf("expected the following week, got %v", next)
+	}
+
+	daily, err := ParseSchedule("daily 23:30")
+	if err != nil || daily.String() != "daily 23:30" {
+		t.Fatalf("unexpected daily schedule %q %v", daily.String(), err)
+	}
+	for _, bad := range []string{"sun", "funday 04:00", "sun 24:00", "sun 4"} {
+		if _, err := ParseSchedule(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestPreferencesFromSettingsDefaults(t *testing.T) {
+	prefs := PreferencesFromSettings(settings.Settings{})
+	if prefs.Channel != ChannelStable || !prefs.BackupBeforeUpdate || prefs.AutoUpdate || prefs.Schedule.String() != DefaultAutoUpdateSchedule {
+		t.Fatalf("unexpected defaults %#v", prefs)
+	}
+	off := false
+	on := true
+	prefs = PreferencesFromSettings(settings.Settings{
+		UpdateChannel:       "beta",
+		UpdateBackupEnabled: &off,
+		AutoUpdateEnabled:   &on,
+		AutoUpdateSchedule:  "mon,thu 03:15",
+	})
+	if prefs.Channel != ChannelBeta || prefs.BackupBeforeUpdate || !prefs.AutoUpdate || prefs.Schedule.String() != "mon,thu 03:15" {
+		t.Fatalf("unexpected preferences %#v", prefs)
+	}
+}
+
+func TestSchedulerPollWaitsForWindow(t *testing.T) {
+	dir := t.TempDir()
+	settingsManager := settings.NewManager(dir + "/settings.json")
+	on := true
+	if err := settingsManager.Save(settings.Settings{AutoUpdateEnabled: &on, AutoUpdateSchedule: "sun 04:00"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	mgr, err := NewManager(Options{DataDir: dir, Repo: "foo/bar"})
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	scheduler, err := NewScheduler(mgr, settingsManager)
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	scheduler.now = func() time.Time { return now }
+	next := scheduler.poll(t.Context(), time.Time{})
+	want := time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)
+	if !next.Equal(want) {
+		t.Fatalf("unexpected first window %v", next)
+	}
+	if again := scheduler.poll(t.Context(), next); !again.Equal(want) {
+		t.Fatalf("expected to keep waiting for %v, got %v", want, again)
+	}
+	status, err := mgr.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !status.AutoUpdate.Enabled || status.AutoUpdate.Schedule != "sun 04:00" || status.AutoUpdate.LastRunAt != nil {
+		t.Fatalf("unexpected auto-update status %#v", status.AutoUpdate)
+	}
+}
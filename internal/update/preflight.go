@@ -0,0 +1,311 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// requirementsAssetName is an optional release asset declaring what the
+	// release needs from the router; see Requirements.
+	requirementsAssetName = "requirements.json"
+
+	// stagingCopies is how many copies of the binary an update holds on
+	// disk at once: staged, ".new" and ".previous".
+	stagingCopies = 3
+	// defaultBinaryBytes sizes the disk check when a release omits sizes.
+	defaultBinaryBytes = 32 << 20
+
+	preflightCommandTimeout = 10 * time.Second
+
+	conntrackAcctPath = "/proc/sys/net/netfilter/nf_conntrack_acct"
+)
+
+// unifiVersionFiles hold the UniFi OS firmware string, e.g.
+// "UDMPRO.al324.v4.0.6.4f9d0f5.240405.1000".
+var unifiVersionFiles = []string{"/usr/lib/version", "/etc/version"}
+
+var firmwareVersionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)\.(\d+)`)
+
+// Requirements is what a release needs from the router. Releases without a
+// requirements.json asset get the baseline: ipset, any iptables backend
+// and room for the staged binaries.
+type Requirements struct {
+	MinUniFiOS string `json:"minUnifiOs,omitempty"`
+	// IPTablesBackend is "", "legacy" or "nf_tables".
+	IPTablesBackend string `json:"iptablesBackend,omitempty"`
+	ConntrackAcct   bool   `json:"conntrackAcct,omitempty"`
+	MinFreeBytes    uint64 `json:"minFreeBytes,omitempty"`
+}
+
+// PreflightCheck is one verified capability.
+type PreflightCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Remedy string `json:"remedy,omitempty"`
+}
+
+// PreflightReport is the outcome of checking a release against the router.
+type PreflightReport struct {
+	TargetVersion string           `json:"targetVersion"`
+	CheckedAt     time.Time        `json:"checkedAt"`
+	Passed        bool             `json:"passed"`
+	Requirements  Requirements     `json:"requirements"`
+	Checks        []PreflightCheck `json:"checks"`
+}
+
+// Err summarizes failed checks with their remedies, or returns nil.
+func (r PreflightReport) Err() error {
+	if r.Passed {
+		return nil
+	}
+	failures := make([]string, 0, len(r.Checks))
+	for _, check := range r.Checks {
+		if check.Status != "fail" {
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", check.Name, check.Detail)
+		if check.Remedy != "" {
+			line += " (" + check.Remedy + ")"
+		}
+		failures = append(failures, line)
+	}
+	return fmt.Errorf("preflight failed for %s: %s", r.TargetVersion, strings.Join(failures, "; "))
+}
+
+// SystemProbe inspects the router for preflight checks.
+type SystemProbe interface {
+	Output(ctx context.Context, name string, args ...string) (string, error)
+	ReadFile(path string) ([]byte, error)
+	FreeBytes(path string) (uint64, error)
+}
+
+type systemProbe struct{}
+
+func (systemProbe) Output(ctx context.Context, name string, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+func (systemProbe) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (systemProbe) FreeBytes(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// Preflight resolves the release for tag on the configured channel and
+// checks the router against its requirements without staging anything.
+func (m *Manager) Preflight(ctx context.Context, tag string) (PreflightReport, error) {
+	release, err := m.resolveRelease(ctx, tag)
+	if err != nil {
+		return PreflightReport{}, err
+	}
+	return m.preflightRelease(ctx, release), nil
+}
+
+func (m *Manager) preflightRelease(ctx context.Context, release ReleaseMetadata) PreflightReport {
+	report := PreflightReport{TargetVersion: release.Tag, CheckedAt: m.now().UTC()}
+	requirements, reqErr := m.releaseRequirements(ctx, release)
+	report.Requirements = requirements
+	if reqErr != nil {
+		report.Checks = append(report.Checks, PreflightCheck{
+			Name:   "requirements",
+			Status: "warn",
+			Detail: "could not read " + requirementsAssetName + ", using baseline checks: " + reqErr.Error(),
+		})
+	}
+	report.Checks = append(report.Checks,
+		m.checkIPSet(ctx),
+		m.checkIPTables(ctx, requirements.IPTablesBackend),
+		m.checkConntrackAcct(requirements.ConntrackAcct),
+		m.checkUniFiOS(requirements.MinUniFiOS),
+		m.checkDiskSpace(release, requirements.MinFreeBytes),
+	)
+	report.Passed = true
+	for _, check := range report.Checks {
+		if check.Status == "fail" {
+			report.Passed = false
+		}
+	}
+	m.prefsMu.Lock()
+	m.lastPreflight = &report
+	m.prefsMu.Unlock()
+	return report
+}
+
+func (m *Manager) releaseRequirements(ctx context.Context, release ReleaseMetadata) (Requirements, error) {
+	var asset *ReleaseAsset
+	for i := range release.Assets {
+		if strings.EqualFold(release.Assets[i].Name, requirementsAssetName) {
+			asset = &release.Assets[i]
+			break
+		}
+	}
+	if asset == nil {
+		return Requirements{}, nil
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, defaultDownloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return Requirements{}, err
+	}
+	req.Header.Set("User-Agent", "split-vpn-webui-updater")
+	resp, err := m.github.client.Do(req)
+	if err != nil {
+		return Requirements{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Requirements{}, fmt.Errorf("download returned %d", resp.StatusCode)
+	}
+	var requirements Requirements
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&requirements); err != nil {
+		return Requirements{}, fmt.Errorf("decode %s: %w", requirementsAssetName, err)
+	}
+	return requirements, nil
+}
+
+func (m *Manager) probeOutput(ctx context.Context, name string, args ...string) (string, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, preflightCommandTimeout)
+	defer cancel()
+	return m.probe.Output(probeCtx, name, args...)
+}
+
+func (m *Manager) checkIPSet(ctx context.Context) PreflightCheck {
+	check := PreflightCheck{Name: "ipset"}
+	output, err := m.probeOutput(ctx, "ipset", "version")
+	if err != nil {
+		check.Status = "fail"
+		check.Detail = "ipset is unavailable: " + firstLine(output, err)
+		check.Remedy = "domain and ASN routing need the ipset tool and the ip_set kernel module"
+		return check
+	}
+	check.Status = "ok"
+	check.Detail = firstLine(output, nil)
+	return check
+}
+
+func (m *Manager) checkIPTables(ctx context.Context, required string) PreflightCheck {
+	check := PreflightCheck{Name: "iptables"}
+	output, err := m.probeOutput(ctx, "iptables", "-V")
+	if err != nil {
+		check.Status = "fail"
+		check.Detail = "iptables is unavailable: " + firstLine(output, err)
+		check.Remedy = "policy routing installs its marks with iptables"
+		return check
+	}
+	backend := "legacy"
+	if strings.Contains(output, "nf_tables") {
+		backend = "nf_tables"
+	}
+	check.Detail = fmt.Sprintf("%s (%s backend)", firstLine(output, nil), backend)
+	required = strings.TrimSpace(strings.ToLower(required))
+	if required != "" && required != backend {
+		check.Status = "fail"
+		check.Remedy = fmt.Sprintf("this release requires the %s iptables backend; stay on the current version until the firmware provides it", required)
+		return check
+	}
+	check.Status = "ok"
+	return check
+}
+
+func (m *Manager) checkConntrackAcct(required bool) PreflightCheck {
+	check := PreflightCheck{Name: "conntrack accounting"}
+	if _, err := m.probe.ReadFile(conntrackAcctPath); err != nil {
+		check.Detail = "nf_conntrack_acct is not available"
+		check.Status = "warn"
+		if required {
+			check.Status = "fail"
+			check.Remedy = "this release needs conntrack byte counters; load the nf_conntrack module"
+		}
+		return check
+	}
+	check.Status = "ok"
+	check.Detail = "nf_conntrack_acct is available"
+	return check
+}
+
+func (m *Manager) checkUniFiOS(minimum string) PreflightCheck {
+	check := PreflightCheck{Name: "UniFi OS"}
+	installed := ""
+	for _, path := range unifiVersionFiles {
+		data, err := m.probe.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if match := firmwareVersionPattern.FindStringSubmatch(string(data)); match != nil {
+			installed = fmt.Sprintf("v%s.%s.%s", match[1], match[2], match[3])
+			break
+		}
+	}
+	if installed == "" {
+		check.Status = "warn"
+		check.Detail = "firmware version could not be determined"
+		if minimum != "" {
+			check.Detail += fmt.Sprintf("; this release expects UniFi OS %s or newer", minimum)
+		}
+		return check
+	}
+	check.Detail = "UniFi OS " + installed
+	if minimum != "" {
+		required := "v" + strings.TrimPrefix(strings.TrimSpace(minimum), "v")
+		if isNewerVersion(installed, required) {
+			check.Status = "fail"
+			check.Detail += ", release requires " + required
+			check.Remedy = "update the router firmware first, or stay on the current version"
+			return check
+		}
+	}
+	check.Status = "ok"
+	return check
+}
+
+func (m *Manager) checkDiskSpace(release ReleaseMetadata, minimum uint64) PreflightCheck {
+	check := PreflightCheck{Name: "disk space"}
+	needed := uint64(defaultBinaryBytes) * stagingCopies
+	if asset, err := selectBinaryAsset(release, m.arch); err == nil && asset.Size > 0 {
+		needed = uint64(asset.Size) * stagingCopies
+	}
+	if minimum > needed {
+		needed = minimum
+	}
+	free, err := m.probe.FreeBytes(m.dataDir)
+	if err != nil {
+		check.Status = "warn"
+		check.Detail = "free space could not be measured: " + err.Error()
+		return check
+	}
+	check.Detail = fmt.Sprintf("%d MiB free in %s, %d MiB needed", free>>20, m.dataDir, needed>>20)
+	if free < needed {
+		check.Status = "fail"
+		check.Remedy = "free space in the data directory, e.g. old files under updates/ or backups/"
+		return check
+	}
+	check.Status = "ok"
+	return check
+}
+
+func firstLine(output string, err error) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	if line == "" && err != nil {
+		return err.Error()
+	}
+	return line
+}
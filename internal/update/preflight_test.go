@@ -0,0 +1,153 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"split-vpn-webui/internal/version"
+)
+
+type fakeProbe struct {
+	outputs map[string]string
+	files   map[string]string
+	free    uint64
+}
+
+// newFakeProbe describes a router that satisfies the baseline checks.
+func newFakeProbe() *fakeProbe {
+	return &fakeProbe{
+		outputs: map[string]string{
+			"ipset":    "ipset v7.17, protocol version: 7",
+			"iptables": "iptables v1.8.7 (legacy)",
+		},
+		files: map[string]string{
+			conntrackAcctPath:  "1\n",
+			"/usr/lib/version": "UDMPRO.al324.v4.0.6.4f9d0f5.240405.1000\n",
+		},
+		free: 1 << 30,
+	}
+}
+
+func (f *fakeProbe) Output(_ context.Context, name string, _ ...string) (string, error) {
+	output, ok := f.outputs[name]
+	if !ok {
+		return "", fmt.Errorf("exec: %q: executable file not found in $PATH", name)
+	}
+	return output, nil
+}
+
+func (f *fakeProbe) ReadFile(path string) ([]byte, error) {
+	content, ok := f.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (f *fakeProbe) FreeBytes(string) (uint64, error) {
+	return f.free, nil
+}
+
+func preflightTestServer(t *testing.T, requirements string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/foo/bar/releases/latest":
+			assets := []map[string]any{
+				{"name": "split-vpn-webui-linux-amd64", "browser_download_url": server.URL + "/assets/bin", "size": 20 << 20},
+				{"name": "split-vpn-webui-linux-arm64", "browser_download_url": server.URL + "/assets/bin", "size": 20 << 20},
+				{"name": "SHA256SUMS", "browser_download_url": server.URL + "/assets/SHA256SUMS"},
+			}
+			if requirements != "" {
+				assets = append(assets, map[string]any{"name": "requirements.json", "browser_download_url": server.URL + "/assets/requirements.json"})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"tag_name": "v2.0.0", "assets": assets})
+		case "/assets/requirements.json":
+			_, _ = w.Write([]byte(requirements))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPreflightPassesBaselineAndWarnsOnUnknownFirmware(t *testing.T) {
+	mgr := newTestManager(t, preflightTestServer(t, ""), nil)
+	probe := newFakeProbe()
+	delete(probe.files, "/usr/lib/version")
+	mgr.probe = probe
+
+	report, err := mgr.Preflight(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Preflight failed: %v", err)
+	}
+	if !report.Passed || report.Err() != nil {
+		t.Fatalf("expected baseline preflight to pass, got %#v", report)
+	}
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	if statuses["UniFi OS"] != "warn" || statuses["ipset"] != "ok" || statuses["disk space"] != "ok" {
+		t.Fatalf("unexpected check statuses: %#v", statuses)
+	}
+}
+
+func TestPreflightFailsOnReleaseRequirements(t *testing.T) {
+	server := preflightTestServer(t, `{"minUnifiOs":"4.1.0","iptablesBackend":"nf_tables","conntrackAcct":true}`)
+	mgr := newTestManager(t, server, &fakeUnitController{})
+	probe := newFakeProbe()
+	delete(probe.files, conntrackAcctPath)
+	mgr.probe = probe
+
+	report, err := mgr.Preflight(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Preflight failed: %v", err)
+	}
+	if report.Passed {
+		t.Fatalf("expected preflight failure, got %#v", report)
+	}
+	message := report.Err().Error()
+	for _, want := range []string{"UniFi OS", "nf_tables", "conntrack accounting"} {
+		if !strings.Contains(message, want) {
+			t.Fatalf("expected %q in %q", want, message)
+		}
+	}
+}
+
+func TestStartUpdateRefusedByPreflight(t *testing.T) {
+	controller := &fakeUnitController{}
+	mgr := newTestManager(t, preflightTestServer(t, ""), controller)
+	probe := newFakeProbe()
+	delete(probe.outputs, "ipset")
+	probe.free = 10 << 20
+	mgr.probe = probe
+	origVersion := version.AppVersion
+	version.AppVersion = "v1.0.0"
+	t.Cleanup(func() { version.AppVersion = origVersion })
+
+	if _, err := mgr.StartUpdate(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "preflight failed") {
+		t.Fatalf("expected preflight error, got %v", err)
+	}
+	if len(controller.started) != 0 {
+		t.Fatalf("updater unit must not start after a failed preflight")
+	}
+	status, err := mgr.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.State != "failed" || status.InProgress || status.Preflight == nil || status.Preflight.Passed {
+		t.Fatalf("unexpected status after failed preflight: %#v", status)
+	}
+	if _, err := os.Stat(mgr.updatesDir + "/v2.0.0"); !os.IsNotExist(err) {
+		t.Fatalf("nothing should be staged after a failed preflight")
+	}
+}
@@ -0,0 +1,246 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"split-vpn-webui/internal/version"
+)
+
+const (
+	// RetainedVersions is how many previous versions keep their binary in
+	// the updates directory as rollback targets.
+	RetainedVersions = 3
+
+	stagedMetaName     = "release.json"
+	archivedBinaryName = "split-vpn-webui"
+)
+
+// RollbackTarget is a previously installed or staged version that can be
+// restored without downloading.
+type RollbackTarget struct {
+	Version  string    `json:"version"`
+	SHA256   string    `json:"sha256"`
+	StagedAt time.Time `json:"stagedAt"`
+}
+
+// stagedRelease is written next to each binary kept in the updates
+// directory so it can be verified before a rollback.
+type stagedRelease struct {
+	Version  string `json:"version"`
+	Binary   string `json:"binary"`
+	SHA256   string `json:"sha256"`
+	StagedAt int64  `json:"stagedAt"`
+}
+
+// RollbackTargets lists retained versions other than the running one,
+// newest first.
+func (m *Manager) RollbackTargets() []RollbackTarget {
+	current := version.Current().Version
+	releases := m.stagedReleases()
+	targets := make([]RollbackTarget, 0, len(releases))
+	for _, release := range releases {
+		if release.Version == current {
+			continue
+		}
+		targets = append(targets, RollbackTarget{
+			Version:  release.Version,
+			SHA256:   release.SHA256,
+			StagedAt: time.Unix(release.StagedAt, 0).UTC(),
+		})
+	}
+	return targets
+}
+
+// StartRollback schedules a downgrade to a retained version through the
+// updater unit, the same way StartUpdate schedules an upgrade.
+func (m *Manager) StartRollback(ctx context.Context, targetVersion string) (Status, error) {
+	if m.unsupported != "" {
+		return Status{}, fmt.Errorf("self-update unavailable: %s", m.unsupported)
+	}
+	if m.systemd == nil {
+		return Status{}, fmt.Errorf("systemd manager unavailable")
+	}
+	target, err := normalizeTag(targetVersion)
+	if err != nil {
+		return Status{}, err
+	}
+	var release *stagedRelease
+	for _, candidate := range m.stagedReleases() {
+		if candidate.Version == target {
+			found := candidate
+			release = &found
+			break
+		}
+	}
+	if release == nil {
+		return Status{}, fmt.Errorf("version %s is not retained for rollback", target)
+	}
+	if target == version.Current().Version {
+		return Status{}, fmt.Errorf("already running %s", target)
+	}
+
+	var alreadyInProgress bool
+	if err := m.updateStatusLocked(func(stored *persistedStatus) {
+		if stored.InProgress {
+			alreadyInProgress = true
+			return
+		}
+		stored.InProgress = true
+		stored.State = "checking"
+		stored.Message = fmt.Sprintf("verifying retained %s", target)
+		stored.LastError = ""
+		stored.LastAttemptedVersion = target
+		stored.LastAttemptAt = m.now().UTC().Unix()
+	}); err != nil {
+		return Status{}, err
+	}
+	if alreadyInProgress {
+		return Status{}, fmt.Errorf("update already in progress")
+	}
+
+	stagedPath, err := m.validateStagedPath(filepath.Join(m.updatesDir, target, release.Binary))
+	if err != nil {
+		_ = m.failAttempt("retained binary unavailable: " + err.Error())
+		return Status{}, err
+	}
+	actualHash, err := fileSHA256(stagedPath)
+	if err != nil {
+		_ = m.failAttempt("hash retained binary: " + err.Error())
+		return Status{}, err
+	}
+	if !strings.EqualFold(actualHash, release.SHA256) {
+		err := fmt.Errorf("retained binary for %s failed checksum verification", target)
+		_ = m.failAttempt(err.Error())
+		return Status{}, err
+	}
+	if err := m.backupBeforeUpdate(ctx, target); err != nil {
+		_ = m.failAttempt("pre-update backup failed: " + err.Error())
+		return Status{}, err
+	}
+	job := Job{
+		TargetVersion:  target,
+		AssetName:      release.Binary,
+		StagedBinary:   stagedPath,
+		ExpectedSHA256: actualHash,
+		PreparedAt:     m.now().UTC().Unix(),
+		Rollback:       true,
+	}
+	if err := m.writeJob(job); err != nil {
+		_ = m.failAttempt("failed to persist rollback job: " + err.Error())
+		return Status{}, err
+	}
+	if err := m.ensureUpdaterUnit(); err != nil {
+		_ = m.failAttempt("failed to ensure updater unit: " + err.Error())
+		return Status{}, err
+	}
+	if err := m.systemd.Start(m.updaterUnit); err != nil {
+		_ = m.failAttempt("failed to start updater unit: " + err.Error())
+		return Status{}, err
+	}
+	if err := m.updateStatusLocked(func(stored *persistedStatus) {
+		stored.State = "scheduled"
+		stored.Message = fmt.Sprintf("rollback to %s scheduled; service restart pending", target)
+	}); err != nil {
+		return Status{}, err
+	}
+	return m.Status()
+}
+
+// retainPreviousBinary archives the binary that was just replaced, found at
+// previousPath, under the running version's tag so it becomes a rollback
+// target even when it was installed outside the updater. Older versions
+// beyond RetainedVersions are pruned; keep is never removed.
+func (m *Manager) retainPreviousBinary(previousPath, keep string) error {
+	previous := version.Current().Version
+	if _, ok := parseSemver(previous); ok {
+		dir := filepath.Join(m.updatesDir, previous)
+		if _, err := os.Stat(filepath.Join(dir, stagedMetaName)); os.IsNotExist(err) {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+			archived := filepath.Join(dir, archivedBinaryName)
+			if err := copyFile(previousPath, archived, 0o755); err != nil {
+				return err
+			}
+			sum, err := fileSHA256(archived)
+			if err != nil {
+				return err
+			}
+			if err := writeStagedRelease(dir, stagedRelease{
+				Version:  previous,
+				Binary:   archivedBinaryName,
+				SHA256:   sum,
+				StagedAt: m.now().UTC().Unix(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	m.pruneStagedReleases(keep)
+	return nil
+}
+
+func (m *Manager) pruneStagedReleases(keep string) {
+	retained := 0
+	for _, release := range m.stagedReleases() {
+		if release.Version == keep {
+			continue
+		}
+		retained++
+		if retained > RetainedVersions {
+			_ = os.RemoveAll(filepath.Join(m.updatesDir, release.Version))
+		}
+	}
+}
+
+// stagedReleases reads every version directory with metadata, newest first.
+func (m *Manager) stagedReleases() []stagedRelease {
+	entries, err := os.ReadDir(m.updatesDir)
+	if err != nil {
+		return nil
+	}
+	releases := make([]stagedRelease, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.updatesDir, entry.Name(), stagedMetaName))
+		if err != nil {
+			continue
+		}
+		var release stagedRelease
+		if err := json.Unmarshal(data, &release); err != nil || release.Version != entry.Name() || release.Binary == "" {
+			continue
+		}
+		if filepath.Base(release.Binary) != release.Binary {
+			continue
+		}
+		releases = append(releases, release)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		if releases[i].StagedAt != releases[j].StagedAt {
+			return releases[i].StagedAt > releases[j].StagedAt
+		}
+		return releases[i].Version > releases[j].Version
+	})
+	return releases
+}
+
+func writeStagedRelease(dir string, release stagedRelease) error {
+	data, err := json.MarshalIndent(release, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, stagedMetaName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, stagedMetaName))
+}
@@ -0,0 +1,114 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"split-vpn-webui/internal/version"
+)
+
+func TestStartRollbackSchedulesRetainedVersion(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	controller := &fakeUnitController{}
+	mgr := newTestManager(t, server, controller)
+	origVersion := version.AppVersion
+	version.AppVersion = "v1.3.0"
+	t.Cleanup(func() { version.AppVersion = origVersion })
+
+	previous := filepath.Join(t.TempDir(), "previous")
+	if err := os.WriteFile(previous, []byte("v1.2.0 binary"), 0o755); err != nil {
+		t.Fatalf("write previous binary: %v", err)
+	}
+	// Archiving as v1.3.0 then switching the running version makes v1.3.0
+	// a rollback target for v1.4.0.
+	if err := mgr.retainPreviousBinary(previous, "v1.4.0"); err != nil {
+		t.Fatalf("retainPreviousBinary failed: %v", err)
+	}
+	version.AppVersion = "v1.4.0"
+
+	targets := mgr.RollbackTargets()
+	if len(targets) != 1 || targets[0].Version != "v1.3.0" {
+		t.Fatalf("unexpected rollback targets %#v", targets)
+	}
+	status, err := mgr.StartRollback(context.Background(), "v1.3.0")
+	if err != nil {
+		t.Fatalf("StartRollback failed: %v", err)
+	}
+	if status.State != "scheduled" || !status.InProgress || len(controller.started) != 1 {
+		t.Fatalf("expected scheduled rollback, got %#v started=%v", status, controller.started)
+	}
+	data, err := os.ReadFile(mgr.jobPath)
+	if err != nil {
+		t.Fatalf("read job: %v", err)
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	if !job.Rollback || job.TargetVersion != "v1.3.0" || job.StagedBinary != filepath.Join(mgr.updatesDir, "v1.3.0", archivedBinaryName) {
+		t.Fatalf("unexpected rollback job %#v", job)
+	}
+
+	if _, err := mgr.StartRollback(context.Background(), "v1.1.0"); err == nil {
+		t.Fatalf("expected an error for a version that is not retained")
+	}
+}
+
+func TestRollbackRejectsTamperedBinary(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	mgr := newTestManager(t, server, &fakeUnitController{})
+	origVersion := version.AppVersion
+	version.AppVersion = "v1.3.0"
+	t.Cleanup(func() { version.AppVersion = origVersion })
+
+	if err := mgr.retainPreviousBinary(mgr.binaryPath, "v1.4.0"); err != nil {
+		t.Fatalf("retainPreviousBinary failed: %v", err)
+	}
+	version.AppVersion = "v1.4.0"
+	if err := os.WriteFile(filepath.Join(mgr.updatesDir, "v1.3.0", archivedBinaryName), []byte("tampered"), 0o755); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if _, err := mgr.StartRollback(context.Background(), "v1.3.0"); err == nil {
+		t.Fatalf("expected checksum failure")
+	}
+	status, _ := mgr.Status()
+	if status.InProgress || status.State != "failed" {
+		t.Fatalf("expected failed idle state, got %#v", status)
+	}
+}
+
+func TestPruneStagedReleasesKeepsNewest(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	mgr := newTestManager(t, server, nil)
+	for i, tag := range []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0", "v1.5.0"} {
+		dir := filepath.Join(mgr.updatesDir, tag)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := writeStagedRelease(dir, stagedRelease{Version: tag, Binary: archivedBinaryName, StagedAt: int64(100 + i)}); err != nil {
+			t.Fatalf("write meta: %v", err)
+		}
+	}
+	mgr.pruneStagedReleases("v1.5.0")
+	var kept []string
+	for _, release := range mgr.stagedReleases() {
+		kept = append(kept, release.Version)
+	}
+	want := []string{"v1.5.0", "v1.4.0", "v1.3.0", "v1.2.0"}
+	if len(kept) != len(want) {
+		t.Fatalf("unexpected retained versions %v", kept)
+	}
+	for i := range want {
+		if kept[i] != want[i] {
+			t.Fatalf("unexpected retained versions %v", kept)
+		}
+	}
+}
@@ -28,10 +28,14 @@ func (m *Manager) RunPendingJob(ctx context.Context) error {
 		_ = m.failAttempt("failed to read update job: " + err.Error())
 		return err
 	}
+	action, done := "applying", "updated to"
+	if job.Rollback {
+		action, done = "rolling back to", "rolled back to"
+	}
 	if err := m.updateStatusLocked(func(stored *persistedStatus) {
 		stored.InProgress = true
 		stored.State = "applying"
-		stored.Message = fmt.Sprintf("applying %s", job.TargetVersion)
+		stored.Message = fmt.Sprintf("%s %s", action, job.TargetVersion)
 		stored.LastError = ""
 		stored.LastAttemptedVersion = job.TargetVersion
 		stored.LastAttemptAt = m.now().UTC().Unix()
@@ -45,7 +49,7 @@ func (m *Manager) RunPendingJob(ctx context.Context) error {
 	if err := m.updateStatusLocked(func(stored *persistedStatus) {
 		stored.InProgress = false
 		stored.State = "success"
-		stored.Message = fmt.Sprintf("updated to %s", job.TargetVersion)
+		stored.Message = fmt.Sprintf("%s %s", done, job.TargetVersion)
 		stored.LastError = ""
 		stored.LastSuccessVersion = job.TargetVersion
 		stored.LastSuccessAt = m.now().UTC().Unix()
@@ -102,7 +106,9 @@ func (m *Manager) applyJob(ctx context.Context, job Job) error {
 		}
 		return fmt.Errorf("restart failed after update and rollback was applied: %w", err)
 	}
-	_ = os.Remove(stagedPath)
+	// The staged binary stays in place as a future rollback target, and the
+	// binary it replaced is archived alongside it.
+	_ = m.retainPreviousBinary(backupPath, job.TargetVersion)
 	return nil
 }
 
@@ -8,7 +8,7 @@ import (
 )
 
 var (
-	semverPattern     = regexp.MustCompile(`^v([0-9]+)\.([0-9]+)\.([0-9]+)(?:[-+][A-Za-z0-9._-]+)?$`)
+	semverPattern     = regexp.MustCompile(`^v([0-9]+)\.([0-9]+)\.([0-9]+)(?:-([A-Za-z0-9._-]+))?(?:\+[A-Za-z0-9._-]+)?$`)
 	allowedTagPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
 )
 
@@ -16,6 +16,9 @@ type semverParts struct {
 	major int
 	minor int
 	patch int
+	// prerelease is the part after "-", e.g. "beta.2"; empty for a final
+	// release, which sorts after all of its prereleases.
+	prerelease string
 }
 
 func normalizeTag(tag string) (string, error) {
@@ -31,7 +34,7 @@ func normalizeTag(tag string) (string, error) {
 
 func parseSemver(tag string) (semverParts, bool) {
 	matches := semverPattern.FindStringSubmatch(strings.TrimSpace(tag))
-	if len(matches) != 4 {
+	if len(matches) != 5 {
 		return semverParts{}, false
 	}
 	major, err := strconv.Atoi(matches[1])
@@ -46,7 +49,7 @@ func parseSemver(tag string) (semverParts, bool) {
 	if err != nil {
 		return semverParts{}, false
 	}
-	return semverParts{major: major, minor: minor, patch: patch}, true
+	return semverParts{major: major, minor: minor, patch: patch, prerelease: matches[4]}, true
 }
 
 func isNewerVersion(current, candidate string) bool {
@@ -67,7 +70,55 @@ func isNewerVersion(current, candidate string) bool {
 		if candidateSemver.minor != currentSemver.minor {
 			return candidateSemver.minor > currentSemver.minor
 		}
-		return candidateSemver.patch > currentSemver.patch
+		if candidateSemver.patch != currentSemver.patch {
+			return candidateSemver.patch > currentSemver.patch
+		}
+		return comparePrerelease(candidateSemver.prerelease, currentSemver.prerelease) > 0
 	}
 	return candidateTag != currentTag
 }
+
+// comparePrerelease orders prerelease strings per semver precedence: no
+// prerelease beats any prerelease, numeric identifiers compare numerically
+// and sort before alphanumeric ones, and a longer list wins a tie.
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	left := strings.Split(a, ".")
+	right := strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		leftNum, leftErr := strconv.Atoi(left[i])
+		rightNum, rightErr := strconv.Atoi(right[i])
+		switch {
+		case leftErr == nil && rightErr == nil:
+			if leftNum != rightNum {
+				if leftNum > rightNum {
+					return 1
+				}
+				return -1
+			}
+		case leftErr == nil:
+			return -1
+		case rightErr == nil:
+			return 1
+		default:
+			if cmp := strings.Compare(left[i], right[i]); cmp != 0 {
+				return cmp
+			}
+		}
+	}
+	switch {
+	case len(left) > len(right):
+		return 1
+	case len(left) < len(right):
+		return -1
+	}
+	return 0
+}
@@ -14,6 +14,10 @@ func TestIsNewerVersionSemver(t *testing.T) {
 		{current: "v1.2.3", candidate: "v1.1.9", want: false},
 		{current: "dev", candidate: "v1.0.0", want: true},
 		{current: "v1.0.0", candidate: "dev", want: true},
+		{current: "v1.3.0-beta.1", candidate: "v1.3.0", want: true},
+		{current: "v1.3.0-beta.2", candidate: "v1.3.0-beta.10", want: true},
+		{current: "v1.3.0", candidate: "v1.3.0-beta.3", want: false},
+		{current: "v1.3.0-rc.1", candidate: "v1.3.0-beta.9", want: false},
 	}
 	for _, tc := range cases {
 		got := isNewerVersion(tc.current, tc.candidate)
@@ -54,7 +54,9 @@ func toPublicStatus(stored persistedStatus, current version.Info) Status {
 		LastError:            stored.LastError,
 		LastAttemptedVersion: stored.LastAttemptedVersion,
 		LastSuccessVersion:   stored.LastSuccessVersion,
+		LastBackupPath:       stored.LastBackupPath,
 	}
+	out.AutoUpdate.LastResult = stored.LastAutoResult
 	if out.State == "" {
 		out.State = "idle"
 	}
@@ -74,6 +76,14 @@ func toPublicStatus(stored persistedStatus, current version.Info) Status {
 		t := time.Unix(stored.LastSuccessAt, 0).UTC()
 		out.LastSuccessAt = &t
 	}
+	if stored.LastBackupAt > 0 {
+		t := time.Unix(stored.LastBackupAt, 0).UTC()
+		out.LastBackupAt = &t
+	}
+	if stored.LastAutoRunAt > 0 {
+		t := time.Unix(stored.LastAutoRunAt, 0).UTC()
+		out.AutoUpdate.LastRunAt = &t
+	}
 	out.UpdateAvailable = isNewerVersion(out.Current.Version, out.LatestVersion)
 	return out
 }
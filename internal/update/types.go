@@ -24,19 +24,36 @@ type ReleaseMetadata struct {
 
 // Status captures updater state exposed by the API.
 type Status struct {
-	Current              version.Info `json:"current"`
-	LatestVersion        string       `json:"latestVersion,omitempty"`
-	LatestPublishedAt    *time.Time   `json:"latestPublishedAt,omitempty"`
-	UpdateAvailable      bool         `json:"updateAvailable"`
-	InProgress           bool         `json:"inProgress"`
-	State                string       `json:"state"`
-	Message              string       `json:"message,omitempty"`
-	LastError            string       `json:"lastError,omitempty"`
-	LastCheckedAt        *time.Time   `json:"lastCheckedAt,omitempty"`
-	LastAttemptedVersion string       `json:"lastAttemptedVersion,omitempty"`
-	LastAttemptAt        *time.Time   `json:"lastAttemptAt,omitempty"`
-	LastSuccessVersion   string       `json:"lastSuccessVersion,omitempty"`
-	LastSuccessAt        *time.Time   `json:"lastSuccessAt,omitempty"`
+	Current              version.Info     `json:"current"`
+	LatestVersion        string           `json:"latestVersion,omitempty"`
+	LatestPublishedAt    *time.Time       `json:"latestPublishedAt,omitempty"`
+	UpdateAvailable      bool             `json:"updateAvailable"`
+	InProgress           bool             `json:"inProgress"`
+	State                string           `json:"state"`
+	Message              string           `json:"message,omitempty"`
+	LastError            string           `json:"lastError,omitempty"`
+	LastCheckedAt        *time.Time       `json:"lastCheckedAt,omitempty"`
+	LastAttemptedVersion string           `json:"lastAttemptedVersion,omitempty"`
+	LastAttemptAt        *time.Time       `json:"lastAttemptAt,omitempty"`
+	LastSuccessVersion   string           `json:"lastSuccessVersion,omitempty"`
+	LastSuccessAt        *time.Time       `json:"lastSuccessAt,omitempty"`
+	Channel              string           `json:"channel"`
+	BackupBeforeUpdate   bool             `json:"backupBeforeUpdate"`
+	LastBackupPath       string           `json:"lastBackupPath,omitempty"`
+	LastBackupAt         *time.Time       `json:"lastBackupAt,omitempty"`
+	AutoUpdate           AutoStatus       `json:"autoUpdate"`
+	RollbackTargets      []RollbackTarget `json:"rollbackTargets"`
+	Preflight            *PreflightReport `json:"preflight,omitempty"`
+	Unsupported          string           `json:"unsupported,omitempty"`
+}
+
+// AutoStatus describes the scheduled auto-update window and its last run.
+type AutoStatus struct {
+	Enabled    bool       `json:"enabled"`
+	Schedule   string     `json:"schedule"`
+	NextRunAt  *time.Time `json:"nextRunAt,omitempty"`
+	LastRunAt  *time.Time `json:"lastRunAt,omitempty"`
+	LastResult string     `json:"lastResult,omitempty"`
 }
 
 type persistedStatus struct {
@@ -51,6 +68,10 @@ type persistedStatus struct {
 	LastAttemptAt        int64  `json:"lastAttemptAt,omitempty"`
 	LastSuccessVersion   string `json:"lastSuccessVersion,omitempty"`
 	LastSuccessAt        int64  `json:"lastSuccessAt,omitempty"`
+	LastBackupPath       string `json:"lastBackupPath,omitempty"`
+	LastBackupAt         int64  `json:"lastBackupAt,omitempty"`
+	LastAutoRunAt        int64  `json:"lastAutoRunAt,omitempty"`
+	LastAutoResult       string `json:"lastAutoResult,omitempty"`
 }
 
 // Job describes a prepared update staging artifact consumed by --self-update-run.
@@ -61,6 +82,8 @@ type Job struct {
 	ExpectedSHA256 string `json:"expectedSha256"`
 	PreparedAt     int64  `json:"preparedAt"`
 	ReleaseSource  string `json:"releaseSource,omitempty"`
+	// Rollback marks a downgrade to a retained version.
+	Rollback bool `json:"rollback,omitempty"`
 }
 
 // Options configures the updater manager.
@@ -72,6 +95,12 @@ type Options struct {
 	UpdaterUnit string
 	HTTPClient  HTTPDoer
 	Systemd     UnitController
+	// Probe inspects the router for update preflight checks; nil uses the
+	// local system.
+	Probe SystemProbe
+	// Unsupported, when set, says why this install cannot update itself.
+	// Updates and rollbacks are refused with it and Status reports it.
+	Unsupported string
 }
 
 // UnitController is the minimal systemd surface required by the updater manager.
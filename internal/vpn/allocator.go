@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+
+	"split-vpn-webui/internal/iprule"
 )
 
 const (
@@ -33,6 +35,12 @@ type CommandExecutor interface {
 
 type systemCommandExecutor struct{}
 
+// ruleSource reads policy rules and route tables over rtnetlink.
+type ruleSource interface {
+	List(ipv6 bool) ([]iprule.Rule, error)
+	RouteTables(ipv6 bool) ([]int, error)
+}
+
 func (systemCommandExecutor) CombinedOutput(name string, args ...string) ([]byte, error) {
 	return exec.Command(name, args...).CombinedOutput()
 }
@@ -45,6 +53,7 @@ type Allocator struct {
 	routeTablesPath string
 	configRoots     []string
 	exec            CommandExecutor
+	rules           ruleSource
 
 	usedTables   map[int]struct{}
 	usedMarks    map[uint32]struct{}
@@ -54,18 +63,18 @@ type Allocator struct {
 
 // NewAllocator creates an allocator using live system information.
 func NewAllocator(vpnsDir string) (*Allocator, error) {
-	return newAllocator(vpnsDir, "/etc/iproute2/rt_tables", systemCommandExecutor{}, nil)
+	return newAllocator(vpnsDir, "/etc/iproute2/rt_tables", systemCommandExecutor{}, iprule.New(), nil)
 }
 
 // NewAllocatorWithConfigRoots creates an allocator that additionally scans
 // external config roots (e.g. peacey /data/split-vpn) for persisted allocations.
 func NewAllocatorWithConfigRoots(vpnsDir string, configRoots []string) (*Allocator, error) {
-	return newAllocator(vpnsDir, "/etc/iproute2/rt_tables", systemCommandExecutor{}, configRoots)
+	return newAllocator(vpnsDir, "/etc/iproute2/rt_tables", systemCommandExecutor{}, iprule.New(), configRoots)
 }
 
 // NewAllocatorWithDeps creates an allocator with custom dependencies for tests.
 func NewAllocatorWithDeps(vpnsDir, routeTablesPath string, executor CommandExecutor) (*Allocator, error) {
-	return newAllocator(vpnsDir, routeTablesPath, executor, nil)
+	return newAllocator(vpnsDir, routeTablesPath, executor, nil, nil)
 }
 
 // NewAllocatorWithDepsAndConfigRoots creates an allocator with custom
@@ -75,10 +84,15 @@ func NewAllocatorWithDepsAndConfigRoots(
 	executor CommandExecutor,
 	configRoots []string,
 ) (*Allocator, error) {
-	return newAllocator(vpnsDir, routeTablesPath, executor, configRoots)
+	return newAllocator(vpnsDir, routeTablesPath, executor, nil, configRoots)
 }
 
-func newAllocator(vpnsDir, routeTablesPath string, executor CommandExecutor, configRoots []string) (*Allocator, error) {
+func newAllocator(
+	vpnsDir, routeTablesPath string,
+	executor CommandExecutor,
+	rules ruleSource,
+	configRoots []string,
+) (*Allocator, error) {
 	trimmedDir := strings.TrimSpace(vpnsDir)
 	if trimmedDir == "" {
 		return nil, fmt.Errorf("vpns directory is required")
@@ -95,6 +109,7 @@ func newAllocator(vpnsDir, routeTablesPath string, executor CommandExecutor, con
 		routeTablesPath: routeTablesPath,
 		configRoots:     normalizeConfigRoots(trimmedDir, configRoots),
 		exec:            executor,
+		rules:           rules,
 		usedTables:      make(map[int]struct{}),
 		usedMarks:       make(map[uint32]struct{}),
 		stickyTables:    make(map[int]struct{}),
@@ -262,6 +277,9 @@ func (a *Allocator) seedFromRouteTables() error {
 
 func (a *Allocator) seedFromIPRules() {
 	for _, args := range [][]string{{"rule", "show"}, {"-6", "rule", "show"}} {
+		if a.seedFromNetlinkRules(args[0] == "-6") {
+			continue
+		}
 		output, err := a.exec.CombinedOutput("ip", args...)
 		if err != nil {
 			continue
@@ -270,6 +288,27 @@ func (a *Allocator) seedFromIPRules() {
 	}
 }
 
+// seedFromNetlinkRules records fwmark rules read over rtnetlink. It reports
+// false when netlink is unavailable so the caller can parse `ip rule` output.
+func (a *Allocator) seedFromNetlinkRules(ipv6 bool) bool {
+	if a.rules == nil {
+		return false
+	}
+	rules, err := a.rules.List(ipv6)
+	if err != nil {
+		return false
+	}
+	for _, rule := range rules {
+		if rule.Mark >= minFWMark {
+			a.markMarkUsed(rule.Mark, true)
+		}
+		if rule.Table >= minRouteTableID {
+			a.markTableUsed(rule.Table, true)
+		}
+	}
+	return true
+}
+
 func (a *Allocator) parseIPRulesOutput(output string) {
 	scanner := bufio.NewScanner(strings.NewReader(output))
 	for scanner.Scan() {
@@ -293,6 +332,9 @@ func (a *Allocator) parseIPRulesOutput(output string) {
 
 func (a *Allocator) seedFromIPRoutes() {
 	for _, args := range [][]string{{"route", "show", "table", "all"}, {"-6", "route", "show", "table", "all"}} {
+		if a.seedFromNetlinkRoutes(args[0] == "-6") {
+			continue
+		}
 		output, err := a.exec.CombinedOutput("ip", args...)
 		if err != nil {
 			continue
@@ -301,6 +343,22 @@ func (a *Allocator) seedFromIPRoutes() {
 	}
 }
 
+func (a *Allocator) seedFromNetlinkRoutes(ipv6 bool) bool {
+	if a.rules == nil {
+		return false
+	}
+	tables, err := a.rules.RouteTables(ipv6)
+	if err != nil {
+		return false
+	}
+	for _, tableID := range tables {
+		if tableID >= minRouteTableID && tableID <= maxRouteTableID {
+			a.markTableUsed(tableID, true)
+		}
+	}
+	return true
+}
+
 func (a *Allocator) parseIPRoutesOutput(output string) {
 	scanner := bufio.NewScanner(strings.NewReader(output))
 	for scanner.Scan() {
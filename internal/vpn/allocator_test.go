@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"split-vpn-webui/internal/iprule"
 )
 
 type mockCommandExecutor struct {
@@ -327,3 +329,51 @@ func TestAllocatorScansAdditionalConfigRoots(t *testing.T) {
 		t.Fatalf("expected persisted allocation conflict from additional config root, got %v", err)
 	}
 }
+
+type fakeRuleSource struct {
+	rules  []iprule.Rule
+	tables []int
+}
+
+func (f fakeRuleSource) List(ipv6 bool) ([]iprule.Rule, error) {
+	if ipv6 {
+		return nil, errors.New("netlink unavailable")
+	}
+	return f.rules, nil
+}
+
+func (f fakeRuleSource) RouteTables(ipv6 bool) ([]int, error) {
+	if ipv6 {
+		return nil, errors.New("netlink unavailable")
+	}
+	return f.tables, nil
+}
+
+func TestAllocatorSeedsFromNetlinkWithCommandFallback(t *testing.T) {
+	routeTables := filepath.Join(t.TempDir(), "rt_tables")
+	if err := os.WriteFile(routeTables, []byte("\n"), 0o644); err != nil {
+		t.Fatalf("write route tables file: %v", err)
+	}
+	alloc, err := newAllocator(t.TempDir(), routeTables, mockCommandExecutor{
+		outputs: map[string][]byte{
+			"ip -6 rule show":            []byte("100: from all fwmark 0xcb lookup 203\n"),
+			"ip -6 route show table all": []byte("default dev wg1 table 204\n"),
+		},
+	}, fakeRuleSource{
+		rules:  []iprule.Rule{{Priority: 100, Mark: 0xc8, Table: 200}, {Priority: 32766, Table: 254}},
+		tables: []int{201, 254, 255},
+	}, nil)
+	if err != nil {
+		t.Fatalf("newAllocator failed: %v", err)
+	}
+	for _, table := range []int{200, 201, 203, 204} {
+		if _, ok := alloc.usedTables[table]; !ok {
+			t.Fatalf("expected table %d to be reserved, got %v", table, alloc.usedTables)
+		}
+	}
+	for _, mark := range []uint32{0xc8, 0xcb} {
+		if _, ok := alloc.usedMarks[mark]; !ok {
+			t.Fatalf("expected mark 0x%x to be reserved, got %v", mark, alloc.usedMarks)
+		}
+	}
+}
@@ -43,7 +43,7 @@ func (p *AmneziaWGProvider) ParseConfig(raw string) (*VPNProfile, error) {
 	}, nil
 }
 
-func (p *AmneziaWGProvider) GenerateUnit(profile *VPNProfile, dataDir string) string {
+func (p *AmneziaWGProvider) GenerateUnit(profile *VPNProfile, dataDir, vpnsDir string) string {
 	if profile == nil {
 		return ""
 	}
@@ -52,6 +52,10 @@ func (p *AmneziaWGProvider) GenerateUnit(profile *VPNProfile, dataDir string) st
 		name = "vpn"
 	}
 	binaryPath := filepath.Join(dataDir, "split-vpn-webui")
+	runArgs := fmt.Sprintf("--name %s --data-dir %s", name, dataDir)
+	if vpnsDir != filepath.Join(dataDir, "vpns") {
+		runArgs += " --vpns-dir " + vpnsDir
+	}
 	return fmt.Sprintf(`[Unit]
 Description=split-vpn-webui AmneziaWG tunnel (%s)
 After=network-online.target
@@ -60,7 +64,7 @@ Wants=network-online.target
 [Service]
 Type=notify
 NotifyAccess=main
-ExecStart=%s tunnel run --name %s --data-dir %s
+ExecStart=%s tunnel run %s
 Restart=on-failure
 RestartSec=5
 TimeoutStartSec=2min
@@ -70,5 +74,5 @@ AmbientCapabilities=CAP_NET_ADMIN CAP_NET_RAW
 
 [Install]
 WantedBy=multi-user.target
-`, name, binaryPath, name, dataDir)
+`, name, binaryPath, runArgs)
 }
@@ -154,7 +154,7 @@ Endpoint = 1.2.3.4:51820
 
 func TestAmneziaWGGenerateUnit(t *testing.T) {
 	provider := NewAmneziaWGProvider()
-	unit := provider.GenerateUnit(&VPNProfile{Name: "awg-sgp"}, "/data/split-vpn-webui")
+	unit := provider.GenerateUnit(&VPNProfile{Name: "awg-sgp"}, "/data/split-vpn-webui", "/data/split-vpn-webui/vpns")
 	for _, want := range []string{
 		"Type=notify",
 		"ExecStart=/data/split-vpn-webui/split-vpn-webui tunnel run --name awg-sgp --data-dir /data/split-vpn-webui",
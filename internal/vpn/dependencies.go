@@ -0,0 +1,324 @@
+package vpn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const dependsOnMetaKey = "VPN_DEPENDS_ON"
+
+// Dependencies returns, for every profile, the VPNs that must be up before it
+// starts. A profile depends on the VPNs listed in DependsOn, on its uplink VPN
+// and on any VPN whose interface it is bound to (a tunnel running inside
+// another tunnel).
+// Names that do not match a profile are dropped.
+func Dependencies(profiles []*VPNProfile) map[string][]string {
+	byName := make(map[string]struct{}, len(profiles))
+	byInterface := make(map[string]string, len(profiles))
+	for _, profile := range profiles {
+		if profile == nil {
+			continue
+		}
+		byName[profile.Name] = struct{}{}
+		if profile.InterfaceName != "" {
+			byInterface[profile.InterfaceName] = profile.Name
+		}
+	}
+	result := make(map[string][]string, len(byName))
+	for _, profile := range profiles {
+		if profile == nil {
+			continue
+		}
+		seen := map[string]struct{}{}
+		deps := make([]string, 0, len(profile.DependsOn)+2)
+		add := func(name string) {
+			if name == "" || name == profile.Name {
+				return
+			}
+			if _, ok := byName[name]; !ok {
+				return
+			}
+			if _, ok := seen[name]; ok {
+				return
+			}
+			seen[name] = struct{}{}
+			deps = append(deps, name)
+		}
+		for _, name := range profile.DependsOn {
+			add(name)
+		}
+		add(profile.UplinkVPN)
+		if profile.BoundInterface != "" {
+			add(byInterface[profile.BoundInterface])
+		}
+		sort.Strings(deps)
+		result[profile.Name] = deps
+	}
+	return result
+}
+
+// DependencyOrder sorts VPN names so each one follows everything it depends
+// on. Independent VPNs keep alphabetical order so bring-up is deterministic.
+func DependencyOrder(deps map[string][]string) ([]string, error) {
+	remaining := make(map[string]int, len(deps))
+	dependents := make(map[string][]string, len(deps))
+	for name, required := range deps {
+		if _, ok := remaining[name]; !ok {
+			remaining[name] = 0
+		}
+		for _, dep := range required {
+			if _, ok := deps[dep]; !ok {
+				continue
+			}
+			remaining[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	ready := make([]string, 0, len(remaining))
+	for name, count := range remaining {
+		if count == 0 {
+			ready = append(ready, name)
+		}
+	}
+	order := make([]string, 0, len(remaining))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		next := ready[0]
+		ready = ready[1:]
+		order = append(order, next)
+		for _, dependent := range dependents[next] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(order) != len(remaining) {
+		cycle := make([]string, 0, len(remaining)-len(order))
+		for name, count := range remaining {
+			if count > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("%w: dependency cycle between %s", ErrVPNValidation, strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+// StartOrder returns every managed VPN in dependency order.
+func (m *Manager) StartOrder() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	profiles, err := m.listProfilesLocked()
+	if err != nil {
+		return nil, err
+	}
+	return DependencyOrder(Dependencies(profiles))
+}
+
+func (m *Manager) listProfilesLocked() ([]*VPNProfile, error) {
+	entries, err := os.ReadDir(m.vpnsDir)
+	if err != nil {
+		return nil, err
+	}
+	profiles := make([]*VPNProfile, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		profile, err := m.readProfileLocked(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// validateDependsOnLocked normalizes the requested dependency list and rejects
+// unknown VPNs, self references and cycles.
+func (m *Manager) validateDependsOnLocked(name string, requested []string, candidate *VPNProfile) ([]string, []string, error) {
+	profiles, err := m.listProfilesLocked()
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[string]struct{}, len(profiles))
+	for _, profile := range profiles {
+		known[profile.Name] = struct{}{}
+	}
+	seen := map[string]struct{}{}
+	dependsOn := make([]string, 0, len(requested))
+	for _, raw := range requested {
+		dep := strings.TrimSpace(raw)
+		if dep == "" {
+			continue
+		}
+		if dep == name {
+			return nil, nil, fmt.Errorf("%w: vpn %s cannot depend on itself", ErrVPNValidation, name)
+		}
+		if _, ok := known[dep]; !ok {
+			return nil, nil, fmt.Errorf("%w: dependency %q is not a managed vpn", ErrVPNValidation, dep)
+		}
+		if _, ok := seen[dep]; ok {
+			continue
+		}
+		seen[dep] = struct{}{}
+		dependsOn = append(dependsOn, dep)
+	}
+	sort.Strings(dependsOn)
+
+	candidate.DependsOn = dependsOn
+	merged := make([]*VPNProfile, 0, len(profiles)+1)
+	merged = append(merged, candidate)
+	for _, profile := range profiles {
+		if profile.Name != name {
+			merged = append(merged, profile)
+		}
+	}
+	deps := Dependencies(merged)
+	if _, err := DependencyOrder(deps); err != nil {
+		return nil, nil, err
+	}
+	return dependsOn, deps[name], nil
+}
+
+// rewriteDependentUnitsLocked regenerates the units of VPNs whose dependency
+// set may have changed because the named VPN was created, changed interface
+// or was removed.
+func (m *Manager) rewriteDependentUnitsLocked(name string, interfaces ...string) error {
+	if m.units == nil && m.writer == nil {
+		return nil
+	}
+	profiles, err := m.listProfilesLocked()
+	if err != nil {
+		return err
+	}
+	deps := Dependencies(profiles)
+	byName := make(map[string]*VPNProfile, len(profiles))
+	for _, profile := range profiles {
+		byName[profile.Name] = profile
+	}
+	for _, profile := range profiles {
+		if profile.Name == name || !dependsOnChanged(profile, name, interfaces) {
+			continue
+		}
+		if _, ok := m.providers[profile.Type]; !ok {
+			continue
+		}
+		var parent *VPNProfile
+		if profile.UplinkVPN != "" {
+			parent = byName[profile.UplinkVPN]
+		}
+		if err := m.writeUnitLocked(unitSpecFor(profile, deps[profile.Name], parent)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RewriteUnits regenerates the unit of every VPN, e.g. once a unit writer
+// is set so existing units are replaced by ones it generated. VPNs whose
+// unit cannot be written are skipped and reported together.
+func (m *Manager) RewriteUnits() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	profiles, err := m.listProfilesLocked()
+	if err != nil {
+		return err
+	}
+	deps := Dependencies(profiles)
+	byName := make(map[string]*VPNProfile, len(profiles))
+	for _, profile := range profiles {
+		byName[profile.Name] = profile
+	}
+	var errs []error
+	for _, profile := range profiles {
+		if _, ok := m.providers[profile.Type]; !ok {
+			continue
+		}
+		var parent *VPNProfile
+		if profile.UplinkVPN != "" {
+			parent = byName[profile.UplinkVPN]
+		}
+		if err := m.writeUnitLocked(unitSpecFor(profile, deps[profile.Name], parent)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", profile.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func dependsOnChanged(profile *VPNProfile, name string, interfaces []string) bool {
+	if profile.UplinkVPN == name {
+		return true
+	}
+	for _, dep := range profile.DependsOn {
+		if dep == name {
+			return true
+		}
+	}
+	for _, iface := range interfaces {
+		if iface != "" && profile.BoundInterface == iface {
+			return true
+		}
+	}
+	return false
+}
+
+// dependentsOfLocked lists VPNs that explicitly depend on name or use it as
+// their uplink.
+func (m *Manager) dependentsOfLocked(name string) ([]string, error) {
+	profiles, err := m.listProfilesLocked()
+	if err != nil {
+		return nil, err
+	}
+	dependents := make([]string, 0)
+	for _, profile := range profiles {
+		if profile.UplinkVPN == name {
+			dependents = append(dependents, profile.Name)
+			continue
+		}
+		for _, dep := range profile.DependsOn {
+			if dep == name {
+				dependents = append(dependents, profile.Name)
+				break
+			}
+		}
+	}
+	return dependents, nil
+}
+
+// withUnitDependencies adds After=/Requires= lines for each dependency to the
+// [Unit] section of a generated unit.
+func withUnitDependencies(unit string, deps []string) string {
+	if len(deps) == 0 {
+		return unit
+	}
+	var lines strings.Builder
+	for _, dep := range deps {
+		unitName := vpnServiceUnitName(dep)
+		lines.WriteString("After=" + unitName + "\n")
+		lines.WriteString("Requires=" + unitName + "\n")
+	}
+	marker := "\n\n[Service]"
+	idx := strings.Index(unit, marker)
+	if idx < 0 {
+		return unit
+	}
+	return unit[:idx+1] + lines.String() + unit[idx+1:]
+}
+
+func parseDependsOn(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.Strings(fields)
+	return fields
+}
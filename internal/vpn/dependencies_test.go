@@ -0,0 +1,115 @@
+package vpn
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	profiles := []*VPNProfile{
+		{Name: "wg-inner", InterfaceName: "wg-sv-inner", BoundInterface: "wg-sv-outer"},
+		{Name: "wg-outer", InterfaceName: "wg-sv-outer"},
+		{Name: "ovpn-app", InterfaceName: "tun1", DependsOn: []string{"wg-inner", "wg-missing"}},
+		{Name: "awg-solo", InterfaceName: "awg-sv-solo"},
+	}
+	deps := Dependencies(profiles)
+	if !reflect.DeepEqual(deps["wg-inner"], []string{"wg-outer"}) {
+		t.Fatalf("expected bound interface dependency, got %#v", deps["wg-inner"])
+	}
+	if !reflect.DeepEqual(deps["ovpn-app"], []string{"wg-inner"}) {
+		t.Fatalf("expected unknown dependency dropped, got %#v", deps["ovpn-app"])
+	}
+
+	order, err := DependencyOrder(deps)
+	if err != nil {
+		t.Fatalf("DependencyOrder failed: %v", err)
+	}
+	if want := []string{"awg-solo", "wg-outer", "wg-inner", "ovpn-app"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("unexpected order: got %v want %v", order, want)
+	}
+
+	_, err = DependencyOrder(map[string][]string{"a": {"b"}, "b": {"a"}, "c": nil})
+	if !errors.Is(err, ErrVPNValidation) || !strings.Contains(err.Error(), "a, b") {
+		t.Fatalf("expected cycle error naming a and b, got %v", err)
+	}
+}
+
+func TestWithUnitDependencies(t *testing.T) {
+	unit := NewWireGuardProvider().GenerateUnit(&VPNProfile{Name: "wg-inner"}, "/data/split-vpn-webui", "/data/split-vpn-webui/vpns")
+	got := withUnitDependencies(unit, []string{"wg-outer"})
+	want := "Wants=network-online.target\nAfter=svpn-wg-outer.service\nRequires=svpn-wg-outer.service\n\n[Service]"
+	if !strings.Contains(got, want) {
+		t.Fatalf("dependency lines not in [Unit] section:\n%s", got)
+	}
+	if withUnitDependencies(unit, nil) != unit {
+		t.Fatalf("unit without dependencies should be unchanged")
+	}
+}
+
+func TestManagerDependsOnLifecycle(t *testing.T) {
+	manager, _, units := newTestManager(t)
+	config := func(host string) string {
+		return "[Interface]\nPrivateKey = test\nAddress = 10.0.0.2/32\n[Peer]\nPublicKey = peer\nAllowedIPs = 0.0.0.0/0\nEndpoint = " + host + ":51820\n"
+	}
+
+	if _, err := manager.Create(UpsertRequest{Name: "wg-app", Type: "wireguard", Config: config("app"), DependsOn: []string{"wg-outer"}}); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected unknown dependency to be rejected, got %v", err)
+	}
+	outer, err := manager.Create(UpsertRequest{Name: "wg-outer", Type: "wireguard", Config: config("outer")})
+	if err != nil {
+		t.Fatalf("create outer: %v", err)
+	}
+	app, err := manager.Create(UpsertRequest{Name: "wg-app", Type: "wireguard", Config: config("app"), DependsOn: []string{"wg-outer"}})
+	if err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+	if !reflect.DeepEqual(app.DependsOn, []string{"wg-outer"}) {
+		t.Fatalf("dependsOn not persisted: %#v", app.DependsOn)
+	}
+	if !strings.Contains(units.written["svpn-wg-app.service"], "Requires=svpn-wg-outer.service") {
+		t.Fatalf("app unit missing Requires=:\n%s", units.written["svpn-wg-app.service"])
+	}
+
+	// A tunnel bound to another VPN's interface depends on it implicitly; the
+	// existing dependent's unit is rewritten when the outer VPN changes.
+	if _, err := manager.Create(UpsertRequest{Name: "wg-inner", Type: "wireguard", Config: config("inner"), BoundInterface: outer.InterfaceName}); err != nil {
+		t.Fatalf("create inner: %v", err)
+	}
+	if !strings.Contains(units.written["svpn-wg-inner.service"], "After=svpn-wg-outer.service") {
+		t.Fatalf("inner unit missing After=:\n%s", units.written["svpn-wg-inner.service"])
+	}
+	delete(units.written, "svpn-wg-app.service")
+	if _, err := manager.WriteConfig("wg-outer", config("outer2")); err != nil {
+		t.Fatalf("write outer config: %v", err)
+	}
+	if !strings.Contains(units.written["svpn-wg-app.service"], "Requires=svpn-wg-outer.service") {
+		t.Fatalf("dependent unit not rewritten after outer change")
+	}
+
+	if _, err := manager.Update("wg-outer", UpsertRequest{Config: config("outer"), DependsOn: []string{"wg-app"}}); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected cycle to be rejected, got %v", err)
+	}
+	if err := manager.Delete("wg-outer"); !errors.Is(err, ErrVPNValidation) || !strings.Contains(err.Error(), "wg-app") {
+		t.Fatalf("expected delete to be blocked by wg-app, got %v", err)
+	}
+
+	order, err := manager.StartOrder()
+	if err != nil {
+		t.Fatalf("StartOrder: %v", err)
+	}
+	if want := []string{"wg-outer", "wg-app", "wg-inner"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("unexpected start order: got %v want %v", order, want)
+	}
+
+	if err := manager.Delete("wg-app"); err != nil {
+		t.Fatalf("delete app: %v", err)
+	}
+	if err := manager.Delete("wg-outer"); err != nil {
+		t.Fatalf("delete outer: %v", err)
+	}
+	if strings.Contains(units.written["svpn-wg-inner.service"], "svpn-wg-outer.service") {
+		t.Fatalf("inner unit still requires removed outer VPN:\n%s", units.written["svpn-wg-inner.service"])
+	}
+}
@@ -0,0 +1,39 @@
+package vpn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	endpointRefreshMetaKey = "VPN_ENDPOINT_REFRESH_SECONDS"
+	// minEndpointRefreshSeconds and maxEndpointRefreshSeconds bound how
+	// often a WireGuard endpoint host name is looked up again.
+	minEndpointRefreshSeconds = 30
+	maxEndpointRefreshSeconds = 24 * 60 * 60
+)
+
+// ValidateEndpointRefreshSeconds checks how often the peer endpoints of a
+// WireGuard or AmneziaWG VPN are resolved again. Zero turns re-resolution
+// off; other VPN types resolve their remote themselves.
+func ValidateEndpointRefreshSeconds(vpnType string, seconds int) (int, error) {
+	if seconds == 0 {
+		return 0, nil
+	}
+	if !isWireGuardLike(vpnType) {
+		return 0, fmt.Errorf("endpoint re-resolution is only available for WireGuard and AmneziaWG VPNs")
+	}
+	if seconds < minEndpointRefreshSeconds || seconds > maxEndpointRefreshSeconds {
+		return 0, fmt.Errorf("endpoint re-resolution interval must be 0 or between %d and %d seconds", minEndpointRefreshSeconds, maxEndpointRefreshSeconds)
+	}
+	return seconds, nil
+}
+
+func parseEndpointRefreshSeconds(raw string) int {
+	seconds, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || seconds < minEndpointRefreshSeconds || seconds > maxEndpointRefreshSeconds {
+		return 0
+	}
+	return seconds
+}
@@ -0,0 +1,56 @@
+package vpn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateEndpointRefreshSeconds(t *testing.T) {
+	cases := []struct {
+		vpnType string
+		seconds int
+		want    int
+		wantErr bool
+	}{
+		{"wireguard", 0, 0, false},
+		{"wireguard", 300, 300, false},
+		{"amneziawg", 30, 30, false},
+		{"wireguard", 10, 0, true},
+		{"wireguard", 2 * 24 * 60 * 60, 0, true},
+		{"openvpn", 300, 0, true},
+		{"openvpn", 0, 0, false},
+	}
+	for _, tc := range cases {
+		got, err := ValidateEndpointRefreshSeconds(tc.vpnType, tc.seconds)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Fatalf("ValidateEndpointRefreshSeconds(%q, %d) = %d, %v", tc.vpnType, tc.seconds, got, err)
+		}
+	}
+}
+
+func TestManagerEndpointRefreshSeconds(t *testing.T) {
+	manager, _, _ := newTestManager(t)
+	config := "[Interface]\nPrivateKey = test\nAddress = 10.0.0.2/32\n[Peer]\nPublicKey = peer\nAllowedIPs = 0.0.0.0/0\nEndpoint = home.example.net:51820\n"
+
+	if _, err := manager.Create(UpsertRequest{Name: "wg-home", Type: "wireguard", Config: config, EndpointRefreshSeconds: 5}); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected short interval to be rejected, got %v", err)
+	}
+	if _, err := manager.Create(UpsertRequest{Name: "wg-home", Type: "wireguard", Config: config, EndpointRefreshSeconds: 120}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	loaded, err := manager.Get("wg-home")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if loaded.EndpointRefreshSeconds != 120 {
+		t.Fatalf("interval not persisted, got %d", loaded.EndpointRefreshSeconds)
+	}
+
+	rewritten, err := manager.WriteConfig("wg-home", config)
+	if err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if rewritten.EndpointRefreshSeconds != 120 {
+		t.Fatalf("interval lost on raw config write, got %d", rewritten.EndpointRefreshSeconds)
+	}
+}
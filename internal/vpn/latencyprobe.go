@@ -0,0 +1,68 @@
+package vpn
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+const (
+	latencyProbeMetaKey       = "VPN_LATENCY_PROBE"
+	latencyProbeTargetMetaKey = "VPN_LATENCY_PROBE_TARGET"
+)
+
+// ValidateLatencyProbe checks how the latency monitor measures a VPN. The
+// empty probe pings the gateway, or the target host when one is set;
+// "https" times a GET of the target URL and "dns" times a query to the
+// target resolver, which defaults to the profile's DNS server.
+func ValidateLatencyProbe(probe, target string) (string, string, error) {
+	probe = strings.ToLower(strings.TrimSpace(probe))
+	target = strings.TrimSpace(target)
+	switch probe {
+	case "", "icmp":
+		if target != "" && !validProbeHost(target) {
+			return "", "", fmt.Errorf("latency probe target must be a host name or IP address")
+		}
+		return "", target, nil
+	case "https":
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || parsed.User != nil {
+			return "", "", fmt.Errorf("https latency probe needs an http:// or https:// URL")
+		}
+		return probe, target, nil
+	case "dns":
+		if target == "" {
+			return probe, "", nil
+		}
+		if _, err := netip.ParseAddr(target); err == nil {
+			return probe, target, nil
+		}
+		if addrPort, err := netip.ParseAddrPort(target); err == nil && addrPort.Port() != 0 {
+			return probe, target, nil
+		}
+		return "", "", fmt.Errorf("dns latency probe target must be a resolver IP address or IP:port")
+	default:
+		return "", "", fmt.Errorf("latency probe must be icmp, https or dns")
+	}
+}
+
+func validProbeHost(host string) bool {
+	if _, err := netip.ParseAddr(host); err == nil {
+		return true
+	}
+	if strings.ContainsAny(host, " /:@") || strings.HasPrefix(host, "-") || len(host) > 253 {
+		return false
+	}
+	_, _, err := net.SplitHostPort(host + ":0")
+	return err == nil
+}
+
+func parseLatencyProbe(meta VPNMeta) (string, string) {
+	probe, target, err := ValidateLatencyProbe(meta[latencyProbeMetaKey], meta[latencyProbeTargetMetaKey])
+	if err != nil {
+		return "", ""
+	}
+	return probe, target
+}
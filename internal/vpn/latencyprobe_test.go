@@ -0,0 +1,70 @@
+package vpn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestManagerLatencyProbe(t *testing.T) {
+	manager, _, _ := newTestManager(t)
+	config := "[Interface]\nPrivateKey = test\nAddress = 10.0.0.2/32\n[Peer]\nPublicKey = peer\nAllowedIPs = 0.0.0.0/0\nEndpoint = vpn.example.com:51820\n"
+
+	if _, err := manager.Create(UpsertRequest{Name: "wg-probe", Type: "wireguard", Config: config, LatencyProbe: "https"}); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected https probe without a URL to be rejected, got %v", err)
+	}
+	created, err := manager.Create(UpsertRequest{Name: "wg-probe", Type: "wireguard", Config: config, LatencyProbe: "HTTPS", LatencyProbeTarget: " https://www.cloudflare.com/cdn-cgi/trace "})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.LatencyProbe != "https" || created.LatencyProbeTarget != "https://www.cloudflare.com/cdn-cgi/trace" {
+		t.Fatalf("unexpected probe %q target %q", created.LatencyProbe, created.LatencyProbeTarget)
+	}
+	loaded, err := manager.Get("wg-probe")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if loaded.LatencyProbe != "https" || loaded.LatencyProbeTarget != created.LatencyProbeTarget {
+		t.Fatalf("probe not persisted, got %q %q", loaded.LatencyProbe, loaded.LatencyProbeTarget)
+	}
+
+	// Rewriting the config keeps the probe.
+	rewritten, err := manager.WriteConfig("wg-probe", config)
+	if err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if rewritten.LatencyProbe != "https" {
+		t.Fatalf("expected probe to survive a config rewrite, got %q", rewritten.LatencyProbe)
+	}
+}
+
+func TestValidateLatencyProbe(t *testing.T) {
+	for _, tc := range []struct {
+		probe, target         string
+		wantProbe, wantTarget string
+	}{
+		{"", "", "", ""},
+		{"icmp", "1.1.1.1", "", "1.1.1.1"},
+		{"", "ping.example.net", "", "ping.example.net"},
+		{"dns", "", "dns", ""},
+		{"dns", "10.64.0.1", "dns", "10.64.0.1"},
+		{"dns", "[fd00::1]:5353", "dns", "[fd00::1]:5353"},
+		{"https", "http://192.168.1.1/", "https", "http://192.168.1.1/"},
+	} {
+		probe, target, err := ValidateLatencyProbe(tc.probe, tc.target)
+		if err != nil || probe != tc.wantProbe || target != tc.wantTarget {
+			t.Fatalf("ValidateLatencyProbe(%q, %q) = %q, %q, %v", tc.probe, tc.target, probe, target, err)
+		}
+	}
+	for _, tc := range [][2]string{
+		{"tcp", ""},
+		{"icmp", "https://example.com"},
+		{"https", "ftp://example.com"},
+		{"https", "https://user:pw@example.com"},
+		{"dns", "resolver.lan"},
+		{"dns", "10.64.0.1:0"},
+	} {
+		if _, _, err := ValidateLatencyProbe(tc[0], tc[1]); err == nil {
+			t.Fatalf("expected probe %q target %q to be rejected", tc[0], tc[1])
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"net"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 )
@@ -29,8 +28,16 @@ type UpsertRequest struct {
 	SupportingFiles []SupportingFileUpload `json:"supportingFiles,omitempty"`
 	InterfaceName  string `json:"interfaceName,omitempty"`
 	BoundInterface string `json:"boundInterface,omitempty"`
+	DependsOn      []string `json:"dependsOn,omitempty"`
+	UplinkVPN      string `json:"uplinkVpn,omitempty"`
 	MSSClampV4     string `json:"mssClampV4,omitempty"`
 	MSSClampV6     string `json:"mssClampV6,omitempty"`
+	IPv6Policy     string `json:"ipv6Policy,omitempty"`
+	IPv6Prefix     string `json:"ipv6Prefix,omitempty"`
+	OnDemandIdleMinutes int `json:"onDemandIdleMinutes,omitempty"`
+	LatencyProbe        string `json:"latencyProbe,omitempty"`
+	LatencyProbeTarget  string `json:"latencyProbeTarget,omitempty"`
+	EndpointRefreshSeconds int `json:"endpointRefreshSeconds,omitempty"`
 }
 
 // SupportingFileUpload represents one uploaded OpenVPN support file.
@@ -48,6 +55,7 @@ type Manager struct {
 	peaceyDir string
 	allocator *Allocator
 	units     UnitManager
+	writer    UnitWriter
 	providers map[string]Provider
 
 	listInterfaces func() ([]net.Interface, error)
@@ -84,38 +92,33 @@ func NewManager(vpnsDir string, allocator *Allocator, unitManager UnitManager) (
 		peaceyDir: "/data/split-vpn",
 		allocator: allocator,
 		units:     unitManager,
-		providers: map[string]Provider{
-			"wireguard": NewWireGuardProvider(),
-			"openvpn":   NewOpenVPNProvider(),
-			"amneziawg": NewAmneziaWGProvider(),
-		},
+		providers: defaultProviders(),
 		listInterfaces: net.Interfaces,
 	}, nil
 }
 
+func defaultProviders() map[string]Provider {
+	return map[string]Provider{
+		"wireguard": NewWireGuardProvider(),
+		"openvpn":   NewOpenVPNProvider(),
+		"amneziawg": NewAmneziaWGProvider(),
+	}
+}
+
+// SetUnitWriter makes VPN units go through writer, which generates them
+// from a spec, instead of being rendered here and written with the unit
+// manager.
+func (m *Manager) SetUnitWriter(writer UnitWriter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writer = writer
+}
+
 // List returns all VPN profiles from disk.
 func (m *Manager) List() ([]*VPNProfile, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-
-	entries, err := os.ReadDir(m.vpnsDir)
-	if err != nil {
-		return nil, err
-	}
-
-	profiles := make([]*VPNProfile, 0, len(entries))
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		profile, err := m.readProfileLocked(entry.Name())
-		if err != nil {
-			return nil, err
-		}
-		profiles = append(profiles, profile)
-	}
-	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
-	return profiles, nil
+	return m.listProfilesLocked()
 }
 
 // Get returns a specific VPN profile.
@@ -135,6 +138,14 @@ func (m *Manager) Create(req UpsertRequest) (*VPNProfile, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	uploads, err := parseSupportingUploads(req.SupportingFiles)
+	if err != nil {
+		return nil, err
+	}
+	return m.createLocked(req, uploads)
+}
+
+func (m *Manager) createLocked(req UpsertRequest, uploads map[string][]byte) (*VPNProfile, error) {
 	name, err := validateCreateName(req.Name)
 	if err != nil {
 		return nil, err
@@ -150,11 +161,6 @@ func (m *Manager) Create(req UpsertRequest) (*VPNProfile, error) {
 	if err != nil {
 		return nil, err
 	}
-	uploads, err := parseSupportingUploads(req.SupportingFiles)
-	if err != nil {
-		m.allocator.Release(prepared.routeTableReserved, prepared.markReserved)
-		return nil, err
-	}
 	if err := validateRequiredSupportingFiles("", prepared.requiredSupportingFiles, uploads); err != nil {
 		m.allocator.Release(prepared.routeTableReserved, prepared.markReserved)
 		return nil, err
@@ -185,12 +191,10 @@ func (m *Manager) Create(req UpsertRequest) (*VPNProfile, error) {
 		_ = os.RemoveAll(dir)
 		return nil, err
 	}
-	if m.units != nil {
-		if err := m.units.WriteUnit(prepared.unitName, prepared.unitContent); err != nil {
-			m.allocator.Release(prepared.routeTableReserved, prepared.markReserved)
-			_ = os.RemoveAll(dir)
-			return nil, err
-		}
+	if err := m.writeUnitLocked(prepared.unitSpec); err != nil {
+		m.allocator.Release(prepared.routeTableReserved, prepared.markReserved)
+		_ = os.RemoveAll(dir)
+		return nil, err
 	}
 
 	profile, err := m.readProfileLocked(name)
@@ -198,6 +202,9 @@ func (m *Manager) Create(req UpsertRequest) (*VPNProfile, error) {
 		return nil, err
 	}
 	profile.Warnings = append(profile.Warnings, prepared.warnings...)
+	if err := m.rewriteDependentUnitsLocked(name, profile.InterfaceName); err != nil {
+		profile.Warnings = append(profile.Warnings, fmt.Sprintf("failed to update units of dependent VPNs: %v", err))
+	}
 	return profile, nil
 }
 
@@ -205,7 +212,10 @@ func (m *Manager) Create(req UpsertRequest) (*VPNProfile, error) {
 func (m *Manager) Update(name string, req UpsertRequest) (*VPNProfile, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.updateLocked(name, req)
+}
 
+func (m *Manager) updateLocked(name string, req UpsertRequest) (*VPNProfile, error) {
 	validatedName, err := validateExistingName(name)
 	if err != nil {
 		return nil, err
@@ -246,13 +256,11 @@ func (m *Manager) Update(name string, req UpsertRequest) (*VPNProfile, error) {
 		m.allocator.Release(prepared.routeTableReserved, prepared.markReserved)
 		return nil, err
 	}
-	if m.units != nil {
-		if err := m.units.WriteUnit(prepared.unitName, prepared.unitContent); err != nil {
-			if prepared.releaseTable > 0 || prepared.releaseMark > 0 {
-				m.allocator.Release(prepared.releaseTable, prepared.releaseMark)
-			}
-			return nil, err
+	if err := m.writeUnitLocked(prepared.unitSpec); err != nil {
+		if prepared.releaseTable > 0 || prepared.releaseMark > 0 {
+			m.allocator.Release(prepared.releaseTable, prepared.releaseMark)
 		}
+		return nil, err
 	}
 	if existing.ConfigFile != "" && existing.ConfigFile != prepared.configFileName {
 		_ = os.Remove(filepath.Join(dir, existing.ConfigFile))
@@ -266,10 +274,14 @@ func (m *Manager) Update(name string, req UpsertRequest) (*VPNProfile, error) {
 		return nil, err
 	}
 	profile.Warnings = append(profile.Warnings, prepared.warnings...)
+	if err := m.rewriteDependentUnitsLocked(validatedName, existing.InterfaceName, profile.InterfaceName); err != nil {
+		profile.Warnings = append(profile.Warnings, fmt.Sprintf("failed to update units of dependent VPNs: %v", err))
+	}
 	return profile, nil
 }
 
-// Delete removes a VPN profile.
+// Delete removes a VPN profile's unit and moves its directory to the trash,
+// releasing its route table and mark.
 func (m *Manager) Delete(name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -282,16 +294,22 @@ func (m *Manager) Delete(name string) error {
 	if err != nil {
 		return err
 	}
-	if m.units != nil {
-		if err := m.units.RemoveUnit(vpnServiceUnitName(validated)); err != nil {
-			return err
-		}
+	dependents, err := m.dependentsOfLocked(validated)
+	if err != nil {
+		return err
+	}
+	if len(dependents) > 0 {
+		return fmt.Errorf("%w: vpn %s is required by %s", ErrVPNValidation, validated, strings.Join(dependents, ", "))
+	}
+	if err := m.removeUnitLocked(validated); err != nil {
+		return err
 	}
-	if err := os.RemoveAll(filepath.Join(m.vpnsDir, validated)); err != nil {
+	if err := m.moveToTrashLocked(validated); err != nil {
 		return err
 	}
 	m.allocator.Release(profile.RouteTable, profile.FWMark)
-	return nil
+	// VPNs bound to the removed tunnel's interface lose their Requires= on it.
+	return m.rewriteDependentUnitsLocked(validated, profile.InterfaceName)
 }
 
 type preparedProfile struct {
@@ -305,6 +323,5 @@ type preparedProfile struct {
 	markReserved       uint32
 	releaseTable       int
 	releaseMark        uint32
-	unitName           string
-	unitContent        string
+	unitSpec           UnitSpec
 }
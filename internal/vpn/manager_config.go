@@ -0,0 +1,43 @@
+package vpn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WriteConfig replaces a profile's WireGuard/OpenVPN config, keeping every
+// other profile setting. It runs the same validation and allocation as
+// Update.
+func (m *Manager) WriteConfig(name, content string) (*VPNProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	validated, err := validateExistingName(name)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := m.readProfileLocked(validated)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("%w: vpn config must not be empty", ErrVPNValidation)
+	}
+	return m.updateLocked(validated, UpsertRequest{
+		Type:                   existing.Type,
+		Config:                 content,
+		ConfigFile:             existing.ConfigFile,
+		InterfaceName:          existing.InterfaceName,
+		BoundInterface:         existing.BoundInterface,
+		DependsOn:              existing.DependsOn,
+		UplinkVPN:              existing.UplinkVPN,
+		MSSClampV4:             existing.MSSClampV4,
+		MSSClampV6:             existing.MSSClampV6,
+		IPv6Policy:             existing.IPv6Policy,
+		IPv6Prefix:             existing.IPv6Prefix,
+		OnDemandIdleMinutes:    existing.OnDemandIdleMinutes,
+		LatencyProbe:           existing.LatencyProbe,
+		LatencyProbeTarget:     existing.LatencyProbeTarget,
+		EndpointRefreshSeconds: existing.EndpointRefreshSeconds,
+	})
+}
@@ -0,0 +1,64 @@
+package vpn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const writeConfigTestConfig = `[Interface]
+PrivateKey = test-private-key
+Address = 10.49.1.2/32
+
+[Peer]
+PublicKey = test-peer-key
+AllowedIPs = 0.0.0.0/0
+Endpoint = fra.contoso.com:51820
+`
+
+func TestManagerWriteConfigKeepsProfileSettings(t *testing.T) {
+	manager, _, _ := newTestManager(t)
+	created, err := manager.Create(UpsertRequest{
+		Name:       "wg-fra",
+		Type:       "wireguard",
+		Config:     writeConfigTestConfig,
+		MSSClampV4: "pmtu",
+		IPv6Policy: "native",
+		IPv6Prefix: "2001:db8:1234::/48",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	edited := strings.Replace(writeConfigTestConfig, "fra.contoso.com", "ams.contoso.com", 1)
+	updated, err := manager.WriteConfig("wg-fra", edited)
+	if err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+	if updated.MSSClampV4 != "pmtu" || updated.IPv6Policy != "native" || updated.IPv6Prefix != "2001:db8:1234::/48" {
+		t.Fatalf("WriteConfig dropped per-vpn settings: %+v", updated)
+	}
+	if updated.RouteTable != created.RouteTable || updated.FWMark != created.FWMark {
+		t.Fatalf("WriteConfig changed profile settings: %+v", updated)
+	}
+	if !strings.Contains(updated.RawConfig, "ams.contoso.com") {
+		t.Fatalf("config not written:\n%s", updated.RawConfig)
+	}
+}
+
+func TestManagerWriteConfigRejectsInvalidContent(t *testing.T) {
+	manager, _, _ := newTestManager(t)
+	if _, err := manager.Create(UpsertRequest{Name: "wg-fra", Type: "wireguard", Config: writeConfigTestConfig}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := manager.WriteConfig("wg-fra", "[Interface]\nAddress = 10.0.0.1/32\n"); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if _, err := manager.WriteConfig("wg-missing", writeConfigTestConfig); !errors.Is(err, ErrVPNNotFound) {
+		t.Fatalf("expected ErrVPNNotFound, got %v", err)
+	}
+	profile, err := manager.Get("wg-fra")
+	if err != nil || !strings.Contains(profile.RawConfig, "fra.contoso.com") {
+		t.Fatalf("rejected edit must leave the config untouched: %v", err)
+	}
+}
@@ -28,6 +28,22 @@ func (m *Manager) prepareProfileLocked(name string, req UpsertRequest, existing
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrVPNValidation, err)
 	}
+	bound, err := ValidateBoundInterface(req.BoundInterface)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVPNValidation, err)
+	}
+	ipv6Policy, ipv6Prefix, err := ValidateIPv6Policy(req.IPv6Policy, req.IPv6Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVPNValidation, err)
+	}
+	onDemandIdle, err := ValidateOnDemandIdleMinutes(req.OnDemandIdleMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVPNValidation, err)
+	}
+	latencyProbe, latencyProbeTarget, err := ValidateLatencyProbe(req.LatencyProbe, req.LatencyProbeTarget)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVPNValidation, err)
+	}
 
 	parsed, err := provider.ParseConfig(rawConfig)
 	if err != nil {
@@ -35,6 +51,10 @@ func (m *Manager) prepareProfileLocked(name string, req UpsertRequest, existing
 	}
 	parsed.Name = name
 	parsed.Type = vpnType
+	endpointRefresh, err := ValidateEndpointRefreshSeconds(vpnType, req.EndpointRefreshSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVPNValidation, err)
+	}
 
 	iface, err := resolveInterfaceName(req.InterfaceName, existing, parsed, name)
 	if err != nil {
@@ -43,6 +63,23 @@ func (m *Manager) prepareProfileLocked(name string, req UpsertRequest, existing
 	if err := m.ensureInterfaceUniqueLocked(name, iface, existing); err != nil {
 		return nil, err
 	}
+	parent, err := m.resolveUplinkLocked(name, req.UplinkVPN, bound)
+	if err != nil {
+		return nil, err
+	}
+	uplink := ""
+	if parent != nil {
+		uplink = parent.Name
+	}
+	dependsOn, unitDeps, err := m.validateDependsOnLocked(name, req.DependsOn, &VPNProfile{
+		Name:           name,
+		InterfaceName:  iface,
+		BoundInterface: bound,
+		UplinkVPN:      uplink,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	configFileName, err := resolveConfigFileName(req.ConfigFile, existing, name, vpnType, iface)
 	if err != nil {
@@ -86,6 +123,11 @@ func (m *Manager) prepareProfileLocked(name string, req UpsertRequest, existing
 			}
 			return nil, fmt.Errorf("%w: %v", ErrVPNValidation, err)
 		}
+		if parent != nil {
+			sanitizedConfig = withWireGuardFwMark(sanitizedConfig, parent.FWMark)
+		} else if existing != nil && existing.UplinkVPN != "" {
+			sanitizedConfig = withWireGuardFwMark(sanitizedConfig, 0)
+		}
 		if vpnType == "amneziawg" {
 			if parsed.AmneziaWG.IsEmpty() {
 				warnings = append(warnings, "No AmneziaWG obfuscation parameters set; the tunnel will behave like vanilla WireGuard")
@@ -119,21 +161,42 @@ func (m *Manager) prepareProfileLocked(name string, req UpsertRequest, existing
 			meta["VPN_ENDPOINT_IPV4"] = parsed.Gateway
 		}
 	}
-	bound := strings.TrimSpace(req.BoundInterface)
-	if bound == "" && existing != nil {
-		bound = existing.BoundInterface
-	}
+	// The bound interface and MSS clamp are authoritative from the request (the
+	// editor always submits the current values); empty values unbind the
+	// endpoint and disable clamping for that family.
 	if bound != "" {
 		meta["VPN_BOUND_IFACE"] = bound
 	}
-	// MSS clamp is authoritative from the request (the editor always submits the
-	// current value); an empty value disables clamping for that family.
+	if len(dependsOn) > 0 {
+		meta[dependsOnMetaKey] = strings.Join(dependsOn, ",")
+	}
+	if uplink != "" {
+		meta[uplinkMetaKey] = uplink
+	}
 	if mssV4 != "" {
 		meta["MSS_CLAMPING_IPV4"] = mssV4
 	}
 	if mssV6 != "" {
 		meta["MSS_CLAMPING_IPV6"] = mssV6
 	}
+	if ipv6Policy != "" {
+		meta["IPV6_POLICY"] = ipv6Policy
+	}
+	if ipv6Prefix != "" {
+		meta["IPV6_PREFIX"] = ipv6Prefix
+	}
+	if onDemandIdle > 0 {
+		meta[onDemandMetaKey] = strconv.Itoa(onDemandIdle)
+	}
+	if latencyProbe != "" {
+		meta[latencyProbeMetaKey] = latencyProbe
+	}
+	if latencyProbeTarget != "" {
+		meta[latencyProbeTargetMetaKey] = latencyProbeTarget
+	}
+	if endpointRefresh > 0 {
+		meta[endpointRefreshMetaKey] = strconv.Itoa(endpointRefresh)
+	}
 
 	unitProfile := &VPNProfile{
 		Name:          name,
@@ -152,8 +215,7 @@ func (m *Manager) prepareProfileLocked(name string, req UpsertRequest, existing
 		markReserved:            reservedMark,
 		releaseTable:            releaseTable,
 		releaseMark:             releaseMark,
-		unitName:                vpnServiceUnitName(name),
-		unitContent:             provider.GenerateUnit(unitProfile, m.dataDir),
+		unitSpec:                unitSpecFor(unitProfile, unitDeps, parent),
 	}, nil
 }
 
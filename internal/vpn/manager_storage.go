@@ -12,7 +12,13 @@ import (
 )
 
 func (m *Manager) readProfileLocked(name string) (*VPNProfile, error) {
-	values, err := parseVPNConf(filepath.Join(m.vpnsDir, name, "vpn.conf"))
+	return m.readProfileDir(filepath.Join(m.vpnsDir, name), name)
+}
+
+// readProfileDir reads the profile stored in dir under the given name; it
+// also reads profiles parked in the trash.
+func (m *Manager) readProfileDir(dir, name string) (*VPNProfile, error) {
+	values, err := parseVPNConf(filepath.Join(dir, "vpn.conf"))
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, fmt.Errorf("%w: %s", ErrVPNNotFound, name)
@@ -31,13 +37,13 @@ func (m *Manager) readProfileLocked(name string) (*VPNProfile, error) {
 
 	configFileName := strings.TrimSpace(values["CONFIG_FILE"])
 	if configFileName == "" {
-		configFileName, err = detectConfigFile(filepath.Join(m.vpnsDir, name), vpnType)
+		configFileName, err = detectConfigFile(dir, vpnType)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	rawConfigBytes, err := os.ReadFile(filepath.Join(m.vpnsDir, name, configFileName))
+	rawConfigBytes, err := os.ReadFile(filepath.Join(dir, configFileName))
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +57,7 @@ func (m *Manager) readProfileLocked(name string) (*VPNProfile, error) {
 	parsed.Type = vpnType
 	parsed.ConfigFile = configFileName
 	parsed.RawConfig = rawConfig
-	supportingFiles, err := listSupportingFiles(filepath.Join(m.vpnsDir, name), configFileName)
+	supportingFiles, err := listSupportingFiles(dir, configFileName)
 	if err != nil {
 		return nil, err
 	}
@@ -67,8 +73,15 @@ func (m *Manager) readProfileLocked(name string) (*VPNProfile, error) {
 		parsed.FWMark = mark
 	}
 	parsed.BoundInterface = strings.TrimSpace(values["VPN_BOUND_IFACE"])
+	parsed.DependsOn = parseDependsOn(values[dependsOnMetaKey])
+	parsed.UplinkVPN = strings.TrimSpace(values[uplinkMetaKey])
 	parsed.MSSClampV4 = strings.TrimSpace(values["MSS_CLAMPING_IPV4"])
 	parsed.MSSClampV6 = strings.TrimSpace(values["MSS_CLAMPING_IPV6"])
+	parsed.IPv6Policy = strings.TrimSpace(values["IPV6_POLICY"])
+	parsed.IPv6Prefix = strings.TrimSpace(values["IPV6_PREFIX"])
+	parsed.OnDemandIdleMinutes = parseOnDemandIdleMinutes(values[onDemandMetaKey])
+	parsed.LatencyProbe, parsed.LatencyProbeTarget = parseLatencyProbe(values)
+	parsed.EndpointRefreshSeconds = parseEndpointRefreshSeconds(values[endpointRefreshMetaKey])
 	if endpointV4 := strings.TrimSpace(values["VPN_ENDPOINT_IPV4"]); endpointV4 != "" {
 		parsed.Gateway = endpointV4
 	} else if endpointV6 := strings.TrimSpace(values["VPN_ENDPOINT_IPV6"]); endpointV6 != "" {
@@ -138,6 +151,12 @@ func renderVPNConf(meta VPNMeta) string {
 		"VPN_BOUND_IFACE",
 		"MSS_CLAMPING_IPV4",
 		"MSS_CLAMPING_IPV6",
+		"IPV6_POLICY",
+		"IPV6_PREFIX",
+		onDemandMetaKey,
+		latencyProbeMetaKey,
+		latencyProbeTargetMetaKey,
+		endpointRefreshMetaKey,
 		"CONFIG_FILE",
 	}
 	lines := make([]string, 0, len(order)+2)
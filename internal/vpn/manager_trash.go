@@ -0,0 +1,212 @@
+package vpn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTrashedVPNNotFound indicates a missing trash entry.
+var ErrTrashedVPNNotFound = errors.New("trashed vpn not found")
+
+// TrashedVPN is a deleted profile kept in the trash. Its directory is parked
+// under <data>/trash/vpns/<deleted-at>-<name> with the config, supporting
+// files and vpn.conf intact; its unit, route table and mark are released.
+type TrashedVPN struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	InterfaceName string   `json:"interfaceName"`
+	Gateway       string   `json:"gateway,omitempty"`
+	DependsOn     []string `json:"dependsOn,omitempty"`
+	DeletedAt     int64    `json:"deletedAt"`
+}
+
+func (m *Manager) trashDir() string {
+	return filepath.Join(m.dataDir, "trash", "vpns")
+}
+
+func (m *Manager) moveToTrashLocked(name string) error {
+	trashDir := m.trashDir()
+	if err := os.MkdirAll(trashDir, 0o700); err != nil {
+		return err
+	}
+	id := fmt.Sprintf("%d-%s", time.Now().Unix(), name)
+	target := filepath.Join(trashDir, id)
+	// A profile deleted twice within a second replaces the older copy.
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(m.vpnsDir, name), target)
+}
+
+// parseTrashID splits a trash entry id into its deletion time and name.
+func parseTrashID(id string) (int64, string, error) {
+	stamp, name, ok := strings.Cut(strings.TrimSpace(id), "-")
+	if !ok {
+		return 0, "", fmt.Errorf("%w: invalid trash id %q", ErrVPNValidation, id)
+	}
+	deletedAt, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil || deletedAt <= 0 {
+		return 0, "", fmt.Errorf("%w: invalid trash id %q", ErrVPNValidation, id)
+	}
+	if err := ValidateName(name); err != nil {
+		return 0, "", fmt.Errorf("%w: invalid trash id %q", ErrVPNValidation, id)
+	}
+	return deletedAt, name, nil
+}
+
+// ListTrash returns trashed profiles, most recently deleted first. Entries
+// that can no longer be read are skipped.
+func (m *Manager) ListTrash() ([]TrashedVPN, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listTrashLocked()
+}
+
+func (m *Manager) listTrashLocked() ([]TrashedVPN, error) {
+	entries, err := os.ReadDir(m.trashDir())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []TrashedVPN{}, nil
+		}
+		return nil, err
+	}
+	out := make([]TrashedVPN, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		deletedAt, name, err := parseTrashID(entry.Name())
+		if err != nil {
+			continue
+		}
+		trashed := TrashedVPN{ID: entry.Name(), Name: name, DeletedAt: deletedAt}
+		if profile, err := m.readProfileDir(filepath.Join(m.trashDir(), entry.Name()), name); err == nil {
+			trashed.Type = profile.Type
+			trashed.InterfaceName = profile.InterfaceName
+			trashed.Gateway = profile.Gateway
+			trashed.DependsOn = profile.DependsOn
+		}
+		out = append(out, trashed)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].DeletedAt != out[j].DeletedAt {
+			return out[i].DeletedAt > out[j].DeletedAt
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// RestoreTrash recreates a trashed profile under its original name. It gets
+// a fresh route table and mark, and its unit is written again but not
+// started. The trash entry is removed once the profile is back.
+func (m *Manager) RestoreTrash(id string) (*VPNProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, name, err := m.trashEntryDirLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	trashed, err := m.readProfileDir(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	uploads := make(map[string][]byte, len(trashed.SupportingFiles))
+	for _, fileName := range trashed.SupportingFiles {
+		content, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+		if len(content) == 0 {
+			continue
+		}
+		uploads[fileName] = content
+	}
+	profile, err := m.createLocked(UpsertRequest{
+		Name:                   name,
+		Type:                   trashed.Type,
+		Config:                 trashed.RawConfig,
+		ConfigFile:             trashed.ConfigFile,
+		InterfaceName:          trashed.InterfaceName,
+		BoundInterface:         trashed.BoundInterface,
+		DependsOn:              trashed.DependsOn,
+		UplinkVPN:              trashed.UplinkVPN,
+		MSSClampV4:             trashed.MSSClampV4,
+		MSSClampV6:             trashed.MSSClampV6,
+		IPv6Policy:             trashed.IPv6Policy,
+		IPv6Prefix:             trashed.IPv6Prefix,
+		OnDemandIdleMinutes:    trashed.OnDemandIdleMinutes,
+		LatencyProbe:           trashed.LatencyProbe,
+		LatencyProbeTarget:     trashed.LatencyProbeTarget,
+		EndpointRefreshSeconds: trashed.EndpointRefreshSeconds,
+	}, uploads)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		profile.Warnings = append(profile.Warnings, fmt.Sprintf("failed to remove trash entry: %v", err))
+	}
+	return profile, nil
+}
+
+// DeleteTrash permanently removes a trashed profile.
+func (m *Manager) DeleteTrash(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, _, err := m.trashEntryDirLocked(id)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// PurgeTrash permanently removes profiles trashed before cutoff and returns
+// how many were removed.
+func (m *Manager) PurgeTrash(cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := m.listTrashLocked()
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, entry := range entries {
+		if entry.DeletedAt >= cutoff.Unix() {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(m.trashDir(), entry.ID)); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (m *Manager) trashEntryDirLocked(id string) (string, string, error) {
+	_, name, err := parseTrashID(id)
+	if err != nil {
+		return "", "", err
+	}
+	dir := filepath.Join(m.trashDir(), strings.TrimSpace(id))
+	info, err := os.Stat(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", "", fmt.Errorf("%w: %s", ErrTrashedVPNNotFound, id)
+		}
+		return "", "", err
+	}
+	if !info.IsDir() {
+		return "", "", fmt.Errorf("%w: %s", ErrTrashedVPNNotFound, id)
+	}
+	return dir, name, nil
+}
@@ -0,0 +1,90 @@
+package vpn
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManagerDeleteMovesProfileToTrashAndRestores(t *testing.T) {
+	manager, _, unitManager := newTestManager(t)
+
+	config := `[Interface]
+PrivateKey = test-private-key
+Address = 10.49.1.2/32
+
+[Peer]
+PublicKey = test-peer-key
+AllowedIPs = 0.0.0.0/0
+Endpoint = vpn.contoso.com:51820
+`
+	created, err := manager.Create(UpsertRequest{Name: "wg-sgp", Type: "wireguard", Config: config, MSSClampV4: "1360"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := manager.Delete("wg-sgp"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := manager.Get("wg-sgp"); !errors.Is(err, ErrVPNNotFound) {
+		t.Fatalf("expected ErrVPNNotFound after delete, got %v", err)
+	}
+
+	trash, err := manager.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(trash) != 1 || trash[0].Name != "wg-sgp" || trash[0].Type != "wireguard" || trash[0].DeletedAt == 0 {
+		t.Fatalf("unexpected trash: %#v", trash)
+	}
+
+	writes := unitManager.writeCalls
+	restored, err := manager.RestoreTrash(trash[0].ID)
+	if err != nil {
+		t.Fatalf("RestoreTrash failed: %v", err)
+	}
+	if restored.InterfaceName != created.InterfaceName || restored.MSSClampV4 != "1360" {
+		t.Fatalf("unexpected restored profile: %#v", restored)
+	}
+	if unitManager.writeCalls != writes+1 {
+		t.Fatalf("expected restore to rewrite the unit")
+	}
+	if trash, err := manager.ListTrash(); err != nil || len(trash) != 0 {
+		t.Fatalf("expected empty trash after restore, got %#v err=%v", trash, err)
+	}
+	if _, err := manager.RestoreTrash(trash[0].ID); !errors.Is(err, ErrTrashedVPNNotFound) {
+		t.Fatalf("expected ErrTrashedVPNNotFound, got %v", err)
+	}
+	if _, err := manager.RestoreTrash("../wg-sgp"); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected ErrVPNValidation for invalid id, got %v", err)
+	}
+}
+
+func TestManagerPurgeTrashRemovesExpiredEntries(t *testing.T) {
+	manager, _, _ := newTestManager(t)
+
+	config := `[Interface]
+PrivateKey = test-private-key
+Address = 10.49.1.2/32
+
+[Peer]
+PublicKey = test-peer-key
+AllowedIPs = 0.0.0.0/0
+Endpoint = vpn.contoso.com:51820
+`
+	if _, err := manager.Create(UpsertRequest{Name: "wg-old", Type: "wireguard", Config: config}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := manager.Delete("wg-old"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if purged, err := manager.PurgeTrash(time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Fatalf("expected nothing purged before retention, got %d err=%v", purged, err)
+	}
+	if purged, err := manager.PurgeTrash(time.Now().Add(time.Hour)); err != nil || purged != 1 {
+		t.Fatalf("expected one purged entry, got %d err=%v", purged, err)
+	}
+	if trash, err := manager.ListTrash(); err != nil || len(trash) != 0 {
+		t.Fatalf("expected empty trash after purge, got %#v err=%v", trash, err)
+	}
+}
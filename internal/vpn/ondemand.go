@@ -0,0 +1,30 @@
+package vpn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	onDemandMetaKey = "VPN_ON_DEMAND_IDLE_MINUTES"
+	// maxOnDemandIdleMinutes bounds the idle timeout of on-demand VPNs.
+	maxOnDemandIdleMinutes = 24 * 60
+)
+
+// ValidateOnDemandIdleMinutes checks the idle timeout of an on-demand VPN.
+// Zero turns on-demand mode off.
+func ValidateOnDemandIdleMinutes(minutes int) (int, error) {
+	if minutes < 0 || minutes > maxOnDemandIdleMinutes {
+		return 0, fmt.Errorf("on-demand idle timeout must be between 0 and %d minutes", maxOnDemandIdleMinutes)
+	}
+	return minutes, nil
+}
+
+func parseOnDemandIdleMinutes(raw string) int {
+	minutes, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || minutes <= 0 || minutes > maxOnDemandIdleMinutes {
+		return 0
+	}
+	return minutes
+}
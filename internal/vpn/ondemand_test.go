@@ -0,0 +1,37 @@
+package vpn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestManagerOnDemandIdleMinutes(t *testing.T) {
+	manager, _, _ := newTestManager(t)
+	config := "[Interface]\nPrivateKey = test\nAddress = 10.0.0.2/32\n[Peer]\nPublicKey = peer\nAllowedIPs = 0.0.0.0/0\nEndpoint = vpn.example.com:51820\n"
+
+	if _, err := manager.Create(UpsertRequest{Name: "wg-lazy", Type: "wireguard", Config: config, OnDemandIdleMinutes: -1}); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected negative idle timeout to be rejected, got %v", err)
+	}
+	created, err := manager.Create(UpsertRequest{Name: "wg-lazy", Type: "wireguard", Config: config, OnDemandIdleMinutes: 15})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.OnDemandIdleMinutes != 15 {
+		t.Fatalf("expected idle timeout 15, got %d", created.OnDemandIdleMinutes)
+	}
+	loaded, err := manager.Get("wg-lazy")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if loaded.OnDemandIdleMinutes != 15 {
+		t.Fatalf("idle timeout not persisted, got %d", loaded.OnDemandIdleMinutes)
+	}
+
+	updated, err := manager.Update("wg-lazy", UpsertRequest{Config: config})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.OnDemandIdleMinutes != 0 {
+		t.Fatalf("expected on-demand mode off after update without timeout, got %d", updated.OnDemandIdleMinutes)
+	}
+}
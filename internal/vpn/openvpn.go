@@ -38,7 +38,7 @@ func (p *OpenVPNProvider) ParseConfig(raw string) (*VPNProfile, error) {
 	}, nil
 }
 
-func (p *OpenVPNProvider) GenerateUnit(profile *VPNProfile, dataDir string) string {
+func (p *OpenVPNProvider) GenerateUnit(profile *VPNProfile, dataDir, vpnsDir string) string {
 	if profile == nil {
 		return ""
 	}
@@ -54,7 +54,7 @@ func (p *OpenVPNProvider) GenerateUnit(profile *VPNProfile, dataDir string) stri
 	if iface == "" {
 		iface = "tun0"
 	}
-	configPath := filepath.Join(dataDir, "vpns", name, fileName)
+	configPath := filepath.Join(vpnsDir, name, fileName)
 	return fmt.Sprintf(`[Unit]
 Description=split-vpn-webui OpenVPN tunnel (%s)
 After=network-online.target
@@ -108,7 +108,7 @@ abc
 
 func TestOpenVPNGenerateUnit(t *testing.T) {
 	provider := NewOpenVPNProvider()
-	unit := provider.GenerateUnit(&VPNProfile{Name: "ovpn-web", ConfigFile: "DreamMachine.ovpn", InterfaceName: "tun1"}, "/data/split-vpn-webui")
+	unit := provider.GenerateUnit(&VPNProfile{Name: "ovpn-web", ConfigFile: "DreamMachine.ovpn", InterfaceName: "tun1"}, "/data/split-vpn-webui", "/data/split-vpn-webui/vpns")
 
 	checks := []string{
 		"Description=split-vpn-webui OpenVPN tunnel (ovpn-web)",
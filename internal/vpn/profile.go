@@ -1,27 +1,65 @@
 package vpn
 
+import (
+	"net/netip"
+	"strings"
+)
+
 // VPNMeta stores key-value metadata persisted in vpn.conf.
 type VPNMeta map[string]string
 
 // VPNProfile is a normalized representation of a managed VPN profile.
 type VPNProfile struct {
-	Name            string           `json:"name"`
-	Type            string           `json:"type"`
-	RawConfig       string           `json:"rawConfig"`
-	ConfigFile      string           `json:"configFile"`
-	SupportingFiles []string         `json:"supportingFiles,omitempty"`
-	RouteTable      int              `json:"routeTable"`
-	FWMark          uint32           `json:"fwMark"`
-	InterfaceName   string           `json:"interfaceName"`
-	Gateway         string           `json:"gateway"`
-	BoundInterface  string           `json:"boundInterface"`
-	MSSClampV4      string           `json:"mssClampV4"`
-	MSSClampV6      string           `json:"mssClampV6"`
-	Meta            VPNMeta          `json:"meta"`
-	Warnings        []string         `json:"warnings,omitempty"`
-	WireGuard       *WireGuardConfig `json:"wireguard,omitempty"`
-	OpenVPN         *OpenVPNConfig   `json:"openvpn,omitempty"`
-	AmneziaWG       *AmneziaWGParams `json:"amneziawg,omitempty"`
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	RawConfig       string   `json:"rawConfig"`
+	ConfigFile      string   `json:"configFile"`
+	SupportingFiles []string `json:"supportingFiles,omitempty"`
+	RouteTable      int      `json:"routeTable"`
+	FWMark          uint32   `json:"fwMark"`
+	InterfaceName   string   `json:"interfaceName"`
+	Gateway         string   `json:"gateway"`
+	BoundInterface  string   `json:"boundInterface"`
+	DependsOn       []string `json:"dependsOn"`
+	UplinkVPN       string   `json:"uplinkVpn"`
+	MSSClampV4      string   `json:"mssClampV4"`
+	MSSClampV6      string   `json:"mssClampV6"`
+	IPv6Policy      string   `json:"ipv6Policy"`
+	IPv6Prefix      string   `json:"ipv6Prefix,omitempty"`
+	// OnDemandIdleMinutes, when positive, keeps the VPN down until traffic
+	// for its groups appears and stops it after that many idle minutes.
+	OnDemandIdleMinutes int              `json:"onDemandIdleMinutes,omitempty"`
+	Meta                VPNMeta          `json:"meta"`
+	Warnings            []string         `json:"warnings,omitempty"`
+	WireGuard           *WireGuardConfig `json:"wireguard,omitempty"`
+	OpenVPN             *OpenVPNConfig   `json:"openvpn,omitempty"`
+	AmneziaWG           *AmneziaWGParams `json:"amneziawg,omitempty"`
+	// LatencyProbe is how the latency monitor measures the tunnel: empty
+	// for ICMP, "https" or "dns"; see ValidateLatencyProbe.
+	LatencyProbe       string `json:"latencyProbe,omitempty"`
+	LatencyProbeTarget string `json:"latencyProbeTarget,omitempty"`
+	// EndpointRefreshSeconds, when positive, resolves the peer endpoint
+	// host names again at that interval and moves the running tunnel to a
+	// changed address; see ValidateEndpointRefreshSeconds.
+	EndpointRefreshSeconds int `json:"endpointRefreshSeconds,omitempty"`
+}
+
+// DNSServers returns the resolver addresses pushed by the profile. Only
+// WireGuard configs carry them; DNS entries that are not addresses are
+// wg-quick search domains and are skipped.
+func (p *VPNProfile) DNSServers() []netip.Addr {
+	if p == nil || p.WireGuard == nil {
+		return nil
+	}
+	servers := make([]netip.Addr, 0, len(p.WireGuard.Interface.DNS))
+	for _, entry := range p.WireGuard.Interface.DNS {
+		addr, err := netip.ParseAddr(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+		servers = append(servers, addr.Unmap())
+	}
+	return servers
 }
 
 // WireGuardConfig captures parsed fields from a WireGuard config.
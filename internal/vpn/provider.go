@@ -5,5 +5,8 @@ type Provider interface {
 	Type() string
 	ValidateConfig(raw string) error
 	ParseConfig(raw string) (*VPNProfile, error)
-	GenerateUnit(profile *VPNProfile, dataDir string) string
+	// GenerateUnit renders the systemd unit. The tunnel reads its config
+	// from vpnsDir, which is <dataDir>/vpns unless the privilege helper
+	// installed a root-owned copy.
+	GenerateUnit(profile *VPNProfile, dataDir, vpnsDir string) string
 }
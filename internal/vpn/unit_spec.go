@@ -0,0 +1,246 @@
+package vpn
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UnitSpec is everything a VPN's systemd unit is generated from. An
+// unprivileged web UI sends it to the privilege helper in place of unit
+// text, so it can only ask for units this package would write.
+type UnitSpec struct {
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	ConfigFile string      `json:"configFile"`
+	Interface  string      `json:"interface"`
+	DependsOn  []string    `json:"dependsOn,omitempty"`
+	Uplink     *UplinkSpec `json:"uplink,omitempty"`
+}
+
+// UplinkSpec is the parent VPN a nested tunnel reaches its endpoint through.
+type UplinkSpec struct {
+	Interface  string `json:"interface,omitempty"`
+	FWMark     uint32 `json:"fwmark"`
+	RouteTable int    `json:"routeTable"`
+}
+
+// UnitWriter installs VPN units generated from a spec. The privilege helper
+// client is one: it hands the spec and the VPN's files to the helper, which
+// writes root-owned copies the unit starts from.
+type UnitWriter interface {
+	WriteVPNUnit(spec UnitSpec, files map[string][]byte) error
+	RemoveVPNUnit(name string) error
+}
+
+// reservedRouteTables are the kernel's default, main and local tables.
+var reservedRouteTables = map[int]bool{253: true, 254: true, 255: true}
+
+func unitSpecFor(profile *VPNProfile, deps []string, parent *VPNProfile) UnitSpec {
+	spec := UnitSpec{
+		Name:       profile.Name,
+		Type:       profile.Type,
+		ConfigFile: profile.ConfigFile,
+		Interface:  profile.InterfaceName,
+		DependsOn:  append([]string(nil), deps...),
+	}
+	if parent != nil {
+		spec.Uplink = &UplinkSpec{
+			Interface:  parent.InterfaceName,
+			FWMark:     parent.FWMark,
+			RouteTable: parent.RouteTable,
+		}
+	}
+	return spec
+}
+
+// writeUnitLocked installs the unit for spec: through the unit writer when
+// one is set, handing it the files in the VPN's directory, and otherwise
+// rendered here and written with the unit manager.
+func (m *Manager) writeUnitLocked(spec UnitSpec) error {
+	if m.writer != nil {
+		files, err := m.profileFilesLocked(spec.Name)
+		if err != nil {
+			return err
+		}
+		return m.writer.WriteVPNUnit(spec, files)
+	}
+	if m.units == nil {
+		return nil
+	}
+	content, err := RenderUnit(spec, m.dataDir, m.vpnsDir)
+	if err != nil {
+		return err
+	}
+	return m.units.WriteUnit(vpnServiceUnitName(spec.Name), content)
+}
+
+func (m *Manager) removeUnitLocked(name string) error {
+	if m.writer != nil {
+		return m.writer.RemoveVPNUnit(name)
+	}
+	if m.units == nil {
+		return nil
+	}
+	return m.units.RemoveUnit(vpnServiceUnitName(name))
+}
+
+// profileFilesLocked reads the regular files in a VPN's directory: its
+// config, vpn.conf and any supporting files.
+func (m *Manager) profileFilesLocked(name string) (map[string][]byte, error) {
+	dir := filepath.Join(m.vpnsDir, name)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[entry.Name()] = content
+	}
+	return files, nil
+}
+
+// Validate checks every value that ends up in the generated unit.
+func (s UnitSpec) Validate() error {
+	if err := ValidateName(s.Name); err != nil {
+		return err
+	}
+	if _, ok := defaultProviders()[s.Type]; !ok {
+		return fmt.Errorf("unsupported vpn type %q", s.Type)
+	}
+	if err := validateInterfaceName(s.Interface); err != nil {
+		return err
+	}
+	if !configFilePattern.MatchString(s.ConfigFile) {
+		return fmt.Errorf("config file name %q is invalid", s.ConfigFile)
+	}
+	if isWireGuardLike(s.Type) && s.ConfigFile != s.Interface+".conf" {
+		return fmt.Errorf("config file %q does not match interface %q", s.ConfigFile, s.Interface)
+	}
+	for _, dep := range s.DependsOn {
+		if err := ValidateName(dep); err != nil {
+			return fmt.Errorf("dependency: %v", err)
+		}
+		if dep == s.Name {
+			return fmt.Errorf("vpn %s cannot depend on itself", s.Name)
+		}
+	}
+	if s.Uplink == nil {
+		return nil
+	}
+	if s.Uplink.Interface != "" && !ifacePattern.MatchString(s.Uplink.Interface) {
+		return fmt.Errorf("uplink interface %q is not a valid interface name", s.Uplink.Interface)
+	}
+	if s.Uplink.FWMark < minFWMark || s.Uplink.FWMark > maxFWMark {
+		return fmt.Errorf("uplink fwmark 0x%x is out of range", s.Uplink.FWMark)
+	}
+	if s.Uplink.RouteTable < minRouteTableID || s.Uplink.RouteTable > maxRouteTableID || reservedRouteTables[s.Uplink.RouteTable] {
+		return fmt.Errorf("uplink route table %d is out of range", s.Uplink.RouteTable)
+	}
+	return nil
+}
+
+// RenderUnit validates spec and generates its unit. The tunnel reads its
+// config from vpnsDir.
+func RenderUnit(spec UnitSpec, dataDir, vpnsDir string) (string, error) {
+	if err := spec.Validate(); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrVPNValidation, err)
+	}
+	profile := &VPNProfile{
+		Name:          spec.Name,
+		Type:          spec.Type,
+		ConfigFile:    spec.ConfigFile,
+		InterfaceName: spec.Interface,
+	}
+	unit := withUnitDependencies(defaultProviders()[spec.Type].GenerateUnit(profile, dataDir, vpnsDir), spec.DependsOn)
+	if spec.Uplink != nil {
+		unit = withUplinkUnit(unit, profile, &VPNProfile{
+			InterfaceName: spec.Uplink.Interface,
+			FWMark:        spec.Uplink.FWMark,
+			RouteTable:    spec.Uplink.RouteTable,
+		})
+	}
+	return unit, nil
+}
+
+// wireGuardHookKeys are the wg-quick keys whose values run as shell commands.
+var wireGuardHookKeys = map[string]bool{"preup": true, "postup": true, "predown": true, "postdown": true}
+
+// rootUnsafeOpenVPNDirectives run commands, load code into the openvpn
+// process, or read and write files at paths the config chooses.
+var rootUnsafeOpenVPNDirectives = map[string]bool{
+	"up": true, "down": true, "route-up": true, "route-pre-down": true, "ipchange": true,
+	"tls-verify": true, "tls-crypt-v2-verify": true, "client-connect": true, "client-disconnect": true,
+	"learn-address": true, "auth-user-pass-verify": true, "iproute": true, "script-security": true,
+	"plugin": true, "engine": true, "providers": true, "pkcs11-providers": true,
+	"config": true, "cd": true, "chroot": true, "daemon": true, "management": true,
+	"log": true, "log-append": true, "writepid": true, "status": true, "replay-persist": true,
+	"tmp-dir": true, "tls-export-cert": true, "ifconfig-pool-persist": true,
+	"askpass": true, "capath": true, "dh": true, "extra-certs": true, "auth-gen-token-secret": true,
+}
+
+// ValidateTunnelConfig checks a config the privilege helper is about to
+// start as root on the web UI's behalf. WireGuard hooks and OpenVPN
+// directives that run commands or touch other files are rejected. It
+// returns the supporting files an OpenVPN config reads.
+func ValidateTunnelConfig(vpnType, raw string) ([]string, error) {
+	provider, ok := defaultProviders()[vpnType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported vpn type %q", vpnType)
+	}
+	parsed, err := provider.ParseConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	if isWireGuardLike(vpnType) {
+		for _, line := range strings.Split(raw, "\n") {
+			key, _, ok := splitINIKeyValue(strings.TrimSpace(line))
+			if ok && wireGuardHookKeys[strings.ToLower(strings.TrimSpace(key))] {
+				return nil, fmt.Errorf("%s hooks run as root and are not allowed when the web UI runs unprivileged", strings.TrimSpace(key))
+			}
+		}
+		return nil, nil
+	}
+	for directive, values := range parsed.OpenVPN.Directives {
+		if err := checkRootOpenVPNDirective(directive, values); err != nil {
+			return nil, err
+		}
+	}
+	for _, line := range strings.Split(parsed.OpenVPN.InlineBlocks["connection"], "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+		if err := checkRootOpenVPNDirective(strings.ToLower(fields[0]), []string{strings.Join(fields[1:], " ")}); err != nil {
+			return nil, err
+		}
+	}
+	return requiredOpenVPNFiles(parsed.OpenVPN)
+}
+
+func checkRootOpenVPNDirective(directive string, values []string) error {
+	key := strings.TrimPrefix(directive, "--")
+	if rootUnsafeOpenVPNDirectives[key] {
+		return fmt.Errorf("openvpn directive %q is not allowed when the web UI runs unprivileged", key)
+	}
+	if key != "http-proxy" {
+		return nil
+	}
+	// http-proxy host port [authfile|auto|auto-nct] [method]: an auth file
+	// would be read as root and sent to the proxy.
+	for _, value := range values {
+		fields := strings.Fields(value)
+		if len(fields) > 2 && fields[2] != "auto" && fields[2] != "auto-nct" {
+			return fmt.Errorf("openvpn http-proxy auth files are not allowed when the web UI runs unprivileged")
+		}
+	}
+	return nil
+}
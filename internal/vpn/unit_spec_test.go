@@ -0,0 +1,131 @@
+package vpn
+
+import (
+	"strings"
+	"testing"
+)
+
+type testUnitWriter struct {
+	specs   map[string]UnitSpec
+	files   map[string]map[string][]byte
+	removed []string
+}
+
+func (w *testUnitWriter) WriteVPNUnit(spec UnitSpec, files map[string][]byte) error {
+	if w.specs == nil {
+		w.specs = map[string]UnitSpec{}
+		w.files = map[string]map[string][]byte{}
+	}
+	w.specs[spec.Name] = spec
+	w.files[spec.Name] = files
+	return nil
+}
+
+func (w *testUnitWriter) RemoveVPNUnit(name string) error {
+	w.removed = append(w.removed, name)
+	return nil
+}
+
+func TestManagerHandsSpecToUnitWriter(t *testing.T) {
+	manager, _, units := newTestManager(t)
+	writer := &testUnitWriter{}
+	manager.SetUnitWriter(writer)
+	config := "[Interface]\nPrivateKey = test\nAddress = 10.0.0.2/32\n[Peer]\nPublicKey = peer\nAllowedIPs = 0.0.0.0/0\nEndpoint = office:51820\n"
+
+	created, err := manager.Create(UpsertRequest{Name: "wg-office", Type: "wireguard", Config: config})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(units.written) != 0 {
+		t.Fatalf("unit manager bypassed the unit writer: %v", units.written)
+	}
+	spec := writer.specs["wg-office"]
+	if spec.Type != "wireguard" || spec.Interface != created.InterfaceName || spec.ConfigFile != created.InterfaceName+".conf" {
+		t.Fatalf("unexpected spec %+v", spec)
+	}
+	files := writer.files["wg-office"]
+	if string(files[spec.ConfigFile]) != created.RawConfig || len(files["vpn.conf"]) == 0 {
+		t.Fatalf("writer did not get the vpn's files: %v", files)
+	}
+	if err := manager.Delete("wg-office"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if len(writer.removed) != 1 || writer.removed[0] != "wg-office" {
+		t.Fatalf("unexpected removals %v", writer.removed)
+	}
+}
+
+func TestRenderUnitRunsFromVPNsDir(t *testing.T) {
+	spec := UnitSpec{
+		Name:       "wg-inner",
+		Type:       "wireguard",
+		ConfigFile: "wg-sv-inner.conf",
+		Interface:  "wg-sv-inner",
+		DependsOn:  []string{"wg-outer"},
+		Uplink:     &UplinkSpec{Interface: "wg-sv-outer", FWMark: 0x169, RouteTable: 201},
+	}
+	unit, err := RenderUnit(spec, "/data/split-vpn-webui", "/data/split-vpn-webui/units/vpns")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	for _, want := range []string{
+		"/data/split-vpn-webui/units/vpns/wg-inner/wg-sv-inner.conf",
+		"Requires=svpn-wg-outer.service",
+		"ip rule add fwmark 0x169 table 201 priority 99",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Fatalf("unit missing %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestUnitSpecValidateRejectsUnsafeValues(t *testing.T) {
+	base := UnitSpec{Name: "wg-office", Type: "wireguard", ConfigFile: "wg-sv-office.conf", Interface: "wg-sv-office"}
+	for name, mutate := range map[string]func(*UnitSpec){
+		"type":            func(s *UnitSpec) { s.Type = "shell" },
+		"name":            func(s *UnitSpec) { s.Name = "../etc" },
+		"config path":     func(s *UnitSpec) { s.ConfigFile = "../../etc/shadow" },
+		"config mismatch": func(s *UnitSpec) { s.ConfigFile = "other.conf" },
+		"interface":       func(s *UnitSpec) { s.Interface = "wg sv\nExecStart=/bin/sh" },
+		"dependency":      func(s *UnitSpec) { s.DependsOn = []string{"a b"} },
+		"self dependency": func(s *UnitSpec) { s.DependsOn = []string{"wg-office"} },
+		"uplink table":    func(s *UnitSpec) { s.Uplink = &UplinkSpec{FWMark: 0x169, RouteTable: 254} },
+		"uplink mark":     func(s *UnitSpec) { s.Uplink = &UplinkSpec{FWMark: 0, RouteTable: 201} },
+	} {
+		spec := base
+		mutate(&spec)
+		if err := spec.Validate(); err == nil {
+			t.Errorf("%s: expected spec to be rejected", name)
+		}
+	}
+	if err := base.Validate(); err != nil {
+		t.Fatalf("expected base spec to be valid: %v", err)
+	}
+}
+
+func TestValidateTunnelConfigRejectsRootCommands(t *testing.T) {
+	wg := "[Interface]\nPrivateKey = test\nAddress = 10.0.0.2/32\n%s[Peer]\nPublicKey = peer\nAllowedIPs = 0.0.0.0/0\nEndpoint = office:51820\n"
+	if _, err := ValidateTunnelConfig("wireguard", strings.Replace(wg, "%s", "", 1)); err != nil {
+		t.Fatalf("expected plain wireguard config to pass: %v", err)
+	}
+	if _, err := ValidateTunnelConfig("amneziawg", strings.Replace(wg, "%s", "PostUp = id > /tmp/x\n", 1)); err == nil {
+		t.Fatalf("expected PostUp hook to be rejected")
+	}
+
+	ovpn := "client\nremote 198.51.100.1 1194\ndev tun\nca ca.crt\n%s"
+	required, err := ValidateTunnelConfig("openvpn", strings.Replace(ovpn, "%s", "http-proxy proxy.example 8080 auto\n", 1))
+	if err != nil || len(required) != 1 || required[0] != "ca.crt" {
+		t.Fatalf("expected ca.crt to be required, got %v: %v", required, err)
+	}
+	for _, directive := range []string{
+		"up /tmp/x\n",
+		"plugin /tmp/evil.so\n",
+		"log /etc/cron.d/x\n",
+		"http-proxy proxy.example 8080 /etc/shadow\n",
+		"<connection>\nremote 198.51.100.2 1194\nroute-up /tmp/x\n</connection>\n",
+	} {
+		if _, err := ValidateTunnelConfig("openvpn", strings.Replace(ovpn, "%s", directive, 1)); err == nil {
+			t.Errorf("expected %q to be rejected", strings.TrimSpace(directive))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package vpn
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	uplinkMetaKey = "VPN_UPLINK"
+	// uplinkRulePriority sits just ahead of the routing manager's fwmark rules
+	// (priority 100), which it flushes on every apply, so a nested tunnel keeps
+	// reaching its endpoint through the parent even when no routing group
+	// uses the parent VPN.
+	uplinkRulePriority = 99
+)
+
+// resolveUplinkLocked validates the requested uplink VPN and returns its
+// profile. A nested tunnel sends its encapsulated traffic with the parent's
+// fwmark so it leaves through the parent's route table.
+func (m *Manager) resolveUplinkLocked(name, requested, bound string) (*VPNProfile, error) {
+	uplink := strings.TrimSpace(requested)
+	if uplink == "" {
+		return nil, nil
+	}
+	if uplink == name {
+		return nil, fmt.Errorf("%w: vpn %s cannot use itself as uplink", ErrVPNValidation, name)
+	}
+	if bound != "" {
+		return nil, fmt.Errorf("%w: uplink vpn and bound interface are mutually exclusive", ErrVPNValidation)
+	}
+	if err := ValidateName(uplink); err != nil {
+		return nil, fmt.Errorf("%w: uplink %v", ErrVPNValidation, err)
+	}
+	parent, err := m.readProfileLocked(uplink)
+	if err != nil {
+		if errors.Is(err, ErrVPNNotFound) {
+			return nil, fmt.Errorf("%w: uplink %q is not a managed vpn", ErrVPNValidation, uplink)
+		}
+		return nil, err
+	}
+	if parent.RouteTable <= 0 || parent.FWMark < minFWMark {
+		return nil, fmt.Errorf("%w: uplink %s has no route table or fwmark allocated", ErrVPNValidation, uplink)
+	}
+	return parent, nil
+}
+
+// withUplinkUnit adds the policy rule that sends packets carrying the
+// parent's fwmark to the parent's route table and relaxes reverse-path
+// filtering on the parent interface, where the nested tunnel's replies
+// arrive. OpenVPN marks its socket from the command line; WireGuard-based
+// tunnels carry FwMark in their config instead (see withWireGuardFwMark).
+func withUplinkUnit(unit string, profile *VPNProfile, parent *VPNProfile) string {
+	if parent == nil || profile == nil {
+		return unit
+	}
+	ruleArgs := fmt.Sprintf("fwmark 0x%x table %d priority %d", parent.FWMark, parent.RouteTable, uplinkRulePriority)
+	var lines strings.Builder
+	if parent.InterfaceName != "" {
+		fmt.Fprintf(&lines, "ExecStartPre=-/sbin/sysctl -q -w net.ipv4.conf.%s.rp_filter=2\n", parent.InterfaceName)
+	}
+	lines.WriteString("ExecStartPre=-/sbin/ip rule del " + ruleArgs + "\n")
+	lines.WriteString("ExecStartPre=/sbin/ip rule add " + ruleArgs + "\n")
+	// IPv6 is best effort: the parent may not carry v6 at all.
+	lines.WriteString("ExecStartPre=-/sbin/ip -6 rule del " + ruleArgs + "\n")
+	lines.WriteString("ExecStartPre=-/sbin/ip -6 rule add " + ruleArgs + "\n")
+
+	if profile.Type == "openvpn" {
+		unit = appendExecStartArgs(unit, "--mark "+strconv.FormatUint(uint64(parent.FWMark), 10))
+	}
+	marker := "\n\n[Install]"
+	idx := strings.Index(unit, marker)
+	if idx < 0 {
+		return unit
+	}
+	return unit[:idx+1] + lines.String() + unit[idx+1:]
+}
+
+func appendExecStartArgs(unit, args string) string {
+	idx := strings.Index(unit, "\nExecStart=")
+	if idx < 0 {
+		return unit
+	}
+	end := strings.Index(unit[idx+1:], "\n")
+	if end < 0 {
+		return unit + " " + args
+	}
+	end += idx + 1
+	return unit[:end] + " " + args + unit[end:]
+}
+
+// withWireGuardFwMark sets FwMark in the [Interface] section of a sanitized
+// WireGuard config, or removes it when mark is zero.
+func withWireGuardFwMark(config string, mark uint32) string {
+	lines := strings.Split(config, "\n")
+	out := make([]string, 0, len(lines)+1)
+	inInterface := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			inInterface = strings.EqualFold(strings.TrimSpace(trimmed[1:len(trimmed)-1]), "interface")
+			out = append(out, line)
+			if inInterface && mark > 0 {
+				out = append(out, fmt.Sprintf("FwMark = 0x%x", mark))
+			}
+			continue
+		}
+		if inInterface {
+			if key, _, ok := splitINIKeyValue(trimmed); ok && strings.EqualFold(strings.TrimSpace(key), "fwmark") {
+				continue
+			}
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
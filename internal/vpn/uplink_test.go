@@ -0,0 +1,79 @@
+package vpn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestManagerUplinkLifecycle(t *testing.T) {
+	manager, _, units := newTestManager(t)
+	config := func(host string) string {
+		return "[Interface]\nPrivateKey = test\nAddress = 10.0.0.2/32\nFwMark = 0x1\n[Peer]\nPublicKey = peer\nAllowedIPs = 0.0.0.0/0\nEndpoint = " + host + ":51820\n"
+	}
+
+	if _, err := manager.Create(UpsertRequest{Name: "wg-inner", Type: "wireguard", Config: config("inner"), UplinkVPN: "wg-outer"}); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected unknown uplink to be rejected, got %v", err)
+	}
+	outer, err := manager.Create(UpsertRequest{Name: "wg-outer", Type: "wireguard", Config: config("outer")})
+	if err != nil {
+		t.Fatalf("create outer: %v", err)
+	}
+	if _, err := manager.Create(UpsertRequest{Name: "wg-inner", Type: "wireguard", Config: config("inner"), UplinkVPN: "wg-outer", BoundInterface: "eth8"}); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected uplink with bound interface to be rejected, got %v", err)
+	}
+
+	inner, err := manager.Create(UpsertRequest{Name: "wg-inner", Type: "wireguard", Config: config("inner"), UplinkVPN: "wg-outer"})
+	if err != nil {
+		t.Fatalf("create inner: %v", err)
+	}
+	if inner.UplinkVPN != "wg-outer" {
+		t.Fatalf("uplink not persisted: %q", inner.UplinkVPN)
+	}
+	wantMark := fmt.Sprintf("FwMark = 0x%x", outer.FWMark)
+	if !strings.Contains(inner.RawConfig, wantMark) || strings.Contains(inner.RawConfig, "FwMark = 0x1\n") {
+		t.Fatalf("inner config does not carry the parent's fwmark:\n%s", inner.RawConfig)
+	}
+	unit := units.written["svpn-wg-inner.service"]
+	for _, want := range []string{
+		"Requires=svpn-wg-outer.service",
+		fmt.Sprintf("ExecStartPre=/sbin/ip rule add fwmark 0x%x table %d priority 99", outer.FWMark, outer.RouteTable),
+		"ExecStartPre=-/sbin/sysctl -q -w net.ipv4.conf." + outer.InterfaceName + ".rp_filter=2",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Fatalf("inner unit missing %q:\n%s", want, unit)
+		}
+	}
+
+	if err := manager.Delete("wg-outer"); !errors.Is(err, ErrVPNValidation) || !strings.Contains(err.Error(), "wg-inner") {
+		t.Fatalf("expected delete to be blocked by nested vpn, got %v", err)
+	}
+	if _, err := manager.Update("wg-outer", UpsertRequest{Config: config("outer"), UplinkVPN: "wg-inner"}); !errors.Is(err, ErrVPNValidation) {
+		t.Fatalf("expected uplink cycle to be rejected, got %v", err)
+	}
+
+	// Dropping the uplink removes the injected mark and the policy rule.
+	inner, err = manager.Update("wg-inner", UpsertRequest{Config: inner.RawConfig})
+	if err != nil {
+		t.Fatalf("clear uplink: %v", err)
+	}
+	if inner.UplinkVPN != "" || strings.Contains(inner.RawConfig, "FwMark") {
+		t.Fatalf("uplink not cleared:\n%s", inner.RawConfig)
+	}
+	if strings.Contains(units.written["svpn-wg-inner.service"], "ip rule") {
+		t.Fatalf("unit still installs uplink rule:\n%s", units.written["svpn-wg-inner.service"])
+	}
+}
+
+func TestWithUplinkUnitMarksOpenVPNSocket(t *testing.T) {
+	profile := &VPNProfile{Name: "ovpn-inner", Type: "openvpn", InterfaceName: "tun1"}
+	parent := &VPNProfile{Name: "wg-outer", InterfaceName: "wg-sv-outer", RouteTable: 201, FWMark: 0x169}
+	unit := withUplinkUnit(NewOpenVPNProvider().GenerateUnit(profile, "/data/split-vpn-webui", "/data/split-vpn-webui/vpns"), profile, parent)
+	if !strings.Contains(unit, "--script-security 1 --mark 361\n") {
+		t.Fatalf("openvpn ExecStart missing --mark:\n%s", unit)
+	}
+	if !strings.Contains(unit, "ExecStartPre=-/sbin/ip -6 rule add fwmark 0x169 table 201 priority 99\n\n[Install]") {
+		t.Fatalf("uplink rules not at the end of [Service]:\n%s", unit)
+	}
+}
@@ -2,6 +2,7 @@ package vpn
 
 import (
 	"fmt"
+	"net/netip"
 	"regexp"
 	"strconv"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 var (
 	namePattern       = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$`)
+	ifacePattern      = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._@-]{0,14}$`)
 	domainLabelRegexp = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
 )
 
@@ -71,6 +73,57 @@ func ValidateMSSClamp(value string) (string, error) {
 	return strconv.Itoa(mss), nil
 }
 
+// ValidateBoundInterface checks the uplink a VPN's endpoint is pinned to.
+// An empty value leaves the endpoint on the active WAN.
+func ValidateBoundInterface(value string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return "", nil
+	}
+	if !ifacePattern.MatchString(trimmed) {
+		return "", fmt.Errorf("bound interface %q is not a valid interface name", value)
+	}
+	return trimmed, nil
+}
+
+// IPv6 policies decide what happens to IPv6 traffic routed into a VPN.
+const (
+	// IPv6PolicyNAT66 masquerades IPv6 behind the tunnel address (default).
+	IPv6PolicyNAT66 = "nat66"
+	// IPv6PolicyDrop drops IPv6 from matched clients, for providers without
+	// IPv6, so it cannot fall back to the WAN.
+	IPv6PolicyDrop = "drop"
+	// IPv6PolicyNative forwards traffic sourced from a provider-routed prefix
+	// without NAT.
+	IPv6PolicyNative = "native"
+)
+
+// ValidateIPv6Policy checks a VPN's IPv6 policy and the prefix the native
+// policy requires. It returns the normalized policy and prefix; an empty
+// policy means nat66 and is returned as "".
+func ValidateIPv6Policy(policy, prefix string) (string, string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(policy))
+	trimmedPrefix := strings.TrimSpace(prefix)
+	switch normalized {
+	case "", IPv6PolicyNAT66, IPv6PolicyDrop:
+		if normalized == IPv6PolicyNAT66 {
+			normalized = ""
+		}
+		if trimmedPrefix != "" {
+			return "", "", fmt.Errorf("ipv6 prefix is only used with the native ipv6 policy")
+		}
+		return normalized, "", nil
+	case IPv6PolicyNative:
+		parsed, err := netip.ParsePrefix(trimmedPrefix)
+		if err != nil || !parsed.Addr().Is6() || parsed.Addr().Is4In6() {
+			return "", "", fmt.Errorf("native ipv6 policy requires an IPv6 prefix, got %q", prefix)
+		}
+		return normalized, parsed.Masked().String(), nil
+	default:
+		return "", "", fmt.Errorf("ipv6 policy must be %q, %q or %q", IPv6PolicyNAT66, IPv6PolicyDrop, IPv6PolicyNative)
+	}
+}
+
 // ValidateDomain checks user-supplied domain entries, including wildcard form (*.example.com).
 func ValidateDomain(domain string) error {
 	trimmed := strings.TrimSpace(strings.ToLower(domain))
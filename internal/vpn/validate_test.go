@@ -97,3 +97,48 @@ func TestValidateDomain(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateBoundInterface(t *testing.T) {
+	valid := map[string]string{"": "", " eth9 ": "eth9", "ppp0": "ppp0", "eth8.832": "eth8.832"}
+	for input, want := range valid {
+		got, err := ValidateBoundInterface(input)
+		if err != nil || got != want {
+			t.Fatalf("ValidateBoundInterface(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"eth9; reboot", "a-very-long-interface-name", "../eth0", "-eth0"} {
+		if _, err := ValidateBoundInterface(input); err == nil {
+			t.Fatalf("expected ValidateBoundInterface(%q) to fail", input)
+		}
+	}
+}
+
+func TestValidateIPv6Policy(t *testing.T) {
+	valid := []struct {
+		policy, prefix         string
+		wantPolicy, wantPrefix string
+	}{
+		{"", "", "", ""},
+		{"NAT66", "", "", ""},
+		{" drop ", "", "drop", ""},
+		{"native", "2001:db8:1234:5::1/48", "native", "2001:db8:1234::/48"},
+	}
+	for _, tc := range valid {
+		policy, prefix, err := ValidateIPv6Policy(tc.policy, tc.prefix)
+		if err != nil || policy != tc.wantPolicy || prefix != tc.wantPrefix {
+			t.Fatalf("ValidateIPv6Policy(%q, %q) = %q, %q, %v; want %q, %q", tc.policy, tc.prefix, policy, prefix, err, tc.wantPolicy, tc.wantPrefix)
+		}
+	}
+	invalid := [][2]string{
+		{"native", ""},
+		{"native", "10.0.0.0/8"},
+		{"native", "::ffff:10.0.0.0/104"},
+		{"drop", "2001:db8::/32"},
+		{"nat64", ""},
+	}
+	for _, tc := range invalid {
+		if _, _, err := ValidateIPv6Policy(tc[0], tc[1]); err == nil {
+			t.Fatalf("expected ValidateIPv6Policy(%q, %q) to fail", tc[0], tc[1])
+		}
+	}
+}
@@ -40,7 +40,7 @@ func (p *WireGuardProvider) ParseConfig(raw string) (*VPNProfile, error) {
 	}, nil
 }
 
-func (p *WireGuardProvider) GenerateUnit(profile *VPNProfile, dataDir string) string {
+func (p *WireGuardProvider) GenerateUnit(profile *VPNProfile, dataDir, vpnsDir string) string {
 	if profile == nil {
 		return ""
 	}
@@ -52,7 +52,7 @@ func (p *WireGuardProvider) GenerateUnit(profile *VPNProfile, dataDir string) st
 	if fileName == "" {
 		fileName = name + ".wg"
 	}
-	configPath := filepath.Join(dataDir, "vpns", name, fileName)
+	configPath := filepath.Join(vpnsDir, name, fileName)
 	return fmt.Sprintf(`[Unit]
 Description=split-vpn-webui WireGuard tunnel (%s)
 After=network-online.target
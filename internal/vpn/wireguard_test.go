@@ -127,7 +127,7 @@ AllowedIPs = 0.0.0.0/0
 
 func TestWireGuardGenerateUnit(t *testing.T) {
 	provider := NewWireGuardProvider()
-	unit := provider.GenerateUnit(&VPNProfile{Name: "wg-sgp", ConfigFile: "wg0.conf"}, "/data/split-vpn-webui")
+	unit := provider.GenerateUnit(&VPNProfile{Name: "wg-sgp", ConfigFile: "wg0.conf"}, "/data/split-vpn-webui", "/data/split-vpn-webui/vpns")
 
 	checks := []string{
 		"Description=split-vpn-webui WireGuard tunnel (wg-sgp)",
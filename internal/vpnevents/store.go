@@ -0,0 +1,211 @@
+// Package vpnevents records a per-VPN connection timeline: link up/down
+// transitions, WireGuard handshake stalls, user restart actions and watchdog
+// interventions. The timeline backs the availability history and uptime
+// percentage shown per tunnel.
+package vpnevents
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event types.
+const (
+	TypeUp                = "up"
+	TypeDown              = "down"
+	TypeHandshakeStale    = "handshake_stale"
+	TypeHandshakeRestored = "handshake_restored"
+	TypeStart             = "start"
+	TypeStop              = "stop"
+	TypeRestart           = "restart"
+	TypeWatchdog          = "watchdog"
+)
+
+const (
+	// DefaultPageSize and MaxPageSize bound List pagination.
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// Event is one entry of a VPN's connection timeline.
+type Event struct {
+	ID     int64     `json:"id"`
+	VPN    string    `json:"vpn"`
+	Type   string    `json:"type"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Page is one page of a VPN's timeline, newest first.
+type Page struct {
+	Events []Event `json:"events"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// Store persists timeline events in the vpn_events table.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a store backed by db.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	return &Store{db: db}, nil
+}
+
+// Record appends an event. A zero At is set to the current time.
+func (s *Store) Record(ctx context.Context, event Event) (Event, error) {
+	event.VPN = strings.TrimSpace(event.VPN)
+	event.Type = strings.TrimSpace(event.Type)
+	if event.VPN == "" || event.Type == "" {
+		return Event{}, fmt.Errorf("event vpn and type are required")
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	event.At = event.At.UTC().Truncate(time.Second)
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO vpn_events (vpn, type, detail, at) VALUES (?, ?, ?, ?)`,
+		event.VPN, event.Type, event.Detail, event.At.Unix(),
+	)
+	if err != nil {
+		return Event{}, err
+	}
+	if event.ID, err = result.LastInsertId(); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
+
+// List returns one page of a VPN's events, newest first.
+func (s *Store) List(ctx context.Context, vpn string, limit, offset int) (Page, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	page := Page{Events: []Event{}, Limit: limit, Offset: offset}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM vpn_events WHERE vpn = ?`, vpn).Scan(&page.Total); err != nil {
+		return Page{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, vpn, type, detail, at
+		FROM vpn_events
+		WHERE vpn = ?
+		ORDER BY at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, vpn, limit, offset)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return Page{}, err
+		}
+		page.Events = append(page.Events, event)
+	}
+	return page, rows.Err()
+}
+
+// LastLinkState returns the type of the most recent up/down event, or ""
+// when none was recorded.
+func (s *Store) LastLinkState(ctx context.Context, vpn string) (string, error) {
+	var state string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT type FROM vpn_events
+		WHERE vpn = ? AND type IN (?, ?)
+		ORDER BY at DESC, id DESC
+		LIMIT 1
+	`, vpn, TypeUp, TypeDown).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return state, err
+}
+
+// Uptime returns the percentage of [since, now) the VPN was up according to
+// its up/down transitions. ok is false when no transition is known before
+// now, so there is nothing to base a figure on.
+func (s *Store) Uptime(ctx context.Context, vpn string, since, now time.Time) (float64, bool, error) {
+	if !now.After(since) {
+		return 0, false, nil
+	}
+	state := ""
+	err := s.db.QueryRowContext(ctx, `
+		SELECT type FROM vpn_events
+		WHERE vpn = ? AND type IN (?, ?) AND at < ?
+		ORDER BY at DESC, id DESC
+		LIMIT 1
+	`, vpn, TypeUp, TypeDown, since.Unix()).Scan(&state)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT type, at FROM vpn_events
+		WHERE vpn = ? AND type IN (?, ?) AND at >= ? AND at < ?
+		ORDER BY at ASC, id ASC
+	`, vpn, TypeUp, TypeDown, since.Unix(), now.Unix())
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+
+	// Time before the first known transition is not counted either way.
+	var up, known time.Duration
+	cursor := since
+	for rows.Next() {
+		var next string
+		var at int64
+		if err := rows.Scan(&next, &at); err != nil {
+			return 0, false, err
+		}
+		ts := time.Unix(at, 0)
+		if state != "" {
+			span := ts.Sub(cursor)
+			known += span
+			if state == TypeUp {
+				up += span
+			}
+		}
+		state, cursor = next, ts
+	}
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+	if state == "" {
+		return 0, false, nil
+	}
+	span := now.Sub(cursor)
+	known += span
+	if state == TypeUp {
+		up += span
+	}
+	if known <= 0 {
+		return 0, false, nil
+	}
+	return float64(up) / float64(known) * 100, true, nil
+}
+
+func scanEvent(rows *sql.Rows) (Event, error) {
+	var event Event
+	var at int64
+	if err := rows.Scan(&event.ID, &event.VPN, &event.Type, &event.Detail, &at); err != nil {
+		return Event{}, err
+	}
+	event.At = time.Unix(at, 0).UTC()
+	return event, nil
+}
@@ -0,0 +1,128 @@
+package vpnevents
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/database"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return store
+}
+
+func TestStoreListPaginatesNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	base := time.Unix(1_700_000_000, 0)
+	for i := 0; i < 5; i++ {
+		if _, err := store.Record(ctx, Event{VPN: "wg-sgp", Type: TypeRestart, At: base.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	if _, err := store.Record(ctx, Event{VPN: "other", Type: TypeUp, At: base}); err != nil {
+		t.Fatalf("record other: %v", err)
+	}
+
+	page, err := store.List(ctx, "wg-sgp", 2, 1)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if page.Total != 5 || page.Limit != 2 || page.Offset != 1 {
+		t.Fatalf("unexpected page metadata: %+v", page)
+	}
+	if len(page.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(page.Events))
+	}
+	if !page.Events[0].At.Equal(base.Add(3*time.Minute)) || !page.Events[1].At.Equal(base.Add(2*time.Minute)) {
+		t.Fatalf("unexpected order: %+v", page.Events)
+	}
+
+	page, err = store.List(ctx, "wg-sgp", 0, 0)
+	if err != nil {
+		t.Fatalf("list default: %v", err)
+	}
+	if page.Limit != DefaultPageSize || len(page.Events) != 5 {
+		t.Fatalf("unexpected default page: %+v", page)
+	}
+}
+
+func TestStoreRecordRequiresVPNAndType(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := store.Record(context.Background(), Event{VPN: " ", Type: TypeUp}); err == nil {
+		t.Fatalf("expected error for empty vpn")
+	}
+}
+
+func TestStoreLastLinkState(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	state, err := store.LastLinkState(ctx, "wg-sgp")
+	if err != nil || state != "" {
+		t.Fatalf("expected empty state, got %q err=%v", state, err)
+	}
+	base := time.Unix(1_700_000_000, 0)
+	for i, eventType := range []string{TypeUp, TypeDown, TypeRestart} {
+		if _, err := store.Record(ctx, Event{VPN: "wg-sgp", Type: eventType, At: base.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	state, err = store.LastLinkState(ctx, "wg-sgp")
+	if err != nil || state != TypeDown {
+		t.Fatalf("expected down, got %q err=%v", state, err)
+	}
+}
+
+func TestStoreUptime(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	base := time.Unix(1_700_000_000, 0)
+	since := base
+	now := base.Add(10 * time.Hour)
+
+	if _, ok, err := store.Uptime(ctx, "wg-sgp", since, now); err != nil || ok {
+		t.Fatalf("expected no uptime without events, ok=%v err=%v", ok, err)
+	}
+
+	// Up before the window, down for 2h, up again for the rest.
+	events := []Event{
+		{VPN: "wg-sgp", Type: TypeUp, At: base.Add(-time.Hour)},
+		{VPN: "wg-sgp", Type: TypeDown, At: base.Add(4 * time.Hour)},
+		{VPN: "wg-sgp", Type: TypeRestart, At: base.Add(5 * time.Hour)},
+		{VPN: "wg-sgp", Type: TypeUp, At: base.Add(6 * time.Hour)},
+	}
+	for _, event := range events {
+		if _, err := store.Record(ctx, event); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	pct, ok, err := store.Uptime(ctx, "wg-sgp", since, now)
+	if err != nil || !ok {
+		t.Fatalf("uptime: ok=%v err=%v", ok, err)
+	}
+	if math.Abs(pct-80) > 0.001 {
+		t.Fatalf("expected 80%% uptime, got %f", pct)
+	}
+
+	// Without a transition before the window, only the known span counts.
+	if _, err := store.Record(ctx, Event{VPN: "wg-new", Type: TypeUp, At: base.Add(8 * time.Hour)}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	pct, ok, err = store.Uptime(ctx, "wg-new", since, now)
+	if err != nil || !ok || math.Abs(pct-100) > 0.001 {
+		t.Fatalf("expected 100%% uptime, got %f ok=%v err=%v", pct, ok, err)
+	}
+}
@@ -0,0 +1,278 @@
+package vpnevents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/util"
+	"split-vpn-webui/internal/vpn"
+)
+
+const (
+	pollInterval = 10 * time.Second
+	// staleHandshakeAfter matches WireGuard's REJECT_AFTER_TIME: once the
+	// latest handshake is older, the session keys are gone and no traffic
+	// passes until a new handshake succeeds.
+	staleHandshakeAfter = 180 * time.Second
+)
+
+// VPNLister provides the VPN profiles to track.
+type VPNLister interface {
+	List() ([]*vpn.VPNProfile, error)
+}
+
+// linkState is what the tracker last observed for one VPN.
+type linkState struct {
+	link    string
+	upSince time.Time
+	stale   bool
+}
+
+// Tracker polls VPN interfaces and records link and handshake transitions.
+type Tracker struct {
+	store *Store
+	vpns  VPNLister
+	now   func() time.Time
+
+	linkUp    func(iface string) bool
+	handshake func(tool, iface string) (time.Time, error)
+
+	mu         sync.Mutex
+	started    bool
+	states     map[string]*linkState
+	handler    func(Event)
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewTracker creates a tracker that records into store.
+func NewTracker(store *Store, vpns VPNLister) (*Tracker, error) {
+	if store == nil {
+		return nil, fmt.Errorf("event store is required")
+	}
+	if vpns == nil {
+		return nil, fmt.Errorf("vpn lister is required")
+	}
+	return &Tracker{
+		store: store,
+		vpns:  vpns,
+		now:   time.Now,
+		linkUp: func(iface string) bool {
+			up, _, err := util.InterfaceOperState(iface)
+			return err == nil && up
+		},
+		handshake: latestHandshake,
+		states:    make(map[string]*linkState),
+	}, nil
+}
+
+// SetHandler registers a callback invoked for every recorded event.
+func (t *Tracker) SetHandler(handler func(Event)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handler = handler
+}
+
+// Record stores an event and notifies the handler. It is used for actions
+// taken outside the tracker, such as restarts and watchdog interventions.
+func (t *Tracker) Record(ctx context.Context, vpnName, eventType, detail string) (Event, error) {
+	event, err := t.store.Record(ctx, Event{VPN: vpnName, Type: eventType, Detail: detail, At: t.now()})
+	if err != nil {
+		return Event{}, err
+	}
+	t.mu.Lock()
+	handler := t.handler
+	t.mu.Unlock()
+	if handler != nil {
+		handler(event)
+	}
+	return event, nil
+}
+
+// Start launches the polling loop.
+func (t *Tracker) Start() error {
+	t.mu.Lock()
+	if t.started {
+		t.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.started = true
+	t.loopCancel = cancel
+	t.mu.Unlock()
+
+	t.loopWG.Add(1)
+	go func() {
+		defer t.loopWG.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		_ = t.Poll(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = t.Poll(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the polling loop.
+func (t *Tracker) Stop() error {
+	t.mu.Lock()
+	loopCancel := t.loopCancel
+	t.started = false
+	t.loopCancel = nil
+	t.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	t.loopWG.Wait()
+	return nil
+}
+
+// Poll observes every VPN once and records transitions since the last poll.
+// The first observation of a VPN is compared with its last stored link
+// state, so restarting the service does not duplicate events.
+func (t *Tracker) Poll(ctx context.Context) error {
+	profiles, err := t.vpns.List()
+	if err != nil {
+		return fmt.Errorf("list vpns: %w", err)
+	}
+	now := t.now()
+	seen := make(map[string]struct{}, len(profiles))
+	var errs []string
+	for _, profile := range profiles {
+		if profile == nil || profile.Name == "" {
+			continue
+		}
+		seen[profile.Name] = struct{}{}
+		if err := t.observe(ctx, profile, now); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", profile.Name, err))
+		}
+	}
+	t.mu.Lock()
+	for name := range t.states {
+		if _, ok := seen[name]; !ok {
+			delete(t.states, name)
+		}
+	}
+	t.mu.Unlock()
+	if len(errs) > 0 {
+		return fmt.Errorf("record vpn events: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (t *Tracker) observe(ctx context.Context, profile *vpn.VPNProfile, now time.Time) error {
+	t.mu.Lock()
+	state, ok := t.states[profile.Name]
+	t.mu.Unlock()
+	if !ok {
+		last, err := t.store.LastLinkState(ctx, profile.Name)
+		if err != nil {
+			return err
+		}
+		state = &linkState{link: last, upSince: now}
+		t.mu.Lock()
+		t.states[profile.Name] = state
+		t.mu.Unlock()
+	}
+
+	iface := profile.InterfaceName
+	current := TypeDown
+	if t.linkUp(iface) {
+		current = TypeUp
+	}
+	if current != state.link {
+		if _, err := t.Record(ctx, profile.Name, current, fmt.Sprintf("interface %s is %s", iface, current)); err != nil {
+			return err
+		}
+		state.link = current
+		state.upSince = now
+		state.stale = false
+	}
+	if current != TypeUp {
+		return nil
+	}
+
+	tool := handshakeTool(profile.Type)
+	if tool == "" || now.Sub(state.upSince) < staleHandshakeAfter {
+		// OpenVPN has no handshake age; fresh links get time to handshake.
+		return nil
+	}
+	latest, err := t.handshake(tool, iface)
+	if err != nil {
+		return nil
+	}
+	age := now.Sub(latest)
+	stale := latest.IsZero() || age > staleHandshakeAfter
+	switch {
+	case stale && !state.stale:
+		detail := "no handshake since the interface came up"
+		if !latest.IsZero() {
+			detail = fmt.Sprintf("last handshake %s ago", age.Truncate(time.Second))
+		}
+		if _, err := t.Record(ctx, profile.Name, TypeHandshakeStale, detail); err != nil {
+			return err
+		}
+	case !stale && state.stale:
+		if _, err := t.Record(ctx, profile.Name, TypeHandshakeRestored, "handshake completed"); err != nil {
+			return err
+		}
+	}
+	state.stale = stale
+	return nil
+}
+
+func handshakeTool(vpnType string) string {
+	switch vpnType {
+	case "wireguard":
+		return "wg"
+	case "amneziawg":
+		return "awg"
+	}
+	return ""
+}
+
+// latestHandshake returns the newest peer handshake time reported by
+// `wg show <iface> latest-handshakes`, or the zero time if none happened.
+func latestHandshake(tool, iface string) (time.Time, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool, "show", iface, "latest-handshakes")
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return time.Time{}, fmt.Errorf("%w: %s", err, detail)
+		}
+		return time.Time{}, err
+	}
+	return parseLatestHandshakes(string(output)), nil
+}
+
+func parseLatestHandshakes(output string) time.Time {
+	var latest int64
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if ts, err := strconv.ParseInt(fields[1], 10, 64); err == nil && ts > latest {
+			latest = ts
+		}
+	}
+	if latest == 0 {
+		return time.Time{}
+	}
+	return time.Unix(latest, 0)
+}
@@ -0,0 +1,109 @@
+package vpnevents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/vpn"
+)
+
+type fakeLister struct {
+	profiles []*vpn.VPNProfile
+}
+
+func (f *fakeLister) List() ([]*vpn.VPNProfile, error) {
+	return f.profiles, nil
+}
+
+func TestTrackerRecordsLinkAndHandshakeTransitions(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	lister := &fakeLister{profiles: []*vpn.VPNProfile{{Name: "wg-sgp", Type: "wireguard", InterfaceName: "wg-sv-sgp"}}}
+	tracker, err := NewTracker(store, lister)
+	if err != nil {
+		t.Fatalf("new tracker: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	up := true
+	handshakeAt := time.Time{}
+	tracker.now = func() time.Time { return now }
+	tracker.linkUp = func(string) bool { return up }
+	tracker.handshake = func(tool, iface string) (time.Time, error) {
+		if tool != "wg" || iface != "wg-sv-sgp" {
+			t.Fatalf("unexpected handshake query %s %s", tool, iface)
+		}
+		return handshakeAt, nil
+	}
+	var notified []string
+	tracker.SetHandler(func(event Event) { notified = append(notified, event.Type) })
+
+	poll := func() {
+		t.Helper()
+		if err := tracker.Poll(ctx); err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+	}
+
+	poll() // up
+	now = now.Add(time.Minute)
+	poll() // within grace, no handshake check
+	now = now.Add(3 * time.Minute)
+	poll() // stale: never handshaked
+	handshakeAt = now.Add(-10 * time.Second)
+	now = now.Add(10 * time.Second)
+	poll() // restored
+	up = false
+	now = now.Add(10 * time.Second)
+	poll() // down
+	poll() // unchanged
+
+	want := []string{TypeUp, TypeHandshakeStale, TypeHandshakeRestored, TypeDown}
+	if len(notified) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, notified)
+	}
+	for i := range want {
+		if notified[i] != want[i] {
+			t.Fatalf("expected events %v, got %v", want, notified)
+		}
+	}
+	page, err := store.List(ctx, "wg-sgp", 0, 0)
+	if err != nil || page.Total != len(want) {
+		t.Fatalf("expected %d stored events, got %+v err=%v", len(want), page, err)
+	}
+}
+
+func TestTrackerResumesFromStoredLinkState(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	if _, err := store.Record(ctx, Event{VPN: "ovpn", Type: TypeUp, At: time.Unix(1_700_000_000, 0)}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	lister := &fakeLister{profiles: []*vpn.VPNProfile{{Name: "ovpn", Type: "openvpn", InterfaceName: "tun0"}}}
+	tracker, err := NewTracker(store, lister)
+	if err != nil {
+		t.Fatalf("new tracker: %v", err)
+	}
+	tracker.linkUp = func(string) bool { return true }
+	tracker.handshake = func(string, string) (time.Time, error) {
+		t.Fatalf("openvpn has no handshake check")
+		return time.Time{}, nil
+	}
+	if err := tracker.Poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	page, err := store.List(ctx, "ovpn", 0, 0)
+	if err != nil || page.Total != 1 {
+		t.Fatalf("expected no duplicate up event, got %+v err=%v", page, err)
+	}
+}
+
+func TestParseLatestHandshakes(t *testing.T) {
+	output := "peerA=\t1700000000\npeerB=\t1700000100\npeerC=\t0\n"
+	if got := parseLatestHandshakes(output); !got.Equal(time.Unix(1700000100, 0)) {
+		t.Fatalf("unexpected latest handshake %v", got)
+	}
+	if got := parseLatestHandshakes("peerA=\t0\n"); !got.IsZero() {
+		t.Fatalf("expected zero time, got %v", got)
+	}
+}
@@ -0,0 +1,140 @@
+package vpnrevisions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffLines bounds the quadratic line diff; configs are far smaller.
+const maxDiffLines = 5000
+
+// Line operations in a Diff.
+const (
+	OpEqual  = " "
+	OpInsert = "+"
+	OpDelete = "-"
+)
+
+// DiffLine is one line of a line-based diff.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff compares two configs line by line using a longest common
+// subsequence, returning every line tagged as kept, inserted or deleted.
+func Diff(from, to string) ([]DiffLine, error) {
+	a := splitLines(from)
+	b := splitLines(to)
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		return nil, fmt.Errorf("config too large to diff (limit %d lines)", maxDiffLines)
+	}
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: OpEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: OpDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: OpInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: OpDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: OpInsert, Text: b[j]})
+	}
+	return lines, nil
+}
+
+// Unified renders diff lines as unified diff text with the given context.
+func Unified(lines []DiffLine, fromLabel, toLabel string, context int) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromLabel, toLabel)
+	changed := make([]bool, len(lines))
+	for idx, line := range lines {
+		changed[idx] = line.Op != OpEqual
+	}
+	aLine, bLine := 1, 1
+	for start := 0; start < len(lines); {
+		// Find the next change and the hunk around it.
+		next := start
+		for next < len(lines) && !changed[next] {
+			next++
+		}
+		if next == len(lines) {
+			break
+		}
+		hunkStart := max(start, next-context)
+		for k := start; k < hunkStart; k++ {
+			aLine++
+			bLine++
+		}
+		end := next
+		for end < len(lines) {
+			if changed[end] {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && !changed[run] && run-end < 2*context {
+				run++
+			}
+			if run < len(lines) && changed[run] {
+				end = run
+				continue
+			}
+			end = min(len(lines), end+context)
+			break
+		}
+		aCount, bCount := 0, 0
+		for _, line := range lines[hunkStart:end] {
+			if line.Op != OpInsert {
+				aCount++
+			}
+			if line.Op != OpDelete {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, line := range lines[hunkStart:end] {
+			out.WriteString(line.Op)
+			out.WriteString(line.Text)
+			out.WriteByte('\n')
+		}
+		aLine += aCount
+		bLine += bCount
+		start = end
+	}
+	return out.String()
+}
+
+func splitLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.TrimSuffix(content, "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
@@ -0,0 +1,49 @@
+package vpnrevisions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffMarksChangedLines(t *testing.T) {
+	lines, err := Diff("a\nb\nc\n", "a\nB\nc\nd\n")
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	var got []string
+	for _, line := range lines {
+		got = append(got, line.Op+line.Text)
+	}
+	want := []string{" a", "-b", "+B", " c", "+d"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("Diff = %v, want %v", got, want)
+	}
+}
+
+func TestUnifiedGroupsHunks(t *testing.T) {
+	var from, to []string
+	for i := 0; i < 20; i++ {
+		line := string(rune('a' + i))
+		from = append(from, line)
+		to = append(to, line)
+	}
+	to[1] = "B"
+	to[15] = "P"
+	lines, err := Diff(strings.Join(from, "\n"), strings.Join(to, "\n"))
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	text := Unified(lines, "rev 1", "rev 2", 3)
+	if !strings.HasPrefix(text, "--- rev 1\n+++ rev 2\n") {
+		t.Fatalf("missing headers:\n%s", text)
+	}
+	if !strings.Contains(text, "@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n") {
+		t.Fatalf("unexpected first hunk:\n%s", text)
+	}
+	if !strings.Contains(text, "@@ -13,7 +13,7 @@\n m\n n\n o\n-p\n+P\n q\n r\n s\n") {
+		t.Fatalf("unexpected second hunk:\n%s", text)
+	}
+	if same, _ := Diff("x\n", "x"); Unified(same, "a", "b", 3) != "--- a\n+++ b\n" {
+		t.Fatalf("identical configs should produce no hunks")
+	}
+}
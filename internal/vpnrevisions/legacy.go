@@ -0,0 +1,91 @@
+package vpnrevisions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LegacyDirName is where the file-based editor history kept previous
+// configs, as <data dir>/config-revisions/<vpn>/<unix nanos>.conf.
+const LegacyDirName = "config-revisions"
+
+// ImportLegacy moves the file-based revisions under dir into the store,
+// oldest first, and removes dir afterwards. VPNs that already have history
+// are skipped so imported rows never interleave with newer ones. A missing
+// dir is not an error.
+func (s *Store) ImportLegacy(ctx context.Context, dir string) (int, error) {
+	vpns, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	imported := 0
+	for _, entry := range vpns {
+		if !entry.IsDir() {
+			continue
+		}
+		latest, err := s.latest(ctx, entry.Name())
+		if err != nil {
+			return imported, err
+		}
+		if latest != nil {
+			continue
+		}
+		revisions, err := readLegacyRevisions(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return imported, err
+		}
+		for _, rev := range revisions {
+			rev.VPN = entry.Name()
+			if _, ok, err := s.Record(ctx, rev); err != nil {
+				return imported, fmt.Errorf("import revision for %s: %w", rev.VPN, err)
+			} else if ok {
+				imported++
+			}
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return imported, err
+	}
+	return imported, nil
+}
+
+func readLegacyRevisions(dir string) ([]Revision, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]Revision, 0, len(entries))
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".conf")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		nanos, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, Revision{
+			Action:    ActionImport,
+			Content:   string(content),
+			CreatedAt: time.Unix(0, nanos),
+		})
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].CreatedAt.Before(revisions[j].CreatedAt)
+	})
+	return revisions, nil
+}
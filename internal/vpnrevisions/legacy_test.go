@@ -0,0 +1,49 @@
+package vpnrevisions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportLegacyMovesFileRevisionsIntoStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	dir := filepath.Join(t.TempDir(), LegacyDirName)
+	for vpn, files := range map[string]map[string]string{
+		"wg-fra": {"1700000000000000000.conf": "old\n", "1700000100000000000.conf": "older edit\n", "notes.txt": "skip"},
+		"wg-sgp": {"1700000000000000000.conf": "sgp\n"},
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, vpn), 0o700); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, vpn, name), []byte(content), 0o600); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+		}
+	}
+	// wg-sgp already has SQLite history, so its files are not imported.
+	if _, _, err := store.Record(ctx, Revision{VPN: "wg-sgp", Action: ActionCreate, Content: "current\n"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	imported, err := store.ImportLegacy(ctx, dir)
+	if err != nil || imported != 2 {
+		t.Fatalf("expected 2 imported revisions, got %d err=%v", imported, err)
+	}
+	page, _ := store.List(ctx, "wg-fra", 0, 0)
+	if page.Total != 2 || page.Revisions[0].Action != ActionImport || page.Revisions[0].CreatedAt.Unix() != 1700000100 {
+		t.Fatalf("expected newest imported revision first, got %+v", page)
+	}
+	if page, _ := store.List(ctx, "wg-sgp", 0, 0); page.Total != 1 {
+		t.Fatalf("expected wg-sgp history untouched, got %+v", page)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected legacy dir removed, got %v", err)
+	}
+	if imported, err := store.ImportLegacy(ctx, dir); err != nil || imported != 0 {
+		t.Fatalf("expected missing dir to be a no-op, got %d err=%v", imported, err)
+	}
+}
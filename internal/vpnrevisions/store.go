@@ -0,0 +1,217 @@
+// Package vpnrevisions keeps the history of every WireGuard/OpenVPN config
+// saved for a profile — who changed it, when and how — so accidental edits
+// can be reviewed, diffed and reverted.
+package vpnrevisions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Revision actions.
+const (
+	// ActionBaseline records the config a profile had before its first
+	// tracked change.
+	ActionBaseline = "baseline"
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionEdit     = "edit"
+	ActionRevert   = "revert"
+	ActionRestore  = "restore"
+	// ActionImport marks configs carried over from the file-based editor
+	// history.
+	ActionImport = "import"
+	// ActionSync marks configs replicated from a config sync leader.
+	ActionSync = "sync"
+)
+
+const (
+	// DefaultPageSize and MaxPageSize bound List pagination.
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+	// MaxPerVPN caps the revisions kept per profile; the oldest are pruned.
+	MaxPerVPN = 100
+)
+
+// ErrNotFound indicates a missing revision.
+var ErrNotFound = errors.New("config revision not found")
+
+// Revision is one saved config. Content is omitted from list results.
+type Revision struct {
+	ID        int64     `json:"id"`
+	VPN       string    `json:"vpn"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor,omitempty"`
+	Size      int       `json:"size"`
+	Content   string    `json:"content,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Page is one page of a VPN's revisions, newest first.
+type Page struct {
+	Revisions []Revision `json:"revisions"`
+	Total     int        `json:"total"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+}
+
+// Store persists revisions in the vpn_revisions table.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a store backed by db.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	return &Store{db: db}, nil
+}
+
+// Record saves a revision. A config identical to the latest revision is not
+// stored again; ok reports whether a row was written. A zero CreatedAt is
+// set to the current time.
+func (s *Store) Record(ctx context.Context, rev Revision) (saved Revision, ok bool, err error) {
+	rev.VPN = strings.TrimSpace(rev.VPN)
+	rev.Action = strings.TrimSpace(rev.Action)
+	if rev.VPN == "" || rev.Action == "" {
+		return Revision{}, false, fmt.Errorf("revision vpn and action are required")
+	}
+	latest, err := s.latest(ctx, rev.VPN)
+	if err != nil {
+		return Revision{}, false, err
+	}
+	if latest != nil && latest.Content == rev.Content {
+		return *latest, false, nil
+	}
+	if rev.CreatedAt.IsZero() {
+		rev.CreatedAt = time.Now()
+	}
+	rev.CreatedAt = rev.CreatedAt.UTC().Truncate(time.Second)
+	rev.Size = len(rev.Content)
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO vpn_revisions (vpn, action, actor, content, created_at) VALUES (?, ?, ?, ?, ?)`,
+		rev.VPN, rev.Action, rev.Actor, rev.Content, rev.CreatedAt.Unix(),
+	)
+	if err != nil {
+		return Revision{}, false, err
+	}
+	if rev.ID, err = result.LastInsertId(); err != nil {
+		return Revision{}, false, err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM vpn_revisions
+		WHERE vpn = ? AND id NOT IN (
+			SELECT id FROM vpn_revisions WHERE vpn = ? ORDER BY id DESC LIMIT ?
+		)
+	`, rev.VPN, rev.VPN, MaxPerVPN); err != nil {
+		return Revision{}, false, err
+	}
+	return rev, true, nil
+}
+
+// EnsureBaseline records content as the baseline when the VPN has no
+// history yet, so the config from before the first tracked change can
+// still be reviewed and restored.
+func (s *Store) EnsureBaseline(ctx context.Context, vpn, content string) error {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	latest, err := s.latest(ctx, vpn)
+	if err != nil || latest != nil {
+		return err
+	}
+	_, _, err = s.Record(ctx, Revision{VPN: vpn, Action: ActionBaseline, Content: content})
+	return err
+}
+
+// List returns one page of a VPN's revisions without content, newest first.
+func (s *Store) List(ctx context.Context, vpn string, limit, offset int) (Page, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	page := Page{Revisions: []Revision{}, Limit: limit, Offset: offset}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM vpn_revisions WHERE vpn = ?`, vpn).Scan(&page.Total); err != nil {
+		return Page{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, vpn, action, actor, LENGTH(CAST(content AS BLOB)), created_at
+		FROM vpn_revisions
+		WHERE vpn = ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, vpn, limit, offset)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rev Revision
+		var createdAt int64
+		if err := rows.Scan(&rev.ID, &rev.VPN, &rev.Action, &rev.Actor, &rev.Size, &createdAt); err != nil {
+			return Page{}, err
+		}
+		rev.CreatedAt = time.Unix(createdAt, 0).UTC()
+		page.Revisions = append(page.Revisions, rev)
+	}
+	return page, rows.Err()
+}
+
+// Get returns one revision of vpn with its content.
+func (s *Store) Get(ctx context.Context, vpn string, id int64) (Revision, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, vpn, action, actor, content, created_at
+		FROM vpn_revisions
+		WHERE vpn = ? AND id = ?
+	`, vpn, id)
+	rev, err := scanRevision(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Revision{}, fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+	return rev, err
+}
+
+// Delete removes a VPN's history, used when the profile itself is deleted.
+func (s *Store) Delete(ctx context.Context, vpn string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM vpn_revisions WHERE vpn = ?`, vpn)
+	return err
+}
+
+func (s *Store) latest(ctx context.Context, vpn string) (*Revision, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, vpn, action, actor, content, created_at
+		FROM vpn_revisions
+		WHERE vpn = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`, vpn)
+	rev, err := scanRevision(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func scanRevision(row *sql.Row) (Revision, error) {
+	var rev Revision
+	var createdAt int64
+	if err := row.Scan(&rev.ID, &rev.VPN, &rev.Action, &rev.Actor, &rev.Content, &createdAt); err != nil {
+		return Revision{}, err
+	}
+	rev.Size = len(rev.Content)
+	rev.CreatedAt = time.Unix(createdAt, 0).UTC()
+	return rev, nil
+}
@@ -0,0 +1,91 @@
+package vpnrevisions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"split-vpn-webui/internal/database"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "revisions.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return store
+}
+
+func TestStoreRecordSkipsUnchangedContent(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	first, ok, err := store.Record(ctx, Revision{VPN: "wg-fra", Action: ActionCreate, Actor: "web", Content: "a\n"})
+	if err != nil || !ok || first.ID == 0 || first.Size != 2 {
+		t.Fatalf("record create: ok=%v err=%v rev=%+v", ok, err, first)
+	}
+	same, ok, err := store.Record(ctx, Revision{VPN: "wg-fra", Action: ActionUpdate, Content: "a\n"})
+	if err != nil || ok || same.ID != first.ID {
+		t.Fatalf("unchanged content should not be stored: ok=%v err=%v rev=%+v", ok, err, same)
+	}
+	if _, ok, err := store.Record(ctx, Revision{VPN: "wg-fra", Action: ActionEdit, Content: "b\n"}); err != nil || !ok {
+		t.Fatalf("record edit: ok=%v err=%v", ok, err)
+	}
+
+	page, err := store.List(ctx, "wg-fra", 0, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if page.Total != 2 || len(page.Revisions) != 2 || page.Revisions[0].Action != ActionEdit || page.Revisions[0].Content != "" {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	got, err := store.Get(ctx, "wg-fra", first.ID)
+	if err != nil || got.Content != "a\n" || got.Actor != "web" {
+		t.Fatalf("get: %+v %v", got, err)
+	}
+	if _, err := store.Get(ctx, "other", first.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another vpn, got %v", err)
+	}
+}
+
+func TestStoreEnsureBaselineOnlyWithoutHistory(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	if err := store.EnsureBaseline(ctx, "wg-fra", "old\n"); err != nil {
+		t.Fatalf("baseline: %v", err)
+	}
+	if err := store.EnsureBaseline(ctx, "wg-fra", "newer\n"); err != nil {
+		t.Fatalf("second baseline: %v", err)
+	}
+	page, _ := store.List(ctx, "wg-fra", 0, 0)
+	if page.Total != 1 || page.Revisions[0].Action != ActionBaseline {
+		t.Fatalf("expected a single baseline, got %+v", page)
+	}
+}
+
+func TestStorePrunesAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	for i := 0; i < MaxPerVPN+5; i++ {
+		if _, _, err := store.Record(ctx, Revision{VPN: "wg-fra", Action: ActionEdit, Content: fmt.Sprintf("v%d\n", i)}); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+	page, _ := store.List(ctx, "wg-fra", 1, 0)
+	if page.Total != MaxPerVPN || page.Revisions[0].Size != len(fmt.Sprintf("v%d\n", MaxPerVPN+4)) {
+		t.Fatalf("expected %d newest revisions kept, got %+v", MaxPerVPN, page)
+	}
+	if err := store.Delete(ctx, "wg-fra"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if page, _ := store.List(ctx, "wg-fra", 0, 0); page.Total != 0 {
+		t.Fatalf("expected history removed, got %d", page.Total)
+	}
+}
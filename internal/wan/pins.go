@@ -0,0 +1,195 @@
+package wan
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pin is one installed endpoint host route.
+type pin struct {
+	vpn     string
+	iface   string
+	gateway string
+}
+
+// resolvedHost caches the addresses of a hostname endpoint.
+type resolvedHost struct {
+	addrs []netip.Addr
+	at    time.Time
+}
+
+// desiredPins maps every VPN endpoint address to the uplink it must leave
+// through: the VPN's bound interface when it has one, the active WAN
+// otherwise. Endpoints whose uplink is unusable keep their current route.
+func (m *Monitor) desiredPins(ctx context.Context, candidates map[string]Candidate, active string, allDown bool, current map[netip.Addr]pin) (map[netip.Addr]pin, []string) {
+	profiles, err := m.vpns.List()
+	if err != nil {
+		return nil, []string{fmt.Sprintf("list vpns: %v", err)}
+	}
+	desired := make(map[netip.Addr]pin)
+	errs := make([]string, 0)
+	for _, profile := range profiles {
+		if profile == nil || profile.UplinkVPN != "" {
+			// Nested tunnels reach their endpoint through the parent VPN.
+			continue
+		}
+		iface := active
+		bound := profile.BoundInterface != ""
+		if bound {
+			iface = profile.BoundInterface
+		}
+		if iface == "" {
+			continue
+		}
+		target, known := candidates[iface]
+		if !known {
+			target = m.inspect(iface)
+			candidates[iface] = target
+		}
+		hold := (bound && !target.usable()) || (!bound && allDown)
+		if bound && !target.usable() {
+			errs = append(errs, fmt.Sprintf("%s: bound interface %s is down or has no default route", profile.Name, iface))
+		}
+		for _, addr := range m.endpointAddrs(ctx, profile.Gateway) {
+			if hold {
+				if existing, ok := current[addr]; ok {
+					desired[addr] = existing
+				}
+				continue
+			}
+			gateway := target.GatewayV4
+			if addr.Is6() {
+				gateway = target.GatewayV6
+			}
+			if gateway == "" {
+				errs = append(errs, fmt.Sprintf("%s: no %s gateway on %s for endpoint %s", profile.Name, familyName(addr), iface, addr))
+				continue
+			}
+			desired[addr] = pin{vpn: profile.Name, iface: iface, gateway: gateway}
+		}
+	}
+	return desired, errs
+}
+
+// endpointAddrs resolves a VPN endpoint host. Hostnames are re-resolved every
+// resolveInterval so dynamic DNS endpoints are followed; the last good answer
+// is reused when resolution fails, which is likely right after an uplink died.
+func (m *Monitor) endpointAddrs(ctx context.Context, host string) []netip.Addr {
+	host = strings.Trim(strings.TrimSpace(host), "[]")
+	if host == "" {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr.Unmap()}
+	}
+	m.mu.Lock()
+	cached, ok := m.resolved[host]
+	m.mu.Unlock()
+	if ok && m.now().Sub(cached.at) < resolveInterval {
+		return cached.addrs
+	}
+	addrs, err := m.lookup(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return cached.addrs
+	}
+	unmapped := make([]netip.Addr, 0, len(addrs))
+	for _, addr := range addrs {
+		unmapped = append(unmapped, addr.Unmap())
+	}
+	sort.Slice(unmapped, func(i, j int) bool { return unmapped[i].Less(unmapped[j]) })
+	m.mu.Lock()
+	m.resolved[host] = resolvedHost{addrs: unmapped, at: m.now()}
+	m.mu.Unlock()
+	return unmapped
+}
+
+// reconcile installs missing or moved pins and removes ones no longer wanted.
+// It returns the set of pins now in place and the routes it removed.
+func (m *Monitor) reconcile(current, desired map[netip.Addr]pin) (map[netip.Addr]pin, []string, []string) {
+	removed := make([]string, 0)
+	pinned := make(map[netip.Addr]pin, len(desired))
+	errs := make([]string, 0)
+	for addr, route := range desired {
+		if existing, ok := current[addr]; ok && existing == route {
+			pinned[addr] = route
+			continue
+		}
+		args := []string{familyFlag(addr), "route", "replace", hostPrefix(addr), "via", route.gateway, "dev", route.iface}
+		if output, err := m.exec("ip", args...); err != nil {
+			errs = append(errs, fmt.Sprintf("pin %s via %s: %s", addr, route.iface, commandError(err, output)))
+			continue
+		}
+		pinned[addr] = route
+	}
+	for addr, route := range current {
+		if _, keep := desired[addr]; keep {
+			if _, ok := pinned[addr]; !ok {
+				// Replacing failed; the old route is still installed.
+				pinned[addr] = route
+			}
+			continue
+		}
+		args := []string{familyFlag(addr), "route", "del", hostPrefix(addr), "dev", route.iface}
+		if output, err := m.exec("ip", args...); err != nil {
+			errs = append(errs, fmt.Sprintf("unpin %s: %s", addr, commandError(err, output)))
+			continue
+		}
+		removed = append(removed, describePin(addr, route))
+	}
+	sort.Strings(removed)
+	return pinned, removed, errs
+}
+
+func describePins(pins map[netip.Addr]pin) []string {
+	out := make([]string, 0, len(pins))
+	for addr, route := range pins {
+		out = append(out, describePin(addr, route))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func describePin(addr netip.Addr, route pin) string {
+	return fmt.Sprintf("%s: %s via %s dev %s", route.vpn, addr, route.gateway, route.iface)
+}
+
+func samePins(a, b map[netip.Addr]pin) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for addr, route := range a {
+		if other, ok := b[addr]; !ok || other != route {
+			return false
+		}
+	}
+	return true
+}
+
+func hostPrefix(addr netip.Addr) string {
+	return netip.PrefixFrom(addr, addr.BitLen()).String()
+}
+
+func familyFlag(addr netip.Addr) string {
+	if addr.Is6() {
+		return "-6"
+	}
+	return "-4"
+}
+
+func familyName(addr netip.Addr) string {
+	if addr.Is6() {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+func commandError(err error, output []byte) string {
+	if detail := strings.TrimSpace(string(output)); detail != "" {
+		return detail
+	}
+	return err.Error()
+}
@@ -0,0 +1,338 @@
+// Package wan tracks which uplink of a multi-WAN gateway is active and keeps
+// VPN endpoint host routes pinned to an uplink, so tunnels follow a failover
+// instead of trying to reach their peer through a dead uplink, and tunnels
+// bound to a specific WAN stay on it.
+package wan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/util"
+	"split-vpn-webui/internal/vpn"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	// resolveInterval bounds how often hostname endpoints are re-resolved.
+	resolveInterval = time.Minute
+)
+
+var ifaceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.:@-]{1,15}$`)
+
+// VPNLister provides the VPN profiles whose endpoints are pinned.
+type VPNLister interface {
+	List() ([]*vpn.VPNProfile, error)
+}
+
+// Candidate is the observed state of one WAN from the priority list.
+type Candidate struct {
+	Interface string `json:"interface"`
+	Up        bool   `json:"up"`
+	GatewayV4 string `json:"gatewayV4,omitempty"`
+	GatewayV6 string `json:"gatewayV6,omitempty"`
+}
+
+// usable reports whether the WAN can carry tunnel traffic.
+func (c Candidate) usable() bool {
+	return c.Up && (c.GatewayV4 != "" || c.GatewayV6 != "")
+}
+
+// Event describes a change of the active WAN or of the pinned endpoint
+// routes, e.g. after a dynamic DNS endpoint moved.
+type Event struct {
+	Previous  string    `json:"previous,omitempty"`
+	Active    string    `json:"active"`
+	ChangedAt time.Time `json:"changedAt"`
+	Pinned    []string  `json:"pinned,omitempty"`
+	Removed   []string  `json:"removed,omitempty"`
+	Errors    []string  `json:"errors,omitempty"`
+}
+
+// Status is returned by the API status endpoint.
+type Status struct {
+	Priority   []string    `json:"priority"`
+	Active     string      `json:"active,omitempty"`
+	Candidates []Candidate `json:"candidates"`
+	Pinned     []string    `json:"pinned"`
+	LastEvent  *Event      `json:"lastEvent,omitempty"`
+}
+
+// Monitor polls the configured WANs and keeps a host route to every VPN
+// endpoint: VPNs bound to an interface leave through it, the rest follow the
+// active WAN of the priority list. Unbound VPNs are not pinned while no
+// priority list is configured.
+type Monitor struct {
+	settings *settings.Manager
+	vpns     VPNLister
+	now      func() time.Time
+
+	linkUp func(iface string) bool
+	exec   func(name string, args ...string) ([]byte, error)
+	lookup func(ctx context.Context, host string) ([]netip.Addr, error)
+
+	mu         sync.Mutex
+	started    bool
+	priority   []string
+	candidates []Candidate
+	active     string
+	pins       map[netip.Addr]pin
+	resolved   map[string]resolvedHost
+	last       *Event
+	handler    func(Event)
+	loopCancel context.CancelFunc
+	loopWG     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor that manages routes with the ip command.
+func NewMonitor(settingsManager *settings.Manager, vpns VPNLister) (*Monitor, error) {
+	if settingsManager == nil {
+		return nil, fmt.Errorf("settings manager is required")
+	}
+	if vpns == nil {
+		return nil, fmt.Errorf("vpn lister is required")
+	}
+	return &Monitor{
+		settings: settingsManager,
+		vpns:     vpns,
+		now:      time.Now,
+		linkUp: func(iface string) bool {
+			up, _, err := util.InterfaceOperState(iface)
+			return err == nil && up
+		},
+		exec: func(name string, args ...string) ([]byte, error) {
+			return exec.Command(name, args...).CombinedOutput()
+		},
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+		pins:     make(map[netip.Addr]pin),
+		resolved: make(map[string]resolvedHost),
+	}, nil
+}
+
+// SetHandler registers a callback invoked whenever the active WAN or the
+// pinned endpoint routes change.
+func (m *Monitor) SetHandler(handler func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// Active returns the interface currently selected as the active WAN, or ""
+// when failover is not configured or no WAN is usable.
+func (m *Monitor) Active() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// Status returns the last observed WAN state.
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := Status{
+		Priority:   append([]string{}, m.priority...),
+		Active:     m.active,
+		Candidates: append([]Candidate{}, m.candidates...),
+		Pinned:     describePins(m.pins),
+	}
+	if m.last != nil {
+		copied := *m.last
+		status.LastEvent = &copied
+	}
+	return status
+}
+
+// Start launches the polling loop.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.started = true
+	m.loopCancel = cancel
+	m.mu.Unlock()
+
+	m.loopWG.Add(1)
+	go func() {
+		defer m.loopWG.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		m.Poll(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Poll(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the polling loop. Pinned routes are left in place so
+// tunnels keep working while the service restarts.
+func (m *Monitor) Stop() error {
+	m.mu.Lock()
+	loopCancel := m.loopCancel
+	m.started = false
+	m.loopCancel = nil
+	m.mu.Unlock()
+
+	if loopCancel != nil {
+		loopCancel()
+	}
+	m.loopWG.Wait()
+	return nil
+}
+
+// Poll runs one detection step and reconciles endpoint routes. It returns an
+// event when the active WAN changed or endpoint routes moved, and nil
+// otherwise.
+func (m *Monitor) Poll(ctx context.Context) *Event {
+	priority := []string{}
+	if loaded, err := m.settings.Get(); err == nil {
+		priority = ParsePriority(loaded.WANPriority)
+	}
+	candidates := make(map[string]Candidate, len(priority))
+	ordered := make([]Candidate, 0, len(priority))
+	active := ""
+	for _, iface := range priority {
+		candidate := m.inspect(iface)
+		candidates[iface] = candidate
+		ordered = append(ordered, candidate)
+		if active == "" && candidate.usable() {
+			active = iface
+		}
+	}
+	// With every uplink down, keep the last active WAN and its routes until
+	// one returns.
+	allDown := active == "" && len(priority) > 0
+
+	m.mu.Lock()
+	previous := m.active
+	m.priority = priority
+	m.candidates = ordered
+	if allDown {
+		active = previous
+	}
+	m.active = active
+	current := make(map[netip.Addr]pin, len(m.pins))
+	for addr, route := range m.pins {
+		current[addr] = route
+	}
+	m.mu.Unlock()
+
+	desired, errs := m.desiredPins(ctx, candidates, active, allDown, current)
+	pinned := current
+	var removed []string
+	if desired != nil {
+		// A nil set means the VPN list could not be read; leave routes alone.
+		var routeErrs []string
+		pinned, removed, routeErrs = m.reconcile(current, desired)
+		errs = append(errs, routeErrs...)
+	}
+
+	m.mu.Lock()
+	m.pins = pinned
+	if previous == active && samePins(current, pinned) {
+		m.mu.Unlock()
+		return nil
+	}
+	event := Event{
+		Previous:  previous,
+		Active:    active,
+		ChangedAt: m.now().UTC(),
+		Pinned:    describePins(pinned),
+		Removed:   removed,
+		Errors:    errs,
+	}
+	m.last = &event
+	handler := m.handler
+	m.mu.Unlock()
+	if handler != nil {
+		handler(event)
+	}
+	return &event
+}
+
+func (m *Monitor) inspect(iface string) Candidate {
+	candidate := Candidate{Interface: iface, Up: m.linkUp(iface)}
+	if !candidate.Up {
+		return candidate
+	}
+	candidate.GatewayV4 = m.defaultGateway(iface, false)
+	candidate.GatewayV6 = m.defaultGateway(iface, true)
+	return candidate
+}
+
+// defaultGateway finds the next hop of a default route through iface in any
+// table; UniFi keeps per-WAN default routes in their own tables.
+func (m *Monitor) defaultGateway(iface string, ipv6 bool) string {
+	family, prefix := "-4", "0.0.0.0/0"
+	if ipv6 {
+		family, prefix = "-6", "::/0"
+	}
+	output, err := m.exec("ip", family, "route", "show", "table", "all", "exact", prefix, "dev", iface)
+	if err != nil {
+		return ""
+	}
+	return parseGateway(string(output))
+}
+
+// parseGateway returns the first "via" address in ip route output.
+func parseGateway(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] != "via" {
+				continue
+			}
+			if addr, err := netip.ParseAddr(fields[i+1]); err == nil {
+				return addr.String()
+			}
+		}
+	}
+	return ""
+}
+
+// ParsePriority splits a WAN priority setting into interface names, in order.
+func ParsePriority(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '\n' || r == ' ' || r == '\t' || r == '\r'
+	})
+	seen := make(map[string]struct{}, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		names = append(names, field)
+	}
+	return names
+}
+
+// NormalizePriority validates a WAN priority setting and returns it as a
+// comma-separated list.
+func NormalizePriority(raw string) (string, error) {
+	names := ParsePriority(raw)
+	for _, name := range names {
+		if !ifaceNamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid WAN interface %q", name)
+		}
+	}
+	return strings.Join(names, ","), nil
+}
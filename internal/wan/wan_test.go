@@ -0,0 +1,221 @@
+package wan
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"split-vpn-webui/internal/settings"
+	"split-vpn-webui/internal/vpn"
+)
+
+type staticLister []*vpn.VPNProfile
+
+func (l staticLister) List() ([]*vpn.VPNProfile, error) {
+	return l, nil
+}
+
+// fakeHost records ip commands and answers default route queries.
+type fakeHost struct {
+	up       map[string]bool
+	gateways map[string]string
+	commands []string
+}
+
+func (h *fakeHost) exec(name string, args ...string) ([]byte, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	if len(args) > 2 && args[1] == "route" && args[2] == "show" {
+		iface := args[len(args)-1]
+		if gateway, ok := h.gateways[args[0]+" "+iface]; ok {
+			return []byte("default via " + gateway + " table 201 proto static metric 100\n"), nil
+		}
+		return nil, nil
+	}
+	h.commands = append(h.commands, command)
+	return nil, nil
+}
+
+func newTestMonitor(t *testing.T, priority string, profiles staticLister, host *fakeHost) *Monitor {
+	t.Helper()
+	manager := settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	if err := manager.Save(settings.Settings{WANPriority: priority}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	monitor, err := NewMonitor(manager, profiles)
+	if err != nil {
+		t.Fatalf("NewMonitor: %v", err)
+	}
+	monitor.linkUp = func(iface string) bool { return host.up[iface] }
+	monitor.exec = host.exec
+	monitor.lookup = func(ctx context.Context, name string) ([]netip.Addr, error) {
+		return nil, errors.New("no resolver in tests")
+	}
+	return monitor
+}
+
+func TestPollPinsEndpointsAndFollowsFailover(t *testing.T) {
+	host := &fakeHost{
+		up: map[string]bool{"eth8": true, "eth9": true},
+		gateways: map[string]string{
+			"-4 eth8": "100.64.0.1",
+			"-4 eth9": "192.168.100.1",
+			"-6 eth9": "fe80::1",
+		},
+	}
+	profiles := staticLister{
+		{Name: "sgp", Gateway: "203.0.113.10"},
+		{Name: "v6", Gateway: "2001:db8::10"},
+		{Name: "pinned", Gateway: "198.51.100.7", BoundInterface: "eth9"},
+		// Nested tunnels reach their endpoint through the parent VPN.
+		{Name: "nested", Gateway: "192.0.2.44", UplinkVPN: "sgp"},
+	}
+	monitor := newTestMonitor(t, "eth8,eth9", profiles, host)
+
+	event := monitor.Poll(context.Background())
+	if event == nil || event.Active != "eth8" || event.Previous != "" {
+		t.Fatalf("expected eth8 to become active, got %+v", event)
+	}
+	want := []string{
+		"ip -4 route replace 198.51.100.7/32 via 192.168.100.1 dev eth9",
+		"ip -4 route replace 203.0.113.10/32 via 100.64.0.1 dev eth8",
+	}
+	if !reflect.DeepEqual(sortedCopy(host.commands), want) {
+		t.Fatalf("unexpected commands %v", host.commands)
+	}
+	if len(event.Errors) != 1 || !strings.Contains(event.Errors[0], "no ipv6 gateway on eth8") {
+		t.Fatalf("expected missing ipv6 gateway error, got %v", event.Errors)
+	}
+
+	host.commands = nil
+	if event := monitor.Poll(context.Background()); event != nil || len(host.commands) != 0 {
+		t.Fatalf("expected a steady poll to change nothing, got %+v %v", event, host.commands)
+	}
+
+	host.up["eth8"] = false
+	event = monitor.Poll(context.Background())
+	if event == nil || event.Previous != "eth8" || event.Active != "eth9" {
+		t.Fatalf("expected failover to eth9, got %+v", event)
+	}
+	want = []string{
+		"ip -4 route replace 203.0.113.10/32 via 192.168.100.1 dev eth9",
+		"ip -6 route replace 2001:db8::10/128 via fe80::1 dev eth9",
+	}
+	if !reflect.DeepEqual(sortedCopy(host.commands), want) {
+		t.Fatalf("unexpected failover commands %v", host.commands)
+	}
+	if monitor.Active() != "eth9" || len(monitor.Status().Pinned) != 3 {
+		t.Fatalf("unexpected status %+v", monitor.Status())
+	}
+}
+
+func TestPollKeepsRoutesWhileAllWANsAreDown(t *testing.T) {
+	host := &fakeHost{up: map[string]bool{"eth8": true}, gateways: map[string]string{"-4 eth8": "100.64.0.1"}}
+	monitor := newTestMonitor(t, "eth8 eth9", staticLister{{Name: "sgp", Gateway: "203.0.113.10"}}, host)
+	monitor.Poll(context.Background())
+
+	host.up["eth8"] = false
+	host.commands = nil
+	if event := monitor.Poll(context.Background()); event != nil || len(host.commands) != 0 {
+		t.Fatalf("expected no changes with every uplink down, got %+v %v", event, host.commands)
+	}
+	if monitor.Active() != "eth8" {
+		t.Fatalf("expected the last active WAN to be kept, got %q", monitor.Active())
+	}
+}
+
+func TestPollRemovesPinsWhenFailoverIsDisabled(t *testing.T) {
+	host := &fakeHost{up: map[string]bool{"eth8": true}, gateways: map[string]string{"-4 eth8": "100.64.0.1"}}
+	monitor := newTestMonitor(t, "eth8", staticLister{{Name: "sgp", Gateway: "203.0.113.10"}}, host)
+	monitor.Poll(context.Background())
+
+	if err := monitor.settings.Save(settings.Settings{}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	host.commands = nil
+	event := monitor.Poll(context.Background())
+	if event == nil || event.Active != "" {
+		t.Fatalf("expected failover to be reported as disabled, got %+v", event)
+	}
+	if !reflect.DeepEqual(host.commands, []string{"ip -4 route del 203.0.113.10/32 dev eth8"}) {
+		t.Fatalf("unexpected commands %v", host.commands)
+	}
+}
+
+func TestPollPinsBoundVPNsWithoutFailover(t *testing.T) {
+	host := &fakeHost{
+		up:       map[string]bool{"eth9": true},
+		gateways: map[string]string{"-4 eth9": "192.168.100.1"},
+	}
+	profiles := staticLister{
+		{Name: "sgp", Gateway: "203.0.113.10"},
+		{Name: "wan2", Gateway: "vpn.example.net", BoundInterface: "eth9"},
+	}
+	monitor := newTestMonitor(t, "", profiles, host)
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	monitor.now = func() time.Time { return clock }
+	answer := "198.51.100.7"
+	lookups := 0
+	monitor.lookup = func(ctx context.Context, name string) ([]netip.Addr, error) {
+		lookups++
+		return []netip.Addr{netip.MustParseAddr(answer)}, nil
+	}
+
+	event := monitor.Poll(context.Background())
+	if event == nil || event.Active != "" {
+		t.Fatalf("expected bound pin event without an active WAN, got %+v", event)
+	}
+	if !reflect.DeepEqual(host.commands, []string{"ip -4 route replace 198.51.100.7/32 via 192.168.100.1 dev eth9"}) {
+		t.Fatalf("expected only the bound VPN to be pinned, got %v", host.commands)
+	}
+
+	// The dynamic DNS name moves; the cached answer is used until it expires.
+	answer = "198.51.100.99"
+	host.commands = nil
+	if event := monitor.Poll(context.Background()); event != nil || lookups != 1 {
+		t.Fatalf("expected cached endpoint within the resolve interval, got %+v after %d lookups", event, lookups)
+	}
+	clock = clock.Add(resolveInterval)
+	event = monitor.Poll(context.Background())
+	if event == nil || len(event.Removed) != 1 {
+		t.Fatalf("expected the old endpoint route to be removed, got %+v", event)
+	}
+	want := []string{
+		"ip -4 route del 198.51.100.7/32 dev eth9",
+		"ip -4 route replace 198.51.100.99/32 via 192.168.100.1 dev eth9",
+	}
+	if !reflect.DeepEqual(sortedCopy(host.commands), want) {
+		t.Fatalf("unexpected re-pin commands %v", host.commands)
+	}
+
+	// A bound WAN going down keeps its route instead of falling back.
+	host.up["eth9"] = false
+	host.commands = nil
+	if event := monitor.Poll(context.Background()); event != nil || len(host.commands) != 0 {
+		t.Fatalf("expected the bound route to be held, got %+v %v", event, host.commands)
+	}
+}
+
+func TestNormalizePriority(t *testing.T) {
+	normalized, err := NormalizePriority(" eth8,\neth9  eth8 ")
+	if err != nil || normalized != "eth8,eth9" {
+		t.Fatalf("unexpected normalization %q %v", normalized, err)
+	}
+	if _, err := NormalizePriority("eth8, bad/name"); err == nil {
+		t.Fatalf("expected invalid interface name to be rejected")
+	}
+	if got := parseGateway("default via 2001:db8::1 dev eth9 proto ra metric 1024\n"); got != "2001:db8::1" {
+		t.Fatalf("unexpected gateway %q", got)
+	}
+}
+
+func sortedCopy(values []string) []string {
+	out := append([]string{}, values...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,73 @@
+(() => {
+  const settingsModalElement = document.getElementById('settingsModal');
+  const eventList = document.getElementById('auth-events');
+  const errorBox = document.getElementById('auth-events-error');
+
+  if (!settingsModalElement || !eventList || !errorBox) {
+    return;
+  }
+
+  const labels = {
+    login_succeeded: 'login',
+    login_failed: 'failed',
+    lockout: 'locked out',
+    rate_limited: 'rate limited',
+    token_rejected: 'bad token',
+  };
+  const badges = {
+    login_succeeded: 'text-bg-success',
+    lockout: 'text-bg-danger',
+    rate_limited: 'text-bg-danger',
+  };
+
+  settingsModalElement.addEventListener('shown.bs.modal', () => {
+    load();
+  });
+
+  async function load() {
+    errorBox.textContent = '';
+    errorBox.classList.add('d-none');
+    try {
+      const response = await fetch('/api/auth/events?limit=20');
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Failed to load sign-in activity');
+      }
+      render(payload.events || []);
+    } catch (err) {
+      eventList.innerHTML = '';
+      errorBox.textContent = err.message;
+      errorBox.classList.remove('d-none');
+    }
+  }
+
+  function render(events) {
+    eventList.innerHTML = '';
+    if (!events.length) {
+      const empty = document.createElement('div');
+      empty.className = 'text-body-secondary';
+      empty.textContent = 'No sign-in activity recorded yet.';
+      eventList.appendChild(empty);
+      return;
+    }
+    events.forEach((event) => {
+      const item = document.createElement('div');
+      item.className = 'list-group-item px-0';
+      const heading = document.createElement('div');
+      const badge = document.createElement('span');
+      badge.className = `badge me-2 ${badges[event.type] || 'text-bg-warning'}`;
+      badge.textContent = labels[event.type] || event.type;
+      const time = document.createElement('span');
+      time.textContent = `${new Date(event.at).toLocaleString()} · ${event.remote || 'unknown'}`;
+      heading.append(badge, time);
+      item.appendChild(heading);
+      if (event.detail) {
+        const detail = document.createElement('div');
+        detail.className = 'text-body-secondary';
+        detail.textContent = event.detail;
+        item.appendChild(detail);
+      }
+      eventList.appendChild(item);
+    });
+  }
+})();
@@ -0,0 +1,106 @@
+(() => {
+  const settingsModalElement = document.getElementById('settingsModal');
+  const sessionList = document.getElementById('auth-sessions');
+  const errorBox = document.getElementById('auth-sessions-error');
+  const revokeOthersButton = document.getElementById('revoke-other-sessions');
+
+  if (!settingsModalElement || !sessionList || !errorBox || !revokeOthersButton) {
+    return;
+  }
+
+  settingsModalElement.addEventListener('shown.bs.modal', () => {
+    load();
+  });
+
+  revokeOthersButton.addEventListener('click', async () => {
+    if (!window.confirm('Sign out every other browser? They will need the password to log in again.')) {
+      return;
+    }
+    revokeOthersButton.disabled = true;
+    try {
+      await request('/api/auth/sessions/revoke-others', { method: 'POST' });
+      await load();
+    } catch (err) {
+      showError(err.message);
+    } finally {
+      revokeOthersButton.disabled = false;
+    }
+  });
+
+  async function request(url, options) {
+    const response = await fetch(url, options);
+    const payload = await response.json().catch(() => ({}));
+    if (!response.ok) {
+      throw new Error(payload.error || response.statusText || 'Request failed');
+    }
+    return payload;
+  }
+
+  async function load() {
+    errorBox.textContent = '';
+    errorBox.classList.add('d-none');
+    try {
+      const payload = await request('/api/auth/sessions');
+      render(payload.sessions || []);
+    } catch (err) {
+      sessionList.innerHTML = '';
+      showError(err.message);
+    }
+  }
+
+  function showError(message) {
+    errorBox.textContent = message;
+    errorBox.classList.remove('d-none');
+  }
+
+  async function revoke(session, button) {
+    button.disabled = true;
+    try {
+      await request(`/api/auth/sessions/${encodeURIComponent(session.id)}`, { method: 'DELETE' });
+      if (session.current) {
+        window.location.href = '/login';
+        return;
+      }
+      await load();
+    } catch (err) {
+      showError(err.message);
+      button.disabled = false;
+    }
+  }
+
+  function render(sessions) {
+    sessionList.innerHTML = '';
+    if (!sessions.length) {
+      const empty = document.createElement('div');
+      empty.className = 'text-body-secondary';
+      empty.textContent = 'No browser sessions.';
+      sessionList.appendChild(empty);
+      return;
+    }
+    sessions.forEach((session) => {
+      const item = document.createElement('div');
+      item.className = 'list-group-item px-0 d-flex align-items-start justify-content-between gap-2';
+      const body = document.createElement('div');
+      body.className = 'text-break';
+      const heading = document.createElement('div');
+      if (session.current) {
+        const badge = document.createElement('span');
+        badge.className = 'badge text-bg-success me-2';
+        badge.textContent = 'this browser';
+        heading.appendChild(badge);
+      }
+      heading.append(session.userAgent || 'Unknown browser');
+      const detail = document.createElement('div');
+      detail.className = 'text-body-secondary';
+      detail.textContent = `${session.remote || 'unknown'} · signed in ${new Date(session.createdAt).toLocaleString()} · last used ${new Date(session.lastSeen).toLocaleString()}`;
+      body.append(heading, detail);
+      const button = document.createElement('button');
+      button.type = 'button';
+      button.className = 'btn btn-outline-danger btn-sm flex-shrink-0';
+      button.textContent = 'Sign out';
+      button.addEventListener('click', () => revoke(session, button));
+      item.append(body, button);
+      sessionList.appendChild(item);
+    });
+  }
+})();
@@ -325,7 +325,8 @@
         record.ifaceEl.textContent = iface.interface || '';
       }
       if (record.badge) {
-        record.badge.textContent = iface.type === 'wan' ? 'WAN' : 'VPN';
+        // Only the active uplink carries tunnels; other WANs are failover standbys.
+        record.badge.textContent = iface.type === 'wan' ? (iface.active ? 'WAN' : 'WAN • Standby') : 'VPN';
       }
       if (record.inspectButton) {
         const vpnName = String(cfg?.name || '').trim();
@@ -383,6 +384,18 @@
         return { text: '', level: 'muted' };
       }
       const displayName = resolveInterfaceDisplayName(iface, cfg);
+      const health = iface.health || {};
+      if (health.status === 'disabled') {
+        return { text: `${displayName} • Stats disabled`, level: 'muted' };
+      }
+      if (health.status === 'read-error') {
+        const errors = Number(health.consecutiveErrors || 0);
+        const detail = health.missingCounters ? 'counters missing' : `${errors} failed read${errors === 1 ? '' : 's'}`;
+        return { text: `${displayName} • Collector error (${detail})`, level: 'danger' };
+      }
+      if (health.status === 'missing') {
+        return { text: `${displayName} • Interface not present`, level: 'warning' };
+      }
       if (!iface.available) {
         return { text: `${displayName} • Interface unavailable`, level: 'warning' };
       }
@@ -413,7 +426,7 @@
         return '';
       }
       if (iface.type === 'wan') {
-        return 'WAN';
+        return iface.name || 'WAN';
       }
       if (cfg && cfg.name) {
         return cfg.name;
@@ -0,0 +1,23 @@
+(() => {
+  // Browser sessions must echo their CSRF token on every request that
+  // changes state; add it to same-origin fetches so callers need not.
+  const meta = document.querySelector('meta[name="csrf-token"]');
+  const token = meta ? meta.getAttribute('content') : '';
+  if (!token || !window.fetch) {
+    return;
+  }
+  const safeMethods = new Set(['GET', 'HEAD', 'OPTIONS']);
+  const nativeFetch = window.fetch.bind(window);
+
+  window.fetch = (input, init = {}) => {
+    const request = input instanceof Request ? input : null;
+    const method = String(init.method || (request && request.method) || 'GET').toUpperCase();
+    const url = new URL(request ? request.url : String(input), window.location.href);
+    if (safeMethods.has(method) || url.origin !== window.location.origin) {
+      return nativeFetch(input, init);
+    }
+    const headers = new Headers(init.headers || (request && request.headers) || undefined);
+    headers.set('X-CSRF-Token', token);
+    return nativeFetch(input, { ...init, headers });
+  };
+})();
@@ -0,0 +1,71 @@
+(() => {
+  const settingsModalElement = document.getElementById('settingsModal');
+  const statusEl = document.getElementById('database-status');
+
+  if (!settingsModalElement || !statusEl) {
+    return;
+  }
+
+  const trackedTables = [
+    ['stats_history', 'throughput samples'],
+    ['resolver_runs', 'resolver runs'],
+    ['prewarm_runs', 'pre-warm runs'],
+    ['vpn_events', 'connection events'],
+  ];
+
+  settingsModalElement.addEventListener('shown.bs.modal', () => {
+    load();
+  });
+
+  async function load() {
+    statusEl.textContent = 'Loading database status…';
+    try {
+      const response = await fetch('/api/database/status');
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Failed to load database status');
+      }
+      render(payload.database || {}, payload.maintenance || {});
+    } catch (err) {
+      statusEl.textContent = err.message;
+    }
+  }
+
+  function render(database, maintenance) {
+    const counts = {};
+    (database.tables || []).forEach((table) => {
+      counts[table.name] = table.rows;
+    });
+    const parts = [`File ${formatBytes(database.sizeBytes)} (WAL ${formatBytes(database.walBytes)})`];
+    trackedTables.forEach(([name, label]) => {
+      if (name in counts) {
+        parts.push(`${Number(counts[name]).toLocaleString()} ${label}`);
+      }
+    });
+    const lastRun = maintenance.lastRun;
+    if (lastRun?.error) {
+      parts.push(`last maintenance failed: ${lastRun.error}`);
+    } else if (lastRun?.at) {
+      parts.push(`last maintenance ${new Date(lastRun.at).toLocaleString()}`);
+    }
+    if (maintenance.lastVacuum) {
+      parts.push(`last vacuum ${new Date(maintenance.lastVacuum).toLocaleString()}`);
+    }
+    statusEl.textContent = parts.join(' · ');
+  }
+
+  function formatBytes(value) {
+    const bytes = Number(value || 0);
+    if (bytes < 1024) {
+      return `${bytes} B`;
+    }
+    const units = ['KB', 'MB', 'GB'];
+    let size = bytes / 1024;
+    let unit = 0;
+    while (size >= 1024 && unit < units.length - 1) {
+      size /= 1024;
+      unit += 1;
+    }
+    return `${size.toFixed(1)} ${units[unit]}`;
+  }
+})();
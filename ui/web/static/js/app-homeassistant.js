@@ -0,0 +1,106 @@
+(() => {
+  const settingsModalElement = document.getElementById('settingsModal');
+  const tokenInput = document.getElementById('ha-token');
+  const copyButton = document.getElementById('copy-ha-token');
+  const regenerateButton = document.getElementById('regenerate-ha-token');
+  const revokeButton = document.getElementById('revoke-ha-token');
+  const statusText = document.getElementById('ha-token-status');
+
+  if (!settingsModalElement || !tokenInput || !copyButton || !regenerateButton || !revokeButton || !statusText) {
+    return;
+  }
+
+  const helpText = statusText.innerHTML;
+
+  settingsModalElement.addEventListener('shown.bs.modal', async () => {
+    statusText.innerHTML = helpText;
+    try {
+      const result = await fetchJSON('/api/auth/ha-token');
+      renderToken(result.token || '');
+    } catch (err) {
+      showStatus(err.message);
+    }
+  });
+
+  regenerateButton.addEventListener('click', async () => {
+    if (tokenInput.value && !window.confirm('Generate a new Home Assistant token? The current one stops working.')) {
+      return;
+    }
+    regenerateButton.disabled = true;
+    try {
+      const result = await fetchJSON('/api/auth/ha-token', { method: 'POST' });
+      renderToken(result.token || '');
+      showStatus('Home Assistant token generated.');
+    } catch (err) {
+      showStatus(err.message);
+    } finally {
+      regenerateButton.disabled = false;
+    }
+  });
+
+  revokeButton.addEventListener('click', async () => {
+    revokeButton.disabled = true;
+    try {
+      await fetchJSON('/api/auth/ha-token', { method: 'DELETE' });
+      renderToken('');
+      showStatus('Home Assistant token revoked.');
+    } catch (err) {
+      showStatus(err.message);
+      revokeButton.disabled = false;
+    }
+  });
+
+  copyButton.addEventListener('click', async () => {
+    const token = tokenInput.value || '';
+    if (!token) {
+      return;
+    }
+    try {
+      if (navigator.clipboard && navigator.clipboard.writeText) {
+        await navigator.clipboard.writeText(token);
+      } else {
+        tokenInput.select();
+        tokenInput.setSelectionRange(0, token.length);
+        if (!document.execCommand('copy')) {
+          throw new Error('copy failed');
+        }
+      }
+      showStatus('Home Assistant token copied.');
+    } catch (err) {
+      showStatus('Failed to copy Home Assistant token.');
+    }
+  });
+
+  function renderToken(token) {
+    tokenInput.value = token;
+    copyButton.disabled = !token;
+    revokeButton.disabled = !token;
+    regenerateButton.innerHTML = token
+      ? '<i class="bi bi-arrow-repeat me-1"></i>Regenerate'
+      : '<i class="bi bi-arrow-repeat me-1"></i>Generate';
+  }
+
+  function showStatus(message) {
+    statusText.textContent = message || '';
+  }
+
+  async function fetchJSON(url, options = {}) {
+    const response = await fetch(url, options);
+    const contentType = response.headers.get('content-type') || '';
+    let parsed = null;
+    if (contentType.includes('application/json')) {
+      try {
+        parsed = await response.json();
+      } catch (err) {
+        parsed = null;
+      }
+    }
+    if (!response.ok) {
+      if (parsed && typeof parsed.error === 'string' && parsed.error) {
+        throw new Error(parsed.error);
+      }
+      throw new Error(response.statusText || 'Request failed');
+    }
+    return parsed || {};
+  }
+})();
@@ -0,0 +1,260 @@
+(() => {
+  const grid = document.getElementById('kiosk-vpns');
+  const updated = document.getElementById('kiosk-updated');
+  const wanLabel = document.getElementById('kiosk-wan');
+  const errorBox = document.getElementById('kiosk-error');
+  const inspector = document.getElementById('kiosk-inspector');
+  const inspectorTitle = document.getElementById('kiosk-inspector-title');
+  const inspectorBody = document.getElementById('kiosk-inspector-body');
+  const inspectorClose = document.getElementById('kiosk-inspector-close');
+
+  if (!grid || !updated || !errorBox || !inspector) {
+    return;
+  }
+
+  const cards = new Map();
+  let inspected = '';
+
+  inspectorClose.addEventListener('click', () => {
+    inspected = '';
+    inspector.classList.add('d-none');
+  });
+
+  const stream = new EventSource('/api/stream');
+  stream.onmessage = (event) => {
+    try {
+      render(JSON.parse(event.data));
+      errorBox.classList.add('d-none');
+    } catch (err) {
+      showError(`Bad update: ${err.message}`);
+    }
+  };
+  stream.onerror = () => {
+    showError('Connection lost; reconnecting…');
+  };
+
+  // Refresh the open inspector alongside the live cards.
+  setInterval(() => {
+    if (inspected) {
+      inspect(inspected);
+    }
+  }, 60000);
+
+  function showError(message) {
+    errorBox.textContent = message;
+    errorBox.classList.remove('d-none');
+  }
+
+  function render(payload) {
+    const interfaces = payload.stats?.interfaces || [];
+    const latency = new Map((payload.latency || []).map((result) => [result.name, result]));
+    const configs = (payload.configs || []).slice().sort((a, b) => a.name.localeCompare(b.name));
+
+    const wan = interfaces.find((iface) => iface.type === 'wan' && (iface.active || iface.name === payload.stats?.activeWan))
+      || interfaces.find((iface) => iface.type === 'wan');
+    if (wan && wanLabel) {
+      wanLabel.textContent = `WAN ${wan.name}: ↓ ${formatThroughput(wan.currentRxThroughput || 0)} ↑ ${formatThroughput(wan.currentTxThroughput || 0)}`;
+    }
+
+    const seen = new Set();
+    configs.forEach((config) => {
+      seen.add(config.name);
+      const iface = interfaces.find((item) => item.name === config.name || item.interface === config.interfaceName);
+      const card = cards.get(config.name) || createCard(config.name);
+      updateCard(card, config, iface, latency.get(config.name));
+    });
+    cards.forEach((card, name) => {
+      if (!seen.has(name)) {
+        card.chart.destroy();
+        card.column.remove();
+        cards.delete(name);
+      }
+    });
+    if (!configs.length) {
+      grid.textContent = 'No VPNs configured.';
+    }
+    updated.textContent = `Updated ${new Date(payload.stats?.generatedAt || Date.now()).toLocaleTimeString()}`;
+  }
+
+  function createCard(name) {
+    if (!cards.size) {
+      grid.textContent = '';
+    }
+    const column = document.createElement('div');
+    column.className = 'col-12 col-md-6 col-xl-4';
+    const card = document.createElement('div');
+    card.className = 'card h-100';
+    card.setAttribute('role', 'button');
+    card.title = 'Show routing and top destinations';
+    card.addEventListener('click', () => inspect(name));
+    const body = document.createElement('div');
+    body.className = 'card-body';
+    const heading = document.createElement('div');
+    heading.className = 'd-flex align-items-center justify-content-between mb-2';
+    const title = document.createElement('h5');
+    title.className = 'mb-0 text-truncate';
+    title.textContent = name;
+    const badge = document.createElement('span');
+    badge.className = 'badge';
+    heading.append(title, badge);
+    const metrics = document.createElement('div');
+    metrics.className = 'd-flex justify-content-between small text-body-secondary mb-2';
+    const latencyText = document.createElement('span');
+    const throughputText = document.createElement('span');
+    metrics.append(latencyText, throughputText);
+    const canvasWrap = document.createElement('div');
+    canvasWrap.style.height = '80px';
+    const canvas = document.createElement('canvas');
+    canvasWrap.appendChild(canvas);
+    body.append(heading, metrics, canvasWrap);
+    card.appendChild(body);
+    column.appendChild(card);
+    grid.appendChild(column);
+
+    const chart = new Chart(canvas.getContext('2d'), {
+      type: 'line',
+      data: {
+        labels: [],
+        datasets: [
+          { data: [], borderColor: '#60a5fa', backgroundColor: 'rgba(96, 165, 250, 0.15)', fill: true, pointRadius: 0, tension: 0.3 },
+          { data: [], borderColor: '#f87171', backgroundColor: 'rgba(248, 113, 113, 0.15)', fill: true, pointRadius: 0, tension: 0.3 },
+        ],
+      },
+      options: {
+        animation: false,
+        maintainAspectRatio: false,
+        plugins: { legend: { display: false }, tooltip: { enabled: false } },
+        scales: { x: { display: false }, y: { display: false, beginAtZero: true } },
+      },
+    });
+    const entry = { column, card, badge, latencyText, throughputText, chart };
+    cards.set(name, entry);
+    return entry;
+  }
+
+  function updateCard(card, config, iface, result) {
+    const healthy = config.connected && (!result || !result.checkedAt || result.success);
+    card.badge.className = `badge ${config.connected ? (healthy ? 'text-bg-success' : 'text-bg-warning') : 'text-bg-danger'}`;
+    card.badge.textContent = config.connected ? (healthy ? 'up' : 'degraded') : 'down';
+    card.card.classList.toggle('border-danger', !config.connected);
+    card.latencyText.textContent = result && result.success ? `Latency ${formatLatency(result.latencyMs)}` : 'Latency –';
+    card.throughputText.textContent = iface
+      ? `↓ ${formatThroughput(iface.currentRxThroughput || 0)} ↑ ${formatThroughput(iface.currentTxThroughput || 0)}`
+      : '';
+    const history = iface?.history || [];
+    card.chart.data.labels = history.map((point) => point.timestamp);
+    card.chart.data.datasets[0].data = history.map((point) => point.rxThroughput);
+    card.chart.data.datasets[1].data = history.map((point) => point.txThroughput);
+    card.chart.update('none');
+  }
+
+  async function inspect(name) {
+    inspected = name;
+    inspector.classList.remove('d-none');
+    inspectorTitle.textContent = name;
+    const encoded = encodeURIComponent(name);
+    const [routing, flows] = await Promise.all([
+      fetchJSON(`/api/vpns/${encoded}/routing-inspector`),
+      fetchJSON(`/api/vpns/${encoded}/flow-inspector/history?limit=10`),
+    ]);
+    if (inspected !== name) {
+      return;
+    }
+    inspectorBody.innerHTML = '';
+    inspectorBody.append(renderRouting(routing), renderFlows(flows));
+  }
+
+  async function fetchJSON(url) {
+    try {
+      const response = await fetch(url);
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        return { error: payload.error || response.statusText };
+      }
+      return payload;
+    } catch (err) {
+      return { error: err.message };
+    }
+  }
+
+  function renderRouting(routing) {
+    const section = document.createElement('div');
+    section.className = 'mb-3';
+    const heading = document.createElement('h6');
+    heading.textContent = 'Routing';
+    section.appendChild(heading);
+    if (routing.error) {
+      section.append(routing.error);
+      return section;
+    }
+    const summary = document.createElement('div');
+    summary.className = 'text-body-secondary mb-1';
+    summary.textContent = `${routing.routingV4Size || 0} IPv4 and ${routing.routingV6Size || 0} IPv6 destinations routed`;
+    section.appendChild(summary);
+    (routing.groups || []).forEach((group) => {
+      const line = document.createElement('div');
+      const rules = group.rules || [];
+      const domains = rules.reduce((count, rule) => count + (rule.domains || []).length + (rule.wildcardDomains || []).length, 0);
+      line.textContent = `${group.name}: ${rules.length} rule${rules.length === 1 ? '' : 's'}, ${domains} domain${domains === 1 ? '' : 's'}`;
+      section.appendChild(line);
+    });
+    return section;
+  }
+
+  function renderFlows(flows) {
+    const section = document.createElement('div');
+    const heading = document.createElement('h6');
+    heading.textContent = 'Top destinations';
+    section.appendChild(heading);
+    if (flows.error) {
+      section.append(flows.error);
+      return section;
+    }
+    const destinations = flows.destinations || [];
+    if (!destinations.length) {
+      section.append('No flows recorded recently.');
+      return section;
+    }
+    const table = document.createElement('table');
+    table.className = 'table table-sm mb-0';
+    destinations.forEach((destination) => {
+      const row = table.insertRow();
+      row.insertCell().textContent = destination.domain || destination.destination;
+      row.insertCell().textContent = `${destination.flows} flow${destination.flows === 1 ? '' : 's'}`;
+      const bytes = row.insertCell();
+      bytes.className = 'text-end';
+      bytes.textContent = formatBytes(destination.totalBytes || 0);
+    });
+    section.appendChild(table);
+    return section;
+  }
+
+  function formatThroughput(value) {
+    const units = ['bps', 'Kbps', 'Mbps', 'Gbps', 'Tbps'];
+    let val = value;
+    let index = 0;
+    while (val >= 1000 && index < units.length - 1) {
+      val /= 1000;
+      index++;
+    }
+    return `${val.toFixed(val >= 100 ? 0 : val >= 10 ? 1 : 2)} ${units[index]}`;
+  }
+
+  function formatBytes(value) {
+    const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+    let val = value;
+    let index = 0;
+    while (val >= 1024 && index < units.length - 1) {
+      val /= 1024;
+      index++;
+    }
+    return `${val.toFixed(val >= 100 ? 0 : val >= 10 ? 1 : 2)} ${units[index]}`;
+  }
+
+  function formatLatency(value) {
+    if (value >= 1000) {
+      return `${(value / 1000).toFixed(2)} s`;
+    }
+    return `${value.toFixed(0)} ms`;
+  }
+})();
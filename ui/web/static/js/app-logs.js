@@ -0,0 +1,177 @@
+(() => {
+  const openButton = document.getElementById('open-app-logs');
+  const modalElement = document.getElementById('appLogsModal');
+  const levelSelect = document.getElementById('app-logs-level');
+  const moduleSelect = document.getElementById('app-logs-module');
+  const followToggle = document.getElementById('app-logs-follow');
+  const clearButton = document.getElementById('app-logs-clear');
+  const statusBox = document.getElementById('app-logs-status');
+  const scrollBox = document.getElementById('app-logs-scroll');
+  const body = document.getElementById('app-logs-body');
+
+  if (!openButton || !modalElement || !levelSelect || !moduleSelect || !followToggle || !clearButton || !statusBox || !scrollBox || !body) {
+    return;
+  }
+
+  const maxRows = 1000;
+  const modal = new bootstrap.Modal(modalElement);
+  let stream = null;
+  let lastID = 0;
+  let reconnectTimer = null;
+
+  openButton.addEventListener('click', () => {
+    modal.show();
+    reload();
+  });
+
+  modalElement.addEventListener('hidden.bs.modal', () => {
+    disconnect();
+  });
+
+  levelSelect.addEventListener('change', reload);
+  moduleSelect.addEventListener('change', reload);
+  followToggle.addEventListener('change', () => {
+    if (followToggle.checked) {
+      connect();
+    } else {
+      disconnect();
+    }
+  });
+  clearButton.addEventListener('click', () => {
+    body.innerHTML = '';
+  });
+
+  async function reload() {
+    disconnect();
+    hideStatus();
+    body.innerHTML = '';
+    lastID = 0;
+    try {
+      const response = await fetch(`/api/logs?${filterQuery()}`);
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Failed to load log');
+      }
+      renderModules(Array.isArray(payload.modules) ? payload.modules : []);
+      appendEntries(Array.isArray(payload.entries) ? payload.entries : []);
+      if (!body.children.length) {
+        showStatus('No log entries match the current filter.', 'alert-secondary');
+      }
+    } catch (err) {
+      showStatus(err.message, 'alert-danger');
+      return;
+    }
+    if (followToggle.checked) {
+      connect();
+    }
+  }
+
+  function connect() {
+    disconnect();
+    stream = new EventSource(`/api/logs/stream?${filterQuery()}&since=${lastID}`);
+    stream.onmessage = (event) => {
+      try {
+        hideStatus();
+        appendEntries([JSON.parse(event.data)]);
+      } catch (err) {}
+    };
+    stream.onerror = () => {
+      disconnect();
+      reconnectTimer = setTimeout(connect, 4000);
+    };
+  }
+
+  function disconnect() {
+    if (reconnectTimer) {
+      clearTimeout(reconnectTimer);
+      reconnectTimer = null;
+    }
+    if (stream) {
+      stream.close();
+      stream = null;
+    }
+  }
+
+  function filterQuery() {
+    const params = new URLSearchParams({ level: levelSelect.value });
+    if (moduleSelect.value) {
+      params.set('module', moduleSelect.value);
+    }
+    return params.toString();
+  }
+
+  function renderModules(modules) {
+    const selected = moduleSelect.value;
+    const names = modules.includes(selected) || !selected ? modules : [...modules, selected];
+    moduleSelect.innerHTML = '<option value="">All modules</option>' + names
+      .map((name) => `<option value="${escapeHTML(name)}">${escapeHTML(name)}</option>`)
+      .join('');
+    moduleSelect.value = selected;
+  }
+
+  function appendEntries(entries) {
+    if (!entries.length) {
+      return;
+    }
+    const stickToBottom = scrollBox.scrollTop + scrollBox.clientHeight >= scrollBox.scrollHeight - 8;
+    const rows = entries
+      .filter((entry) => entry.id > lastID)
+      .map((entry) => {
+        lastID = Math.max(lastID, entry.id);
+        return `
+          <tr>
+            <td class="text-nowrap font-monospace">${escapeHTML(formatTime(entry.time))}</td>
+            <td><span class="badge ${levelBadge(entry.level)}">${escapeHTML(entry.level)}</span></td>
+            <td>${escapeHTML(entry.module)}</td>
+            <td class="font-monospace text-break">${escapeHTML(entry.message)}</td>
+          </tr>`;
+      })
+      .join('');
+    body.insertAdjacentHTML('beforeend', rows);
+    while (body.children.length > maxRows) {
+      body.removeChild(body.firstElementChild);
+    }
+    if (stickToBottom) {
+      scrollBox.scrollTop = scrollBox.scrollHeight;
+    }
+  }
+
+  function levelBadge(level) {
+    switch (level) {
+      case 'ERROR':
+        return 'text-bg-danger';
+      case 'WARN':
+        return 'text-bg-warning';
+      case 'DEBUG':
+        return 'text-bg-secondary';
+      default:
+        return 'text-bg-info';
+    }
+  }
+
+  function formatTime(value) {
+    const date = new Date(value);
+    if (Number.isNaN(date.getTime())) {
+      return '';
+    }
+    return date.toLocaleString();
+  }
+
+  function escapeHTML(value) {
+    return String(value ?? '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;');
+  }
+
+  function showStatus(message, variant) {
+    statusBox.className = `alert py-2 small mb-3 ${variant}`;
+    statusBox.textContent = message;
+  }
+
+  function hideStatus() {
+    statusBox.className = 'alert d-none py-2 small mb-3';
+    statusBox.textContent = '';
+  }
+})();
@@ -0,0 +1,284 @@
+(() => {
+  const openButton = document.getElementById('open-remote-agents');
+  const modalElement = document.getElementById('remoteAgentsModal');
+  const list = document.getElementById('remote-agents-list');
+  const statusBox = document.getElementById('remote-agents-status');
+  const nameInput = document.getElementById('remote-agent-name');
+  const hostInput = document.getElementById('remote-agent-host');
+  const portInput = document.getElementById('remote-agent-port');
+  const userInput = document.getElementById('remote-agent-user');
+  const hostKeyInput = document.getElementById('remote-agent-host-key');
+  const scanButton = document.getElementById('remote-agent-scan');
+  const dnsmasqDirInput = document.getElementById('remote-agent-dnsmasq-dir');
+  const enabledInput = document.getElementById('remote-agent-enabled');
+  const stateLabel = document.getElementById('remote-agent-state');
+  const publicKeyInput = document.getElementById('remote-agents-public-key');
+  const newButton = document.getElementById('new-remote-agent');
+  const applyButton = document.getElementById('apply-remote-agent');
+  const deleteButton = document.getElementById('delete-remote-agent');
+  const saveButton = document.getElementById('save-remote-agent');
+
+  if (
+    !openButton ||
+    !modalElement ||
+    !list ||
+    !statusBox ||
+    !nameInput ||
+    !hostInput ||
+    !portInput ||
+    !userInput ||
+    !hostKeyInput ||
+    !scanButton ||
+    !dnsmasqDirInput ||
+    !enabledInput ||
+    !stateLabel ||
+    !publicKeyInput ||
+    !newButton ||
+    !applyButton ||
+    !deleteButton ||
+    !saveButton
+  ) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  let agents = [];
+  let statuses = [];
+  let selectedID = 0;
+
+  openButton.addEventListener('click', async () => {
+    hideStatus();
+    await reload();
+    modal.show();
+  });
+
+  modalElement.addEventListener('remote-agents:changed', () => {
+    if (modalElement.classList.contains('show')) {
+      reloadStatuses();
+    }
+  });
+
+  newButton.addEventListener('click', () => {
+    selectedID = 0;
+    fillEditor(null);
+    renderList();
+  });
+
+  scanButton.addEventListener('click', async () => {
+    const host = hostInput.value.trim();
+    if (!host) {
+      showStatus('Enter the router host first.', true);
+      return;
+    }
+    scanButton.disabled = true;
+    try {
+      const result = await fetchJSON('/api/agents/host-key', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ host, port: Number(portInput.value || 0) }),
+      });
+      hostKeyInput.value = result.hostKey || '';
+      showStatus('Host key fetched. Check it against the router before saving.', false);
+    } catch (err) {
+      showStatus(err.message, true);
+    } finally {
+      scanButton.disabled = false;
+    }
+  });
+
+  saveButton.addEventListener('click', async () => {
+    const payload = {
+      name: nameInput.value.trim(),
+      host: hostInput.value.trim(),
+      port: Number(portInput.value || 0),
+      user: userInput.value.trim(),
+      hostKey: hostKeyInput.value.trim(),
+      dnsmasqDir: dnsmasqDirInput.value.trim(),
+      enabled: enabledInput.checked,
+    };
+    saveButton.disabled = true;
+    try {
+      const url = selectedID > 0 ? `/api/agents/${selectedID}` : '/api/agents';
+      const method = selectedID > 0 ? 'PUT' : 'POST';
+      const result = await fetchJSON(url, {
+        method,
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+      });
+      selectedID = result.agent ? result.agent.id : selectedID;
+      await reload();
+      showStatus(payload.enabled ? 'Agent saved; applying routing in the background.' : 'Agent saved.', false);
+    } catch (err) {
+      showStatus(err.message, true);
+    } finally {
+      saveButton.disabled = false;
+    }
+  });
+
+  applyButton.addEventListener('click', async () => {
+    if (selectedID <= 0) {
+      return;
+    }
+    applyButton.disabled = true;
+    try {
+      await fetchJSON(`/api/agents/${selectedID}/apply`, { method: 'POST' });
+      showStatus('Routing applied on the router.', false);
+    } catch (err) {
+      showStatus(err.message, true);
+    } finally {
+      applyButton.disabled = false;
+      await reloadStatuses();
+    }
+  });
+
+  deleteButton.addEventListener('click', async () => {
+    if (selectedID <= 0) {
+      return;
+    }
+    deleteButton.disabled = true;
+    try {
+      try {
+        await fetchJSON(`/api/agents/${selectedID}`, { method: 'DELETE' });
+      } catch (err) {
+        if (!window.confirm(`${err.message}\n\nDelete the agent anyway and leave its rules on the router?`)) {
+          throw err;
+        }
+        await fetchJSON(`/api/agents/${selectedID}?force=1`, { method: 'DELETE' });
+      }
+      selectedID = 0;
+      await reload();
+      showStatus('Agent deleted.', false);
+    } catch (err) {
+      showStatus(err.message, true);
+    } finally {
+      deleteButton.disabled = false;
+    }
+  });
+
+  async function reload() {
+    try {
+      const result = await fetchJSON('/api/agents');
+      agents = Array.isArray(result.agents) ? result.agents : [];
+      statuses = Array.isArray(result.statuses) ? result.statuses : [];
+      publicKeyInput.value = result.publicKey || '';
+    } catch (err) {
+      agents = [];
+      statuses = [];
+      showStatus(err.message, true);
+    }
+    if (!agents.some((agent) => agent.id === selectedID)) {
+      selectedID = 0;
+    }
+    fillEditor(agents.find((agent) => agent.id === selectedID) || null);
+    renderList();
+  }
+
+  async function reloadStatuses() {
+    try {
+      const result = await fetchJSON('/api/agents');
+      statuses = Array.isArray(result.statuses) ? result.statuses : [];
+    } catch (err) {
+      return;
+    }
+    renderList();
+    renderState();
+  }
+
+  function renderList() {
+    list.innerHTML = '';
+    if (agents.length === 0) {
+      const empty = document.createElement('div');
+      empty.className = 'text-body-secondary small';
+      empty.textContent = 'No remote agents yet.';
+      list.appendChild(empty);
+      return;
+    }
+    agents.forEach((agent) => {
+      const item = document.createElement('button');
+      item.type = 'button';
+      item.className = 'list-group-item list-group-item-action d-flex align-items-center gap-2';
+      if (agent.id === selectedID) {
+        item.classList.add('active');
+      }
+      const dot = document.createElement('i');
+      dot.className = `bi bi-circle-fill ${stateClass(agent)}`;
+      const label = document.createElement('span');
+      label.textContent = agent.name;
+      item.append(dot, label);
+      item.addEventListener('click', () => {
+        selectedID = agent.id;
+        fillEditor(agent);
+        renderList();
+      });
+      list.appendChild(item);
+    });
+  }
+
+  function fillEditor(agent) {
+    nameInput.value = agent ? agent.name : '';
+    hostInput.value = agent ? agent.host : '';
+    portInput.value = agent ? String(agent.port || '') : '';
+    userInput.value = agent ? agent.user : '';
+    hostKeyInput.value = agent ? agent.hostKey : '';
+    dnsmasqDirInput.value = agent ? agent.dnsmasqDir : '';
+    enabledInput.checked = agent ? Boolean(agent.enabled) : true;
+    deleteButton.disabled = !agent;
+    applyButton.disabled = !agent || !agent.enabled;
+    renderState();
+  }
+
+  function renderState() {
+    const status = statuses.find((entry) => entry.agentId === selectedID);
+    if (!status || selectedID <= 0) {
+      stateLabel.textContent = '';
+      return;
+    }
+    if (!status.enabled) {
+      stateLabel.textContent = 'Disabled; rules already on the router are left in place.';
+    } else if (status.lastError) {
+      stateLabel.textContent = `Last apply failed: ${status.lastError}`;
+    } else if (status.lastSuccess) {
+      stateLabel.textContent = `In sync since ${new Date(status.lastSuccess).toLocaleString()}.`;
+    } else {
+      stateLabel.textContent = 'Not applied yet.';
+    }
+  }
+
+  function stateClass(agent) {
+    const status = statuses.find((entry) => entry.agentId === agent.id);
+    if (!agent.enabled || !status || !status.lastAttempt) {
+      return 'text-secondary';
+    }
+    return status.lastError ? 'text-danger' : 'text-success';
+  }
+
+  async function fetchJSON(url, options = {}) {
+    const response = await fetch(url, options);
+    const contentType = response.headers.get('content-type') || '';
+    let parsed = null;
+    if (contentType.includes('application/json')) {
+      try {
+        parsed = await response.json();
+      } catch (err) {
+        parsed = null;
+      }
+    }
+    if (!response.ok) {
+      if (parsed && typeof parsed.error === 'string' && parsed.error) {
+        throw new Error(parsed.error);
+      }
+      throw new Error(response.statusText || 'Request failed');
+    }
+    return parsed || {};
+  }
+
+  function showStatus(message, isError) {
+    statusBox.classList.remove('d-none', 'alert-success', 'alert-danger');
+    statusBox.classList.add(isError ? 'alert-danger' : 'alert-success');
+    statusBox.textContent = message || '';
+  }
+
+  function hideStatus() {
+    statusBox.classList.add('d-none');
+  }
+})();
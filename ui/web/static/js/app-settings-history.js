@@ -0,0 +1,108 @@
+(() => {
+  const settingsModalElement = document.getElementById('settingsModal');
+  const revisionList = document.getElementById('settings-revisions');
+  const errorBox = document.getElementById('settings-revisions-error');
+
+  if (!settingsModalElement || !revisionList || !errorBox) {
+    return;
+  }
+
+  settingsModalElement.addEventListener('shown.bs.modal', () => {
+    load();
+  });
+
+  revisionList.addEventListener('click', async (event) => {
+    const button = event.target.closest('[data-settings-revision]');
+    if (!button) {
+      return;
+    }
+    const id = button.getAttribute('data-settings-revision');
+    hideError();
+    button.disabled = true;
+    try {
+      const detail = await request(`/api/settings/revisions/${encodeURIComponent(id)}`);
+      const changes = Array.isArray(detail.changes) ? detail.changes : [];
+      if (!changes.length) {
+        throw new Error('The current settings already match this revision.');
+      }
+      if (!window.confirm(`Roll back to revision ${id}? This changes: ${changes.join(', ')}.`)) {
+        return;
+      }
+      await request(`/api/settings/revisions/${encodeURIComponent(id)}/rollback`, { method: 'POST' });
+      window.location.reload();
+    } catch (err) {
+      showError(err.message);
+    } finally {
+      button.disabled = false;
+    }
+  });
+
+  async function load() {
+    hideError();
+    try {
+      const page = await request('/api/settings/revisions?limit=20');
+      render(page.revisions || []);
+    } catch (err) {
+      revisionList.innerHTML = '';
+      showError(err.message);
+    }
+  }
+
+  function render(revisions) {
+    revisionList.innerHTML = '';
+    if (!revisions.length) {
+      const empty = document.createElement('div');
+      empty.className = 'text-body-secondary';
+      empty.textContent = 'No settings changes recorded yet.';
+      revisionList.appendChild(empty);
+      return;
+    }
+    revisions.forEach((revision, index) => {
+      const item = document.createElement('div');
+      item.className = 'list-group-item px-0 d-flex align-items-start gap-2';
+      const body = document.createElement('div');
+      body.className = 'me-auto';
+      const heading = document.createElement('div');
+      const badge = document.createElement('span');
+      badge.className = `badge me-2 ${index === 0 ? 'text-bg-success' : 'text-bg-secondary'}`;
+      badge.textContent = revision.action;
+      const time = document.createElement('span');
+      time.textContent = new Date(revision.createdAt).toLocaleString();
+      heading.append(badge, time);
+      const detail = document.createElement('div');
+      detail.className = 'text-body-secondary';
+      const fields = Array.isArray(revision.fields) ? revision.fields : [];
+      detail.textContent = `${revision.actor || 'unknown'}${fields.length ? ` · ${fields.join(', ')}` : ''}`;
+      body.append(heading, detail);
+      item.appendChild(body);
+      if (index > 0) {
+        const button = document.createElement('button');
+        button.type = 'button';
+        button.className = 'btn btn-sm btn-outline-warning';
+        button.textContent = 'Roll back';
+        button.setAttribute('data-settings-revision', String(revision.id));
+        item.appendChild(button);
+      }
+      revisionList.appendChild(item);
+    });
+  }
+
+  async function request(url, options = {}) {
+    const response = await fetch(url, options);
+    const payload = await response.json().catch(() => ({}));
+    if (!response.ok) {
+      throw new Error(payload.error || response.statusText || 'Request failed');
+    }
+    return payload;
+  }
+
+  function showError(message) {
+    errorBox.textContent = message;
+    errorBox.classList.remove('d-none');
+  }
+
+  function hideError() {
+    errorBox.textContent = '';
+    errorBox.classList.add('d-none');
+  }
+})();
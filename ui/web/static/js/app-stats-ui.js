@@ -65,7 +65,8 @@
         wanLabel.textContent = '';
         return;
       }
-      const wan = (stats.interfaces || []).find((iface) => iface.type === 'wan');
+      const wans = (stats.interfaces || []).filter((iface) => iface.type === 'wan');
+      const wan = wans.find((iface) => iface.active) || wans[0];
       if (!wan) {
         wanLabel.textContent = 'WAN interface not detected';
         return;
@@ -0,0 +1,82 @@
+(() => {
+  const checkButton = document.getElementById('check-capabilities');
+  const reportEl = document.getElementById('capabilities-report');
+
+  if (!checkButton || !reportEl) {
+    return;
+  }
+
+  const statusBadges = {
+    ok: 'text-bg-success',
+    warn: 'text-bg-warning',
+    fail: 'text-bg-danger',
+  };
+
+  checkButton.addEventListener('click', async () => {
+    checkButton.disabled = true;
+    reportEl.classList.remove('d-none');
+    reportEl.textContent = 'Checking…';
+    try {
+      const response = await fetch('/api/system/capabilities');
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Capability check failed');
+      }
+      render(payload);
+    } catch (err) {
+      reportEl.textContent = err.message;
+    } finally {
+      checkButton.disabled = false;
+    }
+  });
+
+  function render(report) {
+    reportEl.innerHTML = '';
+    const summary = document.createElement('div');
+    summary.className = `mb-2 ${report.passed ? 'text-success' : 'text-danger'}`;
+    summary.textContent = report.passed
+      ? 'Everything routing requires is available.'
+      : 'Required tools or kernel modules are missing.';
+    if (report.kernel) {
+      summary.textContent += ` Kernel ${report.kernel}.`;
+    }
+    reportEl.appendChild(summary);
+
+    const list = document.createElement('div');
+    list.className = 'list-group list-group-flush';
+    // Problems first, so a failing router shows what to fix at the top.
+    const order = { fail: 0, warn: 1, ok: 2 };
+    const checks = Array.isArray(report.checks) ? [...report.checks] : [];
+    checks.sort((a, b) => (order[a.status] ?? 3) - (order[b.status] ?? 3));
+    checks.forEach((check) => {
+      const item = document.createElement('div');
+      item.className = 'list-group-item px-0';
+      const heading = document.createElement('div');
+      heading.className = 'd-flex align-items-center gap-2';
+      const badge = document.createElement('span');
+      badge.className = `badge ${statusBadges[check.status] || 'text-bg-secondary'}`;
+      badge.textContent = check.status;
+      const name = document.createElement('span');
+      name.className = 'fw-semibold';
+      name.textContent = check.name;
+      const kind = document.createElement('span');
+      kind.className = 'text-body-secondary';
+      kind.textContent = check.required ? `${check.kind}, required` : check.kind;
+      heading.append(badge, name, kind);
+      item.appendChild(heading);
+      [check.version, check.detail].filter(Boolean).forEach((text) => {
+        const line = document.createElement('div');
+        line.className = 'text-body-secondary';
+        line.textContent = text;
+        item.appendChild(line);
+      });
+      if (check.remedy) {
+        const remedy = document.createElement('div');
+        remedy.textContent = `Fix: ${check.remedy}`;
+        item.appendChild(remedy);
+      }
+      list.appendChild(item);
+    });
+    reportEl.appendChild(list);
+  }
+})();
@@ -13,6 +13,13 @@
     const targetVersionInput = document.getElementById('update-target-version');
     const checkButton = document.getElementById('check-updates');
     const applyButton = document.getElementById('apply-update');
+    const autoStatusEl = document.getElementById('update-auto-status');
+    const rollbackSelect = document.getElementById('update-rollback-version');
+    const rollbackButton = document.getElementById('rollback-update');
+    const preflightButton = document.getElementById('preflight-update');
+    const preflightEl = document.getElementById('update-preflight');
+    const preflightSummaryEl = document.getElementById('update-preflight-summary');
+    const preflightChecksEl = document.getElementById('update-preflight-checks');
 
     if (
       !settingsModalElement ||
@@ -55,6 +62,23 @@
       }
     });
 
+    preflightButton?.addEventListener('click', async () => {
+      preflightButton.disabled = true;
+      try {
+        const report = await fetchJSON('/api/update/preflight', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify(buildVersionPayload()),
+        });
+        renderPreflight(report);
+        setStatus(report.passed ? `Preflight for ${report.targetVersion} passed.` : `Preflight for ${report.targetVersion} failed.`, !report.passed);
+      } catch (err) {
+        setStatus(err.message, true);
+      } finally {
+        preflightButton.disabled = false;
+      }
+    });
+
     applyButton.addEventListener('click', async () => {
       const confirmed = window.confirm('Apply update now? The web UI service will restart.');
       if (!confirmed) {
@@ -75,11 +99,39 @@
         schedulePolling();
       } catch (err) {
         setStatus(err.message, true);
+        await refreshStatus();
       } finally {
         checkButton.disabled = false;
       }
     });
 
+    rollbackButton?.addEventListener('click', async () => {
+      const version = String(rollbackSelect?.value || '').trim();
+      if (!version) {
+        return;
+      }
+      const confirmed = window.confirm(`Roll back to ${version}? The web UI service will restart.`);
+      if (!confirmed) {
+        return;
+      }
+      rollbackButton.disabled = true;
+      applyButton.disabled = true;
+      try {
+        const status = await fetchJSON('/api/update/rollback', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ version }),
+        });
+        currentStatus = status;
+        renderStatus(status);
+        setStatus(`Rollback to ${version} scheduled. The UI may reconnect shortly.`, false);
+        schedulePolling();
+      } catch (err) {
+        setStatus(err.message, true);
+        renderStatus(currentStatus);
+      }
+    });
+
     async function refreshStatus() {
       try {
         const status = await fetchJSON('/api/update/status');
@@ -108,6 +160,7 @@
       const lastError = String(status?.lastError || '').trim();
       const inProgress = Boolean(status?.inProgress);
       const updateAvailable = Boolean(status?.updateAvailable);
+      const unsupported = String(status?.unsupported || '').trim();
       const explicitTarget = String(targetVersionInput.value || '').trim() !== '';
 
       currentVersionEl.textContent = currentVersion;
@@ -115,19 +168,89 @@
       lastCheckedEl.textContent = formatTimestamp(status?.lastCheckedAt) || 'Never';
 
       let stateText = capitalize(state);
-      if (message) {
+      if (unsupported) {
+        stateText = `Unavailable: ${unsupported}`;
+      } else if (message) {
         stateText += `: ${message}`;
       } else if (lastError) {
         stateText += `: ${lastError}`;
       }
       stateEl.textContent = stateText;
 
-      const canApply = !inProgress && (updateAvailable || explicitTarget || currentVersion === 'dev');
+      const canApply = !inProgress && !unsupported && (updateAvailable || explicitTarget || currentVersion === 'dev');
       applyButton.disabled = !canApply;
       checkButton.disabled = inProgress;
       applyButton.innerHTML = inProgress
         ? '<i class="bi bi-hourglass-split me-1"></i>In Progress'
         : '<i class="bi bi-arrow-repeat me-1"></i>Update';
+      renderAutoStatus(status);
+      renderRollbackTargets(status, inProgress || Boolean(unsupported));
+      renderPreflight(status?.preflight);
+    }
+
+    function renderPreflight(report) {
+      if (!preflightEl || !preflightSummaryEl || !preflightChecksEl) {
+        return;
+      }
+      if (!report) {
+        preflightEl.classList.add('d-none');
+        return;
+      }
+      preflightEl.classList.remove('d-none');
+      const checkedAt = formatTimestamp(report.checkedAt);
+      preflightSummaryEl.textContent = `Preflight for ${report.targetVersion || 'release'} ${report.passed ? 'passed' : 'failed'}${checkedAt ? ` (${checkedAt})` : ''}`;
+      const icons = { ok: 'bi-check-circle text-success', warn: 'bi-exclamation-triangle text-warning', fail: 'bi-x-circle text-danger' };
+      preflightChecksEl.replaceChildren();
+      (Array.isArray(report.checks) ? report.checks : []).forEach((check) => {
+        const item = document.createElement('li');
+        const icon = document.createElement('i');
+        icon.className = `bi ${icons[check.status] || 'bi-question-circle'} me-1`;
+        const text = document.createElement('span');
+        text.textContent = `${check.name}: ${check.detail || check.status}${check.remedy ? ` — ${check.remedy}` : ''}`;
+        item.append(icon, text);
+        preflightChecksEl.append(item);
+      });
+    }
+
+    function renderRollbackTargets(status, inProgress) {
+      if (!rollbackSelect || !rollbackButton) {
+        return;
+      }
+      const targets = Array.isArray(status?.rollbackTargets) ? status.rollbackTargets : [];
+      const selected = rollbackSelect.value;
+      rollbackSelect.replaceChildren();
+      if (!targets.length) {
+        rollbackSelect.append(new Option('No previous versions retained', ''));
+      }
+      targets.forEach((target) => {
+        const staged = formatTimestamp(target.stagedAt);
+        rollbackSelect.append(new Option(staged ? `${target.version} (kept ${staged})` : target.version, target.version));
+      });
+      if (targets.some((target) => target.version === selected)) {
+        rollbackSelect.value = selected;
+      }
+      rollbackButton.disabled = inProgress || !targets.length;
+    }
+
+    function renderAutoStatus(status) {
+      if (!autoStatusEl) {
+        return;
+      }
+      const parts = [`Channel: ${status?.channel || 'stable'}`];
+      const auto = status?.autoUpdate || {};
+      if (auto.enabled) {
+        const next = formatTimestamp(auto.nextRunAt);
+        parts.push(`Auto-update ${auto.schedule || ''}${next ? `, next ${next}` : ''}`);
+      } else {
+        parts.push('Auto-update off');
+      }
+      if (auto.lastRunAt) {
+        parts.push(`Last auto-run ${formatTimestamp(auto.lastRunAt)}: ${auto.lastResult || 'unknown'}`);
+      }
+      if (status?.lastBackupPath) {
+        parts.push(`Last pre-update backup: ${status.lastBackupPath} (${formatTimestamp(status.lastBackupAt)})`);
+      }
+      autoStatusEl.textContent = parts.join(' · ');
     }
 
     function renderErrorState(message) {
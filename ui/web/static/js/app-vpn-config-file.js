@@ -0,0 +1,215 @@
+(() => {
+  const vpnTableBody = document.querySelector('#vpn-table tbody');
+  const modalElement = document.getElementById('vpnConfigFileModal');
+  const title = document.getElementById('vpn-config-file-title');
+  const errorBox = document.getElementById('vpn-config-file-error');
+  const meta = document.getElementById('vpn-config-file-meta');
+  const editor = document.getElementById('vpn-config-file-content');
+  const revisionList = document.getElementById('vpn-config-file-revisions');
+  const saveButton = document.getElementById('vpn-config-file-save');
+  const diffWrap = document.getElementById('vpn-config-file-diff-wrap');
+  const diffTitle = document.getElementById('vpn-config-file-diff-title');
+  const diffView = document.getElementById('vpn-config-file-diff');
+  const diffClose = document.getElementById('vpn-config-file-diff-close');
+
+  if (!vpnTableBody || !modalElement || !title || !errorBox || !meta || !editor || !revisionList || !saveButton || !diffWrap || !diffTitle || !diffView || !diffClose) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  let currentVPN = '';
+  let configFile = '';
+
+  vpnTableBody.addEventListener('click', (event) => {
+    const target = event.target.closest('[data-action="vpn-config-file"]');
+    if (!target) {
+      return;
+    }
+    const name = target.getAttribute('data-name');
+    if (!name) {
+      return;
+    }
+    currentVPN = name;
+    title.textContent = `Config File — ${name}`;
+    editor.value = '';
+    meta.textContent = '';
+    revisionList.innerHTML = '';
+    hideDiff();
+    hideError();
+    modal.show();
+    load();
+  });
+
+  saveButton.addEventListener('click', async () => {
+    if (!currentVPN) {
+      return;
+    }
+    saveButton.disabled = true;
+    hideError();
+    try {
+      const payload = await request(`/api/configs/${encodeURIComponent(currentVPN)}/file`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ content: editor.value }),
+      });
+      await applySaved(payload, 'Saved.');
+    } catch (err) {
+      showError(err.message);
+    } finally {
+      saveButton.disabled = false;
+    }
+  });
+
+  diffClose.addEventListener('click', hideDiff);
+
+  revisionList.addEventListener('click', async (event) => {
+    const button = event.target.closest('[data-revision]');
+    if (!button || !currentVPN) {
+      return;
+    }
+    const id = button.getAttribute('data-revision');
+    const label = button.getAttribute('data-revision-label') || `revision ${id}`;
+    const base = `/api/vpns/${encodeURIComponent(currentVPN)}/revisions`;
+    hideError();
+    try {
+      switch (button.getAttribute('data-revision-action')) {
+        case 'diff': {
+          const payload = await request(`${base}/diff?from=${encodeURIComponent(id)}`);
+          renderDiff(`Changes since ${label}`, payload.lines || []);
+          break;
+        }
+        case 'load': {
+          const payload = await request(`${base}/${encodeURIComponent(id)}`);
+          editor.value = payload?.revision?.content || '';
+          meta.textContent = `Loaded ${label} — Save to apply it.`;
+          break;
+        }
+        case 'revert': {
+          if (!window.confirm(`Revert the ${currentVPN} config to ${label}?`)) {
+            return;
+          }
+          const payload = await request(`${base}/${encodeURIComponent(id)}/revert`, { method: 'POST' });
+          await applySaved(payload, `Reverted to ${label}.`);
+          break;
+        }
+        default:
+          break;
+      }
+    } catch (err) {
+      showError(err.message);
+    }
+  });
+
+  async function load() {
+    try {
+      const file = await request(`/api/configs/${encodeURIComponent(currentVPN)}/file`);
+      configFile = file.configFile || '';
+      editor.value = file.content || '';
+      meta.textContent = configFile;
+      await loadRevisions();
+    } catch (err) {
+      showError(err.message);
+    }
+  }
+
+  async function loadRevisions() {
+    const page = await request(`/api/vpns/${encodeURIComponent(currentVPN)}/revisions?limit=100`);
+    renderRevisions(page.revisions || []);
+  }
+
+  async function applySaved(payload, message) {
+    editor.value = payload?.vpn?.rawConfig || editor.value;
+    const warnings = Array.isArray(payload?.vpn?.warnings) ? payload.vpn.warnings : [];
+    meta.textContent = warnings.length ? `${message} Warnings: ${warnings.join(' | ')}` : `${configFile} — ${message}`;
+    hideDiff();
+    await loadRevisions();
+  }
+
+  function renderDiff(heading, lines) {
+    diffTitle.textContent = heading;
+    diffView.innerHTML = '';
+    if (!lines.some((line) => line.op !== ' ')) {
+      diffView.textContent = 'No differences.';
+    } else {
+      lines.forEach((line) => {
+        const row = document.createElement('div');
+        if (line.op === '+') {
+          row.className = 'text-success';
+        } else if (line.op === '-') {
+          row.className = 'text-danger';
+        } else {
+          row.className = 'text-body-secondary';
+        }
+        row.textContent = `${line.op}${line.text}`;
+        diffView.appendChild(row);
+      });
+    }
+    diffWrap.classList.remove('d-none');
+  }
+
+  function hideDiff() {
+    diffWrap.classList.add('d-none');
+    diffView.innerHTML = '';
+  }
+
+  function renderRevisions(revisions) {
+    revisionList.innerHTML = '';
+    if (!revisions.length) {
+      const empty = document.createElement('div');
+      empty.className = 'text-body-secondary';
+      empty.textContent = 'No revisions recorded yet.';
+      revisionList.appendChild(empty);
+      return;
+    }
+    revisions.forEach((revision, index) => {
+      const when = new Date(revision.createdAt).toLocaleString();
+      const label = `revision ${revision.id} (${when})`;
+      const item = document.createElement('div');
+      item.className = 'list-group-item px-0';
+      const heading = document.createElement('div');
+      heading.className = 'd-flex align-items-center gap-2';
+      const badge = document.createElement('span');
+      badge.className = `badge ${index === 0 ? 'text-bg-success' : 'text-bg-secondary'}`;
+      badge.textContent = revision.action;
+      const time = document.createElement('span');
+      time.className = 'me-auto';
+      time.textContent = when;
+      heading.append(badge, time);
+      const detail = document.createElement('div');
+      detail.className = 'text-body-secondary';
+      detail.textContent = `${revision.actor || 'unknown'} · ${revision.size} B`;
+      const actions = document.createElement('div');
+      actions.className = 'btn-group btn-group-sm mt-1';
+      [['diff', 'Diff', 'btn-outline-secondary'], ['load', 'Load', 'btn-outline-secondary'], ['revert', 'Revert', 'btn-outline-warning']].forEach(([action, text, style]) => {
+        const button = document.createElement('button');
+        button.type = 'button';
+        button.className = `btn ${style}`;
+        button.textContent = text;
+        button.setAttribute('data-revision', String(revision.id));
+        button.setAttribute('data-revision-action', action);
+        button.setAttribute('data-revision-label', label);
+        actions.appendChild(button);
+      });
+      item.append(heading, detail, actions);
+      revisionList.appendChild(item);
+    });
+  }
+
+  async function request(url, options = {}) {
+    const response = await fetch(url, options);
+    const payload = await response.json().catch(() => ({}));
+    if (!response.ok) {
+      throw new Error(payload.error || response.statusText || 'Request failed');
+    }
+    return payload;
+  }
+
+  function showError(message) {
+    errorBox.textContent = message;
+    errorBox.classList.remove('d-none');
+  }
+
+  function hideError() {
+    errorBox.classList.add('d-none');
+  }
+})();
@@ -0,0 +1,125 @@
+(() => {
+  const vpnTableBody = document.querySelector('#vpn-table tbody');
+  const modalElement = document.getElementById('dnsLeakModal');
+  const title = document.getElementById('dns-leak-title');
+  const statusBox = document.getElementById('dns-leak-status');
+  const exitLabel = document.getElementById('dns-leak-exit');
+  const asnsLabel = document.getElementById('dns-leak-asns');
+  const pathsContainer = document.getElementById('dns-leak-paths');
+  const rerunButton = document.getElementById('dns-leak-rerun');
+
+  if (!vpnTableBody || !modalElement || !title || !statusBox || !exitLabel || !asnsLabel || !pathsContainer || !rerunButton) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  const pathLabels = { vpn: 'VPN resolver (via tunnel)', router: 'Router resolver' };
+  let currentVPN = '';
+  let running = false;
+
+  vpnTableBody.addEventListener('click', (event) => {
+    const target = event.target.closest('[data-action="dns-leak-test"]');
+    if (!target) {
+      return;
+    }
+    const name = target.getAttribute('data-name');
+    if (!name) {
+      return;
+    }
+    currentVPN = name;
+    title.textContent = `DNS Leak Test — ${name}`;
+    modal.show();
+    run();
+  });
+
+  rerunButton.addEventListener('click', () => {
+    if (currentVPN) {
+      run();
+    }
+  });
+
+  async function run() {
+    if (running) {
+      return;
+    }
+    running = true;
+    rerunButton.disabled = true;
+    exitLabel.textContent = '–';
+    asnsLabel.textContent = '–';
+    pathsContainer.innerHTML = '';
+    showStatus('Running DNS leak test…', 'alert-secondary');
+    try {
+      const response = await fetch(`/api/vpns/${encodeURIComponent(currentVPN)}/dns-leak-test`, { method: 'POST' });
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'DNS leak test failed');
+      }
+      render(payload.result || {});
+    } catch (err) {
+      showStatus(err.message, 'alert-danger');
+    } finally {
+      running = false;
+      rerunButton.disabled = false;
+    }
+  }
+
+  function render(result) {
+    exitLabel.textContent = result.exitIp || 'unknown';
+    asnsLabel.textContent = (result.vpnAsns || []).join(', ') || 'unknown';
+    const warnings = result.warnings || [];
+    if (result.leak) {
+      showStatus('Leak detected: at least one resolver is outside the VPN network.', 'alert-danger', warnings);
+    } else if (!(result.vpnAsns || []).length) {
+      showStatus('Could not determine the VPN network; resolvers are listed without a verdict.', 'alert-warning', warnings);
+    } else {
+      showStatus('No leak detected: every resolver belongs to the VPN network.', 'alert-success', warnings);
+    }
+    (result.paths || []).forEach((path) => pathsContainer.appendChild(renderPath(path)));
+  }
+
+  function renderPath(path) {
+    const wrapper = document.createElement('div');
+    wrapper.className = 'border rounded p-2 mb-2';
+    const heading = document.createElement('div');
+    heading.className = 'fw-semibold small mb-1';
+    heading.textContent = `${pathLabels[path.path] || path.path} — ${path.via}`;
+    wrapper.appendChild(heading);
+    if (path.error) {
+      const error = document.createElement('div');
+      error.className = 'small text-danger';
+      error.textContent = path.error;
+      wrapper.appendChild(error);
+    }
+    (path.resolvers || []).forEach((resolver) => {
+      const row = document.createElement('div');
+      row.className = 'd-flex flex-wrap align-items-center gap-2 small';
+      const ip = document.createElement('span');
+      ip.className = 'font-monospace';
+      ip.textContent = resolver.ip;
+      const asns = document.createElement('span');
+      asns.className = 'text-body-secondary';
+      asns.textContent = (resolver.asns || []).join(', ') || 'ASN unknown';
+      const services = document.createElement('span');
+      services.className = 'text-body-secondary';
+      services.textContent = `via ${(resolver.services || []).join(', ')}`;
+      const badge = document.createElement('span');
+      badge.className = `badge ${resolver.matchesVpn ? 'text-bg-success' : 'text-bg-danger'}`;
+      badge.textContent = resolver.matchesVpn ? 'VPN' : 'Outside VPN';
+      row.append(ip, asns, services, badge);
+      wrapper.appendChild(row);
+    });
+    return wrapper;
+  }
+
+  function showStatus(message, className, details = []) {
+    statusBox.classList.remove('d-none', 'alert-secondary', 'alert-success', 'alert-warning', 'alert-danger');
+    statusBox.classList.add(className);
+    statusBox.textContent = message;
+    details.forEach((detail) => {
+      const line = document.createElement('div');
+      line.className = 'small';
+      line.textContent = detail;
+      statusBox.appendChild(line);
+    });
+  }
+})();
@@ -0,0 +1,132 @@
+(() => {
+  const vpnTableBody = document.querySelector('#vpn-table tbody');
+  const modalElement = document.getElementById('vpnEventsModal');
+  const title = document.getElementById('vpn-events-title');
+  const errorBox = document.getElementById('vpn-events-error');
+  const uptimeDay = document.getElementById('vpn-events-uptime-day');
+  const uptimeWeek = document.getElementById('vpn-events-uptime-week');
+  const rows = document.getElementById('vpn-events-rows');
+  const rangeLabel = document.getElementById('vpn-events-range');
+  const newerButton = document.getElementById('vpn-events-newer');
+  const olderButton = document.getElementById('vpn-events-older');
+
+  if (!vpnTableBody || !modalElement || !title || !errorBox || !uptimeDay || !uptimeWeek || !rows || !rangeLabel || !newerButton || !olderButton) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  const pageSize = 50;
+  const eventLabels = {
+    up: { text: 'Up', badge: 'text-bg-success' },
+    down: { text: 'Down', badge: 'text-bg-danger' },
+    handshake_stale: { text: 'Handshake stale', badge: 'text-bg-warning' },
+    handshake_restored: { text: 'Handshake restored', badge: 'text-bg-success' },
+    start: { text: 'Start', badge: 'text-bg-info' },
+    stop: { text: 'Stop', badge: 'text-bg-secondary' },
+    restart: { text: 'Restart', badge: 'text-bg-info' },
+    watchdog: { text: 'Watchdog', badge: 'text-bg-warning' },
+    traffic_high: { text: 'High Traffic', badge: 'text-bg-warning' },
+    traffic_normal: { text: 'Traffic Normal', badge: 'text-bg-success' },
+    traffic_stalled: { text: 'Stalled', badge: 'text-bg-danger' },
+    traffic_resumed: { text: 'Traffic Resumed', badge: 'text-bg-success' },
+    quota_warning: { text: 'Quota Warning', badge: 'text-bg-warning' },
+    quota_exceeded: { text: 'Quota Used Up', badge: 'text-bg-danger' },
+    quota_reset: { text: 'Quota Reset', badge: 'text-bg-info' },
+    endpoint_changed: { text: 'Endpoint Moved', badge: 'text-bg-info' },
+    endpoint_failed: { text: 'Endpoint Lookup Failed', badge: 'text-bg-warning' },
+  };
+  let currentVPN = '';
+  let offset = 0;
+  let total = 0;
+
+  vpnTableBody.addEventListener('click', (event) => {
+    const target = event.target.closest('[data-action="vpn-events"]');
+    if (!target) {
+      return;
+    }
+    const name = target.getAttribute('data-name');
+    if (!name) {
+      return;
+    }
+    currentVPN = name;
+    offset = 0;
+    total = 0;
+    title.textContent = `Connection History — ${name}`;
+    modal.show();
+    load();
+  });
+
+  newerButton.addEventListener('click', () => {
+    offset = Math.max(0, offset - pageSize);
+    load();
+  });
+
+  olderButton.addEventListener('click', () => {
+    offset += pageSize;
+    load();
+  });
+
+  async function load() {
+    if (!currentVPN) {
+      return;
+    }
+    newerButton.disabled = true;
+    olderButton.disabled = true;
+    errorBox.classList.add('d-none');
+    try {
+      const response = await fetch(`/api/vpns/${encodeURIComponent(currentVPN)}/events?limit=${pageSize}&offset=${offset}`);
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Failed to load connection history');
+      }
+      render(payload);
+    } catch (err) {
+      errorBox.textContent = err.message;
+      errorBox.classList.remove('d-none');
+    }
+  }
+
+  function render(payload) {
+    const events = payload.events || [];
+    total = payload.total || 0;
+    const uptime = payload.uptime || {};
+    uptimeDay.textContent = formatPercent(uptime.day);
+    uptimeWeek.textContent = formatPercent(uptime.week);
+    rows.innerHTML = '';
+    if (!events.length) {
+      const row = document.createElement('tr');
+      const cell = document.createElement('td');
+      cell.colSpan = 3;
+      cell.className = 'text-body-secondary small';
+      cell.textContent = 'No events recorded yet.';
+      row.appendChild(cell);
+      rows.appendChild(row);
+    }
+    events.forEach((event) => rows.appendChild(renderEvent(event)));
+    rangeLabel.textContent = events.length ? `${offset + 1}–${offset + events.length} of ${total}` : '';
+    newerButton.disabled = offset === 0;
+    olderButton.disabled = offset + events.length >= total;
+  }
+
+  function renderEvent(event) {
+    const label = eventLabels[event.type] || { text: event.type, badge: 'text-bg-secondary' };
+    const row = document.createElement('tr');
+    const time = document.createElement('td');
+    time.className = 'small text-nowrap';
+    time.textContent = new Date(event.at).toLocaleString();
+    const type = document.createElement('td');
+    const badge = document.createElement('span');
+    badge.className = `badge ${label.badge}`;
+    badge.textContent = label.text;
+    type.appendChild(badge);
+    const detail = document.createElement('td');
+    detail.className = 'small text-body-secondary';
+    detail.textContent = event.detail || '';
+    row.append(time, type, detail);
+    return row;
+  }
+
+  function formatPercent(value) {
+    return typeof value === 'number' ? `${value.toFixed(2)}%` : '–';
+  }
+})();
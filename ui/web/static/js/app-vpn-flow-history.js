@@ -0,0 +1,117 @@
+(() => {
+  window.SplitVPNUI = window.SplitVPNUI || {};
+
+  window.SplitVPNUI.createFlowHistoryController = function createFlowHistoryController(ctx) {
+    const {
+      flowHistoryWindow,
+      flowHistorySort,
+      flowHistoryRefresh,
+      flowHistorySummary,
+      flowHistoryTableBody,
+      fetchJSON,
+      formatBytes,
+    } = ctx || {};
+
+    if (
+      !flowHistoryWindow ||
+      !flowHistorySort ||
+      !flowHistoryRefresh ||
+      !flowHistorySummary ||
+      !flowHistoryTableBody ||
+      typeof fetchJSON !== 'function'
+    ) {
+      return null;
+    }
+
+    const toBytes = typeof formatBytes === 'function'
+      ? formatBytes
+      : (value) => `${Number(value || 0)} B`;
+    const state = {
+      vpnName: '',
+      requestID: 0,
+    };
+
+    flowHistoryWindow.addEventListener('change', () => {
+      load(state.vpnName);
+    });
+    flowHistorySort.addEventListener('change', () => {
+      load(state.vpnName);
+    });
+    flowHistoryRefresh.addEventListener('click', () => {
+      load(state.vpnName);
+    });
+
+    async function load(vpnName) {
+      const name = String(vpnName || '').trim();
+      if (!name) {
+        return;
+      }
+      state.vpnName = name;
+      state.requestID += 1;
+      const requestID = state.requestID;
+      flowHistorySummary.textContent = 'Loading…';
+      const params = new URLSearchParams({
+        window: flowHistoryWindow.value || '3600',
+        sort: flowHistorySort.value || 'bytes',
+      });
+      try {
+        const summary = await fetchJSON(`/api/vpns/${encodeURIComponent(name)}/flow-inspector/history?${params.toString()}`);
+        if (requestID !== state.requestID) {
+          return;
+        }
+        render(summary || {});
+      } catch (err) {
+        if (requestID !== state.requestID) {
+          return;
+        }
+        flowHistorySummary.textContent = err.message || 'Failed to load flow history.';
+        flowHistoryTableBody.innerHTML = '<tr><td class="text-body-secondary small" colspan="8">Flow history unavailable.</td></tr>';
+      }
+    }
+
+    function render(summary) {
+      const destinations = Array.isArray(summary.destinations) ? summary.destinations : [];
+      flowHistorySummary.textContent = `${Number(summary.flows || 0)} flows • ${toBytes(Number(summary.totalBytes || 0))} (↓ ${toBytes(Number(summary.downloadBytes || 0))} / ↑ ${toBytes(Number(summary.uploadBytes || 0))})`;
+      flowHistoryTableBody.innerHTML = '';
+      if (destinations.length === 0) {
+        flowHistoryTableBody.innerHTML = '<tr><td class="text-body-secondary small" colspan="8">No flows recorded in this window.</td></tr>';
+        return;
+      }
+      destinations.forEach((dest) => {
+        const tr = document.createElement('tr');
+        const addresses = Number(dest.addresses || 0);
+        const devices = Array.isArray(dest.sourceDevices) ? dest.sourceDevices : [];
+        const ports = Array.isArray(dest.ports) ? dest.ports : [];
+        const lastSeen = dest.lastSeen ? new Date(dest.lastSeen) : null;
+        tr.innerHTML = `
+          <td>
+            <div class="fw-semibold">${escapeHTML(dest.destination || 'n/a')}</div>
+            ${dest.domain && addresses > 0 ? `<div class="small text-body-secondary">${addresses} address${addresses === 1 ? '' : 'es'}</div>` : ''}
+          </td>
+          <td class="text-end">${Number(dest.flows || 0)}</td>
+          <td>
+            <div>${Number(dest.sources || 0)}</div>
+            ${devices.length ? `<div class="small text-body-secondary">${escapeHTML(devices.join(', '))}</div>` : ''}
+          </td>
+          <td class="small">${escapeHTML(ports.join(', ') || '–')}</td>
+          <td class="text-end text-primary">${toBytes(Number(dest.downloadBytes || 0))}</td>
+          <td class="text-end text-danger">${toBytes(Number(dest.uploadBytes || 0))}</td>
+          <td class="text-end fw-semibold">${toBytes(Number(dest.totalBytes || 0))}</td>
+          <td class="text-end small text-body-secondary">${lastSeen && !Number.isNaN(lastSeen.getTime()) ? escapeHTML(lastSeen.toLocaleTimeString()) : '–'}</td>
+        `;
+        flowHistoryTableBody.appendChild(tr);
+      });
+    }
+
+    function escapeHTML(value) {
+      return String(value || '')
+        .replaceAll('&', '&amp;')
+        .replaceAll('<', '&lt;')
+        .replaceAll('>', '&gt;')
+        .replaceAll('"', '&quot;')
+        .replaceAll("'", '&#39;');
+    }
+
+    return { load };
+  };
+})();
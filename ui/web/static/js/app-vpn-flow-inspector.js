@@ -64,6 +64,11 @@
       return `${scaled.toFixed(scaled >= 10 ? 1 : 2)} ${units[idx]}`;
     };
 
+    const flowInspectorAccounting = flowInspectorModalElement.querySelector('#flow-inspector-accounting');
+    const flowInspectorGroup = flowInspectorModalElement.querySelector('#flow-inspector-group');
+    const flowInspectorPager = flowInspectorModalElement.querySelector('#flow-inspector-pager');
+    const flowInspectorPageLabel = flowInspectorModalElement.querySelector('#flow-inspector-page-label');
+    const pageSize = 100;
     const toThroughput = typeof formatThroughput === 'function' ? formatThroughput : fallbackThroughput;
     const toBytes = typeof formatBytes === 'function' ? formatBytes : fallbackBytes;
     const state = {
@@ -77,6 +82,10 @@
       rows: [],
       sortKey: 'total',
       sortDirection: 'desc',
+      offset: 0,
+      total: 0,
+      groupBy: '',
+      accountingBusy: false,
     };
 
     flowInspectorModalElement.addEventListener('hidden.bs.modal', () => {
@@ -98,7 +107,32 @@
         state.sortKey = sortKey;
         state.sortDirection = sortKey === 'source' || sortKey === 'destination' ? 'asc' : 'desc';
       }
-      renderRows(state.rows);
+      state.offset = 0;
+      poll().catch(() => {});
+    });
+
+    flowInspectorGroup?.addEventListener('change', () => {
+      state.groupBy = flowInspectorGroup.checked ? 'destination' : '';
+      state.offset = 0;
+      poll().catch(() => {});
+    });
+
+    flowInspectorPager?.addEventListener('click', (event) => {
+      const target = event.target.closest('[data-flow-page]');
+      if (!target) {
+        return;
+      }
+      const step = target.getAttribute('data-flow-page') === 'next' ? pageSize : -pageSize;
+      state.offset = Math.max(0, state.offset + step);
+      poll().catch(() => {});
+    });
+
+    flowInspectorAccounting?.addEventListener('click', (event) => {
+      const target = event.target.closest('[data-accounting-enable]');
+      if (!target) {
+        return;
+      }
+      setAccounting(target.getAttribute('data-accounting-enable') === 'true').catch(() => {});
     });
 
     async function open(vpnName) {
@@ -113,7 +147,7 @@
       flowInspectorModal.show();
       setInspectorStatus('Starting flow inspection session…', false);
       try {
-        const response = await fetchJSON(`/api/vpns/${encodeURIComponent(name)}/flow-inspector/start`, {
+        const response = await fetchJSON(`/api/vpns/${encodeURIComponent(name)}/flow-inspector/start${viewQuery()}`, {
           method: 'POST',
         });
         const startedSessionID = String(response?.sessionId || '').trim();
@@ -171,12 +205,20 @@
       flowInspectorSummaryFlows.textContent = 'Flows: 0';
       flowInspectorSummaryTotal.textContent = 'Session Data: 0 B';
       flowInspectorStatus.classList.add('d-none');
+      renderAccounting(null);
     }
 
     function resetUI(vpnName) {
       state.rows = [];
       state.sortKey = 'total';
       state.sortDirection = 'desc';
+      state.offset = 0;
+      state.total = 0;
+      state.groupBy = '';
+      if (flowInspectorGroup) {
+        flowInspectorGroup.checked = false;
+      }
+      renderPager();
       flowInspectorTitle.innerHTML = `<i class=\"bi bi-search me-2\"></i>VPN Flow Inspector — ${escapeHTML(vpnName)}`;
       flowInspectorSummaryVPN.textContent = `VPN: ${vpnName}`;
       flowInspectorSummaryFlows.textContent = 'Flows: 0';
@@ -203,7 +245,7 @@
       }
       state.pollInFlight = true;
       try {
-        const response = await fetchJSON(`/api/vpns/${encodeURIComponent(state.vpnName)}/flow-inspector/${encodeURIComponent(state.sessionID)}`);
+        const response = await fetchJSON(`/api/vpns/${encodeURIComponent(state.vpnName)}/flow-inspector/${encodeURIComponent(state.sessionID)}${viewQuery()}`);
         renderSnapshot(response?.snapshot || {});
       } catch (err) {
         const message = err.message || 'Failed to refresh flow inspector.';
@@ -226,7 +268,7 @@
       }
       state.recoveryInFlight = true;
       try {
-        const response = await fetchJSON(`/api/vpns/${encodeURIComponent(state.vpnName)}/flow-inspector/start`, {
+        const response = await fetchJSON(`/api/vpns/${encodeURIComponent(state.vpnName)}/flow-inspector/start${viewQuery()}`, {
           method: 'POST',
         });
         const newSessionID = String(response?.sessionId || '').trim();
@@ -262,9 +304,17 @@
       const downloadBytes = Number(totals.downloadBytes || 0);
       const totalBytes = Number(totals.totalBytes || (uploadBytes + downloadBytes));
       state.rows = Array.isArray(snapshot?.flows) ? snapshot.flows : [];
-      flowInspectorSummaryFlows.textContent = `Flows: ${state.rows.length}`;
+      state.total = Number(snapshot?.total ?? state.rows.length);
+      state.offset = Number(snapshot?.offset || 0);
+      if (state.rows.length === 0 && state.offset > 0 && state.total > 0) {
+        // The list shrank below the current page; step back on the next poll.
+        state.offset = Math.max(0, Math.floor((state.total - 1) / pageSize) * pageSize);
+      }
+      flowInspectorSummaryFlows.textContent = `Flows: ${Number(snapshot?.flowCount ?? state.rows.length)}`;
       flowInspectorSummaryTotal.textContent = `Session Data: ${toBytes(totalBytes)} (↓ ${toBytes(downloadBytes)} / ↑ ${toBytes(uploadBytes)})`;
       renderRows(state.rows);
+      renderPager();
+      renderAccounting(snapshot?.accounting || null);
       if (state.rows.length > 0) {
         setInspectorStatus(`Flow inspector session active for ${snapshot?.vpnName || 'VPN'}.`, false);
       } else {
@@ -272,9 +322,95 @@
       }
     }
 
+    async function setAccounting(enabled) {
+      if (state.accountingBusy) {
+        return;
+      }
+      state.accountingBusy = true;
+      try {
+        const status = await fetchJSON('/api/flow-inspector/accounting', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ enabled }),
+        });
+        renderAccounting(status);
+        setInspectorStatus(enabled
+          ? 'Conntrack accounting enabled. Rates appear for flows opened from now on.'
+          : 'Conntrack accounting disabled.', false);
+      } catch (err) {
+        setInspectorStatus(err.message || 'Failed to change conntrack accounting.', true);
+      } finally {
+        state.accountingBusy = false;
+      }
+    }
+
+    function renderAccounting(accounting) {
+      if (!flowInspectorAccounting) {
+        return;
+      }
+      if (!accounting || !accounting.available) {
+        flowInspectorAccounting.classList.add('d-none');
+        flowInspectorAccounting.innerHTML = '';
+        return;
+      }
+      flowInspectorAccounting.classList.remove('d-none');
+      if (!accounting.enabled) {
+        flowInspectorAccounting.innerHTML = `
+          <div class=\"alert alert-warning py-2 small mb-0 d-flex flex-wrap align-items-center gap-2\">
+            <span><i class=\"bi bi-exclamation-triangle me-1\"></i>Conntrack accounting (nf_conntrack_acct) is off, so flows carry no byte counters and every rate reads zero.</span>
+            <button class=\"btn btn-warning btn-sm ms-auto\" type=\"button\" data-accounting-enable=\"true\">Enable accounting</button>
+          </div>
+        `;
+        return;
+      }
+      const persistence = accounting.persistent ? ' and re-applied on every start' : '';
+      flowInspectorAccounting.innerHTML = `
+        <div class=\"small text-body-secondary d-flex flex-wrap align-items-center gap-2\">
+          <span><i class=\"bi bi-check-circle me-1\"></i>Conntrack accounting is on${persistence}.</span>
+          <button class=\"btn btn-link btn-sm p-0\" type=\"button\" data-accounting-enable=\"false\">Turn off</button>
+        </div>
+      `;
+    }
+
+    function viewQuery() {
+      const params = new URLSearchParams({
+        sort: state.sortKey,
+        order: state.sortDirection,
+        limit: String(pageSize),
+        offset: String(state.offset),
+      });
+      if (state.groupBy) {
+        params.set('groupBy', state.groupBy);
+      }
+      return `?${params.toString()}`;
+    }
+
+    function renderPager() {
+      if (!flowInspectorPager || !flowInspectorPageLabel) {
+        return;
+      }
+      if (state.total <= pageSize && state.offset === 0) {
+        flowInspectorPager.classList.add('d-none');
+        return;
+      }
+      flowInspectorPager.classList.remove('d-none');
+      const first = state.rows.length > 0 ? state.offset + 1 : 0;
+      const last = state.offset + state.rows.length;
+      const unit = state.groupBy ? 'destinations' : 'flows';
+      flowInspectorPageLabel.textContent = `${first}–${last} of ${state.total} ${unit}`;
+      const prev = flowInspectorPager.querySelector('[data-flow-page="prev"]');
+      const next = flowInspectorPager.querySelector('[data-flow-page="next"]');
+      if (prev) {
+        prev.disabled = state.offset === 0;
+      }
+      if (next) {
+        next.disabled = last >= state.total;
+      }
+    }
+
     function renderRows(rows) {
-      const list = Array.isArray(rows) ? [...rows] : [];
-      list.sort((left, right) => compareRows(left, right, state.sortKey, state.sortDirection));
+      // Rows arrive sorted and paged by the server.
+      const list = Array.isArray(rows) ? rows : [];
       flowInspectorTableBody.innerHTML = '';
       if (list.length === 0) {
         flowInspectorTableBody.innerHTML = '<tr><td class="text-body-secondary small" colspan="5">No matching VPN flows at this time.</td></tr>';
@@ -294,6 +430,10 @@
     }
 
     function renderSourceCell(row) {
+      const merged = Number(row?.flows || 0);
+      if (merged > 0) {
+        return `<div class=\"fw-semibold\">${merged} flow${merged === 1 ? '' : 's'}</div>`;
+      }
       const sourceName = String(row?.sourceDeviceName || '').trim();
       const sourceMAC = String(row?.sourceMac || '').trim();
       const sourceIP = String(row?.sourceIp || '').trim();
@@ -325,43 +465,32 @@
       const heading = destinationDomain
         ? `${destinationDomain}${destinationPort > 0 ? `:${destinationPort}` : ''}`
         : destinationEndpoint;
+      const badge = renderMonitorBadge(row) + renderReputationBadge(row?.reputation);
       if (destinationDomain) {
         return `
-          <div class=\"fw-semibold\">${escapeHTML(heading)}</div>
+          <div class=\"fw-semibold\">${escapeHTML(heading)}${badge}</div>
           <div class=\"small text-body-secondary\">${escapeHTML(destinationEndpoint || 'n/a')}</div>
         `;
       }
-      return `<div class=\"fw-semibold\">${escapeHTML(heading || 'n/a')}</div>`;
+      return `<div class=\"fw-semibold\">${escapeHTML(heading || 'n/a')}${badge}</div>`;
     }
 
-    function compareRows(left, right, sortKey, direction) {
-      const order = direction === 'asc' ? 1 : -1;
-      switch (sortKey) {
-        case 'source': {
-          const a = `${String(left?.sourceDeviceName || '').toLowerCase()} ${String(left?.sourceIp || '').toLowerCase()}:${Number(left?.sourcePort || 0)}`;
-          const b = `${String(right?.sourceDeviceName || '').toLowerCase()} ${String(right?.sourceIp || '').toLowerCase()}:${Number(right?.sourcePort || 0)}`;
-          return a.localeCompare(b) * order;
-        }
-        case 'destination': {
-          const a = `${String(left?.destinationDomain || '').toLowerCase()} ${String(left?.destinationIp || '').toLowerCase()}:${Number(left?.destinationPort || 0)}`;
-          const b = `${String(right?.destinationDomain || '').toLowerCase()} ${String(right?.destinationIp || '').toLowerCase()}:${Number(right?.destinationPort || 0)}`;
-          return a.localeCompare(b) * order;
-        }
-        case 'download':
-          return compareNumber(Number(left?.downloadBps || 0), Number(right?.downloadBps || 0), order);
-        case 'upload':
-          return compareNumber(Number(left?.uploadBps || 0), Number(right?.uploadBps || 0), order);
-        case 'total':
-        default:
-          return compareNumber(Number(left?.totalBytes || 0), Number(right?.totalBytes || 0), order);
+    function renderMonitorBadge(row) {
+      if (!row?.monitorOnly) {
+        return '';
       }
+      return ' <span class=\"badge text-bg-info ms-1\" title=\"Matched by a monitor-only rule; this flow is not routed through the VPN\"><i class=\"bi bi-eye me-1\"></i>Monitor only</span>';
     }
 
-    function compareNumber(left, right, order) {
-      if (left === right) {
-        return 0;
+    function renderReputationBadge(reputation) {
+      if (!reputation?.flagged) {
+        return '';
       }
-      return left > right ? order : -order;
+      const reasons = (Array.isArray(reputation.verdicts) ? reputation.verdicts : [])
+        .filter((verdict) => verdict?.listed)
+        .map((verdict) => `${verdict.source}${verdict.detail ? `: ${verdict.detail}` : ''}`)
+        .join('; ');
+      return ` <span class=\"badge text-bg-danger ms-1\" title=\"${escapeHTML(reasons)}\"><i class=\"bi bi-shield-exclamation me-1\"></i>Flagged</span>`;
     }
 
     function setInspectorStatus(message, isError) {
@@ -20,6 +20,17 @@
       vpnMSSV6Wrap,
       vpnMSSV4Input,
       vpnMSSV6Input,
+      vpnBoundInterfaceInput,
+      vpnDependsOnSelect,
+      vpnUplinkSelect,
+      vpnOnDemandIdleInput,
+      vpnLatencyProbeSelect,
+      vpnLatencyProbeTargetInput,
+      vpnEndpointRefreshWrap,
+      vpnEndpointRefreshInput,
+      vpnIPv6PolicySelect,
+      vpnIPv6PrefixWrap,
+      vpnIPv6PrefixInput,
       saveVPNButton,
       saveVPNLabel,
       deleteVPNModal,
@@ -130,6 +141,145 @@
       vpnMSSMode.addEventListener('change', syncMSSCustomVisibility);
     }
 
+    function setBoundInterface(value) {
+      if (vpnBoundInterfaceInput) {
+        vpnBoundInterfaceInput.value = (value || '').trim();
+      }
+    }
+
+    function setOnDemandIdle(minutes) {
+      if (vpnOnDemandIdleInput) {
+        vpnOnDemandIdleInput.value = Number(minutes) > 0 ? String(minutes) : '';
+      }
+    }
+
+    function setLatencyProbe(probe, target) {
+      if (vpnLatencyProbeSelect) {
+        vpnLatencyProbeSelect.value = probe || '';
+      }
+      if (vpnLatencyProbeTargetInput) {
+        vpnLatencyProbeTargetInput.value = (target || '').trim();
+      }
+      syncLatencyProbePlaceholder();
+    }
+
+    function setEndpointRefresh(seconds) {
+      if (vpnEndpointRefreshInput) {
+        vpnEndpointRefreshInput.value = Number(seconds) > 0 ? String(seconds) : '';
+      }
+      syncEndpointRefreshVisibility();
+    }
+
+    // Only WireGuard-style peers have an Endpoint to re-resolve.
+    function syncEndpointRefreshVisibility() {
+      if (vpnEndpointRefreshWrap) {
+        vpnEndpointRefreshWrap.classList.toggle('d-none', normalizeVPNType(vpnTypeSelect.value || '') === 'openvpn');
+      }
+    }
+
+    function syncLatencyProbePlaceholder() {
+      if (!vpnLatencyProbeSelect || !vpnLatencyProbeTargetInput) {
+        return;
+      }
+      const placeholders = {
+        https: 'https://www.cloudflare.com/cdn-cgi/trace',
+        dns: 'Tunnel DNS server',
+      };
+      vpnLatencyProbeTargetInput.placeholder = placeholders[vpnLatencyProbeSelect.value] || 'Gateway';
+    }
+
+    function syncIPv6PrefixVisibility() {
+      if (vpnIPv6PolicySelect && vpnIPv6PrefixWrap) {
+        vpnIPv6PrefixWrap.classList.toggle('d-none', vpnIPv6PolicySelect.value !== 'native');
+      }
+    }
+
+    function setIPv6Policy(policy, prefix) {
+      if (!vpnIPv6PolicySelect) {
+        return;
+      }
+      const clean = (policy || '').trim().toLowerCase();
+      vpnIPv6PolicySelect.value = clean === 'drop' || clean === 'native' ? clean : '';
+      if (vpnIPv6PrefixInput) {
+        vpnIPv6PrefixInput.value = (prefix || '').trim();
+      }
+      syncIPv6PrefixVisibility();
+    }
+
+    function readIPv6Policy() {
+      if (!vpnIPv6PolicySelect) {
+        return {};
+      }
+      const ipv6Policy = vpnIPv6PolicySelect.value || '';
+      const ipv6Prefix = ipv6Policy === 'native' ? (vpnIPv6PrefixInput?.value || '').trim() : '';
+      return { ipv6Policy, ipv6Prefix };
+    }
+
+    vpnIPv6PolicySelect?.addEventListener('change', syncIPv6PrefixVisibility);
+    vpnLatencyProbeSelect?.addEventListener('change', syncLatencyProbePlaceholder);
+
+    let knownVPNNames = [];
+
+    function setDependsOn(currentName, selected) {
+      if (!vpnDependsOnSelect) {
+        return;
+      }
+      const chosen = new Set(Array.isArray(selected) ? selected : []);
+      vpnDependsOnSelect.innerHTML = '';
+      knownVPNNames
+        .filter((name) => name !== currentName)
+        .forEach((name) => {
+          const option = document.createElement('option');
+          option.value = name;
+          option.textContent = name;
+          option.selected = chosen.has(name);
+          vpnDependsOnSelect.appendChild(option);
+        });
+    }
+
+    function readDependsOn() {
+      if (!vpnDependsOnSelect) {
+        return [];
+      }
+      return Array.from(vpnDependsOnSelect.selectedOptions).map((option) => option.value);
+    }
+
+    function setUplinkVPN(currentName, selected) {
+      if (!vpnUplinkSelect) {
+        return;
+      }
+      vpnUplinkSelect.innerHTML = '';
+      const none = document.createElement('option');
+      none.value = '';
+      none.textContent = 'None (direct)';
+      vpnUplinkSelect.appendChild(none);
+      knownVPNNames
+        .filter((name) => name !== currentName)
+        .forEach((name) => {
+          const option = document.createElement('option');
+          option.value = name;
+          option.textContent = name;
+          vpnUplinkSelect.appendChild(option);
+        });
+      vpnUplinkSelect.value = knownVPNNames.includes(selected) ? selected : '';
+      syncUplinkExclusivity();
+    }
+
+    // A nested tunnel reaches its endpoint through the uplink VPN, so the
+    // egress WAN pin does not apply while an uplink is selected.
+    function syncUplinkExclusivity() {
+      if (!vpnUplinkSelect || !vpnBoundInterfaceInput) {
+        return;
+      }
+      const nested = vpnUplinkSelect.value !== '';
+      if (nested) {
+        vpnBoundInterfaceInput.value = '';
+      }
+      vpnBoundInterfaceInput.disabled = nested;
+    }
+
+    vpnUplinkSelect?.addEventListener('change', syncUplinkExclusivity);
+
     addVPNButton.addEventListener('click', () => {
       openAddVPNModal();
     });
@@ -161,6 +311,7 @@
         if (detected) {
           vpnTypeSelect.value = detected;
           renderSupportingFilesMeta();
+          syncEndpointRefreshVisibility();
         }
         awgEditor?.loadFromConfig();
         vpnEditorMeta.textContent = `Loaded file: ${file.name}`;
@@ -176,6 +327,7 @@
         if (detected) {
           vpnTypeSelect.value = detected;
           renderSupportingFilesMeta();
+          syncEndpointRefreshVisibility();
         }
         awgEditor?.loadFromConfig();
       }, 0);
@@ -208,6 +360,7 @@
     vpnTypeSelect.addEventListener('change', () => {
       renderSupportingFilesMeta();
       awgEditor?.loadFromConfig();
+      syncEndpointRefreshVisibility();
     });
 
     confirmDeleteVPNButton.addEventListener('click', async () => {
@@ -248,7 +401,7 @@
             await restartVPN(name);
           }
         } catch (err) {
-          setStatus(err.message, true);
+          setStatus(describeControlError(err), true);
         } finally {
           target.disabled = false;
         }
@@ -301,6 +454,7 @@
     function render(configs, latency, interfaces = []) {
       const latencyMap = new Map(latency.map((item) => [item.name, item]));
       const interfaceMap = new Map((interfaces || []).map((iface) => [iface.interface, iface]));
+      knownVPNNames = configs.map((cfg) => cfg.name).filter(Boolean).sort();
       vpnTableBody.innerHTML = '';
       configs.forEach((cfg) => {
         const row = document.createElement('tr');
@@ -340,6 +494,24 @@
               <button class="btn btn-outline-primary" data-action="inspect-flows" data-name="${cfg.name}" title="Inspect live flows" ${flowInspectorEnabled ? '' : 'disabled'}>
                 <i class="bi bi-search"></i>
               </button>
+              <button class="btn btn-outline-secondary" data-action="dns-leak-test" data-name="${cfg.name}" title="DNS leak test" ${cfg.connected ? '' : 'disabled'}>
+                <i class="bi bi-shield-check"></i>
+              </button>
+              <button class="btn btn-outline-secondary" data-action="path-trace" data-name="${cfg.name}" title="Trace path (MTR)" ${cfg.connected ? '' : 'disabled'}>
+                <i class="bi bi-signpost-split"></i>
+              </button>
+              <button class="btn btn-outline-secondary" data-action="packet-capture" data-name="${cfg.name}" data-interface="${cfg.interfaceName || ''}" title="Capture packets">
+                <i class="bi bi-record-circle"></i>
+              </button>
+              <button class="btn btn-outline-secondary" data-action="vpn-events" data-name="${cfg.name}" title="Connection history">
+                <i class="bi bi-clock-history"></i>
+              </button>
+              <button class="btn btn-outline-secondary" data-action="vpn-quota" data-name="${cfg.name}" title="Data quota">
+                <i class="bi bi-speedometer"></i>
+              </button>
+              <button class="btn btn-outline-secondary" data-action="vpn-config-file" data-name="${cfg.name}" title="Edit config file">
+                <i class="bi bi-file-earmark-code"></i>
+              </button>
               <button class="btn btn-outline-light" data-action="edit" data-name="${cfg.name}" title="Edit">
                 <i class="bi bi-pencil"></i>
               </button>
@@ -384,7 +556,9 @@
         latencyCell.textContent = text;
         latencyCell.classList.add(tone);
         if (latencyInfo && latencyInfo.target) {
-          latencyCell.title = `Gateway: ${latencyInfo.target}`;
+          const probeLabels = { https: 'HTTPS GET', dns: 'DNS query' };
+          const probeLabel = probeLabels[latencyInfo.probe];
+          latencyCell.title = probeLabel ? `${probeLabel}: ${latencyInfo.target}` : `Gateway: ${latencyInfo.target}`;
         } else {
           latencyCell.removeAttribute('title');
         }
@@ -402,12 +576,12 @@
     }
 
     async function startVPN(name) {
-      await fetchJSON(`/api/configs/${encodeURIComponent(name)}/start`, { method: 'POST' });
+      await fetchJSON(`/api/vpns/${encodeURIComponent(name)}/start`, { method: 'POST' });
       setStatus(`Started ${name}.`, false);
     }
 
     async function stopVPN(name) {
-      await fetchJSON(`/api/configs/${encodeURIComponent(name)}/stop`, { method: 'POST' });
+      await fetchJSON(`/api/vpns/${encodeURIComponent(name)}/stop`, { method: 'POST' });
       setStatus(`Stopped ${name}.`, false);
     }
 
@@ -416,6 +590,14 @@
       setStatus(`Restarted ${name}.`, false);
     }
 
+    function describeControlError(err) {
+      const journal = Array.isArray(err?.payload?.journal) ? err.payload.journal : [];
+      if (!journal.length) {
+        return err.message;
+      }
+      return `${err.message} — journal: ${journal.slice(-3).join(' | ')}`;
+    }
+
     async function deleteVPN(name) {
       await fetchJSON(`/api/vpns/${encodeURIComponent(name)}`, { method: 'DELETE' });
       setStatus(`Deleted ${name}.`, false);
@@ -446,6 +628,13 @@
       vpnConfigEditor.value = '';
       vpnEditorMeta.textContent = '';
       setMSSFields('', '');
+      setBoundInterface('');
+      setOnDemandIdle(0);
+      setLatencyProbe('', '');
+      setEndpointRefresh(0);
+      setDependsOn('', []);
+      setUplinkVPN('', '');
+      setIPv6Policy('', '');
       awgEditor?.reset();
       renderSupportingFilesMeta();
       vpnEditorModal.show();
@@ -473,6 +662,13 @@
         vpnConfigEditor.value = profile.rawConfig || '';
         vpnEditorMeta.textContent = `Config file: ${profile.configFile || 'auto'}`;
         setMSSFields(profile.mssClampV4, profile.mssClampV6);
+        setBoundInterface(profile.boundInterface);
+        setOnDemandIdle(profile.onDemandIdleMinutes);
+        setLatencyProbe(profile.latencyProbe, profile.latencyProbeTarget);
+        setEndpointRefresh(profile.endpointRefreshSeconds);
+        setDependsOn(profile.name || name, profile.dependsOn);
+        setUplinkVPN(profile.name || name, profile.uplinkVpn);
+        setIPv6Policy(profile.ipv6Policy, profile.ipv6Prefix);
         awgEditor?.loadFromConfig();
         renderSupportingFilesMeta();
         vpnEditorModal.show();
@@ -496,7 +692,24 @@
       if (!config.trim()) {
         throw new Error('VPN configuration content is required.');
       }
-      const payload = { name, type, config, ...readMSSFields() };
+      const payload = { name, type, config, ...readMSSFields(), ...readIPv6Policy() };
+      if (vpnBoundInterfaceInput) {
+        payload.boundInterface = (vpnBoundInterfaceInput.value || '').trim();
+      }
+      payload.dependsOn = readDependsOn();
+      if (vpnOnDemandIdleInput) {
+        payload.onDemandIdleMinutes = Number(vpnOnDemandIdleInput.value || 0);
+      }
+      if (vpnLatencyProbeSelect) {
+        payload.latencyProbe = vpnLatencyProbeSelect.value || '';
+        payload.latencyProbeTarget = (vpnLatencyProbeTargetInput?.value || '').trim();
+      }
+      if (vpnEndpointRefreshInput) {
+        payload.endpointRefreshSeconds = type === 'openvpn' ? 0 : Number(vpnEndpointRefreshInput.value || 0);
+      }
+      if (vpnUplinkSelect) {
+        payload.uplinkVpn = vpnUplinkSelect.value || '';
+      }
       const explicitFile = (state.vpnEditor?.configFileName || '').trim();
       if (explicitFile) {
         payload.configFile = explicitFile;
@@ -0,0 +1,70 @@
+(() => {
+  const probeButton = document.getElementById('vpn-mss-probe');
+  const resultLabel = document.getElementById('vpn-mss-probe-result');
+  const nameInput = document.getElementById('vpn-name');
+  const modeSelect = document.getElementById('vpn-mss-mode');
+  const v4Input = document.getElementById('vpn-mss-v4');
+  const v6Input = document.getElementById('vpn-mss-v6');
+  const editorModal = document.getElementById('vpnEditorModal');
+
+  if (!probeButton || !resultLabel || !nameInput || !modeSelect || !v4Input || !v6Input || !editorModal) {
+    return;
+  }
+
+  editorModal.addEventListener('show.bs.modal', () => {
+    resultLabel.textContent = '';
+    resultLabel.classList.remove('text-danger');
+  });
+
+  probeButton.addEventListener('click', async () => {
+    const name = (nameInput.value || '').trim();
+    if (!name || !nameInput.readOnly) {
+      showResult('Save the VPN and start it before probing.', true);
+      return;
+    }
+    probeButton.disabled = true;
+    showResult('Probing path MTU through the tunnel…', false);
+    try {
+      const response = await fetch(`/api/vpns/${encodeURIComponent(name)}/mtu-probe`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: '{}',
+      });
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'MTU probe failed');
+      }
+      apply(payload.probe || {});
+    } catch (err) {
+      showResult(err.message, true);
+    } finally {
+      probeButton.disabled = false;
+    }
+  });
+
+  function apply(probe) {
+    const v4 = probe.ipv4 || {};
+    const v6 = probe.ipv6 || {};
+    if (!v4.recommendedMss && !v6.recommendedMss) {
+      showResult(`No reply through the tunnel. ${v4.error || v6.error || ''}`.trim(), true);
+      return;
+    }
+    modeSelect.value = 'custom';
+    modeSelect.dispatchEvent(new Event('change'));
+    v4Input.value = v4.recommendedMss ? String(v4.recommendedMss) : '';
+    v6Input.value = v6.recommendedMss ? String(v6.recommendedMss) : '';
+    showResult(
+      `Interface MTU ${probe.interfaceMtu}; path MTU IPv4 ${describe(v4)}, IPv6 ${describe(v6)}. Save to apply the clamp.`,
+      false,
+    );
+  }
+
+  function describe(result) {
+    return result.mtu ? `${result.mtu} (MSS ${result.recommendedMss})` : 'unavailable';
+  }
+
+  function showResult(message, isError) {
+    resultLabel.textContent = message;
+    resultLabel.classList.toggle('text-danger', isError);
+  }
+})();
@@ -0,0 +1,148 @@
+(() => {
+  const vpnTableBody = document.querySelector('#vpn-table tbody');
+  const modalElement = document.getElementById('packetCaptureModal');
+  const statusBox = document.getElementById('packet-capture-status');
+  const interfaceSelect = document.getElementById('packet-capture-interface');
+  const hostInput = document.getElementById('packet-capture-host');
+  const portInput = document.getElementById('packet-capture-port');
+  const protocolSelect = document.getElementById('packet-capture-protocol');
+  const durationInput = document.getElementById('packet-capture-duration');
+  const sizeInput = document.getElementById('packet-capture-size');
+  const startButton = document.getElementById('packet-capture-start');
+
+  if (!vpnTableBody || !modalElement || !statusBox || !interfaceSelect || !hostInput || !portInput
+    || !protocolSelect || !durationInput || !sizeInput || !startButton) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  let controller = null;
+
+  vpnTableBody.addEventListener('click', (event) => {
+    const target = event.target.closest('[data-action="packet-capture"]');
+    if (!target) {
+      return;
+    }
+    open(target.getAttribute('data-interface') || '');
+  });
+
+  modalElement.addEventListener('hidden.bs.modal', () => {
+    if (controller) {
+      controller.abort();
+    }
+  });
+
+  startButton.addEventListener('click', () => {
+    capture();
+  });
+
+  async function open(preferred) {
+    hideStatus();
+    modal.show();
+    try {
+      const response = await fetch('/api/diagnostics/capture/interfaces');
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Failed to load interfaces');
+      }
+      renderInterfaces(payload.interfaces || [], preferred);
+      const limits = payload.limits || {};
+      if (limits.maxDurationSeconds) {
+        durationInput.max = String(limits.maxDurationSeconds);
+      }
+      if (limits.maxBytes) {
+        sizeInput.max = String(Math.floor(limits.maxBytes / (1024 * 1024)));
+      }
+    } catch (err) {
+      showStatus(err.message, 'alert-danger');
+    }
+  }
+
+  function renderInterfaces(interfaces, preferred) {
+    interfaceSelect.innerHTML = '';
+    interfaces.forEach((item) => {
+      const option = document.createElement('option');
+      option.value = item.name;
+      option.textContent = item.vpn ? `${item.name} (${item.vpn})` : `${item.name} (bridge)`;
+      interfaceSelect.appendChild(option);
+    });
+    if (preferred && interfaces.some((item) => item.name === preferred)) {
+      interfaceSelect.value = preferred;
+    }
+    startButton.disabled = interfaces.length === 0;
+  }
+
+  async function capture() {
+    const iface = interfaceSelect.value;
+    if (!iface || controller) {
+      return;
+    }
+    const params = new URLSearchParams({ interface: iface });
+    const host = hostInput.value.trim();
+    if (host) {
+      params.set('host', host);
+    }
+    if (portInput.value) {
+      params.set('port', portInput.value);
+    }
+    if (protocolSelect.value) {
+      params.set('protocol', protocolSelect.value);
+    }
+    const seconds = Number(durationInput.value || 0);
+    if (seconds > 0) {
+      params.set('duration', String(Math.round(seconds)));
+    }
+    const megabytes = Number(sizeInput.value || 0);
+    if (megabytes > 0) {
+      params.set('maxBytes', String(Math.round(megabytes * 1024 * 1024)));
+    }
+
+    controller = new AbortController();
+    startButton.disabled = true;
+    showStatus(`Capturing on ${iface} for up to ${seconds || 30}s…`, 'alert-secondary');
+    try {
+      const response = await fetch(`/api/diagnostics/capture?${params.toString()}`, { signal: controller.signal });
+      if (!response.ok) {
+        const payload = await response.json().catch(() => ({}));
+        throw new Error(payload.error || response.statusText || 'Capture failed');
+      }
+      const blob = await response.blob();
+      download(blob, filenameFrom(response) || `capture-${iface}.pcap`);
+      showStatus(`Capture finished (${(blob.size / 1024).toFixed(1)} KiB).`, 'alert-success');
+    } catch (err) {
+      if (err.name !== 'AbortError') {
+        showStatus(err.message, 'alert-danger');
+      }
+    } finally {
+      controller = null;
+      startButton.disabled = false;
+    }
+  }
+
+  function filenameFrom(response) {
+    const disposition = response.headers.get('Content-Disposition') || '';
+    const match = disposition.match(/filename="([^"]+)"/);
+    return match ? match[1] : '';
+  }
+
+  function download(blob, filename) {
+    const url = URL.createObjectURL(blob);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = filename;
+    document.body.appendChild(link);
+    link.click();
+    link.remove();
+    URL.revokeObjectURL(url);
+  }
+
+  function showStatus(message, variant) {
+    statusBox.className = `alert py-2 small mb-3 ${variant}`;
+    statusBox.textContent = message;
+  }
+
+  function hideStatus() {
+    statusBox.className = 'alert d-none py-2 small mb-3';
+    statusBox.textContent = '';
+  }
+})();
@@ -0,0 +1,145 @@
+(() => {
+  const vpnTableBody = document.querySelector('#vpn-table tbody');
+  const modalElement = document.getElementById('pathTraceModal');
+  const title = document.getElementById('path-trace-title');
+  const targetInput = document.getElementById('path-trace-target');
+  const compareWAN = document.getElementById('path-trace-compare-wan');
+  const runButton = document.getElementById('path-trace-run');
+  const statusBox = document.getElementById('path-trace-status');
+  const results = document.getElementById('path-trace-results');
+
+  if (!vpnTableBody || !modalElement || !title || !targetInput || !compareWAN || !runButton || !statusBox || !results) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  let currentVPN = '';
+  let running = false;
+
+  vpnTableBody.addEventListener('click', (event) => {
+    const target = event.target.closest('[data-action="path-trace"]');
+    if (!target) {
+      return;
+    }
+    const name = target.getAttribute('data-name');
+    if (!name) {
+      return;
+    }
+    currentVPN = name;
+    title.textContent = `Path Trace — ${name}`;
+    results.innerHTML = '';
+    hideStatus();
+    modal.show();
+  });
+
+  runButton.addEventListener('click', () => {
+    run();
+  });
+
+  targetInput.addEventListener('keydown', (event) => {
+    if (event.key === 'Enter') {
+      event.preventDefault();
+      run();
+    }
+  });
+
+  async function run() {
+    const target = targetInput.value.trim();
+    if (!currentVPN || !target || running) {
+      return;
+    }
+    running = true;
+    runButton.disabled = true;
+    results.innerHTML = '';
+    showStatus('Tracing… this takes a few seconds per path.', 'alert-secondary');
+    const paths = [{ label: currentVPN, vpn: currentVPN }];
+    if (compareWAN.checked) {
+      paths.push({ label: 'WAN', vpn: '' });
+    }
+    const outcomes = await Promise.all(paths.map((path) => trace(path, target)));
+    const failed = outcomes.filter((outcome) => outcome.error);
+    results.innerHTML = outcomes.map((outcome) => renderPath(outcome, outcomes.length)).join('');
+    if (failed.length === outcomes.length) {
+      showStatus(failed[0].error, 'alert-danger');
+    } else {
+      hideStatus();
+    }
+    running = false;
+    runButton.disabled = false;
+  }
+
+  async function trace(path, target) {
+    try {
+      const response = await fetch('/api/diagnostics/mtr', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ target, vpn: path.vpn }),
+      });
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Trace failed');
+      }
+      return { label: path.label, report: payload.mtr || {} };
+    } catch (err) {
+      return { label: path.label, error: err.message };
+    }
+  }
+
+  function renderPath(outcome, count) {
+    const column = count > 1 ? 'col-lg-6' : 'col-12';
+    if (outcome.error) {
+      return `<div class="${column}"><h6>${escapeHTML(outcome.label)}</h6><div class="alert alert-danger py-2 small">${escapeHTML(outcome.error)}</div></div>`;
+    }
+    const report = outcome.report;
+    const hops = Array.isArray(report.hops) ? report.hops : [];
+    const rows = hops.map((hop) => `
+      <tr>
+        <td>${hop.ttl}</td>
+        <td class="font-monospace">${escapeHTML(hop.address || '???')}${(hop.alternate || []).length ? ` <span class="text-body-secondary">+${hop.alternate.length}</span>` : ''}</td>
+        <td class="${hop.lossPct > 0 ? 'text-warning' : ''}">${Number(hop.lossPct || 0).toFixed(0)}%</td>
+        <td>${formatMs(hop.lastMs)}</td>
+        <td>${formatMs(hop.avgMs)}</td>
+        <td>${formatMs(hop.bestMs)}</td>
+        <td>${formatMs(hop.worstMs)}</td>
+        <td>${formatMs(hop.stdDevMs)}</td>
+      </tr>`).join('');
+    const reached = report.reached ? '<span class="badge text-bg-success">reached</span>' : '<span class="badge text-bg-warning">not reached</span>';
+    return `
+      <div class="${column}">
+        <h6 class="d-flex align-items-center gap-2">${escapeHTML(outcome.label)}
+          <span class="small text-body-secondary font-monospace">${escapeHTML(report.interface || '')} → ${escapeHTML(report.address || '')}</span>
+          ${reached}
+        </h6>
+        <div class="table-responsive">
+          <table class="table table-sm align-middle small mb-0">
+            <thead>
+              <tr><th>#</th><th>Host</th><th>Loss</th><th>Last</th><th>Avg</th><th>Best</th><th>Worst</th><th>StDev</th></tr>
+            </thead>
+            <tbody>${rows}</tbody>
+          </table>
+        </div>
+      </div>`;
+  }
+
+  function formatMs(value) {
+    return value ? `${Number(value).toFixed(1)}` : '–';
+  }
+
+  function escapeHTML(value) {
+    return String(value)
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;');
+  }
+
+  function showStatus(message, variant) {
+    statusBox.className = `alert py-2 small mb-3 ${variant}`;
+    statusBox.textContent = message;
+  }
+
+  function hideStatus() {
+    statusBox.className = 'alert d-none py-2 small mb-3';
+    statusBox.textContent = '';
+  }
+})();
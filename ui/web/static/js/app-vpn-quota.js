@@ -0,0 +1,159 @@
+(() => {
+  const vpnTableBody = document.querySelector('#vpn-table tbody');
+  const modalElement = document.getElementById('vpnQuotaModal');
+  const title = document.getElementById('vpn-quota-title');
+  const errorBox = document.getElementById('vpn-quota-error');
+  const usedLabel = document.getElementById('vpn-quota-used');
+  const periodLabel = document.getElementById('vpn-quota-period');
+  const bar = document.getElementById('vpn-quota-bar');
+  const limitInput = document.getElementById('vpn-quota-limit');
+  const resetDayInput = document.getElementById('vpn-quota-reset-day');
+  const warnInput = document.getElementById('vpn-quota-warn');
+  const actionSelect = document.getElementById('vpn-quota-action');
+  const failoverSelect = document.getElementById('vpn-quota-failover');
+  const deleteButton = document.getElementById('vpn-quota-delete');
+  const resetButton = document.getElementById('vpn-quota-reset');
+  const saveButton = document.getElementById('vpn-quota-save');
+
+  if (!vpnTableBody || !modalElement || !title || !errorBox || !usedLabel || !periodLabel || !bar
+    || !limitInput || !resetDayInput || !warnInput || !actionSelect || !failoverSelect
+    || !deleteButton || !resetButton || !saveButton) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  const bytesPerGB = 1e9;
+  let currentVPN = '';
+  let hasQuota = false;
+
+  vpnTableBody.addEventListener('click', (event) => {
+    const target = event.target.closest('[data-action="vpn-quota"]');
+    if (!target) {
+      return;
+    }
+    const name = target.getAttribute('data-name');
+    if (!name) {
+      return;
+    }
+    currentVPN = name;
+    title.textContent = `Data Quota — ${name}`;
+    modal.show();
+    load();
+  });
+
+  actionSelect.addEventListener('change', () => {
+    failoverSelect.disabled = actionSelect.value !== 'failover';
+  });
+
+  saveButton.addEventListener('click', async () => {
+    const limitGB = Number(limitInput.value || 0);
+    if (!(limitGB > 0)) {
+      showError('Enter a monthly quota in GB.');
+      return;
+    }
+    const warnPercents = String(warnInput.value || '')
+      .split(/[\s,]+/)
+      .filter(Boolean)
+      .map(Number);
+    await send('PUT', '', {
+      limitBytes: Math.round(limitGB * bytesPerGB),
+      warnPercents,
+      resetDay: Number(resetDayInput.value || 1),
+      action: actionSelect.value,
+      failoverVpn: actionSelect.value === 'failover' ? failoverSelect.value : '',
+    }, 'Quota saved');
+  });
+
+  resetButton.addEventListener('click', () => send('POST', '/reset', null, 'Usage reset'));
+  deleteButton.addEventListener('click', () => send('DELETE', '', null, 'Quota removed'));
+
+  async function send(method, suffix, body, done) {
+    errorBox.classList.add('d-none');
+    try {
+      const response = await fetch(`/api/vpns/${encodeURIComponent(currentVPN)}/quota${suffix}`, {
+        method,
+        headers: body ? { 'Content-Type': 'application/json' } : undefined,
+        body: body ? JSON.stringify(body) : undefined,
+      });
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Quota request failed');
+      }
+      usedLabel.textContent = done;
+      await load();
+    } catch (err) {
+      showError(err.message);
+    }
+  }
+
+  async function load() {
+    if (!currentVPN) {
+      return;
+    }
+    errorBox.classList.add('d-none');
+    try {
+      const [quotaResponse, vpnResponse] = await Promise.all([fetch('/api/quotas'), fetch('/api/vpns')]);
+      const quotaPayload = await quotaResponse.json().catch(() => ({}));
+      if (!quotaResponse.ok) {
+        throw new Error(quotaPayload.error || quotaResponse.statusText || 'Failed to load quotas');
+      }
+      const vpnPayload = await vpnResponse.json().catch(() => ({}));
+      const names = (vpnPayload.vpns || []).map((profile) => profile.name).filter((name) => name && name !== currentVPN);
+      const status = (quotaPayload.quotas || []).find((entry) => entry.vpn === currentVPN);
+      render(status, names);
+    } catch (err) {
+      showError(err.message);
+    }
+  }
+
+  function render(status, names) {
+    hasQuota = Boolean(status);
+    failoverSelect.innerHTML = '';
+    names.forEach((name) => {
+      const option = document.createElement('option');
+      option.value = name;
+      option.textContent = name;
+      failoverSelect.appendChild(option);
+    });
+    deleteButton.disabled = !hasQuota;
+    resetButton.disabled = !hasQuota;
+    if (!status) {
+      usedLabel.textContent = 'No quota set';
+      periodLabel.textContent = '';
+      setBar(0, false);
+      limitInput.value = '';
+      resetDayInput.value = '';
+      warnInput.value = '80, 95';
+      actionSelect.value = 'none';
+      failoverSelect.disabled = true;
+      return;
+    }
+    const percent = Number(status.percent || 0);
+    usedLabel.textContent = `${formatGB(status.usedBytes)} of ${formatGB(status.limitBytes)} (${percent.toFixed(1)}%)`;
+    periodLabel.textContent = `resets ${new Date(status.periodEnd).toLocaleDateString()}`;
+    setBar(percent, Boolean(status.exceeded));
+    limitInput.value = String(Number(status.limitBytes || 0) / bytesPerGB);
+    resetDayInput.value = String(status.resetDay || 1);
+    warnInput.value = (status.warnPercents || []).join(', ');
+    actionSelect.value = status.action || 'none';
+    if (status.failoverVpn) {
+      failoverSelect.value = status.failoverVpn;
+    }
+    failoverSelect.disabled = actionSelect.value !== 'failover';
+  }
+
+  function setBar(percent, exceeded) {
+    bar.style.width = `${Math.min(100, Math.max(0, percent))}%`;
+    bar.classList.toggle('bg-danger', exceeded);
+    bar.classList.toggle('bg-warning', !exceeded && percent >= 80);
+  }
+
+  function formatGB(bytes) {
+    return `${(Number(bytes || 0) / bytesPerGB).toFixed(2)} GB`;
+  }
+
+  function showError(message) {
+    errorBox.textContent = message;
+    errorBox.classList.remove('d-none');
+  }
+})();
@@ -148,6 +148,8 @@
       const excludedAsns = joinValues(rule?.excludedDestinationAsns);
       const excludedDestinationCidrs = joinValues(rule?.excludedDestinationCidrs);
       const excludeMulticast = rule?.excludeMulticast !== false ? 'enabled' : 'disabled';
+      const formatLimit = (kbit) => (kbit > 0 ? `${kbit / 1000} Mbit/s` : 'unlimited');
+      const bandwidth = `up ${formatLimit(rule?.uploadLimitKbit)}, down ${formatLimit(rule?.downloadLimitKbit)}`;
       const domains = joinValues(rule?.domains);
       const wildcards = joinValues(rule?.wildcardDomains);
       const sourceMACs = formatSourceMACs(rule?.sourceMacs);
@@ -183,6 +185,10 @@
       meta.appendChild(createSearchLine(`Excluded destination ASNs: ${excludedAsns}`));
       meta.appendChild(createSearchLine(`Excluded destination CIDRs: ${excludedDestinationCidrs}`));
       meta.appendChild(createSearchLine(`Exclude multicast: ${excludeMulticast}`));
+      meta.appendChild(createSearchLine(`Bandwidth limit: ${bandwidth}`));
+      if (rule?.monitorOnly) {
+        meta.appendChild(createSearchLine('Mode: monitor only (logged to NFLOG, not routed)'));
+      }
       meta.appendChild(createSearchLine(`Domains: ${domains}`));
       meta.appendChild(createSearchLine(`Wildcard domains: ${wildcards}`));
       wrapper.appendChild(meta);
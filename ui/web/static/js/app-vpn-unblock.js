@@ -0,0 +1,136 @@
+(() => {
+  const card = document.getElementById('unblock-card');
+  const tableBody = document.querySelector('#unblock-table tbody');
+  const table = document.getElementById('unblock-table');
+  const emptyState = document.getElementById('unblock-empty');
+  const statusBox = document.getElementById('unblock-status');
+  const scheduleLabel = document.getElementById('unblock-schedule');
+  const runButton = document.getElementById('run-unblock-check');
+
+  if (!card || !tableBody || !table || !emptyState || !statusBox || !scheduleLabel || !runButton) {
+    return;
+  }
+
+  const services = ['netflix', 'disney-plus', 'hbo-max'];
+  const statusLabels = {
+    unblocked: { text: 'Unblocked', className: 'text-bg-success' },
+    'originals-only': { text: 'Originals only', className: 'text-bg-warning' },
+    blocked: { text: 'Blocked', className: 'text-bg-danger' },
+    unreachable: { text: 'Unreachable', className: 'text-bg-secondary' },
+  };
+  let running = false;
+
+  runButton.addEventListener('click', () => run(''));
+  tableBody.addEventListener('click', (event) => {
+    const target = event.target.closest('[data-action="unblock-recheck"]');
+    if (target) {
+      run(target.getAttribute('data-name') || '');
+    }
+  });
+  card.addEventListener('unblock:results', () => load());
+
+  load();
+
+  async function load() {
+    try {
+      const response = await fetch('/api/unblock');
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Failed to load unblock results');
+      }
+      const hours = Number(payload.intervalHours || 0);
+      scheduleLabel.textContent = hours > 0 ? `Checks run every ${hours} h.` : 'Checks run on demand.';
+      render(payload.results || []);
+    } catch (err) {
+      showStatus(err.message, 'alert-danger');
+    }
+  }
+
+  async function run(vpn) {
+    if (running) {
+      return;
+    }
+    running = true;
+    runButton.disabled = true;
+    showStatus(vpn ? `Checking streaming services through ${vpn}…` : 'Checking streaming services through every connected VPN…', 'alert-secondary');
+    try {
+      const response = await fetch('/api/unblock/run', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ vpn }),
+      });
+      const payload = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(payload.error || response.statusText || 'Unblock check failed');
+      }
+      hideStatus();
+      render(payload.results || []);
+    } catch (err) {
+      showStatus(err.message, 'alert-danger');
+    } finally {
+      running = false;
+      runButton.disabled = false;
+    }
+  }
+
+  function render(results) {
+    tableBody.innerHTML = '';
+    table.classList.toggle('d-none', results.length === 0);
+    emptyState.classList.toggle('d-none', results.length > 0);
+    results.forEach((result) => {
+      const row = document.createElement('tr');
+      const name = document.createElement('td');
+      name.className = 'fw-semibold';
+      name.textContent = result.vpn;
+      row.appendChild(name);
+      const byService = new Map((result.services || []).map((service) => [service.service, service]));
+      services.forEach((id) => {
+        const cell = document.createElement('td');
+        cell.appendChild(renderBadge(byService.get(id)));
+        row.appendChild(cell);
+      });
+      const checked = document.createElement('td');
+      checked.className = 'text-body-secondary small';
+      checked.textContent = result.checkedAt ? new Date(result.checkedAt).toLocaleString() : '–';
+      row.appendChild(checked);
+      const actions = document.createElement('td');
+      actions.className = 'text-end';
+      const recheck = document.createElement('button');
+      recheck.className = 'btn btn-outline-secondary btn-sm';
+      recheck.setAttribute('data-action', 'unblock-recheck');
+      recheck.setAttribute('data-name', result.vpn);
+      recheck.title = `Check ${result.vpn} again`;
+      recheck.innerHTML = '<i class="bi bi-arrow-repeat"></i>';
+      actions.appendChild(recheck);
+      row.appendChild(actions);
+      tableBody.appendChild(row);
+    });
+  }
+
+  function renderBadge(service) {
+    const badge = document.createElement('span');
+    if (!service) {
+      badge.className = 'text-body-secondary';
+      badge.textContent = '–';
+      return badge;
+    }
+    const label = statusLabels[service.status] || { text: service.status, className: 'text-bg-secondary' };
+    badge.className = `badge ${label.className}`;
+    badge.textContent = service.region ? `${label.text} · ${service.region}` : label.text;
+    if (service.detail) {
+      badge.title = service.detail;
+    }
+    return badge;
+  }
+
+  function showStatus(message, className) {
+    statusBox.classList.remove('d-none', 'alert-secondary', 'alert-danger');
+    statusBox.classList.add(className);
+    statusBox.textContent = message;
+  }
+
+  function hideStatus() {
+    statusBox.classList.add('d-none');
+    statusBox.textContent = '';
+  }
+})();
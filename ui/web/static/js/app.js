@@ -23,6 +23,17 @@
   const vpnMSSV6Wrap = document.getElementById('vpn-mss-v6-wrap');
   const vpnMSSV4Input = document.getElementById('vpn-mss-v4');
   const vpnMSSV6Input = document.getElementById('vpn-mss-v6');
+  const vpnBoundInterfaceInput = document.getElementById('vpn-bound-interface');
+  const vpnDependsOnSelect = document.getElementById('vpn-depends-on');
+  const vpnUplinkSelect = document.getElementById('vpn-uplink');
+  const vpnOnDemandIdleInput = document.getElementById('vpn-on-demand-idle');
+  const vpnLatencyProbeSelect = document.getElementById('vpn-latency-probe');
+  const vpnLatencyProbeTargetInput = document.getElementById('vpn-latency-probe-target');
+  const vpnEndpointRefreshWrap = document.getElementById('vpn-endpoint-refresh-wrap');
+  const vpnEndpointRefreshInput = document.getElementById('vpn-endpoint-refresh');
+  const vpnIPv6PolicySelect = document.getElementById('vpn-ipv6-policy');
+  const vpnIPv6PrefixWrap = document.getElementById('vpn-ipv6-prefix-wrap');
+  const vpnIPv6PrefixInput = document.getElementById('vpn-ipv6-prefix');
   const saveVPNButton = document.getElementById('save-vpn');
   const saveVPNLabel = document.getElementById('save-vpn-label');
   const deleteVPNModalElement = document.getElementById('deleteVpnModal');
@@ -58,10 +69,73 @@
   const speedtestModal = speedtestModalElement ? new bootstrap.Modal(speedtestModalElement) : null;
   const settingsModalElement = document.getElementById('settingsModal');
   const settingsModal = new bootstrap.Modal(settingsModalElement);
-  const listenSelect = document.getElementById('listen-interface');
+  const listenInput = document.getElementById('listen-interface');
+  const listenOptions = document.getElementById('listen-interface-options');
   const wanSelect = document.getElementById('wan-interface');
+  const wanPriorityInput = document.getElementById('wan-priority');
+  const statsPollInput = document.getElementById('stats-poll-seconds');
+  const statsDisabledInput = document.getElementById('stats-disabled-interfaces');
+  const latencyIntervalInput = document.getElementById('latency-interval-seconds');
   const debugLogEnabledInput = document.getElementById('debug-log-enabled');
   const debugLogLevelSelect = document.getElementById('debug-log-level');
+  const publicStatusEnabledInput = document.getElementById('public-status-enabled');
+  const kioskListenInput = document.getElementById('kiosk-listen');
+  const reputationEnabledInput = document.getElementById('reputation-enabled');
+  const reputationSpamhausEnabledInput = document.getElementById('reputation-spamhaus-enabled');
+  const reputationAbuseIPDBKeyInput = document.getElementById('reputation-abuseipdb-key');
+  const reputationAbuseIPDBMinScoreInput = document.getElementById('reputation-abuseipdb-min-score');
+  const driftModeSelect = document.getElementById('drift-mode');
+  const driftIntervalInput = document.getElementById('drift-interval-seconds');
+  const provisionWatchEnabledInput = document.getElementById('provision-watch-enabled');
+  const anomalyHighMbpsInput = document.getElementById('anomaly-high-mbps');
+  const anomalyHighMinutesInput = document.getElementById('anomaly-high-minutes');
+  const anomalyStallMinutesInput = document.getElementById('anomaly-stall-minutes');
+  const unblockIntervalInput = document.getElementById('unblock-check-interval-hours');
+  const dnsBackendSelect = document.getElementById('dns-backend');
+  const dnsBackendConfigPathInput = document.getElementById('dns-backend-config-path');
+  const adguardURLInput = document.getElementById('adguard-url');
+  const adguardUsernameInput = document.getElementById('adguard-username');
+  const adguardPasswordInput = document.getElementById('adguard-password');
+  const unifiControllerURLInput = document.getElementById('unifi-controller-url');
+  const unifiControllerSiteInput = document.getElementById('unifi-controller-site');
+  const unifiControllerAPIKeyInput = document.getElementById('unifi-controller-api-key');
+  const hostnameDiscoveryEnabledInput = document.getElementById('hostname-discovery-enabled');
+  const statsRetentionInput = document.getElementById('stats-retention-days');
+  const runRetentionInput = document.getElementById('run-retention-days');
+  const eventRetentionInput = document.getElementById('event-retention-days');
+  const trashRetentionInput = document.getElementById('trash-retention-days');
+  const syncRoleSelect = document.getElementById('sync-role');
+  const syncLeaderURLInput = document.getElementById('sync-leader-url');
+  const syncTokenInput = document.getElementById('sync-token');
+  const syncIntervalInput = document.getElementById('sync-interval');
+  const syncLeaderFingerprintInput = document.getElementById('sync-leader-fingerprint');
+  const syncRunButton = document.getElementById('sync-run');
+  const syncStatusLabel = document.getElementById('sync-status');
+  const mqttBrokerURLInput = document.getElementById('mqtt-broker-url');
+  const mqttUsernameInput = document.getElementById('mqtt-username');
+  const mqttPasswordInput = document.getElementById('mqtt-password');
+  const mqttTopicPrefixInput = document.getElementById('mqtt-topic-prefix');
+  const mqttDiscoveryPrefixInput = document.getElementById('mqtt-discovery-prefix');
+  const mqttIntervalInput = document.getElementById('mqtt-interval');
+  const mqttStatusLabel = document.getElementById('mqtt-status');
+  const metricsExportURLInput = document.getElementById('metrics-export-url');
+  const metricsExportFormatSelect = document.getElementById('metrics-export-format');
+  const metricsExportUsernameInput = document.getElementById('metrics-export-username');
+  const metricsExportTokenInput = document.getElementById('metrics-export-token');
+  const metricsExportIntervalInput = document.getElementById('metrics-export-interval');
+  const metricsExportStatusLabel = document.getElementById('metrics-export-status');
+  const logShipURLInput = document.getElementById('log-ship-url');
+  const logShipMinLevelSelect = document.getElementById('log-ship-min-level');
+  const logShipUsernameInput = document.getElementById('log-ship-username');
+  const logShipTokenInput = document.getElementById('log-ship-token');
+  const logShipStatusLabel = document.getElementById('log-ship-status');
+  const ipfixCollectorInput = document.getElementById('ipfix-collector');
+  const ipfixObservationDomainInput = document.getElementById('ipfix-observation-domain');
+  const ipfixStatusLabel = document.getElementById('ipfix-status');
+  const updateChannelSelect = document.getElementById('update-channel');
+  const updateBackupEnabledInput = document.getElementById('update-backup-enabled');
+  const autoUpdateEnabledInput = document.getElementById('auto-update-enabled');
+  const autoUpdateScheduleInput = document.getElementById('auto-update-schedule');
   const saveSettingsButton = document.getElementById('save-settings');
 
   const palette = ['#3b82f6', '#22d3ee', '#f97316', '#a855f7', '#f43f5e', '#14b8a6', '#eab308'];
@@ -94,6 +168,7 @@
     ? window.SplitVPNUI.createChartHelpers
     : null;
   let flowInspectorController = null;
+  let flowHistoryController = null;
   let speedtestController = null;
   const chartHelpers = chartHelpersFactory
     ? chartHelpersFactory({
@@ -108,6 +183,7 @@
       onInspectFlows: (vpnName) => {
         if (flowInspectorController?.open) {
           flowInspectorController.open(vpnName);
+          flowHistoryController?.load(vpnName);
           return;
         }
         setStatus('Flow inspector is unavailable in this UI build.', true);
@@ -157,6 +233,96 @@
         console.error('Failed to parse payload', err);
       }
     };
+    stream.addEventListener('drift', (event) => {
+      try {
+        showDriftStatus(JSON.parse(event.data));
+      } catch (err) {
+        console.error('Failed to parse drift event', err);
+      }
+    });
+    stream.addEventListener('provision', (event) => {
+      try {
+        const provision = JSON.parse(event.data);
+        if (provision?.repairError) {
+          setStatus(`Re-apply after UniFi provisioning failed: ${provision.repairError}`, true);
+        } else {
+          setStatus('Routing re-applied after UniFi provisioning.', false);
+        }
+      } catch (err) {
+        console.error('Failed to parse provision event', err);
+      }
+    });
+    stream.addEventListener('anomaly', (event) => {
+      try {
+        const anomaly = JSON.parse(event.data);
+        const raised = anomaly?.kind === 'traffic_high' || anomaly?.kind === 'traffic_stalled';
+        setStatus(`${anomaly?.vpn || 'VPN'}: ${anomaly?.detail || anomaly?.kind || 'traffic anomaly'}`, raised);
+      } catch (err) {
+        console.error('Failed to parse anomaly event', err);
+      }
+    });
+    stream.addEventListener('quota', (event) => {
+      try {
+        const quota = JSON.parse(event.data);
+        setStatus(`${quota?.vpn || 'VPN'}: ${quota?.detail || quota?.kind || 'quota event'}`, quota?.kind === 'quota_exceeded');
+      } catch (err) {
+        console.error('Failed to parse quota event', err);
+      }
+    });
+    stream.addEventListener('on-demand', (event) => {
+      try {
+        const change = JSON.parse(event.data);
+        const failed = change?.kind === 'start_failed' || change?.kind === 'stop_failed';
+        setStatus(`${change?.vpn || 'VPN'}: ${change?.detail || change?.kind || 'on-demand change'}`, failed);
+      } catch (err) {
+        console.error('Failed to parse on-demand event', err);
+      }
+    });
+    stream.addEventListener('endpoint-refresh', (event) => {
+      try {
+        const change = JSON.parse(event.data);
+        setStatus(`${change?.vpn || 'VPN'}: ${change?.detail || change?.kind || 'endpoint change'}`, change?.kind === 'endpoint_failed');
+      } catch (err) {
+        console.error('Failed to parse endpoint-refresh event', err);
+      }
+    });
+    stream.addEventListener('sync', (event) => {
+      try {
+        const sync = JSON.parse(event.data);
+        setStatus(sync?.detail || sync?.kind || 'config sync', sync?.kind === 'sync_failed');
+      } catch (err) {
+        console.error('Failed to parse sync event', err);
+      }
+    });
+    stream.addEventListener('agents', (event) => {
+      try {
+        const change = JSON.parse(event.data);
+        if (change?.kind) {
+          setStatus(change.detail || change.kind, change.kind === 'agent_failed');
+        }
+        document.getElementById('remoteAgentsModal')?.dispatchEvent(new CustomEvent('remote-agents:changed'));
+      } catch (err) {
+        console.error('Failed to parse agents event', err);
+      }
+    });
+    stream.addEventListener('unblock', (event) => {
+      try {
+        const results = JSON.parse(event.data);
+        document.getElementById('unblock-card')?.dispatchEvent(new CustomEvent('unblock:results', { detail: results }));
+      } catch (err) {
+        console.error('Failed to parse unblock event', err);
+      }
+    });
+    stream.addEventListener('rule-expiry', (event) => {
+      try {
+        const expired = JSON.parse(event.data) || [];
+        const names = expired.map((rule) => `${rule.group}/${rule.ruleName || `Rule ${Number(rule.ruleIndex || 0) + 1}`}`);
+        setStatus(`Expired routing rules disabled: ${names.join(', ')}`, false);
+        document.getElementById('domain-groups-list')?.dispatchEvent(new CustomEvent('domain-groups:changed'));
+      } catch (err) {
+        console.error('Failed to parse rule expiry event', err);
+      }
+    });
     stream.onerror = () => {
       if (stream) {
         stream.close();
@@ -190,11 +356,23 @@
     await openSettingsModal();
   });
 
+  syncRunButton?.addEventListener('click', async () => {
+    syncRunButton.disabled = true;
+    try {
+      const data = await fetchJSON('/api/sync/run', { method: 'POST' });
+      syncStatusLabel.textContent = describeSyncStatus(data.sync);
+    } catch (err) {
+      syncStatusLabel.textContent = err.message;
+    } finally {
+      syncRunButton.disabled = false;
+    }
+  });
+
   saveSettingsButton.addEventListener('click', async () => {
     const debugLogEnabled = Boolean(debugLogEnabledInput?.checked);
     const debugLogLevel = String(debugLogLevelSelect?.value || 'info').trim().toLowerCase();
     const payload = {
-      listenInterface: listenSelect.value || '',
+      listenInterface: listenInput.value.trim(),
       wanInterface: wanSelect.value || '',
       prewarmParallelism: Number(state.settings?.prewarmParallelism || 0),
       prewarmDoHTimeoutSeconds: Number(state.settings?.prewarmDoHTimeoutSeconds || 0),
@@ -213,23 +391,228 @@
       resolverWildcardEnabled: state.settings?.resolverWildcardEnabled !== false,
       debugLogEnabled,
       debugLogLevel,
+      publicStatusEnabled: Boolean(publicStatusEnabledInput?.checked),
+      kioskListen: String(kioskListenInput?.value || '').trim(),
+      reputationEnabled: Boolean(reputationEnabledInput?.checked),
+      reputationSpamhausEnabled: Boolean(reputationSpamhausEnabledInput?.checked),
+      reputationAbuseIpdbMinScore: Number(reputationAbuseIPDBMinScoreInput?.value || 0),
+      driftMode: String(driftModeSelect?.value || 'alert'),
+      driftIntervalSeconds: Number(driftIntervalInput?.value || 0),
+      provisionWatchEnabled: Boolean(provisionWatchEnabledInput?.checked),
+      anomalyHighMbps: Number(anomalyHighMbpsInput?.value || 0),
+      anomalyHighMinutes: Number(anomalyHighMinutesInput?.value || 0),
+      anomalyStallMinutes: Number(anomalyStallMinutesInput?.value || 0),
+      unblockCheckIntervalHours: Number(unblockIntervalInput?.value || 0),
+      unifiControllerUrl: String(unifiControllerURLInput?.value || '').trim(),
+      unifiControllerSite: String(unifiControllerSiteInput?.value || '').trim(),
+      dnsBackend: String(dnsBackendSelect?.value || 'dnsmasq'),
+      dnsBackendConfigPath: String(dnsBackendConfigPathInput?.value || '').trim(),
+      adguardUrl: String(adguardURLInput?.value || '').trim(),
+      adguardUsername: String(adguardUsernameInput?.value || '').trim(),
+      hostnameDiscoveryEnabled: Boolean(hostnameDiscoveryEnabledInput?.checked),
+      wanPriority: String(wanPriorityInput?.value || '').trim(),
+      statsPollSeconds: Number(statsPollInput?.value || 0),
+      statsDisabledInterfaces: String(statsDisabledInput?.value || '').trim(),
+      latencyIntervalSeconds: Number(latencyIntervalInput?.value || 0),
+      statsRetentionDays: Number(statsRetentionInput?.value || 0),
+      runRetentionDays: Number(runRetentionInput?.value || 0),
+      eventRetentionDays: Number(eventRetentionInput?.value || 0),
+      trashRetentionDays: Number(trashRetentionInput?.value || 0),
+      syncRole: String(syncRoleSelect?.value || ''),
+      syncLeaderUrl: String(syncLeaderURLInput?.value || '').trim(),
+      syncLeaderFingerprint: String(syncLeaderFingerprintInput?.value || '').trim(),
+      syncIntervalSeconds: Number(syncIntervalInput?.value || 0),
+      mqttBrokerUrl: String(mqttBrokerURLInput?.value || '').trim(),
+      mqttUsername: String(mqttUsernameInput?.value || '').trim(),
+      mqttTopicPrefix: String(mqttTopicPrefixInput?.value || '').trim(),
+      mqttDiscoveryPrefix: String(mqttDiscoveryPrefixInput?.value || '').trim(),
+      mqttIntervalSeconds: Number(mqttIntervalInput?.value || 0),
+      metricsExportUrl: String(metricsExportURLInput?.value || '').trim(),
+      metricsExportFormat: String(metricsExportFormatSelect?.value || 'influx'),
+      metricsExportUsername: String(metricsExportUsernameInput?.value || '').trim(),
+      metricsExportIntervalSeconds: Number(metricsExportIntervalInput?.value || 0),
+      logShipUrl: String(logShipURLInput?.value || '').trim(),
+      logShipMinLevel: String(logShipMinLevelSelect?.value || 'info'),
+      logShipUsername: String(logShipUsernameInput?.value || '').trim(),
+      ipfixCollector: String(ipfixCollectorInput?.value || '').trim(),
+      ipfixObservationDomain: Number(ipfixObservationDomainInput?.value || 0),
+      updateChannel: String(updateChannelSelect?.value || 'stable'),
+      updateBackupEnabled: Boolean(updateBackupEnabledInput?.checked),
+      autoUpdateEnabled: Boolean(autoUpdateEnabledInput?.checked),
+      autoUpdateSchedule: String(autoUpdateScheduleInput?.value || '').trim(),
     };
+    const abuseIPDBKey = String(reputationAbuseIPDBKeyInput?.value || '').trim();
+    if (abuseIPDBKey) {
+      payload.reputationAbuseIpdbKey = abuseIPDBKey;
+    }
+    const unifiControllerKey = String(unifiControllerAPIKeyInput?.value || '').trim();
+    if (unifiControllerKey) {
+      payload.unifiControllerApiKey = unifiControllerKey;
+    }
+    const adguardPassword = String(adguardPasswordInput?.value || '');
+    if (adguardPassword) {
+      payload.adguardPassword = adguardPassword;
+    }
+    const syncToken = String(syncTokenInput?.value || '').trim();
+    if (syncToken) {
+      payload.syncToken = syncToken;
+    }
+    const mqttPassword = String(mqttPasswordInput?.value || '');
+    if (mqttPassword) {
+      payload.mqttPassword = mqttPassword;
+    }
+    const metricsExportToken = String(metricsExportTokenInput?.value || '').trim();
+    if (metricsExportToken) {
+      payload.metricsExportToken = metricsExportToken;
+    }
+    const logShipToken = String(logShipTokenInput?.value || '').trim();
+    if (logShipToken) {
+      payload.logShipToken = logShipToken;
+    }
     saveSettingsButton.disabled = true;
+    clearSettingsFieldErrors();
     try {
-      await fetchJSON('/api/settings', {
+      const result = await fetchJSON('/api/settings', {
         method: 'PUT',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(payload),
       });
+      delete payload.reputationAbuseIpdbKey;
+      delete payload.unifiControllerApiKey;
+      delete payload.adguardPassword;
+      delete payload.syncToken;
+      delete payload.mqttPassword;
+      delete payload.metricsExportToken;
+      delete payload.logShipToken;
       state.settings = { ...state.settings, ...payload };
-      setStatus('Settings saved.', false);
+      if (abuseIPDBKey) {
+        state.reputationAbuseIpdbKeyConfigured = true;
+        reputationAbuseIPDBKeyInput.value = '';
+      }
+      if (unifiControllerKey) {
+        state.unifiControllerApiKeyConfigured = true;
+        unifiControllerAPIKeyInput.value = '';
+      }
+      if (adguardPassword) {
+        state.adguardPasswordConfigured = true;
+        adguardPasswordInput.value = '';
+      }
+      if (syncToken) {
+        state.syncTokenConfigured = true;
+        syncTokenInput.value = '';
+      }
+      if (mqttPassword) {
+        state.mqttPasswordConfigured = true;
+        mqttPasswordInput.value = '';
+      }
+      if (metricsExportToken) {
+        state.metricsExportTokenConfigured = true;
+        metricsExportTokenInput.value = '';
+      }
+      if (logShipToken) {
+        state.logShipTokenConfigured = true;
+        logShipTokenInput.value = '';
+      }
+      setStatus(describeSettingsReload(result?.reload), Boolean(result?.reload?.error));
       settingsModal.hide();
     } catch (err) {
+      markSettingsFieldErrors(err.payload?.fieldErrors);
       setStatus(err.message, true);
     } finally {
       saveSettingsButton.disabled = false;
     }
   });
+
+  // settingsFieldInputs maps the settings fields the server validates to
+  // their inputs in the settings modal.
+  function settingsFieldInputs() {
+    return {
+      listenInterface: listenInput,
+      kioskListen: kioskListenInput,
+      wanInterface: wanSelect,
+      wanPriority: wanPriorityInput,
+      statsPollSeconds: statsPollInput,
+      statsDisabledInterfaces: statsDisabledInput,
+      latencyIntervalSeconds: latencyIntervalInput,
+      debugLogLevel: debugLogLevelSelect,
+      reputationAbuseIpdbMinScore: reputationAbuseIPDBMinScoreInput,
+      driftMode: driftModeSelect,
+      driftIntervalSeconds: driftIntervalInput,
+      anomalyHighMbps: anomalyHighMbpsInput,
+      anomalyHighMinutes: anomalyHighMinutesInput,
+      anomalyStallMinutes: anomalyStallMinutesInput,
+      unblockCheckIntervalHours: unblockIntervalInput,
+      dnsBackend: dnsBackendSelect,
+      dnsBackendConfigPath: dnsBackendConfigPathInput,
+      adguardUrl: adguardURLInput,
+      unifiControllerUrl: unifiControllerURLInput,
+      unifiControllerSite: unifiControllerSiteInput,
+      statsRetentionDays: statsRetentionInput,
+      runRetentionDays: runRetentionInput,
+      eventRetentionDays: eventRetentionInput,
+      trashRetentionDays: trashRetentionInput,
+      syncRole: syncRoleSelect,
+      syncLeaderUrl: syncLeaderURLInput,
+      syncToken: syncTokenInput,
+      syncIntervalSeconds: syncIntervalInput,
+      syncLeaderFingerprint: syncLeaderFingerprintInput,
+      mqttBrokerUrl: mqttBrokerURLInput,
+      mqttTopicPrefix: mqttTopicPrefixInput,
+      mqttDiscoveryPrefix: mqttDiscoveryPrefixInput,
+      mqttIntervalSeconds: mqttIntervalInput,
+      metricsExportUrl: metricsExportURLInput,
+      metricsExportFormat: metricsExportFormatSelect,
+      metricsExportIntervalSeconds: metricsExportIntervalInput,
+      logShipUrl: logShipURLInput,
+      logShipMinLevel: logShipMinLevelSelect,
+      ipfixCollector: ipfixCollectorInput,
+      ipfixObservationDomain: ipfixObservationDomainInput,
+      updateChannel: updateChannelSelect,
+      autoUpdateSchedule: autoUpdateScheduleInput,
+    };
+  }
+
+  function clearSettingsFieldErrors() {
+    settingsModalElement.querySelectorAll('.is-invalid').forEach((input) => {
+      input.classList.remove('is-invalid');
+    });
+    settingsModalElement.querySelectorAll('.js-settings-field-error').forEach((feedback) => {
+      feedback.remove();
+    });
+  }
+
+  // markSettingsFieldErrors flags each rejected input with the server's
+  // message and scrolls the first one into view. Fields without an input in
+  // this modal are only reported through the status line.
+  function markSettingsFieldErrors(fieldErrors) {
+    if (!Array.isArray(fieldErrors) || fieldErrors.length === 0) {
+      return;
+    }
+    const inputs = settingsFieldInputs();
+    const messages = new Map();
+    fieldErrors.forEach((fieldError) => {
+      const input = inputs[fieldError?.field];
+      if (!input) {
+        return;
+      }
+      const existing = messages.get(input) || [];
+      existing.push(String(fieldError.message || 'Invalid value.'));
+      messages.set(input, existing);
+    });
+    let first = null;
+    messages.forEach((lines, input) => {
+      input.classList.add('is-invalid');
+      const feedback = document.createElement('div');
+      feedback.className = 'invalid-feedback d-block js-settings-field-error';
+      feedback.textContent = lines.join(' ');
+      input.insertAdjacentElement('afterend', feedback);
+      first = first || input;
+    });
+    if (first) {
+      first.scrollIntoView({ block: 'center' });
+      first.focus();
+    }
+  }
   const routingInspectorFactory = window.SplitVPNUI && typeof window.SplitVPNUI.createRoutingInspectorController === 'function'
     ? window.SplitVPNUI.createRoutingInspectorController
     : null;
@@ -270,6 +653,20 @@
       formatBytes,
     })
     : null;
+  const flowHistoryFactory = window.SplitVPNUI && typeof window.SplitVPNUI.createFlowHistoryController === 'function'
+    ? window.SplitVPNUI.createFlowHistoryController
+    : null;
+  flowHistoryController = flowHistoryFactory
+    ? flowHistoryFactory({
+      flowHistoryWindow: document.getElementById('flow-history-window'),
+      flowHistorySort: document.getElementById('flow-history-sort'),
+      flowHistoryRefresh: document.getElementById('flow-history-refresh'),
+      flowHistorySummary: document.getElementById('flow-history-summary'),
+      flowHistoryTableBody: document.getElementById('flow-history-table-body'),
+      fetchJSON,
+      formatBytes,
+    })
+    : null;
   const speedtestFactory = window.SplitVPNUI && typeof window.SplitVPNUI.createSpeedtestController === 'function'
     ? window.SplitVPNUI.createSpeedtestController
     : null;
@@ -312,6 +709,17 @@
       vpnMSSV6Wrap,
       vpnMSSV4Input,
       vpnMSSV6Input,
+      vpnBoundInterfaceInput,
+      vpnDependsOnSelect,
+      vpnUplinkSelect,
+      vpnOnDemandIdleInput,
+      vpnLatencyProbeSelect,
+      vpnLatencyProbeTargetInput,
+      vpnEndpointRefreshWrap,
+      vpnEndpointRefreshInput,
+      vpnIPv6PolicySelect,
+      vpnIPv6PrefixWrap,
+      vpnIPv6PrefixInput,
       saveVPNButton,
       saveVPNLabel,
       deleteVPNModal,
@@ -341,7 +749,20 @@
       const data = await fetchJSON('/api/settings');
       state.settings = data.settings || { listenInterface: '', wanInterface: '' };
       state.availableInterfaces = Array.isArray(data.interfaces) ? data.interfaces : [];
+      state.reputationAbuseIpdbKeyConfigured = data.reputationAbuseIpdbKeyConfigured === true;
+      state.unifiControllerApiKeyConfigured = data.unifiControllerApiKeyConfigured === true;
+      state.adguardPasswordConfigured = data.adguardPasswordConfigured === true;
+      state.syncTokenConfigured = data.syncTokenConfigured === true;
+      state.mqttPasswordConfigured = data.mqttPasswordConfigured === true;
+      state.metricsExportTokenConfigured = data.metricsExportTokenConfigured === true;
+      state.logShipTokenConfigured = data.logShipTokenConfigured === true;
       populateSettingsForm();
+      clearSettingsFieldErrors();
+      refreshSyncStatus();
+      refreshMQTTStatus();
+      refreshMetricsExportStatus();
+      refreshLogShipStatus();
+      refreshIPFIXStatus();
       if (updateController?.refreshStatus) {
         await updateController.refreshStatus();
       }
@@ -352,12 +773,8 @@
   }
 
   function populateSettingsForm() {
-    populateInterfaceSelect(
-      listenSelect,
-      state.availableInterfaces,
-      state.settings?.listenInterface || '',
-      'Use default bind address (127.0.0.1)'
-    );
+    listenInput.value = state.settings?.listenInterface || '';
+    populateInterfaceOptions(listenOptions, state.availableInterfaces);
     populateInterfaceSelect(
       wanSelect,
       state.availableInterfaces,
@@ -373,6 +790,324 @@
       debugLogLevelSelect.value = validLevels.has(level) ? level : 'info';
       debugLogLevelSelect.disabled = !(debugLogEnabledInput?.checked);
     }
+    if (publicStatusEnabledInput) {
+      publicStatusEnabledInput.checked = state.settings?.publicStatusEnabled === true;
+    }
+    if (kioskListenInput) {
+      kioskListenInput.value = state.settings?.kioskListen || '';
+    }
+    if (reputationEnabledInput) {
+      reputationEnabledInput.checked = state.settings?.reputationEnabled === true;
+    }
+    if (reputationSpamhausEnabledInput) {
+      reputationSpamhausEnabledInput.checked = state.settings?.reputationSpamhausEnabled === true;
+    }
+    if (reputationAbuseIPDBKeyInput) {
+      reputationAbuseIPDBKeyInput.value = '';
+      reputationAbuseIPDBKeyInput.placeholder = state.reputationAbuseIpdbKeyConfigured ? 'Key stored' : 'Not configured';
+    }
+    if (reputationAbuseIPDBMinScoreInput) {
+      const minScore = Number(state.settings?.reputationAbuseIpdbMinScore || 0);
+      reputationAbuseIPDBMinScoreInput.value = minScore > 0 ? String(minScore) : '';
+    }
+    if (driftModeSelect) {
+      const mode = String(state.settings?.driftMode || 'alert');
+      driftModeSelect.value = ['off', 'alert', 'repair'].includes(mode) ? mode : 'alert';
+    }
+    if (driftIntervalInput) {
+      const interval = Number(state.settings?.driftIntervalSeconds || 0);
+      driftIntervalInput.value = interval > 0 ? String(interval) : '';
+    }
+    if (provisionWatchEnabledInput) {
+      provisionWatchEnabledInput.checked = state.settings?.provisionWatchEnabled !== false;
+    }
+    [
+      [anomalyHighMbpsInput, 'anomalyHighMbps'],
+      [anomalyHighMinutesInput, 'anomalyHighMinutes'],
+      [anomalyStallMinutesInput, 'anomalyStallMinutes'],
+      [unblockIntervalInput, 'unblockCheckIntervalHours'],
+    ].forEach(([input, key]) => {
+      if (input) {
+        const value = Number(state.settings?.[key] || 0);
+        input.value = value > 0 ? String(value) : '';
+      }
+    });
+    if (dnsBackendSelect) {
+      const backend = String(state.settings?.dnsBackend || 'dnsmasq');
+      dnsBackendSelect.value = ['dnsmasq', 'adguard', 'pihole'].includes(backend) ? backend : 'dnsmasq';
+    }
+    if (dnsBackendConfigPathInput) {
+      dnsBackendConfigPathInput.value = String(state.settings?.dnsBackendConfigPath || '');
+    }
+    if (adguardURLInput) {
+      adguardURLInput.value = String(state.settings?.adguardUrl || '');
+    }
+    if (adguardUsernameInput) {
+      adguardUsernameInput.value = String(state.settings?.adguardUsername || '');
+    }
+    if (unifiControllerURLInput) {
+      unifiControllerURLInput.value = String(state.settings?.unifiControllerUrl || '');
+    }
+    if (unifiControllerSiteInput) {
+      unifiControllerSiteInput.value = String(state.settings?.unifiControllerSite || '');
+    }
+    if (statsPollInput) {
+      const poll = Number(state.settings?.statsPollSeconds || 0);
+      statsPollInput.value = poll > 0 ? String(poll) : '';
+    }
+    if (latencyIntervalInput) {
+      const interval = Number(state.settings?.latencyIntervalSeconds || 0);
+      latencyIntervalInput.value = interval > 0 ? String(interval) : '';
+    }
+    if (wanPriorityInput) {
+      wanPriorityInput.value = String(state.settings?.wanPriority || '').split(',').filter(Boolean).join(', ');
+    }
+    if (statsDisabledInput) {
+      statsDisabledInput.value = String(state.settings?.statsDisabledInterfaces || '').split(',').filter(Boolean).join(', ');
+    }
+    if (hostnameDiscoveryEnabledInput) {
+      hostnameDiscoveryEnabledInput.checked = state.settings?.hostnameDiscoveryEnabled !== false;
+    }
+    [
+      [statsRetentionInput, state.settings?.statsRetentionDays],
+      [runRetentionInput, state.settings?.runRetentionDays],
+      [eventRetentionInput, state.settings?.eventRetentionDays],
+      [trashRetentionInput, state.settings?.trashRetentionDays],
+    ].forEach(([input, value]) => {
+      if (input) {
+        const days = Number(value || 0);
+        input.value = days > 0 ? String(days) : '';
+      }
+    });
+    if (updateChannelSelect) {
+      updateChannelSelect.value = state.settings?.updateChannel === 'beta' ? 'beta' : 'stable';
+    }
+    if (updateBackupEnabledInput) {
+      updateBackupEnabledInput.checked = state.settings?.updateBackupEnabled !== false;
+    }
+    if (autoUpdateEnabledInput) {
+      autoUpdateEnabledInput.checked = state.settings?.autoUpdateEnabled === true;
+    }
+    if (autoUpdateScheduleInput) {
+      autoUpdateScheduleInput.value = String(state.settings?.autoUpdateSchedule || '');
+    }
+    if (unifiControllerAPIKeyInput) {
+      unifiControllerAPIKeyInput.value = '';
+      unifiControllerAPIKeyInput.placeholder = state.unifiControllerApiKeyConfigured ? 'Key stored' : 'Not configured';
+    }
+    if (adguardPasswordInput) {
+      adguardPasswordInput.value = '';
+      adguardPasswordInput.placeholder = state.adguardPasswordConfigured ? 'Password stored' : 'Not configured';
+    }
+    if (syncRoleSelect) {
+      const role = String(state.settings?.syncRole || '');
+      syncRoleSelect.value = ['leader', 'follower'].includes(role) ? role : '';
+    }
+    if (syncLeaderURLInput) {
+      syncLeaderURLInput.value = String(state.settings?.syncLeaderUrl || '');
+    }
+    if (syncLeaderFingerprintInput) {
+      syncLeaderFingerprintInput.value = String(state.settings?.syncLeaderFingerprint || '');
+    }
+    if (syncIntervalInput) {
+      const interval = Number(state.settings?.syncIntervalSeconds || 0);
+      syncIntervalInput.value = interval > 0 ? String(interval) : '';
+    }
+    if (syncTokenInput) {
+      syncTokenInput.value = '';
+      syncTokenInput.placeholder = state.syncTokenConfigured ? 'Token stored' : 'Not configured';
+    }
+    if (mqttBrokerURLInput) {
+      mqttBrokerURLInput.value = String(state.settings?.mqttBrokerUrl || '');
+    }
+    if (mqttUsernameInput) {
+      mqttUsernameInput.value = String(state.settings?.mqttUsername || '');
+    }
+    if (mqttPasswordInput) {
+      mqttPasswordInput.value = '';
+      mqttPasswordInput.placeholder = state.mqttPasswordConfigured ? 'Password stored' : 'Not configured';
+    }
+    if (mqttTopicPrefixInput) {
+      mqttTopicPrefixInput.value = String(state.settings?.mqttTopicPrefix || '');
+    }
+    if (mqttDiscoveryPrefixInput) {
+      mqttDiscoveryPrefixInput.value = String(state.settings?.mqttDiscoveryPrefix || '');
+    }
+    if (mqttIntervalInput) {
+      const interval = Number(state.settings?.mqttIntervalSeconds || 0);
+      mqttIntervalInput.value = interval > 0 ? String(interval) : '';
+    }
+    if (metricsExportURLInput) {
+      metricsExportURLInput.value = String(state.settings?.metricsExportUrl || '');
+    }
+    if (metricsExportFormatSelect) {
+      metricsExportFormatSelect.value = state.settings?.metricsExportFormat === 'prometheus' ? 'prometheus' : 'influx';
+    }
+    if (metricsExportUsernameInput) {
+      metricsExportUsernameInput.value = String(state.settings?.metricsExportUsername || '');
+    }
+    if (metricsExportTokenInput) {
+      metricsExportTokenInput.value = '';
+      metricsExportTokenInput.placeholder = state.metricsExportTokenConfigured ? 'Token stored' : 'Not configured';
+    }
+    if (metricsExportIntervalInput) {
+      const interval = Number(state.settings?.metricsExportIntervalSeconds || 0);
+      metricsExportIntervalInput.value = interval > 0 ? String(interval) : '';
+    }
+    if (logShipURLInput) {
+      logShipURLInput.value = String(state.settings?.logShipUrl || '');
+    }
+    if (logShipMinLevelSelect) {
+      logShipMinLevelSelect.value = String(state.settings?.logShipMinLevel || 'info');
+    }
+    if (logShipUsernameInput) {
+      logShipUsernameInput.value = String(state.settings?.logShipUsername || '');
+    }
+    if (logShipTokenInput) {
+      logShipTokenInput.value = '';
+      logShipTokenInput.placeholder = state.logShipTokenConfigured ? 'Token stored' : 'Not configured';
+    }
+    if (ipfixCollectorInput) {
+      ipfixCollectorInput.value = String(state.settings?.ipfixCollector || '');
+    }
+    if (ipfixObservationDomainInput) {
+      const domain = Number(state.settings?.ipfixObservationDomain || 0);
+      ipfixObservationDomainInput.value = domain > 0 ? String(domain) : '';
+    }
+  }
+
+  function describeSyncStatus(sync) {
+    if (!sync || !sync.role) {
+      return 'Sync is off.';
+    }
+    if (sync.role === 'leader') {
+      return 'Leader: followers can pull this node\'s configuration.';
+    }
+    if (sync.lastError) {
+      return `Last pull failed: ${sync.lastError}`;
+    }
+    if (sync.lastSuccess) {
+      const changed = sync.lastChange ? `, last change applied ${new Date(sync.lastChange).toLocaleString()}` : '';
+      return `Last pull ${new Date(sync.lastSuccess).toLocaleString()}${changed}.`;
+    }
+    return 'Waiting for the first pull.';
+  }
+
+  async function refreshSyncStatus() {
+    if (!syncStatusLabel) {
+      return;
+    }
+    try {
+      const data = await fetchJSON('/api/sync/status');
+      syncStatusLabel.textContent = describeSyncStatus(data.sync);
+      if (syncRunButton) {
+        syncRunButton.disabled = data.sync?.role !== 'follower';
+      }
+    } catch (err) {
+      syncStatusLabel.textContent = err.message;
+    }
+  }
+
+  function describeMQTTStatus(mqtt) {
+    if (!mqtt || !mqtt.enabled) {
+      return 'MQTT publishing is off.';
+    }
+    if (mqtt.connected) {
+      const published = mqtt.lastPublish ? `, last publish ${new Date(mqtt.lastPublish).toLocaleString()}` : '';
+      return `Connected to ${mqtt.broker}${published}.`;
+    }
+    if (mqtt.lastError) {
+      return `Not connected: ${mqtt.lastError}`;
+    }
+    return 'Connecting to the broker.';
+  }
+
+  async function refreshMQTTStatus() {
+    if (!mqttStatusLabel) {
+      return;
+    }
+    try {
+      const data = await fetchJSON('/api/mqtt/status');
+      mqttStatusLabel.textContent = describeMQTTStatus(data);
+    } catch (err) {
+      mqttStatusLabel.textContent = err.message;
+    }
+  }
+
+  function describeMetricsExportStatus(status) {
+    if (!status || !status.enabled) {
+      return 'Metrics export is off.';
+    }
+    if (status.lastError) {
+      return `Last push failed: ${status.lastError}`;
+    }
+    if (status.lastPush) {
+      return `Last push ${new Date(status.lastPush).toLocaleString()} (${status.points || 0} points).`;
+    }
+    return 'Waiting for the first push.';
+  }
+
+  async function refreshMetricsExportStatus() {
+    if (!metricsExportStatusLabel) {
+      return;
+    }
+    try {
+      const data = await fetchJSON('/api/metrics-export/status');
+      metricsExportStatusLabel.textContent = describeMetricsExportStatus(data);
+    } catch (err) {
+      metricsExportStatusLabel.textContent = err.message;
+    }
+  }
+
+  function describeLogShipStatus(status) {
+    if (!status || !status.enabled) {
+      return 'Log shipping is off.';
+    }
+    const dropped = status.dropped ? `, ${status.dropped} dropped` : '';
+    if (status.lastError) {
+      return `Last send failed: ${status.lastError} (${status.shipped || 0} shipped${dropped}).`;
+    }
+    if (status.lastShip) {
+      return `Last sent ${new Date(status.lastShip).toLocaleString()} (${status.shipped || 0} shipped${dropped}).`;
+    }
+    return 'Waiting for log entries.';
+  }
+
+  async function refreshLogShipStatus() {
+    if (!logShipStatusLabel) {
+      return;
+    }
+    try {
+      const data = await fetchJSON('/api/log-shipping/status');
+      logShipStatusLabel.textContent = describeLogShipStatus(data);
+    } catch (err) {
+      logShipStatusLabel.textContent = err.message;
+    }
+  }
+
+  function describeIPFIXStatus(status) {
+    if (!status || !status.enabled) {
+      return 'IPFIX export is off.';
+    }
+    if (status.lastError) {
+      return `Last export to ${status.collector} failed: ${status.lastError}`;
+    }
+    if (status.lastExport) {
+      return `Last export to ${status.collector} ${new Date(status.lastExport).toLocaleString()} (${status.records || 0} records sent).`;
+    }
+    return `Waiting for matched flows to export to ${status.collector}.`;
+  }
+
+  async function refreshIPFIXStatus() {
+    if (!ipfixStatusLabel) {
+      return;
+    }
+    try {
+      const data = await fetchJSON('/api/ipfix/status');
+      ipfixStatusLabel.textContent = describeIPFIXStatus(data);
+    } catch (err) {
+      ipfixStatusLabel.textContent = err.message;
+    }
   }
 
   if (debugLogEnabledInput && debugLogLevelSelect) {
@@ -381,6 +1116,46 @@
     });
   }
 
+  function describeSettingsReload(reload) {
+    if (!reload) {
+      return 'Settings saved.';
+    }
+    if (reload.restarting) {
+      return reload.error
+        ? `Settings saved; ${reload.error}. Restarting the service to apply them.`
+        : 'Settings saved. Restarting the service to apply them.';
+    }
+    if (reload.restartRequired) {
+      return 'Settings saved. Restart the service to apply the listen change.';
+    }
+    const describe = (list) => list.map((l) => `${l.tls ? 'https' : 'http'}://${l.addr}`).join(', ');
+    const listeners = Array.isArray(reload.listeners) ? reload.listeners : [];
+    let message = listeners.length ? `Settings saved. Now listening on ${describe(listeners)}.` : 'Settings saved.';
+    if (Array.isArray(reload.applied) && reload.applied.includes('kiosk')) {
+      const kiosk = Array.isArray(reload.kioskListeners) ? reload.kioskListeners : [];
+      message += kiosk.length ? ` Kiosk on ${describe(kiosk)}.` : ' Kiosk turned off.';
+    }
+    return message;
+  }
+
+  function populateInterfaceOptions(datalist, interfaces) {
+    if (!datalist) {
+      return;
+    }
+    datalist.innerHTML = '';
+    const seen = new Set();
+    interfaces.forEach((iface) => {
+      if (!iface || !iface.name || seen.has(iface.name)) {
+        return;
+      }
+      seen.add(iface.name);
+      const option = document.createElement('option');
+      option.value = iface.name;
+      option.label = formatInterfaceLabel(iface);
+      datalist.appendChild(option);
+    });
+  }
+
   function populateInterfaceSelect(select, interfaces, selected, emptyLabel) {
     select.innerHTML = '';
     const autoOption = document.createElement('option');
@@ -476,6 +1251,34 @@
     chartHelpers.updateGaugeChart(state.totalGauge, labels, totalData);
   }
 
+  function showDriftStatus(report) {
+    if (!report) {
+      return;
+    }
+    if (!report.drifted) {
+      setStatus('Routing state matches saved groups again.', false);
+      return;
+    }
+    const parts = [];
+    if (report.missingSets?.length) {
+      parts.push(`${report.missingSets.length} missing ipset(s)`);
+    }
+    if (report.missingRules?.length || report.unexpectedRules?.length) {
+      parts.push(`${(report.missingRules?.length || 0) + (report.unexpectedRules?.length || 0)} rule difference(s)`);
+    }
+    if (report.dnsmasqChanged) {
+      parts.push('dnsmasq config changed');
+    }
+    const detail = parts.join(', ') || 'live state differs';
+    if (report.repairError) {
+      setStatus(`Routing drift (${detail}); re-apply failed: ${report.repairError}`, true);
+    } else if (report.repaired) {
+      setStatus(`Routing drift repaired (${detail}).`, false);
+    } else {
+      setStatus(`Routing drift detected: ${detail}.`, true);
+    }
+  }
+
   function setStatus(message, isError) {
     if (!message) {
       return;
@@ -507,7 +1310,9 @@
     }
     if (!response.ok) {
       if (parsed && typeof parsed.error === 'string' && parsed.error) {
-        throw new Error(parsed.error);
+        const error = new Error(parsed.error);
+        error.payload = parsed;
+        throw error;
       }
       let text = '';
       try {
@@ -0,0 +1,179 @@
+(() => {
+  window.SplitVPNDomainRoutingCanary = {
+    createController(ctx) {
+      const {
+        bannerElement,
+        controlsElement,
+        deviceInput,
+        deviceDatalist,
+        startButton,
+        state,
+        fetchJSON,
+        showStatus,
+        escapeHTML,
+        buildGroupPayload,
+        onStarted,
+        onFinished,
+      } = ctx || {};
+
+      if (
+        !bannerElement ||
+        !controlsElement ||
+        !deviceInput ||
+        !startButton ||
+        !state ||
+        typeof fetchJSON !== 'function' ||
+        typeof showStatus !== 'function' ||
+        typeof buildGroupPayload !== 'function'
+      ) {
+        return null;
+      }
+
+      let current = null;
+
+      startButton.addEventListener('click', async () => {
+        startButton.disabled = true;
+        try {
+          await start();
+        } catch (err) {
+          showStatus(err.message, true);
+        } finally {
+          startButton.disabled = false;
+        }
+      });
+
+      bannerElement.addEventListener('click', async (event) => {
+        const target = event.target.closest('[data-canary-action]');
+        if (!target) {
+          return;
+        }
+        const action = target.getAttribute('data-canary-action');
+        target.disabled = true;
+        try {
+          if (action === 'probe') {
+            await refresh();
+          } else if (action === 'promote') {
+            await fetchJSON('/api/groups/canary/promote', { method: 'POST' });
+            showStatus('Canary promoted to everyone.', false);
+            await finish();
+          } else if (action === 'rollback') {
+            await fetchJSON('/api/groups/canary/rollback', { method: 'POST' });
+            showStatus('Canary rolled back; live policy restored.', false);
+            await finish();
+          }
+        } catch (err) {
+          showStatus(err.message, true);
+        } finally {
+          target.disabled = false;
+        }
+      });
+
+      function setEditing(groupID) {
+        controlsElement.classList.toggle('d-none', !groupID || Boolean(current));
+        deviceInput.value = '';
+        renderDeviceOptions();
+      }
+
+      async function start() {
+        const groupID = state.editingGroupID;
+        if (!groupID) {
+          throw new Error('Save the group before starting a canary.');
+        }
+        const device = (deviceInput.value || '').trim();
+        if (!device) {
+          throw new Error('Enter the IP address of the canary device.');
+        }
+        const group = buildGroupPayload();
+        await fetchJSON(`/api/groups/${groupID}/canary`, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ device, group }),
+        });
+        showStatus(`Canary started for ${device}. Verify, then promote or roll back.`, false);
+        if (typeof onStarted === 'function') {
+          onStarted();
+        }
+        await refresh();
+      }
+
+      async function finish() {
+        await refresh();
+        if (typeof onFinished === 'function') {
+          await onFinished();
+        }
+      }
+
+      async function refresh() {
+        const data = await fetchJSON('/api/groups/canary');
+        current = data && data.canary ? data.canary : null;
+        render(current, data ? data.probe : null);
+      }
+
+      function render(canary, probe) {
+        if (!canary) {
+          bannerElement.classList.add('d-none');
+          bannerElement.innerHTML = '';
+          return;
+        }
+        const started = canary.startedAt ? new Date(canary.startedAt * 1000).toLocaleString() : 'n/a';
+        bannerElement.classList.remove('d-none');
+        bannerElement.innerHTML = `
+          <div class="d-flex flex-wrap justify-content-between align-items-start gap-2">
+            <div class="min-w-0">
+              <div class="fw-semibold">
+                <i class="bi bi-cone-striped me-1"></i>Canary active for
+                ${escapeHTML(canary.groupName || '')} on ${escapeHTML(canary.device || '')}
+              </div>
+              <div>Proposed egress: ${escapeHTML(canary.proposed?.egressVpn || 'n/a')} &middot; started ${escapeHTML(started)}</div>
+              <div>${renderProbe(probe)}</div>
+            </div>
+            <div class="btn-group btn-group-sm" role="group">
+              <button class="btn btn-outline-secondary" data-canary-action="probe" title="Re-check device flows">
+                <i class="bi bi-arrow-repeat"></i>
+              </button>
+              <button class="btn btn-outline-success" data-canary-action="promote">
+                <i class="bi bi-check2-circle me-1"></i>Promote
+              </button>
+              <button class="btn btn-outline-danger" data-canary-action="rollback">
+                <i class="bi bi-arrow-counterclockwise me-1"></i>Roll Back
+              </button>
+            </div>
+          </div>
+        `;
+      }
+
+      function renderProbe(probe) {
+        if (!probe) {
+          return 'No egress check yet.';
+        }
+        if (probe.error) {
+          return `Egress check failed: ${escapeHTML(probe.error)}`;
+        }
+        const flows = Number(probe.flows || 0);
+        if (flows === 0) {
+          return 'No active flows from the device yet; generate some traffic and re-check.';
+        }
+        const destinations = Array.isArray(probe.destinations) && probe.destinations.length
+          ? ` (${escapeHTML(probe.destinations.slice(0, 5).join(', '))}${probe.destinations.length > 5 ? ', …' : ''})`
+          : '';
+        return `Flows: ${flows} &middot; via ${escapeHTML(probe.egressVpn || 'egress')}: ${Number(probe.viaEgress || 0)}${destinations}`
+          + ` &middot; other VPN: ${Number(probe.otherMark || 0)} &middot; direct: ${Number(probe.unmarked || 0)}`;
+      }
+
+      function renderDeviceOptions() {
+        if (!deviceDatalist) {
+          return;
+        }
+        const devices = Array.isArray(state.devices) ? state.devices : [];
+        deviceDatalist.innerHTML = devices
+          .flatMap((device) => (device.ipHints || []).map((ip) => {
+            const label = device.name ? `${device.name} (${device.mac})` : device.mac;
+            return `<option value="${escapeHTML(ip)}">${escapeHTML(label)}</option>`;
+          }))
+          .join('');
+      }
+
+      return { refresh, setEditing };
+    },
+  };
+})();
@@ -0,0 +1,266 @@
+(() => {
+  const openButton = document.getElementById('open-device-groups');
+  const modalElement = document.getElementById('deviceGroupsModal');
+  const list = document.getElementById('device-groups-list');
+  const statusBox = document.getElementById('device-groups-status');
+  const nameInput = document.getElementById('device-group-name');
+  const macsInput = document.getElementById('device-group-macs');
+  const cidrsInput = document.getElementById('device-group-cidrs');
+  const syncNamesInput = document.getElementById('device-group-sync-names');
+  const syncedLabel = document.getElementById('device-group-synced');
+  const newButton = document.getElementById('new-device-group');
+  const saveButton = document.getElementById('save-device-group');
+  const deleteButton = document.getElementById('delete-device-group');
+  const syncButton = document.getElementById('sync-device-groups');
+  const aliasMACInput = document.getElementById('device-alias-mac');
+  const aliasNameInput = document.getElementById('device-alias-name');
+  const aliasSaveButton = document.getElementById('save-device-alias');
+  const aliasList = document.getElementById('device-aliases-list');
+
+  if (
+    !openButton ||
+    !modalElement ||
+    !list ||
+    !statusBox ||
+    !nameInput ||
+    !macsInput ||
+    !cidrsInput ||
+    !syncNamesInput ||
+    !syncedLabel ||
+    !newButton ||
+    !saveButton ||
+    !deleteButton ||
+    !syncButton ||
+    !aliasMACInput ||
+    !aliasNameInput ||
+    !aliasSaveButton ||
+    !aliasList
+  ) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  let groups = [];
+  let selectedID = 0;
+
+  openButton.addEventListener('click', async () => {
+    hideStatus();
+    await Promise.all([reload(), reloadAliases()]);
+    modal.show();
+  });
+
+  newButton.addEventListener('click', () => {
+    selectedID = 0;
+    fillEditor(null);
+    renderList();
+  });
+
+  saveButton.addEventListener('click', async () => {
+    const payload = {
+      name: nameInput.value.trim(),
+      macs: splitLines(macsInput.value),
+      cidrs: splitLines(cidrsInput.value),
+      syncNames: splitLines(syncNamesInput.value),
+    };
+    saveButton.disabled = true;
+    try {
+      const url = selectedID > 0 ? `/api/device-groups/${selectedID}` : '/api/device-groups';
+      const method = selectedID > 0 ? 'PUT' : 'POST';
+      const result = await fetchJSON(url, {
+        method,
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+      });
+      selectedID = result.deviceGroup ? result.deviceGroup.id : selectedID;
+      await reload();
+      showStatus('Device group saved.', false);
+    } catch (err) {
+      showStatus(err.message, true);
+    } finally {
+      saveButton.disabled = false;
+    }
+  });
+
+  deleteButton.addEventListener('click', async () => {
+    if (selectedID <= 0) {
+      return;
+    }
+    deleteButton.disabled = true;
+    try {
+      await fetchJSON(`/api/device-groups/${selectedID}`, { method: 'DELETE' });
+      selectedID = 0;
+      await reload();
+      showStatus('Device group deleted.', false);
+    } catch (err) {
+      showStatus(err.message, true);
+    } finally {
+      deleteButton.disabled = false;
+    }
+  });
+
+  syncButton.addEventListener('click', async () => {
+    syncButton.disabled = true;
+    try {
+      const result = await fetchJSON('/api/device-groups/sync', { method: 'POST' });
+      const changed = Array.isArray(result.changed) ? result.changed : [];
+      await reload();
+      showStatus(changed.length > 0 ? `Updated ${changed.join(', ')}.` : 'No device group changes.', false);
+    } catch (err) {
+      showStatus(err.message, true);
+    } finally {
+      syncButton.disabled = false;
+    }
+  });
+
+  aliasSaveButton.addEventListener('click', async () => {
+    const mac = aliasMACInput.value.trim();
+    if (!mac) {
+      showStatus('Enter a MAC address for the alias.', true);
+      return;
+    }
+    aliasSaveButton.disabled = true;
+    try {
+      await fetchJSON(`/api/device-aliases/${encodeURIComponent(mac)}`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name: aliasNameInput.value.trim() }),
+      });
+      aliasMACInput.value = '';
+      aliasNameInput.value = '';
+      await reloadAliases();
+      showStatus('Device alias saved.', false);
+    } catch (err) {
+      showStatus(err.message, true);
+    } finally {
+      aliasSaveButton.disabled = false;
+    }
+  });
+
+  async function reloadAliases() {
+    let aliases = [];
+    try {
+      const result = await fetchJSON('/api/device-aliases');
+      aliases = Array.isArray(result.aliases) ? result.aliases : [];
+    } catch (err) {
+      showStatus(err.message, true);
+    }
+    aliasList.innerHTML = '';
+    aliases.forEach((alias) => {
+      const item = document.createElement('div');
+      item.className = 'list-group-item d-flex align-items-center gap-2';
+      const label = document.createElement('span');
+      label.className = 'flex-grow-1';
+      label.textContent = `${alias.name} (${alias.mac})`;
+      const edit = document.createElement('button');
+      edit.type = 'button';
+      edit.className = 'btn btn-link btn-sm p-0';
+      edit.textContent = 'Edit';
+      edit.addEventListener('click', () => {
+        aliasMACInput.value = alias.mac;
+        aliasNameInput.value = alias.name;
+      });
+      const remove = document.createElement('button');
+      remove.type = 'button';
+      remove.className = 'btn btn-link btn-sm p-0 text-danger';
+      remove.textContent = 'Remove';
+      remove.addEventListener('click', async () => {
+        try {
+          await fetchJSON(`/api/device-aliases/${encodeURIComponent(alias.mac)}`, { method: 'DELETE' });
+          await reloadAliases();
+        } catch (err) {
+          showStatus(err.message, true);
+        }
+      });
+      item.append(label, edit, remove);
+      aliasList.appendChild(item);
+    });
+  }
+
+  async function reload() {
+    try {
+      const result = await fetchJSON('/api/device-groups');
+      groups = Array.isArray(result.deviceGroups) ? result.deviceGroups : [];
+    } catch (err) {
+      groups = [];
+      showStatus(err.message, true);
+    }
+    if (!groups.some((group) => group.id === selectedID)) {
+      selectedID = 0;
+    }
+    fillEditor(groups.find((group) => group.id === selectedID) || null);
+    renderList();
+  }
+
+  function renderList() {
+    list.innerHTML = '';
+    if (groups.length === 0) {
+      const empty = document.createElement('div');
+      empty.className = 'text-body-secondary small';
+      empty.textContent = 'No device groups yet.';
+      list.appendChild(empty);
+      return;
+    }
+    groups.forEach((group) => {
+      const item = document.createElement('button');
+      item.type = 'button';
+      item.className = 'list-group-item list-group-item-action';
+      if (group.id === selectedID) {
+        item.classList.add('active');
+      }
+      item.textContent = group.name;
+      item.addEventListener('click', () => {
+        selectedID = group.id;
+        fillEditor(group);
+        renderList();
+      });
+      list.appendChild(item);
+    });
+  }
+
+  function fillEditor(group) {
+    nameInput.value = group ? group.name : '';
+    macsInput.value = group ? (group.macs || []).join('\n') : '';
+    cidrsInput.value = group ? (group.cidrs || []).join('\n') : '';
+    syncNamesInput.value = group ? (group.syncNames || []).join('\n') : '';
+    const synced = group ? [...(group.syncedMacs || []), ...(group.syncedCidrs || [])] : [];
+    syncedLabel.textContent = synced.length > 0 ? `Synced: ${synced.join(', ')}` : '';
+    deleteButton.disabled = !group;
+  }
+
+  function splitLines(value) {
+    return String(value || '')
+      .split(/\r?\n/)
+      .map((line) => line.trim())
+      .filter((line) => line !== '');
+  }
+
+  async function fetchJSON(url, options = {}) {
+    const response = await fetch(url, options);
+    const contentType = response.headers.get('content-type') || '';
+    let parsed = null;
+    if (contentType.includes('application/json')) {
+      try {
+        parsed = await response.json();
+      } catch (err) {
+        parsed = null;
+      }
+    }
+    if (!response.ok) {
+      if (parsed && typeof parsed.error === 'string' && parsed.error) {
+        throw new Error(parsed.error);
+      }
+      throw new Error(response.statusText || 'Request failed');
+    }
+    return parsed || {};
+  }
+
+  function showStatus(message, isError) {
+    statusBox.classList.remove('d-none', 'alert-success', 'alert-danger');
+    statusBox.classList.add(isError ? 'alert-danger' : 'alert-success');
+    statusBox.textContent = message || '';
+  }
+
+  function hideStatus() {
+    statusBox.classList.add('d-none');
+  }
+})();
@@ -0,0 +1,125 @@
+(() => {
+  const openButton = document.getElementById('open-dns-bypass');
+  const modalElement = document.getElementById('dnsBypassModal');
+  const statusBox = document.getElementById('dns-bypass-status');
+  const result = document.getElementById('dns-bypass-result');
+  const refreshButton = document.getElementById('dns-bypass-refresh');
+  const rulesForm = document.getElementById('dns-bypass-rules-form');
+  const interfaceInput = document.getElementById('dns-bypass-interface');
+  const onlyListedInput = document.getElementById('dns-bypass-only-listed');
+  const generateButton = document.getElementById('dns-bypass-generate');
+  const rulesOutput = document.getElementById('dns-bypass-rules');
+
+  if (!openButton || !modalElement || !statusBox || !result || !refreshButton || !rulesForm || !interfaceInput || !onlyListedInput || !generateButton || !rulesOutput) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+  const kindLabels = { dns: 'DNS', dot: 'DoT', doh: 'DoH' };
+  let clients = [];
+
+  openButton.addEventListener('click', () => {
+    rulesOutput.classList.add('d-none');
+    rulesOutput.textContent = '';
+    modal.show();
+    loadReport();
+  });
+
+  refreshButton.addEventListener('click', () => {
+    loadReport();
+  });
+
+  rulesForm.addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const params = new URLSearchParams();
+    params.set('interface', interfaceInput.value.trim());
+    if (onlyListedInput.checked) {
+      if (clients.length === 0) {
+        showStatus('No bypassing clients to generate rules for. Untick the option to cover the whole LAN.', 'alert-secondary');
+        return;
+      }
+      clients.forEach((client) => params.append('client', client.ip));
+    }
+    generateButton.disabled = true;
+    try {
+      const body = await requestJSON(`/api/routing/dns-bypass/rules?${params.toString()}`);
+      const rules = Array.isArray(body.rules) ? body.rules : [];
+      rulesOutput.textContent = rules.join('\n');
+      rulesOutput.classList.remove('d-none');
+    } catch (err) {
+      showStatus(err.message, 'alert-danger');
+    } finally {
+      generateButton.disabled = false;
+    }
+  });
+
+  async function loadReport() {
+    refreshButton.disabled = true;
+    result.innerHTML = '';
+    showStatus('Reading connections…', 'alert-secondary');
+    try {
+      const body = await requestJSON('/api/routing/dns-bypass');
+      clients = Array.isArray(body.clients) ? body.clients : [];
+      if (clients.length === 0) {
+        showStatus('No client is bypassing the router\'s resolver right now.', 'alert-success');
+        return;
+      }
+      showStatus(`${clients.length} client${clients.length === 1 ? '' : 's'} resolving outside the router.`, 'alert-warning');
+      result.innerHTML = renderClients(clients);
+    } catch (err) {
+      showStatus(err.message, 'alert-danger');
+    } finally {
+      refreshButton.disabled = false;
+    }
+  }
+
+  async function requestJSON(url) {
+    const response = await fetch(url);
+    const body = await response.json().catch(() => ({}));
+    if (!response.ok) {
+      throw new Error(body.error || response.statusText || 'Request failed');
+    }
+    return body;
+  }
+
+  function renderClients(list) {
+    const rows = list.map((client) => {
+      const resolvers = (Array.isArray(client.resolvers) ? client.resolvers : []).map((resolver) => `
+        <div class="font-monospace">${escapeHTML(resolver.address)}:${resolver.port}
+          <span class="badge text-bg-secondary ms-1">${escapeHTML(kindLabels[resolver.kind] || resolver.kind)}</span>
+          ${resolver.provider ? `<span class="text-body-secondary ms-1">${escapeHTML(resolver.provider)}</span>` : ''}
+          <span class="text-body-secondary ms-1">×${resolver.flows}</span>
+        </div>`).join('');
+      return `
+        <tr>
+          <td>
+            <div class="font-monospace">${escapeHTML(client.ip)}</div>
+            ${client.name ? `<div class="text-body-secondary">${escapeHTML(client.name)}</div>` : ''}
+            ${client.mac ? `<div class="text-body-secondary font-monospace">${escapeHTML(client.mac)}</div>` : ''}
+          </td>
+          <td>${(client.kinds || []).map((kind) => `<span class="badge text-bg-warning me-1">${escapeHTML(kindLabels[kind] || kind)}</span>`).join('')}</td>
+          <td>${resolvers}</td>
+        </tr>`;
+    }).join('');
+    return `
+      <div class="table-responsive">
+        <table class="table table-sm align-middle small mb-0">
+          <thead><tr><th>Client</th><th>Via</th><th>Resolvers (flows)</th></tr></thead>
+          <tbody>${rows}</tbody>
+        </table>
+      </div>`;
+  }
+
+  function escapeHTML(value) {
+    return String(value ?? '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;');
+  }
+
+  function showStatus(message, variant) {
+    statusBox.className = `alert py-2 small mb-3 ${variant}`;
+    statusBox.textContent = message;
+  }
+})();
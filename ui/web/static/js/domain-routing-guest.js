@@ -0,0 +1,137 @@
+(() => {
+  window.SplitVPNDomainRoutingGuest = {
+    createController(ctx) {
+      const { fetchJSON, getVPNs, onChanged, showStatus } = ctx || {};
+      const openButton = document.getElementById('open-guest-safe-mode');
+      const modalElement = document.getElementById('guestSafeModeModal');
+      const statusBox = document.getElementById('guest-safe-mode-status');
+      const enabledInput = document.getElementById('guest-safe-mode-enabled');
+      const networkInput = document.getElementById('guest-safe-mode-network');
+      const networkDatalist = document.getElementById('guest-safe-mode-networks');
+      const egressSelect = document.getElementById('guest-safe-mode-egress');
+      const saveButton = document.getElementById('guest-safe-mode-save');
+
+      if (
+        !openButton ||
+        !modalElement ||
+        !statusBox ||
+        !enabledInput ||
+        !networkInput ||
+        !networkDatalist ||
+        !egressSelect ||
+        !saveButton ||
+        typeof fetchJSON !== 'function'
+      ) {
+        return null;
+      }
+
+      const modal = new bootstrap.Modal(modalElement);
+
+      openButton.addEventListener('click', () => open());
+      enabledInput.addEventListener('change', () => renderEnabled());
+      saveButton.addEventListener('click', () => save());
+
+      async function open() {
+        clearStatus();
+        renderEgressOptions('');
+        enabledInput.checked = false;
+        networkInput.value = '';
+        renderEnabled();
+        modal.show();
+        loadNetworks();
+        try {
+          const data = await fetchJSON('/api/routing/guest-safe-mode');
+          const policy = data.guestSafeMode || {};
+          enabledInput.checked = policy.enabled === true;
+          networkInput.value = policy.network || '';
+          renderEgressOptions(policy.egressVpn || '');
+          renderEnabled();
+        } catch (err) {
+          setStatus(err.message, 'alert-danger');
+        }
+      }
+
+      // loadNetworks suggests the known UniFi networks. Failures stay quiet:
+      // a VLAN ID can always be typed.
+      async function loadNetworks() {
+        try {
+          const data = await fetchJSON('/api/routing/networks');
+          const networks = Array.isArray(data.networks) ? data.networks : [];
+          networkDatalist.innerHTML = networks
+            .map((network) => {
+              const details = [network.name, network.vlan ? `VLAN ${network.vlan}` : '', network.interface]
+                .filter(Boolean)
+                .join(' · ');
+              return `<option value="${escapeHTML(network.selector)}">${escapeHTML(details)}</option>`;
+            })
+            .join('');
+        } catch (err) {
+          networkDatalist.innerHTML = '';
+        }
+      }
+
+      function renderEgressOptions(selected) {
+        const vpns = typeof getVPNs === 'function' ? getVPNs() : [];
+        egressSelect.innerHTML = vpns
+          .map((vpn) => `<option value="${escapeHTML(vpn.name)}">${escapeHTML(vpn.name)}</option>`)
+          .join('');
+        if (selected && vpns.some((vpn) => vpn.name === selected)) {
+          egressSelect.value = selected;
+        }
+      }
+
+      function renderEnabled() {
+        networkInput.disabled = !enabledInput.checked;
+        egressSelect.disabled = !enabledInput.checked;
+      }
+
+      async function save() {
+        const enabled = enabledInput.checked;
+        saveButton.disabled = true;
+        try {
+          await fetchJSON('/api/routing/guest-safe-mode', {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+              enabled,
+              network: networkInput.value.trim(),
+              egressVpn: egressSelect.value,
+            }),
+          });
+          modal.hide();
+          if (typeof showStatus === 'function') {
+            showStatus(enabled ? 'Guest safe mode on.' : 'Guest safe mode off.', false);
+          }
+          if (typeof onChanged === 'function') {
+            await onChanged();
+          }
+        } catch (err) {
+          setStatus(err.message, 'alert-danger');
+        } finally {
+          saveButton.disabled = false;
+        }
+      }
+
+      function setStatus(message, variant) {
+        statusBox.className = `alert py-2 small mb-3 ${variant}`;
+        statusBox.textContent = message;
+      }
+
+      function clearStatus() {
+        statusBox.className = 'alert d-none py-2 small mb-3';
+        statusBox.textContent = '';
+      }
+
+      return { open };
+    },
+  };
+
+  function escapeHTML(value) {
+    return String(value ?? '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;')
+      .replaceAll("'", '&#39;');
+  }
+})();
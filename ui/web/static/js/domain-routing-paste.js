@@ -0,0 +1,144 @@
+(() => {
+  window.SplitVPNDomainRoutingPaste = {
+    createController(ctx) {
+      const { fetchJSON, onApplied } = ctx || {};
+      const modalElement = document.getElementById('selectorPasteModal');
+      const groupLabel = document.getElementById('selector-paste-group');
+      const statusBox = document.getElementById('selector-paste-status');
+      const ruleSelect = document.getElementById('selector-paste-rule');
+      const textInput = document.getElementById('selector-paste-text');
+      const reportBox = document.getElementById('selector-paste-report');
+      const checkButton = document.getElementById('selector-paste-check');
+      const appendButton = document.getElementById('selector-paste-append');
+
+      if (
+        !modalElement ||
+        !groupLabel ||
+        !statusBox ||
+        !ruleSelect ||
+        !textInput ||
+        !reportBox ||
+        !checkButton ||
+        !appendButton ||
+        typeof fetchJSON !== 'function'
+      ) {
+        return null;
+      }
+
+      const modal = new bootstrap.Modal(modalElement);
+      let groupID = null;
+
+      checkButton.addEventListener('click', () => submit(true));
+      appendButton.addEventListener('click', () => submit(false));
+
+      function open(group) {
+        groupID = Number(group?.id || 0) || null;
+        groupLabel.textContent = group?.name || '';
+        const rules = Array.isArray(group?.rules) ? group.rules : [];
+        ruleSelect.innerHTML = rules
+          .map((rule, index) => `<option value="${index}">${escapeHTML(rule.name || `Rule ${index + 1}`)}</option>`)
+          .join('');
+        textInput.value = '';
+        reportBox.innerHTML = '';
+        clearStatus();
+        modal.show();
+      }
+
+      async function submit(dryRun) {
+        if (!groupID) {
+          return;
+        }
+        const text = textInput.value;
+        if (!text.trim()) {
+          showStatus('Paste at least one entry.', 'alert-secondary');
+          return;
+        }
+        checkButton.disabled = true;
+        appendButton.disabled = true;
+        try {
+          const data = await fetchJSON(`/api/groups/${groupID}/paste`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ ruleIndex: Number(ruleSelect.value || 0), text, dryRun }),
+          });
+          const report = data.report || {};
+          reportBox.innerHTML = renderReport(report);
+          const accepted = countAccepted(report);
+          if (report.applied) {
+            showStatus(`Appended ${accepted} selector${accepted === 1 ? '' : 's'} and applied routing.`, 'alert-success');
+            if (typeof onApplied === 'function') {
+              await onApplied();
+            }
+          } else if (dryRun) {
+            showStatus(`${accepted} new selector${accepted === 1 ? '' : 's'} would be appended.`, 'alert-secondary');
+          } else {
+            showStatus('Nothing new to append.', 'alert-secondary');
+          }
+        } catch (err) {
+          showStatus(err.message, 'alert-danger');
+        } finally {
+          checkButton.disabled = false;
+          appendButton.disabled = false;
+        }
+      }
+
+      function renderReport(report) {
+        const sections = [
+          ['Domains', report.domains],
+          ['Wildcards', report.wildcardDomains],
+          ['CIDRs', report.destinationCidrs],
+          ['ASNs', report.destinationAsns],
+          ['Already present', report.duplicates],
+        ]
+          .filter(([, values]) => Array.isArray(values) && values.length > 0)
+          .map(([label, values]) => `
+            <div class="mb-2">
+              <div class="fw-semibold">${label} (${values.length})</div>
+              <div class="font-monospace text-break">${values.map(escapeHTML).join(', ')}</div>
+            </div>`);
+        const invalid = Array.isArray(report.invalid) ? report.invalid : [];
+        if (invalid.length > 0) {
+          const rows = invalid.map((entry) => `
+            <tr>
+              <td class="text-end">${Number(entry.line || 0)}</td>
+              <td class="font-monospace">${escapeHTML(entry.value)}</td>
+              <td>${escapeHTML(entry.reason)}</td>
+            </tr>`).join('');
+          sections.push(`
+            <div class="fw-semibold text-danger">Rejected (${invalid.length})</div>
+            <table class="table table-sm align-middle small mb-0">
+              <thead><tr><th class="text-end">Line</th><th>Entry</th><th>Reason</th></tr></thead>
+              <tbody>${rows}</tbody>
+            </table>`);
+        }
+        return sections.join('');
+      }
+
+      function countAccepted(report) {
+        return ['domains', 'wildcardDomains', 'destinationCidrs', 'destinationAsns']
+          .reduce((total, key) => total + (Array.isArray(report[key]) ? report[key].length : 0), 0);
+      }
+
+      function showStatus(message, variant) {
+        statusBox.className = `alert py-2 small mb-3 ${variant}`;
+        statusBox.textContent = message;
+      }
+
+      function clearStatus() {
+        statusBox.className = 'alert d-none py-2 small mb-3';
+        statusBox.textContent = '';
+      }
+
+      return { open };
+    },
+  };
+
+  function escapeHTML(value) {
+    return String(value || '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;')
+      .replaceAll("'", '&#39;');
+  }
+})();
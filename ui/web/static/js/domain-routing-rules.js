@@ -20,6 +20,7 @@
       const parseSelectorField = helper.parseSelectorField;
       const parseLines = helper.parseLines;
       const parsePorts = helper.parsePorts;
+      const parseTags = helper.parseTags;
       const ruleHasEditableContent = helper.ruleHasEditableContent;
       const formatPorts = helper.formatPorts;
       const escapeHTML = helper.escapeHTML;
@@ -32,6 +33,7 @@
           const sourceCidrs = parseSelectorField(rawValueFrom(card, '.js-rule-source'));
           const excludedSourceCidrs = parseSelectorField(rawValueFrom(card, '.js-rule-source-excluded'));
           const sourceMacs = parseSelectorField(rawValueFrom(card, '.js-rule-source-mac'));
+          const sourceDeviceGroups = parseSelectorField(rawValueFrom(card, '.js-rule-source-device-groups'));
           const destinationCidrs = parseSelectorField(rawValueFrom(card, '.js-rule-destination'));
           const excludedDestinationCidrs = parseSelectorField(rawValueFrom(card, '.js-rule-destination-excluded'));
           const destinationPortsRaw = parseSelectorField(rawValueFrom(card, '.js-rule-ports'));
@@ -40,13 +42,21 @@
           const excludedDestinationAsns = parseSelectorField(rawValueFrom(card, '.js-rule-asn-excluded'));
           const domains = parseSelectorField(rawValueFrom(card, '.js-rule-domains'));
           const wildcardDomains = parseSelectorField(rawValueFrom(card, '.js-rule-wildcards'));
+          const staticHosts = parseSelectorField(rawValueFrom(card, '.js-rule-static-hosts'));
           const excludeMulticast = !!card.querySelector('.js-rule-exclude-multicast')?.checked;
           const rule = {
+            uploadLimitKbit: mbitToKbit(valueFrom(card, '.js-rule-limit-up')),
+            downloadLimitKbit: mbitToKbit(valueFrom(card, '.js-rule-limit-down')),
+            monitorOnly: !!card.querySelector('.js-rule-monitor-only')?.checked,
             name: valueFrom(card, '.js-rule-name'),
+            notes: valueFrom(card, '.js-rule-notes'),
+            tags: parseTags(valueFrom(card, '.js-rule-tags')),
+            expiresAt: parseExpiry(valueFrom(card, '.js-rule-expires')),
             sourceInterfaces: sourceInterfaces.activeValues,
             sourceCidrs: sourceCidrs.activeValues,
             excludedSourceCidrs: excludedSourceCidrs.activeValues,
             sourceMacs: sourceMacs.activeValues,
+            sourceDeviceGroups: sourceDeviceGroups.activeValues,
             destinationCidrs: destinationCidrs.activeValues,
             excludedDestinationCidrs: excludedDestinationCidrs.activeValues,
             destinationPorts: parsePorts(destinationPortsRaw.activeValues.join('\n')),
@@ -61,6 +71,7 @@
               sourceCidrs: sourceCidrs.rawLines,
               excludedSourceCidrs: excludedSourceCidrs.rawLines,
               sourceMacs: sourceMacs.rawLines,
+              sourceDeviceGroups: sourceDeviceGroups.rawLines,
               destinationCidrs: destinationCidrs.rawLines,
               excludedDestinationCidrs: excludedDestinationCidrs.rawLines,
               destinationPorts: destinationPortsRaw.rawLines,
@@ -69,6 +80,7 @@
               excludedDestinationAsns: excludedDestinationAsns.rawLines,
               domains: domains.rawLines,
               wildcardDomains: wildcardDomains.rawLines,
+              staticHosts: staticHosts.rawLines,
             },
           };
           if (ruleHasEditableContent(rule)) {
@@ -78,6 +90,33 @@
         return rules;
       }
 
+      // parseExpiry turns a datetime-local value into Unix seconds; empty
+      // means the rule never expires.
+      function parseExpiry(value) {
+        if (!value) {
+          return 0;
+        }
+        const time = new Date(value).getTime();
+        if (!Number.isFinite(time)) {
+          throw new Error(`Invalid rule expiry "${value}".`);
+        }
+        return Math.floor(time / 1000);
+      }
+
+      function formatExpiry(expiresAt) {
+        if (!expiresAt) {
+          return '';
+        }
+        const date = new Date(expiresAt * 1000);
+        const pad = (value) => String(value).padStart(2, '0');
+        return `${date.getFullYear()}-${pad(date.getMonth() + 1)}-${pad(date.getDate())}T${pad(date.getHours())}:${pad(date.getMinutes())}`;
+      }
+
+      function mbitToKbit(value) {
+        const mbit = Number.parseFloat(value);
+        return Number.isFinite(mbit) && mbit > 0 ? Math.round(mbit * 1000) : 0;
+      }
+
       function normalizeRules(group) {
         if (Array.isArray(group?.rules) && group.rules.length > 0) {
           return group.rules.map((rule, index) => {
@@ -95,6 +134,7 @@
             const excludeMulticast = typeof rule.excludeMulticast === 'boolean' ? rule.excludeMulticast : true;
             const domains = Array.isArray(rule.domains) ? rule.domains : [];
             const wildcardDomains = Array.isArray(rule.wildcardDomains) ? rule.wildcardDomains : [];
+            const staticHosts = Array.isArray(rule.staticHosts) ? rule.staticHosts : [];
             return {
               name: rule.name || `Rule ${index + 1}`,
               sourceInterfaces,
@@ -108,8 +148,15 @@
               destinationAsns,
               excludedDestinationAsns,
               excludeMulticast,
+              uploadLimitKbit: Number(rule.uploadLimitKbit) || 0,
+              downloadLimitKbit: Number(rule.downloadLimitKbit) || 0,
+              monitorOnly: rule.monitorOnly === true,
+              notes: rule.notes || '',
+              tags: Array.isArray(rule.tags) ? rule.tags : [],
+              expiresAt: Number(rule.expiresAt) || 0,
               domains,
               wildcardDomains,
+              staticHosts,
               rawSelectors: {
                 sourceInterfaces: normalizeRawLinesOrFallback(raw.sourceInterfaces, sourceInterfaces),
                 sourceCidrs: normalizeRawLinesOrFallback(raw.sourceCidrs, sourceCidrs),
@@ -129,6 +176,7 @@
                 excludedDestinationAsns: normalizeRawLinesOrFallback(raw.excludedDestinationAsns, excludedDestinationAsns),
                 domains: normalizeRawLinesOrFallback(raw.domains, domains),
                 wildcardDomains: normalizeRawLinesOrFallback(raw.wildcardDomains, wildcardDomains),
+                staticHosts: normalizeRawLinesOrFallback(raw.staticHosts, formattedStaticHostLines(staticHosts)),
               },
             };
           });
@@ -143,6 +191,7 @@
           sourceCidrs: [],
           excludedSourceCidrs: [],
           sourceMacs: [],
+          sourceDeviceGroups: [],
           destinationCidrs: [],
           excludedDestinationCidrs: [],
           destinationPorts: [],
@@ -157,6 +206,7 @@
             sourceCidrs: [],
             excludedSourceCidrs: [],
             sourceMacs: [],
+            sourceDeviceGroups: [],
             destinationCidrs: [],
             excludedDestinationCidrs: [],
             destinationPorts: [],
@@ -210,6 +260,7 @@
             excludedDestinationAsns: [],
             domains: [],
             wildcardDomains: [],
+            staticHosts: [],
           },
         };
         const raw = payload.rawSelectors || {};
@@ -217,6 +268,7 @@
         const sourceCidrsText = selectorText(raw.sourceCidrs, payload.sourceCidrs || []);
         const excludedSourceCidrsText = selectorText(raw.excludedSourceCidrs, payload.excludedSourceCidrs || []);
         const sourceMacsText = selectorText(raw.sourceMacs, payload.sourceMacs || []);
+        const sourceDeviceGroupsText = selectorText(raw.sourceDeviceGroups, payload.sourceDeviceGroups || []);
         const destinationCidrsText = selectorText(raw.destinationCidrs, payload.destinationCidrs || []);
         const excludedDestinationCidrsText = selectorText(raw.excludedDestinationCidrs, payload.excludedDestinationCidrs || []);
         const destinationPortsText = selectorText(raw.destinationPorts, formattedPortLines(payload.destinationPorts || []));
@@ -225,7 +277,14 @@
         const excludedDestinationAsnsText = selectorText(raw.excludedDestinationAsns, payload.excludedDestinationAsns || []);
         const domainsText = selectorText(raw.domains, payload.domains || []);
         const wildcardDomainsText = selectorText(raw.wildcardDomains, payload.wildcardDomains || []);
+        const staticHostsText = selectorText(raw.staticHosts, formattedStaticHostLines(payload.staticHosts || []));
         const excludeMulticast = typeof payload.excludeMulticast === 'boolean' ? payload.excludeMulticast : true;
+        const uploadLimitMbit = payload.uploadLimitKbit > 0 ? String(payload.uploadLimitKbit / 1000) : '';
+        const downloadLimitMbit = payload.downloadLimitKbit > 0 ? String(payload.downloadLimitKbit / 1000) : '';
+        const monitorOnly = payload.monitorOnly === true;
+        const tagsText = (payload.tags || []).join(', ');
+        const expiresAt = Number(payload.expiresAt) || 0;
+        const expired = expiresAt > 0 && expiresAt * 1000 <= Date.now();
         const pickerInputID = `source-mac-picker-${ruleID}`;
         const card = document.createElement('div');
         card.className = 'routing-rule-card border rounded p-3 mb-3';
@@ -243,11 +302,11 @@
         </div>
         <div class="col-12 col-md-4">
           <label class="form-label small text-body-secondary mb-1">Source Interfaces</label>
-          <textarea class="form-control form-control-sm font-monospace js-rule-source-interface" rows="4" placeholder="br0&#10;br6&#10;#Guest VLAN only">${escapeHTML(sourceInterfacesText)}</textarea>
+          <textarea class="form-control form-control-sm font-monospace js-rule-source-interface" rows="4" placeholder="br0&#10;vlan:20&#10;network:IoT#UniFi network by name">${escapeHTML(sourceInterfacesText)}</textarea>
         </div>
         <div class="col-12 col-md-4">
           <label class="form-label small text-body-secondary mb-1">Source CIDRs</label>
-          <textarea class="form-control form-control-sm font-monospace js-rule-source" rows="4" placeholder="10.0.0.0/24&#10;2001:db8::/64&#10;#Temporary block">${escapeHTML(sourceCidrsText)}</textarea>
+          <textarea class="form-control form-control-sm font-monospace js-rule-source" rows="4" placeholder="10.0.0.0/24&#10;vlan:20#Subnets of VLAN 20&#10;br0+::10#Host ::10 in br0's delegated prefix">${escapeHTML(sourceCidrsText)}</textarea>
         </div>
         <div class="col-12 col-md-4">
           <label class="form-label small text-body-secondary mb-1">Source MACs</label>
@@ -259,7 +318,11 @@
           </div>
           <textarea class="form-control form-control-sm font-monospace js-rule-source-mac" rows="4" placeholder="00:30:93:10:0a:12#Apple TV&#10;#00:11:22:33:44:55">${escapeHTML(sourceMacsText)}</textarea>
         </div>
-        <div class="col-12">
+        <div class="col-12 col-md-6">
+          <label class="form-label small text-body-secondary mb-1">Source Device Groups</label>
+          <textarea class="form-control form-control-sm font-monospace js-rule-source-device-groups" rows="3" placeholder="Kids-Devices&#10;#Guests">${escapeHTML(sourceDeviceGroupsText)}</textarea>
+        </div>
+        <div class="col-12 col-md-6">
           <label class="form-label small text-body-secondary mb-1">Excluded Source CIDRs</label>
           <textarea class="form-control form-control-sm font-monospace js-rule-source-excluded" rows="3" placeholder="10.0.0.50/32&#10;2001:db8::50/128&#10;#Bypass this source">${escapeHTML(excludedSourceCidrsText)}</textarea>
         </div>
@@ -303,10 +366,42 @@
           <label class="form-label small text-body-secondary mb-1">Domains</label>
           <textarea class="form-control form-control-sm font-monospace js-rule-domains" rows="4" placeholder="api.example.com&#10;www.apple.com#All apple website traffic">${escapeHTML(domainsText)}</textarea>
         </div>
+        <div class="col-12 col-md-6">
+          <label class="form-label small text-body-secondary mb-1">Bandwidth Limit (Mbit/s, empty = unlimited)</label>
+          <div class="input-group input-group-sm">
+            <span class="input-group-text">Up</span>
+            <input class="form-control js-rule-limit-up" type="number" min="0" step="0.1" placeholder="unlimited" value="${escapeHTML(uploadLimitMbit)}">
+            <span class="input-group-text">Down</span>
+            <input class="form-control js-rule-limit-down" type="number" min="0" step="0.1" placeholder="unlimited" value="${escapeHTML(downloadLimitMbit)}">
+          </div>
+        </div>
+        <div class="col-12 col-md-6">
+          <label class="form-label small text-body-secondary mb-1">Expires${expired ? ' <span class="badge text-bg-warning ms-1">expired</span>' : ''}</label>
+          <input class="form-control form-control-sm js-rule-expires" type="datetime-local" value="${escapeHTML(formatExpiry(expiresAt))}">
+          <div class="form-text">Empty never expires. After this time the rule stops routing until the expiry is cleared or moved.</div>
+        </div>
+        <div class="col-12 col-md-6 d-flex align-items-end">
+          <div class="form-check form-switch mb-1">
+            <input class="form-check-input js-rule-monitor-only" type="checkbox" role="switch"${monitorOnly ? ' checked' : ''}>
+            <label class="form-check-label small text-body-secondary">Monitor only: log matching connections (NFLOG group 77) and show them in the flow inspector without routing them</label>
+          </div>
+        </div>
         <div class="col-12">
           <label class="form-label small text-body-secondary mb-1">Wildcard Domains</label>
           <textarea class="form-control form-control-sm font-monospace js-rule-wildcards" rows="3" placeholder="*.apple.com&#10;#*.example.net">${escapeHTML(wildcardDomainsText)}</textarea>
         </div>
+        <div class="col-12">
+          <label class="form-label small text-body-secondary mb-1">Static Host Mappings</label>
+          <textarea class="form-control form-control-sm font-monospace js-rule-static-hosts" rows="2" placeholder="api.example.com 203.0.113.5 198.51.100.0/24&#10;*.apple.com 17.0.0.0/8#never resolved by the router">${escapeHTML(staticHostsText)}</textarea>
+        </div>
+        <div class="col-12 col-md-4">
+          <label class="form-label small text-body-secondary mb-1">Tags</label>
+          <input class="form-control form-control-sm js-rule-tags" type="text" placeholder="streaming, kids" value="${escapeHTML(tagsText)}">
+        </div>
+        <div class="col-12 col-md-8">
+          <label class="form-label small text-body-secondary mb-1">Notes</label>
+          <textarea class="form-control form-control-sm js-rule-notes" rows="2" placeholder="Why this rule exists, who asked for it">${escapeHTML(payload.notes || '')}</textarea>
+        </div>
         <div class="col-12">
           <div class="small text-body-secondary">
             Comments are supported in all selector boxes. Anything after <code>#</code> on a line is ignored for matching but saved as entered.
@@ -314,6 +409,9 @@
           <div class="small text-body-secondary">
             Normal Domains match both the exact domain and its subdomains in dnsmasq, but pre-warm only queries domains explicitly listed here.
           </div>
+          <div class="small text-body-secondary">
+            Static Host Mappings pin IPs/CIDRs to one of this rule's domains or wildcards (<code>domain ip/cidr ...</code>). They are always added to the destination sets, for services whose addresses the resolvers never discover.
+          </div>
           <div class="small text-danger mt-1">
             Wildcard Domains discover known subdomains from public data and pre-warm those discovered hosts. Use large top domains (for example <code>*.microsoft.com</code> / <code>microsoft.com</code>) with great care: they can expand into huge domain lists and create massive IPv4/IPv6 ipsets.
           </div>
@@ -338,6 +436,15 @@
         }
         return fallbackValues.map((value) => String(value || '').replace(/\r/g, ''));
       }
+      function formattedStaticHostLines(hosts) {
+        if (!Array.isArray(hosts)) {
+          return [];
+        }
+        return hosts
+          .filter((host) => host && host.domain && Array.isArray(host.cidrs) && host.cidrs.length > 0)
+          .map((host) => `${host.domain} ${host.cidrs.join(' ')}`);
+      }
+
       function formattedPortLines(ports) {
         const text = formatPorts(Array.isArray(ports) ? ports : []);
         if (!text) {
@@ -365,6 +472,12 @@
           if (hints) {
             labelParts.push(hints);
           }
+          if (device.network) {
+            labelParts.push(device.network);
+          }
+          if (device.fixedIp) {
+            labelParts.push(`reserved ${device.fixedIp}`);
+          }
           option.label = labelParts.join(' • ');
           option.setAttribute('data-search', device.searchText || '');
           datalist.appendChild(option);
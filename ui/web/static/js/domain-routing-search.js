@@ -0,0 +1,87 @@
+(() => {
+  const form = document.getElementById('selector-search-form');
+  const input = document.getElementById('selector-search-input');
+  const results = document.getElementById('selector-search-results');
+
+  if (!form || !input || !results) {
+    return;
+  }
+
+  const fieldLabels = {
+    domains: 'domain',
+    wildcardDomains: 'wildcard',
+    sourceInterfaces: 'source interface',
+    sourceCidrs: 'source CIDR',
+    excludedSourceCidrs: 'excluded source',
+    sourceMacs: 'source MAC',
+    sourceDeviceGroups: 'device group',
+    destinationCidrs: 'destination CIDR',
+    excludedDestinationCidrs: 'excluded destination',
+    destinationAsns: 'ASN',
+    excludedDestinationAsns: 'excluded ASN',
+    staticHosts: 'static host',
+    resolved: 'resolved prefix',
+    resolvedExcluded: 'resolved exclusion',
+    notes: 'notes',
+    tags: 'tag',
+  };
+
+  form.addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const query = input.value.trim();
+    if (!query) {
+      results.classList.add('d-none');
+      results.innerHTML = '';
+      return;
+    }
+    results.classList.remove('d-none');
+    results.innerHTML = '<span class="text-body-secondary">Searching…</span>';
+    try {
+      const response = await fetch(`/api/routing/search?q=${encodeURIComponent(query)}`);
+      const body = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(body.error || response.statusText || 'Search failed');
+      }
+      results.innerHTML = renderMatches(Array.isArray(body.matches) ? body.matches : [], body.truncated === true);
+    } catch (err) {
+      results.innerHTML = `<span class="text-danger">${escapeHTML(err.message)}</span>`;
+    }
+  });
+
+  function renderMatches(matches, truncated) {
+    if (matches.length === 0) {
+      return '<span class="text-body-secondary">No group uses a matching selector, note or tag.</span>';
+    }
+    const rows = matches.map((match) => `
+      <tr>
+        <td>${escapeHTML(match.group)} <span class="badge text-bg-primary ms-1">${escapeHTML(match.egressVpn)}</span></td>
+        <td>${escapeHTML(ruleLabel(match))}</td>
+        <td>${escapeHTML(fieldLabels[match.field] || match.field)}</td>
+        <td class="font-monospace">${escapeHTML(match.value)}${match.via ? ` <span class="text-body-secondary">via ${escapeHTML(match.via)}</span>` : ''}</td>
+      </tr>`).join('');
+    return `
+      <div class="table-responsive">
+        <table class="table table-sm align-middle small mb-0">
+          <thead><tr><th>Group</th><th>Rule</th><th>Selector</th><th>Value</th></tr></thead>
+          <tbody>${rows}</tbody>
+        </table>
+      </div>
+      ${truncated ? '<div class="text-body-secondary mt-1">Showing the first matches only; refine the search to see more.</div>' : ''}`;
+  }
+
+  function ruleLabel(match) {
+    const index = Number(match.ruleIndex || 0);
+    if (index < 0) {
+      return 'Group';
+    }
+    return match.ruleName || `Rule ${index + 1}`;
+  }
+
+  function escapeHTML(value) {
+    return String(value ?? '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;');
+  }
+})();
@@ -0,0 +1,152 @@
+(() => {
+  window.SplitVPNDomainRoutingStaging = {
+    createController(ctx) {
+      const { fetchJSON, onChanged, showStatus } = ctx || {};
+      const toggle = document.getElementById('domain-staging-toggle');
+      const banner = document.getElementById('domain-staging-banner');
+      const summary = document.getElementById('domain-staging-summary');
+      const changesBox = document.getElementById('domain-staging-changes');
+      const publishButton = document.getElementById('domain-staging-publish');
+      const discardButton = document.getElementById('domain-staging-discard');
+
+      if (!toggle || !banner || !summary || !changesBox || !publishButton || !discardButton || typeof fetchJSON !== 'function') {
+        return null;
+      }
+
+      const actionLabels = {
+        create: 'new',
+        update: 'changed',
+        delete: 'deleted',
+      };
+      let status = { enabled: false, changes: [] };
+
+      toggle.addEventListener('change', async () => {
+        const enabled = toggle.checked;
+        if (!enabled && status.changes.length > 0
+          && !window.confirm(`Turning staging off publishes ${countLabel(status.changes.length)}. Continue?`)) {
+          toggle.checked = true;
+          return;
+        }
+        toggle.disabled = true;
+        try {
+          const data = await fetchJSON('/api/routing/staging', {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ enabled }),
+          });
+          render(data.staging);
+          notify(enabled ? 'Change staging on: group edits wait for Publish.' : 'Change staging off.', false);
+        } catch (err) {
+          toggle.checked = !enabled;
+          notify(err.message, true);
+        } finally {
+          toggle.disabled = false;
+        }
+      });
+      summary.addEventListener('click', (event) => {
+        if (event.target.closest('[data-staging-review]')) {
+          changesBox.classList.toggle('d-none');
+        }
+      });
+      publishButton.addEventListener('click', () => run('publish', 'Pending changes published.'));
+      discardButton.addEventListener('click', () => {
+        if (window.confirm(`Discard ${countLabel(status.changes.length)} and return to the published groups?`)) {
+          run('discard', 'Pending changes discarded.');
+        }
+      });
+
+      async function load() {
+        try {
+          const data = await fetchJSON('/api/routing/staging');
+          render(data.staging);
+        } catch (err) {
+          banner.classList.add('d-none');
+        }
+      }
+
+      async function run(action, message) {
+        publishButton.disabled = true;
+        discardButton.disabled = true;
+        try {
+          await fetchJSON(`/api/routing/staging/${action}`, { method: 'POST' });
+          notify(message, false);
+          if (typeof onChanged === 'function') {
+            await onChanged();
+          } else {
+            await load();
+          }
+        } catch (err) {
+          notify(err.message, true);
+        } finally {
+          publishButton.disabled = false;
+          discardButton.disabled = false;
+        }
+      }
+
+      function render(next) {
+        status = {
+          enabled: next?.enabled === true,
+          changes: Array.isArray(next?.changes) ? next.changes : [],
+        };
+        toggle.checked = status.enabled;
+        if (!status.enabled) {
+          banner.classList.add('d-none');
+          changesBox.classList.add('d-none');
+          return;
+        }
+        const pending = status.changes.length;
+        banner.classList.remove('d-none');
+        publishButton.disabled = pending === 0;
+        discardButton.disabled = pending === 0;
+        summary.innerHTML = pending === 0
+          ? '<i class="bi bi-layers me-1"></i>Change staging is on. Routing follows the published groups; there are no pending changes.'
+          : `<i class="bi bi-layers me-1"></i>${escapeHTML(countLabel(pending))} not live yet.
+             <button type="button" class="btn btn-link btn-sm p-0 align-baseline" data-staging-review>Review</button>`;
+        changesBox.innerHTML = status.changes.map(renderChange).join('');
+        if (pending === 0) {
+          changesBox.classList.add('d-none');
+        }
+      }
+
+      function renderChange(change) {
+        const diff = change.diff || {};
+        const lines = [
+          ...(Array.isArray(diff.removed) ? diff.removed : []).map((line) => `<div class="text-danger">- ${escapeHTML(line)}</div>`),
+          ...(Array.isArray(diff.added) ? diff.added : []).map((line) => `<div class="text-success">+ ${escapeHTML(line)}</div>`),
+        ].join('');
+        return `
+          <div class="border-top pt-2 mt-2">
+            <span class="badge text-bg-secondary me-1">${escapeHTML(actionLabels[change.action] || change.action)}</span>
+            <strong>${escapeHTML(change.name)}</strong>
+            <span class="badge text-bg-primary ms-1">${escapeHTML(change.egressVpn)}</span>
+            ${lines ? `<div class="font-monospace small mt-1">${lines}</div>` : ''}
+          </div>`;
+      }
+
+      function countLabel(count) {
+        return `${count} pending change${count === 1 ? '' : 's'}`;
+      }
+
+      function notify(message, isError) {
+        if (typeof showStatus === 'function') {
+          showStatus(message, isError);
+        }
+      }
+
+      function isEnabled() {
+        return status.enabled;
+      }
+
+      return { load, isEnabled };
+    },
+  };
+
+  function escapeHTML(value) {
+    return String(value ?? '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;')
+      .replaceAll("'", '&#39;');
+  }
+})();
@@ -0,0 +1,229 @@
+(() => {
+  window.SplitVPNDomainRoutingTemplates = {
+    createController(ctx) {
+      const { fetchJSON, getVPNs, onChanged, showStatus } = ctx || {};
+      const openTemplateButton = document.getElementById('open-group-template');
+      const modalElement = document.getElementById('groupCopyModal');
+      const title = document.getElementById('group-copy-title');
+      const statusBox = document.getElementById('group-copy-status');
+      const templateRow = document.getElementById('group-copy-template-row');
+      const templateSelect = document.getElementById('group-copy-template');
+      const templateDelete = document.getElementById('group-copy-template-delete');
+      const templateDescription = document.getElementById('group-copy-template-description');
+      const nameInput = document.getElementById('group-copy-name');
+      const descriptionRow = document.getElementById('group-copy-description-row');
+      const descriptionInput = document.getElementById('group-copy-description');
+      const egressRow = document.getElementById('group-copy-egress-row');
+      const egressSelect = document.getElementById('group-copy-egress');
+      const submitButton = document.getElementById('group-copy-submit');
+      const submitLabel = document.getElementById('group-copy-submit-label');
+
+      if (
+        !modalElement ||
+        !title ||
+        !statusBox ||
+        !templateRow ||
+        !templateSelect ||
+        !templateDelete ||
+        !templateDescription ||
+        !nameInput ||
+        !descriptionRow ||
+        !descriptionInput ||
+        !egressRow ||
+        !egressSelect ||
+        !submitButton ||
+        !submitLabel ||
+        typeof fetchJSON !== 'function'
+      ) {
+        return null;
+      }
+
+      const modal = new bootstrap.Modal(modalElement);
+      const current = { mode: null, group: null, templates: [] };
+
+      if (openTemplateButton) {
+        openTemplateButton.addEventListener('click', () => openInstantiate());
+      }
+      templateSelect.addEventListener('change', () => renderTemplateDescription());
+      templateDelete.addEventListener('click', () => deleteSelectedTemplate());
+      submitButton.addEventListener('click', () => submit());
+
+      function openDuplicate(group) {
+        current.mode = 'duplicate';
+        current.group = group;
+        show({
+          heading: '<i class="bi bi-copy me-2"></i>Duplicate Group',
+          label: 'Duplicate',
+          name: '',
+          placeholder: 'Leave empty to name it after the group and VPN',
+          egress: group.egressVpn || '',
+        });
+      }
+
+      function openSaveTemplate(group) {
+        current.mode = 'save-template';
+        current.group = group;
+        show({
+          heading: '<i class="bi bi-bookmark-plus me-2"></i>Save as Template',
+          label: 'Save Template',
+          name: group.name || '',
+          placeholder: 'Template name',
+        });
+      }
+
+      async function openInstantiate() {
+        current.mode = 'instantiate';
+        current.group = null;
+        show({
+          heading: '<i class="bi bi-bookmark me-2"></i>New Group from Template',
+          label: 'Create Group',
+          name: '',
+          placeholder: 'New group name',
+          egress: '',
+        });
+        await loadTemplates();
+      }
+
+      function show({ heading, label, name, placeholder, egress }) {
+        title.innerHTML = heading;
+        submitLabel.textContent = label;
+        nameInput.value = name;
+        nameInput.placeholder = placeholder || '';
+        descriptionInput.value = '';
+        clearStatus();
+        templateRow.classList.toggle('d-none', current.mode !== 'instantiate');
+        descriptionRow.classList.toggle('d-none', current.mode !== 'save-template');
+        egressRow.classList.toggle('d-none', current.mode === 'save-template');
+        renderEgressOptions(egress);
+        modal.show();
+      }
+
+      function renderEgressOptions(selected) {
+        const vpns = typeof getVPNs === 'function' ? getVPNs() : [];
+        egressSelect.innerHTML = vpns
+          .map((vpn) => `<option value="${escapeHTML(vpn.name)}">${escapeHTML(vpn.name)}</option>`)
+          .join('');
+        if (selected && vpns.some((vpn) => vpn.name === selected)) {
+          egressSelect.value = selected;
+        }
+      }
+
+      async function loadTemplates() {
+        try {
+          const data = await fetchJSON('/api/group-templates');
+          current.templates = Array.isArray(data.templates) ? data.templates : [];
+        } catch (err) {
+          current.templates = [];
+          setStatus(err.message, 'alert-danger');
+        }
+        templateSelect.innerHTML = current.templates.length
+          ? current.templates.map((template) => `<option value="${template.id}">${escapeHTML(template.name)}</option>`).join('')
+          : '<option value="">No templates saved yet</option>';
+        templateDelete.disabled = current.templates.length === 0;
+        submitButton.disabled = current.templates.length === 0;
+        renderTemplateDescription();
+      }
+
+      function renderTemplateDescription() {
+        const template = selectedTemplate();
+        if (!template) {
+          templateDescription.textContent = 'Save a group as a template from its card first.';
+          return;
+        }
+        const rules = Array.isArray(template.group?.rules) ? template.group.rules.length : 0;
+        const details = `${rules} rule${rules === 1 ? '' : 's'}`;
+        templateDescription.textContent = template.description ? `${template.description} · ${details}` : details;
+      }
+
+      function selectedTemplate() {
+        const id = Number(templateSelect.value || 0);
+        return current.templates.find((template) => Number(template.id) === id) || null;
+      }
+
+      async function deleteSelectedTemplate() {
+        const template = selectedTemplate();
+        if (!template) {
+          return;
+        }
+        templateDelete.disabled = true;
+        try {
+          await fetchJSON(`/api/group-templates/${template.id}`, { method: 'DELETE' });
+          setStatus(`Template ${template.name} deleted.`, 'alert-success');
+          await loadTemplates();
+        } catch (err) {
+          setStatus(err.message, 'alert-danger');
+          templateDelete.disabled = false;
+        }
+      }
+
+      async function submit() {
+        const name = nameInput.value.trim();
+        submitButton.disabled = true;
+        try {
+          if (current.mode === 'duplicate') {
+            await postJSON(`/api/groups/${current.group.id}/duplicate`, { name, egressVpn: egressSelect.value });
+            notify('Policy group duplicated.');
+          } else if (current.mode === 'save-template') {
+            await postJSON('/api/group-templates', {
+              name,
+              description: descriptionInput.value.trim(),
+              groupId: current.group.id,
+            });
+            notify('Group saved as template.');
+            return;
+          } else if (current.mode === 'instantiate') {
+            const template = selectedTemplate();
+            if (!template) {
+              return;
+            }
+            await postJSON(`/api/group-templates/${template.id}/instantiate`, { name, egressVpn: egressSelect.value });
+            notify('Policy group created from template.');
+          }
+          if (typeof onChanged === 'function') {
+            await onChanged();
+          }
+        } catch (err) {
+          setStatus(err.message, 'alert-danger');
+        } finally {
+          submitButton.disabled = false;
+        }
+      }
+
+      function postJSON(url, body) {
+        return fetchJSON(url, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify(body),
+        });
+      }
+
+      function notify(message) {
+        modal.hide();
+        if (typeof showStatus === 'function') {
+          showStatus(message, false);
+        }
+      }
+
+      function setStatus(message, variant) {
+        statusBox.className = `alert py-2 small mb-3 ${variant}`;
+        statusBox.textContent = message;
+      }
+
+      function clearStatus() {
+        statusBox.className = 'alert d-none py-2 small mb-3';
+        statusBox.textContent = '';
+      }
+
+      return { openDuplicate, openSaveTemplate, openInstantiate };
+    },
+  };
+
+  function escapeHTML(value) {
+    return String(value || '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;')
+      .replaceAll("'", '&#39;');
+  }
+})();
@@ -0,0 +1,130 @@
+(() => {
+  const openButton = document.getElementById('open-route-trace');
+  const modalElement = document.getElementById('routeTraceModal');
+  const form = document.getElementById('route-trace-form');
+  const sourceInput = document.getElementById('route-trace-source');
+  const destinationInput = document.getElementById('route-trace-destination');
+  const protocolSelect = document.getElementById('route-trace-protocol');
+  const portInput = document.getElementById('route-trace-port');
+  const runButton = document.getElementById('route-trace-run');
+  const statusBox = document.getElementById('route-trace-status');
+  const result = document.getElementById('route-trace-result');
+
+  if (!openButton || !modalElement || !form || !sourceInput || !destinationInput || !protocolSelect || !portInput || !runButton || !statusBox || !result) {
+    return;
+  }
+
+  const modal = new bootstrap.Modal(modalElement);
+
+  openButton.addEventListener('click', () => {
+    hideStatus();
+    result.innerHTML = '';
+    modal.show();
+  });
+
+  form.addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const payload = {
+      source: sourceInput.value.trim(),
+      destination: destinationInput.value.trim(),
+      protocol: protocolSelect.value,
+      port: Number(portInput.value || 0),
+    };
+    if (!payload.source || !payload.destination) {
+      return;
+    }
+    runButton.disabled = true;
+    result.innerHTML = '';
+    showStatus('Tracing…', 'alert-secondary');
+    try {
+      const response = await fetch('/api/routing/trace', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+      });
+      const body = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(body.error || response.statusText || 'Trace failed');
+      }
+      const trace = body.trace || {};
+      if (trace.mismatch) {
+        showStatus(trace.reason || 'Kernel decision differs from the expected binding.', 'alert-danger');
+      } else {
+        showStatus('Kernel decision matches the expected binding.', 'alert-success');
+      }
+      result.innerHTML = renderTrace(trace);
+    } catch (err) {
+      showStatus(err.message, 'alert-danger');
+    } finally {
+      runButton.disabled = false;
+    }
+  });
+
+  function renderTrace(trace) {
+    const matches = Array.isArray(trace.matches) ? trace.matches : [];
+    const kernel = trace.kernel || {};
+    const expected = trace.expected
+      ? bindingLabel(trace.expected)
+      : '<span class="text-body-secondary">No rule claims this packet — default route expected.</span>';
+    const expectedKey = trace.expected ? `${trace.expected.group}#${trace.expected.rule}` : '';
+    const matchRows = matches.map((match) => `
+      <tr class="${`${match.group}#${match.rule}` === expectedKey ? 'table-active' : ''}">
+        <td>${escapeHTML(match.vpn)}</td>
+        <td>${escapeHTML(match.group)}${match.monitorOnly ? ' <span class="badge text-bg-info ms-1">monitor only</span>' : ''}</td>
+        <td>#${match.rule}</td>
+        <td class="font-monospace">${escapeHTML(match.interface)}</td>
+        <td>${match.routeTable}</td>
+        <td class="font-monospace">${escapeHTML(match.mark)}</td>
+      </tr>`).join('');
+    return `
+      <dl class="row small mb-3">
+        <dt class="col-sm-3">Packet</dt>
+        <dd class="col-sm-9 font-monospace">${escapeHTML(trace.source)} → ${escapeHTML(trace.destination)}</dd>
+        <dt class="col-sm-3">Ingress</dt>
+        <dd class="col-sm-9 font-monospace">${escapeHTML(trace.sourceInterface || 'local')}${trace.sourceMac ? ` (${escapeHTML(trace.sourceMac)})` : ''}</dd>
+        <dt class="col-sm-3">Expected</dt>
+        <dd class="col-sm-9">${expected}</dd>
+        <dt class="col-sm-3">Kernel</dt>
+        <dd class="col-sm-9">
+          <span class="badge ${kernel.type === 'unicast' ? 'text-bg-secondary' : 'text-bg-warning'} me-1">${escapeHTML(kernel.type || 'error')}</span>
+          <span class="font-monospace">${escapeHTML(kernel.interface || '–')}</span>
+          ${kernel.gateway ? `via <span class="font-monospace">${escapeHTML(kernel.gateway)}</span>` : ''}
+          ${kernel.table ? `table <span class="font-monospace">${escapeHTML(kernel.table)}</span>` : ''}
+        </dd>
+      </dl>
+      <div class="small text-body-secondary mb-1">Command</div>
+      <pre class="small bg-body-tertiary p-2 rounded mb-1">${escapeHTML(kernel.command || '')}</pre>
+      <pre class="small bg-body-tertiary p-2 rounded mb-3">${escapeHTML(kernel.raw || '')}</pre>
+      <h6 class="small">Matching rules (${matches.length})</h6>
+      ${matches.length ? `
+      <div class="table-responsive">
+        <table class="table table-sm align-middle small mb-0">
+          <thead><tr><th>VPN</th><th>Group</th><th>Rule</th><th>Interface</th><th>Table</th><th>Mark</th></tr></thead>
+          <tbody>${matchRows}</tbody>
+        </table>
+      </div>` : '<div class="small text-body-secondary">None.</div>'}`;
+  }
+
+  function bindingLabel(binding) {
+    return `<strong>${escapeHTML(binding.vpn)}</strong> via <span class="font-monospace">${escapeHTML(binding.interface)}</span>
+      <span class="text-body-secondary">(${escapeHTML(binding.group)} rule #${binding.rule}, table ${binding.routeTable}, mark ${escapeHTML(binding.mark)})</span>`;
+  }
+
+  function escapeHTML(value) {
+    return String(value ?? '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;');
+  }
+
+  function showStatus(message, variant) {
+    statusBox.className = `alert py-2 small mb-3 ${variant}`;
+    statusBox.textContent = message;
+  }
+
+  function hideStatus() {
+    statusBox.className = 'alert d-none py-2 small mb-3';
+    statusBox.textContent = '';
+  }
+})();
@@ -0,0 +1,140 @@
+(() => {
+  window.SplitVPNDomainRoutingTrash = {
+    createController(ctx) {
+      const { fetchJSON, onChanged, showStatus } = ctx || {};
+      const openButton = document.getElementById('open-trash');
+      const modalElement = document.getElementById('trashModal');
+      const statusBox = document.getElementById('trash-status');
+      const retentionLabel = document.getElementById('trash-retention');
+      const groupsBox = document.getElementById('trash-groups');
+      const vpnsBox = document.getElementById('trash-vpns');
+
+      if (!openButton || !modalElement || !statusBox || !retentionLabel || !groupsBox || !vpnsBox || typeof fetchJSON !== 'function') {
+        return null;
+      }
+
+      const modal = new bootstrap.Modal(modalElement);
+
+      openButton.addEventListener('click', () => {
+        clearStatus();
+        modal.show();
+        load();
+      });
+      modalElement.addEventListener('click', (event) => {
+        const button = event.target.closest('button[data-trash-action]');
+        if (!button) {
+          return;
+        }
+        act(button.dataset.trashAction, button.dataset.trashKind, button.dataset.trashId, button);
+      });
+
+      async function load() {
+        groupsBox.innerHTML = '<span class="small text-body-secondary">Loading…</span>';
+        vpnsBox.innerHTML = '';
+        try {
+          const payload = await fetchJSON('/api/trash');
+          const days = Number(payload?.retentionDays || 0);
+          retentionLabel.textContent = days > 0
+            ? `Deleted items are kept for ${days} day${days === 1 ? '' : 's'} before they are removed for good.`
+            : '';
+          groupsBox.innerHTML = renderEntries(Array.isArray(payload?.groups) ? payload.groups : [], 'group');
+          vpnsBox.innerHTML = renderEntries(Array.isArray(payload?.vpns) ? payload.vpns : [], 'vpn');
+        } catch (err) {
+          groupsBox.innerHTML = '';
+          setStatus(err.message, 'alert-danger');
+        }
+      }
+
+      async function act(action, kind, id, button) {
+        if (!id || (kind !== 'group' && kind !== 'vpn')) {
+          return;
+        }
+        const base = `/api/trash/${kind === 'group' ? 'groups' : 'vpns'}/${encodeURIComponent(id)}`;
+        button.disabled = true;
+        clearStatus();
+        try {
+          if (action === 'restore') {
+            await fetchJSON(`${base}/restore`, { method: 'POST' });
+            setStatus(kind === 'group' ? 'Policy group restored.' : 'VPN profile restored. Start it when ready.', 'alert-success');
+            if (kind === 'group' && typeof onChanged === 'function') {
+              await onChanged();
+            }
+          } else if (action === 'purge') {
+            if (!window.confirm('Delete this item permanently? It cannot be restored afterwards.')) {
+              return;
+            }
+            await fetchJSON(base, { method: 'DELETE' });
+            setStatus('Deleted permanently.', 'alert-success');
+          }
+          await load();
+        } catch (err) {
+          setStatus(err.message, 'alert-danger');
+          if (typeof showStatus === 'function' && kind === 'group') {
+            showStatus(err.message, true);
+          }
+        } finally {
+          button.disabled = false;
+        }
+      }
+
+      function renderEntries(entries, kind) {
+        if (entries.length === 0) {
+          return '<div class="small text-body-secondary">Nothing in the trash.</div>';
+        }
+        const rows = entries.map((entry) => {
+          const detail = kind === 'group'
+            ? `<span class="badge text-bg-primary ms-1">${escapeHTML(entry.egressVpn)}</span>
+               <span class="text-body-secondary ms-1">${Array.isArray(entry.group?.rules) ? entry.group.rules.length : 0} rule(s)</span>`
+            : `<span class="badge text-bg-secondary ms-1">${escapeHTML(entry.type || 'unknown')}</span>
+               <span class="text-body-secondary ms-1 font-monospace">${escapeHTML(entry.interfaceName || '')}</span>`;
+          return `
+            <tr>
+              <td>${escapeHTML(entry.name)}${detail}</td>
+              <td class="text-body-secondary">${escapeHTML(formatDeletedAt(entry.deletedAt))}</td>
+              <td class="text-end text-nowrap">
+                <button type="button" class="btn btn-outline-success btn-sm" data-trash-action="restore" data-trash-kind="${kind}" data-trash-id="${escapeHTML(entry.id)}">
+                  <i class="bi bi-arrow-counterclockwise me-1"></i>Restore
+                </button>
+                <button type="button" class="btn btn-outline-danger btn-sm" data-trash-action="purge" data-trash-kind="${kind}" data-trash-id="${escapeHTML(entry.id)}" title="Delete permanently">
+                  <i class="bi bi-x-lg"></i>
+                </button>
+              </td>
+            </tr>`;
+        }).join('');
+        return `
+          <div class="table-responsive">
+            <table class="table table-sm align-middle small mb-0">
+              <thead><tr><th>Name</th><th>Deleted</th><th></th></tr></thead>
+              <tbody>${rows}</tbody>
+            </table>
+          </div>`;
+      }
+
+      function formatDeletedAt(seconds) {
+        const value = Number(seconds || 0);
+        return value > 0 ? new Date(value * 1000).toLocaleString() : '';
+      }
+
+      function setStatus(message, variant) {
+        statusBox.className = `alert py-2 small mb-3 ${variant}`;
+        statusBox.textContent = message;
+      }
+
+      function clearStatus() {
+        statusBox.className = 'alert d-none py-2 small mb-3';
+        statusBox.textContent = '';
+      }
+
+      return { load };
+    },
+  };
+
+  function escapeHTML(value) {
+    return String(value ?? '')
+      .replaceAll('&', '&amp;')
+      .replaceAll('<', '&lt;')
+      .replaceAll('>', '&gt;')
+      .replaceAll('"', '&quot;')
+      .replaceAll("'", '&#39;');
+  }
+})();
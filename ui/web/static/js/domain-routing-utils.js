@@ -58,12 +58,20 @@
     return parsed;
   }
 
+  function parseTags(rawValue) {
+    return String(rawValue || '')
+      .split(',')
+      .map((tag) => tag.trim())
+      .filter((tag) => tag !== '');
+  }
+
   function ruleHasSelectors(rule) {
     return (
       rule.sourceInterfaces.length > 0 ||
       rule.sourceCidrs.length > 0 ||
       rule.excludedSourceCidrs.length > 0 ||
       rule.sourceMacs.length > 0 ||
+      (rule.sourceDeviceGroups || []).length > 0 ||
       rule.destinationCidrs.length > 0 ||
       rule.destinationPorts.length > 0 ||
       rule.excludedDestinationCidrs.length > 0 ||
@@ -116,6 +124,7 @@
       fieldHasAnyLine(raw.sourceCidrs) ||
       fieldHasAnyLine(raw.excludedSourceCidrs) ||
       fieldHasAnyLine(raw.sourceMacs) ||
+      fieldHasAnyLine(raw.sourceDeviceGroups) ||
       fieldHasAnyLine(raw.destinationCidrs) ||
       fieldHasAnyLine(raw.destinationPorts) ||
       fieldHasAnyLine(raw.excludedDestinationCidrs) ||
@@ -123,7 +132,8 @@
       fieldHasAnyLine(raw.destinationAsns) ||
       fieldHasAnyLine(raw.excludedDestinationAsns) ||
       fieldHasAnyLine(raw.domains) ||
-      fieldHasAnyLine(raw.wildcardDomains)
+      fieldHasAnyLine(raw.wildcardDomains) ||
+      fieldHasAnyLine(raw.staticHosts)
     );
   }
 
@@ -135,6 +145,7 @@
     parseSelectorField,
     parseLines,
     parsePorts,
+    parseTags,
     ruleHasSelectors,
     ruleHasEditableContent,
     formatPorts,
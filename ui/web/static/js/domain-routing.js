@@ -8,6 +8,16 @@
   const groupModalTitle = document.getElementById('domain-group-modal-title');
   const groupNameInput = document.getElementById('domain-group-name');
   const groupEgressSelect = document.getElementById('domain-group-egress');
+  const groupDNSRedirectSelect = document.getElementById('domain-group-dns-redirect');
+  const groupUpstreamsInput = document.getElementById('domain-group-dnsmasq-upstreams');
+  const groupIPSetTimeoutInput = document.getElementById('domain-group-ipset-timeout');
+  const groupIsolatedInput = document.getElementById('domain-group-dnsmasq-isolated');
+  const groupInvertInput = document.getElementById('domain-group-invert-destinations');
+  const groupFullTunnelInput = document.getElementById('domain-group-full-tunnel');
+  const groupKillSwitchInput = document.getElementById('domain-group-kill-switch');
+  const groupPriorityInput = document.getElementById('domain-group-priority');
+  const groupTagsInput = document.getElementById('domain-group-tags');
+  const groupNotesInput = document.getElementById('domain-group-notes');
   const addRuleButton = document.getElementById('add-routing-rule');
   const rulesList = document.getElementById('routing-rules-list');
   const saveGroupButton = document.getElementById('save-domain-group');
@@ -22,6 +32,11 @@
   const deleteGroupName = document.getElementById('delete-group-name');
   const confirmDeleteGroupButton = document.getElementById('confirm-delete-group');
   const refreshButton = document.getElementById('refresh-configs');
+  const canaryBanner = document.getElementById('domain-canary-banner');
+  const canaryControls = document.getElementById('domain-group-canary-controls');
+  const dnsmasqWarnings = document.getElementById('domain-dnsmasq-warnings');
+  const conflictWarnings = document.getElementById('domain-conflict-warnings');
+  const groupConflicts = document.getElementById('domain-group-conflicts');
   if (
     !groupsList ||
     !groupsEmpty ||
@@ -31,6 +46,7 @@
     !groupModalTitle ||
     !groupNameInput ||
     !groupEgressSelect ||
+    !groupDNSRedirectSelect ||
     !addRuleButton ||
     !rulesList ||
     !saveGroupButton ||
@@ -46,6 +62,30 @@
     && typeof window.SplitVPNDomainRoutingRules.createController === 'function'
     ? window.SplitVPNDomainRoutingRules.createController
     : null;
+  const canaryFactory = window.SplitVPNDomainRoutingCanary
+    && typeof window.SplitVPNDomainRoutingCanary.createController === 'function'
+    ? window.SplitVPNDomainRoutingCanary.createController
+    : null;
+  const pasteFactory = window.SplitVPNDomainRoutingPaste
+    && typeof window.SplitVPNDomainRoutingPaste.createController === 'function'
+    ? window.SplitVPNDomainRoutingPaste.createController
+    : null;
+  const templatesFactory = window.SplitVPNDomainRoutingTemplates
+    && typeof window.SplitVPNDomainRoutingTemplates.createController === 'function'
+    ? window.SplitVPNDomainRoutingTemplates.createController
+    : null;
+  const trashFactory = window.SplitVPNDomainRoutingTrash
+    && typeof window.SplitVPNDomainRoutingTrash.createController === 'function'
+    ? window.SplitVPNDomainRoutingTrash.createController
+    : null;
+  const guestFactory = window.SplitVPNDomainRoutingGuest
+    && typeof window.SplitVPNDomainRoutingGuest.createController === 'function'
+    ? window.SplitVPNDomainRoutingGuest.createController
+    : null;
+  const stagingFactory = window.SplitVPNDomainRoutingStaging
+    && typeof window.SplitVPNDomainRoutingStaging.createController === 'function'
+    ? window.SplitVPNDomainRoutingStaging.createController
+    : null;
   const asnPreviewFactory = window.SplitVPNDomainRoutingASNPreview
     && typeof window.SplitVPNDomainRoutingASNPreview.createController === 'function'
     ? window.SplitVPNDomainRoutingASNPreview.createController
@@ -67,12 +107,49 @@
       fetchJSON,
     })
     : null;
+  const pasteController = pasteFactory
+    ? pasteFactory({
+      fetchJSON,
+      onApplied: loadDomainGroups,
+    })
+    : null;
+  const templatesController = templatesFactory
+    ? templatesFactory({
+      fetchJSON,
+      getVPNs: () => state.vpns,
+      onChanged: loadDomainGroups,
+      showStatus,
+    })
+    : null;
+  const stagingController = stagingFactory
+    ? stagingFactory({
+      fetchJSON,
+      onChanged: loadDomainGroups,
+      showStatus,
+    })
+    : null;
+  if (guestFactory) {
+    guestFactory({
+      fetchJSON,
+      getVPNs: () => state.vpns,
+      onChanged: loadDomainGroups,
+      showStatus,
+    });
+  }
+  if (trashFactory) {
+    trashFactory({
+      fetchJSON,
+      onChanged: loadDomainGroups,
+      showStatus,
+    });
+  }
   const state = {
     groups: [],
     vpns: [],
     devices: [],
     editingGroupID: null,
     pendingDeleteID: null,
+    acknowledgedConflicts: null,
     nextRuleID: 1,
   };
 
@@ -94,6 +171,22 @@
     console.error('domain-routing rule controller unavailable');
     return;
   }
+  const canaryController = canaryFactory
+    ? canaryFactory({
+      bannerElement: canaryBanner,
+      controlsElement: canaryControls,
+      deviceInput: document.getElementById('domain-group-canary-device'),
+      deviceDatalist: document.getElementById('domain-group-canary-devices'),
+      startButton: document.getElementById('start-domain-group-canary'),
+      state,
+      fetchJSON,
+      showStatus,
+      escapeHTML,
+      buildGroupPayload,
+      onStarted: () => groupModal.hide(),
+      onFinished: loadDomainGroups,
+    })
+    : null;
 
   addGroupButton.addEventListener('click', async () => {
     await openAddGroupModal();
@@ -140,6 +233,31 @@
     }
     if (action === 'delete') {
       openDeleteGroupModal(groupID);
+      return;
+    }
+    if (action === 'paste' || action === 'duplicate' || action === 'save-template') {
+      const group = state.groups.find((entry) => Number(entry.id) === groupID);
+      if (!group) {
+        return;
+      }
+      if (action === 'paste') {
+        pasteController?.open(group);
+      } else if (action === 'duplicate') {
+        templatesController?.openDuplicate(group);
+      } else {
+        templatesController?.openSaveTemplate(group);
+      }
+      return;
+    }
+    if (action === 'move-up' || action === 'move-down') {
+      actionTarget.disabled = true;
+      try {
+        await moveGroup(groupID, action === 'move-up' ? -1 : 1);
+      } catch (err) {
+        showStatus(err.message, true);
+      } finally {
+        actionTarget.disabled = false;
+      }
     }
   });
 
@@ -152,7 +270,7 @@
     try {
       await fetchJSON(`/api/groups/${id}`, { method: 'DELETE' });
       deleteGroupModal.hide();
-      showStatus('Policy group deleted.', false);
+      showStatus('Policy group moved to the trash.', false);
       state.pendingDeleteID = null;
       await loadDomainGroups();
     } catch (err) {
@@ -162,6 +280,11 @@
     }
   });
 
+  // Rule expiries are announced on the SSE stream.
+  groupsList.addEventListener('domain-groups:changed', () => {
+    loadDomainGroups().catch((err) => showStatus(err.message, true));
+  });
+
   if (refreshButton) {
     refreshButton.addEventListener('click', async () => {
       await Promise.all([loadVPNs(), loadDomainGroups(), loadDevices()]);
@@ -171,9 +294,27 @@
   async function initialize() {
     try {
       await Promise.all([loadVPNs(), loadDomainGroups(), loadDevices()]);
+      await canaryController?.refresh();
     } catch (err) {
       showStatus(err.message, true);
     }
+    loadDnsmasqStatus();
+  }
+
+  // loadDnsmasqStatus warns when the dnsmasq answering clients does not read
+  // the generated config. Detection is best effort and failures stay quiet.
+  async function loadDnsmasqStatus() {
+    if (!dnsmasqWarnings) {
+      return;
+    }
+    try {
+      const status = await fetchJSON('/api/routing/dnsmasq');
+      const warnings = Array.isArray(status.warnings) ? status.warnings : [];
+      dnsmasqWarnings.textContent = warnings.length > 0 ? `dnsmasq: ${warnings.join('; ')}` : '';
+      dnsmasqWarnings.classList.toggle('d-none', warnings.length === 0);
+    } catch (err) {
+      dnsmasqWarnings.classList.add('d-none');
+    }
   }
 
   async function loadVPNs() {
@@ -187,9 +328,68 @@
   async function loadDomainGroups() {
     const data = await fetchJSON('/api/groups');
     const groups = Array.isArray(data.groups) ? data.groups : [];
-    groups.sort((a, b) => (a.name || '').localeCompare(b.name || ''));
     state.groups = groups;
     renderDomainGroups(groups);
+    loadConflicts();
+    if (stagingController) {
+      stagingController.load();
+    }
+  }
+
+  // loadConflicts lists overlapping, duplicate and shadowed rules across the
+  // saved groups. Failures stay quiet like the dnsmasq check.
+  async function loadConflicts() {
+    if (!conflictWarnings) {
+      return;
+    }
+    try {
+      const data = await fetchJSON('/api/routing/conflicts');
+      const conflicts = Array.isArray(data.conflicts) ? data.conflicts : [];
+      conflictWarnings.innerHTML = conflicts.length > 0 ? renderConflicts(conflicts) : '';
+      conflictWarnings.classList.toggle('d-none', conflicts.length === 0);
+    } catch (err) {
+      conflictWarnings.classList.add('d-none');
+    }
+  }
+
+  function renderConflicts(conflicts) {
+    const items = conflicts
+      .map((conflict) => `<li>${escapeHTML(conflict.message || '')}</li>`)
+      .join('');
+    return `<div class="fw-semibold">${conflicts.length} routing conflict${conflicts.length === 1 ? '' : 's'}</div><ul class="mb-0 ps-3">${items}</ul>`;
+  }
+
+  function clearGroupConflicts() {
+    state.acknowledgedConflicts = null;
+    if (groupConflicts) {
+      groupConflicts.innerHTML = '';
+      groupConflicts.classList.add('d-none');
+    }
+  }
+
+  // confirmGroupConflicts checks the payload for conflicts before it is
+  // saved and applied. The first save shows them; saving the same payload
+  // again goes ahead.
+  async function confirmGroupConflicts(payload) {
+    if (!groupConflicts) {
+      return true;
+    }
+    const query = state.editingGroupID ? `?groupId=${state.editingGroupID}` : '';
+    const data = await fetchJSON(`/api/routing/conflicts${query}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(payload),
+    });
+    const conflicts = (Array.isArray(data.conflicts) ? data.conflicts : [])
+      .filter((conflict) => (conflict.rules || []).some((rule) => rule.group === payload.name));
+    const signature = JSON.stringify(payload);
+    if (conflicts.length === 0 || state.acknowledgedConflicts === signature) {
+      return true;
+    }
+    state.acknowledgedConflicts = signature;
+    groupConflicts.innerHTML = `${renderConflicts(conflicts)}<div class="mt-1">Save again to apply anyway.</div>`;
+    groupConflicts.classList.remove('d-none');
+    return false;
   }
 
   async function loadDevices() {
@@ -205,14 +405,18 @@
       const ipHints = Array.isArray(device?.ipHints)
         ? device.ipHints.map((entry) => String(entry || '').trim()).filter((entry) => entry !== '')
         : [];
+      const network = String(device?.network || '').trim();
+      const fixedIp = String(device?.fixedIp || '').trim();
       const existing = byMAC.get(mac);
       if (!existing) {
-        byMAC.set(mac, { mac, name, ipHints });
+        byMAC.set(mac, { mac, name, ipHints, network, fixedIp });
         return;
       }
       if (!existing.name && name) {
         existing.name = name;
       }
+      existing.network = existing.network || network;
+      existing.fixedIp = existing.fixedIp || fixedIp;
       const mergedHints = new Set([...(existing.ipHints || []), ...ipHints]);
       existing.ipHints = Array.from(mergedHints);
     });
@@ -226,10 +430,15 @@
           searchParts.push(entry.name);
         }
         searchParts.push(...hints);
+        if (entry.network) {
+          searchParts.push(entry.network);
+        }
         return {
           mac: entry.mac,
           name: entry.name,
           ipHints: hints,
+          network: entry.network,
+          fixedIp: entry.fixedIp,
           searchText: searchParts.join(' ').toLowerCase(),
         };
       })
@@ -257,8 +466,13 @@
       return;
     }
     groupsEmpty.classList.add('d-none');
-    groups.forEach((group) => {
+    const now = Date.now() / 1000;
+    groups.forEach((group, index) => {
       const rules = rulesController.normalizeRules(group);
+      const managed = Boolean(group.managed);
+      const expiries = rules.map((rule) => Number(rule.expiresAt) || 0).filter((expiresAt) => expiresAt > 0);
+      const expiredCount = expiries.filter((expiresAt) => expiresAt <= now).length;
+      const nextExpiry = Math.min(...expiries.filter((expiresAt) => expiresAt > now));
       const card = document.createElement('div');
       card.className = 'domain-group-card';
       card.innerHTML = `
@@ -267,14 +481,38 @@
             <div class="fw-semibold text-truncate">${escapeHTML(group.name || '')}</div>
             <div class="small text-body-secondary">
               <span class="badge text-bg-primary">${escapeHTML(group.egressVpn || 'n/a')}</span>
+              ${group.invertDestinations ? '<span class="badge text-bg-warning ms-1">all except</span>' : ''}
+              ${group.fullTunnel ? '<span class="badge text-bg-info ms-1">full tunnel</span>' : ''}
+              ${group.killSwitch ? '<span class="badge text-bg-danger ms-1">kill switch</span>' : ''}
+              ${group.managed === 'guest-safe-mode' ? '<span class="badge text-bg-secondary ms-1">guest safe mode</span>' : ''}
+              ${Number(group.priority || 0) !== 0 ? `<span class="badge text-bg-secondary ms-1">priority ${Number(group.priority)}</span>` : ''}
+              ${expiredCount > 0 ? `<span class="badge text-bg-warning ms-1">${expiredCount} expired</span>` : ''}
+              ${Number.isFinite(nextExpiry) ? `<span class="badge text-bg-secondary ms-1" title="A rule stops routing at this time">expires ${escapeHTML(new Date(nextExpiry * 1000).toLocaleString())}</span>` : ''}
+              ${(group.tags || []).map((tag) => `<span class="badge text-bg-light ms-1">${escapeHTML(tag)}</span>`).join('')}
+              ${group.notes ? `<i class="bi bi-journal-text ms-1" title="${escapeHTML(group.notes)}"></i>` : ''}
               <span class="ms-1">${rules.length} rules</span>
             </div>
           </div>
           <div class="btn-group btn-group-sm" role="group">
-            <button class="btn btn-outline-light" data-action="edit" data-group-id="${group.id}" title="Edit group">
+            <button class="btn btn-outline-light" data-action="move-up" data-group-id="${group.id}" title="Raise priority"${index === 0 ? ' disabled' : ''}>
+              <i class="bi bi-arrow-up"></i>
+            </button>
+            <button class="btn btn-outline-light" data-action="move-down" data-group-id="${group.id}" title="Lower priority"${index === groups.length - 1 ? ' disabled' : ''}>
+              <i class="bi bi-arrow-down"></i>
+            </button>
+            <button class="btn btn-outline-light" data-action="paste" data-group-id="${group.id}" title="Paste selectors"${pasteController && !managed ? '' : ' hidden'}>
+              <i class="bi bi-clipboard-plus"></i>
+            </button>
+            <button class="btn btn-outline-light" data-action="duplicate" data-group-id="${group.id}" title="Duplicate group"${templatesController ? '' : ' hidden'}>
+              <i class="bi bi-copy"></i>
+            </button>
+            <button class="btn btn-outline-light" data-action="save-template" data-group-id="${group.id}" title="Save as template"${templatesController ? '' : ' hidden'}>
+              <i class="bi bi-bookmark-plus"></i>
+            </button>
+            <button class="btn btn-outline-light" data-action="edit" data-group-id="${group.id}" title="Edit group"${managed ? ' hidden' : ''}>
               <i class="bi bi-pencil"></i>
             </button>
-            <button class="btn btn-outline-danger" data-action="delete" data-group-id="${group.id}" title="Delete group">
+            <button class="btn btn-outline-danger" data-action="delete" data-group-id="${group.id}" title="Delete group"${managed ? ' hidden' : ''}>
               <i class="bi bi-trash"></i>
             </button>
           </div>
@@ -326,6 +564,9 @@
         if (rule.excludeMulticast !== false) {
           tokens.push('xmc:on');
         }
+        if (rule.monitorOnly) {
+          tokens.push('monitor');
+        }
         if (rule.domains.length) {
           tokens.push(`domain:${rule.domains.length}`);
         }
@@ -344,7 +585,29 @@
     groupNameInput.value = '';
     groupNameInput.readOnly = false;
     selectDefaultEgressVPN();
+    groupDNSRedirectSelect.value = '';
+    if (groupInvertInput) {
+      groupInvertInput.checked = false;
+    }
+    if (groupFullTunnelInput) {
+      groupFullTunnelInput.checked = false;
+    }
+    if (groupKillSwitchInput) {
+      groupKillSwitchInput.checked = false;
+    }
+    if (groupPriorityInput) {
+      groupPriorityInput.value = '';
+    }
+    if (groupTagsInput) {
+      groupTagsInput.value = '';
+    }
+    if (groupNotesInput) {
+      groupNotesInput.value = '';
+    }
+    setGroupDnsmasqFields({});
+    clearGroupConflicts();
     rulesController.resetRules([]);
+    canaryController?.setEditing(null);
     groupModal.show();
   }
 
@@ -360,7 +623,29 @@
     groupNameInput.value = group.name || '';
     groupNameInput.readOnly = false;
     groupEgressSelect.value = group.egressVpn || '';
+    groupDNSRedirectSelect.value = group.dnsRedirect || '';
+    if (groupInvertInput) {
+      groupInvertInput.checked = group.invertDestinations === true;
+    }
+    if (groupFullTunnelInput) {
+      groupFullTunnelInput.checked = group.fullTunnel === true;
+    }
+    if (groupKillSwitchInput) {
+      groupKillSwitchInput.checked = group.killSwitch === true;
+    }
+    if (groupPriorityInput) {
+      groupPriorityInput.value = Number(group.priority || 0) || '';
+    }
+    if (groupTagsInput) {
+      groupTagsInput.value = (group.tags || []).join(', ');
+    }
+    if (groupNotesInput) {
+      groupNotesInput.value = group.notes || '';
+    }
+    setGroupDnsmasqFields(group);
+    clearGroupConflicts();
     rulesController.resetRules(rulesController.normalizeRules(group));
+    canaryController?.setEditing(groupID);
     groupModal.show();
   }
 
@@ -377,25 +662,52 @@
 
   async function saveGroup() {
     const payload = buildGroupPayload();
+    if (!(await confirmGroupConflicts(payload))) {
+      return;
+    }
     if (state.editingGroupID) {
       await fetchJSON(`/api/groups/${state.editingGroupID}`, {
         method: 'PUT',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(payload),
       });
-      showStatus('Policy group updated.', false);
+      showStatus(savedMessage('Policy group updated.'), false);
     } else {
       await fetchJSON('/api/groups', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(payload),
       });
-      showStatus('Policy group created.', false);
+      showStatus(savedMessage('Policy group created.'), false);
     }
     groupModal.hide();
     await loadDomainGroups();
   }
 
+  // savedMessage notes that a saved edit is only pending while staging is on.
+  function savedMessage(message) {
+    return stagingController && stagingController.isEnabled()
+      ? `${message} Publish the pending changes to apply it.`
+      : message;
+  }
+
+  async function moveGroup(groupID, offset) {
+    const ids = state.groups.map((entry) => Number(entry.id));
+    const from = ids.indexOf(groupID);
+    const to = from + offset;
+    if (from < 0 || to < 0 || to >= ids.length) {
+      return;
+    }
+    [ids[from], ids[to]] = [ids[to], ids[from]];
+    await fetchJSON('/api/groups/order', {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ groupIds: ids }),
+    });
+    showStatus('Policy group order updated.', false);
+    await loadDomainGroups();
+  }
+
   function buildGroupPayload() {
     const name = (groupNameInput.value || '').trim();
     const egressVPN = (groupEgressSelect.value || '').trim();
@@ -409,7 +721,52 @@
     if (rules.length === 0) {
       throw new Error('At least one rule with selectors or comment lines is required.');
     }
-    return { name, egressVpn: egressVPN, rules };
+    return {
+      name,
+      egressVpn: egressVPN,
+      dnsRedirect: groupDNSRedirectSelect.value || '',
+      invertDestinations: !!groupInvertInput?.checked,
+      fullTunnel: !!groupFullTunnelInput?.checked,
+      killSwitch: !!groupKillSwitchInput?.checked,
+      priority: Number(groupPriorityInput?.value || 0) || 0,
+      notes: (groupNotesInput?.value || '').trim(),
+      tags: helper.parseTags(groupTagsInput?.value || ''),
+      ...readGroupDnsmasqFields(),
+      rules,
+    };
+  }
+
+  function setGroupDnsmasqFields(group) {
+    if (groupUpstreamsInput) {
+      groupUpstreamsInput.value = (group.dnsmasqUpstreams || []).join(', ');
+    }
+    if (groupIPSetTimeoutInput) {
+      groupIPSetTimeoutInput.value = Number(group.ipsetTimeoutSeconds || 0) || '';
+    }
+    if (groupIsolatedInput) {
+      groupIsolatedInput.checked = group.dnsmasqIsolated === true;
+    }
+  }
+
+  function readGroupDnsmasqFields() {
+    const fields = {};
+    if (groupUpstreamsInput) {
+      fields.dnsmasqUpstreams = (groupUpstreamsInput.value || '')
+        .split(/[\s,]+/)
+        .map((entry) => entry.trim())
+        .filter(Boolean);
+    }
+    if (groupIPSetTimeoutInput) {
+      const timeout = Number(groupIPSetTimeoutInput.value || 0);
+      if (!Number.isFinite(timeout) || timeout < 0) {
+        throw new Error('IPSet entry timeout must be a positive number of seconds.');
+      }
+      fields.ipsetTimeoutSeconds = Math.round(timeout);
+    }
+    if (groupIsolatedInput) {
+      fields.dnsmasqIsolated = groupIsolatedInput.checked;
+    }
+    return fields;
   }
 
   function renderEgressOptions() {
@@ -27,6 +27,7 @@
   const copyTokenButton = document.getElementById('copy-token');
   const regenerateTokenButton = document.getElementById('regenerate-token');
   const downloadBackupButton = document.getElementById('download-backup');
+  const downloadDiagnosticsButton = document.getElementById('download-diagnostics');
   const restoreBackupFileInput = document.getElementById('restore-backup-file');
   const restoreBackupButton = document.getElementById('restore-backup');
   const restartServiceButton = document.getElementById('restart-service');
@@ -36,7 +37,7 @@
     prewarmParallelism, prewarmQueryAttempts, prewarmExtraNameservers, prewarmEcsProfiles, prewarmProgressWrap,
     prewarmProgressBar, prewarmProgressLabel, prewarmProgressMeta, prewarmPerVPNProgress, settingsModalElement,
     currentPasswordInput, newPasswordInput, changePasswordButton, tokenInput, copyTokenButton, regenerateTokenButton,
-    downloadBackupButton, restoreBackupFileInput, restoreBackupButton, restartServiceButton,
+    downloadBackupButton, downloadDiagnosticsButton, restoreBackupFileInput, restoreBackupButton, restartServiceButton,
   ];
   if (requiredElements.some((element) => !element)) {
     return;
@@ -80,9 +81,11 @@
   });
   saveScheduleButton.addEventListener('click', async () => {
     saveScheduleButton.disabled = true;
+    markPrewarmFieldErrors([]);
     try {
       await saveSchedule();
     } catch (err) {
+      markPrewarmFieldErrors(err.payload?.fieldErrors);
       showPrewarmStatus(err.message, true);
     } finally {
       saveScheduleButton.disabled = false;
@@ -126,7 +129,7 @@
   downloadBackupButton.addEventListener('click', async () => {
     downloadBackupButton.disabled = true;
     try {
-      await downloadBackup();
+      await downloadFile('/api/backup/export', 'split-vpn-webui-backup.json');
       showPrewarmStatus('Backup downloaded.', false);
     } catch (err) {
       showPrewarmStatus(err.message, true);
@@ -134,6 +137,18 @@
       downloadBackupButton.disabled = false;
     }
   });
+  downloadDiagnosticsButton.addEventListener('click', async () => {
+    downloadDiagnosticsButton.disabled = true;
+    showPrewarmStatus('Collecting diagnostics…', false);
+    try {
+      await downloadFile('/api/diagnostics/bundle', 'split-vpn-webui-diagnostics.tar.gz');
+      showPrewarmStatus('Diagnostics bundle downloaded.', false);
+    } catch (err) {
+      showPrewarmStatus(err.message, true);
+    } finally {
+      downloadDiagnosticsButton.disabled = false;
+    }
+  });
   restoreBackupButton.addEventListener('click', async () => {
     const file = restoreBackupFileInput.files && restoreBackupFileInput.files[0];
     if (!file) {
@@ -198,8 +213,14 @@
     showPrewarmStatus('Stopping pre-warm run…', false);
   }
   async function loadPrewarmStatus() {
-    const status = await fetchJSON('/api/prewarm/status');
-    renderPrewarmStatus(status);
+    const payload = await fetchJSON('/api/jobs?kind=prewarm&limit=1');
+    const job = Array.isArray(payload.jobs) && payload.jobs.length > 0 ? payload.jobs[0] : null;
+    const running = Boolean(job && (job.state === 'queued' || job.state === 'running'));
+    renderPrewarmStatus({
+      running,
+      lastRun: payload.lastRun || null,
+      progress: running ? job.progress || null : null,
+    });
   }
   function renderPrewarmStatus(status) {
     const running = status?.running === true;
@@ -367,6 +388,20 @@
     prewarmEcsProfiles.value = ecsProfiles.replace(/\r\n/g, '\n').replace(/\n+$/g, '');
     showPrewarmStatus('Pre-warm settings saved.', false);
   }
+  function markPrewarmFieldErrors(fieldErrors) {
+    const inputs = {
+      prewarmIntervalSeconds: prewarmIntervalMinutes,
+      prewarmDoHTimeoutSeconds: prewarmTimeoutSeconds,
+      prewarmParallelism,
+      prewarmQueryAttempts,
+      prewarmExtraNameservers,
+      prewarmEcsProfiles,
+    };
+    const invalid = new Set((Array.isArray(fieldErrors) ? fieldErrors : []).map((item) => item?.field));
+    Object.entries(inputs).forEach(([field, input]) => {
+      input?.classList.toggle('is-invalid', invalid.has(field));
+    });
+  }
   async function loadAuthToken() {
     const response = await fetchJSON('/api/auth/token');
     tokenInput.value = response?.token || '';
@@ -391,8 +426,8 @@
     tokenInput.value = response?.token || '';
     showPrewarmStatus('API token regenerated.', false);
   }
-  async function downloadBackup() {
-    const response = await fetch('/api/backup/export');
+  async function downloadFile(path, fallbackName) {
+    const response = await fetch(path);
     if (!response.ok) {
       throw await responseError(response);
     }
@@ -401,7 +436,7 @@
     try {
       const link = document.createElement('a');
       link.href = url;
-      link.download = filenameFromContentDisposition(response.headers.get('content-disposition')) || 'split-vpn-webui-backup.json';
+      link.download = filenameFromContentDisposition(response.headers.get('content-disposition')) || fallbackName;
       document.body.appendChild(link);
       link.click();
       link.remove();
@@ -458,7 +493,9 @@
     }
     if (!response.ok) {
       if (parsed && typeof parsed.error === 'string' && parsed.error) {
-        throw new Error(parsed.error);
+        const error = new Error(parsed.error);
+        error.payload = parsed;
+        throw error;
       }
       let text = '';
       try {
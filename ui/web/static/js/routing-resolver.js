@@ -1,6 +1,7 @@
 (() => {
   const runResolverButton = document.getElementById('run-resolver-now');
   const clearResolverCacheButton = document.getElementById('clear-resolver-cache');
+  const resumeResolverButton = document.getElementById('resume-resolver-run');
   const resolverLastRunAt = document.getElementById('resolver-last-run-at');
   const resolverLastDuration = document.getElementById('resolver-last-duration');
   const resolverLastSelectors = document.getElementById('resolver-last-selectors');
@@ -17,6 +18,10 @@
   const resolverDomainTimeoutSeconds = document.getElementById('resolver-domain-timeout-seconds');
   const resolverAsnTimeoutSeconds = document.getElementById('resolver-asn-timeout-seconds');
   const resolverWildcardTimeoutSeconds = document.getElementById('resolver-wildcard-timeout-seconds');
+  const resolverDomainRatePerMinute = document.getElementById('resolver-domain-rate-per-minute');
+  const resolverAsnRatePerMinute = document.getElementById('resolver-asn-rate-per-minute');
+  const resolverWildcardRatePerMinute = document.getElementById('resolver-wildcard-rate-per-minute');
+  const resolverEgress = document.getElementById('resolver-egress');
   const resolverDomainEnabled = document.getElementById('resolver-domain-enabled');
   const resolverAsnEnabled = document.getElementById('resolver-asn-enabled');
   const resolverWildcardEnabled = document.getElementById('resolver-wildcard-enabled');
@@ -43,6 +48,9 @@
     !resolverDomainTimeoutSeconds ||
     !resolverAsnTimeoutSeconds ||
     !resolverWildcardTimeoutSeconds ||
+    !resolverDomainRatePerMinute ||
+    !resolverAsnRatePerMinute ||
+    !resolverWildcardRatePerMinute ||
     !resolverDomainEnabled ||
     !resolverAsnEnabled ||
     !resolverWildcardEnabled ||
@@ -67,6 +75,21 @@
     }
   });
 
+  if (resumeResolverButton) {
+    resumeResolverButton.addEventListener('click', async () => {
+      resumeResolverButton.disabled = true;
+      try {
+        await fetchJSON('/api/resolver/resume', { method: 'POST' });
+        showStatus('Resolver run resumed.', false);
+        await loadResolverStatus();
+      } catch (err) {
+        showStatus(err.message, true);
+      } finally {
+        resumeResolverButton.disabled = false;
+      }
+    });
+  }
+
   clearResolverCacheButton.addEventListener('click', async () => {
     if (!window.confirm('Clear resolver cache and immediately run resolver again?')) {
       return;
@@ -115,9 +138,16 @@
   }
 
   async function loadResolverStatus() {
-    const status = await fetchJSON('/api/resolver/status');
+    const payload = await fetchJSON('/api/jobs?kind=resolver&limit=1');
+    const job = Array.isArray(payload.jobs) && payload.jobs.length > 0 ? payload.jobs[0] : null;
+    const running = Boolean(job && (job.state === 'queued' || job.state === 'running'));
+    const status = {
+      running,
+      lastRun: payload.lastRun || null,
+      progress: running ? job.progress || null : null,
+    };
     renderResolverStatus(status);
-    schedulePoll(Boolean(status.running));
+    schedulePoll(running);
   }
 
   function renderResolverStatus(status) {
@@ -126,7 +156,8 @@
     if (run && run.startedAt) {
       resolverLastRunAt.textContent = formatTimestamp(run.startedAt);
       resolverLastDuration.textContent = run.durationMs ? `${Number(run.durationMs).toFixed(0)} ms` : '–';
-      resolverLastSelectors.textContent = `${run.selectorsDone || 0}/${run.selectorsTotal || 0}`;
+      const failed = Number(run.selectorsFailed || 0);
+      resolverLastSelectors.textContent = `${run.selectorsDone || 0}/${run.selectorsTotal || 0}${failed > 0 ? ` (${failed} failed)` : ''}`;
       resolverLastPrefixes.textContent = String(run.prefixesResolved || 0);
       if (run.error) {
         showStatus(`Resolver error: ${run.error}`, true);
@@ -160,7 +191,9 @@
     resolverProgressBar.style.width = `${percent}%`;
     resolverProgressBar.textContent = `${percent}%`;
     resolverProgressLabel.textContent = progress.currentSelector || 'Resolving selectors...';
-    resolverProgressMeta.textContent = `${done}/${total} selectors • ${progress.prefixesResolved || 0} prefixes`;
+    const skipped = Number(progress.selectorsSkipped || 0);
+    const resumed = skipped > 0 ? ` • ${skipped} resumed from checkpoint` : '';
+    resolverProgressMeta.textContent = `${done}/${total} selectors • ${progress.prefixesResolved || 0} prefixes${resumed}`;
     renderProviderProgress(progress.perProvider || {});
   }
 
@@ -257,9 +290,15 @@
     resolverDomainTimeoutSeconds.value = domainTimeout > 0 ? domainTimeout : (timeout > 0 ? timeout : 10);
     resolverAsnTimeoutSeconds.value = asnTimeout > 0 ? asnTimeout : (timeout > 0 ? timeout : 10);
     resolverWildcardTimeoutSeconds.value = wildcardTimeout > 0 ? wildcardTimeout : (timeout > 0 ? timeout : 10);
+    resolverDomainRatePerMinute.value = Number(current.resolverDomainRatePerMinute || 0) || '';
+    resolverAsnRatePerMinute.value = Number(current.resolverAsnRatePerMinute || 0) || '';
+    resolverWildcardRatePerMinute.value = Number(current.resolverWildcardRatePerMinute || 0) || '';
     resolverDomainEnabled.checked = current.resolverDomainEnabled !== false;
     resolverAsnEnabled.checked = current.resolverAsnEnabled !== false;
     resolverWildcardEnabled.checked = current.resolverWildcardEnabled !== false;
+    if (resolverEgress) {
+      resolverEgress.value = current.resolverEgress || '';
+    }
   }
 
   async function saveResolverSettings() {
@@ -269,6 +308,11 @@
     const domainTimeout = Number(resolverDomainTimeoutSeconds.value || 0);
     const asnTimeout = Number(resolverAsnTimeoutSeconds.value || 0);
     const wildcardTimeout = Number(resolverWildcardTimeoutSeconds.value || 0);
+    const rates = {
+      Domain: Number(resolverDomainRatePerMinute.value || 0),
+      ASN: Number(resolverAsnRatePerMinute.value || 0),
+      Wildcard: Number(resolverWildcardRatePerMinute.value || 0),
+    };
     if (!Number.isFinite(intervalMinutes) || intervalMinutes <= 0) {
       throw new Error('Resolver interval must be a positive number of minutes.');
     }
@@ -287,6 +331,11 @@
     if (!Number.isFinite(wildcardTimeout) || wildcardTimeout <= 0) {
       throw new Error('Wildcard resolver timeout must be a positive number of seconds.');
     }
+    for (const [label, rate] of Object.entries(rates)) {
+      if (!Number.isFinite(rate) || rate < 0 || rate > 6000) {
+        throw new Error(`${label} resolver rate must be between 0 (default) and 6000 requests per minute.`);
+      }
+    }
 
     const data = await fetchJSON('/api/settings');
     const current = data && data.settings ? data.settings : {};
@@ -305,9 +354,13 @@
       resolverDomainTimeoutSeconds: Math.round(domainTimeout),
       resolverAsnTimeoutSeconds: Math.round(asnTimeout),
       resolverWildcardTimeoutSeconds: Math.round(wildcardTimeout),
+      resolverDomainRatePerMinute: Math.round(rates.Domain),
+      resolverAsnRatePerMinute: Math.round(rates.ASN),
+      resolverWildcardRatePerMinute: Math.round(rates.Wildcard),
       resolverDomainEnabled: resolverDomainEnabled.checked,
       resolverAsnEnabled: resolverAsnEnabled.checked,
       resolverWildcardEnabled: resolverWildcardEnabled.checked,
+      resolverEgress: (resolverEgress?.value || '').trim(),
       debugLogEnabled: current.debugLogEnabled === true,
       debugLogLevel: String(current.debugLogLevel || 'info').toLowerCase(),
     };
@@ -13,6 +13,10 @@ SERVICE_NAME="${SERVICE_NAME:-split-vpn-webui.service}"
 SERVICE_SYMLINK="${SYSTEMD_DIR}/${SERVICE_NAME}"
 UPDATER_SERVICE_NAME="${UPDATER_SERVICE_NAME:-split-vpn-webui-updater.service}"
 UPDATER_SERVICE_SYMLINK="${SYSTEMD_DIR}/${UPDATER_SERVICE_NAME}"
+RESTORE_SERVICE_NAME="${RESTORE_SERVICE_NAME:-split-vpn-webui-restore.service}"
+RESTORE_SERVICE_SYMLINK="${SYSTEMD_DIR}/${RESTORE_SERVICE_NAME}"
+HELPER_SERVICE_NAME="${HELPER_SERVICE_NAME:-split-vpn-webui-helper.service}"
+HELPER_SERVICE_PATH="${SYSTEMD_DIR}/${HELPER_SERVICE_NAME}"
 BINARY_PATH="${BINARY_PATH:-${DATA_DIR}/split-vpn-webui}"
 UNINSTALL_PATH="${UNINSTALL_PATH:-${DATA_DIR}/uninstall.sh}"
 UPDATE_STATUS_FILE="${UPDATE_STATUS_FILE:-${DATA_DIR}/update-status.json}"
@@ -73,6 +77,9 @@ remove_binaries_category() {
 	safe_systemctl disable "${SERVICE_NAME}"
 	safe_systemctl stop "${UPDATER_SERVICE_NAME}"
 	safe_systemctl disable "${UPDATER_SERVICE_NAME}"
+	safe_systemctl disable "${RESTORE_SERVICE_NAME}"
+	safe_systemctl stop "${HELPER_SERVICE_NAME}"
+	safe_systemctl disable "${HELPER_SERVICE_NAME}"
 
 	if remove_path "${BINARY_PATH}"; then
 		add_removed "Binary removed (${BINARY_PATH})"
@@ -106,6 +113,25 @@ remove_binaries_category() {
 	else
 		add_kept "Updater unit symlink kept (not present)"
 	fi
+
+	if remove_path "${UNITS_DIR}/${RESTORE_SERVICE_NAME}"; then
+		add_removed "Boot restore unit removed (${UNITS_DIR}/${RESTORE_SERVICE_NAME})"
+		daemon_reload_required=1
+	else
+		add_kept "Boot restore unit kept (not present)"
+	fi
+	if remove_path "${RESTORE_SERVICE_SYMLINK}"; then
+		add_removed "Boot restore unit symlink removed (${RESTORE_SERVICE_SYMLINK})"
+		daemon_reload_required=1
+	else
+		add_kept "Boot restore unit symlink kept (not present)"
+	fi
+	if remove_path "${HELPER_SERVICE_PATH}"; then
+		add_removed "Privilege helper unit removed (${HELPER_SERVICE_PATH})"
+		daemon_reload_required=1
+	else
+		add_kept "Privilege helper unit kept (not present)"
+	fi
 }
 
 remove_vpns_units_category() {
@@ -145,6 +171,12 @@ remove_vpns_units_category() {
 		add_kept "No managed VPN units found under ${UNITS_DIR}"
 	fi
 
+	# Copies the privilege helper runs its units from.
+	if [[ -d "${UNITS_DIR}/vpns" ]]; then
+		rm -rf -- "${UNITS_DIR}/vpns"
+		add_removed "Privilege helper VPN copies removed (${UNITS_DIR}/vpns)"
+	fi
+
 	if [[ -d "${VPNS_DIR}" ]]; then
 		shopt -s nullglob dotglob
 		local vpn_entries=("${VPNS_DIR}"/*)